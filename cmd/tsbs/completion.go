@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/timescale/tsbs/pkg/cli/importdata"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/plugins"
+	"github.com/timescale/tsbs/pkg/querygen"
+)
+
+// valuesFunc returns the valid values of a flag, given the arguments before
+// the one being completed
+type valuesFunc func(args []string) []string
+
+// flagValues maps the names of the flags of a tool whose values can be
+// completed to their valid values
+type flagValues map[string]valuesFunc
+
+func staticValues(f func() []string) valuesFunc {
+	return func(_ []string) []string { return f() }
+}
+
+var generateDataValues = flagValues{
+	"format":   staticValues(data.Formats),
+	"use-case": staticValues(data.UseCases),
+}
+
+var importValues = flagValues{
+	"format": staticValues(data.Formats),
+	"source": staticValues(importdata.Sources),
+}
+
+var generateQueriesValues = flagValues{
+	"format":   staticValues(querygen.Targets),
+	"use-case": staticValues(querygen.UseCases),
+	"query-type": func(args []string) []string {
+		if useCase, ok := flagValue(args, "use-case"); ok {
+			return querygen.QueryTypes(useCase)
+		}
+		return allQueryTypes()
+	},
+}
+
+// completeFlagValues returns a completion function completing the values of
+// the given flags, which are parsed by the tool rather than cobra, in either
+// the "-flag value" or the "-flag=value" form. The values come from the
+// registries, after loading any plugins given by -plugins, so they include
+// the formats plugins add.
+func completeFlagValues(values flagValues) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(values) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		if paths, ok := flagValue(args, "plugins"); ok {
+			// if they fail to load, the builtin values are still completed
+			plugins.Load(plugins.ParseList(paths)...)
+		}
+
+		if name, ok := flagName(toComplete); ok {
+			i := strings.Index(toComplete, "=")
+			if f, ok := values[name]; ok && i >= 0 {
+				var completions []string
+				for _, v := range f(args) {
+					completions = append(completions, toComplete[:i+1]+v)
+				}
+				return completions, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if len(args) > 0 {
+			last := args[len(args)-1]
+			if name, ok := flagName(last); ok && !strings.Contains(last, "=") {
+				if f, ok := values[name]; ok {
+					return f(args), cobra.ShellCompDirectiveNoFileComp
+				}
+			}
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
+
+// flagName returns the name of the flag arg sets, with or without a value, in
+// the forms accepted by the flag package
+func flagName(arg string) (string, bool) {
+	if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+		return "", false
+	}
+	name := strings.TrimPrefix(arg[1:], "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+	return name, len(name) > 0
+}
+
+// flagValue returns the last value given to the flag name in args
+func flagValue(args []string, name string) (string, bool) {
+	value, found := "", false
+	for i, arg := range args {
+		n, ok := flagName(arg)
+		if !ok || n != name {
+			continue
+		}
+		if j := strings.Index(arg, "="); j >= 0 {
+			value, found = arg[j+1:], true
+		} else if i+1 < len(args) {
+			value, found = args[i+1], true
+		}
+	}
+	return value, found
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/querygen"
+)
+
+// prefixed returns values, in the same order, each with prefix prepended
+func prefixed(prefix string, values []string) []string {
+	ret := make([]string, len(values))
+	for i, v := range values {
+		ret[i] = prefix + v
+	}
+	return ret
+}
+
+func TestCompleteFlagValues(t *testing.T) {
+	cases := []struct {
+		desc          string
+		values        flagValues
+		args          []string
+		toComplete    string
+		want          []string
+		wantDirective cobra.ShellCompDirective
+	}{
+		{
+			desc:          "separate value",
+			values:        generateDataValues,
+			args:          []string{"-seed", "123", "-format"},
+			want:          data.Formats(),
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			desc:          "double dash",
+			values:        generateDataValues,
+			args:          []string{"--use-case"},
+			toComplete:    "cpu",
+			want:          data.UseCases(),
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			desc:          "value after equals",
+			values:        generateDataValues,
+			toComplete:    "-use-case=",
+			want:          prefixed("-use-case=", data.UseCases()),
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			desc:          "query types of use case",
+			values:        generateQueriesValues,
+			args:          []string{"-use-case=cpu-only", "-query-type"},
+			want:          querygen.QueryTypes(querygen.UseCaseCPUOnly),
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			desc:          "targets",
+			values:        generateQueriesValues,
+			args:          []string{"-format"},
+			want:          querygen.Targets(),
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			desc:          "value of other flag",
+			values:        generateDataValues,
+			args:          []string{"-file"},
+			wantDirective: cobra.ShellCompDirectiveDefault,
+		},
+		{
+			desc:          "flag already has value",
+			values:        generateDataValues,
+			args:          []string{"-format=influx"},
+			wantDirective: cobra.ShellCompDirectiveDefault,
+		},
+		{
+			desc:          "no values",
+			args:          []string{"-format"},
+			wantDirective: cobra.ShellCompDirectiveDefault,
+		},
+	}
+	for _, c := range cases {
+		got, directive := completeFlagValues(c.values)(nil, c.args, c.toComplete)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: incorrect completions: got %v want %v", c.desc, got, c.want)
+		}
+		if directive != c.wantDirective {
+			t.Errorf("%s: incorrect directive: got %v want %v", c.desc, directive, c.wantDirective)
+		}
+	}
+}
+
+func TestFlagValue(t *testing.T) {
+	cases := []struct {
+		desc      string
+		args      []string
+		want      string
+		wantFound bool
+	}{
+		{desc: "missing", args: []string{"-format", "influx"}},
+		{desc: "separate", args: []string{"-use-case", "devops"}, want: "devops", wantFound: true},
+		{desc: "equals", args: []string{"--use-case=devops"}, want: "devops", wantFound: true},
+		{desc: "last wins", args: []string{"-use-case=devops", "-use-case", "cpu-only"}, want: "cpu-only", wantFound: true},
+		{desc: "no value yet", args: []string{"-use-case"}},
+	}
+	for _, c := range cases {
+		got, found := flagValue(c.args, "use-case")
+		if got != c.want || found != c.wantFound {
+			t.Errorf("%s: incorrect value: got %q, %v want %q, %v", c.desc, got, found, c.want, c.wantFound)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build duckdb
+// +build duckdb
+
+package main
+
+import (
+	"github.com/timescale/tsbs/pkg/cli/loadduckdb"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesduckdb"
+)
+
+func init() {
+	addTarget(target{"duckdb", "DuckDB", loadduckdb.Run, runqueriesduckdb.Run})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/querygen"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+const (
+	defaultInitFile           = "tsbs.yaml"
+	defaultInitTarget         = "timescaledb"
+	defaultInitUseCase        = data.UseCaseCPUOnly
+	defaultInitScale          = "100"
+	defaultInitTimestampStart = "2016-01-01T00:00:00Z"
+	defaultInitTimestampEnd   = "2016-01-02T00:00:00Z"
+	defaultInitSeed           = "123"
+)
+
+// newInitCommand returns the init command, which asks for the settings of a
+// benchmark and writes them to a config file read by every tool with -config
+// (see cli.ParseFlags)
+func newInitCommand() *cobra.Command {
+	var file string
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "init",
+		Short: "Interactively write a config file for a benchmark",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !force {
+				if _, err := os.Stat(file); err == nil {
+					return fmt.Errorf("%s already exists (use --force to overwrite it)", file)
+				}
+			}
+			p := &prompter{in: bufio.NewScanner(cmd.InOrStdin()), out: cmd.OutOrStdout()}
+			s, err := askInitSettings(p)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(file, []byte(s.config()), 0644); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "\nWrote %s. To run the benchmark:\n\n%s", file, s.commands(file))
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "output", "o", defaultInitFile, "Config file to write")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite the config file if it exists")
+	return cmd
+}
+
+// initSettings are the answers to the questions of tsbs init, as given, so
+// relative timestamps stay relative
+type initSettings struct {
+	target         string
+	useCase        string
+	scale          string
+	timestampStart string
+	timestampEnd   string
+	seed           string
+}
+
+// askInitSettings asks for each setting in turn, asking again until the
+// answer is valid
+func askInitSettings(p *prompter) (*initSettings, error) {
+	s := &initSettings{}
+	names := targetNames()
+	now := time.Now()
+	var start time.Time
+	questions := []struct {
+		question string
+		def      string
+		answer   *string
+		check    func(string) error
+	}{
+		{
+			question: fmt.Sprintf("Target database (%s)", strings.Join(names, ", ")),
+			def:      defaultInitTarget,
+			answer:   &s.target,
+			check:    choiceCheck("target", names),
+		},
+		{
+			question: fmt.Sprintf("Use case (%s)", strings.Join(data.UseCases(), ", ")),
+			def:      defaultInitUseCase,
+			answer:   &s.useCase,
+			check:    choiceCheck("use case", data.UseCases()),
+		},
+		{
+			question: "Scale (e.g., number of hosts to simulate)",
+			def:      defaultInitScale,
+			answer:   &s.scale,
+			check: func(v string) error {
+				if n, err := strconv.ParseUint(v, 10, 64); err != nil || n == 0 {
+					return fmt.Errorf("scale must be a whole number greater than 0: '%s'", v)
+				}
+				return nil
+			},
+		},
+		{
+			question: "Start of the time range (RFC3339, or relative to now, e.g., now-24h)",
+			def:      defaultInitTimestampStart,
+			answer:   &s.timestampStart,
+			check: func(v string) (err error) {
+				start, err = cli.ParseTime(v, now)
+				return err
+			},
+		},
+		{
+			question: "End of the time range",
+			def:      defaultInitTimestampEnd,
+			answer:   &s.timestampEnd,
+			check: func(v string) error {
+				end, err := cli.ParseTime(v, now)
+				if err != nil {
+					return err
+				}
+				if !end.After(start) {
+					return fmt.Errorf("end %v is not after start %v", end, start)
+				}
+				return nil
+			},
+		},
+		{
+			question: "Random seed",
+			def:      defaultInitSeed,
+			answer:   &s.seed,
+			check: func(v string) error {
+				if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+					return fmt.Errorf("seed must be a whole number: '%s'", v)
+				}
+				return nil
+			},
+		},
+	}
+	for _, q := range questions {
+		answer, err := p.ask(q.question, q.def, q.check)
+		if err != nil {
+			return nil, err
+		}
+		*q.answer = answer
+	}
+	return s, nil
+}
+
+func choiceCheck(kind string, choices []string) func(string) error {
+	return func(v string) error {
+		if !contains(choices, v) {
+			return suggest.Error(kind, v, choices)
+		}
+		return nil
+	}
+}
+
+// config returns the config file of the settings, whose keys are the flags
+// they set in every tool that has them
+func (s *initSettings) config() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by tsbs init. Every tool reads it with -config=<file>, or\n")
+	fmt.Fprintf(&b, "# $%s=<file>, and flags given on the command line override it.\n", cli.EnvConfig)
+	fmt.Fprintf(&b, "format: %s\n", s.target)
+	fmt.Fprintf(&b, "use-case: %s\n", s.useCase)
+	fmt.Fprintf(&b, "scale-var: %s\n", s.scale)
+	// quoted so they are not read as YAML timestamps
+	fmt.Fprintf(&b, "timestamp-start: %q\n", s.timestampStart)
+	fmt.Fprintf(&b, "timestamp-end: %q\n", s.timestampEnd)
+	fmt.Fprintf(&b, "seed: %s\n", s.seed)
+	return b.String()
+}
+
+// commands returns the commands running the benchmark with the config file
+func (s *initSettings) commands(file string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  tsbs generate data -config=%s -file=data.txt\n", file)
+	fmt.Fprintf(&b, "  tsbs load %s -config=%s < data.txt\n", s.target, file)
+	queryTypes := querygen.QueryTypes(s.useCase)
+	if len(queryTypes) > 0 && hasQueryRunner(s.target) {
+		fmt.Fprintf(&b, "  tsbs generate queries -config=%s -query-type=%s -file=queries.gob\n", file, queryTypes[0])
+		fmt.Fprintf(&b, "  tsbs run %s -config=%s < queries.gob\n", s.target, file)
+	}
+	return b.String()
+}
+
+// prompter asks questions on out and reads the answers, one per line, from
+// in
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask asks question until check accepts the answer, which is def if the
+// answer is empty
+func (p *prompter) ask(question, def string, check func(string) error) (string, error) {
+	for {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("no answer to: %s", question)
+		}
+		answer := strings.TrimSpace(p.in.Text())
+		if len(answer) == 0 {
+			answer = def
+		}
+		if err := check(answer); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+func TestInitCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-init")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "tsbs.yaml")
+
+	// invalid answers are asked again, and empty ones take the default
+	answers := strings.Join([]string{"influxdb", "influx", "", "0", "10", "now-1d", "now-2d", "now", ""}, "\n") + "\n"
+	root := newRootCommand()
+	var out bytes.Buffer
+	root.SetIn(strings.NewReader(answers))
+	root.SetOut(&out)
+	root.SetErr(&out)
+	root.SetArgs([]string{"init", "-o", file})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"did you mean 'influx'?", "scale must be", "is not after start", "tsbs load influx -config=" + file} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// the tools read the settings from the file
+	fs := flag.NewFlagSet("tsbs_generate_data", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	format := fs.String("format", "", "")
+	useCase := fs.String("use-case", "", "")
+	scale := fs.Uint64("scale-var", 1, "")
+	start := fs.String("timestamp-start", "", "")
+	end := fs.String("timestamp-end", "", "")
+	seed := fs.Int64("seed", 0, "")
+	if err := cli.ParseFlags(fs, "tsbs_generate_data", []string{"-config", file}); err != nil {
+		t.Fatalf("could not read config file: %v", err)
+	}
+	if *format != "influx" || *useCase != defaultInitUseCase || *scale != 10 || *start != "now-1d" || *end != "now" || *seed != 123 {
+		t.Errorf("incorrect settings: format %s, use case %s, scale %d, start %s, end %s, seed %d", *format, *useCase, *scale, *start, *end, *seed)
+	}
+
+	// an existing file is only overwritten with --force
+	root = newRootCommand()
+	root.SetIn(strings.NewReader(answers))
+	root.SetOut(ioutil.Discard)
+	root.SetErr(ioutil.Discard)
+	root.SetArgs([]string{"init", "-o", file})
+	if err := root.Execute(); err == nil {
+		t.Errorf("did not error when config file exists")
+	}
+
+	// answers ending early are an error
+	root = newRootCommand()
+	root.SetIn(strings.NewReader("influx\n"))
+	root.SetOut(ioutil.Discard)
+	root.SetErr(ioutil.Discard)
+	root.SetArgs([]string{"init", "--force", "-o", file})
+	if err := root.Execute(); err == nil {
+		t.Errorf("did not error when answers ended early")
+	}
+}
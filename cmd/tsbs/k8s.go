@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/k8s"
+	"github.com/timescale/tsbs/pkg/querygen"
+)
+
+// newKubectl returns the Kubectl running the given binary; it is a variable
+// for ease of testing
+var newKubectl = k8s.ExecKubectl
+
+// newK8sCommand returns the k8s command, which runs a benchmark set up by a
+// config file (see tsbs init) as Kubernetes Jobs, or renders their manifests
+func newK8sCommand() *cobra.Command {
+	var configFile string
+	s := &k8s.Spec{}
+	k8sCmd := &cobra.Command{
+		Use:   "k8s",
+		Short: "Run generation, loading and queries as Kubernetes Jobs",
+	}
+	flags := k8sCmd.PersistentFlags()
+	flags.StringVar(&configFile, "config", "", "Config file of the benchmark (see tsbs init), given to every tool in a ConfigMap; it must also tell the tools how to reach the database from the cluster")
+	flags.StringVar(&s.Name, "name", "tsbs", "Name of the benchmark, prefixing those of the objects")
+	flags.StringVarP(&s.Namespace, "namespace", "n", "", "Namespace of the objects (default: that of the kubectl context)")
+	flags.StringVar(&s.Image, "image", "", "Image of the tools, with the tsbs binary on its PATH and a shell")
+	flags.StringVar(&s.Target, "target", "", "Database to load and query (default: the format of the config file)")
+	flags.StringVar(&s.QueryType, "query-type", "", "Type of queries to generate (default: that of the config file, or else the first of its use case)")
+	flags.StringSliceVar(&s.Phases, "phases", append([]string(nil), k8s.Phases...), "Phases to run; without generate, the data and queries of an earlier run of the same name are used")
+	flags.StringVar(&s.Storage, "storage", k8s.DefaultStorage, "Size of the volume holding the data and queries")
+	flags.StringVar(&s.StorageClass, "storage-class", "", "Storage class of the volume holding the data and queries (default: that of the cluster)")
+	flags.StringVar(&s.ResultsImage, "results-image", k8s.DefaultResultsImage, "Image of the sidecar collecting the results, which only needs a shell")
+
+	var output string
+	render := &cobra.Command{
+		Use:   "render",
+		Short: "Print the manifests of the benchmark's objects",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := readK8sConfig(s, configFile); err != nil {
+				return cli.ConfigError(err)
+			}
+			var w io.Writer = cmd.OutOrStdout()
+			if len(output) > 0 {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			return cli.ConfigError(k8s.Render(w, s))
+		},
+	}
+	render.Flags().StringVarP(&output, "output", "o", "", "File to write the manifests to instead of stdout")
+
+	var kubectl string
+	apply := &cobra.Command{
+		Use:   "apply",
+		Short: "Run the benchmark's Jobs one after the other, printing their results",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if err := readK8sConfig(s, configFile); err != nil {
+				return cli.ConfigError(err)
+			}
+			if err := s.Validate(); err != nil {
+				return cli.ConfigError(err)
+			}
+			ctx, cancel := cli.NotifyInterrupt(context.Background())
+			defer cancel()
+			return k8s.Launch(ctx, s, newKubectl(kubectl), cmd.OutOrStdout())
+		},
+	}
+	apply.Flags().StringVar(&kubectl, "kubectl", "kubectl", "kubectl binary, which must be configured for the cluster")
+
+	k8sCmd.AddCommand(render, apply)
+	return k8sCmd
+}
+
+// readK8sConfig reads the config file into s, defaulting its target and
+// query type to those of the file
+func readK8sConfig(s *k8s.Spec, file string) error {
+	if len(file) == 0 {
+		return fmt.Errorf("no config file given (write one with tsbs init)")
+	}
+	config, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return fmt.Errorf("cannot read config file %s: %v", file, err)
+	}
+	s.ConfigFile = filepath.Base(file)
+	s.Config = config
+	if len(s.Target) == 0 {
+		s.Target = v.GetString("format")
+	}
+	if len(s.Target) > 0 {
+		if err := choiceCheck("target", targetNames())(s.Target); err != nil {
+			return err
+		}
+	}
+	if len(s.QueryType) == 0 {
+		s.QueryType = v.GetString("tsbs_generate_queries.query-type")
+	}
+	if len(s.QueryType) == 0 {
+		s.QueryType = v.GetString("query-type")
+	}
+	if len(s.QueryType) == 0 {
+		useCase := v.GetString("tsbs_generate_queries.use-case")
+		if len(useCase) == 0 {
+			useCase = v.GetString("use-case")
+		}
+		if queryTypes := querygen.QueryTypes(useCase); len(queryTypes) > 0 {
+			s.QueryType = queryTypes[0]
+		}
+	}
+	for i, p := range s.Phases {
+		s.Phases[i] = strings.TrimSpace(p)
+		if s.Phases[i] == k8s.PhaseRun && len(s.Target) > 0 && !hasQueryRunner(s.Target) {
+			return fmt.Errorf("target %s has no query runner: leave out the %s phase with --phases", s.Target, k8s.PhaseRun)
+		}
+	}
+	return nil
+}
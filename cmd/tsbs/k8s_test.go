@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+func TestK8sRenderCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-k8s")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "bench.yaml")
+	config := "format: influx\nuse-case: cpu-only\ntsbs_load_influx:\n  urls: http://influx:8086\n"
+	if err := ioutil.WriteFile(file, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		desc      string
+		args      []string
+		want      []string
+		wantError string
+	}{
+		{
+			desc: "defaults from the config file",
+			args: []string{"--config", file, "--image", "tsbs:1"},
+			want: []string{"bench.yaml: |", "urls: http://influx:8086", "tsbs load 'influx'", "-query-type='cpu-max-all-1'", "name: tsbs-run"},
+		},
+		{
+			desc: "flags override the config file",
+			args: []string{"--config", file, "--image", "tsbs:1", "--name", "nightly", "--target", "timescaledb", "--query-type", "lastpoint", "--phases", "load,run"},
+			want: []string{"tsbs load 'timescaledb'", "tsbs run 'timescaledb'", "name: nightly-run"},
+		},
+		{
+			desc:      "unknown target",
+			args:      []string{"--config", file, "--image", "tsbs:1", "--target", "influxdb"},
+			wantError: "did you mean 'influx'?",
+		},
+		{
+			desc:      "no image",
+			args:      []string{"--config", file},
+			wantError: "no image",
+		},
+		{
+			desc:      "no config",
+			args:      []string{"--image", "tsbs:1"},
+			wantError: "no config file",
+		},
+	}
+	for _, c := range cases {
+		root := newRootCommand()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetErr(ioutil.Discard)
+		root.SetArgs(append([]string{"k8s", "render"}, c.args...))
+		err := root.Execute()
+		if c.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantError) {
+				t.Errorf("%s: incorrect error: got %v want %q", c.desc, err, c.wantError)
+			} else if cli.ExitCode(err) != cli.ExitConfig {
+				t.Errorf("%s: incorrect exit code: got %d", c.desc, cli.ExitCode(err))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		for _, want := range c.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s: output missing %q:\n%s", c.desc, want, out.String())
+			}
+		}
+	}
+}
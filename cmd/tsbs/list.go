@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/plugins"
+	"github.com/timescale/tsbs/pkg/querygen"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// newListCommand returns the list command, which prints what the registries
+// hold: the formats, use cases and query types with their descriptions
+func newListCommand() *cobra.Command {
+	var pluginPaths string
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the supported formats, use cases and query types",
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			return plugins.Load(plugins.ParseList(pluginPaths)...)
+		},
+	}
+	list.PersistentFlags().StringVar(&pluginPaths, "plugins", "", "Comma-separated list of Go plugins to load first, so what they register is listed too")
+
+	var target string
+	queryTypes := &cobra.Command{
+		Use:   "query-types",
+		Short: "List the query types queries can be generated for",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return listQueryTypes(cmd.OutOrStdout(), target)
+		},
+	}
+	queryTypes.Flags().StringVar(&target, "target", "", "Only list the query types supported by this target")
+
+	list.AddCommand(
+		&cobra.Command{
+			Use:   "formats",
+			Short: "List the formats data can be generated in",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return listFormats(cmd.OutOrStdout())
+			},
+		},
+		&cobra.Command{
+			Use:   "use-cases",
+			Short: "List the use cases data can be generated for",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return listUseCases(cmd.OutOrStdout())
+			},
+		},
+		queryTypes,
+	)
+	return list
+}
+
+// listFormats writes the registered formats, then the registered schemes in
+// the "scheme:..." form they are used in
+func listFormats(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, f := range data.Formats() {
+		fmt.Fprintf(tw, "%s\t%s\n", f, serialize.Description(f))
+	}
+	for _, s := range serialize.Schemes() {
+		fmt.Fprintf(tw, "%s:...\t%s\n", s, serialize.Description(s))
+	}
+	return tw.Flush()
+}
+
+func listUseCases(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, uc := range data.UseCases() {
+		fmt.Fprintf(tw, "%s\t%s\n", uc, data.UseCaseDescription(uc))
+	}
+	return tw.Flush()
+}
+
+// listQueryTypes writes the query types of all use cases, or only those
+// supported by target according to its declared capabilities if it is not
+// empty
+func listQueryTypes(w io.Writer, target string) error {
+	var caps *querygen.Capabilities
+	if target != "" {
+		if !contains(querygen.Targets(), target) {
+			return suggest.Error("target", target, querygen.Targets())
+		}
+		if c, ok := querygen.TargetCapabilities(target); ok {
+			caps = &c
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, qt := range allQueryTypes() {
+		if caps == nil || caps.SupportsQueryType(qt) {
+			fmt.Fprintf(tw, "%s\t%s\n", qt, querygen.QueryTypeDescription(qt))
+		}
+	}
+	return tw.Flush()
+}
+
+// allQueryTypes returns the query types of every use case, without
+// duplicates
+func allQueryTypes() []string {
+	var queryTypes []string
+	seen := map[string]bool{}
+	for _, useCase := range querygen.UseCases() {
+		for _, qt := range querygen.QueryTypes(useCase) {
+			if !seen[qt] {
+				seen[qt] = true
+				queryTypes = append(queryTypes, qt)
+			}
+		}
+	}
+	return queryTypes
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestListCommand(t *testing.T) {
+	cases := []struct {
+		desc      string
+		args      []string
+		want      []string
+		notWant   []string
+		shouldErr bool
+	}{
+		{
+			desc: "formats",
+			args: []string{"list", "formats"},
+			want: []string{"influx", "InfluxDB line protocol", "exec:..."},
+		},
+		{
+			desc: "use cases",
+			args: []string{"list", "use-cases"},
+			want: []string{"cpu-only", "cpu-single", "devops"},
+		},
+		{
+			desc: "query types",
+			args: []string{"list", "query-types"},
+			want: []string{"lastpoint", "The last reading for each host", "high-cpu-all"},
+		},
+		{
+			desc:    "query types of target",
+			args:    []string{"list", "query-types", "--target=mongo"},
+			want:    []string{"lastpoint", "high-cpu-1"},
+			notWant: []string{"high-cpu-all"},
+		},
+		{
+			desc:      "unknown target",
+			args:      []string{"list", "query-types", "--target=mongodb"},
+			shouldErr: true,
+		},
+	}
+	for _, c := range cases {
+		root := newRootCommand()
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetErr(&buf)
+		root.SetArgs(c.args)
+		err := root.Execute()
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("%s: unexpected lack of error", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		got := buf.String()
+		for _, w := range c.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%s: output missing %q:\n%s", c.desc, w, got)
+			}
+		}
+		for _, w := range c.notWant {
+			if strings.Contains(got, w) {
+				t.Errorf("%s: output unexpectedly has %q:\n%s", c.desc, w, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// tsbs runs the tools of the Time Series Benchmark Suite as subcommands of a
+// single binary:
+//
+//	tsbs generate data     same as tsbs_generate_data
+//	tsbs generate queries  same as tsbs_generate_queries
+//	tsbs load <target>     same as tsbs_load_<target>
+//	tsbs run <target>      same as tsbs_run_queries_<target>
+//	tsbs import            same as tsbs_import
+//
+// Each subcommand takes the same flags as the binary it replaces, which
+// remain available as thin wrappers around the same code. The duckdb and
+// sqlite targets, whose drivers need cgo, are only built in with the build
+// tag of their name.
+//
+// `tsbs list formats|use-cases|query-types` prints the choices of -format,
+// -use-case and -query-type with short descriptions, and `tsbs list
+// query-types --target=<target>` only those the target supports.
+//
+// `tsbs init` asks for the target, use case, scale and time range of a
+// benchmark and writes them to a config file all the tools read with -config.
+//
+// `tsbs k8s render|apply --config=<file> --image=<image>` runs the benchmark
+// of a config file as Kubernetes Jobs, or prints their manifests.
+//
+// `tsbs report` writes a Grafana dashboard or an HTML report of the results
+// files of runs (see -results-file) or of a results database.
+//
+// `tsbs completion bash|zsh|fish` prints a script for shell completion of the
+// subcommands and of the values of flags such as -format and -use-case.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/generatedata"
+	"github.com/timescale/tsbs/pkg/cli/generatequeries"
+	"github.com/timescale/tsbs/pkg/cli/importdata"
+	"github.com/timescale/tsbs/pkg/cli/loadadx"
+	"github.com/timescale/tsbs/pkg/cli/loadbigquery"
+	"github.com/timescale/tsbs/pkg/cli/loadbigtable"
+	"github.com/timescale/tsbs/pkg/cli/loadcassandra"
+	"github.com/timescale/tsbs/pkg/cli/loadgreptime"
+	"github.com/timescale/tsbs/pkg/cli/loadinflux"
+	"github.com/timescale/tsbs/pkg/cli/loadinflux3"
+	"github.com/timescale/tsbs/pkg/cli/loadm3db"
+	"github.com/timescale/tsbs/pkg/cli/loadmongo"
+	"github.com/timescale/tsbs/pkg/cli/loadmysql"
+	"github.com/timescale/tsbs/pkg/cli/loadotlp"
+	"github.com/timescale/tsbs/pkg/cli/loadpinot"
+	"github.com/timescale/tsbs/pkg/cli/loadpostgres"
+	"github.com/timescale/tsbs/pkg/cli/loadsnowflake"
+	"github.com/timescale/tsbs/pkg/cli/loadtimescaledb"
+	"github.com/timescale/tsbs/pkg/cli/loadtimestream"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesadx"
+	"github.com/timescale/tsbs/pkg/cli/runqueriescassandra"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesgreptime"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesinflux"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesinflux3"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesm3db"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesmongo"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesmysql"
+	"github.com/timescale/tsbs/pkg/cli/runqueriespinot"
+	"github.com/timescale/tsbs/pkg/cli/runqueriespostgres"
+	"github.com/timescale/tsbs/pkg/cli/runqueriestimescaledb"
+	"github.com/timescale/tsbs/pkg/version"
+)
+
+// runFunc runs a tool with its command line flags
+type runFunc func(args []string) error
+
+// target is a database with a loader and, unless runQuery is nil, a query
+// runner
+type target struct {
+	name     string
+	desc     string
+	load     runFunc
+	runQuery runFunc
+}
+
+var targets = []target{
+	{"adx", "Azure Data Explorer", loadadx.Run, runqueriesadx.Run},
+	{"bigquery", "Google BigQuery", loadbigquery.Run, nil},
+	{"bigtable", "Google Cloud Bigtable", loadbigtable.Run, nil},
+	{"cassandra", "Cassandra", loadcassandra.Run, runqueriescassandra.Run},
+	{"greptime", "GreptimeDB", loadgreptime.Run, runqueriesgreptime.Run},
+	{"influx", "InfluxDB", loadinflux.Run, runqueriesinflux.Run},
+	{"influx3", "InfluxDB 3", loadinflux3.Run, runqueriesinflux3.Run},
+	{"m3db", "M3DB", loadm3db.Run, runqueriesm3db.Run},
+	{"mongo", "MongoDB", loadmongo.Run, runqueriesmongo.Run},
+	{"mysql", "MySQL and MariaDB", loadmysql.Run, runqueriesmysql.Run},
+	{"otlp", "OpenTelemetry (OTLP) receivers", loadotlp.Run, nil},
+	{"pinot", "Apache Pinot", loadpinot.Run, runqueriespinot.Run},
+	{"postgres", "PostgreSQL", loadpostgres.Run, runqueriespostgres.Run},
+	{"snowflake", "Snowflake", loadsnowflake.Run, nil},
+	{"timescaledb", "TimescaleDB", loadtimescaledb.Run, runqueriestimescaledb.Run},
+	{"timestream", "Amazon Timestream", loadtimestream.Run, nil},
+}
+
+// addTarget adds a target which is only built in with a build tag, keeping
+// the targets sorted by name
+func addTarget(t target) {
+	i := sort.Search(len(targets), func(i int) bool { return targets[i].name >= t.name })
+	targets = append(targets, target{})
+	copy(targets[i+1:], targets[i:])
+	targets[i] = t
+}
+
+// targetNames returns the names of the targets
+func targetNames() []string {
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+// hasQueryRunner returns whether the target named name has a query runner
+func hasQueryRunner(name string) bool {
+	for _, t := range targets {
+		if t.name == name {
+			return t.runQuery != nil
+		}
+	}
+	return false
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(cli.ExitCode(err))
+	}
+}
+
+// newRootCommand returns the tsbs command with all of its subcommands
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:          "tsbs",
+		Short:        "Time Series Benchmark Suite",
+		Version:      version.Get().String(),
+		SilenceUsage: true,
+	}
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return cli.ConfigError(err)
+	})
+
+	generate := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate data or queries for benchmarking",
+	}
+	generate.AddCommand(
+		toolCommand("data", "Generate time series data from pre-specified use cases", generatedata.Run, generateDataValues),
+		toolCommand("queries", "Generate queries for pre-specified use cases", generatequeries.Run, generateQueriesValues),
+	)
+
+	load := &cobra.Command{
+		Use:   "load",
+		Short: "Load generated data from stdin into a database",
+	}
+	run := &cobra.Command{
+		Use:   "run",
+		Short: "Run generated queries from stdin against a database",
+	}
+	for _, t := range targets {
+		load.AddCommand(toolCommand(t.name, "Load generated data from stdin into "+t.desc, t.load, nil))
+		if t.runQuery == nil {
+			continue
+		}
+		run.AddCommand(toolCommand(t.name, "Run generated queries from stdin against "+t.desc, t.runQuery, nil))
+	}
+
+	imp := toolCommand("import", "Import an external dataset as data in any format", importdata.Run, importValues)
+	root.AddCommand(generate, load, run, imp, newListCommand(), newInitCommand(), newK8sCommand(), newReportCommand())
+	return root
+}
+
+// toolCommand returns a command running a tool. Its arguments are parsed by
+// the tool itself, so they are the same flags, with the same conventions
+// (e.g., -format) and help output, as those of the tool's own binary. The
+// values of the flags in values are completed by shell completion.
+func toolCommand(name, short string, run runFunc, values flagValues) *cobra.Command {
+	return &cobra.Command{
+		Use:                name + " [flags]",
+		Short:              short,
+		DisableFlagParsing: true,
+		ValidArgsFunction:  completeFlagValues(values),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// name the usage of the flags after the subcommand rather than
+			// the binary
+			flag.CommandLine.Usage = func() {
+				fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s:\n", cmd.CommandPath())
+				flag.PrintDefaults()
+			}
+			return run(args)
+		},
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRootCommand(t *testing.T) {
+	root := newRootCommand()
+	paths := [][]string{
+		{"generate", "data"},
+		{"generate", "queries"},
+		{"import"},
+	}
+	for _, tgt := range targets {
+		paths = append(paths, []string{"load", tgt.name})
+		if tgt.runQuery != nil {
+			paths = append(paths, []string{"run", tgt.name})
+		}
+	}
+	for _, path := range paths {
+		cmd, args, err := root.Find(append(path, "-format", "influx"))
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", path, err)
+			continue
+		}
+		if got := cmd.Name(); got != path[len(path)-1] {
+			t.Errorf("%v: incorrect command: got %s", path, got)
+		}
+		if !cmd.DisableFlagParsing {
+			t.Errorf("%v: flags not left to the tool", path)
+		}
+		if len(args) != 2 || args[0] != "-format" {
+			t.Errorf("%v: incorrect args: got %v", path, args)
+		}
+	}
+}
+
+func TestAddTarget(t *testing.T) {
+	oldTargets := targets
+	defer func() { targets = oldTargets }()
+	targets = []target{{name: "a"}, {name: "c"}}
+	addTarget(target{name: "b"})
+	addTarget(target{name: "d"})
+	if got := targetNames(); strings.Join(got, ",") != "a,b,c,d" {
+		t.Errorf("incorrect targets: got %v", got)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/report"
+	"github.com/timescale/tsbs/pkg/results"
+)
+
+// newReportCommand returns the report command, which writes a Grafana
+// dashboard or an HTML report of results files (see -results-file) or of
+// the runs in a results database (see -results-db)
+func newReportCommand() *cobra.Command {
+	var db, target, format, title, output string
+	var limit int
+	cmd := &cobra.Command{
+		Use:   "report [results files or directories...]",
+		Short: "Write a Grafana dashboard or HTML report of results",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !contains(report.Formats, format) {
+				return cli.ConfigError(fmt.Errorf("unknown report format: '%s' (valid choices: %s)", format, strings.Join(report.Formats, ", ")))
+			}
+			if len(args) == 0 && len(db) == 0 {
+				return cli.ConfigError(fmt.Errorf("no results given: give results files or directories, or --results-db"))
+			}
+			recs, err := readResultsFiles(args, target)
+			if err != nil {
+				return cli.DataError(err)
+			}
+			if len(db) > 0 {
+				d, err := results.Open(db)
+				if err != nil {
+					return cli.UnreachableError(err)
+				}
+				defer d.Close()
+				dbRecs, err := d.Records(target, limit)
+				if err != nil {
+					return err
+				}
+				recs = append(recs, dbRecs...)
+			}
+			if len(recs) == 0 {
+				return cli.DataError(fmt.Errorf("no results found"))
+			}
+			sort.SliceStable(recs, func(i, j int) bool { return recs[i].Start.Before(recs[j].Start) })
+
+			var w io.Writer = cmd.OutOrStdout()
+			if len(output) > 0 {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			return report.Write(w, format, title, recs)
+		},
+	}
+	cmd.Flags().StringVar(&db, "results-db", "", "URL of a results database to report the runs of, as given to -results-db of the tools")
+	cmd.Flags().StringVar(&target, "target", "", "Only report the runs against this target")
+	cmd.Flags().IntVar(&limit, "limit", 0, "Only report this many of the latest runs in the results database (0 reports all of them)")
+	cmd.Flags().StringVar(&format, "format", report.FormatHTML, "Format of the report (choices: "+strings.Join(report.Formats, ", ")+")")
+	cmd.Flags().StringVar(&title, "title", "TSBS results", "Title of the report")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write the report to instead of stdout")
+	return cmd
+}
+
+// readResultsFiles reads the results files given, reading all *.json files
+// of directories, keeping those of target if given
+func readResultsFiles(paths []string, target string) ([]*results.Record, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && filepath.Ext(e.Name()) == ".json" {
+				files = append(files, filepath.Join(p, e.Name()))
+			}
+		}
+	}
+	var recs []*results.Record
+	for _, f := range files {
+		rec, err := results.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(target) == 0 || rec.Target == target {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, nil
+}
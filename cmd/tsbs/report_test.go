@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/results"
+)
+
+func TestReportCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-report")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, target := range []string{"influx", "mongo"} {
+		rec := &results.Record{
+			Tool:   "tsbs_run_queries_" + target,
+			Target: target,
+			Start:  start.Add(time.Duration(i) * time.Hour),
+			End:    start.Add(time.Duration(i)*time.Hour + time.Minute),
+			Run:    results.Run{Workers: 1, Seconds: 60, Queries: []results.Query{{Label: "all queries", Count: 60, MeanMs: 5}}},
+		}
+		if err := results.WriteFile(filepath.Join(dir, target+".json"), rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// other files in the directory are skipped
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		desc      string
+		args      []string
+		wantRuns  int
+		wantError string
+		wantCode  int
+	}{
+		{desc: "directory", args: []string{dir}, wantRuns: 2},
+		{desc: "files for a target", args: []string{filepath.Join(dir, "influx.json"), filepath.Join(dir, "mongo.json"), "--target", "mongo"}, wantRuns: 1},
+		{desc: "no results", args: []string{}, wantError: "no results given", wantCode: cli.ExitConfig},
+		{desc: "no runs of target", args: []string{dir, "--target", "cassandra"}, wantError: "no results found", wantCode: cli.ExitData},
+		{desc: "unknown format", args: []string{dir, "--format", "pdf"}, wantError: "unknown report format", wantCode: cli.ExitConfig},
+		{desc: "invalid file", args: []string{filepath.Join(dir, "notes.txt")}, wantError: "cannot read results file", wantCode: cli.ExitData},
+	}
+	for _, c := range cases {
+		root := newRootCommand()
+		var out bytes.Buffer
+		root.SetOut(&out)
+		root.SetErr(ioutil.Discard)
+		root.SetArgs(append([]string{"report", "--format", "grafana"}, c.args...))
+		err := root.Execute()
+		if c.wantError != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantError) {
+				t.Errorf("%s: incorrect error: got %v want %q", c.desc, err, c.wantError)
+			} else if code := cli.ExitCode(err); code != c.wantCode {
+				t.Errorf("%s: incorrect exit code: got %d want %d", c.desc, code, c.wantCode)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		var d struct {
+			Panels []struct {
+				Targets []struct {
+					CSVContent string `json:"csvContent"`
+				} `json:"targets"`
+			} `json:"panels"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &d); err != nil {
+			t.Errorf("%s: invalid dashboard: %v", c.desc, err)
+			continue
+		}
+		// the table of runs has a header and a row per run
+		if got := strings.Count(d.Panels[0].Targets[0].CSVContent, "\n") - 1; got != c.wantRuns {
+			t.Errorf("%s: incorrect number of runs: got %d want %d", c.desc, got, c.wantRuns)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build sqlite
+// +build sqlite
+
+package main
+
+import (
+	"github.com/timescale/tsbs/pkg/cli/loadsqlite"
+	"github.com/timescale/tsbs/pkg/cli/runqueriessqlite"
+)
+
+func init() {
+	addTarget(target{"sqlite", "SQLite", loadsqlite.Run, runqueriessqlite.Run})
+}
@@ -1,65 +0,0 @@
-package devops
-
-import (
-	"time"
-
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
-)
-
-// A CPUOnlySimulator generates data similar to telemetry from Telegraf for only CPU metrics.
-// It fulfills the Simulator interface.
-type CPUOnlySimulator struct {
-	*commonDevopsSimulator
-}
-
-// Fields returns a map of subsystems to metrics collected
-func (d *CPUOnlySimulator) Fields() map[string][][]byte {
-	return d.fields(d.hosts[0].SimulatedMeasurements[:1])
-}
-
-// Next advances a Point to the next state in the generator.
-func (d *CPUOnlySimulator) Next(p *serialize.Point) bool {
-	if d.hostIndex == uint64(len(d.hosts)) {
-		d.hostIndex = 0
-
-		for i := 0; i < len(d.hosts); i++ {
-			d.hosts[i].TickAll(d.interval)
-		}
-
-		d.adjustNumHostsForEpoch()
-	}
-
-	return d.populatePoint(p, 0)
-}
-
-// CPUOnlySimulatorConfig is used to create a CPUOnlySimulator.
-type CPUOnlySimulatorConfig commonDevopsSimulatorConfig
-
-// ToSimulator produces a Simulator that conforms to the given SimulatorConfig over the specified interval
-func (c *CPUOnlySimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
-	hostInfos := make([]Host, c.HostCount)
-	for i := 0; i < len(hostInfos); i++ {
-		hostInfos[i] = c.HostConstructor(i, c.Start)
-	}
-
-	epochs := calculateEpochs(commonDevopsSimulatorConfig(*c), interval)
-	maxPoints := epochs * c.HostCount
-	sim := &CPUOnlySimulator{&commonDevopsSimulator{
-		madePoints: 0,
-		maxPoints:  maxPoints,
-
-		hostIndex: 0,
-		hosts:     hostInfos,
-
-		epoch:          0,
-		epochs:         epochs,
-		epochHosts:     c.InitHostCount,
-		initHosts:      c.InitHostCount,
-		timestampStart: c.Start,
-		timestampEnd:   c.End,
-		interval:       interval,
-	}}
-
-	return sim
-}
@@ -0,0 +1,17 @@
+// tsbs_import converts an external dataset, a CSV file or the blocks of a
+// Prometheus TSDB, into data in any format of tsbs_generate_data.
+// It is the same as `tsbs import`; see package importdata.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/importdata"
+)
+
+func main() {
+	if err := importdata.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
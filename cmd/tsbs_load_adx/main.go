@@ -0,0 +1,16 @@
+// tsbs_load_adx loads an Azure Data Explorer (Kusto) database with data from
+// stdin. It is the same as `tsbs load adx`; see package loadadx.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadadx"
+)
+
+func main() {
+	if err := loadadx.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_bigquery loads a Google BigQuery dataset with data from
+// stdin. It is the same as `tsbs load bigquery`; see package loadbigquery.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadbigquery"
+)
+
+func main() {
+	if err := loadbigquery.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_bigtable loads a Google Cloud Bigtable table with data from
+// stdin. It is the same as `tsbs load bigtable`; see package loadbigtable.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadbigtable"
+)
+
+func main() {
+	if err := loadbigtable.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
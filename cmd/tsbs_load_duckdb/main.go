@@ -0,0 +1,16 @@
+// tsbs_load_duckdb loads a DuckDB database file with data from stdin. It is
+// the same as `tsbs load duckdb`; see package loadduckdb.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadduckdb"
+)
+
+func main() {
+	if err := loadduckdb.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_greptime loads a GreptimeDB server with data from stdin. It is
+// the same as `tsbs load greptime`; see package loadgreptime.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadgreptime"
+)
+
+func main() {
+	if err := loadgreptime.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
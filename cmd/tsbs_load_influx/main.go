@@ -1,98 +1,16 @@
-// bulk_load_influx loads an InfluxDB daemon with data from stdin.
-//
-// The caller is responsible for assuring that the database is empty before
-// bulk load.
+// tsbs_load_influx loads an InfluxDB daemon with data from stdin. It is the
+// same as `tsbs load influx`; see package loadinflux.
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"flag"
-	"log"
-	"strings"
-	"sync"
-	"time"
+	"os"
 
-	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadinflux"
 )
 
-// Program option vars:
-var (
-	daemonURLs        []string
-	replicationFactor int
-	backoff           time.Duration
-	useGzip           bool
-	doAbortOnExist    bool
-	consistency       string
-)
-
-// Global vars
-var (
-	loader  *load.BenchmarkRunner
-	bufPool sync.Pool
-)
-
-var consistencyChoices = map[string]struct{}{
-	"any":    struct{}{},
-	"one":    struct{}{},
-	"quorum": struct{}{},
-	"all":    struct{}{},
-}
-
-// allows for testing
-var fatal = log.Fatalf
-
-// Parse args:
-func init() {
-	loader = load.GetBenchmarkRunner()
-	var csvDaemonURLs string
-
-	flag.StringVar(&csvDaemonURLs, "urls", "http://localhost:8086", "InfluxDB URLs, comma-separated. Will be used in a round-robin fashion.")
-	flag.IntVar(&replicationFactor, "replication-factor", 1, "Cluster replication factor (only applies to clustered databases).")
-	flag.StringVar(&consistency, "consistency", "all", "Write consistency. Must be one of: any, one, quorum, all.")
-	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when server indicates backpressure is needed.")
-	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests (default true).")
-
-	flag.Parse()
-
-	if _, ok := consistencyChoices[consistency]; !ok {
-		log.Fatalf("invalid consistency settings")
-	}
-
-	daemonURLs = strings.Split(csvDaemonURLs, ",")
-	if len(daemonURLs) == 0 {
-		log.Fatal("missing 'urls' flag")
-	}
-}
-
-type benchmark struct{}
-
-func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
-	return &decoder{scanner: bufio.NewScanner(br)}
-}
-
-func (b *benchmark) GetBatchFactory() load.BatchFactory {
-	return &factory{}
-}
-
-func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
-	return &load.ConstantIndexer{}
-}
-
-func (b *benchmark) GetProcessor() load.Processor {
-	return &processor{}
-}
-
-func (b *benchmark) GetDBCreator() load.DBCreator {
-	return &dbCreator{}
-}
-
 func main() {
-	bufPool = sync.Pool{
-		New: func() interface{} {
-			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
-		},
+	if err := loadinflux.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
 	}
-
-	loader.RunBenchmark(&benchmark{}, load.SingleQueue)
 }
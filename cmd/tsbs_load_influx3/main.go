@@ -0,0 +1,16 @@
+// tsbs_load_influx3 loads an InfluxDB 3 server with data from stdin. It is
+// the same as `tsbs load influx3`; see package loadinflux3.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadinflux3"
+)
+
+func main() {
+	if err := loadinflux3.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
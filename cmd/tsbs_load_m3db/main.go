@@ -0,0 +1,16 @@
+// tsbs_load_m3db loads M3DB, through an M3 coordinator, with data from
+// stdin. It is the same as `tsbs load m3db`; see package loadm3db.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadm3db"
+)
+
+func main() {
+	if err := loadm3db.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_mysql loads a MySQL server with data from stdin. It is
+// the same as `tsbs load mysql`; see package loadmysql.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadmysql"
+)
+
+func main() {
+	if err := loadmysql.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_otlp loads any receiver of the OpenTelemetry protocol with data
+// from stdin. It is the same as `tsbs load otlp`; see package loadotlp.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadotlp"
+)
+
+func main() {
+	if err := loadotlp.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_pinot loads Apache Pinot with data from stdin. It is the same as
+// `tsbs load pinot`; see package loadpinot.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadpinot"
+)
+
+func main() {
+	if err := loadpinot.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_load_postgres loads a PostgreSQL server with data from stdin. It is
+// the same as `tsbs load postgres`; see package loadpostgres.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadpostgres"
+)
+
+func main() {
+	if err := loadpostgres.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
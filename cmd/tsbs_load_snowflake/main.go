@@ -0,0 +1,17 @@
+// tsbs_load_snowflake loads data from stdin into Snowflake by staged
+// ingestion, or exports it as staged files. It is the same as `tsbs load
+// snowflake`; see package loadsnowflake.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadsnowflake"
+)
+
+func main() {
+	if err := loadsnowflake.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
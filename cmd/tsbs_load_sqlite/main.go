@@ -0,0 +1,16 @@
+// tsbs_load_sqlite loads a SQLite database file with data from stdin. It is
+// the same as `tsbs load sqlite`; see package loadsqlite.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadsqlite"
+)
+
+func main() {
+	if err := loadsqlite.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
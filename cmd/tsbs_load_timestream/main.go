@@ -0,0 +1,17 @@
+// tsbs_load_timestream loads an Amazon Timestream database with data from
+// stdin. It is the same as `tsbs load timestream`; see package
+// loadtimestream.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/loadtimestream"
+)
+
+func main() {
+	if err := loadtimestream.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_run_queries_adx speed tests Azure Data Explorer (Kusto) using queries
+// from stdin. It is the same as `tsbs run adx`; see package runqueriesadx.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesadx"
+)
+
+func main() {
+	if err := runqueriesadx.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
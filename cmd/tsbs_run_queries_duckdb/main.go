@@ -0,0 +1,16 @@
+// tsbs_run_queries_duckdb speed tests DuckDB using queries from stdin.
+// It is the same as `tsbs run duckdb`; see package runqueriesduckdb.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesduckdb"
+)
+
+func main() {
+	if err := runqueriesduckdb.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
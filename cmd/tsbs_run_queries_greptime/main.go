@@ -0,0 +1,16 @@
+// tsbs_run_queries_greptime speed tests GreptimeDB using queries from stdin.
+// It is the same as `tsbs run greptime`; see package runqueriesgreptime.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesgreptime"
+)
+
+func main() {
+	if err := runqueriesgreptime.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
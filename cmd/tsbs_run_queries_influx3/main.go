@@ -0,0 +1,16 @@
+// tsbs_run_queries_influx3 speed tests InfluxDB 3 using queries from stdin.
+// It is the same as `tsbs run influx3`; see package runqueriesinflux3.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesinflux3"
+)
+
+func main() {
+	if err := runqueriesinflux3.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_run_queries_m3db speed tests M3DB using queries from stdin.
+// It is the same as `tsbs run m3db`; see package runqueriesm3db.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesm3db"
+)
+
+func main() {
+	if err := runqueriesm3db.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
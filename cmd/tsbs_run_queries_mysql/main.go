@@ -0,0 +1,16 @@
+// tsbs_run_queries_mysql speed tests MySQL using queries from stdin.
+// It is the same as `tsbs run mysql`; see package runqueriesmysql.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriesmysql"
+)
+
+func main() {
+	if err := runqueriesmysql.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
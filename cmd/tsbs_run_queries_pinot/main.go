@@ -0,0 +1,16 @@
+// tsbs_run_queries_pinot speed tests Apache Pinot using queries from stdin.
+// It is the same as `tsbs run pinot`; see package runqueriespinot.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriespinot"
+)
+
+func main() {
+	if err := runqueriespinot.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_run_queries_postgres speed tests PostgreSQL using queries from stdin.
+// It is the same as `tsbs run postgres`; see package runqueriespostgres.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriespostgres"
+)
+
+func main() {
+	if err := runqueriespostgres.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -0,0 +1,16 @@
+// tsbs_run_queries_sqlite speed tests SQLite using queries from stdin.
+// It is the same as `tsbs run sqlite`; see package runqueriessqlite.
+package main
+
+import (
+	"os"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/cli/runqueriessqlite"
+)
+
+func main() {
+	if err := runqueriessqlite.Run(os.Args[1:]); err != nil {
+		cli.Exit(err)
+	}
+}
@@ -2,13 +2,22 @@ package load
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"math"
 	"os"
+	"runtime/pprof"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/results"
+	"github.com/timescale/tsbs/pkg/telemetry"
+	"github.com/timescale/tsbs/pkg/version"
 )
 
 const (
@@ -21,12 +30,10 @@ const (
 	// SingleQueue is the value to have only a single shared queue of work for all workers
 	SingleQueue = 1
 
-	errDBExistsFmt = "database \"%s\" exists: aborting."
+	errDBExistsFmt       = "database \"%s\" exists: aborting."
+	errFormatMismatchFmt = "input is in the %s format, but this loader expects %s: aborting."
 )
 
-// change for more useful testing
-var printFn = fmt.Printf
-
 // Benchmark is an interface that represents the skeleton of a program
 // needed to run an insert or load benchmark.
 type Benchmark interface {
@@ -42,6 +49,15 @@ type Benchmark interface {
 	GetDBCreator() DBCreator
 }
 
+// BenchmarkFormat is a Benchmark that reports the data format it consumes, so
+// that the header of the input is checked against it before loading
+type BenchmarkFormat interface {
+	Benchmark
+	// DataFormat returns the name of the format the Benchmark consumes, as
+	// given to tsbs_generate_data
+	DataFormat() string
+}
+
 // BenchmarkRunner is responsible for initializing and storing common
 // flags across all database systems and ultimately running a supplied Benchmark
 type BenchmarkRunner struct {
@@ -53,12 +69,22 @@ type BenchmarkRunner struct {
 	doCreateDB      bool
 	doAbortOnExist  bool
 	reportingPeriod time.Duration
+	maxDuration     time.Duration
 	filename        string // TODO implement file reading
+	cpuProfile      string
+	memProfile      string
+	resultsOpts     *results.Options
+	telemetryOpts   *telemetry.Options
 
 	// non-flag fields
 	br        *bufio.Reader
 	metricCnt uint64
 	rowCnt    uint64
+	results   *results.Run
+	telemetry telemetry.Recorder
+	// printFn prints the reports and the summary, fmt.Printf if nil; it is
+	// a field for ease of testing
+	printFn func(format string, args ...interface{}) (int, error)
 }
 
 var loader = &BenchmarkRunner{}
@@ -81,6 +107,11 @@ func GetBenchmarkRunnerWithBatchSize(batchSize uint) *BenchmarkRunner {
 	flag.BoolVar(&loader.doCreateDB, "do-create-db", true, "Whether to create the database. Disable on all but one client if running on a multi client setup.")
 	flag.BoolVar(&loader.doAbortOnExist, "do-abort-on-exist", false, "Whether to abort if a database with the given name already exists.")
 	flag.DurationVar(&loader.reportingPeriod, "reporting-period", 10*time.Second, "Period to report write stats")
+	flag.DurationVar(&loader.maxDuration, "max-duration", 0, "Stop reading the input after running for this long, still loading the batches already read (0 is unlimited)")
+	flag.StringVar(&loader.cpuProfile, "cpu-profile", "", "Write a CPU profile to this file.")
+	flag.StringVar(&loader.memProfile, "mem-profile", "", "Write a memory profile to this file.")
+	loader.resultsOpts = results.AddFlags(flag.CommandLine)
+	loader.telemetryOpts = telemetry.AddFlags(flag.CommandLine)
 
 	return loader
 }
@@ -90,10 +121,60 @@ func (l *BenchmarkRunner) DatabaseName() string {
 	return l.dbName
 }
 
+// SaveResults saves the results of the run described by md, which ended with
+// runErr, as given by -results-db and -results-file, if any (see
+// results.SaveRun), so tools can end with
+// return md.Finish(loader.SaveResults(md, loader.RunBenchmark(...))).
+func (l *BenchmarkRunner) SaveResults(md *cli.Metadata, runErr error) error {
+	return results.SaveRun(l.resultsOpts, md, l.results, runErr)
+}
+
 // RunBenchmark takes in a Benchmark b, a bufio.Reader br, and holders for number of metrics and rows
-// and uses those to run the load benchmark
-func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
+// and uses those to run the load benchmark. An interrupt or SIGTERM stops reading the input, but
+// batches already read are still loaded and the summary printed before cli.ErrInterrupted is
+// returned; a second one exits immediately.
+func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) error {
+	ctx, stop := cli.NotifyInterrupt(context.Background())
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// restore the default behavior for the next interrupt
+		stop()
+	}()
+	return l.RunBenchmarkContext(ctx, b, workQueues)
+}
+
+// RunBenchmarkContext is like RunBenchmark, but stops reading the input once ctx is done rather
+// than on an interrupt. Being stopped by -max-duration is not an error.
+func (l *BenchmarkRunner) RunBenchmarkContext(ctx context.Context, b Benchmark, workQueues uint) error {
+	ctx, cancel := cli.WithMaxDuration(ctx, l.maxDuration)
+	defer cancel()
+
+	// (Optional) start a CPU profile that covers the whole load:
+	if len(l.cpuProfile) > 0 {
+		f, err := os.Create(l.cpuProfile)
+		if err != nil {
+			logging.Fatal("could not create CPU profile", "file", l.cpuProfile, "error", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			logging.Fatal("could not start CPU profile", "error", err)
+		}
+		defer func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		}()
+	}
+
 	l.br = l.GetBufferedReader()
+	if err := l.checkHeader(b); err != nil {
+		return cli.DataError(err)
+	}
+	rec, err := telemetry.Start(ctx, l.telemetryOpts)
+	if err != nil {
+		return err
+	}
+	l.telemetry = rec
+	defer telemetry.Stop(rec)
 	cleanupFn := l.useDBCreator(b.GetDBCreator())
 	defer cleanupFn()
 
@@ -106,15 +187,45 @@ func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
 	}
 
 	start := time.Now()
-	l.scan(b, channels)
+	stopReport := l.startReport()
+	l.scan(ctx, b, channels)
+	interrupted := false
+	if context.Cause(ctx) == cli.ErrMaxDuration {
+		logging.Info("reached max duration, finishing loading the batches already read")
+	} else if ctx.Err() != nil {
+		interrupted = true
+		logging.Warn("caught interrupt, finishing loading the batches already read")
+	}
 
 	for _, c := range channels {
 		c.close()
 	}
 	wg.Wait()
 	end := time.Now()
+	stopReport()
 
 	l.summary(end.Sub(start))
+	l.results = &results.Run{
+		Workers: l.workers,
+		Seconds: end.Sub(start).Seconds(),
+		Load:    &results.Load{Metrics: l.metricCnt, Rows: l.rowCnt},
+	}
+
+	// (Optional) create a memory profile:
+	if len(l.memProfile) > 0 {
+		f, err := os.Create(l.memProfile)
+		if err != nil {
+			logging.Fatal("could not create memory profile", "file", l.memProfile, "error", err)
+		}
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			logging.Fatal("could not write memory profile", "file", l.memProfile, "error", err)
+		}
+		f.Close()
+	}
+	if interrupted {
+		return cli.ErrInterrupted
+	}
+	return nil
 }
 
 // GetBufferedReader returns the buffered Reader that should be used by the loader
@@ -129,6 +240,27 @@ func (l *BenchmarkRunner) GetBufferedReader() *bufio.Reader {
 	return l.br
 }
 
+// checkHeader consumes the header at the start of the input, if any, and
+// checks that the input is in the format consumed by b
+func (l *BenchmarkRunner) checkHeader(b Benchmark) error {
+	h, err := serialize.ReadHeader(l.br)
+	if err != nil {
+		return fmt.Errorf("could not read input header: %v", err)
+	}
+	bf, ok := b.(BenchmarkFormat)
+	if !ok {
+		return nil
+	}
+	if h == nil {
+		logging.Warn("input has no header, cannot check its format", "format", bf.DataFormat())
+		return nil
+	}
+	if h.Format != bf.DataFormat() {
+		return fmt.Errorf(errFormatMismatchFmt, h.Format, bf.DataFormat())
+	}
+	return nil
+}
+
 // useDBCreator handles a DBCreator by running it according to flags set by the
 // user. The function returns a function that the caller should defer or run
 // when the benchmark is finished
@@ -185,23 +317,51 @@ func (l *BenchmarkRunner) createChannels(workQueues uint) []*duplexChannel {
 	return channels
 }
 
-// scan launches any needed reporting mechanism and proceeds to scan input data
-// to distribute to workers
-func (l *BenchmarkRunner) scan(b Benchmark, channels []*duplexChannel) uint64 {
-	if l.reportingPeriod.Nanoseconds() > 0 {
-		go l.report(l.reportingPeriod)
+// startReport launches the periodic reports, if any, and returns a function
+// that stops them and waits for the last one to be printed. The reports are
+// progress, so they are skipped with -quiet.
+func (l *BenchmarkRunner) startReport() (stop func()) {
+	if l.reportingPeriod.Nanoseconds() <= 0 || !logging.InfoEnabled() {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		l.report(done, l.reportingPeriod)
+	}()
+	return func() {
+		close(done)
+		<-stopped
 	}
-	return scanWithIndexer(channels, l.batchSize, l.limit, l.br, b.GetPointDecoder(l.br), b.GetBatchFactory(), b.GetPointIndexer(uint(len(channels))))
+}
+
+// scan proceeds to scan input data to distribute to workers until the input
+// is exhausted or ctx is done.
+func (l *BenchmarkRunner) scan(ctx context.Context, b Benchmark, channels []*duplexChannel) uint64 {
+	return scanWithIndexer(ctx, channels, l.batchSize, l.limit, l.br, b.GetPointDecoder(l.br), b.GetBatchFactory(), b.GetPointIndexer(uint(len(channels))))
 }
 
 // work is the processing function for each worker in the loader
 func (l *BenchmarkRunner) work(b Benchmark, wg *sync.WaitGroup, c *duplexChannel, workerNum int) {
 	proc := b.GetProcessor()
 	proc.Init(workerNum, l.doLoad)
+	verbose := logging.DebugEnabled()
+	traced := l.telemetryOpts.Enabled()
 	for b := range c.toWorker {
+		var start time.Time
+		if verbose || traced {
+			start = time.Now()
+		}
 		metricCnt, rowCnt := proc.ProcessBatch(b, l.doLoad)
 		atomic.AddUint64(&l.metricCnt, metricCnt)
 		atomic.AddUint64(&l.rowCnt, rowCnt)
+		if traced {
+			l.telemetry.Batch(workerNum, start, time.Now(), metricCnt, rowCnt)
+		}
+		if verbose {
+			logging.Debug("processed batch", "worker", workerNum, "metrics", metricCnt, "rows", rowCnt, "took", time.Since(start))
+		}
 		c.sendToScanner()
 	}
 	switch c := proc.(type) {
@@ -214,23 +374,32 @@ func (l *BenchmarkRunner) work(b Benchmark, wg *sync.WaitGroup, c *duplexChannel
 // summary prints the summary of statistics from loading
 func (l *BenchmarkRunner) summary(took time.Duration) {
 	metricRate := float64(l.metricCnt) / float64(took.Seconds())
-	printFn("\nSummary:\n")
-	printFn("loaded %d metrics in %0.3fsec with %d workers (mean rate %0.2f metrics/sec)\n", l.metricCnt, took.Seconds(), l.workers, metricRate)
+	l.printf("\nSummary:\n")
+	l.printf("loaded %d metrics in %0.3fsec with %d workers (mean rate %0.2f metrics/sec)\n", l.metricCnt, took.Seconds(), l.workers, metricRate)
 	if l.rowCnt > 0 {
 		rowRate := float64(l.rowCnt) / float64(took.Seconds())
-		printFn("loaded %d rows in %0.3fsec with %d workers (mean rate %0.2f rows/sec)\n", l.rowCnt, took.Seconds(), l.workers, rowRate)
+		l.printf("loaded %d rows in %0.3fsec with %d workers (mean rate %0.2f rows/sec)\n", l.rowCnt, took.Seconds(), l.workers, rowRate)
 	}
+	l.printf("built by tsbs %s\n", version.Get())
 }
 
-// report handles periodic reporting of loading stats
-func (l *BenchmarkRunner) report(period time.Duration) {
+// report handles periodic reporting of loading stats until done is closed
+func (l *BenchmarkRunner) report(done <-chan struct{}, period time.Duration) {
 	start := time.Now()
 	prevTime := start
 	prevColCount := uint64(0)
 	prevRowCount := uint64(0)
 
-	printFn("time,per. metric/s,metric total,overall metric/s,per. row/s,row total,overall row/s\n")
-	for now := range time.NewTicker(period).C {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	l.printf("time,per. metric/s,metric total,overall metric/s,per. row/s,row total,overall row/s\n")
+	for {
+		var now time.Time
+		select {
+		case <-done:
+			return
+		case now = <-ticker.C:
+		}
 		cCount := atomic.LoadUint64(&l.metricCnt)
 		rCount := atomic.LoadUint64(&l.rowCnt)
 
@@ -241,9 +410,9 @@ func (l *BenchmarkRunner) report(period time.Duration) {
 		if rCount > 0 {
 			rowrate := float64(rCount-prevRowCount) / float64(took.Seconds())
 			overallRowRate := float64(rCount) / float64(sinceStart.Seconds())
-			printFn("%d,%0.2f,%E,%0.2f,%0.2f,%E,%0.2f\n", now.Unix(), colrate, float64(cCount), overallColRate, rowrate, float64(rCount), overallRowRate)
+			l.printf("%d,%0.2f,%E,%0.2f,%0.2f,%E,%0.2f\n", now.Unix(), colrate, float64(cCount), overallColRate, rowrate, float64(rCount), overallRowRate)
 		} else {
-			printFn("%d,%0.2f,%E,%0.2f,-,-,-\n", now.Unix(), colrate, float64(cCount), overallColRate)
+			l.printf("%d,%0.2f,%E,%0.2f,-,-,-\n", now.Unix(), colrate, float64(cCount), overallColRate)
 		}
 
 		prevColCount = cCount
@@ -251,3 +420,12 @@ func (l *BenchmarkRunner) report(period time.Duration) {
 		prevTime = now
 	}
 }
+
+// printf prints with the printFn of l
+func (l *BenchmarkRunner) printf(format string, args ...interface{}) {
+	if l.printFn == nil {
+		fmt.Printf(format, args...)
+		return
+	}
+	l.printFn(format, args...)
+}
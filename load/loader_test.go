@@ -3,12 +3,17 @@ package load
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/version"
 )
 
 type testProcessor struct {
@@ -121,6 +126,77 @@ func TestGetBufferedReader(t *testing.T) {
 	}
 }
 
+type testFormatBenchmark struct {
+	testBenchmark
+	format string
+}
+
+func (b *testFormatBenchmark) DataFormat() string { return b.format }
+
+func TestCheckHeader(t *testing.T) {
+	header := func(format string) []byte {
+		var buf bytes.Buffer
+		serialize.WriteHeader(&buf, &serialize.Header{Format: format, GeneratorVersion: 1})
+		return buf.Bytes()
+	}
+	cases := []struct {
+		desc        string
+		input       []byte
+		b           Benchmark
+		shouldError bool
+	}{
+		{
+			desc:  "no header, no format",
+			input: []byte("data"),
+			b:     &testBenchmark{},
+		},
+		{
+			desc:  "no header, format",
+			input: []byte("data"),
+			b:     &testFormatBenchmark{format: "influx"},
+		},
+		{
+			desc:  "header, no format",
+			input: append(header("influx"), "data"...),
+			b:     &testBenchmark{},
+		},
+		{
+			desc:  "header, matching format",
+			input: append(header("influx"), "data"...),
+			b:     &testFormatBenchmark{format: "influx"},
+		},
+		{
+			desc:        "header, mismatched format",
+			input:       append(header("mongo"), "data"...),
+			b:           &testFormatBenchmark{format: "influx"},
+			shouldError: true,
+		},
+		{
+			desc:        "truncated header",
+			input:       header("influx")[:10],
+			b:           &testFormatBenchmark{format: "influx"},
+			shouldError: true,
+		},
+	}
+	for _, c := range cases {
+		r := &BenchmarkRunner{br: bufio.NewReader(bytes.NewReader(c.input))}
+		err := r.checkHeader(c.b)
+		if c.shouldError {
+			if err == nil {
+				t.Errorf("%s: did not error when should", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if rest, _ := r.br.ReadString('\n'); rest != "data" {
+			t.Errorf("%s: incorrect remaining input: got %q", c.desc, rest)
+		}
+	}
+}
+
 func TestUseDBCreator(t *testing.T) {
 	cases := []struct {
 		desc         string
@@ -423,16 +499,16 @@ func TestSummary(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		br := &BenchmarkRunner{}
-		br.metricCnt = c.metrics
-		br.rowCnt = c.rows
 		var b bytes.Buffer
-		printFn = func(s string, args ...interface{}) (n int, err error) {
+		br := &BenchmarkRunner{printFn: func(s string, args ...interface{}) (n int, err error) {
 			return fmt.Fprintf(&b, s, args...)
-		}
+		}}
+		br.metricCnt = c.metrics
+		br.rowCnt = c.rows
 		br.summary(c.took)
-		if got := string(b.Bytes()); got != c.want {
-			t.Errorf("%s: incorrect summary\ngot %s\nwant %s", c.desc, got, c.want)
+		want := c.want + "built by tsbs " + version.Get().String() + "\n"
+		if got := string(b.Bytes()); got != want {
+			t.Errorf("%s: incorrect summary\ngot %s\nwant %s", c.desc, got, want)
 		}
 	}
 }
@@ -441,15 +517,23 @@ func TestReport(t *testing.T) {
 	var b bytes.Buffer
 	counter := int64(0)
 	var m sync.Mutex
-	printFn = func(s string, args ...interface{}) (n int, err error) {
+	br := &BenchmarkRunner{printFn: func(s string, args ...interface{}) (n int, err error) {
 		atomic.AddInt64(&counter, 1)
 		m.Lock()
 		defer m.Unlock()
 		return fmt.Fprintf(&b, s, args...)
-	}
-	br := &BenchmarkRunner{}
+	}}
 	duration := 200 * time.Millisecond
-	go br.report(duration)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		br.report(done, duration)
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
 
 	time.Sleep(25 * time.Millisecond)
 	if got := atomic.LoadInt64(&counter); got != 1 {
@@ -485,3 +569,61 @@ func TestReport(t *testing.T) {
 		t.Errorf("TestReport: row report ends in -")
 	}
 }
+
+// testScanBenchmark is a testBenchmark that reads each byte of its input as
+// a point
+type testScanBenchmark struct {
+	testBenchmark
+}
+
+func (b *testScanBenchmark) GetPointDecoder(_ *bufio.Reader) PointDecoder { return &testDecoder{} }
+func (b *testScanBenchmark) GetBatchFactory() BatchFactory                { return &testFactory{} }
+
+func TestRunBenchmarkContext(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := cli.WithMaxDuration(context.Background(), time.Nanosecond)
+	defer cancelExpired()
+	<-expired.Done()
+	cases := []struct {
+		desc        string
+		ctx         context.Context
+		wantErr     error
+		wantMetrics uint64
+	}{
+		{
+			desc:        "completed",
+			ctx:         context.Background(),
+			wantMetrics: 5,
+		},
+		{
+			desc:    "interrupted",
+			ctx:     cancelled,
+			wantErr: cli.ErrInterrupted,
+		},
+		{
+			desc: "reached max duration",
+			ctx:  expired,
+		},
+	}
+	for _, c := range cases {
+		r := &BenchmarkRunner{workers: 1, batchSize: 1, reportingPeriod: time.Millisecond}
+		r.printFn = func(s string, args ...interface{}) (n int, err error) {
+			return 0, nil
+		}
+		r.br = bufio.NewReader(strings.NewReader("abcde"))
+		b := &testScanBenchmark{testBenchmark{processors: []*testProcessor{{}}}}
+		if err := r.RunBenchmarkContext(c.ctx, b, SingleQueue); err != c.wantErr {
+			t.Errorf("%s: incorrect error: got %v want %v", c.desc, err, c.wantErr)
+		}
+		if r.metricCnt != c.wantMetrics {
+			t.Errorf("%s: incorrect metric count: got %d want %d", c.desc, r.metricCnt, c.wantMetrics)
+		}
+		if r.results == nil || r.results.Load.Metrics != c.wantMetrics || r.results.Workers != 1 {
+			t.Errorf("%s: incorrect results: %+v", c.desc, r.results)
+		}
+		if !b.processors[0].closed {
+			t.Errorf("%s: processor not closed", c.desc)
+		}
+	}
+}
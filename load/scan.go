@@ -2,6 +2,7 @@ package load
 
 import (
 	"bufio"
+	"context"
 	"reflect"
 )
 
@@ -80,11 +81,12 @@ type PointDecoder interface {
 	Decode(*bufio.Reader) *Point
 }
 
-// ScanWithIndexer reads data from the provided bufio.Reader br until a limit is reached (if -1, all items are read).
+// ScanWithIndexer reads data from the provided bufio.Reader br until a limit is reached (if -1, all items are read)
+// or ctx is done.
 // Data is decoded by PointDecoder decoder and then placed into appropriate batches, using the supplied PointIndexer,
 // which are then dispatched to workers (duplexChannel chosen by PointIndexer). Scan does flow control to make sure workers are not left idle for too long
 // and also that the scanning process  does not starve them of CPU.
-func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br *bufio.Reader, decoder PointDecoder, factory BatchFactory, indexer PointIndexer) uint64 {
+func scanWithIndexer(ctx context.Context, channels []*duplexChannel, batchSize uint, limit uint64, br *bufio.Reader, decoder PointDecoder, factory BatchFactory, indexer PointIndexer) uint64 {
 	var itemsRead uint64
 	numChannels := len(channels)
 
@@ -120,10 +122,17 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 	// a limit (olimit), in order to slow down the scanner so it doesn't starve the workers
 	ocnt := 0
 	olimit := numChannels * cap(channels[0].toWorker) * 3
+	done := ctx.Done()
+scan:
 	for {
 		if limit > 0 && itemsRead == limit {
 			break
 		}
+		select {
+		case <-done:
+			break scan
+		default:
+		}
 
 		caseLimit := len(cases)
 		if ocnt >= olimit { // if we have too many outstanding, wait until one finishes (i.e. no default)
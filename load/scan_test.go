@@ -3,6 +3,7 @@ package load
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"testing"
 )
@@ -207,6 +208,7 @@ func TestScanWithIndexer(t *testing.T) {
 		batchSize   uint
 		limit       uint64
 		wantCalls   uint64
+		cancelled   bool
 		shouldPanic bool
 	}{
 		{
@@ -234,6 +236,13 @@ func TestScanWithIndexer(t *testing.T) {
 			limit:     4,
 			wantCalls: uint64(len(data)),
 		},
+		{
+			desc:      "scan w/ cancelled context",
+			batchSize: 1,
+			limit:     0,
+			cancelled: true,
+			wantCalls: 0,
+		},
 		{
 			desc:        "batchSize = 0 is panic",
 			batchSize:   0,
@@ -246,6 +255,10 @@ func TestScanWithIndexer(t *testing.T) {
 		channels := []*duplexChannel{newDuplexChannel(1)}
 		decoder := &testDecoder{0}
 		indexer := &ConstantIndexer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		if c.cancelled {
+			cancel()
+		}
 		if c.shouldPanic {
 			func() {
 				defer func() {
@@ -253,13 +266,15 @@ func TestScanWithIndexer(t *testing.T) {
 						t.Errorf("%s: did not panic when should", c.desc)
 					}
 				}()
-				scanWithIndexer(channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer)
+				scanWithIndexer(ctx, channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer)
 			}()
+			cancel()
 			continue
 		} else {
 			go _boringWorker(channels[0])
-			read := scanWithIndexer(channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer)
+			read := scanWithIndexer(ctx, channels, c.batchSize, c.limit, br, decoder, &testFactory{}, indexer)
 			_checkScan(t, c.desc, decoder.called, read, c.wantCalls)
 		}
+		cancel()
 	}
 }
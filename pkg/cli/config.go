@@ -0,0 +1,181 @@
+// Package cli holds what the command line tools of TSBS have in common. The
+// tools themselves are in its subpackages.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/version"
+)
+
+const (
+	// ConfigFlag is the name of the flag giving the config file of a tool
+	ConfigFlag = "config"
+	// VersionFlag is the name of the flag printing the build of a tool
+	VersionFlag = "version"
+	// LogLevelFlag is the name of the flag giving the level of the logger
+	LogLevelFlag = "log-level"
+	// LogFormatFlag is the name of the flag giving the format of the logger
+	LogFormatFlag = "log-format"
+	// QuietFlag and VerboseFlag are the names of the flags that only log
+	// errors, and that also log debug detail (e.g., per batch), overriding
+	// -log-level
+	QuietFlag   = "quiet"
+	VerboseFlag = "verbose"
+	// EnvPrefix is the prefix of the environment variables setting flags,
+	// e.g., TSBS_DB_NAME sets -db-name
+	EnvPrefix = "TSBS"
+	// EnvConfig is the environment variable giving the config file if
+	// -config is not given
+	EnvConfig = EnvPrefix + "_CONFIG"
+)
+
+// ErrVersion is returned by ParseFlags when -version is given to a FlagSet
+// that does not exit on errors, like flag.ErrHelp for -help
+var ErrVersion = errors.New("version requested")
+
+// ParseFlags parses args into fs like fs.Parse, then sets each flag not given
+// in args from the environment or else from a config file, so flags take
+// precedence over environment variables, which take precedence over the file.
+//
+// The variable of a flag is its name in upper case with dashes replaced by
+// underscores, prefixed with TSBS_ (e.g., TSBS_USE_CASE for -use-case). The
+// config file, given by the -config flag that ParseFlags adds to fs or else
+// by TSBS_CONFIG, can be in any format viper reads (e.g., YAML, JSON or
+// TOML). Its top level keys are flag names, and a section named after tool
+// (e.g., tsbs_load_timescaledb) sets flags of that tool only, overriding the
+// top level, so one file can hold the settings of every tool.
+//
+// ParseFlags also adds a -version flag, which prints the build of the binary
+// (see package version) to stdout and then exits, or returns ErrVersion if fs
+// does not exit on errors, and the -log-level, -log-format, -quiet and
+// -verbose flags, with which it configures the logger (see package logging).
+// All logs go to stderr, so stdout is left to generated data and results.
+// Finally, it adds the -metadata-file flag, see StartMetadata.
+//
+// Errors other than ErrVersion are marked as ConfigErrors.
+func ParseFlags(fs *flag.FlagSet, tool string, args []string) error {
+	err := parseFlags(fs, tool, args)
+	if err == ErrVersion {
+		return err
+	}
+	return ConfigError(err)
+}
+
+func parseFlags(fs *flag.FlagSet, tool string, args []string) error {
+	if fs.Lookup(ConfigFlag) == nil {
+		fs.String(ConfigFlag, "", "Config file (YAML, JSON or TOML) of flag values, overridden by TSBS_* environment variables and the command line (default $"+EnvConfig+")")
+	}
+	if fs.Lookup(VersionFlag) == nil {
+		fs.Bool(VersionFlag, false, "Print the version and build of the binary and exit")
+	}
+	if fs.Lookup(LogLevelFlag) == nil {
+		fs.String(LogLevelFlag, "info", "Level of the messages to log (choices: "+strings.Join(logging.Levels, ", ")+")")
+	}
+	if fs.Lookup(LogFormatFlag) == nil {
+		fs.String(LogFormatFlag, logging.FormatText, "Format of the log written to stderr (choices: "+logging.FormatText+", "+logging.FormatJSON+")")
+	}
+	if fs.Lookup(QuietFlag) == nil {
+		fs.Bool(QuietFlag, false, "Only log errors, printing nothing else but the final summary (same as -log-level=error)")
+	}
+	if fs.Lookup(VerboseFlag) == nil {
+		fs.Bool(VerboseFlag, false, "Also log debug detail, e.g., of each batch loaded or query run (same as -log-level=debug)")
+	}
+	if fs.Lookup(MetadataFlag) == nil {
+		fs.String(MetadataFlag, "", "File to which to write JSON metadata of the run: the effective seed and flags, the build, start and end times, and checksums of the outputs")
+	}
+	// the flag package exits with 2 on flags it cannot parse, which Go also
+	// exits with on a panic, so they are returned to exit with ExitConfig
+	exitOnError := fs.ErrorHandling() == flag.ExitOnError
+	if exitOnError {
+		fs.Init(fs.Name(), flag.ContinueOnError)
+		defer fs.Init(fs.Name(), flag.ExitOnError)
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp && exitOnError {
+			os.Exit(0)
+		}
+		return err
+	}
+	if fs.Lookup(VersionFlag).Value.String() == "true" {
+		fmt.Printf("%s %s\n", tool, version.Get())
+		if exitOnError {
+			os.Exit(0)
+		}
+		return ErrVersion
+	}
+
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+
+	configFile := fs.Lookup(ConfigFlag).Value.String()
+	if len(configFile) == 0 {
+		configFile = os.Getenv(EnvConfig)
+	}
+	if len(configFile) > 0 {
+		v.SetConfigFile(configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("cannot read config file %s: %v", configFile, err)
+		}
+		if section := v.GetStringMap(tool); len(section) > 0 {
+			if err := v.MergeConfigMap(section); err != nil {
+				return fmt.Errorf("cannot read section %s of config file %s: %v", tool, configFile, err)
+			}
+		}
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || given[f.Name] || f.Name == ConfigFlag || f.Name == VersionFlag || !v.IsSet(f.Name) {
+			return
+		}
+		value := configValue(v.Get(f.Name))
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for -%s from the environment or config file: %v", value, f.Name, setErr)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	level, err := logLevel(fs)
+	if err != nil {
+		return err
+	}
+	return logging.Configure(os.Stderr, level, fs.Lookup(LogFormatFlag).Value.String())
+}
+
+// logLevel returns the level of the logger set by the flags of fs
+func logLevel(fs *flag.FlagSet) (string, error) {
+	quiet := fs.Lookup(QuietFlag).Value.String() == "true"
+	verbose := fs.Lookup(VerboseFlag).Value.String() == "true"
+	switch {
+	case quiet && verbose:
+		return "", fmt.Errorf("-%s and -%s cannot be used together", QuietFlag, VerboseFlag)
+	case quiet:
+		return "error", nil
+	case verbose:
+		return "debug", nil
+	}
+	return fs.Lookup(LogLevelFlag).Value.String(), nil
+}
+
+// configValue returns the flag value of a value from the environment or a
+// config file, where lists are comma-separated
+func configValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		return strings.Join(cast.ToStringSlice(list), ",")
+	}
+	return cast.ToString(value)
+}
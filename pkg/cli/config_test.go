@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"flag"
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+const testConfig = `
+db-name: file
+workers: 4
+plugins: [a.so, b.so]
+tsbs_test:
+  workers: 8
+tsbs_other:
+  db-name: other
+`
+
+func TestParseFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-config")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "tsbs.yaml")
+	if err := ioutil.WriteFile(configFile, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("could not write config file: %v", err)
+	}
+
+	cases := []struct {
+		desc        string
+		args        []string
+		env         map[string]string
+		wantDBName  string
+		wantWorkers int
+		wantPlugins string
+		wantTimeout time.Duration
+		shouldErr   bool
+	}{
+		{
+			desc:        "defaults",
+			wantDBName:  "benchmark",
+			wantWorkers: 1,
+			wantTimeout: time.Second,
+		},
+		{
+			desc:        "flags",
+			args:        []string{"-db-name", "flag", "-workers=2"},
+			wantDBName:  "flag",
+			wantWorkers: 2,
+			wantTimeout: time.Second,
+		},
+		{
+			desc:        "environment",
+			env:         map[string]string{"TSBS_DB_NAME": "env", "TSBS_TIMEOUT": "5s"},
+			wantDBName:  "env",
+			wantWorkers: 1,
+			wantTimeout: 5 * time.Second,
+		},
+		{
+			desc:        "flags over environment",
+			args:        []string{"-db-name", "flag"},
+			env:         map[string]string{"TSBS_DB_NAME": "env"},
+			wantDBName:  "flag",
+			wantWorkers: 1,
+			wantTimeout: time.Second,
+		},
+		{
+			desc:        "config file with section",
+			args:        []string{"-config", configFile},
+			wantDBName:  "file",
+			wantWorkers: 8,
+			wantPlugins: "a.so,b.so",
+			wantTimeout: time.Second,
+		},
+		{
+			desc:        "config file from environment",
+			env:         map[string]string{EnvConfig: configFile, "TSBS_WORKERS": "3"},
+			wantDBName:  "file",
+			wantWorkers: 3,
+			wantPlugins: "a.so,b.so",
+			wantTimeout: time.Second,
+		},
+		{
+			desc:        "flags over config file",
+			args:        []string{"-config", configFile, "-workers", "2", "-plugins", "c.so"},
+			wantDBName:  "file",
+			wantWorkers: 2,
+			wantPlugins: "c.so",
+			wantTimeout: time.Second,
+		},
+		{
+			desc:      "missing config file",
+			args:      []string{"-config", filepath.Join(dir, "missing.yaml")},
+			shouldErr: true,
+		},
+		{
+			desc:      "invalid environment value",
+			env:       map[string]string{"TSBS_WORKERS": "many"},
+			shouldErr: true,
+		},
+	}
+	for _, c := range cases {
+		for k, v := range c.env {
+			os.Setenv(k, v)
+		}
+
+		fs := flag.NewFlagSet("tsbs_test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		dbName := fs.String("db-name", "benchmark", "")
+		workers := fs.Int("workers", 1, "")
+		plugins := fs.String("plugins", "", "")
+		timeout := fs.Duration("timeout", time.Second, "")
+		err := ParseFlags(fs, "tsbs_test", c.args)
+
+		for k := range c.env {
+			os.Unsetenv(k)
+		}
+
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("%s: unexpected lack of error", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if *dbName != c.wantDBName {
+			t.Errorf("%s: incorrect db-name: got %s want %s", c.desc, *dbName, c.wantDBName)
+		}
+		if *workers != c.wantWorkers {
+			t.Errorf("%s: incorrect workers: got %d want %d", c.desc, *workers, c.wantWorkers)
+		}
+		if *plugins != c.wantPlugins {
+			t.Errorf("%s: incorrect plugins: got %s want %s", c.desc, *plugins, c.wantPlugins)
+		}
+		if *timeout != c.wantTimeout {
+			t.Errorf("%s: incorrect timeout: got %v want %v", c.desc, *timeout, c.wantTimeout)
+		}
+	}
+}
+
+func TestParseFlagsExitOnError(t *testing.T) {
+	// flags that cannot be parsed exit with ExitConfig, not the 2 of the
+	// flag package
+	fs := flag.NewFlagSet("tsbs_test", flag.ExitOnError)
+	fs.SetOutput(ioutil.Discard)
+	err := ParseFlags(fs, "tsbs_test", []string{"-bogus-flag"})
+	if err == nil || ExitCode(err) != ExitConfig {
+		t.Errorf("incorrect error: got %v with code %d want code %d", err, ExitCode(err), ExitConfig)
+	}
+	if fs.ErrorHandling() != flag.ExitOnError {
+		t.Errorf("error handling not restored: got %v", fs.ErrorHandling())
+	}
+}
+
+func TestParseFlagsVersion(t *testing.T) {
+	fs := flag.NewFlagSet("tsbs_test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if err := ParseFlags(fs, "tsbs_test", []string{"-version"}); err != ErrVersion {
+		t.Errorf("incorrect error: got %v want %v", err, ErrVersion)
+	}
+
+	os.Setenv("TSBS_VERSION", "true")
+	defer os.Unsetenv("TSBS_VERSION")
+	fs = flag.NewFlagSet("tsbs_test", flag.ContinueOnError)
+	if err := ParseFlags(fs, "tsbs_test", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseFlagsLogLevel(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	cases := []struct {
+		desc      string
+		args      []string
+		env       map[string]string
+		wantDebug bool
+		wantInfo  bool
+		shouldErr bool
+	}{
+		{desc: "default", wantInfo: true},
+		{desc: "log level", args: []string{"-log-level=debug"}, wantDebug: true, wantInfo: true},
+		{desc: "quiet", args: []string{"-quiet"}},
+		{desc: "quiet over log level", args: []string{"-quiet", "-log-level=debug"}},
+		{desc: "verbose", args: []string{"-verbose"}, wantDebug: true, wantInfo: true},
+		{desc: "quiet from environment", env: map[string]string{"TSBS_QUIET": "true"}},
+		{desc: "quiet and verbose", args: []string{"-quiet", "-verbose"}, shouldErr: true},
+	}
+	for _, c := range cases {
+		for k, v := range c.env {
+			os.Setenv(k, v)
+		}
+		fs := flag.NewFlagSet("tsbs_test", flag.ContinueOnError)
+		fs.SetOutput(ioutil.Discard)
+		err := ParseFlags(fs, "tsbs_test", c.args)
+		for k := range c.env {
+			os.Unsetenv(k)
+		}
+
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("%s: unexpected lack of error", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if got := logging.DebugEnabled(); got != c.wantDebug {
+			t.Errorf("%s: incorrect debug logging: got %v want %v", c.desc, got, c.wantDebug)
+		}
+		if got := logging.InfoEnabled(); got != c.wantInfo {
+			t.Errorf("%s: incorrect info logging: got %v want %v", c.desc, got, c.wantInfo)
+		}
+	}
+}
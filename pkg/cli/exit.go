@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// Exit codes of the tools, so scripts can tell kinds of failures apart
+// without parsing the log. Errors of a kind are made with the functions
+// below, e.g., DataError, and other errors exit with ExitFailure.
+const (
+	ExitOK = 0
+	// ExitFailure is any failure not of a kind below
+	ExitFailure = 1
+	// ExitConfig is an invalid flag, config file or combination of
+	// options, following the EX_USAGE of sysexits.h. It is not 2, which
+	// Go exits with on a panic.
+	ExitConfig = 64
+	// ExitUnreachable is a target database that could not be reached
+	ExitUnreachable = 3
+	// ExitData is input data or queries that could not be read, or are for
+	// another format or target
+	ExitData = 4
+	// ExitInterrupted is a run stopped early by an interrupt or SIGTERM
+	// (see ErrInterrupted), following the shell's 128+SIGINT
+	ExitInterrupted = 130
+)
+
+// exit is called by Exit and Fatal; it is a variable for ease of testing
+var exit = os.Exit
+
+// exitError is an error with the code the tool exits with because of it
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+func withCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ConfigError marks err, if not nil, as a configuration error (ExitConfig)
+func ConfigError(err error) error { return withCode(ExitConfig, err) }
+
+// UnreachableError marks err, if not nil, as a failure to reach the target
+// database (ExitUnreachable)
+func UnreachableError(err error) error { return withCode(ExitUnreachable, err) }
+
+// DataError marks err, if not nil, as invalid input (ExitData)
+func DataError(err error) error { return withCode(ExitData, err) }
+
+// ExitCode returns the code a tool exits with when it fails with err
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, ErrInterrupted) {
+		return ExitInterrupted
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return ExitFailure
+}
+
+// Exit logs err and exits with its ExitCode, for the main functions of the
+// tools
+func Exit(err error) {
+	logging.Error(err.Error())
+	exit(ExitCode(err))
+}
+
+// Fatal is like logging.Fatal, but exits with the given code, e.g.,
+// ExitUnreachable, for failures deep in a run that cannot be returned
+func Fatal(code int, msg string, args ...interface{}) {
+	logging.Error(msg, args...)
+	exit(code)
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	base := errors.New("failed")
+	cases := []struct {
+		desc string
+		err  error
+		want int
+	}{
+		{desc: "no error", err: nil, want: ExitOK},
+		{desc: "plain error", err: base, want: ExitFailure},
+		{desc: "config", err: ConfigError(base), want: ExitConfig},
+		{desc: "unreachable", err: UnreachableError(base), want: ExitUnreachable},
+		{desc: "data", err: DataError(base), want: ExitData},
+		{desc: "interrupted", err: ErrInterrupted, want: ExitInterrupted},
+		{desc: "wrapped", err: fmt.Errorf("loading: %w", DataError(base)), want: ExitData},
+		{desc: "wrapped interrupted", err: fmt.Errorf("loading: %w", ErrInterrupted), want: ExitInterrupted},
+	}
+	for _, c := range cases {
+		if got := ExitCode(c.err); got != c.want {
+			t.Errorf("%s: incorrect exit code: got %d want %d", c.desc, got, c.want)
+		}
+	}
+
+	if err := DataError(nil); err != nil {
+		t.Errorf("nil error marked: got %v", err)
+	}
+	if got := DataError(base).Error(); got != base.Error() {
+		t.Errorf("incorrect message: got %q want %q", got, base.Error())
+	}
+}
+
+func TestExit(t *testing.T) {
+	defer func(old func(int)) { exit = old }(exit)
+	code := -1
+	exit = func(c int) { code = c }
+
+	Exit(UnreachableError(errors.New("connection refused")))
+	if code != ExitUnreachable {
+		t.Errorf("incorrect exit code: got %d want %d", code, ExitUnreachable)
+	}
+	Fatal(ExitData, "could not decode query", "query", 3)
+	if code != ExitData {
+		t.Errorf("incorrect exit code: got %d want %d", code, ExitData)
+	}
+}
@@ -0,0 +1,230 @@
+package generatedata
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/kubernetes"
+	"github.com/timescale/tsbs/pkg/plugins"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// Config holds all options of tsbs_generate_data. It is filled in from the
+// command line by parseFlags and checked by Validate, so the options can be
+// handled without any global state.
+type Config struct {
+	Format  string
+	UseCase string
+
+	// InitialScale is the scale at the start of the simulation; parseFlags
+	// sets it to Scale if not given
+	InitialScale uint64
+	Scale        uint64
+	// Seed is the seed for all random values; parseFlags sets it from the
+	// current time if not given
+	Seed  int64
+	Debug int
+
+	TimestampStart time.Time
+	TimestampEnd   time.Time
+	LogInterval    time.Duration
+
+	InterleavedGroupID uint
+	InterleavedGroups  uint
+
+	CPUProfileFile string
+	MemProfileFile string
+
+	// OutputFile is the file to write to instead of stdout, which is only
+	// created once generation completes
+	OutputFile        string
+	OutputChunkSize   int
+	OutputPreallocate int64
+	// MaxOutputSize stops generation once the output reaches this many
+	// bytes (0 is unlimited)
+	MaxOutputSize int64
+	// MaxDuration stops generation once it has run this long (0 is
+	// unlimited)
+	MaxDuration time.Duration
+	OrderWindow time.Duration
+
+	ManifestFile string
+	// ProtoFile is the file to write the .proto of the messages of the
+	// protobuf format to
+	ProtoFile string
+	// BigQuerySchemaFile is the file to write the schema of the table of the
+	// rows of the bigquery-ndjson format to
+	BigQuerySchemaFile string
+	VerifyGolden       bool
+	// PodsPerNode is the distribution of the number of pods per node of the
+	// kubernetes use case, or empty for its default
+	PodsPerNode string
+
+	Plugins     []string
+	WriteHeader bool
+	ValueScript string
+	ServeAddr   string
+
+	Hooks hookCommands
+}
+
+// parseFlags parses the command line args (without the program name) into a
+// Config, defining the flags on fs. The result is not validated.
+func parseFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	c := &Config{}
+	var timestampStartStr, timestampEndStr, pluginPaths string
+	var outputChunkSize cli.ByteSize
+
+	fs.StringVar(&c.Format, "format", "", fmt.Sprintf("Format to emit. (choices: %s, or %s<command> to pipe points to a command)", strings.Join(data.Formats(), ", "), data.FormatExecPrefix))
+
+	fs.StringVar(&c.UseCase, "use-case", "", fmt.Sprintf("Use case to model. (choices: %s)", strings.Join(data.UseCases(), ", ")))
+
+	fs.Uint64Var(&c.InitialScale, "initial-scale-var", 0, "Initial scaling variable specific to the use case (e.g., devices in 'devops'). 0 means to use -scale-var value")
+	fs.Uint64Var(&c.Scale, "scale-var", 1, "Scaling variable specific to the use case (e.g., devices in 'devops').")
+
+	fs.StringVar(&timestampStartStr, "timestamp-start", "2016-01-01T00:00:00Z", "Beginning timestamp (RFC3339, or relative to now, e.g., now-24h).")
+	fs.StringVar(&timestampEndStr, "timestamp-end", "2016-01-02T06:00:00Z", "Ending timestamp (RFC3339, or relative to now, e.g., now).")
+
+	fs.Int64Var(&c.Seed, "seed", 0, "PRNG seed (0 uses the current timestamp). (default 0)")
+
+	fs.IntVar(&c.Debug, "debug", 0, "Debug printing (choices: 0, 1, 2). (default 0)")
+
+	fs.UintVar(&c.InterleavedGroupID, "interleaved-generation-group-id", 0, "Group (0-indexed) to perform round-robin serialization within. Use this to scale up data generation to multiple processes.")
+	fs.UintVar(&c.InterleavedGroups, "interleaved-generation-groups", 1, "The number of round-robin serialization groups. Use this to scale up data generation to multiple processes.")
+	fs.StringVar(&c.CPUProfileFile, "cpu-profile", "", "File to which to write go CPU profiling data")
+	fs.StringVar(&c.MemProfileFile, "mem-profile", "", "File to which to write go memory profiling data")
+	fs.StringVar(&c.MemProfileFile, "profile-file", "", "Deprecated: use -mem-profile.")
+
+	fs.DurationVar(&c.LogInterval, "log-interval", 10*time.Second, "Duration between host data points")
+
+	fs.StringVar(&c.OutputFile, "file", "", "File to write the data to instead of stdout. It is written under a temporary name and renamed once complete, or to its name with a "+cli.PartialSuffix+" suffix if generation fails or is interrupted")
+	fs.Var(&outputChunkSize, "output-chunk-size", "When the output is a regular file, write to it in chunks of this size (e.g., 4MiB) using positioned writes (0 uses regular buffered writes)")
+	fs.Var((*cli.ByteSize)(&c.OutputPreallocate), "output-preallocate", "When writing in chunks, preallocate this much of the output file (e.g., 10GB) up front (Linux only; the file only grows as it is written, and the space not written is released at the end)")
+	fs.Var((*cli.ByteSize)(&c.MaxOutputSize), "max-output-size", "Stop generating once the output reaches this size (e.g., 50GB; 0 is unlimited)")
+	fs.DurationVar(&c.MaxDuration, "max-duration", 0, "Stop generating after running for this long (e.g., 30m; 0 is unlimited)")
+	fs.DurationVar(&c.OrderWindow, "order-window", 0, "Buffer this much simulated time and write its points grouped by series instead of by time, which can compress better (0 keeps strict time order)")
+	fs.StringVar(&c.ManifestFile, "manifest-file", "", "File to which to write a JSON manifest of the generator version, flags, and SHA-256 of the output, for checking reproducibility")
+	fs.StringVar(&c.ProtoFile, "proto-file", "", "File to which to write the .proto definition of the messages of -format="+data.FormatProtobuf+", for the use case")
+	fs.StringVar(&c.BigQuerySchemaFile, "bigquery-schema-file", "", "File to which to write the BigQuery table schema of the rows of -format="+data.FormatBigQueryNDJSON+" (or "+data.FormatBigQueryNDJSON+":<measurement>), for bq load")
+	fs.StringVar(&c.PodsPerNode, "pods-per-node", "", "Distribution of the number of pods on each node of the "+data.UseCaseKubernetes+" use case: <n>, uniform:<min>,<max> or normal:<mean>,<stddev> (default "+kubernetes.DefaultPodsPerNode+")")
+	fs.BoolVar(&c.VerifyGolden, "verify-golden", false, "Verify that this binary reproduces the golden outputs of its generator version, then exit")
+	fs.BoolVar(&c.WriteHeader, "header", true, "Start the output of formats read by a tsbs loader with a header of the format, generator version, seed and schema, which the loader checks before loading (disable for data not read by the loader)")
+	fs.StringVar(&c.ValueScript, "value-script", "", "Starlark script of functions computing the values of some fields, replacing the builtin distributions (requires building with -tags starlark)")
+	fs.StringVar(&c.ServeAddr, "serve", "", "Instead of writing to stdout, serve generated data over gRPC on this address (e.g., :9090), with the options of each stream given by the client (requires building with -tags grpc)")
+	fs.StringVar(&c.Hooks.epochStart, "on-epoch-start", "", "Command run (with sh -c) before the points of each simulated reading of all series, with its time in $"+envEpochStart)
+	fs.StringVar(&c.Hooks.simulatedDay, "on-simulated-day", "", "Command run (with sh -c) before the points of each simulated day, with its start in $"+envSimulatedDay)
+	fs.StringVar(&c.Hooks.points, "on-points", "", "Command run (with sh -c) every -on-points-every points, with the number of points written so far in $"+envPoints)
+	fs.Uint64Var(&c.Hooks.pointsEvery, "on-points-every", 1000000, "Number of points between runs of the -on-points command")
+	fs.StringVar(&pluginPaths, "plugins", "", "Comma-separated list of Go plugins to load, which can add output formats")
+	if err := cli.ParseFlags(fs, "tsbs_generate_data", args); err != nil {
+		return nil, err
+	}
+
+	if c.InitialScale == 0 {
+		c.InitialScale = c.Scale
+	}
+	// the default seed is the current timestamp:
+	if c.Seed == 0 {
+		c.Seed = int64(time.Now().Nanosecond())
+	}
+	c.Plugins = plugins.ParseList(pluginPaths)
+	c.OutputChunkSize = int(outputChunkSize)
+
+	// relative timestamps are relative to the same time
+	now := time.Now()
+	var err error
+	if c.TimestampStart, err = cli.ParseTime(timestampStartStr, now); err != nil {
+		return nil, err
+	}
+	if c.TimestampEnd, err = cli.ParseTime(timestampEndStr, now); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that c is usable, returning an error describing the first
+// problem found. Formats added by plugins are only valid once the plugins are
+// loaded.
+func (c *Config) Validate() error {
+	if _, err := validateGroups(c.InterleavedGroupID, c.InterleavedGroups); err != nil {
+		return err
+	}
+	// the data to generate is not given by the flags in these modes
+	if c.VerifyGolden || len(c.ServeAddr) > 0 {
+		return nil
+	}
+	if !validateFormat(c.Format) {
+		return suggest.Error("format", c.Format, data.Formats())
+	}
+	if data.IsFileFormat(c.Format) && len(c.OutputFile) == 0 {
+		return fmt.Errorf("format %s writes files, which requires -file", c.Format)
+	}
+	if len(c.ProtoFile) > 0 && c.Format != data.FormatProtobuf {
+		return fmt.Errorf("-proto-file requires -format=%s", data.FormatProtobuf)
+	}
+	if len(c.BigQuerySchemaFile) > 0 && c.Format != data.FormatBigQueryNDJSON && !strings.HasPrefix(c.Format, data.FormatBigQueryNDJSON+":") {
+		return fmt.Errorf("-bigquery-schema-file requires -format=%s or %s:<measurement>", data.FormatBigQueryNDJSON, data.FormatBigQueryNDJSON)
+	}
+	if !validateUseCase(c.UseCase) {
+		return suggest.Error("use case", c.UseCase, data.UseCases())
+	}
+	if c.Scale == 0 {
+		return fmt.Errorf("scale must be greater than 0")
+	}
+	if len(c.PodsPerNode) > 0 {
+		if c.UseCase != data.UseCaseKubernetes {
+			return fmt.Errorf("-pods-per-node requires -use-case=%s", data.UseCaseKubernetes)
+		}
+		if _, err := kubernetes.ParsePodsPerNode(c.PodsPerNode); err != nil {
+			return err
+		}
+	}
+	if c.MaxOutputSize < 0 {
+		return fmt.Errorf("max output size must not be negative: %d", c.MaxOutputSize)
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("max duration must not be negative: %v", c.MaxDuration)
+	}
+	if c.LogInterval <= 0 {
+		return fmt.Errorf("log interval must be greater than 0: %v", c.LogInterval)
+	}
+	if !c.TimestampEnd.After(c.TimestampStart) {
+		return fmt.Errorf("end timestamp %v is not after start timestamp %v", c.TimestampEnd, c.TimestampStart)
+	}
+	return nil
+}
+
+func parseTimeFromString(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+func validateGroups(groupID, totalGroups uint) (bool, error) {
+	if totalGroups == 0 {
+		return false, fmt.Errorf(errTotalGroupsZero)
+	} else if groupID >= totalGroups {
+		return false, fmt.Errorf(errInvalidGroupsFmt, groupID, totalGroups)
+	}
+	return true, nil
+}
+
+func validateFormat(format string) bool {
+	// e.g., exec:<command> as well as the registered formats
+	return data.IsFormat(format)
+}
+
+func validateUseCase(useCase string) bool {
+	for _, s := range data.UseCases() {
+		if s == useCase {
+			return true
+		}
+	}
+	return false
+}
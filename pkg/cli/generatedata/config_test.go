@@ -0,0 +1,227 @@
+package generatedata
+
+import (
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testParseFlags(args ...string) (*Config, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return parseFlags(fs, args)
+}
+
+func TestParseFlags(t *testing.T) {
+	c, err := testParseFlags(
+		"-format=influx", "-use-case=cpu-only", "-scale-var=10", "-seed=123",
+		"-timestamp-start="+correctTimeStr, "-timestamp-end=2016-01-02T00:00:00Z",
+		"-log-interval=20s", "-plugins=a.so, b.so", "-header=false",
+		"-output-chunk-size=4MiB", "-max-output-size=50GB",
+		"-max-duration=30m",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &Config{
+		Format:            formatInflux,
+		UseCase:           useCaseCPUOnly,
+		InitialScale:      10,
+		Scale:             10,
+		Seed:              123,
+		TimestampStart:    correctTime,
+		TimestampEnd:      correctTime.Add(24 * time.Hour),
+		LogInterval:       20 * time.Second,
+		InterleavedGroups: 1,
+		Plugins:           []string{"a.so", "b.so"},
+		OutputChunkSize:   4 << 20,
+		MaxOutputSize:     50e9,
+		MaxDuration:       30 * time.Minute,
+		Hooks:             hookCommands{pointsEvery: 1000000},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("incorrect config:\ngot  %+v\nwant %+v", c, want)
+	}
+
+	// an initial scale is kept, and the seed defaults to the current time
+	c, err = testParseFlags("-scale-var=10", "-initial-scale-var=2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.InitialScale != 2 {
+		t.Errorf("specified initial scale not kept: got %d", c.InitialScale)
+	}
+	if c.Seed == 0 {
+		t.Errorf("seed = 0 not replaced")
+	}
+	if !c.WriteHeader {
+		t.Errorf("header not written by default")
+	}
+
+	// the deprecated -profile-file is an alias of -mem-profile
+	c, err = testParseFlags("-profile-file=mem.prof")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.MemProfileFile != "mem.prof" {
+		t.Errorf("-profile-file not kept as the memory profile: got %q", c.MemProfileFile)
+	}
+
+	// relative timestamps are relative to the same time
+	c, err = testParseFlags("-timestamp-start=now-1d", "-timestamp-end=now")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := c.TimestampEnd.Sub(c.TimestampStart); got != 24*time.Hour {
+		t.Errorf("incorrect relative time range: got %v", got)
+	}
+
+	for _, args := range [][]string{
+		{"-timestamp-start=" + incorrectTimeStr},
+		{"-timestamp-end=" + incorrectTimeStr},
+		{"-max-output-size=50XB"},
+		{"-bogus-flag"},
+	} {
+		if _, err := testParseFlags(args...); err == nil {
+			t.Errorf("%v: did not error when should", args)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc      string
+		modify    func(c *Config)
+		errPrefix string
+	}{
+		{
+			desc:   "valid config",
+			modify: func(c *Config) {},
+		},
+		{
+			desc:   "exec format",
+			modify: func(c *Config) { c.Format = "exec:cat" },
+		},
+		{
+			desc:   "file format",
+			modify: func(c *Config) { c.Format = "parquet"; c.OutputFile = "data.parquet" },
+		},
+		{
+			desc:      "file format to stdout",
+			modify:    func(c *Config) { c.Format = "parquet:1000" },
+			errPrefix: "format parquet:1000 writes files",
+		},
+		{
+			desc:   "protobuf format with a .proto",
+			modify: func(c *Config) { c.Format = "protobuf"; c.ProtoFile = "devops.proto" },
+		},
+		{
+			desc:      ".proto of another format",
+			modify:    func(c *Config) { c.ProtoFile = "devops.proto" },
+			errPrefix: "-proto-file requires -format=protobuf",
+		},
+		{
+			desc:   "bigquery-ndjson format with a schema",
+			modify: func(c *Config) { c.Format = "bigquery-ndjson"; c.BigQuerySchemaFile = "schema.json" },
+		},
+		{
+			desc:   "bigquery-ndjson format of a measurement with a schema",
+			modify: func(c *Config) { c.Format = "bigquery-ndjson:cpu"; c.BigQuerySchemaFile = "schema.json" },
+		},
+		{
+			desc:      "BigQuery schema of another format",
+			modify:    func(c *Config) { c.Format = "bigquery"; c.BigQuerySchemaFile = "schema.json" },
+			errPrefix: "-bigquery-schema-file requires -format=bigquery-ndjson",
+		},
+		{
+			desc:   "kubernetes with pods per node",
+			modify: func(c *Config) { c.UseCase = "kubernetes"; c.PodsPerNode = "normal:50,5" },
+		},
+		{
+			desc:      "pods per node of another use case",
+			modify:    func(c *Config) { c.PodsPerNode = "50" },
+			errPrefix: "-pods-per-node requires -use-case=kubernetes",
+		},
+		{
+			desc:      "invalid pods per node",
+			modify:    func(c *Config) { c.UseCase = "kubernetes"; c.PodsPerNode = "200" },
+			errPrefix: "invalid pods per node",
+		},
+		{
+			desc:      "invalid format",
+			modify:    func(c *Config) { c.Format = "bogus" },
+			errPrefix: "unknown format",
+		},
+		{
+			desc:      "invalid use case",
+			modify:    func(c *Config) { c.UseCase = "bogus" },
+			errPrefix: "unknown use case",
+		},
+		{
+			desc:      "misspelled format",
+			modify:    func(c *Config) { c.Format = "timescale" },
+			errPrefix: "unknown format: 'timescale', did you mean 'timescaledb'?",
+		},
+		{
+			desc:      "missing use case",
+			modify:    func(c *Config) { c.UseCase = "" },
+			errPrefix: "no use case given",
+		},
+		{
+			desc:      "0 scale",
+			modify:    func(c *Config) { c.Scale = 0 },
+			errPrefix: "scale must be",
+		},
+		{
+			desc:      "negative max duration",
+			modify:    func(c *Config) { c.MaxDuration = -time.Second },
+			errPrefix: "max duration must not be negative",
+		},
+		{
+			desc:      "0 log interval",
+			modify:    func(c *Config) { c.LogInterval = 0 },
+			errPrefix: "log interval must be",
+		},
+		{
+			desc:      "end before start",
+			modify:    func(c *Config) { c.TimestampEnd = c.TimestampStart },
+			errPrefix: "end timestamp",
+		},
+		{
+			desc:      "group id too large",
+			modify:    func(c *Config) { c.InterleavedGroupID = 1 },
+			errPrefix: "incorrect interleaved groups",
+		},
+		{
+			desc: "serve mode ignores data options",
+			modify: func(c *Config) {
+				c.ServeAddr = ":9090"
+				c.Format = ""
+			},
+		},
+		{
+			desc: "golden verification ignores data options",
+			modify: func(c *Config) {
+				c.VerifyGolden = true
+				c.UseCase = ""
+			},
+		},
+	}
+	for _, c := range cases {
+		cfg := testConfig(useCaseDevops, formatInflux)
+		c.modify(cfg)
+		err := cfg.Validate()
+		if c.errPrefix == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		} else if c.errPrefix != "" {
+			if err == nil {
+				t.Errorf("%s: did not error when should", c.desc)
+			} else if !strings.HasPrefix(err.Error(), c.errPrefix) {
+				t.Errorf("%s: incorrect error: got %s want prefix %s", c.desc, err.Error(), c.errPrefix)
+			}
+		}
+	}
+}
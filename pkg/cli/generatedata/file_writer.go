@@ -0,0 +1,85 @@
+package generatedata
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// positionalWriter writes sequentially to a file using positioned writes
+// (pwrite), starting at a given offset. Positioned writes avoid needing the
+// kernel to track the file offset and let the output be preallocated ahead
+// of time.
+type positionalWriter struct {
+	f           *os.File
+	off         int64
+	preallocEnd int64
+}
+
+// newPositionalWriter returns a positionalWriter for f if it is a regular
+// file that supports positioned writes, otherwise nil. If prealloc is greater
+// than 0, that many bytes are reserved past the current offset, without
+// growing the file, which only grows as it is written.
+func newPositionalWriter(f *os.File, prealloc int64) *positionalWriter {
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil
+	}
+	off, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil
+	}
+	// Files opened with O_APPEND (e.g., shell '>>') do not allow WriteAt, so
+	// check with an empty write first.
+	if _, err := f.WriteAt(nil, off); err != nil {
+		return nil
+	}
+
+	w := &positionalWriter{f: f, off: off}
+	if prealloc > 0 && preallocate(f, off, prealloc) == nil {
+		w.preallocEnd = off + prealloc
+	}
+	return w
+}
+
+// Write writes p at the current offset and advances the offset
+func (w *positionalWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.off)
+	w.off += int64(n)
+	return n, err
+}
+
+// Close releases any preallocated space that was not written to and moves
+// the file offset to the end of the written data
+func (w *positionalWriter) Close() error {
+	if w.preallocEnd > w.off {
+		if err := w.f.Truncate(w.off); err != nil {
+			return err
+		}
+	}
+	_, err := w.f.Seek(w.off, io.SeekStart)
+	return err
+}
+
+// getOutputWriter returns the buffered writer for generated data. When f is a
+// regular file and chunkSize is greater than 0, data is written in chunks of
+// chunkSize bytes with positioned writes, optionally preallocating prealloc
+// bytes. The returned function must be called once all data is written.
+func getOutputWriter(f *os.File, chunkSize int, prealloc int64) (*bufio.Writer, func() error) {
+	if chunkSize > 0 {
+		if pw := newPositionalWriter(f, prealloc); pw != nil {
+			out := bufio.NewWriterSize(pw, chunkSize)
+			return out, func() error {
+				// pw is closed even if the flush fails, to release the
+				// preallocated space
+				err := out.Flush()
+				if closeErr := pw.Close(); err == nil {
+					err = closeErr
+				}
+				return err
+			}
+		}
+	}
+	out := bufio.NewWriterSize(f, inputBufSize)
+	return out, out.Flush
+}
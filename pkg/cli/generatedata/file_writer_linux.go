@@ -0,0 +1,17 @@
+package generatedata
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is the FALLOC_FL_KEEP_SIZE mode of fallocate, which
+// reserves the space without changing the size of the file
+const fallocKeepSize = 0x1
+
+// preallocate reserves size bytes of f starting at off. The size of f is
+// kept, so that it never has a tail of zeros which was not written, even if
+// the process is killed before the space not written is released.
+func preallocate(f *os.File, off, size int64) error {
+	return syscall.Fallocate(int(f.Fd()), fallocKeepSize, off, size)
+}
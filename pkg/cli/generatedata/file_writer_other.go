@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package generatedata
+
+import (
+	"fmt"
+	"os"
+)
+
+// preallocate is only supported on Linux
+func preallocate(f *os.File, off, size int64) error {
+	return fmt.Errorf("preallocation not supported on this platform")
+}
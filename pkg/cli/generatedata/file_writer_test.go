@@ -0,0 +1,81 @@
+package generatedata
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGetOutputWriterRegularFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "tsbs_file_writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	f.WriteString("header\n")
+	out, closeFn := getOutputWriter(f, 4, 1024)
+	out.WriteString("foo\nbar\nbaz\n")
+	// before it is closed, e.g., when killed, the file only has what was
+	// written, without a tail of preallocated zeros
+	out.Flush()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := info.Size(), int64(len("header\nfoo\nbar\nbaz\n")); got != want {
+		t.Errorf("incorrect file size before closing: got %d want %d", got, want)
+	}
+	if err := closeFn(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	// preallocated space should be trimmed and offset at the end of data
+	want := "header\nfoo\nbar\nbaz\n"
+	info, err = f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Size(); got != int64(len(want)) {
+		t.Errorf("incorrect file size: got %d want %d", got, len(want))
+	}
+	f.WriteString("end\n")
+	got, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want+"end\n" {
+		t.Errorf("incorrect file contents: got\n%s\nwant\n%s", got, want+"end\n")
+	}
+}
+
+func TestNewPositionalWriterNotRegular(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if pw := newPositionalWriter(w, 0); pw != nil {
+		t.Errorf("got positional writer for a pipe")
+	}
+}
+
+func TestNewPositionalWriterAppend(t *testing.T) {
+	f, err := ioutil.TempFile("", "tsbs_file_writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	f, err = os.OpenFile(f.Name(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if pw := newPositionalWriter(f, 0); pw != nil {
+		t.Errorf("got positional writer for a file opened to append")
+	}
+}
@@ -0,0 +1,97 @@
+package generatedata
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+)
+
+// goldenCase is a small, fixed configuration whose output is checked into
+// testdata/golden and whose digest is compiled in, so that the output of a
+// given generatorVersion can be verified both by tests and by the
+// -verify-golden self-test of an installed binary.
+type goldenCase struct {
+	format  string
+	useCase string
+	sha256  string
+}
+
+const (
+	goldenSeed     = 123
+	goldenScaleVar = 2
+	goldenStart    = "2016-01-01T00:00:00Z"
+	goldenEnd      = "2016-01-01T00:00:30Z"
+	goldenInterval = 10 * time.Second
+)
+
+var goldenCases = []goldenCase{
+	{formatCassandra, useCaseCPUOnly, "eff059851e75f627619f8db52b4199782474c80e222f214ca8a6a8aef2f1a4ef"},
+	{formatCassandra, useCaseDevops, "67ec4131159e7664ea7a5a5e8ae1d626f95d9a0266b0e0e6d870476f43f76ffb"},
+	{formatInflux, useCaseCPUOnly, "a671a7f2c05c71329cc22d9aa3a11ed1ecccf79ca10b8347f23b417de0787ca4"},
+	{formatInflux, useCaseDevops, "6ad64753b72a87d606372513a8caf84d50510dffd071a0b7cbb2eeb4468ab647"},
+	{formatMongo, useCaseCPUOnly, "43853dbda25be4f6d79539f97df2fbf313b96a348f04d0ab50c5aa9175791981"},
+	{formatMongo, useCaseDevops, "45d80696ce499ab711bbb492a7e91e5f3399ca40f612cac530b6db40c62dc01e"},
+	{formatTimescaleDB, useCaseCPUOnly, "3b1358972832f0d7a24bb377b3b32064a3b2c38b2e1af3972c482617bfe9ddac"},
+	{formatTimescaleDB, useCaseDevops, "8f49b3691e4a869bd4f7806f596b01add1d980e400566ecb113afd1f66ee34bd"},
+}
+
+// filename returns the name of the case's golden file within testdata/golden
+func (c goldenCase) filename() string {
+	return fmt.Sprintf("%s-%s", c.format, c.useCase)
+}
+
+// generate writes the output of the golden case to w
+func (c goldenCase) generate(w io.Writer) error {
+	start, err := parseTimeFromString(goldenStart)
+	if err != nil {
+		return err
+	}
+	end, err := parseTimeFromString(goldenEnd)
+	if err != nil {
+		return err
+	}
+	g, err := data.NewGenerator(data.GeneratorConfig{
+		Format:         c.format,
+		UseCase:        c.useCase,
+		Scale:          goldenScaleVar,
+		Seed:           goldenSeed,
+		TimestampStart: start,
+		TimestampEnd:   end,
+		LogInterval:    goldenInterval,
+	})
+	if err != nil {
+		return err
+	}
+	return g.Generate(context.Background(), w)
+}
+
+// verifyGolden regenerates every golden case and compares the digest of its
+// output against the expected one, reporting the result of each case to w
+func verifyGolden(w io.Writer) error {
+	failed := 0
+	for _, c := range goldenCases {
+		var buf bytes.Buffer
+		if err := c.generate(&buf); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		got := hex.EncodeToString(sum[:])
+		if got != c.sha256 {
+			failed++
+			fmt.Fprintf(w, "FAIL %s: got sha256 %s want %s\n", c.filename(), got, c.sha256)
+		} else {
+			fmt.Fprintf(w, "ok   %s\n", c.filename())
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d golden cases do not match generator version %d", failed, len(goldenCases), generatorVersion)
+	}
+	fmt.Fprintf(w, "all %d golden cases match generator version %d\n", len(goldenCases), generatorVersion)
+	return nil
+}
@@ -0,0 +1,46 @@
+package generatedata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGoldenCases(t *testing.T) {
+	for _, c := range goldenCases {
+		want, err := ioutil.ReadFile(filepath.Join("testdata", "golden", c.filename()))
+		if err != nil {
+			t.Fatalf("%s: could not read golden file: %v", c.filename(), err)
+		}
+		sum := sha256.Sum256(want)
+		if got := hex.EncodeToString(sum[:]); got != c.sha256 {
+			t.Errorf("%s: golden file does not match compiled in sha256: got %s want %s", c.filename(), got, c.sha256)
+		}
+
+		var buf bytes.Buffer
+		if err := c.generate(&buf); err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.filename(), err)
+		}
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: output does not match golden file; if the change is intended, increment data.GeneratorVersion and regenerate testdata/golden", c.filename())
+		}
+	}
+}
+
+func TestVerifyGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := verifyGolden(&buf); err != nil {
+		t.Errorf("unexpected error: %v\n%s", err, buf.String())
+	}
+
+	old := goldenCases
+	goldenCases = []goldenCase{{formatInflux, useCaseCPUOnly, "bogus"}}
+	defer func() { goldenCases = old }()
+	buf.Reset()
+	if err := verifyGolden(&buf); err == nil {
+		t.Errorf("did not error for incorrect sha256")
+	}
+}
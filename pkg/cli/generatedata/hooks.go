@@ -0,0 +1,70 @@
+package generatedata
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+)
+
+// Environment variables describing the event to hook commands
+const (
+	envEpochStart   = "TSBS_EPOCH_START"
+	envSimulatedDay = "TSBS_SIMULATED_DAY"
+	envPoints       = "TSBS_POINTS"
+)
+
+// hookCommands are the commands run by the hooks of generation
+type hookCommands struct {
+	epochStart   string
+	simulatedDay string
+	points       string
+	pointsEvery  uint64
+}
+
+// toHooks returns the data.Hooks running the commands, or nil if there are
+// none. flush is called before each command so it sees all data generated so
+// far.
+func (c hookCommands) toHooks(flush func() error) *data.Hooks {
+	if c.epochStart == "" && c.simulatedDay == "" && (c.points == "" || c.pointsEvery == 0) {
+		return nil
+	}
+	hooks := &data.Hooks{}
+	if c.epochStart != "" {
+		hooks.OnEpochStart = func(t time.Time) error {
+			return runHookCommand(c.epochStart, flush, envEpochStart+"="+t.Format(time.RFC3339))
+		}
+	}
+	if c.simulatedDay != "" {
+		hooks.OnSimulatedDay = func(day time.Time) error {
+			return runHookCommand(c.simulatedDay, flush, envSimulatedDay+"="+day.Format(time.RFC3339))
+		}
+	}
+	if c.points != "" {
+		hooks.N = c.pointsEvery
+		hooks.OnNPoints = func(n uint64) error {
+			return runHookCommand(c.points, flush, envPoints+"="+strconv.FormatUint(n, 10))
+		}
+	}
+	return hooks
+}
+
+// runHookCommand runs command with sh -c and env added to its environment,
+// waiting for it to exit. Its output goes to stderr, since stdout is the
+// generated data.
+func runHookCommand(command string, flush func() error, env string) error {
+	if err := flush(); err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), env)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("hook '%s' failed: %v", command, err)
+	}
+	return nil
+}
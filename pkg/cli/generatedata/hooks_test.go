@@ -0,0 +1,73 @@
+package generatedata
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHookCommandsToHooks(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	if h := (hookCommands{pointsEvery: 10}).toHooks(nil); h != nil {
+		t.Errorf("hooks returned without commands: %+v", h)
+	}
+	if h := (hookCommands{points: "true"}).toHooks(nil); h != nil {
+		t.Errorf("hooks returned for points command run every 0 points: %+v", h)
+	}
+
+	dir, err := ioutil.TempDir("", "tsbs_hooks")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "log")
+	flushes := 0
+	flush := func() error {
+		flushes++
+		return nil
+	}
+	h := hookCommands{
+		epochStart:   "echo epoch $" + envEpochStart + " >> " + log,
+		simulatedDay: "echo day $" + envSimulatedDay + " >> " + log,
+		points:       "echo points $" + envPoints + " >> " + log,
+		pointsEvery:  10,
+	}.toHooks(flush)
+	if h == nil {
+		t.Fatalf("no hooks returned")
+	}
+	if h.N != 10 {
+		t.Errorf("incorrect N: got %d want 10", h.N)
+	}
+	now := time.Date(2016, 1, 1, 0, 0, 10, 0, time.UTC)
+	if err := h.OnSimulatedDay(now.Truncate(24 * time.Hour)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := h.OnEpochStart(now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := h.OnNPoints(20); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "day 2016-01-01T00:00:00Z\nepoch 2016-01-01T00:00:10Z\npoints 20\n"
+	if string(got) != want {
+		t.Errorf("incorrect commands run:\ngot\n%s\nwant\n%s", got, want)
+	}
+	if flushes != 3 {
+		t.Errorf("incorrect number of flushes: got %d want 3", flushes)
+	}
+
+	h = hookCommands{epochStart: "exit 1"}.toHooks(flush)
+	if err := h.OnEpochStart(now); err == nil {
+		t.Errorf("did not error when hook command failed")
+	}
+}
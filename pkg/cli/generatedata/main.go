@@ -0,0 +1,389 @@
+// Package generatedata implements tsbs_generate_data (also run as
+// `tsbs generate data`), which generates time series data from
+// pre-specified use cases.
+//
+// Supported formats:
+// Cassandra CSV format
+// InfluxDB bulk load format
+// MongoDB BSON format
+// TimescaleDB pseudo-CSV format
+//
+// Supported use cases:
+// devops: scale-var is the number of hosts to simulate, with log messages
+// every log-interval seconds.
+// cpu-only: same as `devops` but only generate metrics for CPU
+package generatedata
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/kubernetes"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigquery"
+	"github.com/timescale/tsbs/pkg/data/serialize/protobuf"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/plugins"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+const (
+	// Output data format choices (alphabetical order)
+	formatCassandra   = data.FormatCassandra
+	formatInflux      = data.FormatInflux
+	formatMongo       = data.FormatMongo
+	formatTimescaleDB = data.FormatTimescaleDB
+
+	// Use case choices (make sure to update TestGetConfig if adding a new one)
+	useCaseCPUOnly   = data.UseCaseCPUOnly
+	useCaseCPUSingle = data.UseCaseCPUSingle
+	useCaseDevops    = data.UseCaseDevops
+
+	errTotalGroupsZero  = "incorrect interleaved groups configuration: total groups = 0"
+	errInvalidGroupsFmt = "incorrect interleaved groups configuration: id %d >= total groups %d"
+
+	inputBufSize = 4 << 20
+)
+
+// Run runs tsbs_generate_data with args as its command line flags
+func Run(args []string) error {
+	c, err := parseFlags(flag.CommandLine, args)
+	if err != nil {
+		return err
+	}
+	if c.VerifyGolden {
+		return verifyGolden(os.Stderr)
+	}
+	if err := plugins.Load(c.Plugins...); err != nil {
+		return cli.ConfigError(err)
+	}
+	// plugins may have registered more formats, so validate after loading
+	if err := c.Validate(); err != nil {
+		return cli.ConfigError(err)
+	}
+	if len(c.ServeAddr) > 0 {
+		return serveData(c.ServeAddr)
+	}
+	logging.Info("using random seed", "seed", c.Seed)
+	return generate(c, newMetadata(flag.CommandLine, c))
+}
+
+// newMetadata returns the metadata of generating the data described by c,
+// with the flags resolved by parseFlags
+func newMetadata(fs *flag.FlagSet, c *Config) *cli.Metadata {
+	m := cli.StartMetadata(fs, "tsbs_generate_data")
+	m.Seed = c.Seed
+	m.Flags["seed"] = strconv.FormatInt(c.Seed, 10)
+	m.Flags["initial-scale-var"] = strconv.FormatUint(c.InitialScale, 10)
+	m.Flags["timestamp-start"] = c.TimestampStart.Format(time.RFC3339)
+	m.Flags["timestamp-end"] = c.TimestampEnd.Format(time.RFC3339)
+	return m
+}
+
+// generate writes the data described by c to stdout, or to c.OutputFile. If
+// it fails part way or is interrupted, what was generated is still flushed
+// (to c.OutputFile with cli.PartialSuffix) and the manifest and metadata
+// (marked incomplete) are still written before the error, or
+// cli.ErrInterrupted, is returned.
+func generate(c *Config, md *cli.Metadata) (err error) {
+	if len(c.CPUProfileFile) > 0 || len(c.MemProfileFile) > 0 {
+		stopProfiles, profileErr := startProfiles(c.CPUProfileFile, c.MemProfileFile)
+		if profileErr != nil {
+			return profileErr
+		}
+		defer func() {
+			if stopErr := stopProfiles(); stopErr != nil && err == nil {
+				err = stopErr
+			}
+		}()
+	}
+
+	// an interrupt or SIGTERM stops generation early, but still flushes
+	// what was generated and writes the manifest (marked incomplete); a
+	// second one exits immediately
+	ctx, stop := cli.NotifyInterrupt(context.Background())
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	ctx, cancelMaxDuration := cli.WithMaxDuration(ctx, c.MaxDuration)
+	defer cancelMaxDuration()
+	completed := false
+
+	dst := os.Stdout
+	var file *cli.OutputFile
+	if len(c.OutputFile) > 0 {
+		if file, err = cli.CreateOutputFile(c.OutputFile); err != nil {
+			return err
+		}
+		dst = file.File
+	}
+	out, closeOut := getOutputWriter(dst, c.OutputChunkSize, c.OutputPreallocate)
+	flushOut := out.Flush
+	var digest hash.Hash
+	if len(c.ManifestFile) > 0 || md.Enabled() {
+		out, digest, closeOut = getDigestWriter(out, closeOut)
+		flushDigest, flushInner := out.Flush, flushOut
+		flushOut = func() error {
+			if err := flushDigest(); err != nil {
+				return err
+			}
+			return flushInner()
+		}
+	}
+	defer func() {
+		if closeErr := closeOut(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		outputName := c.OutputFile
+		if file != nil {
+			if completed && err == nil {
+				err = file.Commit()
+			} else if partial, abortErr := file.Abort(); abortErr != nil {
+				logging.Error("could not keep incomplete output", "error", abortErr)
+			} else {
+				logging.Warn("kept incomplete output", "file", partial)
+				outputName = partial
+			}
+		}
+		if len(c.ManifestFile) > 0 {
+			m := newManifest(c, digest)
+			m.Incomplete = !completed || err != nil
+			if manifestErr := writeManifest(c.ManifestFile, m); manifestErr != nil && err == nil {
+				err = manifestErr
+			}
+		}
+		if digest != nil {
+			md.AddOutput(outputName, hex.EncodeToString(digest.Sum(nil)))
+		}
+		err = md.Finish(err)
+	}()
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return err
+	}
+	sim := cfg.ToSimulator(c.LogInterval)
+	if len(c.ProtoFile) > 0 {
+		if err := writeProto(c.ProtoFile, sim); err != nil {
+			return err
+		}
+	}
+	if len(c.BigQuerySchemaFile) > 0 {
+		if err := writeBigQuerySchema(c.BigQuerySchemaFile, c.Format, sim); err != nil {
+			return err
+		}
+	}
+	if c.WriteHeader {
+		if err := data.WriteHeader(out, c.Format, sim, c.Seed); err != nil {
+			return err
+		}
+	}
+	serializer, err := getSerializer(sim, c.Format, out)
+	if err != nil {
+		return err
+	}
+	if closer, ok := serializer.(io.Closer); ok {
+		// e.g., the command of an exec format, which must finish writing
+		// before out is closed
+		defer func() {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}()
+	}
+	if len(c.ValueScript) > 0 {
+		transformer, err := loadValueScript(c.ValueScript)
+		if err != nil {
+			return err
+		}
+		serializer = data.NewTransformingSerializer(transformer, serializer)
+	}
+
+	var w io.Writer = out
+	if c.MaxOutputSize > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		w = &limitWriter{w: out, remaining: c.MaxOutputSize - int64(out.Buffered()), stop: cancel}
+	}
+
+	hooks := c.Hooks.toHooks(flushOut)
+	if c.OrderWindow > 0 {
+		ordered := newSeriesOrderedSerializer(serializer, c.OrderWindow)
+		completed, err = runSimulator(ctx, sim, ordered, w, c.InterleavedGroupID, c.InterleavedGroups, hooks)
+		// points held back for ordering are flushed even on failure, so the
+		// output holds everything that was generated
+		if flushErr := ordered.Flush(out); flushErr != nil && err == nil {
+			err = flushErr
+		}
+		return err
+	}
+	completed, err = runSimulator(ctx, sim, serializer, w, c.InterleavedGroupID, c.InterleavedGroups, hooks)
+	return err
+}
+
+// errMaxOutputSize is the cause of generation being stopped by a limitWriter
+var errMaxOutputSize = errors.New("reached max output size")
+
+// runSimulator writes the points of sim using serializer, calling hooks (if
+// not nil) as it goes. It returns false and cli.ErrInterrupted if it was
+// stopped early by ctx being cancelled, or any error writing the points.
+// Being stopped by a limitWriter or by -max-duration completes the output,
+// so it returns true.
+func runSimulator(ctx context.Context, sim common.Simulator, serializer serialize.PointSerializer, out io.Writer, groupID, totalGroups uint, hooks *data.Hooks) (bool, error) {
+	err := data.RunWithHooks(ctx, sim, serializer, out, groupID, totalGroups, hooks)
+	if err != nil && err == ctx.Err() {
+		if context.Cause(ctx) == errMaxOutputSize {
+			logging.Info("reached max output size, stopping generation")
+			return true, nil
+		}
+		if context.Cause(ctx) == cli.ErrMaxDuration {
+			logging.Info("reached max duration, stopping generation")
+			return true, nil
+		}
+		logging.Warn("caught interrupt, stopping generation early")
+		return false, cli.ErrInterrupted
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// limitWriter passes writes through to w and calls stop once remaining bytes
+// have been written. Writes are never cut short, so generation stops at the
+// end of the batch of points reaching the limit.
+type limitWriter struct {
+	w         io.Writer
+	remaining int64
+	stop      context.CancelCauseFunc
+}
+
+func (l *limitWriter) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	l.remaining -= int64(n)
+	if l.remaining <= 0 {
+		l.stop(errMaxOutputSize)
+	}
+	return n, err
+}
+
+func getConfig(c *Config) (common.SimulatorConfig, error) {
+	cfg, err := data.NewSimulatorConfig(c.UseCase, c.TimestampStart, c.TimestampEnd, c.InitialScale, c.Scale, rng.New(c.Seed))
+	if err != nil {
+		return nil, err
+	}
+	if kc, ok := cfg.(*kubernetes.SimulatorConfig); ok && len(c.PodsPerNode) > 0 {
+		if kc.PodsPerNode, err = kubernetes.ParsePodsPerNode(c.PodsPerNode); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// writeProto writes the .proto of the messages of the protobuf format of the
+// points of sim to filename
+func writeProto(filename string, sim common.Simulator) error {
+	proto, err := protobuf.Proto(sim.Fields())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, proto, 0644)
+}
+
+// writeBigQuerySchema writes the schema of the table of the rows of format, a
+// bigquery-ndjson format, of the points of sim to filename
+func writeBigQuerySchema(filename, format string, sim common.Simulator) error {
+	measurement := strings.TrimPrefix(strings.TrimPrefix(format, data.FormatBigQueryNDJSON), ":")
+	schema, err := bigquery.TableSchema(sim.Fields(), measurement)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, schema, 0644)
+}
+
+func getSerializer(sim common.Simulator, format string, out *bufio.Writer) (serialize.PointSerializer, error) {
+	return data.NewSerializer(format, sim, out)
+}
+
+// startProfiles sets up CPU and/or memory profiling to be written to the given
+// files; an empty filename disables that profile. It returns a function to
+// cleanup/write that should be deferred by the caller
+func startProfiles(cpuProfileFile, memProfileFile string) (func() error, error) {
+	stops := []func() error{}
+	stopAll := func() error {
+		var err error
+		for _, fn := range stops {
+			if stopErr := fn(); stopErr != nil && err == nil {
+				err = stopErr
+			}
+		}
+		return err
+	}
+	if len(cpuProfileFile) > 0 {
+		stop, err := startCPUProfile(cpuProfileFile)
+		if err != nil {
+			return nil, err
+		}
+		stops = append(stops, stop)
+	}
+	if len(memProfileFile) > 0 {
+		stop, err := startMemoryProfile(memProfileFile)
+		if err != nil {
+			stopAll()
+			return nil, err
+		}
+		stops = append(stops, stop)
+	}
+	return stopAll, nil
+}
+
+// startCPUProfile starts CPU profiling to be written to profileFile. It
+// returns a function that stops the profile and closes the file
+func startCPUProfile(profileFile string) (func() error, error) {
+	f, err := os.Create(profileFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not create CPU profile: %v", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not start CPU profile: %v", err)
+	}
+
+	return func() error {
+		pprof.StopCPUProfile()
+		return f.Close()
+	}, nil
+}
+
+// startMemoryProfile sets up memory profiling to be written to profileFile. It
+// returns a function that writes the heap profile and closes the file
+func startMemoryProfile(profileFile string) (func() error, error) {
+	f, err := os.Create(profileFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not create memory profile: %v", err)
+	}
+
+	return func() error {
+		defer f.Close()
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			return fmt.Errorf("could not write memory profile: %v", err)
+		}
+		return nil
+	}, nil
+}
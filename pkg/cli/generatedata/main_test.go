@@ -0,0 +1,500 @@
+package generatedata
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/devops"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/cassandra"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+	"github.com/timescale/tsbs/pkg/data/serialize/mongo"
+	"github.com/timescale/tsbs/pkg/data/serialize/timescaledb"
+)
+
+const (
+	correctTimeStr   = "2016-01-01T00:00:00Z"
+	incorrectTimeStr = "2017-01-01"
+)
+
+var correctTime = time.Date(2016, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestParseTimeFromStrong(t *testing.T) {
+	parsedTime, err := parseTimeFromString(correctTimeStr)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if parsedTime != correctTime {
+		t.Errorf("did not get correct time back: got %v want %v", parsedTime, correctTime)
+	}
+
+	if _, err := parseTimeFromString(incorrectTimeStr); err == nil {
+		t.Errorf("did not error for incorrect time")
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	cases := []struct {
+		desc        string
+		groupID     uint
+		totalGroups uint
+		shouldErr   bool
+		errFmt      string
+	}{
+		{
+			desc:        "id < total, no err",
+			groupID:     0,
+			totalGroups: 1,
+			shouldErr:   false,
+		},
+		{
+			desc:        "id = total, should err",
+			groupID:     1,
+			totalGroups: 1,
+			shouldErr:   true,
+			errFmt:      errInvalidGroupsFmt,
+		},
+		{
+			desc:        "total = 0, should err",
+			groupID:     0,
+			totalGroups: 0,
+			shouldErr:   true,
+			errFmt:      errTotalGroupsZero,
+		},
+	}
+	for _, c := range cases {
+		ok, err := validateGroups(c.groupID, c.totalGroups)
+		if ok != c.shouldErr {
+			magic := 46 // first 45 chars are the same for both error messages, so check up to 46 to make sure its different
+			if c.shouldErr && err.Error()[:magic] != c.errFmt[:magic] {
+				t.Errorf("%s: did not get correct error: got\n%v\nwant\n%v\n", c.desc, err, c.errFmt)
+			}
+			if !c.shouldErr && err != nil {
+				t.Errorf("%s: got unexpected error: %v", c.desc, err)
+			}
+		}
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, f := range data.Formats() {
+		if !validateFormat(f) {
+			t.Errorf("format '%s' did not return true when it should", f)
+		}
+	}
+	if validateFormat("incorrect format!") {
+		t.Errorf("validateFormat returned true for invalid format")
+	}
+	if !validateFormat(data.FormatExecPrefix + "./serializer") {
+		t.Errorf("validateFormat returned false for exec format")
+	}
+}
+
+var keyIteration = []byte("iteration")
+
+type testSimulator struct {
+	limit            uint64
+	shouldWriteLimit uint64
+	iteration        uint64
+}
+
+func (s *testSimulator) Finished() bool {
+	return s.iteration >= s.limit
+}
+
+func (s *testSimulator) Next(ctx context.Context, p *serialize.Point) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	p.AppendField(keyIteration, s.iteration)
+	ret := s.iteration < s.shouldWriteLimit
+	s.iteration++
+	return ret
+}
+
+func (s *testSimulator) NextBatch(ctx context.Context, points []serialize.Point) int {
+	n := 0
+	for n < len(points) && !s.Finished() {
+		points[n].Reset()
+		if s.Next(ctx, &points[n]) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *testSimulator) Split(n int) []common.Simulator {
+	return []common.Simulator{s}
+}
+
+func (s *testSimulator) Fields() *serialize.Schema {
+	return nil
+}
+
+type testSerializer struct {
+	shouldError bool
+}
+
+func (s *testSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	if s.shouldError {
+		return fmt.Errorf("erroring")
+	}
+	w.Write(keyIteration)
+	w.Write([]byte("="))
+	str := fmt.Sprintf("%d", p.GetFieldValue(keyIteration).(uint64))
+	w.Write([]byte(str))
+	w.Write([]byte("\n"))
+	return nil
+}
+
+// testBatchSerializer is a testSerializer that also serializes PointBatches
+type testBatchSerializer struct {
+	testSerializer
+}
+
+func (s *testBatchSerializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	p := serialize.NewPoint()
+	for i := 0; i < b.Len(); i++ {
+		b.PointAt(i, p)
+		if err := s.Serialize(p, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRunSimulator(t *testing.T) {
+	cases := []struct {
+		desc             string
+		limit            uint64
+		shouldWriteLimit uint64
+		groupID          uint
+		totalGroups      uint
+		shouldError      bool
+		wantPoints       uint
+	}{
+		{
+			desc:             "shouldWriteLimit = limit",
+			limit:            10,
+			shouldWriteLimit: 10,
+			totalGroups:      1,
+			wantPoints:       10,
+		},
+		{
+			desc:             "shouldWriteLimit < limit",
+			limit:            10,
+			shouldWriteLimit: 5,
+			totalGroups:      1,
+			wantPoints:       5,
+		},
+		{
+			desc:             "shouldWriteLimit > limit",
+			limit:            10,
+			shouldWriteLimit: 15,
+			totalGroups:      1,
+			wantPoints:       10,
+		},
+		{
+			desc:             "shouldWriteLimit = limit, totalGroups=2",
+			limit:            10,
+			shouldWriteLimit: 10,
+			totalGroups:      2,
+			wantPoints:       5,
+		},
+		{
+			desc:             "shouldWriteLimit < limit, totalGroups=2",
+			limit:            10,
+			shouldWriteLimit: 6,
+			totalGroups:      2,
+			wantPoints:       3,
+		},
+		{
+			desc:             "shouldWriteLimit < limit, totalGroups=2, other half",
+			limit:            10,
+			shouldWriteLimit: 6,
+			groupID:          1,
+			totalGroups:      2,
+			wantPoints:       3,
+		},
+		{
+			desc:             "should error in serializer",
+			shouldError:      true,
+			limit:            10,
+			totalGroups:      1,
+			shouldWriteLimit: 10,
+		},
+	}
+	for _, useBatch := range []bool{false, true} {
+		for _, c := range cases {
+			var buf bytes.Buffer
+			sim := &testSimulator{
+				limit:            c.limit,
+				shouldWriteLimit: c.shouldWriteLimit,
+			}
+			var serializer serialize.PointSerializer = &testSerializer{shouldError: c.shouldError}
+			if useBatch {
+				serializer = &testBatchSerializer{testSerializer{shouldError: c.shouldError}}
+			}
+
+			completed, err := runSimulator(context.Background(), sim, serializer, &buf, c.groupID, c.totalGroups, nil)
+			if c.shouldError && err == nil {
+				t.Errorf("%s (batch %v): did not error when should", c.desc, useBatch)
+			} else if !c.shouldError && err != nil {
+				t.Errorf("%s (batch %v): unexpected error: %v", c.desc, useBatch, err)
+			} else if !c.shouldError && !completed {
+				t.Errorf("%s (batch %v): reported being interrupted", c.desc, useBatch)
+			}
+			if !c.shouldError {
+				scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+				lines := uint(0)
+				for {
+					ok := scanner.Scan()
+					if !ok && scanner.Err() != nil {
+						t.Fatal(scanner.Err())
+					} else if !ok {
+						break
+					}
+					line := scanner.Text()
+					want := fmt.Sprintf("iteration=%d", (lines*c.totalGroups)+c.groupID)
+					if line != want {
+						t.Errorf("%s: incorrect line: got\n%s\nwant\n%s\n", c.desc, line, want)
+					}
+					lines++
+				}
+				if lines != c.wantPoints {
+					t.Errorf("%s: incorrect number of points: got %d want %d", c.desc, lines, c.wantPoints)
+				}
+			}
+		}
+	}
+}
+
+func TestRunSimulatorInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	sim := &testSimulator{limit: 10, shouldWriteLimit: 10}
+	completed, err := runSimulator(ctx, sim, &testSerializer{}, &buf, 0, 1, nil)
+	if err != cli.ErrInterrupted {
+		t.Errorf("incorrect error: got %v want %v", err, cli.ErrInterrupted)
+	}
+	if completed {
+		t.Errorf("did not report being interrupted")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("interrupted simulator wrote output: got %q", buf.String())
+	}
+}
+
+func TestRunSimulatorMaxOutputSize(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	var buf bytes.Buffer
+	w := &limitWriter{w: &buf, remaining: 100, stop: cancel}
+	sim := &testSimulator{limit: 5000, shouldWriteLimit: 5000}
+	completed, err := runSimulator(ctx, sim, &testSerializer{}, w, 0, 1, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !completed {
+		t.Errorf("reported being interrupted when stopped by size")
+	}
+	// the batch of points reaching the limit is still written in full
+	lines := bytes.Count(buf.Bytes(), []byte("\n"))
+	if buf.Len() < 100 || lines == 0 || lines >= 5000 {
+		t.Errorf("incorrect output size: got %d bytes, %d points", buf.Len(), lines)
+	}
+}
+
+func TestRunSimulatorMaxDuration(t *testing.T) {
+	ctx, cancel := cli.WithMaxDuration(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	var buf bytes.Buffer
+	sim := &testSimulator{limit: 10, shouldWriteLimit: 10}
+	completed, err := runSimulator(ctx, sim, &testSerializer{}, &buf, 0, 1, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !completed {
+		t.Errorf("reported being interrupted when stopped by max duration")
+	}
+}
+
+// testConfig returns a valid Config for the given use case and format
+func testConfig(useCase, format string) *Config {
+	return &Config{
+		Format:            format,
+		UseCase:           useCase,
+		InitialScale:      1,
+		Scale:             1,
+		Seed:              123,
+		TimestampStart:    correctTime,
+		TimestampEnd:      correctTime.Add(time.Hour),
+		LogInterval:       10 * time.Second,
+		InterleavedGroups: 1,
+		WriteHeader:       true,
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	cfg, err := getConfig(testConfig(useCaseDevops, formatInflux))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := cfg.(type) {
+	case *devops.DevopsSimulatorConfig:
+	default:
+		t.Errorf("use case '%s' does not run the right type: got %T", useCaseDevops, got)
+	}
+
+	cfg, err = getConfig(testConfig(useCaseCPUOnly, formatInflux))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := cfg.(type) {
+	case *devops.CPUOnlySimulatorConfig:
+	default:
+		t.Errorf("use case '%s' does not run the right type: got %T", useCaseDevops, got)
+	}
+
+	cfg, err = getConfig(testConfig(useCaseCPUSingle, formatInflux))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := cfg.(type) {
+	case *devops.CPUOnlySimulatorConfig:
+	default:
+		t.Errorf("use case '%s' does not run the right type: got %T", useCaseDevops, got)
+	}
+
+	cfg, err = getConfig(testConfig("bogus config", formatInflux))
+	if err == nil {
+		t.Errorf("no error on bogus use case")
+	}
+	if cfg != nil {
+		t.Errorf("got a non-nil config for bogus use case: got %T", cfg)
+	}
+}
+
+func TestGetSerializer(t *testing.T) {
+	c := testConfig(useCaseCPUOnly, formatInflux)
+	cfg, err := getConfig(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sim := cfg.ToSimulator(c.LogInterval)
+	buf := bytes.NewBuffer(make([]byte, 1024))
+	out := bufio.NewWriter(buf)
+	defer out.Flush()
+
+	s, err := getSerializer(sim, formatCassandra, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := s.(type) {
+	case *cassandra.Serializer:
+	default:
+		t.Errorf("format '%s' does not run the right serializer: got %T", formatCassandra, got)
+	}
+
+	s, err = getSerializer(sim, formatInflux, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := s.(type) {
+	case *influx.Serializer:
+	default:
+		t.Errorf("format '%s' does not run the right serializer: got %T", formatInflux, got)
+	}
+
+	s, err = getSerializer(sim, formatMongo, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := s.(type) {
+	case *mongo.Serializer:
+	default:
+		t.Errorf("format '%s' does not run the right serializer: got %T", formatMongo, got)
+	}
+
+	s, err = getSerializer(sim, formatTimescaleDB, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	switch got := s.(type) {
+	case *timescaledb.Serializer:
+	default:
+		t.Errorf("format '%s' does not run the right serializer: got %T", formatTimescaleDB, got)
+	}
+
+	s, err = getSerializer(sim, "bogus format", out)
+	if err == nil {
+		t.Errorf("no error on bogus format")
+	}
+	if s != nil {
+		t.Errorf("got a non-nil config for bogus format: got %T", cfg)
+	}
+}
+
+func TestWriteProto(t *testing.T) {
+	c := testConfig(useCaseCPUOnly, data.FormatProtobuf)
+	cfg, err := getConfig(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	filename := filepath.Join(t.TempDir(), "cpu-only.proto")
+	if err := writeProto(filename, cfg.ToSimulator(c.LogInterval)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	proto, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"message Point {", "CpuFields cpu = 3;", "string hostname = 1;", "optional int64 usage_user = 1;"} {
+		if !strings.Contains(string(proto), want) {
+			t.Errorf(".proto does not have %q:\n%s", want, proto)
+		}
+	}
+}
+
+func TestWriteBigQuerySchema(t *testing.T) {
+	c := testConfig(useCaseCPUOnly, data.FormatBigQueryNDJSON)
+	cfg, err := getConfig(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sim := cfg.ToSimulator(c.LogInterval)
+	cases := map[string]string{
+		data.FormatBigQueryNDJSON:          `{"name":"cpu_usage_user","type":"INTEGER"}`,
+		data.FormatBigQueryNDJSON + ":cpu": `{"name":"usage_user","type":"INTEGER"}`,
+	}
+	for format, want := range cases {
+		filename := filepath.Join(t.TempDir(), "schema.json")
+		if err := writeBigQuerySchema(filename, format, sim); err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		schema, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		if !strings.Contains(string(schema), want) {
+			t.Errorf("%s: schema does not have %q:\n%s", format, want, schema)
+		}
+	}
+
+	if err := writeBigQuerySchema(filepath.Join(t.TempDir(), "schema.json"), data.FormatBigQueryNDJSON+":mem", sim); err == nil {
+		t.Errorf("unexpected lack of error for a measurement not in the use case")
+	}
+}
@@ -0,0 +1,109 @@
+package generatedata
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/version"
+)
+
+// generatorVersion identifies the data produced by this generator; see
+// data.GeneratorVersion for when it changes
+const generatorVersion = data.GeneratorVersion
+
+// manifest describes a generated dataset: the generator version and every
+// flag that affects the data, along with a digest of the output. Generating
+// again with the same version and flags should give the same digest. The
+// build of the binary is recorded too, though any build with the same
+// generator version gives the same data.
+type manifest struct {
+	GeneratorVersion int          `json:"generator_version"`
+	Build            version.Info `json:"build"`
+	Format           string       `json:"format"`
+	UseCase          string       `json:"use_case"`
+	Seed             int64        `json:"seed"`
+	ScaleVar         uint64       `json:"scale_var"`
+	InitialScaleVar  uint64       `json:"initial_scale_var"`
+	TimestampStart   string       `json:"timestamp_start"`
+	TimestampEnd     string       `json:"timestamp_end"`
+	LogInterval      string       `json:"log_interval"`
+	GroupID          uint         `json:"interleaved_generation_group_id"`
+	TotalGroups      uint         `json:"interleaved_generation_groups"`
+	OrderWindow      string       `json:"order_window,omitempty"`
+	MaxOutputSize    int64        `json:"max_output_size,omitempty"`
+	MaxDuration      string       `json:"max_duration,omitempty"`
+	OmitHeader       bool         `json:"omit_header,omitempty"`
+	ValueScript      string       `json:"value_script,omitempty"`
+	PodsPerNode      string       `json:"pods_per_node,omitempty"`
+	SHA256           string       `json:"sha256"`
+	// Incomplete is set when generation was interrupted, so the output (and
+	// SHA256) only covers the points generated up to that point
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// newManifest returns a manifest for the data generated with c along with
+// the digest of the output
+func newManifest(c *Config, digest hash.Hash) *manifest {
+	m := &manifest{
+		GeneratorVersion: generatorVersion,
+		Build:            version.Get(),
+		Format:           c.Format,
+		UseCase:          c.UseCase,
+		Seed:             c.Seed,
+		ScaleVar:         c.Scale,
+		InitialScaleVar:  c.InitialScale,
+		TimestampStart:   c.TimestampStart.Format(time.RFC3339),
+		TimestampEnd:     c.TimestampEnd.Format(time.RFC3339),
+		LogInterval:      c.LogInterval.String(),
+		GroupID:          c.InterleavedGroupID,
+		TotalGroups:      c.InterleavedGroups,
+		SHA256:           hex.EncodeToString(digest.Sum(nil)),
+	}
+	if c.OrderWindow > 0 {
+		m.OrderWindow = c.OrderWindow.String()
+	}
+	m.MaxOutputSize = c.MaxOutputSize
+	if c.MaxDuration > 0 {
+		m.MaxDuration = c.MaxDuration.String()
+	}
+	m.OmitHeader = !c.WriteHeader
+	m.ValueScript = c.ValueScript
+	m.PodsPerNode = c.PodsPerNode
+	return m
+}
+
+// writeManifest writes m as JSON to the given file
+func writeManifest(filename string, m *manifest) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// getDigestWriter wraps a buffered writer so that all data written to it is
+// also added to a SHA-256 digest. The returned function flushes the wrapper
+// and then calls closeOut, and must be used in its place.
+func getDigestWriter(out *bufio.Writer, closeOut func() error) (*bufio.Writer, hash.Hash, func() error) {
+	digest := sha256.New()
+	w := bufio.NewWriterSize(io.MultiWriter(out, digest), inputBufSize)
+	return w, digest, func() error {
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		return closeOut()
+	}
+}
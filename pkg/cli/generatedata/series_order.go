@@ -0,0 +1,86 @@
+package generatedata
+
+import (
+	"io"
+	"sort"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// seriesOrderedSerializer wraps a PointSerializer to emit points grouped by
+// series rather than in the time-interleaved order the simulators produce
+// them. Points are buffered until one falls outside the current window of
+// simulated time, at which point the buffered points are written out sorted
+// by measurement and then by series (i.e., tag values), keeping their time
+// order within a series.
+//
+// Grouping like points together generally compresses better and gives loaders
+// better locality, at the cost of holding a whole window of points in memory
+// and output that is only ordered by time within each window.
+type seriesOrderedSerializer struct {
+	serializer serialize.PointSerializer
+	window     int64
+	windowEnd  int64
+
+	points []serialize.Point
+	keys   [][]byte
+	order  []int
+}
+
+func newSeriesOrderedSerializer(serializer serialize.PointSerializer, window time.Duration) *seriesOrderedSerializer {
+	return &seriesOrderedSerializer{
+		serializer: serializer,
+		window:     int64(window),
+	}
+}
+
+// Serialize buffers a copy of p, first writing out the current window to w if
+// p falls after it
+func (s *seriesOrderedSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	ts := p.Timestamp()
+	if len(s.order) == 0 {
+		s.windowEnd = ts - ts%s.window + s.window
+	} else if ts >= s.windowEnd {
+		if err := s.Flush(w); err != nil {
+			return err
+		}
+		s.windowEnd = ts - ts%s.window + s.window
+	}
+
+	idx := len(s.order)
+	if idx == len(s.points) {
+		s.points = append(s.points, serialize.Point{})
+		s.keys = append(s.keys, nil)
+	}
+	p.CopyTo(&s.points[idx])
+	s.keys[idx] = appendSeriesKey(s.keys[idx][:0], p)
+	s.order = append(s.order, idx)
+	return nil
+}
+
+// Flush writes out all buffered points to w
+func (s *seriesOrderedSerializer) Flush(w io.Writer) error {
+	// the stable sort keeps points of the same series in time order
+	sort.SliceStable(s.order, func(i, j int) bool {
+		return string(s.keys[s.order[i]]) < string(s.keys[s.order[j]])
+	})
+	for _, idx := range s.order {
+		if err := s.serializer.Serialize(&s.points[idx], w); err != nil {
+			return err
+		}
+	}
+	s.order = s.order[:0]
+	return nil
+}
+
+// appendSeriesKey appends a key to buf that sorts points by measurement name
+// and then tag values
+func appendSeriesKey(buf []byte, p *serialize.Point) []byte {
+	buf = append(buf, p.MeasurementName()...)
+	for _, v := range p.TagValues() {
+		buf = append(buf, 0)
+		buf = append(buf, v...)
+	}
+	return buf
+}
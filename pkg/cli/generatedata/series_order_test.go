@@ -0,0 +1,84 @@
+package generatedata
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+var (
+	keyMeasurementA = []byte("a")
+	keyMeasurementB = []byte("b")
+	keyHost         = []byte("hostname")
+)
+
+type seriesTestSerializer struct{}
+
+func (s *seriesTestSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s,%s,%d\n", p.MeasurementName(), p.GetTagValue(keyHost), p.Timestamp())
+	return err
+}
+
+func TestSeriesOrderedSerializer(t *testing.T) {
+	type input struct {
+		measurement []byte
+		host        string
+		ts          time.Duration
+	}
+	inputs := []input{
+		{keyMeasurementB, "host_1", 0},
+		{keyMeasurementA, "host_1", 0},
+		{keyMeasurementB, "host_0", 0},
+		{keyMeasurementA, "host_0", 0},
+		{keyMeasurementB, "host_1", 10 * time.Second},
+		{keyMeasurementA, "host_1", 10 * time.Second},
+		{keyMeasurementB, "host_0", 10 * time.Second},
+		{keyMeasurementA, "host_0", 10 * time.Second},
+		// starts a new window
+		{keyMeasurementB, "host_0", 20 * time.Second},
+		{keyMeasurementA, "host_0", 20 * time.Second},
+	}
+	want := "a,host_0,0\n" +
+		"a,host_0,10000000000\n" +
+		"a,host_1,0\n" +
+		"a,host_1,10000000000\n" +
+		"b,host_0,0\n" +
+		"b,host_0,10000000000\n" +
+		"b,host_1,0\n" +
+		"b,host_1,10000000000\n" +
+		"a,host_0,20000000000\n" +
+		"b,host_0,20000000000\n"
+
+	var buf bytes.Buffer
+	s := newSeriesOrderedSerializer(&seriesTestSerializer{}, 20*time.Second)
+	p := serialize.NewPoint()
+	for _, in := range inputs {
+		// reuse the same Point, as the simulators do
+		p.Reset()
+		p.SetMeasurementName(in.measurement)
+		p.AppendTag(keyHost, []byte(in.host))
+		p.SetTimestamp(int64(in.ts))
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := s.Flush(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output: got\n%s\nwant\n%s", got, want)
+	}
+
+	// flushing again should be a no-op
+	buf.Reset()
+	if err := s.Flush(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.Len(); got != 0 {
+		t.Errorf("flush of empty buffer wrote %d bytes", got)
+	}
+}
@@ -0,0 +1,10 @@
+package generatedata
+
+import "fmt"
+
+// serveData serves generated data over gRPC on addr, as given by -serve.
+// Support for serving is only built in with the grpc build tag, which
+// replaces it.
+var serveData = func(addr string) error {
+	return fmt.Errorf("cannot serve on %s: built without gRPC support (rebuild with -tags grpc)", addr)
+}
@@ -0,0 +1,16 @@
+//go:build grpc
+// +build grpc
+
+package generatedata
+
+import (
+	"github.com/timescale/tsbs/pkg/data/service"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+func init() {
+	serveData = func(addr string) error {
+		logging.Info("serving data", "service", service.ServiceName, "addr", addr)
+		return service.ListenAndServe(addr)
+	}
+}
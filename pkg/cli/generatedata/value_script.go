@@ -0,0 +1,13 @@
+package generatedata
+
+import (
+	"fmt"
+
+	"github.com/timescale/tsbs/pkg/data"
+)
+
+// loadValueScript loads the script given by -value-script. Support for
+// scripts is only built in with the starlark build tag, which replaces it.
+var loadValueScript = func(filename string) (data.PointTransformer, error) {
+	return nil, fmt.Errorf("cannot load value script %s: built without Starlark support (rebuild with -tags starlark)", filename)
+}
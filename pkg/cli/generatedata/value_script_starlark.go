@@ -0,0 +1,15 @@
+//go:build starlark
+// +build starlark
+
+package generatedata
+
+import (
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/script"
+)
+
+func init() {
+	loadValueScript = func(filename string) (data.PointTransformer, error) {
+		return script.Load(filename)
+	}
+}
@@ -0,0 +1,136 @@
+package generatequeries
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/plugins"
+	"github.com/timescale/tsbs/pkg/querygen"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// Config holds all options of tsbs_generate_queries. It is filled in from
+// the command line by parseFlags and checked by Validate, so the options can
+// be handled without any global state.
+type Config struct {
+	Target  string
+	UseCase string
+	// Query holds the options of the queries generated
+	Query querygen.Config
+
+	Debug int
+	// OutputFile is the file to write to instead of stdout, which is only
+	// created once generation completes
+	OutputFile string
+	// MaxDuration stops generation once it has run this long (0 is
+	// unlimited)
+	MaxDuration time.Duration
+	ServeAddr   string
+	Plugins     []string
+	// PrintCapabilities prints the capabilities of the target, or of all
+	// targets if none is given, instead of generating queries
+	PrintCapabilities bool
+}
+
+// parseFlags parses the command line args (without the program name) into a
+// Config, defining the flags on fs. The result is not validated.
+func parseFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	// Change the Usage function to print the use case matrix of choices:
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+
+		fmt.Fprintf(fs.Output(), "\n")
+		fmt.Fprintf(fs.Output(), "The use case matrix of choices is:\n")
+		for _, uc := range querygen.UseCases() {
+			for _, qt := range querygen.QueryTypes(uc) {
+				fmt.Fprintf(fs.Output(), "  use case: %s, query type: %s\n", uc, qt)
+			}
+		}
+	}
+
+	c := &Config{}
+	var timestampStartStr, timestampEndStr, pluginPaths string
+
+	fs.StringVar(&c.Target, "format", "", "Format to emit. (Choices are in the use case matrix.)")
+	fs.StringVar(&c.UseCase, "use-case", "", "Use case to model. (Choices are in the use case matrix.)")
+	fs.StringVar(&c.Query.QueryType, "query-type", "", "Query type. (Choices are in the use case matrix.)")
+
+	fs.IntVar(&c.Query.Scale, "scale-var", 1, "Scaling variable (must be the equal to the scalevar used for data generation).")
+	fs.IntVar(&c.Query.Count, "queries", 1000, "Number of queries to generate.")
+
+	fs.BoolVar(&c.Query.TimescaleUseJSON, "timescale-use-json", false, "TimescaleDB only: Use separate JSON tags table when querying")
+	fs.BoolVar(&c.Query.TimescaleUseTags, "timescale-use-tags", true, "TimescaleDB only: Use separate tags table when querying")
+
+	fs.StringVar(&timestampStartStr, "timestamp-start", "2016-01-01T00:00:00Z", "Beginning timestamp (RFC3339, or relative to now, e.g., now-24h).")
+	fs.StringVar(&timestampEndStr, "timestamp-end", "2016-01-02T06:00:00Z", "Ending timestamp (RFC3339, or relative to now, e.g., now).")
+
+	fs.Int64Var(&c.Query.Seed, "seed", 0, "PRNG seed (default, or 0, uses the current timestamp).")
+	fs.IntVar(&c.Debug, "debug", 0, "Debug printing (choices: 0, 1) (default 0).")
+
+	fs.UintVar(&c.Query.InterleavedGroupID, "interleaved-generation-group-id", 0, "Group (0-indexed) to perform round-robin serialization within. Use this to scale up data generation to multiple processes.")
+	fs.UintVar(&c.Query.InterleavedNumGroups, "interleaved-generation-groups", 1, "The number of round-robin serialization groups. Use this to scale up data generation to multiple processes.")
+
+	fs.StringVar(&c.OutputFile, "file", "", "File to write the queries to instead of stdout. It is written under a temporary name and renamed once complete, or to its name with a "+cli.PartialSuffix+" suffix if generation fails or is interrupted.")
+	fs.DurationVar(&c.MaxDuration, "max-duration", 0, "Stop generating after running for this long (e.g., 30m; 0 is unlimited).")
+	fs.StringVar(&c.ServeAddr, "serve", "", "Address (e.g., :8080) to serve generated queries over HTTP as JSON on, instead of writing them to stdout.")
+	fs.BoolVar(&c.PrintCapabilities, "capabilities", false, "Print the capabilities (e.g., supported query types) of the format, or of all formats if none is given, as JSON and exit.")
+	fs.StringVar(&pluginPaths, "plugins", "", "Comma-separated list of Go plugins to load, which can add formats.")
+
+	if err := cli.ParseFlags(fs, "tsbs_generate_queries", args); err != nil {
+		return nil, err
+	}
+	c.Plugins = plugins.ParseList(pluginPaths)
+
+	// the default seed is the current timestamp:
+	if c.Query.Seed == 0 {
+		c.Query.Seed = int64(time.Now().Nanosecond())
+	}
+
+	// Parse timestamps, relative ones being relative to the same time:
+	now := time.Now()
+	var err error
+	c.Query.TimestampStart, err = cli.ParseTime(timestampStartStr, now)
+	if err != nil {
+		return nil, err
+	}
+	c.Query.TimestampEnd, err = cli.ParseTime(timestampEndStr, now)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that c is usable, returning an error describing the first
+// problem found. Formats added by plugins are only valid once the plugins are
+// loaded.
+func (c *Config) Validate() error {
+	// the queries to generate are given by each request when serving
+	if len(c.ServeAddr) > 0 || c.PrintCapabilities {
+		return nil
+	}
+	if c.MaxDuration < 0 {
+		return fmt.Errorf("max duration must not be negative: %v", c.MaxDuration)
+	}
+	if !(c.Query.InterleavedGroupID < c.Query.InterleavedNumGroups) {
+		return fmt.Errorf("incorrect interleaved groups configuration")
+	}
+	if !validTarget(c.Target) {
+		return suggest.Error("format", c.Target, querygen.Targets())
+	}
+	if err := c.Query.Validate(c.UseCase); err != nil {
+		return err
+	}
+	return querygen.CheckSupported(c.Target, c.Query.QueryType)
+}
+
+func validTarget(target string) bool {
+	for _, t := range querygen.Targets() {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package generatequeries
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/querygen"
+)
+
+func testParseFlags(args ...string) (*Config, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return parseFlags(fs, args)
+}
+
+func TestParseFlags(t *testing.T) {
+	c, err := testParseFlags(
+		"-format=influx", "-use-case=devops", "-query-type=lastpoint",
+		"-scale-var=10", "-seed=123", "-queries=5", "-timestamp-end=2016-01-02T00:00:00Z",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := querygen.Config{
+		QueryType:            "lastpoint",
+		Scale:                10,
+		Seed:                 123,
+		TimestampStart:       start,
+		TimestampEnd:         start.Add(24 * time.Hour),
+		Count:                5,
+		InterleavedNumGroups: 1,
+		TimescaleUseTags:     true,
+	}
+	if c.Target != querygen.TargetInflux || c.UseCase != querygen.UseCaseDevops {
+		t.Errorf("incorrect target or use case: got %s, %s", c.Target, c.UseCase)
+	}
+	if c.Query != want {
+		t.Errorf("incorrect query config:\ngot  %+v\nwant %+v", c.Query, want)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error validating: %v", err)
+	}
+
+	c, err = testParseFlags()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Query.Seed == 0 {
+		t.Errorf("seed = 0 not replaced")
+	}
+
+	for _, args := range [][]string{
+		{"-timestamp-start=2017-01-01"},
+		{"-timestamp-end=2017-01-01"},
+		{"-bogus-flag"},
+	} {
+		if _, err := testParseFlags(args...); err == nil {
+			t.Errorf("%v: did not error when should", args)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := []string{"-format=influx", "-use-case=devops", "-query-type=lastpoint"}
+	cases := []struct {
+		desc      string
+		args      []string
+		shouldErr bool
+	}{
+		{desc: "valid", args: valid},
+		{desc: "unknown format", args: append([]string{"-format=bogus"}, valid[1:]...), shouldErr: true},
+		{desc: "unknown use case", args: []string{"-format=influx", "-use-case=bogus", "-query-type=lastpoint"}, shouldErr: true},
+		{desc: "unknown query type", args: append(valid[:2:2], "-query-type=bogus"), shouldErr: true},
+		{desc: "0 groups", args: append(valid, "-interleaved-generation-groups=0"), shouldErr: true},
+		{desc: "group id too large", args: append(valid, "-interleaved-generation-group-id=1"), shouldErr: true},
+		{desc: "negative max duration", args: append(valid, "-max-duration=-1s"), shouldErr: true},
+		{desc: "unsupported query type", args: []string{"-format=mongo-naive", "-use-case=devops", "-query-type=lastpoint"}, shouldErr: true},
+		{desc: "serve mode ignores query options", args: []string{"-serve=:8080"}},
+		{desc: "capabilities mode ignores query options", args: []string{"-capabilities"}},
+	}
+	for _, c := range cases {
+		cfg, err := testParseFlags(c.args...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error parsing: %v", c.desc, err)
+		}
+		if err := cfg.Validate(); (err != nil) != c.shouldErr {
+			t.Errorf("%s: incorrect validation: got error %v, want error %v", c.desc, err, c.shouldErr)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package generatequeries implements tsbs_generate_queries (also run as
+// `tsbs generate queries`), which generates queries for various use cases.
+// Its output will be consumed by the corresponding tsbs_run_queries_ program.
+package generatequeries
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/plugins"
+	"github.com/timescale/tsbs/pkg/querygen"
+	"github.com/timescale/tsbs/query"
+)
+
+// Run runs tsbs_generate_queries with args as its command line flags
+func Run(args []string) error {
+	c, err := parseFlags(flag.CommandLine, args)
+	if err != nil {
+		return err
+	}
+	if err := plugins.Load(c.Plugins...); err != nil {
+		return cli.ConfigError(err)
+	}
+	// plugins may have registered more formats, so validate after loading
+	if err := c.Validate(); err != nil {
+		return cli.ConfigError(err)
+	}
+	if c.PrintCapabilities {
+		return printCapabilities(os.Stdout, c.Target)
+	}
+	if c.ServeAddr != "" {
+		logging.Info("serving queries", "addr", c.ServeAddr)
+		return http.ListenAndServe(c.ServeAddr, querygen.NewHandler())
+	}
+	logging.Info("using random seed", "seed", c.Query.Seed)
+	return generate(c, newMetadata(flag.CommandLine, c))
+}
+
+// newMetadata returns the metadata of generating the queries described by
+// c, with the flags resolved by parseFlags
+func newMetadata(fs *flag.FlagSet, c *Config) *cli.Metadata {
+	m := cli.StartMetadata(fs, "tsbs_generate_queries")
+	m.Seed = c.Query.Seed
+	m.Flags["seed"] = strconv.FormatInt(c.Query.Seed, 10)
+	m.Flags["timestamp-start"] = c.Query.TimestampStart.Format(time.RFC3339)
+	m.Flags["timestamp-end"] = c.Query.TimestampEnd.Format(time.RFC3339)
+	return m
+}
+
+// printCapabilities writes the capabilities of target, or the capability
+// matrix of all targets if target is empty, to w as JSON
+func printCapabilities(w io.Writer, target string) error {
+	var v interface{} = querygen.CapabilityMatrix()
+	if target != "" {
+		c, ok := querygen.TargetCapabilities(target)
+		if !ok {
+			return fmt.Errorf("no capabilities declared for format '%s'", target)
+		}
+		v = c
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// generate writes the queries described by c to stdout, or to c.OutputFile,
+// and then the metadata md. If it fails part way or is interrupted, the
+// queries generated so far are still flushed (to c.OutputFile with
+// cli.PartialSuffix) and the metadata (marked incomplete) is still written
+// before the error, or cli.ErrInterrupted, is returned.
+func generate(c *Config, md *cli.Metadata) (err error) {
+	// Make the query generator:
+	it, err := querygen.New(c.Target, c.UseCase, c.Query)
+	if err != nil {
+		return err
+	}
+
+	// Set up bookkeeping, stopping early on an interrupt or SIGTERM, or
+	// after -max-duration:
+	stats := make(map[string]int64)
+	ctx, stop := cli.NotifyInterrupt(context.Background())
+	defer stop()
+	ctx, cancelMaxDuration := cli.WithMaxDuration(ctx, c.MaxDuration)
+	defer cancelMaxDuration()
+
+	// Set up output buffering, and a checksum of the output for the
+	// metadata:
+	var dst io.Writer = os.Stdout
+	var file *cli.OutputFile
+	if c.OutputFile != "" {
+		if file, err = cli.CreateOutputFile(c.OutputFile); err != nil {
+			return err
+		}
+		dst = file.File
+	}
+	var digest hash.Hash
+	if md.Enabled() {
+		digest = sha256.New()
+		dst = io.MultiWriter(dst, digest)
+	}
+	out := bufio.NewWriter(dst)
+	defer func() {
+		if flushErr := out.Flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+		outputName := c.OutputFile
+		if file != nil {
+			if err == nil {
+				err = file.Commit()
+			} else if partial, abortErr := file.Abort(); abortErr != nil {
+				logging.Error("could not keep incomplete output", "error", abortErr)
+			} else {
+				logging.Warn("kept incomplete output", "file", partial)
+				outputName = partial
+			}
+		}
+		if digest != nil {
+			md.AddOutput(outputName, hex.EncodeToString(digest.Sum(nil)))
+		}
+		err = md.Finish(err)
+	}()
+
+	// Start with a header describing the queries, which runners check
+	// before executing them:
+	err = query.WriteFileHeader(out, &query.FileHeader{
+		Target:  c.Target,
+		UseCase: c.UseCase,
+		Scale:   uint64(c.Query.Scale),
+		Seed:    c.Query.Seed,
+		Count:   uint64(c.Query.Count),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create request instances, serializing them to stdout and collecting
+	// counts for each kind. If applicable, only prints queries that
+	// belong to this interleaved group id:
+	enc := gob.NewEncoder(out)
+	for it.Next() {
+		if ctx.Err() != nil {
+			if context.Cause(ctx) == cli.ErrMaxDuration {
+				logging.Info("reached max duration, stopping generation")
+				break
+			}
+			logging.Warn("caught interrupt, stopping generation early")
+			return cli.ErrInterrupted
+		}
+		q := it.Query()
+		if err := enc.Encode(q); err != nil {
+			return fmt.Errorf("encoder %v", err)
+		}
+		stats[string(q.HumanLabelName())]++
+
+		if c.Debug == 1 {
+			logging.Info("generated query", "label", string(q.HumanLabelName()))
+		} else if c.Debug == 2 {
+			logging.Info("generated query", "description", string(q.HumanDescriptionName()))
+		} else if c.Debug >= 3 {
+			logging.Info("generated query", "query", q.String())
+		}
+		q.Release()
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+
+	// Print stats:
+	keys := []string{}
+	for k := range stats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		logging.Info("generated queries", "label", k, "count", stats[k])
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package generatequeries
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/querygen"
+	"github.com/timescale/tsbs/query"
+)
+
+func TestPrintCapabilities(t *testing.T) {
+	var buf bytes.Buffer
+	if err := printCapabilities(&buf, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var matrix map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &matrix); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := matrix[querygen.TargetMongoNaive]; !ok {
+		t.Errorf("capabilities of %s missing: %s", querygen.TargetMongoNaive, buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := printCapabilities(&buf, querygen.TargetMongoNaive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var c querygen.Capabilities
+	if err := json.Unmarshal(buf.Bytes(), &c); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.SupportsQueryType("double-groupby-1") || c.SupportsQueryType("lastpoint") {
+		t.Errorf("incorrect query types: %v", c.QueryTypes)
+	}
+
+	if err := printCapabilities(&buf, "bogus"); err == nil {
+		t.Errorf("did not error for format without capabilities")
+	}
+}
+
+func TestGenerateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-generate-queries")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	metadataFile := filepath.Join(dir, "metadata.json")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	c, err := parseFlags(fs, []string{
+		"-format=influx", "-use-case=devops", "-query-type=lastpoint",
+		"-seed=123", "-queries=5", "-file=" + filepath.Join(dir, "queries.gob"),
+		"-timestamp-end=now", "-metadata-file=" + metadataFile,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := generate(c, newMetadata(fs, c)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(c.OutputFile)
+	if err != nil {
+		t.Fatalf("could not read output: %v", err)
+	}
+	h, err := query.ReadFileHeader(bufio.NewReader(bytes.NewReader(b)))
+	if err != nil {
+		t.Fatalf("could not read header: %v", err)
+	}
+	if h.Target != querygen.TargetInflux || h.Count != 5 {
+		t.Errorf("incorrect header: %v", h)
+	}
+
+	// the metadata has the resolved flags and the checksum of the output
+	var md cli.Metadata
+	mb, err := ioutil.ReadFile(metadataFile)
+	if err != nil {
+		t.Fatalf("could not read metadata: %v", err)
+	}
+	if err := json.Unmarshal(mb, &md); err != nil {
+		t.Fatalf("could not parse metadata: %v", err)
+	}
+	sum := sha256.Sum256(b)
+	wantOutputs := []cli.OutputChecksum{{File: c.OutputFile, SHA256: hex.EncodeToString(sum[:])}}
+	if !reflect.DeepEqual(md.Outputs, wantOutputs) {
+		t.Errorf("incorrect outputs: got %v want %v", md.Outputs, wantOutputs)
+	}
+	if md.Seed != 123 || md.Flags["query-type"] != "lastpoint" || md.Incomplete {
+		t.Errorf("incorrect metadata: %+v", md)
+	}
+	if got, want := md.Flags["timestamp-end"], c.Query.TimestampEnd.Format(time.RFC3339); got != want {
+		t.Errorf("relative timestamp not resolved: got %s want %s", got, want)
+	}
+
+	// an unknown query type fails before the output is created
+	c.Query.QueryType = "bogus"
+	c.OutputFile = filepath.Join(dir, "bogus.gob")
+	if err := generate(c, newMetadata(fs, c)); err == nil {
+		t.Fatalf("unexpected lack of error")
+	}
+	if _, err := os.Stat(c.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("output of failed generation exists")
+	}
+}
@@ -0,0 +1,102 @@
+package importdata
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// Sources of the datasets imported
+const (
+	sourceCSV        = "csv"
+	sourcePrometheus = "prometheus"
+)
+
+var sources = []string{sourceCSV, sourcePrometheus}
+
+// Sources returns the kinds of datasets -source takes
+func Sources() []string {
+	return append([]string(nil), sources...)
+}
+
+// Config holds all options of tsbs_import. It is filled in from the command
+// line by parseFlags and checked by Validate.
+type Config struct {
+	Source string
+	// Input is the CSV file, or stdin if empty, or the directory of the
+	// Prometheus TSDB or block
+	Input   string
+	Mapping string
+	// Match is the regular expression of the names of the Prometheus
+	// metrics imported, which are all if it is empty
+	Match string
+
+	Format string
+	// OutputFile is the file to write to instead of stdout, which is only
+	// created once the import completes
+	OutputFile  string
+	WriteHeader bool
+	// TimestampStart, if not zero, is the time the points are shifted to
+	// start at
+	TimestampStart time.Time
+}
+
+// parseFlags parses the command line args (without the program name) into a
+// Config, defining the flags on fs. The result is not validated.
+func parseFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	c := &Config{}
+	var timestampStartStr string
+
+	fs.StringVar(&c.Source, "source", sourceCSV, fmt.Sprintf("Kind of dataset to import. (choices: %s)", strings.Join(sources, ", ")))
+	fs.StringVar(&c.Input, "input", "", "Dataset to import: the CSV file (default: stdin), or the data directory of a Prometheus TSDB, or one of its blocks")
+	fs.StringVar(&c.Mapping, "mapping", "", "YAML or JSON file mapping the columns of the CSV file to the time, tags and fields of points (required for csv)")
+	fs.StringVar(&c.Match, "match", "", "Regular expression of the names of the Prometheus metrics to import (default: all)")
+	fs.StringVar(&c.Format, "format", "", fmt.Sprintf("Format to emit. (choices: %s, or %s<command> to pipe points to a command)", strings.Join(data.Formats(), ", "), data.FormatExecPrefix))
+	fs.StringVar(&c.OutputFile, "file", "", "File to write the data to instead of stdout. It is written under a temporary name and renamed once complete, or to its name with a "+cli.PartialSuffix+" suffix if the import fails or is interrupted")
+	fs.BoolVar(&c.WriteHeader, "header", true, "Start the output of formats read by a tsbs loader with a header of the format and schema, which the loader checks before loading (disable for data not read by the loader)")
+	fs.StringVar(&timestampStartStr, "timestamp-start", "", "Shift the timestamps of the points so the first one is at this time (RFC3339, or relative to now, e.g., now-24h), e.g., to match the time range of generated queries (default: keep them)")
+	if err := cli.ParseFlags(fs, "tsbs_import", args); err != nil {
+		return nil, err
+	}
+
+	if len(timestampStartStr) > 0 {
+		var err error
+		if c.TimestampStart, err = cli.ParseTime(timestampStartStr, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Validate checks that c is usable, returning an error describing the first
+// problem found
+func (c *Config) Validate() error {
+	switch c.Source {
+	case sourceCSV:
+		if len(c.Mapping) == 0 {
+			return fmt.Errorf("no CSV mapping: set -mapping")
+		}
+	case sourcePrometheus:
+		if len(c.Input) == 0 {
+			return fmt.Errorf("no Prometheus TSDB: set -input")
+		}
+		if _, err := regexp.Compile(c.Match); err != nil {
+			return fmt.Errorf("invalid match: %v", err)
+		}
+	default:
+		return suggest.Error("source", c.Source, sources)
+	}
+	if !data.IsFormat(c.Format) {
+		return suggest.Error("format", c.Format, data.Formats())
+	}
+	if data.IsFileFormat(c.Format) && len(c.OutputFile) == 0 {
+		return fmt.Errorf("format %s writes files, which requires -file", c.Format)
+	}
+	return nil
+}
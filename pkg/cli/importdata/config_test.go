@@ -0,0 +1,72 @@
+package importdata
+
+import (
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testParseFlags(args ...string) (*Config, error) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	return parseFlags(fs, args)
+}
+
+func TestParseFlags(t *testing.T) {
+	c, err := testParseFlags("-source=prometheus", "-input=data", "-match=^node_", "-format=influx", "-header=false", "-timestamp-start=2016-01-01T00:00:00Z")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Config{
+		Source:         sourcePrometheus,
+		Input:          "data",
+		Match:          "^node_",
+		Format:         "influx",
+		TimestampStart: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if *c != want {
+		t.Errorf("incorrect config:\ngot  %+v\nwant %+v", *c, want)
+	}
+
+	c, err = testParseFlags("-mapping=m.yaml", "-format=mysql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Source != sourceCSV || !c.WriteHeader || !c.TimestampStart.IsZero() {
+		t.Errorf("incorrect defaults: %+v", *c)
+	}
+
+	if _, err := testParseFlags("-timestamp-start=yesterday"); err == nil {
+		t.Errorf("invalid timestamp parsed")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc    string
+		c       Config
+		wantErr string
+	}{
+		{desc: "csv", c: Config{Source: sourceCSV, Mapping: "m.yaml", Format: "influx"}},
+		{desc: "prometheus", c: Config{Source: sourcePrometheus, Input: "data", Format: "mysql"}},
+		{desc: "unknown source", c: Config{Source: "prometeus", Format: "influx"}, wantErr: "did you mean"},
+		{desc: "no mapping", c: Config{Source: sourceCSV, Format: "influx"}, wantErr: "no CSV mapping"},
+		{desc: "no input", c: Config{Source: sourcePrometheus, Format: "influx"}, wantErr: "no Prometheus TSDB"},
+		{desc: "invalid match", c: Config{Source: sourcePrometheus, Input: "data", Match: "(", Format: "influx"}, wantErr: "invalid match"},
+		{desc: "file format", c: Config{Source: sourceCSV, Mapping: "m.yaml", Format: "parquet", OutputFile: "data.parquet"}},
+		{desc: "file format to stdout", c: Config{Source: sourceCSV, Mapping: "m.yaml", Format: "parquet"}, wantErr: "requires -file"},
+		{desc: "unknown format", c: Config{Source: sourceCSV, Mapping: "m.yaml", Format: "xlsx"}, wantErr: "format"},
+	}
+	for _, c := range cases {
+		err := c.c.Validate()
+		if len(c.wantErr) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", c.desc, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.wantErr)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package importdata implements tsbs_import (also run as `tsbs import`),
+// which converts an external dataset into data in any format of
+// tsbs_generate_data, so that real data, rather than simulated, can be
+// loaded by the loaders and queried by the query runners.
+//
+// Supported datasets:
+// csv: a CSV file, whose columns are mapped to the time, tags and fields of
+// points by a YAML or JSON file (see importer.CSVMapping)
+// prometheus: the blocks of a Prometheus TSDB, whose samples are points of
+// a measurement named after their metric, with the labels of their series
+// as tags and a value field
+//
+// Queries generated for a use case only match imported data whose
+// measurements, tags and fields are those of the use case, e.g., a cpu
+// measurement with a hostname tag for devops, and whose time range is that
+// given to tsbs_generate_queries, which -timestamp-start can shift the data
+// to.
+package importdata
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/importer"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+const writeBufSize = 4 << 20
+
+// Run runs tsbs_import with args as its command line flags
+func Run(args []string) error {
+	c, err := parseFlags(flag.CommandLine, args)
+	if err != nil {
+		return err
+	}
+	if err := c.Validate(); err != nil {
+		return cli.ConfigError(err)
+	}
+	imp, err := open(c)
+	if err != nil {
+		return cli.ConfigError(err)
+	}
+	defer imp.Close()
+	return importToOutput(c, imp)
+}
+
+// open returns the Importer of the dataset of c
+func open(c *Config) (importer.Importer, error) {
+	if c.Source == sourcePrometheus {
+		var match *regexp.Regexp
+		if len(c.Match) > 0 {
+			match = regexp.MustCompile(c.Match)
+		}
+		return importer.OpenPrometheus(c.Input, match)
+	}
+	mapping, err := importer.LoadCSVMapping(c.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	// stdin is not closed with the importer, unlike a file
+	var r io.Reader = bufio.NewReaderSize(os.Stdin, writeBufSize)
+	if len(c.Input) > 0 {
+		f, err := os.Open(c.Input)
+		if err != nil {
+			return nil, err
+		}
+		r = f
+	}
+	imp, err := importer.NewCSV(r, mapping)
+	if err != nil {
+		if f, ok := r.(*os.File); ok {
+			f.Close()
+		}
+		return nil, err
+	}
+	return imp, nil
+}
+
+// importToOutput writes the points of imp to stdout, or to c.OutputFile,
+// which is only committed if the import completes
+func importToOutput(c *Config, imp importer.Importer) (err error) {
+	ctx, stop := cli.NotifyInterrupt(context.Background())
+	defer stop()
+
+	dst := os.Stdout
+	var file *cli.OutputFile
+	if len(c.OutputFile) > 0 {
+		if file, err = cli.CreateOutputFile(c.OutputFile); err != nil {
+			return err
+		}
+		dst = file.File
+	}
+	out := bufio.NewWriterSize(dst, writeBufSize)
+	defer func() {
+		if flushErr := out.Flush(); flushErr != nil && err == nil {
+			err = flushErr
+		}
+		if file == nil {
+			return
+		}
+		if err == nil {
+			err = file.Commit()
+		} else if partial, abortErr := file.Abort(); abortErr != nil {
+			logging.Error("could not keep incomplete output", "error", abortErr)
+		} else {
+			logging.Warn("kept incomplete output", "file", partial)
+		}
+	}()
+	return write(ctx, c, imp, out)
+}
+
+// write serializes the points of imp in the format of c to w, shifting
+// them to c.TimestampStart if set. It returns cli.ErrInterrupted if ctx is
+// done first.
+func write(ctx context.Context, c *Config, imp importer.Importer, w io.Writer) (err error) {
+	sim := imp
+	if !c.TimestampStart.IsZero() {
+		sim = importer.Rebase(imp, c.TimestampStart)
+	}
+	if c.WriteHeader {
+		// imported data has no seed
+		if err := data.WriteHeader(w, c.Format, sim, 0); err != nil {
+			return err
+		}
+	}
+	serializer, err := data.NewSerializer(c.Format, sim, w)
+	if err != nil {
+		return err
+	}
+	if closer, ok := serializer.(io.Closer); ok {
+		defer func() {
+			if closeErr := closer.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}()
+	}
+
+	start := time.Now()
+	if err := data.Run(ctx, sim, serializer, w, 0, 1); err != nil {
+		if err == ctx.Err() {
+			logging.Warn("caught interrupt, stopping import early")
+			return cli.ErrInterrupted
+		}
+		return err
+	}
+	if err := imp.Err(); err != nil {
+		return cli.DataError(err)
+	}
+	if prom, ok := imp.(*importer.PrometheusImporter); ok && prom.SkippedChunks() > 0 {
+		logging.Warn("skipped chunks of native histograms", "chunks", prom.SkippedChunks())
+	}
+	logging.Info("imported dataset", "source", c.Source, "measurements", sim.Fields().Len(), "took", time.Since(start))
+	return nil
+}
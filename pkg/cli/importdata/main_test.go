@@ -0,0 +1,97 @@
+package importdata
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/importer"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+const testInput = "ts,host,usage_user\n" +
+	"1451606400000,host_0,58.5\n" +
+	"1451606410000,host_1,3\n"
+
+func testImporter(t *testing.T, input string) importer.Importer {
+	m := &importer.CSVMapping{
+		Time: importer.CSVTime{Column: "ts", Format: importer.TimeUnixMillis},
+		Tags: []importer.CSVColumn{{Column: "host", Name: "hostname"}},
+		Measurements: []importer.CSVMeasurement{
+			{Name: "cpu", Fields: []importer.CSVColumn{{Column: "usage_user"}}},
+		},
+	}
+	imp, err := importer.NewCSV(strings.NewReader(input), m)
+	if err != nil {
+		t.Fatalf("could not read CSV: %v", err)
+	}
+	return imp
+}
+
+func TestWrite(t *testing.T) {
+	cases := []struct {
+		desc  string
+		start time.Time
+		want  []string
+	}{
+		{
+			desc: "as read",
+			want: []string{
+				"cpu,hostname=host_0 usage_user=58.5 1451606400000000000",
+				"cpu,hostname=host_1 usage_user=3 1451606410000000000",
+			},
+		},
+		{
+			desc:  "rebased",
+			start: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: []string{
+				"cpu,hostname=host_0 usage_user=58.5 1577836800000000000",
+				"cpu,hostname=host_1 usage_user=3 1577836810000000000",
+			},
+		},
+	}
+	for _, c := range cases {
+		conf := &Config{Source: sourceCSV, Format: "influx", WriteHeader: true, TimestampStart: c.start}
+		var buf bytes.Buffer
+		if err := write(context.Background(), conf, testImporter(t, testInput), &buf); err != nil {
+			t.Fatalf("%s: could not write: %v", c.desc, err)
+		}
+		br := bufio.NewReader(&buf)
+		header, err := serialize.ReadHeader(br)
+		if err != nil || header == nil {
+			t.Fatalf("%s: no header: %v", c.desc, err)
+		}
+		if header.Format != "influx" {
+			t.Errorf("%s: incorrect header format: %s", c.desc, header.Format)
+		}
+		rest, _ := br.ReadString(0)
+		if got := strings.Split(strings.TrimSpace(rest), "\n"); strings.Join(got, "\n") != strings.Join(c.want, "\n") {
+			t.Errorf("%s: incorrect output:\ngot  %q\nwant %q", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	conf := &Config{Source: sourceCSV, Format: "influx"}
+	input := testInput + "1451606420000,host_2,high\n"
+	var buf bytes.Buffer
+	err := write(context.Background(), conf, testImporter(t, input), &buf)
+	if err == nil || !strings.Contains(err.Error(), "high") {
+		t.Fatalf("invalid value imported: %v", err)
+	}
+	if cli.ExitCode(err) != cli.ExitData {
+		t.Errorf("incorrect exit code for invalid data: %d", cli.ExitCode(err))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = write(ctx, conf, testImporter(t, testInput), &buf)
+	if !errors.Is(err, cli.ErrInterrupted) {
+		t.Errorf("incorrect error when interrupted: %v", err)
+	}
+}
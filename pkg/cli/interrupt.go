@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// ErrInterrupted is returned by a tool that was stopped early by a signal
+// (see NotifyInterrupt) once it has cleaned up, e.g., flushed its output and
+// written its manifest, so it still exits nonzero
+var ErrInterrupted = errors.New("interrupted")
+
+// NotifyInterrupt returns a copy of parent that is done once the process
+// gets an interrupt (e.g., ctrl+c) or SIGTERM (e.g., from a job scheduler or
+// container runtime), and a function to stop listening for them, after which
+// another signal kills the process as usual
+func NotifyInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+}
+
+// ErrMaxDuration is the cause of a context returned by WithMaxDuration being
+// done once its duration has passed
+var ErrMaxDuration = errors.New("reached max duration")
+
+// WithMaxDuration returns a copy of parent that is done, with ErrMaxDuration
+// as its cause, once d has passed, for tools taking -max-duration. Unlike an
+// interrupt, this is a normal end to a run. If d is 0 it is only done when
+// parent is.
+func WithMaxDuration(parent context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeoutCause(parent, d, ErrMaxDuration)
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithMaxDuration(t *testing.T) {
+	ctx, cancel := WithMaxDuration(context.Background(), time.Millisecond)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("context not done after its max duration")
+	}
+	if got := context.Cause(ctx); got != ErrMaxDuration {
+		t.Errorf("incorrect cause: got %v want %v", got, ErrMaxDuration)
+	}
+
+	// 0 is unlimited, but the context is still done with its parent
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel = WithMaxDuration(parent, 0)
+	defer cancel()
+	if ctx.Err() != nil {
+		t.Errorf("unlimited context done early")
+	}
+	cancelParent()
+	<-ctx.Done()
+	if got := context.Cause(ctx); got != context.Canceled {
+		t.Errorf("incorrect cause: got %v want %v", got, context.Canceled)
+	}
+}
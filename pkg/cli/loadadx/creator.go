@@ -0,0 +1,176 @@
+package loadadx
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/kusto"
+)
+
+const createTablePrefix = ".create-merge table "
+
+// metricsPerRow holds the number of metrics in each row of each table, as
+// read from the header
+var metricsPerRow map[string]uint64
+
+type dbCreator struct {
+	br *bufio.Reader
+	// commands are the control commands of the header, and tables the
+	// tables they create
+	commands []string
+	tables   []string
+	exists   bool
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the control commands at the start of the data, up to
+// an empty line
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	metricsPerRow = make(map[string]uint64)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		if !strings.HasPrefix(line, ".") {
+			fatalData("input has wrong header format: not a control command: %s", line)
+			return
+		}
+		d.commands = append(d.commands, line)
+		if !strings.HasPrefix(line, createTablePrefix) {
+			continue
+		}
+		table, metrics, err := parseCreateTable(line)
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		d.tables = append(d.tables, table)
+		metricsPerRow[table] = metrics
+	}
+}
+
+// parseCreateTable returns the table a .create-merge table command of the
+// header creates, and the number of its columns which are metrics: all but
+// the timestamp and the tags, which are strings
+func parseCreateTable(command string) (string, uint64, error) {
+	table, rest, err := parseName(strings.TrimPrefix(command, createTablePrefix))
+	if err != nil || !strings.HasPrefix(rest, " (") {
+		return "", 0, fmt.Errorf("invalid command: %s", command)
+	}
+	rest = rest[2:]
+	var metrics uint64
+	for len(rest) > 0 && rest[0] != ')' {
+		_, rest, err = parseName(strings.TrimPrefix(rest, ", "))
+		if err != nil || !strings.HasPrefix(rest, ":") {
+			return "", 0, fmt.Errorf("invalid command: %s", command)
+		}
+		end := strings.IndexAny(rest, ",)")
+		if end < 0 {
+			return "", 0, fmt.Errorf("invalid command: %s", command)
+		}
+		if t := rest[1:end]; t != "string" && t != "datetime" {
+			metrics++
+		}
+		rest = rest[end:]
+	}
+	return table, metrics, nil
+}
+
+// parseName parses a Kusto identifier of the form ['name'] at the start of
+// s, returning the name and the rest of s
+func parseName(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "['") {
+		return "", "", fmt.Errorf("not a name: %s", s)
+	}
+	var b strings.Builder
+	for i := 2; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", fmt.Errorf("unterminated name: %s", s)
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		case '\'':
+			if !strings.HasPrefix(s[i:], "']") {
+				return "", "", fmt.Errorf("unterminated name: %s", s)
+			}
+			return b.String(), s[i+2:], nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated name: %s", s)
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	r, err := client.Mgmt("", ".show databases")
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not list databases", "cluster", clusterURL, "error", err)
+		return false
+	}
+	for _, name := range r.Column("DatabaseName") {
+		if name == dbName {
+			d.exists = true
+		}
+	}
+	return d.exists
+}
+
+// RemoveOldDB drops the tables of the header, as databases of a cluster are
+// usually managed outside of it
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	if len(d.tables) == 0 {
+		return nil
+	}
+	names := make([]string, len(d.tables))
+	for i, t := range d.tables {
+		names[i] = kusto.QuoteName(t)
+	}
+	_, err := client.Mgmt(dbName, ".drop tables ("+strings.Join(names, ", ")+") ifexists")
+	return err
+}
+
+// CreateDB creates the tables and mappings of the header, after creating
+// the database if it does not exist, which only the ADX emulator and free
+// clusters allow
+func (d *dbCreator) CreateDB(dbName string) error {
+	if !d.exists {
+		if _, err := client.Mgmt("", ".create database "+kusto.QuoteName(dbName)+" volatile"); err != nil {
+			return fmt.Errorf("could not create database %s, which may need to be created beforehand: %v", dbName, err)
+		}
+	}
+	for _, c := range d.commands {
+		if _, err := client.Mgmt(dbName, c); err != nil {
+			return err
+		}
+	}
+	if ingestion == kusto.Streaming {
+		for _, t := range d.tables {
+			if _, err := client.Mgmt(dbName, ".alter table "+kusto.QuoteName(t)+" policy streamingingestion enable"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package loadadx
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/kusto"
+)
+
+const testHeader = `.create-merge table ['cpu'] (['timestamp']:datetime, ['hostname']:string, ['usage_user']:real, ['usage_system']:long)
+.create-or-alter table ['cpu'] ingestion csv mapping 'tsbs' '[{"column":"timestamp","Properties":{"Ordinal":"0"}}]'
+.create-merge table ['it\'s'] (['timestamp']:datetime, ['up']:bool)
+
+cpu,2016-01-01T00:00:00Z,host_0,1,2
+`
+
+func TestParseCreateTable(t *testing.T) {
+	cases := []struct {
+		desc        string
+		command     string
+		wantTable   string
+		wantMetrics uint64
+		wantErr     bool
+	}{
+		{
+			desc:        "tags and fields",
+			command:     ".create-merge table ['cpu'] (['timestamp']:datetime, ['hostname']:string, ['usage_user']:real, ['usage_system']:long)",
+			wantTable:   "cpu",
+			wantMetrics: 2,
+		},
+		{
+			desc:        "escaped names",
+			command:     `.create-merge table ['it\'s'] (['timestamp']:datetime, ['a, b']:real, ['c\\']:bool)`,
+			wantTable:   "it's",
+			wantMetrics: 2,
+		},
+		{desc: "unterminated name", command: ".create-merge table ['cpu (['timestamp']:datetime)", wantErr: true},
+		{desc: "no columns", command: ".create-merge table ['cpu']", wantErr: true},
+		{desc: "no type", command: ".create-merge table ['cpu'] (['timestamp']", wantErr: true},
+	}
+	for _, c := range cases {
+		table, metrics, err := parseCreateTable(c.command)
+		if (err != nil) != c.wantErr {
+			t.Errorf("%s: incorrect error: %v", c.desc, err)
+		} else if table != c.wantTable || metrics != c.wantMetrics {
+			t.Errorf("%s: incorrect table: got %s with %d metrics, want %s with %d", c.desc, table, metrics, c.wantTable, c.wantMetrics)
+		}
+	}
+}
+
+// testCluster is a fake cluster answering control commands, recording them
+type testCluster struct {
+	mu       sync.Mutex
+	commands []string
+	// fail is the command that fails, if any
+	fail string
+}
+
+func (c *testCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var in struct{ DB, CSL string }
+	json.NewDecoder(r.Body).Decode(&in)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands = append(c.commands, in.DB+": "+in.CSL)
+	if in.CSL == c.fail {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":"BadRequest","message":"not allowed"}}`))
+		return
+	}
+	w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"DatabaseName","DataType":"String"}],"Rows":[["other"]]}]}`))
+}
+
+func TestDBCreator(t *testing.T) {
+	cluster := &testCluster{}
+	server := httptest.NewServer(cluster)
+	defer server.Close()
+	oldClient := client
+	defer func() { client = oldClient }()
+	client = kusto.NewClient(server.URL, kusto.Credentials{})
+
+	br := bufio.NewReader(strings.NewReader(testHeader))
+	d := &dbCreator{br: br}
+	d.Init()
+	if len(d.commands) != 3 || strings.Join(d.tables, ",") != "cpu,it's" {
+		t.Fatalf("incorrect header: commands %q, tables %q", d.commands, d.tables)
+	}
+	if metricsPerRow["cpu"] != 2 || metricsPerRow["it's"] != 1 {
+		t.Errorf("incorrect metrics per row: %v", metricsPerRow)
+	}
+	if rest, _ := br.ReadString('\n'); rest != "cpu,2016-01-01T00:00:00Z,host_0,1,2\n" {
+		t.Errorf("header not consumed: next line %q", rest)
+	}
+
+	if d.DBExists("benchmark") {
+		t.Errorf("database exists")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		": .show databases",
+		`benchmark: .drop tables (['cpu'], ['it\'s']) ifexists`,
+		": .create database ['benchmark'] volatile",
+		"benchmark: " + d.commands[0],
+		"benchmark: " + d.commands[1],
+		"benchmark: " + d.commands[2],
+		"benchmark: .alter table ['cpu'] policy streamingingestion enable",
+		`benchmark: .alter table ['it\'s'] policy streamingingestion enable`,
+	}
+	if got := strings.Join(cluster.commands, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("incorrect commands:\ngot\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	// the database cannot be created on most clusters
+	cluster.fail = ".create database ['benchmark'] volatile"
+	if err := d.CreateDB("benchmark"); err == nil || !strings.Contains(err.Error(), "created beforehand") {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
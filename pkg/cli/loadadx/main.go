@@ -0,0 +1,132 @@
+// Package loadadx implements tsbs_load_adx (also run as `tsbs load adx`),
+// which loads an Azure Data Explorer (Kusto) database with data from stdin.
+//
+// The tables and ingestion mappings are created with the control commands
+// in the header of the data, and each batch is ingested with streaming
+// ingestion or, with -ingestion=queued, queued for ingestion through the
+// storage of the cluster. Requests are authenticated with the credentials
+// in the environment (see kusto.CredentialsFromEnv), or not at all, as the
+// ADX emulator expects.
+//
+// If the tables exist beforehand, they will be *DROPPED*.
+package loadadx
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/adx"
+	"github.com/timescale/tsbs/pkg/kusto"
+)
+
+// Program option vars:
+var (
+	clusterURL string
+	ingestURL  string
+	ingestion  string
+	useJSON    bool
+	backoff    time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *kusto.Client
+	ingest ingestor
+)
+
+// ingestor ingests the rows of a table, as kusto.Client.StreamIngest and
+// kusto.QueuedIngestor.Ingest do
+type ingestor func(db, table, format, mapping string, data []byte) error
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_adx and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&clusterURL, "cluster", "http://localhost:8080", "URL of the Azure Data Explorer cluster.")
+	flag.StringVar(&ingestURL, "ingest-url", "", "URL of the data management endpoint of the cluster for queued ingestion (default: the cluster URL with its host prefixed by ingest-)")
+	flag.StringVar(&ingestion, "ingestion", kusto.Streaming, "How batches are ingested: streaming, which waits for them to be ingested, or queued, which returns once they are queued")
+	flag.BoolVar(&useJSON, "json", false, "Whether the data is in the "+adx.FormatJSON+" format rather than "+adx.Format)
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when the cluster throttles them.")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_adx", args); err != nil {
+		return err
+	}
+	if ingestion != kusto.Streaming && ingestion != kusto.Queued {
+		return cli.ConfigError(fmt.Errorf("invalid ingestion '%s': must be %s or %s", ingestion, kusto.Streaming, kusto.Queued))
+	}
+	if len(ingestURL) == 0 {
+		ingestURL = kusto.IngestURL(clusterURL)
+	}
+	return nil
+}
+
+// dataFormat returns the format of the data and of the rows ingested
+func dataFormat() (string, string) {
+	if useJSON {
+		return adx.FormatJSON, "json"
+	}
+	return adx.Format, "csv"
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br), csv: !useJSON}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	format, _ := dataFormat()
+	return format
+}
+
+// Run runs tsbs_load_adx with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	creds := kusto.CredentialsFromEnv()
+	if err := creds.Validate(); err != nil {
+		return cli.ConfigError(err)
+	}
+	client = kusto.NewClient(clusterURL, creds)
+	if ingestion == kusto.Queued {
+		ingest = kusto.NewQueuedIngestor(kusto.NewClient(ingestURL, creds)).Ingest
+	} else {
+		ingest = client.StreamIngest
+	}
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_adx")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
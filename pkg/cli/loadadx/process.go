@@ -0,0 +1,70 @@
+package loadadx
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/adx"
+	"github.com/timescale/tsbs/pkg/kusto"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	format    string
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	_, p.format = dataFormat()
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch ingests the rows of each table of the batch with one request
+// per table
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		for table, r := range batch.tables {
+			p.ingest(table, r.buf.Bytes())
+		}
+	}
+	return batch.metrics, batch.rows
+}
+
+// ingest ingests the rows of a table, sleeping for -backoff between attempts
+// as long as the cluster throttles them
+func (p *processor) ingest(table string, data []byte) {
+	for {
+		err := ingest(loader.DatabaseName(), table, p.format, adx.MappingName, data)
+		if err == nil {
+			return
+		}
+		e, ok := err.(*kusto.Error)
+		switch {
+		case !ok:
+			fatal(cli.ExitUnreachable, "could not ingest rows", "worker", p.workerNum, "table", table, "error", err)
+		case e.Throttled():
+			p.throttled++
+			logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+			sleep(backoff)
+			continue
+		case e.Status == http.StatusBadRequest:
+			fatal(cli.ExitData, "rows rejected", "worker", p.workerNum, "table", table, "error", err)
+		default:
+			fatal(cli.ExitFailure, "could not ingest rows", "worker", p.workerNum, "table", table, "error", err)
+		}
+		return
+	}
+}
@@ -0,0 +1,90 @@
+package loadadx
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/kusto"
+)
+
+func TestProcessBatch(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 10, "mem": 2}
+	oldIngest, oldSleep := ingest, sleep
+	defer func() { ingest, sleep = oldIngest, oldSleep }()
+	var ingested []string
+	throttled := false
+	ingest = func(db, table, format, mapping string, data []byte) error {
+		// the first request is throttled once
+		if !throttled {
+			throttled = true
+			return &kusto.Error{Status: http.StatusTooManyRequests}
+		}
+		ingested = append(ingested, strings.Join([]string{db, table, format, mapping, string(data)}, " "))
+		return nil
+	}
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d }
+
+	b := (&factory{}).New().(*batch)
+	for _, line := range []string{"cpu,2016-01-01T00:00:00Z,1", "mem,2016-01-01T00:00:00Z,2", "cpu,2016-01-01T00:00:10Z,3"} {
+		b.Append(load.NewPoint([]byte(line)))
+	}
+	p := &processor{}
+	p.Init(0, true)
+	metrics, rows := p.ProcessBatch(b, true)
+	if metrics != 22 || rows != 3 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	sort.Strings(ingested)
+	want := "benchmark cpu csv tsbs 2016-01-01T00:00:00Z,1\n2016-01-01T00:00:10Z,3\n|benchmark mem csv tsbs 2016-01-01T00:00:00Z,2\n"
+	if got := strings.Join(ingested, "|"); got != want {
+		t.Errorf("incorrect ingestion:\ngot  %q\nwant %q", got, want)
+	}
+	if p.throttled != 1 || slept != backoff {
+		t.Errorf("throttled request not retried after backoff: %d throttled, slept %v", p.throttled, slept)
+	}
+
+	// without loading, nothing is ingested
+	ingested = nil
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 22 || rows != 3 || ingested != nil {
+		t.Errorf("incorrect counts without loading: %d, %d, ingested %v", metrics, rows, ingested)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		err      error
+		wantCode int
+	}{
+		{desc: "rejected", err: &kusto.Error{Status: http.StatusBadRequest, Code: "BadRequest_InvalidMapping"}, wantCode: cli.ExitData},
+		{desc: "forbidden", err: &kusto.Error{Status: http.StatusForbidden}, wantCode: cli.ExitFailure},
+		{desc: "unreachable", err: errors.New("connection refused"), wantCode: cli.ExitUnreachable},
+	}
+	oldIngest, oldFatal := ingest, fatal
+	defer func() { ingest, fatal = oldIngest, oldFatal }()
+	for _, c := range cases {
+		calls := 0
+		ingest = func(db, table, format, mapping string, data []byte) error {
+			calls++
+			return c.err
+		}
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte("cpu,2016-01-01T00:00:00Z,1")))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		if gotCode != c.wantCode || calls != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, calls)
+		}
+	}
+}
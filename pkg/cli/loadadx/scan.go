@@ -0,0 +1,97 @@
+package loadadx
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/timescale/tsbs/load"
+)
+
+type decoder struct {
+	scanner *bufio.Scanner
+	// csv is whether rows are CSV, which may span lines
+	csv  bool
+	line []byte
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	// the header is only read by the DBCreator when loading
+	if metricsPerRow == nil {
+		metricsPerRow = make(map[string]uint64)
+		for d.scan() && len(d.scanner.Bytes()) > 0 {
+		}
+	}
+	if !d.scan() {
+		return nil
+	}
+	if !d.csv {
+		return load.NewPoint(d.scanner.Bytes())
+	}
+	// a quoted value with line breaks continues on the next lines, until
+	// its quotes are balanced
+	d.line = append(d.line[:0], d.scanner.Bytes()...)
+	for bytes.Count(d.line, quote)%2 == 1 {
+		if !d.scan() {
+			fatalData("parse error: unterminated quoted value: %s", d.line)
+			return nil
+		}
+		d.line = append(d.line, '\n')
+		d.line = append(d.line, d.scanner.Bytes()...)
+	}
+	return load.NewPoint(d.line)
+}
+
+// scan scans the next line, returning false at the end of the input
+func (d *decoder) scan() bool {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() != nil {
+		fatalData("scan error: %v", d.scanner.Err())
+	}
+	return ok
+}
+
+var quote = []byte{'"'}
+
+// rows are the rows of a table in a batch, one per line
+type rows struct {
+	buf   bytes.Buffer
+	count uint64
+}
+
+// batch holds the rows of a batch by table, as written by
+// tsbs_generate_data: the name of the table, a comma and the row
+type batch struct {
+	tables  map[string]*rows
+	rows    uint64
+	metrics uint64
+}
+
+func (b *batch) Len() int {
+	return int(b.rows)
+}
+
+func (b *batch) Append(item *load.Point) {
+	line := item.Data.([]byte)
+	i := bytes.IndexByte(line, ',')
+	if i <= 0 {
+		fatalData("parse error: line is not a table name and row: %s", line)
+		return
+	}
+	table := string(line[:i])
+	r, ok := b.tables[table]
+	if !ok {
+		r = &rows{}
+		b.tables[table] = r
+	}
+	r.buf.Write(line[i+1:])
+	r.buf.WriteByte('\n')
+	r.count++
+	b.rows++
+	b.metrics += metricsPerRow[table]
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{tables: make(map[string]*rows)}
+}
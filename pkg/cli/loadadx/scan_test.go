@@ -0,0 +1,93 @@
+package loadadx
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/load"
+)
+
+func TestDecoder(t *testing.T) {
+	cases := []struct {
+		desc  string
+		csv   bool
+		input string
+		want  []string
+	}{
+		{
+			desc:  "csv",
+			csv:   true,
+			input: "cpu,2016-01-01T00:00:00Z,host_0,1\nmem,2016-01-01T00:00:00Z,host_0,2\n",
+			want:  []string{"cpu,2016-01-01T00:00:00Z,host_0,1", "mem,2016-01-01T00:00:00Z,host_0,2"},
+		},
+		{
+			desc:  "csv with line breaks in quotes",
+			csv:   true,
+			input: "cpu,2016-01-01T00:00:00Z,\"host\n\"\"0\n\",1\ncpu,2016-01-01T00:00:00Z,host_1,1\n",
+			want:  []string{"cpu,2016-01-01T00:00:00Z,\"host\n\"\"0\n\",1", "cpu,2016-01-01T00:00:00Z,host_1,1"},
+		},
+		{
+			desc:  "json",
+			input: "cpu,{\"hostname\":\"\\\"host\"}\n",
+			want:  []string{"cpu,{\"hostname\":\"\\\"host\"}"},
+		},
+	}
+	metricsPerRow = map[string]uint64{}
+	for _, c := range cases {
+		d := &decoder{scanner: bufio.NewScanner(strings.NewReader(c.input)), csv: c.csv}
+		var got []string
+		for p := d.Decode(nil); p != nil; p = d.Decode(nil) {
+			got = append(got, string(p.Data.([]byte)))
+		}
+		if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", c.want) {
+			t.Errorf("%s: incorrect rows: got %q want %q", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDecoderSkipsHeader(t *testing.T) {
+	metricsPerRow = nil
+	input := ".create-merge table ['cpu'] (['timestamp']:datetime, ['usage_user']:real)\n\ncpu,2016-01-01T00:00:00Z,1\n"
+	d := &decoder{scanner: bufio.NewScanner(strings.NewReader(input)), csv: true}
+	p := d.Decode(nil)
+	if p == nil || string(p.Data.([]byte)) != "cpu,2016-01-01T00:00:00Z,1" || d.Decode(nil) != nil {
+		t.Errorf("header not skipped: got %v", p)
+	}
+}
+
+func TestDecoderUnterminated(t *testing.T) {
+	metricsPerRow = map[string]uint64{}
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = fmt.Sprintf(format, args...) }
+	d := &decoder{scanner: bufio.NewScanner(strings.NewReader("cpu,\"host_0\n")), csv: true}
+	if p := d.Decode(nil); p != nil || !strings.HasPrefix(got, "parse error: unterminated") {
+		t.Errorf("unterminated value not rejected: got %v, %q", p, got)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 10, "mem": 2}
+	b := (&factory{}).New().(*batch)
+	for _, line := range []string{"cpu,2016-01-01T00:00:00Z,1", "mem,2016-01-01T00:00:00Z,2", "cpu,2016-01-01T00:00:10Z,3"} {
+		b.Append(load.NewPoint([]byte(line)))
+	}
+	if b.Len() != 3 || b.metrics != 22 {
+		t.Errorf("incorrect batch: %d rows, %d metrics", b.Len(), b.metrics)
+	}
+	if got := b.tables["cpu"].buf.String(); got != "2016-01-01T00:00:00Z,1\n2016-01-01T00:00:10Z,3\n" || b.tables["cpu"].count != 2 {
+		t.Errorf("incorrect cpu rows: %q", got)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = fmt.Sprintf(format, args...) }
+	b.Append(load.NewPoint([]byte("no table")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 3 {
+		t.Errorf("line without table not rejected: %q", got)
+	}
+}
@@ -0,0 +1,125 @@
+package loadbigquery
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigquery"
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// metricsPerRow holds the number of metrics in each row of each table, as
+// read from the header
+var metricsPerRow map[string]uint64
+
+// column is a column of the schema of a table, as the BigQuery API takes it
+type column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// table is a table of the header and its schema
+type table struct {
+	name    string
+	columns []column
+}
+
+type dbCreator struct {
+	br     *bufio.Reader
+	tables []table
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the schemas of the tables at the start of the data,
+// up to an empty line
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	metricsPerRow = make(map[string]uint64)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		t, err := parseSchema(line)
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		d.tables = append(d.tables, t)
+		for _, c := range t.columns {
+			if c.Type != "STRING" && c.Type != "TIMESTAMP" {
+				metricsPerRow[t.name]++
+			}
+		}
+	}
+}
+
+// parseSchema parses a line of the header: the name of a table, a comma and
+// its columns in JSON
+func parseSchema(line string) (table, error) {
+	i := strings.IndexByte(line, ',')
+	if i <= 0 {
+		return table{}, fmt.Errorf("not a table name and schema: %s", line)
+	}
+	t := table{name: line[:i]}
+	if err := json.Unmarshal([]byte(line[i+1:]), &t.columns); err != nil || len(t.columns) == 0 {
+		return table{}, fmt.Errorf("invalid schema of table %s: %s", t.name, line[i+1:])
+	}
+	return t, nil
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	err := client.JSON(http.MethodGet, apiURL("/datasets/"+url.PathEscape(dbName)), nil, nil)
+	if e, ok := err.(*gcp.Error); ok && e.NotFound() {
+		return false
+	} else if err != nil {
+		fatal(cli.ExitUnreachable, "could not get dataset", "dataset", dbName, "error", err)
+	}
+	return true
+}
+
+// RemoveOldDB deletes the dataset along with its tables
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	return client.JSON(http.MethodDelete, apiURL("/datasets/"+url.PathEscape(dbName)+"?deleteContents=true"), nil, nil)
+}
+
+// CreateDB creates the dataset and the tables of the header, partitioned
+// and clustered as -partition and -clustering say
+func (d *dbCreator) CreateDB(dbName string) error {
+	dataset := map[string]interface{}{
+		"datasetReference": map[string]string{"projectId": project, "datasetId": dbName},
+		"location":         location,
+	}
+	if err := client.JSON(http.MethodPost, apiURL("/datasets"), dataset, nil); err != nil {
+		return err
+	}
+	for _, t := range d.tables {
+		in := map[string]interface{}{
+			"tableReference": map[string]string{"projectId": project, "datasetId": dbName, "tableId": t.name},
+			"schema":         map[string]interface{}{"fields": t.columns},
+		}
+		if partition != "none" {
+			in["timePartitioning"] = map[string]string{"type": strings.ToUpper(partition), "field": bigquery.TimeColumn}
+		}
+		if len(clustering) > 0 {
+			in["clustering"] = map[string][]string{"fields": strings.Split(clustering, ",")}
+		}
+		if err := client.JSON(http.MethodPost, apiURL("/datasets/"+url.PathEscape(dbName)+"/tables"), in, nil); err != nil {
+			return fmt.Errorf("could not create table %s: %v", t.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package loadbigquery
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+const testHeader = `cpu,[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},{"name":"usage_user","type":"FLOAT"},{"name":"usage_system","type":"INTEGER"}]
+mem,[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"used","type":"INTEGER"}]
+
+cpu,{"timestamp":"2016-01-01 00:00:00.000000","hostname":"host_0","usage_user":1,"usage_system":2}
+`
+
+// testAPI is a fake BigQuery API, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+	// handle, if set, answers requests instead of an empty JSON object
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.bodies = append(a.bodies, string(body))
+	status, resp := http.StatusOK, `{}`
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldEndpoint, oldFatal := client, endpoint, fatal
+	client = gcp.NewClient(gcp.Credentials{})
+	endpoint = server.URL
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	return func() {
+		server.Close()
+		client, endpoint, fatal = oldClient, oldEndpoint, oldFatal
+	}
+}
+
+func TestReadDataHeader(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader(testHeader))
+	d := &dbCreator{br: br}
+	d.Init()
+	if len(d.tables) != 2 || d.tables[0].name != "cpu" || len(d.tables[0].columns) != 4 || d.tables[1].name != "mem" {
+		t.Fatalf("incorrect tables: %v", d.tables)
+	}
+	if metricsPerRow["cpu"] != 2 || metricsPerRow["mem"] != 1 {
+		t.Errorf("incorrect metrics per row: %v", metricsPerRow)
+	}
+	if rest, _ := br.ReadString('\n'); !strings.HasPrefix(rest, "cpu,{") {
+		t.Errorf("header not consumed: next line %q", rest)
+	}
+
+	for _, line := range []string{"cpu", "cpu,{}", "cpu,[]", ",[]"} {
+		if _, err := parseSchema(line); err == nil {
+			t.Errorf("expected an error parsing %q", line)
+		}
+	}
+}
+
+func TestCreateDB(t *testing.T) {
+	api := &testAPI{handle: func(r *http.Request, _ []byte) (int, string) {
+		if r.Method == http.MethodGet {
+			return http.StatusNotFound, `{"error":{"code":404,"message":"Not found: Dataset project:benchmark","status":"NOT_FOUND"}}`
+		}
+		return http.StatusOK, `{}`
+	}}
+	defer useTestAPI(t, api)()
+	oldClustering := clustering
+	defer func() { clustering = oldClustering }()
+	clustering = "hostname"
+
+	d := &dbCreator{br: bufio.NewReader(strings.NewReader(testHeader))}
+	d.Init()
+	if d.DBExists("benchmark") {
+		t.Errorf("dataset not found exists")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"GET /bigquery/v2/projects/project/datasets/benchmark",
+		"DELETE /bigquery/v2/projects/project/datasets/benchmark?deleteContents=true",
+		"POST /bigquery/v2/projects/project/datasets",
+		"POST /bigquery/v2/projects/project/datasets/benchmark/tables",
+		"POST /bigquery/v2/projects/project/datasets/benchmark/tables",
+	}
+	if got := strings.Join(api.requests, "\n"); got != strings.Join(want, "\n") {
+		t.Fatalf("incorrect requests:\ngot\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	var table struct {
+		TableReference   struct{ TableID string }
+		Schema           struct{ Fields []column }
+		TimePartitioning struct {
+			Type  string
+			Field string
+		}
+		Clustering struct{ Fields []string }
+	}
+	if err := json.Unmarshal([]byte(api.bodies[3]), &table); err != nil {
+		t.Fatalf("invalid table: %v", err)
+	}
+	if table.TableReference.TableID != "cpu" || len(table.Schema.Fields) != 4 || table.TimePartitioning.Type != "DAY" ||
+		table.TimePartitioning.Field != "timestamp" || len(table.Clustering.Fields) != 1 {
+		t.Errorf("incorrect table: %s", api.bodies[3])
+	}
+}
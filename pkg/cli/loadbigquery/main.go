@@ -0,0 +1,143 @@
+// Package loadbigquery implements tsbs_load_bigquery (also run as `tsbs load
+// bigquery`), which loads a Google BigQuery dataset with data from stdin.
+//
+// The tables are created from the schemas in the header of the data,
+// partitioned by the time of their rows, and the rows of each table in a
+// batch are loaded with a load job or, with -method=stream, streamed with
+// insertAll requests. Requests are authenticated with the credentials in
+// the environment (see gcp.CredentialsFromEnv), or not at all, as emulators
+// expect.
+//
+// If the dataset exists beforehand, it will be *DELETED* along with its
+// tables.
+package loadbigquery
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigquery"
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// Methods of loading rows
+const (
+	// methodLoad loads the rows of each table in a batch with a load job,
+	// waiting for it to finish
+	methodLoad = "load"
+	// methodStream streams the rows with insertAll requests
+	methodStream = "stream"
+)
+
+// Program option vars:
+var (
+	project      string
+	location     string
+	endpoint     string
+	method       string
+	partition    string
+	clustering   string
+	pollInterval time.Duration
+	backoff      time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *gcp.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_bigquery and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&project, "project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "Google Cloud project of the dataset (default: $GOOGLE_CLOUD_PROJECT)")
+	flag.StringVar(&location, "location", "US", "Location of the dataset created, and of the load jobs")
+	flag.StringVar(&endpoint, "endpoint", "https://bigquery.googleapis.com", "URL of the BigQuery API, e.g., of an emulator")
+	flag.StringVar(&method, "method", methodLoad, "How batches are loaded: load, with a load job per table, or stream, with insertAll requests")
+	flag.StringVar(&partition, "partition", "day", "Time partitioning of the tables created by the timestamp of their rows: hour, day, month, year or none")
+	flag.StringVar(&clustering, "clustering", "", "Comma-separated columns to cluster the tables created by, e.g., hostname (default: none)")
+	flag.DurationVar(&pollInterval, "poll-interval", time.Second, "Time to sleep between checks of whether a load job is done")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when BigQuery throttles them")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_bigquery", args); err != nil {
+		return err
+	}
+	if len(project) == 0 {
+		return cli.ConfigError(fmt.Errorf("no project: set -project or GOOGLE_CLOUD_PROJECT"))
+	}
+	if method != methodLoad && method != methodStream {
+		return cli.ConfigError(fmt.Errorf("invalid method '%s': must be %s or %s", method, methodLoad, methodStream))
+	}
+	switch partition = strings.ToLower(partition); partition {
+	case "hour", "day", "month", "year", "none":
+	default:
+		return cli.ConfigError(fmt.Errorf("invalid partition '%s': must be hour, day, month, year or none", partition))
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	return nil
+}
+
+// apiURL returns the URL of a resource of the BigQuery API, given by a path
+// relative to the project
+func apiURL(path string) string {
+	return endpoint + "/bigquery/v2/projects/" + project + path
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return bigquery.Format
+}
+
+// Run runs tsbs_load_bigquery with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	creds := gcp.CredentialsFromEnv()
+	if err := creds.Load(); err != nil {
+		return cli.ConfigError(err)
+	}
+	client = gcp.NewClient(creds)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_bigquery")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
@@ -0,0 +1,204 @@
+package loadbigquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/gcp"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// maxRowsPerInsert is the most rows streamed with an insertAll request, as
+// BigQuery recommends
+const maxRowsPerInsert = 500
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	// jobPrefix starts the IDs of the load jobs of the worker, and jobs
+	// counts them, making the IDs unique
+	jobPrefix string
+	jobs      int
+	body      bytes.Buffer
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	p.jobPrefix = fmt.Sprintf("tsbs_%d_%d_", time.Now().UnixNano(), workerNum)
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch loads the rows of each table of the batch with a load job,
+// or streams them, as -method says
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		for table, r := range batch.tables {
+			if method == methodStream {
+				p.stream(table, r.buf.Bytes())
+			} else {
+				p.loadJob(table, r.buf.Bytes())
+			}
+		}
+	}
+	return batch.metrics, batch.rows
+}
+
+// job is the part of a load job the loader needs
+type job struct {
+	Status struct {
+		State       string
+		ErrorResult *struct {
+			Reason  string
+			Message string
+		}
+	}
+}
+
+// loadJob loads the rows of a table, one JSON object per line, with a load
+// job, waiting for it to finish
+func (p *processor) loadJob(table string, data []byte) {
+	p.jobs++
+	jobID := p.jobPrefix + strconv.Itoa(p.jobs)
+	body, contentType := p.loadJobRequest(jobID, table, data)
+	var j job
+	for {
+		err := client.Do(http.MethodPost, endpoint+"/upload/bigquery/v2/projects/"+project+"/jobs?uploadType=multipart", contentType, body, &j)
+		// a conflict means an earlier attempt created the job after all
+		if e, ok := err.(*gcp.Error); err == nil || ok && e.Status == http.StatusConflict {
+			break
+		}
+		if !p.retry(err, "could not start load job", table) {
+			return
+		}
+	}
+	for j.Status.State != "DONE" {
+		sleep(pollInterval)
+		err := client.JSON(http.MethodGet, apiURL("/jobs/"+jobID+"?location="+url.QueryEscape(location)), nil, &j)
+		if err != nil && !p.retry(err, "could not get load job", table) {
+			return
+		}
+	}
+	if e := j.Status.ErrorResult; e != nil {
+		code := cli.ExitFailure
+		if e.Reason == "invalid" {
+			code = cli.ExitData
+		}
+		fatal(code, "load job failed", "worker", p.workerNum, "table", table, "job", jobID, "reason", e.Reason, "error", e.Message)
+	}
+}
+
+// loadJobRequest returns the multipart body inserting a load job of the
+// rows of a table, along with its content type
+func (p *processor) loadJobRequest(jobID, table string, data []byte) ([]byte, string) {
+	config, _ := json.Marshal(map[string]interface{}{
+		"jobReference": map[string]string{"projectId": project, "jobId": jobID, "location": location},
+		"configuration": map[string]interface{}{
+			"load": map[string]interface{}{
+				"destinationTable": map[string]string{"projectId": project, "datasetId": loader.DatabaseName(), "tableId": table},
+				"sourceFormat":     "NEWLINE_DELIMITED_JSON",
+				"writeDisposition": "WRITE_APPEND",
+			},
+		},
+	})
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, _ := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	part.Write(config)
+	part, _ = w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}})
+	part.Write(data)
+	w.Close()
+	return body.Bytes(), "multipart/related; boundary=" + w.Boundary()
+}
+
+// stream streams the rows of a table, one JSON object per line, with an
+// insertAll request for each maxRowsPerInsert of them
+func (p *processor) stream(table string, data []byte) {
+	u := apiURL("/datasets/" + url.PathEscape(loader.DatabaseName()) + "/tables/" + url.PathEscape(table) + "/insertAll")
+	for len(data) > 0 {
+		p.body.Reset()
+		p.body.WriteString(`{"rows":[`)
+		for n := 0; n < maxRowsPerInsert && len(data) > 0; n++ {
+			end := bytes.IndexByte(data, '\n')
+			if end < 0 {
+				end = len(data)
+			}
+			if n > 0 {
+				p.body.WriteByte(',')
+			}
+			p.body.WriteString(`{"json":`)
+			p.body.Write(data[:end])
+			p.body.WriteByte('}')
+			if end < len(data) {
+				end++
+			}
+			data = data[end:]
+		}
+		p.body.WriteString("]}")
+
+		for {
+			var out struct {
+				InsertErrors []struct {
+					Errors []struct {
+						Reason  string
+						Message string
+					}
+				}
+			}
+			err := client.Do(http.MethodPost, u, "application/json", p.body.Bytes(), &out)
+			if err != nil {
+				if p.retry(err, "could not stream rows", table) {
+					continue
+				}
+				return
+			}
+			if len(out.InsertErrors) > 0 {
+				msg := ""
+				if errs := out.InsertErrors[0].Errors; len(errs) > 0 {
+					msg = errs[0].Message
+				}
+				fatal(cli.ExitData, "rows rejected", "worker", p.workerNum, "table", table, "count", len(out.InsertErrors), "error", msg)
+				return
+			}
+			break
+		}
+	}
+}
+
+// retry returns whether a failed request should be retried, sleeping for
+// -backoff first, which it is as long as BigQuery throttles it; otherwise it
+// exits with msg
+func (p *processor) retry(err error, msg, table string) bool {
+	e, ok := err.(*gcp.Error)
+	switch {
+	case !ok:
+		fatal(cli.ExitUnreachable, msg, "worker", p.workerNum, "table", table, "error", err)
+	case e.Throttled():
+		p.throttled++
+		logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+		sleep(backoff)
+		return true
+	case e.Status == http.StatusBadRequest:
+		fatal(cli.ExitData, msg, "worker", p.workerNum, "table", table, "error", err)
+	default:
+		fatal(cli.ExitFailure, msg, "worker", p.workerNum, "table", table, "error", err)
+	}
+	return false
+}
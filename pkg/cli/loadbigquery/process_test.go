@@ -0,0 +1,179 @@
+package loadbigquery
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+const testRow = `cpu,{"timestamp":"2016-01-01 00:00:00.000000","hostname":"host_0","usage_user":1,"usage_system":2}`
+
+// testBatch returns a batch of n rows of the cpu table and one of the mem
+// table
+func testBatch(n int) *batch {
+	b := (&factory{}).New().(*batch)
+	for i := 0; i < n; i++ {
+		b.Append(load.NewPoint([]byte(testRow)))
+	}
+	b.Append(load.NewPoint([]byte(`mem,{"timestamp":"2016-01-01 00:00:00.000000","used":3}`)))
+	return b
+}
+
+func TestBatchAppend(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 2, "mem": 1}
+	b := testBatch(2)
+	if b.Len() != 3 || b.metrics != 5 || b.tables["cpu"].count != 2 {
+		t.Errorf("incorrect batch: %d rows, %d metrics", b.Len(), b.metrics)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu,2016-01-01T00:00:00Z,host_0,1")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 3 {
+		t.Errorf("line in another format not rejected: %q", got)
+	}
+}
+
+func TestProcessBatchLoadJobs(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 2, "mem": 1}
+	var loaded []string
+	polls := 0
+	throttled := false
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		if r.Method == http.MethodGet {
+			// each job is running when first checked
+			polls++
+			if polls%2 == 1 {
+				return http.StatusOK, `{"status":{"state":"RUNNING"}}`
+			}
+			return http.StatusOK, `{"status":{"state":"DONE"}}`
+		}
+		// the first request is throttled once
+		if !throttled {
+			throttled = true
+			return http.StatusForbidden, `{"error":{"code":403,"message":"Exceeded rate limits","errors":[{"reason":"rateLimitExceeded"}]}}`
+		}
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		mr := multipart.NewReader(strings.NewReader(string(body)), params["boundary"])
+		var config struct {
+			Configuration struct {
+				Load struct {
+					DestinationTable struct{ DatasetID, TableID string }
+					SourceFormat     string
+				}
+			}
+		}
+		part, err := mr.NextPart()
+		if err != nil || json.NewDecoder(part).Decode(&config) != nil {
+			return http.StatusBadRequest, `{"error":{"code":400,"message":"invalid job"}}`
+		}
+		part, err = mr.NextPart()
+		if err != nil {
+			return http.StatusBadRequest, `{"error":{"code":400,"message":"no data"}}`
+		}
+		data, _ := ioutil.ReadAll(part)
+		l := config.Configuration.Load
+		loaded = append(loaded, l.DestinationTable.DatasetID+"."+l.DestinationTable.TableID+" "+l.SourceFormat+" "+string(data))
+		return http.StatusOK, `{"status":{"state":"PENDING"}}`
+	}}
+	defer useTestAPI(t, api)()
+	oldSleep := sleep
+	defer func() { sleep = oldSleep }()
+	var slept time.Duration
+	sleep = func(d time.Duration) { slept += d }
+
+	p := &processor{}
+	p.Init(0, true)
+	metrics, rows := p.ProcessBatch(testBatch(2), true)
+	if metrics != 5 || rows != 3 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	row := testRow[len("cpu,"):]
+	if len(loaded) != 2 {
+		t.Fatalf("incorrect number of load jobs: %q", loaded)
+	}
+	for _, l := range loaded {
+		if l != "benchmark.cpu NEWLINE_DELIMITED_JSON "+row+"\n"+row+"\n" && !strings.HasPrefix(l, "benchmark.mem ") {
+			t.Errorf("incorrect load job: %q", l)
+		}
+	}
+	if p.throttled != 1 || polls != 4 || slept != backoff+4*pollInterval {
+		t.Errorf("incorrect waits: %d throttled, %d polls, slept %v", p.throttled, polls, slept)
+	}
+}
+
+func TestProcessBatchStream(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 2, "mem": 1}
+	var sizes []int
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		var in struct {
+			Rows []struct {
+				JSON map[string]interface{}
+			}
+		}
+		if err := json.Unmarshal(body, &in); err != nil || len(in.Rows) == 0 || in.Rows[0].JSON["timestamp"] == nil {
+			return http.StatusBadRequest, `{"error":{"code":400,"message":"invalid rows"}}`
+		}
+		if strings.HasSuffix(r.URL.Path, "/tables/cpu/insertAll") {
+			sizes = append(sizes, len(in.Rows))
+		}
+		return http.StatusOK, `{"kind":"bigquery#tableDataInsertAllResponse"}`
+	}}
+	defer useTestAPI(t, api)()
+	oldMethod := method
+	defer func() { method = oldMethod }()
+	method = methodStream
+
+	p := &processor{}
+	p.Init(0, true)
+	if metrics, rows := p.ProcessBatch(testBatch(1200), true); metrics != 2401 || rows != 1201 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	if len(sizes) != 3 || sizes[0] != 500 || sizes[1] != 500 || sizes[2] != 200 {
+		t.Errorf("incorrect requests: got sizes %v", sizes)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		method   string
+		status   int
+		resp     string
+		wantCode int
+	}{
+		{desc: "invalid rows", method: methodStream, status: http.StatusOK, resp: `{"insertErrors":[{"index":0,"errors":[{"reason":"invalid","message":"no such field"}]}]}`, wantCode: cli.ExitData},
+		{desc: "bad request", method: methodStream, status: http.StatusBadRequest, resp: `{"error":{"code":400,"message":"bad"}}`, wantCode: cli.ExitData},
+		{desc: "forbidden", method: methodLoad, status: http.StatusForbidden, resp: `{"error":{"code":403,"message":"Access denied","errors":[{"reason":"accessDenied"}]}}`, wantCode: cli.ExitFailure},
+		{desc: "failed job", method: methodLoad, status: http.StatusOK, resp: `{"status":{"state":"DONE","errorResult":{"reason":"invalid","message":"bad row"}}}`, wantCode: cli.ExitData},
+	}
+	oldMethod := method
+	defer func() { method = oldMethod }()
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) { return c.status, c.resp }}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+		method = c.method
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testRow)))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
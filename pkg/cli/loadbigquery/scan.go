@@ -0,0 +1,78 @@
+package loadbigquery
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/timescale/tsbs/load"
+)
+
+type decoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	// the header is only read by the DBCreator when loading
+	if metricsPerRow == nil {
+		metricsPerRow = make(map[string]uint64)
+		for d.scan() && len(d.scanner.Bytes()) > 0 {
+		}
+	}
+	if !d.scan() {
+		return nil
+	}
+	return load.NewPoint(d.scanner.Bytes())
+}
+
+// scan scans the next line, returning false at the end of the input
+func (d *decoder) scan() bool {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() != nil {
+		fatalData("scan error: %v", d.scanner.Err())
+	}
+	return ok
+}
+
+// rows are the JSON rows of a table in a batch, one per line
+type rows struct {
+	buf   bytes.Buffer
+	count int
+}
+
+// batch holds the rows of a batch by table, as written by
+// tsbs_generate_data: the name of the table, a comma and the row
+type batch struct {
+	tables  map[string]*rows
+	rows    uint64
+	metrics uint64
+}
+
+func (b *batch) Len() int {
+	return int(b.rows)
+}
+
+func (b *batch) Append(item *load.Point) {
+	line := item.Data.([]byte)
+	i := bytes.IndexByte(line, ',')
+	if i <= 0 || i+1 == len(line) || line[i+1] != '{' {
+		fatalData("parse error: line is not a table name and JSON row: %s", line)
+		return
+	}
+	table := string(line[:i])
+	r, ok := b.tables[table]
+	if !ok {
+		r = &rows{}
+		b.tables[table] = r
+	}
+	r.buf.Write(line[i+1:])
+	r.buf.WriteByte('\n')
+	r.count++
+	b.rows++
+	b.metrics += metricsPerRow[table]
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{tables: make(map[string]*rows)}
+}
@@ -0,0 +1,44 @@
+package loadbigtable
+
+import (
+	"net/http"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigtable"
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+type dbCreator struct{}
+
+func (d *dbCreator) Init() {}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	err := client.JSON(http.MethodGet, adminEndpoint+"/v2/"+tablePath(dbName)+"?view=NAME_ONLY", nil, nil)
+	if e, ok := err.(*gcp.Error); ok && e.NotFound() {
+		return false
+	} else if err != nil {
+		fatal(cli.ExitUnreachable, "could not get table", "table", dbName, "error", err)
+	}
+	return true
+}
+
+// RemoveOldDB deletes the table
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	return client.JSON(http.MethodDelete, adminEndpoint+"/v2/"+tablePath(dbName), nil, nil)
+}
+
+// CreateDB creates the table with its two column families, which keep only
+// the latest version of each cell
+func (d *dbCreator) CreateDB(dbName string) error {
+	family := map[string]interface{}{"gcRule": map[string]int{"maxNumVersions": 1}}
+	in := map[string]interface{}{
+		"tableId": dbName,
+		"table": map[string]interface{}{
+			"columnFamilies": map[string]interface{}{
+				bigtable.FamilyFields: family,
+				bigtable.FamilyTags:   family,
+			},
+		},
+	}
+	return client.JSON(http.MethodPost, adminEndpoint+"/v2/projects/"+project+"/instances/"+instance+"/tables", in, nil)
+}
@@ -0,0 +1,119 @@
+// Package loadbigtable implements tsbs_load_bigtable (also run as `tsbs load
+// bigtable`), which loads a Google Cloud Bigtable table with data from stdin.
+//
+// The rows of a batch are written with MutateRows requests of up to 100,000
+// mutations, the most Bigtable accepts, and the rows Bigtable fails to write
+// for lack of capacity are retried. The table is named by -db-name and has
+// a column family for the fields of the rows and one for their tags.
+// Requests are authenticated with the credentials in the environment (see
+// gcp.CredentialsFromEnv).
+//
+// If the table exists beforehand, it will be *DELETED*.
+package loadbigtable
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigtable"
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// Program option vars:
+var (
+	project       string
+	instance      string
+	endpoint      string
+	adminEndpoint string
+	backoff       time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *gcp.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_bigtable and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&project, "project", os.Getenv("GOOGLE_CLOUD_PROJECT"), "Google Cloud project of the instance (default: $GOOGLE_CLOUD_PROJECT)")
+	flag.StringVar(&instance, "instance", "tsbs", "Bigtable instance to create the table in")
+	flag.StringVar(&endpoint, "endpoint", "https://bigtable.googleapis.com", "URL of the Bigtable data API")
+	flag.StringVar(&adminEndpoint, "admin-endpoint", "https://bigtableadmin.googleapis.com", "URL of the Bigtable admin API, which creates the table")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep before retrying rows Bigtable could not write for lack of capacity")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_bigtable", args); err != nil {
+		return err
+	}
+	if len(project) == 0 {
+		return cli.ConfigError(fmt.Errorf("no project: set -project or GOOGLE_CLOUD_PROJECT"))
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	adminEndpoint = strings.TrimSuffix(adminEndpoint, "/")
+	return nil
+}
+
+// tablePath returns the resource name of the table named name
+func tablePath(name string) string {
+	return "projects/" + project + "/instances/" + instance + "/tables/" + name
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return bigtable.Format
+}
+
+// Run runs tsbs_load_bigtable with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	creds := gcp.CredentialsFromEnv()
+	if err := creds.Load(); err != nil {
+		return cli.ConfigError(err)
+	}
+	client = gcp.NewClient(creds)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_bigtable")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
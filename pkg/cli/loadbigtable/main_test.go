@@ -0,0 +1,16 @@
+package loadbigtable
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does,
+// for the project named project
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Setenv("GOOGLE_CLOUD_PROJECT", "project")
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,167 @@
+package loadbigtable
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/gcp"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// maxMutationsPerRequest is the most mutations a MutateRows request takes
+const maxMutationsPerRequest = 100000
+
+// Status codes of the rows of a MutateRows response
+const (
+	codeOK                = 0
+	codeInvalidArgument   = 3
+	codeDeadlineExceeded  = 4
+	codeResourceExhausted = 8
+	codeAborted           = 10
+	codeUnavailable       = 14
+)
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	body      bytes.Buffer
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch writes the rows of the batch with as few MutateRows requests
+// as their mutations allow
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		start, mutations := 0, 0
+		for i, n := range batch.mutations {
+			if mutations+n > maxMutationsPerRequest && i > start {
+				p.mutateRows(batch.rows[start:i])
+				start, mutations = i, 0
+			}
+			mutations += n
+		}
+		if start < len(batch.rows) {
+			p.mutateRows(batch.rows[start:])
+		}
+	}
+	return batch.metrics, uint64(len(batch.rows))
+}
+
+// entryStatus is the status of a row of a MutateRows request
+type entryStatus struct {
+	Index  json.Number
+	Status struct {
+		Code    int
+		Message string
+	}
+}
+
+// mutateRows writes rows with a MutateRows request, retrying the rows which
+// failed for lack of capacity, after sleeping for -backoff, until all are
+// written
+func (p *processor) mutateRows(rows [][]byte) {
+	u := endpoint + "/v2/" + tablePath(loader.DatabaseName()) + ":mutateRows"
+	for len(rows) > 0 {
+		p.body.Reset()
+		p.body.WriteString(`{"entries":[`)
+		for i, r := range rows {
+			if i > 0 {
+				p.body.WriteByte(',')
+			}
+			p.body.Write(r)
+		}
+		p.body.WriteString("]}")
+
+		var resp json.RawMessage
+		err := client.Do(http.MethodPost, u, "application/json", p.body.Bytes(), &resp)
+		if err != nil {
+			e, ok := err.(*gcp.Error)
+			switch {
+			case !ok:
+				fatal(cli.ExitUnreachable, "could not write rows", "worker", p.workerNum, "error", err)
+			case e.Throttled():
+				p.throttled++
+				logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+				sleep(backoff)
+				continue
+			case e.Status == http.StatusBadRequest:
+				fatal(cli.ExitData, "rows rejected", "worker", p.workerNum, "error", err)
+			default:
+				fatal(cli.ExitFailure, "could not write rows", "worker", p.workerNum, "error", err)
+			}
+			return
+		}
+
+		statuses, err := parseStatuses(resp)
+		if err != nil {
+			fatal(cli.ExitFailure, "invalid response to MutateRows", "worker", p.workerNum, "error", err)
+			return
+		}
+		var retry [][]byte
+		for _, s := range statuses {
+			i, err := strconv.Atoi(s.Index.String())
+			if err != nil || i < 0 || i >= len(rows) {
+				fatal(cli.ExitFailure, "invalid response to MutateRows", "worker", p.workerNum, "index", s.Index)
+				return
+			}
+			switch s.Status.Code {
+			case codeOK:
+			case codeDeadlineExceeded, codeResourceExhausted, codeAborted, codeUnavailable:
+				retry = append(retry, rows[i])
+			case codeInvalidArgument:
+				fatal(cli.ExitData, "row rejected", "worker", p.workerNum, "row", string(rows[i]), "error", s.Status.Message)
+				return
+			default:
+				fatal(cli.ExitFailure, "could not write row", "worker", p.workerNum, "code", s.Status.Code, "error", s.Status.Message)
+				return
+			}
+		}
+		if len(retry) > 0 {
+			p.throttled++
+			logging.Debug("rows throttled", "worker", p.workerNum, "count", len(retry))
+			sleep(backoff)
+		}
+		rows = retry
+	}
+}
+
+// parseStatuses returns the statuses of the rows in a MutateRows response,
+// which is a stream of messages, so a JSON array of them over REST
+func parseStatuses(resp json.RawMessage) ([]entryStatus, error) {
+	type message struct {
+		Entries []entryStatus
+	}
+	var messages []message
+	if len(resp) > 0 && resp[0] == '{' {
+		var m message
+		if err := json.Unmarshal(resp, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	} else if err := json.Unmarshal(resp, &messages); err != nil {
+		return nil, err
+	}
+	var statuses []entryStatus
+	for _, m := range messages {
+		statuses = append(statuses, m.Entries...)
+	}
+	return statuses, nil
+}
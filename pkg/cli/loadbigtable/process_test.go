@@ -0,0 +1,227 @@
+package loadbigtable
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// testRow is a row of cpu#host_0#7771765636854775807 with two fields and a
+// tag
+const testRow = `{"rowKey":"Y3B1I2hvc3RfMCM3NzcxNzY1NjM2ODU0Nzc1ODA3","mutations":[` +
+	`{"setCell":{"familyName":"fields","columnQualifier":"dXNhZ2VfdXNlcg==","timestampMicros":"1451606400000000","value":"AAAAAAAAADo="}},` +
+	`{"setCell":{"familyName":"fields","columnQualifier":"dXNhZ2Vfc3lzdGVt","timestampMicros":"1451606400000000","value":"AAAAAAAAAAI="}},` +
+	`{"setCell":{"familyName":"tags","columnQualifier":"aG9zdG5hbWU=","timestampMicros":"1451606400000000","value":"aG9zdF8w"}}]}`
+
+// testAPI is a fake Bigtable API, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	// handle, if set, answers requests instead of an empty JSON object
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	status, resp := http.StatusOK, `{}`
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldEndpoint, oldAdmin, oldFatal, oldSleep := client, endpoint, adminEndpoint, fatal, sleep
+	client = gcp.NewClient(gcp.Credentials{})
+	endpoint, adminEndpoint = server.URL, server.URL+"/admin"
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	sleep = func(time.Duration) {}
+	return func() {
+		server.Close()
+		client, endpoint, adminEndpoint, fatal, sleep = oldClient, oldEndpoint, oldAdmin, oldFatal, oldSleep
+	}
+}
+
+// mutateRowsResponse returns the response to a MutateRows request of n
+// rows, in which the rows at the indexes in codes have those status codes
+func mutateRowsResponse(n int, codes map[int]int) string {
+	var entries []string
+	for i := 0; i < n; i++ {
+		entries = append(entries, fmt.Sprintf(`{"index":"%d","status":{"code":%d}}`, i, codes[i]))
+	}
+	return `[{"entries":[` + strings.Join(entries, ",") + `]}]`
+}
+
+func TestBatchAppend(t *testing.T) {
+	b := (&factory{}).New().(*batch)
+	b.Append(load.NewPoint([]byte(testRow)))
+	b.Append(load.NewPoint([]byte(testRow)))
+	if b.Len() != 2 || b.metrics != 4 || b.mutations[0] != 3 {
+		t.Errorf("incorrect batch: %d rows, %d metrics, mutations %v", b.Len(), b.metrics, b.mutations)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu,hostname=host_0 usage_user=58i 1451606400000000000")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 2 {
+		t.Errorf("line in another format not rejected: %q", got)
+	}
+}
+
+func TestCreateDB(t *testing.T) {
+	var created string
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		switch r.Method {
+		case http.MethodGet:
+			return http.StatusNotFound, `{"error":{"code":404,"message":"table not found","status":"NOT_FOUND"}}`
+		case http.MethodPost:
+			created = string(body)
+		}
+		return http.StatusOK, `{}`
+	}}
+	defer useTestAPI(t, api)()
+	oldProject := project
+	defer func() { project = oldProject }()
+	project = "project"
+
+	d := &dbCreator{}
+	if d.DBExists("benchmark") {
+		t.Errorf("table not found exists")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "GET /admin/v2/projects/project/instances/tsbs/tables/benchmark?view=NAME_ONLY\n" +
+		"DELETE /admin/v2/projects/project/instances/tsbs/tables/benchmark\n" +
+		"POST /admin/v2/projects/project/instances/tsbs/tables"
+	if got := strings.Join(api.requests, "\n"); got != want {
+		t.Errorf("incorrect requests:\ngot\n%s\nwant\n%s", got, want)
+	}
+	var in struct {
+		TableID string
+		Table   struct {
+			ColumnFamilies map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(created), &in); err != nil || in.TableID != "benchmark" || len(in.Table.ColumnFamilies) != 2 {
+		t.Errorf("incorrect table created: %s", created)
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	var sizes []int
+	calls := 0
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		var in struct {
+			Entries []json.RawMessage
+		}
+		if err := json.Unmarshal(body, &in); err != nil || !strings.HasSuffix(r.URL.Path, "/tables/benchmark:mutateRows") {
+			return http.StatusBadRequest, `{"error":{"code":400,"message":"invalid request"}}`
+		}
+		calls++
+		sizes = append(sizes, len(in.Entries))
+		switch calls {
+		case 1:
+			// the first request is throttled
+			return http.StatusTooManyRequests, `{"error":{"code":429,"message":"slow down","status":"RESOURCE_EXHAUSTED"}}`
+		case 2:
+			// and then two of its rows are not written
+			return http.StatusOK, mutateRowsResponse(len(in.Entries), map[int]int{1: codeUnavailable, 5: codeResourceExhausted})
+		}
+		return http.StatusOK, mutateRowsResponse(len(in.Entries), nil)
+	}}
+	defer useTestAPI(t, api)()
+	oldProject := project
+	defer func() { project = oldProject }()
+	project = "project"
+
+	// each row has 3 mutations, so 33,333 fit a request
+	b := (&factory{}).New().(*batch)
+	for i := 0; i < 40000; i++ {
+		b.Append(load.NewPoint([]byte(testRow)))
+	}
+	p := &processor{}
+	p.Init(0, true)
+	metrics, rows := p.ProcessBatch(b, true)
+	if metrics != 80000 || rows != 40000 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	if fmt.Sprint(sizes) != "[33333 33333 2 6667]" {
+		t.Errorf("incorrect requests: got sizes %v", sizes)
+	}
+	if p.throttled != 2 {
+		t.Errorf("incorrect number throttled: got %d", p.throttled)
+	}
+
+	// without loading, nothing is written
+	sizes = nil
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 80000 || rows != 40000 || sizes != nil {
+		t.Errorf("incorrect counts without loading: %d, %d, requests %v", metrics, rows, sizes)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		resp     string
+		wantCode int
+	}{
+		{desc: "row rejected", status: http.StatusOK, resp: mutateRowsResponse(1, map[int]int{0: codeInvalidArgument}), wantCode: cli.ExitData},
+		{desc: "row failed", status: http.StatusOK, resp: mutateRowsResponse(1, map[int]int{0: 13}), wantCode: cli.ExitFailure},
+		{desc: "bad request", status: http.StatusBadRequest, resp: `{"error":{"code":400,"message":"bad"}}`, wantCode: cli.ExitData},
+		{desc: "forbidden", status: http.StatusForbidden, resp: `{"error":{"code":403,"message":"denied","status":"PERMISSION_DENIED"}}`, wantCode: cli.ExitFailure},
+		{desc: "invalid response", status: http.StatusOK, resp: `[{"entries":[{"index":"7","status":{}}]}]`, wantCode: cli.ExitFailure},
+	}
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) { return c.status, c.resp }}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testRow)))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
+
+func TestParseStatuses(t *testing.T) {
+	for _, resp := range []string{
+		`[{"entries":[{"index":"0","status":{}}]},{"entries":[{"index":"1","status":{"code":14}}]}]`,
+		`{"entries":[{"index":0,"status":{}},{"index":1,"status":{"code":14}}]}`,
+	} {
+		statuses, err := parseStatuses(json.RawMessage(resp))
+		if err != nil || len(statuses) != 2 || statuses[1].Index.String() != "1" || statuses[1].Status.Code != codeUnavailable {
+			t.Errorf("incorrect statuses of %s: %v, %v", resp, statuses, err)
+		}
+	}
+}
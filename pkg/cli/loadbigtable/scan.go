@@ -0,0 +1,59 @@
+package loadbigtable
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/timescale/tsbs/load"
+)
+
+// setCell appears once per cell of a row, and fieldsFamily once per field,
+// so counting them counts the mutations and metrics of the row
+var (
+	setCell      = []byte(`"setCell":`)
+	fieldsFamily = []byte(`"familyName":"fields"`)
+)
+
+type decoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
+		return nil
+	} else if !ok {
+		fatalData("scan error: %v", d.scanner.Err())
+		return nil
+	}
+	return load.NewPoint(d.scanner.Bytes())
+}
+
+// batch holds the rows of a batch, one MutateRows entry per line as written
+// by tsbs_generate_data, and the number of mutations of each
+type batch struct {
+	rows      [][]byte
+	mutations []int
+	metrics   uint64
+}
+
+func (b *batch) Len() int {
+	return len(b.rows)
+}
+
+func (b *batch) Append(item *load.Point) {
+	line := item.Data.([]byte)
+	if !bytes.HasPrefix(line, []byte(`{"rowKey":`)) {
+		fatalData("parse error: line is not a Bigtable row: %s", line)
+		return
+	}
+	b.metrics += uint64(bytes.Count(line, fieldsFamily))
+	b.mutations = append(b.mutations, bytes.Count(line, setCell))
+	b.rows = append(b.rows, append([]byte(nil), line...))
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{}
+}
@@ -1,12 +1,12 @@
-package main
+package loadcassandra
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/timescale/tsbs/pkg/cli"
 )
 
 type dbCreator struct {
@@ -21,7 +21,7 @@ func (d *dbCreator) Init() {
 	cluster.Timeout = 10 * time.Second
 	session, err := cluster.CreateSession()
 	if err != nil {
-		log.Fatal(err)
+		cli.Fatal(cli.ExitUnreachable, "could not connect to Cassandra", "error", err)
 	}
 	d.globalSession = session
 }
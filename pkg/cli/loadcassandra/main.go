@@ -0,0 +1,142 @@
+// Package loadcassandra implements tsbs_load_cassandra (also run as
+// `tsbs load cassandra`), which loads a Cassandra daemon with data from stdin.
+//
+// The caller is responsible for assuring that the database is empty before
+// bulk load.
+package loadcassandra
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/cassandra"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/provision"
+)
+
+// Program option vars:
+var (
+	hosts             string
+	replicationFactor int
+	consistencyLevel  string
+	writeTimeout      time.Duration
+	container         *provision.Options
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+)
+
+// Map of user specified strings to gocql consistency settings
+var consistencyMapping = map[string]gocql.Consistency{
+	"ALL":    gocql.All,
+	"ANY":    gocql.Any,
+	"QUORUM": gocql.Quorum,
+	"ONE":    gocql.One,
+	"TWO":    gocql.Two,
+	"THREE":  gocql.Three,
+}
+
+// parseFlags registers the command line flags of tsbs_load_cassandra and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunnerWithBatchSize(100)
+
+	flag.StringVar(&hosts, "hosts", "localhost:9042", "Comma separated list of Cassandra hosts in a cluster.")
+
+	flag.IntVar(&replicationFactor, "replication-factor", 1, "Number of nodes that must have a copy of each key.")
+	flag.StringVar(&consistencyLevel, "consistency", "ALL", "Desired write consistency level. See Cassandra consistency documentation. Default: ALL")
+	flag.DurationVar(&writeTimeout, "write-timeout", 10*time.Second, "Write timeout.")
+
+	container = provision.AddFlags(flag.CommandLine, "cassandra")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_cassandra", args); err != nil {
+		return err
+	}
+
+	if _, ok := consistencyMapping[consistencyLevel]; !ok {
+		return cli.ConfigError(fmt.Errorf("invalid consistency level: '%s'", consistencyLevel))
+	}
+
+	return nil
+}
+
+type benchmark struct {
+	dbc *dbCreator
+}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{b.dbc}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return b.dbc
+}
+
+func (b *benchmark) DataFormat() string {
+	return cassandra.Format
+}
+
+// Run runs tsbs_load_cassandra with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "cassandra", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		hosts = c.Endpoint()
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_cassandra")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{dbc: &dbCreator{}}, load.SingleQueue)))
+}
+
+type processor struct {
+	dbc *dbCreator
+}
+
+func (p *processor) Init(_ int, _ bool) {}
+
+// ProcessBatch reads eventsBatches which contain rows of CQL strings and
+// creates a gocql.LoggedBatch to insert
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	events := b.(*eventsBatch)
+
+	if doLoad {
+		batch := p.dbc.clientSession.NewBatch(gocql.LoggedBatch)
+		for _, event := range events.rows {
+			batch.Query(singleMetricToInsertStatement(event))
+		}
+
+		err := p.dbc.clientSession.ExecuteBatch(batch)
+		if err != nil {
+			logging.Fatal("could not write batch", "error", err)
+		}
+	}
+	metricCnt := uint64(len(events.rows))
+	events.rows = events.rows[:0]
+	ePool.Put(events)
+	return metricCnt, 0
+}
@@ -1,13 +1,13 @@
-package main
+package loadcassandra
 
 import (
 	"bufio"
 	"fmt"
-	"log"
 	"strings"
 	"sync"
 
 	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
 )
 
 type decoder struct {
@@ -22,7 +22,7 @@ func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
 	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
 		return nil
 	} else if !ok {
-		log.Fatalf("scan error: %v", d.scanner.Err())
+		cli.Fatal(cli.ExitData, "could not scan input", "error", d.scanner.Err())
 	}
 
 	return load.NewPoint(d.scanner.Text())
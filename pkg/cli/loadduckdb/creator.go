@@ -0,0 +1,134 @@
+package loadduckdb
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// column is a field column of a table, as given by the header
+type column struct {
+	name string
+	typ  string
+}
+
+// sqlTypes are the types of the field columns the header may give, all of
+// which DuckDB has, TEXT as an alias of VARCHAR
+var sqlTypes = map[string]bool{"DOUBLE": true, "BIGINT": true, "BOOLEAN": true, "TEXT": true}
+
+type dbCreator struct {
+	br *bufio.Reader
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the header at the start of the data, up to an empty
+// line: the tag keys, as the tags line, followed by each measurement and the
+// definitions of its field columns
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	tableCols = make(map[string][]column)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		fatalData("input has wrong header format: %v", err)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+	if parts[0] != tagsPrefix || len(parts) < 2 {
+		fatalData("input has wrong header format: got '%s', expected the tags", parts[0])
+		return
+	}
+	tagCols = make([]string, len(parts)-1)
+	for i, p := range parts[1:] {
+		tagCols[i] = mysql.Unescape(p)
+	}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			break
+		}
+		parts = strings.Split(line, "\t")
+		cols := make([]column, len(parts)-1)
+		for i, p := range parts[1:] {
+			j := strings.LastIndexByte(p, ' ')
+			if j < 0 || !sqlTypes[p[j+1:]] {
+				fatalData("input has wrong header format: invalid column definition '%s'", p)
+				return
+			}
+			cols[i] = column{name: mysql.Unescape(p[:j]), typ: p[j+1:]}
+		}
+		tableCols[mysql.Unescape(parts[0])] = cols
+	}
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	_, err := os.Stat(dbPath(dbName))
+	return err == nil
+}
+
+// RemoveOldDB deletes the database file, and its write-ahead log
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	for _, suffix := range []string{"", ".wal"} {
+		if err := os.Remove(dbPath(dbName) + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateDB creates the database file, with the table of each measurement
+func (d *dbCreator) CreateDB(dbName string) error {
+	db := sql.OpenDB(mustOpen(dbName))
+	defer db.Close()
+	for table, cols := range tableCols {
+		if _, err := db.Exec(createTable(table, cols)); err != nil {
+			return fmt.Errorf("could not create table %s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// PostCreateDB opens the database for the workers, whether or not it was
+// created by this process
+func (d *dbCreator) PostCreateDB(dbName string) error {
+	connector = mustOpen(dbName)
+	return nil
+}
+
+// Close closes the database once the workers are done, which checkpoints
+// its write-ahead log into the database file
+func (d *dbCreator) Close() {
+	if connector != nil {
+		connector.Close()
+	}
+}
+
+// createTable returns the statement creating the table of a measurement,
+// with the given field columns. It has no indexes: DuckDB skips the row
+// groups a filter on the time or on a tag rules out with the min-max
+// statistics it keeps of every column, and indexes would slow the load.
+func createTable(table string, cols []column) string {
+	defs := []string{`"time" TIMESTAMP NOT NULL`}
+	for _, t := range tagCols {
+		defs = append(defs, quote(t)+" VARCHAR")
+	}
+	for _, c := range cols {
+		defs = append(defs, quote(c.name)+" "+c.typ)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", quote(table), strings.Join(defs, ", "))
+}
+
+// quote returns name quoted as a DuckDB identifier
+func quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
@@ -0,0 +1,64 @@
+package loadduckdb
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDBCreatorReadDataHeader(t *testing.T) {
+	cases := []struct {
+		desc        string
+		input       string
+		wantTags    []string
+		wantTables  map[string][]column
+		shouldFatal bool
+	}{
+		{
+			desc:       "two tables",
+			input:      "tags\ttag1\ttag2\ncols\tcol1 DOUBLE\tcol2 BIGINT\ncols2\tcol 21 TEXT\n\n",
+			wantTags:   []string{"tag1", "tag2"},
+			wantTables: map[string][]column{"cols": {{"col1", "DOUBLE"}, {"col2", "BIGINT"}}, "cols2": {{"col 21", "TEXT"}}},
+		},
+		{
+			desc:        "invalid type",
+			input:       "tags\ttag1\ncols\tcol1 REAL\n\n",
+			shouldFatal: true,
+		},
+		{
+			desc:        "no empty line",
+			input:       "tags\ttag1\ncols\tcol1 DOUBLE\n",
+			shouldFatal: true,
+		},
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	for _, c := range cases {
+		called := false
+		fatalData = func(string, ...interface{}) { called = true }
+		(&dbCreator{}).readDataHeader(bufio.NewReader(bytes.NewBufferString(c.input)))
+		if called != c.shouldFatal {
+			t.Errorf("%s: incorrect fatal: got %v want %v", c.desc, called, c.shouldFatal)
+			continue
+		}
+		if c.shouldFatal {
+			continue
+		}
+		if !reflect.DeepEqual(tagCols, c.wantTags) || !reflect.DeepEqual(tableCols, c.wantTables) {
+			t.Errorf("%s: incorrect header: got %q %v", c.desc, tagCols, tableCols)
+		}
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	defer useTestHeader()()
+	want := `CREATE TABLE "cpu" ("time" TIMESTAMP NOT NULL, "hostname" VARCHAR, "region" VARCHAR, "usage_user" DOUBLE, "usage_system" BIGINT, "up" BOOLEAN, "state" TEXT)`
+	if got := createTable("cpu", tableCols["cpu"]); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+	if got, want := quote(`a"b`), `"a""b"`; got != want {
+		t.Errorf("incorrect quoting: got %s want %s", got, want)
+	}
+}
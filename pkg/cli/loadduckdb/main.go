@@ -0,0 +1,148 @@
+// Package loadduckdb implements tsbs_load_duckdb (also run as `tsbs load
+// duckdb`), which loads a DuckDB database file with data from stdin.
+//
+// The data is in the mysql format, whose header gives the tables to create:
+// a table for each measurement, with a column of the time, of each tag and
+// of each field. The rows of each batch are loaded either with an appender
+// of each table, which hands the typed values to DuckDB directly, or with
+// COPY from a CSV file each worker writes the batch to.
+//
+// The database is the file <db-name>.duckdb in -db-dir. If it exists
+// beforehand, it will be *DELETED*.
+package loadduckdb
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// Load methods, of the -method flag
+const (
+	methodAppender = "appender"
+	methodCopy     = "copy"
+)
+
+// Program option vars:
+var (
+	dbDir       string
+	method      string
+	threads     int
+	memoryLimit string
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	// tagCols are the tag columns of the tables, and tableCols the field
+	// columns of the table of each measurement, as read from the header
+	tagCols   []string
+	tableCols map[string][]column
+	// connector opens the connections of the workers: DuckDB lets a single
+	// process at a time write to a database file, so they share it
+	connector *duckdb.Connector
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_duckdb and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&dbDir, "db-dir", ".", "Directory of the database file, which is named after -db-name with the extension .duckdb")
+	flag.StringVar(&method, "method", methodAppender, "How to load the rows of each batch: "+methodAppender+", with an appender of each table, or "+methodCopy+", with COPY from a CSV file")
+	flag.IntVar(&threads, "threads", 0, "Number of threads DuckDB uses for each statement (0 = the number of CPUs)")
+	flag.StringVar(&memoryLimit, "memory-limit", "", "Most memory DuckDB uses, e.g., 4GB (empty = 80% of the RAM)")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_duckdb", args); err != nil {
+		return err
+	}
+	method = strings.ToLower(method)
+	if method != methodAppender && method != methodCopy {
+		return cli.ConfigError(fmt.Errorf("invalid method '%s': it must be %s or %s", method, methodAppender, methodCopy))
+	}
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{br: br, scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return mysql.Format
+}
+
+// Run runs tsbs_load_duckdb with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_duckdb")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
+
+// dbPath returns the path of the file of the database dbName
+func dbPath(dbName string) string {
+	return filepath.Join(dbDir, dbName+".duckdb")
+}
+
+// getDSN returns the data source name to open the database dbName with,
+// setting the options of the flags
+func getDSN(dbName string) string {
+	params := url.Values{}
+	if threads > 0 {
+		params.Set("threads", strconv.Itoa(threads))
+	}
+	if memoryLimit != "" {
+		params.Set("memory_limit", memoryLimit)
+	}
+	if len(params) == 0 {
+		return dbPath(dbName)
+	}
+	return dbPath(dbName) + "?" + params.Encode()
+}
+
+// mustOpen opens the database dbName, exiting if it cannot be opened
+func mustOpen(dbName string) *duckdb.Connector {
+	c, err := duckdb.NewConnector(getDSN(dbName), nil)
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not open DuckDB database", "path", dbPath(dbName), "error", err)
+	}
+	return c
+}
@@ -0,0 +1,27 @@
+package loadduckdb
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
+func TestGetDSN(t *testing.T) {
+	if got, want := getDSN("benchmark"), "benchmark.duckdb"; got != want {
+		t.Errorf("incorrect DSN: got %s want %s", got, want)
+	}
+
+	oldThreads, oldMemoryLimit := threads, memoryLimit
+	defer func() { threads, memoryLimit = oldThreads, oldMemoryLimit }()
+	threads, memoryLimit = 4, "4GB"
+	if got, want := getDSN("benchmark"), "benchmark.duckdb?memory_limit=4GB&threads=4"; got != want {
+		t.Errorf("incorrect DSN: got %s want %s", got, want)
+	}
+}
@@ -0,0 +1,230 @@
+package loadduckdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+type processor struct {
+	conn driver.Conn
+	// appenders are those of each table, with the appender method
+	appenders map[string]*duckdb.Appender
+	// csvFile is the file each batch is written to, with the copy method
+	csvFile *os.File
+}
+
+func (p *processor) Init(_ int, doLoad bool) {
+	if !doLoad {
+		return
+	}
+	conn, err := connector.Connect(context.Background())
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not connect to DuckDB database", "error", err)
+		return
+	}
+	p.conn = conn
+	p.appenders = make(map[string]*duckdb.Appender)
+	if method == methodCopy {
+		if p.csvFile, err = ioutil.TempFile("", "tsbs_load_duckdb*.csv"); err != nil {
+			fatal(cli.ExitFailure, "could not create CSV file", "error", err)
+		}
+	}
+}
+
+func (p *processor) Close(doLoad bool) {
+	if !doLoad {
+		return
+	}
+	for table, a := range p.appenders {
+		// closing an appender appends the rows it still holds
+		if err := a.Close(); err != nil {
+			p.fail(table, err)
+		}
+	}
+	if p.csvFile != nil {
+		p.csvFile.Close()
+		os.Remove(p.csvFile.Name())
+	}
+	p.conn.Close()
+}
+
+// ProcessBatch parses the rows of each table in the batch into the values
+// of their columns, and loads them with the method of the flags
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	var metrics uint64
+	parsed := make(map[string][][]driver.Value, len(batch.tables))
+	for table, rows := range batch.tables {
+		cols, ok := tableCols[table]
+		if !ok {
+			fatalData("row of table %s, which is not in the header", table)
+			return 0, 0
+		}
+		values := make([][]driver.Value, len(rows))
+		for i, r := range rows {
+			if values[i] = parseRow(r, cols); values[i] == nil {
+				return 0, 0
+			}
+			metrics += uint64(len(cols))
+		}
+		parsed[table] = values
+	}
+	if doLoad {
+		for table, rows := range parsed {
+			var err error
+			if method == methodCopy {
+				err = p.copy(table, rows)
+			} else {
+				err = p.append(table, rows)
+			}
+			if err != nil {
+				p.fail(table, err)
+				return 0, 0
+			}
+		}
+	}
+	return metrics, uint64(batch.rows)
+}
+
+// parseRow returns the values of the columns of a row of a table with the
+// field columns cols, as the types the appender takes for the types of the
+// columns, with nil for those which are NULL
+func parseRow(r string, cols []column) []driver.Value {
+	parts := strings.Split(r, "\t")
+	if nCols := 1 + len(tagCols) + len(cols); len(parts) != nCols {
+		fatalData("parse error: row has %d columns, expected %d: %s", len(parts), nCols, r)
+		return nil
+	}
+	values := make([]driver.Value, len(parts))
+	t, err := time.Parse(mysql.TimeFormat, parts[0])
+	if err != nil {
+		fatalData("parse error: invalid time '%s': %v", parts[0], err)
+		return nil
+	}
+	values[0] = t
+	tags := parts[1 : 1+len(tagCols)]
+	for i, v := range tags {
+		if v != mysql.Null {
+			values[1+i] = mysql.Unescape(v)
+		}
+	}
+	for i, v := range parts[1+len(tagCols):] {
+		if v == mysql.Null {
+			continue
+		}
+		if values[1+len(tags)+i], err = parseValue(cols[i].typ, v); err != nil {
+			fatalData("parse error: invalid value '%s' of %s %s: %v", v, cols[i].typ, cols[i].name, err)
+			return nil
+		}
+	}
+	return values
+}
+
+// parseValue returns the value s of a field column of type typ
+func parseValue(typ, s string) (driver.Value, error) {
+	switch typ {
+	case "DOUBLE":
+		return strconv.ParseFloat(s, 64)
+	case "BIGINT":
+		return strconv.ParseInt(s, 10, 64)
+	case "BOOLEAN":
+		return strconv.ParseBool(s)
+	default:
+		return mysql.Unescape(s), nil
+	}
+}
+
+// append appends rows to table with its appender, flushing them so that
+// each batch is loaded once processed
+func (p *processor) append(table string, rows [][]driver.Value) error {
+	a, ok := p.appenders[table]
+	if !ok {
+		var err error
+		if a, err = duckdb.NewAppenderFromConn(p.conn, "", table); err != nil {
+			return err
+		}
+		p.appenders[table] = a
+	}
+	for _, r := range rows {
+		if err := a.AppendRow(r...); err != nil {
+			return err
+		}
+	}
+	return a.Flush()
+}
+
+// copy writes rows to the CSV file, in place of the previous batch, and
+// loads them into table with COPY
+func (p *processor) copy(table string, rows [][]driver.Value) error {
+	if err := p.csvFile.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := p.csvFile.Seek(0, 0); err != nil {
+		return err
+	}
+	w := csv.NewWriter(p.csvFile)
+	record := make([]string, 0, len(rows[0]))
+	for _, r := range rows {
+		record = record[:0]
+		for _, v := range r {
+			record = append(record, formatValue(v))
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	_, err := p.conn.(driver.ExecerContext).ExecContext(context.Background(), copyStatement(table, p.csvFile.Name()), nil)
+	return err
+}
+
+// copyStatement returns the COPY loading the CSV file path into table
+func copyStatement(table, path string) string {
+	return fmt.Sprintf(`COPY %s FROM '%s' (FORMAT csv, HEADER false, NULL '\N')`, quote(table), strings.Replace(path, "'", "''", -1))
+}
+
+// formatValue returns v as a value of the CSV file COPY reads
+func formatValue(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return mysql.Null
+	case time.Time:
+		return v.Format(mysql.TimeFormat)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return v.(string)
+	}
+}
+
+// fail exits on an error loading rows into table, with ExitData if the rows
+// were rejected
+func (p *processor) fail(table string, err error) {
+	var e *duckdb.Error
+	if errors.As(err, &e) {
+		switch e.Type {
+		case duckdb.ErrorTypeConversion, duckdb.ErrorTypeOutOfRange, duckdb.ErrorTypeConstraint, duckdb.ErrorTypeMismatchType:
+			fatal(cli.ExitData, "rows rejected", "table", table, "error", err)
+			return
+		}
+	}
+	fatal(cli.ExitFailure, "could not load rows", "table", table, "error", err)
+}
@@ -0,0 +1,102 @@
+package loadduckdb
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useTestHeader sets the header read as that of the cpu table with a field
+// of each type, returning a function restoring it
+func useTestHeader() func() {
+	oldTagCols, oldTableCols := tagCols, tableCols
+	tagCols = []string{"hostname", "region"}
+	tableCols = map[string][]column{"cpu": {{"usage_user", "DOUBLE"}, {"usage_system", "BIGINT"}, {"up", "BOOLEAN"}, {"state", "TEXT"}}}
+	return func() {
+		tagCols, tableCols = oldTagCols, oldTableCols
+	}
+}
+
+const testRows = "cpu\t2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58.5\t2\t1\tok\n" +
+	"cpu\t2016-01-01 00:00:10.000000\thost\\t1\t\\N\t3\t\\N\t0\t\\N\n"
+
+// decodeTestRows returns a batch of testRows
+func decodeTestRows() *batch {
+	d := &decoder{scanner: bufio.NewScanner(bytes.NewBufferString(testRows))}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(nil); p != nil; p = d.Decode(nil) {
+		b.Append(p)
+	}
+	return b
+}
+
+func TestDecodeAndProcessBatch(t *testing.T) {
+	defer useTestHeader()()
+	b := decodeTestRows()
+	if b.Len() != 2 || len(b.tables["cpu"]) != 2 {
+		t.Fatalf("incorrect batch: %d rows, %v", b.Len(), b.tables)
+	}
+
+	p := &processor{}
+	p.Init(0, false)
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 8 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+}
+
+func TestParseRow(t *testing.T) {
+	defer useTestHeader()()
+	b := decodeTestRows()
+	cases := []struct {
+		desc string
+		row  string
+		want []driver.Value
+	}{
+		{
+			desc: "all values",
+			row:  b.tables["cpu"][0],
+			want: []driver.Value{time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC), "host_0", "eu-west-1", 58.5, int64(2), true, "ok"},
+		},
+		{
+			desc: "nulls and escapes",
+			row:  b.tables["cpu"][1],
+			want: []driver.Value{time.Date(2016, 1, 1, 0, 0, 10, 0, time.UTC), "host\t1", nil, 3.0, nil, false, nil},
+		},
+	}
+	for _, c := range cases {
+		got := parseRow(c.row, tableCols["cpu"])
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: incorrect values: got %#v want %#v", c.desc, got, c.want)
+		}
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	for _, row := range []string{"2016-01-01 00:00:00.000000\thost_0\t\\N\t1\t2\t1", "2016-01-01 00:00:00.000000\thost_0\t\\N\tx\t2\t1\tok"} {
+		called := false
+		fatalData = func(string, ...interface{}) { called = true }
+		if got := parseRow(row, tableCols["cpu"]); !called || got != nil {
+			t.Errorf("invalid row parsed: %s", row)
+		}
+	}
+}
+
+func TestCopyStatement(t *testing.T) {
+	want := `COPY "cpu" FROM '/tmp/it''s.csv' (FORMAT csv, HEADER false, NULL '\N')`
+	if got := copyStatement("cpu", "/tmp/it's.csv"); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	values := []driver.Value{time.Date(2016, 1, 1, 0, 0, 10, 0, time.UTC), "host_0", nil, 58.5, int64(2), true}
+	want := []string{"2016-01-01 00:00:10.000000", "host_0", `\N`, "58.5", "2", "true"}
+	for i, v := range values {
+		if got := formatValue(v); got != want[i] {
+			t.Errorf("incorrect value of %#v: got %s want %s", v, got, want[i])
+		}
+	}
+}
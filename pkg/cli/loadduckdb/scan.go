@@ -0,0 +1,73 @@
+package loadduckdb
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// tagsPrefix starts the tags line of the header
+const tagsPrefix = "tags"
+
+// point is a row of a table, as the tab-separated values of its columns,
+// still escaped
+type point struct {
+	table  string
+	values string
+}
+
+type decoder struct {
+	br      *bufio.Reader
+	scanner *bufio.Scanner
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	// the header is only read by the DBCreator when loading, and the rows
+	// are parsed by the processor even when not
+	if tableCols == nil {
+		(&dbCreator{}).readDataHeader(d.br)
+	}
+	if !d.scan() {
+		return nil
+	}
+	// each line is a row, prefixed by the name of its table
+	parts := strings.SplitN(d.scanner.Text(), "\t", 2)
+	if len(parts) < 2 {
+		fatalData("data file in invalid format; row without values: %s", d.scanner.Text())
+		return nil
+	}
+	return load.NewPoint(&point{table: mysql.Unescape(parts[0]), values: parts[1]})
+}
+
+// scan scans the next line, returning false at the end of the input
+func (d *decoder) scan() bool {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() != nil {
+		fatalData("scan error: %v", d.scanner.Err())
+	}
+	return ok
+}
+
+// batch holds the rows of a batch by table
+type batch struct {
+	tables map[string][]string
+	rows   int
+}
+
+func (b *batch) Len() int {
+	return b.rows
+}
+
+func (b *batch) Append(item *load.Point) {
+	p := item.Data.(*point)
+	b.tables[p.table] = append(b.tables[p.table], p.values)
+	b.rows++
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{tables: map[string][]string{}}
+}
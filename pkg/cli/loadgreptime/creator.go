@@ -0,0 +1,47 @@
+package loadgreptime
+
+import (
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+// systemDB is the database the statements managing databases are run in,
+// which every server has
+const systemDB = "public"
+
+type dbCreator struct{}
+
+func (d *dbCreator) Init() {}
+
+// DBExists returns whether dbName is among the databases of the server
+func (d *dbCreator) DBExists(dbName string) bool {
+	var out struct {
+		Output []struct {
+			Records struct {
+				Rows [][]string
+			}
+		}
+	}
+	if err := client.SQL(systemDB, "SHOW DATABASES", &out); err != nil {
+		fatal(cli.ExitUnreachable, "could not list databases", "url", client.URL(), "error", err)
+		return false
+	}
+	for _, o := range out.Output {
+		for _, row := range o.Records.Rows {
+			if len(row) > 0 && row[0] == dbName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RemoveOldDB drops the database along with its tables
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	return client.SQL(systemDB, "DROP DATABASE "+dbName, nil)
+}
+
+// CreateDB creates the database. The server creates the tables as lines of
+// their measurements are written.
+func (d *dbCreator) CreateDB(dbName string) error {
+	return client.SQL(systemDB, "CREATE DATABASE "+dbName, nil)
+}
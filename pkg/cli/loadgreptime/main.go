@@ -0,0 +1,102 @@
+// Package loadgreptime implements tsbs_load_greptime (also run as `tsbs load
+// greptime`), which loads a GreptimeDB server with data from stdin.
+//
+// The data is in the influx format, line protocol, and each batch is written
+// with a request to the InfluxDB compatible write endpoint of the server
+// (/v1/influxdb/write), which creates the table of each measurement when its
+// first lines are written. Requests are authenticated with -username and
+// -password, if any.
+//
+// If the database exists beforehand, it will be *DELETED*.
+package loadgreptime
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+	"github.com/timescale/tsbs/pkg/greptime"
+)
+
+// Program option vars:
+var (
+	serverURL  string
+	username   string
+	password   string
+	useGzip    bool
+	appendMode bool
+	backoff    time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *greptime.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not valid line protocol; it is a
+// variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_greptime and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&serverURL, "url", "http://localhost:4000", "URL of the HTTP API of the GreptimeDB server")
+	flag.StringVar(&username, "username", "", "User to authenticate requests with (default: none)")
+	flag.StringVar(&password, "password", os.Getenv(greptime.PasswordEnv), "Password of the user (default: $"+greptime.PasswordEnv+")")
+	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests")
+	flag.BoolVar(&appendMode, "append-mode", false, "Whether the tables created keep duplicate rows, rather than the last row of each series and time")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when the server is overloaded")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_load_greptime", args)
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return influx.Format
+}
+
+// Run runs tsbs_load_greptime with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = greptime.NewClient(serverURL, username, password)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_greptime")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
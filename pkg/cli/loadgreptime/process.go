@@ -0,0 +1,94 @@
+package loadgreptime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/greptime"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// hintsHeader is the header of the options of the tables created by writes
+const hintsHeader = "X-Greptime-Hints"
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	path      string
+	header    http.Header
+	gzipped   bytes.Buffer
+	gzip      *gzip.Writer
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	v := url.Values{}
+	v.Set("db", loader.DatabaseName())
+	v.Set("precision", "ns")
+	p.path = "/v1/influxdb/write?" + v.Encode()
+	p.header = http.Header{}
+	p.header.Set("Content-Type", "text/plain; charset=utf-8")
+	if appendMode {
+		p.header.Set(hintsHeader, "append_mode=true")
+	}
+	if useGzip {
+		p.header.Set("Content-Encoding", "gzip")
+		p.gzip = gzip.NewWriter(&p.gzipped)
+	}
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch writes the lines of the batch with a single request,
+// retrying it after sleeping for -backoff while the server is overloaded
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		body := batch.buf.Bytes()
+		if p.gzip != nil {
+			p.gzipped.Reset()
+			p.gzip.Reset(&p.gzipped)
+			p.gzip.Write(body)
+			p.gzip.Close()
+			body = p.gzipped.Bytes()
+		}
+		p.write(body)
+	}
+	return batch.metrics, batch.rows
+}
+
+func (p *processor) write(body []byte) {
+	for {
+		_, err := client.Do(http.MethodPost, p.path, p.header, body)
+		if err == nil {
+			return
+		}
+		e, ok := err.(*greptime.Error)
+		switch {
+		case !ok:
+			fatal(cli.ExitUnreachable, "could not write lines", "worker", p.workerNum, "url", client.URL(), "error", err)
+		case e.Throttled():
+			p.throttled++
+			logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+			sleep(backoff)
+			continue
+		case e.Status == http.StatusBadRequest:
+			fatal(cli.ExitData, "lines rejected", "worker", p.workerNum, "error", err)
+		default:
+			fatal(cli.ExitFailure, "could not write lines", "worker", p.workerNum, "error", err)
+		}
+		return
+	}
+}
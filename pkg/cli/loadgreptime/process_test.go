@@ -0,0 +1,194 @@
+package loadgreptime
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/greptime"
+)
+
+const testLine = "cpu,hostname=host_0,region=eu-west-1 usage_user=58i,usage_system=2i 1451606400000000000"
+
+// testAPI is a fake GreptimeDB API, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+	// handle, if set, answers requests instead of an empty response
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var body []byte
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ = ioutil.ReadAll(zr)
+	} else {
+		body, _ = ioutil.ReadAll(r.Body)
+	}
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.bodies = append(a.bodies, string(body))
+	status, resp := http.StatusOK, ""
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldFatal, oldSleep := client, fatal, sleep
+	client = greptime.NewClient(server.URL, "user", "secret")
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	sleep = func(time.Duration) {}
+	return func() {
+		server.Close()
+		client, fatal, sleep = oldClient, oldFatal, oldSleep
+	}
+}
+
+func TestBatchAppend(t *testing.T) {
+	b := (&factory{}).New().(*batch)
+	b.Append(load.NewPoint([]byte(testLine)))
+	b.Append(load.NewPoint([]byte(testLine)))
+	if b.Len() != 2 || b.metrics != 4 || b.buf.String() != testLine+"\n"+testLine+"\n" {
+		t.Errorf("incorrect batch: %d rows, %d metrics, %q", b.Len(), b.metrics, b.buf.String())
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu usage_user=58i")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 2 {
+		t.Errorf("invalid line not rejected: %q", got)
+	}
+}
+
+func TestCreateDB(t *testing.T) {
+	api := &testAPI{handle: func(_ *http.Request, body []byte) (int, string) {
+		if string(body) == "sql=SHOW+DATABASES" {
+			return http.StatusOK, `{"output":[{"records":{"schema":{"column_schemas":[{"name":"Database","data_type":"String"}]},"rows":[["greptime_private"],["benchmark"],["public"]],"total_rows":3}}],"execution_time_ms":1}`
+		}
+		return http.StatusOK, `{"output":[{"affectedrows":1}],"execution_time_ms":1}`
+	}}
+	defer useTestAPI(t, api)()
+
+	d := &dbCreator{}
+	if !d.DBExists("benchmark") || d.DBExists("other") {
+		t.Errorf("incorrect databases found")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "sql=SHOW+DATABASES\n" +
+		"sql=SHOW+DATABASES\n" +
+		"sql=DROP+DATABASE+benchmark\n" +
+		"sql=CREATE+DATABASE+benchmark"
+	if got := strings.Join(api.bodies, "\n"); got != want {
+		t.Errorf("incorrect statements:\ngot\n%s\nwant\n%s", got, want)
+	}
+	for _, r := range api.requests {
+		if r != "POST /v1/sql?db=public" {
+			t.Errorf("incorrect request: %s", r)
+		}
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	calls := 0
+	api := &testAPI{handle: func(r *http.Request, _ []byte) (int, string) {
+		if user, password, _ := r.BasicAuth(); user != "user" || password != "secret" {
+			return http.StatusUnauthorized, `{"code":7002,"error":"User not found"}`
+		}
+		calls++
+		if calls == 1 {
+			return http.StatusServiceUnavailable, `{"code":6001,"error":"Rate limited"}`
+		}
+		return http.StatusNoContent, ""
+	}}
+	defer useTestAPI(t, api)()
+
+	for _, gzipped := range []bool{true, false} {
+		oldGzip, oldAppendMode := useGzip, appendMode
+		useGzip, appendMode = gzipped, !gzipped
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testLine)))
+		p := &processor{}
+		p.Init(0, true)
+		useGzip, appendMode = oldGzip, oldAppendMode
+		if hints := p.header.Get(hintsHeader); hints != "" && gzipped || hints != "append_mode=true" && !gzipped {
+			t.Errorf("incorrect hints: %q", hints)
+		}
+		if metrics, rows := p.ProcessBatch(b, true); metrics != 2 || rows != 1 {
+			t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+		}
+		if metrics, rows := p.ProcessBatch(b, false); metrics != 2 || rows != 1 {
+			t.Errorf("incorrect counts without loading: got %d metrics, %d rows", metrics, rows)
+		}
+	}
+	want := "POST /v1/influxdb/write?db=benchmark&precision=ns"
+	if len(api.requests) != 3 || api.requests[2] != want {
+		t.Fatalf("incorrect requests: %v", api.requests)
+	}
+	for _, body := range api.bodies {
+		if body != testLine+"\n" {
+			t.Errorf("incorrect body: %q", body)
+		}
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		resp     string
+		wantCode int
+	}{
+		{
+			desc:     "line rejected",
+			status:   http.StatusBadRequest,
+			resp:     `{"code":1004,"error":"Invalid InfluxDB line protocol"}`,
+			wantCode: cli.ExitData,
+		},
+		{desc: "no database", status: http.StatusNotFound, resp: `{"code":4002,"error":"Database not found: benchmark"}`, wantCode: cli.ExitFailure},
+		{desc: "unauthorized", status: http.StatusUnauthorized, resp: "", wantCode: cli.ExitFailure},
+	}
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) { return c.status, c.resp }}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testLine)))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
@@ -1,13 +1,14 @@
-package main
+package loadinflux
 
 import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
 )
 
 type dbCreator struct {
@@ -21,7 +22,7 @@ func (d *dbCreator) Init() {
 func (d *dbCreator) DBExists(dbName string) bool {
 	dbs, err := d.listDatabases()
 	if err != nil {
-		log.Fatal(err)
+		cli.Fatal(cli.ExitUnreachable, "could not list databases", "error", err)
 	}
 
 	for _, db := range dbs {
@@ -1,4 +1,4 @@
-package main
+package loadinflux
 
 // This file lifted wholesale from mountainflux by Mark Rushakoff.
 
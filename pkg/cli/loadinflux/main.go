@@ -0,0 +1,134 @@
+// Package loadinflux implements tsbs_load_influx (also run as
+// `tsbs load influx`), which loads an InfluxDB daemon with data from stdin.
+//
+// The caller is responsible for assuring that the database is empty before
+// bulk load.
+package loadinflux
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/provision"
+)
+
+// Program option vars:
+var (
+	daemonURLs        []string
+	replicationFactor int
+	backoff           time.Duration
+	useGzip           bool
+	doAbortOnExist    bool
+	consistency       string
+	container         *provision.Options
+)
+
+// Global vars
+var (
+	loader  *load.BenchmarkRunner
+	bufPool sync.Pool
+)
+
+var consistencyChoices = map[string]struct{}{
+	"any":    struct{}{},
+	"one":    struct{}{},
+	"quorum": struct{}{},
+	"all":    struct{}{},
+}
+
+// allows for testing
+var fatal = logging.Fatalf
+
+// fatalData exits on input that is not valid line protocol; it is a
+// variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_influx and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+	var csvDaemonURLs string
+
+	flag.StringVar(&csvDaemonURLs, "urls", "http://localhost:8086", "InfluxDB URLs, comma-separated. Will be used in a round-robin fashion.")
+	flag.IntVar(&replicationFactor, "replication-factor", 1, "Cluster replication factor (only applies to clustered databases).")
+	flag.StringVar(&consistency, "consistency", "all", "Write consistency. Must be one of: any, one, quorum, all.")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when server indicates backpressure is needed.")
+	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests (default true).")
+
+	container = provision.AddFlags(flag.CommandLine, "influx")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_influx", args); err != nil {
+		return err
+	}
+
+	if _, ok := consistencyChoices[consistency]; !ok {
+		return cli.ConfigError(fmt.Errorf("invalid consistency settings: '%s'", consistency))
+	}
+
+	daemonURLs = strings.Split(csvDaemonURLs, ",")
+	if len(daemonURLs) == 0 {
+		return cli.ConfigError(fmt.Errorf("missing 'urls' flag"))
+	}
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return influx.Format
+}
+
+// Run runs tsbs_load_influx with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "influx", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		daemonURLs = []string{"http://" + c.Endpoint()}
+	}
+	bufPool = sync.Pool{
+		New: func() interface{} {
+			return bytes.NewBuffer(make([]byte, 0, 4*1024*1024))
+		},
+	}
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_influx")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
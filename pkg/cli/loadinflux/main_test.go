@@ -0,0 +1,14 @@
+package loadinflux
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
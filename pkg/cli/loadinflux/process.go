@@ -1,8 +1,9 @@
-package main
+package loadinflux
 
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/timescale/tsbs/load"
@@ -12,7 +13,11 @@ import (
 const backingOffChanCap = 100
 
 // allows for testing
-var printFn = fmt.Printf
+// printFn prints backoff messages to stderr; it is a variable for ease of
+// testing
+var printFn = func(format string, args ...interface{}) (int, error) {
+	return fmt.Fprintf(os.Stderr, format, args...)
+}
 
 type processor struct {
 	backingOffChan chan bool
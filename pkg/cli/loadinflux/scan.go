@@ -1,4 +1,4 @@
-package main
+package loadinflux
 
 import (
 	"bufio"
@@ -21,7 +21,7 @@ func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
 	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
 		return nil
 	} else if !ok {
-		fatal("scan error: %v", d.scanner.Err())
+		fatalData("scan error: %v", d.scanner.Err())
 		return nil
 	}
 	return load.NewPoint(d.scanner.Bytes())
@@ -45,7 +45,7 @@ func (b *batch) Append(item *load.Point) {
 	// and then on the middle element, we split by comma to count number of fields added
 	args := strings.Split(thatStr, " ")
 	if len(args) != 3 {
-		fatal(errNotThreeTuplesFmt, len(args))
+		fatalData(errNotThreeTuplesFmt, len(args))
 		return
 	}
 	b.metrics += uint64(len(strings.Split(args[1], ",")))
@@ -1,4 +1,4 @@
-package main
+package loadinflux
 
 import (
 	"bufio"
@@ -53,7 +53,7 @@ func TestBatch(t *testing.T) {
 		Data: []byte("bad_point"),
 	}
 	errMsg := ""
-	fatal = func(f string, args ...interface{}) {
+	fatalData = func(f string, args ...interface{}) {
 		errMsg = fmt.Sprintf(f, args...)
 	}
 	b.Append(p)
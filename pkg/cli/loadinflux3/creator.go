@@ -0,0 +1,43 @@
+package loadinflux3
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+// databasePath is the path of the API configuring databases
+const databasePath = "/api/v3/configure/database"
+
+type dbCreator struct{}
+
+func (d *dbCreator) Init() {}
+
+// DBExists returns whether dbName is among the databases of the server,
+// which are listed as rows of a system table
+func (d *dbCreator) DBExists(dbName string) bool {
+	var rows []map[string]string
+	if err := client.JSON(http.MethodGet, databasePath+"?format=json", nil, &rows); err != nil {
+		fatal(cli.ExitUnreachable, "could not list databases", "url", client.URL(), "error", err)
+		return false
+	}
+	for _, row := range rows {
+		if row["iox::database"] == dbName {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOldDB deletes the database, which the server keeps until its files
+// are removed, but under another name
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	return client.JSON(http.MethodDelete, databasePath+"?db="+url.QueryEscape(dbName), nil, nil)
+}
+
+// CreateDB creates the database. The server creates the tables as lines of
+// their measurements are written.
+func (d *dbCreator) CreateDB(dbName string) error {
+	return client.JSON(http.MethodPost, databasePath, map[string]string{"db": dbName}, nil)
+}
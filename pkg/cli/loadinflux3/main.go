@@ -0,0 +1,100 @@
+// Package loadinflux3 implements tsbs_load_influx3 (also run as `tsbs load
+// influx3`), which loads an InfluxDB 3 server with data from stdin.
+//
+// The data is in the influx format, line protocol, and each batch is written
+// with a request to the v3 write endpoint (/api/v3/write_lp), gzipped unless
+// -gzip=false is given. Requests are authenticated with the token in -token.
+//
+// If the database exists beforehand, it will be *DELETED*.
+package loadinflux3
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+	"github.com/timescale/tsbs/pkg/influx3"
+)
+
+// Program option vars:
+var (
+	serverURL     string
+	token         string
+	useGzip       bool
+	noSync        bool
+	acceptPartial bool
+	backoff       time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *influx3.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not valid line protocol; it is a
+// variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_influx3 and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&serverURL, "url", "http://localhost:8181", "URL of the InfluxDB 3 server")
+	flag.StringVar(&token, "token", os.Getenv(influx3.TokenEnv), "Token to authenticate requests with (default: $"+influx3.TokenEnv+")")
+	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests")
+	flag.BoolVar(&noSync, "no-sync", false, "Whether to acknowledge writes before they are persisted to the write-ahead log")
+	flag.BoolVar(&acceptPartial, "accept-partial", false, "Whether the server writes the valid lines of a batch with invalid ones, rather than rejecting it")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when the server is overloaded")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_load_influx3", args)
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return influx.Format
+}
+
+// Run runs tsbs_load_influx3 with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = influx3.NewClient(serverURL, token)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_influx3")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
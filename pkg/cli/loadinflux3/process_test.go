@@ -0,0 +1,189 @@
+package loadinflux3
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/influx3"
+)
+
+const testLine = "cpu,hostname=host_0,region=eu-west-1 usage_user=58i,usage_system=2i 1451606400000000000"
+
+// testAPI is a fake InfluxDB 3 API, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+	// handle, if set, answers requests instead of an empty response
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var body []byte
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ = ioutil.ReadAll(zr)
+	} else {
+		body, _ = ioutil.ReadAll(r.Body)
+	}
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.bodies = append(a.bodies, string(body))
+	status, resp := http.StatusOK, ""
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldFatal, oldSleep := client, fatal, sleep
+	client = influx3.NewClient(server.URL, "token")
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	sleep = func(time.Duration) {}
+	return func() {
+		server.Close()
+		client, fatal, sleep = oldClient, oldFatal, oldSleep
+	}
+}
+
+func TestBatchAppend(t *testing.T) {
+	b := (&factory{}).New().(*batch)
+	b.Append(load.NewPoint([]byte(testLine)))
+	b.Append(load.NewPoint([]byte(testLine)))
+	if b.Len() != 2 || b.metrics != 4 || b.buf.String() != testLine+"\n"+testLine+"\n" {
+		t.Errorf("incorrect batch: %d rows, %d metrics, %q", b.Len(), b.metrics, b.buf.String())
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu usage_user=58i")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 2 {
+		t.Errorf("invalid line not rejected: %q", got)
+	}
+}
+
+func TestCreateDB(t *testing.T) {
+	api := &testAPI{handle: func(r *http.Request, _ []byte) (int, string) {
+		if r.Method == http.MethodGet {
+			return http.StatusOK, `[{"iox::database":"_internal"},{"iox::database":"benchmark"}]`
+		}
+		return http.StatusOK, ""
+	}}
+	defer useTestAPI(t, api)()
+
+	d := &dbCreator{}
+	if !d.DBExists("benchmark") || d.DBExists("other") {
+		t.Errorf("incorrect databases found")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "GET /api/v3/configure/database?format=json\n" +
+		"GET /api/v3/configure/database?format=json\n" +
+		"DELETE /api/v3/configure/database?db=benchmark\n" +
+		"POST /api/v3/configure/database"
+	if got := strings.Join(api.requests, "\n"); got != want {
+		t.Errorf("incorrect requests:\ngot\n%s\nwant\n%s", got, want)
+	}
+	if api.bodies[3] != `{"db":"benchmark"}` {
+		t.Errorf("incorrect database created: %s", api.bodies[3])
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	calls := 0
+	api := &testAPI{handle: func(r *http.Request, _ []byte) (int, string) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			return http.StatusUnauthorized, `{"error":"unauthorized"}`
+		}
+		calls++
+		if calls == 1 {
+			return http.StatusServiceUnavailable, `{"error":"busy"}`
+		}
+		return http.StatusNoContent, ""
+	}}
+	defer useTestAPI(t, api)()
+
+	for _, gzipped := range []bool{true, false} {
+		oldGzip := useGzip
+		useGzip = gzipped
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testLine)))
+		p := &processor{}
+		p.Init(0, true)
+		useGzip = oldGzip
+		if metrics, rows := p.ProcessBatch(b, true); metrics != 2 || rows != 1 {
+			t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+		}
+		if metrics, rows := p.ProcessBatch(b, false); metrics != 2 || rows != 1 {
+			t.Errorf("incorrect counts without loading: got %d metrics, %d rows", metrics, rows)
+		}
+	}
+	want := "POST /api/v3/write_lp?accept_partial=false&db=benchmark&precision=nanosecond"
+	if len(api.requests) != 3 || api.requests[2] != want {
+		t.Fatalf("incorrect requests: %v", api.requests)
+	}
+	for _, body := range api.bodies {
+		if body != testLine+"\n" {
+			t.Errorf("incorrect body: %q", body)
+		}
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		resp     string
+		wantCode int
+	}{
+		{
+			desc:     "line rejected",
+			status:   http.StatusBadRequest,
+			resp:     `{"error":"parsing failed for write_lp endpoint","data":[{"original_line":"cpu","line_number":1,"error_message":"invalid"}]}`,
+			wantCode: cli.ExitData,
+		},
+		{desc: "no database", status: http.StatusNotFound, resp: `{"error":"database not found"}`, wantCode: cli.ExitFailure},
+		{desc: "unauthorized", status: http.StatusUnauthorized, resp: "", wantCode: cli.ExitFailure},
+	}
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) { return c.status, c.resp }}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testLine)))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package loadinflux3
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/timescale/tsbs/load"
+)
+
+type decoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
+		return nil
+	} else if !ok {
+		fatalData("scan error: %v", d.scanner.Err())
+		return nil
+	}
+	return load.NewPoint(d.scanner.Bytes())
+}
+
+// batch holds the lines of a batch, as the body of a write request
+type batch struct {
+	buf     bytes.Buffer
+	rows    uint64
+	metrics uint64
+}
+
+func (b *batch) Len() int {
+	return int(b.rows)
+}
+
+// Append adds a line to the batch. Each line is in the form "csv-tags
+// csv-fields timestamp", so the metrics of a line are the commas of its
+// fields, plus one.
+func (b *batch) Append(item *load.Point) {
+	line := item.Data.([]byte)
+	parts := bytes.Split(line, []byte(" "))
+	if len(parts) != 3 {
+		fatalData("parse error: line does not have 3 tuples, has %d", len(parts))
+		return
+	}
+	b.rows++
+	b.metrics += uint64(bytes.Count(parts[1], []byte(",")) + 1)
+	b.buf.Write(line)
+	b.buf.WriteByte('\n')
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{}
+}
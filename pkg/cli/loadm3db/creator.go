@@ -0,0 +1,85 @@
+package loadm3db
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+// namespacePath is the path of the API managing the namespaces of M3DB
+const namespacePath = "/api/v1/services/m3db/namespace"
+
+type dbCreator struct{}
+
+func (d *dbCreator) Init() {}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	var resp struct {
+		Registry struct {
+			Namespaces map[string]interface{}
+		}
+	}
+	if err := client.JSON(http.MethodGet, namespacePath, nil, &resp); err != nil {
+		fatal(cli.ExitUnreachable, "could not list namespaces", "url", client.URL(), "error", err)
+		return false
+	}
+	_, ok := resp.Registry.Namespaces[dbName]
+	return ok
+}
+
+// RemoveOldDB deletes the namespace
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	return client.JSON(http.MethodDelete, namespacePath+"/"+url.PathEscape(dbName), nil, nil)
+}
+
+// CreateDB adds the namespace to the placement of the cluster, which must
+// exist, and waits for it to be ready. Cold writes are enabled, so that
+// samples outside the buffer around the current time are accepted, as long
+// as they are within the retention.
+func (d *dbCreator) CreateDB(dbName string) error {
+	in := map[string]interface{}{
+		"name": dbName,
+		"options": map[string]interface{}{
+			"bootstrapEnabled":  true,
+			"flushEnabled":      true,
+			"writesToCommitLog": true,
+			"cleanupEnabled":    true,
+			"coldWritesEnabled": true,
+			"retentionOptions": map[string]string{
+				"retentionPeriodDuration": formatDuration(retention),
+				"blockSizeDuration":       formatDuration(blockSize),
+				"bufferFutureDuration":    "10m",
+				"bufferPastDuration":      "10m",
+			},
+			"indexOptions": map[string]interface{}{
+				"enabled":           true,
+				"blockSizeDuration": formatDuration(blockSize),
+			},
+		},
+	}
+	if err := client.JSON(http.MethodPost, namespacePath, in, nil); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		var resp struct{ Ready bool }
+		err := client.JSON(http.MethodPost, namespacePath+"/ready", map[string]string{"name": dbName}, &resp)
+		if err == nil && resp.Ready {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("namespace %s not ready after %v (last error: %v)", dbName, readyTimeout, err)
+		}
+		sleep(time.Second)
+	}
+}
+
+// formatDuration formats d in seconds, e.g., 172800s, which the API parses
+// whether it takes Go or protobuf durations
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d/time.Second))
+}
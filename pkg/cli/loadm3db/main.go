@@ -0,0 +1,106 @@
+// Package loadm3db implements tsbs_load_m3db (also run as `tsbs load m3db`),
+// which loads M3 with data from stdin through the Prometheus remote-write
+// endpoint of an M3 coordinator.
+//
+// The data is in the m3db format, a WriteRequest of the remote-write
+// protocol per reading, and the WriteRequests of each batch are sent as one,
+// compressed with snappy as the protocol requires. The namespace is named
+// by -db-name, and must be the unaggregated namespace the coordinator writes
+// to.
+//
+// If the namespace exists beforehand, it will be *DELETED*.
+package loadm3db
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/m3db"
+	"github.com/timescale/tsbs/pkg/m3"
+)
+
+// Program option vars:
+var (
+	coordinatorURL string
+	retention      time.Duration
+	blockSize      time.Duration
+	readyTimeout   time.Duration
+	backoff        time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *m3.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_m3db and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&coordinatorURL, "url", "http://localhost:7201", "URL of the M3 coordinator")
+	flag.DurationVar(&retention, "retention", 48*time.Hour, "Retention of the namespace created, which must cover the timestamps of the data")
+	flag.DurationVar(&blockSize, "block-size", 2*time.Hour, "Block size of the namespace created")
+	flag.DurationVar(&readyTimeout, "ready-timeout", 2*time.Minute, "Time to wait for the namespace created to be ready for writes")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when the coordinator is overloaded")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_m3db", args); err != nil {
+		return err
+	}
+	if retention < blockSize || blockSize <= 0 {
+		return cli.ConfigError(fmt.Errorf("invalid retention %v and block size %v: the retention must be at least one block", retention, blockSize))
+	}
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(_ *bufio.Reader) load.PointDecoder {
+	return &decoder{}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return m3db.Format
+}
+
+// Run runs tsbs_load_m3db with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = m3.NewClient(coordinatorURL)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_m3db")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
@@ -0,0 +1,76 @@
+package loadm3db
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/m3"
+)
+
+// writePath is the path of the Prometheus remote-write endpoint of the
+// coordinator
+const writePath = "/api/v1/prom/remote/write"
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum  int
+	header     http.Header
+	compressed []byte
+	throttled  uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	p.header = http.Header{}
+	p.header.Set("Content-Type", "application/x-protobuf")
+	p.header.Set("Content-Encoding", "snappy")
+	p.header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch writes the WriteRequest of the batch with a single request,
+// retrying it after sleeping for -backoff while the coordinator is
+// overloaded
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		p.compressed = snappy.Encode(p.compressed[:cap(p.compressed)], batch.buf.Bytes())
+		p.write(p.compressed)
+	}
+	return batch.metrics, batch.rows
+}
+
+func (p *processor) write(body []byte) {
+	for {
+		_, err := client.Do(http.MethodPost, writePath, p.header, body)
+		if err == nil {
+			return
+		}
+		e, ok := err.(*m3.Error)
+		switch {
+		case !ok:
+			fatal(cli.ExitUnreachable, "could not write samples", "worker", p.workerNum, "url", client.URL(), "error", err)
+		case e.Throttled():
+			p.throttled++
+			logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+			sleep(backoff)
+			continue
+		case e.Status == http.StatusBadRequest:
+			fatal(cli.ExitData, "samples rejected", "worker", p.workerNum, "error", err)
+		default:
+			fatal(cli.ExitFailure, "could not write samples", "worker", p.workerNum, "error", err)
+		}
+		return
+	}
+}
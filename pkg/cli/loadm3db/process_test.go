@@ -0,0 +1,230 @@
+package loadm3db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/m3db"
+	"github.com/timescale/tsbs/pkg/m3"
+)
+
+// testAPI is a fake M3 coordinator, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   [][]byte
+	// handle, if set, answers requests instead of an empty JSON object
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.bodies = append(a.bodies, body)
+	status, resp := http.StatusOK, `{}`
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldFatal, oldSleep := client, fatal, sleep
+	client = m3.NewClient(server.URL)
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	sleep = func(time.Duration) {}
+	return func() {
+		server.Close()
+		client, fatal, sleep = oldClient, oldFatal, oldSleep
+	}
+}
+
+// testData returns n readings of two fields in the m3db format
+func testData(t *testing.T, n int) []byte {
+	var buf bytes.Buffer
+	s, err := serialize.New(m3db.Format, nil, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		p := serialize.NewPoint()
+		p.SetMeasurementName([]byte("cpu"))
+		p.SetTimestamp(int64(i) * 1e9)
+		p.AppendTag([]byte("hostname"), []byte("host_0"))
+		p.AppendField([]byte("usage_user"), 58.0)
+		p.AppendField([]byte("usage_system"), int64(2))
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeAndAppend(t *testing.T) {
+	data := testData(t, 3)
+	br := bufio.NewReader(bytes.NewReader(data))
+	d := &decoder{}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(br); p != nil; p = d.Decode(br) {
+		b.Append(p)
+	}
+	if b.Len() != 3 || b.metrics != 6 {
+		t.Errorf("incorrect batch: %d rows, %d metrics", b.Len(), b.metrics)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu,hostname=host_0 usage_user=58i 0")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 3 {
+		t.Errorf("data in another format not rejected: %q", got)
+	}
+	got = ""
+	if p := d.Decode(bufio.NewReader(bytes.NewReader(data[:len(data)-1]))); p == nil || got != "" {
+		t.Errorf("first reading not decoded: %q", got)
+	}
+	d.Decode(bufio.NewReader(bytes.NewReader(data[:10])))
+	if !strings.HasPrefix(got, "could not read WriteRequest") {
+		t.Errorf("truncated data not rejected: %q", got)
+	}
+}
+
+func TestCreateDB(t *testing.T) {
+	readyCalls := 0
+	api := &testAPI{handle: func(r *http.Request, _ []byte) (int, string) {
+		switch {
+		case r.Method == http.MethodGet:
+			return http.StatusOK, `{"registry":{"namespaces":{"default":{}}}}`
+		case strings.HasSuffix(r.URL.Path, "/ready"):
+			readyCalls++
+			if readyCalls == 1 {
+				return http.StatusBadRequest, `{"status":"error","error":"namespace is not ready"}`
+			}
+			return http.StatusOK, `{"ready":true}`
+		}
+		return http.StatusOK, `{}`
+	}}
+	defer useTestAPI(t, api)()
+
+	d := &dbCreator{}
+	if !d.DBExists("default") || d.DBExists("benchmark") {
+		t.Errorf("incorrect namespaces found")
+	}
+	if err := d.RemoveOldDB("default"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("default"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "GET /api/v1/services/m3db/namespace\n" +
+		"GET /api/v1/services/m3db/namespace\n" +
+		"DELETE /api/v1/services/m3db/namespace/default\n" +
+		"POST /api/v1/services/m3db/namespace\n" +
+		"POST /api/v1/services/m3db/namespace/ready\n" +
+		"POST /api/v1/services/m3db/namespace/ready"
+	if got := strings.Join(api.requests, "\n"); got != want {
+		t.Errorf("incorrect requests:\ngot\n%s\nwant\n%s", got, want)
+	}
+	var in struct {
+		Name    string
+		Options struct {
+			ColdWritesEnabled bool
+			RetentionOptions  struct {
+				RetentionPeriodDuration string
+			}
+		}
+	}
+	if err := json.Unmarshal(api.bodies[3], &in); err != nil || in.Name != "default" || !in.Options.ColdWritesEnabled ||
+		in.Options.RetentionOptions.RetentionPeriodDuration != "172800s" {
+		t.Errorf("incorrect namespace created: %s", api.bodies[3])
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	calls := 0
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		calls++
+		if calls == 1 {
+			return http.StatusTooManyRequests, `{"status":"error","error":"rate limit exceeded"}`
+		}
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			return http.StatusBadRequest, `{"status":"error","error":"not compressed"}`
+		}
+		return http.StatusOK, ``
+	}}
+	defer useTestAPI(t, api)()
+
+	data := testData(t, 2)
+	br := bufio.NewReader(bytes.NewReader(data))
+	b := (&factory{}).New().(*batch)
+	for p := (&decoder{}).Decode(br); p != nil; p = (&decoder{}).Decode(br) {
+		b.Append(p)
+	}
+	p := &processor{}
+	p.Init(0, true)
+	if metrics, rows := p.ProcessBatch(b, true); metrics != 4 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	if len(api.requests) != 2 || api.requests[1] != "POST /api/v1/prom/remote/write" || p.throttled != 1 {
+		t.Fatalf("incorrect requests: %v, %d throttled", api.requests, p.throttled)
+	}
+	// the body is the WriteRequests of the readings concatenated, without
+	// their length prefixes
+	body, err := snappy.Decode(nil, api.bodies[1])
+	if err != nil || string(body) != b.buf.String() {
+		t.Errorf("incorrect body: %q, %v", body, err)
+	}
+
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 4 || rows != 2 || len(api.requests) != 2 {
+		t.Errorf("incorrect counts without loading: %d, %d, requests %v", metrics, rows, api.requests)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		wantCode int
+	}{
+		{desc: "rejected", status: http.StatusBadRequest, wantCode: cli.ExitData},
+		{desc: "failed", status: http.StatusInternalServerError, wantCode: cli.ExitFailure},
+	}
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) {
+			return c.status, `{"status":"error","error":"failed"}`
+		}}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append((&decoder{}).Decode(bufio.NewReader(bytes.NewReader(testData(t, 1)))))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
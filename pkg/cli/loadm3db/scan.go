@@ -0,0 +1,92 @@
+package loadm3db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/timescale/tsbs/load"
+)
+
+// maxRowSize is the largest WriteRequest of a reading read, so that data in
+// another format fails instead of being read as a huge WriteRequest
+const maxRowSize = 16 << 20
+
+// tagTimeSeries is the tag of the time series of a WriteRequest, its field 1
+// and length-delimited
+const tagTimeSeries = 1<<3 | 2
+
+type decoder struct{}
+
+// Decode reads the WriteRequest of a reading, prefixed by its length as a
+// varint
+func (d *decoder) Decode(br *bufio.Reader) *load.Point {
+	n, err := binary.ReadUvarint(br)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		fatalData("could not read length of WriteRequest: %v", err)
+		return nil
+	}
+	if n > maxRowSize {
+		fatalData("parse error: WriteRequest of %d bytes is too large", n)
+		return nil
+	}
+	row := make([]byte, n)
+	if _, err := io.ReadFull(br, row); err != nil {
+		fatalData("could not read WriteRequest: %v", err)
+		return nil
+	}
+	return load.NewPoint(row)
+}
+
+// batch holds the WriteRequests of a batch concatenated, which is the
+// WriteRequest of the whole batch
+type batch struct {
+	buf     bytes.Buffer
+	rows    uint64
+	metrics uint64
+}
+
+func (b *batch) Len() int {
+	return int(b.rows)
+}
+
+// Append adds a WriteRequest to the batch. Each of its time series has a
+// single sample, so they are its metrics.
+func (b *batch) Append(item *load.Point) {
+	row := item.Data.([]byte)
+	series, ok := countTimeSeries(row)
+	if !ok {
+		fatalData("parse error: invalid WriteRequest of %d bytes", len(row))
+		return
+	}
+	b.rows++
+	b.metrics += uint64(series)
+	b.buf.Write(row)
+}
+
+// countTimeSeries returns the number of time series of a WriteRequest, and
+// false if it is not one written by tsbs_generate_data
+func countTimeSeries(row []byte) (int, bool) {
+	n := 0
+	for len(row) > 0 {
+		if row[0] != tagTimeSeries {
+			return 0, false
+		}
+		size, k := binary.Uvarint(row[1:])
+		if k <= 0 || size > uint64(len(row)-1-k) {
+			return 0, false
+		}
+		row = row[1+k+int(size):]
+		n++
+	}
+	return n, true
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{}
+}
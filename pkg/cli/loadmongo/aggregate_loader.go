@@ -1,16 +1,16 @@
-package main
+package loadmongo
 
 import (
 	"fmt"
 	"hash/fnv"
-	"log"
 	"sync"
 	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data/serialize/mongo"
+	"github.com/timescale/tsbs/pkg/logging"
 )
 
 type hostnameIndexer struct {
@@ -18,8 +18,8 @@ type hostnameIndexer struct {
 }
 
 func (i *hostnameIndexer) GetIndex(item *load.Point) int {
-	p := item.Data.(*serialize.MongoPoint)
-	t := &serialize.MongoTag{}
+	p := item.Data.(*mongo.MongoPoint)
+	t := &mongo.MongoTag{}
 	for j := 0; j < p.TagsLength(); j++ {
 		p.Tags(t, j)
 		if string(t.Key()) == "hostname" {
@@ -121,7 +121,7 @@ func (p *aggProcessor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64)
 	eventCnt := uint64(0)
 	for _, event := range batch.arr {
 		tagsMap := map[string]string{}
-		t := &serialize.MongoTag{}
+		t := &mongo.MongoTag{}
 		for j := 0; j < event.TagsLength(); j++ {
 			event.Tags(t, j)
 			tagsMap[string(t.Key())] = string(t.Value())
@@ -155,7 +155,7 @@ func (p *aggProcessor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64)
 		}
 		x := pPool.Get().(*point)
 		x.Fields = map[string]interface{}{}
-		f := &serialize.MongoReading{}
+		f := &mongo.MongoReading{}
 		for j := 0; j < event.FieldsLength(); j++ {
 			event.Fields(f, j)
 			x.Fields[string(f.Key())] = f.Value()
@@ -194,7 +194,7 @@ func (p *aggProcessor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64)
 		// All documents accounted for, finally run the operation
 		_, err := bulk.Run()
 		if err != nil {
-			log.Fatalf("Bulk aggregate update err: %s\n", err.Error())
+			logging.Fatal("could not bulk update aggregate docs", "error", err)
 		}
 
 		for _, events := range docToEvents {
@@ -222,7 +222,7 @@ func insertNewAggregateDocs(collection *mgo.Collection, bulk *mgo.Bulk, createQu
 			b.Insert(createQueue[off:l]...)
 			_, err := b.Run()
 			if err != nil {
-				log.Fatalf("Bulk aggregate docs err: %s\n", err.Error())
+				logging.Fatal("could not bulk insert aggregate docs", "error", err)
 			}
 			b = collection.Bulk()
 
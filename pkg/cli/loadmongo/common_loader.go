@@ -1,15 +1,15 @@
-package main
+package loadmongo
 
 import (
 	"bufio"
 	"encoding/binary"
 	"fmt"
 	"io"
-	"log"
 
 	flatbuffers "github.com/google/flatbuffers/go"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mongo"
 )
 
 type decoder struct {
@@ -17,14 +17,14 @@ type decoder struct {
 }
 
 func (d *decoder) Decode(r *bufio.Reader) *load.Point {
-	item := &serialize.MongoPoint{}
+	item := &mongo.MongoPoint{}
 
 	_, err := r.Read(d.lenBuf)
 	if err == io.EOF {
 		return nil
 	}
 	if err != nil {
-		log.Fatal(err.Error())
+		cli.Fatal(cli.ExitData, "could not read item length", "error", err)
 	}
 
 	// ensure correct len of receiving buffer
@@ -37,7 +37,7 @@ func (d *decoder) Decode(r *bufio.Reader) *load.Point {
 		m, err := r.Read(itemBuf[totRead:])
 		// (EOF is also fatal)
 		if err != nil {
-			log.Fatal(err.Error())
+			cli.Fatal(cli.ExitData, "could not read item", "error", err)
 		}
 		totRead += m
 	}
@@ -51,7 +51,7 @@ func (d *decoder) Decode(r *bufio.Reader) *load.Point {
 }
 
 type batch struct {
-	arr []*serialize.MongoPoint
+	arr []*mongo.MongoPoint
 }
 
 func (b *batch) Len() int {
@@ -59,14 +59,14 @@ func (b *batch) Len() int {
 }
 
 func (b *batch) Append(item *load.Point) {
-	that := item.Data.(*serialize.MongoPoint)
+	that := item.Data.(*mongo.MongoPoint)
 	b.arr = append(b.arr, that)
 }
 
 type factory struct{}
 
 func (f *factory) New() load.Batch {
-	return &batch{arr: []*serialize.MongoPoint{}}
+	return &batch{arr: []*mongo.MongoPoint{}}
 }
 
 type mongoBenchmark struct {
@@ -85,3 +85,7 @@ func (b *mongoBenchmark) GetBatchFactory() load.BatchFactory {
 func (b *mongoBenchmark) GetDBCreator() load.DBCreator {
 	return b.dbc
 }
+
+func (b *mongoBenchmark) DataFormat() string {
+	return mongo.Format
+}
@@ -1,12 +1,12 @@
-package main
+package loadmongo
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
+	"github.com/timescale/tsbs/pkg/cli"
 )
 
 type dbCreator struct {
@@ -17,7 +17,7 @@ func (d *dbCreator) Init() {
 	var err error
 	d.session, err = mgo.DialWithTimeout(daemonURL, writeTimeout)
 	if err != nil {
-		log.Fatal(err)
+		cli.Fatal(cli.ExitUnreachable, "could not connect to MongoDB", "error", err)
 	}
 	d.session.SetMode(mgo.Eventual, false)
 }
@@ -25,7 +25,7 @@ func (d *dbCreator) Init() {
 func (d *dbCreator) DBExists(dbName string) bool {
 	dbs, err := d.session.DatabaseNames()
 	if err != nil {
-		log.Fatal(err)
+		cli.Fatal(cli.ExitUnreachable, "could not list databases", "error", err)
 	}
 	for _, name := range dbs {
 		if name == dbName {
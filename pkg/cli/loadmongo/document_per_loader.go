@@ -1,12 +1,12 @@
-package main
+package loadmongo
 
 import (
-	"log"
 	"sync"
 
 	"github.com/globalsign/mgo"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
 	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/data/serialize/mongo"
+	"github.com/timescale/tsbs/pkg/logging"
 )
 
 // naiveBenchmark allows you to run a benchmark using the naive, one document per
@@ -69,12 +69,12 @@ func (p *naiveProcessor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64
 		x.Timestamp = event.Timestamp()
 		x.Fields = map[string]interface{}{}
 		x.Tags = map[string]string{}
-		f := &serialize.MongoReading{}
+		f := &mongo.MongoReading{}
 		for j := 0; j < event.FieldsLength(); j++ {
 			event.Fields(f, j)
 			x.Fields[string(f.Key())] = f.Value()
 		}
-		t := &serialize.MongoTag{}
+		t := &mongo.MongoTag{}
 		for j := 0; j < event.TagsLength(); j++ {
 			event.Tags(t, j)
 			x.Tags[string(t.Key())] = string(t.Value())
@@ -88,7 +88,7 @@ func (p *naiveProcessor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64
 		bulk.Insert(p.pvs...)
 		_, err := bulk.Run()
 		if err != nil {
-			log.Fatalf("Bulk insert docs err: %s\n", err.Error())
+			logging.Fatal("could not bulk insert docs", "error", err)
 		}
 	}
 	for _, p := range p.pvs {
@@ -0,0 +1,78 @@
+// Package loadmongo implements tsbs_load_mongo (also run as
+// `tsbs load mongo`), which loads a Mongo daemon with data from stdin.
+//
+// Any existing collections in the database will be removed.
+package loadmongo
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/provision"
+)
+
+const (
+	collectionName     = "point_data"
+	aggDocID           = "doc_id"
+	aggDateFmt         = "20060102_15" // see Go docs for how we arrive at this time format
+	aggKeyID           = "key_id"
+	aggInsertBatchSize = 500 // found via trial-and-error
+	timestampField     = "timestamp_ns"
+)
+
+// Program option vars:
+var (
+	daemonURL    string
+	documentPer  bool
+	writeTimeout time.Duration
+	container    *provision.Options
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+)
+
+// parseFlags registers the command line flags of tsbs_load_mongo and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&daemonURL, "url", "localhost:27017", "Mongo URL.")
+	flag.DurationVar(&writeTimeout, "write-timeout", 10*time.Second, "Write timeout.")
+	flag.BoolVar(&documentPer, "document-per-event", false, "Whether to use one document per event or aggregate by hour")
+
+	container = provision.AddFlags(flag.CommandLine, "mongo")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_load_mongo", args)
+}
+
+// Run runs tsbs_load_mongo with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "mongo", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		daemonURL = c.Endpoint()
+	}
+	var benchmark load.Benchmark
+	var workQueues uint
+	if documentPer {
+		benchmark = newNaiveBenchmark(loader)
+		workQueues = load.SingleQueue
+	} else {
+		benchmark = newAggBenchmark(loader)
+		workQueues = load.WorkerPerQueue
+	}
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_mongo")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(benchmark, workQueues)))
+}
@@ -0,0 +1,132 @@
+package loadmysql
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// column is a column of a table after the tag columns of all tables, of a
+// tag of its measurement alone or of a field, as given by the header
+type column struct {
+	name string
+	typ  string
+}
+
+// sqlTypes are the types of the columns the header may give
+var sqlTypes = map[string]bool{"DOUBLE": true, "BIGINT": true, "BOOLEAN": true, "TEXT": true, mysql.TagType: true}
+
+type dbCreator struct {
+	br *bufio.Reader
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the header at the start of the data, up to an empty
+// line: the tag keys, as the tags line, followed by each measurement and the
+// definitions of its other columns
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	tableCols = make(map[string][]column)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		fatalData("input has wrong header format: %v", err)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+	if parts[0] != tagsPrefix || len(parts) < 2 {
+		fatalData("input has wrong header format: got '%s', expected the tags", parts[0])
+		return
+	}
+	tagCols = make([]string, len(parts)-1)
+	for i, p := range parts[1:] {
+		tagCols[i] = mysql.Unescape(p)
+	}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			break
+		}
+		parts = strings.Split(line, "\t")
+		cols := make([]column, len(parts)-1)
+		for i, p := range parts[1:] {
+			j := strings.LastIndexByte(p, ' ')
+			if j < 0 || !sqlTypes[p[j+1:]] {
+				fatalData("input has wrong header format: invalid column definition '%s'", p)
+				return
+			}
+			cols[i] = column{name: mysql.Unescape(p[:j]), typ: p[j+1:]}
+		}
+		tableCols[mysql.Unescape(parts[0])] = cols
+	}
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	db := mustConnect(getDSN(""))
+	defer db.Close()
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", dbName); err != nil {
+		fatal(cli.ExitFailure, "could not list databases", "error", err)
+	}
+	return count > 0
+}
+
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	db := mustConnect(getDSN(""))
+	defer db.Close()
+	_, err := db.Exec("DROP DATABASE IF EXISTS " + quote(dbName))
+	return err
+}
+
+// CreateDB creates the database, with the table of each measurement
+func (d *dbCreator) CreateDB(dbName string) error {
+	db := mustConnect(getDSN(""))
+	_, err := db.Exec("CREATE DATABASE " + quote(dbName))
+	db.Close()
+	if err != nil {
+		return err
+	}
+
+	dbBench := mustConnect(getDSN(dbName))
+	defer dbBench.Close()
+	for table, cols := range tableCols {
+		if _, err := dbBench.Exec(createTable(table, cols)); err != nil {
+			return fmt.Errorf("could not create table %s: %v", table, err)
+		}
+	}
+	return nil
+}
+
+// createTable returns the statement creating the table of a measurement,
+// with the given columns after those of the tags, and its indexes. Tags are VARCHARs, so the
+// primary one can be indexed.
+func createTable(table string, cols []column) string {
+	defs := []string{"`time` DATETIME(6) NOT NULL"}
+	for _, t := range tagCols {
+		defs = append(defs, quote(t)+" VARCHAR(255)")
+	}
+	for _, c := range cols {
+		defs = append(defs, quote(c.name)+" "+c.typ)
+	}
+	if partitionIndex {
+		defs = append(defs, fmt.Sprintf("INDEX (%s, `time`)", quote(tagCols[0])))
+	}
+	if timeIndex {
+		defs = append(defs, "INDEX (`time`)")
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s) ENGINE=%s", quote(table), strings.Join(defs, ", "), engine)
+}
+
+// quote returns name quoted as a MySQL identifier
+func quote(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
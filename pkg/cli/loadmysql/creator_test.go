@@ -0,0 +1,100 @@
+package loadmysql
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDBCreatorReadDataHeader(t *testing.T) {
+	cases := []struct {
+		desc         string
+		input        string
+		wantTags     []string
+		wantTables   map[string][]column
+		wantBuffered int
+		shouldFatal  bool
+	}{
+		{
+			desc:       "a single table",
+			input:      "tags\ttag1\ttag2\ncols\tcol1 DOUBLE\tcol2 BIGINT\n\n",
+			wantTags:   []string{"tag1", "tag2"},
+			wantTables: map[string][]column{"cols": {{"col1", "DOUBLE"}, {"col2", "BIGINT"}}},
+		},
+		{
+			desc:         "escaped names, followed by rows",
+			input:        "tags\ttag\\t1\ncols\tcol 1 TEXT\ncols2\tcol21 BOOLEAN\n\nrow1\nrow2\n",
+			wantTags:     []string{"tag\t1"},
+			wantTables:   map[string][]column{"cols": {{"col 1", "TEXT"}}, "cols2": {{"col21", "BOOLEAN"}}},
+			wantBuffered: len("row1\nrow2\n"),
+		},
+		{
+			desc:       "tags of a single table",
+			input:      "tags\ttag1\ncols\ttag2 VARCHAR(255)\tcol1 DOUBLE\n\n",
+			wantTags:   []string{"tag1"},
+			wantTables: map[string][]column{"cols": {{"tag2", "VARCHAR(255)"}, {"col1", "DOUBLE"}}},
+		},
+		{
+			desc:        "no tags",
+			input:       "tags\ncols\tcol1 DOUBLE\n\n",
+			shouldFatal: true,
+		},
+		{
+			desc:        "invalid type",
+			input:       "tags\ttag1\ncols\tcol1 DOUBLE); DROP TABLE cols\n\n",
+			shouldFatal: true,
+		},
+		{
+			desc:        "no empty line",
+			input:       "tags\ttag1\ncols\tcol1 DOUBLE\n",
+			shouldFatal: true,
+		},
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	for _, c := range cases {
+		called := false
+		fatalData = func(string, ...interface{}) { called = true }
+		br := bufio.NewReader(bytes.NewBufferString(c.input))
+		(&dbCreator{}).readDataHeader(br)
+		if called != c.shouldFatal {
+			t.Errorf("%s: incorrect fatal: got %v want %v", c.desc, called, c.shouldFatal)
+			continue
+		}
+		if c.shouldFatal {
+			continue
+		}
+		if !reflect.DeepEqual(tagCols, c.wantTags) {
+			t.Errorf("%s: incorrect tags: got %q want %q", c.desc, tagCols, c.wantTags)
+		}
+		if !reflect.DeepEqual(tableCols, c.wantTables) {
+			t.Errorf("%s: incorrect tables: got %v want %v", c.desc, tableCols, c.wantTables)
+		}
+		if br.Buffered() != c.wantBuffered {
+			t.Errorf("%s: incorrect amount buffered: got %d want %d", c.desc, br.Buffered(), c.wantBuffered)
+		}
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	defer useTestHeader()()
+	oldTimeIndex := timeIndex
+	defer func() { timeIndex = oldTimeIndex }()
+
+	want := "CREATE TABLE `cpu` (`time` DATETIME(6) NOT NULL, `hostname` VARCHAR(255), `region` VARCHAR(255), " +
+		"`usage_user` DOUBLE, `usage_system` DOUBLE, INDEX (`hostname`, `time`), INDEX (`time`)) ENGINE=InnoDB"
+	if got := createTable("cpu", tableCols["cpu"]); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+
+	timeIndex = false
+	if got := createTable("cpu", tableCols["cpu"]); strings.Contains(got, "INDEX (`time`)") {
+		t.Errorf("time index created when disabled: %s", got)
+	}
+	if got, want := quote("a`b"), "`a``b`"; got != want {
+		t.Errorf("incorrect quoting: got %s want %s", got, want)
+	}
+}
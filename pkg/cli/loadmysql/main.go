@@ -0,0 +1,151 @@
+// Package loadmysql implements tsbs_load_mysql (also run as `tsbs load
+// mysql`), which loads a MySQL or MariaDB server with data from stdin.
+//
+// The data is in the mysql format: a table is created for each measurement,
+// from the header of the data, with a column of the time, of each tag and of
+// each field, and the rows of each batch are loaded with multi-row INSERTs.
+//
+// If the database exists beforehand, it will be *DROPPED*.
+package loadmysql
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+	"github.com/timescale/tsbs/pkg/provision"
+)
+
+// Program option vars:
+var (
+	host     string
+	port     int
+	user     string
+	password string
+
+	engine         string
+	timeIndex      bool
+	partitionIndex bool
+
+	container *provision.Options
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	// tagCols are the tag columns of the tables, and tableCols the field
+	// columns of the table of each measurement, as read from the header
+	tagCols   []string
+	tableCols map[string][]column
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_mysql and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&host, "host", "localhost", "Hostname of the MySQL server")
+	flag.IntVar(&port, "port", 3306, "Port of the MySQL server")
+	flag.StringVar(&user, "user", "root", "User to connect to MySQL as")
+	flag.StringVar(&password, "password", "", "Password of the user")
+
+	flag.StringVar(&engine, "engine", "InnoDB", "Storage engine of the tables")
+	flag.BoolVar(&timeIndex, "time-index", true, "Whether to build an index on the time of the tables")
+	flag.BoolVar(&partitionIndex, "partition-index", true, "Whether to build an index on the primary tag and time of the tables")
+
+	container = provision.AddFlags(flag.CommandLine, "mysql")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_mysql", args); err != nil {
+		return err
+	}
+	if port <= 0 || port > 65535 {
+		return cli.ConfigError(fmt.Errorf("invalid port %d", port))
+	}
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{br: br, scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return mysql.Format
+}
+
+// Run runs tsbs_load_mysql with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "mysql", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		host, port = c.Host, c.Port
+	}
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_mysql")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
+
+// getDSN returns the data source name to connect to dbName with, or to the
+// server without a database if it is empty. Parameters are interpolated by
+// the driver rather than sent with prepared statements, so each INSERT is a
+// single round trip.
+func getDSN(dbName string) string {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = user
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	cfg.DBName = dbName
+	cfg.InterpolateParams = true
+	return cfg.FormatDSN()
+}
+
+// mustConnect connects to MySQL with dsn, exiting if it cannot be reached
+func mustConnect(dsn string) *sqlx.DB {
+	db, err := sqlx.Connect("mysql", dsn)
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not connect to MySQL", "error", err)
+	}
+	return db
+}
@@ -0,0 +1,27 @@
+package loadmysql
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
+func TestGetDSN(t *testing.T) {
+	oldPassword := password
+	defer func() { password = oldPassword }()
+
+	if got, want := getDSN("benchmark"), "root@tcp(localhost:3306)/benchmark?interpolateParams=true"; got != want {
+		t.Errorf("incorrect DSN: got %s want %s", got, want)
+	}
+	password = "secret"
+	if got, want := getDSN(""), "root:secret@tcp(localhost:3306)/?interpolateParams=true"; got != want {
+		t.Errorf("incorrect DSN without a database: got %s want %s", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package loadmysql
+
+import (
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// maxParams is the most placeholders MySQL takes in a statement, which the
+// rows of each INSERT are kept under, though the driver interpolates them
+const maxParams = 65535
+
+type processor struct {
+	db *sqlx.DB
+}
+
+func (p *processor) Init(_ int, doLoad bool) {
+	if doLoad {
+		p.db = mustConnect(getDSN(loader.DatabaseName()))
+	}
+}
+
+func (p *processor) Close(doLoad bool) {
+	if doLoad {
+		p.db.Close()
+	}
+}
+
+// ProcessBatch inserts the rows of each table in the batch, with as few
+// multi-row INSERTs as maxParams allows
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	var metrics uint64
+	for table, rows := range batch.tables {
+		cols, ok := tableCols[table]
+		if !ok {
+			fatalData("row of table %s, which is not in the header", table)
+			return 0, 0
+		}
+		nCols := 1 + len(tagCols) + len(cols)
+		args := make([]interface{}, 0, len(rows)*nCols)
+		for _, r := range rows {
+			values := parseRow(r, nCols)
+			if values == nil {
+				return 0, 0
+			}
+			args = append(args, values...)
+			metrics += uint64(len(cols))
+		}
+		if !doLoad {
+			continue
+		}
+		perInsert := maxParams / nCols
+		for start := 0; start < len(rows); start += perInsert {
+			end := start + perInsert
+			if end > len(rows) {
+				end = len(rows)
+			}
+			p.insert(table, cols, args[start*nCols:end*nCols], end-start)
+		}
+	}
+	return metrics, uint64(batch.rows)
+}
+
+// parseRow returns the values of the nCols columns of a row, unescaped, with
+// nil for those which are NULL
+func parseRow(r string, nCols int) []interface{} {
+	parts := strings.Split(r, "\t")
+	if len(parts) != nCols {
+		fatalData("parse error: row has %d columns, expected %d: %s", len(parts), nCols, r)
+		return nil
+	}
+	values := make([]interface{}, nCols)
+	for i, v := range parts {
+		if v != mysql.Null {
+			values[i] = mysql.Unescape(v)
+		}
+	}
+	return values
+}
+
+// insertStatement returns the INSERT of rows rows into table, with its
+// field columns cols
+func insertStatement(table string, cols []column, rows int) string {
+	names := make([]string, 0, 1+len(tagCols)+len(cols))
+	names = append(names, "`time`")
+	for _, t := range tagCols {
+		names = append(names, quote(t))
+	}
+	for _, c := range cols {
+		names = append(names, quote(c.name))
+	}
+	params := "(" + strings.TrimSuffix(strings.Repeat("?,", len(names)), ",") + ")"
+
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO ")
+	sb.WriteString(quote(table))
+	sb.WriteString(" (")
+	sb.WriteString(strings.Join(names, ","))
+	sb.WriteString(") VALUES ")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(params)
+	}
+	return sb.String()
+}
+
+// insert inserts rows rows into table, with their values in args
+func (p *processor) insert(table string, cols []column, args []interface{}, rows int) {
+	_, err := p.db.Exec(insertStatement(table, cols, rows), args...)
+	if err == nil {
+		return
+	}
+	if isDataError(err) {
+		fatal(cli.ExitData, "rows rejected", "table", table, "error", err)
+		return
+	}
+	fatal(cli.ExitFailure, "could not insert rows", "table", table, "error", err)
+}
+
+// isDataError returns whether err is from values which do not fit their
+// columns, e.g., a value that is not a number, which are of SQLSTATE class
+// 22, or incorrect values of strict mode
+func isDataError(err error) bool {
+	e, ok := err.(*mysqldriver.MySQLError)
+	return ok && (string(e.SQLState[:2]) == "22" || e.Number == 1366)
+}
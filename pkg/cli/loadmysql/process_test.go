@@ -0,0 +1,83 @@
+package loadmysql
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// useTestHeader sets the header read as that of the cpu table with two
+// fields, returning a function restoring it
+func useTestHeader() func() {
+	oldTagCols, oldTableCols := tagCols, tableCols
+	tagCols = []string{"hostname", "region"}
+	tableCols = map[string][]column{"cpu": {{"usage_user", "DOUBLE"}, {"usage_system", "DOUBLE"}}}
+	return func() {
+		tagCols, tableCols = oldTagCols, oldTableCols
+	}
+}
+
+func TestDecodeAndProcessBatch(t *testing.T) {
+	defer useTestHeader()()
+	data := "cpu\t2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58\t2\n" +
+		"cpu\t2016-01-01 00:00:10.000000\thost_1\t\\N\t3\t\\N\n"
+	d := &decoder{scanner: bufio.NewScanner(bytes.NewBufferString(data))}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(nil); p != nil; p = d.Decode(nil) {
+		b.Append(p)
+	}
+	if b.Len() != 2 || len(b.tables["cpu"]) != 2 || b.tables["cpu"][1] != "2016-01-01 00:00:10.000000\thost_1\t\\N\t3\t\\N" {
+		t.Fatalf("incorrect batch: %d rows, %v", b.Len(), b.tables)
+	}
+
+	p := &processor{}
+	p.Init(0, false)
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 4 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	defer useTestHeader()()
+	tableCols = nil
+	data := "tags\thostname\tregion\ncpu\tusage_user DOUBLE\n\ncpu\t2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58\n"
+	br := bufio.NewReader(bytes.NewBufferString(data))
+	d := &decoder{br: br, scanner: bufio.NewScanner(br)}
+	p := d.Decode(br)
+	if p == nil || len(tableCols["cpu"]) != 1 || len(tagCols) != 2 {
+		t.Fatalf("header not read: %v %v", tagCols, tableCols)
+	}
+	if got := p.Data.(*point); got.table != "cpu" || got.values != "2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58" {
+		t.Errorf("incorrect row after the header: %+v", got)
+	}
+}
+
+func TestParseRow(t *testing.T) {
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+
+	values := parseRow("2016-01-01 00:00:00.000000\thost\\t0\t\\N\t58", 4)
+	if got != "" || len(values) != 4 {
+		t.Fatalf("row not parsed: %s", got)
+	}
+	if values[0] != "2016-01-01 00:00:00.000000" || values[1] != "host\t0" || values[2] != nil || values[3] != "58" {
+		t.Errorf("incorrect values: %q", values)
+	}
+	if parseRow("2016-01-01 00:00:00.000000\thost_0", 4) != nil || got == "" {
+		t.Errorf("row with too few columns not rejected")
+	}
+}
+
+func TestInsertStatement(t *testing.T) {
+	defer useTestHeader()()
+	want := "INSERT INTO `cpu` (`time`,`hostname`,`region`,`usage_user`,`usage_system`) VALUES (?,?,?,?,?),(?,?,?,?,?)"
+	if got := insertStatement("cpu", tableCols["cpu"], 2); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+	if got := insertStatement("cpu", tableCols["cpu"], 1); strings.Count(got, "?") != 5 {
+		t.Errorf("incorrect statement of a row: %s", got)
+	}
+}
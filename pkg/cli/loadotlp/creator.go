@@ -0,0 +1,19 @@
+package loadotlp
+
+// dbCreator does nothing, as OTLP has no databases: the receiver stores the
+// metrics sent to it as it is configured to
+type dbCreator struct{}
+
+func (d *dbCreator) Init() {}
+
+func (d *dbCreator) DBExists(_ string) bool {
+	return false
+}
+
+func (d *dbCreator) RemoveOldDB(_ string) error {
+	return nil
+}
+
+func (d *dbCreator) CreateDB(_ string) error {
+	return nil
+}
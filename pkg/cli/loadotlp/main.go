@@ -0,0 +1,109 @@
+// Package loadotlp implements tsbs_load_otlp (also run as `tsbs load otlp`),
+// which loads any receiver of the OpenTelemetry protocol (OTLP), e.g., an
+// OpenTelemetry collector or a backend implementing OTLP/HTTP, with data from
+// stdin.
+//
+// The data is in the otlp format, an ExportMetricsServiceRequest per
+// reading, and the requests of each batch are sent as one to the metrics
+// endpoint of the receiver (/v1/metrics), in protobuf. Requests have the
+// headers in -headers, e.g., to authenticate them.
+//
+// OTLP has no databases, so -db-name is not used and nothing is created or
+// deleted: the receiver stores the metrics as it is configured to.
+package loadotlp
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/otlp"
+	otlpapi "github.com/timescale/tsbs/pkg/otlp"
+)
+
+// Program option vars:
+var (
+	receiverURL string
+	headers     string
+	useGzip     bool
+	backoff     time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	header http.Header
+	client *otlpapi.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_otlp and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&receiverURL, "url", "http://localhost:4318", "Base URL of the OTLP/HTTP receiver, which metrics are sent to at "+otlpapi.MetricsPath)
+	flag.StringVar(&headers, "headers", os.Getenv(otlpapi.HeadersEnv), "Headers of the requests, as comma-separated key=value pairs with URL-encoded values (default: $"+otlpapi.HeadersEnv+")")
+	flag.BoolVar(&useGzip, "gzip", true, "Whether to gzip encode requests")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when the receiver is overloaded")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_otlp", args); err != nil {
+		return err
+	}
+	var err error
+	if header, err = otlpapi.ParseHeaders(headers); err != nil {
+		return cli.ConfigError(fmt.Errorf("invalid -headers: %v", err))
+	}
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(_ *bufio.Reader) load.PointDecoder {
+	return &decoder{}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return otlp.Format
+}
+
+// Run runs tsbs_load_otlp with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = otlpapi.NewClient(receiverURL, header)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_otlp")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
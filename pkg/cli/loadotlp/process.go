@@ -0,0 +1,134 @@
+package loadotlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"net/http"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	otlpapi "github.com/timescale/tsbs/pkg/otlp"
+)
+
+// Tags of the fields of ExportMetricsServiceResponse.partial_success (field
+// 1, length-delimited), and of its rejected_data_points (field 1, a varint)
+// and error_message (field 2, length-delimited)
+const (
+	tagPartialSuccess     = 1<<3 | 2
+	tagRejectedDataPoints = 1<<3 | 0
+	tagErrorMessage       = 2<<3 | 2
+)
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	header    http.Header
+	gzipped   bytes.Buffer
+	gzip      *gzip.Writer
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	p.header = http.Header{}
+	p.header.Set("Content-Type", "application/x-protobuf")
+	if useGzip {
+		p.header.Set("Content-Encoding", "gzip")
+		p.gzip = gzip.NewWriter(&p.gzipped)
+	}
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch exports the ExportMetricsServiceRequest of the batch with a
+// single request, retrying it after sleeping for -backoff while the
+// receiver is overloaded
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		body := batch.buf.Bytes()
+		if p.gzip != nil {
+			p.gzipped.Reset()
+			p.gzip.Reset(&p.gzipped)
+			p.gzip.Write(body)
+			p.gzip.Close()
+			body = p.gzipped.Bytes()
+		}
+		p.write(body)
+	}
+	return batch.metrics, batch.rows
+}
+
+func (p *processor) write(body []byte) {
+	for {
+		resp, err := client.Do(http.MethodPost, otlpapi.MetricsPath, p.header, body)
+		if err == nil {
+			// receivers accepting only some of the data points say so in
+			// the partial success of the response
+			if rejected, msg := partialSuccess(resp); rejected > 0 {
+				fatal(cli.ExitData, "data points rejected", "worker", p.workerNum, "count", rejected, "error", msg)
+			}
+			return
+		}
+		e, ok := err.(*otlpapi.Error)
+		switch {
+		case !ok:
+			fatal(cli.ExitUnreachable, "could not export metrics", "worker", p.workerNum, "url", client.URL(), "error", err)
+		case e.Throttled():
+			p.throttled++
+			logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+			sleep(backoff)
+			continue
+		case e.Status == http.StatusBadRequest:
+			fatal(cli.ExitData, "metrics rejected", "worker", p.workerNum, "error", err)
+		default:
+			fatal(cli.ExitFailure, "could not export metrics", "worker", p.workerNum, "error", err)
+		}
+		return
+	}
+}
+
+// partialSuccess returns the number of data points rejected and the error
+// message of the partial success of an ExportMetricsServiceResponse in
+// protobuf, which are zero and empty if it has none or is not valid
+func partialSuccess(resp []byte) (int64, string) {
+	var rejected int64
+	var msg string
+	for len(resp) > 1 && resp[0] == tagPartialSuccess {
+		size, k := binary.Uvarint(resp[1:])
+		if k <= 0 || size > uint64(len(resp)-1-k) {
+			return 0, ""
+		}
+		ps := resp[1+k : 1+k+int(size)]
+		resp = resp[1+k+int(size):]
+		for len(ps) > 1 {
+			x, n := binary.Uvarint(ps[1:])
+			if n <= 0 {
+				return 0, ""
+			}
+			switch ps[0] {
+			case tagRejectedDataPoints:
+				rejected = int64(x)
+				ps = ps[1+n:]
+			case tagErrorMessage:
+				if x > uint64(len(ps)-1-n) {
+					return 0, ""
+				}
+				msg = string(ps[1+n : 1+n+int(x)])
+				ps = ps[1+n+int(x):]
+			default:
+				return 0, ""
+			}
+		}
+	}
+	return rejected, msg
+}
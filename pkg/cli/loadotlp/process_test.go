@@ -0,0 +1,206 @@
+package loadotlp
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/otlp"
+	otlpapi "github.com/timescale/tsbs/pkg/otlp"
+)
+
+// testAPI is a fake OTLP/HTTP receiver, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   [][]byte
+	// handle, if set, answers requests instead of an empty response
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var body []byte
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ = ioutil.ReadAll(zr)
+	} else {
+		body, _ = ioutil.ReadAll(r.Body)
+	}
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.bodies = append(a.bodies, body)
+	status, resp := http.StatusOK, ""
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldFatal, oldSleep := client, fatal, sleep
+	client = otlpapi.NewClient(server.URL, http.Header{"X-Scope-Orgid": {"tsbs"}})
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	sleep = func(time.Duration) {}
+	return func() {
+		server.Close()
+		client, fatal, sleep = oldClient, oldFatal, oldSleep
+	}
+}
+
+// testData returns n readings of three fields, one a string, in the otlp
+// format
+func testData(t *testing.T, n int) []byte {
+	var buf bytes.Buffer
+	s, err := serialize.New(otlp.Format, nil, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		p := serialize.NewPoint()
+		p.SetMeasurementName([]byte("cpu"))
+		p.SetTimestamp(int64(i) * 1e9)
+		p.AppendTag([]byte("hostname"), []byte("host_0"))
+		p.AppendField([]byte("usage_user"), 58.0)
+		p.AppendField([]byte("usage_system"), int64(2))
+		p.AppendField([]byte("label"), "text")
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestDecodeAndAppend(t *testing.T) {
+	data := testData(t, 3)
+	br := bufio.NewReader(bytes.NewReader(data))
+	d := &decoder{}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(br); p != nil; p = d.Decode(br) {
+		b.Append(p)
+	}
+	if b.Len() != 3 || b.metrics != 6 {
+		t.Errorf("incorrect batch: %d rows, %d metrics", b.Len(), b.metrics)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu,hostname=host_0 usage_user=58i 0")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 3 {
+		t.Errorf("data in another format not rejected: %q", got)
+	}
+	got = ""
+	if p := d.Decode(bufio.NewReader(bytes.NewReader(data[:len(data)-1]))); p == nil || got != "" {
+		t.Errorf("first reading not decoded: %q", got)
+	}
+	d.Decode(bufio.NewReader(bytes.NewReader(data[:10])))
+	if !strings.HasPrefix(got, "could not read ExportMetricsServiceRequest") {
+		t.Errorf("truncated data not rejected: %q", got)
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	calls := 0
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		calls++
+		if calls == 1 {
+			return http.StatusServiceUnavailable, ""
+		}
+		if r.Header.Get("Content-Type") != "application/x-protobuf" || r.Header.Get("X-Scope-Orgid") != "tsbs" {
+			return http.StatusBadRequest, ""
+		}
+		return http.StatusOK, ""
+	}}
+	defer useTestAPI(t, api)()
+
+	data := testData(t, 2)
+	br := bufio.NewReader(bytes.NewReader(data))
+	b := (&factory{}).New().(*batch)
+	for p := (&decoder{}).Decode(br); p != nil; p = (&decoder{}).Decode(br) {
+		b.Append(p)
+	}
+	p := &processor{}
+	p.Init(0, true)
+	if metrics, rows := p.ProcessBatch(b, true); metrics != 4 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	if len(api.requests) != 2 || api.requests[1] != "POST /v1/metrics" || p.throttled != 1 {
+		t.Fatalf("incorrect requests: %v, %d throttled", api.requests, p.throttled)
+	}
+	// the body is the requests of the readings concatenated, without their
+	// length prefixes
+	if string(api.bodies[1]) != b.buf.String() {
+		t.Errorf("incorrect body: %q", api.bodies[1])
+	}
+
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 4 || rows != 2 || len(api.requests) != 2 {
+		t.Errorf("incorrect counts without loading: %d, %d, requests %v", metrics, rows, api.requests)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		resp     string
+		wantCode int
+	}{
+		{desc: "rejected", status: http.StatusBadRequest, wantCode: cli.ExitData},
+		{desc: "failed", status: http.StatusInternalServerError, wantCode: cli.ExitFailure},
+		// a partial success of 2 data points rejected, for "invalid"
+		{desc: "partially rejected", status: http.StatusOK, resp: "\x0a\x0b\x08\x02\x12\x07invalid", wantCode: cli.ExitData},
+		{desc: "an empty partial success", status: http.StatusOK, resp: "\x0a\x00", wantCode: -1},
+	}
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) {
+			return c.status, c.resp
+		}}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append((&decoder{}).Decode(bufio.NewReader(bytes.NewReader(testData(t, 1)))))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
+
+func TestPartialSuccess(t *testing.T) {
+	if rejected, msg := partialSuccess([]byte("\x0a\x0b\x08\x02\x12\x07invalid")); rejected != 2 || msg != "invalid" {
+		t.Errorf("incorrect partial success: %d, %q", rejected, msg)
+	}
+	if rejected, msg := partialSuccess(nil); rejected != 0 || msg != "" {
+		t.Errorf("incorrect partial success of an empty response: %d, %q", rejected, msg)
+	}
+	if rejected, _ := partialSuccess([]byte("\x0a\x7f\x08\x02")); rejected != 0 {
+		t.Errorf("incorrect partial success of an invalid response: %d", rejected)
+	}
+}
@@ -0,0 +1,120 @@
+package loadotlp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/timescale/tsbs/load"
+)
+
+// maxRowSize is the largest ExportMetricsServiceRequest of a reading read,
+// so that data in another format fails instead of being read as a huge
+// request
+const maxRowSize = 16 << 20
+
+// Tags of the fields of the OTLP messages holding the metrics of a request,
+// which are all length-delimited: ExportMetricsServiceRequest.resource_metrics
+// (field 1), ResourceMetrics.scope_metrics (field 2) and ScopeMetrics.metrics
+// (field 2)
+const (
+	tagResourceMetrics = 1<<3 | 2
+	tagScopeMetrics    = 2<<3 | 2
+	tagMetric          = 2<<3 | 2
+)
+
+type decoder struct{}
+
+// Decode reads the ExportMetricsServiceRequest of a reading, prefixed by its
+// length as a varint
+func (d *decoder) Decode(br *bufio.Reader) *load.Point {
+	n, err := binary.ReadUvarint(br)
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		fatalData("could not read length of ExportMetricsServiceRequest: %v", err)
+		return nil
+	}
+	if n > maxRowSize {
+		fatalData("parse error: ExportMetricsServiceRequest of %d bytes is too large", n)
+		return nil
+	}
+	row := make([]byte, n)
+	if _, err := io.ReadFull(br, row); err != nil {
+		fatalData("could not read ExportMetricsServiceRequest: %v", err)
+		return nil
+	}
+	return load.NewPoint(row)
+}
+
+// batch holds the ExportMetricsServiceRequests of a batch concatenated,
+// which is the request of the whole batch
+type batch struct {
+	buf     bytes.Buffer
+	rows    uint64
+	metrics uint64
+}
+
+func (b *batch) Len() int {
+	return int(b.rows)
+}
+
+// Append adds an ExportMetricsServiceRequest to the batch. Each of its
+// metrics is a gauge of a single data point, so they are its metrics.
+func (b *batch) Append(item *load.Point) {
+	row := item.Data.([]byte)
+	metrics, ok := countMetrics(row)
+	if !ok {
+		fatalData("parse error: invalid ExportMetricsServiceRequest of %d bytes", len(row))
+		return
+	}
+	b.rows++
+	b.metrics += uint64(metrics)
+	b.buf.Write(row)
+}
+
+// countMetrics returns the number of metrics of an
+// ExportMetricsServiceRequest, and false if it is not one written by
+// tsbs_generate_data
+func countMetrics(row []byte) (int, bool) {
+	n := 0
+	ok := eachField(row, func(tag byte, rm []byte) bool {
+		return tag == tagResourceMetrics && eachField(rm, func(tag byte, sm []byte) bool {
+			return tag != tagScopeMetrics || eachField(sm, func(tag byte, _ []byte) bool {
+				if tag == tagMetric {
+					n++
+				}
+				return true
+			})
+		})
+	})
+	return n, ok
+}
+
+// eachField calls f with the tag and contents of each field of the protobuf
+// message msg, whose fields must all be length-delimited with single-byte
+// tags, until f returns false. It returns false if f does or msg is not such
+// a message.
+func eachField(msg []byte, f func(tag byte, contents []byte) bool) bool {
+	for len(msg) > 0 {
+		if msg[0]&7 != 2 || msg[0] >= 0x80 {
+			return false
+		}
+		size, k := binary.Uvarint(msg[1:])
+		if k <= 0 || size > uint64(len(msg)-1-k) {
+			return false
+		}
+		if !f(msg[0], msg[1+k:1+k+int(size)]) {
+			return false
+		}
+		msg = msg[1+k+int(size):]
+	}
+	return true
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{}
+}
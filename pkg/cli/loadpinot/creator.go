@@ -0,0 +1,142 @@
+package loadpinot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/pinot"
+)
+
+// metricsPerRow holds the number of metrics in each row of each table, as
+// read from the header
+var metricsPerRow map[string]uint64
+
+// table is a table of the header, with its schema and table config as the
+// controller API takes them
+type table struct {
+	name   string
+	schema json.RawMessage
+	config json.RawMessage
+}
+
+type dbCreator struct {
+	br     *bufio.Reader
+	tables []table
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the schemas and table configs of the tables at the
+// start of the data, up to an empty line
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	metricsPerRow = make(map[string]uint64)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		t, metrics, err := parseTable(line)
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		d.tables = append(d.tables, t)
+		metricsPerRow[t.name] = metrics
+	}
+}
+
+// fieldSpec is a column of a schema
+type fieldSpec struct {
+	Name     string
+	DataType string
+}
+
+// parseTable parses a line of the header: the name of a table, a comma and
+// its schema and table config in JSON. It also returns the number of
+// metrics in each row of the table, which are its metric columns and its
+// BOOLEAN dimensions, while other dimensions are tags or string fields.
+func parseTable(line string) (table, uint64, error) {
+	i := strings.IndexByte(line, ',')
+	if i <= 0 {
+		return table{}, 0, fmt.Errorf("not a table name, schema and table config: %s", line)
+	}
+	t := table{name: line[:i]}
+	var in struct {
+		Schema json.RawMessage
+		Table  json.RawMessage
+	}
+	var schema struct {
+		DimensionFieldSpecs []fieldSpec
+		MetricFieldSpecs    []fieldSpec
+	}
+	if err := json.Unmarshal([]byte(line[i+1:]), &in); err != nil || len(in.Schema) == 0 || len(in.Table) == 0 {
+		return table{}, 0, fmt.Errorf("invalid schema and table config of table %s: %s", t.name, line[i+1:])
+	}
+	if err := json.Unmarshal(in.Schema, &schema); err != nil {
+		return table{}, 0, fmt.Errorf("invalid schema of table %s: %s", t.name, in.Schema)
+	}
+	t.schema, t.config = in.Schema, in.Table
+	metrics := uint64(len(schema.MetricFieldSpecs))
+	for _, f := range schema.DimensionFieldSpecs {
+		if f.DataType == "BOOLEAN" {
+			metrics++
+		}
+	}
+	return t, metrics, nil
+}
+
+// DBExists returns whether the schema of any table of the header exists
+func (d *dbCreator) DBExists(_ string) bool {
+	for _, t := range d.tables {
+		err := client.JSON(http.MethodGet, "/schemas/"+url.PathEscape(t.name), nil, nil)
+		if e, ok := err.(*pinot.Error); ok && e.NotFound() {
+			continue
+		} else if err != nil {
+			fatal(cli.ExitUnreachable, "could not get schema", "table", t.name, "error", err)
+		}
+		return true
+	}
+	return false
+}
+
+// RemoveOldDB deletes the offline tables of the header, along with their
+// segments, and their schemas
+func (d *dbCreator) RemoveOldDB(_ string) error {
+	for _, t := range d.tables {
+		err := client.JSON(http.MethodDelete, "/tables/"+url.PathEscape(t.name)+"?type=offline", nil, nil)
+		if e, ok := err.(*pinot.Error); err != nil && !(ok && e.NotFound()) {
+			return fmt.Errorf("could not delete table %s: %v", t.name, err)
+		}
+		err = client.JSON(http.MethodDelete, "/schemas/"+url.PathEscape(t.name), nil, nil)
+		if e, ok := err.(*pinot.Error); err != nil && !(ok && e.NotFound()) {
+			return fmt.Errorf("could not delete schema %s: %v", t.name, err)
+		}
+	}
+	return nil
+}
+
+// CreateDB creates the schema and offline table of each table of the header
+func (d *dbCreator) CreateDB(_ string) error {
+	header := http.Header{"Content-Type": {"application/json"}}
+	for _, t := range d.tables {
+		if _, err := client.Do(http.MethodPost, "/schemas", header, t.schema); err != nil {
+			return fmt.Errorf("could not create schema %s: %v", t.name, err)
+		}
+		if _, err := client.Do(http.MethodPost, "/tables", header, t.config); err != nil {
+			return fmt.Errorf("could not create table %s: %v", t.name, err)
+		}
+	}
+	return nil
+}
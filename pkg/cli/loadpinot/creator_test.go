@@ -0,0 +1,124 @@
+package loadpinot
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/pinot"
+)
+
+const testHeader = `cpu,{"schema":{"schemaName":"cpu","dimensionFieldSpecs":[{"name":"hostname","dataType":"STRING"},{"name":"idle","dataType":"BOOLEAN"}],"metricFieldSpecs":[{"name":"usage_user","dataType":"DOUBLE"},{"name":"usage_system","dataType":"LONG"}]},"table":{"tableName":"cpu","tableType":"OFFLINE"}}
+mem,{"schema":{"schemaName":"mem","dimensionFieldSpecs":[],"metricFieldSpecs":[{"name":"used","dataType":"LONG"}]},"table":{"tableName":"mem","tableType":"OFFLINE"}}
+
+cpu,{"ts":1451606400000,"hostname":"host_0","idle":false,"usage_user":1,"usage_system":2}
+`
+
+// testAPI is a fake Pinot controller, recording the requests made to it
+type testAPI struct {
+	mu       sync.Mutex
+	requests []string
+	bodies   []string
+	// handle, if set, answers requests instead of an empty JSON object
+	handle func(r *http.Request, body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	body, _ := ioutil.ReadAll(r.Body)
+	a.requests = append(a.requests, r.Method+" "+r.URL.RequestURI())
+	a.bodies = append(a.bodies, string(body))
+	status, resp := http.StatusOK, `{}`
+	if a.handle != nil {
+		status, resp = a.handle(r, body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldFatal, oldSleep := client, fatal, sleep
+	client = pinot.NewClient(server.URL, "")
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	sleep = func(time.Duration) {}
+	return func() {
+		server.Close()
+		client, fatal, sleep = oldClient, oldFatal, oldSleep
+	}
+}
+
+func TestReadDataHeader(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader(testHeader))
+	d := &dbCreator{br: br}
+	d.Init()
+	if len(d.tables) != 2 || d.tables[0].name != "cpu" || d.tables[1].name != "mem" || !strings.Contains(string(d.tables[1].config), `"tableName":"mem"`) {
+		t.Fatalf("incorrect tables: %v", d.tables)
+	}
+	if metricsPerRow["cpu"] != 3 || metricsPerRow["mem"] != 1 {
+		t.Errorf("incorrect metrics per row: %v", metricsPerRow)
+	}
+	if rest, _ := br.ReadString('\n'); !strings.HasPrefix(rest, "cpu,{") {
+		t.Errorf("header not consumed: next line %q", rest)
+	}
+
+	for _, line := range []string{"cpu", "cpu,{}", `cpu,{"schema":{}}`, `cpu,{"schema":[],"table":{}}`, ",{}"} {
+		if _, _, err := parseTable(line); err == nil {
+			t.Errorf("expected an error parsing %q", line)
+		}
+	}
+}
+
+func TestCreateDB(t *testing.T) {
+	api := &testAPI{handle: func(r *http.Request, _ []byte) (int, string) {
+		if r.Method != http.MethodPost && strings.HasSuffix(r.URL.Path, "/mem") {
+			return http.StatusNotFound, `{"code":404,"error":"Schema mem not found"}`
+		}
+		return http.StatusOK, `{}`
+	}}
+	defer useTestAPI(t, api)()
+
+	d := &dbCreator{br: bufio.NewReader(strings.NewReader(testHeader))}
+	d.Init()
+	if !d.DBExists("benchmark") {
+		t.Errorf("existing schema not found")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"GET /schemas/cpu",
+		"DELETE /tables/cpu?type=offline",
+		"DELETE /schemas/cpu",
+		"DELETE /tables/mem?type=offline",
+		"DELETE /schemas/mem",
+		"POST /schemas",
+		"POST /tables",
+		"POST /schemas",
+		"POST /tables",
+	}
+	if got := strings.Join(api.requests, "\n"); got != strings.Join(want, "\n") {
+		t.Fatalf("incorrect requests:\ngot\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	if api.bodies[7] != string(d.tables[1].schema) || api.bodies[8] != `{"tableName":"mem","tableType":"OFFLINE"}` {
+		t.Errorf("incorrect schema and table config: %s, %s", api.bodies[7], api.bodies[8])
+	}
+
+	api.requests = nil
+	d.tables = d.tables[1:]
+	if d.DBExists("benchmark") || len(api.requests) != 1 {
+		t.Errorf("schema not found exists")
+	}
+}
@@ -0,0 +1,97 @@
+// Package loadpinot implements tsbs_load_pinot (also run as `tsbs load
+// pinot`), which loads Apache Pinot with data from stdin.
+//
+// The schemas and offline tables are created from the header of the data
+// with the API of the controller, and the rows of each table in a batch are
+// ingested as a segment of it (/ingestFromFile). Requests are authenticated
+// with the token in -token, if any.
+//
+// Pinot has no databases: the tables are named after the measurements of
+// the data, and -db-name is not used. If the tables or their schemas exist
+// beforehand, they will be *DELETED*.
+package loadpinot
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/pinot"
+	pinotapi "github.com/timescale/tsbs/pkg/pinot"
+)
+
+// Program option vars:
+var (
+	controllerURL string
+	token         string
+	backoff       time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	client *pinotapi.Client
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_pinot and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&controllerURL, "url", "http://localhost:9000", "URL of the Pinot controller")
+	flag.StringVar(&token, "token", os.Getenv(pinotapi.TokenEnv), "Authorization header to authenticate requests with, e.g., 'Basic <base64 user:password>' (default: $"+pinotapi.TokenEnv+")")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when the controller is overloaded")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_load_pinot", args)
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return pinot.Format
+}
+
+// Run runs tsbs_load_pinot with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = pinotapi.NewClient(controllerURL, token)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_pinot")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
@@ -0,0 +1,88 @@
+package loadpinot
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/pinot"
+)
+
+// batchConfig is the batch config of the segments ingested, whose rows are
+// JSON objects
+const batchConfig = `{"inputFormat":"json"}`
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	body      bytes.Buffer
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch ingests the rows of each table of the batch as a segment of
+// its offline table, retrying after sleeping for -backoff while the
+// controller is overloaded
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		for table, r := range batch.tables {
+			p.ingest(table, r.buf.Bytes())
+		}
+	}
+	return batch.metrics, batch.rows
+}
+
+// ingest ingests the rows of a table, one JSON object per line, as a
+// segment, uploading them as a file to the controller, which builds it
+func (p *processor) ingest(table string, data []byte) {
+	v := url.Values{}
+	v.Set("tableNameWithType", table+"_OFFLINE")
+	v.Set("batchConfigMapStr", batchConfig)
+	path := "/ingestFromFile?" + v.Encode()
+
+	p.body.Reset()
+	w := multipart.NewWriter(&p.body)
+	part, _ := w.CreateFormFile("file", table+".json")
+	part.Write(data)
+	w.Close()
+	header := http.Header{"Content-Type": {w.FormDataContentType()}}
+
+	for {
+		_, err := client.Do(http.MethodPost, path, header, p.body.Bytes())
+		if err == nil {
+			return
+		}
+		e, ok := err.(*pinot.Error)
+		switch {
+		case !ok:
+			fatal(cli.ExitUnreachable, "could not ingest rows", "worker", p.workerNum, "url", client.URL(), "error", err)
+		case e.Throttled():
+			p.throttled++
+			logging.Debug("request throttled", "worker", p.workerNum, "error", err)
+			sleep(backoff)
+			continue
+		case e.Status == http.StatusBadRequest:
+			fatal(cli.ExitData, "rows rejected", "worker", p.workerNum, "table", table, "error", err)
+		default:
+			fatal(cli.ExitFailure, "could not ingest rows", "worker", p.workerNum, "table", table, "error", err)
+		}
+		return
+	}
+}
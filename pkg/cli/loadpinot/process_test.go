@@ -0,0 +1,126 @@
+package loadpinot
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+const testRow = `cpu,{"ts":1451606400000,"hostname":"host_0","usage_user":1,"usage_system":2}`
+
+// testBatch returns a batch of n rows of the cpu table and one of the mem
+// table
+func testBatch(n int) *batch {
+	b := (&factory{}).New().(*batch)
+	for i := 0; i < n; i++ {
+		b.Append(load.NewPoint([]byte(testRow)))
+	}
+	b.Append(load.NewPoint([]byte(`mem,{"ts":1451606400000,"used":3}`)))
+	return b
+}
+
+func TestBatchAppend(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 2, "mem": 1}
+	b := testBatch(2)
+	if b.Len() != 3 || b.metrics != 5 || b.tables["cpu"].count != 2 {
+		t.Errorf("incorrect batch: %d rows, %d metrics", b.Len(), b.metrics)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+	b.Append(load.NewPoint([]byte("cpu,2016-01-01T00:00:00Z,host_0,1")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 3 {
+		t.Errorf("line in another format not rejected: %q", got)
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	metricsPerRow = map[string]uint64{"cpu": 2, "mem": 1}
+	files := map[string]string{}
+	calls := 0
+	api := &testAPI{handle: func(r *http.Request, body []byte) (int, string) {
+		calls++
+		if calls == 1 {
+			// the first request is throttled
+			return http.StatusServiceUnavailable, `{"code":503,"error":"too many segment uploads"}`
+		}
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			return http.StatusBadRequest, `{"code":400,"error":"not multipart"}`
+		}
+		part, err := multipart.NewReader(strings.NewReader(string(body)), params["boundary"]).NextPart()
+		if err != nil || part.FormName() != "file" {
+			return http.StatusBadRequest, `{"code":400,"error":"no file"}`
+		}
+		data, _ := ioutil.ReadAll(part)
+		files[r.URL.Query().Get("tableNameWithType")+" "+r.URL.Query().Get("batchConfigMapStr")] = string(data)
+		return http.StatusOK, `{"status":"Successfully ingested file into table"}`
+	}}
+	defer useTestAPI(t, api)()
+
+	p := &processor{}
+	p.Init(0, true)
+	metrics, rows := p.ProcessBatch(testBatch(2), true)
+	if metrics != 5 || rows != 3 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	var tables []string
+	for table := range files {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	want := []string{`cpu_OFFLINE {"inputFormat":"json"}`, `mem_OFFLINE {"inputFormat":"json"}`}
+	if strings.Join(tables, "\n") != strings.Join(want, "\n") || len(api.requests) != 3 || p.throttled != 1 {
+		t.Fatalf("incorrect requests: %v, throttled %d", api.requests, p.throttled)
+	}
+	if got := files[want[1]]; got != `{"ts":1451606400000,"used":3}`+"\n" {
+		t.Errorf("incorrect rows of mem: %q", got)
+	}
+	if got := files[want[0]]; strings.Count(got, "\n") != 2 || !strings.HasPrefix(got, `{"ts":1451606400000,"hostname":"host_0"`) {
+		t.Errorf("incorrect rows of cpu: %q", got)
+	}
+
+	// without loading, nothing is ingested
+	api.requests = nil
+	if metrics, rows := p.ProcessBatch(testBatch(1), false); metrics != 3 || rows != 2 || len(api.requests) != 0 {
+		t.Errorf("incorrect counts without loading: %d, %d, requests %v", metrics, rows, api.requests)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		resp     string
+		wantCode int
+	}{
+		{desc: "bad request", status: http.StatusBadRequest, resp: `{"code":400,"error":"Caught exception while reading file"}`, wantCode: cli.ExitData},
+		{desc: "no table", status: http.StatusNotFound, resp: `{"code":404,"error":"Table cpu_OFFLINE not found"}`, wantCode: cli.ExitFailure},
+		{desc: "server error", status: http.StatusInternalServerError, resp: `{"code":500,"error":"Caught exception when ingesting file"}`, wantCode: cli.ExitFailure},
+	}
+	for _, c := range cases {
+		api := &testAPI{handle: func(*http.Request, []byte) (int, string) { return c.status, c.resp }}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testRow)))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		restore()
+		if gotCode != c.wantCode || len(api.requests) != 1 {
+			t.Errorf("%s: incorrect exit code: got %d want %d after %d requests", c.desc, gotCode, c.wantCode, len(api.requests))
+		}
+	}
+}
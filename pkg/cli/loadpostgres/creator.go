@@ -0,0 +1,134 @@
+package loadpostgres
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+type dbCreator struct {
+	br      *bufio.Reader
+	connStr string
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+
+	// Needed to connect to user's database in order to drop/create db-name database
+	re := regexp.MustCompile(`(dbname)=\S*\b`)
+	d.connStr = strings.TrimSpace(re.ReplaceAllString(d.connStr, ""))
+}
+
+// readDataHeader reads the header at the start of the data, up to an empty
+// line: the tag keys, as the tags line, followed by each measurement and its
+// field keys
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	tableCols = make(map[string][]string)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		fatalData("input has wrong header format: %v", err)
+		return
+	}
+	parts := strings.Split(strings.TrimSpace(line), ",")
+	if parts[0] != tagsPrefix || len(parts) < 2 {
+		fatalData("input has wrong header format: got '%s', expected the tags", parts[0])
+		return
+	}
+	tagCols = parts[1:]
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			break
+		}
+		parts = strings.Split(line, ",")
+		tableCols[parts[0]] = parts[1:]
+	}
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	db := mustConnect(d.connStr)
+	defer db.Close()
+	var exists bool
+	if err := db.Get(&exists, "SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)", dbName); err != nil {
+		fatal(cli.ExitFailure, "could not list databases", "error", err)
+	}
+	return exists
+}
+
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	db := mustConnect(d.connStr)
+	defer db.Close()
+	_, err := db.Exec("DROP DATABASE IF EXISTS " + dbName)
+	return err
+}
+
+// CreateDB creates the database, with the tags table and the partitioned
+// table of each measurement. The partitions are created as rows are loaded.
+func (d *dbCreator) CreateDB(dbName string) error {
+	db := mustConnect(d.connStr)
+	_, err := db.Exec("CREATE DATABASE " + dbName)
+	db.Close()
+	if err != nil {
+		return err
+	}
+
+	dbBench := mustConnect(getConnectString())
+	defer dbBench.Close()
+	for _, stmt := range createTagsTable(tagCols) {
+		if _, err := dbBench.Exec(stmt); err != nil {
+			return fmt.Errorf("could not create the tags table: %v", err)
+		}
+	}
+	for table, cols := range tableCols {
+		for _, stmt := range createTable(table, cols) {
+			if _, err := dbBench.Exec(stmt); err != nil {
+				return fmt.Errorf("could not create table %s: %v", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createTagsTable returns the statements creating the tags table, with a
+// column of each tag and a unique index of them all, so a tag set has a
+// single id
+func createTagsTable(tags []string) []string {
+	return []string{
+		fmt.Sprintf("CREATE TABLE tags(id SERIAL PRIMARY KEY, %s TEXT)", strings.Join(tags, " TEXT, ")),
+		fmt.Sprintf("CREATE UNIQUE INDEX uniq1 ON tags(%s)", strings.Join(tags, ",")),
+		fmt.Sprintf("CREATE INDEX ON tags(%s)", tags[0]),
+	}
+}
+
+// createTable returns the statements creating the table of a measurement
+// with the given fields, partitioned by range of time, and its indexes,
+// which PostgreSQL creates on each partition
+func createTable(table string, fields []string) []string {
+	fieldDefs := make([]string, len(fields))
+	for i, f := range fields {
+		fieldDefs[i] = f + " DOUBLE PRECISION"
+	}
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (time timestamptz NOT NULL, tags_id integer, %s, additional_tags JSONB DEFAULT NULL) PARTITION BY RANGE (time)",
+		table, strings.Join(fieldDefs, ", "))}
+	if partitionIndex {
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX ON %s(tags_id, \"time\" DESC)", table))
+	}
+	if timeIndex {
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX ON %s(\"time\" DESC)", table))
+	}
+	for i, f := range fields {
+		if fieldIndexCount >= 0 && i >= fieldIndexCount {
+			break
+		}
+		stmts = append(stmts, fmt.Sprintf("CREATE INDEX ON %s(%s, \"time\" DESC)", table, f))
+	}
+	return stmts
+}
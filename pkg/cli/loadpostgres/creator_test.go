@@ -0,0 +1,103 @@
+package loadpostgres
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDBCreatorReadDataHeader(t *testing.T) {
+	cases := []struct {
+		desc         string
+		input        string
+		wantTags     string
+		wantTables   map[string]string
+		wantBuffered int
+		shouldFatal  bool
+	}{
+		{
+			desc:       "a single table",
+			input:      "tags,tag1,tag2\ncols,col1,col2\n\n",
+			wantTags:   "tag1,tag2",
+			wantTables: map[string]string{"cols": "col1,col2"},
+		},
+		{
+			desc:         "multiple tables, followed by rows",
+			input:        "tags,tag1,tag2\ncols,col1,col2\ncols2,col21\n\nrow1\nrow2\n",
+			wantTags:     "tag1,tag2",
+			wantTables:   map[string]string{"cols": "col1,col2", "cols2": "col21"},
+			wantBuffered: len("row1\nrow2\n"),
+		},
+		{
+			desc:        "no tags",
+			input:       "tags\ncols\n\n",
+			shouldFatal: true,
+		},
+		{
+			desc:        "no empty line",
+			input:       "tags,tag1\ncols,col1\n",
+			shouldFatal: true,
+		},
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	for _, c := range cases {
+		called := false
+		fatalData = func(string, ...interface{}) { called = true }
+		br := bufio.NewReader(bytes.NewBufferString(c.input))
+		(&dbCreator{}).readDataHeader(br)
+		if called != c.shouldFatal {
+			t.Errorf("%s: incorrect fatal: got %v want %v", c.desc, called, c.shouldFatal)
+			continue
+		}
+		if c.shouldFatal {
+			continue
+		}
+		if got := strings.Join(tagCols, ","); got != c.wantTags {
+			t.Errorf("%s: incorrect tags: got %s want %s", c.desc, got, c.wantTags)
+		}
+		if len(tableCols) != len(c.wantTables) {
+			t.Errorf("%s: incorrect tables: %v", c.desc, tableCols)
+		}
+		for table, want := range c.wantTables {
+			if got := strings.Join(tableCols[table], ","); got != want {
+				t.Errorf("%s: incorrect columns of %s: got %s want %s", c.desc, table, got, want)
+			}
+		}
+		if br.Buffered() != c.wantBuffered {
+			t.Errorf("%s: incorrect amount buffered: got %d want %d", c.desc, br.Buffered(), c.wantBuffered)
+		}
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	oldCount := fieldIndexCount
+	defer func() { fieldIndexCount = oldCount }()
+
+	fieldIndexCount = 1
+	want := []string{
+		`CREATE TABLE cpu (time timestamptz NOT NULL, tags_id integer, usage_user DOUBLE PRECISION, usage_system DOUBLE PRECISION, additional_tags JSONB DEFAULT NULL) PARTITION BY RANGE (time)`,
+		`CREATE INDEX ON cpu(tags_id, "time" DESC)`,
+		`CREATE INDEX ON cpu("time" DESC)`,
+		`CREATE INDEX ON cpu(usage_user, "time" DESC)`,
+	}
+	if got := createTable("cpu", []string{"usage_user", "usage_system"}); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("incorrect statements:\ngot\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	fieldIndexCount = -1
+	if got := createTable("cpu", []string{"usage_user", "usage_system"}); len(got) != 5 {
+		t.Errorf("incorrect statements with all fields indexed:\n%s", strings.Join(got, "\n"))
+	}
+
+	want = []string{
+		"CREATE TABLE tags(id SERIAL PRIMARY KEY, hostname TEXT, region TEXT)",
+		"CREATE UNIQUE INDEX uniq1 ON tags(hostname,region)",
+		"CREATE INDEX ON tags(hostname)",
+	}
+	if got := createTagsTable([]string{"hostname", "region"}); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("incorrect statements of the tags table:\n%s", strings.Join(got, "\n"))
+	}
+}
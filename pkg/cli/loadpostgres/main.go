@@ -0,0 +1,153 @@
+// Package loadpostgres implements tsbs_load_postgres (also run as `tsbs load
+// postgres`), which loads a plain PostgreSQL instance, without TimescaleDB,
+// with data from stdin.
+//
+// The data is in the timescaledb format, and is stored in the same tables
+// as by tsbs_load_timescaledb with its default options: a tags table, and a
+// table of each measurement whose rows refer to their tags by tags_id. The
+// tables of the measurements are partitioned by range of time, with the
+// declarative partitioning of PostgreSQL, rather than being hypertables:
+// each partition covers -partition-interval, and is created as the first
+// rows of its time range are loaded. The rows are loaded with COPY.
+//
+// If the database exists beforehand, it will be *DROPPED*.
+package loadpostgres
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/timescaledb"
+	"github.com/timescale/tsbs/pkg/provision"
+)
+
+// Program option vars:
+var (
+	postgresConnect string
+	host            string
+	user            string
+
+	partitionInterval time.Duration
+	timeIndex         bool
+	partitionIndex    bool
+	fieldIndexCount   int
+
+	container *provision.Options
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	// tagCols are the columns of the tags table, and tableCols the field
+	// columns of the table of each measurement, as read from the header
+	tagCols   []string
+	tableCols map[string][]string
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_postgres and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&postgresConnect, "postgres", "sslmode=disable", "PostgreSQL connection string")
+	flag.StringVar(&host, "host", "localhost", "Hostname of the PostgreSQL instance")
+	flag.StringVar(&user, "user", "postgres", "User to connect to PostgreSQL as")
+
+	flag.DurationVar(&partitionInterval, "partition-interval", 12*time.Hour, "Time range of each partition of the tables, e.g., 12h")
+	flag.BoolVar(&timeIndex, "time-index", true, "Whether to build an index on the time of the tables")
+	flag.BoolVar(&partitionIndex, "partition-index", true, "Whether to build an index on the tags_id and time of the tables")
+	flag.IntVar(&fieldIndexCount, "field-index-count", 0, "Number of fields of each table to build an index on, with the time (-1 for all)")
+
+	container = provision.AddFlags(flag.CommandLine, "postgres")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_postgres", args); err != nil {
+		return err
+	}
+	if partitionInterval < time.Second {
+		return cli.ConfigError(fmt.Errorf("invalid partition interval %v: it must be at least a second", partitionInterval))
+	}
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{br: br, scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader(), connStr: getConnectString()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return timescaledb.Format
+}
+
+// Run runs tsbs_load_postgres with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "postgres", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		host = c.Host
+		postgresConnect += fmt.Sprintf(" port=%d", c.Port)
+	}
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_postgres")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
+
+func getConnectString() string {
+	// User might be passing in host=hostname the connect string out of habit which may override the
+	// multi host configuration. Same for dbname= and user=. This sanitizes that.
+	re := regexp.MustCompile(`(host|dbname|user)=\S*\b`)
+	connectString := strings.TrimSpace(re.ReplaceAllString(postgresConnect, ""))
+
+	return fmt.Sprintf("host=%s dbname=%s user=%s %s", host, loader.DatabaseName(), user, connectString)
+}
+
+// mustConnect connects to PostgreSQL with connStr, exiting if it cannot be
+// reached
+func mustConnect(connStr string) *sqlx.DB {
+	db, err := sqlx.Connect("postgres", connStr)
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not connect to PostgreSQL", "error", err)
+	}
+	return db
+}
@@ -0,0 +1,26 @@
+package loadpostgres
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
+func TestGetConnectString(t *testing.T) {
+	oldConnect := postgresConnect
+	defer func() { postgresConnect = oldConnect }()
+	want := "host=localhost dbname=benchmark user=postgres sslmode=disable"
+	for _, connect := range []string{"host=foo dbname=bar user=joe sslmode=disable", "dbname=bar sslmode=disable", "sslmode=disable"} {
+		postgresConnect = connect
+		if got := getConnectString(); got != want {
+			t.Errorf("incorrect connect string for %q: got %s want %s", connect, got, want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package loadpostgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+// maxParams is the most parameters PostgreSQL takes in a statement
+const maxParams = 65535
+
+// tagIDs maps the values of the tags of each tag set loaded, joined by
+// NULs, to its id in the tags table, for all workers
+var tagIDs = struct {
+	sync.RWMutex
+	m map[string]int64
+}{m: map[string]int64{}}
+
+// partitions holds the partitions created by the workers, keyed by the
+// table and the start of the partition in nanoseconds
+var partitions = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: map[string]bool{}}
+
+// parsedRow is a row to COPY into the table of a measurement, with the
+// values of the tags of its tag set
+type parsedRow struct {
+	tagValues []string
+	tagsKey   string
+	time      time.Time
+	// values are the time, tags_id, additional_tags and fields of the row
+	values []interface{}
+}
+
+type processor struct {
+	db       *sqlx.DB
+	tagIndex map[string]int
+}
+
+func (p *processor) Init(_ int, doLoad bool) {
+	p.tagIndex = make(map[string]int, len(tagCols))
+	for i, k := range tagCols {
+		p.tagIndex[k] = i
+	}
+	if doLoad {
+		p.db = mustConnect(getConnectString())
+	}
+}
+
+func (p *processor) Close(doLoad bool) {
+	if doLoad {
+		p.db.Close()
+	}
+}
+
+// ProcessBatch loads the rows of each table in the batch with COPY, after
+// inserting their new tag sets and creating any partitions missing for
+// their times
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	var metrics uint64
+	for table, rows := range batch.tables {
+		parsed := make([]*parsedRow, 0, len(rows))
+		for _, r := range rows {
+			if pr := p.parseRow(table, r); pr != nil {
+				parsed = append(parsed, pr)
+				metrics += uint64(len(pr.values) - 3)
+			}
+		}
+		if doLoad && len(parsed) > 0 {
+			p.ensureTags(parsed)
+			p.ensurePartitions(table, parsed)
+			p.copyRows(table, parsed)
+		}
+	}
+	return metrics, uint64(batch.rows)
+}
+
+// parseRow parses a row of table. Tags of the header are in the tags table,
+// and any others in additional_tags, as JSON. Empty field values are NULL.
+func (p *processor) parseRow(table string, r *row) *parsedRow {
+	cols, ok := tableCols[table]
+	if !ok {
+		fatalData("row of table %s, which is not in the header", table)
+		return nil
+	}
+	pr := &parsedRow{tagValues: make([]string, len(tagCols))}
+	var additional map[string]string
+	if len(r.tags) > 0 {
+		for _, tag := range strings.Split(r.tags, ",") {
+			kv := strings.SplitN(tag, "=", 2)
+			if len(kv) != 2 {
+				fatalData("parse error: tag %q is not key=value", tag)
+				return nil
+			}
+			if i, ok := p.tagIndex[kv[0]]; ok {
+				pr.tagValues[i] = kv[1]
+			} else {
+				if additional == nil {
+					additional = map[string]string{}
+				}
+				additional[kv[0]] = kv[1]
+			}
+		}
+	}
+	pr.tagsKey = strings.Join(pr.tagValues, "\x00")
+
+	fields := strings.Split(r.fields, ",")
+	if len(fields) != len(cols)+1 {
+		fatalData("parse error: row of table %s has %d fields, expected %d", table, len(fields)-1, len(cols))
+		return nil
+	}
+	ns, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		fatalData("parse error: invalid timestamp %q: %v", fields[0], err)
+		return nil
+	}
+	pr.time = time.Unix(0, ns).UTC()
+	pr.values = make([]interface{}, 0, len(fields)+2)
+	pr.values = append(pr.values, pr.time, nil, nil)
+	if additional != nil {
+		b, _ := json.Marshal(additional)
+		pr.values[2] = string(b)
+	}
+	for _, v := range fields[1:] {
+		if len(v) == 0 {
+			pr.values = append(pr.values, nil)
+		} else {
+			pr.values = append(pr.values, v)
+		}
+	}
+	return pr
+}
+
+// ensureTags sets the tags_id of the rows, inserting their tag sets which
+// are not yet known to any worker into the tags table
+func (p *processor) ensureTags(rows []*parsedRow) {
+	var missing []*parsedRow
+	seen := map[string]bool{}
+	tagIDs.RLock()
+	for _, r := range rows {
+		if _, ok := tagIDs.m[r.tagsKey]; !ok && !seen[r.tagsKey] {
+			missing = append(missing, r)
+			seen[r.tagsKey] = true
+		}
+	}
+	tagIDs.RUnlock()
+
+	if len(missing) > 0 {
+		// sorted, so that loaders inserting the same tag sets lock them
+		// in the same order
+		sort.Slice(missing, func(i, j int) bool { return missing[i].tagsKey < missing[j].tagsKey })
+		tagIDs.Lock()
+		perInsert := maxParams / len(tagCols)
+		for start := 0; start < len(missing); start += perInsert {
+			end := start + perInsert
+			if end > len(missing) {
+				end = len(missing)
+			}
+			p.insertTags(missing[start:end])
+		}
+		tagIDs.Unlock()
+	}
+
+	tagIDs.RLock()
+	for _, r := range rows {
+		r.values[1] = tagIDs.m[r.tagsKey]
+	}
+	tagIDs.RUnlock()
+}
+
+// insertTags inserts the tag sets of rows into the tags table, or gets
+// their ids if they exist, e.g., when inserted by another loader, adding
+// them to tagIDs, which must be locked
+func (p *processor) insertTags(rows []*parsedRow) {
+	values := make([]string, len(rows))
+	args := make([]interface{}, 0, len(rows)*len(tagCols))
+	for i, r := range rows {
+		params := make([]string, len(tagCols))
+		for j, v := range r.tagValues {
+			args = append(args, v)
+			params[j] = "$" + strconv.Itoa(len(args))
+		}
+		values[i] = "(" + strings.Join(params, ",") + ")"
+	}
+	cols := strings.Join(tagCols, ",")
+	// DO UPDATE, rather than DO NOTHING, so the ids of existing tag sets are
+	// returned too
+	q := fmt.Sprintf("INSERT INTO tags(%s) VALUES %s ON CONFLICT (%s) DO UPDATE SET %s = EXCLUDED.%s RETURNING id, %s",
+		cols, strings.Join(values, ","), cols, tagCols[0], tagCols[0], cols)
+	res, err := p.db.Queryx(q, args...)
+	if err != nil {
+		fatal(cli.ExitFailure, "could not insert tags", "error", err)
+		return
+	}
+	defer res.Close()
+	for res.Next() {
+		row, err := res.SliceScan()
+		if err != nil {
+			fatal(cli.ExitFailure, "could not insert tags", "error", err)
+			return
+		}
+		tagValues := make([]string, len(tagCols))
+		for i, v := range row[1:] {
+			tagValues[i] = toString(v)
+		}
+		tagIDs.m[strings.Join(tagValues, "\x00")] = row[0].(int64)
+	}
+	if err := res.Err(); err != nil {
+		fatal(cli.ExitFailure, "could not insert tags", "error", err)
+	}
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	case string:
+		return x
+	}
+	return ""
+}
+
+// partitionStart returns the start of the partition of t: the partitions of
+// the tables are aligned to multiples of -partition-interval since the
+// epoch
+func partitionStart(t time.Time) time.Time {
+	ns := t.UnixNano()
+	offset := ns % int64(partitionInterval)
+	if offset < 0 {
+		offset += int64(partitionInterval)
+	}
+	return time.Unix(0, ns-offset).UTC()
+}
+
+// createPartition returns the statement creating the partition of table
+// starting at start
+func createPartition(table string, start time.Time) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s_p%s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+		table, start.Format("20060102_150405"), table, start.Format(time.RFC3339), start.Add(partitionInterval).Format(time.RFC3339))
+}
+
+// ensurePartitions creates the partitions of table for the times of rows
+// which have not been created yet
+func (p *processor) ensurePartitions(table string, rows []*parsedRow) {
+	partitions.Lock()
+	defer partitions.Unlock()
+	for _, r := range rows {
+		start := partitionStart(r.time)
+		key := table + "/" + strconv.FormatInt(start.UnixNano(), 10)
+		if partitions.m[key] {
+			continue
+		}
+		if _, err := p.db.Exec(createPartition(table, start)); err != nil && !isDuplicate(err) {
+			fatal(cli.ExitFailure, "could not create partition", "table", table, "start", start, "error", err)
+			return
+		}
+		partitions.m[key] = true
+	}
+}
+
+// isDuplicate returns whether err is from creating a table which another
+// loader created at the same time, which IF NOT EXISTS does not prevent
+func isDuplicate(err error) bool {
+	e, ok := err.(*pq.Error)
+	return ok && (e.Code == "42P07" || e.Code == "23505")
+}
+
+// copyRows loads rows into table with COPY, in a transaction
+func (p *processor) copyRows(table string, rows []*parsedRow) {
+	cols := append([]string{"time", "tags_id", "additional_tags"}, tableCols[table]...)
+	tx, err := p.db.Begin()
+	if err != nil {
+		fatal(cli.ExitFailure, "could not begin transaction", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(pq.CopyIn(table, cols...))
+	if err == nil {
+		for _, r := range rows {
+			if _, err = stmt.Exec(r.values...); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		_, err = stmt.Exec()
+	}
+	if err == nil {
+		err = stmt.Close()
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err == nil {
+		return
+	}
+	// data exceptions, e.g., a value that is not a number, are of class 22
+	if e, ok := err.(*pq.Error); ok && e.Code.Class() == "22" {
+		fatal(cli.ExitData, "rows rejected", "table", table, "error", err)
+		return
+	}
+	fatal(cli.ExitFailure, "could not copy rows", "table", table, "error", err)
+}
@@ -0,0 +1,103 @@
+package loadpostgres
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+)
+
+// useTestHeader sets the header read as that of the cpu table with two
+// fields, returning a function restoring it
+func useTestHeader() func() {
+	oldTagCols, oldTableCols := tagCols, tableCols
+	tagCols = []string{"hostname", "region"}
+	tableCols = map[string][]string{"cpu": {"usage_user", "usage_system"}}
+	return func() {
+		tagCols, tableCols = oldTagCols, oldTableCols
+	}
+}
+
+func TestDecodeAndProcessBatch(t *testing.T) {
+	defer useTestHeader()()
+	data := "tags,hostname=host_0,region=eu-west-1\ncpu,1451606400000000000,58,2\n" +
+		"tags,hostname=host_1,region=eu-west-1\ncpu,1451606410000000000,3,\n"
+	d := &decoder{scanner: bufio.NewScanner(bytes.NewBufferString(data))}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(nil); p != nil; p = d.Decode(nil) {
+		b.Append(p)
+	}
+	if b.Len() != 2 || len(b.tables["cpu"]) != 2 || b.tables["cpu"][1].fields != "1451606410000000000,3," {
+		t.Fatalf("incorrect batch: %d rows, %v", b.Len(), b.tables)
+	}
+
+	p := &processor{}
+	p.Init(0, false)
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 4 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	defer useTestHeader()()
+	tableCols = nil
+	data := "tags,hostname,region\ncpu,usage_user\n\ntags,hostname=host_0,region=eu-west-1\ncpu,1451606400000000000,58\n"
+	br := bufio.NewReader(bytes.NewBufferString(data))
+	d := &decoder{br: br, scanner: bufio.NewScanner(br)}
+	p := d.Decode(br)
+	if p == nil || len(tableCols["cpu"]) != 1 || len(tagCols) != 2 {
+		t.Fatalf("header not read: %v %v", tagCols, tableCols)
+	}
+	if got := p.Data.(*point); got.table != "cpu" || got.row.fields != "1451606400000000000,58" {
+		t.Errorf("incorrect row after the header: %+v", got.row)
+	}
+}
+
+func TestParseRow(t *testing.T) {
+	defer useTestHeader()()
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = format }
+
+	p := &processor{}
+	p.Init(0, false)
+	r := p.parseRow("cpu", &row{tags: "region=eu-west-1,hostname=host_0,rack=12", fields: "1451606400000000000,58,"})
+	if r == nil || got != "" {
+		t.Fatalf("row not parsed: %s", got)
+	}
+	if r.tagsKey != "host_0\x00eu-west-1" || !r.time.Equal(time.Unix(1451606400, 0)) {
+		t.Errorf("incorrect tags %q or time %v", r.tagsKey, r.time)
+	}
+	if r.values[2] != `{"rack":"12"}` || r.values[3] != "58" || r.values[4] != nil {
+		t.Errorf("incorrect values: %v", r.values)
+	}
+
+	if p.parseRow("cpu", &row{tags: "hostname=host_0", fields: "1451606400000000000,58"}) != nil || got == "" {
+		t.Errorf("row with too few fields not rejected")
+	}
+	got = ""
+	if p.parseRow("mem", &row{fields: "1451606400000000000,58,2"}) != nil || got == "" {
+		t.Errorf("row of a table not in the header not rejected")
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	oldInterval := partitionInterval
+	defer func() { partitionInterval = oldInterval }()
+
+	partitionInterval = 12 * time.Hour
+	start := partitionStart(time.Date(2016, 1, 1, 13, 14, 15, 0, time.UTC))
+	if want := time.Date(2016, 1, 1, 12, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("incorrect start: got %v want %v", start, want)
+	}
+	want := "CREATE TABLE IF NOT EXISTS cpu_p20160101_120000 PARTITION OF cpu FOR VALUES FROM ('2016-01-01T12:00:00Z') TO ('2016-01-02T00:00:00Z')"
+	if got := createPartition("cpu", start); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+
+	partitionInterval = 7 * 24 * time.Hour
+	if start := partitionStart(time.Unix(-1, 0)); !start.Equal(time.Unix(-7*24*3600, 0)) {
+		t.Errorf("incorrect start before the epoch: %v", start)
+	}
+}
@@ -0,0 +1,94 @@
+package loadpostgres
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/timescale/tsbs/load"
+)
+
+// tagsPrefix starts the tags line of each reading, and of the header
+const tagsPrefix = "tags"
+
+// row is a reading, as its tags line, without the prefix, and its fields
+// line, without the table name: the timestamp and field values
+type row struct {
+	tags   string
+	fields string
+}
+
+// point is a row keyed by the table it belongs to
+type point struct {
+	table string
+	row   *row
+}
+
+type decoder struct {
+	br      *bufio.Reader
+	scanner *bufio.Scanner
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	// the header is only read by the DBCreator when loading, and the rows
+	// are parsed by the processor even when not
+	if tableCols == nil {
+		(&dbCreator{}).readDataHeader(d.br)
+	}
+	if !d.scan() {
+		return nil
+	}
+	// the first line of a reading is its tags, prefixed by "tags"
+	parts := strings.SplitN(d.scanner.Text(), ",", 2)
+	if parts[0] != tagsPrefix {
+		fatalData("data file in invalid format; got %s expected %s", parts[0], tagsPrefix)
+		return nil
+	}
+	r := &row{}
+	if len(parts) > 1 {
+		r.tags = parts[1]
+	}
+
+	// and the second its fields, prefixed by the name of its table
+	if !d.scan() {
+		fatalData("data file in invalid format; tags line without fields line")
+		return nil
+	}
+	parts = strings.SplitN(d.scanner.Text(), ",", 2)
+	if len(parts) < 2 {
+		fatalData("data file in invalid format; fields line without timestamp: %s", d.scanner.Text())
+		return nil
+	}
+	r.fields = parts[1]
+	return load.NewPoint(&point{table: parts[0], row: r})
+}
+
+// scan scans the next line, returning false at the end of the input
+func (d *decoder) scan() bool {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() != nil {
+		fatalData("scan error: %v", d.scanner.Err())
+	}
+	return ok
+}
+
+// batch holds the rows of a batch by table
+type batch struct {
+	tables map[string][]*row
+	rows   int
+}
+
+func (b *batch) Len() int {
+	return b.rows
+}
+
+func (b *batch) Append(item *load.Point) {
+	p := item.Data.(*point)
+	b.tables[p.table] = append(b.tables[p.table], p.row)
+	b.rows++
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{tables: map[string][]*row{}}
+}
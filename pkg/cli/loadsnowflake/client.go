@@ -0,0 +1,287 @@
+package loadsnowflake
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Types of the tokens requests are authenticated with, as the
+// X-Snowflake-Authorization-Token-Type header gives them
+const (
+	tokenTypeOAuth  = "OAUTH"
+	tokenTypeKeyJWT = "KEYPAIR_JWT"
+)
+
+// jwtLifetime is how long the JWTs of key pair authentication are valid,
+// the most Snowflake allows
+const jwtLifetime = time.Hour
+
+// apiError is an error returned by the SQL API, e.g., of a statement that
+// failed
+type apiError struct {
+	status   int
+	Code     string `json:"code"`
+	SQLState string `json:"sqlState"`
+	Message  string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	if len(e.SQLState) > 0 {
+		return fmt.Sprintf("snowflake: %d %s (SQL state %s): %s", e.status, e.Code, e.SQLState, e.Message)
+	}
+	return fmt.Sprintf("snowflake: %d %s", e.status, e.Message)
+}
+
+// throttled returns whether the request was rejected because of too many
+// requests or an overloaded service, so it can be retried later
+func (e *apiError) throttled() bool {
+	return e.status == http.StatusTooManyRequests || e.status == http.StatusServiceUnavailable
+}
+
+// dataError returns whether the statement failed on the data, e.g., a value
+// COPY could not convert to the type of its column
+func (e *apiError) dataError() bool {
+	return strings.HasPrefix(e.SQLState, "22")
+}
+
+// result is the part of the result of a statement the loader needs. Values
+// are strings, or nil if they are NULL, whatever the type of their column.
+type result struct {
+	ResultSetMetaData struct {
+		RowType []struct {
+			Name string `json:"name"`
+		} `json:"rowType"`
+	} `json:"resultSetMetaData"`
+	Data               [][]*string `json:"data"`
+	StatementStatusURL string      `json:"statementStatusUrl"`
+}
+
+// column returns the values of the column name of the rows of r
+func (r *result) column(name string) []string {
+	for i, t := range r.ResultSetMetaData.RowType {
+		if !strings.EqualFold(t.Name, name) {
+			continue
+		}
+		values := make([]string, 0, len(r.Data))
+		for _, row := range r.Data {
+			if i < len(row) && row[i] != nil {
+				values = append(values, *row[i])
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// keyPair is the key a user authenticates with, signing JWTs
+type keyPair struct {
+	// issuer and subject are the claims of the JWTs: the account and user,
+	// and, for the issuer, the fingerprint of the public key
+	issuer  string
+	subject string
+	key     *rsa.PrivateKey
+}
+
+// loadKeyPair reads the unencrypted PEM private key of user of account in
+// file
+func loadKeyPair(file, account, user string) (*keyPair, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key %s: no PEM private key", file)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid private key %s: %v (encrypted keys are not supported)", file, err)
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid private key %s: not an RSA key", file)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := sha256.Sum256(pub)
+	// the account in the claims has no region or cloud, as in a locator
+	// such as xy12345.us-east-2.aws, and is upper case, as is the user
+	if i := strings.IndexByte(account, '.'); i >= 0 {
+		account = account[:i]
+	}
+	subject := strings.ToUpper(account) + "." + strings.ToUpper(user)
+	return &keyPair{
+		issuer:  subject + ".SHA256:" + base64.StdEncoding.EncodeToString(fingerprint[:]),
+		subject: subject,
+		key:     key,
+	}, nil
+}
+
+// jwt returns a JWT signed with the key, valid for jwtLifetime from now
+func (k *keyPair) jwt(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": k.issuer,
+		"sub": k.subject,
+		"iat": now.Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, k.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
+
+// apiClient runs statements with the Snowflake SQL API, authenticating its
+// requests with an OAuth token or, without one, with JWTs signed with a key
+// pair. Statements that run for more than 45s are run asynchronously by
+// Snowflake, and their results polled for every -poll-interval.
+type apiClient struct {
+	http     *http.Client
+	endpoint string
+	// warehouse, role and schema are the context of the statements, as
+	// given by the flags
+	warehouse string
+	role      string
+	schema    string
+
+	oauthToken string
+	keys       *keyPair
+
+	mu      sync.Mutex
+	jwt     string
+	expires time.Time
+
+	// throttled counts the requests Snowflake throttled
+	throttled uint64
+
+	// now is the time JWTs are signed at; it is a variable for testing
+	now func() time.Time
+}
+
+func newAPIClient(endpoint string) *apiClient {
+	return &apiClient{
+		http:     &http.Client{Timeout: 5 * time.Minute},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		now:      time.Now,
+	}
+}
+
+// token returns the token to authenticate requests with and its type,
+// signing a new JWT shortly before the last expires
+func (c *apiClient) token() (string, string, error) {
+	if len(c.oauthToken) > 0 {
+		return c.oauthToken, tokenTypeOAuth, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.jwt) == 0 || !c.now().Before(c.expires) {
+		now := c.now()
+		jwt, err := c.keys.jwt(now)
+		if err != nil {
+			return "", "", err
+		}
+		c.jwt = jwt
+		// sign a new JWT a minute before it expires
+		c.expires = now.Add(jwtLifetime - time.Minute)
+	}
+	return c.jwt, tokenTypeKeyJWT, nil
+}
+
+// exec runs statement in database, or in none if it is empty, returning its
+// result once it is done. It retries the statement for as long as Snowflake
+// throttles it, sleeping for -backoff between tries.
+func (c *apiClient) exec(database, statement string) (*result, error) {
+	req := map[string]interface{}{"statement": statement}
+	for k, v := range map[string]string{"database": database, "schema": c.schema, "warehouse": c.warehouse, "role": c.role} {
+		if len(v) > 0 && (k != "schema" || len(database) > 0) {
+			req[k] = v
+		}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		r, status, err := c.do(http.MethodPost, c.endpoint+"/api/v2/statements", body)
+		for err == nil && status == http.StatusAccepted {
+			sleep(pollInterval)
+			r, status, err = c.do(http.MethodGet, c.endpoint+r.StatementStatusURL, nil)
+		}
+		if e, ok := err.(*apiError); ok && e.throttled() {
+			atomic.AddUint64(&c.throttled, 1)
+			sleep(backoff)
+			continue
+		}
+		return r, err
+	}
+}
+
+// do makes an authenticated request, returning its result and status, or
+// an *apiError if it is not successful
+func (c *apiClient) do(method, url string, body []byte) (*result, int, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	token, tokenType, err := c.token()
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Snowflake-Authorization-Token-Type", tokenType)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "tsbs")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		e := &apiError{status: resp.StatusCode}
+		if json.Unmarshal(respBody, e) != nil || len(e.Message) == 0 {
+			e.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, resp.StatusCode, e
+	}
+	var r result
+	if err := json.Unmarshal(respBody, &r); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("snowflake: invalid response to %s %s: %v", method, url, err)
+	}
+	return &r, resp.StatusCode, nil
+}
@@ -0,0 +1,151 @@
+package loadsnowflake
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadKeyPairJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "tsbs_load_snowflake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "rsa_key.p8")
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	if err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := loadKeyPair(file, "xy12345.us-east-2.aws", "tsbs_user")
+	if err != nil {
+		t.Fatalf("could not load key: %v", err)
+	}
+	pub, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	sum := sha256.Sum256(pub)
+	if want := "XY12345.TSBS_USER.SHA256:" + base64.StdEncoding.EncodeToString(sum[:]); k.issuer != want {
+		t.Errorf("incorrect issuer: got %s want %s", k.issuer, want)
+	}
+
+	now := time.Unix(1451606400, 0)
+	jwt, err := k.jwt(now)
+	if err != nil {
+		t.Fatalf("could not sign JWT: %v", err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("invalid JWT: %s", jwt)
+	}
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+		t.Errorf("invalid signature: %v", err)
+	}
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iss, Sub string
+		Iat, Exp int64
+	}
+	json.Unmarshal(claimsJSON, &claims)
+	if claims.Iss != k.issuer || claims.Sub != "XY12345.TSBS_USER" || claims.Iat != now.Unix() || claims.Exp != now.Add(time.Hour).Unix() {
+		t.Errorf("incorrect claims: %+v", claims)
+	}
+
+	if _, err := loadKeyPair(filepath.Join(dir, "missing"), "a", "u"); err == nil {
+		t.Errorf("missing key loaded")
+	}
+}
+
+func TestClientExec(t *testing.T) {
+	oldSleep := sleep
+	defer func() { sleep = oldSleep }()
+	sleep = func(time.Duration) {}
+
+	var requests []string
+	throttle := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("X-Snowflake-Authorization-Token-Type") != tokenTypeOAuth {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodGet {
+			requests = append(requests, "GET "+r.URL.Path)
+			w.Write([]byte(`{"resultSetMetaData":{"rowType":[{"name":"N"}]},"data":[["42"]]}`))
+			return
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		requests = append(requests, req["statement"]+" in "+req["database"]+"."+req["schema"]+" with "+req["warehouse"])
+		switch req["statement"] {
+		case "SELECT 1":
+			if throttle {
+				throttle = false
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte(`{"data":[]}`))
+		case "SELECT 2":
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"code":"333334","statementStatusUrl":"/api/v2/statements/h1"}`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"code":"100038","sqlState":"22018","message":"Numeric value 'x' is not recognized"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := newAPIClient(server.URL + "/")
+	c.oauthToken, c.schema, c.warehouse = "token", "PUBLIC", "wh"
+	r, err := c.exec("benchmark", "SELECT 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := r.column("n"); len(got) != 1 || got[0] != "42" {
+		t.Errorf("incorrect result: %v", got)
+	}
+	want := []string{"SELECT 2 in benchmark.PUBLIC with wh", "GET /api/v2/statements/h1"}
+	if strings.Join(requests, "\n") != strings.Join(want, "\n") {
+		t.Errorf("incorrect requests:\n%s", strings.Join(requests, "\n"))
+	}
+
+	requests = nil
+	_, err = c.exec("", "COPY")
+	e, ok := err.(*apiError)
+	if !ok || !e.dataError() || e.status != http.StatusUnprocessableEntity {
+		t.Errorf("incorrect error: %v", err)
+	}
+	// the schema is only given along with a database
+	if len(requests) != 1 || requests[0] != "COPY in . with wh" {
+		t.Errorf("incorrect requests: %v", requests)
+	}
+
+	if _, err = c.exec("", "SELECT 1"); err != nil {
+		t.Errorf("throttled statement not retried: %v", err)
+	}
+	if c.throttled != 1 {
+		t.Errorf("incorrect throttled count: got %d want 1", c.throttled)
+	}
+
+	c.oauthToken = "wrong"
+	if _, err = c.exec("", "SELECT 3"); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
@@ -0,0 +1,286 @@
+package loadsnowflake
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// scriptName is the name of the SnowSQL script written to the directory of
+// the stage when exporting
+const scriptName = "load.sql"
+
+// fileFormat is that of the staged files: Snowflake reads the escapes and
+// NULLs of the rows by default
+const fileFormat = `(TYPE = CSV FIELD_DELIMITER = '\t' COMPRESSION = GZIP)`
+
+// column is a field column of a table, as given by the header
+type column struct {
+	name string
+	typ  string
+}
+
+// sqlTypes are the types of the field columns the header may give, all of
+// which Snowflake has, TEXT as an alias of VARCHAR
+var sqlTypes = map[string]bool{"DOUBLE": true, "BIGINT": true, "BOOLEAN": true, "TEXT": true}
+
+var (
+	// runPrefix starts the paths of the files staged by this run, so that
+	// those of earlier runs are not loaded, unless exporting
+	runPrefix string
+	// loadStart is when the load started, once the database was created
+	loadStart time.Time
+	// stagedRows counts the rows of the files staged
+	stagedRows uint64
+)
+
+type dbCreator struct {
+	br *bufio.Reader
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the header at the start of the data, up to an empty
+// line: the tag keys, as the tags line, followed by each measurement and the
+// definitions of its field columns
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	tableCols = make(map[string][]column)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		fatalData("input has wrong header format: %v", err)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+	if parts[0] != tagsPrefix || len(parts) < 2 {
+		fatalData("input has wrong header format: got '%s', expected the tags", parts[0])
+		return
+	}
+	tagCols = make([]string, len(parts)-1)
+	for i, p := range parts[1:] {
+		tagCols[i] = mysql.Unescape(p)
+	}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			break
+		}
+		parts = strings.Split(line, "\t")
+		cols := make([]column, len(parts)-1)
+		for i, p := range parts[1:] {
+			j := strings.LastIndexByte(p, ' ')
+			if j < 0 || !sqlTypes[p[j+1:]] {
+				fatalData("input has wrong header format: invalid column definition '%s'", p)
+				return
+			}
+			cols[i] = column{name: mysql.Unescape(p[:j]), typ: p[j+1:]}
+		}
+		tableCols[mysql.Unescape(parts[0])] = cols
+	}
+}
+
+// scriptPath returns the path of the SnowSQL script when exporting
+func scriptPath() string {
+	return filepath.Join(stage.(dirStage).dir, scriptName)
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	if export {
+		_, err := os.Stat(scriptPath())
+		return err == nil
+	}
+	r, err := client.exec("", fmt.Sprintf("SHOW TERSE DATABASES LIKE %s", literal(dbName)))
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not list databases", "error", err)
+		return false
+	}
+	// LIKE is case-insensitive, unlike quoted names
+	for _, name := range r.column("name") {
+		if name == dbName {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveOldDB drops the database or, when exporting, deletes the script and
+// the files of the tables of the header
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	if !export {
+		_, err := client.exec("", "DROP DATABASE IF EXISTS "+quote(dbName))
+		return err
+	}
+	if err := os.Remove(scriptPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for table := range tableCols {
+		if err := os.RemoveAll(filepath.Join(stage.(dirStage).dir, table)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateDB creates the database, with the table of each measurement and the
+// stage, or, when exporting, writes the script doing so
+func (d *dbCreator) CreateDB(dbName string) error {
+	stmts := createStatements(dbName)
+	if export {
+		return writeScript(stmts)
+	}
+	for i, stmt := range stmts {
+		// the database does not exist before its first statement
+		database := dbName
+		if i == 0 {
+			database = ""
+		}
+		if _, err := client.exec(database, stmt); err != nil {
+			return fmt.Errorf("could not create database: %v", err)
+		}
+	}
+	return nil
+}
+
+// PostCreateDB starts the load, choosing the prefix of the files of this
+// run
+func (d *dbCreator) PostCreateDB(dbName string) error {
+	loadStart = time.Now()
+	if !export {
+		runPrefix = fmt.Sprintf("%s_%d/", dbName, loadStart.UnixNano())
+	}
+	return nil
+}
+
+// Close loads the files staged with -copy=end, and reports the time to
+// queryable once all rows staged are in the tables
+func (d *dbCreator) Close() {
+	if export {
+		logging.Info("files exported, load them with SnowSQL", "script", scriptPath())
+		return
+	}
+	dbName := loader.DatabaseName()
+	if copyMode == copyEnd {
+		start := time.Now()
+		for table := range tableCols {
+			if _, err := client.exec(dbName, copyPrefixStatement(table, runPrefix+table+"/")); err != nil {
+				failCopy(table, err)
+				return
+			}
+		}
+		logging.Info("staged files loaded", "took", time.Since(start))
+	}
+	var rows uint64
+	for table := range tableCols {
+		r, err := client.exec(dbName, "SELECT COUNT(*) AS n FROM "+quote(table))
+		if err != nil {
+			fatal(cli.ExitFailure, "could not count rows", "table", table, "error", err)
+			return
+		}
+		var n uint64
+		if counts := r.column("n"); len(counts) > 0 {
+			fmt.Sscan(counts[0], &n)
+		}
+		rows += n
+	}
+	// other clients may load the same tables, with -do-create-db=false
+	staged := atomic.LoadUint64(&stagedRows)
+	if rows < staged {
+		logging.Warn("rows staged are missing from the tables", "staged", staged, "queryable", rows)
+	} else {
+		logging.Info("rows queryable", "rows", staged, "time-to-queryable", time.Since(loadStart))
+	}
+	if n := atomic.LoadUint64(&client.throttled); n > 0 {
+		logging.Info("requests throttled", "count", n)
+	}
+}
+
+// createStatements returns the statements creating the database, its
+// tables and its stage, which is external unless exporting
+func createStatements(dbName string) []string {
+	stmts := []string{"CREATE DATABASE " + quote(dbName)}
+	if schema != "PUBLIC" {
+		stmts = append(stmts, "CREATE SCHEMA "+quote(schema))
+	}
+	for table, cols := range tableCols {
+		stmts = append(stmts, createTable(table, cols))
+	}
+	if export {
+		return append(stmts, fmt.Sprintf("CREATE STAGE %s FILE_FORMAT = %s", quote(stageName), fileFormat))
+	}
+	s := stage.(*gcsStage)
+	return append(stmts, fmt.Sprintf("CREATE STAGE %s URL = %s STORAGE_INTEGRATION = %s FILE_FORMAT = %s",
+		quote(stageName), literal("gcs://"+s.bucket+"/"+s.prefix), storageIntegration, fileFormat))
+}
+
+// createTable returns the statement creating the table of a measurement,
+// with the given field columns
+func createTable(table string, cols []column) string {
+	defs := []string{`"time" TIMESTAMP_NTZ NOT NULL`}
+	for _, t := range tagCols {
+		defs = append(defs, quote(t)+" VARCHAR")
+	}
+	for _, c := range cols {
+		defs = append(defs, quote(c.name)+" "+c.typ)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", quote(table), strings.Join(defs, ", "))
+}
+
+// writeScript writes the SnowSQL script creating the database with stmts,
+// and then uploading the files of each table to the stage and loading them
+func writeScript(stmts []string) error {
+	dir, err := filepath.Abs(stage.(dirStage).dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- Loads the files exported by tsbs_load_snowflake: snowsql -f %s\n", filepath.Join(dir, scriptName))
+	for _, stmt := range stmts {
+		b.WriteString(stmt + ";\n")
+	}
+	for table := range tableCols {
+		fmt.Fprintf(&b, "PUT %s @%s/%s/ AUTO_COMPRESS = FALSE;\n", literal("file://"+filepath.ToSlash(filepath.Join(dir, table))+"/*.tsv.gz"), quote(stageName), table)
+		b.WriteString(copyPrefixStatement(table, table+"/") + ";\n")
+	}
+	return ioutil.WriteFile(filepath.Join(dir, scriptName), []byte(b.String()), 0644)
+}
+
+// copyPrefixStatement returns the COPY INTO loading the files under prefix
+// in the stage into table
+func copyPrefixStatement(table, prefix string) string {
+	return fmt.Sprintf("COPY INTO %s FROM @%s/%s", quote(table), quote(stageName), prefix)
+}
+
+// copyFileStatement returns the COPY INTO loading the file at path in the
+// stage into table
+func copyFileStatement(table, path string) string {
+	return fmt.Sprintf("COPY INTO %s FROM @%s FILES = (%s)", quote(table), quote(stageName), literal(path))
+}
+
+// quote returns name quoted as a Snowflake identifier, which makes it case
+// sensitive
+func quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// literal returns s as a Snowflake string literal
+func literal(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,162 @@
+package loadsnowflake
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDBCreatorReadDataHeader(t *testing.T) {
+	cases := []struct {
+		desc        string
+		input       string
+		wantTags    []string
+		wantTables  map[string][]column
+		shouldFatal bool
+	}{
+		{
+			desc:       "two tables",
+			input:      "tags\ttag1\ttag2\ncols\tcol1 DOUBLE\tcol2 BIGINT\ncols2\tcol 21 TEXT\n\n",
+			wantTags:   []string{"tag1", "tag2"},
+			wantTables: map[string][]column{"cols": {{"col1", "DOUBLE"}, {"col2", "BIGINT"}}, "cols2": {{"col 21", "TEXT"}}},
+		},
+		{
+			desc:        "invalid type",
+			input:       "tags\ttag1\ncols\tcol1 REAL\n\n",
+			shouldFatal: true,
+		},
+		{
+			desc:        "no empty line",
+			input:       "tags\ttag1\ncols\tcol1 DOUBLE\n",
+			shouldFatal: true,
+		},
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	for _, c := range cases {
+		called := false
+		fatalData = func(string, ...interface{}) { called = true }
+		(&dbCreator{}).readDataHeader(bufio.NewReader(bytes.NewBufferString(c.input)))
+		if called != c.shouldFatal {
+			t.Errorf("%s: incorrect fatal: got %v want %v", c.desc, called, c.shouldFatal)
+			continue
+		}
+		if c.shouldFatal {
+			continue
+		}
+		if !reflect.DeepEqual(tagCols, c.wantTags) || !reflect.DeepEqual(tableCols, c.wantTables) {
+			t.Errorf("%s: incorrect header: got %q %v", c.desc, tagCols, tableCols)
+		}
+	}
+}
+
+func TestCreateTable(t *testing.T) {
+	defer useTestHeader()()
+	want := `CREATE TABLE "cpu" ("time" TIMESTAMP_NTZ NOT NULL, "hostname" VARCHAR, "region" VARCHAR, "usage_user" DOUBLE, "usage_system" BIGINT, "up" BOOLEAN, "state" TEXT)`
+	if got := createTable("cpu", tableCols["cpu"]); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+	if got, want := quote(`a"b`), `"a""b"`; got != want {
+		t.Errorf("incorrect quoting: got %s want %s", got, want)
+	}
+	if got, want := literal(`it's a\b`), `'it\'s a\\b'`; got != want {
+		t.Errorf("incorrect literal: got %s want %s", got, want)
+	}
+}
+
+func TestCreateStatements(t *testing.T) {
+	defer useTestHeader()()
+	defer func(oldStage stager, oldExport bool, oldSchema string) {
+		stage, export, schema = oldStage, oldExport, oldSchema
+	}(stage, export, schema)
+
+	stage, export, schema = &gcsStage{bucket: "bucket", prefix: "tsbs/"}, false, "METRICS"
+	storageIntegration = "gcs_int"
+	defer func() { storageIntegration = "" }()
+	want := []string{
+		`CREATE DATABASE "benchmark"`,
+		`CREATE SCHEMA "METRICS"`,
+		createTable("cpu", tableCols["cpu"]),
+		`CREATE STAGE "tsbs_stage" URL = 'gcs://bucket/tsbs/' STORAGE_INTEGRATION = gcs_int FILE_FORMAT = ` + fileFormat,
+	}
+	if got := createStatements("benchmark"); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect statements:\ngot  %q\nwant %q", got, want)
+	}
+
+	stage, export, schema = dirStage{dir: "out"}, true, "PUBLIC"
+	want = []string{
+		`CREATE DATABASE "benchmark"`,
+		createTable("cpu", tableCols["cpu"]),
+		`CREATE STAGE "tsbs_stage" FILE_FORMAT = ` + fileFormat,
+	}
+	if got := createStatements("benchmark"); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect statements:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestExportScript(t *testing.T) {
+	defer useTestHeader()()
+	dir, err := ioutil.TempDir("", "tsbs_load_snowflake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(oldStage stager, oldExport bool) { stage, export = oldStage, oldExport }(stage, export)
+	stage, export = dirStage{dir: dir}, true
+
+	d := &dbCreator{}
+	if d.DBExists("benchmark") {
+		t.Errorf("database exists before the script is written")
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("could not write script: %v", err)
+	}
+	if !d.DBExists("benchmark") {
+		t.Errorf("database does not exist once the script is written")
+	}
+	script, err := ioutil.ReadFile(filepath.Join(dir, scriptName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(script), "\n"), "\n")
+	want := []string{
+		`CREATE DATABASE "benchmark";`,
+		createTable("cpu", tableCols["cpu"]) + ";",
+		`CREATE STAGE "tsbs_stage" FILE_FORMAT = ` + fileFormat + ";",
+		`PUT 'file://` + filepath.ToSlash(dir) + `/cpu/*.tsv.gz' @"tsbs_stage"/cpu/ AUTO_COMPRESS = FALSE;`,
+		`COPY INTO "cpu" FROM @"tsbs_stage"/cpu/;`,
+	}
+	if !strings.HasPrefix(lines[0], "-- ") || !reflect.DeepEqual(lines[1:], want) {
+		t.Errorf("incorrect script:\n%s", script)
+	}
+
+	if err := stage.put("cpu/0_1.tsv.gz", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("could not remove files: %v", err)
+	}
+	if d.DBExists("benchmark") {
+		t.Errorf("script not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cpu")); !os.IsNotExist(err) {
+		t.Errorf("files not removed: %v", err)
+	}
+}
+
+func TestCopyStatements(t *testing.T) {
+	want := `COPY INTO "cpu" FROM @"tsbs_stage"/benchmark_1/cpu/`
+	if got := copyPrefixStatement("cpu", "benchmark_1/cpu/"); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+	want = `COPY INTO "cpu" FROM @"tsbs_stage" FILES = ('benchmark_1/cpu/0_1.tsv.gz')`
+	if got := copyFileStatement("cpu", "benchmark_1/cpu/0_1.tsv.gz"); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,190 @@
+// Package loadsnowflake implements tsbs_load_snowflake (also run as `tsbs
+// load snowflake`), which loads data from stdin into Snowflake, or another
+// cloud warehouse, by staged ingestion: the rows of each table in a batch
+// are written to a gzipped CSV file, which is staged and then loaded with
+// COPY INTO.
+//
+// The data is in the mysql format, whose rows are tab-separated, with NULL
+// as \N and tabs, newlines and backslashes escaped with a backslash, as
+// Snowflake reads CSV files by default once their delimiter is a tab. The
+// stage is either:
+//   - a local directory (the default), which the files are exported to,
+//     along with load.sql, a script loading them with SnowSQL; or
+//   - gcs://<bucket>/<prefix>, where the files are uploaded to, and loaded
+//     from with an external stage, which the loader creates along with the
+//     tables, with statements run by the Snowflake SQL API.
+//
+// Files are loaded as soon as they are staged or, with -copy=end, all at
+// once, once the input is staged, as bulk loads usually are. The loader then
+// checks that all rows are in the tables, and reports the time from the
+// start of the load until they were: the end-to-end time to queryable,
+// which includes staging.
+//
+// If the database exists beforehand, it will be *DROPPED*, or, when
+// exporting, its load.sql and files will be *DELETED*.
+package loadsnowflake
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// When files are loaded, as -copy gives it
+const (
+	// copyBatch loads the files of each batch once they are staged
+	copyBatch = "batch"
+	// copyEnd loads all files at once, once the input is staged
+	copyEnd = "end"
+)
+
+// stageName is the name of the stage the loader creates in the database
+const stageName = "tsbs_stage"
+
+// Program option vars:
+var (
+	stageURL           string
+	copyMode           string
+	account            string
+	endpoint           string
+	user               string
+	privateKeyFile     string
+	token              string
+	warehouse          string
+	role               string
+	schema             string
+	storageIntegration string
+	gcsEndpoint        string
+	pollInterval       time.Duration
+	backoff            time.Duration
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	// tagCols are the tag columns of the tables, and tableCols the field
+	// columns of the table of each measurement, as read from the header
+	tagCols   []string
+	tableCols map[string][]column
+	// stage is where files are staged, and export whether it is a local
+	// directory they are exported to, rather than loaded from
+	stage  stager
+	export bool
+	// client runs statements, unless exporting
+	client *apiClient
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_snowflake and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&stageURL, "stage-url", "snowflake_stage", "Where files are staged: a local directory, to export them with a SnowSQL script loading them, or gcs://<bucket>/<prefix>, to load them from")
+	flag.StringVar(&copyMode, "copy", copyEnd, "When staged files are loaded: batch, once the files of each batch are, or end, once all are")
+	flag.StringVar(&account, "account", os.Getenv("SNOWFLAKE_ACCOUNT"), "Snowflake account identifier, e.g., myorg-myaccount (default: $SNOWFLAKE_ACCOUNT)")
+	flag.StringVar(&endpoint, "endpoint", "", "URL of the Snowflake SQL API (default: https://<account>.snowflakecomputing.com)")
+	flag.StringVar(&user, "user", os.Getenv("SNOWFLAKE_USER"), "User authenticated with -private-key-file (default: $SNOWFLAKE_USER)")
+	flag.StringVar(&privateKeyFile, "private-key-file", "", "Unencrypted PEM private key of the key pair of -user")
+	flag.StringVar(&token, "token", os.Getenv("SNOWFLAKE_TOKEN"), "OAuth token to authenticate with, rather than a key pair (default: $SNOWFLAKE_TOKEN)")
+	flag.StringVar(&warehouse, "warehouse", "", "Warehouse running the statements (default: that of the user)")
+	flag.StringVar(&role, "role", "", "Role running the statements (default: that of the user)")
+	flag.StringVar(&schema, "schema", "PUBLIC", "Schema of the tables created")
+	flag.StringVar(&storageIntegration, "storage-integration", "", "Storage integration of the external stage created, allowing Snowflake to read -stage-url")
+	flag.StringVar(&gcsEndpoint, "gcs-endpoint", "https://storage.googleapis.com", "URL of the Google Cloud Storage API, e.g., of an emulator")
+	flag.DurationVar(&pollInterval, "poll-interval", time.Second, "Time to sleep between checks of whether a statement is done")
+	flag.DurationVar(&backoff, "backoff", time.Second, "Time to sleep between requests when Snowflake or Cloud Storage throttles them")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_snowflake", args); err != nil {
+		return err
+	}
+	if copyMode != copyBatch && copyMode != copyEnd {
+		return cli.ConfigError(fmt.Errorf("invalid copy '%s': must be %s or %s", copyMode, copyBatch, copyEnd))
+	}
+	return nil
+}
+
+// setup sets the stage and, unless exporting, the client of the flags
+func setup() error {
+	var err error
+	if stage, err = newStager(stageURL); err != nil {
+		return err
+	}
+	if _, export = stage.(dirStage); export {
+		return nil
+	}
+	if len(account) == 0 && len(endpoint) == 0 {
+		return fmt.Errorf("no account: set -account or SNOWFLAKE_ACCOUNT")
+	}
+	if len(storageIntegration) == 0 {
+		return fmt.Errorf("no storage integration for the stage %s: set -storage-integration", stageURL)
+	}
+	if len(endpoint) == 0 {
+		endpoint = "https://" + strings.ToLower(account) + ".snowflakecomputing.com"
+	}
+	client = newAPIClient(endpoint)
+	client.warehouse, client.role, client.schema = warehouse, role, schema
+	if len(token) > 0 {
+		client.oauthToken = token
+		return nil
+	}
+	if len(user) == 0 || len(privateKeyFile) == 0 {
+		return fmt.Errorf("no credentials: set -token, or -user and -private-key-file")
+	}
+	client.keys, err = loadKeyPair(privateKeyFile, account, user)
+	return err
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{br: br, scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return mysql.Format
+}
+
+// Run runs tsbs_load_snowflake with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if err := setup(); err != nil {
+		return cli.ConfigError(err)
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_snowflake")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
@@ -0,0 +1,73 @@
+package loadsnowflake
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
+func TestSetup(t *testing.T) {
+	oldStageURL, oldAccount, oldEndpoint, oldIntegration, oldToken := stageURL, account, endpoint, storageIntegration, token
+	oldNewGCSClient := newGCSClient
+	defer func() {
+		stageURL, account, endpoint, storageIntegration, token = oldStageURL, oldAccount, oldEndpoint, oldIntegration, oldToken
+		newGCSClient = oldNewGCSClient
+		stage, export, client = nil, false, nil
+	}()
+	newGCSClient = func() (*gcp.Client, error) { return gcp.NewClient(gcp.Credentials{}), nil }
+	cases := []struct {
+		desc        string
+		stageURL    string
+		account     string
+		integration string
+		token       string
+		wantExport  bool
+		wantURL     string
+		wantErr     string
+	}{
+		{desc: "directory", stageURL: "out/stage", wantExport: true},
+		{desc: "file URL", stageURL: "file:///tmp/stage", wantExport: true},
+		{desc: "gcs", stageURL: "gcs://bucket/tsbs/", account: "MyOrg-Acct", integration: "gcs_int", token: "t", wantURL: "https://myorg-acct.snowflakecomputing.com"},
+		{desc: "gcs without account", stageURL: "gcs://bucket", integration: "gcs_int", token: "t", wantErr: "no account"},
+		{desc: "gcs without integration", stageURL: "gcs://bucket", account: "a", token: "t", wantErr: "no storage integration"},
+		{desc: "gcs without credentials", stageURL: "gcs://bucket", account: "a", integration: "gcs_int", wantErr: "no credentials"},
+		{desc: "gcs without bucket", stageURL: "gcs:///tsbs", wantErr: "no bucket"},
+		{desc: "s3", stageURL: "s3://bucket", wantErr: "unsupported stage URL"},
+	}
+	for _, c := range cases {
+		stageURL, account, endpoint, storageIntegration, token = c.stageURL, c.account, "", c.integration, c.token
+		err := setup()
+		if len(c.wantErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if export != c.wantExport {
+			t.Errorf("%s: incorrect export: got %v", c.desc, export)
+		}
+		if c.wantExport {
+			continue
+		}
+		if client.endpoint != c.wantURL {
+			t.Errorf("%s: incorrect endpoint: got %s want %s", c.desc, client.endpoint, c.wantURL)
+		}
+		if s := stage.(*gcsStage); s.bucket != "bucket" || s.prefix != "tsbs/" {
+			t.Errorf("%s: incorrect stage: %+v", c.desc, s)
+		}
+	}
+}
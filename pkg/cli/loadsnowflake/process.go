@@ -0,0 +1,135 @@
+package loadsnowflake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/gcp"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// sleep is time.Sleep; it is a variable for testing
+var sleep = time.Sleep
+
+type processor struct {
+	workerNum int
+	// files counts the files the worker staged, naming them
+	files     int
+	buf       bytes.Buffer
+	gz        *gzip.Writer
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	p.gz = gzip.NewWriter(&p.buf)
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("uploads throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+// ProcessBatch stages a file of the rows of each table of the batch, and
+// loads it with -copy=batch
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	var metrics uint64
+	for table, rows := range batch.tables {
+		cols, ok := tableCols[table]
+		if !ok {
+			fatalData("row of table %s, which is not in the header", table)
+			return 0, 0
+		}
+		nCols := 1 + len(tagCols) + len(cols)
+		for _, r := range rows {
+			if n := strings.Count(r, "\t") + 1; n != nCols {
+				fatalData("parse error: row has %d columns, expected %d: %s", n, nCols, r)
+				return 0, 0
+			}
+		}
+		metrics += uint64(len(rows) * len(cols))
+		if doLoad && !p.stage(table, rows) {
+			return 0, 0
+		}
+	}
+	return metrics, uint64(batch.rows)
+}
+
+// filePath returns the path in the stage of the nth file of a worker of the
+// rows of table
+func filePath(table string, workerNum, n int) string {
+	return fmt.Sprintf("%s%s/%d_%d.tsv.gz", runPrefix, table, workerNum, n)
+}
+
+// stage stages the rows of table as a gzipped file, and loads it with
+// -copy=batch, returning whether it succeeded
+func (p *processor) stage(table string, rows []string) bool {
+	p.files++
+	path := filePath(table, p.workerNum, p.files)
+	p.buf.Reset()
+	p.gz.Reset(&p.buf)
+	for _, r := range rows {
+		p.gz.Write([]byte(r))
+		p.gz.Write([]byte{'\n'})
+	}
+	p.gz.Close()
+	for {
+		err := stage.put(path, p.buf.Bytes())
+		if err == nil {
+			break
+		}
+		if !p.retry(err, table) {
+			return false
+		}
+	}
+	atomic.AddUint64(&stagedRows, uint64(len(rows)))
+	if export || copyMode != copyBatch {
+		return true
+	}
+	if _, err := client.exec(loader.DatabaseName(), copyFileStatement(table, path)); err != nil {
+		failCopy(table, err)
+		return false
+	}
+	return true
+}
+
+// retry returns whether a failed upload should be retried, sleeping for
+// -backoff first, which it is as long as Cloud Storage throttles it;
+// otherwise it exits
+func (p *processor) retry(err error, table string) bool {
+	e, ok := err.(*gcp.Error)
+	switch {
+	case !ok:
+		fatal(cli.ExitFailure, "could not stage file", "worker", p.workerNum, "table", table, "error", err)
+	case e.Throttled():
+		p.throttled++
+		logging.Debug("upload throttled", "worker", p.workerNum, "error", err)
+		sleep(backoff)
+		return true
+	default:
+		fatal(cli.ExitUnreachable, "could not stage file", "worker", p.workerNum, "table", table, "error", err)
+	}
+	return false
+}
+
+// failCopy exits on an error loading staged files into table, with ExitData
+// if their rows were rejected
+func failCopy(table string, err error) {
+	e, ok := err.(*apiError)
+	switch {
+	case !ok:
+		fatal(cli.ExitUnreachable, "could not load staged files", "table", table, "error", err)
+	case e.dataError():
+		fatal(cli.ExitData, "rows rejected", "table", table, "error", err)
+	default:
+		fatal(cli.ExitFailure, "could not load staged files", "table", table, "error", err)
+	}
+}
@@ -0,0 +1,185 @@
+package loadsnowflake
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// useTestHeader sets the header read as that of the cpu table with a field
+// of each type, returning a function restoring it
+func useTestHeader() func() {
+	oldTagCols, oldTableCols := tagCols, tableCols
+	tagCols = []string{"hostname", "region"}
+	tableCols = map[string][]column{"cpu": {{"usage_user", "DOUBLE"}, {"usage_system", "BIGINT"}, {"up", "BOOLEAN"}, {"state", "TEXT"}}}
+	return func() {
+		tagCols, tableCols = oldTagCols, oldTableCols
+	}
+}
+
+const testRows = "cpu\t2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58.5\t2\t1\tok\n" +
+	"cpu\t2016-01-01 00:00:10.000000\thost\\t1\t\\N\t3\t\\N\t0\t\\N\n"
+
+// decodeTestRows returns a batch of testRows
+func decodeTestRows() *batch {
+	d := &decoder{scanner: bufio.NewScanner(bytes.NewBufferString(testRows))}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(nil); p != nil; p = d.Decode(nil) {
+		b.Append(p)
+	}
+	return b
+}
+
+// gunzip returns the decompressed data
+func gunzip(t *testing.T, data []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestDecodeAndProcessBatch(t *testing.T) {
+	defer useTestHeader()()
+	b := decodeTestRows()
+	if b.Len() != 2 || len(b.tables["cpu"]) != 2 {
+		t.Fatalf("incorrect batch: %d rows, %v", b.Len(), b.tables)
+	}
+
+	p := &processor{}
+	p.Init(0, false)
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 8 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	called := false
+	fatalData = func(string, ...interface{}) { called = true }
+	b.tables["cpu"] = append(b.tables["cpu"], "2016-01-01 00:00:00.000000\thost_0\t\\N\t1\t2\t1")
+	if metrics, _ := p.ProcessBatch(b, false); !called || metrics != 0 {
+		t.Errorf("row with missing columns processed")
+	}
+}
+
+func TestProcessBatchExport(t *testing.T) {
+	defer useTestHeader()()
+	dir, err := ioutil.TempDir("", "tsbs_load_snowflake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(oldStage stager, oldExport bool) { stage, export = oldStage, oldExport }(stage, export)
+	stage, export = dirStage{dir: dir}, true
+	stagedRows = 0
+
+	p := &processor{}
+	p.Init(2, true)
+	for i := 0; i < 2; i++ {
+		if metrics, rows := p.ProcessBatch(decodeTestRows(), true); metrics != 8 || rows != 2 {
+			t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+		}
+	}
+	if stagedRows != 4 {
+		t.Errorf("incorrect staged rows: got %d want 4", stagedRows)
+	}
+	for _, name := range []string{"2_1.tsv.gz", "2_2.tsv.gz"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "cpu", name))
+		if err != nil {
+			t.Fatalf("file not staged: %v", err)
+		}
+		if got, want := gunzip(t, data), "2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58.5\t2\t1\tok\n"+
+			"2016-01-01 00:00:10.000000\thost\\t1\t\\N\t3\t\\N\t0\t\\N\n"; got != want {
+			t.Errorf("incorrect file %s:\ngot  %q\nwant %q", name, got, want)
+		}
+	}
+}
+
+func TestProcessBatchCopy(t *testing.T) {
+	defer useTestHeader()()
+	oldSleep := sleep
+	defer func() { sleep = oldSleep }()
+	sleep = func(time.Duration) {}
+
+	var uploads []string
+	throttle := true
+	gcsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if throttle {
+			throttle = false
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		uploads = append(uploads, r.URL.Path+" "+r.URL.Query().Get("name")+" "+gunzip(t, body)[:26])
+		w.Write([]byte(`{}`))
+	}))
+	defer gcsServer.Close()
+	var statements []string
+	sfServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		statements = append(statements, req["database"]+": "+req["statement"])
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer sfServer.Close()
+
+	defer func(oldStage stager, oldExport bool, oldClient *apiClient, oldCopyMode, oldGCSEndpoint, oldRunPrefix string) {
+		stage, export, client, copyMode, gcsEndpoint, runPrefix = oldStage, oldExport, oldClient, oldCopyMode, oldGCSEndpoint, oldRunPrefix
+	}(stage, export, client, copyMode, gcsEndpoint, runPrefix)
+	stage = &gcsStage{client: gcp.NewClient(gcp.Credentials{}), bucket: "bucket", prefix: "tsbs/"}
+	export, copyMode, gcsEndpoint, runPrefix = false, copyBatch, gcsServer.URL, "benchmark_1/"
+	client = newAPIClient(sfServer.URL)
+	client.oauthToken = "token"
+
+	p := &processor{}
+	p.Init(0, true)
+	if metrics, rows := p.ProcessBatch(decodeTestRows(), true); metrics != 8 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	if want := []string{"/upload/storage/v1/b/bucket/o tsbs/benchmark_1/cpu/0_1.tsv.gz 2016-01-01 00:00:00.000000"}; !reflect.DeepEqual(uploads, want) {
+		t.Errorf("incorrect uploads:\ngot  %q\nwant %q", uploads, want)
+	}
+	if p.throttled != 1 {
+		t.Errorf("incorrect throttled count: got %d want 1", p.throttled)
+	}
+	if want := []string{`benchmark: COPY INTO "cpu" FROM @"tsbs_stage" FILES = ('benchmark_1/cpu/0_1.tsv.gz')`}; !reflect.DeepEqual(statements, want) {
+		t.Errorf("incorrect statements:\ngot  %q\nwant %q", statements, want)
+	}
+}
+
+func TestFailCopy(t *testing.T) {
+	oldFatal := fatal
+	defer func() { fatal = oldFatal }()
+	var code int
+	fatal = func(c int, _ string, _ ...interface{}) { code = c }
+	cases := []struct {
+		err  error
+		want int
+	}{
+		{&apiError{status: 422, SQLState: "22018"}, cli.ExitData},
+		{&apiError{status: 422, SQLState: "42S02"}, cli.ExitFailure},
+		{os.ErrClosed, cli.ExitUnreachable},
+	}
+	for _, c := range cases {
+		failCopy("cpu", c.err)
+		if code != c.want {
+			t.Errorf("%v: incorrect exit code: got %d want %d", c.err, code, c.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package loadsnowflake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/gcp"
+)
+
+// stager stages files for COPY INTO to load
+type stager interface {
+	// put stages data as the file at path, relative to the stage
+	put(path string, data []byte) error
+}
+
+// newStager returns the stager of the URL of -stage-url: a gcsStage for a
+// gcs:// URL, or otherwise a dirStage of the local directory it is
+func newStager(u string) (stager, error) {
+	i := strings.Index(u, "://")
+	if i < 0 {
+		return dirStage{dir: u}, nil
+	}
+	switch scheme, rest := u[:i], u[i+3:]; scheme {
+	case "file":
+		return dirStage{dir: rest}, nil
+	case "gcs":
+		bucket, prefix := rest, ""
+		if j := strings.IndexByte(rest, '/'); j >= 0 {
+			bucket, prefix = rest[:j], strings.Trim(rest[j+1:], "/")
+		}
+		if len(bucket) == 0 {
+			return nil, fmt.Errorf("invalid stage URL '%s': no bucket", u)
+		}
+		if len(prefix) > 0 {
+			prefix += "/"
+		}
+		client, err := newGCSClient()
+		if err != nil {
+			return nil, err
+		}
+		return &gcsStage{client: client, bucket: bucket, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("unsupported stage URL '%s': must be a local directory or gcs://<bucket>/<prefix>", u)
+	}
+}
+
+// dirStage stages files in a local directory, exporting them
+type dirStage struct {
+	dir string
+}
+
+func (s dirStage) put(path string, data []byte) error {
+	name := filepath.Join(s.dir, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, 0644)
+}
+
+// gcsStage uploads files to a Google Cloud Storage bucket, under a prefix,
+// with the credentials in the environment (see gcp.CredentialsFromEnv)
+type gcsStage struct {
+	client *gcp.Client
+	bucket string
+	// prefix is that of the names of the objects, ending with a slash
+	// unless it is empty
+	prefix string
+}
+
+func (s *gcsStage) put(path string, data []byte) error {
+	u := gcsEndpoint + "/upload/storage/v1/b/" + url.PathEscape(s.bucket) + "/o?uploadType=media&name=" + url.QueryEscape(s.prefix+path)
+	return s.client.Do(http.MethodPost, u, "application/gzip", data, nil)
+}
+
+// newGCSClient returns the client files are uploaded to Cloud Storage with;
+// it is a variable for testing
+var newGCSClient = func() (*gcp.Client, error) {
+	creds := gcp.CredentialsFromEnv()
+	if err := creds.Load(); err != nil {
+		return nil, err
+	}
+	return gcp.NewClient(creds), nil
+}
@@ -0,0 +1,129 @@
+package loadsqlite
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// column is a field column of a table, as given by the header
+type column struct {
+	name string
+	typ  string
+}
+
+// sqlTypes are the types of the field columns the header may give, which
+// SQLite takes as the affinity of the columns
+var sqlTypes = map[string]bool{"DOUBLE": true, "BIGINT": true, "BOOLEAN": true, "TEXT": true}
+
+type dbCreator struct {
+	br *bufio.Reader
+}
+
+func (d *dbCreator) Init() {
+	d.readDataHeader(d.br)
+}
+
+// readDataHeader reads the header at the start of the data, up to an empty
+// line: the tag keys, as the tags line, followed by each measurement and the
+// definitions of its field columns
+func (d *dbCreator) readDataHeader(br *bufio.Reader) {
+	tableCols = make(map[string][]column)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		fatalData("input has wrong header format: %v", err)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(line, "\n"), "\t")
+	if parts[0] != tagsPrefix || len(parts) < 2 {
+		fatalData("input has wrong header format: got '%s', expected the tags", parts[0])
+		return
+	}
+	tagCols = make([]string, len(parts)-1)
+	for i, p := range parts[1:] {
+		tagCols[i] = mysql.Unescape(p)
+	}
+	for {
+		line, err = br.ReadString('\n')
+		if err != nil {
+			fatalData("input has wrong header format: %v", err)
+			return
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if len(line) == 0 {
+			break
+		}
+		parts = strings.Split(line, "\t")
+		cols := make([]column, len(parts)-1)
+		for i, p := range parts[1:] {
+			j := strings.LastIndexByte(p, ' ')
+			if j < 0 || !sqlTypes[p[j+1:]] {
+				fatalData("input has wrong header format: invalid column definition '%s'", p)
+				return
+			}
+			cols[i] = column{name: mysql.Unescape(p[:j]), typ: p[j+1:]}
+		}
+		tableCols[mysql.Unescape(parts[0])] = cols
+	}
+}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	_, err := os.Stat(dbPath(dbName))
+	return err == nil
+}
+
+// RemoveOldDB deletes the database file, and its journal files
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	for _, suffix := range []string{"", "-wal", "-shm", "-journal"} {
+		if err := os.Remove(dbPath(dbName) + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateDB creates the database file, with the table of each measurement
+func (d *dbCreator) CreateDB(dbName string) error {
+	db := mustConnect(dbName)
+	defer db.Close()
+	for table, cols := range tableCols {
+		for _, stmt := range createTable(table, cols) {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("could not create table %s: %v", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createTable returns the statements creating the table of a measurement,
+// with the given field columns, and its indexes. The time is stored as text,
+// as in the data, which sorts as the times do and which the date and time
+// functions of SQLite take.
+func createTable(table string, cols []column) []string {
+	defs := []string{`"time" TEXT NOT NULL`}
+	for _, t := range tagCols {
+		defs = append(defs, quote(t)+" TEXT")
+	}
+	for _, c := range cols {
+		defs = append(defs, quote(c.name)+" "+c.typ)
+	}
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (%s)", quote(table), strings.Join(defs, ", "))}
+	// the names of indexes are unique in a database, so they are prefixed by
+	// the name of their table
+	if partitionIndex {
+		stmts = append(stmts, fmt.Sprintf(`CREATE INDEX %s ON %s (%s, "time")`, quote(table+"_"+tagCols[0]+"_time"), quote(table), quote(tagCols[0])))
+	}
+	if timeIndex {
+		stmts = append(stmts, fmt.Sprintf(`CREATE INDEX %s ON %s ("time")`, quote(table+"_time"), quote(table)))
+	}
+	return stmts
+}
+
+// quote returns name quoted as an SQLite identifier
+func quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
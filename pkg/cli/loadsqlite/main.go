@@ -0,0 +1,161 @@
+// Package loadsqlite implements tsbs_load_sqlite (also run as `tsbs load
+// sqlite`), which loads a SQLite database file with data from stdin.
+//
+// The data is in the mysql format, whose typed, tab-separated rows suit
+// SQLite as they are: a table is created for each measurement, from the
+// header of the data, with a column of the time, of each tag and of each
+// field. The rows of each batch are inserted in a transaction with a
+// prepared statement.
+//
+// The database is the file <db-name>.db in -db-dir. If it exists
+// beforehand, it will be *DELETED*.
+package loadsqlite
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+// Program option vars:
+var (
+	dbDir       string
+	journalMode string
+	synchronous string
+	cacheSize   int
+	busyTimeout time.Duration
+
+	timeIndex      bool
+	partitionIndex bool
+)
+
+// Global vars
+var (
+	loader *load.BenchmarkRunner
+	// tagCols are the tag columns of the tables, and tableCols the field
+	// columns of the table of each measurement, as read from the header
+	tagCols   []string
+	tableCols map[string][]column
+)
+
+var (
+	journalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	syncModes    = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_sqlite and parses
+// them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&dbDir, "db-dir", ".", "Directory of the database file, which is named after -db-name with the extension .db")
+	flag.StringVar(&journalMode, "journal-mode", "WAL", "Journal mode of the database: "+strings.Join(journalModes, ", "))
+	flag.StringVar(&synchronous, "synchronous", "NORMAL", "How often SQLite syncs the database to disk: "+strings.Join(syncModes, ", "))
+	flag.IntVar(&cacheSize, "cache-size", -2000, "Size of the page cache of each connection, in pages if positive or KiB if negative")
+	flag.DurationVar(&busyTimeout, "busy-timeout", 5*time.Second, "How long a worker waits for the others to finish writing, as SQLite has a single writer at a time")
+
+	flag.BoolVar(&timeIndex, "time-index", true, "Whether to build an index on the time of the tables")
+	flag.BoolVar(&partitionIndex, "partition-index", true, "Whether to build an index on the primary tag and time of the tables")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_sqlite", args); err != nil {
+		return err
+	}
+	journalMode = strings.ToUpper(journalMode)
+	if !contains(journalModes, journalMode) {
+		return cli.ConfigError(fmt.Errorf("invalid journal mode '%s': it must be one of %s", journalMode, strings.Join(journalModes, ", ")))
+	}
+	synchronous = strings.ToUpper(synchronous)
+	if !contains(syncModes, synchronous) {
+		return cli.ConfigError(fmt.Errorf("invalid synchronous '%s': it must be one of %s", synchronous, strings.Join(syncModes, ", ")))
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{br: br, scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return mysql.Format
+}
+
+// Run runs tsbs_load_sqlite with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_sqlite")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
+
+// dbPath returns the path of the file of the database dbName
+func dbPath(dbName string) string {
+	return filepath.Join(dbDir, dbName+".db")
+}
+
+// getDSN returns the data source name to open the database dbName with,
+// setting the pragmas of the flags on each connection
+func getDSN(dbName string) string {
+	params := url.Values{}
+	params.Set("_journal_mode", journalMode)
+	params.Set("_synchronous", synchronous)
+	params.Set("_cache_size", strconv.Itoa(cacheSize))
+	params.Set("_busy_timeout", strconv.FormatInt(busyTimeout.Nanoseconds()/1e6, 10))
+	return "file:" + dbPath(dbName) + "?" + params.Encode()
+}
+
+// mustConnect opens the database dbName, exiting if it cannot be opened
+func mustConnect(dbName string) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", getDSN(dbName))
+	if err != nil {
+		fatal(cli.ExitUnreachable, "could not open SQLite database", "path", dbPath(dbName), "error", err)
+	}
+	return db
+}
@@ -0,0 +1,21 @@
+package loadsqlite
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
+func TestGetDSN(t *testing.T) {
+	want := "file:benchmark.db?_busy_timeout=5000&_cache_size=-2000&_journal_mode=WAL&_synchronous=NORMAL"
+	if got := getDSN("benchmark"); got != want {
+		t.Errorf("incorrect DSN: got %s want %s", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package loadsqlite
+
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+)
+
+type processor struct {
+	db *sqlx.DB
+}
+
+func (p *processor) Init(_ int, doLoad bool) {
+	if doLoad {
+		p.db = mustConnect(loader.DatabaseName())
+	}
+}
+
+func (p *processor) Close(doLoad bool) {
+	if doLoad {
+		p.db.Close()
+	}
+}
+
+// ProcessBatch inserts the rows of each table in the batch, in a single
+// transaction, which SQLite commits much faster than a row at a time
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	var metrics uint64
+	parsed := make(map[string][][]interface{}, len(batch.tables))
+	for table, rows := range batch.tables {
+		cols, ok := tableCols[table]
+		if !ok {
+			fatalData("row of table %s, which is not in the header", table)
+			return 0, 0
+		}
+		nCols := 1 + len(tagCols) + len(cols)
+		values := make([][]interface{}, len(rows))
+		for i, r := range rows {
+			if values[i] = parseRow(r, nCols); values[i] == nil {
+				return 0, 0
+			}
+			metrics += uint64(len(cols))
+		}
+		parsed[table] = values
+	}
+	if doLoad {
+		p.insert(parsed)
+	}
+	return metrics, uint64(batch.rows)
+}
+
+// parseRow returns the values of the nCols columns of a row, unescaped, with
+// nil for those which are NULL
+func parseRow(r string, nCols int) []interface{} {
+	parts := strings.Split(r, "\t")
+	if len(parts) != nCols {
+		fatalData("parse error: row has %d columns, expected %d: %s", len(parts), nCols, r)
+		return nil
+	}
+	values := make([]interface{}, nCols)
+	for i, v := range parts {
+		if v != mysql.Null {
+			values[i] = mysql.Unescape(v)
+		}
+	}
+	return values
+}
+
+// insertStatement returns the INSERT of a row into table, with its field
+// columns cols
+func insertStatement(table string, cols []column) string {
+	names := make([]string, 0, 1+len(tagCols)+len(cols))
+	names = append(names, `"time"`)
+	for _, t := range tagCols {
+		names = append(names, quote(t))
+	}
+	for _, c := range cols {
+		names = append(names, quote(c.name))
+	}
+	params := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+	return "INSERT INTO " + quote(table) + " (" + strings.Join(names, ",") + ") VALUES (" + params + ")"
+}
+
+// insert inserts the rows of each table in a transaction, with a prepared
+// statement of each table
+func (p *processor) insert(tables map[string][][]interface{}) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		fatal(cli.ExitFailure, "could not begin transaction", "error", err)
+		return
+	}
+	defer tx.Rollback()
+	for table, rows := range tables {
+		stmt, err := tx.Prepare(insertStatement(table, tableCols[table]))
+		if err != nil {
+			fatal(cli.ExitFailure, "could not prepare insert", "table", table, "error", err)
+			return
+		}
+		for _, r := range rows {
+			if _, err = stmt.Exec(r...); err != nil {
+				break
+			}
+		}
+		stmt.Close()
+		if err != nil {
+			p.fail(table, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		fatal(cli.ExitFailure, "could not commit rows", "error", err)
+	}
+}
+
+// fail exits on an error inserting rows into table, with ExitData if the
+// rows were rejected
+func (p *processor) fail(table string, err error) {
+	if e, ok := err.(sqlite3.Error); ok {
+		switch e.Code {
+		case sqlite3.ErrConstraint, sqlite3.ErrMismatch, sqlite3.ErrTooBig:
+			fatal(cli.ExitData, "rows rejected", "table", table, "error", err)
+			return
+		}
+	}
+	fatal(cli.ExitFailure, "could not insert rows", "table", table, "error", err)
+}
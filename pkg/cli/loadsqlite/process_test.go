@@ -0,0 +1,101 @@
+package loadsqlite
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// useTestHeader sets the header read as that of the cpu table with two
+// fields, returning a function restoring it
+func useTestHeader() func() {
+	oldTagCols, oldTableCols := tagCols, tableCols
+	tagCols = []string{"hostname", "region"}
+	tableCols = map[string][]column{"cpu": {{"usage_user", "DOUBLE"}, {"usage_system", "DOUBLE"}}}
+	return func() {
+		tagCols, tableCols = oldTagCols, oldTableCols
+	}
+}
+
+const testRows = "cpu\t2016-01-01 00:00:00.000000\thost_0\teu-west-1\t58\t2\n" +
+	"cpu\t2016-01-01 00:00:10.000000\thost\\t1\t\\N\t3\t\\N\n"
+
+// decodeTestRows returns a batch of testRows
+func decodeTestRows() *batch {
+	d := &decoder{scanner: bufio.NewScanner(bytes.NewBufferString(testRows))}
+	b := (&factory{}).New().(*batch)
+	for p := d.Decode(nil); p != nil; p = d.Decode(nil) {
+		b.Append(p)
+	}
+	return b
+}
+
+func TestDecodeAndProcessBatch(t *testing.T) {
+	defer useTestHeader()()
+	b := decodeTestRows()
+	if b.Len() != 2 || len(b.tables["cpu"]) != 2 {
+		t.Fatalf("incorrect batch: %d rows, %v", b.Len(), b.tables)
+	}
+
+	p := &processor{}
+	p.Init(0, false)
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 4 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+}
+
+func TestInsertStatement(t *testing.T) {
+	defer useTestHeader()()
+	want := `INSERT INTO "cpu" ("time","hostname","region","usage_user","usage_system") VALUES (?,?,?,?,?)`
+	if got := insertStatement("cpu", tableCols["cpu"]); got != want {
+		t.Errorf("incorrect statement:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+// TestLoad loads testRows into a database in a temporary directory, as
+// SQLite needs no server
+func TestLoad(t *testing.T) {
+	defer useTestHeader()()
+	oldDir := dbDir
+	defer func() { dbDir = oldDir }()
+	var err error
+	if dbDir, err = ioutil.TempDir("", "tsbs_load_sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dbDir)
+
+	c := &dbCreator{}
+	if c.DBExists("benchmark") {
+		t.Fatalf("database exists before being created")
+	}
+	if err := c.CreateDB("benchmark"); err != nil {
+		t.Fatalf("could not create database: %v", err)
+	}
+	p := &processor{db: mustConnect("benchmark")}
+	if metrics, rows := p.ProcessBatch(decodeTestRows(), true); metrics != 4 || rows != 2 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	var got struct {
+		Count  int
+		Host   string
+		Region *string
+		System *float64
+	}
+	err = p.db.Get(&got, `SELECT count(*) OVER () AS count, hostname AS host, region, usage_system AS system FROM cpu ORDER BY time DESC LIMIT 1`)
+	p.Close(true)
+	if err != nil {
+		t.Fatalf("could not query rows: %v", err)
+	}
+	if got.Count != 2 || got.Host != "host\t1" || got.Region != nil || got.System != nil {
+		t.Errorf("incorrect row: %+v", got)
+	}
+
+	if !c.DBExists("benchmark") {
+		t.Fatalf("database does not exist once created")
+	}
+	if err := c.RemoveOldDB("benchmark"); err != nil || c.DBExists("benchmark") {
+		t.Errorf("database not removed: %v", err)
+	}
+}
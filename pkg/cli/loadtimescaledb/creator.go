@@ -1,12 +1,10 @@
-package main
+package loadtimescaledb
 
 import (
 	"bufio"
 	"fmt"
 	"regexp"
 	"strings"
-
-	"github.com/jmoiron/sqlx"
 )
 
 type dbCreator struct {
@@ -54,7 +52,7 @@ func (d *dbCreator) readDataHeader(br *bufio.Reader) {
 }
 
 func (d *dbCreator) DBExists(dbName string) bool {
-	db := sqlx.MustConnect(dbType, d.connStr)
+	db := mustConnect(d.connStr)
 	defer db.Close()
 	r, _ := db.Queryx("SELECT 1 from pg_database WHERE datname = $1", dbName)
 	defer r.Close()
@@ -62,18 +60,18 @@ func (d *dbCreator) DBExists(dbName string) bool {
 }
 
 func (d *dbCreator) RemoveOldDB(dbName string) error {
-	db := sqlx.MustConnect(dbType, d.connStr)
+	db := mustConnect(d.connStr)
 	defer db.Close()
 	db.MustExec("DROP DATABASE IF EXISTS " + dbName)
 	return nil
 }
 
 func (d *dbCreator) CreateDB(dbName string) error {
-	db := sqlx.MustConnect(dbType, d.connStr)
+	db := mustConnect(d.connStr)
 	db.MustExec("CREATE DATABASE " + dbName)
 	db.Close()
 
-	dbBench := sqlx.MustConnect(dbType, getConnectString())
+	dbBench := mustConnect(getConnectString())
 	defer dbBench.Close()
 
 	parts := strings.Split(strings.TrimSpace(d.tags), ",")
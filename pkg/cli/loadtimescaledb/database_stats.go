@@ -1,14 +1,14 @@
-package main
+package loadtimescaledb
 
 import (
 	"encoding/csv"
 	"fmt"
-	"log"
 	"os"
 	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/timescale/tsbs/pkg/logging"
 )
 
 const ReplicationStatsTable = "pg_stat_replication"
@@ -42,12 +42,12 @@ func getReplicationStats(db *sqlx.DB) []ReplicationStats {
 */
 func OutputReplicationStats(dbConnString string, outputFileName string, wg *sync.WaitGroup) {
 	wg.Add(1)
-	db := sqlx.MustConnect("postgres", dbConnString)
+	db := mustConnect(dbConnString)
 	defer wg.Done()
 	defer db.Close()
 	outputFile, err := os.Create(outputFileName)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("could not create replication stats file", "file", outputFileName, "error", err)
 	}
 	defer outputFile.Close()
 	writer := csv.NewWriter(outputFile)
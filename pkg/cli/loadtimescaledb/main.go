@@ -0,0 +1,215 @@
+// Package loadtimescaledb implements tsbs_load_timescaledb (also run as
+// `tsbs load timescaledb`), which loads a TimescaleDB instance with data from
+// stdin.
+//
+// If the database exists beforehand, it will be *DROPPED*.
+package loadtimescaledb
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/timescaledb"
+	"github.com/timescale/tsbs/pkg/provision"
+)
+
+const (
+	dbType       = "postgres"
+	timeValueIdx = "TIME-VALUE"
+	valueTimeIdx = "VALUE-TIME"
+)
+
+// Program option vars:
+var (
+	postgresConnect string
+	host            string
+	user            string
+
+	useHypertable bool
+	logBatches    bool
+	useJSON       bool
+	inTableTag    bool
+	hashWorkers   bool
+
+	numberPartitions int
+	chunkTime        time.Duration
+
+	timeIndex          bool
+	timePartitionIndex bool
+	partitionIndex     bool
+	fieldIndex         string
+	fieldIndexCount    int
+
+	profileFile          string
+	replicationStatsFile string
+	container            *provision.Options
+)
+
+type insertData struct {
+	tags   string
+	fields string
+}
+
+// Global vars
+var (
+	loader    *load.BenchmarkRunner
+	tableCols map[string][]string
+)
+
+// fatal exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatal = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_timescaledb and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunner()
+
+	flag.StringVar(&postgresConnect, "postgres", "sslmode=disable", "PostgreSQL connection string")
+	flag.StringVar(&host, "host", "localhost", "Hostname of TimescaleDB (PostgreSQL) instance")
+	flag.StringVar(&user, "user", "postgres", "User to connect to PostgreSQL as")
+
+	flag.BoolVar(&logBatches, "log-batches", false, "Whether to time individual batches.")
+
+	flag.BoolVar(&useHypertable, "use-hypertable", true, "Whether to make the table a hypertable. Set this flag to false to check input write speed against regular PostgreSQL.")
+	flag.BoolVar(&useJSON, "use-jsonb-tags", false, "Whether tags should be stored as JSONB (instead of a separate table with schema)")
+	flag.BoolVar(&inTableTag, "in-table-partition-tag", false, "Whether the partition key (e.g. hostname) should also be in the metrics hypertable")
+	// TODO - This flag could potentially be done as a string/enum with other options besides no-hash, round-robin, etc
+	flag.BoolVar(&hashWorkers, "hash-workers", false, "Whether to consistently hash insert data to the same workers (i.e., the data for a particular host always goes to the same worker)")
+
+	flag.IntVar(&numberPartitions, "partitions", 1, "Number of patitions")
+	flag.DurationVar(&chunkTime, "chunk-time", 12*time.Hour, "Duration that each chunk should represent, e.g., 12h")
+
+	flag.BoolVar(&timeIndex, "time-index", true, "Whether to build an index on the time dimension")
+	flag.BoolVar(&timePartitionIndex, "time-partition-index", false, "Whether to build an index on the time dimension, compounded with partition")
+	flag.BoolVar(&partitionIndex, "partition-index", true, "Whether to build an index on the partition key")
+	flag.StringVar(&fieldIndex, "field-index", valueTimeIdx, "index types for tags (comma deliminated)")
+	flag.IntVar(&fieldIndexCount, "field-index-count", 0, "Number of indexed fields (-1 for all)")
+
+	flag.StringVar(&profileFile, "write-profile", "", "File to output CPU/memory profile to")
+	flag.StringVar(&replicationStatsFile, "write-replication-stats", "", "File to output replication stats to")
+
+	container = provision.AddFlags(flag.CommandLine, "timescaledb")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_timescaledb", args); err != nil {
+		return err
+	}
+	for _, idx := range strings.Split(fieldIndex, ",") {
+		if idx != "" && idx != timeValueIdx && idx != valueTimeIdx {
+			return cli.ConfigError(fmt.Errorf("unknown field index type: '%s' (valid choices: %s, %s)", idx, timeValueIdx, valueTimeIdx))
+		}
+	}
+	tableCols = make(map[string][]string)
+	return nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(maxPartitions uint) load.PointIndexer {
+	if hashWorkers {
+		return &hostnameIndexer{partitions: maxPartitions}
+	}
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{br: loader.GetBufferedReader(), connStr: getConnectString()}
+}
+
+func (b *benchmark) DataFormat() string {
+	return timescaledb.Format
+}
+
+// Run runs tsbs_load_timescaledb with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "timescaledb", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		host = c.Host
+		postgresConnect += fmt.Sprintf(" port=%d", c.Port)
+	}
+	// If specified, generate a performance profile
+	if len(profileFile) > 0 {
+		go profileCPUAndMem(profileFile)
+	}
+
+	var replicationStatsWaitGroup sync.WaitGroup
+	if len(replicationStatsFile) > 0 {
+		go OutputReplicationStats(getConnectString(), replicationStatsFile, &replicationStatsWaitGroup)
+	}
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_timescaledb")
+	workQueues := uint(load.SingleQueue)
+	if hashWorkers {
+		workQueues = load.WorkerPerQueue
+	}
+	err := loader.RunBenchmark(&benchmark{}, workQueues)
+
+	if len(replicationStatsFile) > 0 {
+		replicationStatsWaitGroup.Wait()
+	}
+	return md.Finish(loader.SaveResults(md, err))
+}
+
+func getConnectString() string {
+	// User might be passing in host=hostname the connect string out of habit which may override the
+	// multi host configuration. Same for dbname= and user=. This sanitizes that.
+	re := regexp.MustCompile(`(host|dbname|user)=\S*\b`)
+	connectString := strings.TrimSpace(re.ReplaceAllString(postgresConnect, ""))
+
+	return fmt.Sprintf("host=%s dbname=%s user=%s %s", host, loader.DatabaseName(), user, connectString)
+}
+
+// mustConnect connects to TimescaleDB with connStr, exiting if it cannot be
+// reached
+func mustConnect(connStr string) *sqlx.DB {
+	db, err := sqlx.Connect(dbType, connStr)
+	if err != nil {
+		cli.Fatal(cli.ExitUnreachable, "could not connect to TimescaleDB", "error", err)
+	}
+	return db
+}
+
+func createTagsTable(db *sqlx.DB, tags []string) {
+	if useJSON {
+		db.MustExec("CREATE TABLE tags(id SERIAL PRIMARY KEY, tagset JSONB)")
+		db.MustExec("CREATE UNIQUE INDEX uniq1 ON tags(tagset)")
+		db.MustExec("CREATE INDEX idxginp ON tags USING gin (tagset jsonb_path_ops);")
+	} else {
+		cols := strings.Join(tags, " TEXT, ")
+		cols += " TEXT"
+		db.MustExec(fmt.Sprintf("CREATE TABLE tags(id SERIAL PRIMARY KEY, %s)", cols))
+		db.MustExec(fmt.Sprintf("CREATE UNIQUE INDEX uniq1 ON tags(%s)", strings.Join(tags, ",")))
+		db.MustExec(fmt.Sprintf("CREATE INDEX ON tags(%s)", tags[0]))
+	}
+}
@@ -1,10 +1,19 @@
-package main
+package loadtimescaledb
 
 import (
+	"flag"
 	"fmt"
+	"os"
 	"testing"
 )
 
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
 func TestGetConnectString(t *testing.T) {
 	wantHost := "localhost"
 	wantDB := "benchmark"
@@ -1,4 +1,4 @@
-package main
+package loadtimescaledb
 
 import (
 	"fmt"
@@ -10,6 +10,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
 	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/logging"
 )
 
 const insertCSI = `INSERT INTO %s(time,tags_id,%s%s,additional_tags) VALUES %s`
@@ -206,7 +207,7 @@ type processor struct {
 
 func (p *processor) Init(workerNum int, doLoad bool) {
 	if doLoad {
-		p.db = sqlx.MustConnect(dbType, getConnectString())
+		p.db = mustConnect(getConnectString())
 		if hashWorkers {
 			p.csi = newSyncCSI()
 		} else {
@@ -235,7 +236,7 @@ func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
 				now := time.Now()
 				took := now.Sub(start)
 				batchSize := len(rows)
-				fmt.Printf("BATCH: batchsize %d row rate %f/sec (took %v)\n", batchSize, float64(batchSize)/float64(took.Seconds()), took)
+				logging.Info("loaded batch", "table", hypertable, "rows", batchSize, "rows_per_sec", float64(batchSize)/float64(took.Seconds()), "took", took)
 			}
 		}
 	}
@@ -1,19 +1,19 @@
-package main
+package loadtimescaledb
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/shirou/gopsutil/process"
+	"github.com/timescale/tsbs/pkg/logging"
 )
 
 func profileCPUAndMem(file string) {
 	f, err := os.Create(file)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("could not create profile file", "file", file, "error", err)
 	}
 	defer f.Close()
 
@@ -0,0 +1,243 @@
+package loadtimestream
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// apiVersion prefixes the names of the operations of the Timestream
+	// write API in the X-Amz-Target header
+	apiVersion = "Timestream_20181101"
+	// signingName is the name Timestream is signed for
+	signingName = "timestream"
+	contentType = "application/x-amz-json-1.0"
+)
+
+// credentials are the AWS credentials requests are signed with
+type credentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// apiError is an error returned by the Timestream API
+type apiError struct {
+	status int
+	// Type is the name of the exception, e.g., ThrottlingException
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+	// RejectedRecords are the records of a WriteRecords request that were
+	// rejected, e.g., for being outside of the retention of the table
+	RejectedRecords []struct {
+		RecordIndex int
+		Reason      string
+	}
+}
+
+func (e *apiError) Error() string {
+	msg := fmt.Sprintf("%s (status %d): %s", e.Type, e.status, e.Message)
+	for _, r := range e.RejectedRecords {
+		msg += fmt.Sprintf("; record %d: %s", r.RecordIndex, r.Reason)
+	}
+	return msg
+}
+
+// throttled returns whether the request failed for being over the limits of
+// Timestream, and should be retried more slowly
+func (e *apiError) throttled() bool {
+	return e.Type == "ThrottlingException" || e.status == http.StatusTooManyRequests || e.status >= http.StatusInternalServerError
+}
+
+// client calls the Timestream write API, signing requests with Signature
+// Version 4. Unless given an endpoint, it discovers the endpoint of the
+// account with DescribeEndpoints, as the AWS SDKs do.
+type client struct {
+	http   *http.Client
+	region string
+	creds  credentials
+	// discoveryURL is where DescribeEndpoints is called
+	discoveryURL string
+
+	mu       sync.Mutex
+	endpoint string
+	// expires is when the discovered endpoint must be discovered again
+	expires time.Time
+
+	// now is the time requests are signed at; it is a variable for testing
+	now func() time.Time
+}
+
+func newClient(region, endpoint string, creds credentials) *client {
+	return &client{
+		http:         &http.Client{Timeout: time.Minute},
+		region:       region,
+		creds:        creds,
+		discoveryURL: fmt.Sprintf("https://ingest.timestream.%s.amazonaws.com", region),
+		endpoint:     endpoint,
+		now:          time.Now,
+	}
+}
+
+// call calls operation with the JSON body in, decoding the response into
+// out if it is not nil
+func (c *client) call(operation string, in []byte, out interface{}) error {
+	endpoint, err := c.currentEndpoint()
+	if err != nil {
+		return err
+	}
+	return c.post(endpoint, operation, in, out)
+}
+
+// callJSON is like call, but encodes in as JSON
+func (c *client) callJSON(operation string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return c.call(operation, body, out)
+}
+
+// currentEndpoint returns the endpoint to send requests to, discovering it
+// again once the previous one expired
+func (c *client) currentEndpoint() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.endpoint) > 0 && (c.expires.IsZero() || c.now().Before(c.expires)) {
+		return c.endpoint, nil
+	}
+	var resp struct {
+		Endpoints []struct {
+			Address              string
+			CachePeriodInMinutes int64
+		}
+	}
+	if err := c.post(c.discoveryURL, "DescribeEndpoints", []byte("{}"), &resp); err != nil {
+		return "", fmt.Errorf("could not discover the Timestream endpoint: %v", err)
+	}
+	if len(resp.Endpoints) == 0 {
+		return "", fmt.Errorf("could not discover the Timestream endpoint: none returned")
+	}
+	e := resp.Endpoints[0]
+	c.endpoint = "https://" + e.Address
+	c.expires = c.now().Add(time.Duration(e.CachePeriodInMinutes) * time.Minute)
+	return c.endpoint, nil
+}
+
+func (c *client) post(endpoint, operation string, body []byte, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", apiVersion+"."+operation)
+	sign(req, body, c.creds, c.region, signingName, c.now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &apiError{status: resp.StatusCode}
+		json.Unmarshal(respBody, e)
+		// the type may be qualified, e.g., com.amazonaws.timestream.v20181101#ThrottlingException
+		if i := strings.LastIndexByte(e.Type, '#'); i >= 0 {
+			e.Type = e.Type[i+1:]
+		}
+		if len(e.Type) == 0 {
+			e.Type = http.StatusText(resp.StatusCode)
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// sign signs req, whose body is body, with AWS Signature Version 4 for
+// service in region at time t
+func sign(req *http.Request, body []byte, creds credentials, region, service string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if len(creds.sessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	// all headers set so far are signed, along with the host
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hashHex([]byte(canonicalRequest))
+	key := signingKey(creds.secretAccessKey, date, region, service)
+	signature := hex.EncodeToString(hmacSHA256(key, []byte(stringToSign)))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", creds.accessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery returns the query of a request in the canonical form of
+// Signature Version 4: sorted and with spaces escaped as %20
+func canonicalQuery(v url.Values) string {
+	return strings.Replace(v.Encode(), "+", "%20", -1)
+}
+
+func signingKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), []byte(date))
+	k = hmacSHA256(k, []byte(region))
+	k = hmacSHA256(k, []byte(service))
+	return hmacSHA256(k, []byte("aws4_request"))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,129 @@
+package loadtimestream
+
+import (
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain parses the default flags, which some tests depend on, as Run does
+func TestMain(m *testing.M) {
+	flag.Parse()
+	parseFlags(nil)
+	os.Exit(m.Run())
+}
+
+var testCreds = credentials{accessKeyID: "AKIDEXAMPLE", secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+// TestSign checks the example of the Signature Version 4 documentation
+func TestSign(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	sign(req, nil, testCreds, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("incorrect authorization:\ngot  %s\nwant %s", got, want)
+	}
+
+	creds := testCreds
+	creds.sessionToken = "token"
+	sign(req, nil, creds, "us-east-1", "iam", time.Now())
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, "x-amz-security-token") {
+		t.Errorf("session token not signed: %s", got)
+	}
+}
+
+// testAPI is a fake Timestream API, answering each operation with the
+// handler registered for it
+type testAPI struct {
+	mu       sync.Mutex
+	calls    []string
+	handlers map[string]func(body []byte) (int, string)
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), apiVersion+".")
+	body, _ := ioutil.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || r.Header.Get("Content-Type") != contentType {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	a.mu.Lock()
+	a.calls = append(a.calls, op)
+	h := a.handlers[op]
+	a.mu.Unlock()
+	status, resp := http.StatusOK, "{}"
+	if h != nil {
+		status, resp = h(body)
+	}
+	w.WriteHeader(status)
+	w.Write([]byte(resp))
+}
+
+func TestClientDiscovery(t *testing.T) {
+	api := &testAPI{handlers: map[string]func([]byte) (int, string){
+		"DescribeEndpoints": func([]byte) (int, string) {
+			return http.StatusOK, `{"Endpoints":[{"Address":"ingest-cell2.timestream.us-east-1.amazonaws.com","CachePeriodInMinutes":10}]}`
+		},
+	}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	c := newClient("us-east-1", "", testCreds)
+	c.discoveryURL = server.URL
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		endpoint, err := c.currentEndpoint()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "https://ingest-cell2.timestream.us-east-1.amazonaws.com"; endpoint != want {
+			t.Errorf("incorrect endpoint: got %s want %s", endpoint, want)
+		}
+		// the second call is cached, and the third after it expired
+		now = now.Add(6 * time.Minute)
+	}
+	if got := strings.Join(api.calls, ","); got != "DescribeEndpoints,DescribeEndpoints" {
+		t.Errorf("incorrect calls: got %s", got)
+	}
+
+	// an endpoint given is used as is
+	c = newClient("us-east-1", server.URL, testCreds)
+	if endpoint, err := c.currentEndpoint(); err != nil || endpoint != server.URL {
+		t.Errorf("incorrect endpoint: got %s, %v", endpoint, err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	api := &testAPI{handlers: map[string]func([]byte) (int, string){
+		"WriteRecords": func([]byte) (int, string) {
+			return http.StatusBadRequest, `{"__type":"com.amazonaws.timestream.v20181101#RejectedRecordsException","message":"One or more records have been rejected.","RejectedRecords":[{"RecordIndex":0,"Reason":"The record timestamp is outside the time range"}]}`
+		},
+		"DescribeDatabase": func([]byte) (int, string) {
+			return http.StatusServiceUnavailable, ""
+		},
+	}}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	c := newClient("us-east-1", server.URL, testCreds)
+
+	err := c.call("WriteRecords", []byte(`{"Records":[{}]}`), nil)
+	e, ok := err.(*apiError)
+	if !ok || e.Type != "RejectedRecordsException" || e.throttled() || !strings.Contains(err.Error(), "record 0: The record timestamp") {
+		t.Errorf("incorrect error: %v", err)
+	}
+	err = c.callJSON("DescribeDatabase", map[string]string{"DatabaseName": "benchmark"}, nil)
+	if e, ok := err.(*apiError); !ok || !e.throttled() || e.Type != "Service Unavailable" {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package loadtimestream
+
+import (
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+type dbCreator struct{}
+
+func (d *dbCreator) Init() {}
+
+func (d *dbCreator) DBExists(dbName string) bool {
+	err := writeClient.callJSON("DescribeDatabase", map[string]string{"DatabaseName": dbName}, nil)
+	if e, ok := err.(*apiError); ok && e.Type == "ResourceNotFoundException" {
+		return false
+	} else if err != nil {
+		fatal(cli.ExitUnreachable, "could not describe database", "database", dbName, "error", err)
+	}
+	return true
+}
+
+// RemoveOldDB deletes the database, deleting its tables first as
+// Timestream requires
+func (d *dbCreator) RemoveOldDB(dbName string) error {
+	var tables []string
+	next := ""
+	for {
+		in := map[string]interface{}{"DatabaseName": dbName}
+		if len(next) > 0 {
+			in["NextToken"] = next
+		}
+		var out struct {
+			Tables []struct {
+				TableName string
+			}
+			NextToken string
+		}
+		if err := writeClient.callJSON("ListTables", in, &out); err != nil {
+			return err
+		}
+		for _, t := range out.Tables {
+			tables = append(tables, t.TableName)
+		}
+		if next = out.NextToken; len(next) == 0 {
+			break
+		}
+	}
+	for _, t := range tables {
+		if err := writeClient.callJSON("DeleteTable", map[string]string{"DatabaseName": dbName, "TableName": t}, nil); err != nil {
+			return err
+		}
+	}
+	return writeClient.callJSON("DeleteDatabase", map[string]string{"DatabaseName": dbName}, nil)
+}
+
+// CreateDB creates the database and the table the records are written to
+func (d *dbCreator) CreateDB(dbName string) error {
+	if err := writeClient.callJSON("CreateDatabase", map[string]string{"DatabaseName": dbName}, nil); err != nil {
+		return err
+	}
+	in := map[string]interface{}{
+		"DatabaseName": dbName,
+		"TableName":    tableName,
+		"RetentionProperties": map[string]int64{
+			"MemoryStoreRetentionPeriodInHours":  memoryRetentionHours,
+			"MagneticStoreRetentionPeriodInDays": magneticRetentionDays,
+		},
+		"MagneticStoreWriteProperties": map[string]bool{
+			"EnableMagneticStoreWrites": magneticWrites,
+		},
+	}
+	return writeClient.callJSON("CreateTable", in, nil)
+}
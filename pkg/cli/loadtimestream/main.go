@@ -0,0 +1,137 @@
+// Package loadtimestream implements tsbs_load_timestream (also run as
+// `tsbs load timestream`), which loads an Amazon Timestream database with
+// data from stdin.
+//
+// The records are written with the WriteRecords API, 100 at a time, and the
+// requests of all workers are slowed down whenever Timestream throttles them.
+// Requests are signed with the AWS credentials in the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and (optionally) AWS_SESSION_TOKEN environment
+// variables.
+//
+// If the database exists beforehand, it will be *DROPPED*.
+package loadtimestream
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/data/serialize/timestream"
+)
+
+// Program option vars:
+var (
+	region                string
+	endpoint              string
+	tableName             string
+	memoryRetentionHours  int64
+	magneticRetentionDays int64
+	magneticWrites        bool
+	maxRetries            int
+	minBackoff            time.Duration
+	maxBackoff            time.Duration
+)
+
+// Global vars
+var (
+	loader        *load.BenchmarkRunner
+	writeClient   *client
+	writeThrottle *throttle
+)
+
+// fatal exits with code on failures while loading; it is a variable for
+// testing
+var fatal = cli.Fatal
+
+// fatalData exits on input that is not in the format written by
+// tsbs_generate_data; it is a variable for testing
+var fatalData = func(format string, args ...interface{}) {
+	cli.Fatal(cli.ExitData, fmt.Sprintf(format, args...))
+}
+
+// parseFlags registers the command line flags of tsbs_load_timestream and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	loader = load.GetBenchmarkRunnerWithBatchSize(maxRecordsPerWrite)
+
+	defaultRegion := os.Getenv("AWS_REGION")
+	if len(defaultRegion) == 0 {
+		defaultRegion = "us-east-1"
+	}
+	flag.StringVar(&region, "region", defaultRegion, "AWS region of the Timestream database (default: $AWS_REGION or us-east-1)")
+	flag.StringVar(&endpoint, "endpoint", "", "URL of the Timestream write API, e.g., of a VPC endpoint (default: discovered with DescribeEndpoints)")
+	flag.StringVar(&tableName, "table-name", "metrics", "Name of the table to write the records of all measurements to")
+	flag.Int64Var(&memoryRetentionHours, "memory-retention-hours", 24, "Memory store retention of the table created, in hours")
+	flag.Int64Var(&magneticRetentionDays, "magnetic-retention-days", 365, "Magnetic store retention of the table created, in days")
+	flag.BoolVar(&magneticWrites, "magnetic-writes", true, "Whether the table created accepts records older than its memory store retention, e.g., for data generated for past time ranges")
+	flag.IntVar(&maxRetries, "max-retries", 10, "Number of times a throttled request is retried before giving up")
+	flag.DurationVar(&minBackoff, "min-backoff", 50*time.Millisecond, "Delay between the requests of all workers after the first is throttled, which doubles with each further one")
+	flag.DurationVar(&maxBackoff, "max-backoff", 5*time.Second, "Longest delay between requests when throttled")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_load_timestream", args); err != nil {
+		return err
+	}
+	if minBackoff <= 0 || maxBackoff < minBackoff {
+		return cli.ConfigError(fmt.Errorf("invalid backoff: min-backoff must be greater than 0 and at most max-backoff"))
+	}
+	return nil
+}
+
+// credentialsFromEnv returns the AWS credentials in the environment
+func credentialsFromEnv() (credentials, error) {
+	c := credentials{
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if len(c.accessKeyID) == 0 || len(c.secretAccessKey) == 0 {
+		return c, fmt.Errorf("no AWS credentials: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return c, nil
+}
+
+type benchmark struct{}
+
+func (b *benchmark) GetPointDecoder(br *bufio.Reader) load.PointDecoder {
+	return &decoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *benchmark) GetBatchFactory() load.BatchFactory {
+	return &factory{}
+}
+
+func (b *benchmark) GetPointIndexer(_ uint) load.PointIndexer {
+	return &load.ConstantIndexer{}
+}
+
+func (b *benchmark) GetProcessor() load.Processor {
+	return &processor{}
+}
+
+func (b *benchmark) GetDBCreator() load.DBCreator {
+	return &dbCreator{}
+}
+
+func (b *benchmark) DataFormat() string {
+	return timestream.Format
+}
+
+// Run runs tsbs_load_timestream with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	creds, err := credentialsFromEnv()
+	if err != nil {
+		return cli.ConfigError(err)
+	}
+	writeClient = newClient(region, endpoint, creds)
+	writeThrottle = newThrottle(minBackoff, maxBackoff)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_load_timestream")
+	return md.Finish(loader.SaveResults(md, loader.RunBenchmark(&benchmark{}, load.SingleQueue)))
+}
@@ -0,0 +1,86 @@
+package loadtimestream
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// maxRecordsPerWrite is the most records a WriteRecords request takes
+const maxRecordsPerWrite = 100
+
+type processor struct {
+	workerNum int
+	body      bytes.Buffer
+	// prefix starts the body of every WriteRecords request
+	prefix    []byte
+	throttled uint64
+}
+
+func (p *processor) Init(workerNum int, _ bool) {
+	p.workerNum = workerNum
+	db, _ := json.Marshal(loader.DatabaseName())
+	table, _ := json.Marshal(tableName)
+	p.prefix = []byte(`{"DatabaseName":` + string(db) + `,"TableName":` + string(table) + `,"Records":[`)
+}
+
+func (p *processor) Close(_ bool) {
+	if p.throttled > 0 {
+		logging.Info("requests throttled", "worker", p.workerNum, "count", p.throttled)
+	}
+}
+
+func (p *processor) ProcessBatch(b load.Batch, doLoad bool) (uint64, uint64) {
+	batch := b.(*batch)
+	if doLoad {
+		for i := 0; i < len(batch.records); i += maxRecordsPerWrite {
+			end := i + maxRecordsPerWrite
+			if end > len(batch.records) {
+				end = len(batch.records)
+			}
+			p.writeRecords(batch.records[i:end])
+		}
+	}
+	return batch.metrics, uint64(len(batch.records))
+}
+
+// writeRecords writes records with a WriteRecords request, retrying it as
+// long as it is throttled, up to -max-retries times
+func (p *processor) writeRecords(records [][]byte) {
+	p.body.Reset()
+	p.body.Write(p.prefix)
+	for i, r := range records {
+		if i > 0 {
+			p.body.WriteByte(',')
+		}
+		p.body.Write(r)
+	}
+	p.body.WriteString("]}")
+
+	for attempt := 0; ; attempt++ {
+		writeThrottle.wait()
+		err := writeClient.call("WriteRecords", p.body.Bytes(), nil)
+		if err == nil {
+			writeThrottle.succeeded()
+			return
+		}
+		e, ok := err.(*apiError)
+		switch {
+		case !ok:
+			fatal(cli.ExitUnreachable, "could not write records", "worker", p.workerNum, "error", err)
+		case e.throttled() && attempt < maxRetries:
+			p.throttled++
+			writeThrottle.throttled()
+			logging.Debug("request throttled", "worker", p.workerNum, "delay", writeThrottle.current(), "error", err)
+			continue
+		case e.Type == "RejectedRecordsException" || e.Type == "ValidationException":
+			fatal(cli.ExitData, "records rejected", "worker", p.workerNum, "error", err)
+		default:
+			fatal(cli.ExitFailure, "could not write records", "worker", p.workerNum, "error", err)
+		}
+		return
+	}
+}
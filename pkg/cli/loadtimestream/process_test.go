@@ -0,0 +1,184 @@
+package loadtimestream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/load"
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+const testRecord = `{"Dimensions":[{"Name":"hostname","Value":"host_0"}],"MeasureName":"cpu","MeasureValueType":"MULTI","MeasureValues":[{"Name":"usage_user","Value":"58","Type":"BIGINT"},{"Name":"usage_system","Value":"2.5","Type":"DOUBLE"}],"Time":"1451606400000000000","TimeUnit":"NANOSECONDS"}`
+
+// useTestAPI points the loader at api, returning a function restoring it
+func useTestAPI(t *testing.T, api *testAPI) func() {
+	server := httptest.NewServer(api)
+	oldClient, oldThrottle, oldFatal := writeClient, writeThrottle, fatal
+	writeClient = newClient("us-east-1", server.URL, testCreds)
+	writeThrottle = newThrottle(time.Millisecond, 10*time.Millisecond)
+	writeThrottle.sleep = func(time.Duration) {}
+	fatal = func(code int, msg string, args ...interface{}) {
+		t.Errorf("fatal with code %d: %s %v", code, msg, args)
+	}
+	return func() {
+		server.Close()
+		writeClient, writeThrottle, fatal = oldClient, oldThrottle, oldFatal
+	}
+}
+
+func TestBatchAppend(t *testing.T) {
+	b := (&factory{}).New().(*batch)
+	b.Append(load.NewPoint([]byte(testRecord)))
+	b.Append(load.NewPoint([]byte(testRecord)))
+	if b.Len() != 2 || b.metrics != 4 {
+		t.Errorf("incorrect batch: %d records, %d metrics", b.Len(), b.metrics)
+	}
+
+	oldFatalData := fatalData
+	defer func() { fatalData = oldFatalData }()
+	var got string
+	fatalData = func(format string, args ...interface{}) { got = fmt.Sprintf(format, args...) }
+	b.Append(load.NewPoint([]byte("cpu,hostname=host_0 usage_user=58i 1451606400000000000")))
+	if !strings.HasPrefix(got, "parse error") || b.Len() != 2 {
+		t.Errorf("line in another format not rejected: %q", got)
+	}
+}
+
+func TestProcessBatch(t *testing.T) {
+	var sizes []int
+	throttled := 0
+	api := &testAPI{handlers: map[string]func([]byte) (int, string){
+		"WriteRecords": func(body []byte) (int, string) {
+			var in struct {
+				DatabaseName string
+				TableName    string
+				Records      []json.RawMessage
+			}
+			if err := json.Unmarshal(body, &in); err != nil {
+				return http.StatusBadRequest, `{"__type":"ValidationException","message":"invalid JSON"}`
+			}
+			if in.DatabaseName != "benchmark" || in.TableName != "metrics" {
+				return http.StatusBadRequest, `{"__type":"ValidationException","message":"wrong table"}`
+			}
+			// the first request is throttled once
+			if throttled == 0 {
+				throttled++
+				return http.StatusBadRequest, `{"__type":"com.amazonaws.timestream.v20181101#ThrottlingException","message":"slow down"}`
+			}
+			sizes = append(sizes, len(in.Records))
+			return http.StatusOK, `{"RecordsIngested":{"Total":1}}`
+		},
+	}}
+	defer useTestAPI(t, api)()
+
+	b := (&factory{}).New().(*batch)
+	for i := 0; i < 250; i++ {
+		b.Append(load.NewPoint([]byte(testRecord)))
+	}
+	p := &processor{}
+	p.Init(0, true)
+	metrics, rows := p.ProcessBatch(b, true)
+	if metrics != 500 || rows != 250 {
+		t.Errorf("incorrect counts: got %d metrics, %d rows", metrics, rows)
+	}
+	if fmt.Sprint(sizes) != "[100 100 50]" {
+		t.Errorf("incorrect requests: got sizes %v", sizes)
+	}
+	if p.throttled != 1 {
+		t.Errorf("incorrect number throttled: got %d", p.throttled)
+	}
+
+	// without loading, nothing is written
+	sizes = nil
+	if metrics, rows := p.ProcessBatch(b, false); metrics != 500 || rows != 250 || sizes != nil {
+		t.Errorf("incorrect counts without loading: %d, %d, requests %v", metrics, rows, sizes)
+	}
+}
+
+func TestProcessBatchErrors(t *testing.T) {
+	cases := []struct {
+		desc     string
+		status   int
+		resp     string
+		wantCode int
+		// wantCalls is the number of requests made before giving up
+		wantCalls int
+	}{
+		{desc: "rejected", status: http.StatusBadRequest, resp: `{"__type":"RejectedRecordsException"}`, wantCode: cli.ExitData, wantCalls: 1},
+		{desc: "forbidden", status: http.StatusBadRequest, resp: `{"__type":"AccessDeniedException"}`, wantCode: cli.ExitFailure, wantCalls: 1},
+		{desc: "throttled too often", status: http.StatusTooManyRequests, resp: ``, wantCode: cli.ExitFailure, wantCalls: maxRetries + 1},
+	}
+	for _, c := range cases {
+		api := &testAPI{handlers: map[string]func([]byte) (int, string){
+			"WriteRecords": func([]byte) (int, string) { return c.status, c.resp },
+		}}
+		restore := useTestAPI(t, api)
+		gotCode := -1
+		fatal = func(code int, msg string, args ...interface{}) { gotCode = code }
+
+		b := (&factory{}).New().(*batch)
+		b.Append(load.NewPoint([]byte(testRecord)))
+		p := &processor{}
+		p.Init(0, true)
+		p.ProcessBatch(b, true)
+		if gotCode != c.wantCode {
+			t.Errorf("%s: incorrect exit code: got %d want %d", c.desc, gotCode, c.wantCode)
+		}
+		if len(api.calls) != c.wantCalls {
+			t.Errorf("%s: incorrect number of requests: got %d want %d", c.desc, len(api.calls), c.wantCalls)
+		}
+		restore()
+	}
+}
+
+func TestDBCreator(t *testing.T) {
+	var created map[string]interface{}
+	api := &testAPI{handlers: map[string]func([]byte) (int, string){
+		"DescribeDatabase": func([]byte) (int, string) {
+			return http.StatusOK, `{"Database":{"DatabaseName":"benchmark"}}`
+		},
+		"ListTables": func(body []byte) (int, string) {
+			if strings.Contains(string(body), `"NextToken":"page2"`) {
+				return http.StatusOK, `{"Tables":[{"TableName":"b"}]}`
+			}
+			return http.StatusOK, `{"Tables":[{"TableName":"a"}],"NextToken":"page2"}`
+		},
+		"CreateTable": func(body []byte) (int, string) {
+			json.Unmarshal(body, &created)
+			return http.StatusOK, `{}`
+		},
+	}}
+	defer useTestAPI(t, api)()
+
+	d := &dbCreator{}
+	d.Init()
+	if !d.DBExists("benchmark") {
+		t.Errorf("database does not exist")
+	}
+	if err := d.RemoveOldDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := d.CreateDB("benchmark"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "DescribeDatabase,ListTables,ListTables,DeleteTable,DeleteTable,DeleteDatabase,CreateDatabase,CreateTable"
+	if got := strings.Join(api.calls, ","); got != want {
+		t.Errorf("incorrect calls:\ngot  %s\nwant %s", got, want)
+	}
+	retention := created["RetentionProperties"].(map[string]interface{})
+	if created["TableName"] != "metrics" || retention["MemoryStoreRetentionPeriodInHours"] != 24.0 || retention["MagneticStoreRetentionPeriodInDays"] != 365.0 {
+		t.Errorf("incorrect table created: %v", created)
+	}
+
+	api.handlers["DescribeDatabase"] = func([]byte) (int, string) {
+		return http.StatusBadRequest, `{"__type":"ResourceNotFoundException","message":"no database"}`
+	}
+	if d.DBExists("benchmark") {
+		t.Errorf("missing database exists")
+	}
+}
@@ -0,0 +1,54 @@
+package loadtimestream
+
+import (
+	"bufio"
+	"bytes"
+
+	"github.com/timescale/tsbs/load"
+)
+
+// measureValueType appears once per measure value of a record, so counting
+// it counts the metrics of the record
+var measureValueType = []byte(`"Type":`)
+
+type decoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *decoder) Decode(_ *bufio.Reader) *load.Point {
+	ok := d.scanner.Scan()
+	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
+		return nil
+	} else if !ok {
+		fatalData("scan error: %v", d.scanner.Err())
+		return nil
+	}
+	return load.NewPoint(d.scanner.Bytes())
+}
+
+// batch holds the records of a batch, one JSON object per line as written
+// by tsbs_generate_data
+type batch struct {
+	records [][]byte
+	metrics uint64
+}
+
+func (b *batch) Len() int {
+	return len(b.records)
+}
+
+func (b *batch) Append(item *load.Point) {
+	line := item.Data.([]byte)
+	if len(line) == 0 || line[0] != '{' {
+		fatalData("parse error: line is not a Timestream record: %s", line)
+		return
+	}
+	b.metrics += uint64(bytes.Count(line, measureValueType))
+	b.records = append(b.records, append([]byte(nil), line...))
+}
+
+type factory struct{}
+
+func (f *factory) New() load.Batch {
+	return &batch{}
+}
@@ -0,0 +1,75 @@
+package loadtimestream
+
+import (
+	"sync"
+	"time"
+)
+
+// throttle adapts the rate of the requests of all workers to what Timestream
+// accepts. Requests are spaced by a delay, which doubles each time one is
+// throttled and shrinks by a tenth with each one that succeeds, so the
+// workers back off quickly and then creep back up to the highest rate
+// Timestream sustains.
+type throttle struct {
+	mu sync.Mutex
+	// min and max bound the delay once throttled
+	min, max time.Duration
+	delay    time.Duration
+	// next is when the next request may be sent
+	next time.Time
+
+	// now and sleep are variables for testing
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newThrottle(min, max time.Duration) *throttle {
+	return &throttle{min: min, max: max, now: time.Now, sleep: time.Sleep}
+}
+
+// wait blocks until a request may be sent
+func (t *throttle) wait() {
+	t.mu.Lock()
+	now := t.now()
+	start := now
+	if t.next.After(now) {
+		start = t.next
+	}
+	t.next = start.Add(t.delay)
+	t.mu.Unlock()
+	if d := start.Sub(now); d > 0 {
+		t.sleep(d)
+	}
+}
+
+// throttled slows down the requests after one was throttled
+func (t *throttle) throttled() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delay *= 2
+	if t.delay < t.min {
+		t.delay = t.min
+	}
+	if t.delay > t.max {
+		t.delay = t.max
+	}
+	t.next = t.now().Add(t.delay)
+}
+
+// succeeded speeds up the requests after one succeeded
+func (t *throttle) succeeded() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delay -= t.delay / 10
+	// stop spacing requests once the delay is negligible
+	if t.delay < t.min/10 {
+		t.delay = 0
+	}
+}
+
+// current returns the current delay between requests
+func (t *throttle) current() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.delay
+}
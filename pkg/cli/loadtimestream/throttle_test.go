@@ -0,0 +1,60 @@
+package loadtimestream
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var slept []time.Duration
+	th := newThrottle(100*time.Millisecond, time.Second)
+	th.now = func() time.Time { return now }
+	th.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	// not throttled, requests are not spaced
+	th.wait()
+	th.wait()
+	if len(slept) != 0 {
+		t.Errorf("slept before being throttled: %v", slept)
+	}
+
+	cases := []struct {
+		desc      string
+		throttled bool
+		want      time.Duration
+	}{
+		{desc: "first throttled", throttled: true, want: 100 * time.Millisecond},
+		{desc: "throttled again", throttled: true, want: 200 * time.Millisecond},
+		{desc: "succeeded", want: 180 * time.Millisecond},
+		{desc: "throttled up to max", throttled: true, want: 360 * time.Millisecond},
+		{desc: "throttled at max", throttled: true, want: 720 * time.Millisecond},
+		{desc: "still at max", throttled: true, want: time.Second},
+	}
+	for _, c := range cases {
+		if c.throttled {
+			th.throttled()
+		} else {
+			th.succeeded()
+		}
+		if got := th.current(); got != c.want {
+			t.Errorf("%s: incorrect delay: got %v want %v", c.desc, got, c.want)
+		}
+	}
+
+	// the requests of all workers are spaced by the delay
+	slept = nil
+	th.wait()
+	th.wait()
+	if len(slept) != 2 || slept[0] != time.Second || slept[1] != 2*time.Second {
+		t.Errorf("incorrect sleeps: %v", slept)
+	}
+
+	// the delay shrinks to nothing as requests succeed
+	for i := 0; i < 100; i++ {
+		th.succeeded()
+	}
+	if got := th.current(); got != 0 {
+		t.Errorf("delay did not recover: %v", got)
+	}
+}
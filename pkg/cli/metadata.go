@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/version"
+)
+
+// MetadataFlag is the name of the flag giving the file to which to write the
+// Metadata of a run
+const MetadataFlag = "metadata-file"
+
+// Metadata describes a run of a tool, so it can be reproduced and its
+// outputs checked: the effective value of every flag, including the seed
+// drawn when none is given, the build of the binary, when the run started
+// and ended, and the checksums of its outputs.
+type Metadata struct {
+	Tool  string       `json:"tool"`
+	Build version.Info `json:"build"`
+	// Seed is the seed actually used by tools generating random data or
+	// queries, which is drawn from the current time if -seed is not given
+	Seed int64 `json:"seed,omitempty"`
+	// Flags are the values of all flags, whether given on the command line,
+	// in the environment or in a config file, or left to their defaults.
+	// Tools replace values they resolve, e.g., relative timestamps.
+	Flags   map[string]string `json:"flags"`
+	Start   time.Time         `json:"start"`
+	End     time.Time         `json:"end"`
+	Outputs []OutputChecksum  `json:"outputs,omitempty"`
+	// Incomplete is set when the run failed or was interrupted
+	Incomplete bool `json:"incomplete,omitempty"`
+
+	file string
+}
+
+// OutputChecksum is the checksum of an output of a run
+type OutputChecksum struct {
+	// File is the name of the output file, or "-" for stdout
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
+
+// StartMetadata returns the Metadata of a run of tool starting now, with the
+// flags of fs, which must have been parsed by ParseFlags
+func StartMetadata(fs *flag.FlagSet, tool string) *Metadata {
+	m := &Metadata{
+		Tool:  tool,
+		Build: version.Get(),
+		Flags: make(map[string]string),
+		Start: time.Now().UTC(),
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		switch f.Name {
+		case VersionFlag:
+		case MetadataFlag:
+			m.file = f.Value.String()
+		default:
+			m.Flags[f.Name] = f.Value.String()
+		}
+	})
+	return m
+}
+
+// AddOutput records the SHA-256 checksum, in hex, of an output file
+func (m *Metadata) AddOutput(file, sha256 string) {
+	if len(file) == 0 {
+		file = "-"
+	}
+	m.Outputs = append(m.Outputs, OutputChecksum{File: file, SHA256: sha256})
+}
+
+// Enabled returns whether the metadata is written, so tools can skip
+// computing checksums otherwise
+func (m *Metadata) Enabled() bool {
+	return len(m.file) > 0
+}
+
+// Finish records the end of the run, which is incomplete if runErr (the
+// error the run ended with, e.g., ErrInterrupted) is not nil, and writes the
+// metadata as JSON to the file given by -metadata-file, if any. It returns
+// runErr, or else any error writing the metadata, so tools can end with
+// return md.Finish(err).
+func (m *Metadata) Finish(runErr error) error {
+	m.End = time.Now().UTC()
+	m.Incomplete = runErr != nil
+	if err := m.write(); err != nil && runErr == nil {
+		return err
+	}
+	return runErr
+}
+
+func (m *Metadata) write() error {
+	if !m.Enabled() {
+		return nil
+	}
+	f, err := os.Create(m.file)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
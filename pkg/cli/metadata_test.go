@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-metadata")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "metadata.json")
+
+	fs := flag.NewFlagSet("tsbs_test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	fs.Int("workers", 1, "")
+	fs.String("db-name", "benchmark", "")
+	os.Setenv("TSBS_DB_NAME", "env")
+	err = ParseFlags(fs, "tsbs_test", []string{"-workers=4", "-metadata-file", file})
+	os.Unsetenv("TSBS_DB_NAME")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m := StartMetadata(fs, "tsbs_test")
+	if !m.Enabled() {
+		t.Fatalf("metadata not enabled by -%s", MetadataFlag)
+	}
+	m.Seed = 123
+	m.AddOutput("", "abc")
+	if err := m.Finish(ErrInterrupted); err != ErrInterrupted {
+		t.Fatalf("incorrect error: got %v want %v", err, ErrInterrupted)
+	}
+
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("could not read metadata: %v", err)
+	}
+	var got Metadata
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("could not parse metadata: %v\n%s", err, b)
+	}
+	if got.Tool != "tsbs_test" || got.Seed != 123 || !got.Incomplete {
+		t.Errorf("incorrect metadata: %+v", got)
+	}
+	if got.Flags["workers"] != "4" || got.Flags["db-name"] != "env" {
+		t.Errorf("incorrect flags: %v", got.Flags)
+	}
+	if _, ok := got.Flags[MetadataFlag]; ok {
+		t.Errorf("metadata file recorded as a flag")
+	}
+	if want := []OutputChecksum{{File: "-", SHA256: "abc"}}; !reflect.DeepEqual(got.Outputs, want) {
+		t.Errorf("incorrect outputs: got %v want %v", got.Outputs, want)
+	}
+	if got.Start.IsZero() || got.End.Before(got.Start) {
+		t.Errorf("incorrect start and end: %v, %v", got.Start, got.End)
+	}
+
+	// without -metadata-file nothing is written
+	fs = flag.NewFlagSet("tsbs_test", flag.ContinueOnError)
+	if err := ParseFlags(fs, "tsbs_test", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m = StartMetadata(fs, "tsbs_test")
+	if m.Enabled() {
+		t.Errorf("metadata enabled without -%s", MetadataFlag)
+	}
+	if err := m.Finish(nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
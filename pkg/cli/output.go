@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// PartialSuffix is appended to the name of an output file that was not
+// completed, e.g., because generation failed or was interrupted
+const PartialSuffix = ".partial"
+
+// OutputFile is an output file written under a temporary name in the same
+// directory, and only renamed to its name once complete. A file by that name
+// is thus never left truncated: it is either the complete output or, if
+// there was one, the previous file.
+type OutputFile struct {
+	*os.File
+	name string
+}
+
+// CreateOutputFile creates the temporary file for the output file name
+func CreateOutputFile(name string) (*OutputFile, error) {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("could not create output file %s: %v", name, err)
+	}
+	// temporary files are only readable by their owner, unlike the files
+	// shell redirection creates
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &OutputFile{File: f, name: name}, nil
+}
+
+// Name returns the name the file gets once complete
+func (f *OutputFile) Name() string {
+	return f.name
+}
+
+// Commit closes the file and renames it to its name, replacing any file by
+// that name
+func (f *OutputFile) Commit() error {
+	if err := f.File.Sync(); err != nil {
+		f.Abort()
+		return err
+	}
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.File.Name())
+		return err
+	}
+	return os.Rename(f.File.Name(), f.name)
+}
+
+// Abort closes the file and renames it to its name with PartialSuffix, so
+// what was written is kept without being mistaken for the complete output.
+// It returns the name the file was renamed to.
+func (f *OutputFile) Abort() (string, error) {
+	partial := f.name + PartialSuffix
+	f.File.Close()
+	return partial, os.Rename(f.File.Name(), partial)
+}
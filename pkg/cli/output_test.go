@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs-output")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "out.txt")
+
+	cases := []struct {
+		desc     string
+		commit   bool
+		wantName string
+		wantGone string
+	}{
+		{desc: "commit", commit: true, wantName: name, wantGone: name + PartialSuffix},
+		{desc: "abort", wantName: name + PartialSuffix},
+	}
+	for _, c := range cases {
+		os.Remove(name)
+		os.Remove(name + PartialSuffix)
+
+		f, err := CreateOutputFile(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		if f.Name() != name {
+			t.Errorf("%s: incorrect name: got %s want %s", c.desc, f.Name(), name)
+		}
+		if _, err := f.WriteString("data"); err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Errorf("%s: output exists before it is complete", c.desc)
+		}
+
+		if c.commit {
+			err = f.Commit()
+		} else {
+			var partial string
+			partial, err = f.Abort()
+			if partial != c.wantName {
+				t.Errorf("%s: incorrect partial name: got %s want %s", c.desc, partial, c.wantName)
+			}
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+
+		b, err := ioutil.ReadFile(c.wantName)
+		if err != nil {
+			t.Fatalf("%s: could not read output: %v", c.desc, err)
+		}
+		if got := string(b); got != "data" {
+			t.Errorf("%s: incorrect output: got %q", c.desc, got)
+		}
+		if c.wantGone != "" {
+			if _, err := os.Stat(c.wantGone); !os.IsNotExist(err) {
+				t.Errorf("%s: unexpected file %s", c.desc, c.wantGone)
+			}
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("%s: could not read dir: %v", c.desc, err)
+		}
+		if len(files) != 1 {
+			t.Errorf("%s: temporary file left behind: got %d files", c.desc, len(files))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package runqueriesadx implements tsbs_run_queries_adx (also run as
+// `tsbs run adx`), which speed tests Azure Data Explorer (Kusto) using
+// queries from stdin.
+//
+// It reads encoded Query objects from stdin, and runs their KQL
+// concurrently with the query endpoint of the REST API of the cluster.
+// Requests are authenticated with the credentials in the environment (see
+// kusto.CredentialsFromEnv).
+package runqueriesadx
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/kusto"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	clusterURL string
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+	client *kusto.Client
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_adx and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("adx")
+
+	flag.StringVar(&clusterURL, "cluster", "http://localhost:8080", "URL of the Azure Data Explorer cluster.")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_adx", args)
+}
+
+// Run runs tsbs_run_queries_adx with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	creds := kusto.CredentialsFromEnv()
+	if err := creds.Validate(); err != nil {
+		return cli.ConfigError(err)
+	}
+	client = kusto.NewClient(clusterURL, creds)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_adx")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.HTTPPool, newProcessor)))
+}
+
+type processor struct {
+	printResponses bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	p.printResponses = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	start := time.Now()
+	resp, err := client.Query(runner.DatabaseName(), string(hq.Body))
+	if _, ok := err.(*kusto.Error); err != nil && !ok {
+		cli.Fatal(cli.ExitUnreachable, "could not reach Azure Data Explorer", "cluster", clusterURL, "error", err)
+	} else if err != nil {
+		return nil, err
+	}
+	lag := float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
+
+	if p.printResponses {
+		var pretty bytes.Buffer
+		prefix := fmt.Sprintf("ID %d: ", q.GetID())
+		if err := json.Indent(&pretty, resp, prefix, "  "); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", prefix, pretty.Bytes())
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, nil
+}
@@ -1,12 +1,12 @@
-package main
+package runqueriescassandra
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/timescale/tsbs/pkg/logging"
 )
 
 // A ClientSideIndex wraps runtime data used to translate an HLQuery into
@@ -25,7 +25,7 @@ type ClientSideIndex struct {
 // seriesCollection (typically by calling FetchSeriesCollection).
 func NewClientSideIndex(seriesCollection []Series) *ClientSideIndex {
 	if len(seriesCollection) == 0 {
-		log.Fatal("logic error: no data to build ClientSideIndex")
+		logging.Fatal("logic error: no data to build ClientSideIndex")
 	}
 
 	// build the "time interval -> series" index:
@@ -125,7 +125,7 @@ func (s *Series) parse() {
 	// cpu,hostname=host_0,region=eu-central-1,datacenter=eu-central-1a,rack=42,os=Ubuntu16.10,arch=x64,team=CHI,service=19,service_version=1,service_environment=staging#usage_idle#2016-01-01
 	sections := strings.Split(s.Id, "#")
 	if len(sections) != 3 {
-		log.Fatal("logic error: invalid series id")
+		logging.Fatal("logic error: invalid series id", "id", s.Id)
 	}
 	measurementAndTags := strings.Split(sections[0], ",")
 
@@ -136,7 +136,7 @@ func (s *Series) parse() {
 	tags := map[string]struct{}{}
 	for _, tag := range measurementAndTags[1:] {
 		if _, ok := tags[tag]; ok {
-			log.Fatal("logic error: duplicate tag")
+			logging.Fatal("logic error: duplicate tag", "tag", tag)
 		}
 
 		tags[tag] = struct{}{}
@@ -149,7 +149,7 @@ func (s *Series) parse() {
 	// parse time interval:
 	start, err := time.Parse(BucketTimeLayout, sections[2])
 	if err != nil {
-		log.Fatal("bad time bucket parse in pre-existing database series")
+		logging.Fatal("bad time bucket parse in pre-existing database series", "bucket", sections[2], "error", err)
 	}
 	end := start.Add(BucketDuration)
 	s.TimeInterval = NewTimeInterval(start, end)
@@ -204,7 +204,7 @@ func FetchSeriesCollection(session *gocql.Session) []Series {
 			seriesCollection = append(seriesCollection, s)
 		}
 		if err := iter.Close(); err != nil {
-			log.Fatal(err)
+			logging.Fatal("could not fetch series", "error", err)
 		}
 	}
 
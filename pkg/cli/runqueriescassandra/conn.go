@@ -1,10 +1,10 @@
-package main
+package runqueriescassandra
 
 import (
-	"log"
 	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/timescale/tsbs/pkg/cli"
 )
 
 // NewCassandraSession creates a new Cassandra session. It is goroutine-safe
@@ -17,7 +17,7 @@ func NewCassandraSession(daemonURL, keyspace string, timeout time.Duration) *goc
 	cluster.Timeout = timeout
 	session, err := cluster.CreateSession()
 	if err != nil {
-		log.Fatal(err)
+		cli.Fatal(cli.ExitUnreachable, "could not connect to Cassandra", "error", err)
 	}
 	return session
 }
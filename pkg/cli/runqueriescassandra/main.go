@@ -0,0 +1,157 @@
+// Package runqueriescassandra implements tsbs_run_queries_cassandra (also run
+// as `tsbs run cassandra`), which speed tests Cassandra servers using request
+// data from stdin.
+//
+// It reads encoded HLQuery objects from stdin, and makes concurrent requests
+// to the provided Cassandra cluster. This program is a 'heavy client', i.e.
+// it builds a client-side index of table metadata before beginning the
+// benchmarking.
+package runqueriescassandra
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/provision"
+	"github.com/timescale/tsbs/query"
+)
+
+const (
+	BucketDuration   = 24 * time.Hour
+	BucketTimeLayout = "2006-01-02"
+)
+
+// Blessed tables that hold benchmark data:
+var (
+	BlessedTables = []string{
+		"series_bigint",
+		"series_float",
+		"series_double",
+		"series_boolean",
+		"series_blob",
+	}
+)
+
+// Program option vars:
+var (
+	daemonURL      string
+	aggrPlanLabel  string
+	requestTimeout time.Duration
+	csiTimeout     time.Duration
+	container      *provision.Options
+)
+
+// Helpers for choice-like flags:
+var (
+	aggrPlanChoices = map[string]int{
+		"server": AggrPlanTypeWithServerAggregation,
+		"client": AggrPlanTypeWithoutServerAggregation,
+	}
+)
+
+// Global vars:
+var (
+	runner   *query.BenchmarkRunner
+	aggrPlan int
+	csi      *ClientSideIndex
+	session  *gocql.Session
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_cassandra and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("cassandra")
+
+	flag.StringVar(&daemonURL, "host", "localhost:9042", "Cassandra hostname and port combination.")
+	flag.StringVar(&aggrPlanLabel, "aggregation-plan", "", "Aggregation plan (choices: server, client)")
+	flag.DurationVar(&requestTimeout, "read-timeout", 1*time.Second, "Maximum request timeout.")
+	flag.DurationVar(&csiTimeout, "client-side-index-timeout", 10*time.Second, "Maximum client-side index timeout (only used at initialization).")
+
+	container = provision.AddFlags(flag.CommandLine, "cassandra")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_cassandra", args); err != nil {
+		return err
+	}
+
+	if _, ok := aggrPlanChoices[aggrPlanLabel]; !ok {
+		return cli.ConfigError(fmt.Errorf("invalid aggregation plan: '%s'", aggrPlanLabel))
+	}
+	aggrPlan = aggrPlanChoices[aggrPlanLabel]
+
+	return nil
+}
+
+// Run runs tsbs_run_queries_cassandra with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "cassandra", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		daemonURL = c.Endpoint()
+	}
+	// Make client-side index:
+	session = NewCassandraSession(daemonURL, runner.DatabaseName(), csiTimeout)
+	csi = NewClientSideIndex(FetchSeriesCollection(session))
+	session.Close()
+
+	// Make database connection pool:
+	session = NewCassandraSession(daemonURL, runner.DatabaseName(), requestTimeout)
+	defer session.Close()
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_cassandra")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.CassandraPool, newProcessor)))
+}
+
+type processor struct {
+	qe   *HLQueryExecutor
+	opts *HLQueryExecutorDoOptions
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(workerNumber int) {
+	p.opts = &HLQueryExecutorDoOptions{
+		AggregationPlan:      aggrPlan,
+		Debug:                runner.DebugLevel(),
+		PrettyPrintResponses: runner.DoPrintResponses(),
+	}
+	p.qe = NewHLQueryExecutor(session, csi, runner.DebugLevel())
+}
+
+func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	cq := q.(*query.Cassandra)
+	hlq := &HLQuery{*cq}
+	hlq.ForceUTC()
+	labels := [][]byte{
+		q.HumanLabelName(),
+		append(q.HumanLabelName(), "-qp"...),
+		append(q.HumanLabelName(), "-req"...),
+	}
+	if isWarm {
+		for i, l := range labels {
+			labels[i] = append(l, " (warm)"...)
+		}
+	}
+	qpLagMs, reqLagMs, err := p.qe.Do(hlq, *p.opts)
+	if err != nil {
+		return nil, err
+	}
+	// total stat
+	totalMs := qpLagMs + reqLagMs
+	stats := []*query.Stat{
+		query.GetPartialStat().Init(labels[1], qpLagMs),
+		query.GetPartialStat().Init(labels[2], reqLagMs),
+		query.GetStat().Init(labels[0], totalMs),
+	}
+	return stats, nil
+}
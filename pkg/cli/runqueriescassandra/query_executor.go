@@ -1,4 +1,4 @@
-package main
+package runqueriescassandra
 
 import (
 	"fmt"
@@ -44,7 +44,7 @@ type HLQueryExecutorDoOptions struct {
 // aggregates the results.
 func (qe *HLQueryExecutor) Do(q *HLQuery, opts HLQueryExecutorDoOptions) (qpLagMs, requestLagMs float64, err error) {
 	if opts.Debug >= 1 {
-		fmt.Printf("[hlqe] Do: %s\n", q)
+		fmt.Fprintf(os.Stderr, "[hlqe] Do: %s\n", q)
 	}
 
 	// build the query plan:
@@ -69,7 +69,7 @@ func (qe *HLQueryExecutor) Do(q *HLQuery, opts HLQueryExecutorDoOptions) (qpLagM
 	// print debug info if needed:
 	if opts.Debug >= 1 {
 		// FYI: query planning takes about 0.5ms for 1000 series.
-		fmt.Printf("[hlqe] query planning took %fms\n", qpLagMs)
+		fmt.Fprintf(os.Stderr, "[hlqe] query planning took %fms\n", qpLagMs)
 
 		qp.DebugQueries(opts.Debug)
 	}
@@ -1,7 +1,8 @@
-package main
+package runqueriescassandra
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 	"sort"
 	"strconv"
@@ -87,13 +88,13 @@ func (qp *QueryPlanWithServerAggregation) DebugQueries(level int) {
 		for _, qq := range qp.BucketedCQLQueries {
 			n += len(qq)
 		}
-		fmt.Printf("[qpsa] query with server aggregation plan has %d CQLQuery objects\n", n)
+		fmt.Fprintf(os.Stderr, "[qpsa] query with server aggregation plan has %d CQLQuery objects\n", n)
 	}
 
 	if level >= 2 {
 		for k, qq := range qp.BucketedCQLQueries {
 			for i, q := range qq {
-				fmt.Printf("[qpsa] CQL: %s, %d, %s\n", k, i, q)
+				fmt.Fprintf(os.Stderr, "[qpsa] CQL: %s, %d, %s\n", k, i, q)
 			}
 		}
 	}
@@ -149,12 +150,12 @@ func NewQueryPlanWithoutServerAggregation(aggrLabel string, groupByDuration time
 
 func csiDebugQueries(cqlQueries []CQLQuery, label string, level int) {
 	if level >= 1 {
-		fmt.Printf("[%s] query with client aggregation plan has %d CQLQuery objects\n", label, len(cqlQueries))
+		fmt.Fprintf(os.Stderr, "[%s] query with client aggregation plan has %d CQLQuery objects\n", label, len(cqlQueries))
 	}
 
 	if level >= 2 {
 		for i, q := range cqlQueries {
-			fmt.Printf("[%s] CQL: %d, %s\n", label, i, q)
+			fmt.Fprintf(os.Stderr, "[%s] CQL: %d, %s\n", label, i, q)
 		}
 	}
 }
@@ -0,0 +1,100 @@
+// Package runqueriesgreptime implements tsbs_run_queries_greptime (also run
+// as `tsbs run greptime`), which speed tests GreptimeDB using queries from
+// stdin.
+//
+// It reads encoded Query objects from stdin, and runs their SQL
+// concurrently with the SQL endpoint of the HTTP API of the server
+// (/v1/sql), which returns the results as JSON. Requests are authenticated
+// with -username and -password, if any.
+package runqueriesgreptime
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/greptime"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	serverURL string
+	username  string
+	password  string
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+	client *greptime.Client
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_greptime
+// and parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("greptime")
+
+	flag.StringVar(&serverURL, "url", "http://localhost:4000", "URL of the HTTP API of the GreptimeDB server")
+	flag.StringVar(&username, "username", "", "User to authenticate requests with (default: none)")
+	flag.StringVar(&password, "password", os.Getenv(greptime.PasswordEnv), "Password of the user (default: $"+greptime.PasswordEnv+")")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_greptime", args)
+}
+
+// Run runs tsbs_run_queries_greptime with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = greptime.NewClient(serverURL, username, password)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_greptime")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.HTTPPool, newProcessor)))
+}
+
+type processor struct {
+	printResponses bool
+	path           string
+	header         http.Header
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	p.printResponses = runner.DoPrintResponses()
+	p.path = "?db=" + url.QueryEscape(runner.DatabaseName())
+	p.header = http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	body := url.Values{"sql": {string(hq.Body)}}.Encode()
+	start := time.Now()
+	resp, err := client.Do(http.MethodPost, string(hq.Path)+p.path, p.header, []byte(body))
+	if _, ok := err.(*greptime.Error); err != nil && !ok {
+		cli.Fatal(cli.ExitUnreachable, "could not reach GreptimeDB", "url", serverURL, "error", err)
+	} else if err != nil {
+		return nil, err
+	}
+	lag := float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
+
+	if p.printResponses {
+		var pretty bytes.Buffer
+		prefix := fmt.Sprintf("ID %d: ", q.GetID())
+		if err := json.Indent(&pretty, resp, prefix, "  "); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", prefix, pretty.Bytes())
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, nil
+}
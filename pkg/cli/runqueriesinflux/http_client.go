@@ -1,4 +1,4 @@
-package main
+package runqueriesinflux
 
 import (
 	"bufio"
@@ -10,6 +10,8 @@ import (
 	"os"
 	"time"
 
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
 	"github.com/timescale/tsbs/query"
 )
 
@@ -66,7 +68,7 @@ func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions) (lag float64,
 	start := time.Now()
 	resp, err := w.client.Do(req)
 	if err != nil {
-		panic(err)
+		cli.Fatal(cli.ExitUnreachable, "could not reach InfluxDB", "error", err)
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
@@ -91,16 +93,11 @@ func (w *HTTPClient) Do(q *query.HTTP, opts *HTTPClientDoOptions) (lag float64,
 		// Print debug messages, if applicable:
 		switch opts.Debug {
 		case 1:
-			fmt.Fprintf(os.Stderr, "debug: %s in %7.2fms\n", q.HumanLabel, lag)
+			logging.Info("ran query", "label", string(q.HumanLabel), "ms", lag)
 		case 2:
-			fmt.Fprintf(os.Stderr, "debug: %s in %7.2fms -- %s\n", q.HumanLabel, lag, q.HumanDescription)
-		case 3:
-			fmt.Fprintf(os.Stderr, "debug: %s in %7.2fms -- %s\n", q.HumanLabel, lag, q.HumanDescription)
-			fmt.Fprintf(os.Stderr, "debug:   request: %s\n", string(q.String()))
-		case 4:
-			fmt.Fprintf(os.Stderr, "debug: %s in %7.2fms -- %s\n", q.HumanLabel, lag, q.HumanDescription)
-			fmt.Fprintf(os.Stderr, "debug:   request: %s\n", string(q.String()))
-			//fmt.Fprintf(os.Stderr, "debug:   response: %s\n", string(resp.Body()))
+			logging.Info("ran query", "label", string(q.HumanLabel), "ms", lag, "description", string(q.HumanDescription))
+		case 3, 4:
+			logging.Info("ran query", "label", string(q.HumanLabel), "ms", lag, "description", string(q.HumanDescription), "request", q.String())
 		default:
 		}
 
@@ -0,0 +1,99 @@
+// Package runqueriesinflux implements tsbs_run_queries_influx (also run as
+// `tsbs run influx`), which speed tests InfluxDB using requests from stdin.
+//
+// It reads encoded Query objects from stdin, and makes concurrent requests
+// to the provided HTTP endpoint. This program has no knowledge of the
+// internals of the endpoint.
+package runqueriesinflux
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/provision"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	daemonUrls []string
+	chunkSize  uint64
+	container  *provision.Options
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_influx and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("influx")
+	var csvDaemonUrls string
+
+	flag.StringVar(&csvDaemonUrls, "urls", "http://localhost:8086", "Daemon URLs, comma-separated. Will be used in a round-robin fashion.")
+	flag.Uint64Var(&chunkSize, "chunk-response-size", 0, "Number of series to chunk results into. 0 means no chunking.")
+
+	container = provision.AddFlags(flag.CommandLine, "influx")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_influx", args); err != nil {
+		return err
+	}
+
+	daemonUrls = strings.Split(csvDaemonUrls, ",")
+	if len(daemonUrls) == 0 {
+		return cli.ConfigError(fmt.Errorf("missing 'urls' flag"))
+	}
+	return nil
+}
+
+// Run runs tsbs_run_queries_influx with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "influx", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		daemonUrls = []string{"http://" + c.Endpoint()}
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_influx")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.HTTPPool, newProcessor)))
+}
+
+type processor struct {
+	w    *HTTPClient
+	opts *HTTPClientDoOptions
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(workerNumber int) {
+	p.opts = &HTTPClientDoOptions{
+		Debug:                runner.DebugLevel(),
+		PrettyPrintResponses: runner.DoPrintResponses(),
+		chunkSize:            chunkSize,
+		database:             runner.DatabaseName(),
+	}
+	url := daemonUrls[workerNumber%len(daemonUrls)]
+	p.w = NewHTTPClient(url)
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	lag, err := p.w.Do(hq, p.opts)
+	if err != nil {
+		return nil, err
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, nil
+}
@@ -0,0 +1,98 @@
+// Package runqueriesinflux3 implements tsbs_run_queries_influx3 (also run as
+// `tsbs run influx3`), which speed tests InfluxDB 3 using queries from
+// stdin.
+//
+// It reads encoded Query objects from stdin, and runs their SQL
+// concurrently with the SQL query endpoint of the v3 API of the server
+// (/api/v3/query_sql), which returns the results as JSON. Requests are
+// authenticated with the token in -token.
+package runqueriesinflux3
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/influx3"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	serverURL string
+	token     string
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+	client *influx3.Client
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_influx3
+// and parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("influx3")
+
+	flag.StringVar(&serverURL, "url", "http://localhost:8181", "URL of the InfluxDB 3 server")
+	flag.StringVar(&token, "token", os.Getenv(influx3.TokenEnv), "Token to authenticate requests with (default: $"+influx3.TokenEnv+")")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_influx3", args)
+}
+
+// Run runs tsbs_run_queries_influx3 with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = influx3.NewClient(serverURL, token)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_influx3")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.HTTPPool, newProcessor)))
+}
+
+type processor struct {
+	printResponses bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	p.printResponses = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	in := map[string]string{
+		"db":     runner.DatabaseName(),
+		"q":      string(hq.Body),
+		"format": "json",
+	}
+	var resp json.RawMessage
+	start := time.Now()
+	err := client.JSON(http.MethodPost, string(hq.Path), in, &resp)
+	if _, ok := err.(*influx3.Error); err != nil && !ok {
+		cli.Fatal(cli.ExitUnreachable, "could not reach InfluxDB 3", "url", serverURL, "error", err)
+	} else if err != nil {
+		return nil, err
+	}
+	lag := float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
+
+	if p.printResponses {
+		var pretty bytes.Buffer
+		prefix := fmt.Sprintf("ID %d: ", q.GetID())
+		if err := json.Indent(&pretty, resp, prefix, "  "); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", prefix, pretty.Bytes())
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, nil
+}
@@ -0,0 +1,88 @@
+// Package runqueriesm3db implements tsbs_run_queries_m3db (also run as
+// `tsbs run m3db`), which speed tests M3DB using queries from stdin.
+//
+// It reads encoded Query objects from stdin, and runs their PromQL
+// concurrently with the Prometheus query API of the M3 coordinator
+// (/api/v1/query_range and /api/v1/query), which returns the results as
+// JSON.
+package runqueriesm3db
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/m3"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	serverURL string
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+	client *m3.Client
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_m3db and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("m3db")
+
+	flag.StringVar(&serverURL, "url", "http://localhost:7201", "URL of the M3 coordinator")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_m3db", args)
+}
+
+// Run runs tsbs_run_queries_m3db with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = m3.NewClient(serverURL)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_m3db")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.HTTPPool, newProcessor)))
+}
+
+type processor struct {
+	printResponses bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	p.printResponses = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	start := time.Now()
+	resp, err := client.Do(string(hq.Method), string(hq.Path), nil, nil)
+	if _, ok := err.(*m3.Error); err != nil && !ok {
+		cli.Fatal(cli.ExitUnreachable, "could not reach the M3 coordinator", "url", serverURL, "error", err)
+	} else if err != nil {
+		return nil, err
+	}
+	lag := float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
+
+	if p.printResponses {
+		var pretty bytes.Buffer
+		prefix := fmt.Sprintf("ID %d: ", q.GetID())
+		if err := json.Indent(&pretty, resp, prefix, "  "); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", prefix, pretty.Bytes())
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, nil
+}
@@ -0,0 +1,115 @@
+// Package runqueriesmongo implements tsbs_run_queries_mongo (also run as
+// `tsbs run mongo`), which speed tests Mongo using requests from stdin.
+//
+// It reads encoded Query objects from stdin, and makes concurrent requests
+// to the provided Mongo endpoint using mgo.
+package runqueriesmongo
+
+import (
+	"context"
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/provision"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	daemonURL string
+	timeout   time.Duration
+	container *provision.Options
+)
+
+// Global vars:
+var (
+	runner  *query.BenchmarkRunner
+	session *mgo.Session
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_mongo and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	// needed for deserializing the mongo query from gob
+	gob.Register([]interface{}{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]map[string]interface{}{})
+	gob.Register(bson.M{})
+	gob.Register([]bson.M{})
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("mongo", "mongo-naive")
+
+	flag.StringVar(&daemonURL, "url", "mongodb://localhost:27017", "Daemon URL.")
+	flag.DurationVar(&timeout, "read-timeout", 30*time.Second, "Timeout value for individual queries")
+
+	container = provision.AddFlags(flag.CommandLine, "mongo")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_mongo", args)
+}
+
+// Run runs tsbs_run_queries_mongo with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "mongo", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		daemonURL = "mongodb://" + c.Endpoint()
+	}
+	var err error
+	session, err = mgo.DialWithTimeout(daemonURL, timeout)
+	if err != nil {
+		return cli.UnreachableError(err)
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_mongo")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.MongoPool, newProcessor)))
+}
+
+type processor struct {
+	collection *mgo.Collection
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(workerNumber int) {
+	sess := session.Copy()
+	db := sess.DB(runner.DatabaseName())
+	p.collection = db.C("point_data")
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	mq := q.(*query.Mongo)
+	start := time.Now().UnixNano()
+	pipe := p.collection.Pipe(mq.BsonDoc).AllowDiskUse()
+	iter := pipe.Iter()
+	if runner.DebugLevel() > 0 {
+		fmt.Println(mq.BsonDoc)
+	}
+	var result map[string]interface{}
+	cnt := 0
+	for iter.Next(&result) {
+		if runner.DoPrintResponses() {
+			fmt.Printf("ID %d: %v\n", q.GetID(), result)
+		}
+		cnt++
+	}
+	if runner.DebugLevel() > 0 {
+		fmt.Println(cnt)
+	}
+	err := iter.Close()
+
+	took := time.Now().UnixNano() - start
+	lag := float64(took) / 1e6 // milliseconds
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, err
+}
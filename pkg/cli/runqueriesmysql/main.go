@@ -0,0 +1,193 @@
+// Package runqueriesmysql implements tsbs_run_queries_mysql (also run as
+// `tsbs run mysql`), which speed tests MySQL or MariaDB using requests from
+// stdin.
+//
+// It reads encoded Query objects from stdin, and makes concurrent requests
+// to the provided MySQL endpoint, as loaded by tsbs_load_mysql.
+package runqueriesmysql
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/provision"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	host        string
+	port        int
+	user        string
+	password    string
+	showExplain bool
+	container   *provision.Options
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_mysql and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("mysql")
+
+	flag.StringVar(&host, "host", "localhost", "Hostname of the MySQL server")
+	flag.IntVar(&port, "port", 3306, "Port of the MySQL server")
+	flag.StringVar(&user, "user", "root", "User to connect to MySQL as")
+	flag.StringVar(&password, "password", "", "Password of the user")
+
+	flag.BoolVar(&showExplain, "show-explain", false, "Print out the EXPLAIN ANALYZE output for sample query")
+
+	container = provision.AddFlags(flag.CommandLine, "mysql")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_mysql", args); err != nil {
+		return err
+	}
+	if port <= 0 || port > 65535 {
+		return cli.ConfigError(fmt.Errorf("invalid port %d", port))
+	}
+
+	if showExplain {
+		runner.ResetLimit(1)
+	}
+	return nil
+}
+
+// Run runs tsbs_run_queries_mysql with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "mysql", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		host, port = c.Host, c.Port
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_mysql")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.TimescaleDBPool, newProcessor)))
+}
+
+func getDSN() string {
+	cfg := mysqldriver.NewConfig()
+	cfg.User = user
+	cfg.Passwd = password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, strconv.Itoa(port))
+	cfg.DBName = runner.DatabaseName()
+	return cfg.FormatDSN()
+}
+
+// prettyPrintResponse prints a Query and its response in JSON format with two
+// keys: 'query' which has a value of the SQL used to generate the second key
+// 'results' which is an array of each row in the return set.
+func prettyPrintResponse(rows *sqlx.Rows, q *query.TimescaleDB) error {
+	resp := make(map[string]interface{})
+	resp["query"] = string(q.SqlQuery)
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		r := make(map[string]interface{})
+		if err := rows.MapScan(r); err != nil {
+			return err
+		}
+		// the driver returns the values of most types as their text
+		for k, v := range r {
+			if b, ok := v.([]byte); ok {
+				r[k] = string(b)
+			}
+		}
+		results = append(results, r)
+		resp["results"] = results
+	}
+
+	line, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(line) + "\n")
+	return nil
+}
+
+type processor struct {
+	db            *sqlx.DB
+	debug         bool
+	printResponse bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	db, err := sqlx.Connect("mysql", getDSN())
+	if err != nil {
+		cli.Fatal(cli.ExitUnreachable, "could not connect to MySQL", "error", err)
+	}
+	p.db = db
+	p.debug = runner.DebugLevel() > 0
+	p.printResponse = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	// No need to run again for EXPLAIN
+	if isWarm && showExplain {
+		return nil, nil
+	}
+	tq := q.(*query.TimescaleDB)
+
+	start := time.Now()
+	qry := string(tq.SqlQuery)
+	if showExplain {
+		qry = "EXPLAIN ANALYZE " + qry
+	}
+	rows, err := p.db.Queryx(qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if p.debug {
+		fmt.Println(qry)
+	}
+	if showExplain {
+		text := ""
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				return nil, err
+			}
+			text += s + "\n"
+		}
+		fmt.Printf("%s\n\n%s\n-----\n\n", qry, text)
+	} else if p.printResponse {
+		if err := prettyPrintResponse(rows, tq); err != nil {
+			return nil, err
+		}
+	} else {
+		// read the whole response, so its time is included
+		for rows.Next() {
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	took := float64(time.Since(start).Nanoseconds()) / 1e6
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), took)
+
+	return []*query.Stat{stat}, nil
+}
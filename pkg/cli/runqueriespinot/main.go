@@ -0,0 +1,99 @@
+// Package runqueriespinot implements tsbs_run_queries_pinot (also run as
+// `tsbs run pinot`), which speed tests Apache Pinot using queries from stdin.
+//
+// It reads encoded Query objects from stdin, and runs their SQL
+// concurrently with the SQL query endpoint of a broker (/query/sql), which
+// returns the results as JSON. Requests are authenticated with the token in
+// -token, if any.
+package runqueriespinot
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/pinot"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	brokerURL string
+	token     string
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+	client *pinot.Client
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_pinot and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("pinot")
+
+	flag.StringVar(&brokerURL, "url", "http://localhost:8099", "URL of the Pinot broker")
+	flag.StringVar(&token, "token", os.Getenv(pinot.TokenEnv), "Authorization header to authenticate requests with, e.g., 'Basic <base64 user:password>' (default: $"+pinot.TokenEnv+")")
+
+	return cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_pinot", args)
+}
+
+// Run runs tsbs_run_queries_pinot with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	client = pinot.NewClient(brokerURL, token)
+
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_pinot")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.HTTPPool, newProcessor)))
+}
+
+type processor struct {
+	printResponses bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	p.printResponses = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, _ bool) ([]*query.Stat, error) {
+	hq := q.(*query.HTTP)
+	body, err := json.Marshal(map[string]string{"sql": string(hq.Body)})
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": {"application/json"}}
+	start := time.Now()
+	resp, err := client.Do(http.MethodPost, string(hq.Path), header, body)
+	if _, ok := err.(*pinot.Error); err != nil && !ok {
+		cli.Fatal(cli.ExitUnreachable, "could not reach the Pinot broker", "url", brokerURL, "error", err)
+	} else if err != nil {
+		return nil, err
+	}
+	lag := float64(time.Since(start).Nanoseconds()) / 1e6 // milliseconds
+	if err := pinot.QueryError(resp); err != nil {
+		return nil, err
+	}
+
+	if p.printResponses {
+		var pretty bytes.Buffer
+		prefix := fmt.Sprintf("ID %d: ", q.GetID())
+		if err := json.Indent(&pretty, resp, prefix, "  "); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s%s\n", prefix, pretty.Bytes())
+	}
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), lag)
+	return []*query.Stat{stat}, nil
+}
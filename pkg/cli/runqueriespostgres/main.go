@@ -0,0 +1,180 @@
+// Package runqueriespostgres implements tsbs_run_queries_postgres (also run
+// as `tsbs run postgres`), which speed tests plain PostgreSQL using requests
+// from stdin.
+//
+// It reads encoded Query objects from stdin, and makes concurrent requests
+// to the provided PostgreSQL endpoint, as loaded by tsbs_load_postgres.
+package runqueriespostgres
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/provision"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	postgresConnect string
+	host            string
+	user            string
+	showExplain     bool
+	container       *provision.Options
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_postgres and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("postgres")
+
+	flag.StringVar(&postgresConnect, "postgres", "sslmode=disable",
+		"String of additional PostgreSQL connection parameters, e.g., 'sslmode=disable'. Parameters for host and database will be ignored.")
+	flag.StringVar(&host, "host", "localhost", "Hostname of the PostgreSQL instance")
+	flag.StringVar(&user, "user", "postgres", "User to connect to PostgreSQL as")
+
+	flag.BoolVar(&showExplain, "show-explain", false, "Print out the EXPLAIN output for sample query")
+
+	container = provision.AddFlags(flag.CommandLine, "postgres")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_postgres", args); err != nil {
+		return err
+	}
+
+	if showExplain {
+		runner.ResetLimit(1)
+	}
+	return nil
+}
+
+// Run runs tsbs_run_queries_postgres with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	if container.Enabled {
+		c, err := provision.Start(context.Background(), "postgres", container)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		host = c.Host
+		postgresConnect += fmt.Sprintf(" port=%d", c.Port)
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_postgres")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.TimescaleDBPool, newProcessor)))
+}
+
+func getConnectString() string {
+	// User might be passing in host=hostname the connect string out of habit which may override the
+	// host configuration. Same for dbname= and user=. This sanitizes that.
+	re := regexp.MustCompile(`(host|dbname|user)=\S*\b`)
+	connectString := strings.TrimSpace(re.ReplaceAllString(postgresConnect, ""))
+
+	return fmt.Sprintf("host=%s dbname=%s user=%s %s", host, runner.DatabaseName(), user, connectString)
+}
+
+// prettyPrintResponse prints a Query and its response in JSON format with two
+// keys: 'query' which has a value of the SQL used to generate the second key
+// 'results' which is an array of each row in the return set.
+func prettyPrintResponse(rows *sqlx.Rows, q *query.TimescaleDB) {
+	resp := make(map[string]interface{})
+	resp["query"] = string(q.SqlQuery)
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		r := make(map[string]interface{})
+		if err := rows.MapScan(r); err != nil {
+			panic(err)
+		}
+		results = append(results, r)
+		resp["results"] = results
+	}
+
+	line, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(string(line) + "\n")
+}
+
+type processor struct {
+	db            *sqlx.DB
+	debug         bool
+	printResponse bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	db, err := sqlx.Connect("postgres", getConnectString())
+	if err != nil {
+		cli.Fatal(cli.ExitUnreachable, "could not connect to PostgreSQL", "error", err)
+	}
+	p.db = db
+	p.debug = runner.DebugLevel() > 0
+	p.printResponse = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	// No need to run again for EXPLAIN
+	if isWarm && showExplain {
+		return nil, nil
+	}
+	tq := q.(*query.TimescaleDB)
+
+	start := time.Now()
+	qry := string(tq.SqlQuery)
+	if showExplain {
+		qry = "EXPLAIN ANALYZE " + qry
+	}
+	rows, err := p.db.Queryx(qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if p.debug {
+		fmt.Println(qry)
+	}
+	if showExplain {
+		text := ""
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				return nil, err
+			}
+			text += s + "\n"
+		}
+		fmt.Printf("%s\n\n%s\n-----\n\n", qry, text)
+	} else if p.printResponse {
+		prettyPrintResponse(rows, tq)
+	} else {
+		// read the whole response, so its time is included
+		for rows.Next() {
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	took := float64(time.Since(start).Nanoseconds()) / 1e6
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), took)
+
+	return []*query.Stat{stat}, nil
+}
@@ -0,0 +1,167 @@
+// Package runqueriessqlite implements tsbs_run_queries_sqlite (also run as
+// `tsbs run sqlite`), which speed tests SQLite using requests from stdin.
+//
+// It reads encoded Query objects from stdin, and makes concurrent requests
+// to the database file loaded by tsbs_load_sqlite, which each worker opens
+// read-only.
+package runqueriessqlite
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/query"
+)
+
+// Program option vars:
+var (
+	dbDir       string
+	cacheSize   int
+	showExplain bool
+)
+
+// Global vars:
+var (
+	runner *query.BenchmarkRunner
+)
+
+// parseFlags registers the command line flags of tsbs_run_queries_sqlite and
+// parses them from args, the environment and any config file
+func parseFlags(args []string) error {
+	runner = query.NewBenchmarkRunner()
+	runner.ExpectTargets("sqlite")
+
+	flag.StringVar(&dbDir, "db-dir", ".", "Directory of the database file, which is named after -db-name with the extension .db")
+	flag.IntVar(&cacheSize, "cache-size", -2000, "Size of the page cache of each worker, in pages if positive or KiB if negative")
+
+	flag.BoolVar(&showExplain, "show-explain", false, "Print out the EXPLAIN QUERY PLAN output for sample query")
+
+	if err := cli.ParseFlags(flag.CommandLine, "tsbs_run_queries_sqlite", args); err != nil {
+		return err
+	}
+
+	if showExplain {
+		runner.ResetLimit(1)
+	}
+	return nil
+}
+
+// Run runs tsbs_run_queries_sqlite with args as its command line flags
+func Run(args []string) error {
+	if err := parseFlags(args); err != nil {
+		return err
+	}
+	md := cli.StartMetadata(flag.CommandLine, "tsbs_run_queries_sqlite")
+	return md.Finish(runner.SaveResults(md, runner.Run(&query.TimescaleDBPool, newProcessor)))
+}
+
+// getDSN returns the data source name to open the database read-only with
+func getDSN() string {
+	params := url.Values{}
+	params.Set("mode", "ro")
+	params.Set("_cache_size", strconv.Itoa(cacheSize))
+	return "file:" + filepath.Join(dbDir, runner.DatabaseName()+".db") + "?" + params.Encode()
+}
+
+// prettyPrintResponse prints a Query and its response in JSON format with two
+// keys: 'query' which has a value of the SQL used to generate the second key
+// 'results' which is an array of each row in the return set.
+func prettyPrintResponse(rows *sqlx.Rows, q *query.TimescaleDB) error {
+	resp := make(map[string]interface{})
+	resp["query"] = string(q.SqlQuery)
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		r := make(map[string]interface{})
+		if err := rows.MapScan(r); err != nil {
+			return err
+		}
+		results = append(results, r)
+		resp["results"] = results
+	}
+
+	line, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(line) + "\n")
+	return nil
+}
+
+type processor struct {
+	db            *sqlx.DB
+	debug         bool
+	printResponse bool
+}
+
+func newProcessor() query.Processor { return &processor{} }
+
+func (p *processor) Init(_ int) {
+	db, err := sqlx.Connect("sqlite3", getDSN())
+	if err != nil {
+		cli.Fatal(cli.ExitUnreachable, "could not open SQLite database", "error", err)
+	}
+	p.db = db
+	p.debug = runner.DebugLevel() > 0
+	p.printResponse = runner.DoPrintResponses()
+}
+
+func (p *processor) ProcessQuery(q query.Query, isWarm bool) ([]*query.Stat, error) {
+	// No need to run again for EXPLAIN
+	if isWarm && showExplain {
+		return nil, nil
+	}
+	tq := q.(*query.TimescaleDB)
+
+	start := time.Now()
+	qry := string(tq.SqlQuery)
+	if showExplain {
+		qry = "EXPLAIN QUERY PLAN " + qry
+	}
+	rows, err := p.db.Queryx(qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if p.debug {
+		fmt.Println(qry)
+	}
+	if showExplain {
+		text := ""
+		for rows.Next() {
+			var id, parent, notUsed int
+			var detail string
+			if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+				return nil, err
+			}
+			text += detail + "\n"
+		}
+		fmt.Printf("%s\n\n%s\n-----\n\n", qry, text)
+	} else if p.printResponse {
+		if err := prettyPrintResponse(rows, tq); err != nil {
+			return nil, err
+		}
+	} else {
+		// read the whole response, so its time is included
+		for rows.Next() {
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	took := float64(time.Since(start).Nanoseconds()) / 1e6
+	stat := query.GetStat()
+	stat.Init(q.HumanLabelName(), took)
+
+	return []*query.Stat{stat}, nil
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// byteUnits are the units of sizes accepted by ParseByteSize, largest first
+// within each of the decimal and binary families so String picks the largest
+// exact one
+var byteUnits = []struct {
+	name string
+	size int64
+}{
+	{"TiB", 1 << 40},
+	{"TB", 1e12},
+	{"GiB", 1 << 30},
+	{"GB", 1e9},
+	{"MiB", 1 << 20},
+	{"MB", 1e6},
+	{"KiB", 1 << 10},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// ByteSize is a number of bytes that can be given to a flag with a unit,
+// e.g., -max-output-size=50GB. It implements flag.Value.
+type ByteSize int64
+
+// ParseByteSize parses a number of bytes followed by an optional unit: B,
+// KB, MB, GB or TB for powers of 1000, or KiB, MiB, GiB or TiB for powers of
+// 1024. Units are not case-sensitive, and K, M, G and T are short for KB, MB,
+// GB and TB. The number may have a fraction, e.g., 1.5GB.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.TrimSpace(s[i:])
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	multiplier := int64(1)
+	if len(unit) > 0 {
+		name := strings.ToUpper(unit)
+		if len(name) == 1 && name != "B" {
+			name += "B"
+		}
+		found := false
+		for _, u := range byteUnits {
+			if strings.ToUpper(u.name) == name {
+				multiplier, found = u.size, true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid size '%s': unknown unit '%s'", s, unit)
+		}
+	}
+	size := n * float64(multiplier)
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size '%s': too large", s)
+	}
+	return ByteSize(size), nil
+}
+
+// String returns the size in the largest unit it is a whole number of
+func (b *ByteSize) String() string {
+	if b == nil || *b == 0 {
+		return "0"
+	}
+	for _, u := range byteUnits {
+		if int64(*b)%u.size == 0 {
+			return strconv.FormatInt(int64(*b)/u.size, 10) + u.name
+		}
+	}
+	return strconv.FormatInt(int64(*b), 10) + "B"
+}
+
+// Set sets the size from a string as parsed by ParseByteSize
+func (b *ByteSize) Set(s string) error {
+	size, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
+
+// ParseDuration parses a duration like time.ParseDuration, but also accepts
+// days (d) and weeks (w) before any other units, e.g., 7d or 1d12h
+func ParseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+	rest := s
+	for _, u := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"w", 7 * 24 * time.Hour}, {"d", 24 * time.Hour}} {
+		i := strings.Index(rest, u.suffix)
+		if i < 0 {
+			continue
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		d += time.Duration(n * float64(u.size))
+		rest = rest[i+1:]
+	}
+	if len(rest) == 0 {
+		if rest == s {
+			return 0, fmt.Errorf("invalid duration '%s'", s)
+		}
+		return d, nil
+	}
+	rd, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return d + rd, nil
+}
+
+// ParseTime parses a timestamp that is either absolute, in RFC3339, or
+// relative to now: "now", or "now" followed by + or - and a duration as
+// parsed by ParseDuration, e.g., now-24h or now-7d. The result is in UTC.
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if strings.HasPrefix(s, "now") {
+		rel := s[len("now"):]
+		if len(rel) == 0 {
+			return now.UTC(), nil
+		}
+		if rel[0] != '+' && rel[0] != '-' {
+			return time.Time{}, fmt.Errorf("invalid relative time '%s': expected now+<duration> or now-<duration>", s)
+		}
+		d, err := ParseDuration(rel[1:])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid relative time '%s': %v", s, err)
+		}
+		if rel[0] == '-' {
+			d = -d
+		}
+		return now.Add(d).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s': expected RFC3339 (e.g., 2016-01-01T00:00:00Z) or now-<duration>", s)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		desc       string
+		in         string
+		want       ByteSize
+		wantString string
+		shouldErr  bool
+	}{
+		{desc: "bytes", in: "1234", want: 1234, wantString: "1234B"},
+		{desc: "zero", in: "0", want: 0, wantString: "0"},
+		{desc: "decimal unit", in: "50GB", want: 50e9, wantString: "50GB"},
+		{desc: "binary unit", in: "4MiB", want: 4 << 20, wantString: "4MiB"},
+		{desc: "short unit", in: "2k", want: 2000, wantString: "2KB"},
+		{desc: "lower case", in: "1kib", want: 1024, wantString: "1KiB"},
+		{desc: "fraction", in: "1.5GB", want: 1.5e9, wantString: "1500MB"},
+		{desc: "space", in: "10 TB", want: 10e12, wantString: "10TB"},
+		{desc: "unknown unit", in: "50XB", shouldErr: true},
+		{desc: "negative", in: "-1GB", shouldErr: true},
+		{desc: "empty", in: "", shouldErr: true},
+		{desc: "too large", in: "100000000TB", shouldErr: true},
+	}
+	for _, c := range cases {
+		got, err := ParseByteSize(c.in)
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("%s: unexpected lack of error for '%s'", c.desc, c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: incorrect size: got %d want %d", c.desc, got, c.want)
+		}
+		if s := got.String(); s != c.wantString {
+			t.Errorf("%s: incorrect string: got %s want %s", c.desc, s, c.wantString)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		in        string
+		want      time.Duration
+		shouldErr bool
+	}{
+		{in: "500ms", want: 500 * time.Millisecond},
+		{in: "24h", want: 24 * time.Hour},
+		{in: "7d", want: 7 * 24 * time.Hour},
+		{in: "1d12h", want: 36 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "1w1d", want: 8 * 24 * time.Hour},
+		{in: "0.5d", want: 12 * time.Hour},
+		{in: "", shouldErr: true},
+		{in: "d", shouldErr: true},
+		{in: "12h1d", shouldErr: true},
+		{in: "bogus", shouldErr: true},
+	}
+	for _, c := range cases {
+		got, err := ParseDuration(c.in)
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("unexpected lack of error for '%s'", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %v", c.in, err)
+		} else if got != c.want {
+			t.Errorf("incorrect duration for '%s': got %v want %v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2020, 6, 15, 12, 30, 0, 0, time.FixedZone("X", 3600))
+	cases := []struct {
+		in        string
+		want      time.Time
+		shouldErr bool
+	}{
+		{in: "2016-01-01T00:00:00Z", want: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "2016-01-01T01:00:00+01:00", want: time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "2016-01-02", shouldErr: true},
+		{in: "now", want: time.Date(2020, 6, 15, 11, 30, 0, 0, time.UTC)},
+		{in: "now-24h", want: time.Date(2020, 6, 14, 11, 30, 0, 0, time.UTC)},
+		{in: "now+90m", want: time.Date(2020, 6, 15, 13, 0, 0, 0, time.UTC)},
+		{in: "now-7d", want: time.Date(2020, 6, 8, 11, 30, 0, 0, time.UTC)},
+		{in: "now24h", shouldErr: true},
+		{in: "now-", shouldErr: true},
+		{in: "yesterday", shouldErr: true},
+	}
+	for _, c := range cases {
+		got, err := ParseTime(c.in, now)
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("unexpected lack of error for '%s'", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for '%s': %v", c.in, err)
+		} else if !got.Equal(c.want) || got.Location() != time.UTC {
+			t.Errorf("incorrect time for '%s': got %v want %v", c.in, got, c.want)
+		}
+	}
+}
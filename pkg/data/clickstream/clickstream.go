@@ -0,0 +1,136 @@
+// Package clickstream simulates the clickstream use case: page views of the
+// users of websites, and the sessions they group into, with the users and
+// pages of the views drawn from Zipf distributions and traffic following a
+// strong daily cycle.
+package clickstream
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelPageView = []byte("page_view")
+	labelSession  = []byte("session")
+
+	// TagKeys are the tags of every point, identifying its site, user and
+	// session; page views have the tag of their page too
+	TagKeys = [][]byte{
+		[]byte("site"),
+		[]byte("user"),
+		[]byte("session"),
+		[]byte("device"),
+		[]byte("country"),
+	}
+	tagKeyPage = []byte("page")
+
+	pageViewFields = [][]byte{
+		[]byte("load_time_ms"),
+		[]byte("bytes"),
+		[]byte("status_code"),
+		[]byte("scroll_depth"),
+	}
+	pageViewTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeFloat,
+	}
+	sessionFields = [][]byte{
+		[]byte("duration_seconds"),
+		[]byte("page_views"),
+		[]byte("bounced"),
+		[]byte("converted"),
+	}
+	sessionTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeBool,
+		serialize.FieldTypeBool,
+	}
+
+	schema = serialize.NewTaggedSchema(TagKeys, map[string][][]byte{
+		string(labelPageView): {tagKeyPage},
+	}, map[string][][]byte{
+		string(labelPageView): pageViewFields,
+		string(labelSession):  sessionFields,
+	}, map[string][]serialize.FieldType{
+		string(labelPageView): pageViewTypes,
+		string(labelSession):  sessionTypes,
+	})
+)
+
+// Traffic relative to the peak through the day, in local time: lowest at
+// 04:00 and highest at 16:00, with weekends at weekendTraffic of weekdays
+const (
+	nightTraffic   = 0.05
+	weekendTraffic = 0.7
+	trafficLowHour = 4
+)
+
+// Traffic returns the traffic at local time t relative to the peak of the
+// day, between nightTraffic and 1
+func Traffic(t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	// a raised cosine, squared so most traffic is in the middle of the day
+	day := (1 - math.Cos(2*math.Pi*(hour-trafficLowHour)/24)) / 2
+	traffic := nightTraffic + (1-nightTraffic)*day*day
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		traffic *= weekendTraffic
+	}
+	return traffic
+}
+
+// SimulatorConfig is used to create a Simulator of the clickstream use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitSiteCount is the number of sites to start with in the first
+	// reporting period
+	InitSiteCount uint64
+	// SiteCount is the total number of sites to have in the last reporting
+	// period
+	SiteCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each site. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the page_view and
+// session measurements
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a reading of the page views
+// of each site, and the sessions that ended, every interval. It advances
+// RNG, so each call simulates different sites.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	maxEvents := maxEventsPerReading(interval)
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitSiteCount,
+		Count:            c.SiteCount,
+		PointsPerReading: 2 * maxEvents,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewSite(r, i, start, interval, maxEvents)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
+
+// maxEventsPerReading returns the most page views, and sessions ended, of a
+// site in a reading every interval, well above those of the busiest site at
+// its peak
+func maxEventsPerReading(interval time.Duration) int {
+	return int(math.Ceil(1.5*maxPeakRate*interval.Seconds())) + 10
+}
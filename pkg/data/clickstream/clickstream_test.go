@@ -0,0 +1,68 @@
+package clickstream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestTraffic(t *testing.T) {
+	// Monday
+	day := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	if got := Traffic(day.Add(trafficLowHour * time.Hour)); got != nightTraffic {
+		t.Errorf("incorrect traffic at the low: got %v want %v", got, nightTraffic)
+	}
+	if got := Traffic(day.Add(16 * time.Hour)); got != 1 {
+		t.Errorf("incorrect traffic at the peak: got %v want 1", got)
+	}
+	if Traffic(day.Add(10*time.Hour)) <= Traffic(day.Add(7*time.Hour)) {
+		t.Errorf("traffic does not grow through the morning")
+	}
+	// Saturday
+	if got := Traffic(day.AddDate(0, 0, 5).Add(16 * time.Hour)); got != weekendTraffic {
+		t.Errorf("incorrect traffic at the weekend peak: got %v want %v", got, weekendTraffic)
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:         start,
+		End:           start.Add(24 * time.Hour),
+		InitSiteCount: 2,
+		SiteCount:     2,
+		RNG:           rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(time.Minute)
+	counts := make(map[string]int)
+	users := make(map[string]int)
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		m := string(p.MeasurementName())
+		counts[m]++
+		if len(p.FieldKeys()) != len(c.Fields().FieldKeys(m)) {
+			t.Errorf("incorrect fields of %s: got %s", m, p.FieldKeys())
+		}
+		if m == "page_view" {
+			users[string(p.TagValues()[1])]++
+		}
+	}
+	if counts["page_view"] == 0 || counts["session"] == 0 || counts["session"] >= counts["page_view"] {
+		t.Errorf("incorrect numbers of events: %v", counts)
+	}
+	// a few users make many of the page views
+	if users["user_0"] < 10*users["user_100"] {
+		t.Errorf("page views of users not skewed: user_0 %d, user_100 %d", users["user_0"], users["user_100"])
+	}
+}
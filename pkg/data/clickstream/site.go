@@ -0,0 +1,353 @@
+package clickstream
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Populations of each site, and the exponents of the Zipf distributions the
+// users and pages of its page views are drawn from
+const (
+	usersPerSite = 100000
+	pagesPerSite = 1000
+	userExponent = 1.05
+	pageExponent = 1.2
+)
+
+// Page views per second of a site at its peak, drawn uniformly from
+// [minPeakRate, maxPeakRate)
+const (
+	minPeakRate = 1.0
+	maxPeakRate = 10.0
+)
+
+// Sessions have meanPageViews page views on average, geometrically
+// distributed, with thinkTime between them on average, exponentially
+// distributed, and end after sessionTimeout without page views
+const (
+	meanPageViews  = 4
+	thinkTime      = 45 * time.Second
+	sessionTimeout = 30 * time.Minute
+)
+
+var (
+	// users and pages are the same for every site
+	users = common.NewZipf(userExponent, 1, usersPerSite-1)
+	pages = common.NewZipf(pageExponent, 1, pagesPerSite-1)
+
+	// the first pages are the site's most popular ones; the rest are pages
+	// of products
+	namedPages = [][]byte{
+		[]byte("/"),
+		[]byte("/search"),
+		[]byte("/cart"),
+		[]byte("/checkout"),
+		[]byte("/account"),
+	}
+	checkoutPage = 3
+
+	// utcOffsets are the time zones of the sites' users
+	utcOffsets = []time.Duration{
+		-8 * time.Hour,
+		-5 * time.Hour,
+		0,
+		time.Hour,
+		5*time.Hour + 30*time.Minute,
+		8 * time.Hour,
+		9 * time.Hour,
+	}
+	deviceChoices = [][]byte{
+		[]byte("desktop"),
+		[]byte("mobile"),
+		[]byte("tablet"),
+	}
+	countryChoices = [][]byte{
+		[]byte("US"),
+		[]byte("GB"),
+		[]byte("DE"),
+		[]byte("FR"),
+		[]byte("IN"),
+		[]byte("JP"),
+		[]byte("BR"),
+		[]byte("CA"),
+	}
+)
+
+// session is a visit of a user to a site, ending after sessionTimeout
+// without page views
+type session struct {
+	userID  uint64
+	id      []byte
+	user    []byte
+	device  []byte
+	country []byte
+
+	start     int64 // nanoseconds since the Unix epoch
+	last      int64
+	pageViews int64
+	converted bool
+
+	// remaining is the number of page views to come, the next of them at
+	// next
+	remaining int
+	next      int64
+}
+
+// sessionQueue is a heap of the sessions with page views to come, by the
+// time of the next one
+type sessionQueue []*session
+
+func (q sessionQueue) Len() int            { return len(q) }
+func (q sessionQueue) Less(i, j int) bool  { return q[i].next < q[j].next }
+func (q sessionQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *sessionQueue) Push(x interface{}) { *q = append(*q, x.(*session)) }
+func (q *sessionQueue) Pop() interface{} {
+	old := *q
+	s := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return s
+}
+
+// expiry is when a session ends if it has no more page views
+type expiry struct {
+	user uint64
+	at   int64
+}
+
+type pageView struct {
+	session   *session
+	page      []byte
+	timestamp int64
+	loadTime  float64
+	bytes     int64
+	status    int64
+	scroll    float64
+}
+
+// Site models a website, whose users view its pages in sessions. Sessions
+// start at a rate following the daily cycle of Traffic in the time zone of
+// the users, each by a user drawn from a Zipf distribution, or continue a
+// session of the user in progress. The page views of a reading are those in
+// the interval from its timestamp, and the sessions those that ended in the
+// interval before it.
+type Site struct {
+	// These are all assigned once, at Site creation:
+	Name      []byte
+	UTCOffset time.Duration
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+	interval  time.Duration
+	maxEvents int
+	// peakRate is the page views per second at the peak of the day
+	peakRate float64
+
+	// sessions are the sessions in progress by user, of which those with
+	// page views to come are queued
+	sessions map[uint64]*session
+	queue    sessionQueue
+	// expiries are in the order they are due; those of sessions that had
+	// page views since are stale and skipped
+	expiries []expiry
+
+	views []pageView
+	ended []*session
+}
+
+// NewSite returns site i, with readings every interval from start of at most
+// maxEvents page views and maxEvents sessions each
+func NewSite(r *common.Rand, i int, start time.Time, interval time.Duration, maxEvents int) *Site {
+	s := &Site{
+		Name:      []byte(fmt.Sprintf("site_%d", i)),
+		UTCOffset: utcOffsets[r.Intn(len(utcOffsets))],
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+		interval:  interval,
+		maxEvents: maxEvents,
+		peakRate:  minPeakRate + (maxPeakRate-minPeakRate)*r.Float64(),
+
+		sessions: make(map[uint64]*session),
+	}
+	s.read()
+	return s
+}
+
+// Tick advances the site by d to its next reading
+func (s *Site) Tick(d time.Duration) {
+	s.timestamp += int64(d)
+	s.read()
+}
+
+// read draws the page views and ended sessions of the current reading
+func (s *Site) read() {
+	s.ended = s.ended[:0]
+	for len(s.expiries) > 0 && len(s.ended) < s.maxEvents && s.expiries[0].at <= s.timestamp {
+		e := s.expiries[0]
+		s.expiries = s.expiries[1:]
+		sess := s.sessions[e.user]
+		if sess != nil && sess.remaining == 0 && sess.last+int64(sessionTimeout) == e.at {
+			s.ended = append(s.ended, sess)
+			delete(s.sessions, e.user)
+		}
+	}
+
+	local := time.Unix(0, s.timestamp).UTC().Add(s.UTCOffset)
+	starts := common.Poisson(s.rand, s.peakRate*Traffic(local)*s.interval.Seconds()/meanPageViews)
+	for i := 0; i < starts; i++ {
+		s.start(s.timestamp + s.rand.Int63n(int64(s.interval)))
+	}
+
+	end := s.timestamp + int64(s.interval)
+	s.views = s.views[:0]
+	for len(s.queue) > 0 && s.queue[0].next < end && len(s.views) < s.maxEvents {
+		sess := heap.Pop(&s.queue).(*session)
+		// page views beyond maxEvents are put off to the next reading
+		if sess.next < s.timestamp {
+			sess.next = s.timestamp
+		}
+		s.views = append(s.views, s.view(sess))
+		if sess.remaining > 0 {
+			sess.next += exponential(s.rand, thinkTime)
+			heap.Push(&s.queue, sess)
+		} else {
+			s.expiries = append(s.expiries, expiry{user: sess.userID, at: sess.last + int64(sessionTimeout)})
+		}
+	}
+}
+
+// start starts a session at timestamp of a user drawn from the site's
+// users, or adds page views to the session in progress of the user
+func (s *Site) start(timestamp int64) {
+	user := users.Draw(s.rand)
+	pageViews := geometric(s.rand, meanPageViews)
+	if sess := s.sessions[user]; sess != nil {
+		if sess.remaining == 0 {
+			sess.next = timestamp
+			heap.Push(&s.queue, sess)
+		}
+		sess.remaining += pageViews
+		return
+	}
+
+	sess := &session{
+		userID:    user,
+		id:        []byte(strconv.FormatUint(s.rand.Uint64(), 16)),
+		user:      []byte("user_" + strconv.FormatUint(user, 10)),
+		device:    deviceChoices[0],
+		country:   countryChoices[user%uint64(len(countryChoices))],
+		start:     timestamp,
+		remaining: pageViews,
+		next:      timestamp,
+	}
+	if x := s.rand.Float64(); x < 0.05 {
+		sess.device = deviceChoices[2]
+	} else if x < 0.55 {
+		sess.device = deviceChoices[1]
+	}
+	s.sessions[user] = sess
+	heap.Push(&s.queue, sess)
+}
+
+// view returns the next page view of sess
+func (s *Site) view(sess *session) pageView {
+	sess.last = sess.next
+	sess.pageViews++
+	sess.remaining--
+
+	page := int(pages.Draw(s.rand))
+	if page == checkoutPage {
+		sess.converted = true
+	}
+	v := pageView{
+		session:   sess,
+		page:      pagePath(page),
+		timestamp: sess.next,
+		// log-normal, slower on mobile devices
+		loadTime: 800 * math.Exp(0.5*s.rand.NormFloat64()),
+		bytes:    int64(20000 + s.rand.Intn(400000)),
+		status:   200,
+		scroll:   s.rand.Float64(),
+	}
+	if sess.device[0] == 'm' {
+		v.loadTime *= 1.5
+	}
+	if x := s.rand.Float64(); x < 0.002 {
+		v.status = 500
+	} else if x < 0.012 {
+		v.status = 404
+	}
+	return v
+}
+
+// geometric returns a geometrically distributed number of at least 1 with
+// the given mean
+func geometric(r *common.Rand, mean float64) int {
+	n := 1
+	for r.Float64() >= 1/mean {
+		n++
+	}
+	return n
+}
+
+// exponential returns an exponentially distributed duration with the given
+// mean, in nanoseconds
+func exponential(r *common.Rand, mean time.Duration) int64 {
+	return int64(-math.Log(1-r.Float64()) * float64(mean))
+}
+
+// pagePath returns the path of page i of a site
+func pagePath(i int) []byte {
+	if i < len(namedPages) {
+		return namedPages[i]
+	}
+	return []byte("/products/" + strconv.Itoa(i))
+}
+
+// ToPoint fills p with point i of the current reading: the page views, then
+// the sessions that ended, returning false for points beyond them
+func (s *Site) ToPoint(p *serialize.Point, i int) bool {
+	if i < s.maxEvents {
+		if i >= len(s.views) {
+			return false
+		}
+		v := &s.views[i]
+		s.appendTags(p, v.session)
+		p.AppendTag(tagKeyPage, v.page)
+		p.SetMeasurementName(labelPageView)
+		p.SetTimestamp(v.timestamp)
+		p.AppendField(pageViewFields[0], v.loadTime)
+		p.AppendField(pageViewFields[1], v.bytes)
+		p.AppendField(pageViewFields[2], v.status)
+		p.AppendField(pageViewFields[3], v.scroll)
+		return true
+	}
+
+	i -= s.maxEvents
+	if i >= len(s.ended) {
+		return false
+	}
+	sess := s.ended[i]
+	s.appendTags(p, sess)
+	p.SetMeasurementName(labelSession)
+	p.SetTimestamp(s.timestamp)
+	p.AppendField(sessionFields[0], time.Duration(sess.last-sess.start).Seconds())
+	p.AppendField(sessionFields[1], sess.pageViews)
+	p.AppendField(sessionFields[2], sess.pageViews == 1)
+	p.AppendField(sessionFields[3], sess.converted)
+	return true
+}
+
+func (s *Site) appendTags(p *serialize.Point, sess *session) {
+	p.AppendTag(TagKeys[0], s.Name)
+	p.AppendTag(TagKeys[1], sess.user)
+	p.AppendTag(TagKeys[2], sess.id)
+	p.AppendTag(TagKeys[3], sess.device)
+	p.AppendTag(TagKeys[4], sess.country)
+}
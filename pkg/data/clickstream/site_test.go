@@ -0,0 +1,65 @@
+package clickstream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestSiteTick(t *testing.T) {
+	// midnight UTC on a Monday
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	const maxEvents = 200
+	s := NewSite(common.NewRand(rng.New(123)), 7, start, 10*time.Second, maxEvents)
+	if got := string(s.Name); got != "site_7" {
+		t.Errorf("incorrect name: got %s", got)
+	}
+
+	views := make([]int, 24)
+	sessions := 0
+	for i := 0; i < 24*360; i++ {
+		hour := time.Unix(0, s.timestamp).UTC().Add(s.UTCOffset).Hour()
+		views[hour] += len(s.views)
+		sessions += len(s.ended)
+		prev := s.timestamp
+		for _, v := range s.views {
+			if v.timestamp < prev || v.timestamp >= s.timestamp+int64(10*time.Second) {
+				t.Fatalf("page view outside its reading: %d", v.timestamp)
+			}
+			prev = v.timestamp
+		}
+		for _, sess := range s.ended {
+			if s.timestamp-sess.last < int64(sessionTimeout) {
+				t.Fatalf("session ended before timing out")
+			}
+		}
+		s.Tick(10 * time.Second)
+	}
+	if views[16] < 5*views[trafficLowHour] {
+		t.Errorf("traffic not much higher at the peak: %d at 16:00, %d at 04:00", views[16], views[trafficLowHour])
+	}
+	if sessions == 0 {
+		t.Errorf("no sessions ended")
+	}
+
+	p := serialize.NewPoint()
+	for i := 0; i < 2*maxEvents; i++ {
+		p.Reset()
+		made := s.ToPoint(p, i)
+		switch {
+		case i < len(s.views):
+			if !made || string(p.MeasurementName()) != "page_view" || len(p.TagKeys()) != len(TagKeys)+1 {
+				t.Errorf("point %d: incorrect page view", i)
+			}
+		case i >= maxEvents && i < maxEvents+len(s.ended):
+			if !made || string(p.MeasurementName()) != "session" {
+				t.Errorf("point %d: incorrect session", i)
+			}
+		case made:
+			t.Errorf("point %d: made beyond the events", i)
+		}
+	}
+}
@@ -2,9 +2,16 @@ package common
 
 import (
 	"math"
-	"math/rand"
 )
 
+// AdvanceAll advances each of the given distributions in order. It is
+// equivalent to calling Advance on each, but the distributions of this
+// package share the cost of synchronizing on the package-level source of
+// randomness.
+func AdvanceAll(ds []Distribution) {
+	variates.AdvanceAll(ds)
+}
+
 // Distribution provides an interface to model a statistical distribution.
 type Distribution interface {
 	Advance()
@@ -27,7 +34,13 @@ func ND(mean, stddev float64) *NormalDistribution {
 // Advance advances this distribution. Since the distribution is
 // stateless, this just overwrites the internal cache value.
 func (d *NormalDistribution) Advance() {
-	d.value = rand.NormFloat64()*d.StdDev + d.Mean
+	variates.mu.Lock()
+	d.advance(variates)
+	variates.mu.Unlock()
+}
+
+func (d *NormalDistribution) advance(r *Rand) {
+	d.value = r.normFloat64()*d.StdDev + d.Mean
 }
 
 // Get returns the last computed value for this distribution.
@@ -51,7 +64,13 @@ func UD(low, high float64) *UniformDistribution {
 // Advance advances this distribution. Since the distribution is
 // stateless, this just overwrites the internal cache value.
 func (d *UniformDistribution) Advance() {
-	x := rand.Float64() // uniform
+	variates.mu.Lock()
+	d.advance(variates)
+	variates.mu.Unlock()
+}
+
+func (d *UniformDistribution) advance(r *Rand) {
+	x := r.float64() // uniform
 	x *= d.High - d.Low
 	x += d.Low
 	d.value = x
@@ -77,7 +96,13 @@ func WD(step Distribution, state float64) *RandomWalkDistribution {
 
 // Advance computes the next value of this distribution and stores it.
 func (d *RandomWalkDistribution) Advance() {
-	d.Step.Advance()
+	variates.mu.Lock()
+	d.advance(variates)
+	variates.mu.Unlock()
+}
+
+func (d *RandomWalkDistribution) advance(r *Rand) {
+	r.advance(d.Step)
 	d.State += d.Step.Get()
 }
 
@@ -110,7 +135,13 @@ func CWD(step Distribution, min, max, state float64) *ClampedRandomWalkDistribut
 
 // Advance computes the next value of this distribution and stores it.
 func (d *ClampedRandomWalkDistribution) Advance() {
-	d.Step.Advance()
+	variates.mu.Lock()
+	d.advance(variates)
+	variates.mu.Unlock()
+}
+
+func (d *ClampedRandomWalkDistribution) advance(r *Rand) {
+	r.advance(d.Step)
 	d.State += d.Step.Get()
 	if d.State > d.Max {
 		d.State = d.Max
@@ -136,7 +167,13 @@ type MonotonicRandomWalkDistribution struct {
 
 // Advance computes the next value of this distribution and stores it.
 func (d *MonotonicRandomWalkDistribution) Advance() {
-	d.Step.Advance()
+	variates.mu.Lock()
+	d.advance(variates)
+	variates.mu.Unlock()
+}
+
+func (d *MonotonicRandomWalkDistribution) advance(r *Rand) {
+	r.advance(d.Step)
 	d.State += math.Abs(d.Step.Get())
 }
 
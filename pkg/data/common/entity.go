@@ -0,0 +1,221 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// Entity is a simulated source of readings that a use case is scaled by the
+// number of, e.g., a stock symbol or a weather station. Each reading of an
+// Entity is made of the same number of points, any of which may be left out,
+// e.g., while the market is closed or a machine is down.
+type Entity interface {
+	// Tick advances the Entity by d to its next reading
+	Tick(d time.Duration)
+	// ToPoint fills p with point i of the current reading, returning
+	// whether it should be written
+	ToPoint(p *serialize.Point, i int) bool
+}
+
+// EntityConstructor returns Entity i of a simulation starting at start,
+// drawing its random values from r
+type EntityConstructor func(r *Rand, i int, start time.Time) Entity
+
+// EntitySimulatorConfig is used to create an EntitySimulator
+type EntitySimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitCount is the number of Entities to start with in the first
+	// reporting period
+	InitCount uint64
+	// Count is the total number of Entities to have in the last reporting
+	// period
+	Count uint64
+	// PointsPerReading is the number of points of each reading of an Entity
+	PointsPerReading int
+	// Constructor makes each Entity
+	Constructor EntityConstructor
+	// Schema is the Schema of the points of the Entities
+	Schema *serialize.Schema
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each Entity, so that an Entity's values do not depend on
+	// how the simulation is Split. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated by an EntitySimulator
+// made from c
+func (c *EntitySimulatorConfig) Fields() *serialize.Schema {
+	return c.Schema
+}
+
+// ToSimulator produces an EntitySimulator that makes a reading of each
+// Entity every interval. It advances RNG, so each call simulates different
+// Entities.
+func (c *EntitySimulatorConfig) ToSimulator(interval time.Duration) Simulator {
+	r := c.RNG
+	if r == nil {
+		r = rng.New(0)
+	}
+	entities := make([]Entity, c.Count)
+	for i := range entities {
+		entities[i] = c.Constructor(NewRand(r.Split()), i, c.Start)
+	}
+
+	epochs := uint64(c.End.Sub(c.Start).Nanoseconds() / interval.Nanoseconds())
+	return &EntitySimulator{
+		maxPoints: epochs * c.Count * uint64(c.PointsPerReading),
+
+		entities:         entities,
+		entityEnd:        c.Count,
+		pointsPerReading: c.PointsPerReading,
+
+		epochs:        epochs,
+		epochEntities: c.InitCount,
+		initEntities:  c.InitCount,
+		interval:      interval,
+
+		schema: c.Schema,
+	}
+}
+
+// EntitySimulator simulates a reading of each of its Entities every
+// interval, going through point 0 of every Entity's reading, then point 1,
+// and so on, like the measurements of the hosts of the devops use case. It
+// fulfills the Simulator interface.
+type EntitySimulator struct {
+	madePoints uint64
+	maxPoints  uint64
+
+	entityIndex uint64
+	entities    []Entity
+	// entityStart and entityEnd bound the range of Entities that are
+	// simulated, since Entities may be shared with other simulators after a
+	// Split
+	entityStart uint64
+	entityEnd   uint64
+
+	pointIndex       int
+	pointsPerReading int
+
+	epoch         uint64
+	epochs        uint64
+	epochEntities uint64
+	initEntities  uint64
+
+	interval time.Duration
+
+	schema *serialize.Schema
+}
+
+// Finished tells whether we have simulated all the necessary points
+func (s *EntitySimulator) Finished() bool {
+	return s.madePoints >= s.maxPoints
+}
+
+// Fields returns the Schema of the points of the Entities
+func (s *EntitySimulator) Fields() *serialize.Schema {
+	return s.schema
+}
+
+// Next advances a Point to the next state in the generator.
+func (s *EntitySimulator) Next(ctx context.Context, p *serialize.Point) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return s.next(p)
+}
+
+func (s *EntitySimulator) next(p *serialize.Point) bool {
+	// switch to the next point of the reading if needed
+	if s.entityIndex == s.entityEnd {
+		s.entityIndex = s.entityStart
+		s.pointIndex++
+	}
+
+	if s.pointIndex == s.pointsPerReading {
+		s.pointIndex = 0
+		for i := s.entityStart; i < s.entityEnd; i++ {
+			s.entities[i].Tick(s.interval)
+		}
+		s.adjustNumEntitiesForEpoch()
+	}
+
+	ret := s.entities[s.entityIndex].ToPoint(p, s.pointIndex) && s.entityIndex < s.epochEntities
+	s.madePoints++
+	s.entityIndex++
+	return ret
+}
+
+// NextBatch fills points with the next points to be written, returning how
+// many were filled. A batch never spans more than one epoch.
+func (s *EntitySimulator) NextBatch(ctx context.Context, points []serialize.Point) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	n := 0
+	for n < len(points) && !s.Finished() {
+		p := &points[n]
+		p.Reset()
+		if s.next(p) {
+			n++
+		}
+		if s.epochDone() {
+			break
+		}
+	}
+	return n
+}
+
+// epochDone returns whether the next call to Next will start a new epoch
+func (s *EntitySimulator) epochDone() bool {
+	return s.entityIndex == s.entityEnd && s.pointIndex == s.pointsPerReading-1
+}
+
+// adjustNumEntitiesForEpoch grows the number of Entities written in
+// proportion to the epochs passed, from the initial number to all of them
+// in the last epoch
+func (s *EntitySimulator) adjustNumEntitiesForEpoch() {
+	s.epoch++
+	if s.epochs < 2 {
+		return
+	}
+	missingScale := float64(uint64(len(s.entities)) - s.initEntities)
+	s.epochEntities = s.initEntities + uint64(missingScale*float64(s.epoch)/float64(s.epochs-1))
+}
+
+// Split partitions the Entities simulated by s into n contiguous, (nearly)
+// equal sized ranges and returns an EntitySimulator for each. They share the
+// underlying Entities but never touch each other's, so they can be run
+// concurrently.
+func (s *EntitySimulator) Split(n int) []Simulator {
+	if n < 1 {
+		panic(fmt.Sprintf("cannot split simulator into %d parts", n))
+	}
+	if s.madePoints > 0 {
+		panic("cannot split simulator after points have been made")
+	}
+
+	numEntities := s.entityEnd - s.entityStart
+	pointsPerEntity := uint64(0)
+	if numEntities > 0 {
+		pointsPerEntity = s.maxPoints / numEntities
+	}
+
+	subs := make([]Simulator, n)
+	for i := range subs {
+		sub := *s
+		sub.entityStart = s.entityStart + numEntities*uint64(i)/uint64(n)
+		sub.entityEnd = s.entityStart + numEntities*uint64(i+1)/uint64(n)
+		sub.entityIndex = sub.entityStart
+		sub.maxPoints = pointsPerEntity * (sub.entityEnd - sub.entityStart)
+		subs[i] = &sub
+	}
+	return subs
+}
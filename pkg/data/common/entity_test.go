@@ -0,0 +1,159 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// testEntity writes point i of reading n as measurement "m<i>" with its
+// value, leaving out point 1 of odd readings
+type testEntity struct {
+	id      int
+	reading int
+	value   float64
+	r       *Rand
+}
+
+func (e *testEntity) Tick(d time.Duration) {
+	e.reading++
+	e.value = e.r.Float64()
+}
+
+func (e *testEntity) ToPoint(p *serialize.Point, i int) bool {
+	if i == 1 && e.reading%2 == 1 {
+		return false
+	}
+	p.SetMeasurementName([]byte(fmt.Sprintf("m%d", i)))
+	p.AppendTag([]byte("id"), []byte(fmt.Sprintf("e%d", e.id)))
+	p.SetTimestamp(int64(e.reading))
+	p.AppendField([]byte("value"), e.value)
+	return true
+}
+
+func testEntityConfig(initCount, count uint64) *EntitySimulatorConfig {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &EntitySimulatorConfig{
+		Start:            start,
+		End:              start.Add(3 * time.Second),
+		InitCount:        initCount,
+		Count:            count,
+		PointsPerReading: 2,
+		Constructor: func(r *Rand, i int, start time.Time) Entity {
+			return &testEntity{id: i, value: r.Float64(), r: r}
+		},
+		Schema: serialize.NewSchema([][]byte{[]byte("id")}, map[string][][]byte{
+			"m0": {[]byte("value")},
+			"m1": {[]byte("value")},
+		}),
+		RNG: rng.New(123),
+	}
+}
+
+// entityPoints returns the points of sim that are written, as strings
+func entityPoints(sim Simulator) []string {
+	var ret []string
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if sim.Next(context.Background(), p) {
+			ret = append(ret, fmt.Sprintf("%s %s %d %v", p.MeasurementName(), p.TagValues()[0], p.Timestamp(), p.FieldValues()[0]))
+		}
+	}
+	return ret
+}
+
+func TestEntitySimulatorNext(t *testing.T) {
+	sim := testEntityConfig(1, 3).ToSimulator(time.Second)
+	got := entityPoints(sim)
+	// 3 epochs of 2 points per reading, with the second point left out in
+	// the odd one and the entities growing from 1 to 3
+	var want []string
+	for reading, n := range []int{1, 2, 3} {
+		for i := 0; i < 2; i++ {
+			if i == 1 && reading%2 == 1 {
+				continue
+			}
+			for e := 0; e < n; e++ {
+				want = append(want, fmt.Sprintf("m%d e%d %d", i, e, reading))
+			}
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("incorrect number of points: got %d want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i][:len(want[i])] != want[i] {
+			t.Errorf("incorrect point %d: got %q want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEntitySimulatorNextBatch(t *testing.T) {
+	want := entityPoints(testEntityConfig(3, 3).ToSimulator(time.Second))
+
+	sim := testEntityConfig(3, 3).ToSimulator(time.Second)
+	points := make([]serialize.Point, 4)
+	for i := range points {
+		points[i] = *serialize.NewPoint()
+	}
+	var got []string
+	for !sim.Finished() {
+		n := sim.NextBatch(context.Background(), points)
+		ts := points[0].Timestamp()
+		for _, p := range points[:n] {
+			if p.Timestamp() != ts {
+				t.Errorf("batch spans epochs: got %d and %d", ts, p.Timestamp())
+			}
+			got = append(got, fmt.Sprintf("%s %s %d %v", p.MeasurementName(), p.TagValues()[0], p.Timestamp(), p.FieldValues()[0]))
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("batches differ from points of Next:\ngot  %v\nwant %v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n := testEntityConfig(3, 3).ToSimulator(time.Second).NextBatch(ctx, points); n != 0 {
+		t.Errorf("made points after cancel: got %d", n)
+	}
+}
+
+func TestEntitySimulatorSplit(t *testing.T) {
+	want := make(map[string]bool)
+	for _, p := range entityPoints(testEntityConfig(5, 5).ToSimulator(time.Second)) {
+		want[p] = true
+	}
+
+	subs := testEntityConfig(5, 5).ToSimulator(time.Second).Split(3)
+	if len(subs) != 3 {
+		t.Fatalf("incorrect number of simulators: got %d want 3", len(subs))
+	}
+	got := 0
+	for _, sub := range subs {
+		for _, p := range entityPoints(sub) {
+			if !want[p] {
+				t.Errorf("point not made without split: %s", p)
+			}
+			got++
+		}
+	}
+	if got != len(want) {
+		t.Errorf("incorrect number of points: got %d want %d", got, len(want))
+	}
+
+	sim := testEntityConfig(5, 5).ToSimulator(time.Second)
+	sim.Next(context.Background(), serialize.NewPoint())
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("did not panic when splitting after points were made")
+			}
+		}()
+		sim.Split(2)
+	}()
+}
@@ -0,0 +1,33 @@
+package common
+
+// Interner keeps a single copy of each distinct byte string it is given, so
+// values repeated across many simulated entities (e.g., the rack or service
+// tag of a host) share one backing array instead of each having their own.
+//
+// An Interner is not safe for concurrent use: values are interned once, as
+// the entities are made, and only read afterwards. Values returned from it
+// must not be modified.
+type Interner struct {
+	values map[string][]byte
+}
+
+// NewInterner returns a new empty Interner
+func NewInterner() *Interner {
+	return &Interner{values: make(map[string][]byte)}
+}
+
+// Intern returns the canonical copy of b, adding it if not seen before
+func (in *Interner) Intern(b []byte) []byte {
+	if v, ok := in.values[string(b)]; ok {
+		return v
+	}
+	v := make([]byte, len(b))
+	copy(v, b)
+	in.values[string(v)] = v
+	return v
+}
+
+// Len returns the number of distinct values interned
+func (in *Interner) Len() int {
+	return len(in.values)
+}
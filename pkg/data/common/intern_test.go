@@ -0,0 +1,31 @@
+package common
+
+import "testing"
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+	src := []byte("foo")
+	a := in.Intern(src)
+	if string(a) != "foo" {
+		t.Errorf("incorrect interned value: got %s want %s", a, "foo")
+	}
+
+	// Modifying the input should not affect the interned copy
+	src[0] = 'b'
+	if string(a) != "foo" {
+		t.Errorf("interned value changed with its input: got %s", a)
+	}
+
+	b := in.Intern([]byte("foo"))
+	if &a[0] != &b[0] {
+		t.Errorf("equal values do not share a backing array")
+	}
+	if got := in.Len(); got != 1 {
+		t.Errorf("incorrect len: got %d want %d", got, 1)
+	}
+
+	in.Intern([]byte("bar"))
+	if got := in.Len(); got != 2 {
+		t.Errorf("incorrect len: got %d want %d", got, 2)
+	}
+}
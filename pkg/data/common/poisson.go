@@ -0,0 +1,27 @@
+package common
+
+import (
+	"math"
+
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// Poisson returns the number of events in a period with lambda of them on
+// average, drawn from r, e.g., the number of page views of a site in a
+// reading. Large means are approximated with a normal distribution.
+func Poisson(r rng.RNG, lambda float64) int {
+	if lambda <= 0 {
+		return 0
+	}
+	if lambda > 30 {
+		return int(math.Max(0, math.Round(lambda+math.Sqrt(lambda)*r.NormFloat64())))
+	}
+	// Knuth's method, multiplying uniform variates until they fall below
+	// e ** -lambda
+	limit := math.Exp(-lambda)
+	n := 0
+	for p := r.Float64(); p > limit; p *= r.Float64() {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestPoisson(t *testing.T) {
+	r := rng.New(123)
+	for _, lambda := range []float64{0, 0.5, 5, 100} {
+		const n = 20000
+		sum := 0
+		for i := 0; i < n; i++ {
+			k := Poisson(r, lambda)
+			if k < 0 {
+				t.Fatalf("%v: negative count: %d", lambda, k)
+			}
+			sum += k
+		}
+		if mean := float64(sum) / n; math.Abs(mean-lambda) > 0.05*lambda+0.01 {
+			t.Errorf("%v: incorrect mean: got %v", lambda, mean)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// variateBatchSize is the number of variates of each kind generated at once
+// by the package-level source
+const variateBatchSize = 512
+
+// variates is the source of randomness for distributions advanced with their
+// Advance method or AdvanceAll, rather than by a simulation's Rand
+var variates = newBatchRand(rng.New(1))
+
+// Seed initializes the source of randomness used by Advance and AdvanceAll to
+// a deterministic state. Simulations advance their distributions with their
+// own Rand instead, which does not depend on it.
+func Seed(seed int64) {
+	variates.seed(rng.New(seed))
+}
+
+// Rand is the source of randomness of a simulated series, e.g., a host. It is
+// an rng.RNG for choosing the series' initial state, such as its tag values,
+// and it advances the series' distributions with AdvanceAll. Like an rng.RNG,
+// a Rand must not be used concurrently, so simulations that may run in
+// parallel give each series its own, split from a common rng.RNG.
+type Rand struct {
+	rng.RNG
+
+	// mu is only needed for the package-level source, which may be shared;
+	// apart from seed, the unexported methods require it to be held
+	mu sync.Mutex
+
+	// uniform and normal hold batches of variates handed out one at a time,
+	// or are nil to draw each one from RNG
+	uniform    []float64
+	uniformIdx int
+	normal     []float64
+	normalIdx  int
+}
+
+// NewRand returns a Rand drawing from r
+func NewRand(r rng.RNG) *Rand {
+	return &Rand{RNG: r}
+}
+
+// newBatchRand returns a Rand that draws variates from r a whole batch at a
+// time, which keeps the generator state hot and its per-call overhead out of
+// the hot loop. Callers lock it once for a whole measurement's worth of
+// distributions (see AdvanceAll) rather than once per value, which otherwise
+// dominates when it is shared.
+func newBatchRand(r rng.RNG) *Rand {
+	b := &Rand{
+		uniform: make([]float64, variateBatchSize),
+		normal:  make([]float64, variateBatchSize),
+	}
+	b.seed(r)
+	return b
+}
+
+func (r *Rand) seed(src rng.RNG) {
+	r.mu.Lock()
+	r.RNG = src
+	// discard anything generated from the previous state
+	r.uniformIdx = len(r.uniform)
+	r.normalIdx = len(r.normal)
+	r.mu.Unlock()
+}
+
+// AdvanceAll advances each of the given distributions in order, drawing the
+// variates of the distributions of this package from r. Distributions of
+// other packages are advanced with their Advance method.
+func (r *Rand) AdvanceAll(ds []Distribution) {
+	r.mu.Lock()
+	for _, d := range ds {
+		r.advance(d)
+	}
+	r.mu.Unlock()
+}
+
+// advance advances d; r.mu must be held. The distributions of this package
+// are matched on their concrete types, which is much cheaper than asserting an
+// interface in this hot path. Other distributions may use the package's
+// distributions (and so lock the package-level source) themselves, so r is
+// unlocked while advancing them.
+func (r *Rand) advance(d Distribution) {
+	switch d := d.(type) {
+	case *NormalDistribution:
+		d.advance(r)
+	case *UniformDistribution:
+		d.advance(r)
+	case *RandomWalkDistribution:
+		d.advance(r)
+	case *ClampedRandomWalkDistribution:
+		d.advance(r)
+	case *MonotonicRandomWalkDistribution:
+		d.advance(r)
+	case *ConstantDistribution:
+	default:
+		r.mu.Unlock()
+		d.Advance()
+		r.mu.Lock()
+	}
+}
+
+// float64 returns a uniform variate in [0.0, 1.0); r.mu must be held
+func (r *Rand) float64() float64 {
+	if r.uniform == nil {
+		return r.RNG.Float64()
+	}
+	if r.uniformIdx == len(r.uniform) {
+		for i := range r.uniform {
+			r.uniform[i] = r.RNG.Float64()
+		}
+		r.uniformIdx = 0
+	}
+	v := r.uniform[r.uniformIdx]
+	r.uniformIdx++
+	return v
+}
+
+// normFloat64 returns a standard normal variate; r.mu must be held
+func (r *Rand) normFloat64() float64 {
+	if r.normal == nil {
+		return r.RNG.NormFloat64()
+	}
+	if r.normalIdx == len(r.normal) {
+		for i := range r.normal {
+			r.normal[i] = r.RNG.NormFloat64()
+		}
+		r.normalIdx = 0
+	}
+	v := r.normal[r.normalIdx]
+	r.normalIdx++
+	return v
+}
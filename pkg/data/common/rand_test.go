@@ -0,0 +1,105 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestBatchRandFloat64(t *testing.T) {
+	r := newBatchRand(rng.New(123))
+	// cover more than one batch to check refilling
+	for i := 0; i < 3*variateBatchSize; i++ {
+		if v := r.float64(); v < 0.0 || v >= 1.0 {
+			t.Fatalf("uniform variate out of range at %d: %v", i, v)
+		}
+	}
+}
+
+func TestBatchRandSeed(t *testing.T) {
+	r := newBatchRand(rng.New(123))
+	want := make([]float64, variateBatchSize+1)
+	for i := range want {
+		want[i] = r.normFloat64()
+	}
+	r.float64()
+
+	// reseeding mid-batch should discard what was previously generated
+	r.seed(rng.New(123))
+	for i := range want {
+		if got := r.normFloat64(); got != want[i] {
+			t.Fatalf("incorrect value after reseeding at %d: got %v want %v", i, got, want[i])
+		}
+	}
+}
+
+type lockingDistribution struct {
+	nd *NormalDistribution
+}
+
+func (d *lockingDistribution) Advance() {
+	d.nd.Advance()
+}
+
+func (d *lockingDistribution) Get() float64 {
+	return d.nd.Get()
+}
+
+func TestAdvanceAll(t *testing.T) {
+	makeDistributions := func() []Distribution {
+		return []Distribution{
+			ND(10, 1),
+			UD(0, 10),
+			WD(ND(0, 1), 5),
+			CWD(ND(0, 1), 0, 10, 5),
+			MWD(ND(0, 1), 5),
+			&ConstantDistribution{State: 5},
+			// uses the package's distributions itself, so should not deadlock
+			&lockingDistribution{nd: ND(10, 1)},
+		}
+	}
+
+	Seed(123)
+	want := makeDistributions()
+	for i := 0; i < 10; i++ {
+		for _, d := range want {
+			d.Advance()
+		}
+	}
+
+	Seed(123)
+	got := makeDistributions()
+	for i := 0; i < 10; i++ {
+		AdvanceAll(got)
+	}
+
+	for i := range want {
+		if got[i].Get() != want[i].Get() {
+			t.Errorf("incorrect value for distribution %d: got %v want %v", i, got[i].Get(), want[i].Get())
+		}
+	}
+}
+
+func TestRandAdvanceAll(t *testing.T) {
+	makeDistributions := func() []Distribution {
+		return []Distribution{ND(10, 1), UD(0, 10), CWD(ND(0, 1), 0, 10, 5), MWD(ND(0, 1), 5)}
+	}
+	advance := func(r *Rand) []Distribution {
+		ds := makeDistributions()
+		for i := 0; i < 10; i++ {
+			r.AdvanceAll(ds)
+		}
+		return ds
+	}
+
+	want := advance(NewRand(rng.New(123)))
+	// the package-level source should not affect a Rand
+	Seed(456)
+	AdvanceAll(makeDistributions())
+	got := advance(NewRand(rng.New(123)))
+	for i := range want {
+		if got[i].Get() != want[i].Get() {
+			t.Errorf("incorrect value for distribution %d: got %v want %v", i, got[i].Get(), want[i].Get())
+		}
+	}
+}
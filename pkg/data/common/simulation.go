@@ -0,0 +1,39 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// SimulatorConfig is an interface to create a Simulator from a time.Duration
+type SimulatorConfig interface {
+	ToSimulator(time.Duration) Simulator
+}
+
+// Simulator simulates a use case.
+type Simulator interface {
+	Finished() bool
+	// Next advances the given Point to the next state of the simulation,
+	// returning whether it should be written. Once ctx is done it returns
+	// false without advancing
+	Next(context.Context, *serialize.Point) bool
+	// NextBatch fills the given points with the next points that should be
+	// written, returning how many were filled. It may return 0 even if the
+	// Simulator is not yet Finished, and always does once ctx is done
+	NextBatch(context.Context, []serialize.Point) int
+	// Split partitions the Simulator into n independent Simulators that
+	// together produce the same set of series; it must be called before any
+	// points are generated
+	Split(n int) []Simulator
+	// Fields returns the Schema of the measurements produced; it is computed
+	// once and shared between calls, so it must not be modified
+	Fields() *serialize.Schema
+}
+
+// SimulatedMeasurement simulates one measurement (e.g. Redis for DevOps).
+type SimulatedMeasurement interface {
+	Tick(time.Duration)
+	ToPoint(*serialize.Point)
+}
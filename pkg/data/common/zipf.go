@@ -0,0 +1,64 @@
+package common
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// Zipf draws values in [0, imax] with P(k) proportional to (v + k) ** -s,
+// e.g., the index of the user of an event, most of which are from a few
+// users. It is like math/rand's Zipf, but draws from any rng.RNG passed to
+// Draw, so it can be shared by series with their own RNGs.
+type Zipf struct {
+	imax         float64
+	v            float64
+	q            float64
+	s            float64
+	oneminusQ    float64
+	oneminusQinv float64
+	hxm          float64
+	hx0minusHxm  float64
+}
+
+// NewZipf returns a Zipf with exponent s > 1 and offset v >= 1 over
+// [0, imax]. It panics if s or v are out of range.
+func NewZipf(s, v float64, imax uint64) *Zipf {
+	if s <= 1 || v < 1 {
+		panic(fmt.Sprintf("invalid Zipf parameters: s = %v, v = %v", s, v))
+	}
+	z := &Zipf{
+		imax:         float64(imax),
+		v:            v,
+		q:            s,
+		oneminusQ:    1 - s,
+		oneminusQinv: 1 / (1 - s),
+	}
+	z.hxm = z.h(z.imax + 0.5)
+	z.hx0minusHxm = z.h(0.5) - math.Exp(math.Log(z.v)*(-z.q)) - z.hxm
+	z.s = 1 - z.hinv(z.h(1.5)-math.Exp(-z.q*math.Log(z.v+1)))
+	return z
+}
+
+func (z *Zipf) h(x float64) float64 {
+	return math.Exp(z.oneminusQ*math.Log(z.v+x)) * z.oneminusQinv
+}
+
+func (z *Zipf) hinv(x float64) float64 {
+	return math.Exp(z.oneminusQinv*math.Log(z.oneminusQ*x)) - z.v
+}
+
+// Draw returns a value drawn from r, using the rejection-inversion method of
+// W. Hormann and G. Derflinger, "Rejection-inversion to generate variates
+// from monotone discrete distributions" (1996)
+func (z *Zipf) Draw(r rng.RNG) uint64 {
+	for {
+		ur := z.hxm + r.Float64()*z.hx0minusHxm
+		x := z.hinv(ur)
+		k := math.Floor(x + 0.5)
+		if k-x <= z.s || ur >= z.h(k+0.5)-math.Exp(-math.Log(k+z.v)*z.q) {
+			return uint64(k)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestZipfDraw(t *testing.T) {
+	const imax = 99
+	z := NewZipf(1.2, 1, imax)
+	r := rng.New(123)
+	counts := make([]int, imax+1)
+	for i := 0; i < 100000; i++ {
+		k := z.Draw(r)
+		if k > imax {
+			t.Fatalf("value out of range: %d", k)
+		}
+		counts[k]++
+	}
+	// P(k) is proportional to (1 + k) ** -1.2, so the ratio of the counts of
+	// 0 and 1 should be about 2 ** 1.2 = 2.3
+	if ratio := float64(counts[0]) / float64(counts[1]); ratio < 2.1 || ratio > 2.5 {
+		t.Errorf("incorrect ratio of the counts of 0 and 1: got %v", ratio)
+	}
+	if counts[0] <= counts[10] || counts[10] <= counts[90] {
+		t.Errorf("counts not decreasing: %d, %d, %d", counts[0], counts[10], counts[90])
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("did not panic for s <= 1")
+			}
+		}()
+		NewZipf(1, 1, imax)
+	}()
+}
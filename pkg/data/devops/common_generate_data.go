@@ -0,0 +1,211 @@
+package devops
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+type commonDevopsSimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitHostCount is the number of hosts to start with in the first reporting period
+	InitHostCount uint64
+	// HostCount is the total number of hosts to have in the last reporting period
+	HostCount uint64
+	// HostConstructor is the function used to create a new Host given an id number and start time
+	HostConstructor HostConstructor
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each Host, so that a Host's values do not depend on how the
+	// simulation is Split. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+func calculateEpochs(c commonDevopsSimulatorConfig, interval time.Duration) uint64 {
+	return uint64(c.End.Sub(c.Start).Nanoseconds() / interval.Nanoseconds())
+}
+
+// makeHosts makes the Hosts of c in order, each with its own Rand split from
+// c.RNG. It advances c.RNG, so making them again gives different Hosts. The
+// tag values of the Hosts are interned, so equal values share one copy.
+func makeHosts(c commonDevopsSimulatorConfig) []Host {
+	r := c.RNG
+	if r == nil {
+		r = rng.New(0)
+	}
+	hosts := make([]Host, c.HostCount)
+	values := common.NewInterner()
+	for i := range hosts {
+		hosts[i] = c.HostConstructor(common.NewRand(r.Split()), i, c.Start)
+		hosts[i].intern(values)
+	}
+	return hosts
+}
+
+// schemaHost makes a Host of c to get the schema of its measurements from,
+// without advancing c.RNG
+func schemaHost(c commonDevopsSimulatorConfig) Host {
+	return c.HostConstructor(common.NewRand(rng.New(0)), 0, c.Start)
+}
+
+type commonDevopsSimulator struct {
+	madePoints uint64
+	maxPoints  uint64
+
+	hostIndex uint64
+	hosts     []Host
+	// hostStart and hostEnd bound the range of hosts that are simulated,
+	// since hosts may be shared with other simulators after a Split
+	hostStart uint64
+	hostEnd   uint64
+
+	epoch      uint64
+	epochs     uint64
+	epochHosts uint64
+	initHosts  uint64
+
+	timestampStart time.Time
+	timestampEnd   time.Time
+	interval       time.Duration
+
+	schema *serialize.Schema
+}
+
+// Finished tells whether we have simulated all the necessary points
+func (s *commonDevopsSimulator) Finished() bool {
+	return s.madePoints >= s.maxPoints
+}
+
+// Fields returns the Schema of all measurements simulated, computing it on
+// the first call
+func (s *commonDevopsSimulator) Fields() *serialize.Schema {
+	if s.schema == nil {
+		if len(s.hosts) <= 0 {
+			panic("cannot get fields because no hosts added")
+		}
+		s.schema = measurementsSchema(s.hosts[0].SimulatedMeasurements)
+	}
+	return s.schema
+}
+
+// measurementsSchema returns the Schema of the points of measurements,
+// including their own tags, set after the machine tags, and the types of
+// their fields
+func measurementsSchema(measurements []common.SimulatedMeasurement) *serialize.Schema {
+	tags := make(map[string][][]byte)
+	data := make(map[string][][]byte)
+	types := make(map[string][]serialize.FieldType)
+	for _, sm := range measurements {
+		point := serialize.NewPoint()
+		sm.ToPoint(point)
+		name := string(point.MeasurementName())
+		tags[name] = point.TagKeys()
+		data[name] = point.FieldKeys()
+		for _, v := range point.FieldValues() {
+			types[name] = append(types[name], serialize.FieldTypeOf(v))
+		}
+	}
+
+	return serialize.NewTaggedSchema(MachineTagKeys, tags, data, types)
+}
+
+// split partitions the hosts simulated by s into n contiguous, (nearly) equal
+// sized ranges and returns a simulator for each. The returned simulators share
+// the underlying hosts slice but never touch each other's hosts, so they can
+// be run concurrently.
+func (s *commonDevopsSimulator) split(n int) []*commonDevopsSimulator {
+	if n < 1 {
+		panic(fmt.Sprintf("cannot split simulator into %d parts", n))
+	}
+	if s.madePoints > 0 {
+		panic("cannot split simulator after points have been made")
+	}
+
+	numHosts := s.hostEnd - s.hostStart
+	pointsPerHost := uint64(0)
+	if numHosts > 0 {
+		pointsPerHost = s.maxPoints / numHosts
+	}
+
+	subs := make([]*commonDevopsSimulator, n)
+	for i := range subs {
+		sub := *s
+		sub.hostStart = s.hostStart + numHosts*uint64(i)/uint64(n)
+		sub.hostEnd = s.hostStart + numHosts*uint64(i+1)/uint64(n)
+		sub.hostIndex = sub.hostStart
+		sub.maxPoints = pointsPerHost * (sub.hostEnd - sub.hostStart)
+		subs[i] = &sub
+	}
+	return subs
+}
+
+// tickHosts advances all the hosts simulated by s
+func (s *commonDevopsSimulator) tickHosts() {
+	for i := s.hostStart; i < s.hostEnd; i++ {
+		s.hosts[i].TickAll(s.interval)
+	}
+}
+
+func (s *commonDevopsSimulator) populatePoint(p *serialize.Point, measureIdx int) bool {
+	host := &s.hosts[s.hostIndex]
+
+	// Populate host-specific tags:
+	p.AppendTag(MachineTagKeys[0], host.Name)
+	p.AppendTag(MachineTagKeys[1], host.Region)
+	p.AppendTag(MachineTagKeys[2], host.Datacenter)
+	p.AppendTag(MachineTagKeys[3], host.Rack)
+	p.AppendTag(MachineTagKeys[4], host.OS)
+	p.AppendTag(MachineTagKeys[5], host.Arch)
+	p.AppendTag(MachineTagKeys[6], host.Team)
+	p.AppendTag(MachineTagKeys[7], host.Service)
+	p.AppendTag(MachineTagKeys[8], host.ServiceVersion)
+	p.AppendTag(MachineTagKeys[9], host.ServiceEnvironment)
+
+	// Populate measurement-specific tags and fields:
+	host.SimulatedMeasurements[measureIdx].ToPoint(p)
+
+	ret := s.hostIndex < s.epochHosts
+	s.madePoints++
+	s.hostIndex++
+	return ret
+}
+
+// nextBatch fills points by repeatedly calling next, stopping when points is
+// full, the simulation is finished, or epochDone reports that the following
+// call would start a new epoch. Batches never span epochs, so all points in a
+// batch are from the same reporting period.
+// It returns the number of points filled that should be written.
+func (s *commonDevopsSimulator) nextBatch(points []serialize.Point, next func(*serialize.Point) bool, epochDone func() bool) int {
+	n := 0
+	for n < len(points) && !s.Finished() {
+		p := &points[n]
+		p.Reset()
+		if next(p) {
+			n++
+		}
+		if epochDone() {
+			break
+		}
+	}
+	return n
+}
+
+// TODO(rrk) - Can probably turn this logic into a separate interface and implement other
+// types of scale up, e.g., exponential
+//
+// To "scale up" the number of reporting items, we need to know when
+// which epoch we are currently in. Once we know that, we can take the "missing"
+// amount of scale -- i.e., the max amount of scale less the initial amount
+// -- and add it in proportion to the percentage of epochs that have passed. This
+// way we simulate all items at each epoch, but at the end of the function
+// we check whether the point should be recorded by the calling process.
+func (s *commonDevopsSimulator) adjustNumHostsForEpoch() {
+	s.epoch++
+	missingScale := float64(uint64(len(s.hosts)) - s.initHosts)
+	s.epochHosts = s.initHosts + uint64(missingScale*float64(s.epoch)/float64(s.epochs-1))
+}
@@ -5,8 +5,9 @@ import (
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 const testLayout = "2006-01-02"
@@ -124,16 +125,69 @@ func TestCommonDevopsSimulatorFinished(t *testing.T) {
 	}
 }
 
+func TestCommonDevopsSimulatorSplit(t *testing.T) {
+	s := &commonDevopsSimulator{
+		hosts:     make([]Host, 10),
+		hostStart: 0,
+		hostEnd:   10,
+		maxPoints: 30,
+	}
+	subs := s.split(3)
+	wantRanges := [][2]uint64{{0, 3}, {3, 6}, {6, 10}}
+	for i, sub := range subs {
+		if sub.hostStart != wantRanges[i][0] || sub.hostEnd != wantRanges[i][1] {
+			t.Errorf("incorrect range for %d: got [%d, %d) want [%d, %d)", i, sub.hostStart, sub.hostEnd, wantRanges[i][0], wantRanges[i][1])
+		}
+		if sub.hostIndex != sub.hostStart {
+			t.Errorf("incorrect host index for %d: got %d want %d", i, sub.hostIndex, sub.hostStart)
+		}
+		if want := 3 * (sub.hostEnd - sub.hostStart); sub.maxPoints != want {
+			t.Errorf("incorrect max points for %d: got %d want %d", i, sub.maxPoints, want)
+		}
+	}
+
+	// More parts than hosts leaves some parts empty
+	subs = s.split(20)
+	total := uint64(0)
+	for _, sub := range subs {
+		total += sub.maxPoints
+	}
+	if total != s.maxPoints {
+		t.Errorf("incorrect total max points: got %d want %d", total, s.maxPoints)
+	}
+
+	// Test panic conditions
+	for _, c := range []struct {
+		desc string
+		fn   func()
+	}{
+		{"n < 1", func() { s.split(0) }},
+		{"already started", func() {
+			s.madePoints = 1
+			s.split(2)
+		}},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("%s: did not panic when should", c.desc)
+				}
+			}()
+			c.fn()
+		}()
+	}
+}
+
 func TestCommonDevopsSimulatorFields(t *testing.T) {
 	s := &commonDevopsSimulator{}
 	host := Host{}
-	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now())}
+	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(common.NewRand(rng.New(123)), time.Now())}
 	s.hosts = append(s.hosts, host)
 	fields := s.Fields()
-	if got := len(fields); got != 1 {
+	if got := fields.Len(); got != 1 {
 		t.Errorf("fields length does not equal 1: got %d", got)
 	}
-	if got, ok := fields[string(labelCPU)]; ok {
+	if got := fields.FieldKeys(string(labelCPU)); got != nil {
 		if got2 := len(got); got2 <= 0 {
 			t.Errorf("number of fields is non-positive: got %d", got2)
 		}
@@ -141,25 +195,38 @@ func TestCommonDevopsSimulatorFields(t *testing.T) {
 		t.Errorf("CPU was not one of the labels")
 	}
 
+	// Subsequent calls should return the cached Schema
+	if got := s.Fields(); got != fields {
+		t.Errorf("Fields did not return cached schema")
+	}
+
 	// Add a host with different measurement. This should not affect the results
 	// because we assume each Host has the same set of simulated measurements.
 	// TODO - Examine whether this assumption should be refined.
 	host = Host{}
-	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewMemMeasurement(time.Now())}
+	host.SimulatedMeasurements = []common.SimulatedMeasurement{NewMemMeasurement(common.NewRand(rng.New(123)), time.Now())}
 	s.hosts = append(s.hosts, host)
+	s.schema = nil
 	fields = s.Fields()
-	if got := len(fields); got != 1 {
+	if got := fields.Len(); got != 1 {
 		t.Errorf("fields length does not equal 1: got %d", got)
 	}
 
-	// Add new measurement, this should change the result.
+	// Add new measurement, this should change the result once the cache is cleared.
 	host = s.hosts[0]
-	host.SimulatedMeasurements = append(host.SimulatedMeasurements, NewMemMeasurement(time.Now()))
+	host.SimulatedMeasurements = append(host.SimulatedMeasurements, NewMemMeasurement(common.NewRand(rng.New(123)), time.Now()))
 	s.hosts[0] = host
+	s.schema = nil
 	fields = s.Fields()
-	if got := len(fields); got != 2 {
+	if got := fields.Len(); got != 2 {
 		t.Errorf("fields length does not equal 2: got %d", got)
 	}
+	want := []string{string(labelCPU), string(labelMem)}
+	for i, m := range fields.Measurements() {
+		if m != want[i] {
+			t.Errorf("measurements not sorted: got %s want %s", m, want[i])
+		}
+	}
 
 	// Test panic condition
 	func() {
@@ -169,6 +236,7 @@ func TestCommonDevopsSimulatorFields(t *testing.T) {
 			}
 		}()
 		s.hosts = s.hosts[:0]
+		s.schema = nil
 		_ = s.Fields()
 	}()
 }
@@ -195,7 +263,7 @@ func TestCommonDevopsSimulatorPopulatePoint(t *testing.T) {
 			ServiceVersion:     bprintf("%s%d", prefix[8], i),
 			ServiceEnvironment: bprintf("%s%d", prefix[9], i),
 		}
-		host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(time.Now())}
+		host.SimulatedMeasurements = []common.SimulatedMeasurement{NewCPUMeasurement(common.NewRand(rng.New(123)), time.Now())}
 		s.hosts = append(s.hosts, host)
 	}
 	s.hostIndex = 0
@@ -238,6 +306,39 @@ func TestCommonDevopsSimulatorPopulatePoint(t *testing.T) {
 	}
 }
 
+func TestMakeHostsInterned(t *testing.T) {
+	hosts := makeHosts(commonDevopsSimulatorConfig{
+		HostCount:       20,
+		HostConstructor: NewHost,
+		RNG:             rng.New(123),
+	})
+
+	// Equal tag values of Hosts share one copy
+	for i := range hosts {
+		for j := range hosts {
+			a, b := hosts[i].tags(), hosts[j].tags()
+			for k := range a {
+				if string(*a[k]) == string(*b[k]) && &(*a[k])[0] != &(*b[k])[0] {
+					t.Fatalf("equal %s of hosts %d and %d were not interned", MachineTagKeys[k], i, j)
+				}
+			}
+		}
+	}
+
+	// Hosts of other simulators do not share them
+	other := makeHosts(commonDevopsSimulatorConfig{
+		HostCount:       1,
+		HostConstructor: NewHost,
+		RNG:             rng.New(123),
+	})
+	if string(other[0].Arch) != string(hosts[0].Arch) {
+		t.Fatalf("hosts differ with the same RNG: got %s want %s", other[0].Arch, hosts[0].Arch)
+	}
+	if &other[0].Arch[0] == &hosts[0].Arch[0] {
+		t.Errorf("hosts of different simulators share values")
+	}
+}
+
 func TestAdjustNumHostsForEpoch(t *testing.T) {
 	totalHosts := 100
 	cases := []struct {
@@ -0,0 +1,66 @@
+package devops
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelCPU  = []byte("cpu") // heap optimization
+	cpuFields = []labeledDistributionMaker{
+		{[]byte("usage_user"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_system"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_idle"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_nice"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_iowait"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_irq"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_softirq"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_steal"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_guest"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+		{[]byte("usage_guest_nice"), func(r rng.RNG) common.Distribution {
+			return common.CWD(common.ND(0.0, 1.0), 0.0, 100.0, r.Float64()*100.0)
+		}},
+	}
+)
+
+type CPUMeasurement struct {
+	*subsystemMeasurement
+}
+
+func NewCPUMeasurement(r *common.Rand, start time.Time) *CPUMeasurement {
+	return newCPUMeasurementNumDistributions(r, start, len(cpuFields))
+}
+
+func newSingleCPUMeasurement(r *common.Rand, start time.Time) *CPUMeasurement {
+	return newCPUMeasurementNumDistributions(r, start, 1)
+}
+
+func newCPUMeasurementNumDistributions(r *common.Rand, start time.Time, numDistributions int) *CPUMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, cpuFields[:numDistributions])
+	return &CPUMeasurement{sub}
+}
+
+func (m *CPUMeasurement) ToPoint(p *serialize.Point) {
+	m.toPointAllInt64(p, labelCPU, cpuFields)
+}
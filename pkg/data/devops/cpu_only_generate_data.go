@@ -0,0 +1,105 @@
+package devops
+
+import (
+	"context"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// A CPUOnlySimulator generates data similar to telemetry from Telegraf for only CPU metrics.
+// It fulfills the Simulator interface.
+type CPUOnlySimulator struct {
+	*commonDevopsSimulator
+}
+
+// Fields returns the Schema of subsystems to metrics collected, computing it
+// on the first call
+func (d *CPUOnlySimulator) Fields() *serialize.Schema {
+	if d.schema == nil {
+		d.schema = measurementsSchema(d.hosts[0].SimulatedMeasurements[:1])
+	}
+	return d.schema
+}
+
+// Next advances a Point to the next state in the generator.
+func (d *CPUOnlySimulator) Next(ctx context.Context, p *serialize.Point) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return d.next(p)
+}
+
+func (d *CPUOnlySimulator) next(p *serialize.Point) bool {
+	if d.hostIndex == d.hostEnd {
+		d.hostIndex = d.hostStart
+		d.tickHosts()
+		d.adjustNumHostsForEpoch()
+	}
+
+	return d.populatePoint(p, 0)
+}
+
+// NextBatch fills points with the next points to be written, returning how
+// many were filled. A batch never spans more than one epoch.
+func (d *CPUOnlySimulator) NextBatch(ctx context.Context, points []serialize.Point) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	return d.nextBatch(points, d.next, d.epochDone)
+}
+
+// Split partitions the hosts of d into n CPUOnlySimulators that can be run
+// independently, e.g., in separate goroutines.
+func (d *CPUOnlySimulator) Split(n int) []common.Simulator {
+	subs := d.split(n)
+	ret := make([]common.Simulator, len(subs))
+	for i, sub := range subs {
+		ret[i] = &CPUOnlySimulator{sub}
+	}
+	return ret
+}
+
+// epochDone returns whether the next call to Next will start a new epoch
+func (d *CPUOnlySimulator) epochDone() bool {
+	return d.hostIndex == d.hostEnd
+}
+
+// CPUOnlySimulatorConfig is used to create a CPUOnlySimulator.
+type CPUOnlySimulatorConfig commonDevopsSimulatorConfig
+
+// Fields returns the Schema of the points simulated by a CPUOnlySimulator
+// made from c, without making the simulator. It makes a single Host with
+// HostConstructor, drawing from an RNG of its own rather than RNG.
+func (c *CPUOnlySimulatorConfig) Fields() *serialize.Schema {
+	return measurementsSchema(schemaHost(commonDevopsSimulatorConfig(*c)).SimulatedMeasurements[:1])
+}
+
+// ToSimulator produces a Simulator that conforms to the given SimulatorConfig over the specified interval.
+// It advances RNG, so each call simulates different Hosts.
+func (c *CPUOnlySimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	hostInfos := makeHosts(commonDevopsSimulatorConfig(*c))
+
+	epochs := calculateEpochs(commonDevopsSimulatorConfig(*c), interval)
+	maxPoints := epochs * c.HostCount
+	sim := &CPUOnlySimulator{&commonDevopsSimulator{
+		madePoints: 0,
+		maxPoints:  maxPoints,
+
+		hostIndex: 0,
+		hosts:     hostInfos,
+		hostStart: 0,
+		hostEnd:   c.HostCount,
+
+		epoch:          0,
+		epochs:         epochs,
+		epochHosts:     c.InitHostCount,
+		initHosts:      c.InitHostCount,
+		timestampStart: c.Start,
+		timestampEnd:   c.End,
+		interval:       interval,
+	}}
+
+	return sim
+}
@@ -1,10 +1,11 @@
 package devops
 
 import (
+	"context"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize"
 )
 
 var (
@@ -21,10 +22,10 @@ var (
 func TestCPUOnlySimulatorFields(t *testing.T) {
 	s := testCPUOnlyConf.ToSimulator(time.Second).(*CPUOnlySimulator)
 	fields := s.Fields()
-	if got := len(fields); got != 1 {
+	if got := fields.Len(); got != 1 {
 		t.Errorf("fields length does not equal 1: got %d", got)
 	}
-	if got, ok := fields[string(labelCPU)]; ok {
+	if got := fields.FieldKeys(string(labelCPU)); got != nil {
 		if got2 := len(got); got2 <= 0 {
 			t.Errorf("number of fields is non-positive: got %d", got2)
 		}
@@ -40,11 +41,13 @@ func TestCPUOnlySimulatorFields(t *testing.T) {
 			}
 		}()
 		s.hosts = s.hosts[:0]
+		s.schema = nil
 		_ = s.Fields()
 	}()
 }
 
 func TestCPUOnlySimulatorNext(t *testing.T) {
+	ctx := context.Background()
 	s := testCPUOnlyConf.ToSimulator(time.Second).(*CPUOnlySimulator)
 	// There are two epochs for the test configuration, and a difference of 90
 	// from init to final, so each epoch should add 45 devices to be written.
@@ -53,7 +56,7 @@ func TestCPUOnlySimulatorNext(t *testing.T) {
 
 	runFn := func(run int) {
 		for i := 0; i < 100; i++ {
-			write := s.Next(p)
+			write := s.Next(ctx, p)
 			if got := int(s.hostIndex); got != i+1 {
 				t.Errorf("run %d: host index incorrect, i = %d: got %d want %d", run, i, got, i+1)
 			}
@@ -77,6 +80,36 @@ func TestCPUOnlySimulatorNext(t *testing.T) {
 	runFn(3)
 }
 
+func TestCPUOnlySimulatorNextBatch(t *testing.T) {
+	ctx := context.Background()
+	s := testCPUOnlyConf.ToSimulator(time.Second).(*CPUOnlySimulator)
+	// Batches should stop at the end of each epoch, so each batch should have
+	// the number of hosts written for that epoch.
+	want := []int{10, 55, 100}
+	points := make([]serialize.Point, 1000)
+	for i, w := range want {
+		if got := s.NextBatch(ctx, points); got != w {
+			t.Errorf("batch %d: incorrect number of points: got %d want %d", i, got, w)
+		}
+	}
+	if !s.Finished() {
+		t.Errorf("simulator not finished after all epochs")
+	}
+	if got := s.NextBatch(ctx, points); got != 0 {
+		t.Errorf("finished simulator returned points: got %d", got)
+	}
+
+	// Smaller batches should not exceed their size
+	s = testCPUOnlyConf.ToSimulator(time.Second).(*CPUOnlySimulator)
+	points = points[:4]
+	want = []int{4, 4, 2}
+	for i, w := range want {
+		if got := s.NextBatch(ctx, points); got != w {
+			t.Errorf("small batch %d: incorrect number of points: got %d want %d", i, got, w)
+		}
+	}
+}
+
 func TestCPUOnlySimulatorConfigToSimulator(t *testing.T) {
 	duration := time.Second
 	start := time.Now()
@@ -1,16 +1,20 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestCPUMeasurementTick(t *testing.T) {
+	// use a seeded Rand so the starting values are deterministic too;
+	// otherwise a field starting near 0 can be clamped there by the first
+	// steps
 	now := time.Now()
-	m := NewCPUMeasurement(now)
+	m := NewCPUMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(cpuFields)
@@ -18,7 +22,6 @@ func TestCPUMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.label)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -33,7 +36,7 @@ func TestCPUMeasurementTick(t *testing.T) {
 
 func TestCPUMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewCPUMeasurement(now)
+	m := NewCPUMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	m.Tick(duration)
 
@@ -52,7 +55,7 @@ func TestCPUMeasurementToPoint(t *testing.T) {
 
 func TestSingleCPUMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := newSingleCPUMeasurement(now)
+	m := newSingleCPUMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(cpuFields[:1]) // only the first field in this use case
@@ -63,7 +66,6 @@ func TestSingleCPUMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -78,7 +80,7 @@ func TestSingleCPUMeasurementTick(t *testing.T) {
 
 func TestSingleCPUMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := newSingleCPUMeasurement(now)
+	m := newSingleCPUMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	fields := cpuFields[:1] // only the first field in this use case
 	m.Tick(duration)
@@ -2,11 +2,10 @@ package devops
 
 import (
 	"fmt"
-	"math/rand"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
 )
 
 const (
@@ -55,10 +54,10 @@ type DiskMeasurement struct {
 	uptime       time.Duration
 }
 
-func NewDiskMeasurement(start time.Time) *DiskMeasurement {
-	path := []byte(fmt.Sprintf(pathFmt, rand.Intn(10)))
-	fsType := randomByteStringSliceChoice(diskFSTypeChoices)
-	sub := newSubsystemMeasurement(start, 1)
+func NewDiskMeasurement(r *common.Rand, start time.Time) *DiskMeasurement {
+	path := []byte(fmt.Sprintf(pathFmt, r.Intn(10)))
+	fsType := randomByteStringSliceChoice(r, diskFSTypeChoices)
+	sub := newSubsystemMeasurement(r, start, 1)
 	sub.distributions[0] = common.CWD(common.ND(50, 1), 0, oneTerabyte, oneTerabyte/2)
 
 	return &DiskMeasurement{
@@ -70,7 +69,7 @@ func NewDiskMeasurement(start time.Time) *DiskMeasurement {
 
 func (m *DiskMeasurement) ToPoint(p *serialize.Point) {
 	p.SetMeasurementName(labelDisk)
-	p.SetTimestamp(&m.timestamp)
+	p.SetTimestamp(m.timestamp)
 
 	p.AppendTag(labelDiskPath, m.path)
 	p.AppendTag(labelDiskFSType, m.fsType)
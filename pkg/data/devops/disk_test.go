@@ -1,16 +1,17 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestDiskMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewDiskMeasurement(now)
+	m := NewDiskMeasurement(common.NewRand(rng.New(123)), now)
 	origPath := string(m.path)
 	origFS := string(m.fsType)
 	duration := time.Second
@@ -20,7 +21,6 @@ func TestDiskMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -48,7 +48,7 @@ func TestDiskMeasurementTick(t *testing.T) {
 
 func TestDiskMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiskMeasurement(now)
+	m := NewDiskMeasurement(common.NewRand(rng.New(123)), now)
 	origPath := string(m.path)
 	origFS := string(m.fsType)
 	testIfInByteStringSlice(t, diskFSTypeChoices, m.fsType)
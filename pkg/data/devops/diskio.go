@@ -0,0 +1,44 @@
+package devops
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelDiskIO       = []byte("diskio") // heap optimization
+	labelDiskIOSerial = []byte("serial")
+
+	diskIOFields = []labeledDistributionMaker{
+		{[]byte("reads"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("writes"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("read_bytes"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(100, 1), 0) }},
+		{[]byte("write_bytes"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(100, 1), 0) }},
+		{[]byte("read_time"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("write_time"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("io_time"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+	}
+)
+
+type DiskIOMeasurement struct {
+	*subsystemMeasurement
+	serial []byte
+}
+
+func NewDiskIOMeasurement(r *common.Rand, start time.Time) *DiskIOMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, diskIOFields)
+	serial := []byte(fmt.Sprintf("%03d-%03d-%03d", r.Intn(1000), r.Intn(1000), r.Intn(1000)))
+	return &DiskIOMeasurement{
+		subsystemMeasurement: sub,
+		serial:               serial,
+	}
+}
+
+func (m *DiskIOMeasurement) ToPoint(p *serialize.Point) {
+	m.toPointAllInt64(p, labelDiskIO, diskIOFields)
+	p.AppendTag(labelDiskIOSerial, m.serial)
+}
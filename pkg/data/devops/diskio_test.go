@@ -1,16 +1,17 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestDiskIOMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewDiskIOMeasurement(now)
+	m := NewDiskIOMeasurement(common.NewRand(rng.New(123)), now)
 	origSerial := string(m.serial)
 	duration := time.Second
 	oldVals := map[string]float64{}
@@ -19,7 +20,6 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.label)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -40,7 +40,7 @@ func TestDiskIOMeasurementTick(t *testing.T) {
 
 func TestDiskIOMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewDiskIOMeasurement(now)
+	m := NewDiskIOMeasurement(common.NewRand(rng.New(123)), now)
 	origSerial := string(m.serial)
 	duration := time.Second
 	m.Tick(duration)
@@ -0,0 +1,110 @@
+package devops
+
+import (
+	"context"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// DevopsSimulator generates data similar to telemetry, with metrics from a variety of device systems.
+// It fulfills the Simulator interface.
+type DevopsSimulator struct {
+	*commonDevopsSimulator
+	simulatedMeasurementIndex int
+}
+
+// Next advances a Point to the next state in the generator.
+func (d *DevopsSimulator) Next(ctx context.Context, p *serialize.Point) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return d.next(p)
+}
+
+func (d *DevopsSimulator) next(p *serialize.Point) bool {
+	// switch to the next metric if needed
+	if d.hostIndex == d.hostEnd {
+		d.hostIndex = d.hostStart
+		d.simulatedMeasurementIndex++
+	}
+
+	if d.simulatedMeasurementIndex == len(d.hosts[0].SimulatedMeasurements) {
+		d.simulatedMeasurementIndex = 0
+		d.tickHosts()
+		d.adjustNumHostsForEpoch()
+	}
+
+	return d.populatePoint(p, d.simulatedMeasurementIndex)
+}
+
+// NextBatch fills points with the next points to be written, returning how
+// many were filled. A batch never spans more than one epoch.
+func (d *DevopsSimulator) NextBatch(ctx context.Context, points []serialize.Point) int {
+	if ctx.Err() != nil {
+		return 0
+	}
+	return d.nextBatch(points, d.next, d.epochDone)
+}
+
+// Split partitions the hosts of d into n DevopsSimulators that can be run
+// independently, e.g., in separate goroutines.
+func (d *DevopsSimulator) Split(n int) []common.Simulator {
+	subs := d.split(n)
+	ret := make([]common.Simulator, len(subs))
+	for i, sub := range subs {
+		ret[i] = &DevopsSimulator{
+			commonDevopsSimulator:     sub,
+			simulatedMeasurementIndex: d.simulatedMeasurementIndex,
+		}
+	}
+	return ret
+}
+
+// epochDone returns whether the next call to Next will start a new epoch
+func (d *DevopsSimulator) epochDone() bool {
+	return d.hostIndex == d.hostEnd &&
+		d.simulatedMeasurementIndex == len(d.hosts[0].SimulatedMeasurements)-1
+}
+
+// DevopsSimulatorConfig is used to create a DevopsSimulator.
+type DevopsSimulatorConfig commonDevopsSimulatorConfig
+
+// Fields returns the Schema of the points simulated by a DevopsSimulator
+// made from d, without making the simulator. It makes a single Host with
+// HostConstructor, drawing from an RNG of its own rather than RNG.
+func (d *DevopsSimulatorConfig) Fields() *serialize.Schema {
+	return measurementsSchema(schemaHost(commonDevopsSimulatorConfig(*d)).SimulatedMeasurements)
+}
+
+// ToSimulator produces a Simulator that conforms to the given SimulatorConfig over the specified interval.
+// It advances RNG, so each call simulates different Hosts.
+func (d *DevopsSimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	hostInfos := makeHosts(commonDevopsSimulatorConfig(*d))
+
+	epochs := calculateEpochs(commonDevopsSimulatorConfig(*d), interval)
+	maxPoints := epochs * d.HostCount * uint64(len(hostInfos[0].SimulatedMeasurements))
+	dg := &DevopsSimulator{
+		commonDevopsSimulator: &commonDevopsSimulator{
+			madePoints: 0,
+			maxPoints:  maxPoints,
+
+			hostIndex: 0,
+			hosts:     hostInfos,
+			hostStart: 0,
+			hostEnd:   d.HostCount,
+
+			epoch:          0,
+			epochs:         epochs,
+			epochHosts:     d.InitHostCount,
+			initHosts:      d.InitHostCount,
+			timestampStart: d.Start,
+			timestampEnd:   d.End,
+			interval:       interval,
+		},
+		simulatedMeasurementIndex: 0,
+	}
+
+	return dg
+}
@@ -0,0 +1,214 @@
+package devops
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+const testDevopsHostCount = 100
+
+var testDevopsConf = &DevopsSimulatorConfig{
+	Start:           testTime,
+	End:             testTime.Add(3 * time.Second),
+	InitHostCount:   10,
+	HostCount:       testDevopsHostCount,
+	HostConstructor: NewHost,
+}
+
+func TestDevopsSimulatorNext(t *testing.T) {
+	ctx := context.Background()
+	s := testDevopsConf.ToSimulator(time.Second).(*DevopsSimulator)
+	// There are two epochs for the test configuration, and a difference of 90
+	// from init to final, so each epoch should add 45 devices to be written.
+	writtenIdx := []int{10, 55, 100}
+	p := serialize.NewPoint()
+	totalPerRun := testDevopsHostCount * 9 // 9 subsystems per device
+
+	runFn := func(run int) {
+		for i := 0; i < totalPerRun; i++ {
+			write := s.Next(ctx, p)
+			hostIdx := i % testDevopsHostCount
+			if got := int(s.hostIndex); got != hostIdx+1 {
+				t.Errorf("run %d: host index incorrect, i = %d: got %d want %d", run, i, got, i+1)
+			}
+			if hostIdx < writtenIdx[run-1] && !write {
+				t.Errorf("run %d: should write point at i = %d, but not", run, i)
+			} else if hostIdx >= writtenIdx[run-1] && write {
+				t.Errorf("run %d: should not write point at i = %d, but am", run, i)
+			}
+
+			if got := int(s.epoch); got != run-1 {
+				t.Errorf("run %d: epoch prematurely turned over", run)
+			}
+		}
+	}
+
+	// First run through:
+	runFn(1)
+	// Second run through, should wrap around and do hosts again
+	runFn(2)
+	// Final run through, should be all hosts:
+	runFn(3)
+}
+
+func TestDevopsSimulatorNextBatch(t *testing.T) {
+	ctx := context.Background()
+	s := testDevopsConf.ToSimulator(time.Second).(*DevopsSimulator)
+	// Batches should stop at the end of each epoch, which covers all 9
+	// subsystems for every host written that epoch.
+	want := []int{10 * 9, 55 * 9, 100 * 9}
+	points := make([]serialize.Point, 10000)
+	for i, w := range want {
+		if got := s.NextBatch(ctx, points); got != w {
+			t.Errorf("batch %d: incorrect number of points: got %d want %d", i, got, w)
+		}
+	}
+	if !s.Finished() {
+		t.Errorf("simulator not finished after all epochs")
+	}
+}
+
+func TestDevopsSimulatorCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := testDevopsConf.ToSimulator(time.Second).(*DevopsSimulator)
+	points := make([]serialize.Point, 10)
+	if got := s.NextBatch(ctx, points); got != len(points) {
+		t.Fatalf("incorrect number of points before cancel: got %d want %d", got, len(points))
+	}
+	cancel()
+	hostIndex := s.hostIndex
+	if got := s.NextBatch(ctx, points); got != 0 {
+		t.Errorf("cancelled simulator returned points: got %d", got)
+	}
+	if s.Next(ctx, &points[0]) {
+		t.Errorf("cancelled simulator returned a point to write")
+	}
+	if s.hostIndex != hostIndex {
+		t.Errorf("cancelled simulator advanced: got host index %d want %d", s.hostIndex, hostIndex)
+	}
+}
+
+// pointString describes the contents of p, to compare the points of
+// different simulators
+func pointString(p *serialize.Point) string {
+	return fmt.Sprintf("%s %s %v %d", p.MeasurementName(), p.TagValues(), p.FieldValues(), p.Timestamp())
+}
+
+func TestDevopsSimulatorSplit(t *testing.T) {
+	ctx := context.Background()
+	want := map[string][]string{}
+	s := testDevopsConf.ToSimulator(time.Second)
+	p := serialize.NewPoint()
+	for !s.Finished() {
+		if s.Next(ctx, p) {
+			host := string(p.GetTagValue(MachineTagKeys[0]))
+			want[host] = append(want[host], pointString(p))
+		}
+		p.Reset()
+	}
+
+	// Run the sub-simulators concurrently and make sure together they write
+	// the same points for each host, since each host has its own Rand.
+	subs := testDevopsConf.ToSimulator(time.Second).Split(3)
+	if got := len(subs); got != 3 {
+		t.Fatalf("incorrect number of sub-simulators: got %d want %d", got, 3)
+	}
+	written := make([]map[string][]string, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func(i int, sub common.Simulator) {
+			written[i] = map[string][]string{}
+			points := make([]serialize.Point, 100)
+			for !sub.Finished() {
+				n := sub.NextBatch(ctx, points)
+				for j := 0; j < n; j++ {
+					host := string(points[j].GetTagValue(MachineTagKeys[0]))
+					written[i][host] = append(written[i][host], pointString(&points[j]))
+				}
+			}
+			wg.Done()
+		}(i, sub)
+	}
+	wg.Wait()
+
+	got := map[string][]string{}
+	for i, w := range written {
+		for host, points := range w {
+			if _, ok := got[host]; ok {
+				t.Errorf("host %s written by more than one sub-simulator (incl. %d)", host, i)
+			}
+			got[host] = points
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("incorrect number of hosts written: got %d want %d", len(got), len(want))
+	}
+	for host, points := range want {
+		if len(got[host]) != len(points) {
+			t.Errorf("incorrect number of points for %s: got %d want %d", host, len(got[host]), len(points))
+			continue
+		}
+		for i := range points {
+			if got[host][i] != points[i] {
+				t.Errorf("point %d of %s differs from the unsplit simulator:\ngot  %s\nwant %s", i, host, got[host][i], points[i])
+				break
+			}
+		}
+	}
+}
+
+func TestDevopsSimulatorConfigToSimulator(t *testing.T) {
+	duration := time.Second
+	start := time.Now()
+	end := start.Add(10 * time.Second)
+	numHosts := uint64(100)
+	initHosts := uint64(0)
+	conf := &DevopsSimulatorConfig{
+		Start:           start,
+		End:             end,
+		InitHostCount:   initHosts,
+		HostCount:       numHosts,
+		HostConstructor: NewHost,
+	}
+	sim := conf.ToSimulator(duration).(*DevopsSimulator)
+	if got := sim.madePoints; got != 0 {
+		t.Errorf("incorrect initial points: got %d want %d", got, 0)
+	}
+	if got := sim.epoch; got != 0 {
+		t.Errorf("incorrect initial epoch: got %d want %d", got, 0)
+	}
+	if got := sim.hostIndex; got != 0 {
+		t.Errorf("incorrect initial host index: got %d want %d", got, 0)
+	}
+	if got := sim.simulatedMeasurementIndex; got != 0 {
+		t.Errorf("incorrect simulated measurement index: got %d want %d", got, 0)
+	}
+	if got := sim.epochHosts; got != initHosts {
+		t.Errorf("incorrect initial epoch hosts: got %d want %d", got, initHosts)
+	}
+	if got := sim.initHosts; got != initHosts {
+		t.Errorf("incorrect initial init hosts: got %d want %d", got, initHosts)
+	}
+	if got := sim.timestampStart; got != start {
+		t.Errorf("incorrect start time: got %v want %v", got, start)
+	}
+	if got := sim.timestampEnd; got != end {
+		t.Errorf("incorrect end time: got %v want %v", got, end)
+	}
+	wantEpochs := uint64(10) // 10 seconds between start & end, interval is 1s, so 10 / 1 = 10
+	if got := sim.epochs; got != wantEpochs {
+		t.Errorf("incorrect epochs: got %d want %d", got, wantEpochs)
+	}
+	wantMaxPoints := wantEpochs * numHosts * 9 // 9 measurements per host
+	if got := sim.maxPoints; got != wantMaxPoints {
+		t.Errorf("incorrect max points: got %d want %d", got, wantMaxPoints)
+	}
+
+}
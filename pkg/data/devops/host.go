@@ -0,0 +1,268 @@
+package devops
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// Count of choices for auto-generated tag values:
+const (
+	machineRackChoicesPerDatacenter = 100
+	machineServiceChoices           = 20
+	machineServiceVersionChoices    = 2
+	hostFmt                         = "host_%d"
+)
+
+type region struct {
+	Name        []byte
+	Datacenters [][]byte
+}
+
+var regions = []region{
+	{
+		[]byte("us-east-1"),
+		[][]byte{
+			[]byte("us-east-1a"),
+			[]byte("us-east-1b"),
+			[]byte("us-east-1c"),
+			[]byte("us-east-1e"),
+		},
+	},
+	{
+		[]byte("us-west-1"),
+		[][]byte{
+			[]byte("us-west-1a"),
+			[]byte("us-west-1b"),
+		},
+	},
+	{
+		[]byte("us-west-2"),
+		[][]byte{
+			[]byte("us-west-2a"),
+			[]byte("us-west-2b"),
+			[]byte("us-west-2c"),
+		},
+	},
+	{
+		[]byte("eu-west-1"),
+		[][]byte{
+			[]byte("eu-west-1a"),
+			[]byte("eu-west-1b"),
+			[]byte("eu-west-1c"),
+		},
+	},
+	{
+		[]byte("eu-central-1"),
+		[][]byte{
+			[]byte("eu-central-1a"),
+			[]byte("eu-central-1b"),
+		},
+	},
+	{
+		[]byte("ap-southeast-1"),
+		[][]byte{
+			[]byte("ap-southeast-1a"),
+			[]byte("ap-southeast-1b"),
+		},
+	},
+	{
+		[]byte("ap-southeast-2"),
+		[][]byte{
+			[]byte("ap-southeast-2a"),
+			[]byte("ap-southeast-2b"),
+		},
+	},
+	{
+		[]byte("ap-northeast-1"),
+		[][]byte{
+			[]byte("ap-northeast-1a"),
+			[]byte("ap-northeast-1c"),
+		},
+	},
+	{
+		[]byte("sa-east-1"),
+		[][]byte{
+			[]byte("sa-east-1a"),
+			[]byte("sa-east-1b"),
+			[]byte("sa-east-1c"),
+		},
+	},
+}
+
+var (
+	MachineTeamChoices = [][]byte{
+		[]byte("SF"),
+		[]byte("NYC"),
+		[]byte("LON"),
+		[]byte("CHI"),
+	}
+	MachineOSChoices = [][]byte{
+		[]byte("Ubuntu16.10"),
+		[]byte("Ubuntu16.04LTS"),
+		[]byte("Ubuntu15.10"),
+	}
+	MachineArchChoices = [][]byte{
+		[]byte("x64"),
+		[]byte("x86"),
+	}
+	MachineServiceEnvironmentChoices = [][]byte{
+		[]byte("production"),
+		[]byte("staging"),
+		[]byte("test"),
+	}
+
+	// MachineTagKeys fields common to all hosts:
+	MachineTagKeys = [][]byte{
+		[]byte("hostname"),
+		[]byte("region"),
+		[]byte("datacenter"),
+		[]byte("rack"),
+		[]byte("os"),
+		[]byte("arch"),
+		[]byte("team"),
+		[]byte("service"),
+		[]byte("service_version"),
+		[]byte("service_environment"),
+	}
+)
+
+// Host models a machine being monitored for dev ops
+type Host struct {
+	SimulatedMeasurements []common.SimulatedMeasurement
+
+	// These are all assigned once, at Host creation:
+	Name, Region, Datacenter, Rack, OS, Arch          []byte
+	Team, Service, ServiceVersion, ServiceEnvironment []byte
+}
+
+// HostConstructor creates the Host with index i, whose measurements start at
+// start, drawing all of its randomness from r. Simulators call it once per
+// Host, in order of i, each with its own r.
+type HostConstructor func(r *common.Rand, i int, start time.Time) Host
+
+// MeasurementsMaker makes the measurements simulated for a Host, starting at
+// start and drawing all of their randomness from r. Each call must return new
+// measurements of the same kinds in the same order.
+type MeasurementsMaker func(r *common.Rand, start time.Time) []common.SimulatedMeasurement
+
+// NewHostConstructor returns a HostConstructor for Hosts with the usual tags
+// simulating the measurements made by m, e.g., to simulate an additional
+// measurement along with those of the devops use case:
+//
+//	devops.NewHostConstructor(func(r *common.Rand, start time.Time) []common.SimulatedMeasurement {
+//		return append(devops.DevopsMeasurements(r, start), newGPUMeasurement(r, start))
+//	})
+func NewHostConstructor(m MeasurementsMaker) HostConstructor {
+	return func(r *common.Rand, i int, start time.Time) Host {
+		return newHostWithMeasurementGenerator(r, i, start, m)
+	}
+}
+
+// DevopsMeasurements makes the measurements of a Host in the devops use case
+func DevopsMeasurements(r *common.Rand, start time.Time) []common.SimulatedMeasurement {
+	return []common.SimulatedMeasurement{
+		NewCPUMeasurement(r, start),
+		NewDiskIOMeasurement(r, start),
+		NewDiskMeasurement(r, start),
+		NewKernelMeasurement(r, start),
+		NewMemMeasurement(r, start),
+		NewNetMeasurement(r, start),
+		NewNginxMeasurement(r, start),
+		NewPostgresqlMeasurement(r, start),
+		NewRedisMeasurement(r, start),
+	}
+}
+
+// CPUOnlyMeasurements makes the measurements of a Host in the cpu-only use
+// case
+func CPUOnlyMeasurements(r *common.Rand, start time.Time) []common.SimulatedMeasurement {
+	return []common.SimulatedMeasurement{
+		NewCPUMeasurement(r, start),
+	}
+}
+
+// CPUSingleMeasurements makes the measurements of a Host in the cpu-single
+// use case
+func CPUSingleMeasurements(r *common.Rand, start time.Time) []common.SimulatedMeasurement {
+	return []common.SimulatedMeasurement{
+		newSingleCPUMeasurement(r, start),
+	}
+}
+
+// NewHost creates a new host in a simulated devops use case
+func NewHost(r *common.Rand, i int, start time.Time) Host {
+	return newHostWithMeasurementGenerator(r, i, start, DevopsMeasurements)
+}
+
+// NewHostCPUOnly creates a new host in a simulated cpu-only use case, which is a subset of a devops case
+// with only CPU metrics simulated
+func NewHostCPUOnly(r *common.Rand, i int, start time.Time) Host {
+	return newHostWithMeasurementGenerator(r, i, start, CPUOnlyMeasurements)
+}
+
+// NewHostCPUSingle creates a new host in a simulated cpu-single use case, which is a subset of a devops case
+// with only a single CPU metric is simulated
+func NewHostCPUSingle(r *common.Rand, i int, start time.Time) Host {
+	return newHostWithMeasurementGenerator(r, i, start, CPUSingleMeasurements)
+}
+
+func newHostWithMeasurementGenerator(r *common.Rand, i int, start time.Time, generator MeasurementsMaker) Host {
+	sm := generator(r, start)
+
+	region := randomRegionSliceChoice(r, regions)
+
+	h := Host{
+		// Tag Values that are static throughout the life of a Host:
+		Name:               []byte(fmt.Sprintf(hostFmt, i)),
+		Region:             region.Name,
+		Datacenter:         randomByteStringSliceChoice(r, region.Datacenters),
+		Rack:               getByteStringRandomInt(r, machineRackChoicesPerDatacenter),
+		Arch:               randomByteStringSliceChoice(r, MachineArchChoices),
+		OS:                 randomByteStringSliceChoice(r, MachineOSChoices),
+		Service:            getByteStringRandomInt(r, machineServiceChoices),
+		ServiceVersion:     getByteStringRandomInt(r, machineServiceVersionChoices),
+		ServiceEnvironment: randomByteStringSliceChoice(r, MachineServiceEnvironmentChoices),
+		Team:               randomByteStringSliceChoice(r, MachineTeamChoices),
+
+		SimulatedMeasurements: sm,
+	}
+
+	return h
+}
+
+// TickAll advances all Distributions of a Host.
+func (h *Host) TickAll(d time.Duration) {
+	for i := range h.SimulatedMeasurements {
+		h.SimulatedMeasurements[i].Tick(d)
+	}
+}
+
+// tags returns pointers to the tag values of h, in the order of
+// MachineTagKeys
+func (h *Host) tags() []*[]byte {
+	return []*[]byte{
+		&h.Name, &h.Region, &h.Datacenter, &h.Rack, &h.OS,
+		&h.Arch, &h.Team, &h.Service, &h.ServiceVersion, &h.ServiceEnvironment,
+	}
+}
+
+// intern replaces the tag values of h with their copies in values, so the
+// Hosts of a simulator share one copy of each value
+func (h *Host) intern(values *common.Interner) {
+	for _, v := range h.tags() {
+		*v = values.Intern(*v)
+	}
+}
+
+// getByteStringRandomInt returns a random int in [0, limit) as a byte string
+func getByteStringRandomInt(r rng.RNG, limit int64) []byte {
+	return strconv.AppendInt(nil, r.Int63n(limit), 10)
+}
+
+func randomRegionSliceChoice(r rng.RNG, s []region) *region {
+	return &s[r.Intn(len(s))]
+}
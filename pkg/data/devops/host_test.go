@@ -7,67 +7,68 @@ import (
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestNewHostMeasurements(t *testing.T) {
 	start := time.Now()
-	measurements := newHostMeasurements(start)
+	measurements := DevopsMeasurements(common.NewRand(rng.New(123)), start)
 	if got := len(measurements); got != 9 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
 	// Cast each measurement to its type; will panic if wrong types
 	cpu := measurements[0].(*CPUMeasurement)
-	if got := cpu.timestamp; got != start {
+	if got := cpu.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect CPU measurement timestamp: got %v want %v", got, start)
 	}
 	if got := len(cpu.distributions); got <= 1 {
 		t.Errorf("too few CPU measurements: got %d", got)
 	}
 	diskio := measurements[1].(*DiskIOMeasurement)
-	if got := diskio.timestamp; got != start {
+	if got := diskio.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect diskio measurement timestamp: got %v want %v", got, start)
 	}
 	disk := measurements[2].(*DiskMeasurement)
-	if got := disk.timestamp; got != start {
+	if got := disk.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect disk measurement timestamp: got %v want %v", got, start)
 	}
 	kernel := measurements[3].(*KernelMeasurement)
-	if got := kernel.timestamp; got != start {
+	if got := kernel.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect kernel measurement timestamp: got %v want %v", got, start)
 	}
 	mem := measurements[4].(*MemMeasurement)
-	if got := mem.timestamp; got != start {
+	if got := mem.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect mem measurement timestamp: got %v want %v", got, start)
 	}
 	net := measurements[5].(*NetMeasurement)
-	if got := net.timestamp; got != start {
+	if got := net.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect net measurement timestamp: got %v want %v", got, start)
 	}
 	nginx := measurements[6].(*NginxMeasurement)
-	if got := nginx.timestamp; got != start {
+	if got := nginx.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect nginx measurement timestamp: got %v want %v", got, start)
 	}
 	postgresql := measurements[7].(*PostgresqlMeasurement)
-	if got := postgresql.timestamp; got != start {
+	if got := postgresql.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect postgresql measurement timestamp: got %v want %v", got, start)
 	}
 	redis := measurements[8].(*RedisMeasurement)
-	if got := redis.timestamp; got != start {
+	if got := redis.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect redis measurement timestamp: got %v want %v", got, start)
 	}
 }
 
 func TestNewCPUOnlyHostMeasurements(t *testing.T) {
 	start := time.Now()
-	measurements := newCPUOnlyHostMeasurements(start)
+	measurements := CPUOnlyMeasurements(common.NewRand(rng.New(123)), start)
 	if got := len(measurements); got != 1 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
 	// Cast each measurement to its type; will panic if wrong types
 	cpu := measurements[0].(*CPUMeasurement)
-	if got := cpu.timestamp; got != start {
+	if got := cpu.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect CPU measurement timestamp: got %v want %v", got, start)
 	}
 	if got := len(cpu.distributions); got <= 1 {
@@ -77,13 +78,13 @@ func TestNewCPUOnlyHostMeasurements(t *testing.T) {
 
 func TestNewCPUSingleHostMeasurements(t *testing.T) {
 	start := time.Now()
-	measurements := newCPUSingleHostMeasurements(start)
+	measurements := CPUSingleMeasurements(common.NewRand(rng.New(123)), start)
 	if got := len(measurements); got != 1 {
 		t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 	}
 	// Cast each measurement to its type; will panic if wrong types
 	cpu := measurements[0].(*CPUMeasurement)
-	if got := cpu.timestamp; got != start {
+	if got := cpu.timestamp; got != start.UnixNano() {
 		t.Errorf("incorrect CPU measurement timestamp: got %v want %v", got, start)
 	}
 	if got := len(cpu.distributions); got != 1 {
@@ -92,10 +93,11 @@ func TestNewCPUSingleHostMeasurements(t *testing.T) {
 }
 
 func TestNewHost(t *testing.T) {
+	r := common.NewRand(rng.New(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHost(i, now)
+		h := NewHost(r, i, now)
 		if got := len(h.SimulatedMeasurements); got != 9 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -105,7 +107,7 @@ func TestNewHost(t *testing.T) {
 		}
 
 		cpu := h.SimulatedMeasurements[0].(*CPUMeasurement)
-		if got := cpu.timestamp; got != now {
+		if got := cpu.timestamp; got != now.UnixNano() {
 			t.Errorf("incorrect CPU measurement timestamp: got %v want %v", got, now)
 		}
 		if got := len(cpu.distributions); got <= 1 {
@@ -115,10 +117,11 @@ func TestNewHost(t *testing.T) {
 }
 
 func TestNewHostCPUOnly(t *testing.T) {
+	r := common.NewRand(rng.New(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostCPUOnly(i, now)
+		h := NewHostCPUOnly(r, i, now)
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -128,7 +131,7 @@ func TestNewHostCPUOnly(t *testing.T) {
 		}
 
 		cpu := h.SimulatedMeasurements[0].(*CPUMeasurement)
-		if got := cpu.timestamp; got != now {
+		if got := cpu.timestamp; got != now.UnixNano() {
 			t.Errorf("incorrect CPU measurement timestamp: got %v want %v", got, now)
 		}
 		if got := len(cpu.distributions); got <= 1 {
@@ -138,10 +141,11 @@ func TestNewHostCPUOnly(t *testing.T) {
 }
 
 func TestNewHostCPUSingle(t *testing.T) {
+	r := common.NewRand(rng.New(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := NewHostCPUSingle(i, now)
+		h := NewHostCPUSingle(r, i, now)
 		if got := len(h.SimulatedMeasurements); got != 1 {
 			t.Errorf("incorrect number of measurements: got %d want %d", got, 9)
 		}
@@ -151,7 +155,7 @@ func TestNewHostCPUSingle(t *testing.T) {
 		}
 
 		cpu := h.SimulatedMeasurements[0].(*CPUMeasurement)
-		if got := cpu.timestamp; got != now {
+		if got := cpu.timestamp; got != now.UnixNano() {
 			t.Errorf("incorrect CPU measurement timestamp: got %v want %v", got, now)
 		}
 		if got := len(cpu.distributions); got != 1 {
@@ -160,7 +164,7 @@ func TestNewHostCPUSingle(t *testing.T) {
 	}
 }
 
-func testGenerator(s time.Time) []common.SimulatedMeasurement {
+func testGenerator(_ *common.Rand, s time.Time) []common.SimulatedMeasurement {
 	return []common.SimulatedMeasurement{
 		&testMeasurement{ticks: 0},
 	}
@@ -186,10 +190,11 @@ func testStringNumberIsValid(t *testing.T, limit int64, s []byte) {
 }
 
 func TestNewHostWithMeasurementGenerator(t *testing.T) {
+	r := common.NewRand(rng.New(123))
 	now := time.Now()
 	// test 1000 times to get diversity of results
 	for i := 0; i < 1000; i++ {
-		h := newHostWithMeasurementGenerator(i, now, testGenerator)
+		h := newHostWithMeasurementGenerator(r, i, now, testGenerator)
 		wantName := fmt.Sprintf(hostFmt, i)
 		if got := string(h.Name); got != wantName {
 			t.Errorf("incorrect host name format: got %s want %s", got, wantName)
@@ -210,6 +215,34 @@ func TestNewHostWithMeasurementGenerator(t *testing.T) {
 	}
 }
 
+func TestNewHostConstructor(t *testing.T) {
+	gpu := func(r *common.Rand, start time.Time) common.SimulatedMeasurement {
+		return NewMeasurement(r, start, []byte("gpu"), []FieldDistribution{
+			{Name: []byte("usage"), Make: func(r rng.RNG) common.Distribution { return common.CWD(common.ND(0, 1), 0, 100, 100*r.Float64()) }},
+		})
+	}
+	hc := NewHostConstructor(func(r *common.Rand, start time.Time) []common.SimulatedMeasurement {
+		return append(CPUOnlyMeasurements(r, start), gpu(r, start))
+	})
+	r := common.NewRand(rng.New(123))
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		h := hc(r, i, now)
+		wantName := fmt.Sprintf(hostFmt, i)
+		if got := string(h.Name); got != wantName {
+			t.Errorf("incorrect host name format: got %s want %s", got, wantName)
+		}
+		if got := len(h.SimulatedMeasurements); got != 2 {
+			t.Fatalf("simulated measurements incorrect len: got %d want 2", got)
+		}
+		p := serialize.NewPoint()
+		h.SimulatedMeasurements[1].ToPoint(p)
+		if got := string(p.MeasurementName()); got != "gpu" {
+			t.Errorf("incorrect measurement name: got %s want gpu", got)
+		}
+	}
+}
+
 type testMeasurement struct {
 	ticks int
 }
@@ -219,7 +252,7 @@ func (m *testMeasurement) ToPoint(_ *serialize.Point) {}
 
 func TestHostTickAll(t *testing.T) {
 	now := time.Now()
-	h := newHostWithMeasurementGenerator(0, now, testGenerator)
+	h := newHostWithMeasurementGenerator(common.NewRand(rng.New(123)), 0, now, testGenerator)
 	if got := h.SimulatedMeasurements[0].(*testMeasurement).ticks; got != 0 {
 		t.Errorf("ticks not equal to 0 to start: got %d", got)
 	}
@@ -238,9 +271,10 @@ func TestHostTickAll(t *testing.T) {
 }
 
 func TestGetByteStringRandomInt(t *testing.T) {
+	r := rng.New(123)
 	limit := int64(100)
 	for i := 0; i < 1000000; i++ {
-		s := getByteStringRandomInt(limit)
+		s := getByteStringRandomInt(r, limit)
 		testStringNumberIsValid(t, limit, s)
 	}
 }
@@ -255,8 +289,9 @@ func testIfInRegionSlice(t *testing.T, arr []region, choice *region) {
 }
 
 func TestRandomRegionSliceChoice(t *testing.T) {
+	r := rng.New(123)
 	for i := 0; i < 1000000; i++ {
-		r := randomRegionSliceChoice(regions)
-		testIfInRegionSlice(t, regions, r)
+		choice := randomRegionSliceChoice(r, regions)
+		testIfInRegionSlice(t, regions, choice)
 	}
 }
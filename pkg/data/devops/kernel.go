@@ -0,0 +1,41 @@
+package devops
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelKernel         = []byte("kernel") // heap optimization
+	labelKernelBootTime = []byte("boot_time")
+
+	kernelFields = []labeledDistributionMaker{
+		{[]byte("interrupts"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("context_switches"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("processes_forked"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("disk_pages_in"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("disk_pages_out"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+	}
+)
+
+type KernelMeasurement struct {
+	*subsystemMeasurement
+	bootTime int64
+}
+
+func NewKernelMeasurement(r *common.Rand, start time.Time) *KernelMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, kernelFields)
+	bootTime := r.Int63n(240)
+	return &KernelMeasurement{
+		subsystemMeasurement: sub,
+		bootTime:             bootTime,
+	}
+}
+
+func (m *KernelMeasurement) ToPoint(p *serialize.Point) {
+	p.AppendField(labelKernelBootTime, m.bootTime)
+	m.toPointAllInt64(p, labelKernel, kernelFields)
+}
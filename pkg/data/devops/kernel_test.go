@@ -1,16 +1,17 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestKernelMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewKernelMeasurement(now)
+	m := NewKernelMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	bootTime := m.bootTime
 	oldVals := map[string]float64{}
@@ -19,7 +20,6 @@ func TestKernelMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.label)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -40,7 +40,7 @@ func TestKernelMeasurementTick(t *testing.T) {
 
 func TestKernelMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewKernelMeasurement(now)
+	m := NewKernelMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	bootTime := m.bootTime
 	m.Tick(duration)
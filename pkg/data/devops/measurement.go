@@ -0,0 +1,102 @@
+package devops
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+type subsystemMeasurement struct {
+	timestamp     int64 // nanoseconds since the Unix epoch
+	distributions []common.Distribution
+	// rand advances the distributions
+	rand *common.Rand
+}
+
+func newSubsystemMeasurement(r *common.Rand, start time.Time, numDistributions int) *subsystemMeasurement {
+	return &subsystemMeasurement{
+		timestamp:     start.UnixNano(),
+		distributions: make([]common.Distribution, numDistributions),
+		rand:          r,
+	}
+}
+
+func newSubsystemMeasurementWithDistributionMakers(r *common.Rand, start time.Time, makers []labeledDistributionMaker) *subsystemMeasurement {
+	m := newSubsystemMeasurement(r, start, len(makers))
+	for i := 0; i < len(makers); i++ {
+		m.distributions[i] = makers[i].distributionMaker(r)
+	}
+	return m
+}
+
+func (m *subsystemMeasurement) Tick(d time.Duration) {
+	m.timestamp += int64(d)
+	m.rand.AdvanceAll(m.distributions)
+}
+
+func (m *subsystemMeasurement) toPoint(p *serialize.Point, measurementName []byte, labels []labeledDistributionMaker) {
+	p.SetMeasurementName(measurementName)
+	p.SetTimestamp(m.timestamp)
+
+	for i, d := range m.distributions {
+		p.AppendField(labels[i].label, d.Get())
+	}
+}
+
+// toPointAllInt64 fills in a serialize.Point with a given measurementName and
+// all vales from the distributions stored as int64. The labels for each field
+// are given by the supplied []labeledDistributionMaker, assuming that the distributions
+// are in the same order.
+func (m *subsystemMeasurement) toPointAllInt64(p *serialize.Point, measurementName []byte, labels []labeledDistributionMaker) {
+	p.SetMeasurementName(measurementName)
+	p.SetTimestamp(m.timestamp)
+
+	for i, d := range m.distributions {
+		p.AppendField(labels[i].label, int64(d.Get()))
+	}
+}
+
+type labeledDistributionMaker struct {
+	label             []byte
+	distributionMaker func(rng.RNG) common.Distribution
+}
+
+// FieldDistribution describes a field of a measurement made by NewMeasurement
+type FieldDistribution struct {
+	// Name is the field key
+	Name []byte
+	// Make returns the Distribution the field's values are drawn from, with
+	// any random initial state drawn from r; it is called once per Host
+	Make func(r rng.RNG) common.Distribution
+}
+
+// measurement is a SimulatedMeasurement with float64 fields drawn from
+// Distributions, as made by NewMeasurement
+type measurement struct {
+	*subsystemMeasurement
+	name   []byte
+	labels []labeledDistributionMaker
+}
+
+// NewMeasurement returns a SimulatedMeasurement named name, starting at
+// start, whose float64 fields follow the Distributions made by fields and are
+// advanced by r. It lets custom measurements (e.g., for a HostConstructor
+// made with NewHostConstructor) be defined like the builtin ones.
+func NewMeasurement(r *common.Rand, start time.Time, name []byte, fields []FieldDistribution) common.SimulatedMeasurement {
+	labels := make([]labeledDistributionMaker, len(fields))
+	for i, f := range fields {
+		labels[i] = labeledDistributionMaker{label: f.Name, distributionMaker: f.Make}
+	}
+	return &measurement{
+		subsystemMeasurement: newSubsystemMeasurementWithDistributionMakers(r, start, labels),
+		name:                 name,
+		labels:               labels,
+	}
+}
+
+// ToPoint serializes the measurement to a Point
+func (m *measurement) ToPoint(p *serialize.Point) {
+	m.toPoint(p, m.name, m.labels)
+}
@@ -9,8 +9,10 @@ import (
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func ldmToFieldLabels(ldm []labeledDistributionMaker) [][]byte {
@@ -70,8 +72,8 @@ func TestNewSubsystemMeasurement(t *testing.T) {
 
 	for _, c := range cases {
 		now := time.Now()
-		m := newSubsystemMeasurement(now, c.numDistros)
-		if !m.timestamp.Equal(now) {
+		m := newSubsystemMeasurement(common.NewRand(rng.New(123)), now, c.numDistros)
+		if m.timestamp != now.UnixNano() {
 			t.Errorf("%s: incorrect timestamp set: got %v want %v", c.desc, m.timestamp, now)
 		}
 		if got := len(m.distributions); got != c.numDistros {
@@ -82,12 +84,12 @@ func TestNewSubsystemMeasurement(t *testing.T) {
 
 func TestNewSubsystemMeasurementWithDistributionMakers(t *testing.T) {
 	makers := []labeledDistributionMaker{
-		{[]byte("foo"), func() common.Distribution { return &monotonicDistribution{state: 0.0} }},
-		{[]byte("bar"), func() common.Distribution { return &monotonicDistribution{state: 1.0} }},
+		{[]byte("foo"), func(rng.RNG) common.Distribution { return &monotonicDistribution{state: 0.0} }},
+		{[]byte("bar"), func(rng.RNG) common.Distribution { return &monotonicDistribution{state: 1.0} }},
 	}
 	now := time.Now()
-	m := newSubsystemMeasurementWithDistributionMakers(now, makers)
-	if !m.timestamp.Equal(now) {
+	m := newSubsystemMeasurementWithDistributionMakers(common.NewRand(rng.New(123)), now, makers)
+	if m.timestamp != now.UnixNano() {
 		t.Errorf("incorrect timestamp set: got %v want %v", m.timestamp, now)
 	}
 
@@ -106,12 +108,12 @@ func TestNewSubsystemMeasurementWithDistributionMakers(t *testing.T) {
 func TestSubsytemMeasurementTick(t *testing.T) {
 	now := time.Now()
 	numDistros := 3
-	m := newSubsystemMeasurement(now, numDistros)
+	m := newSubsystemMeasurement(common.NewRand(rng.New(123)), now, numDistros)
 	for i := 0; i < numDistros; i++ {
 		m.distributions[i] = &monotonicDistribution{state: float64(i)}
 	}
 	m.Tick(time.Nanosecond)
-	if got := m.timestamp.UnixNano(); got != now.UnixNano()+1 {
+	if got := m.timestamp; got != now.UnixNano()+1 {
 		t.Errorf("tick did not increase timestamp correct: got %d want %d", got, now.UnixNano()+1)
 	}
 	for i := 0; i < numDistros; i++ {
@@ -143,11 +145,22 @@ func TestToPointAllInt64(t *testing.T) {
 	testCommonToPoint(t, p, math.Floor(toPointState+1.0))
 }
 
+func TestNewMeasurement(t *testing.T) {
+	fields := []FieldDistribution{
+		{Name: []byte(toPointFieldLabel), Make: func(rng.RNG) common.Distribution { return &monotonicDistribution{state: toPointState} }},
+	}
+	m := NewMeasurement(common.NewRand(rng.New(123)), time.Now(), []byte(toPointLabel), fields)
+	m.Tick(time.Nanosecond)
+	p := serialize.NewPoint()
+	m.ToPoint(p)
+	testCommonToPoint(t, p, toPointState+1.0)
+}
+
 func setupToPoint(start time.Time) (*subsystemMeasurement, []labeledDistributionMaker) {
 	makers := []labeledDistributionMaker{
-		{[]byte(toPointFieldLabel), func() common.Distribution { return &monotonicDistribution{state: toPointState} }},
+		{[]byte(toPointFieldLabel), func(rng.RNG) common.Distribution { return &monotonicDistribution{state: toPointState} }},
 	}
-	m := newSubsystemMeasurementWithDistributionMakers(start, makers)
+	m := newSubsystemMeasurementWithDistributionMakers(common.NewRand(rng.New(123)), start, makers)
 	m.Tick(time.Nanosecond)
 	return m, makers
 }
@@ -155,7 +168,7 @@ func setupToPoint(start time.Time) (*subsystemMeasurement, []labeledDistribution
 func testCommonToPoint(t *testing.T, p *serialize.Point, fieldVal float64) {
 	// serialize the point to check output
 	b := new(bytes.Buffer)
-	serializer := &serialize.InfluxSerializer{}
+	serializer := &influx.Serializer{}
 	serializer.Serialize(p, b)
 
 	if got := string(p.MeasurementName()); got != toPointLabel {
@@ -1,11 +1,10 @@
 package devops
 
 import (
-	"math/rand"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/common"
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
 )
 
 var (
@@ -32,9 +31,9 @@ type MemMeasurement struct {
 	bytesTotal int64 // this doesn't change
 }
 
-func NewMemMeasurement(start time.Time) *MemMeasurement {
-	sub := newSubsystemMeasurement(start, 3)
-	bytesTotal := randomInt64SliceChoice(memoryTotalChoices)
+func NewMemMeasurement(r *common.Rand, start time.Time) *MemMeasurement {
+	sub := newSubsystemMeasurement(r, start, 3)
+	bytesTotal := randomInt64SliceChoice(r, memoryTotalChoices)
 
 	// Reuse NormalDistributions as arguments to other distributions. This is
 	// safe to do because the higher-level distribution advances the ND and
@@ -42,11 +41,11 @@ func NewMemMeasurement(start time.Time) *MemMeasurement {
 	nd := common.ND(0.0, float64(bytesTotal)/64)
 
 	// used bytes
-	sub.distributions[0] = common.CWD(nd, 0.0, float64(bytesTotal), rand.Float64()*float64(bytesTotal))
+	sub.distributions[0] = common.CWD(nd, 0.0, float64(bytesTotal), r.Float64()*float64(bytesTotal))
 	// cached bytes
-	sub.distributions[1] = common.CWD(nd, 0.0, float64(bytesTotal), rand.Float64()*float64(bytesTotal))
+	sub.distributions[1] = common.CWD(nd, 0.0, float64(bytesTotal), r.Float64()*float64(bytesTotal))
 	// buffered bytes
-	sub.distributions[2] = common.CWD(nd, 0.0, float64(bytesTotal), rand.Float64()*float64(bytesTotal))
+	sub.distributions[2] = common.CWD(nd, 0.0, float64(bytesTotal), r.Float64()*float64(bytesTotal))
 	return &MemMeasurement{
 		subsystemMeasurement: sub,
 		bytesTotal:           bytesTotal,
@@ -55,7 +54,7 @@ func NewMemMeasurement(start time.Time) *MemMeasurement {
 
 func (m *MemMeasurement) ToPoint(p *serialize.Point) {
 	p.SetMeasurementName(labelMem)
-	p.SetTimestamp(&m.timestamp)
+	p.SetTimestamp(m.timestamp)
 
 	total := m.bytesTotal
 	used := int64(m.distributions[0].Get())
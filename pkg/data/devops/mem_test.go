@@ -1,16 +1,17 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestMemMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewMemMeasurement(now)
+	m := NewMemMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	oldTotal := m.bytesTotal
@@ -20,7 +21,6 @@ func TestMemMeasurementTick(t *testing.T) {
 		oldVals[string(f)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -41,7 +41,7 @@ func TestMemMeasurementTick(t *testing.T) {
 
 func TestMemMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewMemMeasurement(now)
+	m := NewMemMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	m.Tick(duration)
 
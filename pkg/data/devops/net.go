@@ -0,0 +1,45 @@
+package devops
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelNet             = []byte("net") // heap optimization
+	labelNetTagInterface = []byte("interface")
+
+	netFields = []labeledDistributionMaker{
+		{[]byte("bytes_sent"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("bytes_recv"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("packets_sent"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("packets_recv"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("err_in"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("err_out"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("drop_in"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("drop_out"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+	}
+)
+
+type NetMeasurement struct {
+	*subsystemMeasurement
+	interfaceName []byte
+}
+
+func NewNetMeasurement(r *common.Rand, start time.Time) *NetMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, netFields)
+	interfaceName := []byte(fmt.Sprintf("eth%d", r.Intn(4)))
+	return &NetMeasurement{
+		subsystemMeasurement: sub,
+		interfaceName:        interfaceName,
+	}
+}
+
+func (m *NetMeasurement) ToPoint(p *serialize.Point) {
+	m.toPointAllInt64(p, labelNet, netFields)
+	p.AppendTag(labelNetTagInterface, m.interfaceName)
+}
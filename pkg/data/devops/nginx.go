@@ -0,0 +1,48 @@
+package devops
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelNginx          = []byte("nginx") // heap optimization
+	labelNginxTagPort   = []byte("port")
+	labelNginxTagServer = []byte("server")
+
+	nginxFields = []labeledDistributionMaker{
+		{[]byte("accepts"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("active"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 100, 0) }},
+		{[]byte("handled"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("reading"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 100, 0) }},
+		{[]byte("requests"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("waiting"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 100, 0) }},
+		{[]byte("writing"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 100, 0) }},
+	}
+)
+
+type NginxMeasurement struct {
+	*subsystemMeasurement
+	port, serverName []byte
+}
+
+func NewNginxMeasurement(r *common.Rand, start time.Time) *NginxMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, nginxFields)
+	serverName := []byte(fmt.Sprintf("nginx_%d", r.Intn(100000)))
+	port := []byte(fmt.Sprintf("%d", r.Intn(20000)+1024))
+	return &NginxMeasurement{
+		subsystemMeasurement: sub,
+		port:                 port,
+		serverName:           serverName,
+	}
+}
+
+func (m *NginxMeasurement) ToPoint(p *serialize.Point) {
+	m.toPointAllInt64(p, labelNginx, nginxFields)
+	p.AppendTag(labelNginxTagPort, m.port)
+	p.AppendTag(labelNginxTagServer, m.serverName)
+}
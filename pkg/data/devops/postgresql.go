@@ -0,0 +1,45 @@
+package devops
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelPostgresql = []byte("postgresl") // heap optimization
+
+	postgresqlFields = []labeledDistributionMaker{
+		{[]byte("numbackends"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("xact_commit"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("xact_rollback"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("blks_read"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("blks_hit"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("tup_returned"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("tup_fetched"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("tup_inserted"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("tup_updated"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("tup_deleted"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("conflicts"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("temp_files"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("temp_bytes"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(1024, 1), 0, 1024*1024*1024, 0) }},
+		{[]byte("deadlocks"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("blk_read_time"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("blk_write_time"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+	}
+)
+
+type PostgresqlMeasurement struct {
+	*subsystemMeasurement
+}
+
+func NewPostgresqlMeasurement(r *common.Rand, start time.Time) *PostgresqlMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, postgresqlFields)
+	return &PostgresqlMeasurement{sub}
+}
+
+func (m *PostgresqlMeasurement) ToPoint(p *serialize.Point) {
+	m.toPointAllInt64(p, labelPostgresql, postgresqlFields)
+}
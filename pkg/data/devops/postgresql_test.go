@@ -1,16 +1,17 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestPostgresqlMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewPostgresqlMeasurement(now)
+	m := NewPostgresqlMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	oldVals := map[string]float64{}
 	fields := ldmToFieldLabels(postgresqlFields)
@@ -18,7 +19,6 @@ func TestPostgresqlMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.label)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -33,7 +33,7 @@ func TestPostgresqlMeasurementTick(t *testing.T) {
 
 func TestPostgresqlMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewPostgresqlMeasurement(now)
+	m := NewPostgresqlMeasurement(common.NewRand(rng.New(123)), now)
 	duration := time.Second
 	m.Tick(duration)
 
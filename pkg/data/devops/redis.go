@@ -0,0 +1,85 @@
+package devops
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelRedis            = []byte("redis") // heap optimization
+	labelRedisTagPort     = []byte("port")
+	labelRedisTagServer   = []byte("server")
+	labelRedisFieldUptime = []byte("uptime_in_seconds")
+
+	sixteenGB = float64(16 * 1024 * 1024 * 1024)
+
+	redisFields = []labeledDistributionMaker{
+		{[]byte("total_connections_received"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(5, 1), 0) }},
+		{[]byte("expired_keys"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("evicted_keys"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("keyspace_hits"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+		{[]byte("keyspace_misses"), func(rng.RNG) common.Distribution { return common.MWD(common.ND(50, 1), 0) }},
+
+		{[]byte("instantaneous_ops_per_sec"), func(rng.RNG) common.Distribution { return common.WD(common.ND(1, 1), 0) }},
+		{[]byte("instantaneous_input_kbps"), func(rng.RNG) common.Distribution { return common.WD(common.ND(1, 1), 0) }},
+		{[]byte("instantaneous_output_kbps"), func(rng.RNG) common.Distribution { return common.WD(common.ND(1, 1), 0) }},
+		{[]byte("connected_clients"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(50, 1), 0, 10000, 0) }},
+		{[]byte("used_memory"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(50, 1), 0, sixteenGB, sixteenGB/2) }},
+		{[]byte("used_memory_rss"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(50, 1), 0, sixteenGB, sixteenGB/2) }},
+		{[]byte("used_memory_peak"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(50, 1), 0, sixteenGB, sixteenGB/2) }},
+		{[]byte("used_memory_lua"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(50, 1), 0, sixteenGB, sixteenGB/2) }},
+		{[]byte("rdb_changes_since_last_save"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(50, 1), 0, 10000, 0) }},
+
+		{[]byte("sync_full"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("sync_partial_ok"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("sync_partial_err"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("pubsub_channels"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("pubsub_patterns"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("latest_fork_usec"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("connected_slaves"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("master_repl_offset"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("repl_backlog_active"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("repl_backlog_size"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("repl_backlog_histlen"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("mem_fragmentation_ratio"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 100, 0) }},
+		{[]byte("used_cpu_sys"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("used_cpu_user"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("used_cpu_sys_children"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+		{[]byte("used_cpu_user_children"), func(rng.RNG) common.Distribution { return common.CWD(common.ND(5, 1), 0, 1000, 0) }},
+	}
+)
+
+type RedisMeasurement struct {
+	*subsystemMeasurement
+
+	port, serverName []byte
+	uptime           time.Duration
+}
+
+func NewRedisMeasurement(r *common.Rand, start time.Time) *RedisMeasurement {
+	sub := newSubsystemMeasurementWithDistributionMakers(r, start, redisFields)
+	serverName := []byte(fmt.Sprintf("redis_%d", r.Intn(100000)))
+	port := []byte(fmt.Sprintf("%d", r.Intn(20000)+1024))
+	return &RedisMeasurement{
+		subsystemMeasurement: sub,
+		port:                 port,
+		serverName:           serverName,
+		uptime:               time.Duration(0),
+	}
+}
+
+func (m *RedisMeasurement) Tick(d time.Duration) {
+	m.subsystemMeasurement.Tick(d)
+	m.uptime += d
+}
+
+func (m *RedisMeasurement) ToPoint(p *serialize.Point) {
+	p.AppendField(labelRedisFieldUptime, int64(m.uptime.Seconds()))
+	m.toPointAllInt64(p, labelRedis, redisFields)
+	p.AppendTag(labelRedisTagPort, m.port)
+	p.AppendTag(labelRedisTagServer, m.serverName)
+}
@@ -1,16 +1,17 @@
 package devops
 
 import (
-	"math/rand"
 	"testing"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_data/serialize"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestRedisMeasurementTick(t *testing.T) {
 	now := time.Now()
-	m := NewRedisMeasurement(now)
+	m := NewRedisMeasurement(common.NewRand(rng.New(123)), now)
 	origName := string(m.serverName)
 	origPort := string(m.port)
 	duration := time.Second
@@ -20,7 +21,6 @@ func TestRedisMeasurementTick(t *testing.T) {
 		oldVals[string(ldm.label)] = m.distributions[i].Get()
 	}
 
-	rand.Seed(123)
 	m.Tick(duration)
 	err := testDistributionsAreDifferent(oldVals, m.subsystemMeasurement, fields)
 	if err != nil {
@@ -53,7 +53,7 @@ func TestRedisMeasurementTick(t *testing.T) {
 
 func TestRedisMeasurementToPoint(t *testing.T) {
 	now := time.Now()
-	m := NewRedisMeasurement(now)
+	m := NewRedisMeasurement(common.NewRand(rng.New(123)), now)
 	origName := string(m.serverName)
 	origPort := string(m.port)
 	duration := time.Second
@@ -0,0 +1,11 @@
+package devops
+
+import "github.com/timescale/tsbs/pkg/rng"
+
+func randomByteStringSliceChoice(r rng.RNG, s [][]byte) []byte {
+	return s[r.Intn(len(s))]
+}
+
+func randomInt64SliceChoice(r rng.RNG, s []int64) int64 {
+	return s[r.Intn(len(s))]
+}
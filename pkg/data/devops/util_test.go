@@ -3,6 +3,8 @@ package devops
 import (
 	"bytes"
 	"testing"
+
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func testIfInByteStringSlice(t *testing.T, arr [][]byte, choice []byte) {
@@ -20,9 +22,10 @@ func TestRandomByteSliceChoice(t *testing.T) {
 		[]byte("bar"),
 		[]byte("baz"),
 	}
+	r := rng.New(123)
 	// One million attempts ought to catch it?
 	for i := 0; i < 1000000; i++ {
-		choice := randomByteStringSliceChoice(arr)
+		choice := randomByteStringSliceChoice(r, arr)
 		testIfInByteStringSlice(t, arr, choice)
 	}
 }
@@ -38,9 +41,10 @@ func testIfInInt64Slice(t *testing.T, arr []int64, choice int64) {
 
 func TestRandomInt64Choice(t *testing.T) {
 	arr := []int64{0, 10000, 9999}
+	r := rng.New(123)
 	// One million attempts ought to catch it?
 	for i := 0; i < 1000000; i++ {
-		choice := randomInt64SliceChoice(arr)
+		choice := randomInt64SliceChoice(r, arr)
 		testIfInInt64Slice(t, arr, choice)
 	}
 }
@@ -0,0 +1,119 @@
+// Package finance simulates the finance use case: trade and quote ticks of
+// stock symbols, whose prices follow random walks with clustered volatility
+// and which only trade during market hours.
+package finance
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelQuote = []byte("quote")
+	labelTrade = []byte("trade")
+
+	// TagKeys are the tags of every point, identifying its symbol
+	TagKeys = [][]byte{
+		[]byte("symbol"),
+		[]byte("exchange"),
+		[]byte("sector"),
+	}
+
+	quoteFields = [][]byte{
+		[]byte("bid_price"),
+		[]byte("bid_size"),
+		[]byte("ask_price"),
+		[]byte("ask_size"),
+	}
+	quoteTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+	}
+	tradeFields = [][]byte{
+		[]byte("price"),
+		[]byte("size"),
+	}
+	tradeTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+	}
+
+	schema = serialize.NewTypedSchema(TagKeys, map[string][][]byte{
+		string(labelQuote): quoteFields,
+		string(labelTrade): tradeFields,
+	}, map[string][]serialize.FieldType{
+		string(labelQuote): quoteTypes,
+		string(labelTrade): tradeTypes,
+	})
+)
+
+// Market hours, the same for every exchange: 09:30 to 16:00 Eastern
+// Standard Time, Monday to Friday. Daylight saving time and holidays are
+// ignored.
+var (
+	marketZone  = time.FixedZone("EST", -5*60*60)
+	marketOpen  = 9*time.Hour + 30*time.Minute
+	marketClose = 16 * time.Hour
+)
+
+// tradingYear is the time the market is open in a year, which annual
+// volatilities are over
+const tradingYear = 252 * 390 * time.Minute
+
+// IsMarketOpen returns whether the market is open at t
+func IsMarketOpen(t time.Time) bool {
+	t = t.In(marketZone)
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	y, m, d := t.Date()
+	sinceMidnight := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, marketZone))
+	return sinceMidnight >= marketOpen && sinceMidnight < marketClose
+}
+
+// SimulatorConfig is used to create a Simulator of the finance use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitSymbolCount is the number of symbols to start with in the first
+	// reporting period
+	InitSymbolCount uint64
+	// SymbolCount is the total number of symbols to have in the last
+	// reporting period
+	SymbolCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each symbol. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the quote and trade
+// measurements
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a quote of each symbol every
+// interval the market is open, and a trade at some of them. It advances
+// RNG, so each call simulates different symbols.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitSymbolCount,
+		Count:            c.SymbolCount,
+		PointsPerReading: 2,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewSymbol(r, i, start)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
@@ -0,0 +1,82 @@
+package finance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestIsMarketOpen(t *testing.T) {
+	cases := []struct {
+		time string
+		want bool
+	}{
+		{"2016-01-04T14:29:59Z", false}, // Monday 09:29:59 EST
+		{"2016-01-04T14:30:00Z", true},
+		{"2016-01-04T20:59:59Z", true},
+		{"2016-01-04T21:00:00Z", false},
+		{"2016-01-04T09:30:00-05:00", true},
+		{"2016-01-08T15:00:00Z", true},  // Friday
+		{"2016-01-09T15:00:00Z", false}, // Saturday
+		{"2016-01-10T15:00:00Z", false}, // Sunday
+	}
+	for _, c := range cases {
+		ts, err := time.Parse(time.RFC3339, c.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := IsMarketOpen(ts); got != c.want {
+			t.Errorf("%s: incorrect market open: got %v want %v", c.time, got, c.want)
+		}
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	// from an hour before the open on Monday to an hour after it
+	start := time.Date(2016, 1, 4, 13, 30, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:           start,
+		End:             start.Add(2 * time.Hour),
+		InitSymbolCount: 5,
+		SymbolCount:     5,
+		RNG:             rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(time.Minute)
+	counts := make(map[string]int)
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		ts := time.Unix(0, p.Timestamp())
+		if !IsMarketOpen(ts) {
+			t.Fatalf("point outside market hours: %v", ts.UTC())
+		}
+		m := string(p.MeasurementName())
+		counts[m]++
+		if len(p.FieldKeys()) != len(c.Fields().FieldKeys(m)) {
+			t.Errorf("incorrect fields of %s: got %s", m, p.FieldKeys())
+		}
+		if m == "quote" {
+			bid, ask := p.FieldValues()[0].(float64), p.FieldValues()[2].(float64)
+			if bid <= 0 || ask <= bid {
+				t.Errorf("incorrect quote: bid %v ask %v", bid, ask)
+			}
+		}
+	}
+	// a quote per symbol per minute of the hour after the open
+	if got := counts["quote"]; got != 5*60 {
+		t.Errorf("incorrect number of quotes: got %d want %d", got, 5*60)
+	}
+	if got := counts["trade"]; got == 0 || got > 5*60 {
+		t.Errorf("incorrect number of trades: got %d", got)
+	}
+}
@@ -0,0 +1,205 @@
+package finance
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+var (
+	exchangeChoices = [][]byte{
+		[]byte("NYSE"),
+		[]byte("NASDAQ"),
+		[]byte("ARCA"),
+		[]byte("BATS"),
+		[]byte("IEX"),
+	}
+	sectorChoices = [][]byte{
+		[]byte("communication_services"),
+		[]byte("consumer_discretionary"),
+		[]byte("consumer_staples"),
+		[]byte("energy"),
+		[]byte("financials"),
+		[]byte("health_care"),
+		[]byte("industrials"),
+		[]byte("information_technology"),
+		[]byte("materials"),
+		[]byte("real_estate"),
+		[]byte("utilities"),
+	}
+)
+
+// Parameters of the GARCH(1,1) process of the variance of the price
+// returns: a large return raises the variance of the following ones by
+// garchAlpha of its square, and the variance decays towards its long run
+// value by garchBeta each tick, so volatile periods cluster together.
+const (
+	garchAlpha = 0.08
+	garchBeta  = 0.9
+)
+
+// overnightGap is the trading time whose volatility the price moves by
+// between the close and the next open
+const overnightGap = time.Hour
+
+// Symbol models a stock symbol, trading on one exchange, whose price is a
+// random walk of its log with GARCH(1,1) volatility. It is quoted every tick
+// while the market is open, and traded at a tick with a probability that
+// grows with its volatility.
+type Symbol struct {
+	// These are all assigned once, at Symbol creation:
+	Name     []byte
+	Exchange []byte
+	Sector   []byte
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+
+	// annualVolatility is the long run standard deviation of the returns
+	// over a trading year, and liquidity the probability of a trade at a
+	// tick of average volatility
+	annualVolatility float64
+	liquidity        float64
+
+	logPrice float64
+	// variance and longRunVariance are the current and long run variance of
+	// the returns per tick, or 0 before the first tick
+	variance        float64
+	longRunVariance float64
+
+	// state of the current tick
+	open    bool
+	bid     float64
+	ask     float64
+	bidSize int64
+	askSize int64
+	traded  bool
+	price   float64
+	size    int64
+}
+
+// NewSymbol returns symbol i, quoted from start, with its exchange, sector,
+// price and volatility drawn from r
+func NewSymbol(r *common.Rand, i int, start time.Time) *Symbol {
+	s := &Symbol{
+		Name:     Ticker(i),
+		Exchange: exchangeChoices[r.Intn(len(exchangeChoices))],
+		Sector:   sectorChoices[r.Intn(len(sectorChoices))],
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+
+		logPrice:         math.Log(20) + r.NormFloat64(),
+		annualVolatility: 0.15 + 0.45*r.Float64(),
+		liquidity:        0.1 + 0.8*r.Float64(),
+	}
+	// between $1 and $1000
+	s.logPrice = math.Max(0, math.Min(s.logPrice, math.Log(1000)))
+	s.open = IsMarketOpen(start)
+	if s.open {
+		s.quote()
+	}
+	return s
+}
+
+// Ticker returns the ticker of symbol i: AAA, AAB, ..., ZZZ, AAAA, etc
+func Ticker(i int) []byte {
+	// bijective base 26, where AAA is 26*26 + 26 + 1
+	n := i + 26*26 + 26 + 1
+	var b []byte
+	for n > 0 {
+		n--
+		b = append([]byte{byte('A' + n%26)}, b...)
+		n /= 26
+	}
+	return b
+}
+
+// Tick advances the symbol by d. While the market is open, its price moves
+// and it is quoted anew; across the close it moves by overnightGap's worth
+// of volatility at once.
+func (s *Symbol) Tick(d time.Duration) {
+	s.timestamp += int64(d)
+	if s.variance == 0 {
+		s.longRunVariance = s.annualVolatility * s.annualVolatility * float64(d) / float64(tradingYear)
+		s.variance = s.longRunVariance
+	}
+	wasOpen := s.open
+	s.open = IsMarketOpen(time.Unix(0, s.timestamp))
+	if !s.open {
+		return
+	}
+
+	if wasOpen {
+		ret := math.Sqrt(s.variance) * s.rand.NormFloat64()
+		s.logPrice += ret
+		s.variance = s.longRunVariance*(1-garchAlpha-garchBeta) + garchAlpha*ret*ret + garchBeta*s.variance
+	} else {
+		// the gap is not a return of the session, so it leaves the variance
+		// as it was at the close
+		s.logPrice += s.annualVolatility * math.Sqrt(float64(overnightGap)/float64(tradingYear)) * s.rand.NormFloat64()
+	}
+	s.quote()
+}
+
+// quote draws the quote and any trade of the current tick, with a spread
+// and a probability of trading that grow with the volatility
+func (s *Symbol) quote() {
+	relVolatility := 1.0
+	if s.longRunVariance > 0 {
+		relVolatility = math.Sqrt(s.variance / s.longRunVariance)
+	}
+	mid := math.Exp(s.logPrice)
+	spread := math.Max(0.01, roundCents(mid*(0.0002+0.001*relVolatility)))
+	s.bid = math.Max(0.01, roundCents(mid-spread/2))
+	s.ask = roundCents(s.bid + spread)
+	s.bidSize = 100 * int64(1+s.rand.Intn(20))
+	s.askSize = 100 * int64(1+s.rand.Intn(20))
+
+	s.traded = s.rand.Float64() < s.liquidity*relVolatility
+	if !s.traded {
+		return
+	}
+	// buyers lift the ask, sellers hit the bid
+	s.price = s.bid
+	if s.rand.Float64() < 0.5 {
+		s.price = s.ask
+	}
+	// mostly round lots, some odd lots
+	s.size = 100 * int64(1+s.rand.Intn(10))
+	if s.rand.Float64() < 0.2 {
+		s.size = int64(1 + s.rand.Intn(99))
+	}
+}
+
+// roundCents rounds a price to whole cents
+func roundCents(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+// ToPoint fills p with the quote, point 0, or the trade, point 1, of the
+// current tick, returning false outside market hours and for the trade of a
+// tick without one
+func (s *Symbol) ToPoint(p *serialize.Point, i int) bool {
+	if !s.open || i == 1 && !s.traded {
+		return false
+	}
+	p.SetTimestamp(s.timestamp)
+	p.AppendTag(TagKeys[0], s.Name)
+	p.AppendTag(TagKeys[1], s.Exchange)
+	p.AppendTag(TagKeys[2], s.Sector)
+	if i == 0 {
+		p.SetMeasurementName(labelQuote)
+		p.AppendField(quoteFields[0], s.bid)
+		p.AppendField(quoteFields[1], s.bidSize)
+		p.AppendField(quoteFields[2], s.ask)
+		p.AppendField(quoteFields[3], s.askSize)
+	} else {
+		p.SetMeasurementName(labelTrade)
+		p.AppendField(tradeFields[0], s.price)
+		p.AppendField(tradeFields[1], s.size)
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package finance
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestTicker(t *testing.T) {
+	cases := []struct {
+		i    int
+		want string
+	}{
+		{0, "AAA"},
+		{1, "AAB"},
+		{25, "AAZ"},
+		{26, "ABA"},
+		{26*26*26 - 1, "ZZZ"},
+		{26 * 26 * 26, "AAAA"},
+	}
+	for _, c := range cases {
+		if got := string(Ticker(c.i)); got != c.want {
+			t.Errorf("%d: incorrect ticker: got %s want %s", c.i, got, c.want)
+		}
+	}
+}
+
+func TestSymbolTick(t *testing.T) {
+	// a Monday at the open
+	start := time.Date(2016, 1, 4, 14, 30, 0, 0, time.UTC)
+	s := NewSymbol(common.NewRand(rng.New(123)), 0, start)
+	p := serialize.NewPoint()
+	if !s.ToPoint(p, 0) {
+		t.Fatalf("no quote at the open")
+	}
+	if got := p.Timestamp(); got != start.UnixNano() {
+		t.Errorf("incorrect timestamp: got %d want %d", got, start.UnixNano())
+	}
+
+	// returns are more likely to be large after large ones
+	var prev, n, after, total float64
+	for i := 0; i < 100000; i++ {
+		before := s.logPrice
+		s.Tick(time.Second)
+		if !s.open {
+			continue
+		}
+		ret := math.Abs(s.logPrice - before)
+		if prev > 3*math.Sqrt(s.longRunVariance) {
+			after += ret
+			n++
+		}
+		total += ret
+		prev = ret
+
+		if s.bid != roundCents(s.bid) || s.ask != roundCents(s.ask) {
+			t.Fatalf("prices not in whole cents: bid %v ask %v", s.bid, s.ask)
+		}
+	}
+	if n == 0 || after/n <= total/100000 {
+		t.Errorf("volatility does not cluster: mean return %v after large returns, %v overall", after/n, total/100000)
+	}
+
+	// closed overnight, then gapping at the next open
+	s = NewSymbol(common.NewRand(rng.New(123)), 0, start.Add(6*time.Hour+29*time.Minute))
+	s.Tick(time.Minute)
+	if s.open || s.ToPoint(p, 0) || s.ToPoint(p, 1) {
+		t.Errorf("made points after the close")
+	}
+	before := s.logPrice
+	s.Tick(17*time.Hour + 30*time.Minute)
+	if !s.open {
+		t.Errorf("not open at the next open")
+	}
+	if s.logPrice == before {
+		t.Errorf("price did not move at the open")
+	}
+}
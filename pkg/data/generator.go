@@ -0,0 +1,605 @@
+// Package data generates time series data for benchmarking from
+// pre-specified use cases, the same data produced by tsbs_generate_data.
+//
+// Most users only need a Generator, which writes a whole dataset in a given
+// format to an io.Writer:
+//
+//	g, err := data.NewGenerator(data.GeneratorConfig{
+//		Format:         data.FormatInflux,
+//		UseCase:        data.UseCaseCPUOnly,
+//		Scale:          10,
+//		Seed:           123,
+//		TimestampStart: start,
+//		TimestampEnd:   end,
+//		LogInterval:    10 * time.Second,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	err = g.Generate(ctx, w)
+//
+// Points can also be consumed one at a time without being serialized using a
+// PointIterator, from Generator.Points or NewPointIterator. Their Schema,
+// including the types of their fields, is available up front from Schema.
+//
+// The simulators, use cases and serializers it is built from are available in
+// the common, devops and serialize subpackages for finer grained control.
+package data
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/clickstream"
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/devops"
+	"github.com/timescale/tsbs/pkg/data/finance"
+	"github.com/timescale/tsbs/pkg/data/industrial"
+	"github.com/timescale/tsbs/pkg/data/kubernetes"
+	"github.com/timescale/tsbs/pkg/data/logs"
+	"github.com/timescale/tsbs/pkg/data/network"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/adx"
+	"github.com/timescale/tsbs/pkg/data/serialize/akumuli"
+	"github.com/timescale/tsbs/pkg/data/serialize/arrow"
+	"github.com/timescale/tsbs/pkg/data/serialize/avro"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigquery"
+	"github.com/timescale/tsbs/pkg/data/serialize/bigtable"
+	"github.com/timescale/tsbs/pkg/data/serialize/cassandra"
+	"github.com/timescale/tsbs/pkg/data/serialize/cratedb"
+	"github.com/timescale/tsbs/pkg/data/serialize/csv"
+	"github.com/timescale/tsbs/pkg/data/serialize/druid"
+	"github.com/timescale/tsbs/pkg/data/serialize/dynamodb"
+	"github.com/timescale/tsbs/pkg/data/serialize/elasticsearch"
+	"github.com/timescale/tsbs/pkg/data/serialize/external"
+	"github.com/timescale/tsbs/pkg/data/serialize/graphite"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx2"
+	"github.com/timescale/tsbs/pkg/data/serialize/jsonl"
+	"github.com/timescale/tsbs/pkg/data/serialize/kafka"
+	"github.com/timescale/tsbs/pkg/data/serialize/kdb"
+	"github.com/timescale/tsbs/pkg/data/serialize/m3db"
+	"github.com/timescale/tsbs/pkg/data/serialize/mongo"
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+	"github.com/timescale/tsbs/pkg/data/serialize/opentsdb"
+	"github.com/timescale/tsbs/pkg/data/serialize/otlp"
+	"github.com/timescale/tsbs/pkg/data/serialize/parquet"
+	"github.com/timescale/tsbs/pkg/data/serialize/pinot"
+	"github.com/timescale/tsbs/pkg/data/serialize/prometheus"
+	"github.com/timescale/tsbs/pkg/data/serialize/protobuf"
+	"github.com/timescale/tsbs/pkg/data/serialize/questdb"
+	"github.com/timescale/tsbs/pkg/data/serialize/redistimeseries"
+	"github.com/timescale/tsbs/pkg/data/serialize/sql"
+	"github.com/timescale/tsbs/pkg/data/serialize/tdengine"
+	"github.com/timescale/tsbs/pkg/data/serialize/timescaledb"
+	"github.com/timescale/tsbs/pkg/data/serialize/timestream"
+	"github.com/timescale/tsbs/pkg/data/serialize/victoriametrics"
+	"github.com/timescale/tsbs/pkg/data/serialize/warp10"
+	"github.com/timescale/tsbs/pkg/data/smarthome"
+	"github.com/timescale/tsbs/pkg/data/weather"
+	"github.com/timescale/tsbs/pkg/rng"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// GeneratorVersion identifies the data produced by this generator. Any change
+// that alters the output for the same config (e.g., a new random number
+// generator, or a change to a simulated measurement) must increment it, and
+// the golden files of tsbs_generate_data must be regenerated to match.
+const GeneratorVersion = 4
+
+const (
+	// Builtin output data format choices (alphabetical order)
+	FormatADX             = adx.Format
+	FormatADXJSON         = adx.FormatJSON
+	FormatAkumuli         = akumuli.Format
+	FormatArrow           = arrow.Format
+	FormatAvro            = avro.Format
+	FormatBigQuery        = bigquery.Format
+	FormatBigQueryNDJSON  = bigquery.FormatNDJSON
+	FormatBigtable        = bigtable.Format
+	FormatCassandra       = cassandra.Format
+	FormatCrateDB         = cratedb.Format
+	FormatCSV             = csv.Format
+	FormatDruid           = druid.Format
+	FormatDynamoDB        = dynamodb.Format
+	FormatElasticsearch   = elasticsearch.Format
+	FormatGraphite        = graphite.Format
+	FormatGraphitePickle  = graphite.FormatPickle
+	FormatInflux          = influx.Format
+	FormatInflux2         = influx2.Format
+	FormatJSONL           = jsonl.Format
+	FormatKafka           = kafka.Format
+	FormatM3DB            = m3db.Format
+	FormatM3DBJSON        = m3db.FormatJSON
+	FormatMongo           = mongo.Format
+	FormatMongoTimeSeries = mongo.FormatTimeSeries
+	FormatMySQL           = mysql.Format
+	FormatOpenTSDB        = opentsdb.Format
+	FormatOTLP            = otlp.Format
+	FormatParquet         = parquet.Format
+	FormatPinot           = pinot.Format
+	FormatPrometheus      = prometheus.Format
+	FormatProtobuf        = protobuf.Format
+	FormatQuestDB         = questdb.Format
+	FormatRedisTimeSeries = redistimeseries.Format
+	FormatSQL             = sql.Format
+	FormatTDengine        = tdengine.Format
+	FormatTimescaleDB     = timescaledb.Format
+	FormatTimestream      = timestream.Format
+	FormatVictoriaMetrics = victoriametrics.Format
+	FormatWarp10          = warp10.Format
+
+	// FormatExecPrefix starts formats that pipe points to a command, e.g.,
+	// "exec:./my_serializer", see package external
+	FormatExecPrefix = external.Scheme + ":"
+	// FormatKdbPrefix starts formats that write a directory of CSV files and
+	// a q script for kdb+, e.g., "kdb:dir=/data/kdb", see package kdb
+	FormatKdbPrefix = kdb.Scheme + ":"
+
+	// Use case choices
+	UseCaseCPUOnly     = "cpu-only"
+	UseCaseCPUSingle   = "cpu-single"
+	UseCaseDevops      = "devops"
+	UseCaseFinance     = "finance"
+	UseCaseKubernetes  = "kubernetes"
+	UseCaseClickstream = "clickstream"
+	UseCaseWeather     = "weather"
+	UseCaseIndustrial  = "industrial"
+	UseCaseSmartHome   = "smarthome"
+	UseCaseNetwork     = "network"
+	UseCaseLogs        = "logs"
+
+	// pointBatchSize is the max number of points requested from a Simulator at once
+	pointBatchSize = 1000
+	writeBufSize   = 4 << 20
+)
+
+// Formats returns the supported output formats, which are the builtin ones
+// plus any others registered with serialize.Register. Formats of a scheme
+// registered with serialize.RegisterScheme, such as FormatExecPrefix, are
+// also supported but cannot be listed.
+func Formats() []string {
+	return serialize.Formats()
+}
+
+// IsFormat returns whether format is a supported output format
+func IsFormat(format string) bool {
+	return serialize.IsFormat(format)
+}
+
+// IsFileFormat returns whether format writes files of a format of their own,
+// e.g., Parquet files, which must be written to a file and without a Header,
+// see serialize.MarkFileFormat
+func IsFileFormat(format string) bool {
+	return serialize.IsFileFormat(format)
+}
+
+// HasHeader returns whether data in format starts with a Header, which is
+// only the case for formats read by a tsbs loader that checks it, see
+// serialize.MarkHeaderFormat
+func HasHeader(format string) bool {
+	return serialize.HasHeader(format)
+}
+
+// UseCases returns the supported use cases
+func UseCases() []string {
+	return []string{UseCaseCPUOnly, UseCaseCPUSingle, UseCaseDevops, UseCaseFinance, UseCaseKubernetes, UseCaseClickstream, UseCaseWeather, UseCaseIndustrial, UseCaseSmartHome, UseCaseNetwork, UseCaseLogs}
+}
+
+var useCaseDescriptions = map[string]string{
+	UseCaseCPUOnly:     "10 CPU metrics per host and reading",
+	UseCaseCPUSingle:   "A single CPU metric per host and reading",
+	UseCaseDevops:      "100 metrics of 9 systems (CPU, memory, disk, etc) per host and reading",
+	UseCaseFinance:     "Trade and quote ticks per stock symbol during market hours",
+	UseCaseKubernetes:  "cAdvisor-style metrics per node and its churning pods and containers",
+	UseCaseClickstream: "Page view and session events per website, with a daily traffic cycle",
+	UseCaseWeather:     "Temperature, humidity, pressure and wind per weather station",
+	UseCaseIndustrial:  "Vibration, temperature and power per factory machine, with maintenance downtime",
+	UseCaseSmartHome:   "Thermostat, smart plug and motion sensor reports per home, at intervals of their own",
+	UseCaseNetwork:     "Wrapping interface counters per router and switch of a network's sites",
+	UseCaseLogs:        "Structured log lines per service host, at a daily cycle with bursts of errors",
+}
+
+// UseCaseDescription returns a one line description of a use case, or "" if
+// it is not supported
+func UseCaseDescription(useCase string) string {
+	return useCaseDescriptions[useCase]
+}
+
+// GeneratorConfig holds the options for generating a dataset
+type GeneratorConfig struct {
+	// Format is the output format, one of Formats() or a FormatExecPrefix
+	// format
+	Format string
+	// UseCase is the use case to model, one of UseCases()
+	UseCase string
+	// Scale is the use case specific scaling variable, e.g., the number of
+	// hosts in the devops use case
+	Scale uint64
+	// InitialScale is the scale at the start of the simulation, which grows
+	// linearly to Scale by the end. 0 means to start at Scale
+	InitialScale uint64
+	// Seed is the seed for all random values generated
+	Seed int64
+	// RNG, if set, is the source of all random values generated instead of
+	// rng.New(Seed), e.g., one split from another RNG for each of several
+	// Generators. Seed is still recorded in the Header. Each Generate call
+	// advances it, so it must not be shared with anything else.
+	RNG rng.RNG
+	// TimestampStart and TimestampEnd bound the simulated time
+	TimestampStart time.Time
+	TimestampEnd   time.Time
+	// LogInterval is the simulated time between successive readings of a
+	// series
+	LogInterval time.Duration
+	// InterleavedGroupID and InterleavedNumGroups split the points between
+	// multiple generators in a round-robin manner; only points belonging to
+	// group InterleavedGroupID (0-indexed) are written. A InterleavedNumGroups
+	// of 0 is treated as 1, i.e., all points are written
+	InterleavedGroupID   uint
+	InterleavedNumGroups uint
+	// OmitHeader leaves out the Header that otherwise starts the output of
+	// formats read by a tsbs loader (see HasHeader), e.g., for data fed
+	// directly to a database instead
+	OmitHeader bool
+	// Transformer, if set, modifies every point before it is serialized
+	Transformer PointTransformer
+	// Hooks, if set, are called as generation progresses
+	Hooks *Hooks
+	// HostConstructor, if set, replaces the use case's constructor of the
+	// simulated hosts, e.g., to add measurements with
+	// devops.NewHostConstructor. The cpu-only and cpu-single use cases only
+	// simulate the first measurement of each host, and use cases without
+	// hosts, such as finance, ignore it.
+	HostConstructor devops.HostConstructor
+	// PodsPerNode, if set, is the distribution of the number of pods on each
+	// node of the kubernetes use case, as parsed by
+	// kubernetes.ParsePodsPerNode, instead of kubernetes.DefaultPodsPerNode
+	PodsPerNode string
+}
+
+// Validate checks that the config is usable, returning an error describing
+// the first problem found
+func (c *GeneratorConfig) Validate() error {
+	if !IsFormat(c.Format) {
+		return suggest.Error("format", c.Format, Formats())
+	}
+	if !contains(UseCases(), c.UseCase) {
+		return suggest.Error("use case", c.UseCase, UseCases())
+	}
+	if c.Scale == 0 {
+		return fmt.Errorf("scale must be greater than 0")
+	}
+	if c.LogInterval <= 0 {
+		return fmt.Errorf("log interval must be greater than 0: %v", c.LogInterval)
+	}
+	if !c.TimestampEnd.After(c.TimestampStart) {
+		return fmt.Errorf("end timestamp %v is not after start timestamp %v", c.TimestampEnd, c.TimestampStart)
+	}
+	if len(c.PodsPerNode) > 0 {
+		if _, err := kubernetes.ParsePodsPerNode(c.PodsPerNode); err != nil {
+			return err
+		}
+	}
+	if c.InterleavedNumGroups > 0 && c.InterleavedGroupID >= c.InterleavedNumGroups {
+		return fmt.Errorf("incorrect interleaved groups configuration: id %d >= total groups %d", c.InterleavedGroupID, c.InterleavedNumGroups)
+	}
+	return nil
+}
+
+// Generator generates datasets for a GeneratorConfig
+type Generator struct {
+	config GeneratorConfig
+}
+
+// NewGenerator returns a Generator for the given config, or an error if the
+// config is invalid
+func NewGenerator(config GeneratorConfig) (*Generator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.InitialScale == 0 {
+		config.InitialScale = config.Scale
+	}
+	if config.InterleavedNumGroups == 0 {
+		config.InterleavedNumGroups = 1
+	}
+	return &Generator{config: config}, nil
+}
+
+// Generate writes the whole dataset to w. Generating the same config again
+// gives byte-identical output.
+//
+// If ctx is cancelled, generation stops after the current batch of points
+// and the points already generated are flushed to w before returning
+// ctx.Err().
+//
+// Generate does not use the global math/rand source or any other global
+// state, so Generators may run concurrently.
+func (g *Generator) Generate(ctx context.Context, w io.Writer) error {
+	c := g.config
+	out := bufio.NewWriterSize(w, writeBufSize)
+	simConfig, err := c.simulatorConfig()
+	if err != nil {
+		return err
+	}
+	sim := simConfig.ToSimulator(c.LogInterval)
+	if !c.OmitHeader {
+		if err := WriteHeader(out, c.Format, sim, c.Seed); err != nil {
+			return err
+		}
+	}
+	serializer, err := NewSerializer(c.Format, sim, out)
+	if err != nil {
+		return err
+	}
+	base := serializer
+	if c.Transformer != nil {
+		serializer = NewTransformingSerializer(c.Transformer, serializer)
+	}
+	err = RunWithHooks(ctx, sim, serializer, out, c.InterleavedGroupID, c.InterleavedNumGroups, c.Hooks)
+	if closeErr := closeSerializer(base); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	if err != nil && err != ctx.Err() {
+		return err
+	}
+	if flushErr := out.Flush(); flushErr != nil {
+		return flushErr
+	}
+	return err
+}
+
+// simulatorConfig returns the SimulatorConfig of the use case, with the
+// HostConstructor and PodsPerNode replaced if set
+func (c *GeneratorConfig) simulatorConfig() (common.SimulatorConfig, error) {
+	r := c.RNG
+	if r == nil {
+		r = rng.New(c.Seed)
+	}
+	simConfig, err := NewSimulatorConfig(c.UseCase, c.TimestampStart, c.TimestampEnd, c.InitialScale, c.Scale, r)
+	if err != nil {
+		return nil, err
+	}
+	switch sc := simConfig.(type) {
+	case *devops.DevopsSimulatorConfig:
+		if c.HostConstructor != nil {
+			sc.HostConstructor = c.HostConstructor
+		}
+	case *devops.CPUOnlySimulatorConfig:
+		if c.HostConstructor != nil {
+			sc.HostConstructor = c.HostConstructor
+		}
+	case *kubernetes.SimulatorConfig:
+		if len(c.PodsPerNode) > 0 {
+			if sc.PodsPerNode, err = kubernetes.ParsePodsPerNode(c.PodsPerNode); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return simConfig, nil
+}
+
+// NewSimulatorConfig returns the SimulatorConfig for a use case, simulating
+// from start to end while scaling from initialScale to scale, with all random
+// values drawn from r
+func NewSimulatorConfig(useCase string, start, end time.Time, initialScale, scale uint64, r rng.RNG) (common.SimulatorConfig, error) {
+	switch useCase {
+	case UseCaseDevops:
+		return &devops.DevopsSimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitHostCount:   initialScale,
+			HostCount:       scale,
+			HostConstructor: devops.NewHost,
+			RNG:             r,
+		}, nil
+	case UseCaseCPUOnly:
+		return &devops.CPUOnlySimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitHostCount:   initialScale,
+			HostCount:       scale,
+			HostConstructor: devops.NewHostCPUOnly,
+			RNG:             r,
+		}, nil
+	case UseCaseCPUSingle:
+		return &devops.CPUOnlySimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitHostCount:   initialScale,
+			HostCount:       scale,
+			HostConstructor: devops.NewHostCPUSingle,
+			RNG:             r,
+		}, nil
+	case UseCaseFinance:
+		return &finance.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitSymbolCount: initialScale,
+			SymbolCount:     scale,
+			RNG:             r,
+		}, nil
+	case UseCaseKubernetes:
+		return &kubernetes.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitNodeCount: initialScale,
+			NodeCount:     scale,
+			RNG:           r,
+		}, nil
+	case UseCaseClickstream:
+		return &clickstream.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitSiteCount: initialScale,
+			SiteCount:     scale,
+			RNG:           r,
+		}, nil
+	case UseCaseWeather:
+		return &weather.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitStationCount: initialScale,
+			StationCount:     scale,
+			RNG:              r,
+		}, nil
+	case UseCaseIndustrial:
+		return &industrial.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitMachineCount: initialScale,
+			MachineCount:     scale,
+			RNG:              r,
+		}, nil
+	case UseCaseSmartHome:
+		return &smarthome.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitHomeCount: initialScale,
+			HomeCount:     scale,
+			RNG:           r,
+		}, nil
+	case UseCaseNetwork:
+		return &network.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitDeviceCount: initialScale,
+			DeviceCount:     scale,
+			RNG:             r,
+		}, nil
+	case UseCaseLogs:
+		return &logs.SimulatorConfig{
+			Start: start,
+			End:   end,
+
+			InitHostCount: initialScale,
+			HostCount:     scale,
+			RNG:           r,
+		}, nil
+	default:
+		return nil, suggest.Error("use case", useCase, UseCases())
+	}
+}
+
+// WriteHeader writes the serialize.Header describing data in format generated
+// by sim with the given seed to w. It must be written before anything else,
+// including the header of the format written by NewSerializer. Nothing is
+// written for formats without a Header (see HasHeader), which are read by
+// tools that would take it for data.
+func WriteHeader(w io.Writer, format string, sim common.Simulator, seed int64) error {
+	if !HasHeader(format) {
+		return nil
+	}
+	return serialize.WriteHeader(w, &serialize.Header{
+		Format:           format,
+		GeneratorVersion: GeneratorVersion,
+		Seed:             seed,
+		SchemaHash:       sim.Fields().Hash(),
+	})
+}
+
+// NewSerializer returns the PointSerializer for a format. Formats that start
+// with a header (e.g., TimescaleDB's list of tables) have it written to w
+// based on the measurements of sim. Serializers that implement io.Closer
+// (e.g., those of FormatExecPrefix formats) must be closed once all points are
+// serialized, before w is flushed.
+func NewSerializer(format string, sim common.Simulator, w io.Writer) (serialize.PointSerializer, error) {
+	return serialize.New(format, sim.Fields(), w)
+}
+
+// Run generates all points from sim and writes those belonging to group
+// groupID of totalGroups to w using serializer. If ctx is cancelled, it
+// stops after the current batch and returns ctx.Err(); anything buffered in
+// w is left for the caller to flush.
+func Run(ctx context.Context, sim common.Simulator, serializer serialize.PointSerializer, w io.Writer, groupID, totalGroups uint) error {
+	return RunWithHooks(ctx, sim, serializer, w, groupID, totalGroups, nil)
+}
+
+// RunWithHooks is like Run, additionally calling hooks as generation
+// progresses. hooks may be nil.
+func RunWithHooks(ctx context.Context, sim common.Simulator, serializer serialize.PointSerializer, w io.Writer, groupID, totalGroups uint, hooks *Hooks) error {
+	currGroup := uint(0)
+	points := make([]serialize.Point, pointBatchSize)
+	// serializers that can, are given each batch in columnar form at once
+	batchSerializer, useBatch := serializer.(serialize.BatchSerializer)
+	batch := serialize.NewPointBatch()
+	serializeBatch := func() error {
+		if !useBatch || batch.Len() == 0 {
+			return nil
+		}
+		err := batchSerializer.SerializeBatch(batch, w)
+		batch.Reset()
+		return err
+	}
+	h := newHookState(hooks, w)
+	callHooks := func() error {
+		if err := serializeBatch(); err != nil {
+			return err
+		}
+		return h.call()
+	}
+	for !sim.Finished() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := sim.NextBatch(ctx, points)
+		for i := 0; i < n; i++ {
+			// in the default case this is always true
+			if currGroup == groupID {
+				p := &points[i]
+				if h != nil && h.startPoint(p) {
+					if err := callHooks(); err != nil {
+						return err
+					}
+				}
+				if useBatch {
+					batch.Append(p)
+				} else if err := serializer.Serialize(p, w); err != nil {
+					return err
+				}
+				if h != nil && h.endPoint() {
+					if err := callHooks(); err != nil {
+						return err
+					}
+				}
+			}
+
+			currGroup = (currGroup + 1) % totalGroups
+		}
+		if err := serializeBatch(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeSerializer closes serializer if it is an io.Closer
+func closeSerializer(serializer serialize.PointSerializer) error {
+	if c, ok := serializer.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+func contains(choices []string, s string) bool {
+	for _, c := range choices {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,382 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"math/rand"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/devops"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/avro"
+	"github.com/timescale/tsbs/pkg/data/serialize/parquet"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func testGeneratorConfig() GeneratorConfig {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	return GeneratorConfig{
+		Format:         FormatInflux,
+		UseCase:        UseCaseDevops,
+		Scale:          3,
+		Seed:           123,
+		TimestampStart: start,
+		TimestampEnd:   start.Add(time.Minute),
+		LogInterval:    10 * time.Second,
+	}
+}
+
+func TestGeneratorConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc      string
+		modify    func(c *GeneratorConfig)
+		errPrefix string
+	}{
+		{
+			desc:   "valid config",
+			modify: func(c *GeneratorConfig) {},
+		},
+		{
+			desc:      "invalid format",
+			modify:    func(c *GeneratorConfig) { c.Format = "bogus" },
+			errPrefix: "unknown format",
+		},
+		{
+			desc:   "exec format",
+			modify: func(c *GeneratorConfig) { c.Format = FormatExecPrefix + "cat" },
+		},
+		{
+			desc:      "exec format without command",
+			modify:    func(c *GeneratorConfig) { c.Format = FormatExecPrefix },
+			errPrefix: "unknown format",
+		},
+		{
+			desc:      "invalid use case",
+			modify:    func(c *GeneratorConfig) { c.UseCase = "bogus" },
+			errPrefix: "unknown use case",
+		},
+		{
+			desc:      "0 scale",
+			modify:    func(c *GeneratorConfig) { c.Scale = 0 },
+			errPrefix: "scale must be",
+		},
+		{
+			desc:      "0 log interval",
+			modify:    func(c *GeneratorConfig) { c.LogInterval = 0 },
+			errPrefix: "log interval must be",
+		},
+		{
+			desc:      "end before start",
+			modify:    func(c *GeneratorConfig) { c.TimestampEnd = c.TimestampStart.Add(-time.Second) },
+			errPrefix: "end timestamp",
+		},
+		{
+			desc: "pods per node",
+			modify: func(c *GeneratorConfig) {
+				c.UseCase = UseCaseKubernetes
+				c.PodsPerNode = "uniform:10,20"
+			},
+		},
+		{
+			desc:      "invalid pods per node",
+			modify:    func(c *GeneratorConfig) { c.PodsPerNode = "poisson:10" },
+			errPrefix: "invalid pods per node",
+		},
+		{
+			desc: "group id too large",
+			modify: func(c *GeneratorConfig) {
+				c.InterleavedGroupID = 2
+				c.InterleavedNumGroups = 2
+			},
+			errPrefix: "incorrect interleaved groups",
+		},
+	}
+	for _, c := range cases {
+		cfg := testGeneratorConfig()
+		c.modify(&cfg)
+		err := cfg.Validate()
+		if c.errPrefix == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		} else if c.errPrefix != "" {
+			if err == nil {
+				t.Errorf("%s: did not error when should", c.desc)
+			} else if !strings.HasPrefix(err.Error(), c.errPrefix) {
+				t.Errorf("%s: incorrect error: got %s want prefix %s", c.desc, err.Error(), c.errPrefix)
+			}
+		}
+		if _, err := NewGenerator(cfg); (err == nil) != (c.errPrefix == "") {
+			t.Errorf("%s: NewGenerator error does not match Validate: %v", c.desc, err)
+		}
+	}
+}
+
+func TestGeneratorGenerate(t *testing.T) {
+	for _, format := range Formats() {
+		cfg := testGeneratorConfig()
+		cfg.Format = format
+		g, err := NewGenerator(cfg)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+
+		var first, second bytes.Buffer
+		if err := g.Generate(context.Background(), &first); err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		if first.Len() == 0 {
+			t.Errorf("%s: no output generated", format)
+		}
+		// the output should not depend on the global sources
+		rand.Seed(1)
+		common.Seed(1)
+		if err := g.Generate(context.Background(), &second); err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Errorf("%s: output differs between runs with the same config", format)
+		}
+	}
+}
+
+func TestGeneratorGenerateHostConstructor(t *testing.T) {
+	cfg := testGeneratorConfig()
+	cfg.UseCase = UseCaseCPUOnly
+	cfg.OmitHeader = true
+	cfg.HostConstructor = devops.NewHostConstructor(func(r *common.Rand, start time.Time) []common.SimulatedMeasurement {
+		return []common.SimulatedMeasurement{
+			devops.NewMeasurement(r, start, []byte("gpu"), []devops.FieldDistribution{
+				{Name: []byte("usage"), Make: func(r rng.RNG) common.Distribution { return common.CWD(common.ND(0, 1), 0, 100, 100*r.Float64()) }},
+			}),
+		}
+	})
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(context.Background(), &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	points := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") || len(line) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(line, "gpu,hostname=host_") {
+			t.Errorf("incorrect point: %s", line)
+		}
+		points++
+	}
+	// 3 hosts with a reading every 10s for a minute
+	if points != 18 {
+		t.Errorf("incorrect number of points: got %d want 18", points)
+	}
+}
+
+func TestGeneratorGenerateExec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	cfg := testGeneratorConfig()
+	cfg.OmitHeader = true
+	// wc counts the bytes of every frame, so the output is the length of
+	// the stream and not the stream itself
+	cfg.Format = FormatExecPrefix + "wc -c"
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(context.Background(), &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(buf.String()))
+	if err != nil {
+		t.Fatalf("unexpected output %q: %v", buf.String(), err)
+	}
+	// 3 hosts with 9 measurements every 10s for a minute, each frame being
+	// well over 50 bytes
+	if n < 3*9*6*50 {
+		t.Errorf("stream too short: got %d bytes", n)
+	}
+
+	cfg.Format = FormatExecPrefix + "exit 1"
+	g, err = NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := g.Generate(context.Background(), &buf); err == nil {
+		t.Errorf("did not error when command failed")
+	}
+}
+
+func TestGeneratorGenerateGroups(t *testing.T) {
+	countLines := func(cfg GeneratorConfig) int {
+		g, err := NewGenerator(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := g.Generate(context.Background(), &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return strings.Count(buf.String(), "\n")
+	}
+
+	// the binary header may contain newlines, and is repeated for each group
+	cfg := testGeneratorConfig()
+	cfg.OmitHeader = true
+	want := countLines(cfg)
+	got := 0
+	cfg.InterleavedNumGroups = 3
+	for i := uint(0); i < cfg.InterleavedNumGroups; i++ {
+		cfg.InterleavedGroupID = i
+		got += countLines(cfg)
+	}
+	if got != want {
+		t.Errorf("groups do not add up to the whole dataset: got %d lines want %d", got, want)
+	}
+}
+
+func TestGeneratorGenerateCancelled(t *testing.T) {
+	cfg := testGeneratorConfig()
+	cfg.OmitHeader = true
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := g.Generate(ctx, &buf); err != context.Canceled {
+		t.Errorf("incorrect error: got %v want %v", err, context.Canceled)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("cancelled generator wrote output: got %d bytes", buf.Len())
+	}
+}
+
+func TestGeneratorGenerateHeader(t *testing.T) {
+	for _, omit := range []bool{false, true} {
+		cfg := testGeneratorConfig()
+		cfg.OmitHeader = omit
+		g, err := NewGenerator(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := g.Generate(context.Background(), &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		h, err := serialize.ReadHeader(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("unexpected error reading header: %v", err)
+		}
+		if omit {
+			if h != nil {
+				t.Errorf("header written when omitted: %v", h)
+			}
+			continue
+		}
+		if h == nil {
+			t.Fatalf("header not written")
+		}
+		if h.Format != cfg.Format || h.GeneratorVersion != GeneratorVersion || h.Seed != cfg.Seed || h.SchemaHash == 0 {
+			t.Errorf("incorrect header: %v", h)
+		}
+	}
+}
+
+func TestGeneratorGenerateFileFormats(t *testing.T) {
+	// files start with the magic number of their format, which readers
+	// check, rather than a Header
+	cases := []struct {
+		format string
+		magic  string
+	}{
+		{FormatArrow, "\xff\xff\xff\xff"},
+		{FormatAvro, avro.Magic},
+		{FormatParquet, parquet.Magic},
+	}
+	for _, c := range cases {
+		if !IsFileFormat(c.format) {
+			t.Errorf("%s: not a file format", c.format)
+		}
+		cfg := testGeneratorConfig()
+		cfg.Format = c.format
+		g, err := NewGenerator(cfg)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.format, err)
+		}
+		var buf bytes.Buffer
+		if err := g.Generate(context.Background(), &buf); err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.format, err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte(c.magic)) {
+			t.Errorf("%s: output does not start with the magic number %q: %q", c.format, c.magic, buf.Bytes()[:8])
+		}
+	}
+}
+
+func TestHasHeader(t *testing.T) {
+	// the formats read by the tsbs loaders, which check the Header
+	loaderFormats := map[string]bool{
+		FormatADX:         true,
+		FormatADXJSON:     true,
+		FormatBigQuery:    true,
+		FormatBigtable:    true,
+		FormatCassandra:   true,
+		FormatInflux:      true,
+		FormatM3DB:        true,
+		FormatMongo:       true,
+		FormatMySQL:       true,
+		FormatOTLP:        true,
+		FormatPinot:       true,
+		FormatTimescaleDB: true,
+		FormatTimestream:  true,
+	}
+	for _, format := range Formats() {
+		if got := HasHeader(format); got != loaderFormats[format] {
+			t.Errorf("%s: incorrect HasHeader: got %t want %t", format, got, loaderFormats[format])
+		}
+		if HasHeader(format) && IsFileFormat(format) {
+			t.Errorf("%s: file format has a header", format)
+		}
+
+		cfg := testGeneratorConfig()
+		cfg.Format = format
+		g, err := NewGenerator(cfg)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		var buf bytes.Buffer
+		if err := g.Generate(context.Background(), &buf); err != nil {
+			t.Fatalf("%s: unexpected error: %v", format, err)
+		}
+		h, err := serialize.ReadHeader(bufio.NewReader(&buf))
+		if err != nil {
+			t.Fatalf("%s: unexpected error reading header: %v", format, err)
+		}
+		if (h != nil) != loaderFormats[format] {
+			t.Errorf("%s: incorrect header written: got %v", format, h)
+		}
+	}
+}
+
+func TestUseCaseDescription(t *testing.T) {
+	for _, useCase := range UseCases() {
+		if UseCaseDescription(useCase) == "" {
+			t.Errorf("use case '%s' has no description", useCase)
+		}
+	}
+	if got := UseCaseDescription("bogus"); got != "" {
+		t.Errorf("incorrect description of unknown use case: got %q", got)
+	}
+}
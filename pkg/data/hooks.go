@@ -0,0 +1,103 @@
+package data
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+const day = 24 * time.Hour
+
+// Hooks are called as generation progresses so external systems can act on
+// the data generated so far, e.g., to rotate output files, trigger flushes
+// or start loading. Any of the hooks may be nil, and an error returned by a
+// hook stops generation with that error.
+//
+// Hooks are called between points, after everything before them has been
+// serialized and, if the output implements interface{ Flush() error }, flushed.
+// Points held back by the serializer itself (e.g., those of a subprocess)
+// may not have reached the output yet.
+type Hooks struct {
+	// OnEpochStart is called with the simulated time of each epoch, i.e.,
+	// reading of all series, before its first point is written
+	OnEpochStart func(t time.Time) error
+	// OnSimulatedDay is called with the start (UTC midnight) of each
+	// simulated day before its first point is written, including the day
+	// generation starts in
+	OnSimulatedDay func(day time.Time) error
+	// OnNPoints is called every N points with the number of points written
+	// so far; it is not called if N is 0
+	OnNPoints func(n uint64) error
+	N         uint64
+}
+
+// hookState tracks the progress of generation for calling Hooks
+type hookState struct {
+	hooks *Hooks
+	flush func() error
+
+	started bool
+	epoch   int64
+	day     time.Time
+	points  uint64
+
+	// the hooks due to be called
+	epochDue, dayDue, pointsDue bool
+}
+
+func newHookState(hooks *Hooks, w interface{}) *hookState {
+	if hooks == nil {
+		return nil
+	}
+	h := &hookState{hooks: hooks, flush: func() error { return nil }}
+	if f, ok := w.(interface{ Flush() error }); ok {
+		h.flush = f.Flush
+	}
+	return h
+}
+
+// startPoint notes that p is about to be written, returning whether any
+// hooks are due before it
+func (h *hookState) startPoint(p *serialize.Point) bool {
+	ts := p.Timestamp()
+	if h.started && ts == h.epoch {
+		return false
+	}
+	newDay := time.Unix(0, ts).UTC().Truncate(day)
+	h.dayDue = h.hooks.OnSimulatedDay != nil && (!h.started || !newDay.Equal(h.day))
+	h.epochDue = h.hooks.OnEpochStart != nil
+	h.started, h.epoch, h.day = true, ts, newDay
+	return h.dayDue || h.epochDue
+}
+
+// endPoint notes that a point was written, returning whether any hooks are
+// due after it
+func (h *hookState) endPoint() bool {
+	h.points++
+	h.pointsDue = h.hooks.OnNPoints != nil && h.hooks.N > 0 && h.points%h.hooks.N == 0
+	return h.pointsDue
+}
+
+// call flushes the output and calls the hooks that are due
+func (h *hookState) call() error {
+	if err := h.flush(); err != nil {
+		return err
+	}
+	if h.pointsDue {
+		h.pointsDue = false
+		if err := h.hooks.OnNPoints(h.points); err != nil {
+			return err
+		}
+	}
+	if h.dayDue {
+		h.dayDue = false
+		if err := h.hooks.OnSimulatedDay(h.day); err != nil {
+			return err
+		}
+	}
+	if h.epochDue {
+		h.epochDue = false
+		return h.hooks.OnEpochStart(time.Unix(0, h.epoch).UTC())
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGeneratorGenerateHooks(t *testing.T) {
+	start := time.Date(2016, 1, 1, 23, 59, 40, 0, time.UTC)
+	cfg := testGeneratorConfig()
+	cfg.UseCase = UseCaseCPUOnly
+	cfg.Scale = 2
+	cfg.OmitHeader = true
+	cfg.TimestampStart = start
+	cfg.TimestampEnd = start.Add(40 * time.Second)
+
+	var buf bytes.Buffer
+	var events []string
+	// each hook records the number of lines (i.e., points) already written,
+	// which checks that the output was flushed before the hook was called
+	record := func(format string, v interface{}) error {
+		events = append(events, fmt.Sprintf(format+" after %d", v, bytes.Count(buf.Bytes(), []byte("\n"))))
+		return nil
+	}
+	cfg.Hooks = &Hooks{
+		OnEpochStart:   func(t time.Time) error { return record("epoch %s", t.Format("15:04:05")) },
+		OnSimulatedDay: func(d time.Time) error { return record("day %s", d.Format("2006-01-02")) },
+		OnNPoints:      func(n uint64) error { return record("points %d", n) },
+		N:              3,
+	}
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := g.Generate(context.Background(), &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 2 hosts each write a point every 10s
+	want := []string{
+		"day 2016-01-01 after 0",
+		"epoch 23:59:40 after 0",
+		"epoch 23:59:50 after 2",
+		"points 3 after 3",
+		"day 2016-01-02 after 4",
+		"epoch 00:00:00 after 4",
+		"points 6 after 6",
+		"epoch 00:00:10 after 6",
+	}
+	if got := fmt.Sprint(events); got != fmt.Sprint(want) {
+		t.Errorf("incorrect hook calls:\ngot  %v\nwant %v", events, want)
+	}
+
+	hookErr := fmt.Errorf("hook failed")
+	cfg.Hooks = &Hooks{
+		OnNPoints: func(n uint64) error { return hookErr },
+		N:         1,
+	}
+	g, err = NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := g.Generate(context.Background(), &buf); err != hookErr {
+		t.Errorf("incorrect error: got %v want %v", err, hookErr)
+	}
+}
@@ -0,0 +1,444 @@
+package importer
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"gopkg.in/yaml.v3"
+)
+
+// Time formats of CSVTime, besides Go layouts
+const (
+	TimeRFC3339    = "rfc3339"
+	TimeUnix       = "unix"
+	TimeUnixMillis = "unix_ms"
+	TimeUnixMicros = "unix_us"
+	TimeUnixNanos  = "unix_ns"
+)
+
+// Types of the values of CSV fields
+const (
+	TypeFloat  = "float"
+	TypeInt    = "int"
+	TypeBool   = "bool"
+	TypeString = "string"
+)
+
+var fieldTypes = map[string]serialize.FieldType{
+	TypeFloat:  serialize.FieldTypeFloat,
+	TypeInt:    serialize.FieldTypeInt,
+	TypeBool:   serialize.FieldTypeBool,
+	TypeString: serialize.FieldTypeString,
+}
+
+// CSVMapping maps the columns of a CSV file to the timestamp, tags and
+// fields of points, each row giving a point of each measurement. It is read
+// from a YAML or JSON file by LoadCSVMapping, e.g.:
+//
+//	time:
+//	  column: ts
+//	  format: unix_ms
+//	tags:
+//	  - column: host
+//	    name: hostname
+//	measurements:
+//	  - name: cpu
+//	    fields:
+//	      - column: user
+//	        name: usage_user
+//	      - column: state
+//	        type: string
+//	        default: unknown
+type CSVMapping struct {
+	// Delimiter separates the columns; it is a comma by default
+	Delimiter string `yaml:"delimiter"`
+	// Columns names the columns of files without a header line; without
+	// them, the first line of the file names the columns
+	Columns      []string         `yaml:"columns"`
+	Time         CSVTime          `yaml:"time"`
+	Tags         []CSVColumn      `yaml:"tags"`
+	Measurements []CSVMeasurement `yaml:"measurements"`
+}
+
+// CSVTime is the column of the timestamps of the rows and their format:
+// rfc3339 (the default), unix, unix_ms, unix_us or unix_ns, as the
+// seconds, which may have a fraction, or the milli-, micro- or nanoseconds
+// since the Unix epoch, or else a Go time layout, read in UTC unless it has
+// a zone
+type CSVTime struct {
+	Column string `yaml:"column"`
+	Format string `yaml:"format"`
+}
+
+// CSVColumn is a column that is a tag or a field
+type CSVColumn struct {
+	Column string `yaml:"column"`
+	// Name is the key of the tag or field; it is the column by default
+	Name string `yaml:"name"`
+	// Type is that of the values of a field: float (the default), int,
+	// bool or string. Tags are strings.
+	Type string `yaml:"type"`
+	// Default is the value of a field of rows where its column is empty,
+	// which is otherwise an error. Rows do not have the tags whose column
+	// is empty.
+	Default string `yaml:"default"`
+}
+
+// CSVMeasurement is a measurement and the columns of its fields
+type CSVMeasurement struct {
+	Name   string      `yaml:"name"`
+	Fields []CSVColumn `yaml:"fields"`
+}
+
+// LoadCSVMapping reads and checks the CSVMapping in file
+func LoadCSVMapping(file string) (*CSVMapping, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CSV mapping: %v", err)
+	}
+	m := &CSVMapping{}
+	if err := yaml.Unmarshal(buf, m); err != nil {
+		return nil, fmt.Errorf("invalid CSV mapping %s: %v", file, err)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid CSV mapping %s: %v", file, err)
+	}
+	return m, nil
+}
+
+// Validate checks that m is usable, returning an error describing the
+// first problem found
+func (m *CSVMapping) Validate() error {
+	if len(m.Delimiter) > 0 && utf8.RuneCountInString(m.Delimiter) != 1 {
+		return fmt.Errorf("delimiter must be a single character: '%s'", m.Delimiter)
+	}
+	if len(m.Time.Column) == 0 {
+		return fmt.Errorf("no time column")
+	}
+	if len(m.Measurements) == 0 {
+		return fmt.Errorf("no measurements")
+	}
+	tags := map[string]bool{}
+	for _, t := range m.Tags {
+		if len(t.Column) == 0 {
+			return fmt.Errorf("tag without a column")
+		}
+		name := t.name()
+		if tags[name] {
+			return fmt.Errorf("duplicate tag '%s'", name)
+		}
+		tags[name] = true
+	}
+	measurements := map[string]bool{}
+	for _, meas := range m.Measurements {
+		if len(meas.Name) == 0 {
+			return fmt.Errorf("measurement without a name")
+		}
+		if measurements[meas.Name] {
+			return fmt.Errorf("duplicate measurement '%s'", meas.Name)
+		}
+		measurements[meas.Name] = true
+		if len(meas.Fields) == 0 {
+			return fmt.Errorf("measurement '%s' has no fields", meas.Name)
+		}
+		fields := map[string]bool{}
+		for _, f := range meas.Fields {
+			if len(f.Column) == 0 {
+				return fmt.Errorf("field of measurement '%s' without a column", meas.Name)
+			}
+			name := f.name()
+			if fields[name] {
+				return fmt.Errorf("duplicate field '%s' of measurement '%s'", name, meas.Name)
+			}
+			fields[name] = true
+			if _, ok := fieldTypes[f.fieldType()]; !ok {
+				return fmt.Errorf("invalid type '%s' of field '%s': must be float, int, bool or string", f.Type, name)
+			}
+			if len(f.Default) > 0 {
+				if _, err := parseValue(f.Default, f.fieldType()); err != nil {
+					return fmt.Errorf("invalid default of field '%s': %v", name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *CSVColumn) name() string {
+	if len(c.Name) > 0 {
+		return c.Name
+	}
+	return c.Column
+}
+
+func (c *CSVColumn) fieldType() string {
+	if len(c.Type) > 0 {
+		return c.Type
+	}
+	return TypeFloat
+}
+
+// parseValue parses the value of a field of type typ
+func parseValue(s, typ string) (interface{}, error) {
+	switch typ {
+	case TypeInt:
+		return strconv.ParseInt(s, 10, 64)
+	case TypeBool:
+		return strconv.ParseBool(s)
+	case TypeString:
+		return s, nil
+	default:
+		return strconv.ParseFloat(s, 64)
+	}
+}
+
+// parseTime parses a timestamp in format as nanoseconds since the Unix
+// epoch
+func parseTime(s, format string) (int64, error) {
+	var scale int64
+	switch format {
+	case "", TimeRFC3339:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t.UnixNano(), err
+	case TimeUnix:
+		// the fraction is parsed as nanoseconds, which floats cannot hold
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			frac := s[i+1:]
+			if len(frac) == 0 || len(frac) > 9 || strings.Trim(frac, "0123456789") != "" {
+				return 0, fmt.Errorf("invalid fraction of seconds: %s", s)
+			}
+			secs, err := strconv.ParseInt(s[:i], 10, 64)
+			nanos, _ := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+			if strings.HasPrefix(s, "-") {
+				nanos = -nanos
+			}
+			return secs*1e9 + nanos, err
+		}
+		scale = 1e9
+	case TimeUnixMillis:
+		scale = 1e6
+	case TimeUnixMicros:
+		scale = 1e3
+	case TimeUnixNanos:
+		scale = 1
+	default:
+		t, err := time.Parse(format, s)
+		return t.UnixNano(), err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n * scale, err
+}
+
+// csvField is a field column of a CSV file, resolved to its index
+type csvField struct {
+	column int
+	key    []byte
+	typ    string
+	// def is the default value, or nil if there is none
+	def interface{}
+}
+
+// csvMeasurement is a measurement of a CSV file, with its fields resolved
+type csvMeasurement struct {
+	name   []byte
+	fields []csvField
+}
+
+// CSVImporter is an Importer reading the points of a CSV file, as mapped
+// by a CSVMapping
+type CSVImporter struct {
+	r      *csv.Reader
+	closer io.Closer
+	schema *serialize.Schema
+
+	timeColumn int
+	timeFormat string
+	tagColumns []int
+	tagKeys    [][]byte
+	// tagValues keeps a single copy of each tag value, which rows share
+	tagValues    *common.Interner
+	measurements []csvMeasurement
+
+	// the row being read, of which the points of measurements[next:] are
+	// still to be read
+	row       []string
+	timestamp int64
+	next      int
+
+	done bool
+	err  error
+}
+
+// NewCSV returns a CSVImporter of the CSV file r, which has its columns
+// named in its first line unless m names them. If r is an io.Closer, it is
+// closed by Close.
+func NewCSV(r io.Reader, m *CSVMapping) (*CSVImporter, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	c := &CSVImporter{
+		r:          csv.NewReader(r),
+		timeFormat: m.Time.Format,
+		tagValues:  common.NewInterner(),
+	}
+	c.closer, _ = r.(io.Closer)
+	if len(m.Delimiter) > 0 {
+		c.r.Comma, _ = utf8.DecodeRuneInString(m.Delimiter)
+	}
+	c.r.ReuseRecord = true
+
+	columnNames := m.Columns
+	if len(columnNames) == 0 {
+		header, err := c.r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("could not read CSV header: %v", err)
+		}
+		columnNames = append([]string(nil), header...)
+	}
+	columns := make(map[string]int, len(columnNames))
+	for i, name := range columnNames {
+		columns[strings.TrimSpace(name)] = i
+	}
+	column := func(name string) (int, error) {
+		i, ok := columns[name]
+		if !ok {
+			return 0, fmt.Errorf("no column '%s' in the CSV file", name)
+		}
+		return i, nil
+	}
+
+	var err error
+	if c.timeColumn, err = column(m.Time.Column); err != nil {
+		return nil, err
+	}
+	for _, t := range m.Tags {
+		i, err := column(t.Column)
+		if err != nil {
+			return nil, err
+		}
+		c.tagColumns = append(c.tagColumns, i)
+		c.tagKeys = append(c.tagKeys, []byte(t.name()))
+	}
+	fieldKeys := make(map[string][][]byte, len(m.Measurements))
+	types := make(map[string][]serialize.FieldType, len(m.Measurements))
+	for _, meas := range m.Measurements {
+		cm := csvMeasurement{name: []byte(meas.Name)}
+		for _, f := range meas.Fields {
+			i, err := column(f.Column)
+			if err != nil {
+				return nil, err
+			}
+			cf := csvField{column: i, key: []byte(f.name()), typ: f.fieldType()}
+			if len(f.Default) > 0 {
+				cf.def, _ = parseValue(f.Default, cf.typ)
+			}
+			cm.fields = append(cm.fields, cf)
+			fieldKeys[meas.Name] = append(fieldKeys[meas.Name], cf.key)
+			types[meas.Name] = append(types[meas.Name], fieldTypes[cf.typ])
+		}
+		c.measurements = append(c.measurements, cm)
+	}
+	c.schema = serialize.NewTypedSchema(c.tagKeys, fieldKeys, types)
+	c.next = len(c.measurements)
+	return c, nil
+}
+
+// Finished returns whether all rows were read, or reading failed
+func (c *CSVImporter) Finished() bool {
+	return c.done || c.err != nil
+}
+
+// Next sets p to the point of the next measurement of the current row, or
+// of the first of the next row
+func (c *CSVImporter) Next(ctx context.Context, p *serialize.Point) bool {
+	if ctx.Err() != nil || c.Finished() {
+		return false
+	}
+	if c.next == len(c.measurements) && !c.readRow() {
+		return false
+	}
+	m := &c.measurements[c.next]
+	p.SetMeasurementName(m.name)
+	p.SetTimestamp(c.timestamp)
+	for i, col := range c.tagColumns {
+		if v := c.row[col]; len(v) > 0 {
+			p.AppendTag(c.tagKeys[i], c.tagValues.Intern([]byte(v)))
+		}
+	}
+	for _, f := range m.fields {
+		s := c.row[f.column]
+		if len(s) == 0 {
+			if f.def == nil {
+				c.fail(f.column, fmt.Errorf("no value of field '%s'", f.key))
+				return false
+			}
+			p.AppendField(f.key, f.def)
+			continue
+		}
+		v, err := parseValue(s, f.typ)
+		if err != nil {
+			c.fail(f.column, fmt.Errorf("invalid value of field '%s': %v", f.key, err))
+			return false
+		}
+		p.AppendField(f.key, v)
+	}
+	c.next++
+	return true
+}
+
+// readRow reads the next row and its timestamp, returning whether it did
+func (c *CSVImporter) readRow() bool {
+	row, err := c.r.Read()
+	if err == io.EOF {
+		c.done = true
+		return false
+	} else if err != nil {
+		c.err = err
+		return false
+	}
+	c.row = row
+	if c.timestamp, err = parseTime(row[c.timeColumn], c.timeFormat); err != nil {
+		c.fail(c.timeColumn, fmt.Errorf("invalid time: %v", err))
+		return false
+	}
+	c.next = 0
+	return true
+}
+
+// fail stops reading with err, at the given column of the current row
+func (c *CSVImporter) fail(column int, err error) {
+	line, _ := c.r.FieldPos(column)
+	c.err = fmt.Errorf("line %d: %v", line, err)
+}
+
+func (c *CSVImporter) NextBatch(ctx context.Context, points []serialize.Point) int {
+	return nextBatch(ctx, c, points)
+}
+
+func (c *CSVImporter) Split(n int) []common.Simulator {
+	return []common.Simulator{c}
+}
+
+func (c *CSVImporter) Fields() *serialize.Schema {
+	return c.schema
+}
+
+func (c *CSVImporter) Err() error {
+	return c.err
+}
+
+func (c *CSVImporter) Close() error {
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+func testMapping() *CSVMapping {
+	return &CSVMapping{
+		Time: CSVTime{Column: "ts", Format: TimeUnixMillis},
+		Tags: []CSVColumn{{Column: "host", Name: "hostname"}, {Column: "region"}},
+		Measurements: []CSVMeasurement{
+			{Name: "cpu", Fields: []CSVColumn{{Column: "user", Name: "usage_user"}, {Column: "state", Type: TypeString, Default: "unknown"}}},
+			{Name: "mem", Fields: []CSVColumn{{Column: "used", Type: TypeInt}, {Column: "swap", Type: TypeBool}}},
+		},
+	}
+}
+
+func TestCSVImporter(t *testing.T) {
+	input := "ts,host,region,user,state,used,swap\n" +
+		"1451606400000,host_0,eu-west-1,58.5,ok,1024,true\n" +
+		"1451606410000,host_1,,3,,2048,false\n"
+	imp, err := NewCSV(strings.NewReader(input), testMapping())
+	if err != nil {
+		t.Fatalf("could not read CSV: %v", err)
+	}
+	schema := imp.Fields()
+	if got := schema.Measurements(); !reflect.DeepEqual(got, []string{"cpu", "mem"}) {
+		t.Errorf("incorrect measurements: %v", got)
+	}
+	wantTypes := []serialize.FieldType{serialize.FieldTypeInt, serialize.FieldTypeBool}
+	if got := schema.FieldTypes("mem"); !reflect.DeepEqual(got, wantTypes) {
+		t.Errorf("incorrect field types: %v", got)
+	}
+
+	want := []string{
+		"cpu,hostname=host_0,region=eu-west-1 usage_user=58.5 state=ok 1451606400000000000",
+		"mem,hostname=host_0,region=eu-west-1 used=1024 swap=true 1451606400000000000",
+		"cpu,hostname=host_1 usage_user=3 state=unknown 1451606410000000000",
+		"mem,hostname=host_1 used=2048 swap=false 1451606410000000000",
+	}
+	if got := readAll(t, imp); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect points:\ngot  %q\nwant %q", got, want)
+	}
+	if imp.Err() != nil {
+		t.Errorf("unexpected error: %v", imp.Err())
+	}
+}
+
+func TestCSVImporterErrors(t *testing.T) {
+	cases := []struct {
+		desc    string
+		input   string
+		wantErr string
+	}{
+		{
+			desc:    "missing column",
+			input:   "ts,host,region,user,state,used\n",
+			wantErr: "no column 'swap'",
+		},
+		{
+			desc:    "invalid time",
+			input:   "ts,host,region,user,state,used,swap\n2016-01-01,host_0,a,1,ok,1,true\n",
+			wantErr: "line 2: invalid time",
+		},
+		{
+			desc:    "invalid value",
+			input:   "ts,host,region,user,state,used,swap\n0,host_0,a,1,ok,1.5,true\n",
+			wantErr: "line 2: invalid value of field 'used'",
+		},
+		{
+			desc:    "no value",
+			input:   "ts,host,region,user,state,used,swap\n0,host_0,a,1,ok,1,true\n0,host_0,a,,ok,1,true\n",
+			wantErr: "line 3: no value of field 'usage_user'",
+		},
+		{
+			desc:    "wrong number of columns",
+			input:   "ts,host,region,user,state,used,swap\n0,host_0,a,1,ok,1\n",
+			wantErr: "wrong number of fields",
+		},
+	}
+	for _, c := range cases {
+		imp, err := NewCSV(strings.NewReader(c.input), testMapping())
+		if err == nil {
+			readAll(t, imp)
+			err = imp.Err()
+		}
+		if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.wantErr)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	cases := []struct {
+		s      string
+		format string
+		want   int64
+	}{
+		{"2016-01-01T00:00:10.5Z", "", 1451606410500000000},
+		{"2016-01-01T01:00:10+01:00", TimeRFC3339, 1451606410000000000},
+		{"1451606410", TimeUnix, 1451606410000000000},
+		{"1451606410.25", TimeUnix, 1451606410250000000},
+		{"-1.5", TimeUnix, -1500000000},
+		{"1451606410250", TimeUnixMillis, 1451606410250000000},
+		{"1451606410250000", TimeUnixMicros, 1451606410250000000},
+		{"1451606410250000000", TimeUnixNanos, 1451606410250000000},
+		{"2016-01-01 00:00:10", "2006-01-02 15:04:05", 1451606410000000000},
+	}
+	for _, c := range cases {
+		got, err := parseTime(c.s, c.format)
+		if err != nil || got != c.want {
+			t.Errorf("%s as %s: got %d %v want %d", c.s, c.format, got, err, c.want)
+		}
+	}
+}
+
+func TestLoadCSVMapping(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs_import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mapping.yaml")
+	mapping := `delimiter: ";"
+columns: [ts, host, user]
+time:
+  column: ts
+  format: unix
+tags:
+  - column: host
+    name: hostname
+measurements:
+  - name: cpu
+    fields:
+      - column: user
+        name: usage_user
+`
+	if err := ioutil.WriteFile(file, []byte(mapping), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := LoadCSVMapping(file)
+	if err != nil {
+		t.Fatalf("could not load mapping: %v", err)
+	}
+	imp, err := NewCSV(strings.NewReader("1451606400;host_0;58.5\n"), m)
+	if err != nil {
+		t.Fatalf("could not read CSV: %v", err)
+	}
+	want := []string{"cpu,hostname=host_0 usage_user=58.5 1451606400000000000"}
+	if got := readAll(t, imp); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect points:\ngot  %q\nwant %q", got, want)
+	}
+
+	invalid := []struct {
+		mapping string
+		wantErr string
+	}{
+		{"measurements: [{name: cpu, fields: [{column: a}]}]", "no time column"},
+		{"time: {column: ts}", "no measurements"},
+		{"time: {column: ts}\nmeasurements: [{name: cpu}]", "has no fields"},
+		{"time: {column: ts}\nmeasurements: [{name: cpu, fields: [{column: a, type: double}]}]", "invalid type 'double'"},
+		{"time: {column: ts}\nmeasurements: [{name: cpu, fields: [{column: a, type: int, default: x}]}]", "invalid default"},
+		{"time: {column: ts}\ntags: [{column: a}, {column: b, name: a}]\nmeasurements: [{name: cpu, fields: [{column: a}]}]", "duplicate tag 'a'"},
+		{"delimiter: ab\ntime: {column: ts}\nmeasurements: [{name: cpu, fields: [{column: a}]}]", "single character"},
+	}
+	for _, c := range invalid {
+		if err := ioutil.WriteFile(file, []byte(c.mapping), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadCSVMapping(file); err == nil || !strings.Contains(err.Error(), c.wantErr) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.mapping, err, c.wantErr)
+		}
+	}
+}
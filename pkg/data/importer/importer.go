@@ -0,0 +1,94 @@
+// Package importer reads external datasets as points, so that real data,
+// rather than simulated, can be serialized to any format of
+// tsbs_generate_data and drive the loaders and query runners:
+//
+//	imp, err := importer.NewCSV(r, mapping)
+//	if err != nil {
+//		return err
+//	}
+//	serializer, err := data.NewSerializer(format, imp, w)
+//	...
+//	err = data.Run(ctx, imp, serializer, w, 0, 1)
+//	if err == nil {
+//		err = imp.Err()
+//	}
+//
+// An Importer is a common.Simulator whose points are read rather than
+// simulated, so its Schema is known before any point is, as serializers
+// writing headers need it.
+package importer
+
+import (
+	"context"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Importer is a Simulator reading the points of an external dataset. It is
+// Finished once all points are read or reading failed, which Err then
+// returns. Datasets are read in order, so Split does not partition them.
+type Importer interface {
+	common.Simulator
+	// Err returns the error that stopped reading, or nil once all points
+	// were read
+	Err() error
+	// Close releases the files of the dataset
+	Close() error
+}
+
+// nextBatch fills points with the next points of sim, one at a time,
+// returning how many were filled. It stops early once ctx is done.
+func nextBatch(ctx context.Context, sim common.Simulator, points []serialize.Point) int {
+	n := 0
+	for n < len(points) && !sim.Finished() && ctx.Err() == nil {
+		points[n].Reset()
+		if sim.Next(ctx, &points[n]) {
+			n++
+		}
+	}
+	return n
+}
+
+// Rebase returns an Importer shifting the timestamps of the points of imp
+// by the same duration, so that the first point read is at start, e.g., to
+// match the time range queries are generated for
+func Rebase(imp Importer, start time.Time) Importer {
+	return &rebased{Importer: imp, start: start.UnixNano()}
+}
+
+type rebased struct {
+	Importer
+	start int64
+	// offset is added to the timestamps, once the first point is read
+	offset  int64
+	started bool
+}
+
+func (r *rebased) Next(ctx context.Context, p *serialize.Point) bool {
+	if !r.Importer.Next(ctx, p) {
+		return false
+	}
+	r.shift(p)
+	return true
+}
+
+func (r *rebased) NextBatch(ctx context.Context, points []serialize.Point) int {
+	n := r.Importer.NextBatch(ctx, points)
+	for i := 0; i < n; i++ {
+		r.shift(&points[i])
+	}
+	return n
+}
+
+func (r *rebased) Split(n int) []common.Simulator {
+	return []common.Simulator{r}
+}
+
+func (r *rebased) shift(p *serialize.Point) {
+	if !r.started {
+		r.offset, r.started = r.start-p.Timestamp(), true
+	}
+	p.SetTimestamp(p.Timestamp() + r.offset)
+}
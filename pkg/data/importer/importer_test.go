@@ -0,0 +1,29 @@
+package importer
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebase(t *testing.T) {
+	input := "ts,host,region,user,state,used,swap\n" +
+		"1000,host_0,a,1,ok,1,true\n" +
+		"11000,host_0,a,2,ok,2,true\n"
+	imp, err := NewCSV(strings.NewReader(input), testMapping())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebased := Rebase(imp, time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+	var got []int64
+	for _, line := range readAll(t, rebased) {
+		ts, _ := strconv.ParseInt(line[strings.LastIndexByte(line, ' ')+1:], 10, 64)
+		got = append(got, ts)
+	}
+	want := []int64{1451606400000000000, 1451606400000000000, 1451606410000000000, 1451606410000000000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect timestamps: got %v want %v", got, want)
+	}
+}
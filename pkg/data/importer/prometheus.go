@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// metricNameLabel is the label of the name of the metric of a series
+const metricNameLabel = "__name__"
+
+// PrometheusValueField is the key of the field of the points of Prometheus
+// samples
+var PrometheusValueField = []byte("value")
+
+// promSeries is a series of a block, as the points read from it have it
+type promSeries struct {
+	name      []byte
+	tagKeys   [][]byte
+	tagValues [][]byte
+	chunks    []chunkMeta
+}
+
+// promBlock is a block of a TSDB data directory
+type promBlock struct {
+	dir    string
+	meta   blockMeta
+	series []*promSeries
+}
+
+// PrometheusImporter is an Importer reading the samples of the blocks of a
+// Prometheus TSDB, as points of a measurement named after their metric,
+// with the other labels of their series as tags and the value of the sample
+// as a float field named value.
+//
+// The blocks are read in the order of their time ranges and the samples of
+// each block in time order, so the points are in time order unless blocks
+// overlap. Only float samples are read: chunks of native histograms are
+// skipped, and counted by SkippedChunks. Tombstones are not applied, so
+// deleted samples not yet compacted away are read too.
+type PrometheusImporter struct {
+	blocks []*promBlock
+	schema *serialize.Schema
+
+	// the block being read, and the iterators of its series with samples
+	// left, by the timestamp of their current sample
+	block   int
+	chunks  *chunkReader
+	pending iteratorHeap
+
+	skippedChunks int
+	done          bool
+	err           error
+}
+
+// OpenPrometheus returns a PrometheusImporter of dir, which is either the
+// data directory of a Prometheus TSDB or one of its blocks. Only the series
+// whose metric name matches match, if it is not nil, are read. The index of
+// every block is read up front, for the Schema of the points.
+func OpenPrometheus(dir string, match *regexp.Regexp) (*PrometheusImporter, error) {
+	dirs := []string{dir}
+	if _, err := os.Stat(filepath.Join(dir, "meta.json")); os.IsNotExist(err) {
+		if dirs, err = filepath.Glob(filepath.Join(dir, "*", "meta.json")); err != nil {
+			return nil, err
+		}
+		for i, d := range dirs {
+			dirs[i] = filepath.Dir(d)
+		}
+		if len(dirs) == 0 {
+			return nil, fmt.Errorf("no TSDB blocks in %s", dir)
+		}
+	}
+
+	imp := &PrometheusImporter{block: -1}
+	names := common.NewInterner()
+	tagKeys := map[string][]byte{}
+	measurements := map[string]bool{}
+	for _, d := range dirs {
+		b, err := readBlock(d, match, names)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range b.series {
+			measurements[string(s.name)] = true
+			for _, k := range s.tagKeys {
+				tagKeys[string(k)] = k
+			}
+		}
+		imp.blocks = append(imp.blocks, b)
+	}
+	sort.SliceStable(imp.blocks, func(i, j int) bool {
+		return imp.blocks[i].meta.MinTime < imp.blocks[j].meta.MinTime
+	})
+
+	keys := make([]string, 0, len(tagKeys))
+	for k := range tagKeys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	schemaTagKeys := make([][]byte, len(keys))
+	for i, k := range keys {
+		schemaTagKeys[i] = tagKeys[k]
+	}
+	fields := make(map[string][][]byte, len(measurements))
+	types := make(map[string][]serialize.FieldType, len(measurements))
+	for m := range measurements {
+		fields[m] = [][]byte{PrometheusValueField}
+		types[m] = []serialize.FieldType{serialize.FieldTypeFloat}
+	}
+	imp.schema = serialize.NewTypedSchema(schemaTagKeys, fields, types)
+	return imp, nil
+}
+
+// readBlock reads the meta.json and the series of the index of the block
+// in dir, keeping those whose metric matches match. Names and values of
+// labels are interned in names, so the series of all blocks share them.
+func readBlock(dir string, match *regexp.Regexp, names *common.Interner) (*promBlock, error) {
+	b := &promBlock{dir: dir}
+	buf, err := ioutil.ReadFile(filepath.Join(dir, "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &b.meta); err != nil {
+		return nil, fmt.Errorf("invalid block %s: %v", dir, err)
+	}
+	series, err := readIndex(filepath.Join(dir, "index"))
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range series {
+		ps := &promSeries{chunks: s.chunks}
+		for _, l := range s.labels {
+			if string(l.name) == metricNameLabel {
+				ps.name = names.Intern(l.value)
+				continue
+			}
+			ps.tagKeys = append(ps.tagKeys, names.Intern(l.name))
+			ps.tagValues = append(ps.tagValues, names.Intern(l.value))
+		}
+		if ps.name == nil || len(s.chunks) == 0 || (match != nil && !match.Match(ps.name)) {
+			continue
+		}
+		b.series = append(b.series, ps)
+	}
+	return b, nil
+}
+
+// seriesIterator iterates over the samples of a series, one chunk at a
+// time
+type seriesIterator struct {
+	series *promSeries
+	chunk  int
+	xor    xorIterator
+}
+
+type iteratorHeap []*seriesIterator
+
+func (h iteratorHeap) Len() int           { return len(h) }
+func (h iteratorHeap) Less(i, j int) bool { return h[i].xor.t < h[j].xor.t }
+func (h iteratorHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *iteratorHeap) Push(x interface{}) {
+	*h = append(*h, x.(*seriesIterator))
+}
+func (h *iteratorHeap) Pop() interface{} {
+	old := *h
+	it := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return it
+}
+
+// advance moves it to the next sample of its series that is not a
+// staleness marker, reading its next chunks as needed, and returns whether
+// there is one
+func (imp *PrometheusImporter) advance(it *seriesIterator) bool {
+	for {
+		for it.xor.next() {
+			if math.Float64bits(it.xor.v) != staleNaN {
+				return true
+			}
+		}
+		if it.xor.err != nil {
+			imp.err = fmt.Errorf("block %s: chunk %d of series %s: %v", imp.blocks[imp.block].dir, it.series.chunks[it.chunk-1].ref, it.series.name, it.xor.err)
+			return false
+		}
+		if it.chunk == len(it.series.chunks) {
+			return false
+		}
+		ref := it.series.chunks[it.chunk].ref
+		it.chunk++
+		encoding, data, err := imp.chunks.chunk(ref)
+		if err != nil {
+			imp.err = fmt.Errorf("block %s: %v", imp.blocks[imp.block].dir, err)
+			return false
+		}
+		if encoding != encodingXOR {
+			imp.skippedChunks++
+			it.xor = xorIterator{}
+			continue
+		}
+		// the data is only valid until the next chunk is read
+		it.xor.reset(append([]byte(nil), data...))
+	}
+}
+
+// openNextBlock starts reading the next block with samples, returning
+// whether there is one
+func (imp *PrometheusImporter) openNextBlock() bool {
+	for imp.pending.Len() == 0 {
+		if imp.chunks != nil {
+			imp.chunks.Close()
+			imp.chunks = nil
+		}
+		imp.block++
+		if imp.block == len(imp.blocks) {
+			imp.done = true
+			return false
+		}
+		b := imp.blocks[imp.block]
+		var err error
+		if imp.chunks, err = openChunks(filepath.Join(b.dir, "chunks")); err != nil {
+			imp.err = err
+			return false
+		}
+		for _, s := range b.series {
+			it := &seriesIterator{series: s}
+			if imp.advance(it) {
+				imp.pending = append(imp.pending, it)
+			} else if imp.err != nil {
+				return false
+			}
+		}
+		heap.Init(&imp.pending)
+	}
+	return true
+}
+
+// Finished returns whether all samples were read, or reading failed
+func (imp *PrometheusImporter) Finished() bool {
+	return imp.done || imp.err != nil
+}
+
+// Next sets p to the sample with the earliest timestamp left in the block
+// being read
+func (imp *PrometheusImporter) Next(ctx context.Context, p *serialize.Point) bool {
+	if ctx.Err() != nil || imp.Finished() || !imp.openNextBlock() {
+		return false
+	}
+	it := imp.pending[0]
+	s := it.series
+	p.SetMeasurementName(s.name)
+	// Prometheus timestamps are in milliseconds
+	p.SetTimestamp(it.xor.t * 1e6)
+	for i, k := range s.tagKeys {
+		p.AppendTag(k, s.tagValues[i])
+	}
+	p.AppendField(PrometheusValueField, it.xor.v)
+	if imp.advance(it) {
+		heap.Fix(&imp.pending, 0)
+	} else {
+		heap.Pop(&imp.pending)
+	}
+	return true
+}
+
+func (imp *PrometheusImporter) NextBatch(ctx context.Context, points []serialize.Point) int {
+	return nextBatch(ctx, imp, points)
+}
+
+func (imp *PrometheusImporter) Split(n int) []common.Simulator {
+	return []common.Simulator{imp}
+}
+
+func (imp *PrometheusImporter) Fields() *serialize.Schema {
+	return imp.schema
+}
+
+func (imp *PrometheusImporter) Err() error {
+	return imp.err
+}
+
+// SkippedChunks returns the number of chunks skipped so far, which are of
+// native histograms
+func (imp *PrometheusImporter) SkippedChunks() int {
+	return imp.skippedChunks
+}
+
+func (imp *PrometheusImporter) Close() error {
+	if imp.chunks != nil {
+		return imp.chunks.Close()
+	}
+	return nil
+}
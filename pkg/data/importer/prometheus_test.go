@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// formatPoint returns p as a line of its measurement, tags, field and
+// timestamp
+func formatPoint(p *serialize.Point) string {
+	var b strings.Builder
+	b.Write(p.MeasurementName())
+	for i, k := range p.TagKeys() {
+		fmt.Fprintf(&b, ",%s=%s", k, p.TagValues()[i])
+	}
+	for i, k := range p.FieldKeys() {
+		fmt.Fprintf(&b, " %s=%v", k, p.FieldValues()[i])
+	}
+	fmt.Fprintf(&b, " %d", p.Timestamp())
+	return b.String()
+}
+
+// readAll returns the points of imp as formatted by formatPoint
+func readAll(t *testing.T, imp Importer) []string {
+	var got []string
+	points := make([]serialize.Point, 3)
+	for !imp.Finished() {
+		n := imp.NextBatch(context.Background(), points)
+		for i := 0; i < n; i++ {
+			got = append(got, formatPoint(&points[i]))
+		}
+	}
+	return got
+}
+
+// writeTestTSDB writes a data directory of two blocks, the later one first
+func writeTestTSDB(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "tsbs_import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestBlock(t, filepath.Join(dir, "01B"), 20000, 40000, []testSeries{
+		{labels: []string{"__name__", "up", "instance", "a", "job", "node"}, chunks: []testChunk{{ts: []int64{30000}, vs: []float64{0}}}},
+	})
+	writeTestBlock(t, filepath.Join(dir, "01C"), 0, 20000, []testSeries{
+		{labels: []string{"__name__", "cpu_seconds_total", "cpu", "0", "instance", "a"}, chunks: []testChunk{
+			{ts: []int64{0, 10000}, vs: []float64{1.5, 2.5}},
+			// a native histogram
+			{encoding: 2, ts: []int64{12000}, vs: []float64{1}},
+			{ts: []int64{15000, 17000}, vs: []float64{math.Float64frombits(staleNaN), 3}},
+		}},
+		{labels: []string{"__name__", "up", "instance", "a", "job", "node"}, chunks: []testChunk{{ts: []int64{5000, 15000}, vs: []float64{1, 1}}}},
+		// series without a metric name are not read
+		{labels: []string{"job", "node"}, chunks: []testChunk{{ts: []int64{0}, vs: []float64{1}}}},
+	})
+	os.MkdirAll(filepath.Join(dir, "wal"), 0755)
+	return dir
+}
+
+func TestPrometheusImporter(t *testing.T) {
+	dir := writeTestTSDB(t)
+	defer os.RemoveAll(dir)
+
+	imp, err := OpenPrometheus(dir, nil)
+	if err != nil {
+		t.Fatalf("could not open TSDB: %v", err)
+	}
+	defer imp.Close()
+	schema := imp.Fields()
+	if got := fmt.Sprintf("%s", schema.TagKeys()); got != "[cpu instance job]" {
+		t.Errorf("incorrect tag keys: %s", got)
+	}
+	if got := schema.Measurements(); !reflect.DeepEqual(got, []string{"cpu_seconds_total", "up"}) {
+		t.Errorf("incorrect measurements: %v", got)
+	}
+	if got := schema.FieldTypes("up"); !reflect.DeepEqual(got, []serialize.FieldType{serialize.FieldTypeFloat}) {
+		t.Errorf("incorrect field types: %v", got)
+	}
+
+	want := []string{
+		"cpu_seconds_total,cpu=0,instance=a value=1.5 0",
+		"up,instance=a,job=node value=1 5000000000",
+		"cpu_seconds_total,cpu=0,instance=a value=2.5 10000000000",
+		"up,instance=a,job=node value=1 15000000000",
+		"cpu_seconds_total,cpu=0,instance=a value=3 17000000000",
+		"up,instance=a,job=node value=0 30000000000",
+	}
+	if got := readAll(t, imp); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect points:\ngot  %q\nwant %q", got, want)
+	}
+	if imp.Err() != nil {
+		t.Errorf("unexpected error: %v", imp.Err())
+	}
+	if imp.SkippedChunks() != 1 {
+		t.Errorf("incorrect skipped chunks: got %d want 1", imp.SkippedChunks())
+	}
+}
+
+func TestPrometheusImporterMatch(t *testing.T) {
+	dir := writeTestTSDB(t)
+	defer os.RemoveAll(dir)
+
+	// a single block, and only the metrics matched
+	imp, err := OpenPrometheus(filepath.Join(dir, "01C"), regexp.MustCompile("^up$"))
+	if err != nil {
+		t.Fatalf("could not open block: %v", err)
+	}
+	defer imp.Close()
+	want := []string{
+		"up,instance=a,job=node value=1 5000000000",
+		"up,instance=a,job=node value=1 15000000000",
+	}
+	if got := readAll(t, imp); !reflect.DeepEqual(got, want) {
+		t.Errorf("incorrect points:\ngot  %q\nwant %q", got, want)
+	}
+
+	if _, err := OpenPrometheus(filepath.Join(dir, "wal"), nil); err == nil {
+		t.Errorf("directory without blocks opened")
+	}
+}
+
+func TestPrometheusImporterError(t *testing.T) {
+	dir := writeTestTSDB(t)
+	defer os.RemoveAll(dir)
+	// the chunks of the first block are cut short
+	file := filepath.Join(dir, "01C", "chunks", "000001")
+	chunks, _ := ioutil.ReadFile(file)
+	if err := ioutil.WriteFile(file, chunks[:20], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imp, err := OpenPrometheus(dir, nil)
+	if err != nil {
+		t.Fatalf("could not open TSDB: %v", err)
+	}
+	defer imp.Close()
+	readAll(t, imp)
+	if imp.Err() == nil || !strings.Contains(imp.Err().Error(), "01C") {
+		t.Errorf("incorrect error: %v", imp.Err())
+	}
+}
@@ -0,0 +1,482 @@
+package importer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// The on-disk formats of Prometheus TSDB blocks, as documented in the
+// tsdb/docs/format directory of Prometheus
+const (
+	indexMagic   = 0xBAAAD700
+	indexVersion = 2
+	// indexTOCLen is the length of the table of contents at the end of the
+	// index: the offsets of its 6 sections and a CRC32
+	indexTOCLen = 6*8 + 4
+
+	chunksMagic = 0x85BD40DD
+	// chunksHeaderLen is the length of the header of a segment file of
+	// chunks: the magic, a version and padding
+	chunksHeaderLen = 8
+
+	// encodingXOR is the encoding of chunks of float samples; the others
+	// are of native histograms
+	encodingXOR = 1
+)
+
+// staleNaN is the value of the samples Prometheus writes to mark a series
+// as stale, which are not samples of the series
+const staleNaN = 0x7ff0000000000002
+
+var errTruncated = errors.New("truncated")
+
+// decbuf decodes the integers of the index, recording the first error
+type decbuf struct {
+	b   []byte
+	err error
+}
+
+func (d *decbuf) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.b)
+	if n <= 0 {
+		d.err = errTruncated
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decbuf) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.b)
+	if n <= 0 {
+		d.err = errTruncated
+		return 0
+	}
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decbuf) be32() uint32 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.b) < 4 {
+		d.err = errTruncated
+		return 0
+	}
+	v := binary.BigEndian.Uint32(d.b)
+	d.b = d.b[4:]
+	return v
+}
+
+func (d *decbuf) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.b) < n {
+		d.err = errTruncated
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+// label is a label of a series, as symbols of the index
+type label struct {
+	name  []byte
+	value []byte
+}
+
+// chunkMeta is where a chunk of a series is, in the segment files of its
+// block: the sequence of its file in the upper 32 bits and its offset in the
+// lower
+type chunkMeta struct {
+	ref uint64
+}
+
+// tsdbSeries is a series of a block, with its labels sorted by name
+type tsdbSeries struct {
+	labels []label
+	chunks []chunkMeta
+}
+
+// blockMeta is the part of the meta.json of a block the importer needs
+type blockMeta struct {
+	ULID    string `json:"ulid"`
+	MinTime int64  `json:"minTime"`
+	MaxTime int64  `json:"maxTime"`
+}
+
+// readIndex reads the series of the index file of a block
+func readIndex(file string) ([]tsdbSeries, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 5+indexTOCLen || binary.BigEndian.Uint32(b) != indexMagic {
+		return nil, fmt.Errorf("%s: not a TSDB index", file)
+	}
+	if b[4] != indexVersion {
+		return nil, fmt.Errorf("%s: unsupported index version %d", file, b[4])
+	}
+	toc := b[len(b)-indexTOCLen:]
+	offsets := make([]uint64, 6)
+	for i := range offsets {
+		offsets[i] = binary.BigEndian.Uint64(toc[i*8:])
+		if offsets[i] > uint64(len(b)-indexTOCLen) {
+			return nil, fmt.Errorf("%s: invalid table of contents", file)
+		}
+	}
+	symbols, err := readSymbols(b[offsets[0]:])
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid symbols: %v", file, err)
+	}
+	// the series section ends where the next section after it starts
+	end := uint64(len(b) - indexTOCLen)
+	for _, off := range offsets[2:] {
+		if off > offsets[1] && off < end {
+			end = off
+		}
+	}
+	series, err := readSeries(b[:end], offsets[1], symbols)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid series: %v", file, err)
+	}
+	return series, nil
+}
+
+// readSymbols reads the symbol table at the start of b, which the labels of
+// series refer to by their index
+func readSymbols(b []byte) ([][]byte, error) {
+	d := decbuf{b: b}
+	n := int(d.be32())
+	d.b = d.bytes(n)
+	count := int(d.be32())
+	if d.err != nil {
+		return nil, d.err
+	}
+	symbols := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		s := d.bytes(int(d.uvarint()))
+		if d.err != nil {
+			return nil, d.err
+		}
+		symbols = append(symbols, s)
+	}
+	return symbols, nil
+}
+
+// readSeries reads the series from offset start of b to its end. Each
+// series starts at a multiple of 16 bytes, with the length of its entry.
+func readSeries(b []byte, start uint64, symbols [][]byte) ([]tsdbSeries, error) {
+	var series []tsdbSeries
+	symbol := func(d *decbuf) []byte {
+		i := d.uvarint()
+		if d.err == nil && i >= uint64(len(symbols)) {
+			d.err = fmt.Errorf("invalid symbol reference %d", i)
+		}
+		if d.err != nil {
+			return nil
+		}
+		return symbols[i]
+	}
+	for pos := start; ; {
+		pos = (pos + 15) / 16 * 16
+		if pos >= uint64(len(b)) {
+			return series, nil
+		}
+		d := decbuf{b: b[pos:]}
+		n := d.uvarint()
+		if d.err == nil && n == 0 {
+			// padding up to the next section
+			return series, nil
+		}
+		entryStart := uint64(len(b)) - uint64(len(d.b))
+		d.b = d.bytes(int(n))
+		var s tsdbSeries
+		for i := d.uvarint(); i > 0 && d.err == nil; i-- {
+			s.labels = append(s.labels, label{name: symbol(&d), value: symbol(&d)})
+		}
+		var ref uint64
+		var maxt int64
+		for i, count := 0, int(d.uvarint()); i < count && d.err == nil; i++ {
+			if i == 0 {
+				mint := d.varint()
+				maxt = mint + int64(d.uvarint())
+				ref = d.uvarint()
+			} else {
+				mint := maxt + int64(d.uvarint())
+				maxt = mint + int64(d.uvarint())
+				ref = uint64(int64(ref) + d.varint())
+			}
+			s.chunks = append(s.chunks, chunkMeta{ref: ref})
+		}
+		if d.err != nil {
+			return nil, fmt.Errorf("series at %d: %v", pos, d.err)
+		}
+		series = append(series, s)
+		// the entry is followed by its CRC32
+		pos = entryStart + n + 4
+	}
+}
+
+// chunkReader reads the chunks of a block from its segment files
+type chunkReader struct {
+	files []*os.File
+	buf   []byte
+}
+
+// openChunks opens the segment files in the chunks directory of a block,
+// which are numbered from 1 in the order of the sequence of their chunks
+func openChunks(dir string) (*chunkReader, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	r := &chunkReader{}
+	for _, name := range names {
+		f, err := os.Open(name)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.files = append(r.files, f)
+		header := make([]byte, chunksHeaderLen)
+		if _, err := io.ReadFull(f, header); err != nil || binary.BigEndian.Uint32(header) != chunksMagic {
+			r.Close()
+			return nil, fmt.Errorf("%s: not a TSDB chunks file", name)
+		}
+	}
+	return r, nil
+}
+
+// chunk returns the encoding and the data of the chunk at ref, which is
+// only valid until the next call
+func (r *chunkReader) chunk(ref uint64) (byte, []byte, error) {
+	seq, off := int(ref>>32), int64(uint32(ref))
+	if seq >= len(r.files) {
+		return 0, nil, fmt.Errorf("invalid chunk reference %d: no segment file %d", ref, seq+1)
+	}
+	f := r.files[seq]
+	var head [binary.MaxVarintLen32 + 1]byte
+	n, err := f.ReadAt(head[:], off)
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	length, k := binary.Uvarint(head[:n])
+	if k <= 0 || k >= n {
+		return 0, nil, fmt.Errorf("invalid chunk reference %d: %v", ref, errTruncated)
+	}
+	if cap(r.buf) < int(length) {
+		r.buf = make([]byte, length)
+	}
+	data := r.buf[:length]
+	if _, err := f.ReadAt(data, off+int64(k)+1); err != nil {
+		return 0, nil, fmt.Errorf("invalid chunk reference %d: %v", ref, err)
+	}
+	return head[k], data, nil
+}
+
+func (r *chunkReader) Close() error {
+	var err error
+	for _, f := range r.files {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// bitReader reads a stream of bits, most significant first
+type bitReader struct {
+	b   []byte
+	pos uint
+}
+
+func (r *bitReader) readBits(n uint) (uint64, error) {
+	if r.pos+n > uint(len(r.b))*8 {
+		return 0, errTruncated
+	}
+	var v uint64
+	for n > 0 {
+		byteIdx, bitIdx := r.pos/8, r.pos%8
+		take := 8 - bitIdx
+		if take > n {
+			take = n
+		}
+		bits := uint64(r.b[byteIdx]>>(8-bitIdx-take)) & (1<<take - 1)
+		v = v<<take | bits
+		r.pos += take
+		n -= take
+	}
+	return v, nil
+}
+
+func (r *bitReader) readBit() (bool, error) {
+	v, err := r.readBits(1)
+	return v == 1, err
+}
+
+// ReadByte reads 8 bits, for the varints of the stream
+func (r *bitReader) ReadByte() (byte, error) {
+	v, err := r.readBits(8)
+	return byte(v), err
+}
+
+// xorIterator iterates over the samples of a chunk of the XOR encoding of
+// Gorilla: delta of deltas of the timestamps and XORs of the values
+type xorIterator struct {
+	br      bitReader
+	n, read int
+	t       int64
+	v       float64
+
+	tDelta   uint64
+	leading  uint
+	trailing uint
+	err      error
+}
+
+func (it *xorIterator) reset(data []byte) {
+	*it = xorIterator{}
+	if len(data) < 2 {
+		it.err = errTruncated
+		return
+	}
+	it.n = int(binary.BigEndian.Uint16(data))
+	it.br = bitReader{b: data[2:]}
+}
+
+// next advances to the next sample, returning whether there is one
+func (it *xorIterator) next() bool {
+	if it.err != nil || it.read == it.n {
+		return false
+	}
+	switch it.read {
+	case 0:
+		t, err := binary.ReadVarint(&it.br)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		v, err := it.br.readBits(64)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.t, it.v = t, math.Float64frombits(v)
+	case 1:
+		tDelta, err := binary.ReadUvarint(&it.br)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.tDelta = tDelta
+		it.t += int64(tDelta)
+		if it.err = it.readValue(); it.err != nil {
+			return false
+		}
+	default:
+		if it.err = it.readTimestamp(); it.err != nil {
+			return false
+		}
+		if it.err = it.readValue(); it.err != nil {
+			return false
+		}
+	}
+	it.read++
+	return true
+}
+
+func (it *xorIterator) readTimestamp() error {
+	// the delta of deltas is 0, or follows a prefix of its size
+	var prefix uint
+	for i := 0; i < 4; i++ {
+		prefix <<= 1
+		bit, err := it.br.readBit()
+		if err != nil {
+			return err
+		}
+		if !bit {
+			break
+		}
+		prefix |= 1
+	}
+	var size uint
+	var dod int64
+	switch prefix {
+	case 0x02:
+		size = 14
+	case 0x06:
+		size = 17
+	case 0x0e:
+		size = 20
+	case 0x0f:
+		bits, err := it.br.readBits(64)
+		if err != nil {
+			return err
+		}
+		dod = int64(bits)
+	}
+	if size > 0 {
+		bits, err := it.br.readBits(size)
+		if err != nil {
+			return err
+		}
+		// negative deltas are in two's complement of their size
+		if bits > 1<<(size-1) {
+			bits -= 1 << size
+		}
+		dod = int64(bits)
+	}
+	it.tDelta = uint64(int64(it.tDelta) + dod)
+	it.t += int64(it.tDelta)
+	return nil
+}
+
+func (it *xorIterator) readValue() error {
+	changed, err := it.br.readBit()
+	if err != nil || !changed {
+		return err
+	}
+	newWindow, err := it.br.readBit()
+	if err != nil {
+		return err
+	}
+	if newWindow {
+		leading, err := it.br.readBits(5)
+		if err != nil {
+			return err
+		}
+		significant, err := it.br.readBits(6)
+		if err != nil {
+			return err
+		}
+		// 64 significant bits do not fit 6 bits, so they are written as 0
+		if significant == 0 {
+			significant = 64
+		}
+		it.leading, it.trailing = uint(leading), 64-uint(leading)-uint(significant)
+	}
+	bits, err := it.br.readBits(64 - it.leading - it.trailing)
+	if err != nil {
+		return err
+	}
+	it.v = math.Float64frombits(math.Float64bits(it.v) ^ bits<<it.trailing)
+	return nil
+}
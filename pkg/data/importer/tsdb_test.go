@@ -0,0 +1,318 @@
+package importer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// bitWriter writes a stream of bits, most significant first, as Prometheus
+// does
+type bitWriter struct {
+	b []byte
+	n uint
+}
+
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	for i := int(n) - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.b = append(w.b, 0)
+		}
+		if v>>uint(i)&1 == 1 {
+			w.b[len(w.b)-1] |= 1 << (7 - w.n%8)
+		}
+		w.n++
+	}
+}
+
+func (w *bitWriter) writeBytes(b []byte) {
+	for _, c := range b {
+		w.writeBits(uint64(c), 8)
+	}
+}
+
+// encodeXOR returns the data of a chunk of the samples in the XOR encoding,
+// written as the appender of Prometheus writes it
+func encodeXOR(ts []int64, vs []float64) []byte {
+	w := &bitWriter{b: make([]byte, 2)}
+	w.n = 16
+	binary.BigEndian.PutUint16(w.b, uint16(len(ts)))
+	var tDelta int64
+	leading, trailing := uint(0xff), uint(0)
+	writeValue := func(v, prev float64) {
+		delta := math.Float64bits(v) ^ math.Float64bits(prev)
+		if delta == 0 {
+			w.writeBits(0, 1)
+			return
+		}
+		w.writeBits(1, 1)
+		l, t := uint(bits.LeadingZeros64(delta)), uint(bits.TrailingZeros64(delta))
+		if l >= 32 {
+			l = 31
+		}
+		if leading != 0xff && l >= leading && t >= trailing {
+			w.writeBits(0, 1)
+			w.writeBits(delta>>trailing, 64-leading-trailing)
+			return
+		}
+		leading, trailing = l, t
+		w.writeBits(1, 1)
+		w.writeBits(uint64(l), 5)
+		w.writeBits(uint64(64-l-t), 6)
+		w.writeBits(delta>>t, 64-l-t)
+	}
+	buf := make([]byte, binary.MaxVarintLen64)
+	for i, t := range ts {
+		switch i {
+		case 0:
+			w.writeBytes(buf[:binary.PutVarint(buf, t)])
+			w.writeBits(math.Float64bits(vs[0]), 64)
+		case 1:
+			tDelta = t - ts[0]
+			w.writeBytes(buf[:binary.PutUvarint(buf, uint64(tDelta))])
+			writeValue(vs[1], vs[0])
+		default:
+			d := t - ts[i-1]
+			dod := d - tDelta
+			tDelta = d
+			switch {
+			case dod == 0:
+				w.writeBits(0, 1)
+			case -(1<<13-1) <= dod && dod <= 1<<13:
+				w.writeBits(0x02, 2)
+				w.writeBits(uint64(dod), 14)
+			case -(1<<16-1) <= dod && dod <= 1<<16:
+				w.writeBits(0x06, 3)
+				w.writeBits(uint64(dod), 17)
+			case -(1<<19-1) <= dod && dod <= 1<<19:
+				w.writeBits(0x0e, 4)
+				w.writeBits(uint64(dod), 20)
+			default:
+				w.writeBits(0x0f, 4)
+				w.writeBits(uint64(dod), 64)
+			}
+			writeValue(vs[i], vs[i-1])
+		}
+	}
+	return w.b
+}
+
+// testChunk is a chunk of a testSeries, of float samples unless it has
+// another encoding
+type testChunk struct {
+	encoding byte
+	ts       []int64
+	vs       []float64
+}
+
+// testSeries is a series of a test block, with its labels as name, value
+// pairs sorted by name
+type testSeries struct {
+	labels []string
+	chunks []testChunk
+}
+
+// writeTestBlock writes a TSDB block of series, which must be sorted by
+// their labels, to dir
+func writeTestBlock(t *testing.T, dir string, minTime, maxTime int64, series []testSeries) {
+	if err := os.MkdirAll(filepath.Join(dir, "chunks"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := json.Marshal(blockMeta{ULID: filepath.Base(dir), MinTime: minTime, MaxTime: maxTime})
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.json"), meta, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chunks, in a single segment file
+	chunks := []byte{0x85, 0xBD, 0x40, 0xDD, 1, 0, 0, 0}
+	refs := make([][]uint64, len(series))
+	for i, s := range series {
+		for _, c := range s.chunks {
+			refs[i] = append(refs[i], uint64(len(chunks)))
+			data := encodeXOR(c.ts, c.vs)
+			encoding := c.encoding
+			if encoding == 0 {
+				encoding = encodingXOR
+			}
+			chunks = binary.AppendUvarint(chunks, uint64(len(data)))
+			chunks = append(append(append(chunks, encoding), data...), 0, 0, 0, 0)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "chunks", "000001"), chunks, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the symbols, followed by the series, with CRC32s of zeros
+	symbolSet := map[string]bool{}
+	for _, s := range series {
+		for _, l := range s.labels {
+			symbolSet[l] = true
+		}
+	}
+	symbols := make([]string, 0, len(symbolSet))
+	for s := range symbolSet {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+	symbolRefs := map[string]uint64{}
+	var table []byte
+	table = binary.BigEndian.AppendUint32(table, uint32(len(symbols)))
+	for i, s := range symbols {
+		symbolRefs[s] = uint64(i)
+		table = binary.AppendUvarint(table, uint64(len(s)))
+		table = append(table, s...)
+	}
+	index := []byte{0xBA, 0xAA, 0xD7, 0x00, 2}
+	symbolsOffset := uint64(len(index))
+	index = binary.BigEndian.AppendUint32(index, uint32(len(table)))
+	index = append(append(index, table...), 0, 0, 0, 0)
+	for len(index)%16 != 0 {
+		index = append(index, 0)
+	}
+	seriesOffset := uint64(len(index))
+	for i, s := range series {
+		for len(index)%16 != 0 {
+			index = append(index, 0)
+		}
+		entry := binary.AppendUvarint(nil, uint64(len(s.labels)/2))
+		for _, l := range s.labels {
+			entry = binary.AppendUvarint(entry, symbolRefs[l])
+		}
+		entry = binary.AppendUvarint(entry, uint64(len(s.chunks)))
+		var prevMaxt int64
+		for j, c := range s.chunks {
+			mint, maxt := c.ts[0], c.ts[len(c.ts)-1]
+			if j == 0 {
+				entry = binary.AppendVarint(entry, mint)
+				entry = binary.AppendUvarint(entry, uint64(maxt-mint))
+				entry = binary.AppendUvarint(entry, refs[i][0])
+			} else {
+				entry = binary.AppendUvarint(entry, uint64(mint-prevMaxt))
+				entry = binary.AppendUvarint(entry, uint64(maxt-mint))
+				entry = binary.AppendVarint(entry, int64(refs[i][j]-refs[i][j-1]))
+			}
+			prevMaxt = maxt
+		}
+		index = binary.AppendUvarint(index, uint64(len(entry)))
+		index = append(append(index, entry...), 0, 0, 0, 0)
+	}
+	// the other sections are empty
+	end := uint64(len(index))
+	for _, off := range []uint64{symbolsOffset, seriesOffset, end, end, end, end} {
+		index = binary.BigEndian.AppendUint64(index, off)
+	}
+	index = append(index, 0, 0, 0, 0)
+	if err := ioutil.WriteFile(filepath.Join(dir, "index"), index, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestXORIterator(t *testing.T) {
+	cases := []struct {
+		desc string
+		ts   []int64
+		vs   []float64
+	}{
+		{
+			desc: "single sample",
+			ts:   []int64{1451606400000},
+			vs:   []float64{58.5},
+		},
+		{
+			desc: "regular",
+			ts:   []int64{1000, 11000, 21000, 31000, 41000},
+			vs:   []float64{1, 1, 2.5, 2.5, -7},
+		},
+		{
+			desc: "every size of delta of deltas",
+			ts:   []int64{-5000, 0, 6000, 6001, 16001 + 1<<15, 16002 + 1<<15 + 1<<18, 16003 + 1<<15 + 1<<18 + 1<<30, 16003 + 1<<15 + 1<<18 + 1<<30},
+			vs:   []float64{0, math.Inf(1), 1e-300, 42, 42.000001, math.MaxFloat64, -0.5, 3},
+		},
+	}
+	for _, c := range cases {
+		var it xorIterator
+		it.reset(encodeXOR(c.ts, c.vs))
+		var ts []int64
+		var vs []float64
+		for it.next() {
+			ts = append(ts, it.t)
+			vs = append(vs, it.v)
+		}
+		if it.err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, it.err)
+			continue
+		}
+		if len(ts) != len(c.ts) {
+			t.Errorf("%s: incorrect number of samples: got %d want %d", c.desc, len(ts), len(c.ts))
+			continue
+		}
+		for i := range ts {
+			if ts[i] != c.ts[i] || vs[i] != c.vs[i] {
+				t.Errorf("%s: incorrect sample %d: got %d %v want %d %v", c.desc, i, ts[i], vs[i], c.ts[i], c.vs[i])
+			}
+		}
+	}
+
+	// a truncated chunk is an error, not fewer samples
+	data := encodeXOR([]int64{1000, 2000, 3000}, []float64{1, 2, 3})
+	var it xorIterator
+	it.reset(data[:len(data)-2])
+	for it.next() {
+	}
+	if it.err == nil {
+		t.Errorf("truncated chunk read without error")
+	}
+}
+
+func TestReadIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsbs_import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestBlock(t, dir, 0, 100000, []testSeries{
+		{labels: []string{"__name__", "up", "job", "node"}, chunks: []testChunk{{ts: []int64{0}, vs: []float64{1}}}},
+		{labels: []string{"__name__", "up", "instance", "b:9100", "job", "node"}, chunks: []testChunk{{ts: []int64{0, 1}, vs: []float64{1, 0}}, {ts: []int64{50000}, vs: []float64{1}}}},
+	})
+	series, err := readIndex(filepath.Join(dir, "index"))
+	if err != nil {
+		t.Fatalf("could not read index: %v", err)
+	}
+	if len(series) != 2 || len(series[0].labels) != 2 || len(series[1].labels) != 3 || len(series[1].chunks) != 2 {
+		t.Fatalf("incorrect series: %+v", series)
+	}
+	if l := series[1].labels[1]; string(l.name) != "instance" || string(l.value) != "b:9100" {
+		t.Errorf("incorrect label: %s=%s", l.name, l.value)
+	}
+
+	r, err := openChunks(filepath.Join(dir, "chunks"))
+	if err != nil {
+		t.Fatalf("could not open chunks: %v", err)
+	}
+	defer r.Close()
+	encoding, data, err := r.chunk(series[1].chunks[1].ref)
+	if err != nil || encoding != encodingXOR {
+		t.Fatalf("could not read chunk: %d %v", encoding, err)
+	}
+	var it xorIterator
+	it.reset(data)
+	if !it.next() || it.t != 50000 || it.v != 1 || it.next() {
+		t.Errorf("incorrect chunk: %d %v", it.t, it.v)
+	}
+	if _, _, err := r.chunk(1 << 32); err == nil {
+		t.Errorf("chunk of a missing segment file read")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "index"), []byte("not an index, but long enough to have a table of contents"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readIndex(filepath.Join(dir, "index")); err == nil {
+		t.Errorf("invalid index read")
+	}
+}
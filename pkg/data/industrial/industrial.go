@@ -0,0 +1,128 @@
+// Package industrial simulates the industrial use case: high rate readings
+// of the machines of the production lines of factories, as kept by an
+// industrial historian, with each line stopped for maintenance every week.
+package industrial
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// Machines are grouped into lines of machinesPerLine, and lines into
+// factories of linesPerFactory, in the order of the machines
+const (
+	machinesPerLine = 8
+	linesPerFactory = 4
+)
+
+// Every line is down for maintenanceDuration a week, from maintenanceStart
+// on a day of the week that depends on the line, in UTC
+const (
+	maintenanceStart    = 2 * time.Hour
+	maintenanceDuration = 4 * time.Hour
+)
+
+var (
+	labelMachine = []byte("machine")
+
+	// TagKeys are the tags of every point, identifying its machine and where
+	// it is
+	TagKeys = [][]byte{
+		[]byte("factory"),
+		[]byte("line"),
+		[]byte("machine"),
+		[]byte("machine_type"),
+	}
+
+	machineFields = [][]byte{
+		[]byte("speed"),
+		[]byte("power"),
+		[]byte("temperature"),
+		[]byte("vibration_x"),
+		[]byte("vibration_y"),
+		[]byte("vibration_z"),
+		[]byte("parts"),
+		[]byte("running"),
+	}
+	machineTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeBool,
+	}
+
+	schema = serialize.NewTypedSchema(TagKeys, map[string][][]byte{
+		string(labelMachine): machineFields,
+	}, map[string][]serialize.FieldType{
+		string(labelMachine): machineTypes,
+	})
+)
+
+// LineOf returns the index of the line of machine i, and that of its
+// factory
+func LineOf(i int) (line, factory int) {
+	line = i / machinesPerLine
+	return line, line / linesPerFactory
+}
+
+// IsScheduledDowntime returns whether line is down for maintenance at t:
+// the lines of a factory are down on different days of the week, so the
+// factory keeps producing
+func IsScheduledDowntime(line int, t time.Time) bool {
+	t = t.UTC()
+	if t.Weekday() != time.Weekday(line%7) {
+		return false
+	}
+	y, m, d := t.Date()
+	sinceMidnight := t.Sub(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+	return sinceMidnight >= maintenanceStart && sinceMidnight < maintenanceStart+maintenanceDuration
+}
+
+// SimulatorConfig is used to create a Simulator of the industrial use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitMachineCount is the number of machines to start with in the first
+	// reporting period
+	InitMachineCount uint64
+	// MachineCount is the total number of machines to have in the last
+	// reporting period
+	MachineCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each machine. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the machine
+// measurement
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a reading of each machine
+// every interval, running or not. It advances RNG, so each call simulates
+// different machines.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitMachineCount,
+		Count:            c.MachineCount,
+		PointsPerReading: 1,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewMachine(r, i, start)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
@@ -0,0 +1,102 @@
+package industrial
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestLineOf(t *testing.T) {
+	cases := []struct {
+		machine, line, factory int
+	}{
+		{0, 0, 0},
+		{7, 0, 0},
+		{8, 1, 0},
+		{31, 3, 0},
+		{32, 4, 1},
+	}
+	for _, c := range cases {
+		line, factory := LineOf(c.machine)
+		if line != c.line || factory != c.factory {
+			t.Errorf("%d: incorrect line and factory: got %d, %d want %d, %d", c.machine, line, factory, c.line, c.factory)
+		}
+	}
+}
+
+func TestIsScheduledDowntime(t *testing.T) {
+	// 2016-01-03 is a Sunday, the day of line 0
+	cases := []struct {
+		line int
+		time string
+		want bool
+	}{
+		{0, "2016-01-03T01:59:59Z", false},
+		{0, "2016-01-03T02:00:00Z", true},
+		{0, "2016-01-03T05:59:59Z", true},
+		{0, "2016-01-03T06:00:00Z", false},
+		{0, "2016-01-04T03:00:00Z", false},
+		{1, "2016-01-04T03:00:00Z", true},
+		{8, "2016-01-04T03:00:00Z", true},
+		{1, "2016-01-04T04:00:00+01:00", true},
+	}
+	for _, c := range cases {
+		ts, err := time.Parse(time.RFC3339, c.time)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := IsScheduledDowntime(c.line, ts); got != c.want {
+			t.Errorf("line %d at %s: incorrect downtime: got %v", c.line, c.time, got)
+		}
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	// across the maintenance of line 0
+	start := time.Date(2016, 1, 3, 1, 0, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:            start,
+		End:              start.Add(6 * time.Hour),
+		InitMachineCount: 16,
+		MachineCount:     16,
+		RNG:              rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(time.Minute)
+	stopped := make(map[string]int)
+	n := 0
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		n++
+		if len(p.FieldKeys()) != len(machineFields) {
+			t.Fatalf("incorrect fields: got %s", p.FieldKeys())
+		}
+		line := string(p.GetTagValue(TagKeys[1]))
+		if running := p.FieldValues()[7].(bool); !running {
+			stopped[line]++
+			if speed := p.FieldValues()[0].(float64); speed != 0 {
+				t.Errorf("stopped machine turning at %v rpm", speed)
+			}
+		}
+	}
+	if want := 16 * 6 * 60; n != want {
+		t.Errorf("incorrect number of readings: got %d want %d", n, want)
+	}
+	// the 8 machines of line 0 are down for 4 hours, those of line 1 run
+	if got := stopped["line_0"]; got != 8*4*60 {
+		t.Errorf("incorrect readings while line_0 is down: got %d want %d", got, 8*4*60)
+	}
+	if got := stopped["line_1"]; got != 0 {
+		t.Errorf("incorrect readings while line_1 is down: got %d want 0", got)
+	}
+}
@@ -0,0 +1,201 @@
+package industrial
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// machineType is a kind of machine, with its ratings while running
+type machineType struct {
+	name []byte
+	// speed is the nominal speed, in rpm, power the rated power, in kW,
+	// vibration the vibration velocity of a new machine at full speed, in
+	// mm/s RMS, heating the rise in temperature at full load over the
+	// ambient, in degrees Celsius, and cycleTime the time to make a part,
+	// or 0 for machines that make none
+	speed     float64
+	power     float64
+	vibration float64
+	heating   float64
+	cycleTime time.Duration
+}
+
+var machineTypeChoices = []machineType{
+	{[]byte("cnc"), 12000, 15, 1.2, 25, 90 * time.Second},
+	{[]byte("press"), 60, 75, 4.5, 15, time.Second},
+	{[]byte("conveyor"), 1450, 5.5, 1.8, 20, 2 * time.Second},
+	{[]byte("robot"), 3000, 4, 0.8, 18, 30 * time.Second},
+	{[]byte("pump"), 2900, 30, 2.8, 30, 0},
+}
+
+// Parameters of the dynamics of machines: loads change to a new level after
+// loadChange on average, and machines settle at a new load over loadLag and
+// at a new temperature over thermalLag; while stopped they draw idlePower
+// of their rated power
+const (
+	loadChange = 5 * time.Minute
+	loadLag    = 30 * time.Second
+	thermalLag = 15 * time.Minute
+	idlePower  = 0.05
+)
+
+// wearRate is how much the vibration of a machine grows for each hour it
+// runs since its last maintenance, relative to that of a new machine
+const wearRate = 1.0 / (7 * 24)
+
+// Machine models a machine of a production line. While running, it turns at
+// about its nominal speed, under a load that steps between levels as jobs
+// change, heats up with the load, and vibrates more and more as it wears.
+// While its line is down for maintenance it stops, cools down to the
+// ambient temperature and is serviced, so it vibrates like new once it runs
+// again. It keeps reporting either way.
+type Machine struct {
+	// These are all assigned once, at Machine creation:
+	Name    []byte
+	Factory []byte
+	Line    []byte
+	Type    []byte
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+
+	line        int
+	machineType *machineType
+	ambient     float64
+
+	running    bool
+	load       float64
+	targetLoad float64
+	wear       float64
+	// heat is the temperature over the ambient
+	heat float64
+	// parts is the number of parts made, and cycle the fraction of the
+	// current one done
+	parts int64
+	cycle float64
+
+	// readings of the current tick
+	speed       float64
+	power       float64
+	temperature float64
+	vibration   [3]float64
+}
+
+// NewMachine returns machine i, reading from start, with its type drawn
+// from r, partway between maintenances and, if running, warmed up
+func NewMachine(r *common.Rand, i int, start time.Time) *Machine {
+	line, factory := LineOf(i)
+	m := &Machine{
+		Name:    []byte(fmt.Sprintf("machine_%d", i)),
+		Factory: []byte(fmt.Sprintf("factory_%d", factory)),
+		Line:    []byte(fmt.Sprintf("line_%d", line)),
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+
+		line:        line,
+		machineType: &machineTypeChoices[r.Intn(len(machineTypeChoices))],
+		ambient:     18 + 8*r.Float64(),
+
+		wear: 1 + wearRate*7*24*r.Float64(),
+	}
+	m.Type = m.machineType.name
+	m.targetLoad = newLoad(r)
+	m.load = m.targetLoad
+	m.running = !IsScheduledDowntime(line, start)
+	m.heat = m.heating()
+	m.read()
+	return m
+}
+
+// newLoad returns a load of a job, relative to the rated power
+func newLoad(r *common.Rand) float64 {
+	return 0.5 + 0.5*r.Float64()
+}
+
+// heating returns the rise in temperature over the ambient the machine
+// settles at, hotter as it wears
+func (m *Machine) heating() float64 {
+	if !m.running {
+		return 0
+	}
+	return m.machineType.heating * m.load * (0.8 + 0.2*m.wear)
+}
+
+// Tick advances the machine by d, starting or stopping it at the edges of
+// its line's maintenance windows
+func (m *Machine) Tick(d time.Duration) {
+	m.timestamp += int64(d)
+	running := !IsScheduledDowntime(m.line, time.Unix(0, m.timestamp))
+	if running && !m.running {
+		// serviced during the maintenance
+		m.wear = 1
+	}
+	m.running = running
+
+	if m.running {
+		if m.rand.Float64() < 1-math.Exp(-float64(d)/float64(loadChange)) {
+			m.targetLoad = newLoad(m.rand)
+		}
+		m.load = m.targetLoad + (m.load-m.targetLoad)*math.Exp(-float64(d)/float64(loadLag))
+		m.wear += wearRate * d.Hours()
+		if m.machineType.cycleTime > 0 {
+			// slower under heavy load
+			m.cycle += float64(d) / float64(m.machineType.cycleTime) * (1.25 - 0.5*m.load)
+			m.parts += int64(m.cycle)
+			m.cycle -= math.Floor(m.cycle)
+		}
+	}
+	target := m.heating()
+	m.heat = target + (m.heat-target)*math.Exp(-float64(d)/float64(thermalLag))
+	m.read()
+}
+
+// read draws the readings of the current tick, with the noise of the
+// sensors
+func (m *Machine) read() {
+	t := m.machineType
+	m.temperature = m.ambient + m.heat + 0.1*m.rand.NormFloat64()
+	if !m.running {
+		m.speed = 0
+		m.power = t.power * idlePower * (1 + 0.05*m.rand.NormFloat64())
+		for i := range m.vibration {
+			m.vibration[i] = 0.02 * math.Abs(m.rand.NormFloat64())
+		}
+		return
+	}
+	// slowing down a little under heavy load
+	m.speed = t.speed * (1.02 - 0.04*m.load) * (1 + 0.002*m.rand.NormFloat64())
+	m.power = t.power * m.load * (1 + 0.02*m.rand.NormFloat64())
+	for i := range m.vibration {
+		// the radial axes, x and y, vibrate more than the axial one, z
+		axis := 1.0
+		if i == 2 {
+			axis = 0.6
+		}
+		m.vibration[i] = math.Abs(t.vibration * axis * m.wear * (1 + 0.1*m.rand.NormFloat64()))
+	}
+}
+
+// ToPoint fills p with the reading of the current tick
+func (m *Machine) ToPoint(p *serialize.Point, i int) bool {
+	p.SetMeasurementName(labelMachine)
+	p.SetTimestamp(m.timestamp)
+	p.AppendTag(TagKeys[0], m.Factory)
+	p.AppendTag(TagKeys[1], m.Line)
+	p.AppendTag(TagKeys[2], m.Name)
+	p.AppendTag(TagKeys[3], m.Type)
+	p.AppendField(machineFields[0], m.speed)
+	p.AppendField(machineFields[1], m.power)
+	p.AppendField(machineFields[2], m.temperature)
+	p.AppendField(machineFields[3], m.vibration[0])
+	p.AppendField(machineFields[4], m.vibration[1])
+	p.AppendField(machineFields[5], m.vibration[2])
+	p.AppendField(machineFields[6], m.parts)
+	p.AppendField(machineFields[7], m.running)
+	return true
+}
@@ -0,0 +1,53 @@
+package industrial
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestMachineTick(t *testing.T) {
+	// an hour before the maintenance of line 1, on Monday
+	start := time.Date(2016, 1, 4, 1, 0, 0, 0, time.UTC)
+	m := NewMachine(common.NewRand(rng.New(123)), 9, start)
+	if string(m.Name) != "machine_9" || string(m.Line) != "line_1" || string(m.Factory) != "factory_0" {
+		t.Errorf("incorrect names: %s %s %s", m.Name, m.Line, m.Factory)
+	}
+	// a press, which makes a part a second or so
+	m.machineType = &machineTypeChoices[1]
+
+	// until 01:59
+	for i := 0; i < 59; i++ {
+		m.Tick(time.Minute)
+	}
+	if !m.running || m.parts < 59*30 || m.parts > 59*90 {
+		t.Errorf("incorrect parts made in an hour: got %d", m.parts)
+	}
+	warm, worn := m.temperature, m.wear
+	if warm < m.ambient+5 {
+		t.Errorf("running machine not warm: %v at ambient %v", warm, m.ambient)
+	}
+
+	// down for maintenance, it stops and cools
+	parts := m.parts
+	for i := 0; i < 4*60; i++ {
+		m.Tick(time.Minute)
+		if m.running || m.speed != 0 {
+			t.Fatalf("machine running during maintenance")
+		}
+	}
+	if m.parts != parts {
+		t.Errorf("parts made during maintenance: got %d", m.parts-parts)
+	}
+	if m.temperature > m.ambient+1 {
+		t.Errorf("stopped machine not cooled: %v at ambient %v", m.temperature, m.ambient)
+	}
+
+	// and runs again serviced
+	m.Tick(time.Minute)
+	if !m.running || m.wear >= worn || m.wear > 1.01 {
+		t.Errorf("machine not serviced: running %v wear %v before %v", m.running, m.wear, worn)
+	}
+}
@@ -0,0 +1,86 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// PointIterator iterates over the points of a simulation one at a time, so
+// they can be consumed directly (e.g., fed into a custom sink) rather than
+// serialized:
+//
+//	it := data.NewPointIterator(ctx, simConfig, 10*time.Second)
+//	for p, ok := it.Next(); ok; p, ok = it.Next() {
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type PointIterator struct {
+	ctx    context.Context
+	sim    common.Simulator
+	points []serialize.Point
+	i, n   int
+	err    error
+}
+
+// NewPointIterator returns a PointIterator over the points simulated by
+// config with readings every interval. It stops early once ctx is done.
+//
+// The points only depend on config, including the RNG it draws from, so
+// callers wanting reproducible points give it a freshly seeded one (as
+// Generator.Points does).
+func NewPointIterator(ctx context.Context, config common.SimulatorConfig, interval time.Duration) *PointIterator {
+	return &PointIterator{
+		ctx:    ctx,
+		sim:    config.ToSimulator(interval),
+		points: make([]serialize.Point, pointBatchSize),
+	}
+}
+
+// Next returns the next point and true, or nil and false once all points are
+// returned or ctx is done. The point is only valid until the following call
+// to Next, so it must be copied (e.g., with Point.CopyTo) to be kept.
+func (it *PointIterator) Next() (*serialize.Point, bool) {
+	for it.i >= it.n {
+		if it.err != nil || it.sim.Finished() {
+			return nil, false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return nil, false
+		}
+		it.i, it.n = 0, it.sim.NextBatch(it.ctx, it.points)
+	}
+	p := &it.points[it.i]
+	it.i++
+	return p, true
+}
+
+// Err returns the error that stopped the iteration early, i.e., ctx.Err(),
+// or nil if all points were returned
+func (it *PointIterator) Err() error {
+	return it.err
+}
+
+// Fields returns the Schema of the points returned
+func (it *PointIterator) Fields() *serialize.Schema {
+	return it.sim.Fields()
+}
+
+// Points returns a PointIterator over the points of the Generator's config,
+// i.e., the points Generate serializes. Format, the interleaved groups,
+// Transformer and Hooks do not apply to the points returned.
+//
+// Like Generate, it does not use any global state.
+func (g *Generator) Points(ctx context.Context) (*PointIterator, error) {
+	c := g.config
+	simConfig, err := c.simulatorConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewPointIterator(ctx, simConfig, c.LogInterval), nil
+}
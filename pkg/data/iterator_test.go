@@ -0,0 +1,83 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+)
+
+func TestGeneratorPoints(t *testing.T) {
+	cfg := testGeneratorConfig()
+	cfg.OmitHeader = true
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var want bytes.Buffer
+	if err := g.Generate(context.Background(), &want); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// serializing the points returned gives the same data as Generate
+	it, err := g.Points(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if it.Fields().Len() == 0 {
+		t.Errorf("no measurements in schema")
+	}
+	var got bytes.Buffer
+	s := &influx.Serializer{}
+	n := 0
+	for p, ok := it.Next(); ok; p, ok = it.Next() {
+		if err := s.Serialize(p, &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("points differ from generated data")
+	}
+	if wantN := strings.Count(want.String(), "\n"); n != wantN {
+		t.Errorf("incorrect number of points: got %d want %d", n, wantN)
+	}
+	if _, ok := it.Next(); ok {
+		t.Errorf("Next returned a point after the end")
+	}
+}
+
+func TestPointIteratorCancelled(t *testing.T) {
+	cfg := testGeneratorConfig()
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	it, err := g.Points(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var first serialize.Point
+	p, ok := it.Next()
+	if !ok {
+		t.Fatalf("no points returned")
+	}
+	p.CopyTo(&first)
+	cancel()
+	// the rest of the current batch may still be returned
+	for _, ok := it.Next(); ok; _, ok = it.Next() {
+	}
+	if err := it.Err(); err != context.Canceled {
+		t.Errorf("incorrect error: got %v want %v", err, context.Canceled)
+	}
+	if len(first.FieldKeys()) == 0 {
+		t.Errorf("copied point has no fields")
+	}
+}
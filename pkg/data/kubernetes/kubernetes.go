@@ -0,0 +1,256 @@
+// Package kubernetes simulates the kubernetes use case: cAdvisor-style
+// metrics of the nodes of Kubernetes clusters, and of the pods scheduled on
+// them and their containers, with pods being deleted and replaced by new
+// ones as time goes on.
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// MaxPods is the most pods that can be scheduled on a node, the default
+// limit of the kubelet
+const MaxPods = 110
+
+// DefaultPodsPerNode is the distribution of the number of pods per node used
+// if none is given
+const DefaultPodsPerNode = "normal:30,10"
+
+var (
+	labelNode      = []byte("node")
+	labelPod       = []byte("pod")
+	labelContainer = []byte("container")
+
+	// TagKeys are the tags of every point, identifying its node; points of
+	// pods and containers have the tags of their pod and container too
+	TagKeys = [][]byte{
+		[]byte("cluster"),
+		[]byte("zone"),
+		[]byte("node"),
+		[]byte("instance_type"),
+	}
+	podTagKeys = [][]byte{
+		[]byte("namespace"),
+		[]byte("workload"),
+		[]byte("pod"),
+	}
+	containerTagKeys = [][]byte{
+		[]byte("container"),
+		[]byte("image"),
+	}
+
+	nodeFields = [][]byte{
+		[]byte("cpu_usage_seconds_total"),
+		[]byte("cpu_capacity_cores"),
+		[]byte("memory_usage_bytes"),
+		[]byte("memory_working_set_bytes"),
+		[]byte("memory_capacity_bytes"),
+		[]byte("network_receive_bytes_total"),
+		[]byte("network_transmit_bytes_total"),
+		[]byte("fs_usage_bytes"),
+		[]byte("fs_capacity_bytes"),
+		[]byte("running_pods"),
+	}
+	nodeTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+	}
+	podFields = [][]byte{
+		[]byte("network_receive_bytes_total"),
+		[]byte("network_transmit_bytes_total"),
+		[]byte("network_receive_errors_total"),
+		[]byte("network_transmit_errors_total"),
+	}
+	podTypes = []serialize.FieldType{
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+	}
+	containerFields = [][]byte{
+		[]byte("cpu_usage_seconds_total"),
+		[]byte("cpu_cfs_throttled_seconds_total"),
+		[]byte("memory_usage_bytes"),
+		[]byte("memory_working_set_bytes"),
+		[]byte("memory_rss_bytes"),
+		[]byte("fs_reads_bytes_total"),
+		[]byte("fs_writes_bytes_total"),
+		[]byte("restart_count"),
+	}
+	containerTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+	}
+
+	schema = serialize.NewTaggedSchema(TagKeys, map[string][][]byte{
+		string(labelPod):       podTagKeys,
+		string(labelContainer): append(append([][]byte(nil), podTagKeys...), containerTagKeys...),
+	}, map[string][][]byte{
+		string(labelNode):      nodeFields,
+		string(labelPod):       podFields,
+		string(labelContainer): containerFields,
+	}, map[string][]serialize.FieldType{
+		string(labelNode):      nodeTypes,
+		string(labelPod):       podTypes,
+		string(labelContainer): containerTypes,
+	})
+)
+
+// PodsPerNode is the distribution of the number of pods scheduled on each
+// node, as parsed by ParsePodsPerNode
+type PodsPerNode struct {
+	spec string
+	// kind is "", for a constant of a, "uniform", between a and b, or
+	// "normal", with mean a and standard deviation b
+	kind string
+	a, b float64
+}
+
+// ParsePodsPerNode parses a distribution of the number of pods per node: a
+// constant <n>, uniform:<min>,<max> or normal:<mean>,<stddev>. Numbers drawn
+// are rounded, and those of normal distributions kept within 3 standard
+// deviations of the mean, and all must be between 0 and MaxPods.
+func ParsePodsPerNode(spec string) (*PodsPerNode, error) {
+	errInvalid := fmt.Errorf("invalid pods per node '%s': must be <n>, uniform:<min>,<max> or normal:<mean>,<stddev> between 0 and %d", spec, MaxPods)
+	d := &PodsPerNode{spec: spec}
+	kind, args, ok := strings.Cut(spec, ":")
+	if !ok {
+		n, err := strconv.Atoi(spec)
+		if err != nil {
+			return nil, errInvalid
+		}
+		d.a = float64(n)
+	} else {
+		if kind != "uniform" && kind != "normal" {
+			return nil, errInvalid
+		}
+		d.kind = kind
+		first, second, ok := strings.Cut(args, ",")
+		if !ok {
+			return nil, errInvalid
+		}
+		var err1, err2 error
+		d.a, err1 = strconv.ParseFloat(first, 64)
+		d.b, err2 = strconv.ParseFloat(second, 64)
+		if err1 != nil || err2 != nil || d.b < 0 || kind == "uniform" && d.b < d.a {
+			return nil, errInvalid
+		}
+	}
+	if d.min() < 0 || d.Max() > MaxPods {
+		return nil, errInvalid
+	}
+	return d, nil
+}
+
+// String returns the distribution as it was parsed
+func (d *PodsPerNode) String() string {
+	return d.spec
+}
+
+// min returns the fewest pods drawn, possibly negative
+func (d *PodsPerNode) min() int {
+	switch d.kind {
+	case "normal":
+		return int(math.Round(d.a - 3*d.b))
+	default:
+		return int(math.Round(d.a))
+	}
+}
+
+// Max returns the most pods drawn
+func (d *PodsPerNode) Max() int {
+	switch d.kind {
+	case "uniform":
+		return int(math.Round(d.b))
+	case "normal":
+		return int(math.Round(d.a + 3*d.b))
+	default:
+		return int(math.Round(d.a))
+	}
+}
+
+// draw returns a number of pods drawn from r
+func (d *PodsPerNode) draw(r rng.RNG) int {
+	var x float64
+	switch d.kind {
+	case "uniform":
+		x = d.a + r.Float64()*(d.b-d.a)
+	case "normal":
+		x = d.a + math.Max(-3, math.Min(r.NormFloat64(), 3))*d.b
+	default:
+		x = d.a
+	}
+	return int(math.Round(x))
+}
+
+// SimulatorConfig is used to create a Simulator of the kubernetes use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitNodeCount is the number of nodes to start with in the first
+	// reporting period
+	InitNodeCount uint64
+	// NodeCount is the total number of nodes to have in the last reporting
+	// period
+	NodeCount uint64
+	// PodsPerNode is the distribution of the number of pods on each node; if
+	// nil, DefaultPodsPerNode is used
+	PodsPerNode *PodsPerNode
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each node. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the node, pod and
+// container measurements
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a reading of each node, and
+// the pods running on it and their containers, every interval. It advances
+// RNG, so each call simulates different nodes.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	podsPerNode := c.PodsPerNode
+	if podsPerNode == nil {
+		podsPerNode, _ = ParsePodsPerNode(DefaultPodsPerNode)
+	}
+	maxPods := podsPerNode.Max()
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitNodeCount,
+		Count:            c.NodeCount,
+		PointsPerReading: 1 + maxPods*(1+maxContainersPerPod),
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewNode(r, i, start, podsPerNode.draw(r), maxPods)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
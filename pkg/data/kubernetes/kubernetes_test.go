@@ -0,0 +1,113 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestParsePodsPerNode(t *testing.T) {
+	cases := []struct {
+		spec     string
+		min, max int
+		errMsg   string
+	}{
+		{spec: "30", min: 30, max: 30},
+		{spec: "0", min: 0, max: 0},
+		{spec: "uniform:10,50", min: 10, max: 50},
+		{spec: "normal:30,10", min: 0, max: 60},
+		{spec: "normal:50,0", min: 50, max: 50},
+		{spec: "", errMsg: "invalid pods per node"},
+		{spec: "x", errMsg: "invalid pods per node"},
+		{spec: "-1", errMsg: "invalid pods per node"},
+		{spec: "111", errMsg: "invalid pods per node"},
+		{spec: "uniform:50,10", errMsg: "invalid pods per node"},
+		{spec: "uniform:10", errMsg: "invalid pods per node"},
+		{spec: "normal:10,5", errMsg: "invalid pods per node"},
+		{spec: "normal:100,5", errMsg: "invalid pods per node"},
+		{spec: "poisson:10,5", errMsg: "invalid pods per node"},
+	}
+	for _, c := range cases {
+		d, err := ParsePodsPerNode(c.spec)
+		if c.errMsg != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), c.errMsg) {
+				t.Errorf("%q: incorrect error: got %v want prefix %s", c.spec, err, c.errMsg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.spec, err)
+			continue
+		}
+		if d.String() != c.spec {
+			t.Errorf("%q: incorrect string: got %q", c.spec, d.String())
+		}
+		if d.Max() != c.max {
+			t.Errorf("%q: incorrect max: got %d want %d", c.spec, d.Max(), c.max)
+		}
+		r := rng.New(123)
+		for i := 0; i < 1000; i++ {
+			if n := d.draw(r); n < c.min || n > c.max {
+				t.Fatalf("%q: drew %d outside of [%d, %d]", c.spec, n, c.min, c.max)
+			}
+		}
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	podsPerNode, err := ParsePodsPerNode("uniform:5,10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &SimulatorConfig{
+		Start:         start,
+		End:           start.Add(time.Hour),
+		InitNodeCount: 3,
+		NodeCount:     3,
+		PodsPerNode:   podsPerNode,
+		RNG:           rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(10 * time.Second)
+	counts := make(map[string]int)
+	pods := make(map[string]bool)
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		m := string(p.MeasurementName())
+		counts[m]++
+		if len(p.FieldKeys()) != len(c.Fields().FieldKeys(m)) {
+			t.Errorf("incorrect fields of %s: got %s", m, p.FieldKeys())
+		}
+		for i, k := range p.TagKeys() {
+			if string(k) == "pod" {
+				pods[string(p.TagValues()[i])] = true
+			}
+		}
+	}
+	// a node point per node and reading
+	if got := counts["node"]; got != 3*360 {
+		t.Errorf("incorrect number of node points: got %d want %d", got, 3*360)
+	}
+	if got := counts["pod"]; got < 3*5*300 || got > 3*10*360 {
+		t.Errorf("incorrect number of pod points: got %d", got)
+	}
+	if got := counts["container"]; got < counts["pod"] || got > 3*counts["pod"] {
+		t.Errorf("incorrect number of container points: got %d for %d pod points", got, counts["pod"])
+	}
+	// pods are replaced over the hour
+	if len(pods) <= 3*5 {
+		t.Errorf("pods did not churn: got %d distinct pods", len(pods))
+	}
+}
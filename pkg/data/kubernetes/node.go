@@ -0,0 +1,236 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// nodesPerCluster is the number of nodes of each cluster
+const nodesPerCluster = 100
+
+type instanceType struct {
+	name   []byte
+	cores  int64
+	memory int64 // bytes
+}
+
+var (
+	instanceTypeChoices = []instanceType{
+		{[]byte("m5.2xlarge"), 8, 32 << 30},
+		{[]byte("m5.4xlarge"), 16, 64 << 30},
+		{[]byte("c5.4xlarge"), 16, 32 << 30},
+		{[]byte("r5.4xlarge"), 16, 128 << 30},
+		{[]byte("m5.8xlarge"), 32, 128 << 30},
+	}
+	zoneChoices = [][]byte{
+		[]byte("us-east-1a"),
+		[]byte("us-east-1b"),
+		[]byte("us-east-1c"),
+	}
+)
+
+// Node models a node of a Kubernetes cluster and the pods scheduled on it,
+// each taking a slot, with Target slots having pods scheduled on them. The
+// metrics of the node are those of its pods' containers plus the overhead of
+// the system.
+type Node struct {
+	// These are all assigned once, at Node creation:
+	Name         []byte
+	Cluster      []byte
+	Zone         []byte
+	InstanceType []byte
+	Target       int
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+
+	cores      int64
+	memory     int64
+	fsCapacity int64
+
+	pods []pod
+
+	// overhead of the system, and the usage of the filesystem
+	systemCPU    *common.ClampedRandomWalkDistribution // cores
+	systemMemory *common.ClampedRandomWalkDistribution // bytes
+	fsUsage      *common.ClampedRandomWalkDistribution // bytes
+	gauges       []common.Distribution
+
+	cpuSeconds float64
+	rx         int64
+	tx         int64
+}
+
+// NewNode returns node i, started at start, with maxPods slots for pods of
+// which target have pods scheduled on them
+func NewNode(r *common.Rand, i int, start time.Time, target, maxPods int) *Node {
+	it := instanceTypeChoices[r.Intn(len(instanceTypeChoices))]
+	fsCapacity := int64(100+r.Intn(401)) << 30
+	n := &Node{
+		Name:         []byte(fmt.Sprintf("node-%d", i)),
+		Cluster:      []byte(fmt.Sprintf("cluster-%d", i/nodesPerCluster)),
+		Zone:         zoneChoices[r.Intn(len(zoneChoices))],
+		InstanceType: it.name,
+		Target:       target,
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+
+		cores:      it.cores,
+		memory:     it.memory,
+		fsCapacity: fsCapacity,
+
+		pods: make([]pod, maxPods),
+
+		systemCPU:    common.CWD(common.ND(0, 0.01), 0.1, 1, 0.1+0.4*r.Float64()),
+		systemMemory: common.CWD(common.ND(0, 4*mib), 512*mib, 2048*mib, float64(512*mib+r.Intn(1024*mib))),
+		fsUsage:      common.CWD(common.ND(0, mib), 0, float64(fsCapacity), float64(fsCapacity)*(0.1+0.5*r.Float64())),
+	}
+	n.gauges = []common.Distribution{n.systemCPU, n.systemMemory, n.fsUsage}
+	for j := 0; j < target; j++ {
+		n.pods[j].schedule(r, n.timestamp)
+	}
+	return n
+}
+
+// Tick advances the node and its pods by d, deleting the pods that have run
+// their course and scheduling replacements of those deleted earlier
+func (n *Node) Tick(d time.Duration) {
+	n.timestamp += int64(d)
+	n.rand.AdvanceAll(n.gauges)
+	for j := 0; j < n.Target; j++ {
+		p := &n.pods[j]
+		if n.timestamp >= p.next {
+			if p.running {
+				p.delete(n.rand, n.timestamp)
+			} else {
+				p.schedule(n.rand, n.timestamp)
+			}
+		}
+		if p.running {
+			p.advance(n.rand, d, &n.rx, &n.tx)
+		}
+	}
+	n.cpuSeconds += math.Min(n.cpuCores(), float64(n.cores)) * d.Seconds()
+}
+
+// cpuCores returns the cores in use by the system and the running containers
+func (n *Node) cpuCores() float64 {
+	cores := n.systemCPU.Get()
+	for j := 0; j < n.Target; j++ {
+		if n.pods[j].running {
+			for _, c := range n.pods[j].containers {
+				cores += c.cpu.Get()
+			}
+		}
+	}
+	return cores
+}
+
+// memoryUsage returns the working set and usage, which includes the cache,
+// of the system and the running containers, capped at the node's memory
+func (n *Node) memoryUsage() (workingSet, usage int64) {
+	ws := n.systemMemory.Get()
+	cache := 0.0
+	for j := 0; j < n.Target; j++ {
+		if n.pods[j].running {
+			for _, c := range n.pods[j].containers {
+				ws += c.workingSet.Get()
+				cache += c.cache.Get()
+			}
+		}
+	}
+	ws = math.Min(ws, float64(n.memory))
+	return int64(ws), int64(math.Min(ws+cache, float64(n.memory)))
+}
+
+// runningPods returns the number of pods running on the node
+func (n *Node) runningPods() int64 {
+	running := int64(0)
+	for j := 0; j < n.Target; j++ {
+		if n.pods[j].running {
+			running++
+		}
+	}
+	return running
+}
+
+// ToPoint fills p with point i of the current reading: the node, point 0,
+// then each pod slot, then each container slot of each pod slot, returning
+// false for slots without a running pod or container
+func (n *Node) ToPoint(p *serialize.Point, i int) bool {
+	if i == 0 {
+		n.appendTags(p)
+		p.SetMeasurementName(labelNode)
+		workingSet, usage := n.memoryUsage()
+		p.AppendField(nodeFields[0], n.cpuSeconds)
+		p.AppendField(nodeFields[1], n.cores)
+		p.AppendField(nodeFields[2], usage)
+		p.AppendField(nodeFields[3], workingSet)
+		p.AppendField(nodeFields[4], n.memory)
+		p.AppendField(nodeFields[5], n.rx)
+		p.AppendField(nodeFields[6], n.tx)
+		p.AppendField(nodeFields[7], int64(n.fsUsage.Get()))
+		p.AppendField(nodeFields[8], n.fsCapacity)
+		p.AppendField(nodeFields[9], n.runningPods())
+		return true
+	}
+
+	i--
+	if i < len(n.pods) {
+		pd := &n.pods[i]
+		if !pd.running {
+			return false
+		}
+		n.appendTags(p)
+		pd.appendTags(p)
+		p.SetMeasurementName(labelPod)
+		p.AppendField(podFields[0], pd.rx)
+		p.AppendField(podFields[1], pd.tx)
+		p.AppendField(podFields[2], pd.rxErrors)
+		p.AppendField(podFields[3], pd.txErrors)
+		return true
+	}
+
+	i -= len(n.pods)
+	pd := &n.pods[i/maxContainersPerPod]
+	if !pd.running || i%maxContainersPerPod >= len(pd.containers) {
+		return false
+	}
+	c := pd.containers[i%maxContainersPerPod]
+	n.appendTags(p)
+	pd.appendTags(p)
+	p.AppendTag(containerTagKeys[0], c.name)
+	p.AppendTag(containerTagKeys[1], c.image)
+	p.SetMeasurementName(labelContainer)
+	workingSet := c.workingSet.Get()
+	p.AppendField(containerFields[0], c.cpuSeconds)
+	p.AppendField(containerFields[1], c.throttledSeconds)
+	p.AppendField(containerFields[2], int64(workingSet+c.cache.Get()))
+	p.AppendField(containerFields[3], int64(workingSet))
+	p.AppendField(containerFields[4], int64(0.8*workingSet))
+	p.AppendField(containerFields[5], c.fsReads)
+	p.AppendField(containerFields[6], c.fsWrites)
+	p.AppendField(containerFields[7], c.restarts)
+	return true
+}
+
+// appendTags appends the tags of the node to p, and sets its timestamp
+func (n *Node) appendTags(p *serialize.Point) {
+	p.SetTimestamp(n.timestamp)
+	p.AppendTag(TagKeys[0], n.Cluster)
+	p.AppendTag(TagKeys[1], n.Zone)
+	p.AppendTag(TagKeys[2], n.Name)
+	p.AppendTag(TagKeys[3], n.InstanceType)
+}
+
+// appendTags appends the tags of the pod to p
+func (p *pod) appendTags(pt *serialize.Point) {
+	pt.AppendTag(podTagKeys[0], p.workload.namespace)
+	pt.AppendTag(podTagKeys[1], p.workload.name)
+	pt.AppendTag(podTagKeys[2], p.name)
+}
@@ -0,0 +1,91 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestNodeToPoint(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := NewNode(common.NewRand(rng.New(123)), 150, start, 4, 6)
+	if got := string(n.Name); got != "node-150" {
+		t.Errorf("incorrect name: got %s", got)
+	}
+	if got := string(n.Cluster); got != "cluster-1" {
+		t.Errorf("incorrect cluster: got %s", got)
+	}
+
+	p := serialize.NewPoint()
+	if !n.ToPoint(p, 0) || string(p.MeasurementName()) != "node" {
+		t.Fatalf("no node point")
+	}
+	if got := p.FieldValues()[9].(int64); got != 4 {
+		t.Errorf("incorrect running pods: got %d want 4", got)
+	}
+	if got := p.Timestamp(); got != start.UnixNano() {
+		t.Errorf("incorrect timestamp: got %d want %d", got, start.UnixNano())
+	}
+
+	// slots 1-6 are pods, of which the first 4 are running, and the rest
+	// their containers
+	for i := 1; i <= 6; i++ {
+		p.Reset()
+		if got := n.ToPoint(p, i); got != (i <= 4) {
+			t.Errorf("pod slot %d: incorrect point made: got %v", i, got)
+		}
+		if i <= 4 && string(p.MeasurementName()) != "pod" {
+			t.Errorf("pod slot %d: incorrect measurement: got %s", i, p.MeasurementName())
+		}
+	}
+	containers := 0
+	for i := 7; i < 7+6*maxContainersPerPod; i++ {
+		p.Reset()
+		if n.ToPoint(p, i) {
+			containers++
+			if string(p.MeasurementName()) != "container" || len(p.TagKeys()) != len(TagKeys)+len(podTagKeys)+len(containerTagKeys) {
+				t.Errorf("container slot %d: incorrect point: %s %s", i, p.MeasurementName(), p.TagKeys())
+			}
+		}
+	}
+	want := 0
+	for j := 0; j < 4; j++ {
+		want += len(n.pods[j].containers)
+	}
+	if containers != want {
+		t.Errorf("incorrect number of containers: got %d want %d", containers, want)
+	}
+}
+
+func TestNodeTick(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	n := NewNode(common.NewRand(rng.New(123)), 0, start, 10, 10)
+	names := make(map[string]bool)
+	prevCPU, prevRx := 0.0, int64(0)
+	deleted := false
+	for i := 0; i < 24*360; i++ {
+		n.Tick(10 * time.Second)
+		if n.cpuSeconds < prevCPU || n.rx < prevRx {
+			t.Fatalf("node counters decreased")
+		}
+		prevCPU, prevRx = n.cpuSeconds, n.rx
+		if n.runningPods() < 10 {
+			deleted = true
+		}
+		for j := range n.pods {
+			if n.pods[j].running {
+				names[string(n.pods[j].name)] = true
+			}
+		}
+	}
+	if !deleted {
+		t.Errorf("no pods were deleted in a day")
+	}
+	// each slot is replaced about 12 times a day
+	if len(names) < 10*6 {
+		t.Errorf("too few pods in a day: got %d", len(names))
+	}
+}
@@ -0,0 +1,237 @@
+package kubernetes
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+)
+
+// maxContainersPerPod is the most containers of a pod: its main container,
+// and up to two sidecars
+const maxContainersPerPod = 3
+
+// Lifecycle of pods: each one runs for podLifetime on average before it is
+// deleted, and its replacement is scheduled podPending later on average,
+// both exponentially distributed
+const (
+	podLifetime = 2 * time.Hour
+	podPending  = 30 * time.Second
+)
+
+const mib = 1 << 20
+
+type workload struct {
+	namespace []byte
+	name      []byte
+	// replicaSet is the hash of the ReplicaSet of its pods
+	replicaSet []byte
+	image      []byte
+}
+
+type sidecar struct {
+	name  []byte
+	image []byte
+}
+
+var (
+	workloads = []workload{
+		newWorkload("default", "web"),
+		newWorkload("default", "api"),
+		newWorkload("payments", "checkout"),
+		newWorkload("payments", "ledger"),
+		newWorkload("payments", "fraud-detector"),
+		newWorkload("search", "indexer"),
+		newWorkload("search", "query"),
+		newWorkload("data", "kafka"),
+		newWorkload("data", "postgres"),
+		newWorkload("monitoring", "prometheus"),
+		newWorkload("monitoring", "grafana"),
+		newWorkload("kube-system", "coredns"),
+	}
+	sidecars = []sidecar{
+		{[]byte("envoy"), []byte("envoyproxy/envoy:v1.27.0")},
+		{[]byte("fluent-bit"), []byte("fluent/fluent-bit:2.1.8")},
+	}
+)
+
+// safeChars are the characters of the random parts of pod names, as
+// Kubernetes makes them: without vowels, and without digits and letters that
+// look alike
+const safeChars = "bcdfghjklmnpqrstvwxz2456789"
+
+// newWorkload returns the workload name of namespace, whose ReplicaSet hash
+// and image tag are derived from its name
+func newWorkload(namespace, name string) workload {
+	h := fnv.New64a()
+	h.Write([]byte(namespace + "/" + name))
+	sum := h.Sum64()
+	replicaSet := make([]byte, 10)
+	for i := range replicaSet {
+		replicaSet[i] = safeChars[sum%uint64(len(safeChars))]
+		sum /= uint64(len(safeChars))
+	}
+	return workload{
+		namespace:  []byte(namespace),
+		name:       []byte(name),
+		replicaSet: replicaSet,
+		image:      []byte("registry.example.com/" + namespace + "/" + name + ":1." + strconv.FormatUint(sum%10, 10)),
+	}
+}
+
+// pod is a slot of a node that a pod is scheduled on, running until it is
+// deleted, then left empty until its replacement is scheduled
+type pod struct {
+	running bool
+	// next is when the pod is next deleted, if running, or its replacement
+	// scheduled, in nanoseconds since the Unix epoch
+	next int64
+
+	workload   *workload
+	name       []byte
+	containers []*container
+
+	rxRate   *common.ClampedRandomWalkDistribution // bytes per second
+	txRate   *common.ClampedRandomWalkDistribution
+	rates    []common.Distribution
+	rx       int64
+	tx       int64
+	rxErrors int64
+	txErrors int64
+}
+
+// schedule replaces p by a new pod running from now, drawn from r
+func (p *pod) schedule(r *common.Rand, now int64) {
+	w := &workloads[r.Intn(len(workloads))]
+	name := make([]byte, 0, len(w.name)+len(w.replicaSet)+7)
+	name = append(name, w.name...)
+	name = append(name, '-')
+	name = append(name, w.replicaSet...)
+	name = append(name, '-')
+	for i := 0; i < 5; i++ {
+		name = append(name, safeChars[r.Intn(len(safeChars))])
+	}
+
+	// mostly just the main container, sometimes with sidecars
+	n := 1
+	if x := r.Float64(); x < 0.1 {
+		n = 3
+	} else if x < 0.4 {
+		n = 2
+	}
+	containers := make([]*container, n)
+	containers[0] = newContainer(r, w.name, w.image)
+	for i := 1; i < n; i++ {
+		containers[i] = newContainer(r, sidecars[i-1].name, sidecars[i-1].image)
+	}
+
+	rxRate := r.Float64() * mib
+	txRate := r.Float64() * mib
+	*p = pod{
+		running:    true,
+		next:       now + exponential(r, podLifetime),
+		workload:   w,
+		name:       name,
+		containers: containers,
+		rxRate:     common.CWD(common.ND(0, 0.05*mib), 0, 10*mib, rxRate),
+		txRate:     common.CWD(common.ND(0, 0.05*mib), 0, 10*mib, txRate),
+	}
+	p.rates = []common.Distribution{p.rxRate, p.txRate}
+}
+
+// delete deletes p at now, leaving its slot empty until its replacement is
+// scheduled
+func (p *pod) delete(r *common.Rand, now int64) {
+	p.running = false
+	p.next = now + exponential(r, podPending)
+}
+
+// advance advances a running p by d, adding the bytes it received and
+// transmitted to rx and tx
+func (p *pod) advance(r *common.Rand, d time.Duration, rx, tx *int64) {
+	r.AdvanceAll(p.rates)
+	secs := d.Seconds()
+	drx := int64(p.rxRate.Get() * secs)
+	dtx := int64(p.txRate.Get() * secs)
+	p.rx += drx
+	p.tx += dtx
+	*rx += drx
+	*tx += dtx
+	if r.Float64() < 0.01 {
+		p.rxErrors++
+	}
+	if r.Float64() < 0.01 {
+		p.txErrors++
+	}
+	for _, c := range p.containers {
+		c.advance(r, d)
+	}
+}
+
+// exponential returns an exponentially distributed duration with the given
+// mean, in nanoseconds
+func exponential(r *common.Rand, mean time.Duration) int64 {
+	return int64(-math.Log(1-r.Float64()) * float64(mean))
+}
+
+// container is a container of a pod, restarted when it runs out of memory
+type container struct {
+	name  []byte
+	image []byte
+
+	cpuLimit    float64 // cores
+	memoryLimit float64 // bytes
+
+	cpu        *common.ClampedRandomWalkDistribution // cores
+	workingSet *common.ClampedRandomWalkDistribution // bytes
+	cache      *common.ClampedRandomWalkDistribution // bytes
+	gauges     []common.Distribution
+
+	cpuSeconds       float64
+	throttledSeconds float64
+	fsReads          int64
+	fsWrites         int64
+	restarts         int64
+}
+
+func newContainer(r *common.Rand, name, image []byte) *container {
+	cpuLimit := 0.25 * float64(1+r.Intn(8))
+	memoryLimit := float64(128*mib) * float64(1+r.Intn(16))
+	c := &container{
+		name:        name,
+		image:       image,
+		cpuLimit:    cpuLimit,
+		memoryLimit: memoryLimit,
+		cpu:         common.CWD(common.ND(0, 0.02*cpuLimit), 0, cpuLimit, r.Float64()*cpuLimit),
+		workingSet:  common.CWD(common.ND(0, 0.002*memoryLimit), 0, memoryLimit, (0.1+0.5*r.Float64())*memoryLimit),
+		cache:       common.CWD(common.ND(0, 0.001*memoryLimit), 0, 0.5*memoryLimit, 0.1*r.Float64()*memoryLimit),
+	}
+	c.gauges = []common.Distribution{c.cpu, c.workingSet, c.cache}
+	return c
+}
+
+// advance advances c by d, restarting it if its working set reaches its
+// memory limit, which resets its counters
+func (c *container) advance(r *common.Rand, d time.Duration) {
+	r.AdvanceAll(c.gauges)
+	secs := d.Seconds()
+	cores := c.cpu.Get()
+	c.cpuSeconds += cores * secs
+	// throttled when close to its limit
+	if throttled := cores - 0.9*c.cpuLimit; throttled > 0 {
+		c.throttledSeconds += throttled * secs
+	}
+	c.fsReads += int64(r.Float64() * 64 * 1024 * secs)
+	c.fsWrites += int64(r.Float64() * 32 * 1024 * secs)
+
+	if c.workingSet.Get() >= c.memoryLimit {
+		// OOM killed
+		c.restarts++
+		c.cpuSeconds, c.throttledSeconds = 0, 0
+		c.fsReads, c.fsWrites = 0, 0
+		c.workingSet.State = 0.1 * c.memoryLimit
+		c.cache.State = 0
+	}
+}
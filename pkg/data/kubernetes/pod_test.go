@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestPodSchedule(t *testing.T) {
+	r := common.NewRand(rng.New(123))
+	var p pod
+	p.schedule(r, 0)
+	if !p.running || p.next <= 0 {
+		t.Errorf("pod not running until a later time: running %v next %d", p.running, p.next)
+	}
+	// e.g., web-7d4b9c8f6d-x2kqz
+	parts := strings.Split(string(p.name), "-")
+	if len(parts) < 3 || len(parts[len(parts)-2]) != 10 || len(parts[len(parts)-1]) != 5 {
+		t.Errorf("incorrect pod name: %s", p.name)
+	}
+	if !strings.HasPrefix(string(p.name), string(p.workload.name)+"-") {
+		t.Errorf("pod name %s does not start with its workload %s", p.name, p.workload.name)
+	}
+	if len(p.containers) < 1 || len(p.containers) > maxContainersPerPod {
+		t.Errorf("incorrect number of containers: got %d", len(p.containers))
+	}
+	if string(p.containers[0].name) != string(p.workload.name) {
+		t.Errorf("main container not named after workload: got %s", p.containers[0].name)
+	}
+
+	p.delete(r, 100)
+	if p.running || p.next <= 100 {
+		t.Errorf("pod not pending until a later time: running %v next %d", p.running, p.next)
+	}
+}
+
+func TestContainerAdvance(t *testing.T) {
+	r := common.NewRand(rng.New(123))
+	c := newContainer(r, []byte("web"), []byte("web:1.0"))
+	c.advance(r, 10*time.Second)
+	if c.cpuSeconds <= 0 || c.fsReads <= 0 || c.fsWrites <= 0 {
+		t.Errorf("counters did not increase: cpu %v reads %d writes %d", c.cpuSeconds, c.fsReads, c.fsWrites)
+	}
+
+	// running out of memory restarts it
+	c.workingSet.State = c.memoryLimit
+	c.workingSet.Step = common.ND(1, 0)
+	c.advance(r, 10*time.Second)
+	if c.restarts != 1 {
+		t.Errorf("incorrect restarts: got %d want 1", c.restarts)
+	}
+	if c.cpuSeconds != 0 || c.fsReads != 0 || c.workingSet.Get() >= c.memoryLimit {
+		t.Errorf("container not reset by restart")
+	}
+}
@@ -0,0 +1,258 @@
+package logs
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// service is a service run by hosts, serving requests to its endpoints in
+// latency milliseconds on average, some of them calling its upstream
+type service struct {
+	name      []byte
+	endpoints []string
+	upstream  string
+	latency   float64
+}
+
+// Hosts run the services in turn
+var services = []service{
+	{[]byte("api-gateway"), []string{"GET /api/v1/products", "GET /api/v1/orders/%d", "POST /api/v1/orders", "POST /api/v1/login"}, "orders", 40},
+	{[]byte("auth"), []string{"POST /login", "POST /token/refresh", "GET /userinfo"}, "users-db", 15},
+	{[]byte("orders"), []string{"GET /orders/%d", "GET /orders", "POST /orders"}, "payments", 60},
+	{[]byte("payments"), []string{"POST /charges", "POST /refunds/%d"}, "payment-provider", 250},
+	{[]byte("search"), []string{"GET /search", "GET /suggest"}, "elasticsearch", 80},
+	{[]byte("inventory"), []string{"GET /stock/%d", "PUT /stock/%d"}, "inventory-db", 20},
+}
+
+// region is a region of hosts, in a time zone of their users
+type region struct {
+	name      []byte
+	utcOffset time.Duration
+}
+
+// Hosts of each service are in the regions in turn
+var regions = []region{
+	{[]byte("us-east-1"), -5 * time.Hour},
+	{[]byte("eu-west-1"), 0},
+	{[]byte("ap-southeast-1"), 8 * time.Hour},
+}
+
+// template is a message logged at a severity for requests of a status. The
+// message is formatted with the request, its latency in milliseconds and
+// the upstream of the service. Requests logged with it take latency times
+// as long as usual, or until they time out, after timeout milliseconds.
+type template struct {
+	format   string
+	status   int64
+	latency  float64
+	timesOut bool
+}
+
+const timeout = 5000
+
+var templates = [][]template{
+	SeverityDebug: {
+		{"cache hit for %[1]s", 200, 0.2, false},
+		{"cache miss for %[1]s, calling %[3]s", 200, 1, false},
+	},
+	SeverityInfo: {
+		{"%[1]s completed in %[2]dms", 200, 1, false},
+	},
+	SeverityWarn: {
+		{"slow request %[1]s took %[2]dms", 200, 5, false},
+		{"rate limit exceeded for %[1]s", 429, 0.05, false},
+		{"retrying call to %[3]s for %[1]s", 200, 2, false},
+	},
+	SeverityError: {
+		{"%[1]s failed: %[3]s returned 503", 503, 1, false},
+		{"%[1]s timed out after %[2]dms waiting for %[3]s", 504, 0, true},
+		{"%[1]s failed: connection to %[3]s refused", 502, 0.1, false},
+	},
+}
+
+// severityWeights are the probabilities of the severities of lines outside
+// and during bursts
+var severityWeights = [2][]float64{
+	{0.10, 0.85, 0.04, 0.01},
+	{0.05, 0.35, 0.30, 0.30},
+}
+
+// Log lines per second of a host at its peak, drawn uniformly from
+// [minPeakRate, maxPeakRate)
+const (
+	minPeakRate = 1.0
+	maxPeakRate = 10.0
+)
+
+// Hosts burst after burstGap on average, for burstDuration on average,
+// logging minBurst to maxBurst times as many lines, of requests slowed down
+// by burstSlowdown
+const (
+	burstGap      = 3 * time.Hour
+	burstDuration = 5 * time.Minute
+	minBurst      = 4.0
+	maxBurst      = 20.0
+	burstSlowdown = 4.0
+)
+
+// maxID is the most ids in the paths of requests
+const maxID = 100000
+
+type line struct {
+	timestamp int64
+	severity  int
+	message   []byte
+	latency   float64
+	status    int64
+	traceID   []byte
+}
+
+// Host models a host running a service, which logs a line for each request
+// it serves, with a severity that depends on how it went. Requests arrive at
+// a rate following the daily cycle of Traffic in the time zone of the
+// region of the host, except during bursts, when many more arrive, slower
+// and mostly failing. The lines of a reading are those in the interval from
+// its timestamp.
+type Host struct {
+	// These are all assigned once, at Host creation:
+	Name      []byte
+	Service   []byte
+	Region    []byte
+	UTCOffset time.Duration
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+	interval  time.Duration
+	maxEvents int
+	service   *service
+	// peakRate is the lines per second at the peak of the day
+	peakRate float64
+
+	burstEnd int64
+	burst    float64
+
+	lines   []line
+	offsets []int64
+}
+
+// NewHost returns host i, with readings every interval from start of at most
+// maxEvents log lines each
+func NewHost(r *common.Rand, i int, start time.Time, interval time.Duration, maxEvents int) *Host {
+	s := &services[i%len(services)]
+	reg := &regions[i/len(services)%len(regions)]
+	h := &Host{
+		Name:      []byte(fmt.Sprintf("%s-%d", s.name, i)),
+		Service:   s.name,
+		Region:    reg.name,
+		UTCOffset: reg.utcOffset,
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+		interval:  interval,
+		maxEvents: maxEvents,
+		service:   s,
+		peakRate:  minPeakRate + (maxPeakRate-minPeakRate)*r.Float64(),
+	}
+	h.read()
+	return h
+}
+
+// Tick advances the host by d to its next reading
+func (h *Host) Tick(d time.Duration) {
+	h.timestamp += int64(d)
+	h.read()
+}
+
+// bursting returns whether the host bursts at the current reading
+func (h *Host) bursting() bool {
+	return h.timestamp < h.burstEnd
+}
+
+// read draws the log lines of the current reading
+func (h *Host) read() {
+	if !h.bursting() && h.rand.Float64() < 1-math.Exp(-float64(h.interval)/float64(burstGap)) {
+		h.burstEnd = h.timestamp + int64(-math.Log(1-h.rand.Float64())*float64(burstDuration))
+		h.burst = minBurst + (maxBurst-minBurst)*h.rand.Float64()
+	}
+	local := time.Unix(0, h.timestamp).UTC().Add(h.UTCOffset)
+	rate := h.peakRate * Traffic(local)
+	weights := severityWeights[0]
+	if h.bursting() {
+		rate *= h.burst
+		weights = severityWeights[1]
+	}
+	n := common.Poisson(h.rand, rate*h.interval.Seconds())
+	if n > h.maxEvents {
+		// dropped by the logger
+		n = h.maxEvents
+	}
+
+	h.offsets = h.offsets[:0]
+	for i := 0; i < n; i++ {
+		h.offsets = append(h.offsets, h.rand.Int63n(int64(h.interval)))
+	}
+	sort.Slice(h.offsets, func(i, j int) bool { return h.offsets[i] < h.offsets[j] })
+	h.lines = h.lines[:0]
+	for _, offset := range h.offsets {
+		h.lines = append(h.lines, h.line(h.timestamp+offset, weights))
+	}
+}
+
+// line returns a line logged at timestamp, of a severity drawn by weights
+func (h *Host) line(timestamp int64, weights []float64) line {
+	severity := 0
+	for x := h.rand.Float64(); severity < len(weights)-1 && x >= weights[severity]; severity++ {
+		x -= weights[severity]
+	}
+	t := &templates[severity][h.rand.Intn(len(templates[severity]))]
+
+	// log-normal
+	latency := h.service.latency * math.Exp(0.5*h.rand.NormFloat64()) * t.latency
+	if h.bursting() {
+		latency *= burstSlowdown
+	}
+	if t.timesOut || latency > timeout {
+		latency = timeout + 5*h.rand.Float64()
+	}
+	latency = math.Round(latency*100) / 100
+
+	s := h.service
+	request := s.endpoints[h.rand.Intn(len(s.endpoints))]
+	if strings.Contains(request, "%d") {
+		request = fmt.Sprintf(request, h.rand.Intn(maxID))
+	}
+	return line{
+		timestamp: timestamp,
+		severity:  severity,
+		message:   []byte(fmt.Sprintf(t.format, request, int64(latency), s.upstream)),
+		latency:   latency,
+		status:    t.status,
+		traceID:   []byte(fmt.Sprintf("%016x", h.rand.Uint64())),
+	}
+}
+
+// ToPoint fills p with log line i of the current reading, returning false
+// for points beyond them
+func (h *Host) ToPoint(p *serialize.Point, i int) bool {
+	if i >= len(h.lines) {
+		return false
+	}
+	l := &h.lines[i]
+	p.SetMeasurementName(labelLog)
+	p.SetTimestamp(l.timestamp)
+	p.AppendTag(TagKeys[0], h.Service)
+	p.AppendTag(TagKeys[1], h.Name)
+	p.AppendTag(TagKeys[2], h.Region)
+	p.AppendTag(TagKeys[3], severityNames[l.severity])
+	p.AppendField(logFields[0], l.message)
+	p.AppendField(logFields[1], l.latency)
+	p.AppendField(logFields[2], l.status)
+	p.AppendField(logFields[3], l.traceID)
+	return true
+}
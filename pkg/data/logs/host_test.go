@@ -0,0 +1,75 @@
+package logs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestHostTick(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	const interval = 10 * time.Second
+	maxEvents := maxEventsPerReading(interval)
+	h := NewHost(common.NewRand(rng.New(123)), 8, start, interval, maxEvents)
+	if got := string(h.Name); got != "orders-8" {
+		t.Errorf("incorrect name: got %s", got)
+	}
+	if got := string(h.Region); got != "eu-west-1" {
+		t.Errorf("incorrect region: got %s", got)
+	}
+
+	var lines, bursting, burstLines, burstErrors int
+	p := serialize.NewPoint()
+	for i := 0; i < 24*360; i++ {
+		if len(h.lines) > maxEvents {
+			t.Fatalf("lines over the most of a reading: %d", len(h.lines))
+		}
+		prev := h.timestamp
+		for j, l := range h.lines {
+			if l.timestamp < prev || l.timestamp >= h.timestamp+int64(interval) {
+				t.Fatalf("line out of order or outside its reading: %d", l.timestamp)
+			}
+			prev = l.timestamp
+			if l.latency <= 0 || l.latency > timeout+5 {
+				t.Errorf("latency out of range: %v", l.latency)
+			}
+			if strings.Contains(string(l.message), "%") {
+				t.Errorf("message not formatted: %s", l.message)
+			}
+			p.Reset()
+			if !h.ToPoint(p, j) {
+				t.Fatalf("no point made of line %d", j)
+			}
+		}
+		p.Reset()
+		if h.ToPoint(p, len(h.lines)) {
+			t.Errorf("point made beyond the lines")
+		}
+
+		lines += len(h.lines)
+		if h.bursting() {
+			bursting++
+			burstLines += len(h.lines)
+			for _, l := range h.lines {
+				if l.severity == SeverityError {
+					burstErrors++
+				}
+			}
+		}
+		h.Tick(interval)
+	}
+	if bursting == 0 {
+		t.Fatalf("no bursts in a day")
+	}
+	// many more lines a reading during bursts, a lot of them errors
+	if burstLines/bursting < 3*lines/(24*360) {
+		t.Errorf("too few lines during bursts: %d in %d readings", burstLines, bursting)
+	}
+	if burstErrors < burstLines/5 {
+		t.Errorf("too few errors during bursts: %d of %d", burstErrors, burstLines)
+	}
+}
@@ -0,0 +1,126 @@
+// Package logs simulates the logs use case: structured log lines of the
+// hosts of services, each with its severity, a message, and the latency and
+// status of the request it logs, at a rate that follows a daily cycle and
+// bursts now and then, when most lines are warnings and errors.
+package logs
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelLog = []byte("log")
+
+	// TagKeys are the tags of every point, identifying the host that logged
+	// it, its service and region, and its severity
+	TagKeys = [][]byte{
+		[]byte("service"),
+		[]byte("host"),
+		[]byte("region"),
+		[]byte("severity"),
+	}
+
+	logFields = [][]byte{
+		[]byte("message"),
+		[]byte("latency_ms"),
+		[]byte("status_code"),
+		[]byte("trace_id"),
+	}
+	logTypes = []serialize.FieldType{
+		serialize.FieldTypeString,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeString,
+	}
+
+	schema = serialize.NewTypedSchema(TagKeys, map[string][][]byte{
+		string(labelLog): logFields,
+	}, map[string][]serialize.FieldType{
+		string(labelLog): logTypes,
+	})
+)
+
+// Severities of log lines
+const (
+	SeverityDebug = iota
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+)
+
+var severityNames = [][]byte{
+	SeverityDebug: []byte("debug"),
+	SeverityInfo:  []byte("info"),
+	SeverityWarn:  []byte("warn"),
+	SeverityError: []byte("error"),
+}
+
+// Traffic relative to the peak through the day, in local time: lowest at
+// 03:00 and highest at 15:00
+const (
+	nightTraffic   = 0.2
+	trafficLowHour = 3
+)
+
+// Traffic returns the traffic of services at local time t relative to the
+// peak of the day, between nightTraffic and 1
+func Traffic(t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	day := (1 - math.Cos(2*math.Pi*(hour-trafficLowHour)/24)) / 2
+	return nightTraffic + (1-nightTraffic)*day
+}
+
+// SimulatorConfig is used to create a Simulator of the logs use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitHostCount is the number of hosts to start with in the first
+	// reporting period
+	InitHostCount uint64
+	// HostCount is the total number of hosts to have in the last reporting
+	// period
+	HostCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each host. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the log measurement
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a reading of the log lines of
+// each host every interval. It advances RNG, so each call simulates
+// different hosts.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	maxEvents := maxEventsPerReading(interval)
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitHostCount,
+		Count:            c.HostCount,
+		PointsPerReading: maxEvents,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewHost(r, i, start, interval, maxEvents)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
+
+// maxEventsPerReading returns the most log lines of a host in a reading
+// every interval, above those of the busiest host at its peak outside of
+// bursts; hosts drop lines beyond it during the strongest bursts, as
+// loggers limiting their rate do
+func maxEventsPerReading(interval time.Duration) int {
+	return int(math.Ceil(maxBurst/2*maxPeakRate*interval.Seconds())) + 10
+}
@@ -0,0 +1,70 @@
+package logs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestTraffic(t *testing.T) {
+	night := Traffic(time.Date(2016, 1, 4, trafficLowHour, 0, 0, 0, time.UTC))
+	peak := Traffic(time.Date(2016, 1, 4, trafficLowHour+12, 0, 0, 0, time.UTC))
+	if night != nightTraffic || peak != 1 {
+		t.Errorf("incorrect traffic: got %v at night, %v at the peak", night, peak)
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:         start,
+		End:           start.Add(time.Hour),
+		InitHostCount: 6,
+		HostCount:     6,
+		RNG:           rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(10 * time.Second)
+	severities := make(map[string]int)
+	n := 0
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		n++
+		if ts := time.Unix(0, p.Timestamp()); ts.Before(start) || !ts.Before(c.End) {
+			t.Fatalf("point outside the simulation: %v", ts.UTC())
+		}
+		if got := string(p.MeasurementName()); got != "log" {
+			t.Fatalf("incorrect measurement: got %s", got)
+		}
+		for j, v := range p.FieldValues() {
+			if got, want := serialize.FieldTypeOf(v), c.Fields().FieldTypes("log")[j]; got != want {
+				t.Errorf("incorrect type of %s: got %v want %v", p.FieldKeys()[j], got, want)
+			}
+		}
+		if len(p.TagKeys()) != len(TagKeys) {
+			t.Errorf("incorrect tags: got %s", p.TagKeys())
+		}
+		severities[string(p.TagValues()[3])]++
+	}
+	// a host of each service in us-east-1, at 1 to 10 lines per second at
+	// the peak, in the evening there
+	if n < 6*3600*minPeakRate*nightTraffic || n > 6*3600*maxPeakRate*maxBurst {
+		t.Errorf("incorrect number of lines: got %d", n)
+	}
+	if severities["info"] < n/2 {
+		t.Errorf("too few info lines: %d of %d", severities["info"], n)
+	}
+	if severities["error"] == 0 || severities["error"] > n/10 {
+		t.Errorf("incorrect number of error lines: %d of %d", severities["error"], n)
+	}
+}
@@ -0,0 +1,170 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// siteOffsets are the offsets from UTC of the time zones of sites, which
+// take them in turn
+var siteOffsets = []time.Duration{
+	0,
+	-5 * time.Hour,
+	1 * time.Hour,
+	-8 * time.Hour,
+	8 * time.Hour,
+	5*time.Hour + 30*time.Minute,
+	9 * time.Hour,
+}
+
+// Devices reboot after rebootGap on average, taking rebootDuration, during
+// which they are not polled, and start over with counters and uptime at zero
+const (
+	rebootGap      = 90 * 24 * time.Hour
+	rebootDuration = 4 * time.Minute
+)
+
+// uptimeTick is the unit of the uptime of devices, as of SNMP TimeTicks,
+// which wrap at 32 bits too, after about 497 days
+const uptimeTick = 10 * time.Millisecond
+
+// Device models a router or switch of a site, polled for its uptime and
+// resources, and the counters of its interfaces. Its traffic follows the
+// day of its site, and its interfaces go down now and then, counting
+// nothing until they are back up.
+type Device struct {
+	// These are all assigned once, at Device creation:
+	Name      []byte
+	Site      []byte
+	Role      []byte
+	Model     []byte
+	UTCOffset time.Duration
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+
+	interfaces []*netInterface
+
+	rebooting bool
+	bootEnd   int64
+	uptime    uint32
+
+	memory float64
+	// readings of the current tick
+	cpu         float64
+	temperature float64
+}
+
+// NewDevice returns device i, polled from start, with its interfaces and
+// model drawn from r, up for a while and counting since it booted
+func NewDevice(r *common.Rand, i int, start time.Time) *Device {
+	role, site := roleOf(i), i/devicesPerSite
+	d := &Device{
+		Name:      []byte(fmt.Sprintf("%s-%d", role.hostname, i)),
+		Site:      []byte(fmt.Sprintf("site_%d", site)),
+		Role:      role.name,
+		Model:     role.models[r.Intn(len(role.models))],
+		UTCOffset: siteOffsets[site%len(siteOffsets)],
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+
+		uptime: uint32(r.Int63n(int64(rebootGap / uptimeTick))),
+		memory: 30 + 30*r.Float64(),
+	}
+	uptime := time.Duration(d.uptime) * uptimeTick
+	for j := 1; j <= role.downlinks; j++ {
+		name := []byte(fmt.Sprintf(role.downlinkName, j))
+		d.interfaces = append(d.interfaces, newInterface(r, name, role.downlinkMbps, role.downlinkLoad, uptime))
+	}
+	for j := 1; j <= role.uplinks; j++ {
+		name := []byte(fmt.Sprintf(role.uplinkName, j))
+		d.interfaces = append(d.interfaces, newInterface(r, name, role.uplinkMbps, role.uplinkLoad, uptime))
+	}
+	d.read(Traffic(d.local(d.timestamp)))
+	return d
+}
+
+// local returns the time of the site of the device at t, in nanoseconds
+// since the Unix epoch
+func (d *Device) local(t int64) time.Time {
+	return time.Unix(0, t+int64(d.UTCOffset)).UTC()
+}
+
+// Tick advances the device by dt, counting the traffic of its interfaces,
+// unless it reboots
+func (d *Device) Tick(dt time.Duration) {
+	d.timestamp += int64(dt)
+	if d.rebooting {
+		if d.timestamp < d.bootEnd {
+			return
+		}
+		d.rebooting = false
+		d.uptime = uint32((d.timestamp - d.bootEnd) / int64(uptimeTick))
+		for _, n := range d.interfaces {
+			n.resetCounters()
+		}
+		d.read(Traffic(d.local(d.timestamp)))
+		return
+	}
+	if happens(d.rand, dt, rebootGap) {
+		d.rebooting = true
+		d.bootEnd = d.timestamp + int64(float64(rebootDuration)*(0.5+d.rand.Float64()))
+		return
+	}
+
+	d.uptime += uint32(dt / uptimeTick)
+	traffic := Traffic(d.local(d.timestamp))
+	for _, n := range d.interfaces {
+		n.advance(d.rand, d.timestamp, dt, traffic)
+	}
+	d.memory = math.Max(20, math.Min(90, d.memory+0.05*d.rand.NormFloat64()))
+	d.read(traffic)
+}
+
+// read draws the readings of the current tick, which grow with the traffic
+func (d *Device) read(traffic float64) {
+	d.cpu = math.Max(1, math.Min(100, 5+25*traffic+3*d.rand.NormFloat64()))
+	d.temperature = 38 + 8*traffic + 0.5*d.rand.NormFloat64()
+}
+
+// ToPoint fills p with the readings of the device for i 0, and those of
+// its interface i-1 otherwise. It returns false while the device reboots,
+// and for interfaces it does not have.
+func (d *Device) ToPoint(p *serialize.Point, i int) bool {
+	if d.rebooting || i > len(d.interfaces) {
+		return false
+	}
+	p.SetTimestamp(d.timestamp)
+	p.AppendTag(TagKeys[0], d.Site)
+	p.AppendTag(TagKeys[1], d.Name)
+	p.AppendTag(TagKeys[2], d.Role)
+	p.AppendTag(TagKeys[3], d.Model)
+	if i == 0 {
+		p.SetMeasurementName(labelDevice)
+		p.AppendField(deviceFields[0], int64(d.uptime))
+		p.AppendField(deviceFields[1], d.cpu)
+		p.AppendField(deviceFields[2], d.memory)
+		p.AppendField(deviceFields[3], d.temperature)
+		return true
+	}
+	n := d.interfaces[i-1]
+	p.SetMeasurementName(labelInterface)
+	p.AppendTag(tagKeyInterface, n.name)
+	for j, c := range n.counters {
+		p.AppendField(interfaceFields[j], int64(c))
+	}
+	p.AppendField(interfaceFields[interfaceCounters], n.operStatus())
+	p.AppendField(interfaceFields[interfaceCounters+1], n.mbps)
+	return true
+}
+
+// happens returns whether something that happens after mean on average,
+// exponentially distributed, happens in the next d
+func happens(r *common.Rand, d, mean time.Duration) bool {
+	return r.Float64() < 1-math.Exp(-float64(d)/float64(mean))
+}
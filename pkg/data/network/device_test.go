@@ -0,0 +1,74 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestNewDevice(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	d := NewDevice(common.NewRand(rng.New(123)), 11, start)
+	if got := string(d.Name); got != "asw-11" {
+		t.Errorf("incorrect name: got %s", got)
+	}
+	if got := string(d.Site); got != "site_1" {
+		t.Errorf("incorrect site: got %s", got)
+	}
+	if len(d.interfaces) != 26 {
+		t.Fatalf("incorrect number of interfaces: got %d", len(d.interfaces))
+	}
+	if got := string(d.interfaces[0].name); got != "GigabitEthernet1/0/1" {
+		t.Errorf("incorrect name of the first interface: got %s", got)
+	}
+	if got := string(d.interfaces[25].name); got != "TenGigabitEthernet1/1/2" {
+		t.Errorf("incorrect name of the last interface: got %s", got)
+	}
+
+	p := serialize.NewPoint()
+	for i := 0; i <= maxInterfaces; i++ {
+		p.Reset()
+		if !d.ToPoint(p, i) {
+			t.Errorf("no point made for %d", i)
+		}
+	}
+	p.Reset()
+	if d.ToPoint(p, maxInterfaces+1) {
+		t.Errorf("point made beyond the interfaces")
+	}
+}
+
+func TestDeviceReboot(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	d := NewDevice(common.NewRand(rng.New(123)), 0, start)
+	d.rebooting = true
+	d.bootEnd = d.timestamp + int64(3*time.Minute)
+
+	p := serialize.NewPoint()
+	for i := 0; i < 17; i++ {
+		d.Tick(10 * time.Second)
+		p.Reset()
+		if d.ToPoint(p, 0) {
+			t.Fatalf("point made while rebooting")
+		}
+	}
+	d.Tick(10 * time.Second)
+	if !d.ToPoint(p, 0) {
+		t.Fatalf("no point made after rebooting")
+	}
+	if d.uptime != 0 {
+		t.Errorf("uptime not reset: got %d", d.uptime)
+	}
+	for _, n := range d.interfaces {
+		if n.counters != [interfaceCounters]uint32{} {
+			t.Errorf("counters of %s not reset: got %v", n.name, n.counters)
+		}
+	}
+	d.Tick(10 * time.Second)
+	if d.uptime != 1000 {
+		t.Errorf("incorrect uptime: got %d want %d", d.uptime, 1000)
+	}
+}
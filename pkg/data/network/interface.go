@@ -0,0 +1,147 @@
+package network
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+)
+
+// Counters of interfaces, in the order of their fields
+const (
+	inOctets = iota
+	outOctets
+	inPackets
+	outPackets
+	inErrors
+	outErrors
+	inDiscards
+	outDiscards
+	interfaceCounters
+)
+
+// Values of the oper_status of interfaces, as in the IF-MIB
+const (
+	operStatusUp   = 1
+	operStatusDown = 2
+)
+
+// maxLoad is the most utilization of links. Octet counters of 10G links
+// wrap every 14 seconds at it, so polls further apart than that cannot tell
+// how many times they wrapped.
+const maxLoad = 0.25
+
+// Parameters of the traffic of links: its utilization varies from the mean
+// by loadNoise, changing over loadLag, in packets of packetSize bytes on
+// average. A bit in bitErrorRate is in error, except while the cable is
+// faulty, after faultGap on average for faultDuration, when a bit in
+// faultErrorRate is. Links go down for flapDuration after flapGap on average.
+const (
+	loadNoise      = 0.2
+	loadLag        = 5 * time.Minute
+	packetSize     = 800
+	bitErrorRate   = 1e-12
+	faultErrorRate = 1e-8
+	faultGap       = 60 * 24 * time.Hour
+	faultDuration  = time.Hour
+	flapGap        = 30 * 24 * time.Hour
+	flapDuration   = 5 * time.Minute
+)
+
+// netInterface models an interface of a device, counting octets, packets,
+// errors and discards in each direction in counters of 32 bits, which wrap,
+// as SNMP Counter32s do
+type netInterface struct {
+	name []byte
+	mbps int64
+	// load is the mean utilization of the link in each direction at the
+	// peak of the day, and noise its deviation from it, relative to it
+	load  [2]float64
+	noise float64
+	// packetSize is the mean size of packets, in bytes
+	packetSize float64
+
+	up        bool
+	downUntil int64
+	faultEnd  int64
+
+	counters [interfaceCounters]uint32
+}
+
+func newInterface(r *common.Rand, name []byte, mbps int64, load float64, uptime time.Duration) *netInterface {
+	n := &netInterface{
+		name:       name,
+		mbps:       mbps,
+		packetSize: packetSize * (0.5 + r.Float64()),
+		up:         true,
+	}
+	for dir := range n.load {
+		n.load[dir] = math.Min(maxLoad, load*(0.5+r.Float64()))
+		// as if counted at the mean traffic of the day since the device
+		// booted, without faults
+		octets, packets, errors, discards := n.traffic(dir, meanTraffic, bitErrorRate, uptime)
+		n.counters[inOctets+dir] = uint32(uint64(octets))
+		n.counters[inPackets+dir] = uint32(uint64(packets))
+		n.counters[inErrors+dir] = uint32(uint64(errors))
+		n.counters[inDiscards+dir] = uint32(uint64(discards))
+	}
+	return n
+}
+
+// resetCounters sets the counters to zero, as when the device reboots
+func (n *netInterface) resetCounters() {
+	n.counters = [interfaceCounters]uint32{}
+}
+
+// operStatus returns the oper_status of the interface
+func (n *netInterface) operStatus() int64 {
+	if n.up {
+		return operStatusUp
+	}
+	return operStatusDown
+}
+
+// advance counts the traffic of the interface over the d up to now, at the
+// traffic of its network relative to the peak
+func (n *netInterface) advance(r *common.Rand, now int64, d time.Duration, traffic float64) {
+	if n.up && happens(r, d, flapGap) {
+		n.up = false
+		n.downUntil = now + int64(float64(flapDuration)*(0.5+r.Float64()))
+	} else if !n.up && now >= n.downUntil {
+		n.up = true
+	}
+	if n.faultEnd <= now && happens(r, d, faultGap) {
+		n.faultEnd = now + int64(faultDuration)
+	}
+	decay := math.Exp(-float64(d) / float64(loadLag))
+	n.noise = n.noise*decay + loadNoise*math.Sqrt(1-decay*decay)*r.NormFloat64()
+	if !n.up {
+		return
+	}
+
+	bitErrors := bitErrorRate
+	if now < n.faultEnd {
+		bitErrors = faultErrorRate
+	}
+	for dir := range n.load {
+		octets, packets, errors, discards := n.traffic(dir, traffic*(1+n.noise), bitErrors, d)
+		n.counters[inOctets+dir] += uint32(uint64(octets))
+		n.counters[inPackets+dir] += uint32(uint64(packets))
+		n.counters[inErrors+dir] += uint32(common.Poisson(r, errors))
+		n.counters[inDiscards+dir] += uint32(common.Poisson(r, discards))
+	}
+}
+
+// traffic returns the mean octets, packets, errors and discards in
+// direction dir over d, at the traffic relative to the peak and with a bit
+// in bitErrors in error
+func (n *netInterface) traffic(dir int, traffic, bitErrors float64, d time.Duration) (octets, packets, errors, discards float64) {
+	load := math.Max(0, math.Min(maxLoad, n.load[dir]*traffic))
+	octets = load * float64(n.mbps) * 1e6 / 8 * d.Seconds()
+	packets = octets / n.packetSize
+	// a packet is in error if any of its bits is
+	errors = packets * math.Min(1, 8*n.packetSize*bitErrors)
+	// discards grow steeply as the link nears its capacity
+	discards = packets * 1e-6 * math.Pow(load/maxLoad, 4)
+	return octets, packets, errors, discards
+}
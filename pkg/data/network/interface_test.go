@@ -0,0 +1,81 @@
+package network
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestInterfaceCounters(t *testing.T) {
+	r := common.NewRand(rng.New(123))
+	n := newInterface(r, []byte("TenGigabitEthernet1/1/1"), 10000, maxLoad, 0)
+	n.resetCounters()
+	n.counters[inOctets] = 1<<32 - 1000
+
+	const d = 10 * time.Second
+	now := time.Date(2016, 1, 4, 14, 0, 0, 0, time.UTC).UnixNano()
+	var octets [2]uint64
+	wraps := 0
+	for i := 0; i < 60; i++ {
+		prev := n.counters
+		now += int64(d)
+		n.advance(r, now, d, 1)
+		if !n.up {
+			continue
+		}
+		for dir := range octets {
+			// as rate() takes the difference of counters, modulo their range
+			delta := n.counters[inOctets+dir] - prev[inOctets+dir]
+			octets[dir] += uint64(delta)
+			if bps := float64(delta) * 8 / d.Seconds(); bps > maxLoad*10e9*1.01 {
+				t.Errorf("rate over the most utilization: %v bps", bps)
+			}
+		}
+		if n.counters[inOctets] < prev[inOctets] {
+			wraps++
+		}
+		if n.counters[inPackets] == prev[inPackets] {
+			t.Errorf("no packets counted")
+		}
+	}
+	if wraps == 0 {
+		t.Errorf("octet counter never wrapped")
+	}
+	for dir, o := range octets {
+		if bps := float64(o) * 8 / (60 * d.Seconds()); bps < 0.2*maxLoad*10e9 {
+			t.Errorf("direction %d: rate too low: %v bps", dir, bps)
+		}
+	}
+}
+
+func TestInterfaceDown(t *testing.T) {
+	r := common.NewRand(rng.New(123))
+	n := newInterface(r, []byte("GigabitEthernet1/0/1"), 1000, 0.05, time.Hour)
+	now := time.Date(2016, 1, 4, 14, 0, 0, 0, time.UTC).UnixNano()
+	n.up = false
+	n.downUntil = now + int64(time.Minute)
+	counters := n.counters
+	n.advance(r, now+int64(30*time.Second), 30*time.Second, 1)
+	if n.operStatus() != operStatusDown || n.counters != counters {
+		t.Errorf("interface counted while down: %v", n.counters)
+	}
+	n.advance(r, now+int64(time.Minute), 30*time.Second, 1)
+	if n.operStatus() != operStatusUp || n.counters[inOctets] == counters[inOctets] {
+		t.Errorf("interface not counting once up")
+	}
+}
+
+func TestNewInterface(t *testing.T) {
+	r := common.NewRand(rng.New(123))
+	n := newInterface(r, []byte("GigabitEthernet1/0/1"), 1000, 0.05, 24*time.Hour)
+	// a day at a few percent of 1G wraps the octet counter many times, but
+	// counts few errors
+	if n.counters[inOctets] == 0 || n.counters[inPackets] == 0 {
+		t.Errorf("counters not counted since boot: %v", n.counters)
+	}
+	if n.counters[inErrors] > 1000 || n.counters[inDiscards] > 1000 {
+		t.Errorf("too many errors or discards since boot: %v", n.counters)
+	}
+}
@@ -0,0 +1,219 @@
+// Package network simulates the network use case: SNMP-style telemetry of
+// the routers and switches of sites, most of it the counters of their
+// interfaces, which are 32 bits wide and wrap, as queries of their rates
+// have to account for.
+package network
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelDevice    = []byte("device")
+	labelInterface = []byte("interface")
+
+	// TagKeys are the tags of every point, identifying its device and where
+	// it is in the network; interfaces have the tag of their name too
+	TagKeys = [][]byte{
+		[]byte("site"),
+		[]byte("device"),
+		[]byte("role"),
+		[]byte("model"),
+	}
+	tagKeyInterface = []byte("interface")
+
+	deviceFields = [][]byte{
+		[]byte("uptime"),
+		[]byte("cpu_usage"),
+		[]byte("memory_usage"),
+		[]byte("temperature"),
+	}
+	deviceTypes = []serialize.FieldType{
+		serialize.FieldTypeInt,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+	}
+	// interfaceFields are the counters of an interface, in the order of
+	// interfaceCounters, then its status and speed
+	interfaceFields = [][]byte{
+		[]byte("in_octets"),
+		[]byte("out_octets"),
+		[]byte("in_packets"),
+		[]byte("out_packets"),
+		[]byte("in_errors"),
+		[]byte("out_errors"),
+		[]byte("in_discards"),
+		[]byte("out_discards"),
+		[]byte("oper_status"),
+		[]byte("speed"),
+	}
+	interfaceTypes = []serialize.FieldType{
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+		serialize.FieldTypeInt,
+	}
+
+	schema = serialize.NewTaggedSchema(TagKeys, map[string][][]byte{
+		string(labelInterface): {tagKeyInterface},
+	}, map[string][][]byte{
+		string(labelDevice):    deviceFields,
+		string(labelInterface): interfaceFields,
+	}, map[string][]serialize.FieldType{
+		string(labelDevice):    deviceTypes,
+		string(labelInterface): interfaceTypes,
+	})
+)
+
+// role is the role of a device in the network of its site, with the
+// interfaces of devices of the role: downlinks towards the edge of the
+// network and uplinks towards its core, or the WAN for core routers
+type role struct {
+	name     []byte
+	hostname string
+	models   [][]byte
+
+	downlinks    int
+	downlinkName string
+	downlinkMbps int64
+	// downlinkLoad and uplinkLoad are the mean utilization of links at the
+	// peak of the day
+	downlinkLoad float64
+
+	uplinks    int
+	uplinkName string
+	uplinkMbps int64
+	uplinkLoad float64
+}
+
+// Devices are grouped into sites of devicesPerSite, in the order of the
+// devices: a core router, then distributionSwitches distribution switches,
+// then access switches
+const (
+	devicesPerSite       = 8
+	distributionSwitches = 2
+)
+
+var roles = []role{
+	{
+		name:     []byte("core"),
+		hostname: "rtr",
+		models:   [][]byte{[]byte("ASR-9001"), []byte("MX204")},
+
+		downlinks: 4, downlinkName: "TenGigE0/0/0/%d", downlinkMbps: 10000, downlinkLoad: 0.2,
+		uplinks: 2, uplinkName: "TenGigE0/0/1/%d", uplinkMbps: 10000, uplinkLoad: 0.25,
+	},
+	{
+		name:     []byte("distribution"),
+		hostname: "dsw",
+		models:   [][]byte{[]byte("C9500-16X"), []byte("EX4650")},
+
+		downlinks: 8, downlinkName: "TenGigabitEthernet1/0/%d", downlinkMbps: 10000, downlinkLoad: 0.1,
+		uplinks: 2, uplinkName: "TenGigabitEthernet1/1/%d", uplinkMbps: 10000, uplinkLoad: 0.2,
+	},
+	{
+		name:     []byte("access"),
+		hostname: "asw",
+		models:   [][]byte{[]byte("C9300-24T"), []byte("EX2300-24T")},
+
+		downlinks: 24, downlinkName: "GigabitEthernet1/0/%d", downlinkMbps: 1000, downlinkLoad: 0.05,
+		uplinks: 2, uplinkName: "TenGigabitEthernet1/1/%d", uplinkMbps: 10000, uplinkLoad: 0.1,
+	},
+}
+
+// maxInterfaces is the most interfaces of a device, those of access switches
+const maxInterfaces = 26
+
+// RoleOf returns the role of device i in the network of its site, and the
+// index of its site
+func RoleOf(i int) (role []byte, site int) {
+	return roleOf(i).name, i / devicesPerSite
+}
+
+func roleOf(i int) *role {
+	switch j := i % devicesPerSite; {
+	case j == 0:
+		return &roles[0]
+	case j <= distributionSwitches:
+		return &roles[1]
+	default:
+		return &roles[2]
+	}
+}
+
+// Traffic relative to the peak through the day, in local time: lowest at
+// 02:00 and highest at 14:00, with weekends at weekendTraffic of weekdays
+const (
+	nightTraffic   = 0.15
+	weekendTraffic = 0.4
+	trafficLowHour = 2
+)
+
+// meanTraffic is the mean of Traffic over a week
+const meanTraffic = (nightTraffic + (1-nightTraffic)/2) * (5 + 2*weekendTraffic) / 7
+
+// Traffic returns the traffic of a network at local time t relative to the
+// peak of weekdays, between nightTraffic*weekendTraffic and 1
+func Traffic(t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	day := (1 - math.Cos(2*math.Pi*(hour-trafficLowHour)/24)) / 2
+	traffic := nightTraffic + (1-nightTraffic)*day
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		traffic *= weekendTraffic
+	}
+	return traffic
+}
+
+// SimulatorConfig is used to create a Simulator of the network use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitDeviceCount is the number of devices to start with in the first
+	// reporting period
+	InitDeviceCount uint64
+	// DeviceCount is the total number of devices to have in the last
+	// reporting period
+	DeviceCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each device. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the device and
+// interface measurements
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that polls each device, and each of its
+// interfaces, every interval, unless it is rebooting. It advances RNG, so
+// each call simulates different devices.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitDeviceCount,
+		Count:            c.DeviceCount,
+		PointsPerReading: 1 + maxInterfaces,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewDevice(r, i, start)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
@@ -0,0 +1,104 @@
+package network
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestRoleOf(t *testing.T) {
+	cases := []struct {
+		device int
+		role   string
+		site   int
+	}{
+		{0, "core", 0},
+		{1, "distribution", 0},
+		{2, "distribution", 0},
+		{3, "access", 0},
+		{7, "access", 0},
+		{8, "core", 1},
+		{10, "distribution", 1},
+	}
+	for _, c := range cases {
+		role, site := RoleOf(c.device)
+		if string(role) != c.role || site != c.site {
+			t.Errorf("%d: incorrect role and site: got %s, %d want %s, %d", c.device, role, site, c.role, c.site)
+		}
+	}
+	for i := range roles {
+		if n := roles[i].downlinks + roles[i].uplinks; n > maxInterfaces {
+			t.Errorf("%s: more interfaces than maxInterfaces: %d", roles[i].name, n)
+		}
+	}
+}
+
+func TestTraffic(t *testing.T) {
+	// 2016-01-04 is a Monday
+	night := Traffic(time.Date(2016, 1, 4, 2, 0, 0, 0, time.UTC))
+	peak := Traffic(time.Date(2016, 1, 4, 14, 0, 0, 0, time.UTC))
+	weekend := Traffic(time.Date(2016, 1, 9, 14, 0, 0, 0, time.UTC))
+	if night != nightTraffic || peak != 1 {
+		t.Errorf("incorrect traffic: got %v at night, %v at the peak", night, peak)
+	}
+	if weekend != weekendTraffic {
+		t.Errorf("incorrect traffic at the weekend: got %v", weekend)
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:           start,
+		End:             start.Add(time.Hour),
+		InitDeviceCount: 8,
+		DeviceCount:     8,
+		RNG:             rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(10 * time.Second)
+	counts := make(map[string]int)
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		m := string(p.MeasurementName())
+		counts[m]++
+		if ts := time.Unix(0, p.Timestamp()); ts.Before(start) || !ts.Before(c.End) {
+			t.Fatalf("point outside the simulation: %v", ts.UTC())
+		}
+		if len(p.FieldKeys()) != len(c.Fields().FieldKeys(m)) {
+			t.Errorf("incorrect fields of %s: got %s", m, p.FieldKeys())
+		}
+		for j, v := range p.FieldValues() {
+			if got, want := serialize.FieldTypeOf(v), c.Fields().FieldTypes(m)[j]; got != want {
+				t.Errorf("incorrect type of %s %s: got %v want %v", m, p.FieldKeys()[j], got, want)
+			}
+		}
+		wantTags := len(TagKeys)
+		if m == "interface" {
+			wantTags++
+		}
+		if len(p.TagKeys()) != wantTags {
+			t.Errorf("incorrect tags of %s: got %s", m, p.TagKeys())
+		}
+	}
+	// a site: a core router, two distribution switches and five access
+	// switches, each polled every 10 seconds unless rebooting
+	polls := 6 * 60
+	if got := counts["device"]; got < 8*polls*9/10 || got > 8*polls {
+		t.Errorf("incorrect number of device points: got %d", got)
+	}
+	interfaces := 6 + 2*10 + 5*26
+	if got := counts["interface"]; got < interfaces*polls*9/10 || got > interfaces*polls {
+		t.Errorf("incorrect number of interface points: got %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+package data
+
+import (
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// schemaConfig is implemented by SimulatorConfigs that can describe the
+// points of their simulators without making them
+type schemaConfig interface {
+	Fields() *serialize.Schema
+}
+
+// Schema returns the Schema of the points generated for config, i.e., their
+// measurements, tag keys, and field keys and types, without generating any,
+// e.g., so a loader can create its tables up front. Only the UseCase,
+// TimestampStart and HostConstructor of config are used; the schema does not
+// depend on the others.
+func Schema(config GeneratorConfig) (*serialize.Schema, error) {
+	// the schema is the same at any scale, so use the smallest one
+	config.Scale, config.InitialScale = 1, 1
+	if config.TimestampEnd.Before(config.TimestampStart) {
+		config.TimestampEnd = config.TimestampStart
+	}
+	simConfig, err := config.simulatorConfig()
+	if err != nil {
+		return nil, err
+	}
+	if sc, ok := simConfig.(schemaConfig); ok {
+		return sc.Fields(), nil
+	}
+	return simConfig.ToSimulator(time.Second).Fields(), nil
+}
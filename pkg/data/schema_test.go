@@ -0,0 +1,50 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+func TestSchema(t *testing.T) {
+	for _, useCase := range UseCases() {
+		cfg := testGeneratorConfig()
+		cfg.UseCase = useCase
+		got, err := Schema(cfg)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", useCase, err)
+		}
+
+		simConfig, err := cfg.simulatorConfig()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", useCase, err)
+		}
+		want := simConfig.ToSimulator(cfg.LogInterval).Fields()
+		if got.Hash() != want.Hash() {
+			t.Errorf("%s: schema differs from simulator's: got %v want %v", useCase, got.Measurements(), want.Measurements())
+		}
+		for _, m := range got.Measurements() {
+			if len(got.FieldTypes(m)) != len(got.FieldKeys(m)) {
+				t.Errorf("%s: incorrect number of field types for %s: got %d want %d", useCase, m, len(got.FieldTypes(m)), len(got.FieldKeys(m)))
+			}
+		}
+	}
+
+	cfg := testGeneratorConfig()
+	s, err := Schema(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.FieldTypes("cpu")[0]; got != serialize.FieldTypeInt {
+		t.Errorf("incorrect type of cpu field: got %s want %s", got, serialize.FieldTypeInt)
+	}
+	// mem's used_percent
+	if got := s.FieldTypes("mem")[6]; got != serialize.FieldTypeFloat {
+		t.Errorf("incorrect type of mem field: got %s want %s", got, serialize.FieldTypeFloat)
+	}
+
+	cfg.UseCase = "bogus"
+	if _, err := Schema(cfg); err == nil {
+		t.Errorf("did not error for unknown use case")
+	}
+}
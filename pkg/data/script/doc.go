@@ -0,0 +1,29 @@
+// Package script computes field values with user-provided Starlark scripts,
+// so that exotic data shapes can be generated without recompiling TSBS.
+//
+// A script defines a global dict named fields, mapping
+// "<measurement>.<field>" to a function called for every point of that
+// measurement to compute the value of the field:
+//
+//	def usage_user(tags, prev, value, t):
+//	    # tags:  dict of the point's tag keys to values
+//	    # prev:  the value returned for the same series last time, or None
+//	    # value: the value generated by the builtin distribution
+//	    # t:     the simulated time, in nanoseconds since the Unix epoch
+//	    if tags["region"] == "us-west-1":
+//	        return value / 2
+//	    return value
+//
+//	fields = {
+//	    "cpu.usage_user": usage_user,
+//	}
+//
+// A function may return None to keep the builtin value. Returned values are
+// converted to the type of the builtin value, so the type of a field never
+// changes. The math module is predeclared.
+//
+// Scripts are much slower than the builtin distributions, which remain the
+// fast path for fields not listed in fields. Starlark support requires an
+// extra dependency, so this package is only built with the starlark build
+// tag, e.g., go build -tags starlark.
+package script
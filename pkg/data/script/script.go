@@ -0,0 +1,224 @@
+//go:build starlark
+// +build starlark
+
+package script
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+)
+
+// fieldsGlobal is the name of the global dict of field functions
+const fieldsGlobal = "fields"
+
+// fieldFunc is the script function computing one field, along with the last
+// value it computed for each series
+type fieldFunc struct {
+	name string
+	fn   starlark.Callable
+	prev map[string]starlark.Value
+}
+
+// Transformer replaces the values of the fields listed by a script with the
+// values computed by its functions. It implements data.PointTransformer.
+type Transformer struct {
+	thread *starlark.Thread
+	// funcs maps measurement names to field keys to functions
+	funcs map[string]map[string]*fieldFunc
+	// seriesBuf is reused to build the key identifying a point's series
+	seriesBuf []byte
+}
+
+// Load reads and executes the script in filename, returning a Transformer
+// for the functions it defines
+func Load(filename string) (*Transformer, error) {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return New(filename, src)
+}
+
+// New executes the script src, using filename in error messages, and returns
+// a Transformer for the functions it defines
+func New(filename string, src []byte) (*Transformer, error) {
+	thread := &starlark.Thread{Name: filename}
+	predeclared := starlark.StringDict{"math": starlarkmath.Module}
+	globals, err := starlark.ExecFile(thread, filename, src, predeclared)
+	if err != nil {
+		return nil, err
+	}
+	globals.Freeze()
+
+	v, ok := globals[fieldsGlobal]
+	if !ok {
+		return nil, fmt.Errorf("script %s does not define %s", filename, fieldsGlobal)
+	}
+	fields, ok := v.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("script %s: %s must be a dict, not %s", filename, fieldsGlobal, v.Type())
+	}
+
+	t := &Transformer{
+		thread: thread,
+		funcs:  make(map[string]map[string]*fieldFunc),
+	}
+	for _, item := range fields.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("script %s: %s keys must be strings, not %s", filename, fieldsGlobal, item[0].Type())
+		}
+		i := strings.IndexByte(key, '.')
+		if i <= 0 || i == len(key)-1 {
+			return nil, fmt.Errorf("script %s: %s key %q is not of the form <measurement>.<field>", filename, fieldsGlobal, key)
+		}
+		fn, ok := item[1].(starlark.Callable)
+		if !ok {
+			return nil, fmt.Errorf("script %s: %s[%q] must be a function, not %s", filename, fieldsGlobal, key, item[1].Type())
+		}
+
+		measurement, field := key[:i], key[i+1:]
+		if t.funcs[measurement] == nil {
+			t.funcs[measurement] = make(map[string]*fieldFunc)
+		}
+		t.funcs[measurement][field] = &fieldFunc{
+			name: key,
+			fn:   fn,
+			prev: make(map[string]starlark.Value),
+		}
+	}
+	return t, nil
+}
+
+// Transform replaces the values of the fields of p that the script has
+// functions for
+func (t *Transformer) Transform(p *serialize.Point) error {
+	funcs, ok := t.funcs[string(p.MeasurementName())]
+	if !ok {
+		return nil
+	}
+
+	var tags *starlark.Dict
+	var series string
+	for i, key := range p.FieldKeys() {
+		ff, ok := funcs[string(key)]
+		if !ok {
+			continue
+		}
+		// only points with scripted fields pay for converting their tags
+		if tags == nil {
+			tags, series = t.pointTags(p)
+		}
+
+		value := p.FieldValues()[i]
+		sv, err := toStarlark(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", ff.name, err)
+		}
+		prev, ok := ff.prev[series]
+		if !ok {
+			prev = starlark.None
+		}
+		args := starlark.Tuple{tags, prev, sv, starlark.MakeInt64(p.Timestamp())}
+		res, err := starlark.Call(t.thread, ff.fn, args, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %v", ff.name, err)
+		}
+		if res == starlark.None {
+			res = sv
+		}
+		newValue, err := fromStarlark(res, value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", ff.name, err)
+		}
+		ff.prev[series] = res
+		p.SetFieldValue(i, newValue)
+	}
+	return nil
+}
+
+// pointTags returns the tags of p as a frozen Starlark dict, along with a key
+// identifying the series of p
+func (t *Transformer) pointTags(p *serialize.Point) (*starlark.Dict, string) {
+	keys, values := p.TagKeys(), p.TagValues()
+	tags := starlark.NewDict(len(keys))
+	t.seriesBuf = append(t.seriesBuf[:0], p.MeasurementName()...)
+	for i, k := range keys {
+		tags.SetKey(starlark.String(k), starlark.String(values[i]))
+		t.seriesBuf = append(t.seriesBuf, 0)
+		t.seriesBuf = append(t.seriesBuf, values[i]...)
+	}
+	tags.Freeze()
+	return tags, string(t.seriesBuf)
+}
+
+// toStarlark converts a field value to a Starlark value
+func toStarlark(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case float64:
+		return starlark.Float(v), nil
+	case float32:
+		return starlark.Float(v), nil
+	case int:
+		return starlark.MakeInt(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case bool:
+		return starlark.Bool(v), nil
+	case string:
+		return starlark.String(v), nil
+	case []byte:
+		return starlark.String(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported field value type %T", v)
+	}
+}
+
+// fromStarlark converts a Starlark value returned by a script to the type of
+// the builtin field value orig
+func fromStarlark(v starlark.Value, orig interface{}) (interface{}, error) {
+	switch orig.(type) {
+	case float64, float32:
+		f, ok := starlark.AsFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("returned %s for a float field", v.Type())
+		}
+		if _, ok := orig.(float32); ok {
+			return float32(f), nil
+		}
+		return f, nil
+	case int, int64:
+		var i int64
+		switch v := v.(type) {
+		case starlark.Int:
+			var ok bool
+			if i, ok = v.Int64(); !ok {
+				return nil, fmt.Errorf("returned %s, which overflows an int field", v)
+			}
+		case starlark.Float:
+			i = int64(v)
+		default:
+			return nil, fmt.Errorf("returned %s for an int field", v.Type())
+		}
+		if _, ok := orig.(int); ok {
+			return int(i), nil
+		}
+		return i, nil
+	case bool:
+		return bool(v.Truth()), nil
+	default:
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return nil, fmt.Errorf("returned %s for a string field", v.Type())
+		}
+		if _, ok := orig.([]byte); ok {
+			return []byte(s), nil
+		}
+		return s, nil
+	}
+}
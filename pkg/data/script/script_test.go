@@ -0,0 +1,129 @@
+//go:build starlark
+// +build starlark
+
+package script
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+func testPoint(region string, usage float64, ts int64) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("cpu"))
+	p.AppendTag([]byte("hostname"), []byte("host_0"))
+	p.AppendTag([]byte("region"), []byte(region))
+	p.AppendField([]byte("usage_user"), usage)
+	p.AppendField([]byte("usage_system"), usage)
+	p.AppendField([]byte("count"), int64(1))
+	p.SetTimestamp(ts)
+	return p
+}
+
+func TestNewErrors(t *testing.T) {
+	cases := []struct {
+		desc      string
+		src       string
+		errSubstr string
+	}{
+		{
+			desc:      "syntax error",
+			src:       "fields = {",
+			errSubstr: "got end of file",
+		},
+		{
+			desc:      "no fields",
+			src:       "x = 1",
+			errSubstr: "does not define fields",
+		},
+		{
+			desc:      "fields not a dict",
+			src:       "fields = 1",
+			errSubstr: "must be a dict",
+		},
+		{
+			desc:      "bad key",
+			src:       "def f(tags, prev, value, t):\n    return value\nfields = {\"usage_user\": f}",
+			errSubstr: "not of the form",
+		},
+		{
+			desc:      "not a function",
+			src:       "fields = {\"cpu.usage_user\": 1}",
+			errSubstr: "must be a function",
+		},
+	}
+	for _, c := range cases {
+		_, err := New("test.star", []byte(c.src))
+		if err == nil || !strings.Contains(err.Error(), c.errSubstr) {
+			t.Errorf("%s: incorrect error: got %v want %q", c.desc, err, c.errSubstr)
+		}
+	}
+}
+
+func TestTransform(t *testing.T) {
+	src := `
+def usage_user(tags, prev, value, t):
+    if prev == None:
+        prev = 0.0
+    if tags["region"] == "eu-west-1":
+        return prev + 1
+    return None
+
+def count(tags, prev, value, t):
+    return t // 1000000000
+
+fields = {
+    "cpu.usage_user": usage_user,
+    "cpu.count": count,
+    "mem.used": usage_user,
+}
+`
+	tr, err := New("test.star", []byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		p := testPoint("eu-west-1", 50.0, int64(i)*1000000000)
+		if err := tr.Transform(p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := p.GetFieldValue([]byte("usage_user")); got != float64(i) {
+			t.Errorf("incorrect usage_user at %d: got %v want %v", i, got, float64(i))
+		}
+		if got := p.GetFieldValue([]byte("usage_system")); got != 50.0 {
+			t.Errorf("unscripted field changed at %d: got %v", i, got)
+		}
+		if got := p.GetFieldValue([]byte("count")); got != int64(i) {
+			t.Errorf("incorrect count at %d: got %v (%T) want %d", i, got, got, i)
+		}
+	}
+
+	// returning None keeps the builtin value
+	p := testPoint("us-west-1", 50.0, 0)
+	if err := tr.Transform(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := p.GetFieldValue([]byte("usage_user")); got != 50.0 {
+		t.Errorf("incorrect usage_user for None: got %v want %v", got, 50.0)
+	}
+}
+
+func TestTransformError(t *testing.T) {
+	src := `
+def bad(tags, prev, value, t):
+    return "not a number"
+
+fields = {"cpu.usage_user": bad}
+`
+	tr, err := New("test.star", []byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = tr.Transform(testPoint("eu-west-1", 1.0, 0))
+	if err == nil || !strings.HasPrefix(err.Error(), "cpu.usage_user: returned string") {
+		t.Errorf("incorrect error: got %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+// Package adx implements the formats for Azure Data Explorer (Kusto): rows of
+// CSV or JSON, after a header of the control commands creating a table and
+// ingestion mapping for each measurement.
+package adx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format and FormatJSON are the names the formats are registered under
+const (
+	Format     = "adx"
+	FormatJSON = "adx-json"
+)
+
+// MappingName is the name of the ingestion mapping the header creates for
+// each table
+const MappingName = "tsbs"
+
+// TimeColumn is the name of the column holding the timestamp of each row
+const TimeColumn = "timestamp"
+
+func init() {
+	serialize.Describe(Format, "Azure Data Explorer CSV rows, after a header of the tables and CSV mappings to create")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, false, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{TagKeys: tagKeys(schema)}, nil
+	})
+	serialize.Describe(FormatJSON, "Azure Data Explorer JSON rows, after a header of the tables and JSON mappings to create")
+	serialize.Register(FormatJSON, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, true, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{JSON: true, TagKeys: tagKeys(schema)}, nil
+	})
+	// the Header is checked by tsbs_load_adx
+	serialize.MarkHeaderFormat(Format)
+	serialize.MarkHeaderFormat(FormatJSON)
+}
+
+// tagKeys returns the tag keys of each measurement of schema
+func tagKeys(schema *serialize.Schema) map[string][][]byte {
+	keys := make(map[string][][]byte)
+	for _, measurementName := range schema.Measurements() {
+		keys[measurementName] = schema.TagKeysOf(measurementName)
+	}
+	return keys
+}
+
+// writeHeader writes the control commands the loader runs to create its
+// tables, two for each measurement, and then an empty line:
+//
+// .create-merge table ['cpu'] (['timestamp']:datetime, ['hostname']:string, ..., ['usage_user']:real)
+// .create-or-alter table ['cpu'] ingestion csv mapping 'tsbs' '[{"column":"timestamp","Properties":{"Ordinal":"0"}},...]'
+//
+// The columns are the timestamp, the tags and then the fields of the
+// measurement, in the order the rows have them.
+func writeHeader(schema *serialize.Schema, json bool, w io.Writer) error {
+	var buf []byte
+	for _, measurementName := range schema.Measurements() {
+		columns := []string{TimeColumn}
+		types := []string{"datetime"}
+		for _, key := range schema.TagKeysOf(measurementName) {
+			columns = append(columns, string(key))
+			types = append(types, "string")
+		}
+		fieldTypes := schema.FieldTypes(measurementName)
+		for i, key := range schema.FieldKeys(measurementName) {
+			columns = append(columns, string(key))
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			types = append(types, columnType(t))
+		}
+
+		buf = append(buf, ".create-merge table "...)
+		buf = appendName(buf, measurementName)
+		buf = append(buf, " ("...)
+		for i, c := range columns {
+			if i > 0 {
+				buf = append(buf, ", "...)
+			}
+			buf = appendName(buf, c)
+			buf = append(buf, ':')
+			buf = append(buf, types[i]...)
+		}
+		buf = append(buf, ")\n"...)
+
+		buf = append(buf, ".create-or-alter table "...)
+		buf = appendName(buf, measurementName)
+		mapping := []byte{'['}
+		for i, c := range columns {
+			if i > 0 {
+				mapping = append(mapping, ',')
+			}
+			mapping = append(mapping, `{"column":`...)
+			mapping = serialize.AppendJSONString(mapping, []byte(c))
+			if json {
+				mapping = append(mapping, `,"Properties":{"Path":`...)
+				mapping = serialize.AppendJSONString(mapping, []byte("$["+string(appendString(nil, c))+"]"))
+			} else {
+				mapping = append(mapping, `,"Properties":{"Ordinal":"`...)
+				mapping = strconv.AppendInt(mapping, int64(i), 10)
+				mapping = append(mapping, '"')
+			}
+			mapping = append(mapping, "}}"...)
+		}
+		mapping = append(mapping, ']')
+		if json {
+			buf = append(buf, " ingestion json mapping "...)
+		} else {
+			buf = append(buf, " ingestion csv mapping "...)
+		}
+		buf = appendString(buf, MappingName)
+		buf = append(buf, ' ')
+		buf = appendString(buf, string(mapping))
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+// columnType returns the Kusto type of the column for fields of type t.
+// Fields of unknown type are numbers from the simulators, which fit a real.
+func columnType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "long"
+	case serialize.FieldTypeBool:
+		return "bool"
+	case serialize.FieldTypeString:
+		return "string"
+	default:
+		return "real"
+	}
+}
+
+// appendName appends name to buf as a Kusto identifier, e.g., ['cpu']
+func appendName(buf []byte, name string) []byte {
+	buf = append(buf, '[')
+	buf = appendString(buf, name)
+	return append(buf, ']')
+}
+
+// appendString appends s to buf as a single-quoted Kusto string literal
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\'', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '\'')
+}
+
+// Serializer writes a Point in a serialized form for Azure Data Explorer
+type Serializer struct {
+	// JSON is whether rows are written as JSON objects rather than CSV
+	JSON bool
+	// TagKeys are the tag keys of the tag columns of the table of each
+	// measurement, in order; if nil, each row has the tags of its point
+	TagKeys map[string][][]byte
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a row of the table of its measurement,
+// prefixed by the table name and a comma so the loader can tell the tables
+// apart. In CSV the columns are the timestamp, the value of each tag column
+// of the table, empty if p does not have it, and the field values in the
+// order of the Point, which are the order of the header for points from the
+// simulators:
+//
+// cpu,2016-01-01T00:00:00Z,host_0,eu-west-1,...,58.1317132304976170,...
+//
+// In JSON they are named, so rows missing tags have null columns:
+//
+// cpu,{"timestamp":"2016-01-01T00:00:00Z","hostname":"host_0",...,"usage_user":58.1317132304976170,...}
+//
+// Values with commas, quotes or line breaks are quoted in CSV, so a row may
+// span lines. Infinite and NaN values are written as Infinity, -Infinity and
+// NaN, which Kusto parses as reals. A tag the table has no column of is an
+// error.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf, err := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	var err error
+	for i := 0; i < b.Len() && err == nil; i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf, err = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) ([]byte, error) {
+	columns := s.TagKeys[string(measurementName)]
+	if s.TagKeys != nil {
+		for _, key := range tagKeys {
+			if indexOf(columns, key) < 0 {
+				return buf, fmt.Errorf("tag %s of %s has no column", key, measurementName)
+			}
+		}
+	}
+	buf = append(buf, measurementName...)
+	buf = append(buf, ',')
+	if s.JSON {
+		buf = append(buf, `{"`+TimeColumn+`":"`...)
+		buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, time.RFC3339Nano)
+		buf = append(buf, '"')
+		for i, v := range tagValues {
+			buf = append(buf, ',')
+			buf = serialize.AppendJSONString(buf, tagKeys[i])
+			buf = append(buf, ':')
+			buf = serialize.AppendJSONString(buf, v)
+		}
+		for i, v := range fieldValues {
+			buf = append(buf, ',')
+			buf = serialize.AppendJSONString(buf, fieldKeys[i])
+			buf = append(buf, ':')
+			buf = serialize.AppendJSONValue(buf, v)
+		}
+		return append(buf, "}\n"...), nil
+	}
+
+	buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, time.RFC3339Nano)
+	if s.TagKeys == nil {
+		for _, v := range tagValues {
+			buf = append(buf, ',')
+			buf = appendCSV(buf, v)
+		}
+	} else {
+		for _, key := range columns {
+			buf = append(buf, ',')
+			if i := indexOf(tagKeys, key); i >= 0 {
+				buf = appendCSV(buf, tagValues[i])
+			}
+		}
+	}
+	for _, v := range fieldValues {
+		buf = append(buf, ',')
+		switch x := v.(type) {
+		case []byte:
+			buf = appendCSV(buf, x)
+		case string:
+			buf = appendCSV(buf, []byte(x))
+		default:
+			buf = appendNumber(buf, v)
+		}
+	}
+	return append(buf, '\n'), nil
+}
+
+// indexOf returns the index of key among keys, or -1
+func indexOf(keys [][]byte, key []byte) int {
+	for i, k := range keys {
+		if bytes.Equal(k, key) {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendNumber appends a numeric or bool field value, spelling out the
+// floats that strconv would write as +Inf, -Inf and NaN
+func appendNumber(buf []byte, v interface{}) []byte {
+	var f float64
+	switch x := v.(type) {
+	case float64:
+		f = x
+	case float32:
+		f = float64(x)
+	default:
+		return serialize.FastFormatAppend(v, buf)
+	}
+	switch {
+	case math.IsNaN(f):
+		return append(buf, "NaN"...)
+	case math.IsInf(f, 1):
+		return append(buf, "Infinity"...)
+	case math.IsInf(f, -1):
+		return append(buf, "-Infinity"...)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// appendCSV appends s to buf as a CSV value, quoting it if needed
+func appendCSV(buf []byte, s []byte) []byte {
+	quote := false
+	for _, c := range s {
+		if c == ',' || c == '"' || c == '\n' || c == '\r' {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		return append(buf, s...)
+	}
+	buf = append(buf, '"')
+	for _, c := range s {
+		if c == '"' {
+			buf = append(buf, '"')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '"')
+}
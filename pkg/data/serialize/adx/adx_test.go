@@ -0,0 +1,211 @@
+package adx
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "cpu,2016-01-01T00:00:00Z,host_0,eu-west-1,eu-west-1b,38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "cpu,2016-01-01T00:00:00Z,host_0,eu-west-1,eu-west-1b,38\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "cpu,2016-01-01T00:00:00Z,host_0,eu-west-1,eu-west-1b,5000000000,38,38.24311829\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "cpu,2016-01-01T00:00:00Z,,,,38.24311829\n",
+	},
+}
+
+const testTagsJSON = `"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"`
+
+var serializeJSONCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `cpu,{"timestamp":"2016-01-01T00:00:00Z",` + testTagsJSON + `,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `cpu,{"timestamp":"2016-01-01T00:00:00Z",` + testTagsJSON + `,"usage_guest":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     `cpu,{"timestamp":"2016-01-01T00:00:00Z",` + testTagsJSON + `,"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `cpu,{"timestamp":"2016-01-01T00:00:00Z","usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	tagKeys := map[string][][]byte{"cpu": serializetest.TagKeys}
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{TagKeys: tagKeys})
+	serializetest.CheckSerializer(t, serializeJSONCases, &Serializer{JSON: true, TagKeys: tagKeys})
+}
+
+func TestSerializerTagColumns(t *testing.T) {
+	s := &Serializer{TagKeys: map[string][][]byte{
+		"cpu":  {[]byte("hostname")},
+		"disk": {[]byte("hostname"), []byte("path")},
+	}}
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.AppendTag([]byte("path"), []byte("/"))
+	p.AppendField([]byte("free"), int64(1))
+	b := new(bytes.Buffer)
+	if err := s.Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := b.String(), "disk,1970-01-01T00:00:00Z,,/,1\n"; got != want {
+		t.Errorf("incorrect row: got %q want %q", got, want)
+	}
+
+	for _, asJSON := range []bool{false, true} {
+		b.Reset()
+		s.JSON = asJSON
+		p.SetMeasurementName([]byte("cpu"))
+		if err := s.Serialize(p, b); err == nil || err.Error() != "tag path of cpu has no column" {
+			t.Errorf("json %t: incorrect error for a tag of another table: %v", asJSON, err)
+		}
+		if b.Len() != 0 {
+			t.Errorf("json %t: rejected row written: %q", asJSON, b.String())
+		}
+	}
+}
+
+func TestSerializerConformance(t *testing.T) {
+	for format, cases := range map[string][]serializetest.Case{Format: serializeCases, FormatJSON: serializeJSONCases} {
+		format := format
+		t.Run(format, func(t *testing.T) {
+			serializetest.Suite{
+				New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+					return serialize.New(format, schema, w)
+				},
+				Golden: cases,
+			}.Run(t)
+		})
+	}
+}
+
+func TestRegisteredWithHeader(t *testing.T) {
+	schema := serialize.NewTaggedSchema(serializetest.TagKeys[:1], map[string][][]byte{
+		"mem": {[]byte("pool")},
+	}, map[string][][]byte{
+		"mem": {[]byte("used"), []byte("free")},
+		"cpu": {serializetest.ColFloat},
+	}, map[string][]serialize.FieldType{
+		"mem": {serialize.FieldTypeInt, serialize.FieldTypeInt},
+	})
+	cases := []struct {
+		desc   string
+		format string
+		want   string
+	}{
+		{
+			desc:   "csv",
+			format: Format,
+			want: `.create-merge table ['cpu'] (['timestamp']:datetime, ['hostname']:string, ['usage_guest_nice']:real)
+.create-or-alter table ['cpu'] ingestion csv mapping 'tsbs' '[{"column":"timestamp","Properties":{"Ordinal":"0"}},{"column":"hostname","Properties":{"Ordinal":"1"}},{"column":"usage_guest_nice","Properties":{"Ordinal":"2"}}]'
+.create-merge table ['mem'] (['timestamp']:datetime, ['hostname']:string, ['pool']:string, ['used']:long, ['free']:long)
+.create-or-alter table ['mem'] ingestion csv mapping 'tsbs' '[{"column":"timestamp","Properties":{"Ordinal":"0"}},{"column":"hostname","Properties":{"Ordinal":"1"}},{"column":"pool","Properties":{"Ordinal":"2"}},{"column":"used","Properties":{"Ordinal":"3"}},{"column":"free","Properties":{"Ordinal":"4"}}]'
+
+`,
+		},
+		{
+			desc:   "json",
+			format: FormatJSON,
+			want: `.create-merge table ['cpu'] (['timestamp']:datetime, ['hostname']:string, ['usage_guest_nice']:real)
+.create-or-alter table ['cpu'] ingestion json mapping 'tsbs' '[{"column":"timestamp","Properties":{"Path":"$[\'timestamp\']"}},{"column":"hostname","Properties":{"Path":"$[\'hostname\']"}},{"column":"usage_guest_nice","Properties":{"Path":"$[\'usage_guest_nice\']"}}]'
+.create-merge table ['mem'] (['timestamp']:datetime, ['hostname']:string, ['pool']:string, ['used']:long, ['free']:long)
+.create-or-alter table ['mem'] ingestion json mapping 'tsbs' '[{"column":"timestamp","Properties":{"Path":"$[\'timestamp\']"}},{"column":"hostname","Properties":{"Path":"$[\'hostname\']"}},{"column":"pool","Properties":{"Path":"$[\'pool\']"}},{"column":"used","Properties":{"Path":"$[\'used\']"}},{"column":"free","Properties":{"Path":"$[\'free\']"}}]'
+
+`,
+		},
+	}
+	for _, c := range cases {
+		b := new(bytes.Buffer)
+		ps, err := serialize.New(c.format, schema, b)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		if s, ok := ps.(*Serializer); !ok || s.JSON != (c.format == FormatJSON) || len(s.TagKeys["mem"]) != 2 {
+			t.Errorf("%s: incorrect serializer: got %#v", c.desc, ps)
+		}
+		if got := b.String(); got != c.want {
+			t.Errorf("%s: incorrect header: got\n%s\nwant\n%s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestAppendString(t *testing.T) {
+	got := string(appendString(nil, "it's a\\b\n"))
+	if want := `'it\'s a\\b\n'`; got != want {
+		t.Errorf("incorrect string: got %s want %s", got, want)
+	}
+}
+
+// TestSerializerRowsDecode checks that the rows of special values decode to
+// the values of the Point, as CSV and JSON
+func TestSerializerRowsDecode(t *testing.T) {
+	for i, p := range serializetest.FuzzPoints() {
+		var b bytes.Buffer
+		if err := (&Serializer{}).Serialize(p, &b); err != nil {
+			t.Fatalf("point %d: unexpected error: %v", i, err)
+		}
+		r := csv.NewReader(strings.NewReader(b.String()))
+		r.FieldsPerRecord = -1
+		records, err := r.ReadAll()
+		if err != nil || len(records) != 1 {
+			t.Errorf("point %d: invalid CSV %q: %v", i, b.String(), err)
+			continue
+		}
+		// the table name and timestamp come first
+		if got, want := len(records[0]), 2+len(p.TagValues())+len(p.FieldValues()); got != want {
+			t.Errorf("point %d: incorrect number of columns in %q: got %d want %d", i, b.String(), got, want)
+			continue
+		}
+		for j, v := range p.TagValues() {
+			if got := records[0][2+j]; got != string(v) {
+				t.Errorf("point %d: incorrect tag value: got %q want %q", i, got, v)
+			}
+		}
+
+		b.Reset()
+		if err := (&Serializer{JSON: true}).Serialize(p, &b); err != nil {
+			t.Fatalf("point %d: unexpected error: %v", i, err)
+		}
+		row := map[string]interface{}{}
+		if err := json.Unmarshal(bytes.TrimPrefix(b.Bytes(), []byte(string(p.MeasurementName())+",")), &row); err != nil {
+			t.Errorf("point %d: invalid JSON %q: %v", i, b.String(), err)
+			continue
+		}
+		for j, v := range p.TagValues() {
+			if got := row[string(p.TagKeys()[j])]; got != string(v) {
+				t.Errorf("point %d: incorrect tag value: got %q want %q", i, got, v)
+			}
+		}
+	}
+}
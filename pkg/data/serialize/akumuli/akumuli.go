@@ -0,0 +1,182 @@
+// Package akumuli implements the format for Akumuli: the RESP-based protocol
+// of its TCP ingestion, with a dictionary of series names so each is only
+// sent once.
+package akumuli
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as akumuli parses them
+var floatFormat = &serialize.FloatFormat{Fmt: 'g', NaN: "nan", Inf: "inf", NegInf: "-inf"}
+
+// Format is the name the format is registered under
+const Format = "akumuli"
+
+func init() {
+	serialize.Describe(Format, "Akumuli RESP protocol, with compound series and a dictionary of series names")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+}
+
+// Serializer writes a Point in a serialized form for Akumuli
+type Serializer struct {
+	// ids are the ids of the series names sent in the dictionary so far
+	ids map[string]int
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and series that for the series name of
+	// the Point being written
+	buf    []byte
+	series []byte
+}
+
+// Serialize writes Point p to w in the RESP protocol of Akumuli, as a
+// compound series of all its fields: a metric for each of them, named
+// <measurement>.<field> and separated by '|', followed by its tags, e.g.:
+//
+// +cpu.usage_user|cpu.usage_system hostname=host_0 region=eu-west-1\r\n
+// :1451606400000000000\r\n
+// *2\r\n
+// +58.13\r\n
+// +2.6\r\n
+//
+// with the timestamp in nanoseconds and the values as an array, floats as
+// simple strings and ints as integers.
+//
+// Series names are sent as ids: the first time a series is written its name
+// is sent in the dictionary with a new id, as an array of the name and the
+// id, and it is written as the id from then on, e.g.:
+//
+// *2\r\n
+// +cpu.usage_user|cpu.usage_system hostname=host_0 region=eu-west-1\r\n
+// :1\r\n
+// :1\r\n
+// :1451606400000000000\r\n
+// ...
+//
+// Akumuli only stores numbers, so bools are written as 1 or 0 and strings
+// are left out. Spaces, '=' and '|', which separate the parts of series
+// names, and control characters are replaced by underscores in metric names
+// and tags. Tags with empty values are left out.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendPoint(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendPoint(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendPoint(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	series := s.series[:0]
+	n := 0
+	for i, v := range fieldValues {
+		if !isNumber(v) {
+			continue
+		}
+		if n > 0 {
+			series = append(series, '|')
+		}
+		n++
+		series = AppendName(series, measurementName)
+		series = append(series, '.')
+		series = AppendName(series, fieldKeys[i])
+	}
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		series = append(series, ' ')
+		series = AppendName(series, tagKeys[i])
+		series = append(series, '=')
+		series = AppendName(series, v)
+	}
+	s.series = series
+
+	id, ok := s.ids[string(series)]
+	if !ok {
+		if s.ids == nil {
+			s.ids = make(map[string]int)
+		}
+		id = len(s.ids) + 1
+		s.ids[string(series)] = id
+		buf = append(buf, "*2\r\n+"...)
+		buf = append(buf, series...)
+		buf = append(buf, "\r\n:"...)
+		buf = strconv.AppendInt(buf, int64(id), 10)
+		buf = append(buf, "\r\n"...)
+	}
+	buf = append(buf, ':')
+	buf = strconv.AppendInt(buf, int64(id), 10)
+	buf = append(buf, "\r\n:"...)
+	buf = strconv.AppendInt(buf, timestamp, 10)
+	buf = append(buf, "\r\n*"...)
+	buf = strconv.AppendInt(buf, int64(n), 10)
+	buf = append(buf, "\r\n"...)
+	for _, v := range fieldValues {
+		if !isNumber(v) {
+			continue
+		}
+		buf = appendValue(buf, v)
+		buf = append(buf, "\r\n"...)
+	}
+	return buf
+}
+
+// isNumber returns whether v is written as the value of a metric: a number
+// or a bool
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case []byte, string, nil:
+		return false
+	}
+	return true
+}
+
+// appendValue appends a field value to buf as a RESP value: floats as simple
+// strings, with NaN and infinite values as nan, inf and -inf, and ints and
+// bools as integers
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case float64:
+		return serialize.AppendFloat(append(buf, '+'), x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(append(buf, '+'), float64(x), 32, floatFormat)
+	case bool:
+		if x {
+			return append(buf, ":1"...)
+		}
+		return append(buf, ":0"...)
+	}
+	return serialize.FastFormatAppend(v, append(buf, ':'))
+}
+
+// AppendName appends a metric name, tag key or tag value to buf: name with
+// spaces, '=' and '|', which separate the parts of series names, and control
+// characters replaced by underscores
+func AppendName(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		if c <= ' ' || c == 0x7f || c == '=' || c == '|' {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
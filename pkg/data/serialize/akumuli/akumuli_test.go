@@ -0,0 +1,92 @@
+package akumuli
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testTags = " hostname=host_0 region=eu-west-1 datacenter=eu-west-1b"
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "*2\r\n+cpu.usage_guest_nice" + testTags + "\r\n:1\r\n:1\r\n:1451606400000000000\r\n*1\r\n+38.24311829\r\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "*2\r\n+cpu.usage_guest" + testTags + "\r\n:1\r\n:1\r\n:1451606400000000000\r\n*1\r\n:38\r\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "*2\r\n+cpu.big_usage_guest|cpu.usage_guest|cpu.usage_guest_nice" + testTags + "\r\n:1\r\n:1\r\n:1451606400000000000\r\n*3\r\n:5000000000\r\n:38\r\n+38.24311829\r\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "*2\r\n+cpu.usage_guest_nice\r\n:1\r\n:1\r\n:1451606400000000000\r\n*1\r\n+38.24311829\r\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerDictionary(t *testing.T) {
+	s := &Serializer{}
+	var buf bytes.Buffer
+	for _, p := range []*serialize.Point{serializetest.PointDefault, serializetest.PointNoTags, serializetest.PointDefault} {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	b := serialize.NewPointBatch()
+	b.Append(serializetest.PointNoTags)
+	b.Append(serializetest.PointInt)
+	if err := s.SerializeBatch(b, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "*2\r\n+cpu.usage_guest_nice" + testTags + "\r\n:1\r\n:1\r\n:1451606400000000000\r\n*1\r\n+38.24311829\r\n" +
+		"*2\r\n+cpu.usage_guest_nice\r\n:2\r\n:2\r\n:1451606400000000000\r\n*1\r\n+38.24311829\r\n" +
+		":1\r\n:1451606400000000000\r\n*1\r\n+38.24311829\r\n" +
+		":2\r\n:1451606400000000000\r\n*1\r\n+38.24311829\r\n" +
+		"*2\r\n+cpu.usage_guest" + testTags + "\r\n:3\r\n:3\r\n:1451606400000000000\r\n*1\r\n:38\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSerializeValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk io"))
+	p.SetTimestamp(0)
+	p.AppendTag([]byte("path"), []byte("/dev/sda|a=b"))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendField([]byte("free"), math.NaN())
+	p.AppendField([]byte("state"), "full")
+	p.AppendField([]byte("used"), math.Inf(-1))
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("reads"), float32(1.5))
+
+	var buf bytes.Buffer
+	if err := (&Serializer{}).Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "*2\r\n+disk_io.free|disk_io.used|disk_io.ok|disk_io.reads path=/dev/sda_a_b\r\n:1\r\n" +
+		":1\r\n:0\r\n*4\r\n+nan\r\n+-inf\r\n:1\r\n+1.5\r\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
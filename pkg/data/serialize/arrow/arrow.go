@@ -0,0 +1,506 @@
+// Package arrow implements the format for Apache Arrow: an IPC stream of
+// record batches, a batch for the readings of each measurement in each chunk
+// of time, which Arrow readers, and Arrow Flight based systems, ingest
+// without copying or converting them.
+package arrow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes a record
+// batch for each measurement every DefaultChunk of time. Formats of the form
+// Format + ":" + duration, e.g., arrow:10m, write them every duration.
+const Format = "arrow"
+
+// DefaultChunk is the chunk of time of the record batches of Format
+const DefaultChunk = time.Hour
+
+// Columns of every record batch: the time of each reading, as a timestamp
+// in nanoseconds in UTC, and its measurement
+const (
+	TimeColumn        = "timestamp"
+	MeasurementColumn = "measurement"
+)
+
+func init() {
+	serialize.Describe(Format, "Apache Arrow IPC stream, a record batch per measurement every chunk of time, with arrow:<duration> setting the chunk (default "+DefaultChunk.String()+")")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultChunk, w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		chunk, err := time.ParseDuration(arg)
+		if err != nil || chunk <= 0 {
+			return nil, fmt.Errorf("invalid chunk of time '%s': must be a positive duration, e.g., 10m", arg)
+		}
+		return NewSerializer(schema, chunk, w)
+	})
+	serialize.MarkFileFormat(Format)
+}
+
+// continuation starts every message of the stream, and endOfStream ends it
+const (
+	continuation = 0xffffffff
+	endOfStream  = "\xff\xff\xff\xff\x00\x00\x00\x00"
+)
+
+// column is a column of the record batches
+type column struct {
+	name     string
+	typ      byte
+	nullable bool
+}
+
+// array holds the values of a column of a record batch being buffered
+type array struct {
+	// validity has a bit for each of the n values, set if it is not null,
+	// of which there are nulls
+	validity []byte
+	n        int
+	nulls    int
+	// values are the values, as little endian longs or doubles, bit-packed
+	// bools or the bytes of strings, whose offsets are in offsets
+	values  []byte
+	offsets []byte
+	// set is whether the value of the row being added is set
+	set bool
+}
+
+// batch is a record batch being buffered for a measurement, with arrays of
+// the columns of its fields, tags, time and measurement, and no arrays, of
+// nulls only, for the others
+type batch struct {
+	rows   int
+	arrays []*array
+}
+
+// Serializer writes Points as the record batches of an Arrow IPC stream.
+// The stream is only complete, with the rows buffered and its end, once the
+// Serializer is closed.
+type Serializer struct {
+	chunk   int64
+	columns []column
+	// tags are the columns of the tag keys, and fields those of the field
+	// keys of each measurement
+	tags   map[string]int
+	fields map[string]map[string]int
+
+	// batches are the batches of each measurement, and order them in the
+	// order they were created in, with the rows of chunk current buffered.
+	// w is the Writer rows were last added with, which Close writes to.
+	batches map[string]*batch
+	order   []*batch
+	current int64
+	w       io.Writer
+
+	// b, buf, body, nodes and buffers are scratch space reused between
+	// batches
+	b       *flatbuffers.Builder
+	buf     []byte
+	body    []byte
+	nodes   [][2]int64
+	buffers [][2]int64
+}
+
+// NewSerializer returns a Serializer for data described by schema, writing
+// a record batch for each measurement every chunk of time to w. It writes the
+// message of the schema of the stream to w.
+//
+// The schema has a column for the time of each reading, one for its
+// measurement, one for each tag key of schema and one for each field of
+// each measurement, named <measurement>_<field> and of the type of the
+// field: Int64, Bool, Utf8 or, for the numbers of the simulators, Float64.
+// As a stream has a single schema, every record batch has all the columns,
+// with those of the fields of other measurements null.
+func NewSerializer(schema *serialize.Schema, chunk time.Duration, w io.Writer) (*Serializer, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("arrow streams need the schema of the data")
+	}
+	s := &Serializer{
+		chunk:   int64(chunk),
+		tags:    make(map[string]int),
+		fields:  make(map[string]map[string]int),
+		batches: make(map[string]*batch),
+		w:       w,
+		b:       flatbuffers.NewBuilder(0),
+	}
+	names := make(map[string]bool)
+	add := func(c column) (int, error) {
+		if names[c.name] {
+			return 0, fmt.Errorf("duplicate column %s", c.name)
+		}
+		names[c.name] = true
+		s.columns = append(s.columns, c)
+		return len(s.columns) - 1, nil
+	}
+	add(column{name: TimeColumn, typ: typeTimestamp})
+	add(column{name: MeasurementColumn, typ: typeUtf8})
+	for _, key := range schema.AllTagKeys() {
+		i, err := add(column{name: string(key), typ: typeUtf8, nullable: true})
+		if err != nil {
+			return nil, err
+		}
+		s.tags[string(key)] = i
+	}
+	for _, measurementName := range schema.Measurements() {
+		fields := make(map[string]int)
+		fieldTypes := schema.FieldTypes(measurementName)
+		for i, key := range schema.FieldKeys(measurementName) {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			c := column{name: measurementName + "_" + string(key), nullable: true}
+			switch t {
+			case serialize.FieldTypeInt:
+				c.typ = typeInt
+			case serialize.FieldTypeBool:
+				c.typ = typeBool
+			case serialize.FieldTypeString:
+				c.typ = typeUtf8
+			default:
+				c.typ = typeFloatingPoint
+			}
+			j, err := add(c)
+			if err != nil {
+				return nil, err
+			}
+			fields[string(key)] = j
+		}
+		s.fields[measurementName] = fields
+	}
+
+	b := s.b
+	fields := make([]flatbuffers.UOffsetT, len(s.columns))
+	for i, c := range s.columns {
+		var typ flatbuffers.UOffsetT
+		switch c.typ {
+		case typeTimestamp:
+			typ = timestampType(b, "UTC")
+		case typeInt:
+			typ = int64Type(b)
+		case typeFloatingPoint:
+			typ = doubleType(b)
+		default:
+			typ = emptyType(b)
+		}
+		fields[i] = field(b, c.name, c.nullable, c.typ, typ)
+	}
+	b.Finish(message(b, messageHeaderSchema, schemaTable(b, fields), 0))
+	s.buf = appendMessage(s.buf[:0], b.FinishedBytes(), nil)
+	_, err := w.Write(s.buf)
+	return s, err
+}
+
+// Serialize adds Point p as a row of the record batch of its measurement,
+// first writing the record batches buffered to w if it is of another chunk
+// of time than them. Tags with empty values are null, as are the fields of
+// the other measurements. Fields must have values of the types of their
+// columns, though ints may be in Float64 columns, and a tag or field that is
+// not in the Schema, so has no column, is an error.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	return s.addRow(w, p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+}
+
+// SerializeBatch adds all rows of a PointBatch as Serialize does
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		if err := s.addRow(w, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the record batches buffered, and then the end of the stream
+func (s *Serializer) Close() error {
+	if err := s.writeBatches(s.w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(s.w, endOfStream)
+	return err
+}
+
+func (s *Serializer) addRow(w io.Writer, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) error {
+	// the columns and types are checked first, so no array is changed if
+	// any is wrong
+	for _, key := range tagKeys {
+		if _, ok := s.tags[string(key)]; !ok {
+			return fmt.Errorf("tag %s of %s has no column", key, measurementName)
+		}
+	}
+	fields := s.fields[string(measurementName)]
+	for i, key := range fieldKeys {
+		j, ok := fields[string(key)]
+		if !ok {
+			return fmt.Errorf("field %s of %s has no column", key, measurementName)
+		}
+		if !accepts(s.columns[j].typ, fieldValues[i]) {
+			return fmt.Errorf("field %s of %s is a %T, not a value of its column", key, measurementName, fieldValues[i])
+		}
+	}
+
+	chunk := timestamp / s.chunk
+	if timestamp < 0 && timestamp%s.chunk != 0 {
+		chunk--
+	}
+	if chunk != s.current {
+		if err := s.writeBatches(w); err != nil {
+			return err
+		}
+		s.current = chunk
+	}
+	s.w = w
+
+	b := s.batch(measurementName)
+	b.arrays[0].appendInt64(timestamp)
+	b.arrays[1].appendBytes(measurementName)
+	for i, key := range tagKeys {
+		if j := s.tags[string(key)]; !b.arrays[j].set && len(tagValues[i]) > 0 {
+			b.arrays[j].appendBytes(tagValues[i])
+		}
+	}
+	for i, key := range fieldKeys {
+		if j := fields[string(key)]; !b.arrays[j].set && fieldValues[i] != nil {
+			b.arrays[j].appendValue(s.columns[j].typ, fieldValues[i])
+		}
+	}
+	for i, a := range b.arrays {
+		if a == nil {
+			continue
+		}
+		if !a.set {
+			a.appendNull(s.columns[i].typ)
+		}
+		a.set = false
+	}
+	b.rows++
+	return nil
+}
+
+// batch returns the batch of measurementName, creating it with arrays for
+// the time, measurement, tags and fields of the measurement if it has none
+func (s *Serializer) batch(measurementName []byte) *batch {
+	if b, ok := s.batches[string(measurementName)]; ok {
+		return b
+	}
+	b := &batch{arrays: make([]*array, len(s.columns))}
+	b.arrays[0] = newArray(s.columns[0].typ)
+	b.arrays[1] = newArray(s.columns[1].typ)
+	for _, j := range s.tags {
+		b.arrays[j] = newArray(s.columns[j].typ)
+	}
+	for _, j := range s.fields[string(measurementName)] {
+		b.arrays[j] = newArray(s.columns[j].typ)
+	}
+	s.batches[string(measurementName)] = b
+	s.order = append(s.order, b)
+	return b
+}
+
+// writeBatches writes the batches with rows buffered to w, as a record
+// batch message each, in the order they were created in
+func (s *Serializer) writeBatches(w io.Writer) error {
+	buf := s.buf[:0]
+	for _, b := range s.order {
+		if b.rows == 0 {
+			continue
+		}
+		body := s.body[:0]
+		nodes, buffers := s.nodes[:0], s.buffers[:0]
+		rows := int64(b.rows)
+		bitmapSize := (b.rows + 7) / 8
+		for i, a := range b.arrays {
+			typ := s.columns[i].typ
+			if a == nil {
+				// an array of nulls only, with its buffers of zeros
+				nodes = append(nodes, [2]int64{rows, rows})
+				body, buffers = appendBuffer(body, buffers, make([]byte, bitmapSize))
+				switch typ {
+				case typeBool:
+					body, buffers = appendBuffer(body, buffers, make([]byte, bitmapSize))
+				case typeUtf8:
+					body, buffers = appendBuffer(body, buffers, make([]byte, 4*(b.rows+1)))
+					body, buffers = appendBuffer(body, buffers, nil)
+				default:
+					body, buffers = appendBuffer(body, buffers, make([]byte, 8*b.rows))
+				}
+				continue
+			}
+			nodes = append(nodes, [2]int64{rows, int64(a.nulls)})
+			if a.nulls == 0 {
+				body, buffers = appendBuffer(body, buffers, nil)
+			} else {
+				body, buffers = appendBuffer(body, buffers, a.validity)
+			}
+			if typ == typeUtf8 {
+				body, buffers = appendBuffer(body, buffers, a.offsets)
+			}
+			body, buffers = appendBuffer(body, buffers, a.values)
+			a.reset(typ)
+		}
+		b.rows = 0
+
+		s.b.Reset()
+		s.b.Finish(message(s.b, messageHeaderRecordBatch, recordBatch(s.b, rows, nodes, buffers), int64(len(body))))
+		buf = appendMessage(buf, s.b.FinishedBytes(), body)
+		s.body, s.nodes, s.buffers = body, nodes, buffers
+	}
+	s.buf = buf
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// appendBuffer appends data to body as a buffer, padded to 8 bytes, adding
+// its offset and length to buffers
+func appendBuffer(body []byte, buffers [][2]int64, data []byte) ([]byte, [][2]int64) {
+	buffers = append(buffers, [2]int64{int64(len(body)), int64(len(data))})
+	body = append(body, data...)
+	for len(body)%8 != 0 {
+		body = append(body, 0)
+	}
+	return body, buffers
+}
+
+// appendMessage appends an encapsulated message to buf: the continuation
+// marker, the length of the metadata, padded to 8 bytes, the metadata and
+// the body
+func appendMessage(buf []byte, metadata []byte, body []byte) []byte {
+	padded := (len(metadata) + 7) &^ 7
+	buf = binary.LittleEndian.AppendUint32(buf, continuation)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(padded))
+	buf = append(buf, metadata...)
+	for i := len(metadata); i < padded; i++ {
+		buf = append(buf, 0)
+	}
+	return append(buf, body...)
+}
+
+// accepts returns whether v can be a value of a column of type typ
+func accepts(typ byte, v interface{}) bool {
+	switch v.(type) {
+	case nil:
+		return true
+	case int, int64:
+		return typ == typeInt || typ == typeFloatingPoint
+	case float64, float32:
+		return typ == typeFloatingPoint
+	case bool:
+		return typ == typeBool
+	case []byte, string:
+		return typ == typeUtf8
+	}
+	return false
+}
+
+func newArray(typ byte) *array {
+	a := &array{}
+	a.reset(typ)
+	return a
+}
+
+// appendValue appends v, which a column of type typ accepts, to a
+func (a *array) appendValue(typ byte, v interface{}) {
+	switch x := v.(type) {
+	case int:
+		a.appendNumber(typ, int64(x))
+	case int64:
+		a.appendNumber(typ, x)
+	case float64:
+		a.appendFloat(x)
+	case float32:
+		a.appendFloat(float64(x))
+	case bool:
+		a.appendBool(x)
+	case []byte:
+		a.appendBytes(x)
+	case string:
+		a.appendBytes([]byte(x))
+	}
+}
+
+func (a *array) appendNumber(typ byte, x int64) {
+	if typ == typeFloatingPoint {
+		a.appendFloat(float64(x))
+	} else {
+		a.appendInt64(x)
+	}
+}
+
+func (a *array) appendInt64(x int64) {
+	a.values = binary.LittleEndian.AppendUint64(a.values, uint64(x))
+	a.appendValid(true)
+}
+
+func (a *array) appendFloat(f float64) {
+	a.values = binary.LittleEndian.AppendUint64(a.values, math.Float64bits(f))
+	a.appendValid(true)
+}
+
+func (a *array) appendBool(x bool) {
+	if a.n%8 == 0 {
+		a.values = append(a.values, 0)
+	}
+	if x {
+		a.values[len(a.values)-1] |= 1 << (a.n % 8)
+	}
+	a.appendValid(true)
+}
+
+func (a *array) appendBytes(b []byte) {
+	a.values = append(a.values, b...)
+	a.offsets = binary.LittleEndian.AppendUint32(a.offsets, uint32(len(a.values)))
+	a.appendValid(true)
+}
+
+// appendNull appends a null to a, of a column of type typ
+func (a *array) appendNull(typ byte) {
+	switch typ {
+	case typeBool:
+		if a.n%8 == 0 {
+			a.values = append(a.values, 0)
+		}
+	case typeUtf8:
+		a.offsets = binary.LittleEndian.AppendUint32(a.offsets, uint32(len(a.values)))
+	default:
+		a.values = append(a.values, 0, 0, 0, 0, 0, 0, 0, 0)
+	}
+	a.nulls++
+	a.appendValid(false)
+}
+
+// appendValid appends the validity bit of the value appended, and counts it
+func (a *array) appendValid(valid bool) {
+	if a.n%8 == 0 {
+		a.validity = append(a.validity, 0)
+	}
+	if valid {
+		a.validity[len(a.validity)-1] |= 1 << (a.n % 8)
+		a.set = true
+	}
+	a.n++
+}
+
+// reset empties a, of a column of type typ
+func (a *array) reset(typ byte) {
+	a.validity = a.validity[:0]
+	a.values = a.values[:0]
+	a.offsets = a.offsets[:0]
+	if typ == typeUtf8 {
+		a.offsets = append(a.offsets, 0, 0, 0, 0)
+	}
+	a.n = 0
+	a.nulls = 0
+}
@@ -0,0 +1,417 @@
+package arrow
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/timescale/tsbs/pkg/data/devops"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		// record batches are only written once a chunk of time ends, or on
+		// Close
+		IgnoresWriter: true,
+	}.Run(t)
+}
+
+// slot returns the offset of the field in slot n of table t, or 0 if it is
+// not set
+func slot(t flatbuffers.Table, n int) flatbuffers.UOffsetT {
+	return flatbuffers.UOffsetT(t.Offset(flatbuffers.VOffsetT(4 + 2*n)))
+}
+
+// int64Slot returns the long in slot n of table t, or 0 if it is not set
+func int64Slot(t flatbuffers.Table, n int) int64 {
+	return t.GetInt64Slot(flatbuffers.VOffsetT(4+2*n), 0)
+}
+
+// child returns the table in slot n of table t
+func child(t flatbuffers.Table, n int) flatbuffers.Table {
+	return flatbuffers.Table{Bytes: t.Bytes, Pos: t.Indirect(slot(t, n) + t.Pos)}
+}
+
+// decodedColumn is a column of the schema of a stream
+type decodedColumn struct {
+	name     string
+	nullable bool
+	typ      byte
+	// params are the parameters of the type, for Int, FloatingPoint and
+	// Timestamp
+	params string
+}
+
+// decode decodes an Arrow IPC stream written by a Serializer into its
+// columns and the rows of each record batch, as maps of column names to
+// values, with no entries for nulls
+func decode(data []byte) (columns []decodedColumn, batches [][]map[string]interface{}, err error) {
+	for {
+		if len(data) < 8 || binary.LittleEndian.Uint32(data) != continuation {
+			return nil, nil, fmt.Errorf("no continuation marker")
+		}
+		size := int(binary.LittleEndian.Uint32(data[4:]))
+		if size == 0 {
+			if len(data) != 8 {
+				return nil, nil, fmt.Errorf("%d bytes after the end of the stream", len(data)-8)
+			}
+			return columns, batches, nil
+		}
+		if size%8 != 0 {
+			return nil, nil, fmt.Errorf("metadata of %d bytes is not padded", size)
+		}
+		metadata := data[8 : 8+size]
+		m := flatbuffers.Table{Bytes: metadata, Pos: flatbuffers.GetUOffsetT(metadata)}
+		if v := m.GetInt16Slot(4, 0); v != metadataVersionV5 {
+			return nil, nil, fmt.Errorf("metadata version %d", v)
+		}
+		bodyLength := int(int64Slot(m, 3))
+		body := data[8+size : 8+size+bodyLength]
+		data = data[8+size+bodyLength:]
+		header := child(m, 2)
+
+		switch headerType := m.GetByteSlot(6, 0); headerType {
+		case messageHeaderSchema:
+			if columns != nil {
+				return nil, nil, fmt.Errorf("second schema")
+			}
+			fields := slot(header, 1)
+			for i := 0; i < header.VectorLen(fields); i++ {
+				f := flatbuffers.Table{Bytes: metadata, Pos: header.Indirect(header.Vector(fields) + flatbuffers.UOffsetT(4*i))}
+				c := decodedColumn{
+					name:     string(f.ByteVector(slot(f, 0) + f.Pos)),
+					nullable: f.GetBoolSlot(6, false),
+					typ:      f.GetByteSlot(8, 0),
+				}
+				if slot(f, 5) == 0 {
+					return nil, nil, fmt.Errorf("no children of %s", c.name)
+				}
+				typ := child(f, 3)
+				switch c.typ {
+				case typeInt:
+					c.params = fmt.Sprintf("%d %t", typ.GetInt32Slot(4, 0), typ.GetBoolSlot(6, false))
+				case typeFloatingPoint:
+					c.params = fmt.Sprint(typ.GetInt16Slot(4, 0))
+				case typeTimestamp:
+					c.params = fmt.Sprintf("%d %s", typ.GetInt16Slot(4, 0), typ.ByteVector(slot(typ, 1)+typ.Pos))
+				}
+				columns = append(columns, c)
+			}
+		case messageHeaderRecordBatch:
+			length := int(int64Slot(header, 0))
+			nodes := slot(header, 1)
+			buffers := slot(header, 2)
+			if header.VectorLen(nodes) != len(columns) {
+				return nil, nil, fmt.Errorf("%d field nodes for %d columns", header.VectorLen(nodes), len(columns))
+			}
+			rows := make([]map[string]interface{}, length)
+			for i := range rows {
+				rows[i] = make(map[string]interface{})
+			}
+			buffer := 0
+			next := func() []byte {
+				p := header.Vector(buffers) + flatbuffers.UOffsetT(16*buffer)
+				buffer++
+				offset, n := header.GetInt64(p), header.GetInt64(p+8)
+				if offset%8 != 0 {
+					panic("buffer not aligned")
+				}
+				return body[offset : offset+n]
+			}
+			for i, c := range columns {
+				p := header.Vector(nodes) + flatbuffers.UOffsetT(16*i)
+				if n := int(header.GetInt64(p)); n != length {
+					return nil, nil, fmt.Errorf("column %s of %d values in a batch of %d rows", c.name, n, length)
+				}
+				nulls := int(header.GetInt64(p + 8))
+				validity := next()
+				var offsets []byte
+				if c.typ == typeUtf8 {
+					offsets = next()
+				}
+				values := next()
+				counted := 0
+				for j, row := range rows {
+					if nulls > 0 && validity[j/8]>>(j%8)&1 == 0 {
+						counted++
+						continue
+					}
+					var v interface{}
+					switch c.typ {
+					case typeInt, typeTimestamp:
+						v = int64(binary.LittleEndian.Uint64(values[8*j:]))
+					case typeFloatingPoint:
+						v = math.Float64frombits(binary.LittleEndian.Uint64(values[8*j:]))
+					case typeBool:
+						v = values[j/8]>>(j%8)&1 == 1
+					case typeUtf8:
+						v = string(values[binary.LittleEndian.Uint32(offsets[4*j:]):binary.LittleEndian.Uint32(offsets[4*j+4:])])
+					}
+					row[c.name] = v
+				}
+				if counted != nulls {
+					return nil, nil, fmt.Errorf("column %s has %d nulls, not %d", c.name, counted, nulls)
+				}
+			}
+			if buffer != header.VectorLen(buffers) {
+				return nil, nil, fmt.Errorf("%d buffers, not %d", header.VectorLen(buffers), buffer)
+			}
+			batches = append(batches, rows)
+		default:
+			return nil, nil, fmt.Errorf("unexpected message header %d", headerType)
+		}
+	}
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func testSchema() *serialize.Schema {
+	return serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"state": {[]byte("rack")}},
+		map[string][][]byte{
+			"cpu":   {[]byte("usage_user"), []byte("usage_system")},
+			"state": {[]byte("count"), []byte("up"), []byte("status")},
+		},
+		map[string][]serialize.FieldType{
+			"state": {serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString},
+		},
+	)
+}
+
+func TestSerializerBatches(t *testing.T) {
+	const minute = int64(time.Minute)
+	start := serializetest.Now.UnixNano()
+	points := []*serialize.Point{
+		newPoint("cpu", start, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", 58.13, "usage_system", int64(2)),
+		newPoint("state", start+10e9, []string{"hostname", "host_1", "region", ""}, "count", int64(-3), "up", true, "status", "ok"),
+		newPoint("cpu", start+20e9, nil, "usage_system", math.Inf(-1)),
+		newPoint("state", start+minute, []string{"hostname", "host_0", "rack", "r1"}, "up", false),
+		newPoint("cpu", -1, nil, "usage_user", nil),
+	}
+	wantColumns := []decodedColumn{
+		{name: "timestamp", typ: typeTimestamp, params: "3 UTC"},
+		{name: "measurement", typ: typeUtf8},
+		{name: "hostname", nullable: true, typ: typeUtf8},
+		{name: "region", nullable: true, typ: typeUtf8},
+		{name: "rack", nullable: true, typ: typeUtf8},
+		{name: "cpu_usage_user", nullable: true, typ: typeFloatingPoint, params: "2"},
+		{name: "cpu_usage_system", nullable: true, typ: typeFloatingPoint, params: "2"},
+		{name: "state_count", nullable: true, typ: typeInt, params: "64 true"},
+		{name: "state_up", nullable: true, typ: typeBool},
+		{name: "state_status", nullable: true, typ: typeUtf8},
+	}
+	wantBatches := [][]map[string]interface{}{
+		{
+			{"timestamp": start, "measurement": "cpu", "hostname": "host_0", "region": "eu-west-1", "cpu_usage_user": 58.13, "cpu_usage_system": 2.0},
+			{"timestamp": start + 20e9, "measurement": "cpu", "cpu_usage_system": math.Inf(-1)},
+		},
+		{
+			{"timestamp": start + 10e9, "measurement": "state", "hostname": "host_1", "state_count": int64(-3), "state_up": true, "state_status": "ok"},
+		},
+		{
+			{"timestamp": start + minute, "measurement": "state", "hostname": "host_0", "rack": "r1", "state_up": false},
+		},
+		{
+			{"timestamp": int64(-1), "measurement": "cpu"},
+		},
+	}
+
+	for _, batch := range []bool{false, true} {
+		var buf bytes.Buffer
+		s, err := NewSerializer(testSchema(), time.Minute, &buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if batch {
+			b := serialize.NewPointBatch()
+			for _, p := range points {
+				b.Append(p)
+			}
+			err = s.SerializeBatch(b, &buf)
+		} else {
+			for _, p := range points {
+				if err = s.Serialize(p, &buf); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+
+		columns, batches, err := decode(buf.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error decoding: %v", err)
+		}
+		if !reflect.DeepEqual(columns, wantColumns) {
+			t.Errorf("incorrect columns:\ngot  %v\nwant %v", columns, wantColumns)
+		}
+		if !reflect.DeepEqual(batches, wantBatches) {
+			t.Errorf("batch %t: incorrect record batches:\ngot  %v\nwant %v", batch, batches, wantBatches)
+		}
+	}
+}
+
+func TestSerializerGenerated(t *testing.T) {
+	// data in the format is written without a Header, so it can be read as
+	// generated
+	for _, format := range []string{Format, Format + ":10m"} {
+		if !serialize.IsFileFormat(format) {
+			t.Errorf("%s: not a file format", format)
+		}
+	}
+
+	start := serializetest.Now
+	sim := (&devops.DevopsSimulatorConfig{
+		Start:           start,
+		End:             start.Add(time.Minute),
+		InitHostCount:   2,
+		HostCount:       2,
+		HostConstructor: devops.NewHost,
+		RNG:             rng.New(123),
+	}).ToSimulator(10 * time.Second)
+	var buf bytes.Buffer
+	s, err := serialize.New(Format, sim.Fields(), &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows := 0
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rows++
+	}
+	if err := s.(io.Closer).Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	_, batches, err := decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	got := 0
+	for _, batch := range batches {
+		got += len(batch)
+	}
+	if rows == 0 || got != rows {
+		t.Errorf("incorrect number of rows read: got %d want %d", got, rows)
+	}
+}
+
+func TestSerializerBatchesWritten(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema(), time.Minute, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	schemaLen := buf.Len()
+	start := serializetest.Now.UnixNano()
+	s.Serialize(newPoint("cpu", start, nil, "usage_user", 1.0), &buf)
+	s.Serialize(newPoint("cpu", start+59e9, nil, "usage_user", 1.0), &buf)
+	if buf.Len() != schemaLen {
+		t.Errorf("record batch written before its chunk of time ended")
+	}
+	s.Serialize(newPoint("cpu", start+60e9, nil, "usage_user", 1.0), &buf)
+	if buf.Len() == schemaLen {
+		t.Errorf("record batch not written once its chunk of time ended")
+	}
+}
+
+func TestSerializerTypeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema(), DefaultChunk, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = s.Serialize(newPoint("state", 0, nil, "count", int64(1), "up", "yes"), &buf)
+	if err == nil {
+		t.Fatalf("no error for a string in a Bool column")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if _, batches, err := decode(buf.Bytes()); err != nil || len(batches) != 0 {
+		t.Errorf("rejected row written: %v (error %v)", batches, err)
+	}
+}
+
+func TestSerializerNoColumn(t *testing.T) {
+	cases := []struct {
+		desc string
+		p    *serialize.Point
+		want string
+	}{
+		{desc: "tag", p: newPoint("cpu", 0, []string{"hostname", "host_0", "zone", "a"}, "usage_user", 1.0), want: "tag zone of cpu has no column"},
+		{desc: "field", p: newPoint("cpu", 0, nil, "usage_user", 1.0, "usage_idle", 2.0), want: "field usage_idle of cpu has no column"},
+		{desc: "measurement", p: newPoint("other", 0, []string{"hostname", "host_2"}, "value", 1.0), want: "field value of other has no column"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		s, err := NewSerializer(testSchema(), DefaultChunk, &buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Serialize(c.p, &buf); err == nil || err.Error() != c.want {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.want)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+		if _, batches, err := decode(buf.Bytes()); err != nil || len(batches) != 0 {
+			t.Errorf("%s: rejected row written: %v (error %v)", c.desc, batches, err)
+		}
+	}
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	if _, err := NewSerializer(nil, time.Minute, io.Discard); err == nil {
+		t.Errorf("no error without a schema")
+	}
+	schema := serialize.NewSchema([][]byte{[]byte("cpu_usage")}, map[string][][]byte{"cpu": {[]byte("usage")}})
+	if _, err := NewSerializer(schema, time.Minute, io.Discard); err == nil || !strings.Contains(err.Error(), "duplicate column cpu_usage") {
+		t.Errorf("incorrect error for a duplicate column: %v", err)
+	}
+	for _, format := range []string{"arrow:0s", "arrow:-1m", "arrow:hourly"} {
+		if _, err := serialize.New(format, testSchema(), io.Discard); err == nil {
+			t.Errorf("no error for format %s", format)
+		}
+	}
+	if _, err := serialize.New("arrow:10m", testSchema(), io.Discard); err != nil {
+		t.Errorf("unexpected error for format arrow:10m: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package arrow
+
+import (
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// The tables of the FlatBuffers metadata of Arrow IPC messages written, from
+// Message.fbs and Schema.fbs of the Arrow format, as their slots in them
+
+// metadataVersionV5 is the version of the metadata written
+const metadataVersionV5 = 4
+
+// Types of the header union of Message
+const (
+	messageHeaderSchema      = 1
+	messageHeaderRecordBatch = 3
+)
+
+// Types of the type union of Field
+const (
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeUtf8          = 5
+	typeBool          = 6
+	typeTimestamp     = 10
+)
+
+const (
+	precisionDouble = 2
+	timeUnitNano    = 3
+)
+
+// message adds a Message of the header of headerType at offset header,
+// followed by a body of bodyLength bytes
+func message(b *flatbuffers.Builder, headerType byte, header flatbuffers.UOffsetT, bodyLength int64) flatbuffers.UOffsetT {
+	b.StartObject(5)
+	b.PrependInt64Slot(3, bodyLength, 0)
+	b.PrependUOffsetTSlot(2, header, 0)
+	b.PrependInt16Slot(0, metadataVersionV5, 0)
+	b.PrependByteSlot(1, headerType, 0)
+	return b.EndObject()
+}
+
+// schemaTable adds a Schema of the Fields at offsets fields, in little endian
+func schemaTable(b *flatbuffers.Builder, fields []flatbuffers.UOffsetT) flatbuffers.UOffsetT {
+	vector := offsets(b, fields)
+	b.StartObject(4)
+	b.PrependUOffsetTSlot(1, vector, 0)
+	return b.EndObject()
+}
+
+// field adds a Field of the type of typeType at offset typ, with no
+// children
+func field(b *flatbuffers.Builder, name string, nullable bool, typeType byte, typ flatbuffers.UOffsetT) flatbuffers.UOffsetT {
+	nameOffset := b.CreateString(name)
+	// readers expect the children even if there are none
+	children := offsets(b, nil)
+	b.StartObject(7)
+	b.PrependUOffsetTSlot(0, nameOffset, 0)
+	b.PrependUOffsetTSlot(3, typ, 0)
+	b.PrependUOffsetTSlot(5, children, 0)
+	b.PrependBoolSlot(1, nullable, false)
+	b.PrependByteSlot(2, typeType, 0)
+	return b.EndObject()
+}
+
+// int64Type adds an Int type of signed 64 bit integers
+func int64Type(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+	b.StartObject(2)
+	b.PrependInt32Slot(0, 64, 0)
+	b.PrependBoolSlot(1, true, false)
+	return b.EndObject()
+}
+
+// doubleType adds a FloatingPoint type of doubles
+func doubleType(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+	b.StartObject(1)
+	b.PrependInt16Slot(0, precisionDouble, 0)
+	return b.EndObject()
+}
+
+// timestampType adds a Timestamp type in nanoseconds in timezone
+func timestampType(b *flatbuffers.Builder, timezone string) flatbuffers.UOffsetT {
+	tz := b.CreateString(timezone)
+	b.StartObject(2)
+	b.PrependUOffsetTSlot(1, tz, 0)
+	b.PrependInt16Slot(0, timeUnitNano, 0)
+	return b.EndObject()
+}
+
+// emptyType adds a type that has no parameters, i.e., Utf8 or Bool
+func emptyType(b *flatbuffers.Builder) flatbuffers.UOffsetT {
+	b.StartObject(0)
+	return b.EndObject()
+}
+
+// recordBatch adds a RecordBatch of length rows, with the FieldNode of each
+// column, as its length and null count, and the Buffers of their data in the
+// body, as their offset and length
+func recordBatch(b *flatbuffers.Builder, length int64, nodes, buffers [][2]int64) flatbuffers.UOffsetT {
+	nodesVector := structs(b, nodes)
+	buffersVector := structs(b, buffers)
+	b.StartObject(5)
+	b.PrependInt64Slot(0, length, 0)
+	b.PrependUOffsetTSlot(1, nodesVector, 0)
+	b.PrependUOffsetTSlot(2, buffersVector, 0)
+	return b.EndObject()
+}
+
+// offsets adds a vector of the tables at offsets v
+func offsets(b *flatbuffers.Builder, v []flatbuffers.UOffsetT) flatbuffers.UOffsetT {
+	b.StartVector(4, len(v), 4)
+	for i := len(v) - 1; i >= 0; i-- {
+		b.PrependUOffsetT(v[i])
+	}
+	return b.EndVector(len(v))
+}
+
+// structs adds a vector of structs of two longs, i.e., FieldNodes or
+// Buffers
+func structs(b *flatbuffers.Builder, v [][2]int64) flatbuffers.UOffsetT {
+	b.StartVector(16, len(v), 8)
+	for i := len(v) - 1; i >= 0; i-- {
+		b.Prep(8, 16)
+		b.PrependInt64(v[i][1])
+		b.PrependInt64(v[i][0])
+	}
+	return b.EndVector(len(v))
+}
@@ -0,0 +1,389 @@
+// Package avro implements the format for Apache Avro: an object container
+// file, with the Avro schema of the data in its header, as Kafka Connect and
+// schema registry based pipelines ingest it.
+package avro
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes
+// uncompressed blocks. Formats of the form Format + ":" + codec write blocks
+// compressed with codec, one of Codecs.
+const Format = "avro"
+
+// Codecs are the codecs blocks can be compressed with
+var Codecs = []string{"null", "deflate", "snappy"}
+
+// BlockSize is the number of records of each block of the file
+const BlockSize = 1000
+
+// Namespace is the namespace of the records of the schema
+const Namespace = "tsbs"
+
+// TimeField is the field of the time of each record, as a timestamp in
+// microseconds
+const TimeField = "timestamp"
+
+// Magic starts every object container file
+const Magic = "Obj\x01"
+
+func init() {
+	serialize.Describe(Format, "Apache Avro object container file, a record type per measurement, with avro:<codec> compressing the blocks with "+strings.Join(Codecs[1:], " or "))
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, "null", w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, arg, w)
+	})
+	serialize.MarkFileFormat(Format)
+}
+
+// record is the record type of a measurement, with the positions of its
+// tags after the time and of its fields after the tags
+type record struct {
+	index  int
+	tags   map[string]int
+	fields map[string]int
+	types  []serialize.FieldType
+}
+
+// Serializer writes Points as the records of an Avro object container file.
+// The file is only complete, with the records buffered, once the Serializer
+// is closed.
+type Serializer struct {
+	codec   string
+	sync    []byte
+	records map[string]*record
+
+	// block are the encoded records buffered, of which there are count, and
+	// w the Writer they were last added with, which Close writes to
+	block []byte
+	count int
+	w     io.Writer
+
+	// buf, tagValues and fieldValues are scratch space reused between calls
+	buf         []byte
+	tagValues   [][]byte
+	fieldValues []interface{}
+}
+
+// NewSerializer returns a Serializer for data described by schema, writing
+// blocks compressed with codec to w. It writes the header of the file to w,
+// with the Avro schema of the data.
+//
+// The Avro schema is a union of a record type for each measurement, named
+// for it, with a field for the time of each reading, one for each tag key of
+// the measurement in schema and one for each field of the measurement. The fields of the tags
+// and measurement fields are optional: unions of null and a string or the
+// type of the field, a long, a boolean, a string or, for the numbers of the
+// simulators, a double.
+func NewSerializer(schema *serialize.Schema, codec string, w io.Writer) (*Serializer, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("avro files need the schema of the data")
+	}
+	known := false
+	for _, c := range Codecs {
+		known = known || c == codec
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown codec '%s': must be one of %s", codec, strings.Join(Codecs, ", "))
+	}
+	s := &Serializer{
+		codec:   codec,
+		records: make(map[string]*record),
+		w:       w,
+	}
+
+	var avroSchema []byte
+	avroSchema = append(avroSchema, '[')
+	names := make(map[string]bool)
+	for i, measurementName := range schema.Measurements() {
+		name := string(AppendName(nil, []byte(measurementName)))
+		if names[name] {
+			return nil, fmt.Errorf("duplicate record %s", name)
+		}
+		names[name] = true
+		fields := make(map[string]bool)
+		addField := func(key []byte, typ string) error {
+			name := string(AppendName(nil, key))
+			if fields[name] {
+				return fmt.Errorf("duplicate field %s of record %s", name, measurementName)
+			}
+			fields[name] = true
+			avroSchema = append(avroSchema, `,{"name":`...)
+			avroSchema = serialize.AppendJSONString(avroSchema, []byte(name))
+			avroSchema = append(avroSchema, `,"type":["null","`...)
+			avroSchema = append(avroSchema, typ...)
+			avroSchema = append(avroSchema, `"],"default":null}`...)
+			return nil
+		}
+
+		if i > 0 {
+			avroSchema = append(avroSchema, ',')
+		}
+		avroSchema = append(avroSchema, `{"type":"record","name":`...)
+		avroSchema = serialize.AppendJSONString(avroSchema, []byte(name))
+		avroSchema = append(avroSchema, `,"namespace":"`+Namespace+`","fields":[{"name":"`+TimeField+`","type":{"type":"long","logicalType":"timestamp-micros"}}`...)
+		fields[TimeField] = true
+		r := &record{index: i, tags: make(map[string]int), fields: make(map[string]int)}
+		for j, key := range schema.TagKeysOf(measurementName) {
+			if err := addField(key, "string"); err != nil {
+				return nil, err
+			}
+			r.tags[string(key)] = j
+		}
+		fieldTypes := schema.FieldTypes(measurementName)
+		for j, key := range schema.FieldKeys(measurementName) {
+			t := serialize.FieldTypeUnknown
+			if j < len(fieldTypes) {
+				t = fieldTypes[j]
+			}
+			if err := addField(key, fieldType(t)); err != nil {
+				return nil, err
+			}
+			r.fields[string(key)] = j
+			r.types = append(r.types, t)
+		}
+		avroSchema = append(avroSchema, "]}"...)
+		s.records[measurementName] = r
+	}
+	avroSchema = append(avroSchema, ']')
+	// the sync marker only has to be unlikely to be in the data, and is
+	// derived from the schema so the file is the same for the same data
+	sum := md5.Sum(avroSchema)
+	s.sync = sum[:]
+
+	buf := append(s.buf[:0], Magic...)
+	buf = binary.AppendVarint(buf, 2)
+	buf = appendBytes(buf, []byte("avro.schema"))
+	buf = appendBytes(buf, avroSchema)
+	buf = appendBytes(buf, []byte("avro.codec"))
+	buf = appendBytes(buf, []byte(codec))
+	buf = binary.AppendVarint(buf, 0)
+	buf = append(buf, s.sync...)
+	_, err := w.Write(buf)
+	s.buf = buf
+	return s, err
+}
+
+// fieldType returns the Avro type of fields of type t. Fields of unknown
+// type are numbers from the simulators, which fit a double.
+func fieldType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "long"
+	case serialize.FieldTypeBool:
+		return "boolean"
+	case serialize.FieldTypeString:
+		return "string"
+	default:
+		return "double"
+	}
+}
+
+// Serialize adds Point p as a record of the type of its measurement to the
+// block being buffered, writing it to w once it has BlockSize records. Tags
+// with empty values are null, as are fields with nil values. Fields must
+// have values of the types of their fields in the Avro schema, though ints
+// may be doubles. Measurements, tags and fields that are not in the Schema,
+// so have no record type or field, are an error.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	return s.addRecord(w, p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+}
+
+// SerializeBatch adds all rows of a PointBatch as Serialize does
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		if err := s.addRecord(w, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the records left as the last block
+func (s *Serializer) Close() error {
+	return s.writeBlock(s.w)
+}
+
+func (s *Serializer) addRecord(w io.Writer, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) error {
+	r, ok := s.records[string(measurementName)]
+	if !ok {
+		return fmt.Errorf("measurement %s is not in the schema", measurementName)
+	}
+	s.tagValues = s.tagValues[:0]
+	for range r.tags {
+		s.tagValues = append(s.tagValues, nil)
+	}
+	for i, key := range tagKeys {
+		j, ok := r.tags[string(key)]
+		if !ok {
+			return fmt.Errorf("tag %s of %s has no field", key, measurementName)
+		}
+		if s.tagValues[j] == nil && len(tagValues[i]) > 0 {
+			s.tagValues[j] = tagValues[i]
+		}
+	}
+	s.fieldValues = s.fieldValues[:0]
+	for range r.types {
+		s.fieldValues = append(s.fieldValues, nil)
+	}
+	for i, key := range fieldKeys {
+		j, ok := r.fields[string(key)]
+		if !ok {
+			return fmt.Errorf("field %s of %s has no field", key, measurementName)
+		}
+		if s.fieldValues[j] != nil {
+			continue
+		}
+		if !accepts(r.types[j], fieldValues[i]) {
+			return fmt.Errorf("field %s of %s is a %T, not a %s", key, measurementName, fieldValues[i], fieldType(r.types[j]))
+		}
+		s.fieldValues[j] = fieldValues[i]
+	}
+
+	block := binary.AppendVarint(s.block, int64(r.index))
+	micros := timestamp / 1e3
+	if timestamp < 0 && timestamp%1e3 != 0 {
+		micros--
+	}
+	block = binary.AppendVarint(block, micros)
+	for _, v := range s.tagValues {
+		if v == nil {
+			block = append(block, 0)
+		} else {
+			block = appendBytes(append(block, 2), v)
+		}
+	}
+	for j, v := range s.fieldValues {
+		if v == nil {
+			block = append(block, 0)
+		} else {
+			block = appendValue(append(block, 2), r.types[j], v)
+		}
+	}
+	s.block = block
+	s.count++
+	s.w = w
+	if s.count < BlockSize {
+		return nil
+	}
+	return s.writeBlock(w)
+}
+
+// writeBlock writes the records buffered to w as a block: their number, the
+// size of their encoding, compressed with the codec, the encoding and the
+// sync marker
+func (s *Serializer) writeBlock(w io.Writer) error {
+	if s.count == 0 {
+		return nil
+	}
+	data := s.block
+	switch s.codec {
+	case "deflate":
+		var compressed bytes.Buffer
+		fw, _ := flate.NewWriter(&compressed, flate.DefaultCompression)
+		fw.Write(data)
+		fw.Close()
+		data = compressed.Bytes()
+	case "snappy":
+		data = snappy.Encode(nil, data)
+		data = binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(s.block))
+	}
+	buf := binary.AppendVarint(s.buf[:0], int64(s.count))
+	buf = binary.AppendVarint(buf, int64(len(data)))
+	buf = append(buf, data...)
+	buf = append(buf, s.sync...)
+	s.buf = buf
+	s.block = s.block[:0]
+	s.count = 0
+	_, err := w.Write(buf)
+	return err
+}
+
+// accepts returns whether v can be a value of a field of type t
+func accepts(t serialize.FieldType, v interface{}) bool {
+	switch v.(type) {
+	case nil:
+		return true
+	case int, int64:
+		return t != serialize.FieldTypeBool && t != serialize.FieldTypeString
+	case float64, float32:
+		return t != serialize.FieldTypeInt && t != serialize.FieldTypeBool && t != serialize.FieldTypeString
+	case bool:
+		return t == serialize.FieldTypeBool
+	case []byte, string:
+		return t == serialize.FieldTypeString
+	}
+	return false
+}
+
+// appendValue appends v, which a field of type t accepts, to buf in the
+// Avro binary encoding of the type of the field
+func appendValue(buf []byte, t serialize.FieldType, v interface{}) []byte {
+	switch x := v.(type) {
+	case int:
+		return appendNumber(buf, t, int64(x))
+	case int64:
+		return appendNumber(buf, t, x)
+	case float64:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(x))
+	case float32:
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(float64(x)))
+	case bool:
+		if x {
+			return append(buf, 1)
+		}
+		return append(buf, 0)
+	case []byte:
+		return appendBytes(buf, x)
+	case string:
+		return appendBytes(buf, []byte(x))
+	}
+	return buf
+}
+
+func appendNumber(buf []byte, t serialize.FieldType, x int64) []byte {
+	if t == serialize.FieldTypeInt {
+		return binary.AppendVarint(buf, x)
+	}
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(float64(x)))
+}
+
+// appendBytes appends b to buf as Avro bytes or a string: its length and
+// then b
+func appendBytes(buf []byte, b []byte) []byte {
+	buf = binary.AppendVarint(buf, int64(len(b)))
+	return append(buf, b...)
+}
+
+// AppendName appends the name of a record or field to buf: name with the
+// characters Avro does not allow in names, all but letters, digits and
+// underscores, replaced by underscores, and prefixed by an underscore if it
+// is empty or starts with a digit
+func AppendName(buf []byte, name []byte) []byte {
+	if len(name) == 0 || name[0] >= '0' && name[0] <= '9' {
+		buf = append(buf, '_')
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
@@ -0,0 +1,377 @@
+package avro
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+func TestSerializerConformance(t *testing.T) {
+	for _, codec := range Codecs {
+		t.Run(codec, func(t *testing.T) {
+			serializetest.Suite{
+				New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+					return serialize.New(Format+":"+codec, schema, w)
+				},
+				// blocks are only written once they have their records, or
+				// on Close
+				IgnoresWriter: true,
+				Plain:         recordStrings,
+			}.Run(t)
+		})
+	}
+}
+
+// recordStrings returns the string values of the records of a file, which
+// may be compressed, concatenated
+func recordStrings(data []byte) ([]byte, error) {
+	_, _, values, _, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, record := range values {
+		for _, v := range record {
+			if v, ok := v.(string); ok {
+				out = append(out, v...)
+			}
+		}
+	}
+	return out, nil
+}
+
+// avroRecord is a record type of the schema of a file
+type avroRecord struct {
+	Type      string
+	Name      string
+	Namespace string
+	Fields    []struct {
+		Name    string
+		Type    json.RawMessage
+		Default interface{}
+	}
+}
+
+// reader reads values in the Avro binary encoding
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) long() int64 {
+	v, n := binary.Varint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("bad long")
+		r.data = nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := int(r.long())
+	if n < 0 || n > len(r.data) {
+		r.err = fmt.Errorf("bad length %d", n)
+		r.data = nil
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *reader) value(typ string) interface{} {
+	switch typ {
+	case "long":
+		return r.long()
+	case "double":
+		if len(r.data) < 8 {
+			r.err = io.ErrUnexpectedEOF
+			return nil
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(r.data))
+		r.data = r.data[8:]
+		return v
+	case "boolean":
+		v := r.data[0] == 1
+		r.data = r.data[1:]
+		return v
+	case "string":
+		return string(r.bytes())
+	}
+	r.err = fmt.Errorf("unexpected type %s", typ)
+	return nil
+}
+
+// decode decodes an object container file written by a Serializer into its
+// record types and records, as the name of their type and a map of their
+// field names to values, with no entries for nulls, and the number of
+// records of each block
+func decode(data []byte) (records []avroRecord, names []string, values []map[string]interface{}, blocks []int, err error) {
+	if !bytes.HasPrefix(data, []byte(Magic)) {
+		return nil, nil, nil, nil, fmt.Errorf("no magic number")
+	}
+	r := &reader{data: data[len(Magic):]}
+	metadata := make(map[string]string)
+	for n := r.long(); n != 0 && r.err == nil; n = r.long() {
+		for i := int64(0); i < n; i++ {
+			key := r.bytes()
+			metadata[string(key)] = string(r.bytes())
+		}
+	}
+	if err := json.Unmarshal([]byte(metadata["avro.schema"]), &records); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	sync := r.data[:16]
+	r.data = r.data[16:]
+
+	for len(r.data) > 0 && r.err == nil {
+		count := int(r.long())
+		block := r.bytes()
+		if !bytes.Equal(r.data[:16], sync) {
+			return nil, nil, nil, nil, fmt.Errorf("no sync marker after block %d", len(blocks))
+		}
+		r.data = r.data[16:]
+		switch metadata["avro.codec"] {
+		case "deflate":
+			if block, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(block))); err != nil {
+				return nil, nil, nil, nil, err
+			}
+		case "snappy":
+			checksum := binary.BigEndian.Uint32(block[len(block)-4:])
+			if block, err = snappy.Decode(nil, block[:len(block)-4]); err != nil {
+				return nil, nil, nil, nil, err
+			}
+			if crc32.ChecksumIEEE(block) != checksum {
+				return nil, nil, nil, nil, fmt.Errorf("incorrect checksum")
+			}
+		}
+		br := &reader{data: block}
+		for i := 0; i < count; i++ {
+			record := records[br.long()]
+			v := map[string]interface{}{record.Fields[0].Name: br.long()}
+			for _, f := range record.Fields[1:] {
+				var union []string
+				if err := json.Unmarshal(f.Type, &union); err != nil {
+					return nil, nil, nil, nil, err
+				}
+				if br.long() == 1 {
+					v[f.Name] = br.value(union[1])
+				}
+			}
+			names = append(names, record.Name)
+			values = append(values, v)
+		}
+		if br.err != nil || len(br.data) > 0 {
+			return nil, nil, nil, nil, fmt.Errorf("bad block %d: %v", len(blocks), br.err)
+		}
+		blocks = append(blocks, count)
+	}
+	return records, names, values, blocks, r.err
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func testSchema() *serialize.Schema {
+	return serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"disk-state": {[]byte("rack")}},
+		map[string][][]byte{
+			"cpu":        {[]byte("usage_user"), []byte("usage_system")},
+			"disk-state": {[]byte("count"), []byte("up"), []byte("status")},
+		},
+		map[string][]serialize.FieldType{
+			"disk-state": {serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString},
+		},
+	)
+}
+
+func TestSerializerSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := NewSerializer(testSchema(), "null", &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `[{"type":"record","name":"cpu","namespace":"tsbs","fields":[` +
+		`{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-micros"}},` +
+		`{"name":"hostname","type":["null","string"],"default":null},` +
+		`{"name":"region","type":["null","string"],"default":null},` +
+		`{"name":"usage_user","type":["null","double"],"default":null},` +
+		`{"name":"usage_system","type":["null","double"],"default":null}]},` +
+		`{"type":"record","name":"disk_state","namespace":"tsbs","fields":[` +
+		`{"name":"timestamp","type":{"type":"long","logicalType":"timestamp-micros"}},` +
+		`{"name":"hostname","type":["null","string"],"default":null},` +
+		`{"name":"region","type":["null","string"],"default":null},` +
+		`{"name":"rack","type":["null","string"],"default":null},` +
+		`{"name":"count","type":["null","long"],"default":null},` +
+		`{"name":"up","type":["null","boolean"],"default":null},` +
+		`{"name":"status","type":["null","string"],"default":null}]}]`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("incorrect schema in header %q", buf.String())
+	}
+	if !strings.Contains(buf.String(), "\x14avro.codec\x08null") {
+		t.Errorf("no codec in header %q", buf.String())
+	}
+}
+
+func TestSerializerRecords(t *testing.T) {
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400000000000, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", 58.13, "usage_system", int64(2)),
+		newPoint("disk-state", 1451606410000000000, []string{"hostname", "host_1", "region", ""}, "count", int64(-3), "up", true, "status", "ok"),
+		newPoint("cpu", -1500, nil, "usage_system", math.Inf(-1)),
+		newPoint("disk-state", 1451606420000000000, []string{"hostname", "host_0", "rack", "r1"}, "up", false, "count", nil),
+	}
+	wantNames := []string{"cpu", "disk_state", "cpu", "disk_state"}
+	wantValues := []map[string]interface{}{
+		{"timestamp": int64(1451606400000000), "hostname": "host_0", "region": "eu-west-1", "usage_user": 58.13, "usage_system": 2.0},
+		{"timestamp": int64(1451606410000000), "hostname": "host_1", "count": int64(-3), "up": true, "status": "ok"},
+		{"timestamp": int64(-2), "usage_system": math.Inf(-1)},
+		{"timestamp": int64(1451606420000000), "hostname": "host_0", "rack": "r1", "up": false},
+	}
+
+	for _, codec := range Codecs {
+		for _, batch := range []bool{false, true} {
+			var buf bytes.Buffer
+			s, err := NewSerializer(testSchema(), codec, &buf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if batch {
+				b := serialize.NewPointBatch()
+				for _, p := range points {
+					b.Append(p)
+				}
+				err = s.SerializeBatch(b, &buf)
+			} else {
+				for _, p := range points {
+					if err = s.Serialize(p, &buf); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("unexpected error closing: %v", err)
+			}
+
+			_, names, values, _, err := decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("codec %s: unexpected error decoding: %v", codec, err)
+			}
+			if !reflect.DeepEqual(names, wantNames) {
+				t.Errorf("codec %s: incorrect record types: got %q want %q", codec, names, wantNames)
+			}
+			if !reflect.DeepEqual(values, wantValues) {
+				t.Errorf("codec %s, batch %t: incorrect records:\ngot  %v\nwant %v", codec, batch, values, wantValues)
+			}
+		}
+	}
+}
+
+func TestSerializerBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema(), "deflate", &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	headerLen := buf.Len()
+	for i := 0; i < 2*BlockSize+1; i++ {
+		s.Serialize(newPoint("cpu", int64(i)*1e9, []string{"hostname", "host_0"}, "usage_user", float64(i)), &buf)
+		if i == BlockSize-2 && buf.Len() != headerLen {
+			t.Errorf("block written before it had its records")
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	_, _, values, blocks, err := decode(buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	if want := []int{BlockSize, BlockSize, 1}; !reflect.DeepEqual(blocks, want) {
+		t.Errorf("incorrect blocks: got %v want %v", blocks, want)
+	}
+	if got := values[len(values)-1]["usage_user"]; got != float64(2*BlockSize) {
+		t.Errorf("incorrect last record: got %v", got)
+	}
+}
+
+func TestSerializerErrors(t *testing.T) {
+	if _, err := NewSerializer(nil, "null", ioutil.Discard); err == nil {
+		t.Errorf("no error without a schema")
+	}
+	if _, err := serialize.New("avro:zstd", testSchema(), ioutil.Discard); err == nil || !strings.Contains(err.Error(), "unknown codec 'zstd'") {
+		t.Errorf("incorrect error for an unknown codec: %v", err)
+	}
+	schema := serialize.NewSchema([][]byte{[]byte("host.name")}, map[string][][]byte{"cpu": {[]byte("host_name")}})
+	if _, err := NewSerializer(schema, "null", ioutil.Discard); err == nil || !strings.Contains(err.Error(), "duplicate field host_name") {
+		t.Errorf("incorrect error for a duplicate field: %v", err)
+	}
+
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema(), "null", &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Serialize(newPoint("mem", 0, nil, "used", 1.0), &buf); err == nil {
+		t.Errorf("no error for a measurement not in the schema")
+	}
+	if err := s.Serialize(newPoint("cpu", 0, []string{"rack", "r1"}, "usage_user", 1.0), &buf); err == nil || err.Error() != "tag rack of cpu has no field" {
+		t.Errorf("incorrect error for a tag not in the schema: %v", err)
+	}
+	if err := s.Serialize(newPoint("cpu", 0, nil, "usage_user", 1.0, "usage_idle", 2.0), &buf); err == nil || err.Error() != "field usage_idle of cpu has no field" {
+		t.Errorf("incorrect error for a field not in the schema: %v", err)
+	}
+	if err := s.Serialize(newPoint("disk-state", 0, nil, "count", int64(1), "up", "yes"), &buf); err == nil {
+		t.Errorf("no error for a string in a boolean field")
+	}
+	if err := s.Serialize(newPoint("disk-state", 0, nil, "count", 1.5), &buf); err == nil {
+		t.Errorf("no error for a float in a long field")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if _, _, values, _, err := decode(buf.Bytes()); err != nil || len(values) != 0 {
+		t.Errorf("rejected records written: %v (error %v)", values, err)
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	for name, want := range map[string]string{
+		"usage_user": "usage_user",
+		"disk-state": "disk_state",
+		"9lives":     "_9lives",
+		"":           "_",
+		"ünï":        "__n__",
+	} {
+		if got := string(AppendName(nil, []byte(name))); got != want {
+			t.Errorf("AppendName(%q): got %q want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Package bigquery implements the formats for Google BigQuery: in the
+// bigquery format rows as JSON, after a header of the schema of the table to
+// create for each measurement, and in the bigquery-ndjson format the rows of
+// a single table as newline-delimited JSON, which bq load takes with its
+// schema.
+package bigquery
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format of the loader is registered under, and
+// FormatNDJSON that of the newline-delimited JSON of a single table, of all
+// measurements. Formats of the form FormatNDJSON + ":" + measurement, e.g.,
+// bigquery-ndjson:cpu, write that of the table of a measurement.
+const (
+	Format       = "bigquery"
+	FormatNDJSON = "bigquery-ndjson"
+)
+
+// TimeColumn is the name of the column holding the timestamp of each row,
+// which tables are partitioned by, and MeasurementColumn that of the
+// measurement of each row of the table of all measurements
+const (
+	TimeColumn        = "timestamp"
+	MeasurementColumn = "measurement"
+)
+
+// timeLayout is the layout of timestamps, which BigQuery stores with
+// microsecond precision in UTC
+const timeLayout = "2006-01-02 15:04:05.000000"
+
+func init() {
+	serialize.Describe(Format, "Google BigQuery JSON rows, after a header of the schemas of the tables to create")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{}, nil
+	})
+	serialize.Describe(FormatNDJSON, "Google BigQuery newline-delimited JSON rows of a table of all measurements for bq load, with bigquery-ndjson:<measurement> for the table of a measurement")
+	serialize.Register(FormatNDJSON, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewNDJSONSerializer(schema, "")
+	})
+	serialize.RegisterScheme(FormatNDJSON, func(measurement string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewNDJSONSerializer(schema, measurement)
+	})
+	// the Header is checked by tsbs_load_bigquery
+	serialize.MarkHeaderFormat(Format)
+}
+
+// writeHeader writes the schema of the table of each measurement, one per
+// line, as its name, a comma and its fields as the BigQuery API takes them,
+// and then an empty line:
+//
+// cpu,[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},...,{"name":"usage_user","type":"FLOAT"}]
+//
+// The columns are the timestamp, the tags and then the fields of the
+// measurement.
+func writeHeader(schema *serialize.Schema, w io.Writer) error {
+	var buf []byte
+	for _, measurementName := range schema.Measurements() {
+		buf = AppendName(buf, []byte(measurementName))
+		buf = append(buf, ',')
+		buf = appendColumns(buf, schema, measurementName)
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+// appendColumns appends the columns of the table of measurementName to buf:
+// the timestamp, the tags and then the fields of the measurement
+func appendColumns(buf []byte, schema *serialize.Schema, measurementName string) []byte {
+	buf = append(buf, `[{"name":"`+TimeColumn+`","type":"TIMESTAMP","mode":"REQUIRED"}`...)
+	for _, key := range schema.TagKeysOf(measurementName) {
+		buf = appendColumn(buf, nil, key, "STRING")
+	}
+	fieldTypes := schema.FieldTypes(measurementName)
+	for i, key := range schema.FieldKeys(measurementName) {
+		t := serialize.FieldTypeUnknown
+		if i < len(fieldTypes) {
+			t = fieldTypes[i]
+		}
+		buf = appendColumn(buf, nil, key, columnType(t))
+	}
+	return append(buf, ']')
+}
+
+// appendColumn appends a column of the given type to buf, named by name or,
+// if measurementName is set, by the measurement and the field name as
+// appendFieldName names it
+func appendColumn(buf []byte, measurementName, name []byte, typ string) []byte {
+	buf = append(buf, `,{"name":"`...)
+	if measurementName != nil {
+		buf = appendFieldName(buf, measurementName, name)
+	} else {
+		buf = AppendName(buf, name)
+	}
+	buf = append(buf, `","type":"`...)
+	buf = append(buf, typ...)
+	return append(buf, `"}`...)
+}
+
+// TableSchema returns the schema of the table of the rows of the
+// FormatNDJSON format of data described by schema, as bq load takes it, or,
+// if measurement is set, of the rows of FormatNDJSON + ":" + measurement:
+//
+// [{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"measurement","type":"STRING","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},...,{"name":"cpu_usage_user","type":"FLOAT"},...]
+//
+// The columns of the table of all measurements are the timestamp, the
+// measurement, the tags of any measurement and the fields of each measurement, named by the
+// measurement and the field joined by an underscore, e.g., cpu_usage_user.
+// Those of the table of a measurement are the same as those of its table in
+// the header of the bigquery format.
+func TableSchema(schema *serialize.Schema, measurement string) ([]byte, error) {
+	if len(measurement) > 0 {
+		if !hasMeasurement(schema, measurement) {
+			return nil, fmt.Errorf("measurement %s is not in the schema", measurement)
+		}
+		return append(appendColumns(nil, schema, measurement), '\n'), nil
+	}
+	buf := []byte(`[{"name":"` + TimeColumn + `","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"` + MeasurementColumn + `","type":"STRING","mode":"REQUIRED"}`)
+	for _, key := range schema.AllTagKeys() {
+		buf = appendColumn(buf, nil, key, "STRING")
+	}
+	for _, m := range schema.Measurements() {
+		fieldTypes := schema.FieldTypes(m)
+		for i, key := range schema.FieldKeys(m) {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			buf = appendColumn(buf, []byte(m), key, columnType(t))
+		}
+	}
+	return append(buf, "]\n"...), nil
+}
+
+func hasMeasurement(schema *serialize.Schema, measurement string) bool {
+	for _, m := range schema.Measurements() {
+		if m == measurement {
+			return true
+		}
+	}
+	return false
+}
+
+// appendFieldName appends the name of the column of field fieldKey of
+// measurementName in the table of all measurements to buf: the name of the
+// measurement, as AppendName names it, an underscore and the field key, with
+// any bytes other than letters, digits and underscores replaced by
+// underscores
+func appendFieldName(buf []byte, measurementName, fieldKey []byte) []byte {
+	buf = AppendName(buf, measurementName)
+	buf = append(buf, '_')
+	for _, c := range fieldKey {
+		if !isNameChar(c) {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// columnType returns the BigQuery type of the column for fields of type t.
+// Fields of unknown type are numbers from the simulators, which fit a
+// FLOAT.
+func columnType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "INTEGER"
+	case serialize.FieldTypeBool:
+		return "BOOLEAN"
+	case serialize.FieldTypeString:
+		return "STRING"
+	default:
+		return "FLOAT"
+	}
+}
+
+// AppendName appends name to buf as a BigQuery table or column name, which
+// may only have letters, digits and underscores and may not start with a
+// digit: other bytes are replaced by underscores, and names starting with a
+// digit, or empty, are prefixed by one
+func AppendName(buf []byte, name []byte) []byte {
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		buf = append(buf, '_')
+	}
+	for _, c := range name {
+		if !isNameChar(c) {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// Serializer writes a Point in a serialized form for Google BigQuery
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a JSON row of the table of its
+// measurement, prefixed by the table name and a comma so the loader can tell
+// the tables apart:
+//
+// cpu,{"timestamp":"2016-01-01 00:00:00.000000","hostname":"host_0",...,"usage_user":58.1317132304976170,...}
+//
+// Tags with empty values are left out, so their columns are null. Infinite
+// and NaN values are written as the strings Infinity, -Infinity and NaN,
+// which BigQuery parses as floats.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = AppendName(buf, measurementName)
+	buf = append(buf, ',')
+	return appendObject(buf, measurementName, false, tagKeys, tagValues, fieldKeys, fieldValues, timestamp)
+}
+
+// appendObject appends the JSON object of a row to buf, with a column of the
+// measurement and columns of the fields named by appendFieldName if wide is
+// set, as the rows of the table of all measurements
+func appendObject(buf []byte, measurementName []byte, wide bool, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = append(buf, `{"`+TimeColumn+`":"`...)
+	buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, timeLayout)
+	buf = append(buf, '"')
+	if wide {
+		buf = append(buf, `,"`+MeasurementColumn+`":`...)
+		buf = serialize.AppendJSONString(buf, measurementName)
+	}
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		buf = append(buf, `,"`...)
+		buf = AppendName(buf, tagKeys[i])
+		buf = append(buf, `":`...)
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	for i, v := range fieldValues {
+		buf = append(buf, `,"`...)
+		if wide {
+			buf = appendFieldName(buf, measurementName, fieldKeys[i])
+		} else {
+			buf = AppendName(buf, fieldKeys[i])
+		}
+		buf = append(buf, `":`...)
+		buf = serialize.AppendJSONValue(buf, v)
+	}
+	return append(buf, "}\n"...)
+}
+
+// NDJSONSerializer writes a Point in the newline-delimited JSON of a single
+// BigQuery table
+type NDJSONSerializer struct {
+	// measurement is the measurement of the table, or empty for the table of
+	// all measurements
+	measurement string
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// NewNDJSONSerializer returns an NDJSONSerializer writing the rows of the
+// table of all measurements or, if measurement is set, of the table of
+// measurement, which must be in schema unless it is nil
+func NewNDJSONSerializer(schema *serialize.Schema, measurement string) (*NDJSONSerializer, error) {
+	if len(measurement) > 0 && schema != nil && !hasMeasurement(schema, measurement) {
+		return nil, fmt.Errorf("measurement %s is not in the schema", measurement)
+	}
+	return &NDJSONSerializer{measurement: measurement}, nil
+}
+
+// Serialize writes Point p to w as a line of the JSON object of its row, as
+// bq load --source_format=NEWLINE_DELIMITED_JSON takes it with the schema of
+// TableSchema, e.g., of the table of all measurements:
+//
+// {"timestamp":"2016-01-01 00:00:00.000000","measurement":"cpu","hostname":"host_0",...,"cpu_usage_user":58.1317132304976170,...}
+//
+// or of the table of a measurement, whose rows are those of the bigquery
+// format, without the name of the table, and from which the readings of
+// other measurements are left out:
+//
+// {"timestamp":"2016-01-01 00:00:00.000000","hostname":"host_0",...,"usage_user":58.1317132304976170,...}
+func (s *NDJSONSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendLine(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *NDJSONSerializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendLine(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf = buf
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *NDJSONSerializer) appendLine(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	if len(s.measurement) > 0 && string(measurementName) != s.measurement {
+		return buf
+	}
+	return appendObject(buf, measurementName, len(s.measurement) == 0, tagKeys, tagValues, fieldKeys, fieldValues, timestamp)
+}
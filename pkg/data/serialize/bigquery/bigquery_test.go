@@ -0,0 +1,210 @@
+package bigquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testTags = `"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `cpu,{"timestamp":"2016-01-01 00:00:00.000000",` + testTags + `,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `cpu,{"timestamp":"2016-01-01 00:00:00.000000",` + testTags + `,"usage_guest":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     `cpu,{"timestamp":"2016-01-01 00:00:00.000000",` + testTags + `,"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `cpu,{"timestamp":"2016-01-01 00:00:00.000000","usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestRegisteredWithHeader(t *testing.T) {
+	schema := serialize.NewTaggedSchema(serializetest.TagKeys[:1], map[string][][]byte{
+		"disk": {[]byte("path")},
+	}, map[string][][]byte{
+		"mem":  {[]byte("used"), []byte("free")},
+		"cpu":  {serializetest.ColFloat},
+		"disk": {[]byte("inodes-free")},
+	}, map[string][]serialize.FieldType{
+		"mem": {serialize.FieldTypeInt, serialize.FieldTypeInt},
+	})
+	want := `cpu,[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},{"name":"usage_guest_nice","type":"FLOAT"}]
+disk,[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},{"name":"path","type":"STRING"},{"name":"inodes_free","type":"FLOAT"}]
+mem,[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},{"name":"used","type":"INTEGER"},{"name":"free","type":"INTEGER"}]
+
+`
+	b := new(bytes.Buffer)
+	if _, err := serialize.New(Format, schema, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("incorrect header: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	cases := map[string]string{
+		"usage_user":                  "usage_user",
+		"inodes-free":                 "inodes_free",
+		"key with space,comma=equals": "key_with_space_comma_equals",
+		"9lives":                      "_9lives",
+		"":                            "_",
+	}
+	for name, want := range cases {
+		if got := string(AppendName(nil, []byte(name))); got != want {
+			t.Errorf("incorrect name for %q: got %q want %q", name, got, want)
+		}
+	}
+}
+
+// TestSerializerValidJSON checks that the rows of special values are JSON
+// with the tag values of the Point
+func TestSerializerValidJSON(t *testing.T) {
+	s := &Serializer{}
+	for i, p := range serializetest.FuzzPoints() {
+		var b bytes.Buffer
+		if err := s.Serialize(p, &b); err != nil {
+			t.Fatalf("point %d: unexpected error: %v", i, err)
+		}
+		row := map[string]interface{}{}
+		if err := json.Unmarshal(bytes.TrimPrefix(b.Bytes(), []byte(string(p.MeasurementName())+",")), &row); err != nil {
+			t.Errorf("point %d: invalid JSON %q: %v", i, b.String(), err)
+			continue
+		}
+		for j, v := range p.TagValues() {
+			name := string(AppendName(nil, p.TagKeys()[j]))
+			if got, ok := row[name]; len(v) > 0 && got != string(v) || len(v) == 0 && ok {
+				t.Errorf("point %d: incorrect tag value: got %q want %q", i, got, v)
+			}
+		}
+	}
+}
+
+var ndjsonCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `{"timestamp":"2016-01-01 00:00:00.000000","measurement":"cpu",` + testTags + `,"cpu_usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     `{"timestamp":"2016-01-01 00:00:00.000000","measurement":"cpu",` + testTags + `,"cpu_big_usage_guest":5000000000,"cpu_usage_guest":38,"cpu_usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"timestamp":"2016-01-01 00:00:00.000000","measurement":"cpu","cpu_usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+var ndjsonMeasurementCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `{"timestamp":"2016-01-01 00:00:00.000000",` + testTags + `,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"timestamp":"2016-01-01 00:00:00.000000","usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestNDJSONSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, ndjsonCases, &NDJSONSerializer{})
+	serializetest.CheckSerializer(t, ndjsonMeasurementCases, &NDJSONSerializer{measurement: "cpu"})
+}
+
+func TestNDJSONSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(FormatNDJSON, schema, w)
+		},
+		Golden: ndjsonCases,
+	}.Run(t)
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(FormatNDJSON+":cpu", schema, w)
+		},
+		Golden:      ndjsonMeasurementCases,
+		Measurement: "cpu",
+	}.Run(t)
+}
+
+func TestNDJSONSerializerOtherMeasurement(t *testing.T) {
+	s := &NDJSONSerializer{measurement: "mem"}
+	b := new(bytes.Buffer)
+	if err := s.Serialize(serializetest.PointDefault, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("unexpected row of another measurement: %q", b.String())
+	}
+}
+
+func TestTableSchema(t *testing.T) {
+	schema := serialize.NewTaggedSchema(serializetest.TagKeys[:1], map[string][][]byte{
+		"disk": {[]byte("path")},
+	}, map[string][][]byte{
+		"mem":  {[]byte("used")},
+		"cpu":  {serializetest.ColFloat},
+		"disk": {[]byte("inodes-free")},
+	}, map[string][]serialize.FieldType{
+		"mem": {serialize.FieldTypeInt},
+	})
+	cases := map[string]string{
+		"":    `[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"measurement","type":"STRING","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},{"name":"path","type":"STRING"},{"name":"cpu_usage_guest_nice","type":"FLOAT"},{"name":"disk_inodes_free","type":"FLOAT"},{"name":"mem_used","type":"INTEGER"}]` + "\n",
+		"mem": `[{"name":"timestamp","type":"TIMESTAMP","mode":"REQUIRED"},{"name":"hostname","type":"STRING"},{"name":"used","type":"INTEGER"}]` + "\n",
+	}
+	for measurement, want := range cases {
+		got, err := TableSchema(schema, measurement)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", measurement, err)
+		}
+		if string(got) != want {
+			t.Errorf("%q: incorrect schema: got\n%s\nwant\n%s", measurement, got, want)
+		}
+		var columns []map[string]string
+		if err := json.Unmarshal(got, &columns); err != nil {
+			t.Errorf("%q: invalid JSON: %v", measurement, err)
+		}
+	}
+
+	if _, err := TableSchema(schema, "redis"); err == nil {
+		t.Errorf("unexpected lack of error for a measurement not in the schema")
+	}
+	if _, err := serialize.New(FormatNDJSON+":redis", schema, io.Discard); err == nil {
+		t.Errorf("unexpected lack of error for a table of a measurement not in the schema")
+	}
+}
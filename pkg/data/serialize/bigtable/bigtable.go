@@ -0,0 +1,185 @@
+// Package bigtable implements the format for Google Cloud Bigtable: one row
+// per reading, keyed by its series and reversed timestamp, as the entries of
+// a MutateRows request.
+package bigtable
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "bigtable"
+
+// Column families of the table, the first holding the fields of each row and
+// the second its tags
+const (
+	FamilyFields = "fields"
+	FamilyTags   = "tags"
+)
+
+// KeySeparator separates the parts of row keys
+const KeySeparator = '#'
+
+func init() {
+	serialize.Describe(Format, "Google Cloud Bigtable rows keyed by series and reversed timestamp, as JSON MutateRows entries, one per line")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_bigtable
+	serialize.MarkHeaderFormat(Format)
+}
+
+// Serializer writes a Point in a serialized form for Google Cloud Bigtable
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and key and value hold the row key and
+	// cell value being encoded
+	buf   []byte
+	key   []byte
+	value []byte
+}
+
+// Serialize writes Point p to w as a Bigtable row: a cell in the fields
+// family for each field and one in the tags family for each tag. The row is
+// JSON in the form the entries of the MutateRows API take, with the row key,
+// column qualifiers and values in base64, so the loader sends the lines as
+// they are, e.g.:
+//
+// {"rowKey":"Y3B1I2hvc3RfMC...","mutations":[{"setCell":{"familyName":"fields","columnQualifier":"dXNhZ2VfdXNlcg==","timestampMicros":"1451606400000000","value":"QE0Qw..."}},...]}
+//
+// The row key is the series, the measurement and tag values separated by #,
+// then the reversed timestamp, the nanoseconds before the largest int64,
+// zero-padded so that the latest reading of a series sorts first:
+//
+// cpu#host_0#eu-west-1#...#7771765636854775807
+//
+// Float and int values are 8 bytes big-endian, bools a single byte and
+// strings their bytes. Cell timestamps are the milliseconds of the reading,
+// the granularity Bigtable tables have by default. Tags with empty values
+// have no cell.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	s.key = AppendRowKey(s.key[:0], measurementName, tagValues, timestamp)
+	var m [20]byte
+	micros := strconv.AppendInt(m[:0], timestamp/1e6*1e3, 10)
+
+	buf = append(buf, `{"rowKey":"`...)
+	buf = appendBase64(buf, s.key)
+	buf = append(buf, `","mutations":[`...)
+	first := true
+	for i, v := range fieldValues {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		s.value = AppendValue(s.value[:0], v)
+		buf = appendSetCell(buf, FamilyFields, fieldKeys[i], micros, s.value)
+	}
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = appendSetCell(buf, FamilyTags, tagKeys[i], micros, v)
+	}
+	return append(buf, "]}\n"...)
+}
+
+func appendSetCell(buf []byte, family string, qualifier, micros, value []byte) []byte {
+	buf = append(buf, `{"setCell":{"familyName":"`...)
+	buf = append(buf, family...)
+	buf = append(buf, `","columnQualifier":"`...)
+	buf = appendBase64(buf, qualifier)
+	buf = append(buf, `","timestampMicros":"`...)
+	buf = append(buf, micros...)
+	buf = append(buf, `","value":"`...)
+	buf = appendBase64(buf, value)
+	return append(buf, `"}}`...)
+}
+
+// AppendRowKey appends the row key of a reading to buf: the measurement and
+// tag values separated by #, then the reversed timestamp
+func AppendRowKey(buf []byte, measurementName []byte, tagValues [][]byte, timestamp int64) []byte {
+	buf = append(buf, measurementName...)
+	for _, v := range tagValues {
+		buf = append(buf, KeySeparator)
+		buf = append(buf, v...)
+	}
+	buf = append(buf, KeySeparator)
+	reversed := strconv.FormatInt(math.MaxInt64-timestamp, 10)
+	for i := len(reversed); i < 19; i++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, reversed...)
+}
+
+// AppendValue appends the bytes of a field value to buf
+func AppendValue(buf []byte, v interface{}) []byte {
+	var b [8]byte
+	switch x := v.(type) {
+	case float64:
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(x))
+	case float32:
+		binary.BigEndian.PutUint64(b[:], math.Float64bits(float64(x)))
+	case int:
+		binary.BigEndian.PutUint64(b[:], uint64(x))
+	case int64:
+		binary.BigEndian.PutUint64(b[:], uint64(x))
+	case bool:
+		if x {
+			return append(buf, 1)
+		}
+		return append(buf, 0)
+	case []byte:
+		return append(buf, x...)
+	case string:
+		return append(buf, x...)
+	default:
+		return serialize.FastFormatAppend(v, buf)
+	}
+	return append(buf, b[:]...)
+}
+
+// appendBase64 appends s to buf in standard base64, which JSON bytes fields
+// take
+func appendBase64(buf []byte, s []byte) []byte {
+	n := base64.StdEncoding.EncodedLen(len(s))
+	start := len(buf)
+	if cap(buf)-start < n {
+		grown := make([]byte, start, 2*cap(buf)+n)
+		copy(grown, buf)
+		buf = grown
+	}
+	buf = buf[:start+n]
+	base64.StdEncoding.Encode(buf[start:], s)
+	return buf
+}
@@ -0,0 +1,154 @@
+package bigtable
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+// the row key cpu#host_0#eu-west-1#eu-west-1b#7771765636854775807 and cells
+// of the tags of the fixture Points, in base64
+const (
+	testRowKey = `"rowKey":"Y3B1I2hvc3RfMCNldS13ZXN0LTEjZXUtd2VzdC0xYiM3NzcxNzY1NjM2ODU0Nzc1ODA3"`
+	testTags   = `{"setCell":{"familyName":"tags","columnQualifier":"aG9zdG5hbWU=","timestampMicros":"1451606400000000","value":"aG9zdF8w"}},` +
+		`{"setCell":{"familyName":"tags","columnQualifier":"cmVnaW9u","timestampMicros":"1451606400000000","value":"ZXUtd2VzdC0x"}},` +
+		`{"setCell":{"familyName":"tags","columnQualifier":"ZGF0YWNlbnRlcg==","timestampMicros":"1451606400000000","value":"ZXUtd2VzdC0xYg=="}}`
+	testFloatCell = `{"setCell":{"familyName":"fields","columnQualifier":"dXNhZ2VfZ3Vlc3RfbmljZQ==","timestampMicros":"1451606400000000","value":"QEMfHoAITgI="}}`
+	testIntCell   = `{"setCell":{"familyName":"fields","columnQualifier":"dXNhZ2VfZ3Vlc3Q=","timestampMicros":"1451606400000000","value":"AAAAAAAAACY="}}`
+	testInt64Cell = `{"setCell":{"familyName":"fields","columnQualifier":"YmlnX3VzYWdlX2d1ZXN0","timestampMicros":"1451606400000000","value":"AAAAASoF8gA="}}`
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `{` + testRowKey + `,"mutations":[` + testFloatCell + `,` + testTags + `]}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `{` + testRowKey + `,"mutations":[` + testIntCell + `,` + testTags + `]}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     `{` + testRowKey + `,"mutations":[` + testInt64Cell + `,` + testIntCell + `,` + testFloatCell + `,` + testTags + `]}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"rowKey":"Y3B1Izc3NzE3NjU2MzY4NTQ3NzU4MDc=","mutations":[` + testFloatCell + `]}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+		Plain:  cellValues,
+	}.Run(t)
+}
+
+// cellValues returns the values of the cells of the entries in data, one
+// after the other
+func cellValues(data []byte) ([]byte, error) {
+	var out []byte
+	d := json.NewDecoder(bytes.NewReader(data))
+	for d.More() {
+		var e entry
+		if err := d.Decode(&e); err != nil {
+			return nil, err
+		}
+		for _, m := range e.Mutations {
+			out = append(out, m.SetCell.Value...)
+		}
+	}
+	return out, nil
+}
+
+func TestAppendRowKey(t *testing.T) {
+	tags := [][]byte{[]byte("host_0"), []byte("eu-west-1")}
+	older := string(AppendRowKey(nil, []byte("cpu"), tags, 1000))
+	newer := string(AppendRowKey(nil, []byte("cpu"), tags, 2000))
+	if older != "cpu#host_0#eu-west-1#9223372036854774807" {
+		t.Errorf("incorrect row key: %s", older)
+	}
+	if newer >= older {
+		t.Errorf("newer row key does not sort first: %s >= %s", newer, older)
+	}
+	if got := string(AppendRowKey(nil, []byte("cpu"), nil, math.MaxInt64)); got != "cpu#0000000000000000000" {
+		t.Errorf("row key not zero-padded: %s", got)
+	}
+}
+
+func TestAppendValue(t *testing.T) {
+	if got := AppendValue(nil, -1.5); math.Float64frombits(binary.BigEndian.Uint64(got)) != -1.5 {
+		t.Errorf("incorrect float value: %x", got)
+	}
+	if got := AppendValue(nil, int64(-2)); int64(binary.BigEndian.Uint64(got)) != -2 {
+		t.Errorf("incorrect int value: %x", got)
+	}
+	if got := AppendValue(nil, true); !bytes.Equal(got, []byte{1}) {
+		t.Errorf("incorrect bool value: %x", got)
+	}
+	if got := AppendValue(nil, "ok"); string(got) != "ok" {
+		t.Errorf("incorrect string value: %x", got)
+	}
+}
+
+// entry is a MutateRows entry as the API takes it
+type entry struct {
+	RowKey    []byte
+	Mutations []struct {
+		SetCell struct {
+			FamilyName      string
+			ColumnQualifier []byte
+			TimestampMicros string
+			Value           []byte
+		}
+	}
+}
+
+// TestSerializerValidJSON checks that the rows of special values are JSON
+// with the tag values of the Point, in the tags family
+func TestSerializerValidJSON(t *testing.T) {
+	s := &Serializer{}
+	for i, p := range serializetest.FuzzPoints() {
+		var b bytes.Buffer
+		if err := s.Serialize(p, &b); err != nil {
+			t.Fatalf("point %d: unexpected error: %v", i, err)
+		}
+		var e entry
+		if err := json.Unmarshal(b.Bytes(), &e); err != nil {
+			t.Errorf("point %d: invalid JSON %q: %v", i, b.String(), err)
+			continue
+		}
+		if !strings.HasPrefix(string(e.RowKey), string(p.MeasurementName())+"#") {
+			t.Errorf("point %d: incorrect row key %q", i, e.RowKey)
+		}
+		tags := map[string]string{}
+		for _, m := range e.Mutations {
+			if m.SetCell.FamilyName == FamilyTags {
+				tags[string(m.SetCell.ColumnQualifier)] = string(m.SetCell.Value)
+			}
+		}
+		for j, v := range p.TagValues() {
+			if got, ok := tags[string(p.TagKeys()[j])]; len(v) > 0 && got != string(v) || len(v) == 0 && ok {
+				t.Errorf("point %d: incorrect tag value: got %q want %q", i, got, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package cassandra
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "cassandra"
+
+func init() {
+	serialize.Describe(Format, "Cassandra CQL INSERT statements, one table per field type")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_cassandra
+	serialize.MarkHeaderFormat(Format)
+}
+
+// Serializer writes a Point in a serialized form for Cassandra
+type Serializer struct {
+	// buf is scratch space reused between calls to Serialize so that each
+	// Point is built in memory and written with a single call
+	buf []byte
+}
+
+// Serialize writes Point data to the given writer, conforming to the
+// Cassandra format.
+//
+// This function writes output that looks like:
+// series_double,cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,rack=67,os=Ubuntu16.10,arch=x86,team=NYC,service=7,service_version=0,service_environment=production,usage_guest_nice,2016-01-01,1451606400000000000,38.2431182911542820
+//
+// Which the loader will decode into a statement that looks like this:
+// INSERT INTO series_double(series_id,timestamp_ns,value) VALUES('cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,rack=67,os=Ubuntu16.10,arch=x86,team=NYC,service=7,service_version=0,service_environment=production#usage_guest_nice#2016-01-01', 1451606400000000000, 38.2431182911542820)
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) (err error) {
+	buf := s.buf[:0]
+
+	// The series ID prefix is shared by every row, so build it once at the
+	// start of buf and copy it for each field.
+	buf = append(buf, p.MeasurementName()...)
+	for i := 0; i < len(p.TagKeys()); i++ {
+		buf = append(buf, ',')
+		buf = append(buf, p.TagKeys()[i]...)
+		buf = append(buf, '=')
+		buf = append(buf, p.TagValues()[i]...)
+	}
+	prefixLen := len(buf)
+
+	// The same goes for the timestamp suffix of the series ID and the timestamp
+	buf = append(buf, ',')
+	buf = time.Unix(0, p.Timestamp()).UTC().AppendFormat(buf, "2006-01-02")
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, p.Timestamp(), 10)
+	buf = append(buf, ',')
+	timeEnd := len(buf)
+	rowsStart := timeEnd
+
+	for fieldID := 0; fieldID < len(p.FieldKeys()); fieldID++ {
+		value := p.FieldValues()[fieldID]
+
+		buf = append(buf, "series_"...)
+		buf = append(buf, typeNameForCassandra(value)...)
+		buf = append(buf, ',')
+		buf = append(buf, buf[:prefixLen]...)
+		buf = append(buf, ',')
+		buf = append(buf, p.FieldKeys()[fieldID]...)
+		buf = append(buf, buf[prefixLen:timeEnd]...)
+		buf = serialize.FastFormatAppend(value, buf)
+		buf = append(buf, '\n')
+	}
+	s.buf = buf
+
+	_, err = w.Write(buf[rowsStart:])
+	return err
+}
+
+func typeNameForCassandra(v interface{}) string {
+	switch v.(type) {
+	case int, int64:
+		return "bigint"
+	case float64:
+		return "double"
+	case float32:
+		return "float"
+	case bool:
+		return "boolean"
+	case []byte, string:
+		return "blob"
+	default:
+		panic(fmt.Sprintf("unknown field type for %#v", v))
+	}
+}
@@ -0,0 +1,140 @@
+package cassandra
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "series_double,cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,usage_guest_nice,2016-01-01,1451606400000000000,38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "series_bigint,cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,usage_guest,2016-01-01,1451606400000000000,38\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: "series_bigint,cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,big_usage_guest,2016-01-01,1451606400000000000,5000000000\n" +
+			"series_bigint,cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,usage_guest,2016-01-01,1451606400000000000,38\n" +
+			"series_double,cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b,usage_guest_nice,2016-01-01,1451606400000000000,38.24311829\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "series_double,cpu,usage_guest_nice,2016-01-01,1451606400000000000,38.24311829\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerSerializeReuse(t *testing.T) {
+	s := &Serializer{}
+	b := new(bytes.Buffer)
+	s.Serialize(serializetest.PointMultiField, b)
+	b.Reset()
+	s.Serialize(serializetest.PointNoTags, b)
+	want := "series_double,cpu,usage_guest_nice,2016-01-01,1451606400000000000,38.24311829\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect output after reuse: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSerializerSerializeErr(t *testing.T) {
+	p := serializetest.PointMultiField
+	s := &Serializer{}
+	err := s.Serialize(p, &serializetest.ErrWriter{})
+	if err == nil {
+		t.Errorf("no error returned when expected")
+	} else if err.Error() != serializetest.ErrWriterAlwaysErr {
+		t.Errorf("unexpected writer error: %v", err)
+	}
+}
+
+func TestTypeNameForCassandra(t *testing.T) {
+	cases := []struct {
+		desc        string
+		v           interface{}
+		want        string
+		shouldPanic bool
+	}{
+		{
+			desc: "type int",
+			v:    int(5),
+			want: "bigint",
+		},
+		{
+			desc: "type int64",
+			v:    int(5000000000),
+			want: "bigint",
+		},
+		{
+			desc: "type float32",
+			v:    float32(3.2),
+			want: "float",
+		},
+		{
+			desc: "type float64",
+			v:    float64(3.23234545234),
+			want: "double",
+		},
+		{
+			desc: "type bool",
+			v:    true,
+			want: "boolean",
+		},
+		{
+			desc: "type []byte",
+			v:    []byte("test"),
+			want: "blob",
+		},
+		{
+			desc: "type string",
+			v:    "test",
+			want: "blob",
+		},
+		{
+			desc:        "unknown type",
+			v:           []float64{},
+			shouldPanic: true,
+		},
+	}
+	testPanic := func(v interface{}) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("did not panic when should")
+			}
+		}()
+		_ = typeNameForCassandra(v)
+	}
+
+	for _, c := range cases {
+		if c.shouldPanic {
+			testPanic(c.v)
+			continue
+		}
+
+		if got := typeNameForCassandra(c.v); got != c.want {
+			t.Errorf("%s: incorrect type name: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package cratedb implements the format for CrateDB: JSON rows, as COPY FROM
+// reads them, after a header of the statements creating the tables.
+package cratedb
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "cratedb"
+
+// Columns of every table: the time of each row, in milliseconds, and its
+// tags, as an OBJECT with a column for each tag key
+const (
+	TimeColumn = "ts"
+	TagsColumn = "tags"
+)
+
+func init() {
+	serialize.Describe(Format, "CrateDB JSON rows for COPY FROM, after a header of the statements creating the tables")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{}, nil
+	})
+}
+
+// writeHeader writes the statement creating the table of each measurement,
+// one per line, and then an empty line:
+//
+// CREATE TABLE IF NOT EXISTS "cpu" ("ts" TIMESTAMP WITH TIME ZONE, "tags" OBJECT(DYNAMIC) AS ("hostname" TEXT, ...), "usage_user" DOUBLE PRECISION, ...);
+//
+// The tables have the time, the tags and then the fields of the
+// measurement.
+func writeHeader(schema *serialize.Schema, w io.Writer) error {
+	var buf []byte
+	for _, measurementName := range schema.Measurements() {
+		buf = append(buf, `CREATE TABLE IF NOT EXISTS "`...)
+		buf = AppendName(buf, []byte(measurementName))
+		buf = append(buf, `" ("`+TimeColumn+`" TIMESTAMP WITH TIME ZONE, "`+TagsColumn+`" OBJECT(DYNAMIC)`...)
+		tagKeys := schema.TagKeysOf(measurementName)
+		for i, key := range tagKeys {
+			if i == 0 {
+				buf = append(buf, " AS ("...)
+			} else {
+				buf = append(buf, ", "...)
+			}
+			buf = appendColumn(buf, key, "TEXT")
+		}
+		if len(tagKeys) > 0 {
+			buf = append(buf, ')')
+		}
+		fieldTypes := schema.FieldTypes(measurementName)
+		for i, key := range schema.FieldKeys(measurementName) {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			buf = append(buf, ", "...)
+			buf = appendColumn(buf, key, columnType(t))
+		}
+		buf = append(buf, ");\n"...)
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendColumn(buf []byte, name []byte, typ string) []byte {
+	buf = append(buf, '"')
+	buf = AppendName(buf, name)
+	buf = append(buf, `" `...)
+	return append(buf, typ...)
+}
+
+// columnType returns the CrateDB type of the column for fields of type t.
+// Fields of unknown type are numbers from the simulators, which fit a
+// DOUBLE PRECISION.
+func columnType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "BIGINT"
+	case serialize.FieldTypeBool:
+		return "BOOLEAN"
+	case serialize.FieldTypeString:
+		return "TEXT"
+	default:
+		return "DOUBLE PRECISION"
+	}
+}
+
+// Serializer writes a Point in a serialized form for CrateDB
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a JSON row of the table of its
+// measurement, prefixed by the table name and a tab so the rows of each
+// table can be told apart, e.g.,
+//
+// cpu	{"ts":1451606400000,"tags":{"hostname":"host_0","region":"eu-west-1"},"usage_user":58.13}
+//
+// The JSON is a row as COPY FROM reads it. Tags with empty values are left
+// out, so they are NULL, as are NaN and infinite values, which JSON does
+// not have.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = AppendName(buf, measurementName)
+	buf = append(buf, "\t{\""+TimeColumn+"\":"...)
+	millis := timestamp / 1e6
+	if timestamp < 0 && timestamp%1e6 != 0 {
+		millis--
+	}
+	buf = strconv.AppendInt(buf, millis, 10)
+	buf = append(buf, `,"`+TagsColumn+`":{`...)
+	first := true
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, '"')
+		buf = AppendName(buf, tagKeys[i])
+		buf = append(buf, `":`...)
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	buf = append(buf, '}')
+	for i, v := range fieldValues {
+		buf = append(buf, `,"`...)
+		buf = AppendName(buf, fieldKeys[i])
+		buf = append(buf, `":`...)
+		buf = appendJSONValue(buf, v)
+	}
+	return append(buf, "}\n"...)
+}
+
+// appendJSONValue appends a field value to buf as JSON, with NaN and
+// infinite values as null
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return append(buf, "null"...)
+		}
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return append(buf, "null"...)
+		}
+	}
+	return serialize.AppendJSONValue(buf, v)
+}
+
+// AppendName appends the name of a table or column to buf: name with the
+// characters CrateDB does not allow in them, and those that would need
+// escaping in JSON or quoted identifiers, replaced by underscores. Names
+// starting with an underscore, which CrateDB reserves for system columns,
+// are prefixed by an x, and empty names are x.
+func AppendName(buf []byte, name []byte) []byte {
+	if len(name) == 0 || name[0] == '_' {
+		buf = append(buf, 'x')
+	}
+	for _, c := range name {
+		if c <= ' ' || c == 0x7f || strings.IndexByte(`.[]"'\/*?<>|,#:`, c) >= 0 {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
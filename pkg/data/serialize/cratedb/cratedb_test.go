@@ -0,0 +1,139 @@
+package cratedb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testTags = `"tags":{"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"}`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "cpu\t{\"ts\":1451606400000," + testTags + ",\"usage_guest_nice\":38.24311829}\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "cpu\t{\"ts\":1451606400000," + testTags + ",\"usage_guest\":38}\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "cpu\t{\"ts\":1451606400000," + testTags + ",\"big_usage_guest\":5000000000,\"usage_guest\":38,\"usage_guest_nice\":38.24311829}\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "cpu\t{\"ts\":1451606400000,\"tags\":{},\"usage_guest_nice\":38.24311829}\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestRegisteredWithHeader(t *testing.T) {
+	schema := serialize.NewTaggedSchema(serializetest.TagKeys[:2], map[string][][]byte{
+		"disk.io": {[]byte("device")},
+	}, map[string][][]byte{
+		"mem":     {[]byte("used"), []byte("swapping"), []byte("state")},
+		"disk.io": {[]byte("_reads")},
+		"cpu":     {serializetest.ColFloat},
+	}, map[string][]serialize.FieldType{
+		"mem": {serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString},
+	})
+	want := `CREATE TABLE IF NOT EXISTS "cpu" ("ts" TIMESTAMP WITH TIME ZONE, "tags" OBJECT(DYNAMIC) AS ("hostname" TEXT, "region" TEXT), "usage_guest_nice" DOUBLE PRECISION);` + "\n" +
+		`CREATE TABLE IF NOT EXISTS "disk_io" ("ts" TIMESTAMP WITH TIME ZONE, "tags" OBJECT(DYNAMIC) AS ("hostname" TEXT, "region" TEXT, "device" TEXT), "x_reads" DOUBLE PRECISION);` + "\n" +
+		`CREATE TABLE IF NOT EXISTS "mem" ("ts" TIMESTAMP WITH TIME ZONE, "tags" OBJECT(DYNAMIC) AS ("hostname" TEXT, "region" TEXT), "used" BIGINT, "swapping" BOOLEAN, "state" TEXT);` + "\n\n"
+	b := new(bytes.Buffer)
+	if _, err := serialize.New(Format, schema, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("incorrect header: got\n%s\nwant\n%s", got, want)
+	}
+
+	b.Reset()
+	if _, err := serialize.New(Format, serialize.NewSchema(nil, map[string][][]byte{"cpu": {serializetest.ColFloat}}), b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = `CREATE TABLE IF NOT EXISTS "cpu" ("ts" TIMESTAMP WITH TIME ZONE, "tags" OBJECT(DYNAMIC), "usage_guest_nice" DOUBLE PRECISION);` + "\n\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect header without tags: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSerializerSpecialValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("mem"))
+	p.SetTimestamp(-1)
+	p.AppendTag([]byte("host.name"), []byte("say \"hi\"\n"))
+	p.AppendTag([]byte("region"), nil)
+	p.AppendField([]byte("nan"), math.NaN())
+	p.AppendField([]byte("inf"), math.Inf(-1))
+	p.AppendField([]byte("on"), true)
+	p.AppendField([]byte("s"), "a\\b")
+	b := new(bytes.Buffer)
+	if err := (&Serializer{}).Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "mem\t{\"ts\":-1,\"tags\":{\"host_name\":\"say \\\"hi\\\"\\u000a\"},\"nan\":null,\"inf\":null,\"on\":true,\"s\":\"a\\\\b\"}\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect row: got %q want %q", got, want)
+	}
+}
+
+func TestSerializerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Serializer{}
+	for _, p := range serializetest.FuzzPoints() {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		tab := strings.IndexByte(line, '\t')
+		var row map[string]interface{}
+		if tab < 0 {
+			t.Errorf("line %d: no table name in %q", i, line)
+		} else if err := json.Unmarshal([]byte(line[tab+1:]), &row); err != nil {
+			t.Errorf("line %d: invalid JSON %q: %v", i, line, err)
+		}
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	cases := []struct {
+		name, want string
+	}{
+		{"usage_user", "usage_user"},
+		{"disk.io", "disk_io"},
+		{`a"b\c[d]e f/g`, "a_b_c_d_e_f_g"},
+		{"_id", "x_id"},
+		{"ünïcödé", "ünïcödé"},
+		{"", "x"},
+	}
+	for _, c := range cases {
+		if got := string(AppendName(nil, []byte(c.name))); got != c.want {
+			t.Errorf("incorrect name of %q: got %q want %q", c.name, got, c.want)
+		}
+	}
+}
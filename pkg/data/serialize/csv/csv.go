@@ -0,0 +1,464 @@
+// Package csv implements a generic CSV format, for databases TSBS has no
+// format of their own for: a wide CSV with a row per reading, or a long CSV
+// with a row per field of each reading, with options for the delimiter, the
+// header, the format of timestamps and how tags are written.
+package csv
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats with NaN and infinite values as NaN, +Inf and -Inf
+var floatFormat = &serialize.FloatFormat{Fmt: 'g', NaN: "NaN", Inf: "+Inf", NegInf: "-Inf"}
+
+// Format is the name the format is registered under, which writes CSV with
+// the DefaultOptions. Formats of the form Format + ":" + options, e.g.,
+// csv:layout=long,delimiter=tab, write CSV with options, see ParseOptions.
+const Format = "csv"
+
+// Layouts of the rows: a row per reading, with a column per field, or a row
+// per field of each reading, with its name and value
+const (
+	LayoutWide = "wide"
+	LayoutLong = "long"
+)
+
+// Ways tags are written: a column per tag key, a single column of a JSON
+// object of them, or not at all
+const (
+	TagsColumns = "columns"
+	TagsJSON    = "json"
+	TagsNone    = "none"
+)
+
+// Formats of timestamps, besides Go time layouts: integers in nanoseconds,
+// microseconds, milliseconds or seconds since the epoch
+const (
+	TimeNanoseconds  = "ns"
+	TimeMicroseconds = "us"
+	TimeMilliseconds = "ms"
+	TimeSeconds      = "s"
+)
+
+// Columns of the time, the measurement and the tags of each reading, and
+// the field and value of the rows of the long layout
+const (
+	TimeColumn        = "timestamp"
+	MeasurementColumn = "measurement"
+	TagsColumn        = "tags"
+	FieldColumn       = "field"
+	ValueColumn       = "value"
+)
+
+// delimiters are the names of delimiters of options, for those that are
+// awkward on the command line or separate the options
+var delimiters = map[string]byte{"comma": ',', "tab": '\t', "semicolon": ';', "pipe": '|', "space": ' '}
+
+// timeLayouts are the names of Go time layouts of options
+var timeLayouts = map[string]string{"rfc3339": time.RFC3339, "rfc3339nano": time.RFC3339Nano}
+
+func init() {
+	serialize.Describe(Format, "CSV with a header, wide or long, with csv:<option>=<value>,... setting layout, delimiter, header, time, tags and measurement")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultOptions(), w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		opts, err := ParseOptions(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSerializer(schema, opts, w)
+	})
+}
+
+// Options are the options of the CSV written
+type Options struct {
+	// Layout is LayoutWide or LayoutLong
+	Layout string
+	// Delimiter separates the columns of each row
+	Delimiter byte
+	// Header is whether the CSV starts with a row of the names of the
+	// columns
+	Header bool
+	// Time is the format of timestamps: one of TimeNanoseconds,
+	// TimeMicroseconds, TimeMilliseconds or TimeSeconds, or a Go time
+	// layout, in which timestamps are written in UTC
+	Time string
+	// Tags is TagsColumns, TagsJSON or TagsNone
+	Tags string
+	// Measurement, if set, makes the CSV that of that measurement only, with
+	// no column of the measurement and the readings of other measurements
+	// left out
+	Measurement string
+}
+
+// DefaultOptions returns the options of Format: a wide CSV, with a header,
+// commas as delimiters, timestamps in RFC 3339 with nanoseconds and a column
+// per tag key
+func DefaultOptions() Options {
+	return Options{
+		Layout:    LayoutWide,
+		Delimiter: ',',
+		Header:    true,
+		Time:      time.RFC3339Nano,
+		Tags:      TagsColumns,
+	}
+}
+
+// ParseOptions returns the DefaultOptions changed by s, a comma separated
+// list of option=value, of the options:
+//
+//   - layout: wide or long
+//   - delimiter: comma, tab, semicolon, pipe, space or any other single
+//     character
+//   - header: true or false
+//   - time: ns, us, ms or s for integers since the epoch, rfc3339 or
+//     rfc3339nano, or a Go time layout without commas
+//   - tags: columns, json or none
+//   - measurement: the name of the measurement of the CSV
+func ParseOptions(s string) (Options, error) {
+	opts := DefaultOptions()
+	for _, option := range strings.Split(s, ",") {
+		i := strings.IndexByte(option, '=')
+		if i < 0 {
+			return opts, fmt.Errorf("invalid CSV option '%s': must be option=value", option)
+		}
+		name, value := option[:i], option[i+1:]
+		switch name {
+		case "layout":
+			if value != LayoutWide && value != LayoutLong {
+				return opts, fmt.Errorf("invalid CSV layout '%s': must be %s or %s", value, LayoutWide, LayoutLong)
+			}
+			opts.Layout = value
+		case "delimiter":
+			if d, ok := delimiters[value]; ok {
+				opts.Delimiter = d
+			} else if len(value) == 1 && value != `"` && value != "\n" && value != "\r" {
+				opts.Delimiter = value[0]
+			} else {
+				return opts, fmt.Errorf("invalid CSV delimiter '%s': must be a single character or one of %s", value, strings.Join(names(delimiters), ", "))
+			}
+		case "header":
+			header, err := strconv.ParseBool(value)
+			if err != nil {
+				return opts, fmt.Errorf("invalid CSV header '%s': must be true or false", value)
+			}
+			opts.Header = header
+		case "time":
+			if layout, ok := timeLayouts[value]; ok {
+				value = layout
+			}
+			if len(value) == 0 {
+				return opts, fmt.Errorf("invalid CSV time format: must not be empty")
+			}
+			opts.Time = value
+		case "tags":
+			if value != TagsColumns && value != TagsJSON && value != TagsNone {
+				return opts, fmt.Errorf("invalid CSV tags '%s': must be %s, %s or %s", value, TagsColumns, TagsJSON, TagsNone)
+			}
+			opts.Tags = value
+		case "measurement":
+			opts.Measurement = value
+		default:
+			return opts, fmt.Errorf("unknown CSV option '%s': must be layout, delimiter, header, time, tags or measurement", name)
+		}
+	}
+	return opts, nil
+}
+
+func names(m map[string]byte) []string {
+	var names []string
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Serializer writes a Point in a serialized form as CSV
+type Serializer struct {
+	opts Options
+	// tags are the columns of the tag keys, and fields those of the field
+	// keys of each measurement, for the wide layout, counted from the first
+	// column of fields
+	tags    map[string]int
+	fields  map[string]map[string]int
+	columns int
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and tagValues and fieldValues are the
+	// values of the columns of the row being written
+	buf         []byte
+	tagValues   [][]byte
+	fieldValues []interface{}
+}
+
+// NewSerializer returns a Serializer for data described by schema, writing
+// CSV with opts. If opts has a header, it writes the header to w.
+//
+// Rows have a column for each tag key of any measurement, or of
+// Options.Measurement, unless the tags are JSON or left out. Rows of the
+// wide layout have the time, measurement and tags of a reading and then a column for each field of each measurement, named
+// <measurement>_<field>, empty for the fields of other measurements, or a
+// column for each field of Options.Measurement, named for the field. Rows of
+// the long layout have the time, measurement, tags, and then the name and
+// value of a field.
+func NewSerializer(schema *serialize.Schema, opts Options, w io.Writer) (*Serializer, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("CSV needs the schema of the data")
+	}
+	s := &Serializer{
+		opts:   opts,
+		tags:   make(map[string]int),
+		fields: make(map[string]map[string]int),
+	}
+	var header []byte
+	header = s.appendColumn(header, []byte(TimeColumn))
+	if len(opts.Measurement) == 0 {
+		header = s.appendColumn(header, []byte(MeasurementColumn))
+	}
+	switch opts.Tags {
+	case TagsColumns:
+		tagKeys := schema.AllTagKeys()
+		if len(opts.Measurement) > 0 {
+			tagKeys = schema.TagKeysOf(opts.Measurement)
+		}
+		for i, key := range tagKeys {
+			s.tags[string(key)] = i
+			header = s.appendColumn(header, key)
+		}
+	case TagsJSON:
+		header = s.appendColumn(header, []byte(TagsColumn))
+	}
+	if opts.Layout == LayoutLong {
+		header = s.appendColumn(header, []byte(FieldColumn))
+		header = s.appendColumn(header, []byte(ValueColumn))
+	} else {
+		for _, measurementName := range schema.Measurements() {
+			if len(opts.Measurement) > 0 && measurementName != opts.Measurement {
+				continue
+			}
+			fields := make(map[string]int)
+			for _, key := range schema.FieldKeys(measurementName) {
+				fields[string(key)] = s.columns
+				s.columns++
+				if len(opts.Measurement) > 0 {
+					header = s.appendColumn(header, key)
+				} else {
+					header = s.appendColumn(header, []byte(measurementName+"_"+string(key)))
+				}
+			}
+			s.fields[measurementName] = fields
+		}
+	}
+	if !opts.Header {
+		return s, nil
+	}
+	header[len(header)-1] = '\n'
+	_, err := w.Write(header)
+	return s, err
+}
+
+// Serialize writes Point p to w as CSV: a row for the reading in the wide
+// layout, or a row for each field in the long layout, e.g., with the
+// DefaultOptions,
+//
+// 2016-01-01T00:00:00Z,cpu,host_0,eu-west-1,58.13,2.6,,
+//
+// Values with the delimiter, quotes or line breaks are quoted. Tags with
+// empty values, and fields with nil values, are empty. Floats are written
+// as the shortest decimal that is them, and NaN and infinite values as NaN,
+// +Inf and -Inf. Tags that are not in the Schema, so have no column, are an
+// error, as are such fields in the wide layout.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf, err := s.appendRows(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	var err error
+	for i := 0; i < b.Len() && err == nil; i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf, err = s.appendRows(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func (s *Serializer) appendRows(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) ([]byte, error) {
+	if len(s.opts.Measurement) > 0 && string(measurementName) != s.opts.Measurement {
+		return buf, nil
+	}
+	if s.opts.Tags == TagsColumns {
+		for _, key := range tagKeys {
+			if _, ok := s.tags[string(key)]; !ok {
+				return buf, fmt.Errorf("tag %s of %s has no column", key, measurementName)
+			}
+		}
+	}
+	fields := s.fields[string(measurementName)]
+	if s.opts.Layout != LayoutLong {
+		for _, key := range fieldKeys {
+			if _, ok := fields[string(key)]; !ok {
+				return buf, fmt.Errorf("field %s of %s has no column", key, measurementName)
+			}
+		}
+	}
+	// the time, measurement and tags that start every row
+	start := len(buf)
+	buf = s.appendTime(buf, timestamp)
+	buf = append(buf, s.opts.Delimiter)
+	if len(s.opts.Measurement) == 0 {
+		buf = s.appendColumn(buf, measurementName)
+	}
+	switch s.opts.Tags {
+	case TagsColumns:
+		s.tagValues = s.tagValues[:0]
+		for range s.tags {
+			s.tagValues = append(s.tagValues, nil)
+		}
+		for i, key := range tagKeys {
+			if j := s.tags[string(key)]; s.tagValues[j] == nil {
+				s.tagValues[j] = tagValues[i]
+			}
+		}
+		for _, v := range s.tagValues {
+			buf = s.appendColumn(buf, v)
+		}
+	case TagsJSON:
+		tags := []byte{'{'}
+		for i, v := range tagValues {
+			if len(v) == 0 {
+				continue
+			}
+			if len(tags) > 1 {
+				tags = append(tags, ',')
+			}
+			tags = serialize.AppendJSONString(tags, tagKeys[i])
+			tags = append(tags, ':')
+			tags = serialize.AppendJSONString(tags, v)
+		}
+		buf = s.appendColumn(buf, append(tags, '}'))
+	}
+
+	if s.opts.Layout == LayoutLong {
+		prefix := buf[start:]
+		for i, v := range fieldValues {
+			if i > 0 {
+				buf = append(buf, prefix...)
+			}
+			buf = s.appendColumn(buf, fieldKeys[i])
+			buf = s.appendValue(buf, v)
+			buf[len(buf)-1] = '\n'
+		}
+		if len(fieldValues) == 0 {
+			buf = buf[:start]
+		}
+		return buf, nil
+	}
+
+	s.fieldValues = s.fieldValues[:0]
+	for i := 0; i < s.columns; i++ {
+		s.fieldValues = append(s.fieldValues, nil)
+	}
+	for i, key := range fieldKeys {
+		if j := fields[string(key)]; s.fieldValues[j] == nil {
+			s.fieldValues[j] = fieldValues[i]
+		}
+	}
+	for _, v := range s.fieldValues {
+		buf = s.appendValue(buf, v)
+	}
+	buf[len(buf)-1] = '\n'
+	return buf, nil
+}
+
+// appendTime appends timestamp to buf in the time format of the options
+func (s *Serializer) appendTime(buf []byte, timestamp int64) []byte {
+	var unit int64
+	switch s.opts.Time {
+	case TimeNanoseconds:
+		unit = 1
+	case TimeMicroseconds:
+		unit = 1e3
+	case TimeMilliseconds:
+		unit = 1e6
+	case TimeSeconds:
+		unit = 1e9
+	default:
+		return s.appendField(buf, time.Unix(0, timestamp).UTC().AppendFormat(nil, s.opts.Time))
+	}
+	t := timestamp / unit
+	if timestamp < 0 && timestamp%unit != 0 {
+		t--
+	}
+	return strconv.AppendInt(buf, t, 10)
+}
+
+// appendValue appends a field value to buf as a column, followed by the
+// delimiter
+func (s *Serializer) appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+	case []byte:
+		buf = s.appendField(buf, x)
+	case string:
+		buf = s.appendField(buf, []byte(x))
+	case float64:
+		buf = serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		buf = serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	default:
+		buf = serialize.FastFormatAppend(v, buf)
+	}
+	return append(buf, s.opts.Delimiter)
+}
+
+// appendColumn appends b to buf as a column, followed by the delimiter
+func (s *Serializer) appendColumn(buf []byte, b []byte) []byte {
+	return append(s.appendField(buf, b), s.opts.Delimiter)
+}
+
+// appendField appends b to buf as a field of CSV: as is, or quoted if it
+// has the delimiter, quotes, line breaks or leading or trailing spaces,
+// with its quotes doubled
+func (s *Serializer) appendField(buf []byte, b []byte) []byte {
+	quote := len(b) > 0 && (b[0] == ' ' || b[len(b)-1] == ' ')
+	for _, c := range b {
+		if c == s.opts.Delimiter || c == '"' || c == '\n' || c == '\r' {
+			quote = true
+			break
+		}
+	}
+	if !quote {
+		return append(buf, b...)
+	}
+	buf = append(buf, '"')
+	for _, c := range b {
+		if c == '"' {
+			buf = append(buf, '"')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,257 @@
+package csv
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testPrefix = "2016-01-01T00:00:00Z,cpu,host_0,eu-west-1,eu-west-1b,"
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testPrefix + "5000000000,38,38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testPrefix + ",,38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testPrefix + ",38,\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "2016-01-01T00:00:00Z,cpu,,,,,,38.24311829\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerLongConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format+":layout=long,tags=json,delimiter=tab", schema, w)
+		},
+	}.Run(t)
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func TestSerializerOptions(t *testing.T) {
+	schema := serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"disk": {[]byte("path")}},
+		map[string][][]byte{
+			"cpu":  {[]byte("usage_user"), []byte("usage_system")},
+			"disk": {[]byte("free"), []byte("mount")},
+		},
+		nil,
+	)
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400123456789, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", 58.13, "usage_system", int64(2)),
+		newPoint("disk", -1500, []string{"region", "us,east", "hostname", "", "path", "/"}, "free", true, "mount", `/mnt/"a"`),
+	}
+	cases := []struct {
+		desc    string
+		options string
+		want    string
+	}{
+		{
+			desc:    "default",
+			options: "",
+			want: "timestamp,measurement,hostname,region,path,cpu_usage_user,cpu_usage_system,disk_free,disk_mount\n" +
+				"2016-01-01T00:00:00.123456789Z,cpu,host_0,eu-west-1,,58.13,2,,\n" +
+				`1969-12-31T23:59:59.9999985Z,disk,,"us,east",/,,,true,"/mnt/""a"""` + "\n",
+		},
+		{
+			desc:    "single measurement",
+			options: "measurement=cpu,time=s",
+			want: "timestamp,hostname,region,usage_user,usage_system\n" +
+				"1451606400,host_0,eu-west-1,58.13,2\n",
+		},
+		{
+			desc:    "long",
+			options: "layout=long,time=ms,header=false",
+			want: "1451606400123,cpu,host_0,eu-west-1,,usage_user,58.13\n" +
+				"1451606400123,cpu,host_0,eu-west-1,,usage_system,2\n" +
+				`-1,disk,,"us,east",/,free,true` + "\n" +
+				`-1,disk,,"us,east",/,mount,"/mnt/""a"""` + "\n",
+		},
+		{
+			desc:    "json tags and tabs",
+			options: "tags=json,delimiter=tab,time=us",
+			want: "timestamp\tmeasurement\ttags\tcpu_usage_user\tcpu_usage_system\tdisk_free\tdisk_mount\n" +
+				"1451606400123456\tcpu\t" + `"{""hostname"":""host_0"",""region"":""eu-west-1""}"` + "\t58.13\t2\t\t\n" +
+				"-2\tdisk\t" + `"{""region"":""us,east"",""path"":""/""}"` + "\t\t\ttrue\t" + `"/mnt/""a"""` + "\n",
+		},
+		{
+			desc:    "no tags and a time layout",
+			options: "tags=none,delimiter=;,time=2006-01-02 15:04:05,measurement=disk",
+			want: "timestamp;free;mount\n" +
+				`1969-12-31 23:59:59;true;"/mnt/""a"""` + "\n",
+		},
+	}
+	for _, c := range cases {
+		opts := DefaultOptions()
+		if len(c.options) > 0 {
+			var err error
+			if opts, err = ParseOptions(c.options); err != nil {
+				t.Fatalf("%s: unexpected error: %v", c.desc, err)
+			}
+		}
+		for _, batch := range []bool{false, true} {
+			var buf bytes.Buffer
+			s, err := NewSerializer(schema, opts, &buf)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", c.desc, err)
+			}
+			if batch {
+				b := serialize.NewPointBatch()
+				for _, p := range points {
+					b.Append(p)
+				}
+				err = s.SerializeBatch(b, &buf)
+			} else {
+				for _, p := range points {
+					if err = s.Serialize(p, &buf); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", c.desc, err)
+			}
+			if got := buf.String(); got != c.want {
+				t.Errorf("%s (batch %t): incorrect output:\ngot  %q\nwant %q", c.desc, batch, got, c.want)
+			}
+		}
+	}
+}
+
+func TestSerializerNoColumn(t *testing.T) {
+	schema := serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname")},
+		map[string][][]byte{"disk": {[]byte("path")}},
+		map[string][][]byte{
+			"cpu":  {[]byte("usage_user")},
+			"disk": {[]byte("free")},
+		},
+		nil,
+	)
+	cases := []struct {
+		desc    string
+		options string
+		p       *serialize.Point
+		want    string
+	}{
+		{
+			desc: "tag",
+			p:    newPoint("cpu", 0, []string{"hostname", "host_0", "zone", "a"}, "usage_user", 1.0),
+			want: "tag zone of cpu has no column",
+		},
+		{
+			desc:    "tag of another measurement",
+			options: ",measurement=cpu",
+			p:       newPoint("cpu", 0, []string{"path", "/"}, "usage_user", 1.0),
+			want:    "tag path of cpu has no column",
+		},
+		{
+			desc: "field",
+			p:    newPoint("cpu", 0, nil, "usage_user", 1.0, "usage_idle", 2.0),
+			want: "field usage_idle of cpu has no column",
+		},
+		{
+			desc: "measurement",
+			p:    newPoint("mem", 0, nil, "used", 1.0),
+			want: "field used of mem has no column",
+		},
+	}
+	for _, c := range cases {
+		opts, err := ParseOptions("header=false" + c.options)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		var buf bytes.Buffer
+		s, err := NewSerializer(schema, opts, &buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		if err := s.Serialize(c.p, &buf); err == nil || err.Error() != c.want {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.want)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: rejected row written: %q", c.desc, buf.Bytes())
+		}
+	}
+
+	// fields are not columns of the long layout, nor tags when they are JSON
+	var buf bytes.Buffer
+	s, err := serialize.New(Format+":header=false,layout=long,tags=json", schema, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Serialize(newPoint("cpu", 0, []string{"zone", "a"}, "usage_idle", 2.0), &buf); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("layout=long,delimiter=pipe,header=0,time=rfc3339,tags=none,measurement=mem")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Options{Layout: LayoutLong, Delimiter: '|', Header: false, Time: time.RFC3339, Tags: TagsNone, Measurement: "mem"}
+	if opts != want {
+		t.Errorf("incorrect options: got %+v want %+v", opts, want)
+	}
+
+	for _, c := range []struct {
+		options string
+		errMsg  string
+	}{
+		{"layout=tall", "invalid CSV layout 'tall'"},
+		{"delimiter=double", "invalid CSV delimiter 'double'"},
+		{`delimiter="`, "invalid CSV delimiter"},
+		{"header=maybe", "invalid CSV header 'maybe'"},
+		{"time=", "invalid CSV time format"},
+		{"tags=map", "invalid CSV tags 'map'"},
+		{"quote=always", "unknown CSV option 'quote'"},
+		{"long", "invalid CSV option 'long'"},
+	} {
+		if _, err := ParseOptions(c.options); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.options, err, c.errMsg)
+		}
+	}
+	if _, err := serialize.New("csv:layout=tall", serialize.NewSchema(nil, nil), io.Discard); err == nil {
+		t.Errorf("no error for invalid options of the format")
+	}
+}
@@ -0,0 +1,470 @@
+// Package druid implements the format for Apache Druid's native batch
+// ingestion: flat JSON events with the time of each reading in __time, its
+// tags as dimensions and its fields as metrics, optionally written as a
+// directory per measurement, each a datasource, of a file per segment
+// interval and an ingestion spec.
+package druid
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes the
+// events to the output. Formats of the form Format + ":" + options, e.g.,
+// druid:dir=/data/druid,segment=hour, write them to files instead (see
+// ParseOptions).
+const Format = "druid"
+
+// Columns of every event: the time of the reading, in milliseconds since
+// the epoch, and its measurement
+const (
+	TimeColumn        = "__time"
+	MeasurementColumn = "measurement"
+)
+
+// DefaultSegment is the segment granularity of the files of the format,
+// and of their ingestion specs, if no other is given, as in Druid
+const DefaultSegment = "day"
+
+// segments are the segment granularities of Druid of fixed length, which
+// are aligned to the epoch in UTC, and their length
+var segments = map[string]time.Duration{
+	"minute":         time.Minute,
+	"five_minute":    5 * time.Minute,
+	"ten_minute":     10 * time.Minute,
+	"fifteen_minute": 15 * time.Minute,
+	"thirty_minute":  30 * time.Minute,
+	"hour":           time.Hour,
+	"six_hour":       6 * time.Hour,
+	"eight_hour":     8 * time.Hour,
+	"day":            24 * time.Hour,
+}
+
+// fileTimeLayout is the layout of the start of the interval of each file
+// in its name
+const fileTimeLayout = "20060102T150405Z"
+
+func init() {
+	serialize.Describe(Format, "Apache Druid JSON events with __time, tags as dimensions and fields as metrics, with druid:dir=<dir>[,segment=<granularity>] writing a file per segment interval and an ingestion spec for each measurement")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, Options{})
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		opts, err := ParseOptions(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSerializer(schema, opts)
+	})
+}
+
+// Options are the options of the format
+type Options struct {
+	// Dir, if set, is the directory the events are written to, instead of
+	// the output, with a directory of the files of each measurement
+	Dir string
+	// Segment is the segment granularity of the files of Dir, one of those
+	// of Druid of fixed length, e.g., hour or day
+	Segment string
+}
+
+// ParseOptions parses the options of the format from a comma separated list
+// of option=value, e.g., dir=/data/druid,segment=hour. The options are:
+//   - dir: the directory to write the files to
+//   - segment: the segment granularity of the files, DefaultSegment unless
+//     set: minute, five_minute, ten_minute, fifteen_minute, thirty_minute,
+//     hour, six_hour, eight_hour or day
+func ParseOptions(s string) (Options, error) {
+	var opts Options
+	for _, option := range strings.Split(s, ",") {
+		i := strings.IndexByte(option, '=')
+		if i < 0 {
+			return opts, fmt.Errorf("invalid Druid option '%s': must be option=value", option)
+		}
+		name, value := option[:i], option[i+1:]
+		switch name {
+		case "dir":
+			if len(value) == 0 {
+				return opts, fmt.Errorf("invalid Druid dir: must not be empty")
+			}
+			opts.Dir = value
+		case "segment":
+			if _, ok := segments[value]; !ok {
+				return opts, fmt.Errorf("invalid Druid segment granularity '%s': must be one of %s", value, strings.Join(segmentNames(), ", "))
+			}
+			opts.Segment = value
+		default:
+			return opts, fmt.Errorf("unknown Druid option '%s': must be dir or segment", name)
+		}
+	}
+	if len(opts.Dir) == 0 {
+		return opts, fmt.Errorf("missing Druid option dir, the directory to write the files to")
+	}
+	if len(opts.Segment) == 0 {
+		opts.Segment = DefaultSegment
+	}
+	return opts, nil
+}
+
+// segmentNames returns the names of the segment granularities by length
+func segmentNames() []string {
+	var names []string
+	for name := range segments {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return segments[names[i]] < segments[names[j]] })
+	return names
+}
+
+// Serializer writes a Point in a serialized form for Druid
+type Serializer struct {
+	opts Options
+	// segment is the length of the segment intervals of the files, in
+	// nanoseconds
+	segment int64
+	// files are the files being written of each measurement, and created
+	// the names of the files created, which are appended to when a
+	// measurement returns to their interval
+	files   map[string]*segmentFile
+	created map[string]bool
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// segmentFile is the file of a segment interval of a measurement
+type segmentFile struct {
+	start int64
+	f     *os.File
+	w     *bufio.Writer
+}
+
+// NewSerializer returns a Serializer with the given options. If opts.Dir is
+// set, it is created, with a directory of the files of each measurement of
+// schema, named by the measurement with any characters other than letters,
+// digits, dashes and underscores replaced by underscores, e.g., cpu, and an
+// ingestion spec of the measurement next to it, e.g., cpu-spec.json. schema
+// may be nil, e.g., if it is not known, for no ingestion specs.
+//
+// Each ingestion spec is an index_parallel task of a datasource named as the
+// directory, reading its files with the time in __time in milliseconds, the
+// tag keys of schema as string dimensions and the fields of the measurement
+// as metrics: longSum of ints, doubleSum of the numbers of the simulators,
+// whose type is not known, and floats, and string dimensions of bools and
+// strings. Rollup is disabled, so each event is a row, and the segment
+// granularity is that of the files, e.g.:
+//
+// curl -X POST -H 'Content-Type: application/json' -d @/data/druid/cpu-spec.json http://localhost:8081/druid/indexer/v1/task
+func NewSerializer(schema *serialize.Schema, opts Options) (*Serializer, error) {
+	s := &Serializer{opts: opts}
+	if len(opts.Dir) == 0 {
+		return s, nil
+	}
+	if len(opts.Segment) == 0 {
+		opts.Segment = DefaultSegment
+	}
+	segment, ok := segments[opts.Segment]
+	if !ok {
+		return nil, fmt.Errorf("invalid Druid segment granularity '%s': must be one of %s", opts.Segment, strings.Join(segmentNames(), ", "))
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	s.opts.Dir, s.opts.Segment = dir, opts.Segment
+	s.segment = int64(segment)
+	s.files = make(map[string]*segmentFile)
+	s.created = make(map[string]bool)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return s, nil
+	}
+	for _, m := range schema.Measurements() {
+		spec, err := json.MarshalIndent(s.spec(schema, m), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, DataSource(m)+"-spec.json"), append(spec, '\n'), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// DataSource returns the name of the datasource, and directory, of the
+// files of measurement: the measurement with any characters other than
+// letters, digits, dashes and underscores replaced by underscores, or an
+// underscore if it is empty
+func DataSource(measurement string) string {
+	if len(measurement) == 0 {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, measurement)
+}
+
+// The parts of an ingestion spec, as of the API of Druid
+type (
+	task struct {
+		Type string   `json:"type"`
+		Spec taskSpec `json:"spec"`
+	}
+	taskSpec struct {
+		DataSchema   dataSchema   `json:"dataSchema"`
+		IOConfig     ioConfig     `json:"ioConfig"`
+		TuningConfig tuningConfig `json:"tuningConfig"`
+	}
+	dataSchema struct {
+		DataSource      string          `json:"dataSource"`
+		TimestampSpec   timestampSpec   `json:"timestampSpec"`
+		DimensionsSpec  dimensionsSpec  `json:"dimensionsSpec"`
+		MetricsSpec     []metric        `json:"metricsSpec"`
+		GranularitySpec granularitySpec `json:"granularitySpec"`
+	}
+	timestampSpec struct {
+		Column string `json:"column"`
+		Format string `json:"format"`
+	}
+	dimensionsSpec struct {
+		Dimensions []dimension `json:"dimensions"`
+	}
+	dimension struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	metric struct {
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		FieldName string `json:"fieldName"`
+	}
+	granularitySpec struct {
+		SegmentGranularity string `json:"segmentGranularity"`
+		QueryGranularity   string `json:"queryGranularity"`
+		Rollup             bool   `json:"rollup"`
+	}
+	ioConfig struct {
+		Type        string      `json:"type"`
+		InputSource inputSource `json:"inputSource"`
+		InputFormat inputFormat `json:"inputFormat"`
+	}
+	inputSource struct {
+		Type    string `json:"type"`
+		BaseDir string `json:"baseDir"`
+		Filter  string `json:"filter"`
+	}
+	inputFormat struct {
+		Type string `json:"type"`
+	}
+	tuningConfig struct {
+		Type string `json:"type"`
+	}
+)
+
+// spec returns the ingestion spec of the files of measurement m
+func (s *Serializer) spec(schema *serialize.Schema, m string) task {
+	var dimensions []dimension
+	for _, key := range schema.TagKeysOf(m) {
+		dimensions = append(dimensions, dimension{Type: "string", Name: string(key)})
+	}
+	metrics := []metric{}
+	fieldTypes := schema.FieldTypes(m)
+	for i, key := range schema.FieldKeys(m) {
+		t := serialize.FieldTypeUnknown
+		if i < len(fieldTypes) {
+			t = fieldTypes[i]
+		}
+		switch t {
+		case serialize.FieldTypeInt:
+			metrics = append(metrics, metric{Type: "longSum", Name: string(key), FieldName: string(key)})
+		case serialize.FieldTypeBool, serialize.FieldTypeString:
+			dimensions = append(dimensions, dimension{Type: "string", Name: string(key)})
+		default:
+			metrics = append(metrics, metric{Type: "doubleSum", Name: string(key), FieldName: string(key)})
+		}
+	}
+	if dimensions == nil {
+		dimensions = []dimension{}
+	}
+	dataSource := DataSource(m)
+	return task{
+		Type: "index_parallel",
+		Spec: taskSpec{
+			DataSchema: dataSchema{
+				DataSource:      dataSource,
+				TimestampSpec:   timestampSpec{Column: TimeColumn, Format: "millis"},
+				DimensionsSpec:  dimensionsSpec{Dimensions: dimensions},
+				MetricsSpec:     metrics,
+				GranularitySpec: granularitySpec{SegmentGranularity: s.opts.Segment, QueryGranularity: "none"},
+			},
+			IOConfig: ioConfig{
+				Type:        "index_parallel",
+				InputSource: inputSource{Type: "local", BaseDir: filepath.Join(s.opts.Dir, dataSource), Filter: "*.json"},
+				InputFormat: inputFormat{Type: "json"},
+			},
+			TuningConfig: tuningConfig{Type: "index_parallel"},
+		},
+	}
+}
+
+// Serialize writes Point p to w as a line of a flat JSON object, an event
+// of Druid:
+//
+// {"__time":1451606400000,"measurement":"cpu","hostname":"host_0",...,"usage_user":58,...}
+//
+// The time is in milliseconds, the precision of Druid. Tags with empty
+// values, which Druid treats as null, and fields without values are left
+// out. Infinite and NaN values are written as the strings Infinity,
+// -Infinity and NaN, as JSON has no numbers for them.
+//
+// With a directory of files, p is written to the file of the segment
+// interval of its time in the directory of its measurement, e.g.,
+// cpu/20160101T000000Z.json, rather than to w. A file is kept open while the
+// readings of its measurement are in its interval, and appended to if they
+// return to it, e.g., with --order-window.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	if s.files != nil {
+		return s.writeEvent(p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	}
+	buf := appendEvent(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all events of a PointBatch to the given writer, or
+// the files of their segment intervals, in the same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	if s.files != nil {
+		for i := 0; i < b.Len(); i++ {
+			tagKeys, tagValues := b.Tags(i)
+			fieldKeys, fieldValues := b.Fields(i)
+			if err := s.writeEvent(b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendEvent(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// writeEvent writes an event to the file of its measurement and segment
+// interval
+func (s *Serializer) writeEvent(measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) error {
+	start := timestamp - timestamp%s.segment
+	if timestamp%s.segment < 0 {
+		start -= s.segment
+	}
+	file := s.files[string(measurementName)]
+	if file == nil || file.start != start {
+		if file != nil {
+			delete(s.files, string(measurementName))
+			if err := file.close(); err != nil {
+				return err
+			}
+		}
+		var err error
+		if file, err = s.open(string(measurementName), start); err != nil {
+			return err
+		}
+		s.files[string(measurementName)] = file
+	}
+	s.buf = appendEvent(s.buf[:0], measurementName, tagKeys, tagValues, fieldKeys, fieldValues, timestamp)
+	_, err := file.w.Write(s.buf)
+	return err
+}
+
+// open opens the file of the segment interval starting at start of
+// measurement, truncating it if it was not yet created by s
+func (s *Serializer) open(measurement string, start int64) (*segmentFile, error) {
+	dir := filepath.Join(s.opts.Dir, DataSource(measurement))
+	name := filepath.Join(dir, time.Unix(0, start).UTC().Format(fileTimeLayout)+".json")
+	flag := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !s.created[name] {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		flag |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(name, flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.created[name] = true
+	return &segmentFile{start: start, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+func (f *segmentFile) close() error {
+	err := f.w.Flush()
+	if closeErr := f.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Close flushes and closes the files being written, if any
+func (s *Serializer) Close() error {
+	var err error
+	for m, file := range s.files {
+		if closeErr := file.close(); err == nil {
+			err = closeErr
+		}
+		delete(s.files, m)
+	}
+	return err
+}
+
+func appendEvent(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	// floor, so readings before the epoch are not rounded up to it
+	millis := timestamp / 1e6
+	if timestamp%1e6 < 0 {
+		millis--
+	}
+	buf = append(buf, `{"`+TimeColumn+`":`...)
+	buf = serialize.FastFormatAppend(millis, buf)
+	buf = append(buf, `,"`+MeasurementColumn+`":`...)
+	buf = serialize.AppendJSONString(buf, measurementName)
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = serialize.AppendJSONString(buf, tagKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	for i, v := range fieldValues {
+		if v == nil {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = serialize.AppendJSONString(buf, fieldKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONValue(buf, v)
+	}
+	return append(buf, "}\n"...)
+}
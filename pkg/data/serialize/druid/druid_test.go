@@ -0,0 +1,201 @@
+package druid
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testPrefix = `{"__time":1451606400000,"measurement":"cpu","hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b",`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testPrefix + `"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testPrefix + `"usage_guest":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testPrefix + `"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"__time":1451606400000,"measurement":"cpu","usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func TestSerializerFiles(t *testing.T) {
+	schema := serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"disk/io": {[]byte("device")}},
+		map[string][][]byte{
+			"cpu":     {[]byte("usage_user"), []byte("usage_system")},
+			"disk/io": {[]byte("reads"), []byte("ok")},
+		},
+		map[string][]serialize.FieldType{
+			"cpu":     {serialize.FieldTypeInt, serialize.FieldTypeUnknown},
+			"disk/io": {serialize.FieldTypeInt, serialize.FieldTypeBool},
+		},
+	)
+	const hour = int64(3600e9)
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400123456789, []string{"hostname", "host_0", "region", ""}, "usage_user", int64(58), "usage_system", 2.5),
+		newPoint("disk/io", 1451606400000000000, []string{"hostname", "host_0"}, "reads", int64(1), "ok", true),
+		newPoint("cpu", 1451606400000000000+hour, []string{"hostname", "host_1"}, "usage_user", int64(3)),
+		// out of order, back to the interval of the first reading
+		newPoint("cpu", 1451606400000000000+hour-1, []string{"hostname", "host_2"}, "usage_user", int64(4)),
+	}
+	for _, batch := range []bool{false, true} {
+		dir := filepath.Join(t.TempDir(), "druid")
+		ps, err := serialize.New(Format+":dir="+dir+",segment=hour", schema, io.Discard)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s := ps.(*Serializer)
+		if batch {
+			b := serialize.NewPointBatch()
+			for _, p := range points {
+				b.Append(p)
+			}
+			err = s.SerializeBatch(b, io.Discard)
+		} else {
+			for _, p := range points {
+				if err = s.Serialize(p, io.Discard); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+
+		want := map[string]string{
+			"cpu/20160101T000000Z.json": `{"__time":1451606400123,"measurement":"cpu","hostname":"host_0","usage_user":58,"usage_system":2.5}` + "\n" +
+				`{"__time":1451609999999,"measurement":"cpu","hostname":"host_2","usage_user":4}` + "\n",
+			"cpu/20160101T010000Z.json":     `{"__time":1451610000000,"measurement":"cpu","hostname":"host_1","usage_user":3}` + "\n",
+			"disk_io/20160101T000000Z.json": `{"__time":1451606400000,"measurement":"disk/io","hostname":"host_0","reads":1,"ok":true}` + "\n",
+		}
+		for name, contents := range want {
+			got, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Errorf("batch %t: %v", batch, err)
+			} else if string(got) != contents {
+				t.Errorf("batch %t: incorrect contents of %s:\ngot  %s\nwant %s", batch, name, got, contents)
+			}
+		}
+
+		var spec task
+		data, err := os.ReadFile(filepath.Join(dir, "disk_io-spec.json"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.Unmarshal(data, &spec); err != nil {
+			t.Fatalf("invalid spec: %v", err)
+		}
+		wantSchema := dataSchema{
+			DataSource:      "disk_io",
+			TimestampSpec:   timestampSpec{Column: TimeColumn, Format: "millis"},
+			DimensionsSpec:  dimensionsSpec{Dimensions: []dimension{{"string", "hostname"}, {"string", "region"}, {"string", "device"}, {"string", "ok"}}},
+			MetricsSpec:     []metric{{"longSum", "reads", "reads"}},
+			GranularitySpec: granularitySpec{SegmentGranularity: "hour", QueryGranularity: "none"},
+		}
+		if got, _ := json.Marshal(spec.Spec.DataSchema); !bytes.Equal(got, mustMarshal(t, wantSchema)) {
+			t.Errorf("incorrect data schema:\ngot  %s\nwant %s", got, mustMarshal(t, wantSchema))
+		}
+		if got, want := spec.Spec.IOConfig.InputSource.BaseDir, filepath.Join(dir, "disk_io"); got != want {
+			t.Errorf("incorrect base dir: got %s want %s", got, want)
+		}
+		if !strings.Contains(string(mustReadFile(t, filepath.Join(dir, "cpu-spec.json"))), `"type": "doubleSum",
+          "name": "usage_system"`) {
+			t.Errorf("unknown field type not ingested as doubleSum")
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return data
+}
+
+func mustReadFile(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return data
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("dir=/tmp/druid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Options{Dir: "/tmp/druid", Segment: DefaultSegment}); opts != want {
+		t.Errorf("incorrect options: got %+v want %+v", opts, want)
+	}
+
+	for _, c := range []struct {
+		options string
+		errMsg  string
+	}{
+		{"segment=hour", "missing Druid option dir"},
+		{"dir=", "invalid Druid dir"},
+		{"dir=/tmp,segment=week", "invalid Druid segment granularity 'week': must be one of minute, five_minute"},
+		{"dir=/tmp,rollup=true", "unknown Druid option 'rollup'"},
+		{"/tmp", "invalid Druid option '/tmp'"},
+	} {
+		if _, err := ParseOptions(c.options); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.options, err, c.errMsg)
+		}
+	}
+}
+
+func TestDataSource(t *testing.T) {
+	for name, want := range map[string]string{"cpu": "cpu", "disk.io": "disk_io", "../x y": "___x_y", "": "_"} {
+		if got := DataSource(name); got != want {
+			t.Errorf("incorrect datasource of %q: got %q want %q", name, got, want)
+		}
+	}
+}
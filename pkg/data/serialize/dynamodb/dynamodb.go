@@ -0,0 +1,397 @@
+// Package dynamodb implements the format for Amazon DynamoDB: BatchWriteItem
+// requests in JSON, each of the PutRequests of up to 25 readings, keyed by
+// a tag, e.g., the hostname, and their time.
+package dynamodb
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes requests
+// with DefaultOptions. Formats of the form Format + ":" + options, e.g.,
+// dynamodb:partition=region,time=s, write them with other options (see
+// ParseOptions).
+const Format = "dynamodb"
+
+// MaxBatchSize is the most items DynamoDB takes in a BatchWriteItem request
+const MaxBatchSize = 25
+
+// MeasurementPlaceholder is replaced by the measurement in the table names
+// of Options.Table
+const MeasurementPlaceholder = "{measurement}"
+
+// Units of the times of the sort keys: numbers since the epoch, or RFC 3339
+// strings
+const (
+	TimeNanoseconds  = "ns"
+	TimeMicroseconds = "us"
+	TimeMilliseconds = "ms"
+	TimeSeconds      = "s"
+	TimeRFC3339      = "rfc3339"
+)
+
+// units are the number of nanoseconds of each unit of numeric times
+var units = map[string]int64{
+	TimeNanoseconds:  1,
+	TimeMicroseconds: 1e3,
+	TimeMilliseconds: 1e6,
+	TimeSeconds:      1e9,
+}
+
+func init() {
+	serialize.Describe(Format, "Amazon DynamoDB BatchWriteItem requests in JSON, a line of up to 25 readings each, with dynamodb:table=<name>,partition=<tag>,sort=<attribute>,time=<unit>,batch=<items>")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(DefaultOptions())
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		opts, err := ParseOptions(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSerializer(opts)
+	})
+}
+
+// Options are the options of the format
+type Options struct {
+	// Table is the name of the table of each measurement, with
+	// MeasurementPlaceholder replaced by the measurement, e.g.,
+	// tsbs_{measurement}
+	Table string
+	// Partition is the tag key whose value is the partition key of each
+	// item, in an attribute of the same name
+	Partition string
+	// Sort is the name of the attribute of the sort key of each item, its
+	// time, and Time the unit of the time: one of TimeNanoseconds,
+	// TimeMicroseconds, TimeMilliseconds or TimeSeconds for numbers, or
+	// TimeRFC3339 for strings
+	Sort string
+	Time string
+	// BatchSize is the number of items of each request, at most
+	// MaxBatchSize
+	BatchSize int
+}
+
+// DefaultOptions returns the options of Format: requests of MaxBatchSize
+// items of tables named by the measurements, whose partition key is the
+// hostname and sort key the time in milliseconds
+func DefaultOptions() Options {
+	return Options{
+		Table:     MeasurementPlaceholder,
+		Partition: "hostname",
+		Sort:      "time",
+		Time:      TimeMilliseconds,
+		BatchSize: MaxBatchSize,
+	}
+}
+
+// ParseOptions parses the options of the format from a comma separated list
+// of option=value, e.g., partition=region,time=s. Options not in the list
+// are those of DefaultOptions. The options are:
+//   - table: the name of the table of each measurement, which must have
+//     {measurement} in it
+//   - partition: the tag key of the partition key
+//   - sort: the name of the attribute of the sort key
+//   - time: ns, us, ms or s for numbers since the epoch, or rfc3339
+//   - batch: the number of items of each request, from 1 to 25
+func ParseOptions(s string) (Options, error) {
+	opts := DefaultOptions()
+	for _, option := range strings.Split(s, ",") {
+		i := strings.IndexByte(option, '=')
+		if i < 0 {
+			return opts, fmt.Errorf("invalid DynamoDB option '%s': must be option=value", option)
+		}
+		name, value := option[:i], option[i+1:]
+		switch name {
+		case "table":
+			opts.Table = value
+		case "partition":
+			opts.Partition = value
+		case "sort":
+			opts.Sort = value
+		case "time":
+			opts.Time = value
+		case "batch":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return opts, fmt.Errorf("invalid DynamoDB batch '%s': must be an integer", value)
+			}
+			opts.BatchSize = n
+		default:
+			return opts, fmt.Errorf("unknown DynamoDB option '%s': must be table, partition, sort, time or batch", name)
+		}
+	}
+	return opts, opts.validate()
+}
+
+func (o Options) validate() error {
+	if !strings.Contains(o.Table, MeasurementPlaceholder) {
+		return fmt.Errorf("invalid DynamoDB table '%s': must have %s in it", o.Table, MeasurementPlaceholder)
+	}
+	if len(o.Partition) == 0 || len(o.Sort) == 0 {
+		return fmt.Errorf("the DynamoDB partition and sort keys must not be empty")
+	}
+	if o.Partition == o.Sort {
+		return fmt.Errorf("the DynamoDB partition and sort keys must be different attributes, not %s", o.Sort)
+	}
+	if _, ok := units[o.Time]; !ok && o.Time != TimeRFC3339 {
+		return fmt.Errorf("invalid DynamoDB time '%s': must be %s, %s, %s, %s or %s", o.Time, TimeNanoseconds, TimeMicroseconds, TimeMilliseconds, TimeSeconds, TimeRFC3339)
+	}
+	if o.BatchSize < 1 || o.BatchSize > MaxBatchSize {
+		return fmt.Errorf("invalid DynamoDB batch %d: must be from 1 to %d", o.BatchSize, MaxBatchSize)
+	}
+	return nil
+}
+
+// Serializer writes Points as DynamoDB BatchWriteItem requests. The items of
+// the readings are held until they make a request of Options.BatchSize
+// items, so the requests are only complete once the Serializer is closed.
+type Serializer struct {
+	opts Options
+	unit int64
+
+	// items are the items being held, as PutRequests in JSON, and tables
+	// the tables they are put in, and w the Writer items were last written
+	// to, which Close writes the rest to
+	items  [][]byte
+	tables []string
+	w      io.Writer
+
+	// buf, item and key are scratch space reused between calls so each Point
+	// or PointBatch is written with a single call
+	buf  []byte
+	item []byte
+	key  []byte
+}
+
+// NewSerializer returns a Serializer writing requests with the given options
+func NewSerializer(opts Options) (*Serializer, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Serializer{opts: opts, unit: units[opts.Time]}, nil
+}
+
+// Serialize adds the item of Point p to the request being made, and writes
+// the request to w once it has Options.BatchSize items, as a line of JSON:
+//
+// {"RequestItems":{"cpu":[{"PutRequest":{"Item":{"hostname":{"S":"host_0"},"time":{"N":"1451606400000"},"region":{"S":"eu-west-1"},...,"usage_user":{"N":"58"},...}}},...]}}
+//
+// which is the input of BatchWriteItem, e.g., of
+// aws dynamodb batch-write-item --cli-input-json. Items of the readings of
+// different measurements are in the lists of their tables, in the order
+// of their first items in the request.
+//
+// The partition key of each item is the value of its Options.Partition
+// tag, or, for readings without it, the tags of the reading as
+// key=value,..., or its measurement if it has none. Its sort key is the
+// time of the reading, as a number, floored to Options.Time, or as an RFC
+// 3339 string in UTC. The other tags are string attributes, but for those
+// with empty values, which are left out, and fields are number, bool or
+// string attributes. NaN and infinite values, which DynamoDB has no numbers
+// for, and fields without values are left out.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendItem(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf, s.w = buf, w
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// SerializeBatch adds the items of all rows of a PointBatch to the requests
+// being made in the same way as Serialize, and writes those complete to w
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendItem(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf, s.w = buf, w
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// Close writes the request of the items being held, if any, to the Writer
+// items were last written to
+func (s *Serializer) Close() error {
+	if len(s.items) == 0 || s.w == nil {
+		return nil
+	}
+	s.buf = s.appendRequest(s.buf[:0])
+	_, err := s.w.Write(s.buf)
+	return err
+}
+
+// appendItem adds the item of a reading to the request being made,
+// appending the request to buf if it is complete
+func (s *Serializer) appendItem(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	s.key = s.appendKey(s.key[:0], measurementName, tagKeys, tagValues)
+	item := s.item[:0]
+	item = append(item, `{"PutRequest":{"Item":{`...)
+	item = serialize.AppendJSONString(item, []byte(s.opts.Partition))
+	item = append(item, `:{"S":`...)
+	item = serialize.AppendJSONString(item, s.key)
+	item = append(item, `},`...)
+	item = serialize.AppendJSONString(item, []byte(s.opts.Sort))
+	if s.unit == 0 {
+		item = append(item, `:{"S":"`...)
+		item = time.Unix(0, timestamp).UTC().AppendFormat(item, time.RFC3339Nano)
+	} else {
+		// floor, so readings before the epoch are not rounded up to it
+		t := timestamp / s.unit
+		if timestamp%s.unit < 0 {
+			t--
+		}
+		item = append(item, `:{"N":"`...)
+		item = strconv.AppendInt(item, t, 10)
+	}
+	item = append(item, `"}`...)
+	for i, v := range tagValues {
+		key := string(tagKeys[i])
+		if len(v) == 0 || key == s.opts.Partition || key == s.opts.Sort {
+			continue
+		}
+		item = append(item, ',')
+		item = serialize.AppendJSONString(item, tagKeys[i])
+		item = append(item, `:{"S":`...)
+		item = serialize.AppendJSONString(item, v)
+		item = append(item, '}')
+	}
+	for i, v := range fieldValues {
+		key := string(fieldKeys[i])
+		if !hasAttribute(v) || key == s.opts.Partition || key == s.opts.Sort {
+			continue
+		}
+		item = append(item, ',')
+		item = serialize.AppendJSONString(item, fieldKeys[i])
+		item = append(item, ':')
+		item = appendAttribute(item, v)
+	}
+	item = append(item, "}}}"...)
+	s.item = item
+
+	table := strings.ReplaceAll(s.opts.Table, MeasurementPlaceholder, string(measurementName))
+	if len(s.items) < cap(s.items) {
+		// reuse the space of items of earlier requests
+		s.items = s.items[:len(s.items)+1]
+		s.items[len(s.items)-1] = append(s.items[len(s.items)-1][:0], item...)
+	} else {
+		s.items = append(s.items, append([]byte(nil), item...))
+	}
+	s.tables = append(s.tables, table)
+	if len(s.items) < s.opts.BatchSize {
+		return buf
+	}
+	return s.appendRequest(buf)
+}
+
+// appendRequest appends the request of the items being held to buf, and
+// empties it
+func (s *Serializer) appendRequest(buf []byte) []byte {
+	buf = append(buf, `{"RequestItems":{`...)
+	for i, table := range s.tables {
+		if s.seen(i) {
+			continue
+		}
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = serialize.AppendJSONString(buf, []byte(table))
+		buf = append(buf, ':', '[')
+		n := 0
+		for j := i; j < len(s.tables); j++ {
+			if s.tables[j] != table {
+				continue
+			}
+			if n > 0 {
+				buf = append(buf, ',')
+			}
+			n++
+			buf = append(buf, s.items[j]...)
+		}
+		buf = append(buf, ']')
+	}
+	s.items = s.items[:0]
+	s.tables = s.tables[:0]
+	return append(buf, "}}\n"...)
+}
+
+// seen returns whether the table of item i is that of an earlier item
+func (s *Serializer) seen(i int) bool {
+	for j := 0; j < i; j++ {
+		if s.tables[j] == s.tables[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// appendKey appends the partition key of a reading with the given tags to
+// buf
+func (s *Serializer) appendKey(buf []byte, measurementName []byte, tagKeys, tagValues [][]byte) []byte {
+	for i, key := range tagKeys {
+		if string(key) == s.opts.Partition && len(tagValues[i]) > 0 {
+			return append(buf, tagValues[i]...)
+		}
+	}
+	if len(tagKeys) == 0 {
+		return append(buf, measurementName...)
+	}
+	for i, key := range tagKeys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = append(buf, tagValues[i]...)
+	}
+	return buf
+}
+
+// hasAttribute returns whether field value v is written as an attribute
+func hasAttribute(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case float64:
+		return !math.IsNaN(x) && !math.IsInf(x, 0)
+	case float32:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	}
+	return true
+}
+
+// appendAttribute appends field value v to buf as an attribute value of
+// DynamoDB in JSON
+func appendAttribute(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case []byte:
+		buf = append(buf, `{"S":`...)
+		buf = serialize.AppendJSONString(buf, x)
+		return append(buf, '}')
+	case string:
+		buf = append(buf, `{"S":`...)
+		buf = serialize.AppendJSONString(buf, []byte(x))
+		return append(buf, '}')
+	case bool:
+		buf = append(buf, `{"BOOL":`...)
+		buf = strconv.AppendBool(buf, x)
+		return append(buf, '}')
+	}
+	buf = append(buf, `{"N":"`...)
+	buf = serialize.FastFormatAppend(v, buf)
+	return append(buf, `"}`...)
+}
@@ -0,0 +1,153 @@
+package dynamodb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testPrefix = `{"RequestItems":{"cpu":[{"PutRequest":{"Item":{"hostname":{"S":"host_0"},"time":{"N":"1451606400000"},"region":{"S":"eu-west-1"},"datacenter":{"S":"eu-west-1b"},`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testPrefix + `"usage_guest_nice":{"N":"38.24311829"}}}}]}}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testPrefix + `"usage_guest":{"N":"38"}}}}]}}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testPrefix + `"big_usage_guest":{"N":"5000000000"},"usage_guest":{"N":"38"},"usage_guest_nice":{"N":"38.24311829"}}}}]}}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"RequestItems":{"cpu":[{"PutRequest":{"Item":{"hostname":{"S":"cpu"},"time":{"N":"1451606400000"},"usage_guest_nice":{"N":"38.24311829"}}}}]}}` + "\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+		// requests are only written once they have their items, or on
+		// Close
+		IgnoresWriter: true,
+	}.Run(t)
+}
+
+func TestSerializerConformanceUnbatched(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format+":batch=1,time=rfc3339", schema, w)
+		},
+	}.Run(t)
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func TestSerializerRequests(t *testing.T) {
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400123456789, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", int64(58), "usage_system", math.NaN()),
+		newPoint("disk", -1500000000, []string{"region", "", "path", "/\"a\""}, "ok", true, "mode", "rw", "none", nil),
+		newPoint("cpu", 1451606410000000000, []string{"region", "us-east-1", "hostname", "host_1"}, "usage_user", 2.5),
+		newPoint("mem", 0, nil, "used", int64(1)),
+	}
+	want := `{"RequestItems":{"t_cpu":[` +
+		`{"PutRequest":{"Item":{"region":{"S":"eu-west-1"},"ts":{"N":"1451606400"},"hostname":{"S":"host_0"},"usage_user":{"N":"58"}}}},` +
+		`{"PutRequest":{"Item":{"region":{"S":"us-east-1"},"ts":{"N":"1451606410"},"hostname":{"S":"host_1"},"usage_user":{"N":"2.5"}}}}],` +
+		`"t_disk":[{"PutRequest":{"Item":{"region":{"S":"region=,path=/\"a\""},"ts":{"N":"-2"},"path":{"S":"/\"a\""},"ok":{"BOOL":true},"mode":{"S":"rw"}}}}]}}` + "\n" +
+		`{"RequestItems":{"t_mem":[{"PutRequest":{"Item":{"region":{"S":"mem"},"ts":{"N":"0"},"used":{"N":"1"}}}}]}}` + "\n"
+
+	for _, batch := range []bool{false, true} {
+		opts, err := ParseOptions("table=t_{measurement},partition=region,sort=ts,time=s,batch=3")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s, err := NewSerializer(opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var buf bytes.Buffer
+		if batch {
+			b := serialize.NewPointBatch()
+			for _, p := range points {
+				b.Append(p)
+			}
+			err = s.SerializeBatch(b, &buf)
+		} else {
+			for _, p := range points {
+				if err = s.Serialize(p, &buf); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil {
+			err = s.Close()
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("batch %t: incorrect output:\ngot  %s\nwant %s", batch, got, want)
+		}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if !json.Valid([]byte(line)) {
+				t.Errorf("invalid JSON: %s", line)
+			}
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("table=x_{measurement},partition=region,sort=ts,time=rfc3339,batch=10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Options{Table: "x_{measurement}", Partition: "region", Sort: "ts", Time: TimeRFC3339, BatchSize: 10}
+	if opts != want {
+		t.Errorf("incorrect options: got %+v want %+v", opts, want)
+	}
+
+	for _, c := range []struct {
+		options string
+		errMsg  string
+	}{
+		{"table=metrics", "invalid DynamoDB table 'metrics': must have {measurement} in it"},
+		{"partition=", "partition and sort keys must not be empty"},
+		{"sort=hostname", "must be different attributes"},
+		{"time=minutes", "invalid DynamoDB time 'minutes'"},
+		{"batch=26", "invalid DynamoDB batch 26: must be from 1 to 25"},
+		{"batch=many", "invalid DynamoDB batch 'many'"},
+		{"region=us-east-1", "unknown DynamoDB option 'region'"},
+		{"hostname", "invalid DynamoDB option 'hostname'"},
+	} {
+		if _, err := ParseOptions(c.options); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.options, err, c.errMsg)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Package elasticsearch implements the format for Elasticsearch and
+// OpenSearch: the NDJSON body of the _bulk API, with an action line and a
+// document line for each reading, into indexes named by a pattern of the
+// measurement and date of the reading.
+package elasticsearch
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes to
+// indexes named by DefaultIndexPattern. Formats of the form Format + ":" +
+// pattern write to indexes named by pattern.
+const Format = "elasticsearch"
+
+// DefaultIndexPattern names the indexes of Format: an index per measurement
+// and day
+const DefaultIndexPattern = "tsbs-{measurement}-{date}"
+
+// Placeholders of index patterns. The date is in UTC, in the Go layout
+// after DatePlaceholder and a colon, e.g., {date:2006.01} for monthly
+// indexes, or in DefaultDateLayout.
+const (
+	MeasurementPlaceholder = "measurement"
+	DatePlaceholder        = "date"
+	DefaultDateLayout      = "2006.01.02"
+)
+
+// TimestampKey is the key of the timestamp of the documents, that of the
+// Elastic Common Schema
+const TimestampKey = "@timestamp"
+
+// MeasurementKey is the key of the measurement of the documents, if the
+// index pattern does not have the measurement
+const MeasurementKey = "measurement"
+
+func init() {
+	serialize.Describe(Format, "Elasticsearch/OpenSearch _bulk NDJSON, with elasticsearch:<pattern> naming indexes (default "+DefaultIndexPattern+")")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(DefaultIndexPattern)
+	})
+	serialize.RegisterScheme(Format, func(pattern string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(pattern)
+	})
+}
+
+// segment is a part of an index pattern: literal text, the measurement, or
+// the date in layout
+type segment struct {
+	literal     string
+	measurement bool
+	layout      string
+}
+
+// Serializer writes a Point in a serialized form for the _bulk API
+type Serializer struct {
+	pattern        []segment
+	hasMeasurement bool
+	hasDate        bool
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and second and dates the second of the
+	// last timestamp whose date was formatted, and the dates it has
+	buf    []byte
+	second int64
+	dates  []string
+}
+
+// NewSerializer returns a Serializer writing to indexes named by pattern:
+// text, with {measurement} replaced by the measurement of each reading and
+// {date} by its date, e.g., tsbs-{measurement}-{date} for daily indexes of
+// each measurement. Index names are lowercase, with characters they may
+// not have replaced by underscores.
+func NewSerializer(pattern string) (*Serializer, error) {
+	s := &Serializer{second: math.MinInt64}
+	rest := pattern
+	for len(rest) > 0 {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			s.pattern = append(s.pattern, segment{literal: rest})
+			break
+		}
+		if open > 0 {
+			s.pattern = append(s.pattern, segment{literal: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid index pattern '%s': unclosed {", pattern)
+		}
+		name := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+		switch {
+		case name == MeasurementPlaceholder:
+			s.pattern = append(s.pattern, segment{measurement: true})
+			s.hasMeasurement = true
+		case name == DatePlaceholder:
+			s.pattern = append(s.pattern, segment{layout: DefaultDateLayout})
+			s.hasDate = true
+		case strings.HasPrefix(name, DatePlaceholder+":") && len(name) > len(DatePlaceholder)+1:
+			s.pattern = append(s.pattern, segment{layout: name[len(DatePlaceholder)+1:]})
+			s.hasDate = true
+		default:
+			return nil, fmt.Errorf("invalid index pattern '%s': unknown placeholder {%s} (valid: {%s}, {%s}, {%s:<layout>})", pattern, name, MeasurementPlaceholder, DatePlaceholder, DatePlaceholder)
+		}
+	}
+	if len(s.pattern) == 0 {
+		return nil, fmt.Errorf("empty index pattern")
+	}
+	return s, nil
+}
+
+// Serialize writes Point p to w as an action line creating a document in
+// its index, and the document, e.g.:
+//
+// {"create":{"_index":"tsbs-cpu-2016.01.01"}}
+// {"@timestamp":"2016-01-01T00:00:00Z","hostname":"host_0","region":"eu-west-1","usage_user":58.13}
+//
+// The action is create, which data streams require. Documents have the
+// timestamp of the reading as @timestamp, in RFC 3339 in UTC, then its tags
+// and fields as top level keys, and its measurement as measurement if the
+// index pattern does not have it. Tags with empty values, and NaN and
+// infinite values, which Elasticsearch does not index, are left out.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = append(buf, `{"create":{"_index":"`...)
+	buf = s.appendIndex(buf, measurementName, timestamp)
+	buf = append(buf, "\"}}\n{\""+TimestampKey+"\":\""...)
+	buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, time.RFC3339Nano)
+	buf = append(buf, '"')
+	if !s.hasMeasurement {
+		buf = append(buf, `,"`+MeasurementKey+`":`...)
+		buf = serialize.AppendJSONString(buf, measurementName)
+	}
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = serialize.AppendJSONString(buf, tagKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	for i, v := range fieldValues {
+		if !finite(v) {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = serialize.AppendJSONString(buf, fieldKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONValue(buf, v)
+	}
+	return append(buf, "}\n"...)
+}
+
+// appendIndex appends the name of the index of a reading to buf
+func (s *Serializer) appendIndex(buf []byte, measurementName []byte, timestamp int64) []byte {
+	if s.hasDate {
+		s.formatDates(timestamp)
+	}
+	start := len(buf)
+	date := 0
+	for _, seg := range s.pattern {
+		switch {
+		case seg.measurement:
+			buf = append(buf, measurementName...)
+		case len(seg.layout) > 0:
+			buf = append(buf, s.dates[date]...)
+			date++
+		default:
+			buf = append(buf, seg.literal...)
+		}
+	}
+	sanitizeIndex(buf[start:])
+	return buf
+}
+
+// formatDates sets dates to the dates of the pattern at timestamp, unless
+// they are those of the last timestamp formatted, which is in the same
+// second
+func (s *Serializer) formatDates(timestamp int64) {
+	second := timestamp / 1e9
+	if timestamp < 0 && timestamp%1e9 != 0 {
+		second--
+	}
+	if second == s.second {
+		return
+	}
+	s.second = second
+	t := time.Unix(second, 0).UTC()
+	s.dates = s.dates[:0]
+	for _, seg := range s.pattern {
+		if len(seg.layout) > 0 {
+			s.dates = append(s.dates, t.Format(seg.layout))
+		}
+	}
+}
+
+// sanitizeIndex makes name a valid index name in place: lowercase, with
+// the characters index names may not have replaced by underscores
+func sanitizeIndex(name []byte) {
+	for i, c := range name {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			name[i] = c + 'a' - 'A'
+		case c <= ' ' || strings.IndexByte(`\/*?"<>|,#:`, c) >= 0:
+			name[i] = '_'
+		}
+	}
+}
+
+// finite returns whether v is not a NaN or infinite float
+func finite(v interface{}) bool {
+	switch x := v.(type) {
+	case float64:
+		return !math.IsNaN(x) && !math.IsInf(x, 0)
+	case float32:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	}
+	return true
+}
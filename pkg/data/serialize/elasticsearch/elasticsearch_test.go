@@ -0,0 +1,144 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const (
+	testAction = `{"create":{"_index":"tsbs-cpu-2016.01.01"}}` + "\n"
+	testDoc    = `{"@timestamp":"2016-01-01T00:00:00Z","hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"`
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testAction + testDoc + `,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testAction + testDoc + `,"usage_guest":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testAction + testDoc + `,"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     testAction + `{"@timestamp":"2016-01-01T00:00:00Z","usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	s, err := NewSerializer(DefaultIndexPattern)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serializetest.CheckSerializer(t, serializeCases, s)
+}
+
+func TestSerializerConformance(t *testing.T) {
+	for _, format := range []string{Format, Format + ":" + DefaultIndexPattern} {
+		serializetest.Suite{
+			New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+				return serialize.New(format, schema, w)
+			},
+			Golden: serializeCases,
+		}.Run(t)
+	}
+}
+
+func TestIndexPattern(t *testing.T) {
+	day := time.Date(2016, 1, 1, 23, 59, 59, 0, time.UTC)
+	cases := []struct {
+		pattern string
+		time    time.Time
+		want    string
+	}{
+		{pattern: DefaultIndexPattern, time: day, want: "tsbs-disk_io-2016.01.01"},
+		{pattern: DefaultIndexPattern, time: day.Add(time.Second), want: "tsbs-disk_io-2016.01.02"},
+		{pattern: "metrics-{measurement}-{date:2006.01}", time: day, want: "metrics-disk_io-2016.01"},
+		{pattern: "Metrics-{date:2006-01-02T15}", time: day, want: "metrics-2016-01-01t23"},
+		{pattern: "tsbs", time: day, want: "tsbs"},
+		{pattern: "{measurement}", time: time.Unix(0, -1), want: "disk_io"},
+	}
+	for _, c := range cases {
+		s, err := NewSerializer(c.pattern)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.pattern, err)
+			continue
+		}
+		if got := string(s.appendIndex(nil, []byte("Disk IO"), c.time.UnixNano())); got != c.want {
+			t.Errorf("%s: incorrect index at %v: got %s want %s", c.pattern, c.time, got, c.want)
+		}
+	}
+
+	for _, pattern := range []string{"", "tsbs-{measurement", "tsbs-{host}", "tsbs-{date:}"} {
+		if _, err := NewSerializer(pattern); err == nil {
+			t.Errorf("%q: invalid pattern accepted", pattern)
+		}
+	}
+	if _, err := serialize.New(Format+":tsbs-{hostname}", nil, io.Discard); err == nil {
+		t.Errorf("invalid pattern accepted as format")
+	}
+}
+
+func TestSerializeDocument(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.SetTimestamp(time.Date(2016, 1, 1, 0, 0, 0, 1500000, time.UTC).UnixNano())
+	p.AppendTag([]byte("path"), []byte(`C:\ "d"`))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendField([]byte("free"), math.NaN())
+	p.AppendField([]byte("used"), math.Inf(1))
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("state"), "full")
+
+	s, err := NewSerializer("tsbs-{date}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := s.Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"create":{"_index":"tsbs-2016.01.01"}}` + "\n" +
+		`{"@timestamp":"2016-01-01T00:00:00.0015Z","measurement":"disk","path":"C:\\ \"d\"","ok":true,"state":"full"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestSerializerJSON(t *testing.T) {
+	s, err := NewSerializer(DefaultIndexPattern)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	for _, p := range serializetest.FuzzPoints() {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			t.Errorf("line %d: invalid JSON %q: %v", i, line, err)
+		} else if _, isAction := v["create"]; isAction != (i%2 == 0) {
+			t.Errorf("line %d: action and document lines out of order: %q", i, line)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package external implements the exec:<command> formats, which hand every
+// point to a user-provided subprocess so serializers for databases TSBS does
+// not support can be written in any language while reusing its simulators.
+//
+// The command is run with "sh -c". Its stdout becomes the generated output
+// and its stderr is passed through, while its stdin receives a stream of
+// frames, each made of a one byte kind, a uint32 payload length and the
+// payload. All integers are big-endian, and strings are a uint16 length
+// followed by that many bytes. The stream starts with exactly one schema
+// frame:
+//
+//	'S' uint8 version (1)
+//	    uint16 number of tag keys, then each tag key as a string
+//	    uint16 number of measurements, then for each:
+//	        the measurement name as a string
+//	        uint16 number of field keys, then each field key as a string
+//
+// followed by one point frame per point, in the order they are generated:
+//
+//	'P' int64 timestamp in nanoseconds since the Unix epoch
+//	    the measurement name as a string
+//	    uint16 number of tags, then each as a key string and value string
+//	    uint16 number of fields, then each as a key string and a value
+//
+// where a value is a one byte type followed by its data: 'n' for none, 'i'
+// an int64, 'f' a float64 in IEEE 754 format, 'b' a uint8 of 0 or 1, or 's'
+// a string. The stream ends when stdin is closed, after which the command
+// should write any remaining output and exit with status 0.
+//
+// Readers should skip frames of unknown kinds using the payload length, so
+// new kinds can be added without breaking existing serializers.
+package external
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Scheme is the scheme the formats are registered under, i.e., formats are
+// named Scheme + ":" + command
+const Scheme = "exec"
+
+// ProtocolVersion is the version sent in the schema frame. It is incremented
+// for changes existing serializers cannot ignore.
+const ProtocolVersion = 1
+
+// Frame kinds
+const (
+	FrameSchema = 'S'
+	FramePoint  = 'P'
+)
+
+// Value types
+const (
+	ValueNone   = 'n'
+	ValueInt    = 'i'
+	ValueFloat  = 'f'
+	ValueBool   = 'b'
+	ValueString = 's'
+)
+
+const (
+	frameHeaderLen = 5
+	stdinBufSize   = 1 << 20
+)
+
+func init() {
+	serialize.Describe(Scheme, "Points piped to a command that serializes them")
+	serialize.RegisterScheme(Scheme, func(command string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return Start(command, schema, w)
+	})
+}
+
+// Serializer writes each Point to the stdin of a subprocess, which in turn
+// writes the serialized form to the output. It must be closed once all
+// points are serialized so the subprocess finishes.
+type Serializer struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	out   *bufio.Writer
+	buf   []byte
+}
+
+// Start runs command with its stdout writing to w and sends it the schema
+// frame for schema
+func Start(command string, schema *serialize.Schema, w io.Writer) (*Serializer, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start serializer '%s': %v", command, err)
+	}
+	s := &Serializer{
+		cmd:   cmd,
+		stdin: stdin,
+		out:   bufio.NewWriterSize(stdin, stdinBufSize),
+	}
+	s.buf = AppendSchemaFrame(s.buf[:0], schema)
+	if _, err := s.out.Write(s.buf); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Serialize sends p to the subprocess. w is ignored, since the subprocess
+// writes to the output given to Start.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	s.buf = AppendPointFrame(s.buf[:0], p)
+	_, err := s.out.Write(s.buf)
+	return err
+}
+
+// Close ends the stream and waits for the subprocess to exit, returning an
+// error if it did not exit successfully
+func (s *Serializer) Close() error {
+	flushErr := s.out.Flush()
+	closeErr := s.stdin.Close()
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("serializer '%s' failed: %v", s.cmd.Args[2], err)
+	}
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// AppendSchemaFrame appends the schema frame for schema to buf
+func AppendSchemaFrame(buf []byte, schema *serialize.Schema) []byte {
+	buf, start := beginFrame(buf, FrameSchema)
+	buf = append(buf, ProtocolVersion)
+	tagKeys := schema.TagKeys()
+	buf = appendUint16(buf, uint16(len(tagKeys)))
+	for _, k := range tagKeys {
+		buf = appendString(buf, k)
+	}
+	buf = appendUint16(buf, uint16(schema.Len()))
+	for _, m := range schema.Measurements() {
+		buf = appendString(buf, []byte(m))
+		fieldKeys := schema.FieldKeys(m)
+		buf = appendUint16(buf, uint16(len(fieldKeys)))
+		for _, k := range fieldKeys {
+			buf = appendString(buf, k)
+		}
+	}
+	return endFrame(buf, start)
+}
+
+// AppendPointFrame appends the point frame for p to buf
+func AppendPointFrame(buf []byte, p *serialize.Point) []byte {
+	buf, start := beginFrame(buf, FramePoint)
+	buf = appendUint64(buf, uint64(p.Timestamp()))
+	buf = appendString(buf, p.MeasurementName())
+	tagKeys, tagValues := p.TagKeys(), p.TagValues()
+	buf = appendUint16(buf, uint16(len(tagKeys)))
+	for i, k := range tagKeys {
+		buf = appendString(buf, k)
+		buf = appendString(buf, tagValues[i])
+	}
+	fieldKeys, fieldValues := p.FieldKeys(), p.FieldValues()
+	buf = appendUint16(buf, uint16(len(fieldKeys)))
+	for i, k := range fieldKeys {
+		buf = appendString(buf, k)
+		buf = appendValue(buf, fieldValues[i])
+	}
+	return endFrame(buf, start)
+}
+
+// beginFrame appends the header of a frame of the given kind, returning
+// where it starts so endFrame can fill in the payload length
+func beginFrame(buf []byte, kind byte) ([]byte, int) {
+	start := len(buf)
+	return append(buf, kind, 0, 0, 0, 0), start
+}
+
+func endFrame(buf []byte, start int) []byte {
+	binary.BigEndian.PutUint32(buf[start+1:], uint32(len(buf)-start-frameHeaderLen))
+	return buf
+}
+
+func appendString(buf, s []byte) []byte {
+	buf = appendUint16(buf, uint16(len(s)))
+	return append(buf, s...)
+}
+
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(buf, ValueNone)
+	case int:
+		return appendUint64(append(buf, ValueInt), uint64(x))
+	case int64:
+		return appendUint64(append(buf, ValueInt), uint64(x))
+	case float64:
+		return appendUint64(append(buf, ValueFloat), math.Float64bits(x))
+	case float32:
+		return appendUint64(append(buf, ValueFloat), math.Float64bits(float64(x)))
+	case bool:
+		if x {
+			return append(buf, ValueBool, 1)
+		}
+		return append(buf, ValueBool, 0)
+	case []byte:
+		return appendString(append(buf, ValueString), x)
+	case string:
+		return appendString(append(buf, ValueString), []byte(x))
+	default:
+		panic(fmt.Sprintf("unknown field type for %#v", v))
+	}
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return append(buf, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,115 @@
+package external
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+func TestAppendPointFrame(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("m"))
+	p.SetTimestamp(1)
+	p.AppendTag([]byte("t"), []byte("v"))
+	p.AppendField([]byte("a"), 2)
+	p.AppendField([]byte("b"), true)
+	p.AppendField([]byte("c"), nil)
+	p.AppendField([]byte("d"), "x")
+
+	want := []byte{
+		'P', 0, 0, 0, 49,
+		0, 0, 0, 0, 0, 0, 0, 1, // timestamp
+		0, 1, 'm',
+		0, 1, // tags
+		0, 1, 't', 0, 1, 'v',
+		0, 4, // fields
+		0, 1, 'a', 'i', 0, 0, 0, 0, 0, 0, 0, 2,
+		0, 1, 'b', 'b', 1,
+		0, 1, 'c', 'n',
+		0, 1, 'd', 's', 0, 1, 'x',
+	}
+	if got := AppendPointFrame(nil, p); !bytes.Equal(got, want) {
+		t.Errorf("incorrect frame:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestAppendSchemaFrame(t *testing.T) {
+	schema := serialize.NewSchema([][]byte{[]byte("t")}, map[string][][]byte{
+		"m": {[]byte("a"), []byte("b")},
+	})
+	want := []byte{
+		'S', 0, 0, 0, 19,
+		ProtocolVersion,
+		0, 1, 0, 1, 't',
+		0, 1, 0, 1, 'm',
+		0, 2, 0, 1, 'a', 0, 1, 'b',
+	}
+	if got := AppendSchemaFrame(nil, schema); !bytes.Equal(got, want) {
+		t.Errorf("incorrect frame:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	schema := serialize.NewSchema(serializetest.TagKeys, map[string][][]byte{
+		string(serializetest.Measurement): {serializetest.ColFloat},
+	})
+
+	// cat echoes the stream back, so the output is exactly what was sent
+	var out bytes.Buffer
+	ps, err := serialize.New(Scheme+":cat", schema, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s, ok := ps.(*Serializer)
+	if !ok {
+		t.Fatalf("incorrect serializer type: %T", ps)
+	}
+	want := AppendSchemaFrame(nil, schema)
+	for _, p := range []*serialize.Point{serializetest.PointDefault, serializetest.PointNoTags} {
+		if err := s.Serialize(p, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want = AppendPointFrame(want, p)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if got := out.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("incorrect output:\ngot  %v\nwant %v", got, want)
+	}
+
+	s, err = Start("cat > /dev/null; exit 3", schema, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Close(); err == nil {
+		t.Errorf("did not error for failed command")
+	}
+}
+
+func TestSerializerConformance(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	serializetest.Suite{
+		// cat echoes the stream back, so the frames themselves are checked
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Scheme+":cat", schema, w)
+		},
+		Golden: []serializetest.Case{
+			{
+				Desc:       "a regular Point",
+				InputPoint: serializetest.PointDefault,
+				Output:     string(AppendPointFrame(nil, serializetest.PointDefault)),
+			},
+		},
+		IgnoresWriter: true,
+	}.Run(t)
+}
@@ -0,0 +1,41 @@
+package serialize
+
+import (
+	"math"
+	"strconv"
+)
+
+// FloatFormat is how AppendFloat writes floats in the text form of a target:
+// in the strconv format Fmt ('f' or 'g'), with a decimal point if Point, so
+// that they are not read as integers, and NaN and infinite values, which
+// have no number literals, as NaN, Inf and NegInf.
+type FloatFormat struct {
+	Fmt    byte
+	Point  bool
+	NaN    string
+	Inf    string
+	NegInf string
+}
+
+// AppendFloat appends f, of bitSize bits (32 or 64), to buf as ff describes.
+func AppendFloat(buf []byte, f float64, bitSize int, ff *FloatFormat) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(buf, ff.NaN...)
+	case math.IsInf(f, 1):
+		return append(buf, ff.Inf...)
+	case math.IsInf(f, -1):
+		return append(buf, ff.NegInf...)
+	}
+	start := len(buf)
+	buf = strconv.AppendFloat(buf, f, ff.Fmt, -1, bitSize)
+	if !ff.Point {
+		return buf
+	}
+	for _, c := range buf[start:] {
+		if c == '.' || c == 'e' {
+			return buf
+		}
+	}
+	return append(buf, ".0"...)
+}
@@ -0,0 +1,36 @@
+package serialize
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAppendFloat(t *testing.T) {
+	g := &FloatFormat{Fmt: 'g', NaN: "nan", Inf: "inf", NegInf: "-inf"}
+	point := &FloatFormat{Fmt: 'f', Point: true, NaN: "NaN", Inf: "+Inf", NegInf: "-Inf"}
+	cases := []struct {
+		f       float64
+		bitSize int
+		ff      *FloatFormat
+		want    string
+	}{
+		{58.13, 64, g, "58.13"},
+		{1e21, 64, g, "1e+21"},
+		{float64(float32(0.1)), 32, g, "0.1"},
+		{math.NaN(), 64, g, "nan"},
+		{math.Inf(1), 64, g, "inf"},
+		{math.Inf(-1), 32, g, "-inf"},
+		{58.13, 64, point, "58.13"},
+		{3, 64, point, "3.0"},
+		{1e21, 64, point, "1000000000000000000000.0"},
+		{math.NaN(), 64, point, "NaN"},
+		{math.Inf(1), 64, point, "+Inf"},
+		{math.Inf(-1), 64, point, "-Inf"},
+		{math.NaN(), 64, &FloatFormat{Fmt: 'g'}, ""},
+	}
+	for _, c := range cases {
+		if got := string(AppendFloat([]byte("x"), c.f, c.bitSize, c.ff)); got != "x"+c.want {
+			t.Errorf("%v with %+v: got %q want %q", c.f, *c.ff, got, "x"+c.want)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+// Package graphite implements the formats for Graphite: the plaintext and
+// pickle protocols of carbon, with tags flattened into dotted metric paths
+// by a template.
+package graphite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as carbon parses them
+var floatFormat = &serialize.FloatFormat{Fmt: 'g', NaN: "nan", Inf: "inf", NegInf: "-inf"}
+
+// Format is the name the plaintext format is registered under, and
+// FormatPickle that of the pickle format. Both write paths by
+// DefaultTemplate; formats of the form Format + ":" + template, or
+// FormatPickle + ":" + template, write paths by template.
+const (
+	Format       = "graphite"
+	FormatPickle = "graphite-pickle"
+)
+
+// DefaultTemplate is the template of the paths of Format and FormatPickle:
+// the measurement, the values of the tags and the field
+const DefaultTemplate = "{measurement}.{tags}.{field}"
+
+// Placeholders of path templates. Any other placeholder is the key of a tag,
+// replaced by its value.
+const (
+	MeasurementPlaceholder = "measurement"
+	FieldPlaceholder       = "field"
+	TagsPlaceholder        = "tags"
+)
+
+// Missing is the path component of a tag of a template a reading does not
+// have a value for
+const Missing = "none"
+
+func init() {
+	serialize.Describe(Format, "Graphite plaintext protocol, with graphite:<template> flattening tags into paths (default "+DefaultTemplate+")")
+	serialize.Describe(FormatPickle, "Graphite pickle protocol, a message per reading, with graphite-pickle:<template> like graphite")
+	for _, pickle := range []bool{false, true} {
+		pickle := pickle
+		name := Format
+		if pickle {
+			name = FormatPickle
+		}
+		serialize.Register(name, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return NewSerializer(DefaultTemplate, pickle)
+		})
+		serialize.RegisterScheme(name, func(template string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return NewSerializer(template, pickle)
+		})
+	}
+}
+
+// segment kinds of a path template
+const (
+	literalSegment = iota
+	measurementSegment
+	fieldSegment
+	tagsSegment
+	tagSegment
+)
+
+// segment is a part of a path template: literal text, a placeholder, or the
+// value of the tag with key text
+type segment struct {
+	kind int
+	text string
+}
+
+// Serializer writes a Point in a serialized form for Graphite
+type Serializer struct {
+	template []segment
+	pickle   bool
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// NewSerializer returns a Serializer writing metrics with paths by template,
+// in the pickle protocol if pickle is set or else the plaintext protocol.
+// template is text with placeholders: {measurement}, {field}, {tags} for
+// the values of the tags of each reading, separated by dots, and {<key>}
+// for the value of the tag with that key, e.g., {measurement}.{hostname}.{field}.
+// It must have {field}, so each field of a reading has its own path.
+func NewSerializer(template string, pickle bool) (*Serializer, error) {
+	s := &Serializer{pickle: pickle}
+	hasField := false
+	rest := template
+	for len(rest) > 0 {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			s.template = append(s.template, segment{kind: literalSegment, text: rest})
+			break
+		}
+		if open > 0 {
+			s.template = append(s.template, segment{kind: literalSegment, text: rest[:open]})
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid path template '%s': unclosed {", template)
+		}
+		name := rest[open+1 : open+end]
+		rest = rest[open+end+1:]
+		switch name {
+		case MeasurementPlaceholder:
+			s.template = append(s.template, segment{kind: measurementSegment})
+		case FieldPlaceholder:
+			s.template = append(s.template, segment{kind: fieldSegment})
+			hasField = true
+		case TagsPlaceholder:
+			s.template = append(s.template, segment{kind: tagsSegment})
+		case "":
+			return nil, fmt.Errorf("invalid path template '%s': empty placeholder {}", template)
+		default:
+			s.template = append(s.template, segment{kind: tagSegment, text: name})
+		}
+	}
+	if !hasField {
+		return nil, fmt.Errorf("invalid path template '%s': no {%s}", template, FieldPlaceholder)
+	}
+	return s, nil
+}
+
+// Serialize writes Point p to w as a metric for each of its fields. In the
+// plaintext protocol each is a line of its path, value and timestamp in
+// seconds, e.g.:
+//
+// cpu.host_0.eu-west-1.eu-west-1b.usage_user 58.13 1451606400
+//
+// In the pickle protocol they are a message of a list of (path, (timestamp,
+// value)) tuples, pickled with protocol 2 and preceded by its length as a
+// 4 byte big-endian integer, as carbon takes them.
+//
+// Paths are the template with its placeholders replaced. Characters other
+// than letters, digits, '-' and '_' in measurements, fields and tag values
+// are replaced by underscores, so they are each a single component of the
+// path. Tags with empty values are left out of {tags}, and a tag of the
+// template without a value is Missing.
+//
+// Values must be numbers, so bools are written as 1 or 0 and strings are
+// left out. NaN and infinite values are written as nan, inf and -inf, as
+// Python parses them; carbon drops NaN values itself.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendMetrics(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendMetrics(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendMetrics(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	seconds := timestamp / 1e9
+	if timestamp < 0 && timestamp%1e9 != 0 {
+		seconds--
+	}
+
+	start := len(buf)
+	if s.pickle {
+		// the length is set once the message is written
+		buf = append(buf, 0, 0, 0, 0, 0x80, 2, ']', '(')
+	}
+	for i, v := range fieldValues {
+		if !isNumber(v) {
+			continue
+		}
+		if !s.pickle {
+			buf = s.appendPath(buf, measurementName, tagKeys, tagValues, fieldKeys[i])
+			buf = append(buf, ' ')
+			buf = appendValue(buf, v)
+			buf = append(buf, ' ')
+			buf = strconv.AppendInt(buf, seconds, 10)
+			buf = append(buf, '\n')
+			continue
+		}
+		// the path as BINUNICODE, with its length set once it is written
+		buf = append(buf, 'X', 0, 0, 0, 0)
+		pathStart := len(buf)
+		buf = s.appendPath(buf, measurementName, tagKeys, tagValues, fieldKeys[i])
+		binary.LittleEndian.PutUint32(buf[pathStart-4:], uint32(len(buf)-pathStart))
+		buf = appendPickleInt(buf, seconds)
+		buf = append(buf, 'G')
+		buf = appendUint64(buf, math.Float64bits(toFloat(v)))
+		// TUPLE2 of the timestamp and value, and of the path and that
+		buf = append(buf, 0x86, 0x86)
+	}
+	if s.pickle {
+		// APPENDS and STOP
+		buf = append(buf, 'e', '.')
+		binary.BigEndian.PutUint32(buf[start:], uint32(len(buf)-start-4))
+	}
+	return buf
+}
+
+// appendPath appends the path of field fieldKey of a reading to buf
+func (s *Serializer) appendPath(buf []byte, measurementName []byte, tagKeys, tagValues [][]byte, fieldKey []byte) []byte {
+	for _, seg := range s.template {
+		switch seg.kind {
+		case literalSegment:
+			buf = append(buf, seg.text...)
+		case measurementSegment:
+			buf = AppendComponent(buf, measurementName)
+		case fieldSegment:
+			buf = AppendComponent(buf, fieldKey)
+		case tagsSegment:
+			first := true
+			for _, v := range tagValues {
+				if len(v) == 0 {
+					continue
+				}
+				if !first {
+					buf = append(buf, '.')
+				}
+				first = false
+				buf = AppendComponent(buf, v)
+			}
+		case tagSegment:
+			value := []byte(nil)
+			for i, k := range tagKeys {
+				if string(k) == seg.text {
+					value = tagValues[i]
+					break
+				}
+			}
+			if len(value) == 0 {
+				buf = append(buf, Missing...)
+			} else {
+				buf = AppendComponent(buf, value)
+			}
+		}
+	}
+	return buf
+}
+
+// AppendComponent appends a component of a path to buf: name with the
+// characters other than ASCII letters and digits, '-', '_' and the bytes of
+// multibyte UTF-8 characters replaced by underscores, so that it is a single
+// component that carbon can store as a file name
+func AppendComponent(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c >= 0x80, c == '-', c == '_':
+		default:
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// isNumber returns whether v is written as the value of a metric: a number
+// or a bool
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case []byte, string, nil:
+		return false
+	}
+	return true
+}
+
+// appendValue appends a field value to buf as a number of the plaintext
+// protocol
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case float64:
+		return serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	case bool:
+		if x {
+			return append(buf, '1')
+		}
+		return append(buf, '0')
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// toFloat returns a field value as the float carbon stores it as
+func toFloat(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case float32:
+		return float64(x)
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case bool:
+		if x {
+			return 1
+		}
+		return 0
+	}
+	f, _ := strconv.ParseFloat(string(serialize.FastFormatAppend(v, nil)), 64)
+	return f
+}
+
+// appendPickleInt appends x to buf pickled, as a BININT if it fits in 32
+// bits or else as a LONG1 of its minimal little-endian two's complement bytes
+func appendPickleInt(buf []byte, x int64) []byte {
+	if x >= math.MinInt32 && x <= math.MaxInt32 {
+		buf = append(buf, 'J', 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(buf[len(buf)-4:], uint32(x))
+		return buf
+	}
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(x))
+	n := len(b)
+	for n > 1 && (b[n-1] == 0 && b[n-2]&0x80 == 0 || b[n-1] == 0xff && b[n-2]&0x80 != 0) {
+		n--
+	}
+	buf = append(buf, 0x8a, byte(n))
+	return append(buf, b[:n]...)
+}
+
+func appendUint64(buf []byte, x uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], x)
+	return append(buf, b[:]...)
+}
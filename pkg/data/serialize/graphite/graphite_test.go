@@ -0,0 +1,161 @@
+package graphite
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testPath = "cpu.host_0.eu-west-1.eu-west-1b."
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testPath + "usage_guest_nice 38.24311829 1451606400\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testPath + "usage_guest 38 1451606400\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: testPath + "big_usage_guest 5000000000 1451606400\n" +
+			testPath + "usage_guest 38 1451606400\n" +
+			testPath + "usage_guest_nice 38.24311829 1451606400\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "cpu..usage_guest_nice 38.24311829 1451606400\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	s, err := NewSerializer(DefaultTemplate, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serializetest.CheckSerializer(t, serializeCases, s)
+}
+
+func TestSerializerConformance(t *testing.T) {
+	for _, format := range []string{Format, Format + ":tsbs.{hostname}.{measurement}.{field}"} {
+		golden := serializeCases
+		if format != Format {
+			golden = nil
+		}
+		serializetest.Suite{
+			New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+				return serialize.New(format, schema, w)
+			},
+			Golden:    golden,
+			OmitsTags: format != Format,
+		}.Run(t)
+	}
+}
+
+func TestPickleConformance(t *testing.T) {
+	for _, format := range []string{FormatPickle, FormatPickle + ":{region}.{field}"} {
+		serializetest.Suite{
+			New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+				return serialize.New(format, schema, w)
+			},
+			OmitsTags: format != FormatPickle,
+		}.Run(t)
+	}
+}
+
+func newPoint() *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk.io"))
+	p.SetTimestamp(1451606400999999999)
+	p.AppendTag([]byte("hostname"), []byte("10.0.0.1"))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendTag([]byte("path"), []byte("/dev/sda 1"))
+	p.AppendField([]byte("free"), math.Inf(-1))
+	p.AppendField([]byte("state"), "full")
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("reads"), int64(7))
+	return p
+}
+
+func TestPathTemplate(t *testing.T) {
+	cases := []struct {
+		template string
+		want     string
+	}{
+		{template: DefaultTemplate, want: "disk_io.10_0_0_1._dev_sda_1.free -inf 1451606400\ndisk_io.10_0_0_1._dev_sda_1.ok 1 1451606400\ndisk_io.10_0_0_1._dev_sda_1.reads 7 1451606400\n"},
+		{template: "servers.{hostname}.{measurement}.{field}", want: "servers.10_0_0_1.disk_io.free -inf 1451606400\nservers.10_0_0_1.disk_io.ok 1 1451606400\nservers.10_0_0_1.disk_io.reads 7 1451606400\n"},
+		{template: "{empty}.{rack}.{field}", want: "none.none.free -inf 1451606400\nnone.none.ok 1 1451606400\nnone.none.reads 7 1451606400\n"},
+	}
+	for _, c := range cases {
+		s, err := NewSerializer(c.template, false)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.template, err)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := s.Serialize(newPoint(), &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := buf.String(); got != c.want {
+			t.Errorf("%s: incorrect output:\ngot  %q\nwant %q", c.template, got, c.want)
+		}
+	}
+
+	for _, template := range []string{"", "{measurement}.{hostname}", "{measurement}.{field", "{}.{field}"} {
+		if _, err := NewSerializer(template, false); err == nil {
+			t.Errorf("%q: invalid template accepted", template)
+		}
+	}
+	if _, err := serialize.New(FormatPickle+":{measurement}", nil, io.Discard); err == nil {
+		t.Errorf("invalid template accepted as format")
+	}
+}
+
+func TestSerializePickle(t *testing.T) {
+	s, err := NewSerializer("{hostname}.{field}", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := s.Serialize(newPoint(), &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// pickle.dumps([("10_0_0_1.free", (1451606400, -inf)), ("10_0_0_1.ok", (1451606400, 1.0)),
+	// ("10_0_0_1.reads", (1451606400, 7.0))], 2), without the memoization Python adds
+	want := "\x00\x00\x00\x6b" + "\x80\x02](" +
+		"X\x0d\x00\x00\x0010_0_0_1.free" + "J\x80\xc1\x85V" + "G\xff\xf0\x00\x00\x00\x00\x00\x00" + "\x86\x86" +
+		"X\x0b\x00\x00\x0010_0_0_1.ok" + "J\x80\xc1\x85V" + "G\x3f\xf0\x00\x00\x00\x00\x00\x00" + "\x86\x86" +
+		"X\x0e\x00\x00\x0010_0_0_1.reads" + "J\x80\xc1\x85V" + "G\x40\x1c\x00\x00\x00\x00\x00\x00" + "\x86\x86" +
+		"e."
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestAppendPickleInt(t *testing.T) {
+	cases := []struct {
+		x    int64
+		want string
+	}{
+		{0, "J\x00\x00\x00\x00"},
+		{-1, "J\xff\xff\xff\xff"},
+		{math.MaxInt32 + 1, "\x8a\x05\x00\x00\x00\x80\x00"},
+		{math.MinInt32 - 1, "\x8a\x05\xff\xff\xff\x7f\xff"},
+		{math.MaxInt64, "\x8a\x08\xff\xff\xff\xff\xff\xff\xff\x7f"},
+		{math.MinInt64, "\x8a\x08\x00\x00\x00\x00\x00\x00\x00\x80"},
+	}
+	for _, c := range cases {
+		if got := string(appendPickleInt(nil, c.x)); got != c.want {
+			t.Errorf("incorrect pickle of %d: got %q want %q", c.x, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package serialize
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// HeaderVersion is the version of the header layout written by WriteHeader.
+// New fields may be appended without incrementing it, since the header
+// records its own length; it is only incremented for incompatible changes.
+const HeaderVersion = 1
+
+// headerMagic starts every header. No supported format can begin with these
+// bytes, so data without a header is told apart from data with one.
+var headerMagic = []byte("TSBS")
+
+// headerPrefixLen is the length of the magic, version and body length that
+// precede the body of the header
+const headerPrefixLen = 4 + 1 + 2
+
+// Header describes a generated data file so that loaders can check it is what
+// they expect before consuming it, rather than loading garbage. It is written
+// before any other output, including the header of the format itself (e.g.,
+// TimescaleDB's list of tables).
+//
+// The binary layout is the magic "TSBS", a uint8 header version and a uint16
+// length of the body that follows; the body is a uint32 generator version, an
+// int64 seed, a uint64 schema hash, and a uint8 length followed by the format
+// name. All integers are big endian.
+type Header struct {
+	// Format is the name of the format the data is serialized in
+	Format string
+	// GeneratorVersion is the version of the generator that produced the data
+	GeneratorVersion uint32
+	// Seed is the seed the data was generated with
+	Seed int64
+	// SchemaHash is the Hash of the Schema of the data
+	SchemaHash uint64
+}
+
+// String returns a short human readable description of h
+func (h *Header) String() string {
+	return fmt.Sprintf("format %s, generator version %d, seed %d, schema %016x", h.Format, h.GeneratorVersion, h.Seed, h.SchemaHash)
+}
+
+// WriteHeader writes h to w in the binary header layout
+func WriteHeader(w io.Writer, h *Header) error {
+	if len(h.Format) > 255 {
+		return fmt.Errorf("format name too long for header: %s", h.Format)
+	}
+	body := make([]byte, 0, 21+len(h.Format))
+	body = appendUint32(body, h.GeneratorVersion)
+	body = appendUint64(body, uint64(h.Seed))
+	body = appendUint64(body, h.SchemaHash)
+	body = append(body, byte(len(h.Format)))
+	body = append(body, h.Format...)
+
+	buf := make([]byte, 0, headerPrefixLen+len(body))
+	buf = append(buf, headerMagic...)
+	buf = append(buf, HeaderVersion)
+	buf = append(buf, byte(len(body)>>8), byte(len(body)))
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadHeader reads a header from the start of br. If the data does not start
+// with a header (e.g., it was generated before headers were added), nothing
+// is consumed and it returns nil with no error.
+func ReadHeader(br *bufio.Reader) (*Header, error) {
+	prefix, err := br.Peek(headerPrefixLen)
+	if !bytes.HasPrefix(prefix, headerMagic) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("truncated header: %v", err)
+	}
+	if version := prefix[len(headerMagic)]; version > HeaderVersion {
+		return nil, fmt.Errorf("header version %d is newer than supported version %d; upgrade tsbs", version, HeaderVersion)
+	}
+	bodyLen := int(binary.BigEndian.Uint16(prefix[len(headerMagic)+1:]))
+	if _, err := br.Discard(headerPrefixLen); err != nil {
+		return nil, err
+	}
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, fmt.Errorf("truncated header: %v", err)
+	}
+
+	// fields appended by later versions are skipped
+	if len(body) < 21 || len(body) < 21+int(body[20]) {
+		return nil, fmt.Errorf("header body too short: %d bytes", len(body))
+	}
+	return &Header{
+		GeneratorVersion: binary.BigEndian.Uint32(body),
+		Seed:             int64(binary.BigEndian.Uint64(body[4:])),
+		SchemaHash:       binary.BigEndian.Uint64(body[12:]),
+		Format:           string(body[21 : 21+int(body[20])]),
+	}, nil
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(v>>32)), uint32(v))
+}
@@ -0,0 +1,119 @@
+package serialize
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHeaderRoundTrip(t *testing.T) {
+	h := &Header{
+		Format:           "influx",
+		GeneratorVersion: 2,
+		Seed:             -123,
+		SchemaHash:       0xdeadbeefcafe,
+	}
+	var buf bytes.Buffer
+	if err := WriteHeader(&buf, h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf.WriteString("cpu,hostname=host_0 usage_user=1 0\n")
+
+	br := bufio.NewReader(&buf)
+	got, err := ReadHeader(br)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil {
+		t.Fatalf("header not found")
+	}
+	if *got != *h {
+		t.Errorf("incorrect header: got %v want %v", got, h)
+	}
+	rest, _ := br.ReadString('\n')
+	if rest != "cpu,hostname=host_0 usage_user=1 0\n" {
+		t.Errorf("header not fully consumed: got %q", rest)
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	var valid bytes.Buffer
+	WriteHeader(&valid, &Header{Format: "mongo", GeneratorVersion: 1})
+	// a later version of the header with an extra field appended
+	extended := append([]byte{}, valid.Bytes()...)
+	extended[headerPrefixLen-1] += 3
+	extended = append(extended, 1, 2, 3)
+	newer := append([]byte{}, valid.Bytes()...)
+	newer[len(headerMagic)] = HeaderVersion + 1
+
+	cases := []struct {
+		desc       string
+		input      []byte
+		wantFormat string
+		wantRest   string
+		errPrefix  string
+	}{
+		{
+			desc:     "no header",
+			input:    []byte("cpu,hostname=host_0 usage_user=1 0\n"),
+			wantRest: "cpu,hostname=host_0 usage_user=1 0\n",
+		},
+		{
+			desc:     "short input without header",
+			input:    []byte("TS"),
+			wantRest: "TS",
+		},
+		{
+			desc:       "valid header",
+			input:      append(append([]byte{}, valid.Bytes()...), "rest"...),
+			wantFormat: "mongo",
+			wantRest:   "rest",
+		},
+		{
+			desc:       "extended header",
+			input:      append(extended, "rest"...),
+			wantFormat: "mongo",
+			wantRest:   "rest",
+		},
+		{
+			desc:      "newer header version",
+			input:     newer,
+			errPrefix: "header version",
+		},
+		{
+			desc:      "truncated prefix",
+			input:     valid.Bytes()[:headerPrefixLen-1],
+			errPrefix: "truncated header",
+		},
+		{
+			desc:      "truncated body",
+			input:     valid.Bytes()[:valid.Len()-1],
+			errPrefix: "truncated header",
+		},
+	}
+	for _, c := range cases {
+		br := bufio.NewReader(bytes.NewReader(c.input))
+		h, err := ReadHeader(br)
+		if c.errPrefix != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), c.errPrefix) {
+				t.Errorf("%s: incorrect error: got %v want prefix %s", c.desc, err, c.errPrefix)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if c.wantFormat == "" && h != nil {
+			t.Errorf("%s: unexpected header: %v", c.desc, h)
+		} else if c.wantFormat != "" && (h == nil || h.Format != c.wantFormat) {
+			t.Errorf("%s: incorrect header: got %v want format %s", c.desc, h, c.wantFormat)
+		}
+		rest := make([]byte, len(c.input))
+		n, _ := br.Read(rest)
+		if got := string(rest[:n]); got != c.wantRest {
+			t.Errorf("%s: incorrect remaining input: got %q want %q", c.desc, got, c.wantRest)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package influx
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "influx"
+
+func init() {
+	serialize.Describe(Format, "InfluxDB line protocol")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_influx, tsbs_load_influx3 and tsbs_load_greptime
+	serialize.MarkHeaderFormat(Format)
+}
+
+// Serializer writes a Point in a serialized form for InfluxDB
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point data to the given writer, conforming to the
+// InfluxDB wire protocol.
+//
+// This function writes output that looks like:
+// <measurement>,<tag key>=<tag value> <field name>=<field value> <timestamp>\n
+//
+// For example:
+// foo,tag0=bar baz=-1.0 100\n
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) (err error) {
+	buf := appendInfluxLine(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err = w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) (err error) {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendInfluxLine(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err = w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendInfluxLine(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = append(buf, measurementName...)
+
+	for i := 0; i < len(tagKeys); i++ {
+		buf = append(buf, ',')
+		buf = append(buf, tagKeys[i]...)
+		buf = append(buf, '=')
+		buf = append(buf, tagValues[i]...)
+	}
+
+	if len(fieldKeys) > 0 {
+		buf = append(buf, ' ')
+	}
+
+	for i := 0; i < len(fieldKeys); i++ {
+		buf = append(buf, fieldKeys[i]...)
+		buf = append(buf, '=')
+
+		switch v := fieldValues[i].(type) {
+		case []byte:
+			buf = appendString(buf, v)
+		case string:
+			buf = appendString(buf, []byte(v))
+		case int, int64:
+			// Influx uses 'i' to indicate integers:
+			buf = serialize.FastFormatAppend(v, buf)
+			buf = append(buf, 'i')
+		default:
+			buf = serialize.FastFormatAppend(v, buf)
+		}
+
+		if i+1 < len(fieldKeys) {
+			buf = append(buf, ',')
+		}
+	}
+
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, timestamp, 10)
+	buf = append(buf, '\n')
+	return buf
+}
+
+// appendString appends a string field value to buf, in double quotes with
+// any double quotes and backslashes in it escaped
+func appendString(buf []byte, s []byte) []byte {
+	buf = append(buf, '"')
+	for _, c := range s {
+		if c == '"' || c == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,65 @@
+package influx
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b usage_guest=38i 1451606400000000000\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b big_usage_guest=5000000000i,usage_guest=38i,usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "cpu usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializeStrings(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("thermostat"))
+	p.SetTimestamp(1451606400000000000)
+	p.AppendTag([]byte("home"), []byte("home_0"))
+	p.AppendField([]byte("mode"), []byte("heat"))
+	p.AppendField([]byte("label"), `say "hi"\`)
+	p.AppendField([]byte("on"), true)
+
+	var b bytes.Buffer
+	if err := (&Serializer{}).Serialize(p, &b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `thermostat,home=home_0 mode="heat",label="say \"hi\"\\",on=true 1451606400000000000` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
@@ -0,0 +1,313 @@
+// Package influx2 implements the format for InfluxDB 2.x: line protocol as
+// the v2 write API takes it, with nanosecond timestamps, after a header of
+// the org, bucket and measurement schemas of the data.
+package influx2
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats with NaN and infinite values, which are only
+// written in comments, as NaN, +Inf and -Inf
+var floatFormat = &serialize.FloatFormat{Fmt: 'f', NaN: "NaN", Inf: "+Inf", NegInf: "-Inf"}
+
+// Format is the name the format is registered under, which writes the data
+// for DefaultBucket of DefaultOrg. Formats of the form Format + ":" +
+// options, e.g., influx2:org=acme,bucket=metrics, write it for another org or
+// bucket.
+const Format = "influx2"
+
+// Defaults of the org and bucket of the header
+const (
+	DefaultOrg    = "tsbs"
+	DefaultBucket = "benchmark"
+)
+
+func init() {
+	serialize.Describe(Format, "InfluxDB 2.x line protocol after a header of the org, bucket and measurement schemas, with influx2:org=<org>,bucket=<bucket> setting them")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultOrg, DefaultBucket, w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		org, bucket := DefaultOrg, DefaultBucket
+		for _, option := range strings.Split(arg, ",") {
+			i := strings.IndexByte(option, '=')
+			if i < 0 {
+				return nil, fmt.Errorf("invalid InfluxDB 2 option '%s': must be option=value", option)
+			}
+			switch name, value := option[:i], option[i+1:]; name {
+			case "org":
+				org = value
+			case "bucket":
+				bucket = value
+			default:
+				return nil, fmt.Errorf("unknown InfluxDB 2 option '%s': must be org or bucket", name)
+			}
+		}
+		return NewSerializer(schema, org, bucket, w)
+	})
+}
+
+// Serializer writes a Point in a serialized form for InfluxDB 2.x
+type Serializer struct {
+	// fieldTypes are the types of the fields of each measurement
+	fieldTypes map[string]map[string]serialize.FieldType
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and order the order of the tags of the
+	// Point being written
+	buf   []byte
+	order []int
+}
+
+// NewSerializer returns a Serializer for data described by schema, which
+// writes fields as the types it has for them, and writes the header of the
+// data for the given org and bucket to w: comment lines, which the v2 write
+// API skips, of the org, the bucket, the precision of the timestamps and the
+// explicit schema of each measurement, as the JSON columns of
+// influx bucket-schema create, e.g.:
+//
+// #org tsbs
+// #bucket benchmark
+// #precision ns
+// #schema cpu [{"name":"time","type":"timestamp"},{"name":"hostname","type":"tag"},...,{"name":"usage_user","type":"field","dataType":"float"},...]
+//
+// Fields of unknown type are numbers from the simulators, which are floats.
+// schema may be nil, e.g., if it is not known, for a header without schemas
+// and fields written as the types of their values.
+func NewSerializer(schema *serialize.Schema, org, bucket string, w io.Writer) (*Serializer, error) {
+	if len(org) == 0 || len(bucket) == 0 {
+		return nil, fmt.Errorf("org and bucket must not be empty")
+	}
+	s := &Serializer{fieldTypes: make(map[string]map[string]serialize.FieldType)}
+	buf := []byte("#org " + oneLine(org) + "\n#bucket " + oneLine(bucket) + "\n#precision ns\n")
+	if schema != nil {
+		for _, m := range schema.Measurements() {
+			buf = append(buf, "#schema "+oneLine(m)+` [{"name":"time","type":"timestamp"}`...)
+			for _, key := range schema.TagKeysOf(m) {
+				buf = append(buf, `,{"name":`...)
+				buf = serialize.AppendJSONString(buf, key)
+				buf = append(buf, `,"type":"tag"}`...)
+			}
+			fieldTypes := schema.FieldTypes(m)
+			types := make(map[string]serialize.FieldType)
+			for i, key := range schema.FieldKeys(m) {
+				t := serialize.FieldTypeFloat
+				if i < len(fieldTypes) && fieldTypes[i] != serialize.FieldTypeUnknown {
+					t = fieldTypes[i]
+				}
+				types[string(key)] = t
+				buf = append(buf, `,{"name":`...)
+				buf = serialize.AppendJSONString(buf, key)
+				buf = append(buf, `,"type":"field","dataType":"`+dataType(t)+`"}`...)
+			}
+			buf = append(buf, "]\n"...)
+			s.fieldTypes[m] = types
+		}
+	}
+	_, err := w.Write(buf)
+	return s, err
+}
+
+// oneLine returns s with any line breaks replaced by spaces, so it fits a
+// line of the header
+func oneLine(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s)
+}
+
+// dataType returns the data type of the columns of an explicit schema of
+// fields of type t
+func dataType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "integer"
+	case serialize.FieldTypeBool:
+		return "boolean"
+	case serialize.FieldTypeString:
+		return "string"
+	default:
+		return "float"
+	}
+}
+
+// Serialize writes Point p to w as a line of line protocol, as of the v2
+// spec:
+//
+// cpu,hostname=host_0,region=eu-west-1 usage_user=58.13,usage_count=3i 1451606400000000000\n
+//
+// Tags are sorted by key, as InfluxDB 2 recommends for writes, and tags with
+// empty values, which line protocol cannot have, are left out. Fields are
+// written as the types the Schema has for them, so that they match the
+// measurement schemas of the header: ints as integers (with an i suffix),
+// unless the Schema has the field as a float, floats, bools as true or false
+// and strings quoted. NaN and infinite values, which InfluxDB does not
+// store, are left out, and a Point left without fields is written as a
+// comment, #, and its line with those values as NaN, +Inf and -Inf.
+//
+// Commas and spaces in measurement names, and commas, equals signs and
+// spaces in tag keys, tag values and field keys, are escaped with a
+// backslash, as are double quotes and backslashes in strings. Line breaks,
+// which line protocol cannot have in names and tag values, are replaced by
+// spaces.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendLine(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all lines of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendLine(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendLine(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	start := len(buf)
+	buf = appendEscaped(buf, measurementName, " ,")
+	s.sortTags(tagKeys)
+	for _, i := range s.order {
+		if len(tagValues[i]) == 0 {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = appendEscaped(buf, tagKeys[i], " ,=")
+		buf = append(buf, '=')
+		buf = appendEscaped(buf, tagValues[i], " ,=")
+	}
+
+	types := s.fieldTypes[string(measurementName)]
+	n := 0
+	for i, v := range fieldValues {
+		if v == nil || !isFinite(v) {
+			continue
+		}
+		if n == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		n++
+		buf = appendEscaped(buf, fieldKeys[i], " ,=")
+		buf = append(buf, '=')
+		buf = appendValue(buf, v, types[string(fieldKeys[i])])
+	}
+	if n == 0 {
+		// write the line as a comment of all its values, so the reading
+		// is not lost without a trace
+		buf = append(buf[:start], '#', ' ')
+		buf = appendEscaped(buf, measurementName, " ,")
+		for i, v := range fieldValues {
+			if i == 0 {
+				buf = append(buf, ' ')
+			} else {
+				buf = append(buf, ',')
+			}
+			buf = appendEscaped(buf, fieldKeys[i], " ,=")
+			buf = append(buf, '=')
+			buf = appendValue(buf, v, types[string(fieldKeys[i])])
+		}
+	}
+
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, timestamp, 10)
+	return append(buf, '\n')
+}
+
+// sortTags sets order to the indexes of tagKeys in the order of the keys
+func (s *Serializer) sortTags(tagKeys [][]byte) {
+	s.order = s.order[:0]
+	for i := range tagKeys {
+		s.order = append(s.order, i)
+	}
+	// insertion sort, as there are few tags
+	for i := 1; i < len(s.order); i++ {
+		for j := i; j > 0 && string(tagKeys[s.order[j]]) < string(tagKeys[s.order[j-1]]); j-- {
+			s.order[j], s.order[j-1] = s.order[j-1], s.order[j]
+		}
+	}
+}
+
+// isFinite returns whether v is not a NaN or infinite float
+func isFinite(v interface{}) bool {
+	switch x := v.(type) {
+	case float64:
+		return !math.IsNaN(x) && !math.IsInf(x, 0)
+	case float32:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	}
+	return true
+}
+
+// appendValue appends field value v to buf as the line protocol type of a
+// field of type t, or of the type of v if t is not known
+func appendValue(buf []byte, v interface{}, t serialize.FieldType) []byte {
+	switch x := v.(type) {
+	case int:
+		return appendInt(buf, int64(x), t)
+	case int64:
+		return appendInt(buf, x, t)
+	case float64:
+		return serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	case bool:
+		return strconv.AppendBool(buf, x)
+	case []byte:
+		return appendString(buf, x)
+	case string:
+		return appendString(buf, []byte(x))
+	case nil:
+		return append(buf, `""`...)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+func appendInt(buf []byte, x int64, t serialize.FieldType) []byte {
+	buf = strconv.AppendInt(buf, x, 10)
+	if t == serialize.FieldTypeFloat {
+		return buf
+	}
+	return append(buf, 'i')
+}
+
+// appendString appends s to buf as a quoted string field value
+func appendString(buf []byte, s []byte) []byte {
+	buf = append(buf, '"')
+	for _, c := range s {
+		if c == '"' || c == '\\' {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '"')
+}
+
+// appendEscaped appends name to buf with the characters of special escaped
+// with a backslash, and line breaks replaced by escaped spaces
+func appendEscaped(buf []byte, name []byte, special string) []byte {
+	for _, c := range name {
+		if c == '\n' || c == '\r' {
+			c = ' '
+		}
+		if strings.IndexByte(special, c) >= 0 {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
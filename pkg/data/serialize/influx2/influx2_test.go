@@ -0,0 +1,139 @@
+package influx2
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "cpu,datacenter=eu-west-1b,hostname=host_0,region=eu-west-1 big_usage_guest=5000000000,usage_guest=38,usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "cpu,datacenter=eu-west-1b,hostname=host_0,region=eu-west-1 usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "cpu,datacenter=eu-west-1b,hostname=host_0,region=eu-west-1 usage_guest=38 1451606400000000000\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "cpu usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializeConventions(t *testing.T) {
+	schema := serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"disk io": {[]byte("rack")}},
+		map[string][][]byte{
+			"cpu":     {[]byte("usage")},
+			"disk io": {[]byte("used=percent"), []byte("reads"), []byte("ok"), []byte("state")},
+		},
+		map[string][]serialize.FieldType{"disk io": {serialize.FieldTypeFloat, serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString}},
+	)
+	var buf bytes.Buffer
+	s, err := NewSerializer(schema, "acme", "metrics", &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantHeader := "#org acme\n#bucket metrics\n#precision ns\n" +
+		`#schema cpu [{"name":"time","type":"timestamp"},` +
+		`{"name":"hostname","type":"tag"},{"name":"region","type":"tag"},` +
+		`{"name":"usage","type":"field","dataType":"float"}]` + "\n" +
+		`#schema disk io [{"name":"time","type":"timestamp"},` +
+		`{"name":"hostname","type":"tag"},{"name":"region","type":"tag"},{"name":"rack","type":"tag"},` +
+		`{"name":"used=percent","type":"field","dataType":"float"},{"name":"reads","type":"field","dataType":"integer"},` +
+		`{"name":"ok","type":"field","dataType":"boolean"},{"name":"state","type":"field","dataType":"string"}]` + "\n"
+	if got := buf.String(); got != wantHeader {
+		t.Fatalf("incorrect header:\ngot  %q\nwant %q", got, wantHeader)
+	}
+
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk io"))
+	p.SetTimestamp(-1)
+	p.AppendTag([]byte("rack"), []byte("r 1,a=b"))
+	p.AppendTag([]byte("extra"), []byte("c\\d\ne"))
+	p.AppendTag([]byte("region"), nil)
+	p.AppendTag([]byte("hostname"), []byte("host_0"))
+	p.AppendField([]byte("used=percent"), 50)
+	p.AppendField([]byte("reads"), int64(7))
+	p.AppendField([]byte("nan"), math.NaN())
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("state"), "say \"hi\"\\\n")
+
+	buf.Reset()
+	if err := s.Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `disk\ io,extra=c\d\ e,hostname=host_0,rack=r\ 1\,a\=b used\=percent=50,reads=7i,ok=true,state="say \"hi\"\\` + "\n" + `" -1` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSerializeNonFinite(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("cpu"))
+	p.SetTimestamp(5)
+	p.AppendTag([]byte("hostname"), []byte("host_0"))
+	p.AppendField([]byte("a"), math.NaN())
+	p.AppendField([]byte("b"), math.Inf(1))
+	p.AppendField([]byte("c"), float32(math.Inf(-1)))
+
+	var buf bytes.Buffer
+	s, err := NewSerializer(nil, DefaultOrg, DefaultBucket, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "# cpu a=NaN,b=+Inf,c=-Inf 5\n"; got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSchemeOptions(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := serialize.New(Format+":bucket=metrics,org=acme", nil, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := buf.String(), "#org acme\n#bucket metrics\n#precision ns\n"; got != want {
+		t.Errorf("incorrect header:\ngot  %q\nwant %q", got, want)
+	}
+
+	for _, c := range []struct {
+		format string
+		errMsg string
+	}{
+		{Format + ":org", "invalid InfluxDB 2 option 'org'"},
+		{Format + ":token=secret", "unknown InfluxDB 2 option 'token'"},
+		{Format + ":bucket=", "org and bucket must not be empty"},
+	} {
+		if _, err := serialize.New(c.format, nil, io.Discard); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.format, err, c.errMsg)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package serialize
+
+import "math"
+
+const hex = "0123456789abcdef"
+
+// AppendJSONString appends s to buf as a JSON string, escaping quotes,
+// backslashes and control characters. Other bytes are appended as they are,
+// so s should be UTF-8.
+func AppendJSONString(buf []byte, s []byte) []byte {
+	buf = append(buf, '"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			buf = append(buf, '\\', c)
+		case c < 0x20:
+			buf = append(buf, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '"')
+}
+
+// AppendJSONValue appends a field value to buf as JSON: strings as strings,
+// numbers and bools as FastFormatAppend writes them and nil as null.
+// Infinite and NaN floats, which JSON has no numbers for, are written as the
+// strings "Infinity", "-Infinity" and "NaN".
+func AppendJSONValue(buf []byte, v interface{}) []byte {
+	var f float64
+	switch x := v.(type) {
+	case nil:
+		return append(buf, "null"...)
+	case []byte:
+		return AppendJSONString(buf, x)
+	case string:
+		return AppendJSONString(buf, []byte(x))
+	case float64:
+		f = x
+	case float32:
+		f = float64(x)
+	default:
+		return FastFormatAppend(v, buf)
+	}
+	switch {
+	case math.IsNaN(f):
+		return append(buf, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(buf, `"Infinity"`...)
+	case math.IsInf(f, -1):
+		return append(buf, `"-Infinity"`...)
+	}
+	return FastFormatAppend(v, buf)
+}
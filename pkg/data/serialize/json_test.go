@@ -0,0 +1,48 @@
+package serialize
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "host_0", `say "hi"`, `a\b`, "tab\tnew\nline\r", "\x00\x1f", "ünïcode"} {
+		got := AppendJSONString([]byte("x"), []byte(s))
+		var decoded string
+		if err := json.Unmarshal(got[1:], &decoded); err != nil {
+			t.Errorf("%q: invalid JSON %s: %v", s, got, err)
+		} else if decoded != s {
+			t.Errorf("%q: incorrect string: got %q", s, decoded)
+		}
+	}
+}
+
+func TestAppendJSONValue(t *testing.T) {
+	cases := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "null"},
+		{58.13, "58.13"},
+		{float32(2.5), "2.5"},
+		{1e21, "1000000000000000000000"},
+		{int64(-3), "-3"},
+		{7, "7"},
+		{true, "true"},
+		{"a\"b", `"a\"b"`},
+		{[]byte("c\nd"), `"c\u000ad"`},
+		{math.NaN(), `"NaN"`},
+		{math.Inf(1), `"Infinity"`},
+		{float32(math.Inf(-1)), `"-Infinity"`},
+	}
+	for _, c := range cases {
+		got := AppendJSONValue(nil, c.v)
+		if string(got) != c.want {
+			t.Errorf("%#v: incorrect JSON: got %s want %s", c.v, got, c.want)
+		}
+		if !json.Valid(got) {
+			t.Errorf("%#v: invalid JSON %s", c.v, got)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package jsonl implements the JSON Lines format, for ingestion pipelines
+// such as Fluentd, Logstash or HTTP collectors: a JSON object per reading,
+// with its timestamp, measurement, tags and fields.
+package jsonl
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes
+// timestamps as RFC 3339 strings. Formats of the form Format + ":" + unit,
+// e.g., jsonl:ms, write them as integers since the epoch in that unit.
+const Format = "jsonl"
+
+// Units of timestamps written as integers since the epoch
+const (
+	TimeNanoseconds  = "ns"
+	TimeMicroseconds = "us"
+	TimeMilliseconds = "ms"
+	TimeSeconds      = "s"
+)
+
+// units are the number of nanoseconds of each unit of timestamps
+var units = map[string]int64{
+	TimeNanoseconds:  1,
+	TimeMicroseconds: 1e3,
+	TimeMilliseconds: 1e6,
+	TimeSeconds:      1e9,
+}
+
+func init() {
+	serialize.Describe(Format, "JSON Lines of an object per reading with its timestamp, measurement, tags and fields, with jsonl:<ns|us|ms|s> for integer timestamps")
+	serialize.Register(Format, func(_ *serialize.Schema, _ io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	serialize.RegisterScheme(Format, func(arg string, _ *serialize.Schema, _ io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(arg)
+	})
+}
+
+// Serializer writes a Point in a serialized form as JSON Lines
+type Serializer struct {
+	// unit is the number of nanoseconds of the unit of integer timestamps,
+	// or 0 for RFC 3339 strings
+	unit int64
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// NewSerializer returns a Serializer writing timestamps as integers since
+// the epoch in unit, one of TimeNanoseconds, TimeMicroseconds,
+// TimeMilliseconds or TimeSeconds
+func NewSerializer(unit string) (*Serializer, error) {
+	n, ok := units[unit]
+	if !ok {
+		return nil, fmt.Errorf("invalid JSON Lines time unit '%s': must be %s, %s, %s or %s", unit, TimeNanoseconds, TimeMicroseconds, TimeMilliseconds, TimeSeconds)
+	}
+	return &Serializer{unit: n}, nil
+}
+
+// Serialize writes Point p to w as a line of a JSON object:
+//
+// {"timestamp":"2016-01-01T00:00:00Z","measurement":"cpu","tags":{"hostname":"host_0",...},"fields":{"usage_user":58.1317132304976170,...}}
+//
+// Tags and fields are in the order of the Point, and tags with empty values
+// are kept, as empty strings. Infinite and NaN values are written as the
+// strings Infinity, -Infinity and NaN, as JSON has no numbers for them.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendLine(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all lines of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendLine(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendLine(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = append(buf, `{"timestamp":`...)
+	if s.unit == 0 {
+		buf = append(buf, '"')
+		buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, time.RFC3339Nano)
+		buf = append(buf, '"')
+	} else {
+		// floor, so readings before the epoch are not rounded up to it
+		t := timestamp / s.unit
+		if timestamp%s.unit < 0 {
+			t--
+		}
+		buf = strconv.AppendInt(buf, t, 10)
+	}
+	buf = append(buf, `,"measurement":`...)
+	buf = serialize.AppendJSONString(buf, measurementName)
+	buf = append(buf, `,"tags":{`...)
+	for i, v := range tagValues {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = serialize.AppendJSONString(buf, tagKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	buf = append(buf, `},"fields":{`...)
+	for i, v := range fieldValues {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = serialize.AppendJSONString(buf, fieldKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONValue(buf, v)
+	}
+	return append(buf, "}}\n"...)
+}
@@ -0,0 +1,199 @@
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const (
+	testStart = `{"timestamp":"2016-01-01T00:00:00Z","measurement":"cpu",`
+	testTags  = `"tags":{"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"}`
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testStart + testTags + `,"fields":{"usage_guest_nice":38.24311829}}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testStart + testTags + `,"fields":{"usage_guest":38}}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testStart + testTags + `,"fields":{"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     testStart + `"tags":{},"fields":{"usage_guest_nice":38.24311829}}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden:       serializeCases,
+		Decode:       decodeLines,
+		IntsAsFloats: true,
+	}.Run(t)
+}
+
+func TestSerializerNanosecondsConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format+":"+TimeNanoseconds, schema, w)
+		},
+		Decode:       decodeLines,
+		IntsAsFloats: true,
+	}.Run(t)
+}
+
+// decodeLines decodes the lines of data into Points, keeping the order of
+// their tags and fields, which maps do not
+func decodeLines(data []byte) ([]*serialize.Point, error) {
+	var points []*serialize.Point
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var line struct {
+			Timestamp   json.RawMessage
+			Measurement string
+			Tags        json.RawMessage
+			Fields      json.RawMessage
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return nil, err
+		}
+		p := serialize.NewPoint()
+		p.SetMeasurementName([]byte(line.Measurement))
+		var s string
+		if err := json.Unmarshal(line.Timestamp, &s); err == nil {
+			ts, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, err
+			}
+			p.SetTimestamp(ts.UnixNano())
+		} else {
+			ts, err := strconv.ParseInt(string(line.Timestamp), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			p.SetTimestamp(ts)
+		}
+		err := decodeObject(line.Tags, func(key string, v interface{}) error {
+			s, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("tag %s is not a string: %v", key, v)
+			}
+			p.AppendTag([]byte(key), []byte(s))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = decodeObject(line.Fields, func(key string, v interface{}) error {
+			switch x := v.(type) {
+			case json.Number:
+				f, err := strconv.ParseFloat(string(x), 64)
+				if err != nil {
+					return err
+				}
+				v = f
+			case string:
+				f, err := strconv.ParseFloat(x, 64)
+				if err != nil {
+					return err
+				}
+				v = f
+			}
+			p.AppendField([]byte(key), v)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, scanner.Err()
+}
+
+// decodeObject calls f with each key and value of the JSON object data, in
+// order, with numbers as json.Number
+func decodeObject(data []byte, f func(key string, v interface{}) error) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if _, err := d.Token(); err != nil {
+		return err
+	}
+	for d.More() {
+		key, err := d.Token()
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			return err
+		}
+		if err := f(key.(string), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSerializerTimeUnits(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("cpu"))
+	p.AppendField([]byte("usage_user"), 1.5)
+	cases := []struct {
+		unit      string
+		timestamp int64
+		want      string
+	}{
+		{TimeNanoseconds, 1451606400123456789, "1451606400123456789"},
+		{TimeMicroseconds, 1451606400123456789, "1451606400123456"},
+		{TimeMilliseconds, 1451606400123456789, "1451606400123"},
+		{TimeSeconds, 1451606400123456789, "1451606400"},
+		{TimeMilliseconds, -1500, "-1"},
+		{TimeSeconds, -1e9, "-1"},
+	}
+	for _, c := range cases {
+		s, err := NewSerializer(c.unit)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.unit, err)
+		}
+		p.SetTimestamp(c.timestamp)
+		var b bytes.Buffer
+		if err := s.Serialize(p, &b); err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.unit, err)
+		}
+		want := `{"timestamp":` + c.want + `,"measurement":"cpu","tags":{},"fields":{"usage_user":1.5}}` + "\n"
+		if got := b.String(); got != want {
+			t.Errorf("%s: incorrect output: got %q want %q", c.unit, got, want)
+		}
+	}
+
+	if _, err := serialize.New(Format+":minutes", serialize.NewSchema(nil, nil), io.Discard); err == nil || !strings.Contains(err.Error(), "invalid JSON Lines time unit 'minutes'") {
+		t.Errorf("incorrect error for invalid time unit: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Package kafka implements a format of Kafka messages, for a producer to
+// publish the generated data to Kafka: each reading is a frame of the
+// partition, timestamp, key and value of a message, with the key the series
+// of the reading, so the readings of each host stay in order in their
+// partition.
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/influx"
+)
+
+// Format is the name the format is registered under, which writes messages
+// for DefaultPartitions partitions with values in DefaultValueFormat.
+// Formats of the form Format + ":" + partitions, e.g., kafka:12, write them
+// for that many partitions, and those of the form Format + ":" + partitions
+// + ":" + format, e.g., kafka:12:jsonl, with values in that format.
+const Format = "kafka"
+
+// DefaultPartitions is the number of partitions of Format, that of a topic
+// created with the defaults of Kafka
+const DefaultPartitions = 1
+
+// DefaultValueFormat is the format of the values of Format
+const DefaultValueFormat = influx.Format
+
+// KeyTag is the tag whose value is the key of the message of a reading. The
+// key of readings without it is all their tags.
+const KeyTag = "hostname"
+
+func init() {
+	serialize.Describe(Format, "Kafka messages of a partition, timestamp, key and value in influx line protocol, with kafka:<partitions>[:<format>] setting the number of partitions and the format of values")
+	serialize.Register(Format, func(schema *serialize.Schema, _ io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultPartitions, DefaultValueFormat)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, _ io.Writer) (serialize.PointSerializer, error) {
+		partitions, format := arg, DefaultValueFormat
+		if i := strings.IndexByte(arg, ':'); i >= 0 {
+			partitions, format = arg[:i], arg[i+1:]
+		}
+		n, err := strconv.Atoi(partitions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number of partitions '%s': %v", partitions, err)
+		}
+		return NewSerializer(schema, n, format)
+	})
+}
+
+// Serializer writes a Point in a serialized form as a Kafka message
+type Serializer struct {
+	partitions int
+	// values is the serializer of the values of the messages
+	values serialize.PointSerializer
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, key holds the key of the message being
+	// written, value its value and point the row of a PointBatch being
+	// written
+	buf   []byte
+	key   []byte
+	value bytes.Buffer
+	point *serialize.Point
+}
+
+// NewSerializer returns a Serializer of messages for the given number of
+// partitions, with values in the given format. The values must be of a
+// format without a header, whose serializers write each Point as it is
+// serialized, so each message has a reading of its own.
+func NewSerializer(schema *serialize.Schema, partitions int, format string) (*Serializer, error) {
+	if partitions <= 0 {
+		return nil, fmt.Errorf("number of partitions must be greater than 0: %d", partitions)
+	}
+	if format == Format || strings.HasPrefix(format, Format+":") {
+		return nil, fmt.Errorf("format %s cannot be the format of values of %s", format, Format)
+	}
+	if serialize.IsFileFormat(format) {
+		return nil, fmt.Errorf("format %s writes files, which cannot be values of messages", format)
+	}
+	var header bytes.Buffer
+	values, err := serialize.New(format, schema, &header)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := values.(io.Closer); ok {
+		return nil, fmt.Errorf("format %s buffers what it writes, which cannot be values of messages", format)
+	}
+	if header.Len() > 0 {
+		return nil, fmt.Errorf("format %s starts with a header, which messages cannot have", format)
+	}
+	return &Serializer{partitions: partitions, values: values, point: serialize.NewPoint()}, nil
+}
+
+// Serialize writes Point p to w as a frame of a Kafka message:
+//
+//	partition    int32
+//	timestamp    int64, in milliseconds since the epoch
+//	key length   int32
+//	key          bytes
+//	value length int32
+//	value        bytes
+//
+// with the integers big-endian, as in the Kafka protocol. The key is the
+// value of the KeyTag of p or, if it has none, its tags, as key=value pairs
+// joined by commas, e.g., hostname=host_0. The partition is the one the
+// default partitioner of Kafka picks for the key: the murmur2 hash of the
+// key, made positive, modulo the number of partitions. The value is p in the
+// format of values.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf, err := s.appendMessage(s.buf[:0], p)
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// SerializeBatch writes all messages of a PointBatch to the given writer in
+// the same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	var err error
+	for i := 0; i < b.Len() && err == nil; i++ {
+		b.PointAt(i, s.point)
+		buf, err = s.appendMessage(buf, s.point)
+	}
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func (s *Serializer) appendMessage(buf []byte, p *serialize.Point) ([]byte, error) {
+	s.value.Reset()
+	if err := s.values.Serialize(p, &s.value); err != nil {
+		return buf, err
+	}
+	s.key = appendKey(s.key[:0], p.TagKeys(), p.TagValues())
+	millis := p.Timestamp() / 1e6
+	if p.Timestamp() < 0 && p.Timestamp()%1e6 != 0 {
+		millis--
+	}
+	buf = binary.BigEndian.AppendUint32(buf, uint32(Partition(s.key, s.partitions)))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(millis))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(s.key)))
+	buf = append(buf, s.key...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(s.value.Len()))
+	return append(buf, s.value.Bytes()...), nil
+}
+
+// appendKey appends the key of the message of a reading with the given tags
+// to buf
+func appendKey(buf []byte, tagKeys, tagValues [][]byte) []byte {
+	for i, key := range tagKeys {
+		if string(key) == KeyTag && len(tagValues[i]) > 0 {
+			return append(buf, tagValues[i]...)
+		}
+	}
+	for i, key := range tagKeys {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, key...)
+		buf = append(buf, '=')
+		buf = append(buf, tagValues[i]...)
+	}
+	return buf
+}
+
+// Partition returns the partition of the given number of partitions that the
+// default partitioner of Kafka picks for key
+func Partition(key []byte, partitions int) int {
+	return int(murmur2(key)&0x7fffffff) % partitions
+}
+
+// murmur2 returns the 32-bit murmur2 hash of data, as Kafka computes it
+func murmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	h := uint32(seed) ^ uint32(len(data))
+	n := len(data) &^ 3
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch len(data) & 3 {
+	case 3:
+		h ^= uint32(data[n+2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[n+1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[n])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
@@ -0,0 +1,220 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	_ "github.com/timescale/tsbs/pkg/data/serialize/avro"
+	_ "github.com/timescale/tsbs/pkg/data/serialize/csv"
+	_ "github.com/timescale/tsbs/pkg/data/serialize/dynamodb"
+	_ "github.com/timescale/tsbs/pkg/data/serialize/jsonl"
+	_ "github.com/timescale/tsbs/pkg/data/serialize/parquet"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+// frame returns the frame of a message with the given partition, timestamp
+// in milliseconds, key and value
+func frame(partition int, millis int64, key, value string) string {
+	var buf []byte
+	buf = binary.BigEndian.AppendUint32(buf, uint32(partition))
+	buf = binary.BigEndian.AppendUint64(buf, uint64(millis))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(key)))
+	buf = append(buf, key...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(value)))
+	return string(append(buf, value...))
+}
+
+const testTags = "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b "
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     frame(0, 1451606400000, "host_0", testTags+"usage_guest_nice=38.24311829 1451606400000000000\n"),
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     frame(0, 1451606400000, "host_0", testTags+"usage_guest=38i 1451606400000000000\n"),
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     frame(0, 1451606400000, "host_0", testTags+"big_usage_guest=5000000000i,usage_guest=38i,usage_guest_nice=38.24311829 1451606400000000000\n"),
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     frame(0, 1451606400000, "", "cpu usage_guest_nice=38.24311829 1451606400000000000\n"),
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerJSONLConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format+":12:jsonl:ms", schema, w)
+		},
+	}.Run(t)
+}
+
+// TestMurmur2 checks the hashes of the tests of the murmur2 of Kafka
+func TestMurmur2(t *testing.T) {
+	for key, want := range map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	} {
+		if got := int32(murmur2([]byte(key))); got != want {
+			t.Errorf("murmur2(%q): got %d want %d", key, got, want)
+		}
+	}
+	// -790332482 & 0x7fffffff is 1357151166
+	if got := Partition([]byte("foobar"), 10); got != 6 {
+		t.Errorf("incorrect partition: got %d", got)
+	}
+}
+
+// message is a message decoded from its frame
+type message struct {
+	partition int
+	millis    int64
+	key       string
+	value     string
+}
+
+func decode(data []byte) ([]message, error) {
+	var messages []message
+	for len(data) > 0 {
+		if len(data) < 16 {
+			return nil, fmt.Errorf("truncated frame: %d bytes", len(data))
+		}
+		m := message{
+			partition: int(binary.BigEndian.Uint32(data)),
+			millis:    int64(binary.BigEndian.Uint64(data[4:])),
+		}
+		n := int(binary.BigEndian.Uint32(data[12:]))
+		if len(data) < 16+n+4 {
+			return nil, fmt.Errorf("truncated key of %d bytes", n)
+		}
+		m.key = string(data[16 : 16+n])
+		data = data[16+n:]
+		n = int(binary.BigEndian.Uint32(data))
+		if len(data) < 4+n {
+			return nil, fmt.Errorf("truncated value of %d bytes", n)
+		}
+		m.value = string(data[4 : 4+n])
+		data = data[4+n:]
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+func newPoint(timestamp int64, tags ...string) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("cpu"))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	p.AppendField([]byte("usage_user"), 1.5)
+	return p
+}
+
+func TestSerializerMessages(t *testing.T) {
+	points := []*serialize.Point{
+		newPoint(1451606400123456789, "hostname", "host_0", "region", "eu-west-1"),
+		newPoint(-1500, "region", "eu-west-1", "hostname", "host_1"),
+		newPoint(0, "hostname", "", "region", "us-east-1"),
+		newPoint(1000000, "name", "truck_0", "fleet", "East"),
+		newPoint(2000000, "hostname", "host_0", "region", "eu-west-2"),
+	}
+	wantKeys := []string{"host_0", "host_1", "hostname=,region=us-east-1", "name=truck_0,fleet=East", "host_0"}
+	wantMillis := []int64{1451606400123, -1, 0, 1, 2}
+
+	s, err := serialize.New(Format+":5:jsonl:ns", serialize.NewSchema(nil, nil), ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, batch := range []bool{false, true} {
+		var buf bytes.Buffer
+		if batch {
+			b := serialize.NewPointBatch()
+			for _, p := range points {
+				b.Append(p)
+			}
+			err = serialize.SerializeBatch(s, b, &buf)
+		} else {
+			for _, p := range points {
+				if err = s.Serialize(p, &buf); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		messages, err := decode(buf.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error decoding: %v", err)
+		}
+		var keys []string
+		var millis []int64
+		for i, m := range messages {
+			keys = append(keys, m.key)
+			millis = append(millis, m.millis)
+			if want := Partition([]byte(m.key), 5); m.partition != want {
+				t.Errorf("message %d: incorrect partition: got %d want %d", i, m.partition, want)
+			}
+			if want := fmt.Sprintf(`{"timestamp":%d,`, points[i].Timestamp()); !strings.HasPrefix(m.value, want) {
+				t.Errorf("message %d: incorrect value %q", i, m.value)
+			}
+		}
+		if !reflect.DeepEqual(keys, wantKeys) {
+			t.Errorf("batch %t: incorrect keys: got %q want %q", batch, keys, wantKeys)
+		}
+		if !reflect.DeepEqual(millis, wantMillis) {
+			t.Errorf("batch %t: incorrect timestamps: got %v want %v", batch, millis, wantMillis)
+		}
+	}
+}
+
+func TestSerializerErrors(t *testing.T) {
+	schema := serialize.NewSchema(serializetest.TagKeys, map[string][][]byte{"cpu": {serializetest.ColFloat}})
+	for format, want := range map[string]string{
+		"kafka:0":                      "number of partitions must be greater than 0",
+		"kafka:many":                   "invalid number of partitions 'many'",
+		"kafka:4:bogus":                "unknown format",
+		"kafka:4:kafka:2":              "cannot be the format of values",
+		"kafka:4:parquet":              "writes files",
+		"kafka:4:avro":                 "writes files",
+		"kafka:4:dynamodb":             "buffers what it writes",
+		"kafka:4:csv":                  "starts with a header",
+		"kafka:4:csv:header=false,x=y": "unknown CSV option 'x'",
+	} {
+		if _, err := serialize.New(format, schema, ioutil.Discard); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: incorrect error: got %v want %s", format, err, want)
+		}
+	}
+	if _, err := serialize.New("kafka:4:csv:header=false", schema, ioutil.Discard); err != nil {
+		t.Errorf("unexpected error for values in CSV without a header: %v", err)
+	}
+}
@@ -0,0 +1,398 @@
+// Package kdb implements the format for kdb+: a directory of a CSV file per
+// measurement, a table of kdb+, with the time and sym columns of kdb+ tick
+// tables, and a q script loading each file with the types of its columns and
+// saving its table splayed.
+package kdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as q parses them, with NaN values as empty,
+// which is a null float
+var floatFormat = &serialize.FloatFormat{Fmt: 'g', Inf: "0w", NegInf: "-0w"}
+
+// Scheme is the name the formats of the form Scheme + ":" + options, e.g.,
+// kdb:dir=/data/kdb, are registered under (see ParseOptions)
+const Scheme = "kdb"
+
+// Columns of every table: the time of the reading and its sym, the value of
+// the tag of Options.Sym
+const (
+	TimeColumn = "time"
+	SymColumn  = "sym"
+)
+
+// DefaultSym is the tag whose values are the syms of the readings if no
+// other is given
+const DefaultSym = "hostname"
+
+// ScriptName is the name of the q script written to the directory, and
+// DBName that of the directory of the database it saves the tables to
+const (
+	ScriptName = "load.q"
+	DBName     = "db"
+)
+
+// timeLayout is the layout of timestamps of q, which the type P parses
+const timeLayout = "2006.01.02D15:04:05.000000000"
+
+func init() {
+	serialize.Describe(Scheme, "kdb+ CSV of each measurement in a directory, with time and sym columns and a q script loading them with their types, with kdb:dir=<dir>[,sym=<tag>]")
+	serialize.RegisterScheme(Scheme, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		opts, err := ParseOptions(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSerializer(schema, opts)
+	})
+}
+
+// Options are the options of the format
+type Options struct {
+	// Dir is the directory the files are written to
+	Dir string
+	// Sym is the tag whose values are the syms of the readings
+	Sym string
+}
+
+// ParseOptions parses the options of the format from a comma separated list
+// of option=value, e.g., dir=/data/kdb,sym=hostname. The options are:
+//   - dir: the directory to write the files to
+//   - sym: the tag of the sym column, DefaultSym unless set
+func ParseOptions(s string) (Options, error) {
+	var opts Options
+	for _, option := range strings.Split(s, ",") {
+		i := strings.IndexByte(option, '=')
+		if i < 0 {
+			return opts, fmt.Errorf("invalid kdb+ option '%s': must be option=value", option)
+		}
+		name, value := option[:i], option[i+1:]
+		switch name {
+		case "dir":
+			if len(value) == 0 {
+				return opts, fmt.Errorf("invalid kdb+ dir: must not be empty")
+			}
+			opts.Dir = value
+		case "sym":
+			if len(value) == 0 {
+				return opts, fmt.Errorf("invalid kdb+ sym: must not be empty")
+			}
+			opts.Sym = value
+		default:
+			return opts, fmt.Errorf("unknown kdb+ option '%s': must be dir or sym", name)
+		}
+	}
+	if len(opts.Dir) == 0 {
+		return opts, fmt.Errorf("missing kdb+ option dir, the directory to write the files to")
+	}
+	if len(opts.Sym) == 0 {
+		opts.Sym = DefaultSym
+	}
+	return opts, nil
+}
+
+// Serializer writes Points to the CSV files of their tables for kdb+
+type Serializer struct {
+	opts   Options
+	schema *serialize.Schema
+	// tables are the tables of the measurements written so far, and
+	// measurements the measurements in the order they were first written
+	tables       map[string]*table
+	measurements []string
+
+	// buf is scratch space reused between calls so each row is written with
+	// a single call
+	buf []byte
+}
+
+// table is the CSV file of a measurement, with the columns of its first
+// reading
+type table struct {
+	name string
+	f    *os.File
+	w    *bufio.Writer
+	// tagKeys are the tags of the columns after the sym, and fieldKeys the
+	// fields of the columns after those
+	tagKeys   []string
+	fieldKeys []string
+	// types are the types of q of the columns, as 0: takes them
+	types []byte
+}
+
+// NewSerializer returns a Serializer with the given options, creating
+// opts.Dir. schema, which may be nil, gives the types of fields, and fields
+// of unknown type are numbers from the simulators, which are floats.
+func NewSerializer(schema *serialize.Schema, opts Options) (*Serializer, error) {
+	if len(opts.Sym) == 0 {
+		opts.Sym = DefaultSym
+	}
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	opts.Dir = dir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Serializer{opts: opts, schema: schema, tables: make(map[string]*table)}, nil
+}
+
+// Name returns the name of q of the table, and CSV file, of a measurement,
+// or of the column of a tag or field key: s with any characters other than
+// letters, digits and underscores replaced by underscores, prefixed by an x
+// unless it starts with a letter, as names of q must
+func Name(s string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, s)
+	if len(name) == 0 || !(name[0] >= 'a' && name[0] <= 'z' || name[0] >= 'A' && name[0] <= 'Z') {
+		name = "x" + name
+	}
+	return name
+}
+
+// Serialize writes Point p as a row of the CSV file of its measurement, e.g.,
+// cpu.csv, rather than to w:
+//
+// 2016.01.01D00:00:00.000000000,host_0,eu-west-1,...,58,...
+//
+// The file starts with a row of the names of the columns, as 0: of q takes
+// it: the time, the sym, the other tags and then the fields of the first
+// reading of the measurement. Readings without a tag or field of the
+// columns have an empty value, a null of q, and tags and fields not in the
+// columns are left out. NaN values are null too, and infinite values are
+// 0w and -0w.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	return s.writeRow(p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+}
+
+// SerializeBatch writes all rows of a PointBatch to the files of their
+// measurements in the same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		if err := s.writeRow(b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Serializer) writeRow(measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) error {
+	t := s.tables[string(measurementName)]
+	if t == nil {
+		var err error
+		if t, err = s.create(string(measurementName), tagKeys, fieldKeys, fieldValues); err != nil {
+			return err
+		}
+	}
+	buf := time.Unix(0, timestamp).UTC().AppendFormat(s.buf[:0], timeLayout)
+	buf = append(buf, ',')
+	buf = appendString(buf, lookup(tagKeys, tagValues, s.opts.Sym))
+	for _, key := range t.tagKeys {
+		buf = append(buf, ',')
+		buf = appendString(buf, lookup(tagKeys, tagValues, key))
+	}
+	for _, key := range t.fieldKeys {
+		buf = append(buf, ',')
+		for i, k := range fieldKeys {
+			if string(k) == key {
+				buf = appendValue(buf, fieldValues[i])
+				break
+			}
+		}
+	}
+	buf = append(buf, '\n')
+	s.buf = buf
+	_, err := t.w.Write(buf)
+	return err
+}
+
+// lookup returns the value of tag key, or nil if there is none
+func lookup(keys, values [][]byte, key string) []byte {
+	for i, k := range keys {
+		if string(k) == key {
+			return values[i]
+		}
+	}
+	return nil
+}
+
+// create creates the CSV file of the table of measurement, with the columns
+// of its first reading, and writes the row of their names
+func (s *Serializer) create(measurement string, tagKeys, fieldKeys [][]byte, fieldValues []interface{}) (*table, error) {
+	t := &table{name: Name(measurement), types: []byte{'P', 'S'}}
+	buf := append(s.buf[:0], TimeColumn+","+SymColumn...)
+	for _, key := range tagKeys {
+		if string(key) == s.opts.Sym {
+			continue
+		}
+		t.tagKeys = append(t.tagKeys, string(key))
+		t.types = append(t.types, 'S')
+		buf = append(buf, ',')
+		buf = append(buf, Name(string(key))...)
+	}
+	var fieldTypes []serialize.FieldType
+	var schemaKeys [][]byte
+	if s.schema != nil {
+		fieldTypes, schemaKeys = s.schema.FieldTypes(measurement), s.schema.FieldKeys(measurement)
+	}
+	for i, key := range fieldKeys {
+		typ := serialize.FieldTypeUnknown
+		for j, k := range schemaKeys {
+			if string(k) == string(key) && j < len(fieldTypes) {
+				typ = fieldTypes[j]
+			}
+		}
+		t.fieldKeys = append(t.fieldKeys, string(key))
+		t.types = append(t.types, columnType(typ, fieldValues[i]))
+		buf = append(buf, ',')
+		buf = append(buf, Name(string(key))...)
+	}
+	buf = append(buf, '\n')
+	s.buf = buf
+
+	f, err := os.Create(filepath.Join(s.opts.Dir, t.name+".csv"))
+	if err != nil {
+		return nil, err
+	}
+	t.f, t.w = f, bufio.NewWriter(f)
+	s.tables[measurement] = t
+	s.measurements = append(s.measurements, measurement)
+	_, err = t.w.Write(buf)
+	return t, err
+}
+
+// columnType returns the type of q of the column of a field of type t, or,
+// if it is not known, whose first value is v: J for ints, F for floats and
+// numbers of unknown type, B for bools and * for strings
+func columnType(t serialize.FieldType, v interface{}) byte {
+	switch t {
+	case serialize.FieldTypeInt:
+		return 'J'
+	case serialize.FieldTypeFloat:
+		return 'F'
+	case serialize.FieldTypeBool:
+		return 'B'
+	case serialize.FieldTypeString:
+		return '*'
+	}
+	switch v.(type) {
+	case bool:
+		return 'B'
+	case string, []byte:
+		return '*'
+	}
+	return 'F'
+}
+
+// appendValue appends a field value to buf as a value of a column
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return buf
+	case []byte:
+		return appendString(buf, x)
+	case string:
+		return appendString(buf, []byte(x))
+	case bool:
+		if x {
+			return append(buf, '1')
+		}
+		return append(buf, '0')
+	case float64:
+		return serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// appendString appends b to buf as a value of a column: as is, or quoted if
+// it has commas or quotes, with its quotes doubled. Line breaks, which q
+// reads as the end of the row even in quotes, are replaced by spaces.
+func appendString(buf []byte, b []byte) []byte {
+	quote := false
+	for _, c := range b {
+		if c == ',' || c == '"' {
+			quote = true
+			break
+		}
+	}
+	if quote {
+		buf = append(buf, '"')
+	}
+	for _, c := range b {
+		switch c {
+		case '"':
+			buf = append(buf, '"', '"')
+		case '\n', '\r':
+			buf = append(buf, ' ')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	if quote {
+		buf = append(buf, '"')
+	}
+	return buf
+}
+
+// Close flushes and closes the CSV files, and writes the q script loading
+// them to the directory, e.g., load.q:
+//
+// cpu:("PSSSSSSSSSSJJJJJJJJJJ";enlist ",") 0: `:/data/kdb/cpu.csv
+// `:/data/kdb/db/cpu/ set .Q.en[`:/data/kdb/db] `sym`time xasc cpu
+// @[`:/data/kdb/db/cpu/;`sym;`p#]
+//
+// Running it, e.g., with q /data/kdb/load.q, saves each table splayed in
+// the db directory, sorted by sym and time with the sym column parted, as
+// the tables of kdb+ tick are, and with its symbols enumerated in db/sym.
+func (s *Serializer) Close() error {
+	var err error
+	for _, m := range s.measurements {
+		t := s.tables[m]
+		if t.f == nil {
+			continue
+		}
+		if flushErr := t.w.Flush(); err == nil {
+			err = flushErr
+		}
+		if closeErr := t.f.Close(); err == nil {
+			err = closeErr
+		}
+		t.f = nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(s.opts.Dir, ScriptName), s.script(), 0644)
+}
+
+// script returns the q script loading the CSV files of the tables
+func (s *Serializer) script() []byte {
+	db := filepath.Join(s.opts.Dir, DBName)
+	var buf []byte
+	buf = append(buf, "/ loads the CSV of each table and saves it splayed in "+db+"\n"...)
+	for _, m := range s.measurements {
+		t := s.tables[m]
+		splayed := "`:" + filepath.Join(db, t.name) + "/"
+		buf = append(buf, t.name+":(\""+string(t.types)+"\";enlist \",\") 0: `:"+filepath.Join(s.opts.Dir, t.name+".csv")+"\n"...)
+		buf = append(buf, splayed+" set .Q.en[`:"+db+"] `"+SymColumn+"`"+TimeColumn+" xasc "+t.name+"\n"...)
+		buf = append(buf, "@["+splayed+";`"+SymColumn+";`p#]\n"...)
+	}
+	return buf
+}
@@ -0,0 +1,157 @@
+package kdb
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func TestSerializerFiles(t *testing.T) {
+	schema := serialize.NewTypedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{
+			"cpu":     {[]byte("usage_user"), []byte("usage_system")},
+			"disk/io": {[]byte("reads"), []byte("ok")},
+		},
+		map[string][]serialize.FieldType{
+			"cpu": {serialize.FieldTypeInt, serialize.FieldTypeUnknown},
+		},
+	)
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400123456789, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", int64(58), "usage_system", 2.5),
+		newPoint("disk/io", 1451606400000000000, []string{"hostname", "host_0", "path", "/dev/sda"}, "reads", int64(1), "ok", true, "label", "a,\"b\"\nc"),
+		// without a region and usage_user, and with a tag not in the columns
+		newPoint("cpu", 1451606410000000000, []string{"region", "us-east-1", "hostname", "host_1", "rack", "1"}, "usage_system", math.NaN()),
+		newPoint("cpu", 1451606420000000000, []string{"hostname", "host_0"}, "usage_user", int64(3), "usage_system", math.Inf(-1)),
+	}
+	dir := filepath.Join(t.TempDir(), "kdb")
+	ps, err := serialize.New(Scheme+":dir="+dir, schema, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s := ps.(*Serializer)
+	for _, p := range points[:2] {
+		if err := s.Serialize(p, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	b := serialize.NewPointBatch()
+	for _, p := range points[2:] {
+		b.Append(p)
+	}
+	if err := s.SerializeBatch(b, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files := map[string]string{
+		"cpu.csv": `time,sym,region,usage_user,usage_system
+2016.01.01D00:00:00.123456789,host_0,eu-west-1,58,2.5
+2016.01.01D00:00:10.000000000,host_1,us-east-1,,
+2016.01.01D00:00:20.000000000,host_0,,3,-0w
+`,
+		"disk_io.csv": `time,sym,path,reads,ok,label
+2016.01.01D00:00:00.000000000,host_0,/dev/sda,1,1,"a,""b"" c"
+`,
+		ScriptName: "/ loads the CSV of each table and saves it splayed in " + dir + `/db
+cpu:("PSSJF";enlist ",") 0: ` + "`:" + dir + `/cpu.csv
+` + "`:" + dir + "/db/cpu/ set .Q.en[`:" + dir + "/db] `sym`time xasc cpu" + `
+@[` + "`:" + dir + "/db/cpu/;`sym;`p#]" + `
+disk_io:("PSSFB*";enlist ",") 0: ` + "`:" + dir + `/disk_io.csv
+` + "`:" + dir + "/db/disk_io/ set .Q.en[`:" + dir + "/db] `sym`time xasc disk_io" + `
+@[` + "`:" + dir + "/db/disk_io/;`sym;`p#]" + `
+`,
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("incorrect %s: got\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+func TestSerializerSym(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSerializer(nil, Options{Dir: dir, Sym: "region"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Serialize(newPoint("cpu", 0, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", int64(58)), nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "cpu.csv"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// without a schema, the types of fields are not known, so the column of
+	// usage_user is a float
+	want := "time,sym,hostname,usage_user\n1970.01.01D00:00:00.000000000,eu-west-1,host_0,58\n"
+	if string(got) != want {
+		t.Errorf("incorrect CSV: got %q want %q", got, want)
+	}
+	script, err := os.ReadFile(filepath.Join(dir, ScriptName))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(script), `cpu:("PSSF";enlist ",")`) {
+		t.Errorf("incorrect types of the columns:\n%s", script)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("dir=/tmp/kdb")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Options{Dir: "/tmp/kdb", Sym: DefaultSym}); opts != want {
+		t.Errorf("incorrect options: got %+v want %+v", opts, want)
+	}
+
+	for _, c := range []struct {
+		options string
+		errMsg  string
+	}{
+		{"sym=region", "missing kdb+ option dir"},
+		{"dir=", "invalid kdb+ dir"},
+		{"dir=/tmp,sym=", "invalid kdb+ sym"},
+		{"dir=/tmp,splay=true", "unknown kdb+ option 'splay'"},
+		{"/tmp", "invalid kdb+ option '/tmp'"},
+	} {
+		if _, err := ParseOptions(c.options); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.options, err, c.errMsg)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	for name, want := range map[string]string{"cpu": "cpu", "disk.io": "disk_io", "9lives": "x9lives", "_x": "x_x", "": "x"} {
+		if got := Name(name); got != want {
+			t.Errorf("incorrect name of %q: got %q want %q", name, got, want)
+		}
+	}
+}
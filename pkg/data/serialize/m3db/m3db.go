@@ -0,0 +1,220 @@
+// Package m3db implements the formats for M3: in the m3db format each reading
+// is a WriteRequest of the Prometheus remote-write protocol, which the M3
+// coordinator ingests, with a time series for each of its fields, and in the
+// m3db-json format each sample of a field is a JSON write of the coordinator.
+package m3db
+
+import (
+	"encoding/binary"
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/prometheus"
+)
+
+// Format is the name the remote-write format is registered under, and
+// FormatJSON that of the JSON format
+const (
+	Format     = "m3db"
+	FormatJSON = "m3db-json"
+)
+
+func init() {
+	serialize.Describe(Format, "M3 (Prometheus remote-write) WriteRequests, uncompressed protobuf with a length prefix, one per reading")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	serialize.Describe(FormatJSON, "M3 coordinator JSON writes, a line per sample of each field, for /api/v1/json/write")
+	serialize.Register(FormatJSON, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &JSONSerializer{}, nil
+	})
+	// the Header is checked by tsbs_load_m3db
+	serialize.MarkHeaderFormat(Format)
+}
+
+// Serializer writes a Point in a serialized form for M3
+type Serializer struct {
+	enc prometheus.Encoder
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and row holds the WriteRequest being
+	// encoded
+	buf []byte
+	row []byte
+}
+
+// Serialize writes Point p to w as a WriteRequest encoded by a
+// prometheus.Encoder, which maps its fields to time series and its tags to
+// labels, prefixed by its length as a varint. The WriteRequest is protobuf,
+// uncompressed, so that the WriteRequests of a batch concatenated are the
+// WriteRequest of the batch, which the loader compresses and sends. Points
+// without any samples are not written.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	s.row = s.enc.AppendWriteRequest(s.row[:0], measurementName, tagKeys, tagValues, fieldKeys, fieldValues, timestamp)
+	if len(s.row) == 0 {
+		return buf
+	}
+	buf = appendUvarint(buf, uint64(len(s.row)))
+	return append(buf, s.row...)
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	return append(buf, b[:n]...)
+}
+
+// JSONSerializer writes a Point in the JSON form of the M3 coordinator
+type JSONSerializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and names the label names of the tags
+	// of the Point being written, backed by nameBuf
+	buf     []byte
+	names   [][]byte
+	nameBuf []byte
+}
+
+// Serialize writes Point p to w as a line of JSON for each of its numeric or
+// bool fields, each the body of a write of a sample to the JSON write API of
+// the M3 coordinator, /api/v1/json/write, e.g.:
+//
+// {"tags":{"__name__":"cpu_usage_user","hostname":"host_0",...},"timestamp":"1451606400.000","value":58.13}
+//
+// The metric name and labels are those of the m3db format, as mapped by
+// prometheus.AppendMetricName and prometheus.AppendLabelName, so the same
+// series are written either way: tags with empty values have no label, and
+// of tags mapped to the same label name only the first has one. Timestamps
+// are in seconds, with the milliseconds of the m3db format as decimals, and
+// bools are 1 or 0. String fields are left out, as M3 has no such samples,
+// and NaN and infinite values, for which JSON has no numbers, are written as
+// the strings "NaN", "Infinity" and "-Infinity", which the coordinator
+// rejects; the simulators do not generate them.
+func (s *JSONSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendSamples(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all samples of a PointBatch to the given writer in
+// the same format as Serialize
+func (s *JSONSerializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendSamples(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *JSONSerializer) appendSamples(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	s.nameBuf = s.nameBuf[:0]
+	s.names = s.names[:0]
+	for i, k := range tagKeys {
+		start := len(s.nameBuf)
+		s.nameBuf = prometheus.AppendLabelName(s.nameBuf, k)
+		name := s.nameBuf[start:len(s.nameBuf):len(s.nameBuf)]
+		if len(tagValues[i]) == 0 || s.hasName(name) {
+			name = nil
+		}
+		s.names = append(s.names, name)
+	}
+
+	for i, v := range fieldValues {
+		f, ok := sampleValue(v)
+		if !ok {
+			continue
+		}
+		buf = append(buf, `{"tags":{"`...)
+		buf = append(buf, prometheus.NameLabel...)
+		buf = append(buf, `":"`...)
+		buf = prometheus.AppendMetricName(buf, measurementName, fieldKeys[i])
+		buf = append(buf, '"')
+		for j, name := range s.names {
+			if name == nil {
+				continue
+			}
+			buf = append(buf, ',', '"')
+			buf = append(buf, name...)
+			buf = append(buf, '"', ':')
+			buf = serialize.AppendJSONString(buf, tagValues[j])
+		}
+		buf = append(buf, `},"timestamp":"`...)
+		buf = appendSeconds(buf, timestamp)
+		buf = append(buf, `","value":`...)
+		buf = serialize.AppendJSONValue(buf, f)
+		buf = append(buf, '}', '\n')
+	}
+	return buf
+}
+
+// hasName returns whether a label of a tag already has name
+func (s *JSONSerializer) hasName(name []byte) bool {
+	if string(name) == string(prometheus.NameLabel) {
+		return true
+	}
+	for _, n := range s.names {
+		if string(n) == string(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleValue returns the value of a sample of field value v, and whether
+// it has one
+func sampleValue(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// appendSeconds appends timestamp, in nanoseconds, to buf in seconds with
+// three decimals, e.g., 1451606400.123
+func appendSeconds(buf []byte, timestamp int64) []byte {
+	millis := timestamp / 1e6
+	if millis < 0 {
+		buf = append(buf, '-')
+		millis = -millis
+	}
+	buf = strconv.AppendInt(buf, millis/1000, 10)
+	ms := millis % 1000
+	return append(buf, '.', byte('0'+ms/100), byte('0'+ms/10%10), byte('0'+ms%10))
+}
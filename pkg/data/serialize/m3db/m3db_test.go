@@ -0,0 +1,158 @@
+package m3db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/prometheus"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+// Tags of the protobuf fields of the remote-write protocol, see package
+// prometheus
+const (
+	tagTimeSeries  = 1<<3 | 2
+	tagLabel       = 1<<3 | 2
+	tagSample      = 2<<3 | 2
+	tagLabelName   = 1<<3 | 2
+	tagLabelValue  = 2<<3 | 2
+	tagSampleValue = 1<<3 | 1
+	tagSampleTime  = 2<<3 | 0
+)
+
+// field returns a length-delimited protobuf field, whose contents must be
+// shorter than 128 bytes
+func field(tag byte, contents string) string {
+	return string([]byte{tag, byte(len(contents))}) + contents
+}
+
+func label(name, value string) string {
+	return field(tagLabel, field(tagLabelName, name)+field(tagLabelValue, value))
+}
+
+func sample(value float64) string {
+	b := []byte{tagSampleValue, 0, 0, 0, 0, 0, 0, 0, 0, tagSampleTime}
+	binary.LittleEndian.PutUint64(b[1:9], math.Float64bits(value))
+	b = appendUvarint(b, uint64(serializetest.Now.UnixNano()/1e6))
+	return field(tagSample, string(b))
+}
+
+// row returns the output for a Point with the given time series
+func row(series ...string) string {
+	var s string
+	for _, ts := range series {
+		s += field(tagTimeSeries, ts)
+	}
+	return string(appendUvarint(nil, uint64(len(s)))) + s
+}
+
+// testLabels are the labels of the tags of the fixture Points, after the
+// metric name label in order
+var testLabels = label("datacenter", "eu-west-1b") + label("hostname", "host_0") + label("region", "eu-west-1")
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     row(label(prometheus.NameLabel, "cpu_usage_guest_nice") + testLabels + sample(serializetest.Float)),
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     row(label(prometheus.NameLabel, "cpu_usage_guest") + testLabels + sample(serializetest.Int)),
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: row(
+			label(prometheus.NameLabel, "cpu_big_usage_guest")+testLabels+sample(float64(serializetest.Int64)),
+			label(prometheus.NameLabel, "cpu_usage_guest")+testLabels+sample(serializetest.Int),
+			label(prometheus.NameLabel, "cpu_usage_guest_nice")+testLabels+sample(serializetest.Float),
+		),
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     row(label(prometheus.NameLabel, "cpu_usage_guest_nice") + sample(serializetest.Float)),
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+// jsonLabels are the labels of the tags of the fixture Points in the JSON
+// format
+const jsonLabels = `,"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"`
+
+var jsonCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `{"tags":{"__name__":"cpu_usage_guest_nice"` + jsonLabels + `},"timestamp":"1451606400.000","value":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `{"tags":{"__name__":"cpu_usage_guest"` + jsonLabels + `},"timestamp":"1451606400.000","value":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: `{"tags":{"__name__":"cpu_big_usage_guest"` + jsonLabels + `},"timestamp":"1451606400.000","value":5000000000}` + "\n" +
+			`{"tags":{"__name__":"cpu_usage_guest"` + jsonLabels + `},"timestamp":"1451606400.000","value":38}` + "\n" +
+			`{"tags":{"__name__":"cpu_usage_guest_nice"` + jsonLabels + `},"timestamp":"1451606400.000","value":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"tags":{"__name__":"cpu_usage_guest_nice"},"timestamp":"1451606400.000","value":38.24311829}` + "\n",
+	},
+}
+
+func TestJSONSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(FormatJSON, schema, w)
+		},
+		Golden: jsonCases,
+	}.Run(t)
+}
+
+func TestJSONSerializerLabels(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk.io"))
+	p.SetTimestamp(-1500000)
+	p.AppendTag([]byte("host.name"), []byte("a\"b"))
+	p.AppendTag([]byte("host_name"), []byte("dropped"))
+	p.AppendTag([]byte("region"), nil)
+	p.AppendTag([]byte("__source"), []byte("x"))
+	p.AppendTag([]byte("2xx"), []byte("y"))
+	p.AppendField([]byte("state"), "ok")
+	p.AppendField([]byte("up"), true)
+	p.AppendField([]byte("err"), math.Inf(-1))
+
+	var buf bytes.Buffer
+	if err := (&JSONSerializer{}).Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	labels := `"host_name":"a\"b","exported___source":"x","_2xx":"y"`
+	want := `{"tags":{"__name__":"disk_io_up",` + labels + `},"timestamp":"-0.001","value":1}` + "\n" +
+		`{"tags":{"__name__":"disk_io_err",` + labels + `},"timestamp":"-0.001","value":"-Infinity"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %s\nwant %s", got, want)
+	}
+}
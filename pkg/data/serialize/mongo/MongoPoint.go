@@ -1,6 +1,6 @@
 // automatically generated by the FlatBuffers compiler, do not modify
 
-package serialize
+package mongo
 
 import (
 	flatbuffers "github.com/google/flatbuffers/go"
@@ -1,4 +1,4 @@
-package serialize
+package mongo
 
 import (
 	"encoding/binary"
@@ -7,39 +7,48 @@ import (
 	"sync"
 
 	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/timescale/tsbs/pkg/data/serialize"
 )
 
+// Format is the name the format of the loader is registered under, and
+// FormatTimeSeries that of the documents of a time series collection
+const (
+	Format           = "mongo"
+	FormatTimeSeries = "mongo-ts"
+)
+
+func init() {
+	serialize.Describe(Format, "MongoDB documents as length-prefixed FlatBuffers")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	serialize.Describe(FormatTimeSeries, "MongoDB Extended JSON documents of a time series collection, with the time in "+TimeField+" and the measurement and tags in the "+MetaField+" metaField, for mongoimport")
+	serialize.Register(FormatTimeSeries, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &TimeSeriesSerializer{}, nil
+	})
+	// the Header is checked by tsbs_load_mongo
+	serialize.MarkHeaderFormat(Format)
+}
+
 var fbBuilderPool = &sync.Pool{
 	New: func() interface{} {
 		return flatbuffers.NewBuilder(0)
 	},
 }
 
-// MongoSerializer writes a Point in a serialized form for MongoDB
-type MongoSerializer struct{}
+// Serializer writes a Point in a serialized form for MongoDB
+type Serializer struct{}
 
 // Serialize writes Point data to the given Writer, using basic gob encoding
-func (s *MongoSerializer) Serialize(p *Point, w io.Writer) (err error) {
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) (err error) {
 	b := fbBuilderPool.Get().(*flatbuffers.Builder)
 
-	timestampNanos := p.timestamp.UTC().UnixNano()
-
-	fieldsMap := make(map[string]interface{})
-	for i, val := range p.fieldKeys {
-		fieldsMap[string(val)] = p.fieldValues[i]
-	}
-	tagsMap := make(map[string]string)
-	for i, val := range p.tagKeys {
-		tagsMap[string(val)] = string(p.tagValues[i])
-	}
-
 	tags := []flatbuffers.UOffsetT{}
 	// In order to keep the ordering the same on deserialization, we need
 	// to go in reverse order since we are prepending rather than appending.
-	for i := len(p.tagKeys); i > 0; i-- {
-		k := string(p.tagKeys[i-1])
-		key := b.CreateString(k)
-		val := b.CreateString(tagsMap[k])
+	for i := len(p.TagKeys()); i > 0; i-- {
+		key := b.CreateByteString(p.TagKeys()[i-1])
+		val := b.CreateByteString(p.TagValues()[i-1])
 		MongoTagStart(b)
 		MongoTagAddKey(b, key)
 		MongoTagAddValue(b, val)
@@ -54,22 +63,29 @@ func (s *MongoSerializer) Serialize(p *Point, w io.Writer) (err error) {
 	fields := []flatbuffers.UOffsetT{}
 	// In order to keep the ordering the same on deserialization, we need
 	// to go in reverse order since we are prepending rather than appending.
-	for i := len(p.fieldKeys); i > 0; i-- {
-		k := string(p.fieldKeys[i-1])
-		key := b.CreateString(k)
-		MongoReadingStart(b)
-		MongoReadingAddKey(b, key)
-		v := fieldsMap[k]
-		switch val := v.(type) {
+	for i := len(p.FieldKeys()); i > 0; i-- {
+		var value float64
+		switch val := p.FieldValues()[i-1].(type) {
 		case float64:
-			MongoReadingAddValue(b, val)
+			value = val
 		case int:
-			MongoReadingAddValue(b, float64(val))
+			value = float64(val)
 		case int64:
-			MongoReadingAddValue(b, float64(val))
+			value = float64(val)
+		case bool:
+			if val {
+				value = 1
+			}
+		case []byte, string:
+			// readings are numeric only, so string fields are left out
+			continue
 		default:
 			panic(fmt.Sprintf("cannot covert %T to float64", val))
 		}
+		key := b.CreateByteString(p.FieldKeys()[i-1])
+		MongoReadingStart(b)
+		MongoReadingAddKey(b, key)
+		MongoReadingAddValue(b, value)
 		fields = append(fields, MongoReadingEnd(b))
 	}
 	MongoPointStartFieldsVector(b, len(fields))
@@ -78,10 +94,10 @@ func (s *MongoSerializer) Serialize(p *Point, w io.Writer) (err error) {
 	}
 	fieldsArr := b.EndVector(len(fields))
 
-	measurement := b.CreateString(string(p.measurementName))
+	measurement := b.CreateByteString(p.MeasurementName())
 	MongoPointStart(b)
 	MongoPointAddMeasurementName(b, measurement)
-	MongoPointAddTimestamp(b, timestampNanos)
+	MongoPointAddTimestamp(b, p.Timestamp())
 	MongoPointAddTags(b, tagsArr)
 	MongoPointAddFields(b, fieldsArr)
 	point := MongoPointEnd(b)
@@ -0,0 +1,291 @@
+package mongo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+
+	flatbuffers "github.com/google/flatbuffers/go"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+func TestSerializerSerialize(t *testing.T) {
+	type output struct {
+		name        string
+		ts          int64
+		tagKeys     [][]byte
+		tagVals     [][]byte
+		readingKeys [][]byte
+		readingVals []interface{}
+	}
+	cases := []struct {
+		desc       string
+		inputPoint *serialize.Point
+		want       output
+	}{
+		{
+			desc:       "a regular Point",
+			inputPoint: serializetest.PointDefault,
+			want: output{
+				name:        string(serializetest.Measurement),
+				ts:          serializetest.Now.UnixNano(),
+				tagKeys:     serializetest.TagKeys,
+				tagVals:     serializetest.TagValues,
+				readingKeys: serializetest.PointDefault.FieldKeys(),
+				readingVals: serializetest.PointDefault.FieldValues(),
+			},
+		},
+		{
+			desc:       "a regular Point using int as value",
+			inputPoint: serializetest.PointInt,
+			want: output{
+				name:        string(serializetest.Measurement),
+				ts:          serializetest.Now.UnixNano(),
+				tagKeys:     serializetest.TagKeys,
+				tagVals:     serializetest.TagValues,
+				readingKeys: serializetest.PointInt.FieldKeys(),
+				readingVals: serializetest.PointInt.FieldValues(),
+			},
+		},
+		{
+			desc:       "a regular Point with multiple fields",
+			inputPoint: serializetest.PointMultiField,
+			want: output{
+				name:        string(serializetest.Measurement),
+				ts:          serializetest.Now.UnixNano(),
+				tagKeys:     serializetest.TagKeys,
+				tagVals:     serializetest.TagValues,
+				readingKeys: serializetest.PointMultiField.FieldKeys(),
+				readingVals: serializetest.PointMultiField.FieldValues(),
+			},
+		},
+		{
+			desc:       "a Point with no tags",
+			inputPoint: serializetest.PointNoTags,
+			want: output{
+				name:        string(serializetest.Measurement),
+				ts:          serializetest.Now.UnixNano(),
+				tagKeys:     [][]byte{},
+				tagVals:     [][]byte{},
+				readingKeys: serializetest.PointNoTags.FieldKeys(),
+				readingVals: serializetest.PointNoTags.FieldValues(),
+			},
+		},
+	}
+
+	ps := &Serializer{}
+	for _, c := range cases {
+		b := new(bytes.Buffer)
+		ps.Serialize(c.inputPoint, b)
+		br := bufio.NewReader(bytes.NewReader(b.Bytes()))
+		mp := deserializeMongo(br)
+
+		if got := string(mp.MeasurementName()); got != c.want.name {
+			t.Errorf("%s: incorrect measreuement name: got %s want %s", c.desc, got, c.want.name)
+		}
+		if got := mp.Timestamp(); got != c.want.ts {
+			t.Errorf("%s: incorrect timestamp: got %d want %d", c.desc, got, c.want.ts)
+		}
+
+		// Verify tags
+		if got := mp.TagsLength(); got != len(c.want.tagKeys) {
+			t.Errorf("%s: incorrect tag keys length: got %d want %d", c.desc, got, len(c.want.tagKeys))
+		}
+		if got := mp.TagsLength(); got != len(c.want.tagVals) {
+			t.Errorf("%s: incorrect tag vals length: got %d want %d", c.desc, got, len(c.want.tagVals))
+		}
+		tag := &MongoTag{}
+		for i := 0; i < mp.TagsLength(); i++ {
+			mp.Tags(tag, i)
+			want := string(c.want.tagKeys[i])
+			if got := string(tag.Key()); got != want {
+				t.Errorf("%s: incorrect tag key %d: got %s want %s", c.desc, i, got, want)
+			}
+			want = string(c.want.tagVals[i])
+			if got := string(tag.Value()); got != want {
+				t.Errorf("%s: incorrect tag val %d: got %s want %s", c.desc, i, got, want)
+			}
+		}
+
+		// Verify fields
+		if got := mp.FieldsLength(); got != len(c.want.readingKeys) {
+			t.Errorf("%s: incorrect reading keys length: got %d want %d", c.desc, got, len(c.want.readingKeys))
+		}
+		if got := mp.FieldsLength(); got != len(c.want.readingVals) {
+			t.Errorf("%s: incorrect reading vals length: got %d want %d", c.desc, got, len(c.want.readingVals))
+		}
+
+		reading := &MongoReading{}
+		for i := 0; i < mp.FieldsLength(); i++ {
+			mp.Fields(reading, i)
+			want := string(c.want.readingKeys[i])
+			if got := string(reading.Key()); got != want {
+				t.Errorf("%s: incorrect reading key %d: got %s want %s", c.desc, i, got, want)
+			}
+
+			var wantVal float64
+			switch x := c.want.readingVals[i].(type) {
+			case int:
+				wantVal = float64(x)
+			case int64:
+				wantVal = float64(x)
+			case float64:
+				wantVal = x
+			}
+			if got := reading.Value(); got != wantVal {
+				t.Errorf("%s: incorrect reading val %d: got %v want %v", c.desc, i, got, wantVal)
+			}
+		}
+	}
+}
+
+func deserializeMongo(r *bufio.Reader) *MongoPoint {
+	item := &MongoPoint{}
+	lenBuf := make([]byte, 8)
+
+	_, err := r.Read(lenBuf)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
+	// ensure correct len of receiving buffer
+	l := int(binary.LittleEndian.Uint64(lenBuf))
+	itemBuf := make([]byte, l)
+
+	// read the bytes and init the flatbuffer object
+	totRead := 0
+	for totRead < l {
+		m, err := r.Read(itemBuf[totRead:])
+		// (EOF is also fatal)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		totRead += m
+	}
+	if totRead != len(itemBuf) {
+		panic(fmt.Sprintf("reader/writer logic error, %d != %d", totRead, len(itemBuf)))
+	}
+	n := flatbuffers.GetUOffsetT(itemBuf)
+	item.Init(itemBuf, n)
+
+	return item
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Decode:       decodeMongoPoints,
+		IntsAsFloats: true,
+	}.Run(t)
+}
+
+// decodeMongoPoints decodes all the MongoPoints in data into Points
+func decodeMongoPoints(data []byte) ([]*serialize.Point, error) {
+	var points []*serialize.Point
+	for len(data) > 0 {
+		if len(data) < 8 {
+			return nil, fmt.Errorf("truncated length: %d bytes", len(data))
+		}
+		l := int(binary.LittleEndian.Uint64(data))
+		if len(data) < 8+l {
+			return nil, fmt.Errorf("truncated point: %d bytes, want %d", len(data)-8, l)
+		}
+		item := &MongoPoint{}
+		itemBuf := data[8 : 8+l]
+		item.Init(itemBuf, flatbuffers.GetUOffsetT(itemBuf))
+		data = data[8+l:]
+
+		p := serialize.NewPoint()
+		p.SetMeasurementName(item.MeasurementName())
+		p.SetTimestamp(item.Timestamp())
+		tag := &MongoTag{}
+		for i := 0; i < item.TagsLength(); i++ {
+			item.Tags(tag, i)
+			p.AppendTag(tag.Key(), tag.Value())
+		}
+		reading := &MongoReading{}
+		for i := 0; i < item.FieldsLength(); i++ {
+			item.Fields(reading, i)
+			p.AppendField(reading.Key(), reading.Value())
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+func TestSerializerTypePanic(t *testing.T) {
+	testPanic := func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("did not panic when should")
+			}
+		}()
+		p := serialize.NewPoint()
+		p.SetMeasurementName(serializetest.Measurement)
+		p.SetTimestamp(serializetest.Now.UnixNano())
+		p.AppendField([]byte("broken"), []int{1})
+		ps := &Serializer{}
+		b := new(bytes.Buffer)
+
+		ps.Serialize(p, b)
+	}
+	testPanic()
+}
+
+func TestSerializerBoolAndString(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName(serializetest.Measurement)
+	p.SetTimestamp(serializetest.Now.UnixNano())
+	p.AppendField([]byte("on"), true)
+	p.AppendField([]byte("mode"), []byte("heat"))
+	p.AppendField([]byte("off"), false)
+	b := new(bytes.Buffer)
+	if err := (&Serializer{}).Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mp := deserializeMongo(bufio.NewReader(b))
+	want := []struct {
+		key   string
+		value float64
+	}{{"on", 1}, {"off", 0}}
+	if got := mp.FieldsLength(); got != len(want) {
+		t.Fatalf("incorrect readings length: got %d want %d", got, len(want))
+	}
+	reading := &MongoReading{}
+	for i, w := range want {
+		mp.Fields(reading, i)
+		if string(reading.Key()) != w.key || reading.Value() != w.value {
+			t.Errorf("incorrect reading %d: got %s=%v want %s=%v", i, reading.Key(), reading.Value(), w.key, w.value)
+		}
+	}
+}
+
+func TestSerializerSerializeErr(t *testing.T) {
+	p := serializetest.PointMultiField
+	s := &Serializer{}
+	err := s.Serialize(p, &serializetest.ErrWriter{})
+	if err == nil {
+		t.Errorf("no error returned when expected")
+	} else if err.Error() != serializetest.ErrWriterAlwaysErr {
+		t.Errorf("unexpected writer error: %v", err)
+	}
+
+	// check second error condition works
+	err = s.Serialize(p, &serializetest.ErrWriter{SkipOne: true})
+	if err == nil {
+		t.Errorf("no error returned when expected")
+	} else if err.Error() != serializetest.ErrWriterSometimesErr {
+		t.Errorf("unexpected writer error: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package mongo
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as doubles: with a decimal point, or as a
+// $numberDouble if they are NaN or infinite, which JSON has no numbers for
+var floatFormat = &serialize.FloatFormat{
+	Fmt:    'f',
+	Point:  true,
+	NaN:    `{"$numberDouble":"NaN"}`,
+	Inf:    `{"$numberDouble":"Infinity"}`,
+	NegInf: `{"$numberDouble":"-Infinity"}`,
+}
+
+// TimeField is the timeField of the time series collections of the documents
+// of FormatTimeSeries, MetaField their metaField, and MeasurementKey the key
+// of the measurement in it
+const (
+	TimeField      = "time"
+	MetaField      = "tags"
+	MeasurementKey = "measurement"
+)
+
+// dateLayout is the layout of the dates of relaxed Extended JSON, in UTC
+// with millisecond precision, as MongoDB stores them
+const dateLayout = "2006-01-02T15:04:05.000Z"
+
+// TimeSeriesSerializer writes a Point as a document of a time series
+// collection of MongoDB
+type TimeSeriesSerializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a line of the relaxed Extended JSON of a
+// document of a time series collection, as mongoimport takes it:
+//
+// {"time":{"$date":"2016-01-01T00:00:00.000Z"},"tags":{"measurement":"cpu","hostname":"host_0",...},"usage_user":58,...}
+//
+// The measurement and the tags, which identify the series, are the
+// metaField, so MongoDB buckets the readings of each series of each
+// measurement together, and the fields are the measurements of the
+// document. The time is in milliseconds, the precision of MongoDB dates,
+// written as a number of milliseconds for years outside 1970 to 9999, as
+// Extended JSON requires. Tags with empty values and fields without values
+// are left out. Floats are written with a decimal point, so that they are
+// imported as doubles rather than ints, and NaN and infinite values as
+// $numberDouble.
+func (s *TimeSeriesSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := appendDocument(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes the documents of all Points of a PointBatch to the
+// given writer in the same format as Serialize
+func (s *TimeSeriesSerializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendDocument(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendDocument(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	// floor, so readings before the epoch are not rounded up to it
+	millis := timestamp / 1e6
+	if timestamp%1e6 < 0 {
+		millis--
+	}
+	buf = append(buf, `{"`+TimeField+`":{"$date":`...)
+	if t := time.Unix(0, millis*1e6).UTC(); t.Year() >= 1970 && t.Year() <= 9999 {
+		buf = append(buf, '"')
+		buf = t.AppendFormat(buf, dateLayout)
+		buf = append(buf, '"')
+	} else {
+		buf = append(buf, `{"$numberLong":"`...)
+		buf = strconv.AppendInt(buf, millis, 10)
+		buf = append(buf, `"}`...)
+	}
+	buf = append(buf, `},"`+MetaField+`":{"`+MeasurementKey+`":`...)
+	buf = serialize.AppendJSONString(buf, measurementName)
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = serialize.AppendJSONString(buf, tagKeys[i])
+		buf = append(buf, ':')
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	buf = append(buf, '}')
+	for i, v := range fieldValues {
+		if v == nil {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = serialize.AppendJSONString(buf, fieldKeys[i])
+		buf = append(buf, ':')
+		buf = appendJSONValue(buf, v)
+	}
+	return append(buf, "}\n"...)
+}
+
+// appendJSONValue appends a field value to buf as relaxed Extended JSON
+func appendJSONValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case float64:
+		return serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	}
+	return serialize.AppendJSONValue(buf, v)
+}
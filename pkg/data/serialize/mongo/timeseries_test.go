@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testMeta = `{"time":{"$date":"2016-01-01T00:00:00.000Z"},"tags":{"measurement":"cpu","hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"}`
+
+var timeSeriesCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testMeta + `,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testMeta + `,"usage_guest":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testMeta + `,"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"time":{"$date":"2016-01-01T00:00:00.000Z"},"tags":{"measurement":"cpu"},"usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestTimeSeriesSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, timeSeriesCases, &TimeSeriesSerializer{})
+}
+
+func TestTimeSeriesSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(FormatTimeSeries, schema, w)
+		},
+		Golden: timeSeriesCases,
+	}.Run(t)
+}
+
+func TestTimeSeriesSerializerValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.SetTimestamp(-1)
+	p.AppendTag([]byte("path"), []byte(`/dev/"sda"`))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendField([]byte("whole"), 2.0)
+	p.AppendField([]byte("nan"), math.NaN())
+	p.AppendField([]byte("inf"), math.Inf(-1))
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("none"), nil)
+
+	b := new(bytes.Buffer)
+	if err := (&TimeSeriesSerializer{}).Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"time":{"$date":{"$numberLong":"-1"}},"tags":{"measurement":"disk","path":"/dev/\"sda\""},"whole":2.0,"nan":{"$numberDouble":"NaN"},"inf":{"$numberDouble":"-Infinity"},"ok":true}` + "\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect document: got\n%s\nwant\n%s", got, want)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Errorf("invalid JSON: %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+// Package mysql implements the format for MySQL and MariaDB: tab-separated
+// rows, as LOAD DATA reads them by default, after a header of the tables to
+// create.
+package mysql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "mysql"
+
+// TimeFormat is the format of the time column of each row, in UTC, as
+// DATETIME(6) columns take it
+const TimeFormat = "2006-01-02 15:04:05.000000"
+
+// Null is the value of columns which are NULL, e.g., of tags a point does
+// not have
+const Null = `\N`
+
+const tagsPrefix = "tags"
+
+// TagType is the SQL type of tag columns, which the header gives the tag
+// columns of a single measurement
+const TagType = "VARCHAR(255)"
+
+func init() {
+	serialize.Describe(Format, "MySQL/MariaDB tab-separated rows for LOAD DATA, after a header of the tables to create")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{TagKeys: schema.TagKeys(), MeasurementTagKeys: measurementTagKeys(schema)}, nil
+	})
+	// the Header is checked by tsbs_load_mysql, tsbs_load_sqlite, tsbs_load_duckdb and tsbs_load_snowflake
+	serialize.MarkHeaderFormat(Format)
+}
+
+// measurementTagKeys returns the tag keys of each measurement of schema
+// after those all measurements have
+func measurementTagKeys(schema *serialize.Schema) map[string][][]byte {
+	shared := len(schema.TagKeys())
+	tagKeys := make(map[string][][]byte)
+	for _, measurementName := range schema.Measurements() {
+		if keys := schema.TagKeysOf(measurementName); len(keys) > shared {
+			tagKeys[measurementName] = keys[shared:]
+		}
+	}
+	return tagKeys
+}
+
+// writeHeader writes the header the MySQL loader creates its tables from,
+// tab-separated: the tag keys all measurements have, prefixed by "tags",
+// followed by each measurement and the definitions of its columns, as the
+// name and the SQL type of the column, those of the tags of the measurement
+// alone first, and then an empty line:
+//
+// tags	hostname	region	...
+// cpu	usage_user DOUBLE	usage_system DOUBLE	...
+// disk	path VARCHAR(255)	fstype VARCHAR(255)	total DOUBLE	...
+func writeHeader(schema *serialize.Schema, w io.Writer) error {
+	buf := []byte(tagsPrefix)
+	for _, key := range schema.TagKeys() {
+		buf = append(buf, '\t')
+		buf = AppendEscaped(buf, key)
+	}
+	buf = append(buf, '\n')
+	tagKeys := measurementTagKeys(schema)
+	for _, measurementName := range schema.Measurements() {
+		buf = AppendEscaped(buf, []byte(measurementName))
+		for _, key := range tagKeys[measurementName] {
+			buf = append(buf, '\t')
+			buf = AppendEscaped(buf, key)
+			buf = append(buf, ' ')
+			buf = append(buf, TagType...)
+		}
+		fieldTypes := schema.FieldTypes(measurementName)
+		for i, key := range schema.FieldKeys(measurementName) {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			buf = append(buf, '\t')
+			buf = AppendEscaped(buf, key)
+			buf = append(buf, ' ')
+			buf = append(buf, SQLType(t)...)
+		}
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+// SQLType returns the SQL type of the column of fields of type t. Fields of
+// unknown type are numbers from the simulators, which fit a DOUBLE.
+func SQLType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "BIGINT"
+	case serialize.FieldTypeBool:
+		return "BOOLEAN"
+	case serialize.FieldTypeString:
+		return "TEXT"
+	default:
+		return "DOUBLE"
+	}
+}
+
+// AppendEscaped appends s to buf, escaping the bytes LOAD DATA takes as
+// separators, and backslashes, with a backslash
+func AppendEscaped(buf, s []byte) []byte {
+	for _, c := range s {
+		switch c {
+		case '\\':
+			buf = append(buf, `\\`...)
+		case '\t':
+			buf = append(buf, `\t`...)
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		case 0:
+			buf = append(buf, `\0`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// Unescape returns s with the escapes of AppendEscaped undone
+func Unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 't':
+				c = '\t'
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case '0':
+				c = 0
+			default:
+				c = s[i]
+			}
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// Serializer writes a Point in a serialized form for MySQL
+type Serializer struct {
+	// TagKeys are the tag keys of the tag columns of the rows, in order;
+	// if nil, each row has the tags of its point
+	TagKeys [][]byte
+	// MeasurementTagKeys are the tag keys of the tag columns of the rows
+	// of each measurement after those of TagKeys
+	MeasurementTagKeys map[string][][]byte
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a tab-separated row of the table of its
+// measurement, prefixed by the table name so the loader can tell the tables
+// apart, e.g.,
+//
+// cpu	2016-01-01 00:00:00.000000	host_0	eu-west-1	...	58.1317132304976170	...
+//
+// The time is followed by the value of each tag of the table in the
+// header, or \N if p does not have it, and then the fields. A tag the table
+// has no column of is an error. Bools are written as 1 or 0, and NaN and
+// infinite values, which MySQL cannot store, as \N.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf, err := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	var err error
+	for i := 0; i < b.Len() && err == nil; i++ {
+		tagKeys, tagValues := b.Tags(i)
+		_, fieldValues := b.Fields(i)
+		buf, err = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldValues, b.Timestamp(i))
+	}
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues [][]byte, fieldValues []interface{}, timestamp int64) ([]byte, error) {
+	own := s.MeasurementTagKeys[string(measurementName)]
+	if s.TagKeys != nil {
+		for _, key := range tagKeys {
+			if !hasKey(s.TagKeys, key) && !hasKey(own, key) {
+				return buf, fmt.Errorf("tag %s of %s has no column", key, measurementName)
+			}
+		}
+	}
+	buf = AppendEscaped(buf, measurementName)
+	buf = append(buf, '\t')
+	buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, TimeFormat)
+	if s.TagKeys == nil {
+		for _, v := range tagValues {
+			buf = append(buf, '\t')
+			buf = AppendEscaped(buf, v)
+		}
+	} else {
+		for _, key := range s.TagKeys {
+			buf = append(buf, '\t')
+			buf = appendTag(buf, key, tagKeys, tagValues)
+		}
+		for _, key := range own {
+			buf = append(buf, '\t')
+			buf = appendTag(buf, key, tagKeys, tagValues)
+		}
+	}
+	for _, v := range fieldValues {
+		buf = append(buf, '\t')
+		buf = appendValue(buf, v)
+	}
+	return append(buf, '\n'), nil
+}
+
+// hasKey returns whether key is among keys
+func hasKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendTag appends the value of the tag key among tagKeys, or \N
+func appendTag(buf, key []byte, tagKeys, tagValues [][]byte) []byte {
+	for i, k := range tagKeys {
+		if bytes.Equal(k, key) {
+			return AppendEscaped(buf, tagValues[i])
+		}
+	}
+	return append(buf, Null...)
+}
+
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(buf, Null...)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return append(buf, Null...)
+		}
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return append(buf, Null...)
+		}
+	case bool:
+		if x {
+			return append(buf, '1')
+		}
+		return append(buf, '0')
+	case []byte:
+		return AppendEscaped(buf, x)
+	case string:
+		return AppendEscaped(buf, []byte(x))
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
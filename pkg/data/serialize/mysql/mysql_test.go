@@ -0,0 +1,133 @@
+package mysql
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const (
+	testTime = "cpu\t2016-01-01 00:00:00.000000\t"
+	testTags = "host_0\teu-west-1\teu-west-1b\t"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testTime + testTags + "38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testTime + testTags + "38\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testTime + testTags + "5000000000\t38\t38.24311829\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     testTime + "\\N\t\\N\t\\N\t38.24311829\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{TagKeys: serializetest.TagKeys})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestRegisteredWithHeader(t *testing.T) {
+	schema := serialize.NewTaggedSchema(serializetest.TagKeys[:2], map[string][][]byte{
+		"mem": {[]byte("pool\tname")},
+	}, map[string][][]byte{
+		"mem": {[]byte("used"), []byte("swapping"), []byte("inodes\tfree")},
+		"cpu": {serializetest.ColFloat},
+	}, map[string][]serialize.FieldType{
+		"mem": {serialize.FieldTypeInt, serialize.FieldTypeBool},
+	})
+	want := "tags\thostname\tregion\ncpu\tusage_guest_nice DOUBLE\nmem\tpool\\tname VARCHAR(255)\tused BIGINT\tswapping BOOLEAN\tinodes\\tfree DOUBLE\n\n"
+	b := new(bytes.Buffer)
+	if _, err := serialize.New(Format, schema, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("incorrect header: got\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSerializerSpecialValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("mem"))
+	p.SetTimestamp(1451606400123456789)
+	p.AppendTag([]byte("region"), []byte("eu\twest"))
+	p.AppendField([]byte("nan"), math.NaN())
+	p.AppendField([]byte("inf"), math.Inf(-1))
+	p.AppendField([]byte("on"), true)
+	p.AppendField([]byte("off"), false)
+	p.AppendField([]byte("s"), "a\\b\nc")
+	b := new(bytes.Buffer)
+	if err := (&Serializer{TagKeys: serializetest.TagKeys[:2]}).Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "mem\t2016-01-01 00:00:00.123456\t\\N\teu\\twest\t\\N\t\\N\t1\t0\ta\\\\b\\nc\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect row: got %q want %q", got, want)
+	}
+}
+
+func TestSerializerMeasurementTags(t *testing.T) {
+	s := &Serializer{
+		TagKeys:            serializetest.TagKeys[:2],
+		MeasurementTagKeys: map[string][][]byte{"disk": {[]byte("path")}},
+	}
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.AppendTag([]byte("path"), []byte("/"))
+	p.AppendTag([]byte("hostname"), []byte("host_0"))
+	p.AppendField([]byte("free"), int64(1))
+	b := new(bytes.Buffer)
+	if err := s.Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "disk\t1970-01-01 00:00:00.000000\thost_0\t\\N\t/\t1\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect row: got %q want %q", got, want)
+	}
+
+	b.Reset()
+	p.SetMeasurementName([]byte("cpu"))
+	if err := s.Serialize(p, b); err == nil || err.Error() != "tag path of cpu has no column" {
+		t.Errorf("incorrect error for a tag of another measurement: %v", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("rejected row written: %q", b.String())
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	for _, s := range []string{"", "host_0", "with\ttab", "with\nnewline\r", `with\backslash`, "with\x00nul", `\`} {
+		escaped := string(AppendEscaped(nil, []byte(s)))
+		if strings.ContainsAny(escaped, "\t\n") {
+			t.Errorf("separator not escaped in %q", escaped)
+		}
+		if got := Unescape(escaped); got != s {
+			t.Errorf("incorrect round trip: got %q want %q", got, s)
+		}
+	}
+}
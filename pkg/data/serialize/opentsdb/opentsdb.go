@@ -0,0 +1,160 @@
+// Package opentsdb implements the format for OpenTSDB: the JSON body of its
+// /api/put endpoint, with a data point for each field of a reading.
+package opentsdb
+
+import (
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "opentsdb"
+
+func init() {
+	serialize.Describe(Format, "OpenTSDB /api/put JSON arrays, with a metric per field")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+}
+
+// Serializer writes a Point in a serialized form for OpenTSDB
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a line of a JSON array of the data points
+// of its fields, the body of a request to /api/put, e.g.:
+//
+// [{"metric":"cpu.usage_user","timestamp":1451606400000,"value":58.13,"tags":{"hostname":"host_0","region":"eu-west-1"}},...]
+//
+// Metrics are named <measurement>.<field>, and timestamps are in
+// milliseconds. Metric names and tags may only have ASCII letters and
+// digits, '-', '_', '.', '/' and Unicode letters in OpenTSDB, so other
+// characters are replaced by underscores. Tags with empty values, which it
+// rejects, are left out; OpenTSDB also rejects data points without tags,
+// which every use case has.
+//
+// Values must be numbers, so bools are written as 1 or 0, while strings,
+// NaN and infinite values are left out, and a reading without any other
+// field is written as an empty array.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := appendLine(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendLine(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendLine(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = append(buf, '[')
+	first := true
+	for i, v := range fieldValues {
+		if !isNumber(v) {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, `{"metric":"`...)
+		buf = AppendName(buf, measurementName)
+		buf = append(buf, '.')
+		buf = AppendName(buf, fieldKeys[i])
+		buf = append(buf, `","timestamp":`...)
+		buf = strconv.AppendInt(buf, timestamp/1e6, 10)
+		buf = append(buf, `,"value":`...)
+		buf = appendValue(buf, v)
+		buf = append(buf, `,"tags":{`...)
+		firstTag := true
+		for j, tv := range tagValues {
+			if len(tv) == 0 {
+				continue
+			}
+			if !firstTag {
+				buf = append(buf, ',')
+			}
+			firstTag = false
+			buf = append(buf, '"')
+			buf = AppendName(buf, tagKeys[j])
+			buf = append(buf, `":"`...)
+			buf = AppendName(buf, tv)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, "}}"...)
+	}
+	return append(buf, "]\n"...)
+}
+
+// isNumber returns whether v is written as the value of a data point: a
+// finite number or a bool
+func isNumber(v interface{}) bool {
+	switch x := v.(type) {
+	case float64:
+		return !math.IsNaN(x) && !math.IsInf(x, 0)
+	case float32:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	case []byte, string, nil:
+		return false
+	}
+	return true
+}
+
+// appendValue appends a field value to buf as a JSON number
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case float64:
+		return strconv.AppendFloat(buf, x, 'g', -1, 64)
+	case float32:
+		return strconv.AppendFloat(buf, float64(x), 'g', -1, 32)
+	case bool:
+		if x {
+			return append(buf, '1')
+		}
+		return append(buf, '0')
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// AppendName appends a metric name, tag key or tag value to buf: name with
+// the characters OpenTSDB does not allow in them replaced by underscores.
+// Bytes of multibyte UTF-8 characters are kept, as OpenTSDB allows Unicode
+// letters.
+func AppendName(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		if !validNameChar(c) {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+func validNameChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c >= 0x80:
+		return true
+	}
+	switch c {
+	case '-', '_', '.', '/':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package opentsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testTags = `"tags":{"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"}`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `[{"metric":"cpu.usage_guest_nice","timestamp":1451606400000,"value":38.24311829,` + testTags + "}]\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `[{"metric":"cpu.usage_guest","timestamp":1451606400000,"value":38,` + testTags + "}]\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: `[{"metric":"cpu.big_usage_guest","timestamp":1451606400000,"value":5000000000,` + testTags + "}," +
+			`{"metric":"cpu.usage_guest","timestamp":1451606400000,"value":38,` + testTags + "}," +
+			`{"metric":"cpu.usage_guest_nice","timestamp":1451606400000,"value":38.24311829,` + testTags + "}]\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `[{"metric":"cpu.usage_guest_nice","timestamp":1451606400000,"value":38.24311829,"tags":{}}]` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializeValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk io"))
+	p.SetTimestamp(1451606400123456789)
+	p.AppendTag([]byte("path"), []byte(`/dev/"sda"`))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendField([]byte("free"), math.NaN())
+	p.AppendField([]byte("used"), math.Inf(-1))
+	p.AppendField([]byte("state"), "full")
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("read:bytes"), float32(1.5))
+
+	var buf bytes.Buffer
+	if err := (&Serializer{}).Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `[{"metric":"disk_io.ok","timestamp":1451606400123,"value":1,"tags":{"path":"/dev/_sda_"}},` +
+		`{"metric":"disk_io.read_bytes","timestamp":1451606400123,"value":1.5,"tags":{"path":"/dev/_sda_"}}]` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestSerializerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Serializer{}
+	for _, p := range serializetest.FuzzPoints() {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var points []struct {
+			Metric    string
+			Timestamp int64
+			Value     float64
+			Tags      map[string]string
+		}
+		if err := json.Unmarshal([]byte(line), &points); err != nil {
+			t.Errorf("line %d: invalid JSON %q: %v", i, line, err)
+		}
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	cases := []struct {
+		name, want string
+	}{
+		{"sys.cpu-0/user_time", "sys.cpu-0/user_time"},
+		{"key with space,comma=equals", "key_with_space_comma_equals"},
+		{`a"b\c:d`, "a_b_c_d"},
+		{"ünïcödé", "ünïcödé"},
+		{"", ""},
+	}
+	for _, c := range cases {
+		if got := string(AppendName(nil, []byte(c.name))); got != c.want {
+			t.Errorf("incorrect name of %q: got %q want %q", c.name, got, c.want)
+		}
+	}
+}
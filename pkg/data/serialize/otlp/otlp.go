@@ -0,0 +1,194 @@
+// Package otlp implements the format for OpenTelemetry: each reading is an
+// ExportMetricsServiceRequest of the OpenTelemetry protocol (OTLP), which
+// OpenTelemetry collectors and the backends implementing OTLP ingest, with a
+// gauge for each of its fields.
+package otlp
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "otlp"
+
+// ScopeName is the name of the instrumentation scope of the metrics
+const ScopeName = "tsbs"
+
+// Tags of the fields of the protobuf messages of OTLP, e.g.,
+// ExportMetricsServiceRequest.resource_metrics is field 1 and
+// length-delimited (wire type 2), so its tag is 1<<3|2
+const (
+	tagResourceMetrics = 1<<3 | 2 // ExportMetricsServiceRequest.resource_metrics
+	tagScopeMetrics    = 2<<3 | 2 // ResourceMetrics.scope_metrics
+	tagScope           = 1<<3 | 2 // ScopeMetrics.scope
+	tagMetric          = 2<<3 | 2 // ScopeMetrics.metrics
+	tagScopeName       = 1<<3 | 2 // InstrumentationScope.name
+	tagMetricName      = 1<<3 | 2 // Metric.name
+	tagGauge           = 5<<3 | 2 // Metric.gauge
+	tagDataPoint       = 1<<3 | 2 // Gauge.data_points
+	tagTimeUnixNano    = 3<<3 | 1 // NumberDataPoint.time_unix_nano, a fixed64
+	tagAsDouble        = 4<<3 | 1 // NumberDataPoint.as_double, a double
+	tagAsInt           = 6<<3 | 1 // NumberDataPoint.as_int, an sfixed64
+	tagAttribute       = 7<<3 | 2 // NumberDataPoint.attributes
+	tagKey             = 1<<3 | 2 // KeyValue.key
+	tagValue           = 2<<3 | 2 // KeyValue.value
+	tagStringValue     = 1<<3 | 2 // AnyValue.string_value
+)
+
+// scope is the InstrumentationScope field of the ScopeMetrics of every
+// reading
+var scope = appendMessage(nil, tagScope, appendMessage(nil, tagScopeName, []byte(ScopeName)))
+
+func init() {
+	serialize.Describe(Format, "OpenTelemetry (OTLP) ExportMetricsServiceRequests, uncompressed protobuf with a length prefix, one per reading")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_otlp
+	serialize.MarkHeaderFormat(Format)
+}
+
+// Serializer writes a Point in a serialized form for OpenTelemetry
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and the others hold the messages being
+	// encoded: the attributes of the data points, a data point, a metric,
+	// the metrics of the reading and its ResourceMetrics
+	buf     []byte
+	attrs   []byte
+	point   []byte
+	metric  []byte
+	metrics []byte
+	row     []byte
+}
+
+// Serialize writes Point p to w as an ExportMetricsServiceRequest of OTLP,
+// with a gauge of a single data point for each numeric or bool field,
+// prefixed by its length as a varint. The request is protobuf, uncompressed,
+// so that the requests of a batch concatenated are the request of the batch,
+// which the loader sends.
+//
+// The request has a single ResourceMetrics, without a resource, whose
+// ScopeMetrics has the scope tsbs and the gauges. Each gauge is named by the
+// measurement and field, joined by a dot, e.g., cpu.usage_user, and the
+// attributes of its data point are the tags of the Point, as strings. Tags
+// with empty values have no attribute. Integers are int data points, floats
+// double ones, and bools are int data points of 1 or 0. String fields are
+// left out, as OTLP has no such data points, and Points without any other
+// fields are not written.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	// the data points of all fields have the same attributes
+	s.attrs = s.attrs[:0]
+	for i, v := range tagValues {
+		if len(v) > 0 {
+			s.attrs = appendAttribute(s.attrs, tagKeys[i], v)
+		}
+	}
+	s.metrics = append(s.metrics[:0], scope...)
+	for i, v := range fieldValues {
+		s.point = append(s.point[:0], s.attrs...)
+		s.point = appendFixed64(s.point, tagTimeUnixNano, uint64(timestamp))
+		switch x := v.(type) {
+		case float64:
+			s.point = appendFixed64(s.point, tagAsDouble, math.Float64bits(x))
+		case float32:
+			s.point = appendFixed64(s.point, tagAsDouble, math.Float64bits(float64(x)))
+		case int:
+			s.point = appendFixed64(s.point, tagAsInt, uint64(x))
+		case int64:
+			s.point = appendFixed64(s.point, tagAsInt, uint64(x))
+		case bool:
+			var n uint64
+			if x {
+				n = 1
+			}
+			s.point = appendFixed64(s.point, tagAsInt, n)
+		default:
+			continue
+		}
+		s.metric = append(s.metric[:0], tagMetricName)
+		s.metric = appendUvarint(s.metric, uint64(len(measurementName)+1+len(fieldKeys[i])))
+		s.metric = append(s.metric, measurementName...)
+		s.metric = append(s.metric, '.')
+		s.metric = append(s.metric, fieldKeys[i]...)
+		s.metric = append(s.metric, tagGauge)
+		s.metric = appendUvarint(s.metric, uint64(1+uvarintLen(uint64(len(s.point)))+len(s.point)))
+		s.metric = appendMessage(s.metric, tagDataPoint, s.point)
+		s.metrics = appendMessage(s.metrics, tagMetric, s.metric)
+	}
+	if len(s.metrics) == len(scope) {
+		return buf
+	}
+	s.row = appendMessage(s.row[:0], tagScopeMetrics, s.metrics)
+	buf = appendUvarint(buf, uint64(1+uvarintLen(uint64(len(s.row)))+len(s.row)))
+	return appendMessage(buf, tagResourceMetrics, s.row)
+}
+
+// appendAttribute appends a KeyValue message of the given key and string
+// value to buf as an attribute of a NumberDataPoint
+func appendAttribute(buf []byte, key, value []byte) []byte {
+	anyValueSize := 1 + uvarintLen(uint64(len(value))) + len(value)
+	size := 1 + uvarintLen(uint64(len(key))) + len(key) + 1 + uvarintLen(uint64(anyValueSize)) + anyValueSize
+	buf = append(buf, tagAttribute)
+	buf = appendUvarint(buf, uint64(size))
+	buf = appendMessage(buf, tagKey, key)
+	buf = append(buf, tagValue)
+	buf = appendUvarint(buf, uint64(anyValueSize))
+	return appendMessage(buf, tagStringValue, value)
+}
+
+// appendFixed64 appends a 64-bit field with the given tag and value to buf
+func appendFixed64(buf []byte, tag byte, x uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	buf = append(buf, tag)
+	return append(buf, b[:]...)
+}
+
+// appendMessage appends a length-delimited field with the given tag and
+// contents to buf
+func appendMessage(buf []byte, tag byte, contents []byte) []byte {
+	buf = append(buf, tag)
+	buf = appendUvarint(buf, uint64(len(contents)))
+	return append(buf, contents...)
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	return append(buf, b[:n]...)
+}
+
+func uvarintLen(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
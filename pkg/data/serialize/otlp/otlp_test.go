@@ -0,0 +1,128 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+// field returns a length-delimited protobuf field, whose contents must be
+// shorter than 128 bytes
+func field(tag byte, contents string) string {
+	return string([]byte{tag, byte(len(contents))}) + contents
+}
+
+func fixed64(tag byte, x uint64) string {
+	b := []byte{tag, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint64(b[1:], x)
+	return string(b)
+}
+
+func attribute(key, value string) string {
+	return field(tagAttribute, field(tagKey, key)+field(tagValue, field(tagStringValue, value)))
+}
+
+// gauge returns a Metric of the given name, with a gauge of a data point of
+// the given attributes and value, a field with tag tagAsDouble or tagAsInt
+func gauge(name, attrs, value string) string {
+	point := attrs + fixed64(tagTimeUnixNano, uint64(serializetest.Now.UnixNano())) + value
+	return field(tagMetric, field(tagMetricName, name)+field(tagGauge, field(tagDataPoint, point)))
+}
+
+func double(f float64) string {
+	return fixed64(tagAsDouble, math.Float64bits(f))
+}
+
+func integer(n int64) string {
+	return fixed64(tagAsInt, uint64(n))
+}
+
+// row returns the output for a Point with the given metrics, which may be
+// longer than 128 bytes
+func row(metrics ...string) string {
+	s := field(tagScope, field(tagScopeName, ScopeName))
+	for _, m := range metrics {
+		s += m
+	}
+	rm := appendMessage(nil, tagScopeMetrics, []byte(s))
+	req := appendMessage(nil, tagResourceMetrics, rm)
+	return string(appendUvarint(nil, uint64(len(req)))) + string(req)
+}
+
+// testAttrs are the attributes of the tags of the fixture Points
+var testAttrs = attribute("hostname", "host_0") + attribute("region", "eu-west-1") + attribute("datacenter", "eu-west-1b")
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     row(gauge("cpu.usage_guest_nice", testAttrs, double(serializetest.Float))),
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     row(gauge("cpu.usage_guest", testAttrs, integer(serializetest.Int))),
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: row(
+			gauge("cpu.big_usage_guest", testAttrs, integer(serializetest.Int64)),
+			gauge("cpu.usage_guest", testAttrs, integer(serializetest.Int)),
+			gauge("cpu.usage_guest_nice", testAttrs, double(serializetest.Float)),
+		),
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     row(gauge("cpu.usage_guest_nice", "", double(serializetest.Float))),
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializeFieldTypes(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.SetTimestamp(serializetest.Now.UnixNano())
+	p.AppendTag([]byte("path"), []byte("/"))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendField([]byte("used_percent"), float32(0.5))
+	p.AppendField([]byte("readonly"), true)
+	p.AppendField([]byte("label"), "text")
+	p.AppendField([]byte("inodes"), -3)
+
+	s := &Serializer{}
+	attrs := attribute("path", "/")
+	want := row(
+		gauge("disk.used_percent", attrs, double(0.5)),
+		gauge("disk.readonly", attrs, integer(1)),
+		gauge("disk.inodes", attrs, integer(-3)),
+	)
+	buf := s.appendRow(nil, p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	if string(buf) != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", buf, want)
+	}
+
+	onlyStrings := serialize.NewPoint()
+	onlyStrings.SetMeasurementName([]byte("disk"))
+	onlyStrings.AppendField([]byte("label"), "text")
+	if buf := s.appendRow(nil, onlyStrings.MeasurementName(), nil, nil, onlyStrings.FieldKeys(), onlyStrings.FieldValues(), 0); len(buf) != 0 {
+		t.Errorf("output for a Point without data points: %q", buf)
+	}
+}
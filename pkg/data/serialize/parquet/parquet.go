@@ -0,0 +1,526 @@
+// Package parquet implements the format for Apache Parquet: a columnar file
+// of a row for each reading, with columns for its time, its measurement and
+// each tag and field of the Schema, which Spark, DuckDB, BigQuery and others
+// load as is.
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes row
+// groups of DefaultRowGroupSize rows. Formats of the form Format + ":" + n
+// write row groups of n rows.
+const Format = "parquet"
+
+// DefaultRowGroupSize is the number of rows of the row groups of Format
+const DefaultRowGroupSize = 100000
+
+// Columns of every file: the time of each reading, as a timestamp in
+// microseconds in UTC, and its measurement
+const (
+	TimeColumn        = "timestamp"
+	MeasurementColumn = "measurement"
+)
+
+// Magic starts and ends every Parquet file
+const Magic = "PAR1"
+
+// CreatedBy is the application that wrote the files, in their metadata
+const CreatedBy = "tsbs"
+
+func init() {
+	serialize.Describe(Format, "Apache Parquet file, a row per reading, with parquet:<rows> setting the rows of each row group (default "+strconv.Itoa(DefaultRowGroupSize)+")")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultRowGroupSize, w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		rows, err := strconv.Atoi(arg)
+		if err != nil || rows <= 0 {
+			return nil, fmt.Errorf("invalid rows per row group '%s': must be a positive integer", arg)
+		}
+		return NewSerializer(schema, rows, w)
+	})
+	serialize.MarkFileFormat(Format)
+}
+
+// Enums of the Thrift definitions of the Parquet format
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecSnappy = 1
+
+	pageTypeData = 0
+
+	convertedTypeUTF8            = 0
+	convertedTypeTimestampMicros = 10
+)
+
+// column is a column of the file, with the values of the rows of the row
+// group being buffered
+type column struct {
+	name     string
+	typ      int32
+	optional bool
+	// converted is the converted type of the column, or -1 if it has none
+	converted int32
+
+	// defs are the definition levels of the rows, 1 bit each, of which
+	// there are numDefs, and values their values, PLAIN encoded, with bools
+	// bit-packed, of which there are numValues
+	defs      []byte
+	numDefs   int
+	values    []byte
+	numValues int
+	// set is whether the value of the row being added is set
+	set bool
+}
+
+// columnChunk is where a column chunk of a row group was written
+type columnChunk struct {
+	offset           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// rowGroup is a row group written, with a columnChunk for each column
+type rowGroup struct {
+	rows   int64
+	chunks []columnChunk
+}
+
+// Serializer writes Points as the rows of a Parquet file. The file is only
+// complete, with the metadata at its end, once the Serializer is closed.
+type Serializer struct {
+	rowGroupSize int
+	columns      []*column
+	// tags are the columns of the tag keys, and fields those of the field
+	// keys of each measurement
+	tags   map[string]*column
+	fields map[string]map[string]*column
+
+	// rows is the number of rows buffered, offset the number of bytes
+	// written, and w the Writer they were last written to, which Close
+	// writes the metadata to
+	rows      int
+	offset    int64
+	rowGroups []rowGroup
+	w         io.Writer
+
+	// buf and page are scratch space reused between row groups
+	buf  []byte
+	page []byte
+}
+
+// NewSerializer returns a Serializer for data described by schema, writing
+// row groups of rowGroupSize rows to w. It writes the magic number starting
+// the file to w.
+//
+// The file has a column for the time of each reading, one for its
+// measurement, one for each tag key of schema and one for each field of
+// each measurement, named <measurement>_<field> and of the type of the
+// field: INT64, BOOLEAN, BYTE_ARRAY strings or, for the numbers of the
+// simulators, DOUBLE.
+func NewSerializer(schema *serialize.Schema, rowGroupSize int, w io.Writer) (*Serializer, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("parquet files need the schema of the data")
+	}
+	s := &Serializer{
+		rowGroupSize: rowGroupSize,
+		tags:         make(map[string]*column),
+		fields:       make(map[string]map[string]*column),
+		w:            w,
+	}
+	names := make(map[string]bool)
+	add := func(c *column) error {
+		if names[c.name] {
+			return fmt.Errorf("duplicate column %s", c.name)
+		}
+		names[c.name] = true
+		s.columns = append(s.columns, c)
+		return nil
+	}
+	add(&column{name: TimeColumn, typ: typeInt64, converted: convertedTypeTimestampMicros})
+	add(&column{name: MeasurementColumn, typ: typeByteArray, converted: convertedTypeUTF8})
+	for _, key := range schema.AllTagKeys() {
+		c := &column{name: string(key), typ: typeByteArray, optional: true, converted: convertedTypeUTF8}
+		if err := add(c); err != nil {
+			return nil, err
+		}
+		s.tags[string(key)] = c
+	}
+	for _, measurementName := range schema.Measurements() {
+		fields := make(map[string]*column)
+		fieldTypes := schema.FieldTypes(measurementName)
+		for i, key := range schema.FieldKeys(measurementName) {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			c := &column{name: measurementName + "_" + string(key), optional: true, converted: -1}
+			switch t {
+			case serialize.FieldTypeInt:
+				c.typ = typeInt64
+			case serialize.FieldTypeBool:
+				c.typ = typeBoolean
+			case serialize.FieldTypeString:
+				c.typ = typeByteArray
+				c.converted = convertedTypeUTF8
+			default:
+				c.typ = typeDouble
+			}
+			if err := add(c); err != nil {
+				return nil, err
+			}
+			fields[string(key)] = c
+		}
+		s.fields[measurementName] = fields
+	}
+
+	_, err := io.WriteString(w, Magic)
+	s.offset = int64(len(Magic))
+	return s, err
+}
+
+// Serialize adds Point p as a row of the file, writing the row group to w
+// once it has its rows. Tags with empty values are null, as are the fields
+// of the other measurements. Fields must have values of the types of their
+// columns, though ints may be in DOUBLE columns, and a tag or field that is
+// not in the Schema, so has no column, is an error.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	if err := s.addRow(p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp()); err != nil {
+		return err
+	}
+	s.w = w
+	if s.rows < s.rowGroupSize {
+		return nil
+	}
+	return s.writeRowGroup(w)
+}
+
+// SerializeBatch adds all rows of a PointBatch as Serialize does
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		if err := s.addRow(b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i)); err != nil {
+			return err
+		}
+		s.w = w
+		if s.rows < s.rowGroupSize {
+			continue
+		}
+		if err := s.writeRowGroup(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close writes the rows left as the last row group, and then the metadata
+// ending the file
+func (s *Serializer) Close() error {
+	if err := s.writeRowGroup(s.w); err != nil {
+		return err
+	}
+	buf := s.appendFileMetaData(s.buf[:0])
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(buf)))
+	buf = append(buf, Magic...)
+	_, err := s.w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) addRow(measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) error {
+	// the columns and types are checked first, so no column is changed if
+	// any is wrong
+	for _, key := range tagKeys {
+		if _, ok := s.tags[string(key)]; !ok {
+			return fmt.Errorf("tag %s of %s has no column", key, measurementName)
+		}
+	}
+	fields := s.fields[string(measurementName)]
+	for i, key := range fieldKeys {
+		c, ok := fields[string(key)]
+		if !ok {
+			return fmt.Errorf("field %s of %s has no column", key, measurementName)
+		}
+		if !c.accepts(fieldValues[i]) {
+			return fmt.Errorf("field %s of %s is a %T, not a value of its column", key, measurementName, fieldValues[i])
+		}
+	}
+
+	micros := timestamp / 1e3
+	if timestamp < 0 && timestamp%1e3 != 0 {
+		micros--
+	}
+	s.columns[0].appendInt64(micros)
+	s.columns[1].appendBytes(measurementName)
+	for i, key := range tagKeys {
+		if c := s.tags[string(key)]; !c.set && len(tagValues[i]) > 0 {
+			c.appendBytes(tagValues[i])
+		}
+	}
+	for i, key := range fieldKeys {
+		if c := fields[string(key)]; !c.set && fieldValues[i] != nil {
+			c.appendValue(fieldValues[i])
+		}
+	}
+	for _, c := range s.columns {
+		if c.optional {
+			c.appendDef()
+		}
+		c.set = false
+	}
+	s.rows++
+	return nil
+}
+
+// writeRowGroup writes the rows buffered to w as a row group, with a column
+// chunk of a single data page for each column
+func (s *Serializer) writeRowGroup(w io.Writer) error {
+	if s.rows == 0 {
+		return nil
+	}
+	rg := rowGroup{rows: int64(s.rows), chunks: make([]columnChunk, len(s.columns))}
+	buf := s.buf[:0]
+	for i, c := range s.columns {
+		page := s.page[:0]
+		if c.optional {
+			// the definition levels, as a single bit-packed run of the
+			// RLE/bit-packing hybrid, preceded by its length
+			start := len(page)
+			page = append(page, 0, 0, 0, 0)
+			page = binary.AppendUvarint(page, uint64(len(c.defs))<<1|1)
+			page = append(page, c.defs...)
+			binary.LittleEndian.PutUint32(page[start:], uint32(len(page)-start-4))
+		}
+		page = append(page, c.values...)
+		s.page = page
+
+		chunkStart := len(buf)
+		compressed := snappy.Encode(nil, page)
+		h := compact{buf: buf}
+		h.begin()
+		h.i32(1, pageTypeData)
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(compressed)))
+		h.structField(5)
+		h.i32(1, int32(s.rows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.end()
+		h.end()
+		headerSize := len(h.buf) - chunkStart
+		buf = append(h.buf, compressed...)
+
+		rg.chunks[i] = columnChunk{
+			offset:           s.offset + int64(chunkStart),
+			uncompressedSize: int64(headerSize + len(page)),
+			compressedSize:   int64(headerSize + len(compressed)),
+		}
+		c.reset()
+	}
+	s.rows = 0
+	s.rowGroups = append(s.rowGroups, rg)
+	_, err := w.Write(buf)
+	s.offset += int64(len(buf))
+	s.buf = buf
+	return err
+}
+
+// appendFileMetaData appends the FileMetaData of the file to buf
+func (s *Serializer) appendFileMetaData(buf []byte) []byte {
+	var rows int64
+	for _, rg := range s.rowGroups {
+		rows += rg.rows
+	}
+	m := compact{buf: buf}
+	m.begin()
+	m.i32(1, 1)
+	m.list(2, compactStruct, len(s.columns)+1)
+	m.listStruct()
+	m.binary(4, "schema")
+	m.i32(5, int32(len(s.columns)))
+	m.end()
+	for _, c := range s.columns {
+		m.listStruct()
+		m.i32(1, c.typ)
+		if c.optional {
+			m.i32(3, repetitionOptional)
+		} else {
+			m.i32(3, repetitionRequired)
+		}
+		m.binary(4, c.name)
+		if c.converted >= 0 {
+			m.i32(6, c.converted)
+		}
+		// the LogicalType, a union of a struct for each type
+		switch c.converted {
+		case convertedTypeUTF8:
+			m.structField(10)
+			m.structField(1) // STRING
+			m.end()
+			m.end()
+		case convertedTypeTimestampMicros:
+			m.structField(10)
+			m.structField(8) // TIMESTAMP
+			m.bool(1, true)  // isAdjustedToUTC
+			m.structField(2) // unit
+			m.structField(2) // MICROS
+			m.end()
+			m.end()
+			m.end()
+			m.end()
+		}
+		m.end()
+	}
+	m.i64(3, rows)
+	m.list(4, compactStruct, len(s.rowGroups))
+	for _, rg := range s.rowGroups {
+		var uncompressed, compressed int64
+		m.listStruct()
+		m.list(1, compactStruct, len(s.columns))
+		for i, c := range s.columns {
+			chunk := rg.chunks[i]
+			uncompressed += chunk.uncompressedSize
+			compressed += chunk.compressedSize
+			m.listStruct()
+			m.i64(2, chunk.offset)
+			m.structField(3)
+			m.i32(1, c.typ)
+			if c.optional {
+				m.list(2, compactI32, 2)
+				m.elemI32(encodingPlain)
+				m.elemI32(encodingRLE)
+			} else {
+				m.list(2, compactI32, 1)
+				m.elemI32(encodingPlain)
+			}
+			m.list(3, compactBinary, 1)
+			m.elemBinary(c.name)
+			m.i32(4, codecSnappy)
+			m.i64(5, rg.rows)
+			m.i64(6, chunk.uncompressedSize)
+			m.i64(7, chunk.compressedSize)
+			m.i64(9, chunk.offset)
+			m.end()
+			m.end()
+		}
+		m.i64(2, uncompressed)
+		m.i64(3, rg.rows)
+		m.i64(5, rg.chunks[0].offset)
+		m.i64(6, compressed)
+		m.end()
+	}
+	m.binary(6, CreatedBy)
+	m.end()
+	return m.buf
+}
+
+// accepts returns whether v can be a value of c
+func (c *column) accepts(v interface{}) bool {
+	switch v.(type) {
+	case nil:
+		return true
+	case int, int64:
+		return c.typ == typeInt64 || c.typ == typeDouble
+	case float64, float32:
+		return c.typ == typeDouble
+	case bool:
+		return c.typ == typeBoolean
+	case []byte, string:
+		return c.typ == typeByteArray
+	}
+	return false
+}
+
+// appendValue appends v, which c accepts, to the values of c
+func (c *column) appendValue(v interface{}) {
+	switch x := v.(type) {
+	case int:
+		c.appendNumber(int64(x))
+	case int64:
+		c.appendNumber(x)
+	case float64:
+		c.appendFloat(x)
+	case float32:
+		c.appendFloat(float64(x))
+	case bool:
+		if c.numValues%8 == 0 {
+			c.values = append(c.values, 0)
+		}
+		if x {
+			c.values[len(c.values)-1] |= 1 << (c.numValues % 8)
+		}
+		c.numValues++
+		c.set = true
+	case []byte:
+		c.appendBytes(x)
+	case string:
+		c.appendBytes([]byte(x))
+	}
+}
+
+func (c *column) appendNumber(x int64) {
+	if c.typ == typeDouble {
+		c.appendFloat(float64(x))
+	} else {
+		c.appendInt64(x)
+	}
+}
+
+func (c *column) appendInt64(x int64) {
+	c.values = binary.LittleEndian.AppendUint64(c.values, uint64(x))
+	c.numValues++
+	c.set = true
+}
+
+func (c *column) appendFloat(f float64) {
+	c.values = binary.LittleEndian.AppendUint64(c.values, math.Float64bits(f))
+	c.numValues++
+	c.set = true
+}
+
+func (c *column) appendBytes(b []byte) {
+	c.values = binary.LittleEndian.AppendUint32(c.values, uint32(len(b)))
+	c.values = append(c.values, b...)
+	c.numValues++
+	c.set = true
+}
+
+// appendDef appends the definition level of the row being added: 1 if its
+// value is set, or 0 if it is null
+func (c *column) appendDef() {
+	if c.numDefs%8 == 0 {
+		c.defs = append(c.defs, 0)
+	}
+	if c.set {
+		c.defs[len(c.defs)-1] |= 1 << (c.numDefs % 8)
+	}
+	c.numDefs++
+}
+
+func (c *column) reset() {
+	c.defs = c.defs[:0]
+	c.values = c.values[:0]
+	c.numValues = 0
+	c.numDefs = 0
+}
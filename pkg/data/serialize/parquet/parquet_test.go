@@ -0,0 +1,418 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		// row groups are only written once they have their rows, or on
+		// Close
+		IgnoresWriter: true,
+		Plain:         rowStrings,
+	}.Run(t)
+}
+
+// rowStrings returns the strings of the rows of the file data, one after the
+// other, since its pages are compressed
+func rowStrings(data []byte) ([]byte, error) {
+	columns, rows, _, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	for _, row := range rows {
+		for _, c := range columns {
+			if v, ok := row[c].(string); ok {
+				out = append(out, v...)
+			}
+		}
+	}
+	return out, nil
+}
+
+// thriftReader decodes Thrift structs in the compact protocol, as maps of
+// field ids to their values: int64s, bools, strings, lists and structs
+type thriftReader struct {
+	data []byte
+	err  error
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("bad varint")
+		r.data = nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case compactTrue:
+		return true
+	case compactFalse:
+		return false
+	case compactI32, compactI64:
+		return r.varint()
+	case compactBinary:
+		n := int(r.uvarint())
+		if n > len(r.data) {
+			r.err = fmt.Errorf("bad binary length %d", n)
+			r.data = nil
+			return ""
+		}
+		s := string(r.data[:n])
+		r.data = r.data[n:]
+		return s
+	case compactList:
+		if len(r.data) == 0 {
+			r.err = io.ErrUnexpectedEOF
+			return nil
+		}
+		h := r.data[0]
+		r.data = r.data[1:]
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		var list []interface{}
+		for i := 0; i < n && r.err == nil; i++ {
+			elemType := h & 0xf
+			if elemType == compactTrue {
+				// bools in lists are a byte each
+				list = append(list, r.data[0] == compactTrue)
+				r.data = r.data[1:]
+				continue
+			}
+			list = append(list, r.value(elemType))
+		}
+		return list
+	case compactStruct:
+		return r.readStruct()
+	}
+	r.err = fmt.Errorf("unexpected type %d", typ)
+	r.data = nil
+	return nil
+}
+
+func (r *thriftReader) readStruct() map[int16]interface{} {
+	s := make(map[int16]interface{})
+	var last int16
+	for r.err == nil {
+		if len(r.data) == 0 {
+			r.err = io.ErrUnexpectedEOF
+			break
+		}
+		b := r.data[0]
+		r.data = r.data[1:]
+		if b == 0 {
+			break
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(r.varint())
+		}
+		last = id
+		s[id] = r.value(b & 0xf)
+	}
+	return s
+}
+
+// decode decodes a Parquet file written by a Serializer into its rows, as
+// maps of column names to values, with no entries for nulls, checking the
+// metadata is that of columns
+func decode(data []byte) (columns []string, rows []map[string]interface{}, rowGroups int, err error) {
+	if !bytes.HasPrefix(data, []byte(Magic)) || !bytes.HasSuffix(data, []byte(Magic)) || len(data) < 12 {
+		return nil, nil, 0, fmt.Errorf("no magic number")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := &thriftReader{data: data[len(data)-8-footerLen : len(data)-8]}
+	meta := footer.readStruct()
+	if footer.err != nil {
+		return nil, nil, 0, footer.err
+	}
+	if meta[6] != CreatedBy {
+		return nil, nil, 0, fmt.Errorf("created_by is %v", meta[6])
+	}
+	schema := meta[2].([]interface{})
+	var types []int64
+	var optional []bool
+	for _, e := range schema[1:] {
+		element := e.(map[int16]interface{})
+		columns = append(columns, element[4].(string))
+		types = append(types, element[1].(int64))
+		optional = append(optional, element[3].(int64) == repetitionOptional)
+	}
+	if got := schema[0].(map[int16]interface{})[5]; got != int64(len(columns)) {
+		return nil, nil, 0, fmt.Errorf("root has %v children, not %d", got, len(columns))
+	}
+
+	for _, g := range meta[4].([]interface{}) {
+		rg := g.(map[int16]interface{})
+		n := int(rg[3].(int64))
+		groupRows := make([]map[string]interface{}, n)
+		for i := range groupRows {
+			groupRows[i] = make(map[string]interface{})
+		}
+		for i, c := range rg[1].([]interface{}) {
+			chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if path := chunk[3].([]interface{}); path[0] != columns[i] {
+				return nil, nil, 0, fmt.Errorf("column chunk %d is of %v, not %s", i, path, columns[i])
+			}
+			if chunk[5] != int64(n) {
+				return nil, nil, 0, fmt.Errorf("column chunk %d has %v values, not %d", i, chunk[5], n)
+			}
+			offset, size := chunk[9].(int64), chunk[7].(int64)
+			r := &thriftReader{data: data[offset : offset+size]}
+			header := r.readStruct()
+			if r.err != nil {
+				return nil, nil, 0, r.err
+			}
+			if int64(len(r.data)) != header[3].(int64) {
+				return nil, nil, 0, fmt.Errorf("page of column chunk %d is %d bytes, not %d", i, len(r.data), header[3])
+			}
+			page, err := snappy.Decode(nil, r.data)
+			if err != nil {
+				return nil, nil, 0, err
+			}
+			defined := make([]bool, n)
+			if optional[i] {
+				defsLen := binary.LittleEndian.Uint32(page)
+				defs := page[4 : 4+defsLen]
+				page = page[4+defsLen:]
+				header, k := binary.Uvarint(defs)
+				if header&1 != 1 || int(header>>1)*8 < n {
+					return nil, nil, 0, fmt.Errorf("bad definition levels header %d", header)
+				}
+				defs = defs[k:]
+				for j := range defined {
+					defined[j] = defs[j/8]>>(j%8)&1 == 1
+				}
+			} else {
+				for j := range defined {
+					defined[j] = true
+				}
+			}
+			bit := 0
+			for j := range defined {
+				if !defined[j] {
+					continue
+				}
+				var v interface{}
+				switch types[i] {
+				case typeInt64:
+					v = int64(binary.LittleEndian.Uint64(page))
+					page = page[8:]
+				case typeDouble:
+					v = math.Float64frombits(binary.LittleEndian.Uint64(page))
+					page = page[8:]
+				case typeBoolean:
+					v = page[bit/8]>>(bit%8)&1 == 1
+					bit++
+				case typeByteArray:
+					l := binary.LittleEndian.Uint32(page)
+					v = string(page[4 : 4+l])
+					page = page[4+l:]
+				}
+				groupRows[j][columns[i]] = v
+			}
+		}
+		rows = append(rows, groupRows...)
+		rowGroups++
+	}
+	if meta[3] != int64(len(rows)) {
+		return nil, nil, 0, fmt.Errorf("num_rows is %v, not %d", meta[3], len(rows))
+	}
+	return columns, rows, rowGroups, nil
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func testSchema() *serialize.Schema {
+	return serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"state": {[]byte("rack")}},
+		map[string][][]byte{
+			"cpu":   {[]byte("usage_user"), []byte("usage_system")},
+			"state": {[]byte("count"), []byte("up"), []byte("status")},
+		},
+		map[string][]serialize.FieldType{
+			"state": {serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString},
+		},
+	)
+}
+
+func TestSerializerRows(t *testing.T) {
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400000000000, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", 58.13, "usage_system", int64(2)),
+		newPoint("state", 1451606410000000000, []string{"hostname", "host_1", "region", ""}, "count", int64(-3), "up", true, "status", "ok"),
+		newPoint("cpu", -1500, nil, "usage_system", math.Inf(-1)),
+		newPoint("state", 1451606420000000000, []string{"hostname", "host_0", "rack", "r1"}, "up", false),
+	}
+	wantColumns := []string{"timestamp", "measurement", "hostname", "region", "rack", "cpu_usage_user", "cpu_usage_system", "state_count", "state_up", "state_status"}
+	wantRows := []map[string]interface{}{
+		{"timestamp": int64(1451606400000000), "measurement": "cpu", "hostname": "host_0", "region": "eu-west-1", "cpu_usage_user": 58.13, "cpu_usage_system": 2.0},
+		{"timestamp": int64(1451606410000000), "measurement": "state", "hostname": "host_1", "state_count": int64(-3), "state_up": true, "state_status": "ok"},
+		{"timestamp": int64(-2), "measurement": "cpu", "cpu_usage_system": math.Inf(-1)},
+		{"timestamp": int64(1451606420000000), "measurement": "state", "hostname": "host_0", "rack": "r1", "state_up": false},
+	}
+
+	for _, rowGroupSize := range []int{1, 3, DefaultRowGroupSize} {
+		for _, batch := range []bool{false, true} {
+			var buf bytes.Buffer
+			s, err := NewSerializer(testSchema(), rowGroupSize, &buf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if batch {
+				b := serialize.NewPointBatch()
+				for _, p := range points {
+					b.Append(p)
+				}
+				err = s.SerializeBatch(b, &buf)
+			} else {
+				for _, p := range points {
+					if err = s.Serialize(p, &buf); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("unexpected error closing: %v", err)
+			}
+
+			columns, rows, rowGroups, err := decode(buf.Bytes())
+			if err != nil {
+				t.Fatalf("rows per group %d: unexpected error decoding: %v", rowGroupSize, err)
+			}
+			if !reflect.DeepEqual(columns, wantColumns) {
+				t.Errorf("incorrect columns: got %q want %q", columns, wantColumns)
+			}
+			if !reflect.DeepEqual(rows, wantRows) {
+				t.Errorf("rows per group %d, batch %t: incorrect rows:\ngot  %v\nwant %v", rowGroupSize, batch, rows, wantRows)
+			}
+			if want := (len(points) + rowGroupSize - 1) / rowGroupSize; rowGroups != want {
+				t.Errorf("incorrect number of row groups with %d rows per group: got %d want %d", rowGroupSize, rowGroups, want)
+			}
+		}
+	}
+}
+
+func TestSerializerRowGroupsWritten(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema(), 2, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != Magic {
+		t.Fatalf("incorrect start of file: got %q", buf.String())
+	}
+	p := newPoint("cpu", 0, nil, "usage_user", 1.0)
+	s.Serialize(p, &buf)
+	if buf.Len() != len(Magic) {
+		t.Errorf("row group written before it had its rows")
+	}
+	s.Serialize(p, &buf)
+	if buf.Len() == len(Magic) {
+		t.Errorf("row group not written once it had its rows")
+	}
+}
+
+func TestSerializerTypeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema(), DefaultRowGroupSize, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = s.Serialize(newPoint("state", 0, nil, "count", int64(1), "up", "yes"), &buf)
+	if err == nil {
+		t.Fatalf("no error for a string in a BOOLEAN column")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	if _, rows, _, err := decode(buf.Bytes()); err != nil || len(rows) != 0 {
+		t.Errorf("rejected row written: %v (error %v)", rows, err)
+	}
+}
+
+func TestSerializerNoColumn(t *testing.T) {
+	cases := []struct {
+		desc string
+		p    *serialize.Point
+		want string
+	}{
+		{desc: "tag", p: newPoint("cpu", 0, []string{"hostname", "host_0", "zone", "a"}, "usage_user", 1.0), want: "tag zone of cpu has no column"},
+		{desc: "field", p: newPoint("cpu", 0, nil, "usage_user", 1.0, "usage_idle", 2.0), want: "field usage_idle of cpu has no column"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		s, err := NewSerializer(testSchema(), DefaultRowGroupSize, &buf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Serialize(c.p, &buf); err == nil || err.Error() != c.want {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.want)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+		if _, rows, _, err := decode(buf.Bytes()); err != nil || len(rows) != 0 {
+			t.Errorf("%s: rejected row written: %v (error %v)", c.desc, rows, err)
+		}
+	}
+}
+
+func TestNewSerializerErrors(t *testing.T) {
+	if _, err := NewSerializer(nil, 1, io.Discard); err == nil {
+		t.Errorf("no error without a schema")
+	}
+	schema := serialize.NewSchema([][]byte{[]byte("cpu_usage")}, map[string][][]byte{"cpu": {[]byte("usage")}})
+	if _, err := NewSerializer(schema, 1, io.Discard); err == nil || !strings.Contains(err.Error(), "duplicate column cpu_usage") {
+		t.Errorf("incorrect error for a duplicate column: %v", err)
+	}
+	for _, format := range []string{"parquet:0", "parquet:-1", "parquet:many"} {
+		if _, err := serialize.New(format, testSchema(), io.Discard); err == nil {
+			t.Errorf("no error for format %s", format)
+		}
+	}
+	if _, err := serialize.New("parquet:10", testSchema(), io.Discard); err != nil {
+		t.Errorf("unexpected error for format parquet:10: %v", err)
+	}
+}
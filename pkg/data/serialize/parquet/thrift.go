@@ -0,0 +1,100 @@
+package parquet
+
+import "encoding/binary"
+
+// Types of the Thrift compact protocol, the encoding of the page headers and
+// metadata of Parquet files
+const (
+	compactTrue   = 1
+	compactFalse  = 2
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compact appends Thrift structs to buf in the compact protocol. Structs are
+// started with begin, or with structField or listStruct for nested ones,
+// and finished with end.
+type compact struct {
+	buf []byte
+	// last are the ids of the last fields written of the structs being
+	// written, innermost last, as field ids are written as deltas from them
+	last []int16
+}
+
+func (c *compact) begin() {
+	c.last = append(c.last, 0)
+}
+
+func (c *compact) end() {
+	c.buf = append(c.buf, 0)
+	c.last = c.last[:len(c.last)-1]
+}
+
+func (c *compact) field(id int16, typ byte) {
+	last := &c.last[len(c.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		c.buf = append(c.buf, byte(delta)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.buf = binary.AppendVarint(c.buf, int64(id))
+	}
+	*last = id
+}
+
+func (c *compact) bool(id int16, v bool) {
+	if v {
+		c.field(id, compactTrue)
+	} else {
+		c.field(id, compactFalse)
+	}
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.buf = binary.AppendVarint(c.buf, int64(v))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.buf = binary.AppendVarint(c.buf, v)
+}
+
+func (c *compact) binary(id int16, s string) {
+	c.field(id, compactBinary)
+	c.elemBinary(s)
+}
+
+// structField starts a struct as field id
+func (c *compact) structField(id int16) {
+	c.field(id, compactStruct)
+	c.begin()
+}
+
+// list starts a list of n elements of type elemType as field id, whose
+// elements are then written with the elem methods, or listStruct
+func (c *compact) list(id int16, elemType byte, n int) {
+	c.field(id, compactList)
+	if n < 15 {
+		c.buf = append(c.buf, byte(n)<<4|elemType)
+	} else {
+		c.buf = append(c.buf, 0xf0|elemType)
+		c.buf = binary.AppendUvarint(c.buf, uint64(n))
+	}
+}
+
+// listStruct starts a struct element of a list
+func (c *compact) listStruct() {
+	c.begin()
+}
+
+func (c *compact) elemI32(v int32) {
+	c.buf = binary.AppendVarint(c.buf, int64(v))
+}
+
+func (c *compact) elemBinary(s string) {
+	c.buf = binary.AppendUvarint(c.buf, uint64(len(s)))
+	c.buf = append(c.buf, s...)
+}
@@ -0,0 +1,206 @@
+// Package pinot implements the format for Apache Pinot: rows as JSON, after
+// a header of the schema and offline table config of each measurement.
+package pinot
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "pinot"
+
+// TimeColumn is the name of the column holding the timestamp of each row, in
+// milliseconds since the epoch, which is the time column of the tables
+const TimeColumn = "ts"
+
+func init() {
+	serialize.Describe(Format, "Apache Pinot JSON rows, after a header of the schemas and offline table configs of the tables to create")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_pinot
+	serialize.MarkHeaderFormat(Format)
+}
+
+// writeHeader writes the schema and table config of the table of each
+// measurement, one per line, as its name, a comma and a JSON object of both
+// as the Pinot controller API takes them, and then an empty line:
+//
+// cpu,{"schema":{"schemaName":"cpu","dimensionFieldSpecs":[{"name":"hostname","dataType":"STRING"},...],"metricFieldSpecs":[{"name":"usage_user","dataType":"DOUBLE"},...],"dateTimeFieldSpecs":[...]},"table":{"tableName":"cpu","tableType":"OFFLINE",...}}
+//
+// Tags are dimensions, with an inverted index each, and numeric fields are
+// metrics. Bool and string fields are dimensions too, as Pinot metrics are
+// numeric. The table config includes the batch ingestion config of the
+// table, for JSON input appended to it.
+func writeHeader(schema *serialize.Schema, w io.Writer) error {
+	var buf []byte
+	for _, measurementName := range schema.Measurements() {
+		name := AppendName(nil, []byte(measurementName))
+		buf = append(buf, name...)
+		buf = append(buf, `,{"schema":{"schemaName":"`...)
+		buf = append(buf, name...)
+		buf = append(buf, `","dimensionFieldSpecs":[`...)
+		tagKeys := schema.TagKeysOf(measurementName)
+		first := true
+		for _, key := range tagKeys {
+			buf = appendFieldSpec(buf, !first, key, "STRING")
+			first = false
+		}
+		fieldKeys, fieldTypes := schema.FieldKeys(measurementName), schema.FieldTypes(measurementName)
+		types := make([]string, len(fieldKeys))
+		for i := range fieldKeys {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			types[i] = dataType(t)
+			if !isMetric(types[i]) {
+				buf = appendFieldSpec(buf, !first, fieldKeys[i], types[i])
+				first = false
+			}
+		}
+		buf = append(buf, `],"metricFieldSpecs":[`...)
+		first = true
+		for i, key := range fieldKeys {
+			if isMetric(types[i]) {
+				buf = appendFieldSpec(buf, !first, key, types[i])
+				first = false
+			}
+		}
+		buf = append(buf, `],"dateTimeFieldSpecs":[{"name":"`+TimeColumn+`","dataType":"LONG","format":"1:MILLISECONDS:EPOCH","granularity":"1:MILLISECONDS"}]},"table":{"tableName":"`...)
+		buf = append(buf, name...)
+		buf = append(buf, `","tableType":"OFFLINE","segmentsConfig":{"schemaName":"`...)
+		buf = append(buf, name...)
+		buf = append(buf, `","timeColumnName":"`+TimeColumn+`","timeType":"MILLISECONDS","replication":"1"},"tenants":{},"tableIndexConfig":{"loadMode":"MMAP","invertedIndexColumns":[`...)
+		for i, key := range tagKeys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, '"')
+			buf = AppendName(buf, key)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, `]},"metadata":{},"ingestionConfig":{"batchIngestionConfig":{"segmentIngestionType":"APPEND","segmentIngestionFrequency":"DAILY","batchConfigMaps":[{"inputFormat":"json"}]}}}}`+"\n"...)
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+func appendFieldSpec(buf []byte, comma bool, name []byte, typ string) []byte {
+	if comma {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, `{"name":"`...)
+	buf = AppendName(buf, name)
+	buf = append(buf, `","dataType":"`...)
+	buf = append(buf, typ...)
+	return append(buf, `"}`...)
+}
+
+// dataType returns the Pinot data type of the column for fields of type t.
+// Fields of unknown type are numbers from the simulators, which fit a
+// DOUBLE.
+func dataType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "LONG"
+	case serialize.FieldTypeBool:
+		return "BOOLEAN"
+	case serialize.FieldTypeString:
+		return "STRING"
+	default:
+		return "DOUBLE"
+	}
+}
+
+// isMetric returns whether columns of a data type are metrics, rather than
+// dimensions
+func isMetric(typ string) bool {
+	return typ == "LONG" || typ == "DOUBLE"
+}
+
+// AppendName appends name to buf as a Pinot table or column name, which
+// here may only have letters, digits and underscores and may not start with
+// a digit, so it needs no quoting in queries: other bytes are replaced by
+// underscores, and names starting with a digit, or empty, are prefixed by
+// one
+func AppendName(buf []byte, name []byte) []byte {
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		buf = append(buf, '_')
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_':
+			buf = append(buf, c)
+		default:
+			buf = append(buf, '_')
+		}
+	}
+	return buf
+}
+
+// Serializer writes a Point in a serialized form for Apache Pinot
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a JSON row of the table of its
+// measurement, prefixed by the table name and a comma so the loader can tell
+// the tables apart:
+//
+// cpu,{"ts":1451606400000,"hostname":"host_0",...,"usage_user":58.1317132304976170,...}
+//
+// Tags with empty values are left out, so their columns are null. Infinite
+// and NaN values are written as the strings Infinity, -Infinity and NaN,
+// which Pinot parses as doubles.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = AppendName(buf, measurementName)
+	buf = append(buf, `,{"`+TimeColumn+`":`...)
+	buf = strconv.AppendInt(buf, timestamp/1e6, 10)
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		buf = append(buf, `,"`...)
+		buf = AppendName(buf, tagKeys[i])
+		buf = append(buf, `":`...)
+		buf = serialize.AppendJSONString(buf, v)
+	}
+	for i, v := range fieldValues {
+		buf = append(buf, `,"`...)
+		buf = AppendName(buf, fieldKeys[i])
+		buf = append(buf, `":`...)
+		buf = serialize.AppendJSONValue(buf, v)
+	}
+	return append(buf, "}\n"...)
+}
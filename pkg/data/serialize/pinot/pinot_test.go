@@ -0,0 +1,119 @@
+package pinot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testTags = `"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `cpu,{"ts":1451606400000,` + testTags + `,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `cpu,{"ts":1451606400000,` + testTags + `,"usage_guest":38}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     `cpu,{"ts":1451606400000,` + testTags + `,"big_usage_guest":5000000000,"usage_guest":38,"usage_guest_nice":38.24311829}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `cpu,{"ts":1451606400000,"usage_guest_nice":38.24311829}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestRegisteredWithHeader(t *testing.T) {
+	schema := serialize.NewTaggedSchema(serializetest.TagKeys[:2], map[string][][]byte{
+		"mem": {[]byte("pool")},
+	}, map[string][][]byte{
+		"mem": {[]byte("used"), []byte("swapping"), []byte("inodes-free")},
+		"cpu": {serializetest.ColFloat},
+	}, map[string][]serialize.FieldType{
+		"mem": {serialize.FieldTypeInt, serialize.FieldTypeBool},
+	})
+	const table = `"dateTimeFieldSpecs":[{"name":"ts","dataType":"LONG","format":"1:MILLISECONDS:EPOCH","granularity":"1:MILLISECONDS"}]},"table":{"tableName":"%[1]s","tableType":"OFFLINE",` +
+		`"segmentsConfig":{"schemaName":"%[1]s","timeColumnName":"ts","timeType":"MILLISECONDS","replication":"1"},"tenants":{},"tableIndexConfig":{"loadMode":"MMAP","invertedIndexColumns":[%[2]s]},"metadata":{},` +
+		`"ingestionConfig":{"batchIngestionConfig":{"segmentIngestionType":"APPEND","segmentIngestionFrequency":"DAILY","batchConfigMaps":[{"inputFormat":"json"}]}}}}`
+	want := `cpu,{"schema":{"schemaName":"cpu","dimensionFieldSpecs":[{"name":"hostname","dataType":"STRING"},{"name":"region","dataType":"STRING"}],` +
+		`"metricFieldSpecs":[{"name":"usage_guest_nice","dataType":"DOUBLE"}],` + fmt.Sprintf(table, "cpu", `"hostname","region"`) + "\n" +
+		`mem,{"schema":{"schemaName":"mem","dimensionFieldSpecs":[{"name":"hostname","dataType":"STRING"},{"name":"region","dataType":"STRING"},{"name":"pool","dataType":"STRING"},{"name":"swapping","dataType":"BOOLEAN"}],` +
+		`"metricFieldSpecs":[{"name":"used","dataType":"LONG"},{"name":"inodes_free","dataType":"DOUBLE"}],` + fmt.Sprintf(table, "mem", `"hostname","region","pool"`) + "\n\n"
+	b := new(bytes.Buffer)
+	if _, err := serialize.New(Format, schema, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("incorrect header: got\n%s\nwant\n%s", got, want)
+	}
+	for _, line := range bytes.Split(bytes.TrimSpace(b.Bytes()), []byte("\n")) {
+		var v map[string]json.RawMessage
+		if err := json.Unmarshal(line[bytes.IndexByte(line, ',')+1:], &v); err != nil || len(v) != 2 {
+			t.Errorf("invalid schema and table config %s: %v", line, err)
+		}
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	cases := map[string]string{
+		"usage_user":                  "usage_user",
+		"inodes-free":                 "inodes_free",
+		"key with space,comma=equals": "key_with_space_comma_equals",
+		"9lives":                      "_9lives",
+		"":                            "_",
+	}
+	for name, want := range cases {
+		if got := string(AppendName(nil, []byte(name))); got != want {
+			t.Errorf("incorrect name for %q: got %q want %q", name, got, want)
+		}
+	}
+}
+
+// TestSerializerValidJSON checks that the rows of special values are JSON
+// with the tag values of the Point
+func TestSerializerValidJSON(t *testing.T) {
+	s := &Serializer{}
+	for i, p := range serializetest.FuzzPoints() {
+		var b bytes.Buffer
+		if err := s.Serialize(p, &b); err != nil {
+			t.Fatalf("point %d: unexpected error: %v", i, err)
+		}
+		row := map[string]interface{}{}
+		if err := json.Unmarshal(bytes.TrimPrefix(b.Bytes(), []byte(string(p.MeasurementName())+",")), &row); err != nil {
+			t.Errorf("point %d: invalid JSON %q: %v", i, b.String(), err)
+			continue
+		}
+		for j, v := range p.TagValues() {
+			name := string(AppendName(nil, p.TagKeys()[j]))
+			if got, ok := row[name]; len(v) > 0 && got != string(v) || len(v) == 0 && ok {
+				t.Errorf("point %d: incorrect tag value: got %q want %q", i, got, v)
+			}
+		}
+	}
+}
@@ -4,7 +4,6 @@ package serialize
 import (
 	"bytes"
 	"io"
-	"time"
 )
 
 // Point wraps a single data point. It stores database-agnostic data
@@ -18,7 +17,7 @@ type Point struct {
 	tagValues       [][]byte
 	fieldKeys       [][]byte
 	fieldValues     []interface{}
-	timestamp       *time.Time
+	timestamp       int64 // nanoseconds since the Unix epoch
 }
 
 // NewPoint returns a new empty Point
@@ -29,7 +28,7 @@ func NewPoint() *Point {
 		tagValues:       make([][]byte, 0),
 		fieldKeys:       make([][]byte, 0),
 		fieldValues:     make([]interface{}, 0),
-		timestamp:       nil,
+		timestamp:       0,
 	}
 }
 
@@ -40,12 +39,33 @@ func (p *Point) Reset() {
 	p.tagValues = p.tagValues[:0]
 	p.fieldKeys = p.fieldKeys[:0]
 	p.fieldValues = p.fieldValues[:0]
-	p.timestamp = nil
+	p.timestamp = 0
 }
 
-// SetTimestamp sets the Timestamp for this data point
-func (p *Point) SetTimestamp(t *time.Time) {
-	p.timestamp = t
+// CopyTo copies the contents of this Point into dst, reusing dst's storage
+// where possible. Only the slices themselves are copied; the byte strings and
+// values they reference are shared between both Points.
+func (p *Point) CopyTo(dst *Point) {
+	dst.measurementName = p.measurementName
+	dst.tagKeys = append(dst.tagKeys[:0], p.tagKeys...)
+	dst.tagValues = append(dst.tagValues[:0], p.tagValues...)
+	dst.fieldKeys = append(dst.fieldKeys[:0], p.fieldKeys...)
+	dst.fieldValues = append(dst.fieldValues[:0], p.fieldValues...)
+	dst.timestamp = p.timestamp
+}
+
+// SetTimestamp sets the Timestamp for this data point, given as nanoseconds
+// since the Unix epoch. Timestamps are kept as integers rather than time.Time
+// to keep time.Time overhead out of the per-point loop; they are converted as
+// needed during serialization.
+func (p *Point) SetTimestamp(nanos int64) {
+	p.timestamp = nanos
+}
+
+// Timestamp returns the Timestamp of this data point as nanoseconds since the
+// Unix epoch
+func (p *Point) Timestamp() int64 {
+	return p.timestamp
 }
 
 // SetMeasurementName sets the name of the measurement for this data point
@@ -63,12 +83,23 @@ func (p *Point) FieldKeys() [][]byte {
 	return p.fieldKeys
 }
 
+// FieldValues returns the Point's field values, in the same order as FieldKeys
+func (p *Point) FieldValues() []interface{} {
+	return p.fieldValues
+}
+
 // AppendField adds a field with a given key and value to this data point
 func (p *Point) AppendField(key []byte, value interface{}) {
 	p.fieldKeys = append(p.fieldKeys, key)
 	p.fieldValues = append(p.fieldValues, value)
 }
 
+// SetFieldValue replaces the value of the i-th field, in the order of
+// FieldKeys
+func (p *Point) SetFieldValue(i int, value interface{}) {
+	p.fieldValues[i] = value
+}
+
 // GetFieldValue returns the corresponding value for a given field key or nil if it does not exist.
 // This will panic if the internal state has been altered to not have the same number of field keys as field values.
 func (p *Point) GetFieldValue(key []byte) interface{} {
@@ -89,6 +120,16 @@ func (p *Point) AppendTag(key, value []byte) {
 	p.tagValues = append(p.tagValues, value)
 }
 
+// TagKeys returns the Point's tag keys
+func (p *Point) TagKeys() [][]byte {
+	return p.tagKeys
+}
+
+// TagValues returns the Point's tag values, in the same order as TagKeys
+func (p *Point) TagValues() [][]byte {
+	return p.tagValues
+}
+
 // GetTagValue returns the corresponding value for a given tag key or nil if it does not exist.
 // This will panic if the internal state has been altered to not have the same number of tag keys as tag values.
 func (p *Point) GetTagValue(key []byte) []byte {
@@ -0,0 +1,121 @@
+package serialize
+
+import (
+	"io"
+)
+
+// PointBatch is a columnar (struct-of-arrays) representation of a batch of
+// Points. Rather than each Point owning its own slices, the values of every
+// row in the batch are kept in a few flat arrays, with the tags and fields of
+// row i found between offsets i and i+1 of the corresponding offset array.
+//
+// This keeps a batch in a handful of contiguous allocations that are reused
+// between batches, and lets serializers that implement BatchSerializer work
+// through a whole batch in one tight loop. As with Point, byte strings are
+// shared rather than copied, so they must not be modified.
+type PointBatch struct {
+	measurementNames [][]byte
+	timestamps       []int64
+
+	tagOffsets []int
+	tagKeys    [][]byte
+	tagValues  [][]byte
+
+	fieldOffsets []int
+	fieldKeys    [][]byte
+	fieldValues  []interface{}
+}
+
+// NewPointBatch returns a new empty PointBatch
+func NewPointBatch() *PointBatch {
+	b := &PointBatch{}
+	b.Reset()
+	return b
+}
+
+// Reset clears all rows from this PointBatch so it can be reused
+func (b *PointBatch) Reset() {
+	b.measurementNames = b.measurementNames[:0]
+	b.timestamps = b.timestamps[:0]
+	b.tagOffsets = append(b.tagOffsets[:0], 0)
+	b.tagKeys = b.tagKeys[:0]
+	b.tagValues = b.tagValues[:0]
+	b.fieldOffsets = append(b.fieldOffsets[:0], 0)
+	b.fieldKeys = b.fieldKeys[:0]
+	b.fieldValues = b.fieldValues[:0]
+}
+
+// Len returns the number of rows in the PointBatch
+func (b *PointBatch) Len() int {
+	return len(b.timestamps)
+}
+
+// Append adds the contents of p as a new row at the end of the PointBatch
+func (b *PointBatch) Append(p *Point) {
+	b.measurementNames = append(b.measurementNames, p.measurementName)
+	b.timestamps = append(b.timestamps, p.timestamp)
+	b.tagKeys = append(b.tagKeys, p.tagKeys...)
+	b.tagValues = append(b.tagValues, p.tagValues...)
+	b.tagOffsets = append(b.tagOffsets, len(b.tagKeys))
+	b.fieldKeys = append(b.fieldKeys, p.fieldKeys...)
+	b.fieldValues = append(b.fieldValues, p.fieldValues...)
+	b.fieldOffsets = append(b.fieldOffsets, len(b.fieldKeys))
+}
+
+// MeasurementName returns the measurement name of row i
+func (b *PointBatch) MeasurementName(i int) []byte {
+	return b.measurementNames[i]
+}
+
+// Timestamp returns the timestamp of row i as nanoseconds since the Unix epoch
+func (b *PointBatch) Timestamp(i int) int64 {
+	return b.timestamps[i]
+}
+
+// Tags returns the tag keys and values of row i
+func (b *PointBatch) Tags(i int) (keys, values [][]byte) {
+	start, end := b.tagOffsets[i], b.tagOffsets[i+1]
+	return b.tagKeys[start:end], b.tagValues[start:end]
+}
+
+// Fields returns the field keys and values of row i
+func (b *PointBatch) Fields(i int) (keys [][]byte, values []interface{}) {
+	start, end := b.fieldOffsets[i], b.fieldOffsets[i+1]
+	return b.fieldKeys[start:end], b.fieldValues[start:end]
+}
+
+// PointAt fills dst with the contents of row i
+func (b *PointBatch) PointAt(i int, dst *Point) {
+	dst.Reset()
+	dst.measurementName = b.measurementNames[i]
+	dst.timestamp = b.timestamps[i]
+	keys, values := b.Tags(i)
+	dst.tagKeys = append(dst.tagKeys, keys...)
+	dst.tagValues = append(dst.tagValues, values...)
+	fieldKeys, fieldValues := b.Fields(i)
+	dst.fieldKeys = append(dst.fieldKeys, fieldKeys...)
+	dst.fieldValues = append(dst.fieldValues, fieldValues...)
+}
+
+// BatchSerializer is implemented by PointSerializers that can serialize a
+// whole PointBatch at once, which is generally faster than serializing each
+// of its rows as a separate Point
+type BatchSerializer interface {
+	SerializeBatch(b *PointBatch, w io.Writer) error
+}
+
+// SerializeBatch writes all rows of b to w using ps, directly if ps is a
+// BatchSerializer or otherwise one row at a time
+func SerializeBatch(ps PointSerializer, b *PointBatch, w io.Writer) error {
+	if bs, ok := ps.(BatchSerializer); ok {
+		return bs.SerializeBatch(b, w)
+	}
+	p := NewPoint()
+	for i := 0; i < b.Len(); i++ {
+		b.PointAt(i, p)
+		if err := ps.Serialize(p, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package serialize
+
+import (
+	"testing"
+)
+
+func TestPointBatchAppend(t *testing.T) {
+	b := NewPointBatch()
+	if got := b.Len(); got != 0 {
+		t.Errorf("new batch has non-0 len: %d", got)
+	}
+
+	points := []*Point{testPointDefault, testPointNoTags, testPointMultiField}
+	for _, p := range points {
+		b.Append(p)
+	}
+	if got := b.Len(); got != len(points) {
+		t.Fatalf("incorrect len: got %d want %d", got, len(points))
+	}
+
+	dst := NewPoint()
+	for i, want := range points {
+		if got := b.Timestamp(i); got != want.timestamp {
+			t.Errorf("row %d: incorrect timestamp: got %d want %d", i, got, want.timestamp)
+		}
+		if got := string(b.MeasurementName(i)); got != string(want.measurementName) {
+			t.Errorf("row %d: incorrect measurement name: got %s want %s", i, got, want.measurementName)
+		}
+		keys, values := b.Tags(i)
+		if len(keys) != len(want.tagKeys) || len(values) != len(want.tagValues) {
+			t.Errorf("row %d: incorrect number of tags: got %d want %d", i, len(keys), len(want.tagKeys))
+		}
+		fieldKeys, fieldValues := b.Fields(i)
+		if len(fieldKeys) != len(want.fieldKeys) || len(fieldValues) != len(want.fieldValues) {
+			t.Errorf("row %d: incorrect number of fields: got %d want %d", i, len(fieldKeys), len(want.fieldKeys))
+		}
+
+		b.PointAt(i, dst)
+		for j, k := range want.tagKeys {
+			if got := dst.GetTagValue(k); string(got) != string(want.tagValues[j]) {
+				t.Errorf("row %d: incorrect tag value for %s: got %s want %s", i, k, got, want.tagValues[j])
+			}
+		}
+		for j, k := range want.fieldKeys {
+			if got := dst.GetFieldValue(k); got != want.fieldValues[j] {
+				t.Errorf("row %d: incorrect field value for %s: got %v want %v", i, k, got, want.fieldValues[j])
+			}
+		}
+	}
+
+	b.Reset()
+	if got := b.Len(); got != 0 {
+		t.Errorf("reset batch has non-0 len: %d", got)
+	}
+	b.Append(testPointInt)
+	if keys, _ := b.Tags(0); len(keys) != len(testPointInt.tagKeys) {
+		t.Errorf("incorrect number of tags after reset: got %d want %d", len(keys), len(testPointInt.tagKeys))
+	}
+}
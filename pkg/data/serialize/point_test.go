@@ -1,8 +1,6 @@
 package serialize
 
 import (
-	"bytes"
-	"fmt"
 	"testing"
 	"time"
 )
@@ -18,34 +16,16 @@ var (
 )
 
 const (
-	testFloat             = float64(38.24311829)
-	testInt               = 38
-	testInt64             = int64(5000000000)
-	errWriterAlwaysErr    = "bad write: I always error"
-	errWriterSometimesErr = "bad write: I sometimes error"
+	testFloat = float64(38.24311829)
+	testInt   = 38
+	testInt64 = int64(5000000000)
 )
 
-type errWriter struct {
-	skipOne bool
-	cnt     int
-}
-
-func (w *errWriter) Write(p []byte) (n int, err error) {
-	if !w.skipOne {
-		return 0, fmt.Errorf(errWriterAlwaysErr)
-	} else if w.cnt < 1 {
-		w.cnt++
-		return len(p), nil
-	} else {
-		return 0, fmt.Errorf(errWriterSometimesErr)
-	}
-}
-
 var testPointDefault = &Point{
 	measurementName: testMeasurement,
 	tagKeys:         testTagKeys,
 	tagValues:       testTagVals,
-	timestamp:       &testNow,
+	timestamp:       testNow.UnixNano(),
 	fieldKeys:       [][]byte{testColFloat},
 	fieldValues:     []interface{}{testFloat},
 }
@@ -54,7 +34,7 @@ var testPointMultiField = &Point{
 	measurementName: testMeasurement,
 	tagKeys:         testTagKeys,
 	tagValues:       testTagVals,
-	timestamp:       &testNow,
+	timestamp:       testNow.UnixNano(),
 	fieldKeys:       [][]byte{testColInt64, testColInt, testColFloat},
 	fieldValues:     []interface{}{testInt64, testInt, testFloat},
 }
@@ -63,7 +43,7 @@ var testPointInt = &Point{
 	measurementName: testMeasurement,
 	tagKeys:         testTagKeys,
 	tagValues:       testTagVals,
-	timestamp:       &testNow,
+	timestamp:       testNow.UnixNano(),
 	fieldKeys:       [][]byte{testColInt},
 	fieldValues:     []interface{}{testInt},
 }
@@ -72,28 +52,11 @@ var testPointNoTags = &Point{
 	measurementName: testMeasurement,
 	tagKeys:         [][]byte{},
 	tagValues:       [][]byte{},
-	timestamp:       &testNow,
+	timestamp:       testNow.UnixNano(),
 	fieldKeys:       [][]byte{testColFloat},
 	fieldValues:     []interface{}{testFloat},
 }
 
-type serializeCase struct {
-	desc       string
-	inputPoint *Point
-	output     string
-}
-
-func testSerializer(t *testing.T, cases []serializeCase, ps PointSerializer) {
-	for _, c := range cases {
-		b := new(bytes.Buffer)
-		ps.Serialize(c.inputPoint, b)
-		got := b.String()
-		if got != c.output {
-			t.Errorf("%s \nOutput incorrect: \nWant: '%s' \nGot:  '%s'", c.desc, c.output, got)
-		}
-	}
-}
-
 func testEmptyPoint(t *testing.T, p *Point, desc string) {
 	if p.measurementName != nil {
 		t.Errorf("%s has a non-nil measurement name: %s", desc, p.measurementName)
@@ -110,8 +73,8 @@ func testEmptyPoint(t *testing.T, p *Point, desc string) {
 	if got := len(p.fieldValues); got != 0 {
 		t.Errorf("%s has a non-0 len for field values: %d", desc, got)
 	}
-	if p.timestamp != nil {
-		t.Errorf("%s has a non-nil timestamp: %v", desc, p.timestamp)
+	if p.timestamp != 0 {
+		t.Errorf("%s has a non-0 timestamp: %v", desc, p.timestamp)
 	}
 }
 
@@ -122,20 +85,59 @@ func TestNewPoint(t *testing.T) {
 
 func TestReset(t *testing.T) {
 	p := NewPoint()
-	now := time.Now()
-	p.timestamp = &now
+	p.timestamp = time.Now().UnixNano()
 	p.measurementName = []byte("test")
 	p.Reset()
 	testEmptyPoint(t, p, "Reset")
 }
 
+func TestCopyTo(t *testing.T) {
+	p := NewPoint()
+	p.SetMeasurementName(testMeasurement)
+	p.SetTimestamp(testNow.UnixNano())
+	p.AppendTag(testTagKeys[0], testTagVals[0])
+	p.AppendField(testColFloat, testFloat)
+
+	dst := NewPoint()
+	dst.AppendTag(testTagKeys[1], testTagVals[1])
+	dst.AppendTag(testTagKeys[2], testTagVals[2])
+	p.CopyTo(dst)
+
+	// modifying the original afterwards should not affect the copy
+	p.Reset()
+	p.AppendTag(testTagKeys[2], testTagVals[2])
+	p.AppendField(testColInt, testInt)
+
+	if got := string(dst.MeasurementName()); got != string(testMeasurement) {
+		t.Errorf("incorrect name: got %s want %s", got, testMeasurement)
+	}
+	if got := dst.Timestamp(); got != testNow.UnixNano() {
+		t.Errorf("incorrect timestamp: got %d want %d", got, testNow.UnixNano())
+	}
+	if got := len(dst.tagKeys); got != 1 {
+		t.Fatalf("incorrect number of tags: got %d want %d", got, 1)
+	}
+	if got := dst.GetTagValue(testTagKeys[0]); string(got) != string(testTagVals[0]) {
+		t.Errorf("incorrect tag value: got %s want %s", got, testTagVals[0])
+	}
+	if got := len(dst.fieldKeys); got != 1 {
+		t.Fatalf("incorrect number of fields: got %d want %d", got, 1)
+	}
+	if got := dst.GetFieldValue(testColFloat); got != testFloat {
+		t.Errorf("incorrect field value: got %v want %v", got, testFloat)
+	}
+}
+
 func TestSetTimestamp(t *testing.T) {
 	p := NewPoint()
-	now := time.Now()
-	p.SetTimestamp(&now)
-	if p.timestamp != &now {
+	now := time.Now().UnixNano()
+	p.SetTimestamp(now)
+	if p.timestamp != now {
 		t.Errorf("incorrect timestamp: got %v want %v", p.timestamp, now)
 	}
+	if got := p.Timestamp(); got != now {
+		t.Errorf("incorrect timestamp returned: got %v want %v", got, now)
+	}
 }
 
 func TestSetMeasurementName(t *testing.T) {
@@ -175,6 +177,11 @@ func TestFields(t *testing.T) {
 	if got := p.GetFieldValue([]byte("bar")); got != nil {
 		t.Errorf("unexpected non-nil return for get field value: %v", got)
 	}
+
+	p.SetFieldValue(0, 1.5)
+	if got := p.GetFieldValue(k); got != 1.5 {
+		t.Errorf("incorrect value after set: got %v want %v", got, 1.5)
+	}
 }
 
 func TestFieldsPanic(t *testing.T) {
@@ -0,0 +1,339 @@
+// Package prometheus implements the format for the Prometheus remote-write
+// protocol: each reading is a WriteRequest, with a time series for each of
+// its fields, compressed with snappy as remote-write endpoints expect it. Its
+// Encoder of WriteRequests is shared with the m3db format, which leaves them
+// uncompressed.
+package prometheus
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "prometheus"
+
+// NameLabel is the label holding the name of the metric of a time series
+const NameLabel = "__name__"
+
+// ExportedPrefix prefixes the label names of tags whose key starts with
+// "__", which Prometheus reserves for internal labels such as NameLabel. It
+// is the prefix Prometheus itself gives to such labels when scraping.
+const ExportedPrefix = "exported_"
+
+// Tags of the fields of the protobuf messages of the remote-write protocol,
+// e.g., WriteRequest.timeseries is field 1 and length-delimited (wire type
+// 2), so its tag is 1<<3|2
+const (
+	tagTimeSeries  = 1<<3 | 2 // WriteRequest.timeseries
+	tagLabel       = 1<<3 | 2 // TimeSeries.labels
+	tagSample      = 2<<3 | 2 // TimeSeries.samples
+	tagLabelName   = 1<<3 | 2 // Label.name
+	tagLabelValue  = 2<<3 | 2 // Label.value
+	tagSampleValue = 1<<3 | 1 // Sample.value, a double
+	tagSampleTime  = 2<<3 | 0 // Sample.timestamp, a varint
+)
+
+var nameLabel = []byte(NameLabel)
+
+func init() {
+	serialize.Describe(Format, "Prometheus remote-write WriteRequests, snappy-compressed protobuf with a length prefix, one per reading")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+}
+
+// Serializer writes a Point in a serialized form for remote-write endpoints
+type Serializer struct {
+	enc Encoder
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and request and compressed hold the
+	// WriteRequest being encoded and its compressed form
+	buf        []byte
+	request    []byte
+	compressed []byte
+}
+
+// Serialize writes Point p to w as a WriteRequest encoded by an Encoder,
+// compressed with snappy (the block format, as the remote-write protocol
+// requires) and prefixed by the length of the compressed WriteRequest as a
+// varint. Each WriteRequest can thus be read from the stream and sent as the
+// body of a remote-write request as is. Points without any samples are not
+// written.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendFrame(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendFrame(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendFrame(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	s.request = s.enc.AppendWriteRequest(s.request[:0], measurementName, tagKeys, tagValues, fieldKeys, fieldValues, timestamp)
+	if len(s.request) == 0 {
+		return buf
+	}
+	s.compressed = snappy.Encode(s.compressed[:cap(s.compressed)], s.request)
+	buf = appendUvarint(buf, uint64(len(s.compressed)))
+	return append(buf, s.compressed...)
+}
+
+// Encoder encodes the readings of Points as WriteRequests of the
+// remote-write protocol, in protobuf. It reuses its scratch space between
+// calls, so it is not safe for concurrent use.
+type Encoder struct {
+	series []byte
+	// names are the label names of the tags of the Point being encoded,
+	// backed by nameBuf, and order the order of its labels, where -1 is the
+	// label of the metric name
+	names   [][]byte
+	nameBuf []byte
+	metric  []byte
+	order   []int
+}
+
+// AppendWriteRequest appends the WriteRequest of a reading to buf, with a
+// time series of a single sample for each numeric or bool field. It appends
+// nothing if the reading has no such field. As WriteRequests only have
+// repeated time series, WriteRequests appended to the same buf are the
+// WriteRequest of all their time series.
+//
+// The metric of each time series is named by the measurement and field, and
+// its labels are those of the tags of the reading, e.g., in the text format
+// of Prometheus:
+//
+// cpu_usage_user{arch="x86",datacenter="eu-central-1b",hostname="host_0",...} 58.13 1451606400000
+//
+// Tags are mapped to labels by these rules:
+//   - metric and label names may only have letters, digits and underscores,
+//     so any other characters are replaced by underscores, and names that
+//     are empty or start with a digit are prefixed by an underscore
+//   - label names starting with "__" are reserved, so those of such tags are
+//     prefixed by ExportedPrefix, e.g., __source becomes exported___source
+//   - tags with empty values have no label, as Prometheus treats them as
+//     missing
+//   - of tags mapped to the same label name, e.g., host.name and host_name,
+//     only the first has a label, as a time series has each label once
+//
+// Sample timestamps are in milliseconds and bools are 1 or 0. String fields
+// are left out, as Prometheus has no such samples.
+func (e *Encoder) AppendWriteRequest(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	e.sortLabels(tagKeys, tagValues)
+	millis := timestamp / 1e6
+	for i, v := range fieldValues {
+		f, ok := sampleValue(v)
+		if !ok {
+			continue
+		}
+		e.metric = AppendMetricName(e.metric[:0], measurementName, fieldKeys[i])
+		e.series = e.series[:0]
+		for _, j := range e.order {
+			if j < 0 {
+				e.series = appendLabel(e.series, nameLabel, e.metric)
+			} else {
+				e.series = appendLabel(e.series, e.names[j], tagValues[j])
+			}
+		}
+		e.series = appendSample(e.series, f, millis)
+		buf = appendMessage(buf, tagTimeSeries, e.series)
+	}
+	return buf
+}
+
+// sortLabels sets names to the label names of the tags, and order to the
+// order of the labels of the tags with values and of the metric name, sorted
+// by name as the remote-write protocol requires, without duplicate names
+func (e *Encoder) sortLabels(tagKeys, tagValues [][]byte) {
+	e.nameBuf = e.nameBuf[:0]
+	for _, k := range tagKeys {
+		e.nameBuf = AppendLabelName(e.nameBuf, k)
+	}
+	e.names = e.names[:0]
+	start := 0
+	for _, k := range tagKeys {
+		end := start + labelNameLen(k)
+		e.names = append(e.names, e.nameBuf[start:end:end])
+		start = end
+	}
+
+	e.order = append(e.order[:0], -1)
+	for i, v := range tagValues {
+		if len(v) > 0 {
+			e.order = append(e.order, i)
+		}
+	}
+	// insertion sort, as there are few labels; it is stable, so of labels
+	// of the same name, that of the first tag comes first and is kept
+	for i := 1; i < len(e.order); i++ {
+		for j := i; j > 0 && string(e.labelName(e.order[j])) < string(e.labelName(e.order[j-1])); j-- {
+			e.order[j], e.order[j-1] = e.order[j-1], e.order[j]
+		}
+	}
+	n := 1
+	for i := 1; i < len(e.order); i++ {
+		if string(e.labelName(e.order[i])) != string(e.labelName(e.order[n-1])) {
+			e.order[n] = e.order[i]
+			n++
+		}
+	}
+	e.order = e.order[:n]
+}
+
+func (e *Encoder) labelName(i int) []byte {
+	if i < 0 {
+		return nameLabel
+	}
+	return e.names[i]
+}
+
+// sampleValue returns the value of a sample of field value v, and whether
+// it has one
+func sampleValue(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// AppendMetricName appends the name of the metric of field fieldKey of
+// measurementName to buf, e.g., cpu_usage_user
+func AppendMetricName(buf []byte, measurementName, fieldKey []byte) []byte {
+	start := len(buf)
+	buf = appendSanitized(buf, measurementName)
+	buf = append(buf, '_')
+	buf = appendSanitized(buf, fieldKey)
+	if isDigit(buf[start]) {
+		buf = append(buf[:start+1], buf[start:]...)
+		buf[start] = '_'
+	}
+	return buf
+}
+
+// AppendLabelName appends the label name of tag key to buf: the key with
+// any characters other than letters, digits and underscores replaced by
+// underscores, prefixed by an underscore if it is empty or starts with a
+// digit, or by ExportedPrefix if it starts with "__"
+func AppendLabelName(buf []byte, key []byte) []byte {
+	switch {
+	case len(key) == 0 || isDigit(key[0]):
+		buf = append(buf, '_')
+	case isReserved(key):
+		buf = append(buf, ExportedPrefix...)
+	}
+	return appendSanitized(buf, key)
+}
+
+// labelNameLen returns the length of the label name of tag key
+func labelNameLen(key []byte) int {
+	switch {
+	case len(key) == 0 || isDigit(key[0]):
+		return len(key) + 1
+	case isReserved(key):
+		return len(ExportedPrefix) + len(key)
+	}
+	return len(key)
+}
+
+// isReserved returns whether the label name of key, once sanitized, starts
+// with "__"
+func isReserved(key []byte) bool {
+	return len(key) >= 2 && isUnderscore(key[0]) && isUnderscore(key[1])
+}
+
+// isUnderscore returns whether c is an underscore once sanitized
+func isUnderscore(c byte) bool {
+	return c == '_' || !isNameChar(c)
+}
+
+func appendSanitized(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		if !isNameChar(c) {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// appendLabel appends a Label message of the given name and value to buf as
+// a field of a TimeSeries
+func appendLabel(buf []byte, name, value []byte) []byte {
+	size := 2 + uvarintLen(uint64(len(name))) + len(name) + uvarintLen(uint64(len(value))) + len(value)
+	buf = append(buf, tagLabel)
+	buf = appendUvarint(buf, uint64(size))
+	buf = appendMessage(buf, tagLabelName, name)
+	return appendMessage(buf, tagLabelValue, value)
+}
+
+// appendSample appends a Sample message to buf as a field of a TimeSeries
+func appendSample(buf []byte, value float64, millis int64) []byte {
+	size := 10 + uvarintLen(uint64(millis))
+	buf = append(buf, tagSample)
+	buf = appendUvarint(buf, uint64(size))
+	buf = append(buf, tagSampleValue)
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(value))
+	buf = append(buf, b[:]...)
+	buf = append(buf, tagSampleTime)
+	return appendUvarint(buf, uint64(millis))
+}
+
+// appendMessage appends a length-delimited field with the given tag and
+// contents to buf
+func appendMessage(buf []byte, tag byte, contents []byte) []byte {
+	buf = append(buf, tag)
+	buf = appendUvarint(buf, uint64(len(contents)))
+	return append(buf, contents...)
+}
+
+func appendUvarint(buf []byte, x uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	return append(buf, b[:n]...)
+}
+
+func uvarintLen(x uint64) int {
+	n := 1
+	for x >= 0x80 {
+		x >>= 7
+		n++
+	}
+	return n
+}
@@ -0,0 +1,214 @@
+package prometheus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+// field returns a length-delimited protobuf field
+func field(tag byte, contents string) string {
+	return string(appendUvarint([]byte{tag}, uint64(len(contents)))) + contents
+}
+
+func label(name, value string) string {
+	return field(tagLabel, field(tagLabelName, name)+field(tagLabelValue, value))
+}
+
+func sample(value float64) string {
+	b := []byte{tagSampleValue, 0, 0, 0, 0, 0, 0, 0, 0, tagSampleTime}
+	binary.LittleEndian.PutUint64(b[1:9], math.Float64bits(value))
+	b = appendUvarint(b, uint64(serializetest.Now.UnixNano()/1e6))
+	return field(tagSample, string(b))
+}
+
+// request returns the WriteRequest of the given time series
+func request(series ...string) string {
+	var s string
+	for _, ts := range series {
+		s += field(tagTimeSeries, ts)
+	}
+	return s
+}
+
+// frame returns the output for a Point with the given time series
+func frame(series ...string) string {
+	compressed := snappy.Encode(nil, []byte(request(series...)))
+	return string(appendUvarint(nil, uint64(len(compressed)))) + string(compressed)
+}
+
+// testLabels are the labels of the tags of the fixture Points, after the
+// metric name label in order
+var testLabels = label("datacenter", "eu-west-1b") + label("hostname", "host_0") + label("region", "eu-west-1")
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     frame(label(NameLabel, "cpu_usage_guest_nice") + testLabels + sample(serializetest.Float)),
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     frame(label(NameLabel, "cpu_usage_guest") + testLabels + sample(serializetest.Int)),
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: frame(
+			label(NameLabel, "cpu_big_usage_guest")+testLabels+sample(float64(serializetest.Int64)),
+			label(NameLabel, "cpu_usage_guest")+testLabels+sample(serializetest.Int),
+			label(NameLabel, "cpu_usage_guest_nice")+testLabels+sample(serializetest.Float),
+		),
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     frame(label(NameLabel, "cpu_usage_guest_nice") + sample(serializetest.Float)),
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+		Plain:  decompress,
+	}.Run(t)
+}
+
+// decompress returns the WriteRequests of the frames of data, one after the
+// other
+func decompress(data []byte) ([]byte, error) {
+	var out []byte
+	buf := bytes.NewBuffer(data)
+	for buf.Len() > 0 {
+		n, err := binary.ReadUvarint(buf)
+		if err != nil || n > uint64(buf.Len()) {
+			return nil, fmt.Errorf("invalid frame length %d: %v", n, err)
+		}
+		request, err := snappy.Decode(nil, buf.Next(int(n)))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, request...)
+	}
+	return out, nil
+}
+
+func TestSerializerFrames(t *testing.T) {
+	points := []*serialize.Point{serializetest.PointDefault, serializetest.PointMultiField, serializetest.PointNoTags}
+	var buf bytes.Buffer
+	s := &Serializer{}
+	for _, p := range points {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	enc := &Encoder{}
+	for i, p := range points {
+		n, err := binary.ReadUvarint(&buf)
+		if err != nil || n > uint64(buf.Len()) {
+			t.Fatalf("frame %d: invalid length %d: %v", i, n, err)
+		}
+		got, err := snappy.Decode(nil, buf.Next(int(n)))
+		if err != nil {
+			t.Fatalf("frame %d: could not decompress: %v", i, err)
+		}
+		want := enc.AppendWriteRequest(nil, p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+		if !bytes.Equal(got, want) {
+			t.Errorf("frame %d: incorrect WriteRequest:\ngot  %q\nwant %q", i, got, want)
+		}
+	}
+	if buf.Len() > 0 {
+		t.Errorf("trailing output: %q", buf.Bytes())
+	}
+}
+
+func TestEncoderLabels(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.SetTimestamp(serializetest.Now.UnixNano())
+	p.AppendTag([]byte("path"), []byte("/"))
+	p.AppendTag([]byte("Zone"), []byte("a"))
+	p.AppendTag([]byte("1st key"), []byte("b"))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendTag([]byte("__name__"), []byte("c"))
+	p.AppendTag([]byte(".source"), []byte("d"))
+	p.AppendTag([]byte("..source"), []byte("g"))
+	p.AppendTag([]byte("host.name"), []byte("e"))
+	p.AppendTag([]byte("host_name"), []byte("f"))
+	p.AppendField([]byte("used.percent"), true)
+	p.AppendField([]byte("label"), "text")
+
+	enc := &Encoder{}
+	want := request(label("Zone", "a") + label("_1st_key", "b") + label(NameLabel, "disk_used_percent") + label("_source", "d") +
+		label("exported___name__", "c") + label("exported___source", "g") + label("host_name", "e") + label("path", "/") + sample(1))
+	buf := enc.AppendWriteRequest(nil, p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	if string(buf) != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", buf, want)
+	}
+
+	onlyStrings := serialize.NewPoint()
+	onlyStrings.SetMeasurementName([]byte("disk"))
+	onlyStrings.AppendField([]byte("label"), "text")
+	if buf := enc.AppendWriteRequest(nil, onlyStrings.MeasurementName(), nil, nil, onlyStrings.FieldKeys(), onlyStrings.FieldValues(), 0); len(buf) != 0 {
+		t.Errorf("output for a Point without samples: %q", buf)
+	}
+	s := &Serializer{}
+	if buf := s.appendFrame(nil, onlyStrings.MeasurementName(), nil, nil, onlyStrings.FieldKeys(), onlyStrings.FieldValues(), 0); len(buf) != 0 {
+		t.Errorf("frame for a Point without samples: %q", buf)
+	}
+}
+
+func TestAppendLabelName(t *testing.T) {
+	cases := []struct {
+		key, want string
+	}{
+		{"hostname", "hostname"},
+		{"host.name", "host_name"},
+		{"_private", "_private"},
+		{"__meta", "exported___meta"},
+		{"_.x", "exported___x"},
+		{"9p", "_9p"},
+		{"", "_"},
+		{"_", "_"},
+	}
+	for _, c := range cases {
+		got := AppendLabelName([]byte("prefix"), []byte(c.key))
+		if string(got) != "prefix"+c.want {
+			t.Errorf("incorrect label name of %q: got %s", c.key, got)
+		}
+		if n := labelNameLen([]byte(c.key)); n != len(c.want) {
+			t.Errorf("incorrect label name length of %q: got %d want %d", c.key, n, len(c.want))
+		}
+	}
+}
+
+func TestAppendMetricName(t *testing.T) {
+	cases := []struct {
+		measurement, field, want string
+	}{
+		{"cpu", "usage_user", "cpu_usage_user"},
+		{"diskio", "io.time", "diskio_io_time"},
+		{"9p", "x", "_9p_x"},
+		{"", "x", "_x"},
+	}
+	for _, c := range cases {
+		if got := string(AppendMetricName([]byte("prefix"), []byte(c.measurement), []byte(c.field))); got != "prefix"+c.want {
+			t.Errorf("incorrect name of %s %s: got %s", c.measurement, c.field, got)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Package protobuf implements a protobuf format, for gRPC based collectors:
+// a Point message per reading, each preceded by its length, of messages
+// defined by a .proto derived from the schema of the data.
+package protobuf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "protobuf"
+
+// Package is the protobuf package of the messages
+const Package = "tsbs"
+
+// Numbers of the fields of the Point message. The field of the message of
+// the fields of each measurement follow, in the order of the measurements of
+// the schema.
+const (
+	numberTimestamp = 1
+	numberTags      = 2
+	numberFirst     = 3
+)
+
+// Wire types of the fields
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+func init() {
+	serialize.Describe(Format, "protobuf Point messages with a length prefix, one per reading, of the .proto written with -proto-file")
+	serialize.Register(Format, func(schema *serialize.Schema, _ io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema)
+	})
+}
+
+// measurement is the message of the fields of a measurement
+type measurement struct {
+	// number is the number of its field of the Point message
+	number int
+	// fields are the numbers of the fields of the message of each field
+	// key, less one, and types their types
+	fields map[string]int
+	types  []serialize.FieldType
+}
+
+// Serializer writes a Point in a serialized form as protobuf
+type Serializer struct {
+	// tags are the numbers of the fields of the Tags message of each tag
+	// key, less one
+	tags         map[string]int
+	measurements map[string]*measurement
+	// proto is the .proto defining the messages
+	proto []byte
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and the others hold the messages being
+	// encoded: the tags, the fields and the Point
+	buf    []byte
+	tagBuf []byte
+	fields []byte
+	point  []byte
+}
+
+// NewSerializer returns a Serializer of data described by schema, with a
+// Point message with the timestamp of each reading, a Tags message with a
+// string for each tag key of schema, and a oneof of a message of the fields
+// of each measurement, named as the measurement with a Fields suffix, e.g.,
+// CpuFields. The fields of those messages are optional and of the type of the
+// field: an int64, a bool, a string or, for the numbers of the simulators, a
+// double.
+func NewSerializer(schema *serialize.Schema) (*Serializer, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("protobuf messages need the schema of the data")
+	}
+	s := &Serializer{
+		tags:         make(map[string]int),
+		measurements: make(map[string]*measurement),
+	}
+
+	var point, messages []byte
+	point = append(point, "message Point {\n  // nanoseconds since the epoch\n  sfixed64 timestamp = 1;\n  Tags tags = 2;\n  oneof fields {\n"...)
+	// the names of the fields of Point and of the messages
+	names := map[string]bool{"timestamp": true, "tags": true}
+	types := map[string]bool{"Point": true, "Tags": true}
+	for i, measurementName := range schema.Measurements() {
+		name := string(AppendName(nil, []byte(measurementName)))
+		typ := string(appendTypeName(nil, []byte(name))) + "Fields"
+		if names[name] || types[typ] {
+			return nil, fmt.Errorf("duplicate field %s or message %s of Point", name, typ)
+		}
+		names[name], types[typ] = true, true
+		m := &measurement{number: numberFirst + i, fields: make(map[string]int)}
+		point = append(point, "    "+typ+" "+name+" = "...)
+		point = fmt.Appendf(point, "%d;\n", m.number)
+
+		messages = append(messages, "\nmessage "+typ+" {\n"...)
+		fieldNames := make(map[string]bool)
+		fieldTypes := schema.FieldTypes(measurementName)
+		for j, key := range schema.FieldKeys(measurementName) {
+			name := string(AppendName(nil, key))
+			if fieldNames[name] {
+				return nil, fmt.Errorf("duplicate field %s of message %s", name, typ)
+			}
+			fieldNames[name] = true
+			t := serialize.FieldTypeUnknown
+			if j < len(fieldTypes) {
+				t = fieldTypes[j]
+			}
+			if _, ok := m.fields[string(key)]; !ok {
+				m.fields[string(key)] = len(m.types)
+			}
+			m.types = append(m.types, t)
+			messages = fmt.Appendf(messages, "  optional %s %s = %d;\n", fieldType(t), name, len(m.types))
+		}
+		messages = append(messages, "}\n"...)
+		s.measurements[measurementName] = m
+	}
+	point = append(point, "  }\n}\n\nmessage Tags {\n"...)
+	tagNames := make(map[string]bool)
+	for _, key := range schema.AllTagKeys() {
+		name := string(AppendName(nil, key))
+		if tagNames[name] {
+			return nil, fmt.Errorf("duplicate field %s of message Tags", name)
+		}
+		tagNames[name] = true
+		s.tags[string(key)] = len(s.tags)
+		point = fmt.Appendf(point, "  string %s = %d;\n", name, len(s.tags))
+	}
+	point = append(point, "}\n"...)
+
+	s.proto = append(s.proto, "// Messages of the data generated by tsbs_generate_data with -format=protobuf:\n"+
+		"// a Point per reading, each preceded by its length as a varint.\n"+
+		"syntax = \"proto3\";\n\npackage "+Package+";\n\n"...)
+	s.proto = append(s.proto, point...)
+	s.proto = append(s.proto, messages...)
+	return s, nil
+}
+
+// Proto returns the .proto defining the messages of data described by
+// schema, as written by a Serializer of it
+func Proto(schema *serialize.Schema) ([]byte, error) {
+	s, err := NewSerializer(schema)
+	if err != nil {
+		return nil, err
+	}
+	return s.proto, nil
+}
+
+// fieldType returns the protobuf type of fields of type t. Fields of unknown
+// type are numbers from the simulators, which fit a double.
+func fieldType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "int64"
+	case serialize.FieldTypeBool:
+		return "bool"
+	case serialize.FieldTypeString:
+		return "string"
+	default:
+		return "double"
+	}
+}
+
+// Serialize writes Point p to w as a Point message, prefixed by its length
+// as a varint, as protobuf libraries write delimited messages. Tags with
+// empty values are left out, as are fields with nil values. Fields must have
+// values of the types of their fields in the .proto, though ints may be
+// doubles. Measurements, tags and fields that are not in the Schema, so have
+// no field in the .proto, are an error.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf, err := s.appendPoint(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	var err error
+	for i := 0; i < b.Len() && err == nil; i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf, err = s.appendPoint(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+func (s *Serializer) appendPoint(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) ([]byte, error) {
+	m, ok := s.measurements[string(measurementName)]
+	if !ok {
+		return buf, fmt.Errorf("measurement %s is not in the schema", measurementName)
+	}
+	s.tagBuf = s.tagBuf[:0]
+	for i, key := range tagKeys {
+		j, ok := s.tags[string(key)]
+		if !ok {
+			return buf, fmt.Errorf("tag %s of %s has no field", key, measurementName)
+		}
+		if len(tagValues[i]) > 0 {
+			s.tagBuf = appendBytes(s.tagBuf, j+1, tagValues[i])
+		}
+	}
+	s.fields = s.fields[:0]
+	for i, key := range fieldKeys {
+		j, ok := m.fields[string(key)]
+		if !ok {
+			return buf, fmt.Errorf("field %s of %s has no field", key, measurementName)
+		}
+		if fieldValues[i] == nil {
+			continue
+		}
+		if !accepts(m.types[j], fieldValues[i]) {
+			return buf, fmt.Errorf("field %s of %s is a %T, not a %s", key, measurementName, fieldValues[i], fieldType(m.types[j]))
+		}
+		s.fields = appendValue(s.fields, j+1, m.types[j], fieldValues[i])
+	}
+
+	s.point = appendTag(s.point[:0], numberTimestamp, wireFixed64)
+	s.point = binary.LittleEndian.AppendUint64(s.point, uint64(timestamp))
+	if len(s.tagBuf) > 0 {
+		s.point = appendBytes(s.point, numberTags, s.tagBuf)
+	}
+	s.point = appendBytes(s.point, m.number, s.fields)
+	buf = binary.AppendUvarint(buf, uint64(len(s.point)))
+	return append(buf, s.point...), nil
+}
+
+// accepts returns whether v can be a value of a field of type t
+func accepts(t serialize.FieldType, v interface{}) bool {
+	switch v.(type) {
+	case int, int64:
+		return t != serialize.FieldTypeBool && t != serialize.FieldTypeString
+	case float64, float32:
+		return t != serialize.FieldTypeInt && t != serialize.FieldTypeBool && t != serialize.FieldTypeString
+	case bool:
+		return t == serialize.FieldTypeBool
+	case []byte, string:
+		return t == serialize.FieldTypeString
+	}
+	return false
+}
+
+// appendValue appends v, which a field of type t accepts, to buf as the
+// field with the given number
+func appendValue(buf []byte, number int, t serialize.FieldType, v interface{}) []byte {
+	switch x := v.(type) {
+	case int:
+		return appendNumber(buf, number, t, int64(x))
+	case int64:
+		return appendNumber(buf, number, t, x)
+	case float64:
+		return appendDouble(buf, number, x)
+	case float32:
+		return appendDouble(buf, number, float64(x))
+	case bool:
+		buf = appendTag(buf, number, wireVarint)
+		if x {
+			return append(buf, 1)
+		}
+		return append(buf, 0)
+	case []byte:
+		return appendBytes(buf, number, x)
+	case string:
+		return appendBytes(buf, number, []byte(x))
+	}
+	return buf
+}
+
+func appendNumber(buf []byte, number int, t serialize.FieldType, x int64) []byte {
+	if t == serialize.FieldTypeInt {
+		buf = appendTag(buf, number, wireVarint)
+		return binary.AppendUvarint(buf, uint64(x))
+	}
+	return appendDouble(buf, number, float64(x))
+}
+
+func appendDouble(buf []byte, number int, f float64) []byte {
+	buf = appendTag(buf, number, wireFixed64)
+	return binary.LittleEndian.AppendUint64(buf, math.Float64bits(f))
+}
+
+// appendBytes appends a length-delimited field with the given number and
+// contents to buf
+func appendBytes(buf []byte, number int, b []byte) []byte {
+	buf = appendTag(buf, number, wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	return append(buf, b...)
+}
+
+// appendTag appends the tag of the field with the given number and wire
+// type to buf
+func appendTag(buf []byte, number int, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(number)<<3|uint64(wireType))
+}
+
+// AppendName appends the name of a field to buf: name with the characters
+// protobuf does not allow in names, all but letters, digits and underscores,
+// replaced by underscores, and prefixed by an underscore if it is empty or
+// starts with a digit
+func AppendName(buf []byte, name []byte) []byte {
+	if len(name) == 0 || name[0] >= '0' && name[0] <= '9' {
+		buf = append(buf, '_')
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// appendTypeName appends name, a name as of AppendName, to buf in camel
+// case, as protobuf messages are named, e.g., disk_io as DiskIo
+func appendTypeName(buf []byte, name []byte) []byte {
+	start := len(buf)
+	upper := true
+	for _, c := range name {
+		switch {
+		case c == '_':
+			upper = true
+			continue
+		case upper && c >= 'a' && c <= 'z':
+			c -= 'a' - 'A'
+		}
+		upper = false
+		buf = append(buf, c)
+	}
+	// names of messages must start with a letter too
+	if len(buf) == start || buf[start] >= '0' && buf[start] <= '9' {
+		buf = append(buf[:start], append([]byte{'X'}, buf[start:]...)...)
+	}
+	return buf
+}
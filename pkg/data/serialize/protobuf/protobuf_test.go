@@ -0,0 +1,336 @@
+package protobuf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+	}.Run(t)
+}
+
+// reader reads fields of messages in the protobuf wire format
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.err = fmt.Errorf("bad varint")
+		r.data = nil
+		return 0
+	}
+	r.data = r.data[n:]
+	return v
+}
+
+// field reads the tag of a field and its value: a varint, the bits of a
+// fixed64 or the contents of a length-delimited field
+func (r *reader) field() (number int, v uint64, b []byte) {
+	tag := r.uvarint()
+	number = int(tag >> 3)
+	switch tag & 7 {
+	case wireVarint:
+		v = r.uvarint()
+	case wireFixed64:
+		if len(r.data) < 8 {
+			r.err = io.ErrUnexpectedEOF
+			r.data = nil
+			return
+		}
+		v = binary.LittleEndian.Uint64(r.data)
+		r.data = r.data[8:]
+	case wireBytes:
+		n := r.uvarint()
+		if n > uint64(len(r.data)) {
+			r.err = fmt.Errorf("bad length %d", n)
+			r.data = nil
+			return
+		}
+		b = r.data[:n]
+		r.data = r.data[n:]
+	default:
+		r.err = fmt.Errorf("unexpected wire type %d", tag&7)
+		r.data = nil
+	}
+	return
+}
+
+// decode decodes the Point messages written by s into the names of their
+// measurements and maps of the keys of their tags and fields to values, and
+// of timestamp to their timestamps
+func decode(s *Serializer, data []byte) (names []string, values []map[string]interface{}, err error) {
+	tagKeys := make(map[int]string)
+	for key, i := range s.tags {
+		tagKeys[i+1] = key
+	}
+	measurementNames := make(map[int]string)
+	for name, m := range s.measurements {
+		measurementNames[m.number] = name
+	}
+
+	r := &reader{data: data}
+	for len(r.data) > 0 && r.err == nil {
+		n := r.uvarint()
+		if n > uint64(len(r.data)) {
+			return nil, nil, fmt.Errorf("bad length %d of point %d", n, len(values))
+		}
+		pr := &reader{data: r.data[:n]}
+		r.data = r.data[n:]
+		v := make(map[string]interface{})
+		var name string
+		for len(pr.data) > 0 && pr.err == nil {
+			number, x, b := pr.field()
+			switch number {
+			case numberTimestamp:
+				v["timestamp"] = int64(x)
+			case numberTags:
+				tr := &reader{data: b}
+				for len(tr.data) > 0 && tr.err == nil {
+					number, _, b := tr.field()
+					v[tagKeys[number]] = string(b)
+				}
+				if tr.err != nil {
+					return nil, nil, tr.err
+				}
+			default:
+				if len(name) > 0 {
+					return nil, nil, fmt.Errorf("more than one measurement in point %d", len(values))
+				}
+				name = measurementNames[number]
+				m := s.measurements[name]
+				if m == nil {
+					return nil, nil, fmt.Errorf("unknown field %d of point %d", number, len(values))
+				}
+				fieldKeys := make(map[int]string)
+				for key, i := range m.fields {
+					fieldKeys[i+1] = key
+				}
+				fr := &reader{data: b}
+				for len(fr.data) > 0 && fr.err == nil {
+					number, x, b := fr.field()
+					switch m.types[number-1] {
+					case serialize.FieldTypeInt:
+						v[fieldKeys[number]] = int64(x)
+					case serialize.FieldTypeBool:
+						v[fieldKeys[number]] = x == 1
+					case serialize.FieldTypeString:
+						v[fieldKeys[number]] = string(b)
+					default:
+						v[fieldKeys[number]] = math.Float64frombits(x)
+					}
+				}
+				if fr.err != nil {
+					return nil, nil, fr.err
+				}
+			}
+		}
+		if pr.err != nil {
+			return nil, nil, pr.err
+		}
+		names = append(names, name)
+		values = append(values, v)
+	}
+	return names, values, r.err
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func testSchema() *serialize.Schema {
+	return serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"disk-state": {[]byte("rack")}},
+		map[string][][]byte{
+			"cpu":        {[]byte("usage_user"), []byte("usage_system")},
+			"disk-state": {[]byte("count"), []byte("up"), []byte("status")},
+		},
+		map[string][]serialize.FieldType{
+			"disk-state": {serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString},
+		},
+	)
+}
+
+func TestProto(t *testing.T) {
+	proto, err := Proto(testSchema())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `// Messages of the data generated by tsbs_generate_data with -format=protobuf:
+// a Point per reading, each preceded by its length as a varint.
+syntax = "proto3";
+
+package tsbs;
+
+message Point {
+  // nanoseconds since the epoch
+  sfixed64 timestamp = 1;
+  Tags tags = 2;
+  oneof fields {
+    CpuFields cpu = 3;
+    DiskStateFields disk_state = 4;
+  }
+}
+
+message Tags {
+  string hostname = 1;
+  string region = 2;
+  string rack = 3;
+}
+
+message CpuFields {
+  optional double usage_user = 1;
+  optional double usage_system = 2;
+}
+
+message DiskStateFields {
+  optional int64 count = 1;
+  optional bool up = 2;
+  optional string status = 3;
+}
+`
+	if got := string(proto); got != want {
+		t.Errorf("incorrect .proto: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSerializerPoints(t *testing.T) {
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400000000000, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", 58.13, "usage_system", int64(2)),
+		newPoint("disk-state", 1451606410000000000, []string{"hostname", "host_1", "region", ""}, "count", int64(-3), "up", true, "status", "ok"),
+		newPoint("cpu", -1500, nil, "usage_system", math.Inf(-1)),
+		newPoint("disk-state", 1451606420000000000, []string{"hostname", "host_0", "rack", "r1"}, "up", false, "count", nil, "status", ""),
+	}
+	wantNames := []string{"cpu", "disk-state", "cpu", "disk-state"}
+	wantValues := []map[string]interface{}{
+		{"timestamp": int64(1451606400000000000), "hostname": "host_0", "region": "eu-west-1", "usage_user": 58.13, "usage_system": 2.0},
+		{"timestamp": int64(1451606410000000000), "hostname": "host_1", "count": int64(-3), "up": true, "status": "ok"},
+		{"timestamp": int64(-1500), "usage_system": math.Inf(-1)},
+		{"timestamp": int64(1451606420000000000), "hostname": "host_0", "rack": "r1", "up": false, "status": ""},
+	}
+
+	for _, batch := range []bool{false, true} {
+		var buf bytes.Buffer
+		s, err := NewSerializer(testSchema())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if batch {
+			b := serialize.NewPointBatch()
+			for _, p := range points {
+				b.Append(p)
+			}
+			err = s.SerializeBatch(b, &buf)
+		} else {
+			for _, p := range points {
+				if err = s.Serialize(p, &buf); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		names, values, err := decode(s, buf.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error decoding: %v", err)
+		}
+		if !reflect.DeepEqual(names, wantNames) {
+			t.Errorf("incorrect measurements: got %q want %q", names, wantNames)
+		}
+		if !reflect.DeepEqual(values, wantValues) {
+			t.Errorf("batch %t: incorrect points:\ngot  %v\nwant %v", batch, values, wantValues)
+		}
+	}
+}
+
+func TestSerializerErrors(t *testing.T) {
+	if _, err := NewSerializer(nil); err == nil {
+		t.Errorf("no error without a schema")
+	}
+	schema := serialize.NewSchema([][]byte{[]byte("host.name"), []byte("host_name")}, map[string][][]byte{"cpu": {[]byte("usage")}})
+	if _, err := NewSerializer(schema); err == nil || !strings.Contains(err.Error(), "duplicate field host_name of message Tags") {
+		t.Errorf("incorrect error for a duplicate field: %v", err)
+	}
+	schema = serialize.NewSchema(nil, map[string][][]byte{"disk_io": {[]byte("reads")}, "disk-io": {[]byte("reads")}})
+	if _, err := NewSerializer(schema); err == nil || !strings.Contains(err.Error(), "duplicate field disk_io or message DiskIoFields") {
+		t.Errorf("incorrect error for a duplicate measurement: %v", err)
+	}
+
+	var buf bytes.Buffer
+	s, err := NewSerializer(testSchema())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Serialize(newPoint("mem", 0, nil, "used", 1.0), &buf); err == nil {
+		t.Errorf("no error for a measurement not in the schema")
+	}
+	if err := s.Serialize(newPoint("cpu", 0, []string{"zone", "a"}, "usage_user", 1.0), &buf); err == nil || err.Error() != "tag zone of cpu has no field" {
+		t.Errorf("incorrect error for a tag not in the schema: %v", err)
+	}
+	if err := s.Serialize(newPoint("cpu", 0, nil, "usage_user", 1.0, "usage_idle", 2.0), &buf); err == nil || err.Error() != "field usage_idle of cpu has no field" {
+		t.Errorf("incorrect error for a field not in the schema: %v", err)
+	}
+	if err := s.Serialize(newPoint("disk-state", 0, nil, "count", int64(1), "up", "yes"), &buf); err == nil {
+		t.Errorf("no error for a string in a bool field")
+	}
+	b := serialize.NewPointBatch()
+	b.Append(newPoint("cpu", 0, nil, "usage_user", 1.0))
+	b.Append(newPoint("disk-state", 0, nil, "count", 1.5))
+	if err := s.SerializeBatch(b, &buf); err == nil {
+		t.Errorf("no error for a float in an int64 field")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("rejected points written: %q", buf.Bytes())
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	for name, want := range map[string]string{
+		"usage_user": "usage_user",
+		"disk-state": "disk_state",
+		"9lives":     "_9lives",
+		"":           "_",
+		"ünï":        "__n__",
+	} {
+		if got := string(AppendName(nil, []byte(name))); got != want {
+			t.Errorf("AppendName(%q): got %q want %q", name, got, want)
+		}
+	}
+	for name, want := range map[string]string{
+		"usage_user": "UsageUser",
+		"cpu":        "Cpu",
+		"_9lives":    "X9lives",
+		"_":          "X",
+	} {
+		if got := string(appendTypeName(nil, []byte(name))); got != want {
+			t.Errorf("appendTypeName(%q): got %q want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+// Package questdb implements the format for QuestDB: the InfluxDB line
+// protocol (ILP), with the conventions QuestDB needs to create its tables
+// on ingest as intended, as tables with a SYMBOL column for each tag, a
+// column of the right type for each field and a designated timestamp.
+package questdb
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as the line protocol of QuestDB parses them
+var floatFormat = &serialize.FloatFormat{Fmt: 'g', NaN: "NaN", Inf: "Infinity", NegInf: "-Infinity"}
+
+// Format is the name the format is registered under
+const Format = "questdb"
+
+func init() {
+	serialize.Describe(Format, "QuestDB InfluxDB line protocol, with typed fields and tags in schema order")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema), nil
+	})
+}
+
+// Serializer writes a Point in a serialized form for QuestDB
+type Serializer struct {
+	// tagRanks are the positions of the tag keys in the Schema, and
+	// fieldTypes the types of the fields of each measurement
+	tagRanks   map[string]int
+	fieldTypes map[string]map[string]serialize.FieldType
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and order the order of the tags of the
+	// Point being written
+	buf   []byte
+	order []int
+}
+
+// NewSerializer returns a Serializer for data described by schema, which
+// writes tags in the order of its tag keys and fields as the types it has
+// for them. schema may be nil, e.g., if it is not known, for a Serializer
+// writing tags in the order of each Point and fields as the types of their
+// values.
+func NewSerializer(schema *serialize.Schema) *Serializer {
+	s := &Serializer{}
+	if schema == nil {
+		return s
+	}
+	s.tagRanks = make(map[string]int, len(schema.TagKeys()))
+	for i, k := range schema.TagKeys() {
+		s.tagRanks[string(k)] = i
+	}
+	s.fieldTypes = make(map[string]map[string]serialize.FieldType, schema.Len())
+	for _, m := range schema.Measurements() {
+		types := schema.FieldTypes(m)
+		if types == nil {
+			continue
+		}
+		byKey := make(map[string]serialize.FieldType, len(types))
+		for i, k := range schema.FieldKeys(m) {
+			byKey[string(k)] = types[i]
+		}
+		s.fieldTypes[m] = byKey
+	}
+	return s
+}
+
+// Serialize writes Point p to w as a line of the InfluxDB line protocol,
+// with QuestDB conventions:
+//
+// cpu,hostname=host_0,region=eu-west-1 usage_user=58.13,usage_count=3i 1451606400000000000\n
+//
+// The measurement is the table and the tags are its SYMBOL columns, so they
+// are written in the order of the tag keys of the Schema, which is the order
+// QuestDB creates the columns in whichever table or line comes first. Tags
+// with empty values are left out, as QuestDB has them as null.
+//
+// Fields are written as the ILP types of the columns QuestDB creates for
+// them: ints as LONG (with an i suffix), unless the Schema has the field as
+// a float, floats as DOUBLE, with NaN and infinite values as NaN, Infinity
+// and -Infinity, bools as BOOLEAN (t or f) and strings as quoted STRING.
+//
+// The timestamp, in nanoseconds, is always written, so that it is the
+// designated timestamp of the row rather than the time of ingest.
+//
+// QuestDB does not allow some characters in table and column names, such
+// as '.', '-', ',' or spaces, so they are replaced by underscores. Spaces,
+// commas, equals signs, newlines and backslashes in tag values, and double
+// quotes, newlines and backslashes in strings, are escaped with a
+// backslash.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendLine(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendLine(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendLine(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = AppendName(buf, measurementName)
+	s.sortTags(tagKeys)
+	for _, i := range s.order {
+		if len(tagValues[i]) == 0 {
+			continue
+		}
+		buf = append(buf, ',')
+		buf = AppendName(buf, tagKeys[i])
+		buf = append(buf, '=')
+		buf = appendEscaped(buf, tagValues[i], " ,=")
+	}
+
+	types := s.fieldTypes[string(measurementName)]
+	for i, v := range fieldValues {
+		if i == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = AppendName(buf, fieldKeys[i])
+		buf = append(buf, '=')
+		buf = appendValue(buf, v, types[string(fieldKeys[i])])
+	}
+
+	buf = append(buf, ' ')
+	buf = strconv.AppendInt(buf, timestamp, 10)
+	return append(buf, '\n')
+}
+
+// sortTags sets order to the indexes of tagKeys in the order of the tag keys
+// of the Schema, followed by any others in the order they are given
+func (s *Serializer) sortTags(tagKeys [][]byte) {
+	s.order = s.order[:0]
+	for i := range tagKeys {
+		s.order = append(s.order, i)
+	}
+	if s.tagRanks == nil {
+		return
+	}
+	// insertion sort, as there are few tags
+	for i := 1; i < len(s.order); i++ {
+		for j := i; j > 0 && s.tagRank(tagKeys, s.order[j]) < s.tagRank(tagKeys, s.order[j-1]); j-- {
+			s.order[j], s.order[j-1] = s.order[j-1], s.order[j]
+		}
+	}
+}
+
+func (s *Serializer) tagRank(tagKeys [][]byte, i int) int {
+	if rank, ok := s.tagRanks[string(tagKeys[i])]; ok {
+		return rank
+	}
+	return len(s.tagRanks) + i
+}
+
+// appendValue appends field value v to buf as the ILP type of a column of
+// type t, or of the type of v if t is not known
+func appendValue(buf []byte, v interface{}, t serialize.FieldType) []byte {
+	switch x := v.(type) {
+	case int:
+		return appendInt(buf, int64(x), t)
+	case int64:
+		return appendInt(buf, x, t)
+	case float64:
+		return serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	case bool:
+		if x {
+			return append(buf, 't')
+		}
+		return append(buf, 'f')
+	case []byte:
+		return appendString(buf, x)
+	case string:
+		return appendString(buf, []byte(x))
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+func appendInt(buf []byte, x int64, t serialize.FieldType) []byte {
+	buf = strconv.AppendInt(buf, x, 10)
+	if t == serialize.FieldTypeFloat {
+		return buf
+	}
+	return append(buf, 'i')
+}
+
+func appendString(buf []byte, s []byte) []byte {
+	buf = append(buf, '"')
+	buf = appendEscaped(buf, s, `"`)
+	return append(buf, '"')
+}
+
+// appendEscaped appends s to buf, with newlines, backslashes and the
+// characters of special escaped by a backslash
+func appendEscaped(buf []byte, s []byte, special string) []byte {
+	for _, c := range s {
+		if c == '\n' || c == '\\' || strings.IndexByte(special, c) >= 0 {
+			buf = append(buf, '\\')
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// AppendName appends the name of a table or column to buf: name with the
+// characters QuestDB does not allow in them, and those ILP would need
+// escaped, replaced by underscores, or an underscore if name is empty
+func AppendName(buf []byte, name []byte) []byte {
+	if len(name) == 0 {
+		return append(buf, '_')
+	}
+	for _, c := range name {
+		if !validNameChar(c) {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+func validNameChar(c byte) bool {
+	if c < 0x20 || c == 0x7f {
+		return false
+	}
+	switch c {
+	case ' ', '=', '?', '.', ',', '\'', '"', '\\', '/', ':', '(', ')', '+', '-', '*', '%', '~':
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package questdb
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b usage_guest=38i 1451606400000000000\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "cpu,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b big_usage_guest=5000000000i,usage_guest=38i,usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "cpu usage_guest_nice=38.24311829 1451606400000000000\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializeConventions(t *testing.T) {
+	schema := serialize.NewTypedSchema(
+		[][]byte{[]byte("hostname"), []byte("region"), []byte("rack")},
+		map[string][][]byte{"disk.io": {[]byte("used-percent"), []byte("reads")}},
+		map[string][]serialize.FieldType{"disk.io": {serialize.FieldTypeFloat, serialize.FieldTypeInt}},
+	)
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk.io"))
+	p.SetTimestamp(0)
+	p.AppendTag([]byte("rack"), []byte("r 1,a=b"))
+	p.AppendTag([]byte("extra"), []byte(`c\d`))
+	p.AppendTag([]byte("region"), nil)
+	p.AppendTag([]byte("hostname"), []byte("host_0"))
+	p.AppendField([]byte("used-percent"), 50)
+	p.AppendField([]byte("reads"), int64(7))
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("state"), "say \"hi\"\n")
+
+	var buf bytes.Buffer
+	if err := NewSerializer(schema).Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `disk_io,hostname=host_0,rack=r\ 1\,a\=b,extra=c\\d used_percent=50,reads=7i,ok=t,state="say \"hi\"\` + "\n" + `" 0` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	cases := []struct {
+		name, want string
+	}{
+		{"usage_user", "usage_user"},
+		{"io.time", "io_time"},
+		{"key with space,comma=equals", "key_with_space_comma_equals"},
+		{"a/b:c(d)+e-f*g%h~i?j'k\"l\\m", "a_b_c_d__e_f_g_h_i_j_k_l_m"},
+		{"tab\there", "tab_here"},
+		{"ünïcödé", "ünïcödé"},
+		{"", "_"},
+	}
+	for _, c := range cases {
+		if got := string(AppendName([]byte("prefix"), []byte(c.name))); got != "prefix"+c.want {
+			t.Errorf("incorrect name of %q: got %q", c.name, got)
+		}
+	}
+}
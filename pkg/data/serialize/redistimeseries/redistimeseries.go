@@ -0,0 +1,207 @@
+// Package redistimeseries implements the format for RedisTimeSeries: the
+// TS.CREATE and TS.MADD commands storing the readings, in RESP, so that they
+// can be piped into redis-cli --pipe.
+package redistimeseries
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as TS.ADD parses them
+var floatFormat = &serialize.FloatFormat{Fmt: 'g', NaN: "nan", Inf: "inf", NegInf: "-inf"}
+
+// Format is the name the format is registered under
+const Format = "redistimeseries"
+
+// Labels of every time series, besides those of the tags of its readings
+const (
+	MeasurementLabel = "measurement"
+	FieldLabel       = "field"
+)
+
+func init() {
+	serialize.Describe(Format, "RedisTimeSeries TS.CREATE and TS.MADD commands in RESP, for redis-cli --pipe")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+}
+
+// Serializer writes a Point in a serialized form for RedisTimeSeries
+type Serializer struct {
+	// created are the keys of the time series created so far
+	created map[string]bool
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, series holds the tags of the key of the
+	// time series of the Point being written, key the key of one of its
+	// time series and madd its TS.MADD command
+	buf    []byte
+	series []byte
+	key    []byte
+	madd   []byte
+}
+
+// Serialize writes Point p to w as a TS.MADD command adding a sample of each
+// of its fields to its time series, as an array of bulk strings, e.g.:
+//
+// *4\r\n
+// $7\r\nTS.MADD\r\n
+// $48\r\ncpu:usage_user{hostname=host_0,region=eu-west-1}\r\n
+// $13\r\n1451606400000\r\n
+// $5\r\n58.13\r\n
+//
+// with the timestamp in milliseconds. A time series is created, before its
+// first sample, with a TS.CREATE command of its key and labels of its
+// measurement, field and tags:
+//
+// TS.CREATE cpu:usage_user{hostname=host_0,...} LABELS measurement cpu field usage_user hostname host_0 ...
+//
+// The key of the time series of a field is its measurement and field,
+// separated by a colon, and its tags, as key=value pairs joined by commas in
+// braces, so that the time series of a reading hash to the same slot of a
+// Redis Cluster, which TS.MADD requires. Tags with empty values are left
+// out of keys and labels.
+//
+// RedisTimeSeries only stores numbers, so bools are written as 1 or 0 and
+// strings are left out, as are Points without any other fields. NaN and
+// infinite values are written as nan, inf and -inf.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendPoint(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all commands of a PointBatch to the given writer in
+// the same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendPoint(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendPoint(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	series := append(s.series[:0], '{')
+	labels := 0
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		if labels > 0 {
+			series = append(series, ',')
+		}
+		labels++
+		series = append(series, tagKeys[i]...)
+		series = append(series, '=')
+		series = append(series, v...)
+	}
+	series = append(series, '}')
+	s.series = series
+
+	millis := timestamp / 1e6
+	if timestamp < 0 && timestamp%1e6 != 0 {
+		millis--
+	}
+	var ts [20]byte
+	t := strconv.AppendInt(ts[:0], millis, 10)
+	n := 0
+	madd := s.madd[:0]
+	for i, v := range fieldValues {
+		if !isNumber(v) {
+			continue
+		}
+		n++
+		key := append(s.key[:0], measurementName...)
+		key = append(key, ':')
+		key = append(key, fieldKeys[i]...)
+		key = append(key, series...)
+		s.key = key
+		if !s.created[string(key)] {
+			if s.created == nil {
+				s.created = make(map[string]bool)
+			}
+			s.created[string(key)] = true
+			buf = appendArray(buf, 7+2*labels)
+			buf = appendBulk(buf, []byte("TS.CREATE"))
+			buf = appendBulk(buf, key)
+			buf = appendBulk(buf, []byte("LABELS"))
+			buf = appendBulk(buf, []byte(MeasurementLabel))
+			buf = appendBulk(buf, measurementName)
+			buf = appendBulk(buf, []byte(FieldLabel))
+			buf = appendBulk(buf, fieldKeys[i])
+			for j, v := range tagValues {
+				if len(v) > 0 {
+					buf = appendBulk(buf, tagKeys[j])
+					buf = appendBulk(buf, v)
+				}
+			}
+		}
+		madd = appendBulk(madd, key)
+		madd = appendBulk(madd, t)
+		madd = appendValue(madd, v)
+	}
+	s.madd = madd
+	if n == 0 {
+		return buf
+	}
+	buf = appendArray(buf, 1+3*n)
+	buf = appendBulk(buf, []byte("TS.MADD"))
+	return append(buf, madd...)
+}
+
+// isNumber returns whether v is written as the value of a sample: a number
+// or a bool
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case []byte, string, nil:
+		return false
+	}
+	return true
+}
+
+// appendValue appends a field value to buf as a bulk string: floats with NaN
+// and infinite values as nan, inf and -inf, and bools as 1 or 0
+func appendValue(buf []byte, v interface{}) []byte {
+	var value [32]byte
+	var b []byte
+	switch x := v.(type) {
+	case float64:
+		b = serialize.AppendFloat(value[:0], x, 64, floatFormat)
+	case float32:
+		b = serialize.AppendFloat(value[:0], float64(x), 32, floatFormat)
+	case bool:
+		if x {
+			b = append(value[:0], '1')
+		} else {
+			b = append(value[:0], '0')
+		}
+	default:
+		b = serialize.FastFormatAppend(v, value[:0])
+	}
+	return appendBulk(buf, b)
+}
+
+// appendArray appends the start of a RESP array of n elements to buf
+func appendArray(buf []byte, n int) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(n), 10)
+	return append(buf, "\r\n"...)
+}
+
+// appendBulk appends b to buf as a RESP bulk string
+func appendBulk(buf []byte, b []byte) []byte {
+	buf = append(buf, '$')
+	buf = strconv.AppendInt(buf, int64(len(b)), 10)
+	buf = append(buf, "\r\n"...)
+	buf = append(buf, b...)
+	return append(buf, "\r\n"...)
+}
@@ -0,0 +1,138 @@
+package redistimeseries
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+// command returns args as a command of an array of bulk strings
+func command(args ...string) string {
+	s := fmt.Sprintf("*%d\r\n", len(args))
+	for _, arg := range args {
+		s += fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return s
+}
+
+const testSeries = "{hostname=host_0,region=eu-west-1,datacenter=eu-west-1b}"
+
+var testLabels = []string{"hostname", "host_0", "region", "eu-west-1", "datacenter", "eu-west-1b"}
+
+// create returns the TS.CREATE command of the time series of the field of
+// cpu with the given labels of tags
+func create(field, series string, labels ...string) string {
+	return command(append([]string{"TS.CREATE", "cpu:" + field + series, "LABELS", "measurement", "cpu", "field", field}, labels...)...)
+}
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output: create("usage_guest_nice", testSeries, testLabels...) +
+			command("TS.MADD", "cpu:usage_guest_nice"+testSeries, "1451606400000", "38.24311829"),
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output: create("usage_guest", testSeries, testLabels...) +
+			command("TS.MADD", "cpu:usage_guest"+testSeries, "1451606400000", "38"),
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: create("big_usage_guest", testSeries, testLabels...) +
+			create("usage_guest", testSeries, testLabels...) +
+			create("usage_guest_nice", testSeries, testLabels...) +
+			command("TS.MADD",
+				"cpu:big_usage_guest"+testSeries, "1451606400000", "5000000000",
+				"cpu:usage_guest"+testSeries, "1451606400000", "38",
+				"cpu:usage_guest_nice"+testSeries, "1451606400000", "38.24311829"),
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output: create("usage_guest_nice", "{}") +
+			command("TS.MADD", "cpu:usage_guest_nice{}", "1451606400000", "38.24311829"),
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerCreate(t *testing.T) {
+	s := &Serializer{}
+	var buf bytes.Buffer
+	for _, p := range []*serialize.Point{serializetest.PointDefault, serializetest.PointNoTags, serializetest.PointDefault} {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	b := serialize.NewPointBatch()
+	b.Append(serializetest.PointNoTags)
+	b.Append(serializetest.PointInt)
+	if err := s.SerializeBatch(b, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := create("usage_guest_nice", testSeries, testLabels...) +
+		command("TS.MADD", "cpu:usage_guest_nice"+testSeries, "1451606400000", "38.24311829") +
+		create("usage_guest_nice", "{}") +
+		command("TS.MADD", "cpu:usage_guest_nice{}", "1451606400000", "38.24311829") +
+		command("TS.MADD", "cpu:usage_guest_nice"+testSeries, "1451606400000", "38.24311829") +
+		command("TS.MADD", "cpu:usage_guest_nice{}", "1451606400000", "38.24311829") +
+		create("usage_guest", testSeries, testLabels...) +
+		command("TS.MADD", "cpu:usage_guest"+testSeries, "1451606400000", "38")
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSerializeValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.SetTimestamp(-1500)
+	p.AppendTag([]byte("path"), []byte("/dev/sda 1"))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendField([]byte("free"), math.NaN())
+	p.AppendField([]byte("state"), "full")
+	p.AppendField([]byte("used"), math.Inf(-1))
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("reads"), float32(1.5))
+	p.AppendField([]byte("missing"), nil)
+
+	var buf bytes.Buffer
+	if err := (&Serializer{}).Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var want string
+	for _, field := range []string{"free", "used", "ok", "reads"} {
+		want += command("TS.CREATE", "disk:"+field+"{path=/dev/sda 1}", "LABELS", "measurement", "disk", "field", field, "path", "/dev/sda 1")
+	}
+	want += command("TS.MADD",
+		"disk:free{path=/dev/sda 1}", "-1", "nan",
+		"disk:used{path=/dev/sda 1}", "-1", "-inf",
+		"disk:ok{path=/dev/sda 1}", "-1", "1",
+		"disk:reads{path=/dev/sda 1}", "-1", "1.5")
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+
+	p = serialize.NewPoint()
+	p.SetMeasurementName([]byte("log"))
+	p.AppendField([]byte("message"), "hello")
+	buf.Reset()
+	if err := (&Serializer{}).Serialize(p, &buf); err != nil || buf.Len() != 0 {
+		t.Errorf("point of only strings written: %q (error %v)", buf.String(), err)
+	}
+}
@@ -0,0 +1,186 @@
+package serialize
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// Factory creates a PointSerializer for data described by schema. Formats
+// that start with a header (e.g., a list of the tables to create) write it to
+// w before returning.
+type Factory func(schema *Schema, w io.Writer) (PointSerializer, error)
+
+// SchemeFactory creates a PointSerializer for formats of the form
+// "scheme:arg", e.g., "exec:./my_serializer", which are parameterized by arg
+type SchemeFactory func(arg string, schema *Schema, w io.Writer) (PointSerializer, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+	schemes    = make(map[string]SchemeFactory)
+	// descriptions are short descriptions of formats and schemes, e.g., for
+	// tsbs list formats
+	descriptions = make(map[string]string)
+	// fileFormats are the formats and schemes marked by MarkFileFormat
+	fileFormats = make(map[string]bool)
+	// headerFormats are the formats and schemes marked by MarkHeaderFormat
+	headerFormats = make(map[string]bool)
+)
+
+// Register makes a format available by the given name. It is meant to be
+// called from the init function of the package implementing the format, and
+// panics if factory is nil or the name is already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("serialize: Register factory is nil for " + name)
+	}
+	if _, dup := registry[name]; dup {
+		panic("serialize: Register called twice for " + name)
+	}
+	registry[name] = factory
+}
+
+// RegisterScheme makes all formats of the form "scheme:arg" available. Like
+// Register, it panics if factory is nil or the scheme is already registered.
+func RegisterScheme(scheme string, factory SchemeFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("serialize: RegisterScheme factory is nil for " + scheme)
+	}
+	if _, dup := schemes[scheme]; dup {
+		panic("serialize: RegisterScheme called twice for " + scheme)
+	}
+	schemes[scheme] = factory
+}
+
+// Describe sets a one line description of a registered format or scheme,
+// shown when listing them
+func Describe(name, description string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	descriptions[name] = description
+}
+
+// Description returns the description of a format or scheme, or "" if it has
+// none
+func Description(name string) string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return descriptions[name]
+}
+
+// MarkFileFormat marks a registered format or scheme as writing files of a
+// format of their own, e.g., Parquet files, which must start with their own
+// magic number rather than a Header, and can only be read once the
+// serializer is closed. Data in such a format is written to a file, without
+// a Header.
+func MarkFileFormat(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	fileFormats[name] = true
+}
+
+// IsFileFormat returns whether name is a format marked by MarkFileFormat, or
+// is of the form "scheme:arg" for a scheme marked by it
+func IsFileFormat(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if fileFormats[name] {
+		return true
+	}
+	i := strings.IndexByte(name, ':')
+	return i > 0 && fileFormats[name[:i]]
+}
+
+// MarkHeaderFormat marks a registered format or scheme as read by a TSBS
+// loader that checks the Header at the start of its input, so data generated
+// in it starts with one. Data in other formats, which is read by the tools of
+// the databases themselves, is written without a Header, as they would take
+// it for data.
+func MarkHeaderFormat(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	headerFormats[name] = true
+}
+
+// HasHeader returns whether name is a format marked by MarkHeaderFormat, or
+// is of the form "scheme:arg" for a scheme marked by it
+func HasHeader(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if headerFormats[name] {
+		return true
+	}
+	i := strings.IndexByte(name, ':')
+	return i > 0 && headerFormats[name[:i]]
+}
+
+// New returns a PointSerializer for the named format, writing any header the
+// format starts with to w
+func New(name string, schema *Schema, w io.Writer) (PointSerializer, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if ok {
+		return factory(schema, w)
+	}
+	schemeFactory, arg, ok := lookupScheme(name)
+	if !ok {
+		return nil, suggest.Error("format", name, Formats())
+	}
+	return schemeFactory(arg, schema, w)
+}
+
+// IsFormat returns whether name is a registered format, or is of the form
+// "scheme:arg" for a registered scheme
+func IsFormat(name string) bool {
+	registryMu.RLock()
+	_, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		_, _, ok = lookupScheme(name)
+	}
+	return ok
+}
+
+func lookupScheme(name string) (SchemeFactory, string, bool) {
+	i := strings.IndexByte(name, ':')
+	if i < 0 || i == len(name)-1 {
+		return nil, "", false
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := schemes[name[:i]]
+	return factory, name[i+1:], ok
+}
+
+// Formats returns the names of all registered formats in sorted order
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schemes returns the names of all registered schemes in sorted order
+func Schemes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,190 @@
+package serialize
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type testRegistrySerializer struct{}
+
+func (s *testRegistrySerializer) Serialize(p *Point, w io.Writer) error {
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	const name = "test-registry-format"
+	Register(name, func(schema *Schema, w io.Writer) (PointSerializer, error) {
+		_, err := w.Write([]byte("header\n"))
+		return &testRegistrySerializer{}, err
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	}()
+
+	found := false
+	for _, f := range Formats() {
+		if f == name {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("registered format not in Formats(): %v", Formats())
+	}
+
+	var buf bytes.Buffer
+	ps, err := New(name, nil, &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := ps.(*testRegistrySerializer); !ok {
+		t.Errorf("incorrect serializer type: %T", ps)
+	}
+	if got := buf.String(); got != "header\n" {
+		t.Errorf("incorrect header: got %q", got)
+	}
+
+	if _, err := New("bogus", nil, &buf); err == nil {
+		t.Errorf("did not error for unknown format")
+	}
+}
+
+func TestRegisterPanic(t *testing.T) {
+	testPanic := func(desc string, fn func()) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Errorf("%s: did not panic when should", desc)
+			}
+		}()
+		fn()
+	}
+	const name = "test-registry-dup"
+	factory := func(schema *Schema, w io.Writer) (PointSerializer, error) {
+		return &testRegistrySerializer{}, nil
+	}
+	Register(name, factory)
+	defer func() {
+		registryMu.Lock()
+		delete(registry, name)
+		registryMu.Unlock()
+	}()
+
+	testPanic("duplicate name", func() { Register(name, factory) })
+	testPanic("nil factory", func() { Register("test-registry-nil", nil) })
+	testPanic("nil scheme factory", func() { RegisterScheme("test-registry-nil", nil) })
+}
+
+func TestRegisterScheme(t *testing.T) {
+	const scheme = "test-scheme"
+	var gotArg string
+	RegisterScheme(scheme, func(arg string, schema *Schema, w io.Writer) (PointSerializer, error) {
+		gotArg = arg
+		return &testRegistrySerializer{}, nil
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(schemes, scheme)
+		registryMu.Unlock()
+	}()
+
+	found := false
+	for _, s := range Schemes() {
+		if s == scheme {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("registered scheme not in Schemes(): %v", Schemes())
+	}
+
+	cases := []struct {
+		desc string
+		name string
+		want bool
+	}{
+		{desc: "scheme with arg", name: scheme + ":./foo --bar", want: true},
+		{desc: "scheme with arg containing colon", name: scheme + ":a:b", want: true},
+		{desc: "scheme without arg", name: scheme + ":", want: false},
+		{desc: "scheme alone", name: scheme, want: false},
+		{desc: "unknown scheme", name: "bogus:foo", want: false},
+	}
+	for _, c := range cases {
+		if got := IsFormat(c.name); got != c.want {
+			t.Errorf("%s: incorrect IsFormat: got %v want %v", c.desc, got, c.want)
+		}
+		_, err := New(c.name, nil, nil)
+		if c.want && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		} else if !c.want && err == nil {
+			t.Errorf("%s: did not error", c.desc)
+		}
+	}
+
+	if _, err := New(scheme+":a:b", nil, nil); err != nil || gotArg != "a:b" {
+		t.Errorf("incorrect arg: got %q (err %v)", gotArg, err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	const name = "test-describe-format"
+	if got := Description(name); got != "" {
+		t.Errorf("incorrect description before Describe: got %q", got)
+	}
+	Describe(name, "a test format")
+	defer func() {
+		registryMu.Lock()
+		delete(descriptions, name)
+		registryMu.Unlock()
+	}()
+	if got := Description(name); got != "a test format" {
+		t.Errorf("incorrect description: got %q", got)
+	}
+}
+
+func TestMarkFileFormat(t *testing.T) {
+	const name = "test-file-format"
+	if IsFileFormat(name) {
+		t.Errorf("format is a file format before MarkFileFormat")
+	}
+	MarkFileFormat(name)
+	defer func() {
+		registryMu.Lock()
+		delete(fileFormats, name)
+		registryMu.Unlock()
+	}()
+	for _, format := range []string{name, name + ":arg"} {
+		if !IsFileFormat(format) {
+			t.Errorf("%s: not a file format after MarkFileFormat", format)
+		}
+	}
+	for _, format := range []string{"test-file", name + "-other", ":" + name} {
+		if IsFileFormat(format) {
+			t.Errorf("%s: incorrectly a file format", format)
+		}
+	}
+}
+
+func TestMarkHeaderFormat(t *testing.T) {
+	const name = "test-header-format"
+	if HasHeader(name) {
+		t.Errorf("format has a header before MarkHeaderFormat")
+	}
+	MarkHeaderFormat(name)
+	defer func() {
+		registryMu.Lock()
+		delete(headerFormats, name)
+		registryMu.Unlock()
+	}()
+	for _, format := range []string{name, name + ":arg"} {
+		if !HasHeader(format) {
+			t.Errorf("%s: no header after MarkHeaderFormat", format)
+		}
+	}
+	for _, format := range []string{"test-header", name + "-other", ":" + name} {
+		if HasHeader(format) {
+			t.Errorf("%s: incorrectly has a header", format)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package serialize
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// FieldType is the type of the values of a field
+type FieldType uint8
+
+// Field types, as determined by FieldTypeOf
+const (
+	FieldTypeUnknown FieldType = iota
+	FieldTypeFloat
+	FieldTypeInt
+	FieldTypeBool
+	FieldTypeString
+)
+
+var fieldTypeNames = []string{"unknown", "float", "int", "bool", "string"}
+
+// String returns the name of t, e.g., float
+func (t FieldType) String() string {
+	if int(t) < len(fieldTypeNames) {
+		return fieldTypeNames[t]
+	}
+	return fmt.Sprintf("FieldType(%d)", t)
+}
+
+// MarshalText encodes t as its name, e.g., in JSON
+func (t FieldType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText decodes t from its name, as encoded by MarshalText
+func (t *FieldType) UnmarshalText(text []byte) error {
+	for i, name := range fieldTypeNames {
+		if name == string(text) {
+			*t = FieldType(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown field type: %s", text)
+}
+
+// FieldTypeOf returns the FieldType of a field value, as set with
+// Point.AppendField. Values of types not supported by the serializers are
+// FieldTypeUnknown.
+func FieldTypeOf(v interface{}) FieldType {
+	switch v.(type) {
+	case float64, float32:
+		return FieldTypeFloat
+	case int64, int:
+		return FieldTypeInt
+	case bool:
+		return FieldTypeBool
+	case []byte, string:
+		return FieldTypeString
+	default:
+		return FieldTypeUnknown
+	}
+}
+
+// Schema describes the measurements a Simulator produces along with the tag
+// and field keys of each. It is computed once per Simulator and must be treated
+// as immutable, so callers can hold on to it (and the slices it returns)
+// without copying.
+type Schema struct {
+	tagKeys      [][]byte
+	measurements []string
+	fields       map[string][][]byte
+	fieldTypes   map[string][]FieldType
+	// measurementTagKeys are the tag keys of the points of each measurement
+	// that has tags of its own, the shared tag keys followed by its own
+	measurementTagKeys map[string][][]byte
+	// allTagKeys are the tag keys of any point, see AllTagKeys
+	allTagKeys [][]byte
+}
+
+// NewSchema creates a Schema from the tag keys of every point and a map of
+// measurement names to field keys.
+// The measurement names are sorted up front so iteration order is
+// deterministic for things like file headers.
+func NewSchema(tagKeys [][]byte, fields map[string][][]byte) *Schema {
+	return NewTypedSchema(tagKeys, fields, nil)
+}
+
+// NewTypedSchema is like NewSchema, additionally recording the types of the
+// fields of each measurement, in the same order as their keys
+func NewTypedSchema(tagKeys [][]byte, fields map[string][][]byte, fieldTypes map[string][]FieldType) *Schema {
+	measurements := make([]string, 0, len(fields))
+	for k := range fields {
+		measurements = append(measurements, k)
+	}
+	sort.Strings(measurements)
+
+	return &Schema{
+		tagKeys:      tagKeys,
+		measurements: measurements,
+		fields:       fields,
+		fieldTypes:   fieldTypes,
+		allTagKeys:   tagKeys,
+	}
+}
+
+// NewTaggedSchema is like NewTypedSchema, additionally recording the tag keys
+// that only the points of some measurements have, keyed by measurement name.
+// The points of a measurement have its own tag keys set after the shared ones.
+func NewTaggedSchema(tagKeys [][]byte, ownTagKeys map[string][][]byte, fields map[string][][]byte, fieldTypes map[string][]FieldType) *Schema {
+	s := NewTypedSchema(tagKeys, fields, fieldTypes)
+	s.measurementTagKeys = make(map[string][][]byte, len(ownTagKeys))
+	all := append([][]byte(nil), tagKeys...)
+	seen := make(map[string]bool, len(tagKeys))
+	for _, k := range tagKeys {
+		seen[string(k)] = true
+	}
+	for _, m := range s.measurements {
+		own := ownTagKeys[m]
+		if len(own) == 0 {
+			continue
+		}
+		keys := make([][]byte, 0, len(tagKeys)+len(own))
+		keys = append(keys, tagKeys...)
+		s.measurementTagKeys[m] = append(keys, own...)
+		for _, k := range own {
+			if !seen[string(k)] {
+				seen[string(k)] = true
+				all = append(all, k)
+			}
+		}
+	}
+	s.allTagKeys = all
+	return s
+}
+
+// TagKeys returns the tag keys shared by every point, in the order they are
+// set. Points of some measurements have more, see TagKeysOf.
+func (s *Schema) TagKeys() [][]byte {
+	return s.tagKeys
+}
+
+// TagKeysOf returns all the tag keys of the points of a given measurement, in
+// the order they are set, or nil if the measurement is not part of the Schema
+func (s *Schema) TagKeysOf(measurement string) [][]byte {
+	if _, ok := s.fields[measurement]; !ok {
+		return nil
+	}
+	if keys, ok := s.measurementTagKeys[measurement]; ok {
+		return keys
+	}
+	return s.tagKeys
+}
+
+// AllTagKeys returns the tag keys of the points of any measurement: the
+// shared ones, followed by those of the measurements with tags of their own
+// in the order of Measurements, without duplicates. It is what formats
+// writing all measurements with the same columns need.
+func (s *Schema) AllTagKeys() [][]byte {
+	return s.allTagKeys
+}
+
+// Measurements returns the names of all measurements in sorted order
+func (s *Schema) Measurements() []string {
+	return s.measurements
+}
+
+// FieldKeys returns the field keys for a given measurement, or nil if the
+// measurement is not part of the Schema
+func (s *Schema) FieldKeys(measurement string) [][]byte {
+	return s.fields[measurement]
+}
+
+// FieldTypes returns the types of the fields of a given measurement, in the
+// same order as FieldKeys, or nil if the measurement is not part of the Schema
+// or the types are not known
+func (s *Schema) FieldTypes(measurement string) []FieldType {
+	return s.fieldTypes[measurement]
+}
+
+// Len returns the number of measurements in the Schema
+func (s *Schema) Len() int {
+	return len(s.measurements)
+}
+
+// Hash returns a 64-bit FNV-1a hash of the tag keys, measurements, their own
+// tag keys and field keys of the Schema, which identifies the shape of the
+// data it describes.
+// The field types are not included, so the hash of the Schema is the same
+// whether they are known or not.
+func (s *Schema) Hash() uint64 {
+	h := fnv.New64a()
+	sep := []byte{0}
+	tagSep := []byte{1}
+	for _, k := range s.tagKeys {
+		h.Write(k)
+		h.Write(sep)
+	}
+	for _, m := range s.measurements {
+		h.Write(sep)
+		h.Write([]byte(m))
+		if keys, ok := s.measurementTagKeys[m]; ok {
+			// mark the tag keys of the measurement apart from its field keys,
+			// with a byte no key contains
+			for _, k := range keys[len(s.tagKeys):] {
+				h.Write(tagSep)
+				h.Write(k)
+			}
+		}
+		for _, k := range s.fields[m] {
+			h.Write(sep)
+			h.Write(k)
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,174 @@
+package serialize
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNewSchema(t *testing.T) {
+	fields := map[string][][]byte{
+		"mem":  {[]byte("used"), []byte("free")},
+		"cpu":  {[]byte("usage_user")},
+		"disk": {},
+	}
+	s := NewSchema([][]byte{[]byte("hostname")}, fields)
+	if got := s.Len(); got != 3 {
+		t.Errorf("incorrect number of measurements: got %d want %d", got, 3)
+	}
+
+	want := []string{"cpu", "disk", "mem"}
+	got := s.Measurements()
+	if len(got) != len(want) {
+		t.Fatalf("incorrect measurements length: got %d want %d", len(got), len(want))
+	}
+	for i, m := range want {
+		if got[i] != m {
+			t.Errorf("incorrect measurement at %d: got %s want %s", i, got[i], m)
+		}
+	}
+
+	if got := len(s.FieldKeys("mem")); got != 2 {
+		t.Errorf("incorrect number of field keys for mem: got %d want %d", got, 2)
+	}
+	if got := len(s.TagKeys()); got != 1 {
+		t.Errorf("incorrect number of tag keys: got %d want %d", got, 1)
+	}
+	if got := s.FieldKeys("bogus"); got != nil {
+		t.Errorf("non-nil field keys for missing measurement: got %v", got)
+	}
+}
+
+func TestSchemaHash(t *testing.T) {
+	tagKeys := [][]byte{[]byte("hostname")}
+	fields := map[string][][]byte{
+		"cpu": {[]byte("usage_user")},
+		"mem": {[]byte("used"), []byte("free")},
+	}
+	h := NewSchema(tagKeys, fields).Hash()
+	if got := NewSchema(tagKeys, fields).Hash(); got != h {
+		t.Errorf("hash differs for the same schema: got %x want %x", got, h)
+	}
+
+	others := []*Schema{
+		NewSchema([][]byte{[]byte("region")}, fields),
+		NewSchema(tagKeys, map[string][][]byte{"cpu": fields["cpu"]}),
+		NewSchema(tagKeys, map[string][][]byte{"cpu": fields["cpu"], "mem": {[]byte("used")}}),
+	}
+	for i, o := range others {
+		if o.Hash() == h {
+			t.Errorf("schema %d: hash does not differ from original", i)
+		}
+	}
+}
+
+func TestNewTypedSchema(t *testing.T) {
+	tagKeys := [][]byte{[]byte("hostname")}
+	fields := map[string][][]byte{
+		"cpu": {[]byte("usage_user")},
+		"mem": {[]byte("used"), []byte("used_percent")},
+	}
+	types := map[string][]FieldType{
+		"cpu": {FieldTypeFloat},
+		"mem": {FieldTypeInt, FieldTypeFloat},
+	}
+	s := NewTypedSchema(tagKeys, fields, types)
+	got := s.FieldTypes("mem")
+	if len(got) != 2 || got[0] != FieldTypeInt || got[1] != FieldTypeFloat {
+		t.Errorf("incorrect field types for mem: got %v", got)
+	}
+	if got := s.FieldTypes("bogus"); got != nil {
+		t.Errorf("non-nil field types for missing measurement: got %v", got)
+	}
+	if got := NewSchema(tagKeys, fields).FieldTypes("cpu"); got != nil {
+		t.Errorf("non-nil field types for untyped schema: got %v", got)
+	}
+	if s.Hash() != NewSchema(tagKeys, fields).Hash() {
+		t.Errorf("hash differs when field types are known")
+	}
+}
+
+func TestFieldTypeOf(t *testing.T) {
+	cases := []struct {
+		v    interface{}
+		want FieldType
+	}{
+		{v: 1.5, want: FieldTypeFloat},
+		{v: float32(1.5), want: FieldTypeFloat},
+		{v: int64(1), want: FieldTypeInt},
+		{v: 1, want: FieldTypeInt},
+		{v: true, want: FieldTypeBool},
+		{v: []byte("a"), want: FieldTypeString},
+		{v: "a", want: FieldTypeString},
+		{v: nil, want: FieldTypeUnknown},
+		{v: uint8(1), want: FieldTypeUnknown},
+	}
+	for _, c := range cases {
+		if got := FieldTypeOf(c.v); got != c.want {
+			t.Errorf("%#v: incorrect type: got %s want %s", c.v, got, c.want)
+		}
+	}
+	if got := FieldType(99).String(); got != "FieldType(99)" {
+		t.Errorf("incorrect name of unknown type: got %s", got)
+	}
+}
+
+func TestFieldTypeText(t *testing.T) {
+	for _, ft := range []FieldType{FieldTypeUnknown, FieldTypeFloat, FieldTypeInt, FieldTypeBool, FieldTypeString} {
+		text, err := ft.MarshalText()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", ft, err)
+		}
+		var got FieldType
+		if err := got.UnmarshalText(text); err != nil || got != ft {
+			t.Errorf("%s: incorrect round trip: got %s (error %v)", ft, got, err)
+		}
+	}
+	var ft FieldType
+	if err := ft.UnmarshalText([]byte("bogus")); err == nil {
+		t.Errorf("did not error for unknown field type")
+	}
+}
+
+func TestNewTaggedSchema(t *testing.T) {
+	tagKeys := [][]byte{[]byte("hostname")}
+	fields := map[string][][]byte{
+		"cpu":  {[]byte("usage_user")},
+		"disk": {[]byte("used")},
+		"net":  {[]byte("bytes_recv")},
+	}
+	own := map[string][][]byte{
+		"disk": {[]byte("path"), []byte("fstype")},
+		"net":  {[]byte("interface"), []byte("path")},
+	}
+	s := NewTaggedSchema(tagKeys, own, fields, nil)
+
+	cases := []struct {
+		measurement string
+		want        string
+	}{
+		{measurement: "cpu", want: "hostname"},
+		{measurement: "disk", want: "hostname,path,fstype"},
+		{measurement: "net", want: "hostname,interface,path"},
+		{measurement: "bogus", want: ""},
+	}
+	for _, c := range cases {
+		if got := string(bytes.Join(s.TagKeysOf(c.measurement), []byte(","))); got != c.want {
+			t.Errorf("%s: incorrect tag keys: got %s want %s", c.measurement, got, c.want)
+		}
+	}
+	if got := len(s.TagKeys()); got != 1 {
+		t.Errorf("incorrect number of shared tag keys: got %d want %d", got, 1)
+	}
+	if got, want := string(bytes.Join(s.AllTagKeys(), []byte(","))), "hostname,path,fstype,interface"; got != want {
+		t.Errorf("incorrect tag keys of all measurements: got %s want %s", got, want)
+	}
+	if got := NewSchema(tagKeys, fields).TagKeysOf("disk"); len(got) != 1 {
+		t.Errorf("incorrect tag keys without own tag keys: got %q", got)
+	}
+	if s.Hash() == NewSchema(tagKeys, fields).Hash() {
+		t.Errorf("hash does not differ when measurements have tag keys of their own")
+	}
+	if NewTaggedSchema(tagKeys, nil, fields, nil).Hash() != NewSchema(tagKeys, fields).Hash() {
+		t.Errorf("hash differs without tag keys of measurements")
+	}
+}
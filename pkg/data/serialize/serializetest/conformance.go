@@ -0,0 +1,444 @@
+package serializetest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// specialStrings are tag values (and keys) that formats commonly need to
+// escape or quote
+var specialStrings = []string{
+	"with space", "with,comma", "with=equals", `with"quote`, "with'apostrophe",
+	`with\backslash`, "with\nnewline", "with\ttab", "#hash", "ünïcödé", "日本語",
+	"with\x00nul", "",
+}
+
+// specialFloats are float field values that formats commonly mishandle
+var specialFloats = []float64{
+	math.NaN(), math.Inf(1), math.Inf(-1), math.Copysign(0, -1),
+	math.MaxFloat64, math.SmallestNonzeroFloat64, -1.5,
+}
+
+// specialInts are int field values at the limits of their type
+var specialInts = []int64{math.MaxInt64, math.MinInt64, 0, -1}
+
+// ownTagKeys are the tag keys that points of some measurements have after
+// TagKeys, like those of the disk and net measurements of devops
+var ownTagKeys = []struct {
+	measurement []byte
+	tagKeys     [][]byte
+}{
+	{measurement: Measurement},
+	{measurement: []byte("disk"), tagKeys: [][]byte{[]byte("path"), []byte("fstype")}},
+	{measurement: []byte("net"), tagKeys: [][]byte{[]byte("interface")}},
+}
+
+// FuzzPoints returns new Points exercising the edge cases a serializer must
+// handle: special characters in tag keys and values, empty tag values, no
+// tags, measurements whose points have tags of their own, NaN, infinite and
+// extreme field values, and timestamps at the epoch. Their fields are all
+// float64 or int64, the types the simulators produce. The Points are the same
+// on every call, and are followed by ones made pseudo-randomly from the same
+// edge cases.
+func FuzzPoints() []*serialize.Point {
+	var points []*serialize.Point
+	for _, s := range specialStrings {
+		points = append(points, newPoint(TagKeys, [][]byte{[]byte(s), TagValues[1], TagValues[2]}, [][]byte{ColFloat}, []interface{}{Float}))
+	}
+	for _, m := range ownTagKeys[1:] {
+		for _, s := range specialStrings {
+			tagKeys := append(append([][]byte(nil), TagKeys...), m.tagKeys...)
+			tagValues := append(append([][]byte(nil), TagValues...), []byte(s))
+			for len(tagValues) < len(tagKeys) {
+				tagValues = append(tagValues, TagValues[0])
+			}
+			p := newPoint(tagKeys, tagValues, [][]byte{ColFloat}, []interface{}{Float})
+			p.SetMeasurementName(m.measurement)
+			points = append(points, p)
+		}
+	}
+	points = append(points, newPoint([][]byte{[]byte("key with space,comma=equals")}, [][]byte{TagValues[0]}, [][]byte{ColFloat}, []interface{}{Float}))
+	points = append(points, newPoint(TagKeys, [][]byte{{}, {}, {}}, [][]byte{ColFloat}, []interface{}{Float}))
+	for _, f := range specialFloats {
+		points = append(points, newPoint(TagKeys, TagValues, [][]byte{ColFloat}, []interface{}{f}))
+	}
+	for _, i := range specialInts {
+		points = append(points, newPoint(TagKeys, TagValues, [][]byte{ColInt64}, []interface{}{i}))
+	}
+	epoch := newPoint(nil, nil, [][]byte{ColInt64}, []interface{}{Int64})
+	epoch.SetTimestamp(0)
+	points = append(points, epoch)
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		m := ownTagKeys[r.Intn(len(ownTagKeys))]
+		keys := append(append([][]byte(nil), TagKeys...), m.tagKeys...)
+		var tagKeys, tagValues [][]byte
+		for j := r.Intn(len(keys) + 1); j > 0; j-- {
+			tagKeys = append(tagKeys, keys[len(tagKeys)])
+			tagValues = append(tagValues, []byte(specialStrings[r.Intn(len(specialStrings))]))
+		}
+		fieldKeys := [][]byte{ColFloat, ColInt64}
+		fieldValues := []interface{}{specialFloats[r.Intn(len(specialFloats))], specialInts[r.Intn(len(specialInts))]}
+		p := newPoint(tagKeys, tagValues, fieldKeys, fieldValues)
+		p.SetMeasurementName(m.measurement)
+		p.SetTimestamp(r.Int63())
+		points = append(points, p)
+	}
+	return points
+}
+
+// tagPoints returns a Point of each measurement, with all of its tags set to
+// values that no format needs to escape and that are unique to the tag
+func tagPoints() []*serialize.Point {
+	var points []*serialize.Point
+	for _, m := range ownTagKeys {
+		tagKeys := append(append([][]byte(nil), TagKeys...), m.tagKeys...)
+		var tagValues [][]byte
+		for _, k := range tagKeys {
+			tagValues = append(tagValues, []byte(fmt.Sprintf("%s_%s_value", m.measurement, k)))
+		}
+		p := newPoint(tagKeys, tagValues, [][]byte{ColFloat}, []interface{}{Float})
+		p.SetMeasurementName(m.measurement)
+		points = append(points, p)
+	}
+	return points
+}
+
+// Suite is a conformance suite that every serializer format should pass,
+// including formats added by plugins, e.g.:
+//
+//	func TestConformance(t *testing.T) {
+//		serializetest.Suite{
+//			New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+//				return serialize.New("myformat", schema, w)
+//			},
+//			Golden: cases,
+//		}.Run(t)
+//	}
+type Suite struct {
+	// New returns a new serializer of the format under test for data
+	// described by schema, writing any header of the format to w
+	New serialize.Factory
+	// Golden are the expected outputs of serializing fixed Points, not
+	// including any header of the format
+	Golden []Case
+	// Decode, if set, decodes the whole output of a serializer made with New,
+	// including any header, back into Points, to check that they round trip
+	Decode func(data []byte) ([]*serialize.Point, error)
+	// IntsAsFloats makes the round trip accept int fields decoded as float64,
+	// for formats with a single numeric type
+	IntsAsFloats bool
+	// IgnoresWriter is set for serializers that do not write to the Writer
+	// given to Serialize (e.g., ones writing to a subprocess), which skips the
+	// check that write errors are returned
+	IgnoresWriter bool
+	// Plain, if set, returns the whole output of a serializer made with New
+	// with the tag values in it as they are, for formats that encode or
+	// compress them, for the check that no tag is left out
+	Plain func(data []byte) ([]byte, error)
+	// OmitsTags is set for formats configured to write only some of the
+	// tags (e.g., those in a path template), which skips the check that no
+	// tag is left out
+	OmitsTags bool
+	// Measurement, if set, is the only measurement the format is configured
+	// to write, to whose points the checks are limited
+	Measurement string
+}
+
+// Run runs the checks of the suite as subtests of t. Serializers that are an
+// io.Closer are closed before their output is checked.
+func (s Suite) Run(t *testing.T) {
+	t.Run("golden", s.testGolden)
+	t.Run("fuzz", s.testFuzz)
+	if !s.OmitsTags {
+		t.Run("tags", s.testTags)
+	}
+	t.Run("batch", s.testBatch)
+	if !s.IgnoresWriter {
+		t.Run("write errors", s.testWriteErrors)
+	}
+	if s.Decode != nil {
+		t.Run("round trip", s.testRoundTrip)
+	}
+}
+
+func (s Suite) testGolden(t *testing.T) {
+	var points []*serialize.Point
+	for _, c := range s.Golden {
+		points = append(points, c.InputPoint)
+	}
+	schema := schemaOf(points)
+	header, err := s.output(schema, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range s.Golden {
+		got, err := s.output(schema, []*serialize.Point{c.InputPoint}, false)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.Desc, err)
+		} else if want := string(header) + c.Output; string(got) != want {
+			t.Errorf("%s \nOutput incorrect: \nWant: '%s' \nGot:  '%s'", c.Desc, want, got)
+		}
+	}
+}
+
+func (s Suite) testFuzz(t *testing.T) {
+	points := s.only(FuzzPoints())
+	schema := schemaOf(points)
+	header, err := s.output(schema, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, p := range points {
+		first, err := s.output(schema, []*serialize.Point{p}, false)
+		if err != nil {
+			t.Errorf("point %d (%s): unexpected error: %v", i, describe(p), err)
+			continue
+		}
+		if bytes.Equal(first, header) {
+			t.Errorf("point %d (%s): no output", i, describe(p))
+		}
+		second, err := s.output(schema, []*serialize.Point{p}, false)
+		if err != nil || !bytes.Equal(first, second) {
+			t.Errorf("point %d (%s): output differs between runs: got %q then %q (error %v)", i, describe(p), first, second, err)
+		}
+	}
+}
+
+// testTags checks that no tag is left out of the output, including those
+// that only the points of some measurements have
+func (s Suite) testTags(t *testing.T) {
+	points := s.only(tagPoints())
+	out, err := s.output(schemaOf(points), points, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Plain != nil {
+		if out, err = s.Plain(out); err != nil {
+			t.Fatalf("unexpected error decoding: %v", err)
+		}
+	}
+	for _, p := range points {
+		for i, v := range p.TagValues() {
+			if !bytes.Contains(out, v) {
+				t.Errorf("%s: tag %s not in output", p.MeasurementName(), p.TagKeys()[i])
+			}
+		}
+	}
+}
+
+func (s Suite) testBatch(t *testing.T) {
+	points := s.only(append(FuzzPoints(), PointDefault, PointMultiField, PointInt, PointNoTags))
+	schema := schemaOf(points)
+	want, err := s.output(schema, points, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := s.output(schema, points, true)
+	if err != nil {
+		t.Fatalf("unexpected error serializing batch: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("batch output differs from serializing each point:\nWant: %q\nGot:  %q", want, got)
+	}
+}
+
+func (s Suite) testWriteErrors(t *testing.T) {
+	points := []*serialize.Point{PointDefault}
+	ps, err := s.New(schemaOf(points), ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c, ok := ps.(io.Closer); ok {
+		defer c.Close()
+	}
+	if err := ps.Serialize(PointDefault, &ErrWriter{}); err == nil {
+		t.Errorf("no error returned when writing failed")
+	}
+}
+
+func (s Suite) testRoundTrip(t *testing.T) {
+	points := s.only(append(FuzzPoints(), PointDefault, PointMultiField, PointInt, PointNoTags))
+	out, err := s.output(schemaOf(points), points, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := s.Decode(out)
+	if err != nil {
+		t.Fatalf("unexpected error decoding: %v", err)
+	}
+	if len(decoded) != len(points) {
+		t.Fatalf("incorrect number of points decoded: got %d want %d", len(decoded), len(points))
+	}
+	for i, p := range points {
+		if err := s.equal(decoded[i], p); err != nil {
+			t.Errorf("point %d (%s): %v", i, describe(p), err)
+		}
+	}
+}
+
+// only returns the points of s.Measurement, if set, or else all points
+func (s Suite) only(points []*serialize.Point) []*serialize.Point {
+	if s.Measurement == "" {
+		return points
+	}
+	var kept []*serialize.Point
+	for _, p := range points {
+		if string(p.MeasurementName()) == s.Measurement {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// output returns the whole output of a new serializer, including any header,
+// after serializing points one by one or, if batch is set, as a PointBatch
+func (s Suite) output(schema *serialize.Schema, points []*serialize.Point, batch bool) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	var buf bytes.Buffer
+	ps, err := s.New(schema, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if batch {
+		b := serialize.NewPointBatch()
+		for _, p := range points {
+			b.Append(p)
+		}
+		err = serialize.SerializeBatch(ps, b, &buf)
+	} else {
+		for _, p := range points {
+			if err = ps.Serialize(p, &buf); err != nil {
+				break
+			}
+		}
+	}
+	if c, ok := ps.(io.Closer); ok {
+		if closeErr := c.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return buf.Bytes(), err
+}
+
+// equal returns an error describing how the decoded Point got differs from
+// want, or nil if they are the same
+func (s Suite) equal(got, want *serialize.Point) error {
+	if !bytes.Equal(got.MeasurementName(), want.MeasurementName()) {
+		return fmt.Errorf("incorrect measurement name: got %q want %q", got.MeasurementName(), want.MeasurementName())
+	}
+	if got.Timestamp() != want.Timestamp() {
+		return fmt.Errorf("incorrect timestamp: got %d want %d", got.Timestamp(), want.Timestamp())
+	}
+	if !equalBytesSlices(got.TagKeys(), want.TagKeys()) || !equalBytesSlices(got.TagValues(), want.TagValues()) {
+		return fmt.Errorf("incorrect tags: got %q=%q want %q=%q", got.TagKeys(), got.TagValues(), want.TagKeys(), want.TagValues())
+	}
+	if !equalBytesSlices(got.FieldKeys(), want.FieldKeys()) {
+		return fmt.Errorf("incorrect field keys: got %q want %q", got.FieldKeys(), want.FieldKeys())
+	}
+	for i, v := range want.FieldValues() {
+		if !s.equalValues(got.FieldValues()[i], v) {
+			return fmt.Errorf("incorrect value of field %s: got %#v want %#v", want.FieldKeys()[i], got.FieldValues()[i], v)
+		}
+	}
+	return nil
+}
+
+func (s Suite) equalValues(got, want interface{}) bool {
+	switch w := want.(type) {
+	case int:
+		return s.equalValues(got, int64(w))
+	case int64:
+		if f, ok := got.(float64); ok && s.IntsAsFloats {
+			return f == float64(w)
+		}
+		if i, ok := got.(int); ok {
+			got = int64(i)
+		}
+		return got == want
+	case float64:
+		f, ok := got.(float64)
+		// NaN is not equal to itself
+		return ok && (f == w || math.IsNaN(f) && math.IsNaN(w))
+	default:
+		return got == want
+	}
+}
+
+func equalBytesSlices(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaOf returns a Schema describing points. The tag and field keys of each
+// measurement are those of all of its points, in the order they are first
+// set, and the tag keys that all measurements start with are shared.
+func schemaOf(points []*serialize.Point) *serialize.Schema {
+	var measurements []string
+	tagKeys := make(map[string][][]byte)
+	fields := make(map[string][][]byte)
+	for _, p := range points {
+		m := string(p.MeasurementName())
+		if _, ok := fields[m]; !ok {
+			measurements = append(measurements, m)
+			fields[m] = [][]byte{}
+		}
+		tagKeys[m] = appendNew(tagKeys[m], p.TagKeys())
+		fields[m] = appendNew(fields[m], p.FieldKeys())
+	}
+
+	var shared [][]byte
+	if len(measurements) > 0 {
+		shared = tagKeys[measurements[0]]
+	}
+	for _, m := range measurements {
+		n := 0
+		for n < len(shared) && n < len(tagKeys[m]) && bytes.Equal(shared[n], tagKeys[m][n]) {
+			n++
+		}
+		shared = shared[:n]
+	}
+	own := make(map[string][][]byte)
+	for _, m := range measurements {
+		own[m] = tagKeys[m][len(shared):]
+	}
+	return serialize.NewTaggedSchema(shared, own, fields, nil)
+}
+
+// appendNew appends the keys that are not in have to it
+func appendNew(have, keys [][]byte) [][]byte {
+next:
+	for _, k := range keys {
+		for _, h := range have {
+			if bytes.Equal(h, k) {
+				continue next
+			}
+		}
+		have = append(have, k)
+	}
+	return have
+}
+
+// describe returns a short description of p for error messages
+func describe(p *serialize.Point) string {
+	return fmt.Sprintf("tags %q=%q, fields %v, timestamp %d", p.TagKeys(), p.TagValues(), p.FieldValues(), p.Timestamp())
+}
@@ -0,0 +1,115 @@
+// Package serializetest provides fixtures and helpers for testing
+// PointSerializer implementations, including the conformance Suite every
+// format should pass.
+package serializetest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Values the fixture Points are made from
+var (
+	Now         = time.Unix(1451606400, 0)
+	Measurement = []byte("cpu")
+	TagKeys     = [][]byte{[]byte("hostname"), []byte("region"), []byte("datacenter")}
+	TagValues   = [][]byte{[]byte("host_0"), []byte("eu-west-1"), []byte("eu-west-1b")}
+	ColFloat    = []byte("usage_guest_nice")
+	ColInt      = []byte("usage_guest")
+	ColInt64    = []byte("big_usage_guest")
+)
+
+// Values the fixture Points are made from
+const (
+	Float = float64(38.24311829)
+	Int   = 38
+	Int64 = int64(5000000000)
+)
+
+// Fixture Points for serializer tests; they must not be modified
+var (
+	// PointDefault has all tags and a single float field
+	PointDefault = newPoint(TagKeys, TagValues, [][]byte{ColFloat}, []interface{}{Float})
+	// PointMultiField has all tags and fields of each type
+	PointMultiField = newPoint(TagKeys, TagValues, [][]byte{ColInt64, ColInt, ColFloat}, []interface{}{Int64, Int, Float})
+	// PointInt has all tags and a single int field
+	PointInt = newPoint(TagKeys, TagValues, [][]byte{ColInt}, []interface{}{Int})
+	// PointNoTags has no tags and a single float field
+	PointNoTags = newPoint(nil, nil, [][]byte{ColFloat}, []interface{}{Float})
+)
+
+func newPoint(tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName(Measurement)
+	p.SetTimestamp(Now.UnixNano())
+	for i := range tagKeys {
+		p.AppendTag(tagKeys[i], tagValues[i])
+	}
+	for i := range fieldKeys {
+		p.AppendField(fieldKeys[i], fieldValues[i])
+	}
+	return p
+}
+
+// Errors returned by ErrWriter
+const (
+	ErrWriterAlwaysErr    = "bad write: I always error"
+	ErrWriterSometimesErr = "bad write: I sometimes error"
+)
+
+// ErrWriter is an io.Writer that fails, either on every write or, if SkipOne
+// is set, on every write after the first
+type ErrWriter struct {
+	SkipOne bool
+	cnt     int
+}
+
+func (w *ErrWriter) Write(p []byte) (n int, err error) {
+	if !w.SkipOne {
+		return 0, fmt.Errorf(ErrWriterAlwaysErr)
+	} else if w.cnt < 1 {
+		w.cnt++
+		return len(p), nil
+	} else {
+		return 0, fmt.Errorf(ErrWriterSometimesErr)
+	}
+}
+
+// Case is the expected output of serializing a Point
+type Case struct {
+	Desc       string
+	InputPoint *serialize.Point
+	Output     string
+}
+
+// CheckSerializer serializes the input of each case with ps and checks it
+// gives the expected output. It then checks that serializing all the cases
+// as a single PointBatch gives the same output.
+func CheckSerializer(t *testing.T, cases []Case, ps serialize.PointSerializer) {
+	for _, c := range cases {
+		b := new(bytes.Buffer)
+		ps.Serialize(c.InputPoint, b)
+		got := b.String()
+		if got != c.Output {
+			t.Errorf("%s \nOutput incorrect: \nWant: '%s' \nGot:  '%s'", c.Desc, c.Output, got)
+		}
+	}
+
+	batch := serialize.NewPointBatch()
+	want := ""
+	for _, c := range cases {
+		batch.Append(c.InputPoint)
+		want += c.Output
+	}
+	b := new(bytes.Buffer)
+	if err := serialize.SerializeBatch(ps, batch, b); err != nil {
+		t.Fatalf("unexpected error serializing batch: %v", err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("batch output incorrect: \nWant: '%s' \nGot:  '%s'", want, got)
+	}
+}
@@ -0,0 +1,704 @@
+// Package sql implements a generic SQL format, for databases without a
+// format of their own, e.g., vanilla PostgreSQL, MySQL or SQLite: CREATE
+// TABLE statements of a table per measurement, then multi-row INSERT
+// statements, or COPY blocks, of the readings, to be run by the client of
+// the database.
+package sql
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes the
+// statements with DefaultOptions. Formats of the form Format + ":" + options,
+// e.g., sql:dialect=mysql,batch=1000, write them with other options (see
+// ParseOptions).
+const Format = "sql"
+
+// Dialects of SQL the statements are written in
+const (
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+	DialectSQLite   = "sqlite"
+	DialectANSI     = "ansi"
+)
+
+// Modes of writing the rows: INSERT statements, or COPY blocks of
+// PostgreSQL's psql
+const (
+	ModeInsert = "insert"
+	ModeCopy   = "copy"
+)
+
+// MeasurementPlaceholder is replaced by the measurement in the table names
+// of Options.Table
+const MeasurementPlaceholder = "{measurement}"
+
+// TimeColumn is the column of the time of each reading
+const TimeColumn = "time"
+
+// DefaultBatchSize is the number of rows of each statement of Format
+const DefaultBatchSize = 100
+
+// dialect is how statements of a dialect are written
+type dialect struct {
+	// quote quotes identifiers
+	quote byte
+	// ifNotExists is whether CREATE TABLE takes IF NOT EXISTS
+	ifNotExists bool
+	// backslashes is whether backslashes in string literals are escapes
+	backslashes bool
+	// nonFinite is whether float columns store NaN and infinite values, as
+	// the string literals 'NaN', 'Infinity' and '-Infinity'
+	nonFinite bool
+	// boolsAsInts is whether bools are written as 1 or 0
+	boolsAsInts bool
+	// timeLayout is the layout of times, in UTC, and timePrefix precedes
+	// their string literals
+	timeLayout string
+	timePrefix string
+	// types of the columns of times, strings, ints, floats and bools
+	timeType, textType, intType, floatType, boolType string
+}
+
+var dialects = map[string]*dialect{
+	DialectPostgres: {
+		quote:       '"',
+		ifNotExists: true,
+		nonFinite:   true,
+		timeLayout:  "2006-01-02 15:04:05.999999-07",
+		timeType:    "TIMESTAMPTZ",
+		textType:    "TEXT",
+		intType:     "BIGINT",
+		floatType:   "DOUBLE PRECISION",
+		boolType:    "BOOLEAN",
+	},
+	DialectMySQL: {
+		quote:       '`',
+		ifNotExists: true,
+		backslashes: true,
+		timeLayout:  "2006-01-02 15:04:05.999999",
+		timeType:    "DATETIME(6)",
+		textType:    "TEXT",
+		intType:     "BIGINT",
+		floatType:   "DOUBLE",
+		boolType:    "BOOLEAN",
+	},
+	DialectSQLite: {
+		quote:       '"',
+		ifNotExists: true,
+		boolsAsInts: true,
+		timeLayout:  "2006-01-02 15:04:05.999999",
+		timeType:    "TEXT",
+		textType:    "TEXT",
+		intType:     "INTEGER",
+		floatType:   "REAL",
+		boolType:    "INTEGER",
+	},
+	DialectANSI: {
+		quote:      '"',
+		timeLayout: "2006-01-02 15:04:05.999999",
+		timePrefix: "TIMESTAMP ",
+		timeType:   "TIMESTAMP",
+		textType:   "VARCHAR(255)",
+		intType:    "BIGINT",
+		floatType:  "DOUBLE PRECISION",
+		boolType:   "BOOLEAN",
+	},
+}
+
+func init() {
+	serialize.Describe(Format, "SQL CREATE TABLE and multi-row INSERT statements, or COPY blocks, of a table per measurement, with sql:dialect=<postgres|mysql|sqlite|ansi>,mode=<insert|copy>,table=<name>,batch=<rows>,create=<bool>")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultOptions(), w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		opts, err := ParseOptions(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSerializer(schema, opts, w)
+	})
+}
+
+// Options are the options of the format
+type Options struct {
+	// Dialect is DialectPostgres, DialectMySQL, DialectSQLite or
+	// DialectANSI
+	Dialect string
+	// Mode is ModeInsert or, for DialectPostgres, ModeCopy
+	Mode string
+	// Table is the name of the table of each measurement, with
+	// MeasurementPlaceholder replaced by the measurement, e.g.,
+	// tsbs_{measurement}
+	Table string
+	// BatchSize is the number of rows of each statement
+	BatchSize int
+	// Create is whether the statements start with CREATE TABLE statements
+	// of the tables
+	Create bool
+}
+
+// DefaultOptions returns the options of Format: INSERT statements of
+// DefaultBatchSize rows in the dialect of PostgreSQL into tables named by
+// the measurements, after the statements creating them
+func DefaultOptions() Options {
+	return Options{
+		Dialect:   DialectPostgres,
+		Mode:      ModeInsert,
+		Table:     MeasurementPlaceholder,
+		BatchSize: DefaultBatchSize,
+		Create:    true,
+	}
+}
+
+// ParseOptions parses the options of the format from a comma separated list
+// of option=value, e.g., dialect=sqlite,batch=500. Options not in the list
+// are those of DefaultOptions. The options are:
+//   - dialect: postgres, mysql, sqlite or ansi
+//   - mode: insert, or copy for postgres
+//   - table: the name of the table of each measurement, which must have
+//     {measurement} in it
+//   - batch: the number of rows of each statement
+//   - create: true or false
+func ParseOptions(s string) (Options, error) {
+	opts := DefaultOptions()
+	for _, option := range strings.Split(s, ",") {
+		i := strings.IndexByte(option, '=')
+		if i < 0 {
+			return opts, fmt.Errorf("invalid SQL option '%s': must be option=value", option)
+		}
+		name, value := option[:i], option[i+1:]
+		switch name {
+		case "dialect":
+			if _, ok := dialects[value]; !ok {
+				return opts, fmt.Errorf("invalid SQL dialect '%s': must be %s, %s, %s or %s", value, DialectPostgres, DialectMySQL, DialectSQLite, DialectANSI)
+			}
+			opts.Dialect = value
+		case "mode":
+			if value != ModeInsert && value != ModeCopy {
+				return opts, fmt.Errorf("invalid SQL mode '%s': must be %s or %s", value, ModeInsert, ModeCopy)
+			}
+			opts.Mode = value
+		case "table":
+			if !strings.Contains(value, MeasurementPlaceholder) {
+				return opts, fmt.Errorf("invalid SQL table '%s': must have %s in it", value, MeasurementPlaceholder)
+			}
+			opts.Table = value
+		case "batch":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return opts, fmt.Errorf("invalid SQL batch '%s': must be a positive integer", value)
+			}
+			opts.BatchSize = n
+		case "create":
+			create, err := strconv.ParseBool(value)
+			if err != nil {
+				return opts, fmt.Errorf("invalid SQL create '%s': must be true or false", value)
+			}
+			opts.Create = create
+		default:
+			return opts, fmt.Errorf("unknown SQL option '%s': must be dialect, mode, table, batch or create", name)
+		}
+	}
+	if opts.Mode == ModeCopy && opts.Dialect != DialectPostgres {
+		return opts, fmt.Errorf("SQL mode %s requires dialect %s", ModeCopy, DialectPostgres)
+	}
+	return opts, nil
+}
+
+// Serializer writes Points as SQL statements. The rows of each table are
+// held until they make a statement of Options.BatchSize rows, so the
+// statements are only complete once the Serializer is closed.
+type Serializer struct {
+	opts    Options
+	dialect *dialect
+	// tagKeys are the tag keys of the tag columns, and fieldKeys the field
+	// keys of the field columns of each measurement, of the Schema; without
+	// a Schema, the columns of each row are the tags and fields of its point
+	tagKeys   map[string][][]byte
+	fieldKeys map[string][][]byte
+
+	// statements are the statements whose rows are being held, by their
+	// start, in the order they were started, and w the Writer rows were last
+	// written to, which Close writes the rest to
+	statements map[string]*statement
+	order      []*statement
+	w          io.Writer
+
+	// buf and start are scratch space reused between calls so each Point or
+	// PointBatch is written with a single call
+	buf   []byte
+	start []byte
+}
+
+// statement is a statement of the rows of a table with the same columns
+type statement struct {
+	start []byte
+	rows  []byte
+	n     int
+}
+
+// NewSerializer returns a Serializer for data described by schema, writing
+// statements with the given options. Unless opts.Create is false, it writes
+// a CREATE TABLE statement of each measurement of schema to w, e.g.:
+//
+// CREATE TABLE IF NOT EXISTS "cpu" ("time" TIMESTAMPTZ NOT NULL, "hostname" TEXT, ..., "usage_user" BIGINT, ...);
+//
+// Tables have a column of the time, one of each tag key and one of each field
+// of their measurement, of the type of the field in the dialect:
+// integers, booleans, text or, for the numbers of the simulators, floats.
+// IF NOT EXISTS is left out for ANSI SQL, which does not have it. schema may
+// be nil, e.g., if it is not known, for no CREATE TABLE statements and rows
+// of the columns of their points.
+func NewSerializer(schema *serialize.Schema, opts Options, w io.Writer) (*Serializer, error) {
+	d, ok := dialects[opts.Dialect]
+	if !ok {
+		return nil, fmt.Errorf("invalid SQL dialect '%s'", opts.Dialect)
+	}
+	if opts.Mode == ModeCopy && opts.Dialect != DialectPostgres {
+		return nil, fmt.Errorf("SQL mode %s requires dialect %s", ModeCopy, DialectPostgres)
+	}
+	if opts.BatchSize <= 0 {
+		return nil, fmt.Errorf("invalid SQL batch %d: must be positive", opts.BatchSize)
+	}
+	s := &Serializer{
+		opts:       opts,
+		dialect:    d,
+		tagKeys:    make(map[string][][]byte),
+		fieldKeys:  make(map[string][][]byte),
+		statements: make(map[string]*statement),
+	}
+	if schema == nil {
+		return s, nil
+	}
+	var buf []byte
+	for _, m := range schema.Measurements() {
+		s.tagKeys[m] = schema.TagKeysOf(m)
+		s.fieldKeys[m] = schema.FieldKeys(m)
+		if !opts.Create {
+			continue
+		}
+		buf = append(buf, "CREATE TABLE "...)
+		if d.ifNotExists {
+			buf = append(buf, "IF NOT EXISTS "...)
+		}
+		buf = s.appendTable(buf, []byte(m))
+		buf = append(buf, " ("...)
+		buf = s.appendIdentifier(buf, []byte(TimeColumn))
+		buf = append(buf, ' ')
+		buf = append(buf, d.timeType...)
+		buf = append(buf, " NOT NULL"...)
+		for _, key := range s.tagKeys[m] {
+			buf = append(buf, ", "...)
+			buf = s.appendIdentifier(buf, key)
+			buf = append(buf, ' ')
+			buf = append(buf, d.textType...)
+		}
+		fieldTypes := schema.FieldTypes(m)
+		for i, key := range s.fieldKeys[m] {
+			t := serialize.FieldTypeUnknown
+			if i < len(fieldTypes) {
+				t = fieldTypes[i]
+			}
+			buf = append(buf, ", "...)
+			buf = s.appendIdentifier(buf, key)
+			buf = append(buf, ' ')
+			buf = append(buf, d.columnType(t)...)
+		}
+		buf = append(buf, ");\n"...)
+	}
+	if len(buf) == 0 {
+		return s, nil
+	}
+	_, err := w.Write(buf)
+	return s, err
+}
+
+// columnType returns the type of the column of fields of type t
+func (d *dialect) columnType(t serialize.FieldType) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return d.intType
+	case serialize.FieldTypeBool:
+		return d.boolType
+	case serialize.FieldTypeString:
+		return d.textType
+	default:
+		return d.floatType
+	}
+}
+
+// Serialize adds Point p as a row of the table of its measurement to the
+// statement of its rows, and writes the statement to w once it has
+// Options.BatchSize rows, e.g., for INSERT statements:
+//
+// INSERT INTO "cpu" ("time", "hostname", ..., "usage_user", ...) VALUES
+// ('2016-01-01 00:00:00+00', 'host_0', ..., 58, ...),
+// ...;
+//
+// or for COPY blocks, whose rows are in the text format of COPY:
+//
+// COPY "cpu" ("time", "hostname", ..., "usage_user", ...) FROM STDIN;
+// 2016-01-01 00:00:00+00	host_0	...	58	...
+// ...
+// \.
+//
+// The time of each row, in UTC with microseconds, is followed by the value
+// of each tag column, NULL for tags p does not have or whose value is empty,
+// and then each field column. Strings are quoted, and bools are TRUE or
+// FALSE, or 1 or 0 for SQLite. NaN and infinite values are 'NaN',
+// 'Infinity' and '-Infinity' for PostgreSQL, and NULL for the other
+// dialects, whose float columns cannot store them. Tags and fields the
+// table of a measurement of the Schema has no column of are an error.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf, err := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if err != nil {
+		return err
+	}
+	s.w = w
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// SerializeBatch adds all rows of a PointBatch to their statements in the
+// same way as Serialize, and writes those complete to w
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	var err error
+	for i := 0; i < b.Len() && err == nil; i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf, err = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf, s.w = buf, w
+	if len(buf) > 0 {
+		// the statements completed before an error are written, as their
+		// rows are no longer held
+		if _, werr := w.Write(buf); err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// Close writes the statements of the rows being held to the Writer rows
+// were last written to
+func (s *Serializer) Close() error {
+	buf := s.buf[:0]
+	for _, st := range s.order {
+		if st.n > 0 {
+			buf = s.appendStatement(buf, st)
+		}
+	}
+	s.buf = buf
+	if len(buf) == 0 || s.w == nil {
+		return nil
+	}
+	_, err := s.w.Write(buf)
+	return err
+}
+
+// appendRow adds a row to its statement, appending the statement to buf if
+// it is complete
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) ([]byte, error) {
+	columnTags, columnFields := tagKeys, fieldKeys
+	if keys, ok := s.tagKeys[string(measurementName)]; ok {
+		columnTags = keys
+		for _, key := range tagKeys {
+			if !hasKey(keys, key) {
+				return buf, fmt.Errorf("tag %s of %s has no column", key, measurementName)
+			}
+		}
+	}
+	if keys, ok := s.fieldKeys[string(measurementName)]; ok {
+		columnFields = keys
+		for _, key := range fieldKeys {
+			if !hasKey(keys, key) {
+				return buf, fmt.Errorf("field %s of %s has no column", key, measurementName)
+			}
+		}
+	}
+
+	s.start = s.appendStart(s.start[:0], measurementName, columnTags, columnFields)
+	st := s.statements[string(s.start)]
+	if st == nil {
+		st = &statement{start: append([]byte(nil), s.start...)}
+		s.statements[string(st.start)] = st
+		s.order = append(s.order, st)
+	}
+
+	isCopy := s.opts.Mode == ModeCopy
+	rows := st.rows
+	if isCopy {
+		rows = s.dialect.appendCopyTime(rows, timestamp)
+	} else {
+		if st.n > 0 {
+			rows = append(rows, ",\n"...)
+		}
+		rows = append(rows, '(')
+		rows = s.dialect.appendTime(rows, timestamp)
+	}
+	for _, key := range columnTags {
+		v := lookupTag(key, tagKeys, tagValues)
+		if isCopy {
+			rows = append(rows, '\t')
+			rows = appendCopyValue(rows, v)
+		} else {
+			rows = append(rows, ", "...)
+			rows = s.dialect.appendValue(rows, v)
+		}
+	}
+	for _, key := range columnFields {
+		v := lookupField(key, fieldKeys, fieldValues)
+		if isCopy {
+			rows = append(rows, '\t')
+			rows = appendCopyValue(rows, v)
+		} else {
+			rows = append(rows, ", "...)
+			rows = s.dialect.appendValue(rows, v)
+		}
+	}
+	if isCopy {
+		rows = append(rows, '\n')
+	} else {
+		rows = append(rows, ')')
+	}
+	st.rows = rows
+	st.n++
+
+	if st.n < s.opts.BatchSize {
+		return buf, nil
+	}
+	return s.appendStatement(buf, st), nil
+}
+
+// appendStatement appends statement st to buf, and empties it
+func (s *Serializer) appendStatement(buf []byte, st *statement) []byte {
+	buf = append(buf, st.start...)
+	buf = append(buf, st.rows...)
+	if s.opts.Mode == ModeCopy {
+		buf = append(buf, `\.`+"\n"...)
+	} else {
+		buf = append(buf, ";\n"...)
+	}
+	st.rows = st.rows[:0]
+	st.n = 0
+	return buf
+}
+
+// appendStart appends the start of a statement of the rows of the given
+// columns of the table of measurementName to buf
+func (s *Serializer) appendStart(buf []byte, measurementName []byte, tagKeys, fieldKeys [][]byte) []byte {
+	if s.opts.Mode == ModeCopy {
+		buf = append(buf, "COPY "...)
+	} else {
+		buf = append(buf, "INSERT INTO "...)
+	}
+	buf = s.appendTable(buf, measurementName)
+	buf = append(buf, " ("...)
+	buf = s.appendIdentifier(buf, []byte(TimeColumn))
+	for _, keys := range [][][]byte{tagKeys, fieldKeys} {
+		for _, key := range keys {
+			buf = append(buf, ", "...)
+			buf = s.appendIdentifier(buf, key)
+		}
+	}
+	if s.opts.Mode == ModeCopy {
+		return append(buf, ") FROM STDIN;\n"...)
+	}
+	return append(buf, ") VALUES\n"...)
+}
+
+// appendTable appends the quoted name of the table of measurementName to
+// buf
+func (s *Serializer) appendTable(buf []byte, measurementName []byte) []byte {
+	name := strings.ReplaceAll(s.opts.Table, MeasurementPlaceholder, string(measurementName))
+	return s.appendIdentifier(buf, []byte(name))
+}
+
+// appendIdentifier appends name to buf as a quoted identifier
+func (s *Serializer) appendIdentifier(buf []byte, name []byte) []byte {
+	q := s.dialect.quote
+	buf = append(buf, q)
+	for _, c := range name {
+		if c == q {
+			buf = append(buf, q)
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, q)
+}
+
+// hasKey returns whether key is among keys
+func hasKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if string(k) == string(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupTag returns the value of the tag key among tagKeys, or nil if there
+// is none or it is empty
+func lookupTag(key []byte, tagKeys, tagValues [][]byte) interface{} {
+	for i, k := range tagKeys {
+		if string(k) == string(key) {
+			if len(tagValues[i]) == 0 {
+				return nil
+			}
+			return tagValues[i]
+		}
+	}
+	return nil
+}
+
+// lookupField returns the value of the field key among fieldKeys, or nil if
+// there is none
+func lookupField(key []byte, fieldKeys [][]byte, fieldValues []interface{}) interface{} {
+	for i, k := range fieldKeys {
+		if string(k) == string(key) {
+			return fieldValues[i]
+		}
+	}
+	return nil
+}
+
+// appendTime appends timestamp to buf as a literal of the dialect
+func (d *dialect) appendTime(buf []byte, timestamp int64) []byte {
+	buf = append(buf, d.timePrefix...)
+	buf = append(buf, '\'')
+	buf = time.Unix(0, timestamp).UTC().AppendFormat(buf, d.timeLayout)
+	return append(buf, '\'')
+}
+
+// appendCopyTime appends timestamp to buf as a value of COPY
+func (d *dialect) appendCopyTime(buf []byte, timestamp int64) []byte {
+	return time.Unix(0, timestamp).UTC().AppendFormat(buf, d.timeLayout)
+}
+
+// appendValue appends v to buf as a literal of the dialect
+func (d *dialect) appendValue(buf []byte, v interface{}) []byte {
+	var f float64
+	switch x := v.(type) {
+	case nil:
+		return append(buf, "NULL"...)
+	case []byte:
+		return d.appendString(buf, x)
+	case string:
+		return d.appendString(buf, []byte(x))
+	case bool:
+		switch {
+		case d.boolsAsInts && x:
+			return append(buf, '1')
+		case d.boolsAsInts:
+			return append(buf, '0')
+		case x:
+			return append(buf, "TRUE"...)
+		default:
+			return append(buf, "FALSE"...)
+		}
+	case float64:
+		f = x
+	case float32:
+		f = float64(x)
+	default:
+		return serialize.FastFormatAppend(v, buf)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if !d.nonFinite {
+			return append(buf, "NULL"...)
+		}
+		return append(buf, nonFinite(f)...)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// nonFinite returns the string literal of NaN or infinite f
+func nonFinite(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "'NaN'"
+	case f > 0:
+		return "'Infinity'"
+	default:
+		return "'-Infinity'"
+	}
+}
+
+// appendString appends s to buf as a string literal of the dialect
+func (d *dialect) appendString(buf []byte, s []byte) []byte {
+	buf = append(buf, '\'')
+	for _, c := range s {
+		switch {
+		case c == '\'':
+			buf = append(buf, '\'', '\'')
+		case c == '\\' && d.backslashes:
+			buf = append(buf, '\\', '\\')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '\'')
+}
+
+// appendCopyValue appends v to buf as a value of the text format of COPY,
+// with \N for NULL
+func appendCopyValue(buf []byte, v interface{}) []byte {
+	var f float64
+	switch x := v.(type) {
+	case nil:
+		return append(buf, `\N`...)
+	case []byte:
+		return appendCopyEscaped(buf, x)
+	case string:
+		return appendCopyEscaped(buf, []byte(x))
+	case bool:
+		if x {
+			return append(buf, 't')
+		}
+		return append(buf, 'f')
+	case float64:
+		f = x
+	case float32:
+		f = float64(x)
+	default:
+		return serialize.FastFormatAppend(v, buf)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return append(buf, strings.Trim(nonFinite(f), "'")...)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// appendCopyEscaped appends s to buf, escaping backslashes and the bytes
+// COPY takes as separators with a backslash
+func appendCopyEscaped(buf, s []byte) []byte {
+	for _, c := range s {
+		switch c {
+		case '\\':
+			buf = append(buf, `\\`...)
+		case '\t':
+			buf = append(buf, `\t`...)
+		case '\n':
+			buf = append(buf, `\n`...)
+		case '\r':
+			buf = append(buf, `\r`...)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
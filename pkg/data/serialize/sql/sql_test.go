@@ -0,0 +1,287 @@
+package sql
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const (
+	testColumns = `"time", "hostname", "region", "datacenter", "big_usage_guest", "usage_guest", "usage_guest_nice"`
+	testPrefix  = `INSERT INTO "cpu" (` + testColumns + ") VALUES\n('2016-01-01 00:00:00+00', 'host_0', 'eu-west-1', 'eu-west-1b', "
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     testPrefix + "5000000000, 38, 38.24311829);\n",
+	},
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     testPrefix + "NULL, NULL, 38.24311829);\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     testPrefix + "NULL, 38, NULL);\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `INSERT INTO "cpu" (` + testColumns + ") VALUES\n('2016-01-01 00:00:00+00', NULL, NULL, NULL, NULL, NULL, 38.24311829);\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format+":create=false", schema, w)
+		},
+		Golden: serializeCases,
+		// statements are only written once they have their rows, or on
+		// Close
+		IgnoresWriter: true,
+	}.Run(t)
+}
+
+func TestSerializerConformanceOptions(t *testing.T) {
+	for _, options := range []string{"batch=1", "dialect=mysql,batch=1", "dialect=sqlite,batch=1", "dialect=ansi,batch=1", "mode=copy,batch=1"} {
+		t.Run(options, func(t *testing.T) {
+			serializetest.Suite{
+				New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+					return serialize.New(Format+":"+options, schema, w)
+				},
+			}.Run(t)
+		})
+	}
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func TestSerializerOptions(t *testing.T) {
+	schema := serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname"), []byte("region")},
+		map[string][][]byte{"disk": {[]byte("path")}},
+		map[string][][]byte{
+			"cpu":  {[]byte("usage_user"), []byte("usage_system")},
+			"disk": {[]byte("free"), []byte("mount"), []byte("ok")},
+		},
+		map[string][]serialize.FieldType{
+			"cpu":  {serialize.FieldTypeInt, serialize.FieldTypeUnknown},
+			"disk": {serialize.FieldTypeInt, serialize.FieldTypeString, serialize.FieldTypeBool},
+		},
+	)
+	points := []*serialize.Point{
+		newPoint("cpu", 1451606400123456789, []string{"hostname", "host_0", "region", "eu-west-1"}, "usage_user", int64(58), "usage_system", 2.5),
+		newPoint("disk", 1451606400000000000, []string{"region", "", "hostname", "host_0", "path", "/"}, "free", int64(7), "mount", `/mnt/'a'\b`+"\t", "ok", true),
+		newPoint("cpu", 1451606410000000000, []string{"hostname", "host_1"}, "usage_system", math.NaN()),
+	}
+	cases := []struct {
+		desc    string
+		options string
+		want    string
+	}{
+		{
+			desc:    "default",
+			options: "batch=2",
+			want: `CREATE TABLE IF NOT EXISTS "cpu" ("time" TIMESTAMPTZ NOT NULL, "hostname" TEXT, "region" TEXT, "usage_user" BIGINT, "usage_system" DOUBLE PRECISION);` + "\n" +
+				`CREATE TABLE IF NOT EXISTS "disk" ("time" TIMESTAMPTZ NOT NULL, "hostname" TEXT, "region" TEXT, "path" TEXT, "free" BIGINT, "mount" TEXT, "ok" BOOLEAN);` + "\n" +
+				`INSERT INTO "cpu" ("time", "hostname", "region", "usage_user", "usage_system") VALUES` + "\n" +
+				`('2016-01-01 00:00:00.123456+00', 'host_0', 'eu-west-1', 58, 2.5),` + "\n" +
+				`('2016-01-01 00:00:10+00', 'host_1', NULL, NULL, 'NaN');` + "\n" +
+				`INSERT INTO "disk" ("time", "hostname", "region", "path", "free", "mount", "ok") VALUES` + "\n" +
+				`('2016-01-01 00:00:00+00', 'host_0', NULL, '/', 7, '/mnt/''a''\b` + "\t', TRUE);\n",
+		},
+		{
+			desc:    "mysql",
+			options: "dialect=mysql,table=tsbs_{measurement},create=false",
+			want: "INSERT INTO `tsbs_cpu` (`time`, `hostname`, `region`, `usage_user`, `usage_system`) VALUES\n" +
+				`('2016-01-01 00:00:00.123456', 'host_0', 'eu-west-1', 58, 2.5),` + "\n" +
+				`('2016-01-01 00:00:10', 'host_1', NULL, NULL, NULL);` + "\n" +
+				"INSERT INTO `tsbs_disk` (`time`, `hostname`, `region`, `path`, `free`, `mount`, `ok`) VALUES\n" +
+				`('2016-01-01 00:00:00', 'host_0', NULL, '/', 7, '/mnt/''a''\\b` + "\t', TRUE);\n",
+		},
+		{
+			desc:    "sqlite",
+			options: "dialect=sqlite,batch=1",
+			want: `CREATE TABLE IF NOT EXISTS "cpu" ("time" TEXT NOT NULL, "hostname" TEXT, "region" TEXT, "usage_user" INTEGER, "usage_system" REAL);` + "\n" +
+				`CREATE TABLE IF NOT EXISTS "disk" ("time" TEXT NOT NULL, "hostname" TEXT, "region" TEXT, "path" TEXT, "free" INTEGER, "mount" TEXT, "ok" INTEGER);` + "\n" +
+				`INSERT INTO "cpu" ("time", "hostname", "region", "usage_user", "usage_system") VALUES` + "\n" +
+				`('2016-01-01 00:00:00.123456', 'host_0', 'eu-west-1', 58, 2.5);` + "\n" +
+				`INSERT INTO "disk" ("time", "hostname", "region", "path", "free", "mount", "ok") VALUES` + "\n" +
+				`('2016-01-01 00:00:00', 'host_0', NULL, '/', 7, '/mnt/''a''\b` + "\t', 1);\n" +
+				`INSERT INTO "cpu" ("time", "hostname", "region", "usage_user", "usage_system") VALUES` + "\n" +
+				`('2016-01-01 00:00:10', 'host_1', NULL, NULL, NULL);` + "\n",
+		},
+		{
+			desc:    "ansi",
+			options: "dialect=ansi,table=t_{measurement}_{measurement}",
+			want: `CREATE TABLE "t_cpu_cpu" ("time" TIMESTAMP NOT NULL, "hostname" VARCHAR(255), "region" VARCHAR(255), "usage_user" BIGINT, "usage_system" DOUBLE PRECISION);` + "\n" +
+				`CREATE TABLE "t_disk_disk" ("time" TIMESTAMP NOT NULL, "hostname" VARCHAR(255), "region" VARCHAR(255), "path" VARCHAR(255), "free" BIGINT, "mount" VARCHAR(255), "ok" BOOLEAN);` + "\n" +
+				`INSERT INTO "t_cpu_cpu" ("time", "hostname", "region", "usage_user", "usage_system") VALUES` + "\n" +
+				`(TIMESTAMP '2016-01-01 00:00:00.123456', 'host_0', 'eu-west-1', 58, 2.5),` + "\n" +
+				`(TIMESTAMP '2016-01-01 00:00:10', 'host_1', NULL, NULL, NULL);` + "\n" +
+				`INSERT INTO "t_disk_disk" ("time", "hostname", "region", "path", "free", "mount", "ok") VALUES` + "\n" +
+				`(TIMESTAMP '2016-01-01 00:00:00', 'host_0', NULL, '/', 7, '/mnt/''a''\b` + "\t', TRUE);\n",
+		},
+		{
+			desc:    "copy",
+			options: "mode=copy,create=false",
+			want: `COPY "cpu" ("time", "hostname", "region", "usage_user", "usage_system") FROM STDIN;` + "\n" +
+				"2016-01-01 00:00:00.123456+00\thost_0\teu-west-1\t58\t2.5\n" +
+				"2016-01-01 00:00:10+00\thost_1\t\\N\t\\N\tNaN\n" +
+				`\.` + "\n" +
+				`COPY "disk" ("time", "hostname", "region", "path", "free", "mount", "ok") FROM STDIN;` + "\n" +
+				"2016-01-01 00:00:00+00\thost_0\t\\N\t/\t7\t/mnt/'a'\\\\b\\t\tt\n" +
+				`\.` + "\n",
+		},
+	}
+	for _, c := range cases {
+		opts, err := ParseOptions(c.options)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		for _, batch := range []bool{false, true} {
+			var buf bytes.Buffer
+			s, err := NewSerializer(schema, opts, &buf)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", c.desc, err)
+			}
+			if batch {
+				b := serialize.NewPointBatch()
+				for _, p := range points {
+					b.Append(p)
+				}
+				err = s.SerializeBatch(b, &buf)
+			} else {
+				for _, p := range points {
+					if err = s.Serialize(p, &buf); err != nil {
+						break
+					}
+				}
+			}
+			if err == nil {
+				err = s.Close()
+			}
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", c.desc, err)
+			}
+			if got := buf.String(); got != c.want {
+				t.Errorf("%s (batch %t): incorrect output:\ngot  %s\nwant %s", c.desc, batch, got, c.want)
+			}
+		}
+	}
+}
+
+func TestSerializerNoColumn(t *testing.T) {
+	schema := serialize.NewTaggedSchema(
+		[][]byte{[]byte("hostname")},
+		map[string][][]byte{"disk": {[]byte("path")}},
+		map[string][][]byte{
+			"cpu":  {[]byte("usage_user")},
+			"disk": {[]byte("free")},
+		},
+		nil,
+	)
+	cases := []struct {
+		desc string
+		p    *serialize.Point
+		want string
+	}{
+		{desc: "tag", p: newPoint("cpu", 0, []string{"hostname", "host_0", "zone", "a"}, "usage_user", 1.0), want: "tag zone of cpu has no column"},
+		{desc: "tag of another table", p: newPoint("cpu", 0, []string{"path", "/"}, "usage_user", 1.0), want: "tag path of cpu has no column"},
+		{desc: "field", p: newPoint("cpu", 0, nil, "usage_user", 1.0, "usage_idle", 2.0), want: "field usage_idle of cpu has no column"},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		s, err := NewSerializer(schema, DefaultOptions(), &buf)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.desc, err)
+		}
+		buf.Reset()
+		if err := s.Serialize(c.p, &buf); err == nil || err.Error() != c.want {
+			t.Errorf("%s: incorrect error: got %v want %s", c.desc, err, c.want)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatalf("%s: unexpected error closing: %v", c.desc, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: rejected row written: %q", c.desc, buf.Bytes())
+		}
+	}
+}
+
+func TestSerializerNoSchema(t *testing.T) {
+	var buf bytes.Buffer
+	s, err := NewSerializer(nil, DefaultOptions(), &buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, p := range []*serialize.Point{
+		newPoint("cpu", 0, []string{"a", "1"}, "x", int64(1)),
+		newPoint("cpu", 0, []string{"a", "2"}, "x", int64(2), "y", false),
+		newPoint("cpu", 0, []string{"a", "3"}, "x", int64(3)),
+	} {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `INSERT INTO "cpu" ("time", "a", "x") VALUES` + "\n" +
+		`('1970-01-01 00:00:00+00', '1', 1),` + "\n" +
+		`('1970-01-01 00:00:00+00', '3', 3);` + "\n" +
+		`INSERT INTO "cpu" ("time", "a", "x", "y") VALUES` + "\n" +
+		`('1970-01-01 00:00:00+00', '2', 2, FALSE);` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("dialect=postgres,mode=copy,table=x_{measurement},batch=5000,create=0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Options{Dialect: DialectPostgres, Mode: ModeCopy, Table: "x_{measurement}", BatchSize: 5000, Create: false}
+	if opts != want {
+		t.Errorf("incorrect options: got %+v want %+v", opts, want)
+	}
+
+	for _, c := range []struct {
+		options string
+		errMsg  string
+	}{
+		{"dialect=oracle", "invalid SQL dialect 'oracle'"},
+		{"mode=upsert", "invalid SQL mode 'upsert'"},
+		{"dialect=mysql,mode=copy", "SQL mode copy requires dialect postgres"},
+		{"table=metrics", "invalid SQL table 'metrics': must have {measurement} in it"},
+		{"batch=0", "invalid SQL batch '0'"},
+		{"create=maybe", "invalid SQL create 'maybe'"},
+		{"transaction=true", "unknown SQL option 'transaction'"},
+		{"mysql", "invalid SQL option 'mysql'"},
+	} {
+		if _, err := ParseOptions(c.options); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.options, err, c.errMsg)
+		}
+	}
+}
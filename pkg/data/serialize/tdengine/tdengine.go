@@ -0,0 +1,380 @@
+// Package tdengine implements the format for TDengine: SQL statements
+// creating the database, a super table per measurement, with the tags of
+// its readings as the tags of its child tables, and an INSERT statement per
+// reading into the child table of its series, created by the INSERT as
+// needed, as taos -f runs them.
+package tdengine
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under, which writes the
+// statements with DefaultOptions. Formats of the form Format + ":" +
+// options, e.g., tdengine:db=devops,precision=ns, write them with options,
+// see ParseOptions.
+const Format = "tdengine"
+
+// Precisions of the database, and of the timestamps of the readings:
+// milliseconds, microseconds or nanoseconds
+const (
+	PrecisionMilliseconds = "ms"
+	PrecisionMicroseconds = "us"
+	PrecisionNanoseconds  = "ns"
+)
+
+// precisions are the length of the units of each precision, in nanoseconds
+var precisions = map[string]int64{
+	PrecisionMilliseconds: 1e6,
+	PrecisionMicroseconds: 1e3,
+	PrecisionNanoseconds:  1,
+}
+
+// DefaultDB is the database of Format
+const DefaultDB = "benchmark"
+
+// TimeColumn is the first column of every table, of the time of each
+// reading, which TDengine requires to be a TIMESTAMP
+const TimeColumn = "ts"
+
+// TagLength is the length of the VARCHAR of tags, and of fields of strings
+const TagLength = 256
+
+func init() {
+	serialize.Describe(Format, "TDengine SQL CREATE STABLE statements and INSERT statements into child tables of each series, with tdengine:db=<name>,precision=<ms|us|ns>")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(schema, DefaultOptions(), w)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		opts, err := ParseOptions(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewSerializer(schema, opts, w)
+	})
+}
+
+// Options are the options of the format
+type Options struct {
+	// DB is the database the statements create and use
+	DB string
+	// Precision is PrecisionMilliseconds, PrecisionMicroseconds or
+	// PrecisionNanoseconds
+	Precision string
+}
+
+// DefaultOptions returns the options of Format: the DefaultDB, with the
+// precision of milliseconds, the default of TDengine
+func DefaultOptions() Options {
+	return Options{DB: DefaultDB, Precision: PrecisionMilliseconds}
+}
+
+// ParseOptions returns the DefaultOptions changed by s, a comma separated
+// list of option=value, of the options:
+//   - db: the name of the database
+//   - precision: ms, us or ns
+func ParseOptions(s string) (Options, error) {
+	opts := DefaultOptions()
+	for _, option := range strings.Split(s, ",") {
+		i := strings.IndexByte(option, '=')
+		if i < 0 {
+			return opts, fmt.Errorf("invalid TDengine option '%s': must be option=value", option)
+		}
+		name, value := option[:i], option[i+1:]
+		switch name {
+		case "db":
+			if len(value) == 0 {
+				return opts, fmt.Errorf("invalid TDengine db: must not be empty")
+			}
+			opts.DB = value
+		case "precision":
+			if _, ok := precisions[value]; !ok {
+				return opts, fmt.Errorf("invalid TDengine precision '%s': must be %s, %s or %s", value, PrecisionMilliseconds, PrecisionMicroseconds, PrecisionNanoseconds)
+			}
+			opts.Precision = value
+		default:
+			return opts, fmt.Errorf("unknown TDengine option '%s': must be db or precision", name)
+		}
+	}
+	return opts, nil
+}
+
+// Serializer writes Points as SQL statements of TDengine
+type Serializer struct {
+	schema *serialize.Schema
+	// unit is the length of the units of the timestamps, in nanoseconds
+	unit int64
+	// created are the measurements whose tables were created
+	created map[string]bool
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// NewSerializer returns a Serializer with the given options, writing the
+// statements creating and using the database to w:
+//
+// CREATE DATABASE IF NOT EXISTS `benchmark` PRECISION 'ms';
+// USE `benchmark`;
+//
+// schema, which may be nil, gives the types of the columns of fields, and
+// fields of unknown type are numbers from the simulators, which are DOUBLE.
+func NewSerializer(schema *serialize.Schema, opts Options, w io.Writer) (*Serializer, error) {
+	if len(opts.DB) == 0 {
+		opts.DB = DefaultDB
+	}
+	if len(opts.Precision) == 0 {
+		opts.Precision = PrecisionMilliseconds
+	}
+	unit, ok := precisions[opts.Precision]
+	if !ok {
+		return nil, fmt.Errorf("invalid TDengine precision '%s': must be %s, %s or %s", opts.Precision, PrecisionMilliseconds, PrecisionMicroseconds, PrecisionNanoseconds)
+	}
+	buf := append([]byte("CREATE DATABASE IF NOT EXISTS "), appendName(nil, []byte(opts.DB))...)
+	buf = append(buf, " PRECISION '"+opts.Precision+"';\nUSE "...)
+	buf = appendName(buf, []byte(opts.DB))
+	buf = append(buf, ";\n"...)
+	if _, err := w.Write(buf); err != nil {
+		return nil, err
+	}
+	return &Serializer{schema: schema, unit: unit, created: make(map[string]bool)}, nil
+}
+
+// Serialize writes Point p to w as an INSERT statement into the child table
+// of its series, which is created by the statement if it does not exist,
+// after a statement creating the super table of its measurement, if it is
+// the first reading of the measurement:
+//
+// CREATE STABLE IF NOT EXISTS `cpu` (`ts` TIMESTAMP,`usage_user` BIGINT,...) TAGS (`hostname` VARCHAR(256),...);
+// INSERT INTO `cpu_0123456789abcdef` USING `cpu` (`hostname`,...) TAGS ('host_0',...) (`ts`,`usage_user`,...) VALUES (1451606400000,58,...);
+//
+// The columns of the super table are those of the first reading of its
+// measurement, and the child table of a series is named by its measurement
+// and the hash of its tags. A measurement whose first reading has no tags,
+// which a super table must have, is a regular table, which the readings are
+// inserted into. Empty tags and fields without values, or with NaN or
+// infinite values, which TDengine does not store, are NULL.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendStatements(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes the statements of all Points of a PointBatch to the
+// given writer in the same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendStatements(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendStatements(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	if !s.created[string(measurementName)] {
+		s.created[string(measurementName)] = true
+		buf = s.appendCreate(buf, measurementName, tagKeys, fieldKeys, fieldValues)
+	}
+	buf = append(buf, "INSERT INTO "...)
+	if len(tagKeys) == 0 {
+		buf = appendName(buf, measurementName)
+	} else {
+		buf = appendChildName(buf, measurementName, tagKeys, tagValues)
+		buf = append(buf, " USING "...)
+		buf = appendName(buf, measurementName)
+		buf = append(buf, " ("...)
+		for i, key := range tagKeys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendName(buf, key)
+		}
+		buf = append(buf, ") TAGS ("...)
+		for i, v := range tagValues {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			if len(v) == 0 {
+				buf = append(buf, "NULL"...)
+			} else {
+				buf = appendString(buf, v)
+			}
+		}
+		buf = append(buf, ')')
+	}
+	buf = append(buf, " (`"+TimeColumn+"`"...)
+	for _, key := range fieldKeys {
+		buf = append(buf, ',')
+		buf = appendName(buf, key)
+	}
+	buf = append(buf, ") VALUES ("...)
+	// floor, so readings before the epoch are not rounded up to it
+	t := timestamp / s.unit
+	if timestamp%s.unit < 0 {
+		t--
+	}
+	buf = strconv.AppendInt(buf, t, 10)
+	for _, v := range fieldValues {
+		buf = append(buf, ',')
+		buf = appendValue(buf, v)
+	}
+	return append(buf, ");\n"...)
+}
+
+// appendCreate appends the statement creating the table of a measurement,
+// with the columns of its first reading, to buf
+func (s *Serializer) appendCreate(buf []byte, measurementName []byte, tagKeys, fieldKeys [][]byte, fieldValues []interface{}) []byte {
+	if len(tagKeys) == 0 {
+		buf = append(buf, "CREATE TABLE IF NOT EXISTS "...)
+	} else {
+		buf = append(buf, "CREATE STABLE IF NOT EXISTS "...)
+	}
+	buf = appendName(buf, measurementName)
+	buf = append(buf, " (`"+TimeColumn+"` TIMESTAMP"...)
+	var fieldTypes []serialize.FieldType
+	var schemaKeys [][]byte
+	if s.schema != nil {
+		fieldTypes, schemaKeys = s.schema.FieldTypes(string(measurementName)), s.schema.FieldKeys(string(measurementName))
+	}
+	for i, key := range fieldKeys {
+		typ := serialize.FieldTypeUnknown
+		for j, k := range schemaKeys {
+			if string(k) == string(key) && j < len(fieldTypes) {
+				typ = fieldTypes[j]
+			}
+		}
+		buf = append(buf, ',')
+		buf = appendName(buf, key)
+		buf = append(buf, ' ')
+		buf = append(buf, columnType(typ, fieldValues[i])...)
+	}
+	buf = append(buf, ')')
+	if len(tagKeys) > 0 {
+		buf = append(buf, " TAGS ("...)
+		for i, key := range tagKeys {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = appendName(buf, key)
+			buf = append(buf, " VARCHAR("+strconv.Itoa(TagLength)+")"...)
+		}
+		buf = append(buf, ')')
+	}
+	return append(buf, ";\n"...)
+}
+
+// columnType returns the type of TDengine of the column of a field of type
+// t, or, if it is not known, whose first value is v: BIGINT for ints,
+// DOUBLE for floats and numbers of unknown type, BOOL for bools and VARCHAR
+// for strings
+func columnType(t serialize.FieldType, v interface{}) string {
+	switch t {
+	case serialize.FieldTypeInt:
+		return "BIGINT"
+	case serialize.FieldTypeFloat:
+		return "DOUBLE"
+	case serialize.FieldTypeBool:
+		return "BOOL"
+	case serialize.FieldTypeString:
+		return "VARCHAR(" + strconv.Itoa(TagLength) + ")"
+	}
+	switch v.(type) {
+	case bool:
+		return "BOOL"
+	case string, []byte:
+		return "VARCHAR(" + strconv.Itoa(TagLength) + ")"
+	}
+	return "DOUBLE"
+}
+
+// appendChildName appends the name of the child table of a series to buf:
+// its measurement, an underscore and the FNV-1a hash of its tags in hex,
+// e.g., cpu_0123456789abcdef
+func appendChildName(buf []byte, measurementName []byte, tagKeys, tagValues [][]byte) []byte {
+	h := fnv.New64a()
+	sep := []byte{0}
+	for i, k := range tagKeys {
+		h.Write(k)
+		h.Write(sep)
+		h.Write(tagValues[i])
+		h.Write(sep)
+	}
+	name := append(append([]byte(nil), measurementName...), '_')
+	name = append(name, fmt.Sprintf("%016x", h.Sum64())...)
+	return appendName(buf, name)
+}
+
+// appendName appends name to buf as an identifier of TDengine, quoted with
+// backticks so it may be a keyword: any characters other than letters,
+// digits and underscores, which TDengine does not allow in names, are
+// replaced by underscores, and names starting with a digit, or empty, are
+// prefixed by one
+func appendName(buf []byte, name []byte) []byte {
+	buf = append(buf, '`')
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		buf = append(buf, '_')
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			c = '_'
+		}
+		buf = append(buf, c)
+	}
+	return append(buf, '`')
+}
+
+// appendValue appends a field value to buf as a value of TDengine
+func appendValue(buf []byte, v interface{}) []byte {
+	var f float64
+	switch x := v.(type) {
+	case nil:
+		return append(buf, "NULL"...)
+	case []byte:
+		return appendString(buf, x)
+	case string:
+		return appendString(buf, []byte(x))
+	case float64:
+		f = x
+	case float32:
+		f = float64(x)
+	default:
+		return serialize.FastFormatAppend(v, buf)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return append(buf, "NULL"...)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+// appendString appends s to buf as a string of TDengine, in single quotes,
+// with quotes and backslashes escaped by backslashes and line breaks
+// written as \n and \r
+func appendString(buf []byte, s []byte) []byte {
+	buf = append(buf, '\'')
+	for _, c := range s {
+		switch c {
+		case '\'', '\\':
+			buf = append(buf, '\\', c)
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '\'')
+}
@@ -0,0 +1,161 @@
+package tdengine
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const (
+	testTags   = " TAGS (`hostname` VARCHAR(256),`region` VARCHAR(256),`datacenter` VARCHAR(256));\n"
+	testPrefix = "INSERT INTO `cpu_89235386cf58076a` USING `cpu` (`hostname`,`region`,`datacenter`) TAGS ('host_0','eu-west-1','eu-west-1b') "
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output: "CREATE STABLE IF NOT EXISTS `cpu` (`ts` TIMESTAMP,`usage_guest_nice` DOUBLE)" + testTags +
+			testPrefix + "(`ts`,`usage_guest_nice`) VALUES (1451606400000,38.24311829);\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output: "CREATE STABLE IF NOT EXISTS `cpu` (`ts` TIMESTAMP,`usage_guest` DOUBLE)" + testTags +
+			testPrefix + "(`ts`,`usage_guest`) VALUES (1451606400000,38);\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: "CREATE STABLE IF NOT EXISTS `cpu` (`ts` TIMESTAMP,`big_usage_guest` DOUBLE,`usage_guest` DOUBLE,`usage_guest_nice` DOUBLE)" + testTags +
+			testPrefix + "(`ts`,`big_usage_guest`,`usage_guest`,`usage_guest_nice`) VALUES (1451606400000,5000000000,38,38.24311829);\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output: "CREATE TABLE IF NOT EXISTS `cpu` (`ts` TIMESTAMP,`usage_guest_nice` DOUBLE);\n" +
+			"INSERT INTO `cpu` (`ts`,`usage_guest_nice`) VALUES (1451606400000,38.24311829);\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func newPoint(measurement string, timestamp int64, tags []string, fields ...interface{}) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte(measurement))
+	p.SetTimestamp(timestamp)
+	for i := 0; i < len(tags); i += 2 {
+		p.AppendTag([]byte(tags[i]), []byte(tags[i+1]))
+	}
+	for i := 0; i < len(fields); i += 2 {
+		p.AppendField([]byte(fields[i].(string)), fields[i+1])
+	}
+	return p
+}
+
+func TestSerializerStatements(t *testing.T) {
+	schema := serialize.NewTypedSchema(
+		[][]byte{[]byte("hostname")},
+		map[string][][]byte{
+			"disk": {[]byte("used"), []byte("ok"), []byte("label")},
+		},
+		map[string][]serialize.FieldType{
+			"disk": {serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString},
+		},
+	)
+	b := new(bytes.Buffer)
+	s, err := NewSerializer(schema, Options{DB: "devops", Precision: PrecisionNanoseconds}, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	points := []*serialize.Point{
+		newPoint("disk", -1, []string{"hostname", "host_0", "path", "/dev/sda"}, "used", int64(3), "ok", true, "label", "it's\n"),
+		newPoint("disk", 1, []string{"hostname", "", "path", "/dev/sda"}, "used", nil, "ok", false, "weight", math.NaN()),
+	}
+	for _, p := range points {
+		if err := s.Serialize(p, b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	want := "CREATE DATABASE IF NOT EXISTS `devops` PRECISION 'ns';\nUSE `devops`;\n" +
+		"CREATE STABLE IF NOT EXISTS `disk` (`ts` TIMESTAMP,`used` BIGINT,`ok` BOOL,`label` VARCHAR(256)) TAGS (`hostname` VARCHAR(256),`path` VARCHAR(256));\n"
+	if got := b.String(); !strings.HasPrefix(got, want) {
+		t.Fatalf("incorrect statements: got\n%s\nwant them to start with\n%s", got, want)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	for i, want := range []string{
+		"TAGS ('host_0','/dev/sda') (`ts`,`used`,`ok`,`label`) VALUES (-1,3,true,'it\\'s\\n');",
+		"TAGS (NULL,'/dev/sda') (`ts`,`used`,`ok`,`weight`) VALUES (1,NULL,false,NULL);",
+	} {
+		if got := lines[3+i]; !strings.HasSuffix(got, want) {
+			t.Errorf("incorrect INSERT statement %d: got %s want it to end with %s", i, got, want)
+		}
+	}
+	if lines[3][:34] == lines[4][:34] {
+		t.Errorf("same child table of different series: %s", lines[3][:34])
+	}
+}
+
+func TestSerializerPrecision(t *testing.T) {
+	for precision, want := range map[string]string{
+		PrecisionMilliseconds: "VALUES (-1000,",
+		PrecisionMicroseconds: "VALUES (-1000000,",
+		PrecisionNanoseconds:  "VALUES (-999999999,",
+	} {
+		b := new(bytes.Buffer)
+		s, err := NewSerializer(nil, Options{Precision: precision}, b)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := s.Serialize(newPoint("cpu", -999999999, nil, "usage_user", 1.5), b); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("%s: incorrect timestamp: got\n%s\nwant %s", precision, b.String(), want)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("db=devops,precision=us")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (Options{DB: "devops", Precision: PrecisionMicroseconds}); opts != want {
+		t.Errorf("incorrect options: got %+v want %+v", opts, want)
+	}
+
+	for _, c := range []struct {
+		options string
+		errMsg  string
+	}{
+		{"db=", "invalid TDengine db"},
+		{"precision=s", "invalid TDengine precision 's': must be ms, us or ns"},
+		{"vgroups=4", "unknown TDengine option 'vgroups'"},
+		{"devops", "invalid TDengine option 'devops'"},
+	} {
+		if _, err := ParseOptions(c.options); err == nil || !strings.Contains(err.Error(), c.errMsg) {
+			t.Errorf("%s: incorrect error: got %v want %s", c.options, err, c.errMsg)
+		}
+	}
+}
+
+func TestAppendName(t *testing.T) {
+	for name, want := range map[string]string{"cpu": "`cpu`", "inodes-free": "`inodes_free`", "9lives": "`_9lives`", "": "`_`"} {
+		if got := string(appendName(nil, []byte(name))); got != want {
+			t.Errorf("incorrect name of %q: got %s want %s", name, got, want)
+		}
+	}
+}
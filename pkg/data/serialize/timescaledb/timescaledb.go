@@ -0,0 +1,88 @@
+package timescaledb
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "timescaledb"
+
+func init() {
+	serialize.Describe(Format, "TimescaleDB rows of tags and fields, after a header of the tables to create")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		if err := writeHeader(schema, w); err != nil {
+			return nil, err
+		}
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_timescaledb and tsbs_load_postgres
+	serialize.MarkHeaderFormat(Format)
+}
+
+// writeHeader writes the header the TimescaleDB loader uses to create its
+// tables: the tag keys, followed by each measurement and its field keys, and
+// then an empty line
+func writeHeader(schema *serialize.Schema, w io.Writer) error {
+	buf := []byte("tags")
+	for _, key := range schema.TagKeys() {
+		buf = append(buf, ',')
+		buf = append(buf, key...)
+	}
+	buf = append(buf, '\n')
+	// the schema's measurements are sorted so the header is deterministic
+	for _, measurementName := range schema.Measurements() {
+		buf = append(buf, measurementName...)
+		for _, field := range schema.FieldKeys(measurementName) {
+			buf = append(buf, ',')
+			buf = append(buf, field...)
+		}
+		buf = append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	return err
+}
+
+// Serializer writes a Point in a serialized form for TimescaleDB
+type Serializer struct{}
+
+// Serialize writes Point p to the given Writer w, so it can be
+// loaded by the TimescaleDB loader. The format is CSV with two lines per Point,
+// with the first row being the tags and the second row being the field values.
+//
+// e.g.,
+// tags,<tag1>,<tag2>,<tag3>,...
+// <measurement>,<timestamp>,<field1>,<field2>,<field3>,...
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	// Tag row first, prefixed with name 'tags'
+	buf := make([]byte, 0, 256)
+	buf = append(buf, []byte("tags")...)
+	for i, v := range p.TagValues() {
+		buf = append(buf, ',')
+		buf = append(buf, p.TagKeys()[i]...)
+		buf = append(buf, '=')
+		buf = append(buf, v...)
+	}
+	buf = append(buf, '\n')
+	_, err := w.Write(buf)
+	if err != nil {
+		return err
+	}
+
+	// Field row second
+	buf = make([]byte, 0, 256)
+	buf = append(buf, p.MeasurementName()...)
+	buf = append(buf, ',')
+	buf = strconv.AppendInt(buf, p.Timestamp(), 10)
+
+	for _, v := range p.FieldValues() {
+		buf = append(buf, ',')
+		buf = serialize.FastFormatAppend(v, buf)
+	}
+	buf = append(buf, '\n')
+	_, err = w.Write(buf)
+	return err
+}
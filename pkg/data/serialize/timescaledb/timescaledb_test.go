@@ -0,0 +1,76 @@
+package timescaledb
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "tags,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b\ncpu,1451606400000000000,38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "tags,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b\ncpu,1451606400000000000,38\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     "tags,hostname=host_0,region=eu-west-1,datacenter=eu-west-1b\ncpu,1451606400000000000,5000000000,38,38.24311829\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "tags\ncpu,1451606400000000000,38.24311829\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerSerializeErr(t *testing.T) {
+	p := serializetest.PointMultiField
+	s := &Serializer{}
+	err := s.Serialize(p, &serializetest.ErrWriter{})
+	if err == nil {
+		t.Errorf("no error returned when expected")
+	} else if err.Error() != serializetest.ErrWriterAlwaysErr {
+		t.Errorf("unexpected writer error: %v", err)
+	}
+}
+
+func TestRegisteredWithHeader(t *testing.T) {
+	schema := serialize.NewSchema(serializetest.TagKeys, map[string][][]byte{
+		"mem": {[]byte("used"), []byte("free")},
+		"cpu": {serializetest.ColFloat},
+	})
+	b := new(bytes.Buffer)
+	ps, err := serialize.New(Format, schema, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := ps.(*Serializer); !ok {
+		t.Errorf("incorrect serializer type: got %T", ps)
+	}
+	want := "tags,hostname,region,datacenter\ncpu,usage_guest_nice\nmem,used,free\n\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect header: got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package timestream
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Format is the name the format is registered under
+const Format = "timestream"
+
+func init() {
+	serialize.Describe(Format, "Amazon Timestream multi-measure records as JSON, one per line")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+	// the Header is checked by tsbs_load_timestream
+	serialize.MarkHeaderFormat(Format)
+}
+
+// Serializer writes a Point in a serialized form for Amazon Timestream
+type Serializer struct {
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// Serialize writes Point p to w as a Timestream multi-measure record: its
+// tags are the dimensions, its measurement the measure name and its fields
+// the measure values. The record is JSON in the form the WriteRecords API
+// takes, so the loader sends the lines as they are, e.g.:
+//
+// {"Dimensions":[{"Name":"hostname","Value":"host_0"}],"MeasureName":"cpu","MeasureValueType":"MULTI","MeasureValues":[{"Name":"usage_user","Value":"58","Type":"BIGINT"}],"Time":"1451606400000000000","TimeUnit":"NANOSECONDS"}
+//
+// Tags with empty values are left out, since Timestream has no empty
+// dimension values.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := appendRecord(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all rows of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = appendRecord(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func appendRecord(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	buf = append(buf, `{"Dimensions":[`...)
+	first := true
+	for i, v := range tagValues {
+		if len(v) == 0 {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, `{"Name":`...)
+		buf = serialize.AppendJSONString(buf, tagKeys[i])
+		buf = append(buf, `,"Value":`...)
+		buf = serialize.AppendJSONString(buf, v)
+		buf = append(buf, '}')
+	}
+	buf = append(buf, `],"MeasureName":`...)
+	buf = serialize.AppendJSONString(buf, measurementName)
+	buf = append(buf, `,"MeasureValueType":"MULTI","MeasureValues":[`...)
+	for i, v := range fieldValues {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, `{"Name":`...)
+		buf = serialize.AppendJSONString(buf, fieldKeys[i])
+		buf = append(buf, `,"Value":`...)
+		switch s := v.(type) {
+		case []byte:
+			buf = serialize.AppendJSONString(buf, s)
+		case string:
+			buf = serialize.AppendJSONString(buf, []byte(s))
+		default:
+			buf = append(buf, '"')
+			buf = serialize.FastFormatAppend(v, buf)
+			buf = append(buf, '"')
+		}
+		buf = append(buf, `,"Type":"`...)
+		buf = append(buf, measureValueType(v)...)
+		buf = append(buf, `"}`...)
+	}
+	buf = append(buf, `],"Time":"`...)
+	buf = strconv.AppendInt(buf, timestamp, 10)
+	buf = append(buf, `","TimeUnit":"NANOSECONDS"}`...)
+	buf = append(buf, '\n')
+	return buf
+}
+
+// measureValueType returns the Timestream type of a field value
+func measureValueType(v interface{}) string {
+	switch v.(type) {
+	case int, int64:
+		return "BIGINT"
+	case float32, float64:
+		return "DOUBLE"
+	case bool:
+		return "BOOLEAN"
+	default:
+		return "VARCHAR"
+	}
+}
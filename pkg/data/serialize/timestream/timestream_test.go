@@ -0,0 +1,100 @@
+package timestream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testDimensions = `"Dimensions":[{"Name":"hostname","Value":"host_0"},{"Name":"region","Value":"eu-west-1"},{"Name":"datacenter","Value":"eu-west-1b"}]`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `{` + testDimensions + `,"MeasureName":"cpu","MeasureValueType":"MULTI","MeasureValues":[{"Name":"usage_guest_nice","Value":"38.24311829","Type":"DOUBLE"}],"Time":"1451606400000000000","TimeUnit":"NANOSECONDS"}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `{` + testDimensions + `,"MeasureName":"cpu","MeasureValueType":"MULTI","MeasureValues":[{"Name":"usage_guest","Value":"38","Type":"BIGINT"}],"Time":"1451606400000000000","TimeUnit":"NANOSECONDS"}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output:     `{` + testDimensions + `,"MeasureName":"cpu","MeasureValueType":"MULTI","MeasureValues":[{"Name":"big_usage_guest","Value":"5000000000","Type":"BIGINT"},{"Name":"usage_guest","Value":"38","Type":"BIGINT"},{"Name":"usage_guest_nice","Value":"38.24311829","Type":"DOUBLE"}],"Time":"1451606400000000000","TimeUnit":"NANOSECONDS"}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"Dimensions":[],"MeasureName":"cpu","MeasureValueType":"MULTI","MeasureValues":[{"Name":"usage_guest_nice","Value":"38.24311829","Type":"DOUBLE"}],"Time":"1451606400000000000","TimeUnit":"NANOSECONDS"}` + "\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	serializetest.CheckSerializer(t, serializeCases, &Serializer{})
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+// record is a Timestream record as the WriteRecords API takes it
+type record struct {
+	Dimensions []struct {
+		Name  string
+		Value string
+	}
+	MeasureName      string
+	MeasureValueType string
+	MeasureValues    []struct {
+		Name  string
+		Value string
+		Type  string
+	}
+	Time     string
+	TimeUnit string
+}
+
+func TestSerializerValidJSON(t *testing.T) {
+	s := &Serializer{}
+	for i, p := range serializetest.FuzzPoints() {
+		var b bytes.Buffer
+		if err := s.Serialize(p, &b); err != nil {
+			t.Fatalf("point %d: unexpected error: %v", i, err)
+		}
+		var r record
+		if err := json.Unmarshal(b.Bytes(), &r); err != nil {
+			t.Errorf("point %d: invalid JSON %q: %v", i, b.String(), err)
+			continue
+		}
+		for _, d := range r.Dimensions {
+			if d.Value == "" {
+				t.Errorf("point %d: empty dimension %s", i, d.Name)
+			}
+		}
+		// the tag values are the same once decoded
+		j := 0
+		for k, v := range p.TagValues() {
+			if len(v) == 0 {
+				continue
+			}
+			if d := r.Dimensions[j]; d.Name != string(p.TagKeys()[k]) || d.Value != string(v) {
+				t.Errorf("point %d: incorrect dimension: got %s=%q want %s=%q", i, d.Name, d.Value, p.TagKeys()[k], v)
+			}
+			j++
+		}
+		if len(r.MeasureValues) != len(p.FieldKeys()) {
+			t.Errorf("point %d: incorrect number of measure values: got %d want %d", i, len(r.MeasureValues), len(p.FieldKeys()))
+		}
+	}
+}
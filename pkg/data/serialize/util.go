@@ -5,8 +5,8 @@ import (
 	"strconv"
 )
 
-// Utility function for appending various data types to a byte string
-func fastFormatAppend(v interface{}, buf []byte) []byte {
+// FastFormatAppend appends the text form of a field value to a byte string
+func FastFormatAppend(v interface{}, buf []byte) []byte {
 	switch v.(type) {
 	case int:
 		return strconv.AppendInt(buf, int64(v.(int)), 10)
@@ -0,0 +1,275 @@
+// Package victoriametrics implements the format for VictoriaMetrics: the JSON
+// line format of its /api/v1/import endpoint, where each line is a time
+// series with several samples.
+package victoriametrics
+
+import (
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/prometheus"
+)
+
+// Format is the name the format is registered under
+const Format = "victoriametrics"
+
+// DefaultSamplesPerLine is the number of samples of each line, unless set
+// otherwise. Every time series buffers up to that many samples, so it
+// bounds the memory of the Serializer: the devops use case at a scale of
+// 4000 has about 400000 time series.
+const DefaultSamplesPerLine = 10
+
+func init() {
+	serialize.Describe(Format, "VictoriaMetrics /api/v1/import JSON lines, a time series with several samples per line")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return &Serializer{}, nil
+	})
+}
+
+// series is a time series and its samples not written yet
+type series struct {
+	// metric is the start of its lines, up to the values
+	metric     []byte
+	values     []float64
+	timestamps []int64
+}
+
+// Serializer writes Points in a serialized form for VictoriaMetrics. It
+// buffers the samples of each time series until it has a line of them, so
+// it must be closed once all Points are serialized, which writes the lines
+// of the samples left.
+type Serializer struct {
+	// SamplesPerLine is the number of samples of each line, or
+	// DefaultSamplesPerLine if it is not positive
+	SamplesPerLine int
+
+	series []*series
+	// index maps the keys of the time series, their metric name and labels,
+	// to their position in series, which is the order they were first seen
+	index map[string]int
+	// w is the writer the samples left are written to on Close, the one
+	// last given to Serialize or SerializeBatch
+	w io.Writer
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call, and key, metric and names hold the key,
+	// metric name and label names of the time series being looked up
+	buf    []byte
+	key    []byte
+	metric []byte
+	names  []byte
+	ends   []int
+}
+
+// Serialize adds a sample of each numeric or bool field of Point p to its
+// time series, writing a line to w for each of them with SamplesPerLine
+// samples, e.g.:
+//
+// {"metric":{"__name__":"cpu_usage_user","hostname":"host_0","region":"eu-west-1"},"values":[58.13,58.2],"timestamps":[1451606400000,1451606410000]}
+//
+// Metric and label names are those of the prometheus format, i.e., the
+// metric of each time series is named by the measurement and field, and its
+// labels are the tags of the Point with values, with names mapped to valid
+// label names by the same rules. Timestamps are in milliseconds and bools
+// are 1 or 0, while NaN and infinite values, which JSON has no numbers for,
+// are the strings "NaN", "Infinity" and "-Infinity". String fields are left
+// out, as VictoriaMetrics has no such samples.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	s.w = w
+	buf := s.appendRow(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	s.buf = buf
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// SerializeBatch adds the samples of all rows of a PointBatch in the same
+// way as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	s.w = w
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendRow(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	s.buf = buf
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// Close writes the lines of the samples left of every time series, in the
+// order the time series were first seen
+func (s *Serializer) Close() error {
+	buf := s.buf[:0]
+	for _, ts := range s.series {
+		if len(ts.values) > 0 {
+			buf = appendLine(buf, ts)
+		}
+	}
+	s.buf = buf
+	s.series, s.index = nil, nil
+	if len(buf) == 0 || s.w == nil {
+		return nil
+	}
+	_, err := s.w.Write(buf)
+	return err
+}
+
+// appendRow adds the samples of a row to their time series, appending the
+// lines of those that are full to buf
+func (s *Serializer) appendRow(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	if s.index == nil {
+		s.index = make(map[string]int)
+	}
+	samplesPerLine := s.SamplesPerLine
+	if samplesPerLine <= 0 {
+		samplesPerLine = DefaultSamplesPerLine
+	}
+	s.labelNames(tagKeys)
+	millis := timestamp / 1e6
+	for i, v := range fieldValues {
+		f, ok := sampleValue(v)
+		if !ok {
+			continue
+		}
+		s.metric = prometheus.AppendMetricName(s.metric[:0], measurementName, fieldKeys[i])
+		ts := s.lookup(tagValues)
+		ts.values = append(ts.values, f)
+		ts.timestamps = append(ts.timestamps, millis)
+		if len(ts.values) >= samplesPerLine {
+			buf = appendLine(buf, ts)
+			ts.values, ts.timestamps = ts.values[:0], ts.timestamps[:0]
+		}
+	}
+	return buf
+}
+
+// labelNames sets names to the label names of tagKeys, ending at ends
+func (s *Serializer) labelNames(tagKeys [][]byte) {
+	s.names = s.names[:0]
+	s.ends = s.ends[:0]
+	for _, k := range tagKeys {
+		s.names = prometheus.AppendLabelName(s.names, k)
+		s.ends = append(s.ends, len(s.names))
+	}
+}
+
+func (s *Serializer) labelName(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = s.ends[i-1]
+	}
+	return s.names[start:s.ends[i]]
+}
+
+// hasLabel returns whether an earlier tag than i with a value has the same
+// label name as tag i, which then has no label of its own
+func (s *Serializer) hasLabel(i int, tagValues [][]byte) bool {
+	for j := 0; j < i; j++ {
+		if len(tagValues[j]) > 0 && string(s.labelName(j)) == string(s.labelName(i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the time series of metric with the labels of tagValues,
+// adding it if it is new
+func (s *Serializer) lookup(tagValues [][]byte) *series {
+	s.key = append(s.key[:0], s.metric...)
+	for i, v := range tagValues {
+		if len(v) == 0 || s.hasLabel(i, tagValues) {
+			continue
+		}
+		// values are prefixed by their length, as they may have any bytes
+		s.key = append(s.key, 0)
+		s.key = append(s.key, s.labelName(i)...)
+		s.key = append(s.key, 0)
+		s.key = strconv.AppendInt(s.key, int64(len(v)), 10)
+		s.key = append(s.key, ':')
+		s.key = append(s.key, v...)
+	}
+	if i, ok := s.index[string(s.key)]; ok {
+		return s.series[i]
+	}
+
+	metric := append([]byte(nil), `{"metric":{"`+prometheus.NameLabel+`":"`...)
+	metric = append(metric, s.metric...)
+	metric = append(metric, '"')
+	for i, v := range tagValues {
+		if len(v) == 0 || s.hasLabel(i, tagValues) {
+			continue
+		}
+		metric = append(metric, `,"`...)
+		metric = append(metric, s.labelName(i)...)
+		metric = append(metric, `":`...)
+		metric = serialize.AppendJSONString(metric, v)
+	}
+	metric = append(metric, `},"values":[`...)
+	ts := &series{metric: metric}
+	s.index[string(s.key)] = len(s.series)
+	s.series = append(s.series, ts)
+	return ts
+}
+
+// appendLine appends the line of the samples of ts to buf
+func appendLine(buf []byte, ts *series) []byte {
+	buf = append(buf, ts.metric...)
+	for i, v := range ts.values {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONFloat(buf, v)
+	}
+	buf = append(buf, `],"timestamps":[`...)
+	for i, t := range ts.timestamps {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendInt(buf, t, 10)
+	}
+	return append(buf, "]}\n"...)
+}
+
+// sampleValue returns the value of a sample of field value v, and whether
+// it has one
+func sampleValue(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// appendJSONFloat appends f to buf as JSON. Infinite and NaN floats, which
+// JSON has no numbers for, are written as strings.
+func appendJSONFloat(buf []byte, f float64) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(buf, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(buf, `"Infinity"`...)
+	case math.IsInf(f, -1):
+		return append(buf, `"-Infinity"`...)
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, 64)
+}
@@ -0,0 +1,169 @@
+package victoriametrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testLabels = `"hostname":"host_0","region":"eu-west-1","datacenter":"eu-west-1b"`
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     `{"metric":{"__name__":"cpu_usage_guest_nice",` + testLabels + `},"values":[38.24311829],"timestamps":[1451606400000]}` + "\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     `{"metric":{"__name__":"cpu_usage_guest",` + testLabels + `},"values":[38],"timestamps":[1451606400000]}` + "\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: `{"metric":{"__name__":"cpu_big_usage_guest",` + testLabels + `},"values":[5e+09],"timestamps":[1451606400000]}` + "\n" +
+			`{"metric":{"__name__":"cpu_usage_guest",` + testLabels + `},"values":[38],"timestamps":[1451606400000]}` + "\n" +
+			`{"metric":{"__name__":"cpu_usage_guest_nice",` + testLabels + `},"values":[38.24311829],"timestamps":[1451606400000]}` + "\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     `{"metric":{"__name__":"cpu_usage_guest_nice"},"values":[38.24311829],"timestamps":[1451606400000]}` + "\n",
+	},
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+		// samples are only written once a line of them is buffered, or on
+		// Close, see TestSerializerWriteErrors
+		IgnoresWriter: true,
+	}.Run(t)
+}
+
+func newPoint(host string, value interface{}, second int64) *serialize.Point {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("cpu"))
+	p.SetTimestamp(serializetest.Now.UnixNano() + second*1e9)
+	p.AppendTag([]byte("hostname"), []byte(host))
+	p.AppendField([]byte("usage_user"), value)
+	p.AppendField([]byte("state"), "ok")
+	return p
+}
+
+func TestSerializerLines(t *testing.T) {
+	points := []*serialize.Point{
+		newPoint("host_0", 1.5, 0),
+		newPoint("host_1", int64(2), 0),
+		newPoint("host_0", true, 10),
+		newPoint("host_1", 3.0, 10),
+		newPoint("host_0", 4.0, 20),
+	}
+	want := []string{
+		`{"metric":{"__name__":"cpu_usage_user","hostname":"host_0"},"values":[1.5,1],"timestamps":[1451606400000,1451606410000]}`,
+		`{"metric":{"__name__":"cpu_usage_user","hostname":"host_1"},"values":[2,3],"timestamps":[1451606400000,1451606410000]}`,
+	}
+	wantLeft := `{"metric":{"__name__":"cpu_usage_user","hostname":"host_0"},"values":[4],"timestamps":[1451606420000]}`
+
+	for _, batch := range []bool{false, true} {
+		var buf bytes.Buffer
+		s := &Serializer{SamplesPerLine: 2}
+		if batch {
+			b := serialize.NewPointBatch()
+			for _, p := range points {
+				b.Append(p)
+			}
+			if err := s.SerializeBatch(b, &buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		} else {
+			for _, p := range points {
+				if err := s.Serialize(p, &buf); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+		}
+		if got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("batch %v: incorrect lines:\ngot  %q\nwant %q", batch, got, want)
+		}
+		buf.Reset()
+		if err := s.Close(); err != nil {
+			t.Fatalf("unexpected error closing: %v", err)
+		}
+		if got := buf.String(); got != wantLeft+"\n" {
+			t.Errorf("batch %v: incorrect lines left:\ngot  %q\nwant %q", batch, got, wantLeft)
+		}
+	}
+}
+
+func TestSerializerLabels(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk"))
+	p.SetTimestamp(serializetest.Now.UnixNano())
+	p.AppendTag([]byte("host.name"), []byte(`a"b`))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendTag([]byte("__name__"), []byte("c"))
+	p.AppendTag([]byte("host_name"), []byte("d"))
+	p.AppendField([]byte("used.percent"), 1.0)
+
+	var buf bytes.Buffer
+	s := &Serializer{}
+	if err := s.Serialize(p, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	want := `{"metric":{"__name__":"disk_used_percent","host_name":"a\"b","exported___name__":"c"},"values":[1],"timestamps":[1451606400000]}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("incorrect output:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestSerializerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	s := &Serializer{SamplesPerLine: 3}
+	for _, p := range serializetest.FuzzPoints() {
+		if err := s.Serialize(p, &buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		var row struct {
+			Metric     map[string]string
+			Values     []interface{}
+			Timestamps []int64
+		}
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Errorf("line %d: invalid JSON %q: %v", i, line, err)
+		} else if len(row.Values) == 0 || len(row.Values) != len(row.Timestamps) || len(row.Values) > 3 {
+			t.Errorf("line %d: incorrect number of samples: %q", i, line)
+		}
+	}
+}
+
+func TestSerializerWriteErrors(t *testing.T) {
+	s := &Serializer{SamplesPerLine: 1}
+	if err := s.Serialize(serializetest.PointDefault, &serializetest.ErrWriter{}); err == nil {
+		t.Errorf("no error returned when writing a line failed")
+	}
+	s = &Serializer{}
+	if err := s.Serialize(serializetest.PointDefault, &serializetest.ErrWriter{}); err != nil {
+		t.Errorf("unexpected error buffering a sample: %v", err)
+	}
+	if err := s.Close(); err == nil {
+		t.Errorf("no error returned when writing the lines left failed")
+	}
+}
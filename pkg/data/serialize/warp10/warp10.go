@@ -0,0 +1,228 @@
+// Package warp10 implements the format for Warp 10: Geo Time Series input
+// format, a line per value of each reading, with its measurement and field
+// as the class of the series and its tags as labels, as the /update
+// endpoint takes it.
+package warp10
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// floatFormat writes floats as DOUBLEs, with a decimal point so they are
+// not parsed as LONGs, and NaN and infinite values, which are only written
+// in comments, as NaN, +Inf and -Inf
+var floatFormat = &serialize.FloatFormat{Fmt: 'f', Point: true, NaN: "NaN", Inf: "+Inf", NegInf: "-Inf"}
+
+// Format is the name the format is registered under, which writes
+// timestamps in DefaultUnits. Formats of the form Format + ":" + units,
+// e.g., warp10:ns, write them in other units, which must be the time units
+// of the platform.
+const Format = "warp10"
+
+// Time units of the platform, and of the timestamps of the readings:
+// milliseconds, microseconds or nanoseconds
+const (
+	UnitsMilliseconds = "ms"
+	UnitsMicroseconds = "us"
+	UnitsNanoseconds  = "ns"
+)
+
+// DefaultUnits are the time units of Format, the default of Warp 10
+const DefaultUnits = UnitsMicroseconds
+
+// units are the length of each of the time units, in nanoseconds
+var units = map[string]int64{
+	UnitsMilliseconds: 1e6,
+	UnitsMicroseconds: 1e3,
+	UnitsNanoseconds:  1,
+}
+
+func init() {
+	serialize.Describe(Format, "Warp 10 Geo Time Series input format, a line per field with class <measurement>.<field> and tags as labels, with warp10:<ms|us|ns> setting the time units (default "+DefaultUnits+")")
+	serialize.Register(Format, func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(DefaultUnits)
+	})
+	serialize.RegisterScheme(Format, func(arg string, schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+		return NewSerializer(arg)
+	})
+}
+
+// Serializer writes a Point in a serialized form for Warp 10
+type Serializer struct {
+	// unit is the length of the time units of the timestamps, in
+	// nanoseconds
+	unit int64
+
+	// buf is scratch space reused between calls so each Point or PointBatch
+	// is written with a single call
+	buf []byte
+}
+
+// NewSerializer returns a Serializer writing timestamps in the time units
+// timeUnits: UnitsMilliseconds, UnitsMicroseconds or UnitsNanoseconds
+func NewSerializer(timeUnits string) (*Serializer, error) {
+	unit, ok := units[timeUnits]
+	if !ok {
+		return nil, fmt.Errorf("invalid Warp 10 time units '%s': must be %s, %s or %s", timeUnits, UnitsMilliseconds, UnitsMicroseconds, UnitsNanoseconds)
+	}
+	return &Serializer{unit: unit}, nil
+}
+
+// Serialize writes Point p to w as a line of the Geo Time Series input
+// format per field, without a location or elevation:
+//
+// 1451606400000000// cpu.usage_user{hostname=host_0,region=eu-west-1,...} 58
+//
+// The class of each series is the measurement and the field joined by a
+// dot, and its labels the tags, with tags with empty values, which Warp 10
+// treats as absent, left out. Characters of classes and labels other than
+// letters, digits and ._-~/: are percent-encoded, as Warp 10 decodes them.
+//
+// Ints are LONG values, floats DOUBLE values, always with a decimal point,
+// bools true or false, and strings quoted with single quotes, with quotes,
+// percent signs and control characters percent-encoded. Fields without
+// values, and NaN and infinite values, which Warp 10 does not parse, are
+// left out, and a Point left without lines is written as a comment, #, of
+// its fields with those values as NaN, +Inf and -Inf.
+func (s *Serializer) Serialize(p *serialize.Point, w io.Writer) error {
+	buf := s.appendLines(s.buf[:0], p.MeasurementName(), p.TagKeys(), p.TagValues(), p.FieldKeys(), p.FieldValues(), p.Timestamp())
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+// SerializeBatch writes all lines of a PointBatch to the given writer in the
+// same format as Serialize
+func (s *Serializer) SerializeBatch(b *serialize.PointBatch, w io.Writer) error {
+	buf := s.buf[:0]
+	for i := 0; i < b.Len(); i++ {
+		tagKeys, tagValues := b.Tags(i)
+		fieldKeys, fieldValues := b.Fields(i)
+		buf = s.appendLines(buf, b.MeasurementName(i), tagKeys, tagValues, fieldKeys, fieldValues, b.Timestamp(i))
+	}
+	_, err := w.Write(buf)
+	s.buf = buf
+	return err
+}
+
+func (s *Serializer) appendLines(buf []byte, measurementName []byte, tagKeys, tagValues, fieldKeys [][]byte, fieldValues []interface{}, timestamp int64) []byte {
+	// floor, so readings before the epoch are not rounded up to it
+	t := timestamp / s.unit
+	if timestamp%s.unit < 0 {
+		t--
+	}
+	n := 0
+	for i, v := range fieldValues {
+		if v == nil || !isFinite(v) {
+			continue
+		}
+		n++
+		buf = strconv.AppendInt(buf, t, 10)
+		buf = append(buf, "// "...)
+		buf = appendEncoded(buf, measurementName)
+		buf = append(buf, '.')
+		buf = appendEncoded(buf, fieldKeys[i])
+		buf = append(buf, '{')
+		first := true
+		for j, tv := range tagValues {
+			if len(tv) == 0 {
+				continue
+			}
+			if !first {
+				buf = append(buf, ',')
+			}
+			first = false
+			buf = appendEncoded(buf, tagKeys[j])
+			buf = append(buf, '=')
+			buf = appendEncoded(buf, tv)
+		}
+		buf = append(buf, "} "...)
+		buf = appendValue(buf, v)
+		buf = append(buf, '\n')
+	}
+	if n == 0 {
+		// write the reading as a comment of all its values, so it is not
+		// lost without a trace
+		buf = append(buf, "# "...)
+		buf = strconv.AppendInt(buf, t, 10)
+		buf = append(buf, ' ')
+		buf = appendEncoded(buf, measurementName)
+		for i, v := range fieldValues {
+			if i == 0 {
+				buf = append(buf, ' ')
+			} else {
+				buf = append(buf, ',')
+			}
+			buf = appendEncoded(buf, fieldKeys[i])
+			buf = append(buf, '=')
+			buf = appendValue(buf, v)
+		}
+		buf = append(buf, '\n')
+	}
+	return buf
+}
+
+// isFinite returns whether v is not a NaN or infinite float
+func isFinite(v interface{}) bool {
+	switch x := v.(type) {
+	case float64:
+		return !math.IsNaN(x) && !math.IsInf(x, 0)
+	case float32:
+		return !math.IsNaN(float64(x)) && !math.IsInf(float64(x), 0)
+	}
+	return true
+}
+
+// appendValue appends field value v to buf as a value of Warp 10
+func appendValue(buf []byte, v interface{}) []byte {
+	switch x := v.(type) {
+	case float64:
+		return serialize.AppendFloat(buf, x, 64, floatFormat)
+	case float32:
+		return serialize.AppendFloat(buf, float64(x), 32, floatFormat)
+	case bool:
+		return strconv.AppendBool(buf, x)
+	case []byte:
+		return appendString(buf, x)
+	case string:
+		return appendString(buf, []byte(x))
+	case nil:
+		return append(buf, "''"...)
+	}
+	return serialize.FastFormatAppend(v, buf)
+}
+
+const hex = "0123456789ABCDEF"
+
+// appendString appends s to buf as a STRING value, quoted with single
+// quotes, with quotes, percent signs and control characters
+// percent-encoded
+func appendString(buf []byte, s []byte) []byte {
+	buf = append(buf, '\'')
+	for _, c := range s {
+		if c == '\'' || c == '%' || c < 0x20 || c == 0x7f {
+			buf = append(buf, '%', hex[c>>4], hex[c&0xf])
+		} else {
+			buf = append(buf, c)
+		}
+	}
+	return append(buf, '\'')
+}
+
+// appendEncoded appends name to buf as a class, label name or label value,
+// with characters other than letters, digits and ._-~/: percent-encoded
+func appendEncoded(buf []byte, name []byte) []byte {
+	for _, c := range name {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-' || c == '~' || c == '/' || c == ':' {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, '%', hex[c>>4], hex[c&0xf])
+		}
+	}
+	return buf
+}
@@ -0,0 +1,117 @@
+package warp10
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/data/serialize/serializetest"
+)
+
+const testLabels = "{hostname=host_0,region=eu-west-1,datacenter=eu-west-1b} "
+
+var serializeCases = []serializetest.Case{
+	{
+		Desc:       "a regular Point",
+		InputPoint: serializetest.PointDefault,
+		Output:     "1451606400000000// cpu.usage_guest_nice" + testLabels + "38.24311829\n",
+	},
+	{
+		Desc:       "a regular Point using int as value",
+		InputPoint: serializetest.PointInt,
+		Output:     "1451606400000000// cpu.usage_guest" + testLabels + "38\n",
+	},
+	{
+		Desc:       "a regular Point with multiple fields",
+		InputPoint: serializetest.PointMultiField,
+		Output: "1451606400000000// cpu.big_usage_guest" + testLabels + "5000000000\n" +
+			"1451606400000000// cpu.usage_guest" + testLabels + "38\n" +
+			"1451606400000000// cpu.usage_guest_nice" + testLabels + "38.24311829\n",
+	},
+	{
+		Desc:       "a Point with no tags",
+		InputPoint: serializetest.PointNoTags,
+		Output:     "1451606400000000// cpu.usage_guest_nice{} 38.24311829\n",
+	},
+}
+
+func TestSerializerSerialize(t *testing.T) {
+	s, err := NewSerializer(DefaultUnits)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	serializetest.CheckSerializer(t, serializeCases, s)
+}
+
+func TestSerializerConformance(t *testing.T) {
+	serializetest.Suite{
+		New: func(schema *serialize.Schema, w io.Writer) (serialize.PointSerializer, error) {
+			return serialize.New(Format, schema, w)
+		},
+		Golden: serializeCases,
+	}.Run(t)
+}
+
+func TestSerializerValues(t *testing.T) {
+	p := serialize.NewPoint()
+	p.SetMeasurementName([]byte("disk io"))
+	p.SetTimestamp(-1)
+	p.AppendTag([]byte("path"), []byte("/dev/sda,1={x}"))
+	p.AppendTag([]byte("empty"), nil)
+	p.AppendTag([]byte("plus"), []byte("a+b%"))
+	p.AppendField([]byte("whole"), 2.0)
+	p.AppendField([]byte("ok"), true)
+	p.AppendField([]byte("label"), "it's 100%\n")
+	p.AppendField([]byte("nan"), math.NaN())
+	p.AppendField([]byte("none"), nil)
+
+	s, err := NewSerializer(UnitsMilliseconds)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := new(bytes.Buffer)
+	if err := s.Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	const labels = "{path=/dev/sda%2C1%3D%7Bx%7D,plus=a%2Bb%25} "
+	want := "-1// disk%20io.whole" + labels + "2.0\n" +
+		"-1// disk%20io.ok" + labels + "true\n" +
+		"-1// disk%20io.label" + labels + "'it%27s 100%25%0A'\n"
+	if got := b.String(); got != want {
+		t.Errorf("incorrect lines: got\n%s\nwant\n%s", got, want)
+	}
+
+	p.Reset()
+	p.SetMeasurementName([]byte("cpu"))
+	p.SetTimestamp(1451606400000000000)
+	p.AppendField([]byte("usage_user"), math.Inf(1))
+	b.Reset()
+	if err := s.Serialize(p, b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := b.String(), "# 1451606400000 cpu usage_user=+Inf\n"; got != want {
+		t.Errorf("incorrect comment of a Point without values: got %q want %q", got, want)
+	}
+}
+
+func TestNewSerializerUnits(t *testing.T) {
+	for units, want := range map[string]string{UnitsMilliseconds: "1451606400000//", UnitsNanoseconds: "1451606400000000000//"} {
+		ps, err := serialize.New(Format+":"+units, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", units, err)
+		}
+		b := new(bytes.Buffer)
+		if err := ps.Serialize(serializetest.PointDefault, b); err != nil {
+			t.Fatalf("%s: unexpected error: %v", units, err)
+		}
+		if !strings.HasPrefix(b.String(), want) {
+			t.Errorf("%s: incorrect timestamp: got %q want it to start with %q", units, b.String(), want)
+		}
+	}
+	if _, err := serialize.New(Format+":s", nil, nil); err == nil || !strings.Contains(err.Error(), "invalid Warp 10 time units 's'") {
+		t.Errorf("incorrect error for invalid units: %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+// Package service serves generated data over gRPC, so that distributed load
+// agents can pull data on demand instead of pre-staging files.
+//
+// The service is tsbs.Generator with a single server streaming method,
+// Generate, whose messages are defined in generator.proto, from which
+// clients in any language can generate their stubs. A GenerateRequest gives
+// the options of the data, e.g.,
+//
+//	use_case: "cpu-only" scale: 100 seed: 123
+//	timestamp_start: "2016-01-01T00:00:00Z"
+//	timestamp_end: "2016-01-02T00:00:00Z" log_interval: "10s"
+//	shard: 0 num_shards: 4
+//
+// and the response is a stream of PointBatch messages, whose typed points
+// are, in order, those tsbs_generate_data serializes for the same options.
+// Shards split the points between agents the same way as
+// tsbs_generate_data's interleaved generation groups.
+//
+// The Go types of the messages encode themselves, with Codec, rather than
+// with code generated by protoc, so Go clients call the service with
+// grpc.ForceCodec(Codec).
+//
+// gRPC support requires extra dependencies, so this package is only built
+// with the grpc build tag, e.g., go build -tags grpc.
+package service
@@ -0,0 +1,419 @@
+//go:build grpc
+// +build grpc
+
+package service
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of generator.proto, which encode themselves in protobuf
+// with marshal and decode themselves with unmarshal (see Codec)
+type message interface {
+	size() int
+	marshal(b []byte) []byte
+	unmarshal(b []byte) error
+}
+
+// GenerateRequest holds the options for a stream of points; they mirror the
+// flags of tsbs_generate_data
+type GenerateRequest struct {
+	UseCase      string
+	Scale        uint64
+	InitialScale uint64
+	Seed         int64
+	// TimestampStart and TimestampEnd are RFC3339 timestamps
+	TimestampStart string
+	TimestampEnd   string
+	// LogInterval is a Go duration, e.g., 10s
+	LogInterval string
+	// Shard and NumShards select the points of this stream, as with
+	// interleaved generation groups; 0 shards means a single one
+	Shard     uint32
+	NumShards uint32
+	// PodsPerNode is the distribution of the number of pods per node of the
+	// kubernetes use case, or empty for its default
+	PodsPerNode string
+	// BatchSize is the most points of each PointBatch; 0 uses a default of
+	// 1000
+	BatchSize uint32
+}
+
+// PointBatch is a batch of consecutive points
+type PointBatch struct {
+	Points []Point
+}
+
+// Point is a reading of the fields of a measurement of a series, identified
+// by its tags
+type Point struct {
+	Measurement string
+	// Timestamp is in nanoseconds since the Unix epoch
+	Timestamp int64
+	Tags      []Tag
+	Fields    []Field
+}
+
+// Tag is a tag of a Point
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// Field is a field of a Point, whose Value is a float64, int64, bool or
+// string, or nil if it was not read
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+func sizeString(s string) int {
+	if len(s) == 0 {
+		return 0
+	}
+	return 1 + protowire.SizeBytes(len(s))
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if len(s) == 0 {
+		return b
+	}
+	return protowire.AppendString(protowire.AppendTag(b, num, protowire.BytesType), s)
+}
+
+func sizeVarint(v uint64) int {
+	if v == 0 {
+		return 0
+	}
+	return 1 + protowire.SizeVarint(v)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return protowire.AppendVarint(protowire.AppendTag(b, num, protowire.VarintType), v)
+}
+
+// sizeMessage returns the size of m as a field of another message; all the
+// field numbers of the messages are below 16, so their tags are one byte
+func sizeMessage(m message) int {
+	return 1 + protowire.SizeBytes(m.size())
+}
+
+func appendMessage(b []byte, num protowire.Number, m message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(m.size()))
+	return m.marshal(b)
+}
+
+// consumeFields calls fn with each field of the message b, with the bytes of
+// its value
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, typ, b[:n]); err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+// varint returns the value of v, a field of type typ, which must be a varint
+func varint(typ protowire.Type, v []byte) (uint64, error) {
+	if typ != protowire.VarintType {
+		return 0, fmt.Errorf("invalid wire type %d of a varint", typ)
+	}
+	x, _ := protowire.ConsumeVarint(v)
+	return x, nil
+}
+
+// lengthDelimited returns the value of v, a field of type typ, which must be
+// length-delimited
+func lengthDelimited(typ protowire.Type, v []byte) ([]byte, error) {
+	if typ != protowire.BytesType {
+		return nil, fmt.Errorf("invalid wire type %d of a string or message", typ)
+	}
+	x, _ := protowire.ConsumeBytes(v)
+	return x, nil
+}
+
+func (r *GenerateRequest) size() int {
+	return sizeString(r.UseCase) + sizeVarint(r.Scale) + sizeVarint(r.InitialScale) +
+		sizeVarint(uint64(r.Seed)) + sizeString(r.TimestampStart) + sizeString(r.TimestampEnd) +
+		sizeString(r.LogInterval) + sizeVarint(uint64(r.Shard)) + sizeVarint(uint64(r.NumShards)) +
+		sizeString(r.PodsPerNode) + sizeVarint(uint64(r.BatchSize))
+}
+
+func (r *GenerateRequest) marshal(b []byte) []byte {
+	b = appendString(b, 1, r.UseCase)
+	b = appendVarint(b, 2, r.Scale)
+	b = appendVarint(b, 3, r.InitialScale)
+	b = appendVarint(b, 4, uint64(r.Seed))
+	b = appendString(b, 5, r.TimestampStart)
+	b = appendString(b, 6, r.TimestampEnd)
+	b = appendString(b, 7, r.LogInterval)
+	b = appendVarint(b, 8, uint64(r.Shard))
+	b = appendVarint(b, 9, uint64(r.NumShards))
+	b = appendString(b, 10, r.PodsPerNode)
+	return appendVarint(b, 11, uint64(r.BatchSize))
+}
+
+func (r *GenerateRequest) unmarshal(b []byte) error {
+	*r = GenerateRequest{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) (err error) {
+		var s []byte
+		var x uint64
+		switch num {
+		case 1, 5, 6, 7, 10:
+			s, err = lengthDelimited(typ, v)
+		case 2, 3, 4, 8, 9, 11:
+			x, err = varint(typ, v)
+		}
+		switch num {
+		case 1:
+			r.UseCase = string(s)
+		case 2:
+			r.Scale = x
+		case 3:
+			r.InitialScale = x
+		case 4:
+			r.Seed = int64(x)
+		case 5:
+			r.TimestampStart = string(s)
+		case 6:
+			r.TimestampEnd = string(s)
+		case 7:
+			r.LogInterval = string(s)
+		case 8:
+			r.Shard = uint32(x)
+		case 9:
+			r.NumShards = uint32(x)
+		case 10:
+			r.PodsPerNode = string(s)
+		case 11:
+			r.BatchSize = uint32(x)
+		}
+		return err
+	})
+}
+
+func (pb *PointBatch) size() int {
+	n := 0
+	for i := range pb.Points {
+		n += sizeMessage(&pb.Points[i])
+	}
+	return n
+}
+
+func (pb *PointBatch) marshal(b []byte) []byte {
+	for i := range pb.Points {
+		b = appendMessage(b, 1, &pb.Points[i])
+	}
+	return b
+}
+
+func (pb *PointBatch) unmarshal(b []byte) error {
+	pb.Points = pb.Points[:0]
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != 1 {
+			return nil
+		}
+		m, err := lengthDelimited(typ, v)
+		if err != nil {
+			return err
+		}
+		pb.Points = append(pb.Points, Point{})
+		return pb.Points[len(pb.Points)-1].unmarshal(m)
+	})
+}
+
+func (p *Point) size() int {
+	n := sizeString(p.Measurement) + sizeVarint(uint64(p.Timestamp))
+	for i := range p.Tags {
+		n += sizeMessage(&p.Tags[i])
+	}
+	for i := range p.Fields {
+		n += sizeMessage(&p.Fields[i])
+	}
+	return n
+}
+
+func (p *Point) marshal(b []byte) []byte {
+	b = appendString(b, 1, p.Measurement)
+	b = appendVarint(b, 2, uint64(p.Timestamp))
+	for i := range p.Tags {
+		b = appendMessage(b, 3, &p.Tags[i])
+	}
+	for i := range p.Fields {
+		b = appendMessage(b, 4, &p.Fields[i])
+	}
+	return b
+}
+
+func (p *Point) unmarshal(b []byte) error {
+	*p = Point{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch num {
+		case 1:
+			s, err := lengthDelimited(typ, v)
+			p.Measurement = string(s)
+			return err
+		case 2:
+			x, err := varint(typ, v)
+			p.Timestamp = int64(x)
+			return err
+		case 3:
+			m, err := lengthDelimited(typ, v)
+			if err != nil {
+				return err
+			}
+			p.Tags = append(p.Tags, Tag{})
+			return p.Tags[len(p.Tags)-1].unmarshal(m)
+		case 4:
+			m, err := lengthDelimited(typ, v)
+			if err != nil {
+				return err
+			}
+			p.Fields = append(p.Fields, Field{})
+			return p.Fields[len(p.Fields)-1].unmarshal(m)
+		}
+		return nil
+	})
+}
+
+func (t *Tag) size() int {
+	return sizeString(t.Key) + sizeString(t.Value)
+}
+
+func (t *Tag) marshal(b []byte) []byte {
+	return appendString(appendString(b, 1, t.Key), 2, t.Value)
+}
+
+func (t *Tag) unmarshal(b []byte) error {
+	*t = Tag{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		if num != 1 && num != 2 {
+			return nil
+		}
+		s, err := lengthDelimited(typ, v)
+		if num == 1 {
+			t.Key = string(s)
+		} else {
+			t.Value = string(s)
+		}
+		return err
+	})
+}
+
+// The numbers of the fields of the value oneof of a Field
+const (
+	fieldDouble = 2
+	fieldInt    = 3
+	fieldBool   = 4
+	fieldString = 5
+)
+
+func (f *Field) size() int {
+	n := sizeString(f.Key)
+	switch x := f.Value.(type) {
+	case float64:
+		n += 1 + protowire.SizeFixed64()
+	case int64:
+		n += 1 + protowire.SizeVarint(uint64(x))
+	case bool:
+		n += 2
+	case string:
+		n += 1 + protowire.SizeBytes(len(x))
+	}
+	return n
+}
+
+func (f *Field) marshal(b []byte) []byte {
+	b = appendString(b, 1, f.Key)
+	// the value of a oneof is written even if it is the zero value
+	switch x := f.Value.(type) {
+	case float64:
+		b = protowire.AppendTag(b, fieldDouble, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(x))
+	case int64:
+		b = protowire.AppendTag(b, fieldInt, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(x))
+	case bool:
+		b = protowire.AppendTag(b, fieldBool, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(x))
+	case string:
+		b = protowire.AppendTag(b, fieldString, protowire.BytesType)
+		b = protowire.AppendString(b, x)
+	}
+	return b
+}
+
+func (f *Field) unmarshal(b []byte) error {
+	*f = Field{}
+	return consumeFields(b, func(num protowire.Number, typ protowire.Type, v []byte) error {
+		switch num {
+		case 1:
+			s, err := lengthDelimited(typ, v)
+			f.Key = string(s)
+			return err
+		case fieldDouble:
+			if typ != protowire.Fixed64Type {
+				return fmt.Errorf("invalid wire type %d of a double", typ)
+			}
+			x, _ := protowire.ConsumeFixed64(v)
+			f.Value = math.Float64frombits(x)
+		case fieldInt:
+			x, err := varint(typ, v)
+			f.Value = int64(x)
+			return err
+		case fieldBool:
+			x, err := varint(typ, v)
+			f.Value = protowire.DecodeBool(x)
+			return err
+		case fieldString:
+			s, err := lengthDelimited(typ, v)
+			f.Value = string(s)
+			return err
+		}
+		return nil
+	})
+}
+
+// Codec encodes the messages of the service in protobuf, as described by
+// generator.proto. The servers of the service must be created with
+// grpc.ForceServerCodec(Codec), and Go clients call it with
+// grpc.ForceCodec(Codec).
+var Codec = codec{}
+
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a message of %s", v, ServiceName)
+	}
+	return m.marshal(make([]byte, 0, m.size())), nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(message)
+	if !ok {
+		return fmt.Errorf("%T is not a message of %s", v, ServiceName)
+	}
+	return m.unmarshal(data)
+}
+
+func (codec) Name() string { return "proto" }
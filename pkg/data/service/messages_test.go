@@ -0,0 +1,74 @@
+//go:build grpc
+// +build grpc
+
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	messages := []message{
+		&GenerateRequest{},
+		&GenerateRequest{
+			UseCase: "devops", Scale: 100, InitialScale: 10, Seed: -123,
+			TimestampStart: "2016-01-01T00:00:00Z", TimestampEnd: "2016-01-02T00:00:00Z",
+			LogInterval: "10s", Shard: 1, NumShards: 4, PodsPerNode: "uniform:1,5", BatchSize: 500,
+		},
+		&PointBatch{Points: []Point{
+			{
+				Measurement: "cpu",
+				Timestamp:   1451606400000000000,
+				Tags:        []Tag{{Key: "hostname", Value: "host_0"}, {Key: "empty"}},
+				Fields: []Field{
+					{Key: "usage_user", Value: 58.13},
+					{Key: "zero", Value: 0.0},
+					{Key: "nan", Value: math.Inf(-1)},
+					{Key: "count", Value: int64(-3)},
+					{Key: "up", Value: false},
+					{Key: "state", Value: "running"},
+					{Key: "unread"},
+				},
+			},
+			{Measurement: "mem", Timestamp: -1},
+		}},
+	}
+	for _, m := range messages {
+		b, err := Codec.Marshal(m)
+		if err != nil {
+			t.Fatalf("%T: unexpected error: %v", m, err)
+		}
+		if len(b) != m.size() {
+			t.Errorf("%T: incorrect size: got %d want %d", m, m.size(), len(b))
+		}
+		got := reflect.New(reflect.TypeOf(m).Elem()).Interface()
+		if err := Codec.Unmarshal(b, got); err != nil {
+			t.Fatalf("%T: unexpected error: %v", m, err)
+		}
+		if pb, ok := got.(*PointBatch); ok && len(pb.Points) == 0 {
+			pb.Points = nil
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("%T: incorrect message:\ngot  %+v\nwant %+v", m, got, m)
+		}
+	}
+
+	// a GenerateRequest as written by protoc's code: use_case "cpu-only",
+	// scale 10 and num_shards 2
+	b := []byte("\x0a\x08cpu-only\x10\x0a\x48\x02")
+	var r GenerateRequest
+	if err := Codec.Unmarshal(b, &r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (GenerateRequest{UseCase: "cpu-only", Scale: 10, NumShards: 2}); r != want {
+		t.Errorf("incorrect request: got %+v want %+v", r, want)
+	}
+	if err := Codec.Unmarshal([]byte("\x0a\x08cpu"), &r); err == nil {
+		t.Errorf("expected an error for a truncated message")
+	}
+	if _, err := Codec.Marshal("not a message"); err == nil {
+		t.Errorf("expected an error for a value which is not a message")
+	}
+}
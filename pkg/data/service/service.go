@@ -0,0 +1,206 @@
+//go:build grpc
+// +build grpc
+
+package service
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// ServiceName is the full name of the gRPC service
+	ServiceName = "tsbs.Generator"
+	// GenerateMethod is the full name of the Generate method
+	GenerateMethod = "/" + ServiceName + "/Generate"
+
+	defaultBatchSize = 1000
+	// maxBatchBytes keeps batches under gRPC's default 4MB message limit;
+	// a batch is sent before its points would make it larger, whatever its
+	// number of points
+	maxBatchBytes = 2 << 20
+)
+
+// toConfig converts r to a data.GeneratorConfig
+func (r *GenerateRequest) toConfig() (data.GeneratorConfig, error) {
+	start, err := time.Parse(time.RFC3339, r.TimestampStart)
+	if err != nil {
+		return data.GeneratorConfig{}, fmt.Errorf("invalid timestamp_start: %v", err)
+	}
+	end, err := time.Parse(time.RFC3339, r.TimestampEnd)
+	if err != nil {
+		return data.GeneratorConfig{}, fmt.Errorf("invalid timestamp_end: %v", err)
+	}
+	interval, err := time.ParseDuration(r.LogInterval)
+	if err != nil {
+		return data.GeneratorConfig{}, fmt.Errorf("invalid log_interval: %v", err)
+	}
+	return data.GeneratorConfig{
+		// the points do not depend on the format, which is only checked
+		Format:               data.FormatInflux,
+		UseCase:              r.UseCase,
+		Scale:                r.Scale,
+		InitialScale:         r.InitialScale,
+		Seed:                 r.Seed,
+		TimestampStart:       start.UTC(),
+		TimestampEnd:         end.UTC(),
+		LogInterval:          interval,
+		InterleavedGroupID:   uint(r.Shard),
+		InterleavedNumGroups: uint(r.NumShards),
+		PodsPerNode:          r.PodsPerNode,
+	}, nil
+}
+
+// generatorServer is the interface implemented by handlers of the service
+type generatorServer interface {
+	generate(req *GenerateRequest, stream grpc.ServerStream) error
+}
+
+// ServiceDesc describes the tsbs.Generator service for grpc.Server
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*generatorServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Generate",
+			Handler:       generateHandler,
+			ServerStreams: true,
+		},
+	},
+}
+
+func generateHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(GenerateRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(generatorServer).generate(req, stream)
+}
+
+// Server generates the points requested by each stream
+type Server struct{}
+
+// Register registers a Server for the tsbs.Generator service with s, which
+// must have been created with grpc.ForceServerCodec(Codec)
+func Register(s *grpc.Server) {
+	s.RegisterService(&ServiceDesc, &Server{})
+}
+
+// ListenAndServe serves the tsbs.Generator service on addr until it fails
+func ListenAndServe(addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s := grpc.NewServer(grpc.ForceServerCodec(Codec))
+	Register(s)
+	return s.Serve(lis)
+}
+
+func (s *Server) generate(req *GenerateRequest, stream grpc.ServerStream) error {
+	cfg, err := req.toConfig()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	g, err := data.NewGenerator(cfg)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	batchSize := int(req.BatchSize)
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+	numShards := uint64(req.NumShards)
+	if numShards == 0 {
+		numShards = 1
+	}
+
+	ctx := stream.Context()
+	it, err := g.Points(ctx)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	batch := &PointBatch{Points: make([]Point, 0, batchSize)}
+	size := 0
+	send := func() error {
+		err := stream.SendMsg(batch)
+		batch.Points = batch.Points[:0]
+		size = 0
+		return err
+	}
+	var point Point
+	i := uint64(0)
+	for p, ok := it.Next(); ok; p, ok = it.Next() {
+		// the points are split between shards as between interleaved
+		// generation groups
+		shard := i % numShards
+		i++
+		if shard != uint64(req.Shard) {
+			continue
+		}
+		fromPoint(&point, p)
+		pointSize := sizeMessage(&point)
+		if len(batch.Points) > 0 && size+pointSize > maxBatchBytes {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+		batch.Points = append(batch.Points, point)
+		size += pointSize
+		if len(batch.Points) == batchSize {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	if len(batch.Points) > 0 {
+		return send()
+	}
+	return nil
+}
+
+// fromPoint sets dst to the values of p. Field values are converted to the
+// types a Field holds.
+func fromPoint(dst *Point, p *serialize.Point) {
+	dst.Measurement = string(p.MeasurementName())
+	dst.Timestamp = p.Timestamp()
+	keys, values := p.TagKeys(), p.TagValues()
+	dst.Tags = make([]Tag, len(keys))
+	for i := range keys {
+		dst.Tags[i] = Tag{Key: string(keys[i]), Value: string(values[i])}
+	}
+	keys, fieldValues := p.FieldKeys(), p.FieldValues()
+	dst.Fields = make([]Field, len(keys))
+	for i := range keys {
+		dst.Fields[i] = Field{Key: string(keys[i]), Value: fieldValue(fieldValues[i])}
+	}
+}
+
+// fieldValue returns v, a field value of a serialize.Point, as a Field holds
+// it: a float64, int64, bool or string, or nil
+func fieldValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float32:
+		return float64(x)
+	case int:
+		return int64(x)
+	case []byte:
+		return string(x)
+	case float64, int64, bool, string:
+		return x
+	case nil:
+		return nil
+	}
+	return fmt.Sprint(v)
+}
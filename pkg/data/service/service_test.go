@@ -0,0 +1,174 @@
+//go:build grpc
+// +build grpc
+
+package service
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func testRequest() *GenerateRequest {
+	return &GenerateRequest{
+		UseCase:        data.UseCaseCPUOnly,
+		Scale:          10,
+		Seed:           123,
+		TimestampStart: "2016-01-01T00:00:00Z",
+		TimestampEnd:   "2016-01-01T01:00:00Z",
+		LogInterval:    "10s",
+		BatchSize:      100,
+	}
+}
+
+func testClient(t *testing.T) (*grpc.ClientConn, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer(grpc.ForceServerCodec(Codec))
+	Register(s)
+	go s.Serve(lis)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+	cc, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(Codec)))
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	return cc, func() {
+		cc.Close()
+		s.Stop()
+	}
+}
+
+// generate calls Generate with req and returns the points streamed
+func generate(cc *grpc.ClientConn, req *GenerateRequest) ([]Point, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	stream, err := cc.NewStream(ctx, &ServiceDesc.Streams[0], GenerateMethod)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := stream.SendMsg(req); err != nil {
+		return nil, 0, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, 0, err
+	}
+	var points []Point
+	batches := 0
+	for {
+		resp := new(PointBatch)
+		err := stream.RecvMsg(resp)
+		if err == io.EOF {
+			return points, batches, nil
+		} else if err != nil {
+			return nil, 0, err
+		}
+		if len(resp.Points) == 0 || len(resp.Points) > int(req.BatchSize) {
+			return nil, 0, status.Errorf(codes.Internal, "incorrect batch size: %d", len(resp.Points))
+		}
+		points = append(points, resp.Points...)
+		batches++
+	}
+}
+
+// wantPoints returns the points of the Generator of req, of its shard
+func wantPoints(t *testing.T, req *GenerateRequest) []Point {
+	cfg, err := req.toConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g, err := data.NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	it, err := g.Points(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var points []Point
+	i := uint32(0)
+	for p, ok := it.Next(); ok; p, ok = it.Next() {
+		if req.NumShards == 0 || i%req.NumShards == req.Shard {
+			var point Point
+			fromPoint(&point, p)
+			points = append(points, point)
+		}
+		i++
+	}
+	return points
+}
+
+func TestGenerate(t *testing.T) {
+	cc, cleanup := testClient(t)
+	defer cleanup()
+
+	req := testRequest()
+	want := wantPoints(t, req)
+	got, batches, err := generate(cc, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed points do not match generated points: got %d points want %d", len(got), len(want))
+	}
+	if batches != (len(want)+int(req.BatchSize)-1)/int(req.BatchSize) {
+		t.Errorf("incorrect number of batches: got %d", batches)
+	}
+	if p := got[0]; p.Measurement != "cpu" || len(p.Tags) == 0 || p.Tags[0].Key != "hostname" || len(p.Fields) == 0 {
+		t.Errorf("incorrect point: %+v", p)
+	}
+	for _, f := range got[0].Fields {
+		switch f.Value.(type) {
+		case float64, int64:
+		default:
+			t.Errorf("incorrect type of value of %s: %T", f.Key, f.Value)
+		}
+	}
+
+	// the shards split the points
+	req.Shard, req.NumShards = 1, 3
+	want = wantPoints(t, req)
+	if got, _, err = generate(cc, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed points of shard do not match: got %d points want %d", len(got), len(want))
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	cc, cleanup := testClient(t)
+	defer cleanup()
+
+	cases := []struct {
+		desc   string
+		modify func(*GenerateRequest)
+	}{
+		{"bad use case", func(r *GenerateRequest) { r.UseCase = "bogus" }},
+		{"bad timestamp", func(r *GenerateRequest) { r.TimestampStart = "yesterday" }},
+		{"bad interval", func(r *GenerateRequest) { r.LogInterval = "often" }},
+		{"bad shard", func(r *GenerateRequest) { r.Shard, r.NumShards = 2, 2 }},
+	}
+	for _, c := range cases {
+		req := testRequest()
+		c.modify(req)
+		_, _, err := generate(cc, req)
+		if got := status.Code(err); got != codes.InvalidArgument {
+			t.Errorf("%s: incorrect code: got %v want %v (err %v)", c.desc, got, codes.InvalidArgument, err)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package smarthome
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+)
+
+// Modes of thermostats, and their actions in them
+const (
+	modeHeat = iota
+	modeCool
+	modeOff
+)
+
+const (
+	actionIdle = iota
+	actionHeating
+	actionCooling
+)
+
+var (
+	modeNames = [][]byte{
+		modeHeat: []byte("heat"),
+		modeCool: []byte("cool"),
+		modeOff:  []byte("off"),
+	}
+	actionNames = [][]byte{
+		actionIdle:    []byte("idle"),
+		actionHeating: []byte("heating"),
+		actionCooling: []byte("cooling"),
+	}
+)
+
+// setpoints are the temperatures thermostats are set to in each mode, by
+// occupancy
+var setpoints = [3][3]float64{
+	modeHeat: {OccupancyHome: 21, OccupancyAsleep: 18, OccupancyAway: 16},
+	modeCool: {OccupancyHome: 24, OccupancyAsleep: 25, OccupancyAway: 28},
+	modeOff:  {OccupancyHome: 21, OccupancyAsleep: 21, OccupancyAway: 21},
+}
+
+// Parameters of the temperature of homes, in degrees Celsius: homes lose
+// heat to the outdoors over heatLoss, heating and cooling change the
+// temperature by heatingRate and coolingRate an hour, and thermostats keep
+// it within hysteresis of the setpoint
+const (
+	heatLoss    = 6 * time.Hour
+	heatingRate = 8.0
+	coolingRate = 6.0
+	hysteresis  = 0.5
+)
+
+// OutdoorTemperature returns the temperature outdoors, in degrees Celsius,
+// at local time t in the temperate north where the homes are, and the
+// normal temperature of its day of the year
+func OutdoorTemperature(t time.Time) (temperature, normal float64) {
+	day := float64(t.YearDay()-1) + float64(t.Hour())/24
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	// warmest around the 20th of July, and at 15:00
+	normal = 10 + 12*math.Cos(2*math.Pi*(day-200)/365.25)
+	return normal + 5*math.Cos(2*math.Pi*(hour-15)/24), normal
+}
+
+// thermostatMode returns the mode thermostats are in on a day of a normal
+// temperature: heating in the cold months, cooling in the hot ones
+func thermostatMode(normal float64) int {
+	switch {
+	case normal < 15:
+		return modeHeat
+	case normal > 20:
+		return modeCool
+	default:
+		return modeOff
+	}
+}
+
+// thermostat models a thermostat, which heats or cools its home to a
+// setpoint that depends on the occupancy, in a mode that depends on the
+// season. It reports every thermostatInterval, and when its mode, setpoint
+// or action changes.
+type thermostat struct {
+	info deviceInfo
+
+	next       int64
+	nextReport int64
+
+	temperature float64
+	// humidity is the anomaly of the relative humidity from its normal
+	humidity float64
+	setpoint float64
+	mode     int
+	action   int
+}
+
+func newThermostat(h *Home, room []byte) *thermostat {
+	start := h.timestamp
+	t := &thermostat{
+		info: deviceInfo{kind: kindThermostat, name: []byte("thermostat"), room: room},
+
+		next:       start + phase(h.rand, thermostatStep),
+		nextReport: start + phase(h.rand, thermostatInterval),
+
+		action: actionIdle,
+	}
+	_, normal := OutdoorTemperature(h.local(start))
+	t.mode = thermostatMode(normal)
+	t.setpoint = setpoints[t.mode][h.Occupancy(start)]
+	t.temperature = t.setpoint + hysteresis*(2*h.rand.Float64()-1)
+	return t
+}
+
+func (t *thermostat) due() int64 {
+	return t.next
+}
+
+func (t *thermostat) advance(h *Home) (report, bool) {
+	at := t.next
+	t.next += int64(thermostatStep)
+
+	outdoor, normal := OutdoorTemperature(h.local(at))
+	hours := thermostatStep.Hours()
+	t.temperature += (outdoor - t.temperature) * hours / heatLoss.Hours()
+	switch t.action {
+	case actionHeating:
+		t.temperature += heatingRate * hours
+	case actionCooling:
+		t.temperature -= coolingRate * hours
+	}
+	t.humidity = math.Max(-10, math.Min(10, t.humidity+0.2*h.rand.NormFloat64()))
+
+	mode := thermostatMode(normal)
+	setpoint := setpoints[mode][h.Occupancy(at)]
+	action := t.action
+	switch mode {
+	case modeHeat:
+		switch {
+		case t.temperature < setpoint-hysteresis:
+			action = actionHeating
+		case t.temperature > setpoint+hysteresis || action != actionHeating:
+			action = actionIdle
+		}
+	case modeCool:
+		switch {
+		case t.temperature > setpoint+hysteresis:
+			action = actionCooling
+		case t.temperature < setpoint-hysteresis || action != actionCooling:
+			action = actionIdle
+		}
+	default:
+		action = actionIdle
+	}
+	changed := mode != t.mode || setpoint != t.setpoint || action != t.action
+	t.mode, t.setpoint, t.action = mode, setpoint, action
+
+	if at >= t.nextReport {
+		t.nextReport += int64(thermostatInterval)
+	} else if !changed {
+		return report{}, false
+	}
+	// drier while heating, more humid in summer
+	humidity := 40 + 0.5*(normal-10) + t.humidity
+	if action == actionHeating {
+		humidity -= 5
+	}
+	return report{
+		device:      &t.info,
+		timestamp:   at,
+		temperature: math.Round(t.temperature*10) / 10,
+		humidity:    math.Round(humidity),
+		setpoint:    setpoint,
+		mode:        modeNames[mode],
+		action:      actionNames[action],
+	}, true
+}
+
+// appliance is a kind of appliance plugged into a smart plug, which draws
+// standby watts when not in use and active watts when in use, for inUse on
+// average, between times not in use for idle on average. Most are only used
+// while their owners are home and awake.
+type appliance struct {
+	name          []byte
+	standby       float64
+	active        float64
+	inUse         time.Duration
+	idle          time.Duration
+	needsOccupant bool
+	room          []byte
+}
+
+var applianceChoices = []appliance{
+	// the compressor of a fridge cycles on and off
+	{[]byte("fridge"), 2, 120, 15 * time.Minute, 30 * time.Minute, false, []byte("kitchen")},
+	{[]byte("kettle"), 0.2, 2000, 3 * time.Minute, 3 * time.Hour, true, []byte("kitchen")},
+	{[]byte("tv"), 0.5, 110, 90 * time.Minute, 3 * time.Hour, true, []byte("living_room")},
+	{[]byte("lamp"), 0.3, 9, 2 * time.Hour, 2 * time.Hour, true, []byte("living_room")},
+	{[]byte("computer"), 2, 85, 3 * time.Hour, 2 * time.Hour, true, []byte("office")},
+	{[]byte("washer"), 1, 500, time.Hour, 24 * time.Hour, true, []byte("laundry")},
+}
+
+// Plugs are switched off after plugOn on average, and back on after plugOff
+// on average
+const (
+	plugOn  = 3 * 24 * time.Hour
+	plugOff = 6 * time.Hour
+)
+
+// plug models a smart plug, reporting the power drawn by the appliance
+// plugged into it every plugInterval, the energy drawn in total, in kWh, and
+// whether it is switched on
+type plug struct {
+	info      deviceInfo
+	appliance *appliance
+
+	next   int64
+	on     bool
+	inUse  bool
+	energy float64
+}
+
+func newPlug(h *Home, name []byte, a *appliance) *plug {
+	return &plug{
+		info:      deviceInfo{kind: kindPlug, name: name, room: a.room, appliance: a.name},
+		appliance: a,
+
+		next:   h.timestamp + phase(h.rand, plugInterval),
+		on:     true,
+		energy: 1000 * h.rand.Float64(),
+	}
+}
+
+func (p *plug) due() int64 {
+	return p.next
+}
+
+func (p *plug) advance(h *Home) (report, bool) {
+	at := p.next
+	p.next += int64(plugInterval)
+
+	if p.on && happens(h.rand, plugInterval, plugOn) || !p.on && happens(h.rand, plugInterval, plugOff) {
+		p.on = !p.on
+	}
+	a := p.appliance
+	switch {
+	case a.needsOccupant && h.Occupancy(at) != OccupancyHome:
+		p.inUse = false
+	case p.inUse && happens(h.rand, plugInterval, a.inUse), !p.inUse && happens(h.rand, plugInterval, a.idle):
+		p.inUse = !p.inUse
+	}
+
+	var power float64
+	switch {
+	case !p.on:
+	case p.inUse:
+		power = a.active * (1 + 0.03*h.rand.NormFloat64())
+	default:
+		power = a.standby * (1 + 0.05*h.rand.NormFloat64())
+	}
+	p.energy += power * plugInterval.Hours() / 1000
+	return report{
+		device:    &p.info,
+		timestamp: at,
+		power:     math.Round(power*10) / 10,
+		energy:    math.Round(p.energy*1000) / 1000,
+		on:        p.on,
+	}, true
+}
+
+// phase returns when in its first interval a device reports first, in
+// whole milliseconds, as devices time their reports
+func phase(r *common.Rand, interval time.Duration) int64 {
+	return r.Int63n(int64(interval/time.Millisecond)) * int64(time.Millisecond)
+}
+
+// happens returns whether something that happens after mean on average,
+// exponentially distributed, happens in the next d
+func happens(r *common.Rand, d, mean time.Duration) bool {
+	return r.Float64() < 1-math.Exp(-float64(d)/float64(mean))
+}
+
+// motionRoom is a room with a motion sensor, in which there is motion rate
+// times an hour on average while its home is occupied and awake
+type motionRoom struct {
+	name []byte
+	rate float64
+}
+
+var motionRooms = []motionRoom{
+	{[]byte("hallway"), 12},
+	{[]byte("living_room"), 10},
+	{[]byte("kitchen"), 8},
+	{[]byte("office"), 6},
+	{[]byte("bedroom"), 4},
+	{[]byte("garage"), 1},
+}
+
+// activity is the rate of motion in rooms, relative to that while their
+// home is occupied and awake, by occupancy
+var activity = [3]float64{OccupancyHome: 1, OccupancyAsleep: 0.02, OccupancyAway: 0}
+
+// batteryDrain is the battery, in percent, motion sensors use to report
+const batteryDrain = 0.003
+
+// motionSensor models a battery powered motion sensor, which reports when
+// it detects motion, and when the motion clears after motionClear without
+// any, or otherwise every motionHeartbeat
+type motionSensor struct {
+	info deviceInfo
+	rate float64
+
+	// nextMotion is when there may be motion next, clearAt when the motion
+	// detected clears, and heartbeat when the sensor reports without any
+	nextMotion int64
+	clearAt    int64
+	heartbeat  int64
+
+	detected bool
+	battery  float64
+}
+
+func newMotionSensor(h *Home, name []byte, room *motionRoom) *motionSensor {
+	m := &motionSensor{
+		info: deviceInfo{kind: kindMotion, name: name, room: room.name},
+		rate: room.rate,
+
+		heartbeat: h.timestamp + phase(h.rand, motionHeartbeat),
+		battery:   20 + 80*h.rand.Float64(),
+	}
+	m.nextMotion = h.timestamp + m.motionGap(h.rand)
+	return m
+}
+
+// motionGap returns the time to the next possible motion, at the rate of
+// the room while its home is occupied and awake. Whether there is motion
+// then depends on the occupancy at the time.
+func (m *motionSensor) motionGap(r *common.Rand) int64 {
+	gap := time.Duration(-math.Log(1-r.Float64()) / m.rate * float64(time.Hour))
+	return int64(gap.Truncate(time.Millisecond))
+}
+
+func (m *motionSensor) due() int64 {
+	if m.detected && m.clearAt < m.nextMotion && m.clearAt < m.heartbeat {
+		return m.clearAt
+	}
+	if m.nextMotion < m.heartbeat {
+		return m.nextMotion
+	}
+	return m.heartbeat
+}
+
+func (m *motionSensor) advance(h *Home) (report, bool) {
+	at := m.due()
+	switch at {
+	case m.nextMotion:
+		m.nextMotion = at + m.motionGap(h.rand)
+		if h.rand.Float64() >= activity[h.Occupancy(at)] {
+			// no motion after all
+			return report{}, false
+		}
+		m.clearAt = at + int64(motionClear)
+		if m.detected {
+			return report{}, false
+		}
+		m.detected = true
+	case m.clearAt:
+		m.detected = false
+	}
+	m.heartbeat = at + int64(motionHeartbeat)
+	m.battery = math.Max(0, m.battery-batteryDrain)
+	return report{
+		device:    &m.info,
+		timestamp: at,
+		motion:    m.detected,
+		battery:   int64(math.Ceil(m.battery)),
+	}, true
+}
@@ -0,0 +1,107 @@
+package smarthome
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+// newTestHome returns a home of no devices, from a winter Monday
+func newTestHome() *Home {
+	return &Home{
+		rand:      common.NewRand(rng.New(123)),
+		timestamp: time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC).UnixNano(),
+		day:       -1,
+	}
+}
+
+func TestThermostat(t *testing.T) {
+	h := newTestHome()
+	th := newThermostat(h, []byte("hallway"))
+	reports, heating := 0, 0
+	for th.due() < h.timestamp+int64(24*time.Hour) {
+		r, ok := th.advance(h)
+		if !ok {
+			continue
+		}
+		reports++
+		if string(r.mode) != "heat" {
+			t.Fatalf("incorrect mode in winter: got %s", r.mode)
+		}
+		if string(r.action) == "heating" {
+			heating++
+		}
+		// kept near the setpoint, changing over a few minutes
+		if math.Abs(r.temperature-r.setpoint) > 6 {
+			t.Errorf("temperature far from the setpoint: %v at %v", r.temperature, r.setpoint)
+		}
+	}
+	if reports < 24*60/5 || reports > 24*60/2 {
+		t.Errorf("incorrect number of reports: got %d", reports)
+	}
+	if heating == 0 || heating == reports {
+		t.Errorf("incorrect reports while heating: %d of %d", heating, reports)
+	}
+}
+
+func TestPlug(t *testing.T) {
+	h := newTestHome()
+	// a fridge, used whatever the occupancy
+	p := newPlug(h, []byte("plug_0"), &applianceChoices[0])
+	energy := p.energy
+	var inUse, standby int
+	for i := 0; i < 24*60*6; i++ {
+		r, ok := p.advance(h)
+		if !ok || r.timestamp != p.next-int64(plugInterval) {
+			t.Fatalf("plug did not report every interval")
+		}
+		if r.energy < energy {
+			t.Fatalf("energy decreased: %v after %v", r.energy, energy)
+		}
+		energy = r.energy
+		switch {
+		case !r.on && r.power != 0:
+			t.Errorf("power drawn while off: %v", r.power)
+		case r.power > 100:
+			inUse++
+		case r.power > 0:
+			standby++
+		}
+	}
+	// the compressor runs a third of the time
+	if ratio := float64(inUse) / float64(inUse+standby); ratio < 0.2 || ratio > 0.5 {
+		t.Errorf("incorrect ratio of time in use: got %v", ratio)
+	}
+}
+
+func TestMotionSensor(t *testing.T) {
+	h := newTestHome()
+	m := newMotionSensor(h, []byte("motion_0"), &motionRooms[0])
+	var detected int64 = -1
+	last := h.timestamp
+	for m.due() < h.timestamp+int64(7*24*time.Hour) {
+		r, ok := m.advance(h)
+		if !ok {
+			continue
+		}
+		if r.timestamp-last > int64(motionHeartbeat) {
+			t.Fatalf("no report for over a heartbeat: %v", time.Duration(r.timestamp-last))
+		}
+		last = r.timestamp
+		switch {
+		case r.motion && detected < 0:
+			detected = r.timestamp
+		case !r.motion && detected >= 0:
+			if r.timestamp-detected < int64(motionClear) {
+				t.Errorf("motion cleared too soon: %v", time.Duration(r.timestamp-detected))
+			}
+			detected = -1
+		}
+		if r.battery <= 0 || r.battery > 100 {
+			t.Errorf("battery out of range: %d", r.battery)
+		}
+	}
+}
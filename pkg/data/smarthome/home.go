@@ -0,0 +1,275 @@
+package smarthome
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Occupancy is whether the occupants of a home are in and awake, asleep, or
+// away
+type Occupancy int
+
+// Occupancies of a home
+const (
+	OccupancyHome Occupancy = iota
+	OccupancyAsleep
+	OccupancyAway
+)
+
+var (
+	// utcOffsets are the time zones of the homes
+	utcOffsets = []time.Duration{
+		-8 * time.Hour,
+		-7 * time.Hour,
+		-6 * time.Hour,
+		-5 * time.Hour,
+		0,
+		time.Hour,
+		2 * time.Hour,
+	}
+	thermostatRooms = [][]byte{
+		[]byte("living_room"),
+		[]byte("hallway"),
+	}
+)
+
+// schedule is when the occupants of a home wake up, leave and come back, if
+// they go out, and go to sleep on a day, as times since local midnight
+type schedule struct {
+	wake  time.Duration
+	leave time.Duration
+	back  time.Duration
+	sleep time.Duration
+}
+
+// device is a device of a home, which is advanced from one time it is due
+// to the next, in order with the other devices of the home
+type device interface {
+	// due returns when the device is next advanced
+	due() int64
+	// advance advances the device to when it is due, returning its report
+	// then, if it makes one
+	advance(h *Home) (report, bool)
+}
+
+// report is a report of a device, with the fields of its measurement
+type report struct {
+	device    *deviceInfo
+	timestamp int64
+
+	// thermostats
+	temperature float64
+	humidity    float64
+	setpoint    float64
+	mode        []byte
+	action      []byte
+	// plugs
+	power  float64
+	energy float64
+	on     bool
+	// motion sensors
+	motion  bool
+	battery int64
+}
+
+// deviceKind is the kind of a device, which its reports are of the
+// measurement of
+type deviceKind int
+
+const (
+	kindThermostat deviceKind = iota
+	kindPlug
+	kindMotion
+)
+
+// deviceInfo identifies a device, by its kind, name and room and, for plugs,
+// the appliance plugged in
+type deviceInfo struct {
+	kind      deviceKind
+	name      []byte
+	room      []byte
+	appliance []byte
+}
+
+// deviceQueue is a heap of devices, by when they are due
+type deviceQueue []device
+
+func (q deviceQueue) Len() int            { return len(q) }
+func (q deviceQueue) Less(i, j int) bool  { return q[i].due() < q[j].due() }
+func (q deviceQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *deviceQueue) Push(x interface{}) { *q = append(*q, x.(device)) }
+func (q *deviceQueue) Pop() interface{} {
+	old := *q
+	d := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return d
+}
+
+// Home models a home with a thermostat, smart plugs and motion sensors. Its
+// occupants follow a daily schedule in the local time of the home, waking
+// up, mostly going out on weekdays, and going to sleep, drawn anew each
+// day, which the devices respond to. The reports of a reading are those of
+// the devices in the interval from its timestamp.
+type Home struct {
+	// These are all assigned once, at Home creation:
+	Name      []byte
+	UTCOffset time.Duration
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+	interval  time.Duration
+	maxEvents int
+
+	devices deviceQueue
+	// day is the local day of schedule, in days since the Unix epoch
+	day      int64
+	schedule schedule
+
+	reports []report
+}
+
+// NewHome returns home i, with readings every interval from start of at
+// most maxEvents reports each, and its devices drawn from r
+func NewHome(r *common.Rand, i int, start time.Time, interval time.Duration, maxEvents int) *Home {
+	h := &Home{
+		Name:      []byte(fmt.Sprintf("home_%d", i)),
+		UTCOffset: utcOffsets[r.Intn(len(utcOffsets))],
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+		interval:  interval,
+		maxEvents: maxEvents,
+		day:       -1,
+	}
+
+	h.devices = append(h.devices, newThermostat(h, thermostatRooms[r.Intn(len(thermostatRooms))]))
+	for j := 2 + r.Intn(maxPlugs-1); j > 0; j-- {
+		name := []byte(fmt.Sprintf("plug_%d", len(h.devices)-1))
+		h.devices = append(h.devices, newPlug(h, name, &applianceChoices[r.Intn(len(applianceChoices))]))
+	}
+	// in different rooms, drawn by a partial shuffle
+	rooms := make([]int, len(motionRooms))
+	for j := range rooms {
+		rooms[j] = j
+	}
+	for j, n := 0, 1+r.Intn(maxMotionSensors); j < n; j++ {
+		k := j + r.Intn(len(rooms)-j)
+		rooms[j], rooms[k] = rooms[k], rooms[j]
+		name := []byte(fmt.Sprintf("motion_%d", j))
+		h.devices = append(h.devices, newMotionSensor(h, name, &motionRooms[rooms[j]]))
+	}
+	heap.Init(&h.devices)
+	h.read()
+	return h
+}
+
+// Tick advances the home by d to its next reading
+func (h *Home) Tick(d time.Duration) {
+	h.timestamp += int64(d)
+	h.read()
+}
+
+// read advances the devices through the interval of the current reading,
+// collecting their reports
+func (h *Home) read() {
+	end := h.timestamp + int64(h.interval)
+	h.reports = h.reports[:0]
+	for h.devices[0].due() < end && len(h.reports) < h.maxEvents {
+		r, ok := h.devices[0].advance(h)
+		heap.Fix(&h.devices, 0)
+		if ok {
+			h.reports = append(h.reports, r)
+		}
+	}
+}
+
+// local returns the local time of the home at timestamp
+func (h *Home) local(timestamp int64) time.Time {
+	return time.Unix(0, timestamp).UTC().Add(h.UTCOffset)
+}
+
+// Occupancy returns the occupancy of the home at timestamp, which must not
+// be before that of earlier calls
+func (h *Home) Occupancy(timestamp int64) Occupancy {
+	local := timestamp + int64(h.UTCOffset)
+	if day := local / int64(24*time.Hour); day != h.day {
+		h.day = day
+		h.schedule = h.drawSchedule(h.local(timestamp).Weekday())
+	}
+	s := h.schedule
+	switch sinceMidnight := time.Duration(local % int64(24*time.Hour)); {
+	case sinceMidnight < s.wake || sinceMidnight >= s.sleep:
+		return OccupancyAsleep
+	case sinceMidnight >= s.leave && sinceMidnight < s.back:
+		return OccupancyAway
+	default:
+		return OccupancyHome
+	}
+}
+
+// drawSchedule returns the schedule of the occupants on a day: up around
+// 06:30, later at weekends, out from a while after waking up to around
+// 17:00 on most weekdays, and to bed around 23:00
+func (h *Home) drawSchedule(weekday time.Weekday) schedule {
+	around := func(at, stddev time.Duration) time.Duration {
+		return at + time.Duration(h.rand.NormFloat64()*float64(stddev))
+	}
+	weekend := weekday == time.Saturday || weekday == time.Sunday
+	var s schedule
+	s.wake = around(6*time.Hour+30*time.Minute, 30*time.Minute)
+	if weekend {
+		s.wake += 90 * time.Minute
+	}
+	if !weekend && h.rand.Float64() < 0.8 {
+		s.leave = s.wake + time.Hour + time.Duration(h.rand.Int63n(int64(time.Hour)))
+		s.back = around(17*time.Hour, time.Hour)
+		if s.back < s.leave+time.Hour {
+			s.back = s.leave + time.Hour
+		}
+	}
+	s.sleep = around(23*time.Hour, 45*time.Minute)
+	if s.sleep <= s.back {
+		s.sleep = s.back + time.Hour
+	}
+	if s.sleep >= 24*time.Hour {
+		s.sleep = 24*time.Hour - time.Minute
+	}
+	return s
+}
+
+// ToPoint fills p with report i of the current reading, returning false
+// for points beyond them
+func (h *Home) ToPoint(p *serialize.Point, i int) bool {
+	if i >= len(h.reports) {
+		return false
+	}
+	r := &h.reports[i]
+	p.SetTimestamp(r.timestamp)
+	p.AppendTag(TagKeys[0], h.Name)
+	p.AppendTag(TagKeys[1], r.device.name)
+	p.AppendTag(TagKeys[2], r.device.room)
+	switch r.device.kind {
+	case kindThermostat:
+		p.SetMeasurementName(labelThermostat)
+		p.AppendField(thermostatFields[0], r.temperature)
+		p.AppendField(thermostatFields[1], r.humidity)
+		p.AppendField(thermostatFields[2], r.setpoint)
+		p.AppendField(thermostatFields[3], r.mode)
+		p.AppendField(thermostatFields[4], r.action)
+	case kindPlug:
+		p.SetMeasurementName(labelPlug)
+		p.AppendTag(tagKeyAppliance, r.device.appliance)
+		p.AppendField(plugFields[0], r.power)
+		p.AppendField(plugFields[1], r.energy)
+		p.AppendField(plugFields[2], r.on)
+	case kindMotion:
+		p.SetMeasurementName(labelMotion)
+		p.AppendField(motionFields[0], r.motion)
+		p.AppendField(motionFields[1], r.battery)
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package smarthome
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestHomeTick(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	const interval = time.Minute
+	maxEvents := maxEventsPerReading(interval)
+	h := NewHome(common.NewRand(rng.New(123)), 7, start, interval, maxEvents)
+	if got := string(h.Name); got != "home_7" {
+		t.Errorf("incorrect name: got %s", got)
+	}
+	plugs, motions := 0, 0
+	for _, d := range h.devices {
+		switch d.(type) {
+		case *plug:
+			plugs++
+		case *motionSensor:
+			motions++
+		}
+	}
+	if plugs < 2 || plugs > maxPlugs || motions < 1 || motions > maxMotionSensors {
+		t.Errorf("incorrect devices: %d plugs and %d motion sensors", plugs, motions)
+	}
+
+	p := serialize.NewPoint()
+	for i := 0; i < 24*60; i++ {
+		if len(h.reports) >= maxEvents {
+			t.Fatalf("reports reached the most of a reading: %d", len(h.reports))
+		}
+		// every plug reports every plugInterval
+		if i > 0 && len(h.reports) < plugs*int(interval/plugInterval) {
+			t.Fatalf("too few reports: got %d", len(h.reports))
+		}
+		prev := h.timestamp
+		for j, r := range h.reports {
+			if r.timestamp < prev || r.timestamp >= h.timestamp+int64(interval) {
+				t.Fatalf("report out of order or outside its reading: %d", r.timestamp)
+			}
+			prev = r.timestamp
+			p.Reset()
+			if !h.ToPoint(p, j) {
+				t.Fatalf("no point made of report %d", j)
+			}
+		}
+		p.Reset()
+		if h.ToPoint(p, len(h.reports)) {
+			t.Errorf("point made beyond the reports")
+		}
+		h.Tick(interval)
+	}
+}
+
+func TestHomeOccupancy(t *testing.T) {
+	// midnight in the home's time zone on a Monday
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	h := NewHome(common.NewRand(rng.New(123)), 0, start, time.Minute, 1)
+	midnight := start.UnixNano() - int64(h.UTCOffset)
+
+	away := 0
+	for day := int64(0); day < 28; day++ {
+		at := func(hour time.Duration) int64 {
+			return midnight + day*int64(24*time.Hour) + int64(hour)
+		}
+		if got := h.Occupancy(at(3 * time.Hour)); got != OccupancyAsleep {
+			t.Errorf("day %d: not asleep at 03:00: got %v", day, got)
+		}
+		if h.Occupancy(at(12*time.Hour)) == OccupancyAway {
+			away++
+			if day%7 >= 5 {
+				t.Errorf("day %d: away at the weekend", day)
+			}
+		}
+		if got := h.Occupancy(at(23*time.Hour + 59*time.Minute)); got != OccupancyAsleep {
+			t.Errorf("day %d: not asleep at 23:59: got %v", day, got)
+		}
+	}
+	// most weekdays
+	if away < 20*6/10 || away > 20 {
+		t.Errorf("incorrect days away: got %d", away)
+	}
+}
@@ -0,0 +1,149 @@
+// Package smarthome simulates the smart home use case: the devices of
+// homes, thermostats, smart plugs and motion sensors, each reporting at an
+// interval of its own or when its state changes, with boolean and string
+// states as well as numeric readings.
+package smarthome
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelThermostat = []byte("thermostat")
+	labelPlug       = []byte("plug")
+	labelMotion     = []byte("motion")
+
+	// TagKeys are the tags of every point, identifying its home and device;
+	// plugs have the tag of the appliance plugged in too
+	TagKeys = [][]byte{
+		[]byte("home"),
+		[]byte("device"),
+		[]byte("room"),
+	}
+	tagKeyAppliance = []byte("appliance")
+
+	thermostatFields = [][]byte{
+		[]byte("temperature"),
+		[]byte("humidity"),
+		[]byte("setpoint"),
+		[]byte("mode"),
+		[]byte("action"),
+	}
+	thermostatTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeString,
+		serialize.FieldTypeString,
+	}
+	plugFields = [][]byte{
+		[]byte("power"),
+		[]byte("energy"),
+		[]byte("on"),
+	}
+	plugTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeBool,
+	}
+	motionFields = [][]byte{
+		[]byte("motion"),
+		[]byte("battery"),
+	}
+	motionTypes = []serialize.FieldType{
+		serialize.FieldTypeBool,
+		serialize.FieldTypeInt,
+	}
+
+	schema = serialize.NewTaggedSchema(TagKeys, map[string][][]byte{
+		string(labelPlug): {tagKeyAppliance},
+	}, map[string][][]byte{
+		string(labelThermostat): thermostatFields,
+		string(labelPlug):       plugFields,
+		string(labelMotion):     motionFields,
+	}, map[string][]serialize.FieldType{
+		string(labelThermostat): thermostatTypes,
+		string(labelPlug):       plugTypes,
+		string(labelMotion):     motionTypes,
+	})
+)
+
+// Homes have a thermostat, up to maxPlugs smart plugs and up to
+// maxMotionSensors motion sensors
+const (
+	maxPlugs         = 6
+	maxMotionSensors = 4
+)
+
+// Devices report at very different intervals: thermostats every
+// thermostatInterval, and when their state changes, which they check every
+// thermostatStep; plugs every plugInterval; and motion sensors when they
+// detect motion, when it clears motionClear later, and otherwise every
+// motionHeartbeat
+const (
+	thermostatInterval = 5 * time.Minute
+	thermostatStep     = time.Minute
+	plugInterval       = 10 * time.Second
+	motionClear        = 2 * time.Minute
+	motionHeartbeat    = time.Hour
+)
+
+// SimulatorConfig is used to create a Simulator of the smart home use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitHomeCount is the number of homes to start with in the first
+	// reporting period
+	InitHomeCount uint64
+	// HomeCount is the total number of homes to have in the last reporting
+	// period
+	HomeCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each home. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the thermostat, plug
+// and motion measurements
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a reading of the reports of
+// the devices of each home every interval. It advances RNG, so each call
+// simulates different homes.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	maxEvents := maxEventsPerReading(interval)
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitHomeCount,
+		Count:            c.HomeCount,
+		PointsPerReading: maxEvents,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewHome(r, i, start, interval, maxEvents)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
+
+// maxEventsPerReading returns the most reports of the devices of a home in
+// a reading every interval
+func maxEventsPerReading(interval time.Duration) int {
+	times := func(every time.Duration) int {
+		return int(math.Ceil(float64(interval)/float64(every))) + 1
+	}
+	// a motion sensor reports motion and its clearing at most once each
+	// every motionClear, and a heartbeat at most every motionHeartbeat
+	motion := 2*times(motionClear) + times(motionHeartbeat)
+	return times(thermostatStep) + maxPlugs*times(plugInterval) + maxMotionSensors*motion
+}
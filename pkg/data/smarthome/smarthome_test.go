@@ -0,0 +1,79 @@
+package smarthome
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestSimulator(t *testing.T) {
+	start := time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:         start,
+		End:           start.Add(24 * time.Hour),
+		InitHomeCount: 5,
+		HomeCount:     5,
+		RNG:           rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Second).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(time.Minute)
+	counts := make(map[string]int)
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		m := string(p.MeasurementName())
+		counts[m]++
+		if ts := time.Unix(0, p.Timestamp()); ts.Before(start) || !ts.Before(c.End.Add(time.Minute)) {
+			t.Fatalf("point outside the simulation: %v", ts.UTC())
+		}
+		if len(p.FieldKeys()) != len(c.Fields().FieldKeys(m)) {
+			t.Errorf("incorrect fields of %s: got %s", m, p.FieldKeys())
+		}
+		for j, v := range p.FieldValues() {
+			if got, want := serialize.FieldTypeOf(v), c.Fields().FieldTypes(m)[j]; got != want {
+				t.Errorf("incorrect type of %s %s: got %v want %v", m, p.FieldKeys()[j], got, want)
+			}
+		}
+		wantTags := len(TagKeys)
+		if m == "plug" {
+			wantTags++
+		}
+		if len(p.TagKeys()) != wantTags {
+			t.Errorf("incorrect tags of %s: got %s", m, p.TagKeys())
+		}
+	}
+	// at least a thermostat and two plugs per home
+	if got, want := counts["thermostat"], 5*24*60/5; got < want {
+		t.Errorf("too few thermostat reports: got %d want at least %d", got, want)
+	}
+	if got, want := counts["plug"], 5*2*24*60*6; got < want || got > 3*want {
+		t.Errorf("incorrect number of plug reports: got %d", got)
+	}
+	if counts["motion"] == 0 {
+		t.Errorf("no motion reports")
+	}
+}
+
+func TestOutdoorTemperature(t *testing.T) {
+	jan, janNormal := OutdoorTemperature(time.Date(2016, 1, 15, 15, 0, 0, 0, time.UTC))
+	jul, julNormal := OutdoorTemperature(time.Date(2016, 7, 15, 15, 0, 0, 0, time.UTC))
+	night, _ := OutdoorTemperature(time.Date(2016, 7, 15, 3, 0, 0, 0, time.UTC))
+	if julNormal <= janNormal || jul <= jan {
+		t.Errorf("summer not warmer than winter: %v in July, %v in January", jul, jan)
+	}
+	if night >= jul {
+		t.Errorf("night not colder than afternoon: %v at night, %v in the afternoon", night, jul)
+	}
+	if thermostatMode(janNormal) != modeHeat || thermostatMode(julNormal) != modeCool {
+		t.Errorf("incorrect modes: %s in January, %s in July", modeNames[thermostatMode(janNormal)], modeNames[thermostatMode(julNormal)])
+	}
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"io"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// PointTransformer modifies each point after it is simulated and before it is
+// serialized, e.g., to replace the values of some fields with ones computed
+// by a script. Transformers are only ever called from one goroutine.
+type PointTransformer interface {
+	Transform(p *serialize.Point) error
+}
+
+// transformingSerializer applies a PointTransformer to each point before
+// handing it to the PointSerializer it wraps. It deliberately does not
+// implement serialize.BatchSerializer, so points are transformed one by one
+// and only generation with a transformer pays for it.
+type transformingSerializer struct {
+	transformer PointTransformer
+	serializer  serialize.PointSerializer
+}
+
+// NewTransformingSerializer returns a PointSerializer that applies t to each
+// point before serializing it with ps
+func NewTransformingSerializer(t PointTransformer, ps serialize.PointSerializer) serialize.PointSerializer {
+	return &transformingSerializer{transformer: t, serializer: ps}
+}
+
+// Serialize transforms p and then serializes it
+func (s *transformingSerializer) Serialize(p *serialize.Point, w io.Writer) error {
+	if err := s.transformer.Transform(p); err != nil {
+		return err
+	}
+	return s.serializer.Serialize(p, w)
+}
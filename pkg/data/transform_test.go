@@ -0,0 +1,55 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+type testTransformer struct {
+	err   error
+	calls int
+}
+
+func (t *testTransformer) Transform(p *serialize.Point) error {
+	t.calls++
+	if t.err != nil {
+		return t.err
+	}
+	p.SetFieldValue(0, 42)
+	return nil
+}
+
+func TestGeneratorTransformer(t *testing.T) {
+	tr := &testTransformer{}
+	cfg := testGeneratorConfig()
+	cfg.UseCase = UseCaseCPUOnly
+	cfg.OmitHeader = true
+	cfg.Transformer = tr
+	g, err := NewGenerator(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := g.Generate(context.Background(), &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if tr.calls != len(lines) {
+		t.Errorf("incorrect number of transform calls: got %d want %d", tr.calls, len(lines))
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, " usage_user=42i,") {
+			t.Errorf("field not transformed: %s", line)
+		}
+	}
+
+	tr.err = errors.New("transform failed")
+	if err := g.Generate(context.Background(), &buf); err != tr.err {
+		t.Errorf("incorrect error: got %v want %v", err, tr.err)
+	}
+}
@@ -0,0 +1,221 @@
+package weather
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+)
+
+// Stations are placed uniformly between minLatitude and maxLatitude, and at
+// up to maxElevation meters, most of them low
+const (
+	minLatitude  = -55.0
+	maxLatitude  = 72.0
+	maxElevation = 2500.0
+)
+
+// seaLevelPressure is the mean pressure at sea level, in hPa, which
+// stations report their pressure reduced to
+const seaLevelPressure = 1013.25
+
+// Anomalies of the weather from the normal of each station: the pressure,
+// in hPa, as weather systems pass over days, the daily mean temperature, in
+// degrees Celsius, the dew point depression, in degrees, and the wind speed,
+// in m/s, as gusts come and go
+var (
+	pressureAnomaly    = anomalyParams{stddev: 8, timescale: 3 * 24 * time.Hour}
+	temperatureAnomaly = anomalyParams{stddev: 3, timescale: 2 * 24 * time.Hour}
+	depressionAnomaly  = anomalyParams{stddev: 1.5, timescale: 12 * time.Hour}
+	windAnomaly        = anomalyParams{stddev: 1.5, timescale: time.Hour}
+)
+
+// Stations' sensors drop out after meanTimeBetweenDropouts on average, for
+// meanDropout on average, both exponentially distributed
+const (
+	meanTimeBetweenDropouts = 14 * 24 * time.Hour
+	meanDropout             = 3 * time.Hour
+)
+
+// windVeer is how far the wind direction wanders in an hour, in degrees
+const windVeer = 20.0
+
+// Coefficients of the Magnus formula of the saturation vapor pressure,
+// which relative humidity is the ratio of at the dew point and the
+// temperature
+const (
+	magnusA = 17.625
+	magnusB = 243.04
+)
+
+type anomalyParams struct {
+	stddev    float64
+	timescale time.Duration
+}
+
+// anomaly is a mean reverting random walk (an Ornstein-Uhlenbeck process)
+// around 0, whose values timescale apart are only loosely correlated
+type anomaly struct {
+	anomalyParams
+	value float64
+}
+
+func newAnomaly(r *common.Rand, params anomalyParams) anomaly {
+	return anomaly{anomalyParams: params, value: params.stddev * r.NormFloat64()}
+}
+
+// advance moves a by d, exactly, whatever the size of d
+func (a *anomaly) advance(r *common.Rand, d time.Duration) {
+	decay := math.Exp(-float64(d) / float64(a.timescale))
+	a.value = a.value*decay + a.stddev*math.Sqrt(1-decay*decay)*r.NormFloat64()
+}
+
+// Station models a weather station. Its temperature follows the daily and
+// seasonal cycles of its latitude, longitude and elevation; weather systems
+// pass as a slow anomaly of its pressure, and low pressure brings clouds,
+// which damp the daily cycle and raise the humidity, and wind. Now and then
+// its sensors drop out, and it makes no readings until they are back.
+type Station struct {
+	// These are all assigned once, at Station creation:
+	Name      []byte
+	Climate   []byte
+	Latitude  []byte
+	Longitude []byte
+	Elevation []byte
+
+	rand      *common.Rand
+	timestamp int64 // nanoseconds since the Unix epoch
+
+	latitude  float64
+	longitude float64
+	elevation float64
+	// dailyRange is the difference between the warmest and coldest
+	// temperatures of a clear day, dryness scales the dew point depression
+	// of clear days, and meanWind is the wind speed without weather systems
+	dailyRange float64
+	dryness    float64
+	meanWind   float64
+
+	pressure      anomaly
+	temperature   anomaly
+	depression    anomaly
+	wind          anomaly
+	windDirection float64
+
+	// out is whether the sensors are out, until outUntil
+	out      bool
+	outUntil int64
+
+	// readings of the current tick
+	readings [5]float64
+}
+
+// NewStation returns station i, reading from start, with its location and
+// climate drawn from r
+func NewStation(r *common.Rand, i int, start time.Time) *Station {
+	u := r.Float64()
+	s := &Station{
+		Name: []byte(fmt.Sprintf("station_%d", i)),
+
+		rand:      r,
+		timestamp: start.UnixNano(),
+
+		latitude:  minLatitude + (maxLatitude-minLatitude)*r.Float64(),
+		longitude: -180 + 360*r.Float64(),
+		elevation: maxElevation * u * u,
+
+		dailyRange: 6 + 8*r.Float64(),
+		dryness:    0.3 + 1.2*r.Float64(),
+		meanWind:   2 + 4*r.Float64(),
+
+		pressure:      newAnomaly(r, pressureAnomaly),
+		temperature:   newAnomaly(r, temperatureAnomaly),
+		depression:    newAnomaly(r, depressionAnomaly),
+		wind:          newAnomaly(r, windAnomaly),
+		windDirection: 360 * r.Float64(),
+	}
+	s.Climate = ClimateZone(s.latitude)
+	s.Latitude = strconv.AppendFloat(nil, s.latitude, 'f', 4, 64)
+	s.Longitude = strconv.AppendFloat(nil, s.longitude, 'f', 4, 64)
+	s.Elevation = strconv.AppendInt(nil, int64(s.elevation), 10)
+	s.read()
+	return s
+}
+
+// Tick advances the station by d, moving its weather on and reading it
+// anew, unless its sensors are out
+func (s *Station) Tick(d time.Duration) {
+	s.timestamp += int64(d)
+	s.pressure.advance(s.rand, d)
+	s.temperature.advance(s.rand, d)
+	s.depression.advance(s.rand, d)
+	s.wind.advance(s.rand, d)
+	s.windDirection += windVeer * math.Sqrt(d.Hours()) * s.rand.NormFloat64()
+	s.windDirection = math.Mod(s.windDirection+360, 360)
+
+	if s.out && s.timestamp >= s.outUntil {
+		s.out = false
+	}
+	if !s.out && s.rand.Float64() < 1-math.Exp(-float64(d)/float64(meanTimeBetweenDropouts)) {
+		s.out = true
+		s.outUntil = s.timestamp + int64(-math.Log(1-s.rand.Float64())*float64(meanDropout))
+	}
+	if !s.out {
+		s.read()
+	}
+}
+
+// read computes the readings of the current tick from the weather
+func (s *Station) read() {
+	t := time.Unix(0, s.timestamp)
+	// cloud cover, from 0 to 1, mostly with low pressure
+	cloud := 1 / (1 + math.Exp(s.pressure.value/4))
+	// warmest at 15:00 by the sun, coldest at 03:00
+	hour := SolarHour(s.longitude, t)
+	diurnal := math.Cos(2 * math.Pi * (hour - 15) / 24)
+
+	mean := NormalTemperature(s.latitude, s.elevation, t) + s.temperature.value
+	temperature := mean + s.dailyRange/2*(1-0.7*cloud)*diurnal
+	// the dew point changes little through the day, so the humidity is
+	// highest when it is coldest
+	dewPoint := mean - math.Max(0, 12*s.dryness*(1-cloud)+s.depression.value)
+	humidity := 100 * math.Exp(magnusA*dewPoint/(magnusB+dewPoint)-magnusA*temperature/(magnusB+temperature))
+
+	// with a small tide, highest at 10:00 and 22:00
+	pressure := seaLevelPressure + s.pressure.value + math.Cos(4*math.Pi*(hour-10)/24)
+	// windier with low pressure, and in the afternoon
+	windSpeed := (s.meanWind + 0.2*math.Max(0, -s.pressure.value) + s.wind.value) * (1 + 0.2*diurnal)
+
+	s.readings[0] = round(temperature, 10)
+	s.readings[1] = round(math.Min(100, humidity), 10)
+	s.readings[2] = round(pressure, 10)
+	s.readings[3] = round(math.Max(0, windSpeed), 10)
+	s.readings[4] = math.Mod(math.Round(s.windDirection), 360)
+}
+
+// round rounds x to the nearest 1/precision, as sensors report it
+func round(x, precision float64) float64 {
+	return math.Round(x*precision) / precision
+}
+
+// ToPoint fills p with the reading of the current tick, returning false
+// while the sensors are out
+func (s *Station) ToPoint(p *serialize.Point, i int) bool {
+	if s.out {
+		return false
+	}
+	p.SetMeasurementName(labelWeather)
+	p.SetTimestamp(s.timestamp)
+	p.AppendTag(TagKeys[0], s.Name)
+	p.AppendTag(TagKeys[1], s.Climate)
+	p.AppendTag(TagKeys[2], s.Latitude)
+	p.AppendTag(TagKeys[3], s.Longitude)
+	p.AppendTag(TagKeys[4], s.Elevation)
+	for j, v := range s.readings {
+		p.AppendField(weatherFields[j], v)
+	}
+	return true
+}
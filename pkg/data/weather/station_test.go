@@ -0,0 +1,96 @@
+package weather
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestStationTick(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStation(common.NewRand(rng.New(123)), 3, start)
+	if got := string(s.Name); got != "station_3" {
+		t.Errorf("incorrect name: got %s", got)
+	}
+	// a clear, temperate station on the prime meridian, so the solar hour
+	// is the hour in UTC
+	s.latitude, s.longitude = 45, 0
+	s.read()
+
+	// sums of the temperature and humidity at each hour over a year
+	var temperature, humidity [24]float64
+	var winter, summer float64
+	for i := 0; i < 365*24; i++ {
+		s.Tick(time.Hour)
+		s.out = false
+		s.read()
+		hour := time.Unix(0, s.timestamp).UTC().Hour()
+		temperature[hour] += s.readings[0]
+		humidity[hour] += s.readings[1]
+		switch time.Unix(0, s.timestamp).UTC().Month() {
+		case time.January:
+			winter += s.readings[0]
+		case time.July:
+			summer += s.readings[0]
+		}
+	}
+	if temperature[15] <= temperature[3] {
+		t.Errorf("afternoon not warmer than night: %v at 15:00, %v at 03:00", temperature[15]/365, temperature[3]/365)
+	}
+	if humidity[15] >= humidity[3] {
+		t.Errorf("afternoon not drier than night: %v at 15:00, %v at 03:00", humidity[15]/365, humidity[3]/365)
+	}
+	if summer <= winter+10*31*24 {
+		t.Errorf("summer not much warmer than winter: %v in July, %v in January", summer/31/24, winter/31/24)
+	}
+}
+
+func TestStationDropout(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewStation(common.NewRand(rng.New(123)), 0, start)
+	p := serialize.NewPoint()
+	if !s.ToPoint(p, 0) {
+		t.Fatalf("no reading before a dropout")
+	}
+
+	s.out = true
+	s.outUntil = s.timestamp + int64(time.Hour)
+	s.Tick(30 * time.Minute)
+	p.Reset()
+	if s.ToPoint(p, 0) {
+		t.Errorf("reading while sensors out")
+	}
+
+	// over a year, sensors are out for about meanDropout every
+	// meanTimeBetweenDropouts
+	out := 0
+	for i := 0; i < 365*24*6; i++ {
+		s.Tick(10 * time.Minute)
+		if s.out {
+			out++
+		}
+	}
+	if ratio := float64(out) / (365 * 24 * 6); ratio == 0 || ratio > 0.05 {
+		t.Errorf("incorrect ratio of time out: got %v", ratio)
+	}
+}
+
+func TestAnomaly(t *testing.T) {
+	r := common.NewRand(rng.New(123))
+	a := newAnomaly(r, anomalyParams{stddev: 2, timescale: time.Hour})
+	var sum, sumSquares float64
+	const n = 100000
+	for i := 0; i < n; i++ {
+		a.advance(r, 10*time.Minute)
+		sum += a.value
+		sumSquares += a.value * a.value
+	}
+	mean := sum / n
+	if stddev := math.Sqrt(sumSquares/n - mean*mean); math.Abs(mean) > 0.2 || math.Abs(stddev-2) > 0.2 {
+		t.Errorf("incorrect mean or stddev: got %v and %v", mean, stddev)
+	}
+}
@@ -0,0 +1,149 @@
+// Package weather simulates the weather use case: readings of weather
+// stations, whose temperature, humidity, pressure and wind change slowly
+// and together as weather systems pass, on top of daily and seasonal cycles
+// that depend on where each station is.
+package weather
+
+import (
+	"math"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/common"
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+var (
+	labelWeather = []byte("weather")
+
+	// TagKeys are the tags of every point, identifying its station and
+	// where it is
+	TagKeys = [][]byte{
+		[]byte("station"),
+		[]byte("climate"),
+		[]byte("latitude"),
+		[]byte("longitude"),
+		[]byte("elevation"),
+	}
+
+	weatherFields = [][]byte{
+		[]byte("temperature"),
+		[]byte("humidity"),
+		[]byte("pressure"),
+		[]byte("wind_speed"),
+		[]byte("wind_direction"),
+	}
+	weatherTypes = []serialize.FieldType{
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+		serialize.FieldTypeFloat,
+	}
+
+	schema = serialize.NewTypedSchema(TagKeys, map[string][][]byte{
+		string(labelWeather): weatherFields,
+	}, map[string][]serialize.FieldType{
+		string(labelWeather): weatherTypes,
+	})
+)
+
+// climateZones are the climates by latitude, north or south, each up to
+// the latitude of its bound
+var climateZones = []struct {
+	bound float64
+	name  []byte
+}{
+	{23.5, []byte("tropical")},
+	{35, []byte("subtropical")},
+	{55, []byte("temperate")},
+	{66.5, []byte("subarctic")},
+	{90, []byte("polar")},
+}
+
+// ClimateZone returns the climate of the given latitude, in degrees
+func ClimateZone(latitude float64) []byte {
+	latitude = math.Abs(latitude)
+	for _, z := range climateZones {
+		if latitude < z.bound {
+			return z.name
+		}
+	}
+	return climateZones[len(climateZones)-1].name
+}
+
+// Parameters of the normal temperature of a station through the year, in
+// degrees Celsius: warmest at the equator and at sea level, with seasons
+// that are barely felt in the tropics and grow extreme towards the poles,
+// and summer peaking around warmestDay in the north and half a year later
+// in the south
+const (
+	equatorTemperature = 27.0
+	latitudeLapse      = 0.007  // per square degree of latitude
+	elevationLapse     = 0.0065 // per meter
+	seasonalAmplitude  = 0.005  // per square degree of latitude
+	warmestDay         = 200    // day of the year, in the north
+)
+
+// NormalTemperature returns the daily mean temperature, in degrees Celsius,
+// of a station at latitude, in degrees, and elevation, in meters, on the
+// day of t, without the weather
+func NormalTemperature(latitude, elevation float64, t time.Time) float64 {
+	day := float64(t.YearDay()-1) + float64(t.Hour())/24
+	season := math.Cos(2 * math.Pi * (day - warmestDay) / 365.25)
+	if latitude < 0 {
+		season = -season
+	}
+	square := latitude * latitude
+	return equatorTemperature - latitudeLapse*square - elevationLapse*elevation + seasonalAmplitude*square*season
+}
+
+// SolarHour returns the hour of the day at t, in [0, 24), by the sun at
+// longitude, in degrees east, which the daily cycle of the weather follows
+func SolarHour(longitude float64, t time.Time) float64 {
+	t = t.UTC()
+	hour := float64(t.Hour()) + float64(t.Minute())/60 + float64(t.Second())/3600 + longitude/15
+	return math.Mod(hour+24, 24)
+}
+
+// SimulatorConfig is used to create a Simulator of the weather use case
+type SimulatorConfig struct {
+	// Start is the beginning time for the Simulator
+	Start time.Time
+	// End is the ending time for the Simulator
+	End time.Time
+	// InitStationCount is the number of stations to start with in the first
+	// reporting period
+	InitStationCount uint64
+	// StationCount is the total number of stations to have in the last
+	// reporting period
+	StationCount uint64
+	// RNG is the source of randomness of the simulation, which is split into
+	// a Rand for each station. If nil, rng.New(0) is used
+	RNG rng.RNG
+}
+
+// Fields returns the Schema of the points simulated: the weather
+// measurement
+func (c *SimulatorConfig) Fields() *serialize.Schema {
+	return schema
+}
+
+// ToSimulator produces a Simulator that makes a reading of each station
+// every interval, except while its sensors are out. It advances RNG, so
+// each call simulates different stations.
+func (c *SimulatorConfig) ToSimulator(interval time.Duration) common.Simulator {
+	ec := &common.EntitySimulatorConfig{
+		Start:            c.Start,
+		End:              c.End,
+		InitCount:        c.InitStationCount,
+		Count:            c.StationCount,
+		PointsPerReading: 1,
+		Constructor: func(r *common.Rand, i int, start time.Time) common.Entity {
+			return NewStation(r, i, start)
+		},
+		Schema: schema,
+		RNG:    c.RNG,
+	}
+	return ec.ToSimulator(interval)
+}
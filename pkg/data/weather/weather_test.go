@@ -0,0 +1,116 @@
+package weather
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/rng"
+)
+
+func TestClimateZone(t *testing.T) {
+	cases := []struct {
+		latitude float64
+		want     string
+	}{
+		{0, "tropical"},
+		{-23, "tropical"},
+		{30, "subtropical"},
+		{-45, "temperate"},
+		{60, "subarctic"},
+		{70, "polar"},
+		{90, "polar"},
+	}
+	for _, c := range cases {
+		if got := string(ClimateZone(c.latitude)); got != c.want {
+			t.Errorf("%v: incorrect climate: got %s want %s", c.latitude, got, c.want)
+		}
+	}
+}
+
+func TestNormalTemperature(t *testing.T) {
+	jan := time.Date(2016, 1, 15, 12, 0, 0, 0, time.UTC)
+	jul := time.Date(2016, 7, 15, 12, 0, 0, 0, time.UTC)
+	if NormalTemperature(45, 0, jul) <= NormalTemperature(45, 0, jan) {
+		t.Errorf("northern summer not warmer than winter")
+	}
+	if NormalTemperature(-45, 0, jan) <= NormalTemperature(-45, 0, jul) {
+		t.Errorf("southern summer not warmer than winter")
+	}
+	if NormalTemperature(45, 1000, jul) >= NormalTemperature(45, 0, jul) {
+		t.Errorf("higher station not colder")
+	}
+	// no seasons at the equator
+	if NormalTemperature(0, 0, jan) != NormalTemperature(0, 0, jul) {
+		t.Errorf("seasons at the equator")
+	}
+}
+
+func TestSolarHour(t *testing.T) {
+	noon := time.Date(2016, 1, 4, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		longitude float64
+		want      float64
+	}{
+		{0, 12},
+		{90, 18},
+		{-90, 6},
+		{180, 0},
+		{-135, 3},
+	}
+	for _, c := range cases {
+		if got := SolarHour(c.longitude, noon); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%v: incorrect solar hour: got %v want %v", c.longitude, got, c.want)
+		}
+	}
+}
+
+func TestSimulator(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := &SimulatorConfig{
+		Start:            start,
+		End:              start.Add(7 * 24 * time.Hour),
+		InitStationCount: 10,
+		StationCount:     10,
+		RNG:              rng.New(123),
+	}
+	if c.Fields() != c.ToSimulator(time.Minute).Fields() {
+		t.Errorf("schema of config differs from simulator's")
+	}
+
+	sim := c.ToSimulator(10 * time.Minute)
+	n := 0
+	p := serialize.NewPoint()
+	for !sim.Finished() {
+		p.Reset()
+		if !sim.Next(context.Background(), p) {
+			continue
+		}
+		n++
+		if len(p.TagKeys()) != len(TagKeys) || len(p.FieldKeys()) != len(weatherFields) {
+			t.Fatalf("incorrect tags or fields: %s %s", p.TagKeys(), p.FieldKeys())
+		}
+		v := p.FieldValues()
+		temperature, humidity, pressure := v[0].(float64), v[1].(float64), v[2].(float64)
+		windSpeed, windDirection := v[3].(float64), v[4].(float64)
+		if temperature < -70 || temperature > 60 {
+			t.Errorf("temperature out of range: %v", temperature)
+		}
+		if humidity <= 0 || humidity > 100 {
+			t.Errorf("humidity out of range: %v", humidity)
+		}
+		if pressure < 940 || pressure > 1090 {
+			t.Errorf("pressure out of range: %v", pressure)
+		}
+		if windSpeed < 0 || windDirection < 0 || windDirection >= 360 {
+			t.Errorf("wind out of range: %v from %v", windSpeed, windDirection)
+		}
+	}
+	// a reading per station every 10 minutes, less the few while sensors
+	// are out
+	if want := 10 * 7 * 24 * 6; n > want || n < want*9/10 {
+		t.Errorf("incorrect number of readings: got %d want about %d", n, want)
+	}
+}
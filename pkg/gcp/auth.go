@@ -0,0 +1,198 @@
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope is the OAuth scope tokens are requested for, which covers the
+// BigQuery and Bigtable APIs
+const Scope = "https://www.googleapis.com/auth/cloud-platform"
+
+// defaultTokenURI is where tokens are requested if the key does not say
+const defaultTokenURI = "https://oauth2.googleapis.com/token"
+
+// Credentials authenticate requests, either with an access token or with the
+// key of a service account, which tokens are then requested for. Without
+// either, requests are not authenticated, as emulators expect.
+type Credentials struct {
+	AccessToken string
+	// KeyFile is the path of the JSON key of a service account
+	KeyFile string
+
+	key *serviceAccountKey
+}
+
+// serviceAccountKey is the part of the JSON key of a service account needed
+// to request tokens
+type serviceAccountKey struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+
+	rsa *rsa.PrivateKey
+}
+
+// CredentialsFromEnv returns the credentials in the environment: an access
+// token in GOOGLE_OAUTH_ACCESS_TOKEN (e.g., from gcloud auth
+// print-access-token), or the key file of a service account in
+// GOOGLE_APPLICATION_CREDENTIALS
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AccessToken: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		KeyFile:     os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+	}
+}
+
+// Load reads and checks the key file, if there is one and no access token
+func (c *Credentials) Load() error {
+	if len(c.AccessToken) > 0 || len(c.KeyFile) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(c.KeyFile)
+	if err != nil {
+		return fmt.Errorf("could not read service account key: %v", err)
+	}
+	key, err := parseKey(data)
+	if err != nil {
+		return fmt.Errorf("invalid service account key %s: %v", c.KeyFile, err)
+	}
+	c.key = key
+	return nil
+}
+
+// parseKey parses the JSON key of a service account
+func parseKey(data []byte) (*serviceAccountKey, error) {
+	var k serviceAccountKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, err
+	}
+	if k.Type != "service_account" || len(k.ClientEmail) == 0 {
+		return nil, fmt.Errorf("not the key of a service account")
+	}
+	block, _ := pem.Decode([]byte(k.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+	var ok bool
+	if k.rsa, ok = parsed.(*rsa.PrivateKey); !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	if len(k.TokenURI) == 0 {
+		k.TokenURI = defaultTokenURI
+	}
+	return &k, nil
+}
+
+// tokenSource returns the access token to authenticate requests with,
+// requesting a new one shortly before the last expires
+type tokenSource struct {
+	creds Credentials
+	http  *http.Client
+	now   func() time.Time
+
+	mu      sync.Mutex
+	current string
+	expires time.Time
+}
+
+func newTokenSource(creds Credentials, client *http.Client) *tokenSource {
+	return &tokenSource{creds: creds, http: client, now: time.Now}
+}
+
+// token returns the current token, or "" if requests are not authenticated
+func (s *tokenSource) token() (string, error) {
+	if len(s.creds.AccessToken) > 0 {
+		return s.creds.AccessToken, nil
+	}
+	key := s.creds.key
+	if key == nil {
+		return "", nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.current) > 0 && s.now().Before(s.expires) {
+		return s.current, nil
+	}
+
+	assertion, err := key.assertion(s.now())
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+	resp, err := s.http.Post(key.TokenURI, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var r struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil || len(r.AccessToken) == 0 {
+		if len(r.ErrorDescription) > 0 {
+			return "", fmt.Errorf("could not get Google token: %s", r.ErrorDescription)
+		}
+		return "", fmt.Errorf("could not get Google token: %s", resp.Status)
+	}
+	s.current = r.AccessToken
+	// renew the token a minute before it expires
+	s.expires = s.now().Add(time.Duration(r.ExpiresIn)*time.Second - time.Minute)
+	return s.current, nil
+}
+
+// assertion returns a JWT signed with the key, valid for an hour from now,
+// to exchange for an access token
+func (k *serviceAccountKey) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": k.PrivateKeyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   k.ClientEmail,
+		"scope": Scope,
+		"aud":   k.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,120 @@
+// Package gcp is a minimal client for the REST APIs of Google Cloud, which
+// the BigQuery and Bigtable loaders share. It authenticates requests with
+// OAuth tokens and turns the errors of the APIs into *Error.
+package gcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/httpclient"
+)
+
+// Client makes authenticated requests to the REST APIs, of their full URLs
+type Client struct {
+	*httpclient.Client
+	tokens *tokenSource
+}
+
+// NewClient returns a Client authenticating its requests with creds, which
+// must have been loaded
+func NewClient(creds Credentials) *Client {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	tokens := newTokenSource(creds, client)
+	return &Client{
+		Client: httpclient.New(httpclient.Config{
+			Name:   "gcp",
+			Header: http.Header{"Accept": {"application/json"}},
+			Auth: func() (string, error) {
+				token, err := tokens.token()
+				if len(token) == 0 || err != nil {
+					return "", err
+				}
+				return "Bearer " + token, nil
+			},
+			Error: func(status int, _ http.Header, body []byte) error {
+				return newError(status, body)
+			},
+			HTTP: client,
+		}),
+		tokens: tokens,
+	}
+}
+
+// Error is an error returned by an API
+type Error struct {
+	Status int
+	// Code is the canonical status of the error, e.g., NOT_FOUND, and Reason
+	// the more specific reason some APIs give, e.g., rateLimitExceeded
+	Code    string
+	Reason  string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if len(e.Code) > 0 {
+		return fmt.Sprintf("gcp: %d %s: %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("gcp: %d %s", e.Status, e.Message)
+}
+
+// NotFound returns whether the resource of the request does not exist
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// Throttled returns whether the request was rejected because of a quota or
+// an overloaded service, so it can be retried later
+func (e *Error) Throttled() bool {
+	switch {
+	case e.Status == http.StatusTooManyRequests || e.Status == http.StatusServiceUnavailable:
+		return true
+	case e.Reason == "rateLimitExceeded" || e.Reason == "backendError":
+		return true
+	}
+	return false
+}
+
+// Do makes a request with the given body, decoding the JSON response into
+// out, if not nil
+func (c *Client) Do(method, url, contentType string, body []byte, out interface{}) error {
+	header := http.Header{}
+	if body != nil {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := c.Client.Do(method, url, header, body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(resp) == 0 {
+		return nil
+	}
+	return c.Decode(method+" "+url, resp, out)
+}
+
+// newError returns the *Error of a response with the given status and body,
+// which holds the details of the error if it is JSON
+func newError(status int, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Error struct {
+			Status  string
+			Message string
+			Errors  []struct {
+				Reason string
+			}
+		}
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Error.Message) > 0 {
+		e.Code = r.Error.Status
+		e.Message = r.Error.Message
+		if len(r.Error.Errors) > 0 {
+			e.Reason = r.Error.Errors[0].Reason
+		}
+	} else {
+		e.Message = httpclient.Message(status, body)
+	}
+	return e
+}
@@ -0,0 +1,169 @@
+package gcp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestKey writes the key of a service account whose tokens are
+// requested from tokenURI to a new directory, returning its path and RSA key
+func writeTestKey(t *testing.T, tokenURI string) (string, *rsa.PrivateKey) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "tsbs@project.iam.gserviceaccount.com",
+		"private_key_id": "kid",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      tokenURI,
+	})
+	dir, err := ioutil.TempDir("", "gcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "key.json")
+	if err := ioutil.WriteFile(path, key, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, rsaKey
+}
+
+func TestClientWithServiceAccount(t *testing.T) {
+	var rsaKey *rsa.PrivateKey
+	tokens := 0
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			parts := strings.Split(r.Form.Get("assertion"), ".")
+			if len(parts) != 3 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+			var c struct{ Iss, Scope string }
+			json.Unmarshal(claims, &c)
+			if rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, sum[:], sig) != nil || c.Iss != "tsbs@project.iam.gserviceaccount.com" || c.Scope != Scope {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error_description":"bad assertion"}`))
+				return
+			}
+			tokens++
+			w.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+			return
+		}
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"id":"benchmark"}`))
+	}))
+	defer server.Close()
+
+	var path string
+	path, rsaKey = writeTestKey(t, server.URL+"/token")
+	defer os.RemoveAll(filepath.Dir(path))
+	creds := Credentials{KeyFile: path}
+	if err := creds.Load(); err != nil {
+		t.Fatalf("unexpected error loading key: %v", err)
+	}
+	c := NewClient(creds)
+	for i := 0; i < 2; i++ {
+		var out struct{ ID string }
+		if err := c.JSON(http.MethodGet, server.URL+"/datasets/benchmark", nil, &out); err != nil || out.ID != "benchmark" {
+			t.Fatalf("unexpected result: %v, %v", out, err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("token not reused: requested %d times", tokens)
+	}
+	if len(auth) != 2 || auth[0] != "Bearer token" {
+		t.Errorf("incorrect authorization: %v", auth)
+	}
+
+	// a new token is requested shortly before the last expires
+	c.tokens.now = func() time.Time { return time.Now().Add(59*time.Minute + time.Second) }
+	c.JSON(http.MethodGet, server.URL+"/datasets/benchmark", nil, nil)
+	if tokens != 2 {
+		t.Errorf("token not renewed: requested %d times", tokens)
+	}
+}
+
+func TestCredentialsLoad(t *testing.T) {
+	if err := (&Credentials{AccessToken: "token", KeyFile: "/does/not/exist"}).Load(); err != nil {
+		t.Errorf("unexpected error with an access token: %v", err)
+	}
+	if err := (&Credentials{}).Load(); err != nil {
+		t.Errorf("unexpected error without credentials: %v", err)
+	}
+	if err := (&Credentials{KeyFile: "/does/not/exist"}).Load(); err == nil {
+		t.Errorf("expected an error for a missing key file")
+	}
+	if _, err := parseKey([]byte(`{"type":"authorized_user"}`)); err == nil {
+		t.Errorf("expected an error for a key of a user")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	cases := []struct {
+		status    int
+		body      string
+		want      string
+		throttled bool
+		notFound  bool
+	}{
+		{
+			status:   http.StatusNotFound,
+			body:     `{"error":{"code":404,"message":"Not found: Dataset p:benchmark","errors":[{"reason":"notFound"}],"status":"NOT_FOUND"}}`,
+			want:     "gcp: 404 NOT_FOUND: Not found: Dataset p:benchmark",
+			notFound: true,
+		},
+		{
+			status:    http.StatusForbidden,
+			body:      `{"error":{"code":403,"message":"Exceeded rate limits","errors":[{"reason":"rateLimitExceeded"}],"status":"PERMISSION_DENIED"}}`,
+			want:      "gcp: 403 PERMISSION_DENIED: Exceeded rate limits",
+			throttled: true,
+		},
+		{
+			status: http.StatusForbidden,
+			body:   `{"error":{"code":403,"message":"Access denied","errors":[{"reason":"accessDenied"}],"status":"PERMISSION_DENIED"}}`,
+			want:   "gcp: 403 PERMISSION_DENIED: Access denied",
+		},
+		{status: http.StatusServiceUnavailable, body: "overloaded", want: "gcp: 503 overloaded", throttled: true},
+	}
+	for _, c := range cases {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body))
+		}))
+		err := NewClient(Credentials{}).JSON(http.MethodPost, server.URL, map[string]string{}, nil)
+		server.Close()
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("%d: expected an *Error, got %v", c.status, err)
+			continue
+		}
+		if e.Error() != c.want || e.Throttled() != c.throttled || e.NotFound() != c.notFound {
+			t.Errorf("%d: incorrect error: got %q, throttled %v, not found %v", c.status, e.Error(), e.Throttled(), e.NotFound())
+		}
+	}
+}
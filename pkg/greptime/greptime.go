@@ -0,0 +1,99 @@
+// Package greptime is a minimal client for the HTTP API of GreptimeDB, which
+// the loader and query runner of the greptime target share. It
+// authenticates requests with a username and password and turns error
+// responses into *Error.
+package greptime
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/timescale/tsbs/pkg/httpclient"
+)
+
+// PasswordEnv is the environment variable the password of requests is read
+// from by default
+const PasswordEnv = "GREPTIMEDB_PASSWORD"
+
+// SQLPath is the path of the SQL endpoint of the API
+const SQLPath = "/v1/sql"
+
+// Client makes requests to the HTTP API of a server
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a Client of the server at url, authenticating its
+// requests with username and password unless username is empty, as servers
+// without user providers expect
+func NewClient(url, username, password string) *Client {
+	header := http.Header{}
+	if len(username) > 0 {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+	return &Client{httpclient.New(httpclient.Config{
+		Name:   "greptime",
+		URL:    url,
+		Header: header,
+		Error: func(status int, _ http.Header, body []byte) error {
+			return newError(status, body)
+		},
+	})}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	Status int
+	// Code is the status code of GreptimeDB, if any, e.g., 4001 for a table
+	// which was not found
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("greptime: %d %s (code %d)", e.Status, e.Message, e.Code)
+	}
+	return fmt.Sprintf("greptime: %d %s", e.Status, e.Message)
+}
+
+// Throttled returns whether the request was rejected because of a limit or
+// an overloaded server, so it can be retried later
+func (e *Error) Throttled() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status == http.StatusServiceUnavailable
+}
+
+// SQL runs sql in database db, decoding the JSON response into out, if not
+// nil. The response has an output for each statement, of its rows or of the
+// number of rows it affected.
+func (c *Client) SQL(db, sql string, out interface{}) error {
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+	body := url.Values{"sql": {sql}}.Encode()
+	resp, err := c.Do(http.MethodPost, SQLPath+"?db="+url.QueryEscape(db), header, []byte(body))
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return c.Decode(sql, resp, out)
+}
+
+// newError returns the *Error of a response with the given status and body,
+// which holds the details of the error if it is JSON
+func newError(status int, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Code  int
+		Error string
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Error) > 0 {
+		e.Code, e.Message = r.Code, r.Error
+	} else {
+		e.Message = httpclient.Message(status, body)
+	}
+	return e
+}
@@ -0,0 +1,51 @@
+package greptime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientSQL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		switch {
+		case user != "greptime_user" || password != "secret":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":7002,"error":"User not found"}`))
+		case r.URL.Path != SQLPath || r.URL.Query().Get("db") != "public":
+			w.WriteHeader(http.StatusNotFound)
+		case r.FormValue("sql") != "SHOW DATABASES":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":1004,"error":"Failed to parse SQL","execution_time_ms":0}`))
+		default:
+			w.Write([]byte(`{"output":[{"records":{"rows":[["public"]]}}],"execution_time_ms":1}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/", "greptime_user", "secret")
+	var out struct {
+		Output []struct{ Records struct{ Rows [][]string } }
+	}
+	if err := c.SQL("public", "SHOW DATABASES", &out); err != nil || len(out.Output) != 1 || out.Output[0].Records.Rows[0][0] != "public" {
+		t.Errorf("incorrect response: %v, %v", out, err)
+	}
+	err := c.SQL("public", "SHOW", nil)
+	if e, ok := err.(*Error); !ok || e.Error() != "greptime: 400 Failed to parse SQL (code 1004)" || e.Throttled() {
+		t.Errorf("incorrect error: %v", err)
+	}
+	err = NewClient(server.URL, "", "").SQL("public", "SHOW DATABASES", nil)
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusUnauthorized || e.Code != 7002 {
+		t.Errorf("incorrect error without a user: %v", err)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	if e := newError(http.StatusTooManyRequests, []byte("slow down\n")); e.Error() != "greptime: 429 slow down" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+	if e := newError(http.StatusServiceUnavailable, nil); e.Error() != "greptime: 503 Service Unavailable" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+}
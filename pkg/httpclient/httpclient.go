@@ -0,0 +1,149 @@
+// Package httpclient is a minimal client for JSON HTTP APIs, which the
+// clients of the targets with such APIs (e.g., gcp, m3 and pinot) build on.
+// They give it the base URL and authentication of their API and how to turn
+// its error responses into errors.
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config is what a Client needs to know about an API
+type Config struct {
+	// Name is the name of the API the errors of invalid responses start
+	// with, e.g., "m3"
+	Name string
+	// URL is the base URL of the API, which the paths of requests are
+	// relative to, or empty if they are full URLs
+	URL string
+	// Header is sent with every request, e.g., its Authorization
+	Header http.Header
+	// Auth, if not nil, returns the Authorization of each request, for
+	// credentials which expire, such as OAuth tokens
+	Auth func() (string, error)
+	// Error returns the error of an unsuccessful response with the given
+	// status, header and body
+	Error func(status int, header http.Header, body []byte) error
+	// HTTP makes the requests; if nil, a client with a timeout of 5 minutes
+	HTTP *http.Client
+}
+
+// Client makes requests to an API
+type Client struct {
+	name   string
+	url    string
+	header http.Header
+	auth   func() (string, error)
+	error  func(status int, header http.Header, body []byte) error
+	http   *http.Client
+}
+
+// New returns a Client of the API of c
+func New(c Config) *Client {
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	return &Client{
+		name:   c.Name,
+		url:    strings.TrimSuffix(c.URL, "/"),
+		header: c.Header,
+		auth:   c.Auth,
+		error:  c.Error,
+		http:   client,
+	}
+}
+
+// URL returns the base URL of the API
+func (c *Client) URL() string {
+	return c.url
+}
+
+// JSON makes a request with in, if not nil, as its JSON body, decoding the
+// JSON response into out, if not nil
+func (c *Client) JSON(method, path string, in, out interface{}) error {
+	var body []byte
+	header := http.Header{}
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.Do(method, path, header, body)
+	if err != nil {
+		return err
+	}
+	if out == nil || len(resp) == 0 {
+		return nil
+	}
+	return c.Decode(method+" "+path, resp, out)
+}
+
+// Decode decodes resp, the JSON response to request, a description of it
+// for errors (e.g., its method and path), into out
+func (c *Client) Decode(request string, resp []byte, out interface{}) error {
+	if err := json.Unmarshal(resp, out); err != nil {
+		return fmt.Errorf("%s: invalid response to %s: %v", c.name, request, err)
+	}
+	return nil
+}
+
+// Do makes a request of path, which includes any query string, with the
+// given header, in addition to that of the Config, and body, returning the
+// response body, or the error of the Config if the request is not successful
+func (c *Client) Do(method, path string, header http.Header, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if c.auth != nil {
+		auth, err := c.auth()
+		if err != nil {
+			return nil, err
+		}
+		if len(auth) > 0 {
+			req.Header.Set("Authorization", auth)
+		}
+	}
+	req.Header.Set("User-Agent", "tsbs")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		if c.error == nil {
+			return nil, fmt.Errorf("%s: %d %s", c.name, resp.StatusCode, Message(resp.StatusCode, respBody))
+		}
+		return nil, c.error(resp.StatusCode, resp.Header, respBody)
+	}
+	return respBody, nil
+}
+
+// Message returns the message of an error response with the given status
+// and body whose details could not be parsed: the body, if not empty, or the
+// text of the status
+func Message(status int, body []byte) string {
+	if s := strings.TrimSpace(string(body)); len(s) > 0 {
+		return s
+	}
+	return http.StatusText(status)
+}
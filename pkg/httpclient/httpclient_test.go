@@ -0,0 +1,74 @@
+package httpclient
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDo(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			w.Write([]byte(`{"in":` + string(body) + `}`))
+		case "/invalid":
+			w.Write([]byte(`not json`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no such path\n"))
+		}
+	}))
+	defer server.Close()
+
+	errNotFound := errors.New("not found")
+	c := New(Config{
+		Name:   "test",
+		URL:    server.URL + "/",
+		Header: http.Header{"Accept": {"application/json"}},
+		Auth:   func() (string, error) { return "Bearer token", nil },
+		Error: func(status int, _ http.Header, body []byte) error {
+			if status == http.StatusNotFound && Message(status, body) == "no such path" {
+				return errNotFound
+			}
+			return nil
+		},
+	})
+	if c.URL() != server.URL {
+		t.Errorf("incorrect URL: got %s want %s", c.URL(), server.URL)
+	}
+	var out struct{ In struct{ Name string } }
+	if err := c.JSON(http.MethodPost, "/echo", map[string]string{"name": "a"}, &out); err != nil || out.In.Name != "a" {
+		t.Errorf("incorrect response: %v, %v", out, err)
+	}
+	for k, want := range map[string]string{"Accept": "application/json", "Authorization": "Bearer token", "Content-Type": "application/json", "User-Agent": "tsbs"} {
+		if got.Get(k) != want {
+			t.Errorf("incorrect %s header: got %q want %q", k, got.Get(k), want)
+		}
+	}
+	if _, err := c.Do(http.MethodGet, "/other", nil, nil); err != errNotFound {
+		t.Errorf("incorrect error: got %v want %v", err, errNotFound)
+	}
+	if err := c.JSON(http.MethodGet, "/invalid", nil, &out); err == nil {
+		t.Errorf("expected an error for an invalid response")
+	}
+
+	// without an Error, the message of the response is the error
+	c = New(Config{Name: "test", URL: server.URL})
+	if _, err := c.Do(http.MethodGet, "/other", nil, nil); err == nil || err.Error() != "test: 404 no such path" {
+		t.Errorf("incorrect default error: %v", err)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	if got := Message(http.StatusBadRequest, []byte(" bad query\n")); got != "bad query" {
+		t.Errorf("incorrect message of a body: got %q", got)
+	}
+	if got := Message(http.StatusServiceUnavailable, nil); got != "Service Unavailable" {
+		t.Errorf("incorrect message without a body: got %q", got)
+	}
+}
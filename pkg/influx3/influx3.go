@@ -0,0 +1,85 @@
+// Package influx3 is a minimal client for the HTTP API of InfluxDB 3, which
+// the loader and query runner of the influx3 target share. It authenticates
+// requests with a token and turns error responses into *Error.
+package influx3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/timescale/tsbs/pkg/httpclient"
+)
+
+// TokenEnv is the environment variable the token of requests is read from
+// by default
+const TokenEnv = "INFLUXDB3_AUTH_TOKEN"
+
+// Client makes requests to the HTTP API of a server
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a Client of the server at url, authenticating its
+// requests with token unless it is empty, as servers started without
+// authentication expect
+func NewClient(url, token string) *Client {
+	header := http.Header{}
+	if len(token) > 0 {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return &Client{httpclient.New(httpclient.Config{
+		Name:   "influx3",
+		URL:    url,
+		Header: header,
+		Error: func(status int, _ http.Header, body []byte) error {
+			return newError(status, body)
+		},
+	})}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("influx3: %d %s", e.Status, e.Message)
+}
+
+// NotFound returns whether the resource of the request, e.g., a database,
+// does not exist
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// Throttled returns whether the request was rejected because the server is
+// overloaded, so it can be retried later
+func (e *Error) Throttled() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status == http.StatusServiceUnavailable
+}
+
+// newError returns the *Error of a response with the given status and body,
+// which holds the details of the error if it is JSON. The details of the
+// first line a write rejected are added to its message.
+func newError(status int, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Error string
+		Data  json.RawMessage
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Error) > 0 {
+		e.Message = r.Error
+		var lines []struct {
+			LineNumber   int    `json:"line_number"`
+			ErrorMessage string `json:"error_message"`
+		}
+		if json.Unmarshal(r.Data, &lines) == nil && len(lines) > 0 {
+			e.Message += fmt.Sprintf(": line %d: %s", lines[0].LineNumber, lines[0].ErrorMessage)
+		}
+	} else {
+		e.Message = httpclient.Message(status, body)
+	}
+	return e
+}
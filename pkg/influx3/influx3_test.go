@@ -0,0 +1,69 @@
+package influx3
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/api/v3/query_sql":
+			w.Write([]byte(`[{"n":` + string(body) + `}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"database not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/", "token")
+	var out []struct{ N int }
+	if err := c.JSON(http.MethodPost, "/api/v3/query_sql", 1, &out); err != nil || len(out) != 1 || out[0].N != 1 {
+		t.Errorf("incorrect response: %v, %v", out, err)
+	}
+	_, err := c.Do(http.MethodGet, "/api/v3/other", nil, nil)
+	if e, ok := err.(*Error); !ok || !e.NotFound() || e.Message != "database not found" {
+		t.Errorf("incorrect error: %v", err)
+	}
+	_, err = NewClient(server.URL, "").Do(http.MethodGet, "/", nil, nil)
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusUnauthorized || e.Message != "Unauthorized" {
+		t.Errorf("incorrect error without token: %v", err)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	cases := []struct {
+		desc      string
+		status    int
+		body      string
+		want      string
+		throttled bool
+	}{
+		{
+			desc:   "rejected line",
+			status: http.StatusBadRequest,
+			body:   `{"error":"parsing failed for write_lp endpoint","data":[{"original_line":"cpu x","line_number":2,"error_message":"invalid field"}]}`,
+			want:   "influx3: 400 parsing failed for write_lp endpoint: line 2: invalid field",
+		},
+		{
+			desc:   "no details",
+			status: http.StatusInternalServerError,
+			body:   `{"error":"internal error","data":null}`,
+			want:   "influx3: 500 internal error",
+		},
+		{desc: "plain text", status: http.StatusTooManyRequests, body: "slow down\n", want: "influx3: 429 slow down", throttled: true},
+		{desc: "empty", status: http.StatusServiceUnavailable, want: "influx3: 503 Service Unavailable", throttled: true},
+	}
+	for _, c := range cases {
+		e := newError(c.status, []byte(c.body))
+		if e.Error() != c.want || e.Throttled() != c.throttled {
+			t.Errorf("%s: incorrect error: got %q (throttled %v)", c.desc, e.Error(), e.Throttled())
+		}
+	}
+}
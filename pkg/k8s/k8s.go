@@ -0,0 +1,352 @@
+// Package k8s runs a benchmark on Kubernetes: it renders the generation,
+// load and query phases as Jobs, which share a config file in a ConfigMap
+// and the generated data and queries on a PersistentVolumeClaim, and
+// launches them one after the other with kubectl.
+//
+// Each Job has a results sidecar, which waits for the tool to finish, then
+// copies its output and metadata (see cli.Metadata) to the claim under
+// results/<phase> and prints them to its log, so they can be collected with
+// kubectl logs even once the Job's pod is gone from the node.
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"gopkg.in/yaml.v3"
+)
+
+// Phases of a benchmark, in the order they are run
+const (
+	PhaseGenerate = "generate"
+	PhaseLoad     = "load"
+	PhaseRun      = "run"
+)
+
+// Phases are all phases of a benchmark, in the order they are run
+var Phases = []string{PhaseGenerate, PhaseLoad, PhaseRun}
+
+const (
+	// DefaultResultsImage is the image of the results sidecar, which only
+	// needs a shell
+	DefaultResultsImage = "busybox:1.36"
+	// DefaultStorage is the default size of the data claim
+	DefaultStorage = "10Gi"
+
+	configDir  = "/config"
+	dataDir    = "/data"
+	resultsDir = "/results"
+	// doneFile is written by the tool's container once it is done, hidden
+	// so it is not collected as a result
+	doneFile = resultsDir + "/.done"
+
+	dataFile    = dataDir + "/data.txt"
+	queriesFile = dataDir + "/queries.dat"
+)
+
+// names are those of Kubernetes objects (DNS-1123 labels), short enough to
+// be suffixed with the phase
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+const maxNameLen = 50
+
+// Spec is a benchmark to run on Kubernetes
+type Spec struct {
+	// Name prefixes the names of all objects, e.g., to tell runs apart
+	Name      string
+	Namespace string
+	// Image is the image of the tools, which must have the tsbs binary on
+	// its PATH and a shell
+	Image string
+	// Target is the database loaded and queried, e.g., timescaledb
+	Target string
+	// QueryType is the type of queries generated
+	QueryType string
+	// ConfigFile is the name of the config file read by the tools (see
+	// cli.ParseFlags), whose extension gives its format, and Config its
+	// contents. It sets the use case, scale and everything else, including
+	// how to reach the database, e.g., in a tsbs_load_<target> section.
+	ConfigFile string
+	Config     []byte
+	// Phases are the phases to run; without generate, the data and queries
+	// of an earlier run of the same Name are used
+	Phases []string
+	// Storage is the size, e.g., 10Gi, of the data claim, and StorageClass
+	// its class, or the cluster's default if empty
+	Storage      string
+	StorageClass string
+	ResultsImage string
+}
+
+// Validate checks that s is complete and valid
+func (s *Spec) Validate() error {
+	if !nameRegexp.MatchString(s.Name) || len(s.Name) > maxNameLen {
+		return fmt.Errorf("invalid name '%s': must be at most %d lower case letters, digits and dashes", s.Name, maxNameLen)
+	}
+	if len(s.Image) == 0 {
+		return fmt.Errorf("no image of the tools given")
+	}
+	if len(s.Target) == 0 {
+		return fmt.Errorf("no target given")
+	}
+	if len(s.Phases) == 0 {
+		return fmt.Errorf("no phases given")
+	}
+	for i, p := range s.Phases {
+		if !contains(Phases, p) {
+			return fmt.Errorf("unknown phase '%s' (valid choices: %s)", p, strings.Join(Phases, ", "))
+		}
+		if i > 0 && index(Phases, p) <= index(Phases, s.Phases[i-1]) {
+			return fmt.Errorf("phases must be given once each in the order %s", strings.Join(Phases, ", "))
+		}
+	}
+	if len(s.QueryType) == 0 && (contains(s.Phases, PhaseGenerate) || contains(s.Phases, PhaseRun)) {
+		return fmt.Errorf("no query type given")
+	}
+	if len(s.ConfigFile) == 0 || len(path.Ext(s.ConfigFile)) == 0 {
+		return fmt.Errorf("invalid config file name '%s': its extension must give its format, e.g., tsbs.yaml", s.ConfigFile)
+	}
+	return nil
+}
+
+// JobName returns the name of the Job of phase
+func (s *Spec) JobName(phase string) string {
+	return s.Name + "-" + phase
+}
+
+func (s *Spec) configMapName() string { return s.Name + "-config" }
+
+func (s *Spec) claimName() string { return s.Name + "-data" }
+
+// Render writes the manifests of all objects of s, as a YAML stream of the
+// ConfigMap, the claim and then the Jobs in the order they are run. Applied
+// at once, the Jobs would run at the same time; Launch runs them in turn.
+func Render(w io.Writer, s *Spec) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	objs := []*object{s.configMap(), s.claim()}
+	for _, p := range s.Phases {
+		objs = append(objs, s.job(p))
+	}
+	return writeObjects(w, objs...)
+}
+
+func writeObjects(w io.Writer, objs ...*object) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, o := range objs {
+		if err := enc.Encode(o); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// object is the subset of a Kubernetes object's fields that is rendered
+type object struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   metadata          `yaml:"metadata"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	Spec       interface{}       `yaml:"spec,omitempty"`
+}
+
+type metadata struct {
+	Name      string            `yaml:"name,omitempty"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
+
+type claimSpec struct {
+	AccessModes      []string  `yaml:"accessModes"`
+	StorageClassName string    `yaml:"storageClassName,omitempty"`
+	Resources        resources `yaml:"resources"`
+}
+
+type resources struct {
+	Requests map[string]string `yaml:"requests"`
+}
+
+type jobSpec struct {
+	// BackoffLimit is 0, as a benchmark retried from the start measures
+	// something else
+	BackoffLimit int         `yaml:"backoffLimit"`
+	Template     podTemplate `yaml:"template"`
+}
+
+type podTemplate struct {
+	Metadata metadata `yaml:"metadata"`
+	Spec     podSpec  `yaml:"spec"`
+}
+
+type podSpec struct {
+	RestartPolicy string      `yaml:"restartPolicy"`
+	Containers    []container `yaml:"containers"`
+	Volumes       []volume    `yaml:"volumes"`
+}
+
+type container struct {
+	Name         string        `yaml:"name"`
+	Image        string        `yaml:"image"`
+	Command      []string      `yaml:"command"`
+	VolumeMounts []volumeMount `yaml:"volumeMounts"`
+}
+
+type volumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+type volume struct {
+	Name                  string        `yaml:"name"`
+	ConfigMap             *configMapRef `yaml:"configMap,omitempty"`
+	PersistentVolumeClaim *claimRef     `yaml:"persistentVolumeClaim,omitempty"`
+	EmptyDir              *struct{}     `yaml:"emptyDir,omitempty"`
+}
+
+type configMapRef struct {
+	Name string `yaml:"name"`
+}
+
+type claimRef struct {
+	ClaimName string `yaml:"claimName"`
+}
+
+// meta returns the metadata of the object of s named name, labeled with
+// phase if given
+func (s *Spec) meta(name, phase string) metadata {
+	labels := map[string]string{
+		"app.kubernetes.io/name":     "tsbs",
+		"app.kubernetes.io/instance": s.Name,
+	}
+	if len(phase) > 0 {
+		labels["app.kubernetes.io/component"] = phase
+	}
+	return metadata{Name: name, Namespace: s.Namespace, Labels: labels}
+}
+
+func (s *Spec) configMap() *object {
+	return &object{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   s.meta(s.configMapName(), ""),
+		Data:       map[string]string{s.ConfigFile: string(s.Config)},
+	}
+}
+
+func (s *Spec) claim() *object {
+	storage := s.Storage
+	if len(storage) == 0 {
+		storage = DefaultStorage
+	}
+	return &object{
+		APIVersion: "v1",
+		Kind:       "PersistentVolumeClaim",
+		Metadata:   s.meta(s.claimName(), ""),
+		Spec: claimSpec{
+			// the Jobs run one after the other, so one node at a time
+			AccessModes:      []string{"ReadWriteOnce"},
+			StorageClassName: s.StorageClass,
+			Resources:        resources{Requests: map[string]string{"storage": storage}},
+		},
+	}
+}
+
+func (s *Spec) job(phase string) *object {
+	resultsImage := s.ResultsImage
+	if len(resultsImage) == 0 {
+		resultsImage = DefaultResultsImage
+	}
+	mounts := []volumeMount{
+		{Name: "config", MountPath: configDir},
+		{Name: "data", MountPath: dataDir},
+		{Name: "results", MountPath: resultsDir},
+	}
+	return &object{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+		Metadata:   s.meta(s.JobName(phase), phase),
+		Spec: jobSpec{
+			Template: podTemplate{
+				Metadata: metadata{Labels: s.meta("", phase).Labels},
+				Spec: podSpec{
+					RestartPolicy: "Never",
+					Containers: []container{
+						{Name: "tsbs", Image: s.Image, Command: []string{"sh", "-c", s.toolScript(phase)}, VolumeMounts: mounts},
+						{Name: "results", Image: resultsImage, Command: []string{"sh", "-c", resultsScript(phase)}, VolumeMounts: mounts[1:]},
+					},
+					Volumes: []volume{
+						{Name: "config", ConfigMap: &configMapRef{Name: s.configMapName()}},
+						{Name: "data", PersistentVolumeClaim: &claimRef{ClaimName: s.claimName()}},
+						{Name: "results", EmptyDir: &struct{}{}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// toolScript returns the script running the tools of phase, writing their
+// output and metadata to the results directory and then marking it done,
+// whether they succeed or not, so the sidecar always collects the results
+func (s *Spec) toolScript(phase string) string {
+	config := "-config=" + shellQuote(path.Join(configDir, s.ConfigFile))
+	metadata := func(name string) string {
+		return "-" + cli.MetadataFlag + "=" + path.Join(resultsDir, name+".json")
+	}
+	var cmd string
+	switch phase {
+	case PhaseGenerate:
+		cmd = fmt.Sprintf("tsbs generate data %s -file=%s %s && tsbs generate queries %s -query-type=%s -file=%s %s",
+			config, dataFile, metadata("generate-data"),
+			config, shellQuote(s.QueryType), queriesFile, metadata("generate-queries"))
+	case PhaseLoad:
+		cmd = fmt.Sprintf("tsbs load %s %s %s < %s", shellQuote(s.Target), config, metadata(phase), dataFile)
+	case PhaseRun:
+		cmd = fmt.Sprintf("tsbs run %s %s %s < %s", shellQuote(s.Target), config, metadata(phase), queriesFile)
+	}
+	return fmt.Sprintf("(%s) > %s/%s.txt; rc=$?; touch %s; exit $rc", cmd, resultsDir, phase, doneFile)
+}
+
+// resultsScript returns the script of the sidecar collecting the results of
+// phase
+func resultsScript(phase string) string {
+	dir := path.Join(dataDir, "results", phase)
+	return strings.Join([]string{
+		"until [ -e " + doneFile + " ]; do sleep 1; done",
+		"mkdir -p " + dir,
+		"cp " + resultsDir + "/* " + dir,
+		"for f in " + resultsDir + "/*; do echo \"==> $f <==\"; cat \"$f\"; done",
+	}, "\n")
+}
+
+// shellQuote quotes s as a single word for sh
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func contains(list []string, s string) bool {
+	return index(list, s) >= 0
+}
+
+func index(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// renderJob returns the manifest of the Job of phase alone
+func (s *Spec) renderJob(phase string) ([]byte, error) {
+	var b bytes.Buffer
+	err := writeObjects(&b, s.job(phase))
+	return b.Bytes(), err
+}
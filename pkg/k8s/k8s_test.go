@@ -0,0 +1,185 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func testSpec() *Spec {
+	return &Spec{
+		Name:       "bench",
+		Namespace:  "tsbs",
+		Image:      "tsbs:latest",
+		Target:     "timescaledb",
+		QueryType:  "lastpoint",
+		ConfigFile: "tsbs.yaml",
+		Config:     []byte("format: timescaledb\n"),
+		Phases:     append([]string(nil), Phases...),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		desc      string
+		change    func(s *Spec)
+		wantError string
+	}{
+		{desc: "valid", change: func(s *Spec) {}},
+		{desc: "load only needs no query type", change: func(s *Spec) { s.Phases = []string{PhaseLoad}; s.QueryType = "" }},
+		{desc: "invalid name", change: func(s *Spec) { s.Name = "Bench_1" }, wantError: "invalid name"},
+		{desc: "long name", change: func(s *Spec) { s.Name = strings.Repeat("a", maxNameLen+1) }, wantError: "invalid name"},
+		{desc: "no image", change: func(s *Spec) { s.Image = "" }, wantError: "no image"},
+		{desc: "no target", change: func(s *Spec) { s.Target = "" }, wantError: "no target"},
+		{desc: "unknown phase", change: func(s *Spec) { s.Phases = []string{"query"} }, wantError: "unknown phase 'query'"},
+		{desc: "phases out of order", change: func(s *Spec) { s.Phases = []string{PhaseRun, PhaseLoad} }, wantError: "in the order"},
+		{desc: "repeated phase", change: func(s *Spec) { s.Phases = []string{PhaseLoad, PhaseLoad} }, wantError: "in the order"},
+		{desc: "no query type", change: func(s *Spec) { s.QueryType = "" }, wantError: "no query type"},
+		{desc: "config without extension", change: func(s *Spec) { s.ConfigFile = "tsbs" }, wantError: "invalid config file name"},
+	}
+	for _, c := range cases {
+		s := testSpec()
+		c.change(s)
+		err := s.Validate()
+		if c.wantError == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		} else if c.wantError != "" && (err == nil || !strings.Contains(err.Error(), c.wantError)) {
+			t.Errorf("%s: incorrect error: got %v want %q", c.desc, err, c.wantError)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	var b bytes.Buffer
+	if err := Render(&b, testSpec()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dec := yaml.NewDecoder(&b)
+	var kinds, names []string
+	var jobs []map[string]interface{}
+	for {
+		var o map[string]interface{}
+		if err := dec.Decode(&o); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("could not decode manifest: %v", err)
+		}
+		meta := o["metadata"].(map[string]interface{})
+		if meta["namespace"] != "tsbs" {
+			t.Errorf("incorrect namespace of %s: %v", meta["name"], meta["namespace"])
+		}
+		kinds = append(kinds, o["kind"].(string))
+		names = append(names, meta["name"].(string))
+		if o["kind"] == "Job" {
+			jobs = append(jobs, o)
+		}
+	}
+	wantKinds := "ConfigMap,PersistentVolumeClaim,Job,Job,Job"
+	wantNames := "bench-config,bench-data,bench-generate,bench-load,bench-run"
+	if got := strings.Join(kinds, ","); got != wantKinds {
+		t.Errorf("incorrect kinds: got %s want %s", got, wantKinds)
+	}
+	if got := strings.Join(names, ","); got != wantNames {
+		t.Errorf("incorrect names: got %s want %s", got, wantNames)
+	}
+
+	// the tools read the config from the ConfigMap and the data from the
+	// claim, and the sidecar waits for them to be done
+	wantScripts := []string{
+		"tsbs generate queries -config='/config/tsbs.yaml' -query-type='lastpoint' -file=/data/queries.dat",
+		"tsbs load 'timescaledb' -config='/config/tsbs.yaml' -metadata-file=/results/load.json < /data/data.txt",
+		"tsbs run 'timescaledb' -config='/config/tsbs.yaml' -metadata-file=/results/run.json < /data/queries.dat",
+	}
+	for i, job := range jobs {
+		out, err := yaml.Marshal(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{wantScripts[i], "touch /results/.done", "until [ -e /results/.done ]", "backoffLimit: 0", "claimName: bench-data", "name: bench-config"} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("job %d missing %q:\n%s", i, want, out)
+			}
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got, want := shellQuote("it's"), `'it'\''s'`; got != want {
+		t.Errorf("incorrect quoting: got %s want %s", got, want)
+	}
+}
+
+// fakeKubectl records the commands run, answering get job with the status
+// of the job in statuses, if any, and logs with its name
+type fakeKubectl struct {
+	commands []string
+	statuses map[string][]string
+}
+
+func (k *fakeKubectl) run(_ context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+	cmd := strings.Join(args, " ")
+	if stdin != nil {
+		in, _ := ioutil.ReadAll(stdin)
+		for _, line := range strings.Split(string(in), "\n") {
+			if strings.HasPrefix(line, "kind: ") {
+				cmd += " " + strings.TrimPrefix(line, "kind: ")
+			}
+		}
+	}
+	k.commands = append(k.commands, cmd)
+	switch args[0] {
+	case "get":
+		statuses := k.statuses[args[1]]
+		if len(statuses) == 0 {
+			return fmt.Errorf("no status of %s", args[1])
+		}
+		fmt.Fprint(stdout, statuses[0])
+		k.statuses[args[1]] = statuses[1:]
+	case "logs":
+		fmt.Fprintf(stdout, "results of %s\n", args[1])
+	}
+	return nil
+}
+
+func TestLaunch(t *testing.T) {
+	defer func(old time.Duration) { pollInterval = old }(pollInterval)
+	pollInterval = time.Millisecond
+
+	k := &fakeKubectl{statuses: map[string][]string{
+		"job/bench-generate": {",", "1,"},
+		"job/bench-load":     {"1,"},
+		"job/bench-run":      {",1"},
+	}}
+	var out bytes.Buffer
+	err := Launch(context.Background(), testSpec(), k.run, &out)
+	if err == nil || !strings.Contains(err.Error(), "job bench-run failed") {
+		t.Errorf("incorrect error: got %v", err)
+	}
+	want := []string{
+		"apply -f - ConfigMap PersistentVolumeClaim",
+		"apply -f - Job",
+		"get job/bench-generate -o jsonpath={.status.succeeded},{.status.failed} -n tsbs",
+		"get job/bench-generate -o jsonpath={.status.succeeded},{.status.failed} -n tsbs",
+		"logs job/bench-generate -c results -n tsbs",
+		"apply -f - Job",
+		"get job/bench-load -o jsonpath={.status.succeeded},{.status.failed} -n tsbs",
+		"logs job/bench-load -c results -n tsbs",
+		"apply -f - Job",
+		"get job/bench-run -o jsonpath={.status.succeeded},{.status.failed} -n tsbs",
+		"logs job/bench-run -c results -n tsbs",
+	}
+	if got := strings.Join(k.commands, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("incorrect commands:\ngot\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+	// the results of the failed job are collected too
+	if !strings.Contains(out.String(), "# bench-run\nresults of job/bench-run") {
+		t.Errorf("results missing:\n%s", out.String())
+	}
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// Kubectl runs kubectl with args, reading stdin and writing its output to
+// stdout
+type Kubectl func(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error
+
+// ExecKubectl returns a Kubectl running the kubectl binary bin, which logs
+// its errors to stderr
+func ExecKubectl(bin string) Kubectl {
+	return func(ctx context.Context, stdin io.Reader, stdout io.Writer, args ...string) error {
+		cmd := exec.CommandContext(ctx, bin, args...)
+		cmd.Stdin = stdin
+		cmd.Stdout = stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s: %v", bin, strings.Join(args, " "), err)
+		}
+		return nil
+	}
+}
+
+// pollInterval is how often the status of a running Job is checked; it is a
+// variable for ease of testing
+var pollInterval = 5 * time.Second
+
+// Launch applies the ConfigMap and claim of s, then runs the Job of each
+// phase in turn, waiting for it to finish and writing the results collected
+// by its sidecar to out. It stops at the first Job that fails, and returns
+// cli.ErrInterrupted if ctx is done first, leaving the running Job be.
+//
+// Jobs cannot be changed once created, so those of an earlier run of the
+// same Name must be deleted first (kubectl delete job -l
+// app.kubernetes.io/instance=<name>).
+func Launch(ctx context.Context, s *Spec, kubectl Kubectl, out io.Writer) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := writeObjects(&b, s.configMap(), s.claim()); err != nil {
+		return err
+	}
+	if err := kubectl(ctx, &b, os.Stderr, "apply", "-f", "-"); err != nil {
+		return err
+	}
+	for _, phase := range s.Phases {
+		name := s.JobName(phase)
+		manifest, err := s.renderJob(phase)
+		if err != nil {
+			return err
+		}
+		logging.Info("starting job", "job", name)
+		if err := kubectl(ctx, bytes.NewReader(manifest), os.Stderr, "apply", "-f", "-"); err != nil {
+			return err
+		}
+		succeeded, err := waitJob(ctx, s, name, kubectl)
+		if err != nil {
+			if ctx.Err() != nil {
+				logging.Warn("interrupted while waiting for job, which is left running", "job", name)
+				return cli.ErrInterrupted
+			}
+			return err
+		}
+		fmt.Fprintf(out, "# %s\n", name)
+		if err := kubectl(ctx, nil, out, s.namespaceArgs("logs", "job/"+name, "-c", "results")...); err != nil {
+			logging.Warn("could not collect results", "job", name, "error", err)
+		}
+		if !succeeded {
+			return fmt.Errorf("job %s failed (see kubectl logs job/%s -c tsbs)", name, name)
+		}
+		logging.Info("job completed", "job", name)
+	}
+	return nil
+}
+
+// waitJob waits for the Job name to finish, returning whether it succeeded
+func waitJob(ctx context.Context, s *Spec, name string, kubectl Kubectl) (bool, error) {
+	for {
+		var status bytes.Buffer
+		err := kubectl(ctx, nil, &status, s.namespaceArgs("get", "job/"+name, "-o", "jsonpath={.status.succeeded},{.status.failed}")...)
+		if err != nil {
+			return false, err
+		}
+		counts := strings.SplitN(strings.TrimSpace(status.String()), ",", 2)
+		if counts[0] != "" && counts[0] != "0" {
+			return true, nil
+		}
+		if len(counts) == 2 && counts[1] != "" && counts[1] != "0" {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// namespaceArgs returns args of kubectl for the namespace of s
+func (s *Spec) namespaceArgs(args ...string) []string {
+	if len(s.Namespace) > 0 {
+		args = append(args, "-n", s.Namespace)
+	}
+	return args
+}
@@ -0,0 +1,127 @@
+package kusto
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAuthority is where Azure AD tokens are requested
+const defaultAuthority = "https://login.microsoftonline.com"
+
+// Credentials authenticate requests to a cluster, either with an access token
+// or with the client secret of an Azure AD application, which tokens are then
+// requested for. Without either, requests are not authenticated, as the ADX
+// emulator expects.
+type Credentials struct {
+	AccessToken  string
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+
+	// authority is where tokens are requested, if not from Azure AD
+	authority string
+}
+
+// CredentialsFromEnv returns the credentials in the environment: an access
+// token in KUSTO_ACCESS_TOKEN (e.g., from az account get-access-token), or
+// the AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET of an
+// application
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AccessToken:  os.Getenv("KUSTO_ACCESS_TOKEN"),
+		TenantID:     os.Getenv("AZURE_TENANT_ID"),
+		ClientID:     os.Getenv("AZURE_CLIENT_ID"),
+		ClientSecret: os.Getenv("AZURE_CLIENT_SECRET"),
+	}
+}
+
+// Validate returns an error if the credentials of an application are only
+// partly set
+func (c Credentials) Validate() error {
+	if len(c.AccessToken) > 0 {
+		return nil
+	}
+	set := 0
+	for _, v := range []string{c.TenantID, c.ClientID, c.ClientSecret} {
+		if len(v) > 0 {
+			set++
+		}
+	}
+	if set != 0 && set != 3 {
+		return fmt.Errorf("incomplete Azure credentials: set all of AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET")
+	}
+	return nil
+}
+
+// tokenSource returns the access token to authenticate requests with,
+// requesting a new one shortly before the last expires
+type tokenSource struct {
+	creds    Credentials
+	resource string
+	http     *http.Client
+	now      func() time.Time
+
+	mu      sync.Mutex
+	current string
+	expires time.Time
+}
+
+func newTokenSource(creds Credentials, resource string, client *http.Client) *tokenSource {
+	if len(creds.authority) == 0 {
+		creds.authority = defaultAuthority
+	}
+	return &tokenSource{creds: creds, resource: resource, http: client, now: time.Now}
+}
+
+// token returns the current token, or "" if requests are not authenticated
+func (s *tokenSource) token() (string, error) {
+	if len(s.creds.AccessToken) > 0 {
+		return s.creds.AccessToken, nil
+	}
+	if len(s.creds.ClientID) == 0 {
+		return "", nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.current) > 0 && s.now().Before(s.expires) {
+		return s.current, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.creds.ClientID)
+	form.Set("client_secret", s.creds.ClientSecret)
+	form.Set("scope", s.resource+"/.default")
+	u := fmt.Sprintf("%s/%s/oauth2/v2.0/token", s.creds.authority, url.PathEscape(s.creds.TenantID))
+	resp, err := s.http.Post(u, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	var r struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &r); err != nil || len(r.AccessToken) == 0 {
+		if len(r.ErrorDescription) > 0 {
+			return "", fmt.Errorf("could not get Azure AD token: %s", r.ErrorDescription)
+		}
+		return "", fmt.Errorf("could not get Azure AD token: %s", resp.Status)
+	}
+	s.current = r.AccessToken
+	// renew the token a minute before it expires
+	s.expires = s.now().Add(time.Duration(r.ExpiresIn)*time.Second - time.Minute)
+	return s.current, nil
+}
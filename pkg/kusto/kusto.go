@@ -0,0 +1,235 @@
+// Package kusto is a minimal client for the REST API of Azure Data Explorer
+// (Kusto), which the ADX loader and query runner share. It runs control
+// commands and queries, and ingests data with streaming or queued ingestion.
+package kusto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Ingestion methods
+const (
+	// Streaming sends data to the cluster, which ingests it before answering
+	Streaming = "streaming"
+	// Queued uploads data to the storage of the cluster and queues it to be
+	// ingested in the background
+	Queued = "queued"
+)
+
+// Client makes requests to the REST API of a cluster
+type Client struct {
+	// URL is the URL of the cluster, e.g., https://mycluster.westeurope.kusto.windows.net
+	URL string
+
+	http   *http.Client
+	tokens *tokenSource
+}
+
+// NewClient returns a Client for the cluster at clusterURL, authenticating
+// its requests with creds
+func NewClient(clusterURL string, creds Credentials) *Client {
+	clusterURL = strings.TrimSuffix(clusterURL, "/")
+	client := &http.Client{Timeout: 5 * time.Minute}
+	return &Client{
+		URL:    clusterURL,
+		http:   client,
+		tokens: newTokenSource(creds, clusterURL, client),
+	}
+}
+
+// IngestURL returns the URL of the data management endpoint of the cluster at
+// clusterURL, which queued ingestion goes through: the cluster's host name
+// prefixed by ingest-
+func IngestURL(clusterURL string) string {
+	u, err := url.Parse(clusterURL)
+	if err != nil || len(u.Host) == 0 || strings.HasPrefix(u.Host, "ingest-") {
+		return clusterURL
+	}
+	u.Host = "ingest-" + u.Host
+	return strings.TrimSuffix(u.String(), "/")
+}
+
+// Error is an error returned by the REST API
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if len(e.Code) > 0 {
+		return fmt.Sprintf("kusto: %d %s: %s", e.Status, e.Code, e.Message)
+	}
+	return fmt.Sprintf("kusto: %d %s", e.Status, e.Message)
+}
+
+// Throttled returns whether the request was rejected because the cluster is
+// too busy, so it can be retried later
+func (e *Error) Throttled() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status == http.StatusServiceUnavailable
+}
+
+// Result is the result of a control command, in the v1 response format
+type Result struct {
+	Tables []Table
+}
+
+// Table is one of the tables of a Result
+type Table struct {
+	TableName string
+	Columns   []struct {
+		ColumnName string
+		DataType   string
+	}
+	Rows [][]interface{}
+}
+
+// Column returns the values of the named column of the first table of the
+// Result, as strings, or nil if it has no such column
+func (r *Result) Column(name string) []string {
+	if len(r.Tables) == 0 {
+		return nil
+	}
+	t := r.Tables[0]
+	for i, c := range t.Columns {
+		if c.ColumnName != name {
+			continue
+		}
+		values := make([]string, 0, len(t.Rows))
+		for _, row := range t.Rows {
+			if i < len(row) {
+				values = append(values, fmt.Sprint(row[i]))
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Mgmt runs the control command csl in the database db
+func (c *Client) Mgmt(db, csl string) (*Result, error) {
+	body, err := c.post("/v1/rest/mgmt", db, csl)
+	if err != nil {
+		return nil, err
+	}
+	var r Result
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, fmt.Errorf("kusto: invalid response to %q: %v", csl, err)
+	}
+	return &r, nil
+}
+
+// Query runs the query csl in the database db, returning the response as is
+func (c *Client) Query(db, csl string) ([]byte, error) {
+	return c.post("/v1/rest/query", db, csl)
+}
+
+func (c *Client) post(path, db, csl string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"db": db, "csl": csl})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return c.do(req)
+}
+
+// StreamIngest ingests data, in the given format (e.g., csv or json), into a
+// table with streaming ingestion, using the named ingestion mapping. The data
+// is compressed with gzip on the way.
+func (c *Client) StreamIngest(db, table, format, mapping string, data []byte) error {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	v := url.Values{}
+	v.Set("streamFormat", format)
+	if len(mapping) > 0 {
+		v.Set("mappingName", mapping)
+	}
+	u := fmt.Sprintf("%s/v1/rest/ingest/%s/%s?%s", c.URL, url.PathEscape(db), url.PathEscape(table), v.Encode())
+	req, err := http.NewRequest(http.MethodPost, u, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "gzip")
+	_, err = c.do(req)
+	return err
+}
+
+// do makes an authenticated request, returning the response body, or an
+// *Error if it is not successful
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	token, err := c.tokens.token()
+	if err != nil {
+		return nil, err
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-ms-app", "tsbs")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, newError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// newError returns the *Error of a response with the given status and body,
+// which holds the details of the error if it is JSON
+func newError(status int, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Error struct {
+			Code        string
+			Message     string
+			MessageText string `json:"@message"`
+		}
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Error.Code) > 0 {
+		e.Code = r.Error.Code
+		e.Message = r.Error.Message
+		if len(r.Error.MessageText) > 0 {
+			e.Message = r.Error.MessageText
+		}
+	} else if s := strings.TrimSpace(string(body)); len(s) > 0 {
+		e.Message = s
+	}
+	return e
+}
+
+// QuoteName returns name as a Kusto identifier, e.g., ['cpu']
+func QuoteName(name string) string {
+	return "[" + QuoteString(name) + "]"
+}
+
+// QuoteString returns s as a single-quoted Kusto string literal
+func QuoteString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return "'" + r.Replace(s) + "'"
+}
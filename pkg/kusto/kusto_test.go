@@ -0,0 +1,276 @@
+package kusto
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testCluster is a fake cluster, along with its storage and Azure AD,
+// recording the requests made to it
+type testCluster struct {
+	t        *testing.T
+	mu       sync.Mutex
+	requests []string
+	commands []string
+	tokens   int
+	// status, if set, is returned for every request to the cluster
+	status int
+	body   string
+	// ingested are the decompressed bodies of streaming ingestions and blob
+	// uploads, and messages the messages queued
+	ingested []string
+	messages []map[string]interface{}
+	server   *httptest.Server
+}
+
+func newTestCluster(t *testing.T) *testCluster {
+	c := &testCluster{t: t}
+	c.server = httptest.NewServer(c)
+	return c
+}
+
+func (c *testCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+		w.Write([]byte(c.body))
+		return
+	}
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token"):
+		r.ParseForm()
+		if r.Form.Get("client_secret") != "secret" || r.Form.Get("scope") != c.server.URL+"/.default" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_description":"bad secret"}`))
+			return
+		}
+		c.tokens++
+		w.Write([]byte(`{"access_token":"token","expires_in":3600}`))
+	case r.URL.Path == "/v1/rest/mgmt" || r.URL.Path == "/v1/rest/query":
+		var in struct{ DB, CSL string }
+		json.NewDecoder(r.Body).Decode(&in)
+		c.commands = append(c.commands, in.DB+": "+in.CSL)
+		switch in.CSL {
+		case ".get ingestion resources":
+			w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"ResourceTypeName","DataType":"String"},{"ColumnName":"StorageRoot","DataType":"String"}],"Rows":[` +
+				`["SecuredReadyForAggregationQueue","` + c.server.URL + `/queue?sig=q"],["TempStorage","` + c.server.URL + `/container?sig=c"],["FailedIngestionsQueue","` + c.server.URL + `/failed?sig=f"]]}]}`))
+		case ".get kusto identity token":
+			w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"AuthorizationContext","DataType":"String"}],"Rows":[["context"]]}]}`))
+		default:
+			w.Write([]byte(`{"Tables":[{"TableName":"Table_0","Columns":[{"ColumnName":"DatabaseName","DataType":"String"},{"ColumnName":"PersistentStorage","DataType":"String"}],"Rows":[["benchmark",""],["other",""]]}]}`))
+		}
+	case strings.HasPrefix(r.URL.Path, "/v1/rest/ingest/"), strings.HasPrefix(r.URL.Path, "/container/"):
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(zr)
+		c.ingested = append(c.ingested, r.URL.RawQuery+" "+string(data))
+	case r.URL.Path == "/queue/messages":
+		body, _ := ioutil.ReadAll(r.Body)
+		text := strings.TrimSuffix(strings.TrimPrefix(string(body), "<QueueMessage><MessageText>"), "</MessageText></QueueMessage>")
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		msg := map[string]interface{}{}
+		if err != nil || json.Unmarshal(decoded, &msg) != nil || r.URL.RawQuery != "sig=q" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.messages = append(c.messages, msg)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestIngestURL(t *testing.T) {
+	cases := []struct {
+		desc string
+		url  string
+		want string
+	}{
+		{desc: "cluster", url: "https://help.westeurope.kusto.windows.net", want: "https://ingest-help.westeurope.kusto.windows.net"},
+		{desc: "already ingest", url: "https://ingest-help.kusto.windows.net", want: "https://ingest-help.kusto.windows.net"},
+		{desc: "with port", url: "http://localhost:8080/", want: "http://ingest-localhost:8080"},
+	}
+	for _, c := range cases {
+		if got := IngestURL(c.url); got != c.want {
+			t.Errorf("%s: incorrect URL: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if got, want := QuoteName("it's"), `['it\'s']`; got != want {
+		t.Errorf("incorrect name: got %s want %s", got, want)
+	}
+	if got, want := QuoteString("a\\b\nc"), `'a\\b\nc'`; got != want {
+		t.Errorf("incorrect string: got %s want %s", got, want)
+	}
+}
+
+func TestClientMgmt(t *testing.T) {
+	cluster := newTestCluster(t)
+	defer cluster.server.Close()
+	c := NewClient(cluster.server.URL+"/", Credentials{AccessToken: "given"})
+
+	r, err := c.Mgmt("benchmark", ".show databases")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(r.Column("DatabaseName"), ","); got != "benchmark,other" {
+		t.Errorf("incorrect column: got %s", got)
+	}
+	if got := r.Column("Missing"); got != nil {
+		t.Errorf("missing column has values: %v", got)
+	}
+	if _, err := c.Query("benchmark", "cpu | count"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(cluster.commands, "; "); got != "benchmark: .show databases; benchmark: cpu | count" {
+		t.Errorf("incorrect commands: got %s", got)
+	}
+	if got := strings.Join(cluster.requests, "; "); got != "POST /v1/rest/mgmt Bearer given; POST /v1/rest/query Bearer given" {
+		t.Errorf("incorrect requests: got %s", got)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	cases := []struct {
+		desc          string
+		status        int
+		body          string
+		wantErr       string
+		wantThrottled bool
+	}{
+		{
+			desc:    "kusto error",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"code":"General_BadRequest","message":"Request is invalid and cannot be executed.","@message":"Table 'cpu' could not be found."}}`,
+			wantErr: "kusto: 400 General_BadRequest: Table 'cpu' could not be found.",
+		},
+		{
+			desc:          "throttled",
+			status:        http.StatusTooManyRequests,
+			wantErr:       "kusto: 429 Too Many Requests",
+			wantThrottled: true,
+		},
+		{
+			desc:    "text",
+			status:  http.StatusForbidden,
+			body:    "Forbidden by policy\n",
+			wantErr: "kusto: 403 Forbidden by policy",
+		},
+	}
+	for _, c := range cases {
+		cluster := newTestCluster(t)
+		cluster.status, cluster.body = c.status, c.body
+		_, err := NewClient(cluster.server.URL, Credentials{}).Mgmt("benchmark", ".show databases")
+		e, ok := err.(*Error)
+		if !ok || err.Error() != c.wantErr || e.Throttled() != c.wantThrottled {
+			t.Errorf("%s: incorrect error: got %v", c.desc, err)
+		}
+		cluster.server.Close()
+	}
+}
+
+func TestStreamIngest(t *testing.T) {
+	cluster := newTestCluster(t)
+	defer cluster.server.Close()
+	c := NewClient(cluster.server.URL, Credentials{})
+	if err := c.StreamIngest("benchmark", "cpu", "csv", "tsbs", []byte("2016-01-01T00:00:00Z,host_0,1\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := strings.Join(cluster.ingested, ""), "mappingName=tsbs&streamFormat=csv 2016-01-01T00:00:00Z,host_0,1\n"; got != want {
+		t.Errorf("incorrect ingestion: got %q want %q", got, want)
+	}
+	// unauthenticated, as without credentials
+	if got := cluster.requests[0]; got != "POST /v1/rest/ingest/benchmark/cpu " {
+		t.Errorf("incorrect request: got %q", got)
+	}
+}
+
+func TestTokenSource(t *testing.T) {
+	cluster := newTestCluster(t)
+	defer cluster.server.Close()
+	creds := Credentials{TenantID: "tenant", ClientID: "client", ClientSecret: "secret", authority: cluster.server.URL}
+	c := NewClient(cluster.server.URL, creds)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.tokens.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		if _, err := c.Mgmt("benchmark", ".show databases"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// the token is renewed a minute before it expires
+		now = now.Add(30 * time.Minute)
+	}
+	if cluster.tokens != 2 {
+		t.Errorf("incorrect number of tokens requested: got %d want 2", cluster.tokens)
+	}
+	if got := cluster.requests[1]; got != "POST /v1/rest/mgmt Bearer token" {
+		t.Errorf("incorrect request: got %s", got)
+	}
+
+	creds.ClientSecret = "wrong"
+	c = NewClient(cluster.server.URL, creds)
+	if _, err := c.Mgmt("benchmark", ".show databases"); err == nil || !strings.Contains(err.Error(), "bad secret") {
+		t.Errorf("incorrect error: %v", err)
+	}
+}
+
+func TestCredentialsValidate(t *testing.T) {
+	cases := []struct {
+		desc    string
+		creds   Credentials
+		wantErr bool
+	}{
+		{desc: "none"},
+		{desc: "token", creds: Credentials{AccessToken: "token", TenantID: "tenant"}},
+		{desc: "application", creds: Credentials{TenantID: "tenant", ClientID: "client", ClientSecret: "secret"}},
+		{desc: "no secret", creds: Credentials{TenantID: "tenant", ClientID: "client"}, wantErr: true},
+	}
+	for _, c := range cases {
+		if err := c.creds.Validate(); (err != nil) != c.wantErr {
+			t.Errorf("%s: incorrect error: %v", c.desc, err)
+		}
+	}
+}
+
+func TestQueuedIngestor(t *testing.T) {
+	cluster := newTestCluster(t)
+	defer cluster.server.Close()
+	q := NewQueuedIngestor(NewClient(cluster.server.URL, Credentials{}))
+	for i := 0; i < 2; i++ {
+		if err := q.Ingest("benchmark", "cpu", "json", "tsbs", []byte(`{"hostname":"host_0"}`)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// the resources are only requested once
+	if got := strings.Join(cluster.commands, "; "); got != ": .get ingestion resources; : .get kusto identity token" {
+		t.Errorf("incorrect commands: got %s", got)
+	}
+	if len(cluster.ingested) != 2 || cluster.ingested[0] != `sig=c {"hostname":"host_0"}` {
+		t.Errorf("incorrect blobs: got %q", cluster.ingested)
+	}
+	if len(cluster.messages) != 2 {
+		t.Fatalf("incorrect number of messages: got %d", len(cluster.messages))
+	}
+	msg := cluster.messages[0]
+	props := msg["AdditionalProperties"].(map[string]interface{})
+	if msg["DatabaseName"] != "benchmark" || msg["TableName"] != "cpu" || props["authorizationContext"] != "context" || props["format"] != "json" || props["ingestionMappingReference"] != "tsbs" {
+		t.Errorf("incorrect message: %v", msg)
+	}
+	if blob := msg["BlobPath"].(string); !strings.HasPrefix(blob, cluster.server.URL+"/container/benchmark__cpu__") || !strings.HasSuffix(blob, ".json.gz?sig=c") {
+		t.Errorf("incorrect blob path: %s", blob)
+	}
+}
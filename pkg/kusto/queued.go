@@ -0,0 +1,190 @@
+package kusto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// resourcesTTL is how long the ingestion resources of a cluster are used
+// before they are requested again, as the SAS tokens in them expire
+const resourcesTTL = time.Hour
+
+// QueuedIngestor ingests data with queued ingestion: it uploads the data as
+// blobs to the temporary storage of the cluster, and queues messages for its
+// data management service to ingest them in the background. Ingest returns
+// once the data is queued, not once it is ingested.
+type QueuedIngestor struct {
+	client *Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	containers  []string
+	queues      []string
+	authContext string
+	expires     time.Time
+
+	// next is used to spread the uploads over the containers and queues
+	next uint64
+}
+
+// NewQueuedIngestor returns a QueuedIngestor using the ingestion resources
+// of the data management endpoint client is for (see IngestURL)
+func NewQueuedIngestor(client *Client) *QueuedIngestor {
+	return &QueuedIngestor{client: client, now: time.Now}
+}
+
+// Ingest queues data, in the given format (e.g., csv or json), to be
+// ingested into a table using the named ingestion mapping
+func (q *QueuedIngestor) Ingest(db, table, format, mapping string, data []byte) error {
+	containers, queues, authContext, err := q.resources()
+	if err != nil {
+		return err
+	}
+	n := atomic.AddUint64(&q.next, 1)
+
+	id := newUUID()
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	blob, err := withPath(containers[n%uint64(len(containers))], fmt.Sprintf("%s__%s__%s.%s.gz", db, table, id, format))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPut, blob, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", "2019-12-12")
+	if err := q.storageDo(req); err != nil {
+		return err
+	}
+
+	msg, err := json.Marshal(map[string]interface{}{
+		"Id":                  id,
+		"BlobPath":            blob,
+		"RawDataSize":         len(data),
+		"DatabaseName":        db,
+		"TableName":           table,
+		"RetainBlobOnSuccess": false,
+		"FlushImmediately":    false,
+		"ReportLevel":         0,
+		"ReportMethod":        0,
+		"AdditionalProperties": map[string]string{
+			"authorizationContext":      authContext,
+			"format":                    format,
+			"ingestionMappingReference": mapping,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	queue, err := withPath(queues[n%uint64(len(queues))], "messages")
+	if err != nil {
+		return err
+	}
+	xml := "<QueueMessage><MessageText>" + base64.StdEncoding.EncodeToString(msg) + "</MessageText></QueueMessage>"
+	req, err = http.NewRequest(http.MethodPost, queue, strings.NewReader(xml))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-version", "2019-12-12")
+	return q.storageDo(req)
+}
+
+// resources returns the URLs of the temporary storage containers and
+// ingestion queues of the cluster, with their SAS tokens, and the
+// authorization context to queue ingestions with
+func (q *QueuedIngestor) resources() ([]string, []string, string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.containers) > 0 && q.now().Before(q.expires) {
+		return q.containers, q.queues, q.authContext, nil
+	}
+
+	r, err := q.client.Mgmt("", ".get ingestion resources")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var containers, queues []string
+	types, roots := r.Column("ResourceTypeName"), r.Column("StorageRoot")
+	for i := range types {
+		if i >= len(roots) {
+			break
+		}
+		switch types[i] {
+		case "TempStorage":
+			containers = append(containers, roots[i])
+		case "SecuredReadyForAggregationQueue":
+			queues = append(queues, roots[i])
+		}
+	}
+	if len(containers) == 0 || len(queues) == 0 {
+		return nil, nil, "", fmt.Errorf("kusto: no ingestion resources for queued ingestion at %s", q.client.URL)
+	}
+	r, err = q.client.Mgmt("", ".get kusto identity token")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	authContext := r.Column("AuthorizationContext")
+	if len(authContext) == 0 {
+		return nil, nil, "", fmt.Errorf("kusto: no identity token for queued ingestion at %s", q.client.URL)
+	}
+
+	q.containers, q.queues, q.authContext = containers, queues, authContext[0]
+	q.expires = q.now().Add(resourcesTTL)
+	return q.containers, q.queues, q.authContext, nil
+}
+
+// storageDo makes a request to Azure Storage, which is authorized by the SAS
+// token in its URL, returning an *Error if it is not successful
+func (q *QueuedIngestor) storageDo(req *http.Request) error {
+	resp, err := q.client.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return newError(resp.StatusCode, body)
+	}
+	return nil
+}
+
+// withPath returns the URL of a resource under root, keeping the SAS token
+// in the query of root
+func withPath(root, name string) (string, error) {
+	u, err := url.Parse(root)
+	if err != nil {
+		return "", fmt.Errorf("kusto: invalid storage URL: %v", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + name
+	return u.String(), nil
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,93 @@
+// Package logging is the leveled, structured logger of the TSBS tools. It
+// writes progress, warnings and errors to stderr, as text or as one JSON
+// object per line, so the logs of long runs can be parsed by log pipelines
+// while stdout is left to generated data and results.
+//
+// The logger is the default log/slog logger, so messages logged with the
+// standard log package (e.g., by database drivers) go through it too. The
+// tools configure it from their -log-level and -log-format flags; see
+// cli.ParseFlags.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	// FormatText logs lines of key=value pairs
+	FormatText = "text"
+	// FormatJSON logs one JSON object per line
+	FormatJSON = "json"
+)
+
+// Levels are the names of the levels accepted by Configure, from the most
+// to the least verbose
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// exit is called by Fatal; it is a variable for ease of testing
+var exit = os.Exit
+
+// Configure makes the logger write messages of the given level or above to
+// w in the given format. It should be called before logging from several
+// goroutines, e.g., right after parsing flags.
+func Configure(w io.Writer, level, format string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level: '%s' (valid choices: %s)", level, strings.Join(Levels, ", "))
+	}
+	opts := &slog.HandlerOptions{Level: l}
+
+	var h slog.Handler
+	switch format {
+	case FormatText:
+		h = slog.NewTextHandler(w, opts)
+	case FormatJSON:
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format: '%s' (valid choices: %s, %s)", format, FormatText, FormatJSON)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// DebugEnabled returns whether messages at the debug level are logged, so
+// detail that is costly to gather (e.g., per batch) can be skipped otherwise
+func DebugEnabled() bool {
+	return slog.Default().Enabled(context.Background(), slog.LevelDebug)
+}
+
+// InfoEnabled returns whether messages at the info level are logged, which
+// they are not with -quiet
+func InfoEnabled() bool {
+	return slog.Default().Enabled(context.Background(), slog.LevelInfo)
+}
+
+// Debug logs a message with the given key-value pairs at the debug level
+func Debug(msg string, args ...interface{}) { slog.Debug(msg, args...) }
+
+// Info logs a message with the given key-value pairs at the info level
+func Info(msg string, args ...interface{}) { slog.Info(msg, args...) }
+
+// Warn logs a message with the given key-value pairs at the warn level
+func Warn(msg string, args ...interface{}) { slog.Warn(msg, args...) }
+
+// Error logs a message with the given key-value pairs at the error level
+func Error(msg string, args ...interface{}) { slog.Error(msg, args...) }
+
+// Fatal logs a message with the given key-value pairs at the error level and
+// exits with status 1
+func Fatal(msg string, args ...interface{}) {
+	slog.Error(msg, args...)
+	exit(1)
+}
+
+// Fatalf is Fatal with a message formatted like fmt.Sprintf, for messages
+// without key-value pairs
+func Fatalf(format string, args ...interface{}) {
+	Fatal(fmt.Sprintf(format, args...))
+}
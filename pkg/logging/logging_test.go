@@ -0,0 +1,142 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestConfigure(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	cases := []struct {
+		desc      string
+		level     string
+		format    string
+		want      []string
+		shouldErr bool
+	}{
+		{
+			desc:   "info text",
+			level:  "info",
+			format: FormatText,
+			want:   []string{"level=INFO msg=info n=1", "level=WARN msg=warn n=2"},
+		},
+		{
+			desc:   "debug text",
+			level:  "debug",
+			format: FormatText,
+			want:   []string{"level=DEBUG msg=debug n=0", "level=INFO msg=info n=1", "level=WARN msg=warn n=2"},
+		},
+		{
+			desc:   "warn upper case",
+			level:  "WARN",
+			format: FormatText,
+			want:   []string{"level=WARN msg=warn n=2"},
+		},
+		{
+			desc:      "unknown level",
+			level:     "verbose",
+			format:    FormatText,
+			shouldErr: true,
+		},
+		{
+			desc:      "unknown format",
+			level:     "info",
+			format:    "xml",
+			shouldErr: true,
+		},
+	}
+	for _, c := range cases {
+		var buf bytes.Buffer
+		err := Configure(&buf, c.level, c.format)
+		if c.shouldErr {
+			if err == nil {
+				t.Errorf("%s: unexpected lack of error", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		Debug("debug", "n", 0)
+		Info("info", "n", 1)
+		Warn("warn", "n", 2)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(c.want) {
+			t.Errorf("%s: incorrect number of lines: got %d want %d\n%s", c.desc, len(lines), len(c.want), buf.String())
+			continue
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, c.want[i]) {
+				t.Errorf("%s: incorrect line %d: got %s want suffix %s", c.desc, i, line, c.want[i])
+			}
+		}
+	}
+}
+
+func TestConfigureJSON(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	if err := Configure(&buf, "info", FormatJSON); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	Error("failed", "error", "boom", "count", 3)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	if got["level"] != "ERROR" || got["msg"] != "failed" || got["error"] != "boom" || got["count"] != 3.0 {
+		t.Errorf("incorrect record: %v", got)
+	}
+}
+
+func TestFatal(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	defer func(old func(int)) { exit = old }(exit)
+
+	var buf bytes.Buffer
+	if err := Configure(&buf, "info", FormatText); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code := -1
+	exit = func(c int) { code = c }
+	Fatalf("bad value: %d", 7)
+	if code != 1 {
+		t.Errorf("incorrect exit code: got %d want 1", code)
+	}
+	if want := `level=ERROR msg="bad value: 7"`; !strings.Contains(buf.String(), want) {
+		t.Errorf("incorrect output: got %s want %s", buf.String(), want)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	cases := []struct {
+		level     string
+		wantDebug bool
+		wantInfo  bool
+	}{
+		{level: "debug", wantDebug: true, wantInfo: true},
+		{level: "info", wantInfo: true},
+		{level: "error"},
+	}
+	for _, c := range cases {
+		if err := Configure(&bytes.Buffer{}, c.level, FormatText); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := DebugEnabled(); got != c.wantDebug {
+			t.Errorf("%s: incorrect DebugEnabled: got %v want %v", c.level, got, c.wantDebug)
+		}
+		if got := InfoEnabled(); got != c.wantInfo {
+			t.Errorf("%s: incorrect InfoEnabled: got %v want %v", c.level, got, c.wantInfo)
+		}
+	}
+}
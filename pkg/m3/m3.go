@@ -0,0 +1,70 @@
+// Package m3 is a minimal client for the HTTP API of the M3 coordinator,
+// which the loader and query runner of the m3db target share. It turns
+// error responses into *Error.
+package m3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/timescale/tsbs/pkg/httpclient"
+)
+
+// Client makes requests to the HTTP API of a coordinator
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a Client of the coordinator at url
+func NewClient(url string) *Client {
+	return &Client{httpclient.New(httpclient.Config{
+		Name: "m3",
+		URL:  url,
+		Error: func(status int, _ http.Header, body []byte) error {
+			return newError(status, body)
+		},
+	})}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("m3: %d %s", e.Status, e.Message)
+}
+
+// NotFound returns whether the resource of the request, e.g., a namespace,
+// does not exist
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// Throttled returns whether the request was rejected because of a limit or
+// an overloaded coordinator, so it can be retried later
+func (e *Error) Throttled() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status == http.StatusServiceUnavailable
+}
+
+// newError returns the *Error of a response with the given status and body,
+// which holds the details of the error if it is JSON, in the form of the
+// coordinator or of the Prometheus API it implements
+func newError(status int, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Error     string
+		ErrorType string
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Error) > 0 {
+		e.Message = r.Error
+		if len(r.ErrorType) > 0 {
+			e.Message = r.ErrorType + ": " + r.Error
+		}
+	} else {
+		e.Message = httpclient.Message(status, body)
+	}
+	return e
+}
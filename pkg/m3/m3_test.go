@@ -0,0 +1,48 @@
+package m3
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/api/v1/database/create":
+			w.Write([]byte(`{"namespace":` + string(body) + `}`))
+		case "/api/v1/query":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":"error","error":"namespace not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL + "/")
+	var out struct{ Namespace struct{ Name string } }
+	if err := c.JSON(http.MethodPost, "/api/v1/database/create", map[string]string{"name": "default"}, &out); err != nil || out.Namespace.Name != "default" {
+		t.Errorf("incorrect response: %v, %v", out, err)
+	}
+	_, err := c.Do(http.MethodGet, "/api/v1/services/m3db/namespace/other", nil, nil)
+	if e, ok := err.(*Error); !ok || !e.NotFound() || e.Message != "namespace not found" {
+		t.Errorf("incorrect error: %v", err)
+	}
+	_, err = c.Do(http.MethodGet, "/api/v1/query", nil, nil)
+	if e, ok := err.(*Error); !ok || e.Error() != "m3: 400 bad_data: parse error" || e.Throttled() {
+		t.Errorf("incorrect error of a query: %v", err)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	if e := newError(http.StatusTooManyRequests, []byte("slow down\n")); e.Error() != "m3: 429 slow down" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+	if e := newError(http.StatusServiceUnavailable, nil); e.Error() != "m3: 503 Service Unavailable" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+}
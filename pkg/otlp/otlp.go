@@ -0,0 +1,140 @@
+// Package otlp is a minimal client for OTLP/HTTP, the HTTP transport of the
+// OpenTelemetry protocol, which the loader of the otlp target uses. It turns
+// error responses into *Error.
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/httpclient"
+)
+
+// HeadersEnv is the environment variable the OpenTelemetry SDKs take the
+// headers of OTLP requests from, e.g., to authenticate them
+const HeadersEnv = "OTEL_EXPORTER_OTLP_HEADERS"
+
+// MetricsPath is the path of the metrics endpoint, relative to the base URL
+// of an OTLP/HTTP receiver
+const MetricsPath = "/v1/metrics"
+
+// Client makes requests to an OTLP/HTTP receiver, e.g., an OpenTelemetry
+// collector
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a Client of the receiver at url, its base URL, which
+// sends header with every request
+func NewClient(url string, header http.Header) *Client {
+	return &Client{httpclient.New(httpclient.Config{
+		Name:   "otlp",
+		URL:    url,
+		Header: header,
+		Error: func(status int, header http.Header, body []byte) error {
+			return newError(status, header.Get("Content-Type"), body)
+		},
+	})}
+}
+
+// ParseHeaders parses headers in the form of HeadersEnv: comma-separated
+// key=value pairs, whose values are URL-encoded
+func ParseHeaders(s string) (http.Header, error) {
+	header := http.Header{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid header %q: not key=value", pair)
+		}
+		value, err := url.QueryUnescape(strings.TrimSpace(pair[i+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %q: %v", pair, err)
+		}
+		header.Add(strings.TrimSpace(pair[:i]), value)
+	}
+	return header, nil
+}
+
+// Error is an error returned by the receiver
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("otlp: %d %s", e.Status, e.Message)
+}
+
+// Throttled returns whether the request was rejected because the receiver
+// is overloaded or unavailable, which OTLP says to retry later
+func (e *Error) Throttled() bool {
+	switch e.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// newError returns the *Error of a response with the given status, content
+// type and body. OTLP receivers answer errors with a google.rpc.Status, in
+// protobuf or JSON as the request was, whose message is the details of the
+// error.
+func newError(status int, contentType string, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Message string
+	}
+	switch {
+	case strings.HasPrefix(contentType, "application/x-protobuf"):
+		if m := statusMessage(body); len(m) > 0 {
+			e.Message = m
+		}
+	case json.Unmarshal(body, &r) == nil && len(r.Message) > 0:
+		e.Message = r.Message
+	default:
+		e.Message = httpclient.Message(status, body)
+	}
+	return e
+}
+
+// tagStatusMessage is the tag of google.rpc.Status.message, its field 2 and
+// length-delimited
+const tagStatusMessage = 2<<3 | 2
+
+// statusMessage returns the message of a google.rpc.Status in protobuf, or
+// an empty string if it has none or is invalid
+func statusMessage(b []byte) string {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ""
+		}
+		b = b[n:]
+		switch key & 7 {
+		case 0: // varint
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return ""
+			}
+			b = b[n:]
+		case 2: // length-delimited
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return ""
+			}
+			if key == tagStatusMessage {
+				return string(b[n : n+int(size)])
+			}
+			b = b[n+int(size):]
+		default:
+			return ""
+		}
+	}
+	return ""
+}
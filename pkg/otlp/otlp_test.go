@@ -0,0 +1,71 @@
+package otlp
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+		case string(body) == "invalid":
+			// a google.rpc.Status of code 3 and message "invalid metrics"
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("\x08\x03\x12\x0finvalid metrics"))
+		default:
+			w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+			w.Write(body)
+		}
+	}))
+	defer server.Close()
+
+	header, err := ParseHeaders("Authorization=Bearer%20secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(server.URL+"/", header)
+	h := http.Header{}
+	h.Set("Content-Type", "application/x-protobuf")
+	if resp, err := c.Do(http.MethodPost, MetricsPath, h, []byte("ok")); err != nil || string(resp) != "ok" {
+		t.Errorf("incorrect response: %q, %v", resp, err)
+	}
+	_, err = c.Do(http.MethodPost, MetricsPath, h, []byte("invalid"))
+	if e, ok := err.(*Error); !ok || e.Error() != "otlp: 400 invalid metrics" || e.Throttled() {
+		t.Errorf("incorrect error: %v", err)
+	}
+	_, err = NewClient(server.URL, nil).Do(http.MethodPost, MetricsPath, h, nil)
+	if e, ok := err.(*Error); !ok || e.Error() != "otlp: 401 Unauthorized" {
+		t.Errorf("incorrect error without headers: %v", err)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	header, err := ParseHeaders(" api-key = a%3Db ,x-scope-orgid=tsbs,")
+	if err != nil || len(header) != 2 || header.Get("Api-Key") != "a=b" || header.Get("X-Scope-Orgid") != "tsbs" {
+		t.Errorf("incorrect headers: %v, %v", header, err)
+	}
+	if header, err := ParseHeaders(""); err != nil || len(header) != 0 {
+		t.Errorf("incorrect empty headers: %v, %v", header, err)
+	}
+	if _, err := ParseHeaders("api-key"); err == nil {
+		t.Errorf("header without a value not rejected")
+	}
+}
+
+func TestNewError(t *testing.T) {
+	if e := newError(http.StatusTooManyRequests, "text/plain", []byte("slow down\n")); e.Error() != "otlp: 429 slow down" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+	if e := newError(http.StatusServiceUnavailable, "application/json", []byte(`{"code":14,"message":"unavailable"}`)); e.Error() != "otlp: 503 unavailable" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+	if e := newError(http.StatusBadRequest, "application/x-protobuf", []byte("\x12\xff")); e.Error() != "otlp: 400 Bad Request" || e.Throttled() {
+		t.Errorf("incorrect error of an invalid Status: %v", e)
+	}
+}
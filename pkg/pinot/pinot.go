@@ -0,0 +1,100 @@
+// Package pinot is a minimal client for the HTTP APIs of the controller and
+// brokers of Apache Pinot, which the loader and query runner of the pinot
+// target share. It authenticates requests with a token and turns error
+// responses into *Error.
+package pinot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/httpclient"
+)
+
+// TokenEnv is the environment variable the token of requests is read from
+// by default
+const TokenEnv = "PINOT_AUTH_TOKEN"
+
+// Client makes requests to the HTTP API of a controller or broker
+type Client struct {
+	*httpclient.Client
+}
+
+// NewClient returns a Client of the controller or broker at url,
+// authenticating its requests with token, the value of their Authorization
+// header as the Pinot admin tools take it (e.g., "Basic YWRtaW46dmVyeXNlY3JldA"),
+// unless it is empty, as clusters without access control expect
+func NewClient(url, token string) *Client {
+	header := http.Header{}
+	if len(token) > 0 {
+		header.Set("Authorization", token)
+	}
+	return &Client{httpclient.New(httpclient.Config{
+		Name:   "pinot",
+		URL:    url,
+		Header: header,
+		Error: func(status int, _ http.Header, body []byte) error {
+			return newError(status, body)
+		},
+	})}
+}
+
+// Error is an error returned by the API
+type Error struct {
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("pinot: %d %s", e.Status, e.Message)
+}
+
+// NotFound returns whether the resource of the request, e.g., a table or
+// schema, does not exist
+func (e *Error) NotFound() bool {
+	return e.Status == http.StatusNotFound
+}
+
+// Throttled returns whether the request was rejected because of a quota or
+// an overloaded server, so it can be retried later
+func (e *Error) Throttled() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status == http.StatusServiceUnavailable
+}
+
+// newError returns the *Error of a response with the given status and body,
+// which holds the details of the error if it is JSON, in the form of the
+// controller
+func newError(status int, body []byte) *Error {
+	e := &Error{Status: status, Message: http.StatusText(status)}
+	var r struct {
+		Error string
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Error) > 0 {
+		e.Message = r.Error
+	} else {
+		e.Message = httpclient.Message(status, body)
+	}
+	return e
+}
+
+// QueryError returns the error of the response of a broker to a query, of
+// the first of its exceptions, or nil if it has none. Brokers report the
+// failures of queries with exceptions rather than their status.
+func QueryError(resp []byte) error {
+	var r struct {
+		Exceptions []struct {
+			ErrorCode int
+			Message   string
+		}
+	}
+	if err := json.Unmarshal(resp, &r); err != nil {
+		return fmt.Errorf("pinot: invalid response to query: %v", err)
+	}
+	if len(r.Exceptions) == 0 {
+		return nil
+	}
+	x := r.Exceptions[0]
+	return &Error{Status: x.ErrorCode, Message: strings.TrimSpace(x.Message)}
+}
@@ -0,0 +1,60 @@
+package pinot
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientDo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case r.Header.Get("Authorization") != "Basic token":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/schemas":
+			w.Write([]byte(`{"unrecognizedProperties":{},"status":` + string(body) + `}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":404,"error":"Schema cpu not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL+"/", "Basic token")
+	var out struct{ Status struct{ SchemaName string } }
+	if err := c.JSON(http.MethodPost, "/schemas", map[string]string{"schemaName": "cpu"}, &out); err != nil || out.Status.SchemaName != "cpu" {
+		t.Errorf("incorrect response: %v, %v", out, err)
+	}
+	_, err := c.Do(http.MethodGet, "/schemas/cpu", nil, nil)
+	if e, ok := err.(*Error); !ok || !e.NotFound() || e.Error() != "pinot: 404 Schema cpu not found" {
+		t.Errorf("incorrect error: %v", err)
+	}
+	_, err = NewClient(server.URL, "").Do(http.MethodGet, "/schemas", nil, nil)
+	if e, ok := err.(*Error); !ok || e.Status != http.StatusUnauthorized || e.Message != "Unauthorized" {
+		t.Errorf("incorrect error without a token: %v", err)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	if e := newError(http.StatusTooManyRequests, []byte("slow down\n")); e.Error() != "pinot: 429 slow down" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+	if e := newError(http.StatusServiceUnavailable, nil); e.Error() != "pinot: 503 Service Unavailable" || !e.Throttled() {
+		t.Errorf("incorrect error: %v", e)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	if err := QueryError([]byte(`{"resultTable":{"rows":[]},"exceptions":[]}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := QueryError([]byte(`{"exceptions":[{"errorCode":150,"message":"SQLParsingError:\n..."}]}`))
+	if e, ok := err.(*Error); !ok || e.Status != 150 || e.Message != "SQLParsingError:\n..." {
+		t.Errorf("incorrect error: %v", err)
+	}
+	if err := QueryError([]byte(`not json`)); err == nil {
+		t.Errorf("expected an error for an invalid response")
+	}
+}
@@ -0,0 +1,66 @@
+// Package plugins loads Go plugins that add database targets to the TSBS
+// tools at runtime.
+//
+// A target plugin is a Go package built with -buildmode=plugin whose init
+// functions register its implementations with the TSBS registries, e.g.,
+// serialize.Register for the data format and utils.RegisterDevopsGenerator for
+// the query generator. It must also export a variable named TSBSAPIVersion of
+// type int set to APIVersion, so that plugins built against an incompatible
+// version of TSBS are rejected rather than misbehaving.
+//
+// Loaders and query runners are separate binaries for each target, built on
+// the load and query packages, so a plugin does not need to provide them.
+//
+// Go plugins must be built with the same Go version and the same versions of
+// all shared packages as the binary loading them. Targets can instead be
+// added at compile time, without forking, by building a small main package
+// that imports the target for its registrations alongside the TSBS packages.
+package plugins
+
+import (
+	"fmt"
+	"plugin"
+	"strings"
+)
+
+// APIVersion is the version of the registration API plugins are built
+// against. It is incremented whenever a registry changes incompatibly.
+const APIVersion = 1
+
+// versionSymbol is the name of the variable a plugin exports its APIVersion in
+const versionSymbol = "TSBSAPIVersion"
+
+// Load opens each of the plugins at the given paths, which runs their
+// registrations, and checks they were built for this APIVersion
+func Load(paths ...string) error {
+	for _, path := range paths {
+		p, err := plugin.Open(path)
+		if err != nil {
+			return fmt.Errorf("could not load plugin %s: %v", path, err)
+		}
+		sym, err := p.Lookup(versionSymbol)
+		if err != nil {
+			return fmt.Errorf("plugin %s does not export %s", path, versionSymbol)
+		}
+		version, ok := sym.(*int)
+		if !ok {
+			return fmt.Errorf("plugin %s exports %s with type %T, expected int", path, versionSymbol, sym)
+		}
+		if *version != APIVersion {
+			return fmt.Errorf("plugin %s was built for API version %d, expected %d", path, *version, APIVersion)
+		}
+	}
+	return nil
+}
+
+// ParseList splits a comma-separated list of plugin paths, as given on the
+// command line, ignoring empty entries
+func ParseList(s string) []string {
+	paths := []string{}
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); len(p) > 0 {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,55 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	cases := []struct {
+		desc  string
+		input string
+		want  []string
+	}{
+		{
+			desc:  "empty string",
+			input: "",
+			want:  []string{},
+		},
+		{
+			desc:  "single path",
+			input: "foo.so",
+			want:  []string{"foo.so"},
+		},
+		{
+			desc:  "multiple paths with spaces and empty entries",
+			input: "foo.so, /tmp/bar.so,,",
+			want:  []string{"foo.so", "/tmp/bar.so"},
+		},
+	}
+	for _, c := range cases {
+		got := ParseList(c.input)
+		if len(got) != len(c.want) {
+			t.Errorf("%s: incorrect number of paths: got %d want %d", c.desc, len(got), len(c.want))
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: incorrect path %d: got %s want %s", c.desc, i, got[i], c.want[i])
+			}
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	if err := Load(); err != nil {
+		t.Errorf("unexpected error loading no plugins: %v", err)
+	}
+	err := Load("/nonexistent/plugin.so")
+	if err == nil {
+		t.Fatalf("did not error for missing plugin")
+	}
+	if !strings.HasPrefix(err.Error(), "could not load plugin /nonexistent/plugin.so") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+// Package provision starts the target database of a loader or query runner
+// in a Docker container, so a benchmark can be run against any version of a
+// database without setting it up first. It is enabled by the -container flag
+// of the loaders and runners (see AddFlags), and needs them to be built with
+// the testcontainers build tag, which brings in testcontainers-go.
+package provision
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/suggest"
+)
+
+// target describes how to run a target database in a container
+type target struct {
+	image   string
+	version string
+	// port is the port, e.g., 5432/tcp, the database listens on in the
+	// container
+	port string
+	env  map[string]string
+	// the database is ready once readyLog has been logged readyCount
+	// times, or once readyHTTPPath answers on port if it is set
+	readyLog      string
+	readyCount    int
+	readyHTTPPath string
+	timeout       time.Duration
+}
+
+var targets = map[string]target{
+	"cassandra": {
+		image:      "cassandra",
+		version:    "3.11",
+		port:       "9042/tcp",
+		readyLog:   "Starting listening for CQL clients",
+		readyCount: 1,
+		timeout:    5 * time.Minute,
+	},
+	"influx": {
+		image:         "influxdb",
+		version:       "1.8",
+		port:          "8086/tcp",
+		readyHTTPPath: "/ping",
+		timeout:       time.Minute,
+	},
+	"mongo": {
+		image:      "mongo",
+		version:    "4.4",
+		port:       "27017/tcp",
+		readyLog:   "Waiting for connections",
+		readyCount: 1,
+		timeout:    2 * time.Minute,
+	},
+	"mysql": {
+		image:   "mysql",
+		version: "8.4",
+		port:    "3306/tcp",
+		// the tools connect as root without a password
+		env: map[string]string{"MYSQL_ALLOW_EMPTY_PASSWORD": "yes"},
+		// once for the server initializing the database, and once for
+		// the server that is then started for good
+		readyLog:   "ready for connections. Version",
+		readyCount: 2,
+		timeout:    2 * time.Minute,
+	},
+	"postgres": {
+		image:   "postgres",
+		version: "16",
+		port:    "5432/tcp",
+		// the tools connect as postgres without a password
+		env: map[string]string{"POSTGRES_HOST_AUTH_METHOD": "trust"},
+		// once for the server initializing the database, and once for
+		// the server that is then started for good
+		readyLog:   "database system is ready to accept connections",
+		readyCount: 2,
+		timeout:    2 * time.Minute,
+	},
+	"timescaledb": {
+		image:   "timescale/timescaledb",
+		version: "latest-pg16",
+		port:    "5432/tcp",
+		// the tools connect as postgres without a password
+		env: map[string]string{"POSTGRES_HOST_AUTH_METHOD": "trust"},
+		// once for the server initializing the database, and once for
+		// the server that is then started for good
+		readyLog:   "database system is ready to accept connections",
+		readyCount: 2,
+		timeout:    2 * time.Minute,
+	},
+}
+
+// Targets returns the names of the targets that can be started in a
+// container
+func Targets() []string {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Options are the options of the container of a target, as given by the
+// flags added by AddFlags
+type Options struct {
+	Enabled bool
+	Image   string
+	Version string
+	// Env is a comma-separated list of KEY=VALUE environment variables of
+	// the container, added to those of the target
+	Env string
+	// Cmd is the command the image is run with, split on spaces, e.g.,
+	// to pass settings to the database
+	Cmd string
+	// Name, if given, names the container, which is then reused if it
+	// exists and kept once the tool is done, so a loader and a runner can
+	// use the same one
+	Name string
+}
+
+// AddFlags adds the -container flags for target to fs, returning the
+// Options they set. It panics if target is not known.
+func AddFlags(fs *flag.FlagSet, target string) *Options {
+	t, ok := targets[target]
+	if !ok {
+		panic(suggest.Error("target", target, Targets()).Error())
+	}
+	o := &Options{}
+	fs.BoolVar(&o.Enabled, "container", false, "Start the target database in a Docker container for the run, removing it after unless -container-name is given (requires building with -tags testcontainers)")
+	fs.StringVar(&o.Image, "container-image", t.image, "Image of the database started with -container")
+	fs.StringVar(&o.Version, "container-version", t.version, "Version (image tag) of the database started with -container")
+	fs.StringVar(&o.Env, "container-env", "", "Comma-separated KEY=VALUE environment variables of the container started with -container, e.g., to configure the database")
+	fs.StringVar(&o.Cmd, "container-cmd", "", "Command to run the image started with -container with, e.g., to pass settings to the database (default: that of the image)")
+	fs.StringVar(&o.Name, "container-name", "", "Name of the container started with -container, which is reused if it exists and kept once done, e.g., to run queries against the database loaded by a previous run")
+	return o
+}
+
+// env returns the environment variables of the container of t
+func (o *Options) env(t target) (map[string]string, error) {
+	env := make(map[string]string)
+	for k, v := range t.env {
+		env[k] = v
+	}
+	if len(o.Env) == 0 {
+		return env, nil
+	}
+	for _, kv := range strings.Split(o.Env, ",") {
+		kv = strings.TrimSpace(kv)
+		i := strings.Index(kv, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid container environment variable '%s': expected KEY=VALUE", kv)
+		}
+		env[kv[:i]] = kv[i+1:]
+	}
+	return env, nil
+}
+
+// Container is a running target database
+type Container struct {
+	Host string
+	Port int
+
+	terminate func(context.Context) error
+}
+
+// Endpoint returns the host:port address of the database
+func (c *Container) Endpoint() string {
+	return c.Host + ":" + strconv.Itoa(c.Port)
+}
+
+// Terminate stops and removes the container, unless it is named (see
+// Options.Name), in which case it is kept for later runs
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.terminate == nil {
+		return nil
+	}
+	return c.terminate(ctx)
+}
+
+// Close is Terminate for deferring, logging rather than returning any error
+func (c *Container) Close() {
+	if err := c.Terminate(context.Background()); err != nil {
+		logging.Warn("could not remove container", "endpoint", c.Endpoint(), "error", err)
+	}
+}
+
+// Start starts the database of target in a container as given by o, and
+// waits until it is ready. Invalid options, or a binary built without
+// container support, are cli.ConfigErrors, and any failure to start the
+// container a cli.UnreachableError.
+func Start(ctx context.Context, target string, o *Options) (*Container, error) {
+	t, ok := targets[target]
+	if !ok {
+		return nil, cli.ConfigError(suggest.Error("target", target, Targets()))
+	}
+	env, err := o.env(t)
+	if err != nil {
+		return nil, cli.ConfigError(err)
+	}
+	c, err := startContainer(ctx, t, o, env)
+	if err != nil && cli.ExitCode(err) == cli.ExitFailure {
+		err = cli.UnreachableError(err)
+	}
+	return c, err
+}
+
+// startContainer starts the container of t. Support for containers is only
+// built in with the testcontainers build tag, which replaces it.
+var startContainer = func(ctx context.Context, t target, o *Options, env map[string]string) (*Container, error) {
+	return nil, cli.ConfigError(fmt.Errorf("cannot start %s:%s: built without container support (rebuild with -tags testcontainers)", o.Image, o.Version))
+}
@@ -0,0 +1,60 @@
+package provision
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTargets(t *testing.T) {
+	for _, name := range Targets() {
+		tgt := targets[name]
+		if tgt.image == "" || tgt.version == "" || !strings.HasSuffix(tgt.port, "/tcp") || tgt.timeout <= 0 {
+			t.Errorf("%s: incomplete target: %+v", name, tgt)
+		}
+		if tgt.readyHTTPPath == "" && (tgt.readyLog == "" || tgt.readyCount < 1) {
+			t.Errorf("%s: no way to wait for the database to be ready", name)
+		}
+	}
+}
+
+func TestAddFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o := AddFlags(fs, "timescaledb")
+	if o.Image != "timescale/timescaledb" || o.Version != targets["timescaledb"].version {
+		t.Errorf("incorrect defaults: %+v", o)
+	}
+	err := fs.Parse([]string{"-container", "-container-version=2.14.2-pg15", "-container-env=POSTGRES_DB=bench, TZ=UTC"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !o.Enabled || o.Version != "2.14.2-pg15" {
+		t.Errorf("incorrect options: %+v", o)
+	}
+	env, err := o.env(targets["timescaledb"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{"POSTGRES_HOST_AUTH_METHOD": "trust", "POSTGRES_DB": "bench", "TZ": "UTC"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("incorrect env: got %v want %v", env, want)
+	}
+
+	o.Env = "bogus"
+	if _, err := o.env(targets["timescaledb"]); err == nil {
+		t.Errorf("invalid env did not error")
+	}
+}
+
+func TestContainerEndpoint(t *testing.T) {
+	c := &Container{Host: "localhost", Port: 49153}
+	if got := c.Endpoint(); got != "localhost:49153" {
+		t.Errorf("incorrect endpoint: got %s", got)
+	}
+	// a named container has nothing to terminate
+	if err := c.Terminate(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+//go:build testcontainers
+// +build testcontainers
+
+package provision
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+func init() {
+	startContainer = func(ctx context.Context, t target, o *Options, env map[string]string) (*Container, error) {
+		req := testcontainers.ContainerRequest{
+			Image:        o.Image + ":" + o.Version,
+			ExposedPorts: []string{t.port},
+			Env:          env,
+			Cmd:          strings.Fields(o.Cmd),
+			Name:         o.Name,
+			WaitingFor:   waitStrategy(t),
+		}
+		logging.Info("starting container", "image", req.Image, "name", o.Name)
+		c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+			ContainerRequest: req,
+			Started:          true,
+			Reuse:            len(o.Name) > 0,
+		})
+		if err != nil {
+			return nil, err
+		}
+		host, err := c.Host(ctx)
+		if err != nil {
+			c.Terminate(ctx)
+			return nil, err
+		}
+		port, err := c.MappedPort(ctx, nat.Port(t.port))
+		if err != nil {
+			c.Terminate(ctx)
+			return nil, err
+		}
+		ret := &Container{Host: host, Port: port.Int()}
+		if len(o.Name) == 0 {
+			ret.terminate = func(ctx context.Context) error { return c.Terminate(ctx) }
+		}
+		logging.Info("started container", "image", req.Image, "endpoint", ret.Endpoint())
+		return ret, nil
+	}
+}
+
+// waitStrategy returns how to wait for the database of t to be ready
+func waitStrategy(t target) wait.Strategy {
+	if len(t.readyHTTPPath) > 0 {
+		return wait.ForHTTP(t.readyHTTPPath).
+			WithPort(nat.Port(t.port)).
+			WithStatusCodeMatcher(func(status int) bool { return status < http.StatusBadRequest }).
+			WithStartupTimeout(t.timeout)
+	}
+	return wait.ForLog(t.readyLog).
+		WithOccurrence(t.readyCount).
+		WithStartupTimeout(t.timeout)
+}
@@ -0,0 +1,235 @@
+package querygen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/timescale/tsbs/pkg/data/serialize"
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+)
+
+// Capabilities declares what a target supports, so tools can skip or flag
+// unsupported combinations rather than generate unusable workloads
+type Capabilities struct {
+	// QueryTypes are the query types queries can be generated for, of any
+	// use case
+	QueryTypes []string `json:"query_types"`
+	// FieldTypes are the types of field values its format can load
+	FieldTypes []serialize.FieldType `json:"field_types"`
+	// Deletes, Updates and OutOfOrder are whether its database can delete
+	// points, overwrite the fields of existing points, and insert points out
+	// of time order
+	Deletes    bool `json:"deletes"`
+	Updates    bool `json:"updates"`
+	OutOfOrder bool `json:"out_of_order"`
+}
+
+// SupportsQueryType returns whether queries of queryType can be generated
+func (c *Capabilities) SupportsQueryType(queryType string) bool {
+	return contains(c.QueryTypes, queryType)
+}
+
+// SupportsFieldType returns whether field values of type t can be loaded
+func (c *Capabilities) SupportsFieldType(t serialize.FieldType) bool {
+	for _, ft := range c.FieldTypes {
+		if ft == t {
+			return true
+		}
+	}
+	return false
+}
+
+// queryTypesWithPrefixes returns the devops query types starting with any of
+// prefixes, in sorted order
+func queryTypesWithPrefixes(prefixes ...string) []string {
+	var queryTypes []string
+	for _, qt := range QueryTypes(UseCaseDevops) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(qt, prefix) {
+				queryTypes = append(queryTypes, qt)
+				break
+			}
+		}
+	}
+	return queryTypes
+}
+
+// queryTypesExcept returns the devops query types other than excluded, in
+// sorted order
+func queryTypesExcept(excluded ...string) []string {
+	var queryTypes []string
+	for _, qt := range QueryTypes(UseCaseDevops) {
+		if !contains(excluded, qt) {
+			queryTypes = append(queryTypes, qt)
+		}
+	}
+	return queryTypes
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	numericFieldTypes = []serialize.FieldType{serialize.FieldTypeFloat, serialize.FieldTypeInt}
+	allFieldTypes     = []serialize.FieldType{serialize.FieldTypeFloat, serialize.FieldTypeInt, serialize.FieldTypeBool, serialize.FieldTypeString}
+)
+
+var (
+	capabilitiesMu sync.RWMutex
+	capabilities   = map[string]Capabilities{
+		TargetADX: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    false,
+			OutOfOrder: true,
+		},
+		TargetCassandra: {
+			// querying all hosts for high CPU usage is not implemented
+			QueryTypes: queryTypesExcept(devops.LabelHighCPU + "-all"),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetDuckDB: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetGreptime: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetInflux: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetInflux3: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetM3DB: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			// strings are dropped by its format, and bools loaded as 0 or 1
+			FieldTypes: []serialize.FieldType{serialize.FieldTypeFloat, serialize.FieldTypeInt, serialize.FieldTypeBool},
+			// points older than the buffer of a namespace are cold writes
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetMongo: {
+			// querying all hosts for high CPU usage is not implemented
+			QueryTypes: queryTypesExcept(devops.LabelHighCPU + "-all"),
+			FieldTypes: numericFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetMongoNaive: {
+			QueryTypes: queryTypesWithPrefixes(devops.LabelSingleGroupby, devops.LabelDoubleGroupby),
+			FieldTypes: numericFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetMySQL: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetPinot: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			// rows of offline tables are only replaced with whole segments
+			OutOfOrder: true,
+		},
+		TargetPostgres: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: numericFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetSQLite: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: allFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+		TargetTimescaleDB: {
+			QueryTypes: QueryTypes(UseCaseDevops),
+			FieldTypes: numericFieldTypes,
+			Deletes:    true,
+			Updates:    true,
+			OutOfOrder: true,
+		},
+	}
+)
+
+// RegisterCapabilities declares the capabilities of a target that is not
+// built in (e.g., from a plugin). Targets without declared capabilities are
+// assumed to support everything. It panics if the target's capabilities are
+// already declared.
+func RegisterCapabilities(target string, c Capabilities) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	if _, dup := capabilities[target]; dup {
+		panic("querygen: RegisterCapabilities called twice for " + target)
+	}
+	capabilities[target] = c
+}
+
+// TargetCapabilities returns the declared capabilities of a target, and
+// false if it has none
+func TargetCapabilities(target string) (Capabilities, bool) {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+	c, ok := capabilities[target]
+	return c, ok
+}
+
+// CapabilityMatrix returns the declared capabilities of every target that
+// has them
+func CapabilityMatrix() map[string]Capabilities {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+	matrix := make(map[string]Capabilities, len(capabilities))
+	for target, c := range capabilities {
+		matrix[target] = c
+	}
+	return matrix
+}
+
+// CheckSupported returns an error if queries of queryType cannot be generated
+// for target according to its declared capabilities
+func CheckSupported(target, queryType string) error {
+	c, ok := TargetCapabilities(target)
+	if !ok || c.SupportsQueryType(queryType) {
+		return nil
+	}
+	supported := append([]string(nil), c.QueryTypes...)
+	sort.Strings(supported)
+	return fmt.Errorf("query type '%s' is not supported by target '%s' (supported: %s)", queryType, target, strings.Join(supported, ", "))
+}
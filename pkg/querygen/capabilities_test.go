@@ -0,0 +1,86 @@
+package querygen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// fills returns whether queries of queryType can be filled in for target
+func fills(t *testing.T, target, queryType string) (ok bool) {
+	c := testConfig()
+	gen, err := NewDevopsGenerator(target, c.TimestampStart, c.TimestampEnd, c.Scale, c)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", target, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	useCaseMatrix[UseCaseDevops][queryType](gen).Fill(gen.GenerateEmptyQuery())
+	return true
+}
+
+func TestCapabilitiesMatchGenerators(t *testing.T) {
+	for _, target := range Targets() {
+		c, ok := TargetCapabilities(target)
+		if !ok {
+			t.Errorf("%s: no capabilities declared for builtin target", target)
+			continue
+		}
+		for _, qt := range QueryTypes(UseCaseDevops) {
+			if got := fills(t, target, qt); got != c.SupportsQueryType(qt) {
+				t.Errorf("%s: %s declared as supported %v, but generating it succeeds %v", target, qt, c.SupportsQueryType(qt), got)
+			}
+		}
+	}
+}
+
+func TestCheckSupported(t *testing.T) {
+	if err := CheckSupported(TargetMongo, "lastpoint"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := CheckSupported(TargetMongoNaive, "lastpoint")
+	if err == nil || !strings.HasPrefix(err.Error(), "query type 'lastpoint' is not supported") {
+		t.Errorf("incorrect error for unsupported query type: %v", err)
+	}
+	if err := CheckSupported(TargetCassandra, "high-cpu-all"); err == nil {
+		t.Errorf("did not error for unsupported query type")
+	}
+	// targets without declared capabilities are not restricted
+	if err := CheckSupported("bogus", "lastpoint"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c := testConfig()
+	c.QueryType = "lastpoint"
+	if _, err := New(TargetMongoNaive, UseCaseDevops, c); err == nil {
+		t.Errorf("New did not error for unsupported query type")
+	}
+}
+
+func TestRegisterCapabilities(t *testing.T) {
+	RegisterCapabilities("test-capabilities", Capabilities{QueryTypes: []string{"lastpoint"}})
+	c, ok := TargetCapabilities("test-capabilities")
+	if !ok || !c.SupportsQueryType("lastpoint") || c.SupportsQueryType("high-cpu-1") {
+		t.Errorf("incorrect registered capabilities: %+v", c)
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("did not panic when registering twice")
+		}
+	}()
+	RegisterCapabilities("test-capabilities", Capabilities{})
+}
+
+func TestCapabilitiesJSON(t *testing.T) {
+	c, _ := TargetCapabilities(TargetTimescaleDB)
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(b), `"field_types":["float","int"]`) {
+		t.Errorf("incorrect JSON: %s", b)
+	}
+}
@@ -0,0 +1,164 @@
+package adx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+	"github.com/timescale/tsbs/query"
+)
+
+// Devops produces Azure Data Explorer-specific queries, in KQL, for all the
+// devops query types.
+type Devops struct {
+	*devops.Core
+}
+
+// NewDevops makes an Devops object ready to generate Queries.
+func NewDevops(start, end time.Time, scale int) *Devops {
+	return &Devops{devops.NewCore(start, end, scale)}
+}
+
+// GenerateEmptyQuery returns an empty query.HTTP
+func (d *Devops) GenerateEmptyQuery() query.Query {
+	return query.NewHTTP()
+}
+
+func (d *Devops) getHostWhereWithHostnames(hostnames []string) string {
+	quoted := make([]string, len(hostnames))
+	for i, s := range hostnames {
+		quoted[i] = fmt.Sprintf("'%s'", s)
+	}
+	return fmt.Sprintf("hostname in (%s)", strings.Join(quoted, ", "))
+}
+
+func (d *Devops) getHostWhereString(nHosts int) string {
+	hostnames := d.GetRandomHosts(nHosts)
+	return d.getHostWhereWithHostnames(hostnames)
+}
+
+func (d *Devops) getSummarizeClausesAggMetrics(agg string, metrics []string) []string {
+	clauses := make([]string, len(metrics))
+	for i, m := range metrics {
+		clauses[i] = fmt.Sprintf("%[1]s_%[2]s = %[1]s(%[2]s)", agg, m)
+	}
+	return clauses
+}
+
+// getTimeWhere returns the KQL condition for timestamps in [start, end)
+func getTimeWhere(start, end string) string {
+	return fmt.Sprintf("timestamp >= datetime(%s) and timestamp < datetime(%s)", start, end)
+}
+
+// GroupByTime selects the MAX for numMetrics metrics under 'cpu',
+// per minute for nhosts hosts,
+// e.g. in KQL:
+//
+// cpu
+// | where hostname in ('$HOSTNAME_1', ..., '$HOSTNAME_N') and timestamp >= datetime($HOUR_START) and timestamp < datetime($HOUR_END)
+// | summarize max_metric1 = max(metric1), ..., max_metricN = max(metricN) by minute = bin(timestamp, 1m)
+// | order by minute asc
+func (d *Devops) GroupByTime(qi query.Query, nHosts, numMetrics int, timeRange time.Duration) {
+	interval := d.RandWindow(timeRange)
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	clauses := d.getSummarizeClausesAggMetrics("max", metrics)
+	whereHosts := d.getHostWhereString(nHosts)
+
+	humanLabel := fmt.Sprintf("ADX %d cpu metric(s), random %4d hosts, random %s by 1m", numMetrics, nHosts, timeRange)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	kql := fmt.Sprintf("cpu\n| where %s and %s\n| summarize %s by minute = bin(timestamp, 1m)\n| order by minute asc",
+		whereHosts, getTimeWhere(interval.StartString(), interval.EndString()), strings.Join(clauses, ", "))
+	d.fillInQuery(qi, humanLabel, humanDesc, kql)
+}
+
+// GroupByOrderByLimit benchmarks a query that has a time WHERE clause, that groups by a truncated date, orders by that date, and takes a limit:
+// cpu
+// | where timestamp < datetime($TIME)
+// | summarize max_usage_user = max(usage_user) by minute = bin(timestamp, 1m)
+// | top 5 by minute desc
+func (d *Devops) GroupByOrderByLimit(qi query.Query) {
+	interval := d.RandWindow(time.Hour)
+
+	humanLabel := "ADX max cpu over last 5 min-intervals (random end)"
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	kql := fmt.Sprintf("cpu\n| where timestamp < datetime(%s)\n| summarize max_usage_user = max(usage_user) by minute = bin(timestamp, 1m)\n| top 5 by minute desc", interval.EndString())
+	d.fillInQuery(qi, humanLabel, humanDesc, kql)
+}
+
+// GroupByTimeAndPrimaryTag selects the AVG of numMetrics metrics under 'cpu' per device per hour for a day,
+// e.g. in KQL:
+//
+// cpu
+// | where timestamp >= datetime($HOUR_START) and timestamp < datetime($HOUR_END)
+// | summarize avg_metric1 = avg(metric1), ..., avg_metricN = avg(metricN) by hour = bin(timestamp, 1h), hostname
+// | order by hour asc, hostname asc
+func (d *Devops) GroupByTimeAndPrimaryTag(qi query.Query, numMetrics int) {
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	interval := d.RandWindow(devops.DoubleGroupByDuration)
+	clauses := d.getSummarizeClausesAggMetrics("avg", metrics)
+
+	humanLabel := devops.GetDoubleGroupByLabel("ADX", numMetrics)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	kql := fmt.Sprintf("cpu\n| where %s\n| summarize %s by hour = bin(timestamp, 1h), hostname\n| order by hour asc, hostname asc",
+		getTimeWhere(interval.StartString(), interval.EndString()), strings.Join(clauses, ", "))
+	d.fillInQuery(qi, humanLabel, humanDesc, kql)
+}
+
+// MaxAllCPU selects the MAX of all metrics under 'cpu' per hour for nhosts hosts,
+// e.g. in KQL:
+//
+// cpu
+// | where hostname in ('$HOSTNAME_1', ..., '$HOSTNAME_N') and timestamp >= datetime($HOUR_START) and timestamp < datetime($HOUR_END)
+// | summarize max_metric1 = max(metric1), ..., max_metricN = max(metricN) by hour = bin(timestamp, 1h)
+// | order by hour asc
+func (d *Devops) MaxAllCPU(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.MaxAllDuration)
+	whereHosts := d.getHostWhereString(nHosts)
+	clauses := d.getSummarizeClausesAggMetrics("max", devops.GetAllCPUMetrics())
+
+	humanLabel := devops.GetMaxAllLabel("ADX", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	kql := fmt.Sprintf("cpu\n| where %s and %s\n| summarize %s by hour = bin(timestamp, 1h)\n| order by hour asc",
+		whereHosts, getTimeWhere(interval.StartString(), interval.EndString()), strings.Join(clauses, ", "))
+	d.fillInQuery(qi, humanLabel, humanDesc, kql)
+}
+
+// LastPointPerHost finds the last row for every host in the dataset
+func (d *Devops) LastPointPerHost(qi query.Query) {
+	humanLabel := "ADX last row per host"
+	humanDesc := humanLabel + ": cpu"
+	kql := "cpu\n| summarize arg_max(timestamp, *) by hostname"
+	d.fillInQuery(qi, humanLabel, humanDesc, kql)
+}
+
+// HighCPUForHosts populates a query that gets CPU metrics when the CPU has high
+// usage between a time period for a number of hosts (if 0, it will search all hosts),
+// e.g. in KQL:
+//
+// cpu
+// | where usage_user > 90.0 and timestamp >= datetime($TIME_START) and timestamp < datetime($TIME_END) and hostname in ('$HOST', '$HOST2', ...)
+func (d *Devops) HighCPUForHosts(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.HighCPUDuration)
+	var hostWhereClause string
+	if nHosts > 0 {
+		hostWhereClause = " and " + d.getHostWhereString(nHosts)
+	}
+
+	humanLabel := devops.GetHighCPULabel("ADX", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	kql := fmt.Sprintf("cpu\n| where usage_user > 90.0 and %s%s", getTimeWhere(interval.StartString(), interval.EndString()), hostWhereClause)
+	d.fillInQuery(qi, humanLabel, humanDesc, kql)
+}
+
+// fillInQuery fills in qi to run kql with the query endpoint of the REST
+// API. The body is the query alone, as the database is only known to the
+// query runner, which sends it in the request.
+func (d *Devops) fillInQuery(qi query.Query, humanLabel, humanDesc, kql string) {
+	q := qi.(*query.HTTP)
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(humanDesc)
+	q.Method = []byte("POST")
+	q.Path = []byte("/v1/rest/query")
+	q.Body = []byte(kql)
+}
@@ -0,0 +1,117 @@
+package adx
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+)
+
+func TestDevopsGetHostWhereWithHostnames(t *testing.T) {
+	cases := []struct {
+		desc      string
+		hostnames []string
+		want      string
+	}{
+		{
+			desc:      "single host",
+			hostnames: []string{"foo1"},
+			want:      "hostname in ('foo1')",
+		},
+		{
+			desc:      "multi host (2)",
+			hostnames: []string{"foo1", "foo2"},
+			want:      "hostname in ('foo1', 'foo2')",
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := d.getHostWhereWithHostnames(c.hostnames); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsGetSummarizeClausesAggMetrics(t *testing.T) {
+	cases := []struct {
+		desc    string
+		agg     string
+		metrics []string
+		want    string
+	}{
+		{
+			desc:    "single metric - max",
+			agg:     "max",
+			metrics: []string{"foo"},
+			want:    "max_foo = max(foo)",
+		},
+		{
+			desc:    "multiple metric - avg",
+			agg:     "avg",
+			metrics: []string{"foo", "bar"},
+			want:    "avg_foo = avg(foo), avg_bar = avg(bar)",
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := strings.Join(d.getSummarizeClausesAggMetrics(c.agg, c.metrics), ", "); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsQueries(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDevops(start, start.Add(24*time.Hour+time.Minute), 10)
+	cases := []struct {
+		desc string
+		fill func(q query.Query)
+		want []string
+	}{
+		{
+			desc: "groupby time",
+			fill: func(q query.Query) { d.GroupByTime(q, 2, 2, time.Hour) },
+			want: []string{"cpu\n| where hostname in ('host_", "| summarize max_usage_user = max(usage_user), max_usage_system = max(usage_system) by minute = bin(timestamp, 1m)\n| order by minute asc"},
+		},
+		{
+			desc: "groupby orderby limit",
+			fill: d.GroupByOrderByLimit,
+			want: []string{"cpu\n| where timestamp < datetime(2016-01-01T", "| top 5 by minute desc"},
+		},
+		{
+			desc: "double groupby",
+			fill: func(q query.Query) { d.GroupByTimeAndPrimaryTag(q, 1) },
+			want: []string{"| summarize avg_usage_user = avg(usage_user) by hour = bin(timestamp, 1h), hostname\n| order by hour asc, hostname asc"},
+		},
+		{
+			desc: "lastpoint",
+			fill: d.LastPointPerHost,
+			want: []string{"cpu\n| summarize arg_max(timestamp, *) by hostname"},
+		},
+		{
+			desc: "high cpu all",
+			fill: func(q query.Query) { d.HighCPUForHosts(q, 0) },
+			want: []string{"cpu\n| where usage_user > 90.0 and timestamp >= datetime(2016-01-01T"},
+		},
+	}
+	for _, c := range cases {
+		q := d.GenerateEmptyQuery().(*query.HTTP)
+		c.fill(q)
+		if string(q.Method) != "POST" || string(q.Path) != "/v1/rest/query" {
+			t.Errorf("%s: incorrect request: %s %s", c.desc, q.Method, q.Path)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(q.Body), want) {
+				t.Errorf("%s: incorrect query:\n%s\nmissing\n%s", c.desc, q.Body, want)
+			}
+		}
+		if !strings.HasPrefix(string(q.HumanLabel), "ADX ") {
+			t.Errorf("%s: incorrect label %s", c.desc, q.HumanLabel)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package greptime
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+	"github.com/timescale/tsbs/query"
+)
+
+// Devops produces GreptimeDB-specific queries, in SQL, for all the devops
+// query types. The time of the rows is in greptime_timestamp, the time index
+// of the tables GreptimeDB creates for lines of the InfluxDB line protocol.
+type Devops struct {
+	*devops.Core
+}
+
+// NewDevops makes an Devops object ready to generate Queries.
+func NewDevops(start, end time.Time, scale int) *Devops {
+	return &Devops{devops.NewCore(start, end, scale)}
+}
+
+// GenerateEmptyQuery returns an empty query.HTTP
+func (d *Devops) GenerateEmptyQuery() query.Query {
+	return query.NewHTTP()
+}
+
+func (d *Devops) getHostWhereWithHostnames(hostnames []string) string {
+	quoted := make([]string, len(hostnames))
+	for i, s := range hostnames {
+		quoted[i] = fmt.Sprintf("'%s'", s)
+	}
+	return fmt.Sprintf("hostname IN (%s)", strings.Join(quoted, ", "))
+}
+
+func (d *Devops) getHostWhereString(nHosts int) string {
+	hostnames := d.GetRandomHosts(nHosts)
+	return d.getHostWhereWithHostnames(hostnames)
+}
+
+func (d *Devops) getSelectClausesAggMetrics(agg string, metrics []string) []string {
+	selectClauses := make([]string, len(metrics))
+	for i, m := range metrics {
+		selectClauses[i] = fmt.Sprintf("%[1]s(%[2]s) AS %[1]s_%[2]s", agg, m)
+	}
+	return selectClauses
+}
+
+// getTimeWhere returns the SQL condition for times in [start, end)
+func getTimeWhere(start, end string) string {
+	return fmt.Sprintf("greptime_timestamp >= '%s' AND greptime_timestamp < '%s'", start, end)
+}
+
+// GroupByTime selects the MAX for numMetrics metrics under 'cpu',
+// per minute for nhosts hosts,
+// e.g. in SQL:
+//
+// SELECT date_bin(INTERVAL '1 minute', greptime_timestamp) AS minute, max(metric1) AS max_metric1, ..., max(metricN) AS max_metricN
+// FROM cpu
+// WHERE hostname IN ('$HOSTNAME_1', ..., '$HOSTNAME_N')
+// AND greptime_timestamp >= '$HOUR_START' AND greptime_timestamp < '$HOUR_END'
+// GROUP BY minute ORDER BY minute ASC
+func (d *Devops) GroupByTime(qi query.Query, nHosts, numMetrics int, timeRange time.Duration) {
+	interval := d.RandWindow(timeRange)
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	selectClauses := d.getSelectClausesAggMetrics("max", metrics)
+	whereHosts := d.getHostWhereString(nHosts)
+
+	humanLabel := fmt.Sprintf("GreptimeDB %d cpu metric(s), random %4d hosts, random %s by 1m", numMetrics, nHosts, timeRange)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT date_bin(INTERVAL '1 minute', greptime_timestamp) AS minute, %s FROM cpu WHERE %s AND %s GROUP BY minute ORDER BY minute ASC",
+		strings.Join(selectClauses, ", "), whereHosts, getTimeWhere(interval.StartString(), interval.EndString()))
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// GroupByOrderByLimit benchmarks a query that has a time WHERE clause, that groups by a truncated date, orders by that date, and takes a limit:
+// SELECT date_bin(INTERVAL '1 minute', greptime_timestamp) AS minute, max(usage_user) AS max_usage_user FROM cpu
+// WHERE greptime_timestamp < '$TIME'
+// GROUP BY minute ORDER BY minute DESC
+// LIMIT 5
+func (d *Devops) GroupByOrderByLimit(qi query.Query) {
+	interval := d.RandWindow(time.Hour)
+
+	humanLabel := "GreptimeDB max cpu over last 5 min-intervals (random end)"
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT date_bin(INTERVAL '1 minute', greptime_timestamp) AS minute, max(usage_user) AS max_usage_user FROM cpu WHERE greptime_timestamp < '%s' GROUP BY minute ORDER BY minute DESC LIMIT 5",
+		interval.EndString())
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// GroupByTimeAndPrimaryTag selects the AVG of numMetrics metrics under 'cpu' per device per hour for a day,
+// e.g. in SQL:
+//
+// SELECT date_bin(INTERVAL '1 hour', greptime_timestamp) AS hour, hostname, avg(metric1) AS avg_metric1, ..., avg(metricN) AS avg_metricN
+// FROM cpu
+// WHERE greptime_timestamp >= '$HOUR_START' AND greptime_timestamp < '$HOUR_END'
+// GROUP BY hour, hostname ORDER BY hour, hostname
+func (d *Devops) GroupByTimeAndPrimaryTag(qi query.Query, numMetrics int) {
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	interval := d.RandWindow(devops.DoubleGroupByDuration)
+	selectClauses := d.getSelectClausesAggMetrics("avg", metrics)
+
+	humanLabel := devops.GetDoubleGroupByLabel("GreptimeDB", numMetrics)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT date_bin(INTERVAL '1 hour', greptime_timestamp) AS hour, hostname, %s FROM cpu WHERE %s GROUP BY hour, hostname ORDER BY hour, hostname",
+		strings.Join(selectClauses, ", "), getTimeWhere(interval.StartString(), interval.EndString()))
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// MaxAllCPU selects the MAX of all metrics under 'cpu' per hour for nhosts hosts,
+// e.g. in SQL:
+//
+// SELECT date_bin(INTERVAL '1 hour', greptime_timestamp) AS hour, max(metric1) AS max_metric1, ..., max(metricN) AS max_metricN
+// FROM cpu WHERE hostname IN ('$HOSTNAME_1', ..., '$HOSTNAME_N')
+// AND greptime_timestamp >= '$HOUR_START' AND greptime_timestamp < '$HOUR_END'
+// GROUP BY hour ORDER BY hour
+func (d *Devops) MaxAllCPU(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.MaxAllDuration)
+	whereHosts := d.getHostWhereString(nHosts)
+	selectClauses := d.getSelectClausesAggMetrics("max", devops.GetAllCPUMetrics())
+
+	humanLabel := devops.GetMaxAllLabel("GreptimeDB", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT date_bin(INTERVAL '1 hour', greptime_timestamp) AS hour, %s FROM cpu WHERE %s AND %s GROUP BY hour ORDER BY hour",
+		strings.Join(selectClauses, ", "), whereHosts, getTimeWhere(interval.StartString(), interval.EndString()))
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// LastPointPerHost finds the last row for every host in the dataset,
+// ranking the rows of each host by time, e.g. in SQL:
+//
+// SELECT * FROM (SELECT *, row_number() OVER (PARTITION BY hostname ORDER BY greptime_timestamp DESC) AS rank FROM cpu)
+// WHERE rank = 1 ORDER BY hostname
+func (d *Devops) LastPointPerHost(qi query.Query) {
+	humanLabel := "GreptimeDB last row per host"
+	humanDesc := humanLabel + ": cpu"
+	sql := "SELECT * FROM (SELECT *, row_number() OVER (PARTITION BY hostname ORDER BY greptime_timestamp DESC) AS rank FROM cpu) WHERE rank = 1 ORDER BY hostname"
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// HighCPUForHosts populates a query that gets CPU metrics when the CPU has high
+// usage between a time period for a number of hosts (if 0, it will search all hosts),
+// e.g. in SQL:
+//
+// SELECT * FROM cpu
+// WHERE usage_user > 90.0
+// AND greptime_timestamp >= '$TIME_START' AND greptime_timestamp < '$TIME_END'
+// AND hostname IN ('$HOST', '$HOST2', ...)
+func (d *Devops) HighCPUForHosts(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.HighCPUDuration)
+	var hostWhereClause string
+	if nHosts > 0 {
+		hostWhereClause = " AND " + d.getHostWhereString(nHosts)
+	}
+
+	humanLabel := devops.GetHighCPULabel("GreptimeDB", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT * FROM cpu WHERE usage_user > 90.0 AND %s%s", getTimeWhere(interval.StartString(), interval.EndString()), hostWhereClause)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// fillInQuery fills in qi to run sql with the SQL endpoint of the HTTP API.
+// The body is the query alone, as the database is only known to the query
+// runner, which sends it in the request.
+func (d *Devops) fillInQuery(qi query.Query, humanLabel, humanDesc, sql string) {
+	q := qi.(*query.HTTP)
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(humanDesc)
+	q.Method = []byte("POST")
+	q.Path = []byte("/v1/sql")
+	q.Body = []byte(sql)
+}
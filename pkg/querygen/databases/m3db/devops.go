@@ -0,0 +1,178 @@
+package m3db
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+	"github.com/timescale/tsbs/query"
+)
+
+// Devops produces M3-specific queries, in PromQL, for all the devops query
+// types. Each field of a measurement is a metric named by both, e.g.,
+// cpu_usage_user, as written by the m3db format.
+type Devops struct {
+	*devops.Core
+}
+
+// NewDevops makes an Devops object ready to generate Queries.
+func NewDevops(start, end time.Time, scale int) *Devops {
+	return &Devops{devops.NewCore(start, end, scale)}
+}
+
+// GenerateEmptyQuery returns an empty query.HTTP
+func (d *Devops) GenerateEmptyQuery() query.Query {
+	return query.NewHTTP()
+}
+
+func (d *Devops) getHostMatcherWithHostnames(hostnames []string) string {
+	return fmt.Sprintf(`hostname=~"%s"`, strings.Join(hostnames, "|"))
+}
+
+func (d *Devops) getHostMatcherString(nHosts int) string {
+	hostnames := d.GetRandomHosts(nHosts)
+	return d.getHostMatcherWithHostnames(hostnames)
+}
+
+// getAggregateMetrics returns the PromQL expression aggregating each of the
+// cpu metrics with agg, over windows of the given range and then across
+// series, grouped by the by labels if any. Functions over time drop the
+// names of metrics, so the result for each metric is labeled by its field
+// instead, e.g., for max over 1m of usage_user:
+//
+// label_replace(max(max_over_time(cpu_usage_user{matchers}[1m])), "field", "usage_user", "", "")
+func (d *Devops) getAggregateMetrics(agg string, metrics []string, matchers, window, by string) string {
+	if len(by) > 0 {
+		by = fmt.Sprintf(" by (%s) ", by)
+	}
+	if len(matchers) > 0 {
+		matchers = "{" + matchers + "}"
+	}
+	exprs := make([]string, len(metrics))
+	for i, m := range metrics {
+		exprs[i] = fmt.Sprintf(`label_replace(%[1]s%[2]s(%[1]s_over_time(cpu_%[3]s%[4]s[%[5]s])), "field", "%[3]s", "", "")`, agg, by, m, matchers, window)
+	}
+	return strings.Join(exprs, " or ")
+}
+
+// GroupByTime selects the MAX for numMetrics metrics under 'cpu',
+// per minute for nhosts hosts,
+// e.g. in PromQL, evaluated every minute from a minute after $HOUR_START to $HOUR_END:
+//
+// label_replace(max(max_over_time(cpu_metric1{hostname=~"$HOSTNAME_1|...|$HOSTNAME_N"}[1m])), "field", "metric1", "", "")
+// or ...
+// or label_replace(max(max_over_time(cpu_metricN{hostname=~"$HOSTNAME_1|...|$HOSTNAME_N"}[1m])), "field", "metricN", "", "")
+func (d *Devops) GroupByTime(qi query.Query, nHosts, numMetrics int, timeRange time.Duration) {
+	interval := d.RandWindow(timeRange)
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	matchers := d.getHostMatcherString(nHosts)
+
+	humanLabel := fmt.Sprintf("M3DB %d cpu metric(s), random %4d hosts, random %s by 1m", numMetrics, nHosts, timeRange)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	promql := d.getAggregateMetrics("max", metrics, matchers, "1m", "")
+	d.fillInRangeQuery(qi, humanLabel, humanDesc, promql, interval.Start.Add(time.Minute), interval.End, time.Minute)
+}
+
+// GroupByOrderByLimit benchmarks a query that takes the MAX of a metric per
+// minute for the 5 minutes before a random time, e.g. in PromQL, evaluated
+// every minute from 4 minutes before $TIME to $TIME:
+//
+// max(max_over_time(cpu_usage_user[1m]))
+func (d *Devops) GroupByOrderByLimit(qi query.Query) {
+	interval := d.RandWindow(time.Hour)
+
+	humanLabel := "M3DB max cpu over last 5 min-intervals (random end)"
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	promql := "max(max_over_time(cpu_usage_user[1m]))"
+	d.fillInRangeQuery(qi, humanLabel, humanDesc, promql, interval.End.Add(-4*time.Minute), interval.End, time.Minute)
+}
+
+// GroupByTimeAndPrimaryTag selects the AVG of numMetrics metrics under 'cpu' per device per hour for a day,
+// e.g. in PromQL, evaluated every hour from an hour after $HOUR_START to $HOUR_END:
+//
+// label_replace(avg by (hostname) (avg_over_time(cpu_metric1[1h])), "field", "metric1", "", "")
+// or ...
+// or label_replace(avg by (hostname) (avg_over_time(cpu_metricN[1h])), "field", "metricN", "", "")
+func (d *Devops) GroupByTimeAndPrimaryTag(qi query.Query, numMetrics int) {
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	interval := d.RandWindow(devops.DoubleGroupByDuration)
+
+	humanLabel := devops.GetDoubleGroupByLabel("M3DB", numMetrics)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	promql := d.getAggregateMetrics("avg", metrics, "", "1h", "hostname")
+	d.fillInRangeQuery(qi, humanLabel, humanDesc, promql, interval.Start.Add(time.Hour), interval.End, time.Hour)
+}
+
+// MaxAllCPU selects the MAX of all metrics under 'cpu' per hour for nhosts hosts,
+// e.g. in PromQL, evaluated every hour from an hour after $HOUR_START to $HOUR_END:
+//
+// label_replace(max(max_over_time(cpu_metric1{hostname=~"$HOSTNAME_1|...|$HOSTNAME_N"}[1h])), "field", "metric1", "", "")
+// or ...
+func (d *Devops) MaxAllCPU(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.MaxAllDuration)
+	matchers := d.getHostMatcherString(nHosts)
+
+	humanLabel := devops.GetMaxAllLabel("M3DB", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	promql := d.getAggregateMetrics("max", devops.GetAllCPUMetrics(), matchers, "1h", "")
+	d.fillInRangeQuery(qi, humanLabel, humanDesc, promql, interval.Start.Add(time.Hour), interval.End, time.Hour)
+}
+
+// LastPointPerHost finds the last reading of every cpu metric of every host,
+// as of the end of the dataset, with an instant query:
+//
+// {__name__=~"cpu_.+"}
+func (d *Devops) LastPointPerHost(qi query.Query) {
+	humanLabel := "M3DB last row per host"
+	humanDesc := humanLabel + ": cpu"
+	v := url.Values{}
+	v.Set("query", `{__name__=~"cpu_.+"}`)
+	v.Set("time", d.Interval.EndString())
+	d.fillInQuery(qi, humanLabel, humanDesc, "/api/v1/query?"+v.Encode())
+}
+
+// HighCPUForHosts populates a query that gets CPU metrics when the CPU has high
+// usage between a time period for a number of hosts (if 0, it will search all hosts),
+// e.g. in PromQL, evaluated every 10s, the interval of the readings, from
+// $TIME_START to $TIME_END:
+//
+// {__name__=~"cpu_.+",hostname=~"$HOST|$HOST2|..."} and on (hostname) cpu_usage_user{hostname=~"$HOST|$HOST2|..."} > 90
+func (d *Devops) HighCPUForHosts(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.HighCPUDuration)
+	var matchers string
+	if nHosts > 0 {
+		matchers = d.getHostMatcherString(nHosts)
+	}
+
+	humanLabel := devops.GetHighCPULabel("M3DB", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	all, usageUser := `{__name__=~"cpu_.+"}`, "cpu_usage_user"
+	if len(matchers) > 0 {
+		all, usageUser = `{__name__=~"cpu_.+",`+matchers+"}", "cpu_usage_user{"+matchers+"}"
+	}
+	promql := fmt.Sprintf("%s and on (hostname) %s > 90", all, usageUser)
+	d.fillInRangeQuery(qi, humanLabel, humanDesc, promql, interval.Start, interval.End, 10*time.Second)
+}
+
+// fillInRangeQuery fills in qi to evaluate promql every step from start to
+// end, with the range query endpoint of the Prometheus API of the
+// coordinator
+func (d *Devops) fillInRangeQuery(qi query.Query, humanLabel, humanDesc, promql string, start, end time.Time, step time.Duration) {
+	v := url.Values{}
+	v.Set("query", promql)
+	v.Set("start", start.UTC().Format(time.RFC3339))
+	v.Set("end", end.UTC().Format(time.RFC3339))
+	v.Set("step", fmt.Sprintf("%ds", int64(step/time.Second)))
+	d.fillInQuery(qi, humanLabel, humanDesc, "/api/v1/query_range?"+v.Encode())
+}
+
+func (d *Devops) fillInQuery(qi query.Query, humanLabel, humanDesc, path string) {
+	q := qi.(*query.HTTP)
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(humanDesc)
+	q.Method = []byte("GET")
+	q.Path = []byte(path)
+	q.Body = nil
+}
@@ -0,0 +1,139 @@
+package m3db
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+)
+
+func TestDevopsGetHostMatcherWithHostnames(t *testing.T) {
+	cases := []struct {
+		desc      string
+		hostnames []string
+		want      string
+	}{
+		{
+			desc:      "single host",
+			hostnames: []string{"foo1"},
+			want:      `hostname=~"foo1"`,
+		},
+		{
+			desc:      "multi host (2)",
+			hostnames: []string{"foo1", "foo2"},
+			want:      `hostname=~"foo1|foo2"`,
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := d.getHostMatcherWithHostnames(c.hostnames); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsGetAggregateMetrics(t *testing.T) {
+	cases := []struct {
+		desc     string
+		agg      string
+		metrics  []string
+		matchers string
+		by       string
+		want     string
+	}{
+		{
+			desc:     "single metric - max",
+			agg:      "max",
+			metrics:  []string{"foo"},
+			matchers: `hostname=~"h1"`,
+			want:     `label_replace(max(max_over_time(cpu_foo{hostname=~"h1"}[1m])), "field", "foo", "", "")`,
+		},
+		{
+			desc:    "multiple metric - avg by hostname",
+			agg:     "avg",
+			metrics: []string{"foo", "bar"},
+			by:      "hostname",
+			want: `label_replace(avg by (hostname) (avg_over_time(cpu_foo[1m])), "field", "foo", "", "")` +
+				` or label_replace(avg by (hostname) (avg_over_time(cpu_bar[1m])), "field", "bar", "", "")`,
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := d.getAggregateMetrics(c.agg, c.metrics, c.matchers, "1m", c.by); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsQueries(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDevops(start, start.Add(24*time.Hour+time.Minute), 10)
+	cases := []struct {
+		desc string
+		fill func(q query.Query)
+		path string
+		want []string
+	}{
+		{
+			desc: "groupby time",
+			fill: func(q query.Query) { d.GroupByTime(q, 2, 2, time.Hour) },
+			path: "/api/v1/query_range",
+			want: []string{`label_replace(max(max_over_time(cpu_usage_user{hostname=~"host_`, `or label_replace(max(max_over_time(cpu_usage_system{`, "step=60s"},
+		},
+		{
+			desc: "groupby orderby limit",
+			fill: d.GroupByOrderByLimit,
+			path: "/api/v1/query_range",
+			want: []string{"query=max(max_over_time(cpu_usage_user[1m]))", "step=60s"},
+		},
+		{
+			desc: "double groupby",
+			fill: func(q query.Query) { d.GroupByTimeAndPrimaryTag(q, 1) },
+			path: "/api/v1/query_range",
+			want: []string{`query=label_replace(avg by (hostname) (avg_over_time(cpu_usage_user[1h])), "field", "usage_user", "", "")`, "step=3600s"},
+		},
+		{
+			desc: "lastpoint",
+			fill: d.LastPointPerHost,
+			path: "/api/v1/query",
+			want: []string{`query={__name__=~"cpu_.+"}`, "time=2016-01-02T00:01:00Z"},
+		},
+		{
+			desc: "high cpu all",
+			fill: func(q query.Query) { d.HighCPUForHosts(q, 0) },
+			path: "/api/v1/query_range",
+			want: []string{`query={__name__=~"cpu_.+"} and on (hostname) cpu_usage_user > 90`, "step=10s"},
+		},
+		{
+			desc: "high cpu 1",
+			fill: func(q query.Query) { d.HighCPUForHosts(q, 1) },
+			path: "/api/v1/query_range",
+			want: []string{`query={__name__=~"cpu_.+",hostname=~"host_`, `} and on (hostname) cpu_usage_user{hostname=~"host_`},
+		},
+	}
+	for _, c := range cases {
+		q := d.GenerateEmptyQuery().(*query.HTTP)
+		c.fill(q)
+		path, err := url.QueryUnescape(string(q.Path))
+		if err != nil {
+			t.Fatalf("%s: invalid path %s: %v", c.desc, q.Path, err)
+		}
+		if string(q.Method) != "GET" || !strings.HasPrefix(path, c.path+"?") {
+			t.Errorf("%s: incorrect request: %s %s", c.desc, q.Method, path)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(path, want) {
+				t.Errorf("%s: incorrect query:\n%s\nmissing\n%s", c.desc, path, want)
+			}
+		}
+		if !strings.HasPrefix(string(q.HumanLabel), "M3DB ") {
+			t.Errorf("%s: incorrect label %s", c.desc, q.HumanLabel)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/data/serialize/mysql"
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+	"github.com/timescale/tsbs/query"
+)
+
+// Formats of DATE_FORMAT truncating times to the minute and to the hour
+const (
+	minuteFormat = "'%Y-%m-%d %H:%i:00'"
+	hourFormat   = "'%Y-%m-%d %H:00:00'"
+)
+
+// Devops produces MySQL-specific queries for all the devops query types.
+// They query the tables as tsbs_load_mysql creates them, with the tags as
+// columns of each table, in the dialect of MySQL 8 and MariaDB 10.2 or
+// later, which have window functions.
+type Devops struct {
+	*devops.Core
+}
+
+// NewDevops makes an Devops object ready to generate Queries.
+func NewDevops(start, end time.Time, scale int) *Devops {
+	return &Devops{devops.NewCore(start, end, scale)}
+}
+
+// GenerateEmptyQuery returns an empty query.TimescaleDB, which holds any
+// SQL query for MySQL
+func (d *Devops) GenerateEmptyQuery() query.Query {
+	return query.NewTimescaleDB()
+}
+
+func (d *Devops) getHostWhereWithHostnames(hostnames []string) string {
+	quoted := make([]string, len(hostnames))
+	for i, s := range hostnames {
+		quoted[i] = fmt.Sprintf("'%s'", s)
+	}
+	return fmt.Sprintf("hostname IN (%s)", strings.Join(quoted, ", "))
+}
+
+func (d *Devops) getHostWhereString(nHosts int) string {
+	hostnames := d.GetRandomHosts(nHosts)
+	return d.getHostWhereWithHostnames(hostnames)
+}
+
+func (d *Devops) getSelectClausesAggMetrics(agg string, metrics []string) []string {
+	selectClauses := make([]string, len(metrics))
+	for i, m := range metrics {
+		selectClauses[i] = fmt.Sprintf("%[1]s(%[2]s) AS %[1]s_%[2]s", agg, m)
+	}
+	return selectClauses
+}
+
+// getTimeWhere returns the SQL condition for times in [start, end), which
+// the time column has in UTC
+func getTimeWhere(start, end time.Time) string {
+	return fmt.Sprintf("time >= '%s' AND time < '%s'", start.UTC().Format(mysql.TimeFormat), end.UTC().Format(mysql.TimeFormat))
+}
+
+// GroupByTime selects the MAX for numMetrics metrics under 'cpu',
+// per minute for nhosts hosts,
+// e.g. in SQL:
+//
+// SELECT DATE_FORMAT(time, '%Y-%m-%d %H:%i:00') AS minute, max(metric1) AS max_metric1, ..., max(metricN) AS max_metricN
+// FROM cpu
+// WHERE hostname IN ('$HOSTNAME_1', ..., '$HOSTNAME_N')
+// AND time >= '$HOUR_START' AND time < '$HOUR_END'
+// GROUP BY minute ORDER BY minute ASC
+func (d *Devops) GroupByTime(qi query.Query, nHosts, numMetrics int, timeRange time.Duration) {
+	interval := d.RandWindow(timeRange)
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	selectClauses := d.getSelectClausesAggMetrics("max", metrics)
+
+	sql := fmt.Sprintf("SELECT DATE_FORMAT(time, %s) AS minute, %s FROM cpu WHERE %s AND %s GROUP BY minute ORDER BY minute ASC",
+		minuteFormat, strings.Join(selectClauses, ", "), d.getHostWhereString(nHosts), getTimeWhere(interval.Start, interval.End))
+
+	humanLabel := fmt.Sprintf("MySQL %d cpu metric(s), random %4d hosts, random %s by 1m", numMetrics, nHosts, timeRange)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// GroupByOrderByLimit populates a query.Query that has a time WHERE clause, that groups by a truncated date, orders by that date, and takes a limit:
+// SELECT DATE_FORMAT(time, '%Y-%m-%d %H:%i:00') AS minute, max(usage_user) AS max_usage_user FROM cpu
+// WHERE time < '$TIME'
+// GROUP BY minute ORDER BY minute DESC
+// LIMIT 5
+func (d *Devops) GroupByOrderByLimit(qi query.Query) {
+	interval := d.RandWindow(time.Hour)
+
+	sql := fmt.Sprintf("SELECT DATE_FORMAT(time, %s) AS minute, max(usage_user) AS max_usage_user FROM cpu WHERE time < '%s' GROUP BY minute ORDER BY minute DESC LIMIT 5",
+		minuteFormat, interval.End.UTC().Format(mysql.TimeFormat))
+
+	humanLabel := "MySQL max cpu over last 5 min-intervals (random end)"
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.EndString())
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// GroupByTimeAndPrimaryTag selects the AVG of numMetrics metrics under 'cpu' per device per hour for a day,
+// e.g. in SQL:
+//
+// SELECT DATE_FORMAT(time, '%Y-%m-%d %H:00:00') AS hour, hostname, avg(metric1) AS mean_metric1, ..., avg(metricN) AS mean_metricN
+// FROM cpu
+// WHERE time >= '$HOUR_START' AND time < '$HOUR_END'
+// GROUP BY hour, hostname ORDER BY hour, hostname
+func (d *Devops) GroupByTimeAndPrimaryTag(qi query.Query, numMetrics int) {
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	interval := d.RandWindow(devops.DoubleGroupByDuration)
+
+	selectClauses := make([]string, numMetrics)
+	for i, m := range metrics {
+		selectClauses[i] = fmt.Sprintf("avg(%s) AS mean_%s", m, m)
+	}
+
+	sql := fmt.Sprintf("SELECT DATE_FORMAT(time, %s) AS hour, hostname, %s FROM cpu WHERE %s GROUP BY hour, hostname ORDER BY hour, hostname",
+		hourFormat, strings.Join(selectClauses, ", "), getTimeWhere(interval.Start, interval.End))
+
+	humanLabel := devops.GetDoubleGroupByLabel("MySQL", numMetrics)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// MaxAllCPU selects the MAX of all metrics under 'cpu' per hour for nhosts hosts,
+// e.g. in SQL:
+//
+// SELECT DATE_FORMAT(time, '%Y-%m-%d %H:00:00') AS hour, max(metric1) AS max_metric1, ..., max(metricN) AS max_metricN
+// FROM cpu WHERE hostname IN ('$HOSTNAME_1', ..., '$HOSTNAME_N')
+// AND time >= '$HOUR_START' AND time < '$HOUR_END'
+// GROUP BY hour ORDER BY hour
+func (d *Devops) MaxAllCPU(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.MaxAllDuration)
+	selectClauses := d.getSelectClausesAggMetrics("max", devops.GetAllCPUMetrics())
+
+	sql := fmt.Sprintf("SELECT DATE_FORMAT(time, %s) AS hour, %s FROM cpu WHERE %s AND %s GROUP BY hour ORDER BY hour",
+		hourFormat, strings.Join(selectClauses, ", "), d.getHostWhereString(nHosts), getTimeWhere(interval.Start, interval.End))
+
+	humanLabel := devops.GetMaxAllLabel("MySQL", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// LastPointPerHost finds the last row for every host in the dataset, the
+// first of each host when ranked by time descending
+func (d *Devops) LastPointPerHost(qi query.Query) {
+	sql := "SELECT * FROM (SELECT cpu.*, ROW_NUMBER() OVER (PARTITION BY hostname ORDER BY time DESC) AS rn FROM cpu) AS latest WHERE rn = 1 ORDER BY hostname"
+
+	humanLabel := "MySQL last row per host"
+	humanDesc := humanLabel
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// HighCPUForHosts populates a query that gets CPU metrics when the CPU has high
+// usage between a time period for a number of hosts (if 0, it will search all hosts),
+// e.g. in SQL:
+//
+// SELECT * FROM cpu
+// WHERE usage_user > 90.0
+// AND time >= '$TIME_START' AND time < '$TIME_END'
+// AND hostname IN ('$HOST', '$HOST2', ...)
+func (d *Devops) HighCPUForHosts(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.HighCPUDuration)
+	var hostWhereClause string
+	if nHosts > 0 {
+		hostWhereClause = " AND " + d.getHostWhereString(nHosts)
+	}
+
+	sql := fmt.Sprintf("SELECT * FROM cpu WHERE usage_user > 90.0 AND %s%s", getTimeWhere(interval.Start, interval.End), hostWhereClause)
+
+	humanLabel := devops.GetHighCPULabel("MySQL", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+func (d *Devops) fillInQuery(qi query.Query, humanLabel, humanDesc, sql string) {
+	q := qi.(*query.TimescaleDB)
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(humanDesc)
+	q.Hypertable = []byte("cpu")
+	q.SqlQuery = []byte(sql)
+}
@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+)
+
+func TestDevopsGetHostWhereWithHostnames(t *testing.T) {
+	cases := []struct {
+		desc      string
+		hostnames []string
+		want      string
+	}{
+		{
+			desc:      "single host",
+			hostnames: []string{"foo1"},
+			want:      "hostname IN ('foo1')",
+		},
+		{
+			desc:      "multi host (2)",
+			hostnames: []string{"foo1", "foo2"},
+			want:      "hostname IN ('foo1', 'foo2')",
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := d.getHostWhereWithHostnames(c.hostnames); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsGetSelectClausesAggMetrics(t *testing.T) {
+	cases := []struct {
+		desc    string
+		agg     string
+		metrics []string
+		want    string
+	}{
+		{
+			desc:    "single metric - max",
+			agg:     "max",
+			metrics: []string{"foo"},
+			want:    "max(foo) AS max_foo",
+		},
+		{
+			desc:    "multiple metric - avg",
+			agg:     "avg",
+			metrics: []string{"foo", "bar"},
+			want:    "avg(foo) AS avg_foo, avg(bar) AS avg_bar",
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := strings.Join(d.getSelectClausesAggMetrics(c.agg, c.metrics), ", "); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsQueries(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDevops(start, start.Add(24*time.Hour+time.Minute), 10)
+	cases := []struct {
+		desc string
+		fill func(q query.Query)
+		want []string
+	}{
+		{
+			desc: "groupby time",
+			fill: func(q query.Query) { d.GroupByTime(q, 2, 2, time.Hour) },
+			want: []string{
+				"SELECT DATE_FORMAT(time, '%Y-%m-%d %H:%i:00') AS minute, max(usage_user) AS max_usage_user, max(usage_system) AS max_usage_system FROM cpu WHERE hostname IN ('host_",
+				"AND time >= '2016-01-01 ", "GROUP BY minute ORDER BY minute ASC",
+			},
+		},
+		{
+			desc: "groupby orderby limit",
+			fill: d.GroupByOrderByLimit,
+			want: []string{"WHERE time < '2016-01-01 ", "GROUP BY minute ORDER BY minute DESC LIMIT 5"},
+		},
+		{
+			desc: "double groupby",
+			fill: func(q query.Query) { d.GroupByTimeAndPrimaryTag(q, 1) },
+			want: []string{"SELECT DATE_FORMAT(time, '%Y-%m-%d %H:00:00') AS hour, hostname, avg(usage_user) AS mean_usage_user FROM cpu", "GROUP BY hour, hostname ORDER BY hour, hostname"},
+		},
+		{
+			desc: "lastpoint",
+			fill: d.LastPointPerHost,
+			want: []string{"ROW_NUMBER() OVER (PARTITION BY hostname ORDER BY time DESC) AS rn FROM cpu) AS latest WHERE rn = 1"},
+		},
+		{
+			desc: "high cpu all",
+			fill: func(q query.Query) { d.HighCPUForHosts(q, 0) },
+			want: []string{"SELECT * FROM cpu WHERE usage_user > 90.0 AND time >= '2016-01-01 "},
+		},
+	}
+	for _, c := range cases {
+		q := d.GenerateEmptyQuery().(*query.TimescaleDB)
+		c.fill(q)
+		if string(q.Hypertable) != "cpu" {
+			t.Errorf("%s: incorrect table %s", c.desc, q.Hypertable)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(q.SqlQuery), want) {
+				t.Errorf("%s: incorrect query:\n%s\nmissing\n%s", c.desc, q.SqlQuery, want)
+			}
+		}
+		if !strings.HasPrefix(string(q.HumanLabel), "MySQL ") {
+			t.Errorf("%s: incorrect label %s", c.desc, q.HumanLabel)
+		}
+	}
+}
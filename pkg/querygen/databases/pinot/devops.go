@@ -0,0 +1,184 @@
+package pinot
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+	"github.com/timescale/tsbs/query"
+)
+
+// maxRows is the LIMIT of queries of all their rows, since Pinot returns 10
+// rows by default
+const maxRows = 1000000
+
+// Devops produces Pinot-specific queries, in SQL, for all the devops query
+// types.
+type Devops struct {
+	*devops.Core
+}
+
+// NewDevops makes an Devops object ready to generate Queries.
+func NewDevops(start, end time.Time, scale int) *Devops {
+	return &Devops{devops.NewCore(start, end, scale)}
+}
+
+// GenerateEmptyQuery returns an empty query.HTTP
+func (d *Devops) GenerateEmptyQuery() query.Query {
+	return query.NewHTTP()
+}
+
+func (d *Devops) getHostWhereWithHostnames(hostnames []string) string {
+	quoted := make([]string, len(hostnames))
+	for i, s := range hostnames {
+		quoted[i] = fmt.Sprintf("'%s'", s)
+	}
+	return fmt.Sprintf("hostname IN (%s)", strings.Join(quoted, ", "))
+}
+
+func (d *Devops) getHostWhereString(nHosts int) string {
+	hostnames := d.GetRandomHosts(nHosts)
+	return d.getHostWhereWithHostnames(hostnames)
+}
+
+func (d *Devops) getSelectClausesAggMetrics(agg string, metrics []string) []string {
+	selectClauses := make([]string, len(metrics))
+	for i, m := range metrics {
+		selectClauses[i] = fmt.Sprintf("%[1]s(%[2]s) AS %[1]s_%[2]s", agg, m)
+	}
+	return selectClauses
+}
+
+// getTimeWhere returns the SQL condition for times in [start, end), as the
+// milliseconds since the epoch the time column holds
+func getTimeWhere(start, end time.Time) string {
+	return fmt.Sprintf("ts >= %d AND ts < %d", start.UnixNano()/1e6, end.UnixNano()/1e6)
+}
+
+// GroupByTime selects the MAX for numMetrics metrics under 'cpu',
+// per minute for nhosts hosts,
+// e.g. in SQL:
+//
+// SELECT DATETRUNC('MINUTE', ts) AS minute, max(metric1) AS max_metric1, ..., max(metricN) AS max_metricN
+// FROM cpu
+// WHERE hostname IN ('$HOSTNAME_1', ..., '$HOSTNAME_N')
+// AND ts >= $HOUR_START AND ts < $HOUR_END
+// GROUP BY DATETRUNC('MINUTE', ts) ORDER BY DATETRUNC('MINUTE', ts) ASC LIMIT 1000000
+func (d *Devops) GroupByTime(qi query.Query, nHosts, numMetrics int, timeRange time.Duration) {
+	interval := d.RandWindow(timeRange)
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	selectClauses := d.getSelectClausesAggMetrics("max", metrics)
+	whereHosts := d.getHostWhereString(nHosts)
+
+	humanLabel := fmt.Sprintf("Pinot %d cpu metric(s), random %4d hosts, random %s by 1m", numMetrics, nHosts, timeRange)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT DATETRUNC('MINUTE', ts) AS minute, %s FROM cpu WHERE %s AND %s GROUP BY DATETRUNC('MINUTE', ts) ORDER BY DATETRUNC('MINUTE', ts) ASC LIMIT %d",
+		strings.Join(selectClauses, ", "), whereHosts, getTimeWhere(interval.Start, interval.End), maxRows)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// GroupByOrderByLimit benchmarks a query that has a time WHERE clause, that groups by a truncated date, orders by that date, and takes a limit:
+// SELECT DATETRUNC('MINUTE', ts) AS minute, max(usage_user) AS max_usage_user FROM cpu
+// WHERE ts < $TIME
+// GROUP BY DATETRUNC('MINUTE', ts) ORDER BY DATETRUNC('MINUTE', ts) DESC
+// LIMIT 5
+func (d *Devops) GroupByOrderByLimit(qi query.Query) {
+	interval := d.RandWindow(time.Hour)
+
+	humanLabel := "Pinot max cpu over last 5 min-intervals (random end)"
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT DATETRUNC('MINUTE', ts) AS minute, max(usage_user) AS max_usage_user FROM cpu WHERE ts < %d GROUP BY DATETRUNC('MINUTE', ts) ORDER BY DATETRUNC('MINUTE', ts) DESC LIMIT 5",
+		interval.End.UnixNano()/1e6)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// GroupByTimeAndPrimaryTag selects the AVG of numMetrics metrics under 'cpu' per device per hour for a day,
+// e.g. in SQL:
+//
+// SELECT DATETRUNC('HOUR', ts) AS hour, hostname, avg(metric1) AS avg_metric1, ..., avg(metricN) AS avg_metricN
+// FROM cpu
+// WHERE ts >= $HOUR_START AND ts < $HOUR_END
+// GROUP BY DATETRUNC('HOUR', ts), hostname ORDER BY DATETRUNC('HOUR', ts), hostname LIMIT 1000000
+func (d *Devops) GroupByTimeAndPrimaryTag(qi query.Query, numMetrics int) {
+	metrics := devops.GetCPUMetricsSlice(numMetrics)
+	interval := d.RandWindow(devops.DoubleGroupByDuration)
+	selectClauses := d.getSelectClausesAggMetrics("avg", metrics)
+
+	humanLabel := devops.GetDoubleGroupByLabel("Pinot", numMetrics)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT DATETRUNC('HOUR', ts) AS hour, hostname, %s FROM cpu WHERE %s GROUP BY DATETRUNC('HOUR', ts), hostname ORDER BY DATETRUNC('HOUR', ts), hostname LIMIT %d",
+		strings.Join(selectClauses, ", "), getTimeWhere(interval.Start, interval.End), maxRows)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// MaxAllCPU selects the MAX of all metrics under 'cpu' per hour for nhosts hosts,
+// e.g. in SQL:
+//
+// SELECT DATETRUNC('HOUR', ts) AS hour, max(metric1) AS max_metric1, ..., max(metricN) AS max_metricN
+// FROM cpu WHERE hostname IN ('$HOSTNAME_1', ..., '$HOSTNAME_N')
+// AND ts >= $HOUR_START AND ts < $HOUR_END
+// GROUP BY DATETRUNC('HOUR', ts) ORDER BY DATETRUNC('HOUR', ts) LIMIT 1000000
+func (d *Devops) MaxAllCPU(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.MaxAllDuration)
+	whereHosts := d.getHostWhereString(nHosts)
+	selectClauses := d.getSelectClausesAggMetrics("max", devops.GetAllCPUMetrics())
+
+	humanLabel := devops.GetMaxAllLabel("Pinot", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT DATETRUNC('HOUR', ts) AS hour, %s FROM cpu WHERE %s AND %s GROUP BY DATETRUNC('HOUR', ts) ORDER BY DATETRUNC('HOUR', ts) LIMIT %d",
+		strings.Join(selectClauses, ", "), whereHosts, getTimeWhere(interval.Start, interval.End), maxRows)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// LastPointPerHost finds the last reading of every cpu metric of every host
+// in the dataset, with the LASTWITHTIME aggregation of Pinot, e.g. in SQL:
+//
+// SELECT hostname, LASTWITHTIME(metric1, ts, 'DOUBLE') AS metric1, ..., LASTWITHTIME(metricN, ts, 'DOUBLE') AS metricN
+// FROM cpu GROUP BY hostname ORDER BY hostname LIMIT 1000000
+func (d *Devops) LastPointPerHost(qi query.Query) {
+	metrics := devops.GetAllCPUMetrics()
+	selectClauses := make([]string, len(metrics))
+	for i, m := range metrics {
+		selectClauses[i] = fmt.Sprintf("LASTWITHTIME(%[1]s, ts, 'DOUBLE') AS %[1]s", m)
+	}
+
+	humanLabel := "Pinot last row per host"
+	humanDesc := humanLabel + ": cpu"
+	sql := fmt.Sprintf("SELECT hostname, %s FROM cpu GROUP BY hostname ORDER BY hostname LIMIT %d", strings.Join(selectClauses, ", "), maxRows)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// HighCPUForHosts populates a query that gets CPU metrics when the CPU has high
+// usage between a time period for a number of hosts (if 0, it will search all hosts),
+// e.g. in SQL:
+//
+// SELECT * FROM cpu
+// WHERE usage_user > 90.0
+// AND ts >= $TIME_START AND ts < $TIME_END
+// AND hostname IN ('$HOST', '$HOST2', ...)
+// LIMIT 1000000
+func (d *Devops) HighCPUForHosts(qi query.Query, nHosts int) {
+	interval := d.RandWindow(devops.HighCPUDuration)
+	var hostWhereClause string
+	if nHosts > 0 {
+		hostWhereClause = " AND " + d.getHostWhereString(nHosts)
+	}
+
+	humanLabel := devops.GetHighCPULabel("Pinot", nHosts)
+	humanDesc := fmt.Sprintf("%s: %s", humanLabel, interval.StartString())
+	sql := fmt.Sprintf("SELECT * FROM cpu WHERE usage_user > 90.0 AND %s%s LIMIT %d", getTimeWhere(interval.Start, interval.End), hostWhereClause, maxRows)
+	d.fillInQuery(qi, humanLabel, humanDesc, sql)
+}
+
+// fillInQuery fills in qi to run sql with the SQL query endpoint of a
+// broker. The body is the query alone, which the query runner sends in the
+// JSON request the endpoint takes.
+func (d *Devops) fillInQuery(qi query.Query, humanLabel, humanDesc, sql string) {
+	q := qi.(*query.HTTP)
+	q.HumanLabel = []byte(humanLabel)
+	q.HumanDescription = []byte(humanDesc)
+	q.Method = []byte("POST")
+	q.Path = []byte("/query/sql")
+	q.Body = []byte(sql)
+}
@@ -0,0 +1,128 @@
+package pinot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+)
+
+func TestDevopsGetHostWhereWithHostnames(t *testing.T) {
+	cases := []struct {
+		desc      string
+		hostnames []string
+		want      string
+	}{
+		{
+			desc:      "single host",
+			hostnames: []string{"foo1"},
+			want:      "hostname IN ('foo1')",
+		},
+		{
+			desc:      "multi host (2)",
+			hostnames: []string{"foo1", "foo2"},
+			want:      "hostname IN ('foo1', 'foo2')",
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := d.getHostWhereWithHostnames(c.hostnames); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDevopsGetSelectClausesAggMetrics(t *testing.T) {
+	cases := []struct {
+		desc    string
+		agg     string
+		metrics []string
+		want    string
+	}{
+		{
+			desc:    "single metric - max",
+			agg:     "max",
+			metrics: []string{"foo"},
+			want:    "max(foo) AS max_foo",
+		},
+		{
+			desc:    "multiple metric - avg",
+			agg:     "avg",
+			metrics: []string{"foo", "bar"},
+			want:    "avg(foo) AS avg_foo, avg(bar) AS avg_bar",
+		},
+	}
+
+	for _, c := range cases {
+		d := NewDevops(time.Now(), time.Now(), 10)
+
+		if got := strings.Join(d.getSelectClausesAggMetrics(c.agg, c.metrics), ", "); got != c.want {
+			t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestGetTimeWhere(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := "ts >= 1451606400000 AND ts < 1451606460500"
+	if got := getTimeWhere(start, start.Add(time.Minute+500*time.Millisecond)); got != want {
+		t.Errorf("incorrect output: got %s want %s", got, want)
+	}
+}
+
+func TestDevopsQueries(t *testing.T) {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	d := NewDevops(start, start.Add(24*time.Hour+time.Minute), 10)
+	cases := []struct {
+		desc string
+		fill func(q query.Query)
+		want []string
+	}{
+		{
+			desc: "groupby time",
+			fill: func(q query.Query) { d.GroupByTime(q, 2, 2, time.Hour) },
+			want: []string{
+				"SELECT DATETRUNC('MINUTE', ts) AS minute, max(usage_user) AS max_usage_user, max(usage_system) AS max_usage_system FROM cpu WHERE hostname IN ('host_",
+				"AND ts >= 14516", "GROUP BY DATETRUNC('MINUTE', ts) ORDER BY DATETRUNC('MINUTE', ts) ASC LIMIT 1000000",
+			},
+		},
+		{
+			desc: "groupby orderby limit",
+			fill: d.GroupByOrderByLimit,
+			want: []string{"WHERE ts < 14516", "GROUP BY DATETRUNC('MINUTE', ts) ORDER BY DATETRUNC('MINUTE', ts) DESC LIMIT 5"},
+		},
+		{
+			desc: "double groupby",
+			fill: func(q query.Query) { d.GroupByTimeAndPrimaryTag(q, 1) },
+			want: []string{"SELECT DATETRUNC('HOUR', ts) AS hour, hostname, avg(usage_user) AS avg_usage_user FROM cpu", "GROUP BY DATETRUNC('HOUR', ts), hostname ORDER BY DATETRUNC('HOUR', ts), hostname LIMIT 1000000"},
+		},
+		{
+			desc: "lastpoint",
+			fill: d.LastPointPerHost,
+			want: []string{"SELECT hostname, LASTWITHTIME(usage_user, ts, 'DOUBLE') AS usage_user, ", "FROM cpu GROUP BY hostname ORDER BY hostname LIMIT 1000000"},
+		},
+		{
+			desc: "high cpu all",
+			fill: func(q query.Query) { d.HighCPUForHosts(q, 0) },
+			want: []string{"SELECT * FROM cpu WHERE usage_user > 90.0 AND ts >= 14516", " LIMIT 1000000"},
+		},
+	}
+	for _, c := range cases {
+		q := d.GenerateEmptyQuery().(*query.HTTP)
+		c.fill(q)
+		if string(q.Method) != "POST" || string(q.Path) != "/query/sql" {
+			t.Errorf("%s: incorrect request: %s %s", c.desc, q.Method, q.Path)
+		}
+		for _, want := range c.want {
+			if !strings.Contains(string(q.Body), want) {
+				t.Errorf("%s: incorrect query:\n%s\nmissing\n%s", c.desc, q.Body, want)
+			}
+		}
+		if !strings.HasPrefix(string(q.HumanLabel), "Pinot ") {
+			t.Errorf("%s: incorrect label %s", c.desc, q.HumanLabel)
+		}
+	}
+}
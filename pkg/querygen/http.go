@@ -0,0 +1,179 @@
+package querygen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/timescale/tsbs/query"
+)
+
+const (
+	defaultHTTPCount = 100
+	// maxHTTPCount bounds the queries returned by a single request
+	maxHTTPCount = 10000
+)
+
+// NewHandler returns an http.Handler that generates queries on GET requests
+// and returns them as JSON, so queries can be generated without writing
+// files, e.g., by load generators written in other languages:
+//
+//	GET /?target=influx&use_case=devops&query_type=lastpoint&scale=10&seed=123&count=10
+//
+// The parameters match the fields of Config: target, use_case, query_type,
+// scale, seed, timestamp_start and timestamp_end (RFC3339), count (default
+// 100, at most 10000), timescale_use_json and timescale_use_tags (default
+// true). offset skips that many queries first, so a client can page through
+// the queries of a seed. The response is of the form {"queries": [...]}.
+//
+// Since queries are generated from the global math/rand source, requests are
+// handled one at a time.
+func NewHandler() http.Handler {
+	return &handler{}
+}
+
+type handler struct {
+	mu sync.Mutex
+}
+
+type httpResponse struct {
+	Queries []query.Query `json:"queries"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	target, useCase, c, offset, err := parseHTTPParams(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.mu.Lock()
+	queries, err := generate(target, useCase, c, offset)
+	h.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer func() {
+		for _, q := range queries {
+			q.Release()
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(httpResponse{Queries: queries}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// generate returns the queries of c after skipping the first offset
+func generate(target, useCase string, c Config, offset int) ([]query.Query, error) {
+	count := c.Count
+	c.Count += offset
+	it, err := New(target, useCase, c)
+	if err != nil {
+		return nil, err
+	}
+	queries := make([]query.Query, 0, count)
+	for i := 0; it.Next(); i++ {
+		if i < offset {
+			it.Query().Release()
+			continue
+		}
+		queries = append(queries, it.Query())
+	}
+	if err := it.Err(); err != nil {
+		for _, q := range queries {
+			q.Release()
+		}
+		return nil, err
+	}
+	return queries, nil
+}
+
+func parseHTTPParams(v url.Values) (target, useCase string, c Config, offset int, err error) {
+	target = v.Get("target")
+	useCase = v.Get("use_case")
+	c = Config{
+		QueryType: v.Get("query_type"),
+	}
+	if c.Scale, err = intParam(v, "scale", 1); err != nil {
+		return
+	}
+	if c.Count, err = intParam(v, "count", defaultHTTPCount); err != nil {
+		return
+	}
+	if c.Count <= 0 || c.Count > maxHTTPCount {
+		err = fmt.Errorf("count must be between 1 and %d: %d", maxHTTPCount, c.Count)
+		return
+	}
+	if offset, err = intParam(v, "offset", 0); err != nil {
+		return
+	}
+	if offset < 0 {
+		err = fmt.Errorf("offset cannot be negative: %d", offset)
+		return
+	}
+	if s := v.Get("seed"); s != "" {
+		if c.Seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			err = fmt.Errorf("invalid seed: %v", err)
+			return
+		}
+	}
+	if c.TimestampStart, err = timeParam(v, "timestamp_start", "2016-01-01T00:00:00Z"); err != nil {
+		return
+	}
+	if c.TimestampEnd, err = timeParam(v, "timestamp_end", "2016-01-02T06:00:00Z"); err != nil {
+		return
+	}
+	if c.TimescaleUseJSON, err = boolParam(v, "timescale_use_json", false); err != nil {
+		return
+	}
+	c.TimescaleUseTags, err = boolParam(v, "timescale_use_tags", true)
+	return
+}
+
+func intParam(v url.Values, name string, def int) (int, error) {
+	s := v.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return i, nil
+}
+
+func boolParam(v url.Values, name string, def bool) (bool, error) {
+	s := v.Get(name)
+	if s == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return b, nil
+}
+
+func timeParam(v url.Values, name, def string) (time.Time, error) {
+	s := v.Get(name)
+	if s == "" {
+		s = def
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return t, nil
+}
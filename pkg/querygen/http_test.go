@@ -0,0 +1,79 @@
+package querygen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler())
+	defer srv.Close()
+
+	type response struct {
+		Queries []struct {
+			Type       string `json:"type"`
+			HumanLabel string `json:"human_label"`
+			Path       string `json:"path"`
+		} `json:"queries"`
+	}
+	get := func(params string) (int, response) {
+		resp, err := http.Get(srv.URL + "/?" + params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		var r response
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+		}
+		return resp.StatusCode, r
+	}
+
+	const base = "target=influx&use_case=devops&query_type=lastpoint&scale=4&seed=123"
+	status, all := get(base + "&count=5")
+	if status != http.StatusOK {
+		t.Fatalf("incorrect status: got %d want %d", status, http.StatusOK)
+	}
+	if got := len(all.Queries); got != 5 {
+		t.Fatalf("incorrect number of queries: got %d want 5", got)
+	}
+	for _, q := range all.Queries {
+		if q.Type != "http" || q.HumanLabel == "" || q.Path == "" {
+			t.Errorf("incomplete query: %+v", q)
+		}
+	}
+
+	status, page := get(base + "&count=2&offset=3")
+	if status != http.StatusOK {
+		t.Fatalf("incorrect status: got %d want %d", status, http.StatusOK)
+	}
+	if got := len(page.Queries); got != 2 {
+		t.Fatalf("incorrect number of queries: got %d want 2", got)
+	}
+	for i, q := range page.Queries {
+		if q != all.Queries[i+3] {
+			t.Errorf("offset query %d does not match: got %+v want %+v", i, q, all.Queries[i+3])
+		}
+	}
+
+	cases := []struct {
+		desc   string
+		params string
+	}{
+		{desc: "unknown target", params: "target=foo&use_case=devops&query_type=lastpoint"},
+		{desc: "unknown query type", params: "target=influx&use_case=devops&query_type=foo"},
+		{desc: "bad scale", params: "target=influx&use_case=devops&query_type=lastpoint&scale=x"},
+		{desc: "count too large", params: base + "&count=10001"},
+		{desc: "negative offset", params: base + "&offset=-1"},
+		{desc: "bad timestamp", params: base + "&timestamp_start=yesterday"},
+	}
+	for _, c := range cases {
+		if status, _ := get(c.params); status != http.StatusBadRequest {
+			t.Errorf("%s: incorrect status: got %d want %d", c.desc, status, http.StatusBadRequest)
+		}
+	}
+}
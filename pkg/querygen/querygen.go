@@ -0,0 +1,374 @@
+// Package querygen generates queries for benchmarking from pre-specified use
+// cases, the same queries produced by tsbs_generate_queries, so they can be
+// generated and executed in-process, e.g., by a database's integration tests:
+//
+//	it, err := querygen.New(querygen.TargetInflux, querygen.UseCaseDevops, querygen.Config{
+//		QueryType:      "lastpoint",
+//		Scale:          10,
+//		Seed:           123,
+//		TimestampStart: start,
+//		TimestampEnd:   end,
+//		Count:          100,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	for it.Next() {
+//		q := it.Query()
+//		// execute q
+//		q.Release()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+//
+// The query generators for each target and the query types of each use case
+// are available in the databases and uses subpackages.
+package querygen
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/querygen/databases/adx"
+	"github.com/timescale/tsbs/pkg/querygen/databases/cassandra"
+	"github.com/timescale/tsbs/pkg/querygen/databases/duckdb"
+	"github.com/timescale/tsbs/pkg/querygen/databases/greptime"
+	"github.com/timescale/tsbs/pkg/querygen/databases/influx"
+	"github.com/timescale/tsbs/pkg/querygen/databases/influx3"
+	"github.com/timescale/tsbs/pkg/querygen/databases/m3db"
+	"github.com/timescale/tsbs/pkg/querygen/databases/mongo"
+	"github.com/timescale/tsbs/pkg/querygen/databases/mysql"
+	"github.com/timescale/tsbs/pkg/querygen/databases/pinot"
+	"github.com/timescale/tsbs/pkg/querygen/databases/postgres"
+	"github.com/timescale/tsbs/pkg/querygen/databases/sqlite"
+	"github.com/timescale/tsbs/pkg/querygen/databases/timescaledb"
+	"github.com/timescale/tsbs/pkg/querygen/uses/devops"
+	"github.com/timescale/tsbs/pkg/querygen/utils"
+	"github.com/timescale/tsbs/pkg/rng"
+	"github.com/timescale/tsbs/pkg/suggest"
+	"github.com/timescale/tsbs/query"
+)
+
+const (
+	// Builtin target choices (alphabetical order)
+	TargetADX         = "adx"
+	TargetCassandra   = "cassandra"
+	TargetDuckDB      = "duckdb"
+	TargetGreptime    = "greptime"
+	TargetInflux      = "influx"
+	TargetInflux3     = "influx3"
+	TargetM3DB        = "m3db"
+	TargetMongo       = "mongo"
+	TargetMongoNaive  = "mongo-naive"
+	TargetMySQL       = "mysql"
+	TargetPinot       = "pinot"
+	TargetPostgres    = "postgres"
+	TargetSQLite      = "sqlite"
+	TargetTimescaleDB = "timescaledb"
+
+	// Use case choices
+	UseCaseCPUOnly = "cpu-only"
+	UseCaseDevops  = "devops"
+)
+
+var devopsQueryTypes = map[string]utils.QueryFillerMaker{
+	devops.LabelSingleGroupby + "-1-1-1":  devops.NewSingleGroupby(1, 1, 1),
+	devops.LabelSingleGroupby + "-1-1-12": devops.NewSingleGroupby(1, 1, 12),
+	devops.LabelSingleGroupby + "-1-8-1":  devops.NewSingleGroupby(1, 8, 1),
+	devops.LabelSingleGroupby + "-5-1-1":  devops.NewSingleGroupby(5, 1, 1),
+	devops.LabelSingleGroupby + "-5-1-12": devops.NewSingleGroupby(5, 1, 12),
+	devops.LabelSingleGroupby + "-5-8-1":  devops.NewSingleGroupby(5, 8, 1),
+	devops.LabelMaxAll + "-1":             devops.NewMaxAllCPU(1),
+	devops.LabelMaxAll + "-8":             devops.NewMaxAllCPU(8),
+	devops.LabelDoubleGroupby + "-1":      devops.NewGroupBy(1),
+	devops.LabelDoubleGroupby + "-5":      devops.NewGroupBy(5),
+	devops.LabelDoubleGroupby + "-all":    devops.NewGroupBy(devops.GetCPUMetricsLen()),
+	devops.LabelGroupbyOrderbyLimit:       devops.NewGroupByOrderByLimit,
+	devops.LabelHighCPU + "-all":          devops.NewHighCPU(0),
+	devops.LabelHighCPU + "-1":            devops.NewHighCPU(1),
+	devops.LabelLastpoint:                 devops.NewLastPointPerHost,
+}
+
+var queryTypeDescriptions = map[string]string{
+	devops.LabelSingleGroupby + "-1-1-1":  "Simple aggregate (MAX) on one metric for 1 host, every 5 mins for 1 hour",
+	devops.LabelSingleGroupby + "-1-1-12": "Simple aggregate (MAX) on one metric for 1 host, every 5 mins for 12 hours",
+	devops.LabelSingleGroupby + "-1-8-1":  "Simple aggregate (MAX) on one metric for 8 hosts, every 5 mins for 1 hour",
+	devops.LabelSingleGroupby + "-5-1-1":  "Simple aggregate (MAX) on 5 metrics for 1 host, every 5 mins for 1 hour",
+	devops.LabelSingleGroupby + "-5-1-12": "Simple aggregate (MAX) on 5 metrics for 1 host, every 5 mins for 12 hours",
+	devops.LabelSingleGroupby + "-5-8-1":  "Simple aggregate (MAX) on 5 metrics for 8 hosts, every 5 mins for 1 hour",
+	devops.LabelMaxAll + "-1":             "Aggregate across all CPU metrics per hour over 1 hour for a single host",
+	devops.LabelMaxAll + "-8":             "Aggregate across all CPU metrics per hour over 1 hour for eight hosts",
+	devops.LabelDoubleGroupby + "-1":      "Average of 1 CPU metric per host per hour for 24 hours",
+	devops.LabelDoubleGroupby + "-5":      "Average of 5 CPU metrics per host per hour for 24 hours",
+	devops.LabelDoubleGroupby + "-all":    "Average of all (10) CPU metrics per host per hour for 24 hours",
+	devops.LabelGroupbyOrderbyLimit:       "The last 5 aggregate readings (across time) before a randomly chosen endpoint",
+	devops.LabelHighCPU + "-all":          "All the readings where one metric is above a threshold across all hosts",
+	devops.LabelHighCPU + "-1":            "All the readings where one metric is above a threshold for a particular host",
+	devops.LabelLastpoint:                 "The last reading for each host",
+}
+
+// useCaseMatrix maps each use case to its query types
+var useCaseMatrix = map[string]map[string]utils.QueryFillerMaker{
+	UseCaseCPUOnly: devopsQueryTypes,
+	UseCaseDevops:  devopsQueryTypes,
+}
+
+// Targets returns the supported targets, which are the builtin ones plus any
+// others registered with utils.RegisterDevopsGenerator
+func Targets() []string {
+	targets := []string{TargetADX, TargetCassandra, TargetDuckDB, TargetGreptime, TargetInflux, TargetInflux3, TargetM3DB, TargetMongo, TargetMongoNaive, TargetMySQL, TargetPinot, TargetPostgres, TargetSQLite, TargetTimescaleDB}
+	return append(targets, utils.RegisteredFormats()...)
+}
+
+// UseCases returns the supported use cases in sorted order
+func UseCases() []string {
+	useCases := make([]string, 0, len(useCaseMatrix))
+	for uc := range useCaseMatrix {
+		useCases = append(useCases, uc)
+	}
+	sort.Strings(useCases)
+	return useCases
+}
+
+// QueryTypes returns the query types of a use case in sorted order, or nil if
+// the use case is not supported
+func QueryTypes(useCase string) []string {
+	makers, ok := useCaseMatrix[useCase]
+	if !ok {
+		return nil
+	}
+	queryTypes := make([]string, 0, len(makers))
+	for qt := range makers {
+		queryTypes = append(queryTypes, qt)
+	}
+	sort.Strings(queryTypes)
+	return queryTypes
+}
+
+// QueryTypeDescription returns a one line description of a query type, or ""
+// if it is not supported
+func QueryTypeDescription(queryType string) string {
+	return queryTypeDescriptions[queryType]
+}
+
+// Config holds the options for generating queries
+type Config struct {
+	// QueryType is the type of query to generate, one of QueryTypes(useCase)
+	QueryType string
+	// Scale is the use case specific scaling variable, e.g., the number of
+	// hosts in the devops use case. It must match the scale of the data
+	// being queried
+	Scale int
+	// Seed is the seed for all random values generated
+	Seed int64
+	// TimestampStart and TimestampEnd bound the time range of the data being
+	// queried
+	TimestampStart time.Time
+	TimestampEnd   time.Time
+	// Count is the number of queries to generate (before interleaving); 0
+	// means to generate queries indefinitely
+	Count int
+	// InterleavedGroupID and InterleavedNumGroups split the queries between
+	// multiple generators in a round-robin manner; only queries belonging to
+	// group InterleavedGroupID (0-indexed) are returned. A
+	// InterleavedNumGroups of 0 is treated as 1, i.e., all queries are
+	// returned
+	InterleavedGroupID   uint
+	InterleavedNumGroups uint
+
+	// TimescaleUseJSON makes TimescaleDB queries use a separate JSON tags
+	// table
+	TimescaleUseJSON bool
+	// TimescaleUseTags makes TimescaleDB queries use a separate tags table,
+	// as tsbs_generate_queries does by default
+	TimescaleUseTags bool
+}
+
+// Validate checks that the config is usable for the given use case, returning
+// an error describing the first problem found
+func (c *Config) Validate(useCase string) error {
+	if _, ok := useCaseMatrix[useCase]; !ok {
+		return suggest.Error("use case", useCase, UseCases())
+	}
+	if _, ok := useCaseMatrix[useCase][c.QueryType]; !ok {
+		return suggest.Error("query type", c.QueryType, QueryTypes(useCase))
+	}
+	if c.Scale <= 0 {
+		return fmt.Errorf("scale must be greater than 0")
+	}
+	if c.Count < 0 {
+		return fmt.Errorf("count cannot be negative: %d", c.Count)
+	}
+	if !c.TimestampStart.Before(c.TimestampEnd) {
+		return fmt.Errorf("end timestamp %v is not after start timestamp %v", c.TimestampEnd, c.TimestampStart)
+	}
+	if c.InterleavedNumGroups > 0 && c.InterleavedGroupID >= c.InterleavedNumGroups {
+		return fmt.Errorf("incorrect interleaved groups configuration: id %d >= total groups %d", c.InterleavedGroupID, c.InterleavedNumGroups)
+	}
+	return nil
+}
+
+// NewDevopsGenerator returns the DevopsGenerator for a target, querying data
+// from start to end with the given scale
+func NewDevopsGenerator(target string, start, end time.Time, scale int, c Config) (utils.DevopsGenerator, error) {
+	switch target {
+	case TargetADX:
+		return adx.NewDevops(start, end, scale), nil
+	case TargetCassandra:
+		return cassandra.NewDevops(start, end, scale), nil
+	case TargetDuckDB:
+		return duckdb.NewDevops(start, end, scale), nil
+	case TargetGreptime:
+		return greptime.NewDevops(start, end, scale), nil
+	case TargetInflux:
+		return influx.NewDevops(start, end, scale), nil
+	case TargetInflux3:
+		return influx3.NewDevops(start, end, scale), nil
+	case TargetM3DB:
+		return m3db.NewDevops(start, end, scale), nil
+	case TargetMongo:
+		return mongo.NewDevops(start, end, scale), nil
+	case TargetMongoNaive:
+		return mongo.NewNaiveDevops(start, end, scale), nil
+	case TargetMySQL:
+		return mysql.NewDevops(start, end, scale), nil
+	case TargetPinot:
+		return pinot.NewDevops(start, end, scale), nil
+	case TargetPostgres:
+		return postgres.NewDevops(start, end, scale), nil
+	case TargetSQLite:
+		return sqlite.NewDevops(start, end, scale), nil
+	case TargetTimescaleDB:
+		tgen := timescaledb.NewDevops(start, end, scale)
+		tgen.UseJSON = c.TimescaleUseJSON
+		tgen.UseTags = c.TimescaleUseTags
+		return tgen, nil
+	}
+	// otherwise it may be provided by a plugin
+	gen, err := utils.NewRegisteredDevopsGenerator(target, start, end, scale)
+	if err != nil {
+		return nil, suggest.Error("format", target, Targets())
+	}
+	return gen, nil
+}
+
+// Iterator generates the queries for a target, use case and Config one at a
+// time:
+//
+//	for it.Next() {
+//		q := it.Query()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	config    Config
+	generator utils.DevopsGenerator
+	filler    utils.QueryFiller
+
+	generated uint64
+	group     uint
+	current   query.Query
+	err       error
+}
+
+// New returns an Iterator over the queries of c.QueryType for a target and
+// use case, or an error if c is invalid.
+//
+// The queries are generated from an rng.RNG seeded with c.Seed, so they do not
+// depend on any global state. Only plugin generators that do not implement
+// utils.RNGSetter still draw from the global math/rand source, which New
+// reseeds for them.
+func New(target, useCase string, c Config) (*Iterator, error) {
+	if err := c.Validate(useCase); err != nil {
+		return nil, err
+	}
+	if err := CheckSupported(target, c.QueryType); err != nil {
+		return nil, err
+	}
+	if c.InterleavedNumGroups == 0 {
+		c.InterleavedNumGroups = 1
+	}
+	start, end := c.TimestampStart.UTC(), c.TimestampEnd.UTC()
+	it, err := newIterator(target, useCase, start, end, c)
+	if err != nil {
+		return nil, err
+	}
+	if setter, ok := it.generator.(utils.RNGSetter); ok {
+		setter.SetRNG(rng.New(c.Seed))
+	} else {
+		rand.Seed(c.Seed)
+	}
+	return it, nil
+}
+
+func newIterator(target, useCase string, start, end time.Time, c Config) (it *Iterator, err error) {
+	defer recoverError(&err)
+	generator, err := NewDevopsGenerator(target, start, end, c.Scale, c)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{
+		config:    c,
+		generator: generator,
+		filler:    useCaseMatrix[useCase][c.QueryType](generator),
+	}, nil
+}
+
+// recoverError recovers the *devops.Error a query generator panicked with
+// into *err, and must be deferred. Other panics are not recovered.
+func recoverError(err *error) {
+	if r := recover(); r != nil {
+		genErr, ok := r.(*devops.Error)
+		if !ok {
+			panic(r)
+		}
+		*err = genErr
+	}
+}
+
+// Next generates the next query belonging to the Iterator's interleaved
+// group, returning false once Config.Count queries have been generated or
+// generating one fails, in which case Err returns why
+func (it *Iterator) Next() bool {
+	it.current = nil
+	for it.err == nil && (it.config.Count == 0 || it.generated < uint64(it.config.Count)) {
+		q, err := it.fill()
+		if err != nil {
+			it.err = err
+			return false
+		}
+		inGroup := it.group == it.config.InterleavedGroupID
+		it.generated++
+		it.group = (it.group + 1) % it.config.InterleavedNumGroups
+		if inGroup {
+			it.current = q
+			return true
+		}
+		q.Release()
+	}
+	return false
+}
+
+// fill generates a query, returning an error instead of panicking if the
+// query generator cannot
+func (it *Iterator) fill() (q query.Query, err error) {
+	defer recoverError(&err)
+	return it.filler.Fill(it.generator.GenerateEmptyQuery()), nil
+}
+
+// Err returns the error that stopped the Iterator generating queries, or nil
+// if it generated all of them
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// Query returns the query generated by the last call to Next. Callers may
+// Release it once they are done with it so its memory is reused.
+func (it *Iterator) Query() query.Query {
+	return it.current
+}
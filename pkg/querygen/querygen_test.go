@@ -0,0 +1,230 @@
+package querygen
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testConfig() Config {
+	start := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Config{
+		QueryType:      "single-groupby-1-1-1",
+		Scale:          10,
+		Seed:           123,
+		TimestampStart: start,
+		TimestampEnd:   start.Add(24 * time.Hour),
+		Count:          10,
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := []struct {
+		desc      string
+		useCase   string
+		modify    func(*Config)
+		errPrefix string
+	}{
+		{
+			desc:    "valid",
+			useCase: UseCaseDevops,
+			modify:  func(c *Config) {},
+		},
+		{
+			desc:      "invalid use case",
+			useCase:   "bogus",
+			modify:    func(c *Config) {},
+			errPrefix: "unknown use case",
+		},
+		{
+			desc:      "invalid query type",
+			useCase:   UseCaseCPUOnly,
+			modify:    func(c *Config) { c.QueryType = "bogus" },
+			errPrefix: "unknown query type",
+		},
+		{
+			desc:      "zero scale",
+			useCase:   UseCaseDevops,
+			modify:    func(c *Config) { c.Scale = 0 },
+			errPrefix: "scale must be",
+		},
+		{
+			desc:      "negative count",
+			useCase:   UseCaseDevops,
+			modify:    func(c *Config) { c.Count = -1 },
+			errPrefix: "count cannot be negative",
+		},
+		{
+			desc:      "end before start",
+			useCase:   UseCaseDevops,
+			modify:    func(c *Config) { c.TimestampEnd = c.TimestampStart },
+			errPrefix: "end timestamp",
+		},
+		{
+			desc:    "bad interleaved groups",
+			useCase: UseCaseDevops,
+			modify: func(c *Config) {
+				c.InterleavedGroupID = 2
+				c.InterleavedNumGroups = 2
+			},
+			errPrefix: "incorrect interleaved groups",
+		},
+	}
+	for _, c := range cases {
+		cfg := testConfig()
+		c.modify(&cfg)
+		err := cfg.Validate(c.useCase)
+		if c.errPrefix == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+		} else if c.errPrefix != "" && (err == nil || !strings.HasPrefix(err.Error(), c.errPrefix)) {
+			t.Errorf("%s: incorrect error: got %v want prefix %s", c.desc, err, c.errPrefix)
+		}
+	}
+}
+
+func TestNewUnknownTarget(t *testing.T) {
+	if _, err := New("bogus", UseCaseDevops, testConfig()); err == nil {
+		t.Errorf("did not error for unknown target")
+	}
+}
+
+func TestQueryTypes(t *testing.T) {
+	if got := QueryTypes("bogus"); got != nil {
+		t.Errorf("non-nil query types for unknown use case: %v", got)
+	}
+	for _, uc := range UseCases() {
+		qts := QueryTypes(uc)
+		if len(qts) == 0 {
+			t.Errorf("%s: no query types", uc)
+		}
+		for i := 1; i < len(qts); i++ {
+			if qts[i-1] >= qts[i] {
+				t.Errorf("%s: query types not sorted: %v", uc, qts)
+			}
+		}
+		for _, qt := range qts {
+			if QueryTypeDescription(qt) == "" {
+				t.Errorf("%s: query type '%s' has no description", uc, qt)
+			}
+		}
+	}
+}
+
+// generateAll returns the String of every query generated for cfg
+func generateAll(t *testing.T, target string, cfg Config) []string {
+	it, err := New(target, UseCaseDevops, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	all := []string{}
+	for it.Next() {
+		q := it.Query()
+		all = append(all, q.String())
+		q.Release()
+	}
+	if it.Query() != nil {
+		t.Errorf("non-nil query after iteration finished")
+	}
+	if err := it.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	return all
+}
+
+func TestIterator(t *testing.T) {
+	for _, target := range []string{TargetADX, TargetCassandra, TargetDuckDB, TargetGreptime, TargetInflux, TargetInflux3, TargetM3DB, TargetMySQL, TargetPinot, TargetPostgres, TargetSQLite, TargetTimescaleDB} {
+		cfg := testConfig()
+		first := generateAll(t, target, cfg)
+		if len(first) != cfg.Count {
+			t.Errorf("%s: incorrect number of queries: got %d want %d", target, len(first), cfg.Count)
+		}
+		second := generateAll(t, target, cfg)
+		for i := range first {
+			if first[i] != second[i] {
+				t.Errorf("%s: query %d differs between runs with the same config", target, i)
+			}
+		}
+
+		// the groups should partition the queries of a single generator
+		cfg.InterleavedNumGroups = 3
+		got := 0
+		for i := uint(0); i < cfg.InterleavedNumGroups; i++ {
+			cfg.InterleavedGroupID = i
+			group := generateAll(t, target, cfg)
+			for j, q := range group {
+				if want := first[int(i)+j*int(cfg.InterleavedNumGroups)]; q != want {
+					t.Errorf("%s: group %d query %d does not match the ungrouped query", target, i, j)
+				}
+			}
+			got += len(group)
+		}
+		if got != cfg.Count {
+			t.Errorf("%s: groups do not add up to all queries: got %d want %d", target, got, cfg.Count)
+		}
+	}
+}
+
+func TestIteratorIndependent(t *testing.T) {
+	cfg := testConfig()
+	want := generateAll(t, TargetInflux, cfg)
+
+	// iterators interleaved with each other and with users of the global
+	// source should not affect each other's queries
+	a, err := New(TargetInflux, UseCaseDevops, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := New(TargetInflux, UseCaseDevops, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range want {
+		rand.Seed(int64(i))
+		if !a.Next() || !b.Next() {
+			t.Fatalf("iterators stopped after %d queries", i)
+		}
+		if got := a.Query().String(); got != want[i] {
+			t.Errorf("query %d of first iterator differs", i)
+		}
+		if got := b.Query().String(); got != want[i] {
+			t.Errorf("query %d of second iterator differs", i)
+		}
+	}
+}
+
+func TestIteratorUnlimited(t *testing.T) {
+	cfg := testConfig()
+	cfg.Count = 0
+	it, err := New(TargetInflux, UseCaseDevops, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		if !it.Next() {
+			t.Fatalf("unlimited iterator stopped after %d queries", i)
+		}
+		it.Query().Release()
+	}
+}
+
+func TestIteratorErr(t *testing.T) {
+	// more hosts than the scale cannot be queried, which is only found when
+	// generating a query
+	cfg := testConfig()
+	cfg.QueryType = "cpu-max-all-8"
+	cfg.Scale = 1
+	it, err := New(TargetInflux, UseCaseDevops, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if it.Next() {
+		t.Errorf("query generated with more hosts than the scale")
+	}
+	if err := it.Err(); err == nil || !strings.Contains(err.Error(), "larger than --scale-var") {
+		t.Errorf("incorrect error: got %v", err)
+	}
+	if it.Next() {
+		t.Errorf("query generated after an error")
+	}
+}
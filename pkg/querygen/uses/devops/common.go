@@ -2,12 +2,11 @@ package devops
 
 import (
 	"fmt"
-	"log"
-	"math/rand"
 	"reflect"
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
+	"github.com/timescale/tsbs/pkg/querygen/utils"
+	"github.com/timescale/tsbs/pkg/rng"
 	"github.com/timescale/tsbs/query"
 )
 
@@ -40,8 +39,22 @@ const (
 	LabelHighCPU = "high-cpu"
 )
 
-// for ease of testing
-var fatal = log.Fatalf
+// Error is what query generation panics with when a query cannot be
+// generated, e.g., because it needs more hosts than the scale. It is
+// recovered by querygen.Iterator and returned from its Err method, so that
+// generating queries in-process never exits the host process.
+type Error struct {
+	msg string
+}
+
+func (e *Error) Error() string {
+	return e.msg
+}
+
+// fatal panics with an *Error; it is a variable for ease of testing
+var fatal = func(format string, args ...interface{}) {
+	panic(&Error{fmt.Sprintf(format, args...)})
+}
 
 // Core is the common component of all generators for all systems
 type Core struct {
@@ -49,21 +62,37 @@ type Core struct {
 	Interval utils.TimeInterval
 	// Scale is the cardinality of the dataset in terms of devices/hosts
 	Scale int
+
+	// random is the source of the random values of the queries
+	random rng.RNG
 }
 
-// NewCore returns a new Core for the given time range and cardinality
+// NewCore returns a new Core for the given time range and cardinality, whose
+// random values are drawn from rng.New(0) until SetRNG is called
 func NewCore(start, end time.Time, scale int) *Core {
 	if !start.Before(end) {
 		fatal(errBadTimeOrder)
 		return nil
 	}
 
-	return &Core{utils.NewTimeInterval(start, end), scale}
+	return &Core{utils.NewTimeInterval(start, end), scale, rng.New(0)}
+}
+
+// SetRNG sets the source of the random values of the queries, implementing
+// utils.RNGSetter
+func (d *Core) SetRNG(r rng.RNG) {
+	d.random = r
 }
 
 // GetRandomHosts returns a random set of nHosts from a given Core
 func (d *Core) GetRandomHosts(nHosts int) []string {
-	return getRandomHosts(d.Scale, nHosts)
+	return getRandomHosts(d.random, d.Scale, nHosts)
+}
+
+// RandWindow returns a TimeInterval of duration window at a uniformly random
+// start time within the Core's time range
+func (d *Core) RandWindow(window time.Duration) utils.TimeInterval {
+	return d.Interval.RandWindow(d.random, window)
 }
 
 // cpuMetrics is the list of metric names for CPU
@@ -157,7 +186,7 @@ func GetMaxAllLabel(dbName string, nHosts int) string {
 	return fmt.Sprintf("%s max of all CPU metrics, random %4d hosts, random %s by 1h", dbName, nHosts, MaxAllDuration)
 }
 
-func getRandomHosts(scale, nHosts int) []string {
+func getRandomHosts(r rng.RNG, scale, nHosts int) []string {
 	if nHosts < 1 {
 		fatal("number of hosts cannot be < 1; got %d", nHosts)
 		return nil
@@ -167,7 +196,7 @@ func getRandomHosts(scale, nHosts int) []string {
 		return nil
 	}
 
-	nn := getRandomSubsetPerm(scale, nHosts)
+	nn := getRandomSubsetPerm(r, scale, nHosts)
 
 	hostnames := []string{}
 	for _, n := range nn {
@@ -181,7 +210,7 @@ func getRandomHosts(scale, nHosts int) []string {
 // from 0 to scale. This is an alternative to rand.Perm and then taking a
 // sub-slice, which used up a lot more memory and slowed down query generation
 // significantly. The subset of the permutation should have no duplicates.
-func getRandomSubsetPerm(scale, nItems int) []int {
+func getRandomSubsetPerm(r rng.RNG, scale, nItems int) []int {
 	if nItems > scale {
 		fatal(errMoreItemsThanScale)
 		return nil
@@ -191,7 +220,7 @@ func getRandomSubsetPerm(scale, nItems int) []int {
 	res := []int{}
 	for i := 0; i < nItems; i++ {
 		for {
-			n := rand.Intn(scale)
+			n := r.Intn(scale)
 			// Keep iterating until a previously unseen int is found
 			if !seen[n] {
 				seen[n] = true
@@ -204,5 +233,5 @@ func getRandomSubsetPerm(scale, nItems int) []int {
 }
 
 func panicUnimplementedQuery(dg utils.DevopsGenerator) {
-	panic(fmt.Sprintf("database (%v) does not implement query", reflect.TypeOf(dg)))
+	fatal("database (%v) does not implement query", reflect.TypeOf(dg))
 }
@@ -2,11 +2,12 @@ package devops
 
 import (
 	"fmt"
-	"math/rand"
 	"sort"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 func TestNewCore(t *testing.T) {
@@ -127,13 +128,13 @@ func TestGetRandomHosts(t *testing.T) {
 			desc:   "1 host out of 100",
 			scale:  100,
 			nHosts: 1,
-			want:   "host_83",
+			want:   "host_44",
 		},
 		{
 			desc:   "5 host out of 100",
 			scale:  100,
 			nHosts: 5,
-			want:   "host_83,host_68,host_80,host_60,host_62",
+			want:   "host_44,host_70,host_43,host_59,host_16",
 		},
 		{
 			desc:        "5 host out of 1",
@@ -145,13 +146,13 @@ func TestGetRandomHosts(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		rand.Seed(100) // always reset the random number generator
+		r := rng.New(100) // always reset the random number generator
 		if c.shouldFatal {
 			errMsg := ""
 			fatal = func(format string, args ...interface{}) {
 				errMsg = fmt.Sprintf(format, args...)
 			}
-			hosts := getRandomHosts(c.scale, c.nHosts)
+			hosts := getRandomHosts(r, c.scale, c.nHosts)
 			if hosts != nil {
 				t.Errorf("%s: fatal'd but with non-nil return: %v", c.desc, hosts)
 			}
@@ -159,7 +160,7 @@ func TestGetRandomHosts(t *testing.T) {
 				t.Errorf("%s: incorrect fatal msg:\ngot\n%s\nwant\n%s", c.desc, errMsg, c.wantFatal)
 			}
 		} else {
-			hosts := getRandomHosts(c.scale, c.nHosts)
+			hosts := getRandomHosts(r, c.scale, c.nHosts)
 			if got := strings.Join(hosts, ","); got != c.want {
 				t.Errorf("%s: incorrect output: got %s want %s", c.desc, got, c.want)
 			}
@@ -237,7 +238,7 @@ func TestGetRandomSubsetPerm(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		ret := getRandomSubsetPerm(c.scale, c.nItems)
+		ret := getRandomSubsetPerm(rng.New(123), c.scale, c.nItems)
 		if len(ret) != c.nItems {
 			t.Errorf("return list not long enough: got %d want %d (scale %d)", len(ret), c.nItems, c.scale)
 		}
@@ -257,7 +258,7 @@ func TestGetRandomSubsetPermError(t *testing.T) {
 	fatal = func(format string, args ...interface{}) {
 		errMsg = fmt.Sprintf(format, args...)
 	}
-	ret := getRandomSubsetPerm(10, 11)
+	ret := getRandomSubsetPerm(rng.New(123), 10, 11)
 	if ret != nil {
 		t.Errorf("return was non-nil: %v", ret)
 	}
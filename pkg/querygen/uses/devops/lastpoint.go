@@ -1,7 +1,7 @@
 package devops
 
 import (
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
+	"github.com/timescale/tsbs/pkg/querygen/utils"
 	"github.com/timescale/tsbs/query"
 )
 
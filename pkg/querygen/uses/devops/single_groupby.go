@@ -3,7 +3,7 @@ package devops
 import (
 	"time"
 
-	"github.com/timescale/tsbs/cmd/tsbs_generate_queries/utils"
+	"github.com/timescale/tsbs/pkg/querygen/utils"
 	"github.com/timescale/tsbs/query"
 )
 
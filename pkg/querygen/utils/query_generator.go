@@ -1,12 +1,22 @@
 package utils
 
-import "github.com/timescale/tsbs/query"
+import (
+	"github.com/timescale/tsbs/pkg/rng"
+	"github.com/timescale/tsbs/query"
+)
 
 // DevopsGenerator is query generator for a database type that handles the Devops use case
 type DevopsGenerator interface {
 	GenerateEmptyQuery() query.Query
 }
 
+// RNGSetter is implemented by DevopsGenerators that draw their random values
+// from an injected rng.RNG rather than the global math/rand source, e.g.,
+// those embedding devops.Core
+type RNGSetter interface {
+	SetRNG(rng.RNG)
+}
+
 // QueryFiller describes a type that can fill in a query and return it
 type QueryFiller interface {
 	// Fill fills in the query.Query with query details
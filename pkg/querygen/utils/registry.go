@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DevopsGeneratorMaker creates a DevopsGenerator for queries over the given
+// time range and scale
+type DevopsGeneratorMaker func(start, end time.Time, scale int) DevopsGenerator
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]DevopsGeneratorMaker)
+)
+
+// RegisterDevopsGenerator makes a DevopsGenerator available for the given
+// format, for targets that are not built in (e.g., from a plugin). It panics
+// if maker is nil or the format is already registered.
+func RegisterDevopsGenerator(format string, maker DevopsGeneratorMaker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if maker == nil {
+		panic("utils: RegisterDevopsGenerator maker is nil for " + format)
+	}
+	if _, dup := registry[format]; dup {
+		panic("utils: RegisterDevopsGenerator called twice for " + format)
+	}
+	registry[format] = maker
+}
+
+// NewRegisteredDevopsGenerator returns a DevopsGenerator for a format added
+// with RegisterDevopsGenerator
+func NewRegisteredDevopsGenerator(format string, start, end time.Time, scale int) (DevopsGenerator, error) {
+	registryMu.RLock()
+	maker, ok := registry[format]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no devops generator registered for format '%s'", format)
+	}
+	return maker(start, end, scale), nil
+}
+
+// RegisteredFormats returns the formats added with RegisterDevopsGenerator in
+// sorted order
+func RegisteredFormats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	formats := make([]string, 0, len(registry))
+	for f := range registry {
+		formats = append(formats, f)
+	}
+	sort.Strings(formats)
+	return formats
+}
@@ -1,8 +1,9 @@
 package utils
 
 import (
-	"math/rand"
 	"time"
+
+	"github.com/timescale/tsbs/pkg/rng"
 )
 
 // TimeInterval represents an interval of time.
@@ -24,8 +25,8 @@ func (ti *TimeInterval) Duration() time.Duration {
 }
 
 // RandWindow creates a TimeInterval of duration `window` at a uniformly-random
-// start time within this time interval.
-func (ti *TimeInterval) RandWindow(window time.Duration) TimeInterval {
+// start time within this time interval, drawn from r.
+func (ti *TimeInterval) RandWindow(r rng.RNG, window time.Duration) TimeInterval {
 	lower := ti.Start.UnixNano()
 	upper := ti.End.Add(-window).UnixNano()
 
@@ -33,7 +34,7 @@ func (ti *TimeInterval) RandWindow(window time.Duration) TimeInterval {
 		panic("logic error: bad time bounds")
 	}
 
-	start := lower + rand.Int63n(upper-lower)
+	start := lower + r.Int63n(upper-lower)
 	end := start + window.Nanoseconds()
 
 	x := NewTimeInterval(time.Unix(0, start).UTC(), time.Unix(0, end).UTC())
@@ -0,0 +1,207 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/results"
+)
+
+// The dashboard embeds its data as CSV for Grafana's built-in TestData
+// datasource, so it can be imported and shared without access to the
+// results. Which TestData datasource to use is asked for on import.
+const (
+	grafanaDatasourceType = "grafana-testdata-datasource"
+	grafanaDatasourceVar  = "datasource"
+	grafanaSchemaVersion  = 39
+	grafanaPanelWidth     = 12
+	grafanaPanelHeight    = 9
+)
+
+type grafanaDashboard struct {
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	Timezone      string            `json:"timezone"`
+	Editable      bool              `json:"editable"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Time          grafanaTimeRange  `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+	Query string `json:"query"`
+}
+
+type grafanaPanel struct {
+	ID          int                    `json:"id"`
+	Type        string                 `json:"type"`
+	Title       string                 `json:"title"`
+	GridPos     grafanaGridPos         `json:"gridPos"`
+	Datasource  grafanaDatasource      `json:"datasource"`
+	Targets     []grafanaTarget        `json:"targets"`
+	FieldConfig grafanaFieldConfig     `json:"fieldConfig"`
+	Options     map[string]interface{} `json:"options"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaTarget struct {
+	RefID      string            `json:"refId"`
+	Datasource grafanaDatasource `json:"datasource"`
+	ScenarioID string            `json:"scenarioId"`
+	CSVContent string            `json:"csvContent"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults  grafanaFieldDefaults `json:"defaults"`
+	Overrides []interface{}        `json:"overrides"`
+}
+
+type grafanaFieldDefaults struct {
+	Unit string `json:"unit,omitempty"`
+}
+
+// grafanaUnits are the Grafana units of those of charts
+var grafanaUnits = map[string]string{
+	"ms":                 "ms",
+	"per second":         "short",
+	"queries per second": "reqps",
+}
+
+// writeGrafana writes recs as a Grafana dashboard: a table of the runs,
+// then a bar chart panel of each chart, two to a row
+func writeGrafana(w io.Writer, title string, recs []*results.Record) error {
+	datasource := grafanaDatasource{Type: grafanaDatasourceType, UID: "${" + grafanaDatasourceVar + "}"}
+	d := grafanaDashboard{
+		Title:         title,
+		Tags:          []string{"tsbs"},
+		Timezone:      "utc",
+		Editable:      true,
+		SchemaVersion: grafanaSchemaVersion,
+		Time:          timeRange(recs),
+		Templating: grafanaTemplating{List: []grafanaVariable{{
+			Name:  grafanaDatasourceVar,
+			Label: "TestData datasource",
+			Type:  "datasource",
+			Query: grafanaDatasourceType,
+		}}},
+	}
+	panel := func(typ, title, unit string, rows [][]string, options map[string]interface{}) error {
+		content, err := csvContent(rows)
+		if err != nil {
+			return err
+		}
+		i := len(d.Panels)
+		// the table of runs, the first panel, takes a row of its own, and
+		// the charts are two to a row below it
+		pos := grafanaGridPos{H: grafanaPanelHeight, W: 2 * grafanaPanelWidth}
+		if i > 0 {
+			j := i - 1
+			pos = grafanaGridPos{H: grafanaPanelHeight, W: grafanaPanelWidth, X: (j % 2) * grafanaPanelWidth, Y: (j/2 + 1) * grafanaPanelHeight}
+		}
+		d.Panels = append(d.Panels, grafanaPanel{
+			ID:          i + 1,
+			Type:        typ,
+			Title:       title,
+			GridPos:     pos,
+			Datasource:  datasource,
+			Targets:     []grafanaTarget{{RefID: "A", Datasource: datasource, ScenarioID: "csv_content", CSVContent: content}},
+			FieldConfig: grafanaFieldConfig{Defaults: grafanaFieldDefaults{Unit: grafanaUnits[unit]}, Overrides: []interface{}{}},
+			Options:     options,
+		})
+		return nil
+	}
+
+	runs := [][]string{runColumns}
+	for _, rec := range recs {
+		runs = append(runs, runRow(rec))
+	}
+	if err := panel("table", "Runs", "", runs, map[string]interface{}{"showHeader": true}); err != nil {
+		return err
+	}
+	for _, c := range charts(recs) {
+		rows := [][]string{{"run"}}
+		for _, s := range c.Series {
+			rows[0] = append(rows[0], s.Name)
+		}
+		for i, run := range c.Runs {
+			row := []string{run}
+			for _, s := range c.Series {
+				row = append(row, formatValue(s.Values[i]))
+			}
+			rows = append(rows, row)
+		}
+		options := map[string]interface{}{
+			"xField":      "run",
+			"orientation": "horizontal",
+			"showValue":   "auto",
+			"legend":      map[string]interface{}{"showLegend": true, "displayMode": "list", "placement": "bottom"},
+		}
+		if err := panel("barchart", c.Title, c.Unit, rows, options); err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// timeRange returns the time range covering all recs
+func timeRange(recs []*results.Record) grafanaTimeRange {
+	from, to := recs[0].Start, recs[0].End
+	for _, rec := range recs {
+		if rec.Start.Before(from) {
+			from = rec.Start
+		}
+		if rec.End.After(to) {
+			to = rec.End
+		}
+	}
+	return grafanaTimeRange{From: from.UTC().Format(time.RFC3339), To: to.UTC().Format(time.RFC3339)}
+}
+
+func csvContent(rows [][]string) (string, error) {
+	var b bytes.Buffer
+	cw := csv.NewWriter(&b)
+	if err := cw.WriteAll(rows); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// formatValue formats a value of a series for CSV, empty if missing
+func formatValue(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
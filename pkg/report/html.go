@@ -0,0 +1,154 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/results"
+)
+
+// Layout of the bar charts of HTML reports, in pixels: each run is a group
+// of horizontal bars, one per series, labeled on the left
+const (
+	svgLabelWidth = 320
+	svgBarsWidth  = 480
+	svgValueWidth = 90
+	svgBarHeight  = 14
+	svgGroupGap   = 10
+	svgLegendRow  = 22
+)
+
+// svgColors are the colors of the series, in turn
+var svgColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"}
+
+// svgChart is a chart laid out for drawing
+type svgChart struct {
+	Title  string
+	Unit   string
+	Width  int
+	Height int
+	Legend []svgLegend
+	Groups []svgGroup
+}
+
+type svgLegend struct {
+	X     int
+	Y     int
+	Name  string
+	Color string
+}
+
+type svgGroup struct {
+	Label string
+	Y     int
+	Bars  []svgBar
+}
+
+type svgBar struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+	Color  string
+	Value  string
+}
+
+// layout lays out c as horizontal bars scaled to its largest value
+func layout(c chart) svgChart {
+	max := 0.0
+	for _, s := range c.Series {
+		for _, v := range s.Values {
+			if !math.IsNaN(v) && v > max {
+				max = v
+			}
+		}
+	}
+	ret := svgChart{Title: c.Title, Unit: c.Unit, Width: svgLabelWidth + svgBarsWidth + svgValueWidth}
+	// the legend wraps over as many rows as needed
+	x, y := svgLabelWidth, 0
+	for i, s := range c.Series {
+		width := 20 + 8*len(s.Name)
+		if x > svgLabelWidth && x+width > ret.Width {
+			x, y = svgLabelWidth, y+svgLegendRow
+		}
+		ret.Legend = append(ret.Legend, svgLegend{X: x, Y: y, Name: s.Name, Color: svgColors[i%len(svgColors)]})
+		x += width
+	}
+	y += svgLegendRow + svgGroupGap
+	for i, run := range c.Runs {
+		g := svgGroup{Label: run, Y: y}
+		for j, s := range c.Series {
+			v := s.Values[i]
+			bar := svgBar{X: svgLabelWidth, Y: y + j*svgBarHeight, Height: svgBarHeight - 1, Color: svgColors[j%len(svgColors)], Value: "-"}
+			if !math.IsNaN(v) {
+				if max > 0 {
+					bar.Width = int(v / max * svgBarsWidth)
+				}
+				bar.Value = strconv.FormatFloat(v, 'f', 2, 64)
+			}
+			g.Bars = append(g.Bars, bar)
+		}
+		ret.Groups = append(ret.Groups, g)
+		y += len(c.Series)*svgBarHeight + svgGroupGap
+	}
+	ret.Height = y
+	return ret
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; font-size: 13px; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+svg { display: block; margin-bottom: 2em; font-size: 12px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Generated}} from {{len .Runs}} runs.</p>
+<h2>Runs</h2>
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Runs}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{range .Charts}}<h2>{{.Title}} ({{.Unit}})</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}">
+{{range .Legend}}<rect x="{{.X}}" y="{{add .Y 4}}" width="12" height="12" fill="{{.Color}}"/><text x="{{add .X 16}}" y="{{add .Y 14}}">{{.Name}}</text>
+{{end}}{{range .Groups}}<text x="0" y="{{add .Y 11}}">{{.Label}}</text>
+{{range .Bars}}<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" fill="{{.Color}}"/><text x="{{add .X .Width | add 4}}" y="{{add .Y 11}}">{{.Value}}</text>
+{{end}}{{end}}</svg>
+{{end}}</body>
+</html>
+`))
+
+// writeHTML writes recs as a self-contained HTML page: a table of the runs
+// and an SVG bar chart of each chart
+func writeHTML(w io.Writer, title string, recs []*results.Record) error {
+	data := struct {
+		Title     string
+		Generated string
+		Columns   []string
+		Runs      [][]string
+		Charts    []svgChart
+	}{
+		Title:     title,
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Columns:   runColumns,
+	}
+	for _, rec := range recs {
+		data.Runs = append(data.Runs, runRow(rec))
+	}
+	for _, c := range charts(recs) {
+		data.Charts = append(data.Charts, layout(c))
+	}
+	return htmlTemplate.Execute(w, data)
+}
@@ -0,0 +1,180 @@
+// Package report turns the results of runs (see package results) into a
+// Grafana dashboard or a self-contained HTML report, with charts of the
+// throughput of loads and queries and of the latency of each query type, so
+// runs can be shared without plotting them by hand.
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/timescale/tsbs/pkg/results"
+)
+
+// Formats of reports
+const (
+	FormatHTML    = "html"
+	FormatGrafana = "grafana"
+)
+
+// Formats are the formats reports can be written in
+var Formats = []string{FormatGrafana, FormatHTML}
+
+// Write writes the report of recs, titled title, in format
+func Write(w io.Writer, format, title string, recs []*results.Record) error {
+	if len(recs) == 0 {
+		return fmt.Errorf("no results to report")
+	}
+	switch format {
+	case FormatHTML:
+		return writeHTML(w, title, recs)
+	case FormatGrafana:
+		return writeGrafana(w, title, recs)
+	}
+	return fmt.Errorf("unknown report format: '%s' (valid choices: %s)", format, strings.Join(Formats, ", "))
+}
+
+// chart is a chart of series of values, with one value of each series per
+// run
+type chart struct {
+	Title string
+	// Unit is that of the values, as shown on the chart
+	Unit string
+	// Runs are the labels of the runs, see runLabel
+	Runs   []string
+	Series []series
+}
+
+// series is a named series of values, NaN where a run has none
+type series struct {
+	Name   string
+	Values []float64
+}
+
+// runLabel returns the label of rec on charts, which tells apart runs of
+// different targets, versions and hosts
+func runLabel(rec *results.Record) string {
+	label := fmt.Sprintf("%s %s %s %s", rec.Start.Format("2006-01-02 15:04"), rec.Target, rec.Build.Version, rec.Host.Hostname)
+	if rec.Incomplete {
+		label += " (incomplete)"
+	}
+	return label
+}
+
+// charts returns the charts of recs: the throughput of the loads, the
+// throughput of the queries of each label, and the latency of the queries
+// of each label
+func charts(recs []*results.Record) []chart {
+	var ret []chart
+	var loads, queries []*results.Record
+	for _, rec := range recs {
+		if rec.Load != nil {
+			loads = append(loads, rec)
+		}
+		if len(rec.Queries) > 0 {
+			queries = append(queries, rec)
+		}
+	}
+
+	if len(loads) > 0 {
+		c := chart{Title: "Load throughput", Unit: "per second"}
+		metrics := series{Name: "metrics/sec"}
+		rows := series{Name: "rows/sec"}
+		hasRows := false
+		for _, rec := range loads {
+			c.Runs = append(c.Runs, runLabel(rec))
+			metrics.Values = append(metrics.Values, rate(float64(rec.Load.Metrics), rec.Seconds))
+			rows.Values = append(rows.Values, rate(float64(rec.Load.Rows), rec.Seconds))
+			hasRows = hasRows || rec.Load.Rows > 0
+		}
+		c.Series = append(c.Series, metrics)
+		if hasRows {
+			c.Series = append(c.Series, rows)
+		}
+		ret = append(ret, c)
+	}
+
+	if len(queries) > 0 {
+		labels := queryLabels(queries)
+		throughput := chart{Title: "Query throughput", Unit: "queries per second"}
+		latencies := make([]chart, len(labels))
+		for i, label := range labels {
+			throughput.Series = append(throughput.Series, series{Name: label})
+			latencies[i] = chart{
+				Title:  "Query latency: " + label,
+				Unit:   "ms",
+				Series: []series{{Name: "median"}, {Name: "mean"}, {Name: "max"}},
+			}
+		}
+		for _, rec := range queries {
+			run := runLabel(rec)
+			throughput.Runs = append(throughput.Runs, run)
+			byLabel := make(map[string]results.Query, len(rec.Queries))
+			for _, q := range rec.Queries {
+				byLabel[q.Label] = q
+			}
+			for i, label := range labels {
+				latencies[i].Runs = append(latencies[i].Runs, run)
+				q, ok := byLabel[label]
+				if !ok {
+					throughput.Series[i].Values = append(throughput.Series[i].Values, math.NaN())
+					for j := range latencies[i].Series {
+						latencies[i].Series[j].Values = append(latencies[i].Series[j].Values, math.NaN())
+					}
+					continue
+				}
+				throughput.Series[i].Values = append(throughput.Series[i].Values, rate(float64(q.Count), rec.Seconds))
+				for j, v := range []float64{q.MedianMs, q.MeanMs, q.MaxMs} {
+					latencies[i].Series[j].Values = append(latencies[i].Series[j].Values, v)
+				}
+			}
+		}
+		ret = append(ret, throughput)
+		ret = append(ret, latencies...)
+	}
+	return ret
+}
+
+// queryLabels returns the labels of the queries of recs, sorted
+func queryLabels(recs []*results.Record) []string {
+	seen := map[string]bool{}
+	var labels []string
+	for _, rec := range recs {
+		for _, q := range rec.Queries {
+			if !seen[q.Label] {
+				seen[q.Label] = true
+				labels = append(labels, q.Label)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+func rate(n, seconds float64) float64 {
+	if seconds <= 0 {
+		return math.NaN()
+	}
+	return n / seconds
+}
+
+// runColumns are the columns of the table of runs, and runRow their values
+// for a run
+var runColumns = []string{"run", "tool", "version", "commit", "host", "cpus", "cpu model", "workers", "seconds"}
+
+func runRow(rec *results.Record) []string {
+	return []string{
+		runLabel(rec),
+		rec.Tool,
+		rec.Build.Version,
+		rec.Build.Commit,
+		rec.Host.Hostname,
+		fmt.Sprint(rec.Host.CPUs),
+		rec.Host.CPUModel,
+		fmt.Sprint(rec.Workers),
+		fmt.Sprintf("%.3f", rec.Seconds),
+	}
+}
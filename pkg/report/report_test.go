@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/results"
+	"github.com/timescale/tsbs/pkg/version"
+)
+
+func testRecords() []*results.Record {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	host := results.Host{Hostname: "bench-1", CPUs: 8}
+	return []*results.Record{
+		{
+			Tool: "tsbs_load_timescaledb", Target: "timescaledb", Build: version.Info{Version: "v1"}, Host: host,
+			Start: start, End: start.Add(10 * time.Second),
+			Run: results.Run{Workers: 4, Seconds: 10, Load: &results.Load{Metrics: 1000, Rows: 100}},
+		},
+		{
+			Tool: "tsbs_run_queries_timescaledb", Target: "timescaledb", Build: version.Info{Version: "v1"}, Host: host,
+			Start: start.Add(time.Minute), End: start.Add(2 * time.Minute),
+			Run: results.Run{Workers: 4, Seconds: 4, Queries: []results.Query{
+				{Label: "all queries", Count: 20, MedianMs: 5, MeanMs: 6, MaxMs: 9},
+				{Label: "lastpoint", Count: 20, MedianMs: 5, MeanMs: 6, MaxMs: 9},
+			}},
+		},
+		{
+			Tool: "tsbs_run_queries_timescaledb", Target: "timescaledb", Build: version.Info{Version: "v2"}, Host: host,
+			Start: start.Add(time.Hour), End: start.Add(2 * time.Hour), Incomplete: true,
+			Run: results.Run{Workers: 4, Seconds: 2, Queries: []results.Query{
+				{Label: "all queries", Count: 10, MedianMs: 3, MeanMs: 4, MaxMs: 7},
+			}},
+		},
+	}
+}
+
+func TestCharts(t *testing.T) {
+	got := charts(testRecords())
+	titles := make([]string, len(got))
+	for i, c := range got {
+		titles[i] = c.Title
+	}
+	want := "Load throughput,Query throughput,Query latency: all queries,Query latency: lastpoint"
+	if strings.Join(titles, ",") != want {
+		t.Fatalf("incorrect charts: got %v want %s", titles, want)
+	}
+
+	load := got[0]
+	if len(load.Runs) != 1 || len(load.Series) != 2 || load.Series[0].Values[0] != 100 || load.Series[1].Values[0] != 10 {
+		t.Errorf("incorrect load throughput: %+v", load)
+	}
+	throughput := got[1]
+	if len(throughput.Runs) != 2 || !strings.HasSuffix(throughput.Runs[1], "(incomplete)") {
+		t.Errorf("incorrect runs: %v", throughput.Runs)
+	}
+	if v := throughput.Series[0].Values; v[0] != 5 || v[1] != 5 {
+		t.Errorf("incorrect query throughput: %v", v)
+	}
+	// the second run has no lastpoint queries
+	lastpoint := got[3]
+	if v := lastpoint.Series[1].Values; v[0] != 6 || !math.IsNaN(v[1]) {
+		t.Errorf("incorrect mean latency: %v", v)
+	}
+}
+
+func TestWriteGrafana(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, FormatGrafana, "nightly", testRecords()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var d grafanaDashboard
+	if err := json.Unmarshal(b.Bytes(), &d); err != nil {
+		t.Fatalf("invalid dashboard: %v", err)
+	}
+	if d.Title != "nightly" || d.Time.From != "2024-03-01T12:00:00Z" || d.Time.To != "2024-03-01T14:00:00Z" {
+		t.Errorf("incorrect dashboard: %s %+v", d.Title, d.Time)
+	}
+	if len(d.Panels) != 5 || d.Panels[0].Type != "table" || d.Panels[1].Type != "barchart" {
+		t.Fatalf("incorrect panels: %+v", d.Panels)
+	}
+	if pos := d.Panels[2].GridPos; pos.X != grafanaPanelWidth || pos.Y != grafanaPanelHeight {
+		t.Errorf("incorrect position of second chart: %+v", pos)
+	}
+	latency := d.Panels[4]
+	wantCSV := "run,median,mean,max\n" +
+		"2024-03-01 12:01 timescaledb v1 bench-1,5,6,9\n" +
+		"2024-03-01 13:00 timescaledb v2 bench-1 (incomplete),,,\n"
+	if latency.Targets[0].CSVContent != wantCSV || latency.FieldConfig.Defaults.Unit != "ms" {
+		t.Errorf("incorrect latency panel: got %q want %q", latency.Targets[0].CSVContent, wantCSV)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, FormatHTML, "nightly <3>", testRecords()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := b.String()
+	for _, want := range []string{"<title>nightly &lt;3&gt;</title>", "Query latency: lastpoint (ms)", "<svg", "tsbs_load_timescaledb", ">100.00</text>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if n := strings.Count(out, "<svg"); n != 4 {
+		t.Errorf("incorrect number of charts: got %d want 4", n)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, FormatHTML, "", nil); err == nil {
+		t.Errorf("no results did not error")
+	}
+	if err := Write(&bytes.Buffer{}, "pdf", "", testRecords()); err == nil || !strings.Contains(err.Error(), "unknown report format") {
+		t.Errorf("incorrect error: got %v", err)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	c := chart{
+		Title: "t",
+		Runs:  []string{"a", "b"},
+		Series: []series{
+			{Name: strings.Repeat("x", 60), Values: []float64{2, math.NaN()}},
+			{Name: strings.Repeat("y", 60), Values: []float64{4, 1}},
+		},
+	}
+	l := layout(c)
+	if l.Legend[1].Y != svgLegendRow || l.Legend[1].X != svgLabelWidth {
+		t.Errorf("legend did not wrap: %+v", l.Legend)
+	}
+	if w := l.Groups[0].Bars[1].Width; w != svgBarsWidth {
+		t.Errorf("largest value not full width: got %d", w)
+	}
+	if bar := l.Groups[1].Bars[0]; bar.Width != 0 || bar.Value != "-" {
+		t.Errorf("incorrect missing value: %+v", bar)
+	}
+}
@@ -0,0 +1,248 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// drivers are the database/sql drivers of the schemes of results database
+// URLs that are built in
+var drivers = map[string]string{
+	"postgres":   "postgres",
+	"postgresql": "postgres",
+}
+
+// schemes are the schemes of results database URLs, including those not
+// built in, with the build tag bringing them in
+var schemes = map[string]string{
+	"postgres":   "",
+	"postgresql": "",
+	"sqlite":     "sqlite",
+}
+
+// parseURL returns the driver and data source name of a results database URL
+func parseURL(url string) (driver, dsn string, err error) {
+	i := strings.Index(url, "://")
+	if i < 0 {
+		return "", "", fmt.Errorf("invalid results database URL '%s': expected postgres://... or sqlite://<file>", url)
+	}
+	scheme := url[:i]
+	tag, ok := schemes[scheme]
+	if !ok {
+		return "", "", fmt.Errorf("unknown results database scheme '%s' (valid choices: postgres, sqlite)", scheme)
+	}
+	driver, ok = drivers[scheme]
+	if !ok {
+		return "", "", fmt.Errorf("cannot use %s results database: built without %s support (rebuild with -tags %s)", scheme, scheme, tag)
+	}
+	if driver == "postgres" {
+		return driver, url, nil
+	}
+	return driver, url[i+len("://"):], nil
+}
+
+// schema returns the statements creating the tables of the results database
+// for driver
+func schema(driver string) []string {
+	id, jsonType := "INTEGER PRIMARY KEY AUTOINCREMENT", "TEXT"
+	if driver == "postgres" {
+		id, jsonType = "BIGSERIAL PRIMARY KEY", "JSONB"
+	}
+	return []string{
+		`CREATE TABLE IF NOT EXISTS tsbs_runs (
+	id ` + id + `,
+	tool TEXT NOT NULL,
+	target TEXT NOT NULL,
+	version TEXT NOT NULL,
+	commit_hash TEXT NOT NULL,
+	hostname TEXT NOT NULL,
+	os TEXT NOT NULL,
+	arch TEXT NOT NULL,
+	cpus INTEGER NOT NULL,
+	cpu_model TEXT NOT NULL,
+	memory_bytes BIGINT NOT NULL,
+	workers INTEGER NOT NULL,
+	seconds DOUBLE PRECISION NOT NULL,
+	started_at TIMESTAMP NOT NULL,
+	ended_at TIMESTAMP NOT NULL,
+	incomplete BOOLEAN NOT NULL,
+	flags ` + jsonType + ` NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS tsbs_load_results (
+	run_id BIGINT NOT NULL REFERENCES tsbs_runs (id),
+	metric_count BIGINT NOT NULL,
+	row_count BIGINT NOT NULL,
+	metrics_per_sec DOUBLE PRECISION NOT NULL,
+	rows_per_sec DOUBLE PRECISION NOT NULL
+)`,
+		`CREATE TABLE IF NOT EXISTS tsbs_query_results (
+	run_id BIGINT NOT NULL REFERENCES tsbs_runs (id),
+	label TEXT NOT NULL,
+	query_count BIGINT NOT NULL,
+	min_ms DOUBLE PRECISION NOT NULL,
+	median_ms DOUBLE PRECISION NOT NULL,
+	mean_ms DOUBLE PRECISION NOT NULL,
+	max_ms DOUBLE PRECISION NOT NULL,
+	stddev_ms DOUBLE PRECISION NOT NULL,
+	sum_ms DOUBLE PRECISION NOT NULL
+)`,
+	}
+}
+
+// DB is a results database
+type DB struct {
+	db *sqlx.DB
+}
+
+// Open connects to the results database at url, postgres://... or
+// sqlite://<file>, creating its tables if they do not exist
+func Open(url string) (*DB, error) {
+	driver, dsn, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sqlx.Connect(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range schema(driver) {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("could not create results tables: %v", err)
+		}
+	}
+	return &DB{db: db}, nil
+}
+
+// Close closes the connection to the results database
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Save inserts rec in one transaction, and returns the id of its row in
+// tsbs_runs
+func (d *DB) Save(rec *Record) (int64, error) {
+	flags, err := json.Marshal(rec.Flags)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := d.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	h := rec.Host
+	err = tx.QueryRowx(tx.Rebind(`INSERT INTO tsbs_runs
+	(tool, target, version, commit_hash, hostname, os, arch, cpus, cpu_model, memory_bytes, workers, seconds, started_at, ended_at, incomplete, flags)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		rec.Tool, rec.Target, rec.Build.Version, rec.Build.Commit, h.Hostname, h.OS, h.Arch, h.CPUs, h.CPUModel, int64(h.MemoryBytes),
+		int64(rec.Workers), rec.Seconds, rec.Start.UTC(), rec.End.UTC(), rec.Incomplete, string(flags)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	if rec.Load != nil {
+		var metricRate, rowRate float64
+		if rec.Seconds > 0 {
+			metricRate = float64(rec.Load.Metrics) / rec.Seconds
+			rowRate = float64(rec.Load.Rows) / rec.Seconds
+		}
+		_, err = tx.Exec(tx.Rebind(`INSERT INTO tsbs_load_results
+	(run_id, metric_count, row_count, metrics_per_sec, rows_per_sec) VALUES (?, ?, ?, ?, ?)`),
+			id, int64(rec.Load.Metrics), int64(rec.Load.Rows), metricRate, rowRate)
+		if err != nil {
+			return 0, err
+		}
+	}
+	for _, q := range rec.Queries {
+		_, err = tx.Exec(tx.Rebind(`INSERT INTO tsbs_query_results
+	(run_id, label, query_count, min_ms, median_ms, mean_ms, max_ms, stddev_ms, sum_ms) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+			id, q.Label, q.Count, q.MinMs, q.MedianMs, q.MeanMs, q.MaxMs, q.StdDevMs, q.SumMs)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// runRow is a row of tsbs_runs
+type runRow struct {
+	ID          int64     `db:"id"`
+	Tool        string    `db:"tool"`
+	Target      string    `db:"target"`
+	Version     string    `db:"version"`
+	Commit      string    `db:"commit_hash"`
+	Hostname    string    `db:"hostname"`
+	OS          string    `db:"os"`
+	Arch        string    `db:"arch"`
+	CPUs        int       `db:"cpus"`
+	CPUModel    string    `db:"cpu_model"`
+	MemoryBytes int64     `db:"memory_bytes"`
+	Workers     int64     `db:"workers"`
+	Seconds     float64   `db:"seconds"`
+	Flags       []byte    `db:"flags"`
+	Incomplete  bool      `db:"incomplete"`
+	Start       time.Time `db:"started_at"`
+	End         time.Time `db:"ended_at"`
+}
+
+// Records returns the latest limit runs of target, or of all targets if
+// empty, with their results, oldest first. A limit of 0 returns all of them.
+func (d *DB) Records(target string, limit int) ([]*Record, error) {
+	query := `SELECT id, tool, target, version, commit_hash, hostname, os, arch, cpus, cpu_model, memory_bytes,
+	workers, seconds, flags, incomplete, started_at, ended_at FROM tsbs_runs`
+	var args []interface{}
+	if len(target) > 0 {
+		query += " WHERE target = ?"
+		args = append(args, target)
+	}
+	query += " ORDER BY started_at DESC, id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	var rows []runRow
+	if err := d.db.Select(&rows, d.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	recs := make([]*Record, len(rows))
+	for i, row := range rows {
+		rec := &Record{
+			ID:         row.ID,
+			Tool:       row.Tool,
+			Target:     row.Target,
+			Host:       Host{Hostname: row.Hostname, OS: row.OS, Arch: row.Arch, CPUs: row.CPUs, CPUModel: row.CPUModel, MemoryBytes: uint64(row.MemoryBytes)},
+			Start:      row.Start.UTC(),
+			End:        row.End.UTC(),
+			Incomplete: row.Incomplete,
+			Run:        Run{Workers: uint(row.Workers), Seconds: row.Seconds},
+		}
+		rec.Build.Version, rec.Build.Commit = row.Version, row.Commit
+		if err := json.Unmarshal(row.Flags, &rec.Flags); err != nil {
+			return nil, fmt.Errorf("invalid flags of run %d: %v", row.ID, err)
+		}
+		var load []struct {
+			Metrics int64 `db:"metric_count"`
+			Rows    int64 `db:"row_count"`
+		}
+		if err := d.db.Select(&load, d.db.Rebind("SELECT metric_count, row_count FROM tsbs_load_results WHERE run_id = ?"), row.ID); err != nil {
+			return nil, err
+		}
+		if len(load) > 0 {
+			rec.Load = &Load{Metrics: uint64(load[0].Metrics), Rows: uint64(load[0].Rows)}
+		}
+		err := d.db.Select(&rec.Queries, d.db.Rebind(`SELECT label, query_count, min_ms, median_ms, mean_ms, max_ms, stddev_ms, sum_ms
+	FROM tsbs_query_results WHERE run_id = ? ORDER BY label`), row.ID)
+		if err != nil {
+			return nil, err
+		}
+		// oldest first
+		recs[len(rows)-1-i] = rec
+	}
+	return recs, nil
+}
@@ -0,0 +1,215 @@
+// Package results records the results of loaders and query runners, as JSON
+// files and in a results database, PostgreSQL or SQLite, so performance can
+// be tracked across versions and hardware. The tools create the tables below
+// if they do not exist, and insert a row into tsbs_runs for each run, with
+// its results in tsbs_load_results or tsbs_query_results:
+//
+//	tsbs_runs           tool, target, build, host and hardware, start and
+//	                    end, whether the run was incomplete, and its flags
+//	tsbs_load_results   metrics and rows loaded, and their rates
+//	tsbs_query_results  latency statistics of each query label, in ms
+//
+// SQLite support needs the sqlite build tag, which brings in its cgo driver.
+package results
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/version"
+)
+
+// Run is the results of a run of a loader or query runner
+type Run struct {
+	Workers uint `json:"workers"`
+	// Seconds is the wall clock time of the run
+	Seconds float64 `json:"seconds"`
+	// Load is set for loaders, and Queries for query runners
+	Load    *Load   `json:"load,omitempty"`
+	Queries []Query `json:"queries,omitempty"`
+}
+
+// Load is the results of a loader
+type Load struct {
+	Metrics uint64 `json:"metrics"`
+	Rows    uint64 `json:"rows"`
+}
+
+// Query is the latency statistics of the queries with a label, e.g., all
+// queries or those of a query type
+type Query struct {
+	Label    string  `json:"label" db:"label"`
+	Count    int64   `json:"count" db:"query_count"`
+	MinMs    float64 `json:"min_ms" db:"min_ms"`
+	MedianMs float64 `json:"median_ms" db:"median_ms"`
+	MeanMs   float64 `json:"mean_ms" db:"mean_ms"`
+	MaxMs    float64 `json:"max_ms" db:"max_ms"`
+	StdDevMs float64 `json:"stddev_ms" db:"stddev_ms"`
+	SumMs    float64 `json:"sum_ms" db:"sum_ms"`
+}
+
+// Host is the machine a run is on
+type Host struct {
+	Hostname    string `json:"hostname"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	CPUs        int    `json:"cpus"`
+	CPUModel    string `json:"cpu_model,omitempty"`
+	MemoryBytes uint64 `json:"memory_bytes,omitempty"`
+}
+
+// Record is a run with its results, as written to a results file or a row
+// of tsbs_runs with those of its results
+type Record struct {
+	// ID is the id of the run in a results database, if read from one
+	ID     int64        `json:"id,omitempty"`
+	Tool   string       `json:"tool"`
+	Target string       `json:"target"`
+	Build  version.Info `json:"build"`
+	Host   Host         `json:"host"`
+	Start  time.Time    `json:"start"`
+	End    time.Time    `json:"end"`
+	// Incomplete is set when the run failed or was interrupted
+	Incomplete bool              `json:"incomplete,omitempty"`
+	Flags      map[string]string `json:"flags"`
+	Run
+}
+
+// getHost returns the machine the tool runs on; it is a variable for ease
+// of testing
+var getHost = func() Host {
+	h := Host{OS: runtime.GOOS, Arch: runtime.GOARCH, CPUs: runtime.NumCPU()}
+	h.Hostname, _ = os.Hostname()
+	if info, err := cpu.Info(); err == nil && len(info) > 0 {
+		h.CPUModel = info[0].ModelName
+	}
+	if vm, err := mem.VirtualMemory(); err == nil {
+		h.MemoryBytes = vm.Total
+	}
+	return h
+}
+
+// target returns the target of a tool, e.g., influx for tsbs_load_influx
+func target(tool string) string {
+	for _, prefix := range []string{"tsbs_load_", "tsbs_run_queries_"} {
+		if strings.HasPrefix(tool, prefix) {
+			return strings.TrimPrefix(tool, prefix)
+		}
+	}
+	return tool
+}
+
+// NewRecord returns the Record of r, the results of the run described by md
+// that ended at end, on this machine
+func NewRecord(md *cli.Metadata, r *Run, end time.Time, incomplete bool) *Record {
+	return &Record{
+		Tool:       md.Tool,
+		Target:     target(md.Tool),
+		Build:      md.Build,
+		Host:       getHost(),
+		Start:      md.Start.UTC(),
+		End:        end.UTC(),
+		Incomplete: incomplete,
+		Flags:      md.Flags,
+		Run:        *r,
+	}
+}
+
+// ReadFile reads a Record from a results file
+func ReadFile(file string) (*Record, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	rec := &Record{}
+	if err := json.NewDecoder(f).Decode(rec); err != nil {
+		return nil, fmt.Errorf("cannot read results file %s: %v", file, err)
+	}
+	return rec, nil
+}
+
+// WriteFile writes rec as JSON to a results file
+func WriteFile(file string, rec *Record) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rec); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Options are where to save the results of a run, as given by the flags
+// added by AddFlags
+type Options struct {
+	// DB is the URL of a results database (see Open)
+	DB string
+	// File is a file to write the results to as JSON
+	File string
+}
+
+// AddFlags adds the -results-db and -results-file flags to fs, returning
+// the Options they set
+func AddFlags(fs *flag.FlagSet) *Options {
+	o := &Options{}
+	fs.StringVar(&o.DB, "results-db", "", "URL of a results database to insert the results of the run into, creating its tables if needed: postgres://<user>:<password>@<host>/<db> or sqlite://<file> (requires building with -tags sqlite)")
+	fs.StringVar(&o.File, "results-file", "", "File to write the results of the run to as JSON, e.g., to archive them or make a report with tsbs report")
+	return o
+}
+
+// SaveRun saves r, the results of the run described by md, which ended with
+// runErr, as given by o, if the run got as far as having results. Runs
+// ending with an error are saved as incomplete. It returns runErr, or else
+// any error saving the results, so tools can end with
+// return md.Finish(results.SaveRun(o, md, r, err)).
+func SaveRun(o *Options, md *cli.Metadata, r *Run, runErr error) error {
+	if o == nil || (len(o.DB) == 0 && len(o.File) == 0) || r == nil {
+		return runErr
+	}
+	rec := NewRecord(md, r, time.Now(), runErr != nil)
+	err := saveRecord(o, rec)
+	if err == nil {
+		return runErr
+	}
+	if runErr != nil {
+		logging.Warn("could not save results", "error", err)
+		return runErr
+	}
+	return fmt.Errorf("could not save results: %v", err)
+}
+
+func saveRecord(o *Options, rec *Record) error {
+	if len(o.File) > 0 {
+		if err := WriteFile(o.File, rec); err != nil {
+			return err
+		}
+	}
+	if len(o.DB) == 0 {
+		return nil
+	}
+	d, err := Open(o.DB)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	id, err := d.Save(rec)
+	if err != nil {
+		return err
+	}
+	logging.Info("saved results", "run_id", id)
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build sqlite
+// +build sqlite
+
+package results
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	drivers["sqlite"] = "sqlite3"
+}
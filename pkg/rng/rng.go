@@ -0,0 +1,117 @@
+// Package rng provides the deterministic sources of randomness that data and
+// queries are generated from. Rather than sharing the global math/rand
+// source, each simulator and query generator is given its own RNG, so their
+// output only depends on their seed, and not on what else is running in the
+// process:
+//
+//	r := rng.New(123)
+//	hosts := r.Split() // an independent stream, e.g., for a component
+//	n := r.Intn(10)
+package rng
+
+import (
+	"math/bits"
+	"math/rand"
+)
+
+// RNG is a source of pseudo-random values. Its methods behave like those of
+// *rand.Rand. Split returns a new RNG whose values are independent of those
+// of the RNG it was split from, e.g., for a component of a generator that may
+// run in parallel with the others; splitting advances the original RNG, so
+// the RNGs split from it depend only on its seed and the order they are split
+// in.
+//
+// An RNG must not be used concurrently, but RNGs split from each other may be.
+type RNG interface {
+	Int63() int64
+	Uint64() uint64
+	Int63n(n int64) int64
+	Intn(n int) int
+	Float64() float64
+	NormFloat64() float64
+	Split() RNG
+}
+
+// PCG is a rand.Source64 implementing the PCG-DXSM generator with 128 bits of
+// state, the same algorithm as math/rand/v2's PCG. It is fast, has a small
+// state, and its output is of high statistical quality.
+type PCG struct {
+	hi, lo uint64
+}
+
+// NewPCG returns a PCG seeded with seed
+func NewPCG(seed int64) *PCG {
+	p := &PCG{}
+	p.Seed(seed)
+	return p
+}
+
+// Seed initializes the state of the source from seed using splitmix64, so
+// that similar seeds still give unrelated states
+func (p *PCG) Seed(seed int64) {
+	z := uint64(seed)
+	p.hi = splitmix64(&z)
+	p.lo = splitmix64(&z)
+}
+
+func splitmix64(z *uint64) uint64 {
+	*z += 0x9e3779b97f4a7c15
+	v := *z
+	v = (v ^ (v >> 30)) * 0xbf58476d1ce4e5b9
+	v = (v ^ (v >> 27)) * 0x94d049bb133111eb
+	return v ^ (v >> 31)
+}
+
+// Uint64 returns the next pseudo-random 64-bit value
+func (p *PCG) Uint64() uint64 {
+	const (
+		mulHi = 2549297995355413924
+		mulLo = 4865540595714422341
+		incHi = 6364136223846793005
+		incLo = 1442695040888963407
+	)
+	// state = state*mul + inc, as a 128-bit linear congruential generator
+	hi, lo := bits.Mul64(p.lo, mulLo)
+	hi += p.hi*mulLo + p.lo*mulHi
+	lo, c := bits.Add64(lo, incLo, 0)
+	hi, _ = bits.Add64(hi, incHi, c)
+	p.lo, p.hi = lo, hi
+
+	// the "double xorshift multiply" output function
+	const cheapMul = 0xda942042e4dd58b5
+	hi ^= hi >> 32
+	hi *= cheapMul
+	hi ^= hi >> 48
+	hi *= lo | 1
+	return hi
+}
+
+// Int63 returns the next pseudo-random non-negative 63-bit value
+func (p *PCG) Int63() int64 {
+	return int64(p.Uint64() >> 1)
+}
+
+// Split returns a new PCG whose state is taken from the next values of p
+func (p *PCG) Split() *PCG {
+	return &PCG{hi: p.Uint64(), lo: p.Uint64()}
+}
+
+// Rand is the default RNG, a *rand.Rand drawing from a PCG
+type Rand struct {
+	*rand.Rand
+	src *PCG
+}
+
+// New returns a Rand seeded with seed
+func New(seed int64) *Rand {
+	return newRand(NewPCG(seed))
+}
+
+func newRand(src *PCG) *Rand {
+	return &Rand{Rand: rand.New(src), src: src}
+}
+
+// Split returns a new Rand whose values are independent of those of r
+func (r *Rand) Split() RNG {
+	return newRand(r.src.Split())
+}
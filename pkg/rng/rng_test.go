@@ -0,0 +1,95 @@
+package rng
+
+import (
+	"testing"
+)
+
+func TestPCGUint64(t *testing.T) {
+	// the values of math/rand/v2's NewPCG(1, 2)
+	p := &PCG{hi: 1, lo: 2}
+	want := []uint64{0xc4f5a58656eef510, 0x9dcec3ad077dec6c, 0xc8d04605312f8088}
+	for i, w := range want {
+		if got := p.Uint64(); got != w {
+			t.Errorf("incorrect value %d: got %#x want %#x", i, got, w)
+		}
+	}
+}
+
+func TestPCGSeed(t *testing.T) {
+	a := NewPCG(123)
+	b := NewPCG(123)
+	for i := 0; i < 100; i++ {
+		if x, y := a.Uint64(), b.Uint64(); x != y {
+			t.Fatalf("same seed gave different values at %d: %d vs %d", i, x, y)
+		}
+	}
+
+	b.Seed(124)
+	same := 0
+	for i := 0; i < 100; i++ {
+		if a.Uint64() == b.Uint64() {
+			same++
+		}
+	}
+	if same == 100 {
+		t.Errorf("different seeds gave the same values")
+	}
+}
+
+func TestRandSplit(t *testing.T) {
+	values := func(r RNG) []int64 {
+		vals := make([]int64, 10)
+		for i := range vals {
+			vals[i] = r.Int63()
+		}
+		return vals
+	}
+
+	a, b := New(123), New(123)
+	childA, childB := a.Split(), b.Split()
+	// draws from one child should not affect the other RNGs
+	values(childA)
+	if got, want := values(a), values(b); !equal(got, want) {
+		t.Errorf("parent values depend on child draws: got %v want %v", got, want)
+	}
+	if got, want := values(a.Split()), values(childB.Split()); equal(got, want) {
+		t.Errorf("different splits gave the same values: %v", got)
+	}
+
+	// splitting in the same order from the same seed is deterministic
+	c := New(123)
+	c.Split()
+	if got, want := values(c.Split()), values(New(123).Split().Split()); equal(got, want) {
+		t.Errorf("split of a split gave the same values as a sibling: %v", got)
+	}
+	d, e := New(5), New(5)
+	d.Split()
+	e.Split()
+	if got, want := values(d.Split()), values(e.Split()); !equal(got, want) {
+		t.Errorf("splits in the same order differ: got %v want %v", got, want)
+	}
+}
+
+func TestRandRanges(t *testing.T) {
+	r := New(1)
+	for i := 0; i < 1000; i++ {
+		if v := r.Float64(); v < 0 || v >= 1 {
+			t.Fatalf("Float64 out of range: %v", v)
+		}
+		if v := r.Intn(7); v < 0 || v >= 7 {
+			t.Fatalf("Intn out of range: %v", v)
+		}
+	}
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+// Package suggest reports values that are not one of a set of choices, such
+// as the formats and use cases in the registries, suggesting the choice
+// closest to the value in case it is a typo.
+package suggest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// minPrefixLen is the shortest value suggested a choice it is a prefix of,
+// e.g., cpu for cpu-only
+const minPrefixLen = 2
+
+// Closest returns the choice closest to value, ignoring case, if any is
+// close enough for value to likely be a typo of it: either value is a prefix
+// of the choice, or they differ by at most about one edit per three
+// characters. Ties go to the earliest choice.
+func Closest(value string, choices []string) (string, bool) {
+	v := strings.ToLower(value)
+	if len(v) == 0 {
+		return "", false
+	}
+	if len(v) >= minPrefixLen {
+		for _, c := range choices {
+			if strings.HasPrefix(strings.ToLower(c), v) {
+				return c, true
+			}
+		}
+	}
+
+	best, bestDist := "", -1
+	for _, c := range choices {
+		if d := distance(v, strings.ToLower(c)); bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if bestDist < 0 || bestDist > (len(v)+2)/3 {
+		return "", false
+	}
+	return best, true
+}
+
+// Error returns an error saying that value is not a valid kind (e.g.,
+// "format"), suggesting the closest choice if any, and listing all choices
+func Error(kind, value string, choices []string) error {
+	valid := strings.Join(choices, ", ")
+	if len(value) == 0 {
+		return fmt.Errorf("no %s given (valid choices: %s)", kind, valid)
+	}
+	if c, ok := Closest(value, choices); ok {
+		return fmt.Errorf("unknown %s: '%s', did you mean '%s'? (valid choices: %s)", kind, value, c, valid)
+	}
+	return fmt.Errorf("unknown %s: '%s' (valid choices: %s)", kind, value, valid)
+}
+
+// distance returns the Levenshtein distance between a and b, the number of
+// single byte insertions, deletions and substitutions turning a into b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
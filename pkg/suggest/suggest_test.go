@@ -0,0 +1,79 @@
+package suggest
+
+import (
+	"testing"
+)
+
+var testChoices = []string{"cassandra", "influx", "mongo", "mongo-naive", "timescaledb"}
+
+func TestClosest(t *testing.T) {
+	cases := []struct {
+		desc   string
+		value  string
+		want   string
+		wantOK bool
+	}{
+		{desc: "exact", value: "influx", want: "influx", wantOK: true},
+		{desc: "prefix", value: "timescale", want: "timescaledb", wantOK: true},
+		{desc: "prefix of several", value: "mon", want: "mongo", wantOK: true},
+		{desc: "typo", value: "infulx", want: "influx", wantOK: true},
+		{desc: "missing letter", value: "casandra", want: "cassandra", wantOK: true},
+		{desc: "upper case", value: "MongoDB", want: "mongo", wantOK: true},
+		{desc: "too far", value: "bogus"},
+		{desc: "short prefix", value: "i"},
+		{desc: "empty", value: ""},
+	}
+	for _, c := range cases {
+		got, ok := Closest(c.value, testChoices)
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("%s: incorrect suggestion for '%s': got %q, %v want %q, %v", c.desc, c.value, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	cases := []struct {
+		desc  string
+		value string
+		want  string
+	}{
+		{
+			desc:  "suggestion",
+			value: "timescale",
+			want:  "unknown format: 'timescale', did you mean 'timescaledb'? (valid choices: cassandra, influx, mongo, mongo-naive, timescaledb)",
+		},
+		{
+			desc:  "no suggestion",
+			value: "bogus",
+			want:  "unknown format: 'bogus' (valid choices: cassandra, influx, mongo, mongo-naive, timescaledb)",
+		},
+		{
+			desc: "missing",
+			want: "no format given (valid choices: cassandra, influx, mongo, mongo-naive, timescaledb)",
+		},
+	}
+	for _, c := range cases {
+		if got := Error("format", c.value, testChoices).Error(); got != c.want {
+			t.Errorf("%s: incorrect error:\ngot  %s\nwant %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"influx", "infulx", 2},
+		{"mongo", "mongo", 0},
+	}
+	for _, c := range cases {
+		if got := distance(c.a, c.b); got != c.want {
+			t.Errorf("incorrect distance between '%s' and '%s': got %d want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package telemetry instruments the loaders and query runners with
+// OpenTelemetry, exporting a span per batch loaded and per query run, and
+// metrics of them, via OTLP, so the behavior of the benchmark client can be
+// correlated with the traces of the database server. It is enabled by the
+// -otel-endpoint flag of the tools (see AddFlags), and needs them to be built
+// with the otel build tag, which brings in the OpenTelemetry SDK.
+package telemetry
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+)
+
+// shutdownTimeout is how long Stop waits for the telemetry to be flushed
+const shutdownTimeout = 10 * time.Second
+
+// Options are where and how to export telemetry, as given by the flags added
+// by AddFlags
+type Options struct {
+	// Endpoint is the host:port of the OTLP gRPC collector, e.g., that of
+	// an OpenTelemetry Collector or Jaeger. Telemetry is disabled if empty.
+	Endpoint string
+	// Insecure disables TLS to the collector
+	Insecure bool
+	// ServiceName is the service.name of the telemetry, the name of the
+	// tool if empty
+	ServiceName string
+}
+
+// AddFlags adds the -otel flags to fs, returning the Options they set
+func AddFlags(fs *flag.FlagSet) *Options {
+	o := &Options{}
+	fs.StringVar(&o.Endpoint, "otel-endpoint", "", "host:port of an OTLP gRPC collector to export a span per batch or query, and metrics of them, to (requires building with -tags otel)")
+	fs.BoolVar(&o.Insecure, "otel-insecure", false, "Connect to -otel-endpoint without TLS")
+	fs.StringVar(&o.ServiceName, "otel-service-name", "", "service.name of the telemetry exported to -otel-endpoint (default: the name of the tool)")
+	return o
+}
+
+// Enabled returns whether telemetry is to be exported
+func (o *Options) Enabled() bool {
+	return o != nil && len(o.Endpoint) > 0
+}
+
+func (o *Options) serviceName() string {
+	if len(o.ServiceName) > 0 {
+		return o.ServiceName
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// Recorder records the batches or queries of a run as spans, children of a
+// span of the whole run, and as metrics. Batches and queries are recorded
+// once done, with the time they started.
+type Recorder interface {
+	// Batch records a batch of metrics and rows loaded by worker
+	Batch(worker int, start, end time.Time, metrics, rows uint64)
+	// Query records a query with the given label and id run by worker
+	Query(worker int, start, end time.Time, label string, id uint64, warm bool)
+	// Shutdown ends the span of the run and flushes all telemetry
+	Shutdown(ctx context.Context) error
+}
+
+// Noop is the Recorder of runs without telemetry
+var Noop Recorder = noop{}
+
+type noop struct{}
+
+func (noop) Batch(int, time.Time, time.Time, uint64, uint64)       {}
+func (noop) Query(int, time.Time, time.Time, string, uint64, bool) {}
+func (noop) Shutdown(context.Context) error                        { return nil }
+
+// Start starts exporting telemetry as given by o, returning the Recorder of
+// the run, which is Noop if telemetry is not enabled
+func Start(ctx context.Context, o *Options) (Recorder, error) {
+	if !o.Enabled() {
+		return Noop, nil
+	}
+	r, err := newRecorder(ctx, o)
+	if err != nil && cli.ExitCode(err) == cli.ExitFailure {
+		err = cli.UnreachableError(fmt.Errorf("could not export telemetry to %s: %v", o.Endpoint, err))
+	}
+	return r, err
+}
+
+// Stop shuts r down, warning if its telemetry could not all be flushed
+func Stop(r Recorder) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		logging.Warn("could not flush telemetry", "error", err)
+	}
+}
+
+// newRecorder returns a Recorder exporting telemetry via OTLP. Support for
+// OpenTelemetry is only built in with the otel build tag, which replaces it.
+var newRecorder = func(ctx context.Context, o *Options) (Recorder, error) {
+	return nil, cli.ConfigError(fmt.Errorf("cannot export telemetry to %s: built without OpenTelemetry support (rebuild with -tags otel)", o.Endpoint))
+}
@@ -0,0 +1,141 @@
+//go:build otel
+// +build otel
+
+package telemetry
+
+import (
+	"context"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer and meter of the tools
+const instrumentationName = "github.com/timescale/tsbs"
+
+func init() {
+	newRecorder = func(ctx context.Context, o *Options) (Recorder, error) {
+		traceOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(o.Endpoint)}
+		metricOpts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithEndpoint(o.Endpoint)}
+		if o.Insecure {
+			traceOpts = append(traceOpts, otlptracegrpc.WithInsecure())
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithInsecure())
+		}
+		traceExp, err := otlptracegrpc.New(ctx, traceOpts...)
+		if err != nil {
+			return nil, err
+		}
+		metricExp, err := otlpmetricgrpc.New(ctx, metricOpts...)
+		if err != nil {
+			traceExp.Shutdown(ctx)
+			return nil, err
+		}
+		res := resource.NewSchemaless(
+			attribute.String("service.name", o.serviceName()),
+			attribute.String("service.version", version.Get().Version),
+		)
+		r := &otelRecorder{
+			tp: sdktrace.NewTracerProvider(sdktrace.WithBatcher(traceExp), sdktrace.WithResource(res)),
+			mp: sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExp)), sdkmetric.WithResource(res)),
+		}
+		if err := r.init(); err != nil {
+			r.Shutdown(ctx)
+			return nil, err
+		}
+		r.runCtx, r.run = r.tracer.Start(context.Background(), o.serviceName())
+		return r, nil
+	}
+}
+
+// otelRecorder records spans and metrics with the OpenTelemetry SDK
+type otelRecorder struct {
+	tp     *sdktrace.TracerProvider
+	mp     *sdkmetric.MeterProvider
+	tracer trace.Tracer
+
+	// the batches and queries are children of the span of the run
+	runCtx context.Context
+	run    trace.Span
+
+	batches       metric.Int64Counter
+	metrics       metric.Int64Counter
+	rows          metric.Int64Counter
+	batchDuration metric.Float64Histogram
+	queries       metric.Int64Counter
+	queryDuration metric.Float64Histogram
+}
+
+func (r *otelRecorder) init() error {
+	r.tracer = r.tp.Tracer(instrumentationName)
+	meter := r.mp.Meter(instrumentationName)
+	var err error
+	if r.batches, err = meter.Int64Counter("tsbs.load.batches", metric.WithDescription("Batches loaded")); err != nil {
+		return err
+	}
+	if r.metrics, err = meter.Int64Counter("tsbs.load.metrics", metric.WithDescription("Metrics loaded")); err != nil {
+		return err
+	}
+	if r.rows, err = meter.Int64Counter("tsbs.load.rows", metric.WithDescription("Rows loaded")); err != nil {
+		return err
+	}
+	if r.batchDuration, err = meter.Float64Histogram("tsbs.load.batch.duration", metric.WithUnit("ms"), metric.WithDescription("Time to load a batch")); err != nil {
+		return err
+	}
+	if r.queries, err = meter.Int64Counter("tsbs.queries", metric.WithDescription("Queries run")); err != nil {
+		return err
+	}
+	r.queryDuration, err = meter.Float64Histogram("tsbs.query.duration", metric.WithUnit("ms"), metric.WithDescription("Time to run a query"))
+	return err
+}
+
+func (r *otelRecorder) Batch(worker int, start, end time.Time, metrics, rows uint64) {
+	attrs := metric.WithAttributes(attribute.Int("worker", worker))
+	r.batches.Add(r.runCtx, 1, attrs)
+	r.metrics.Add(r.runCtx, int64(metrics), attrs)
+	r.rows.Add(r.runCtx, int64(rows), attrs)
+	r.batchDuration.Record(r.runCtx, milliseconds(end.Sub(start)), attrs)
+
+	_, span := r.tracer.Start(r.runCtx, "batch", trace.WithTimestamp(start), trace.WithAttributes(
+		attribute.Int("worker", worker),
+		attribute.Int64("metrics", int64(metrics)),
+		attribute.Int64("rows", int64(rows)),
+	))
+	span.End(trace.WithTimestamp(end))
+}
+
+func (r *otelRecorder) Query(worker int, start, end time.Time, label string, id uint64, warm bool) {
+	attrs := metric.WithAttributes(attribute.Int("worker", worker), attribute.String("label", label), attribute.Bool("warm", warm))
+	r.queries.Add(r.runCtx, 1, attrs)
+	r.queryDuration.Record(r.runCtx, milliseconds(end.Sub(start)), attrs)
+
+	_, span := r.tracer.Start(r.runCtx, "query", trace.WithTimestamp(start), trace.WithAttributes(
+		attribute.Int("worker", worker),
+		attribute.String("label", label),
+		attribute.Int64("id", int64(id)),
+		attribute.Bool("warm", warm),
+	))
+	span.End(trace.WithTimestamp(end))
+}
+
+func (r *otelRecorder) Shutdown(ctx context.Context) error {
+	if r.run != nil {
+		r.run.End()
+	}
+	err := r.tp.Shutdown(ctx)
+	if merr := r.mp.Shutdown(ctx); err == nil {
+		err = merr
+	}
+	return err
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e6
+}
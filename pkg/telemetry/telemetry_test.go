@@ -0,0 +1,81 @@
+package telemetry
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+)
+
+type testRecorder struct {
+	batches  int
+	shutdown bool
+}
+
+func (r *testRecorder) Batch(int, time.Time, time.Time, uint64, uint64)       { r.batches++ }
+func (r *testRecorder) Query(int, time.Time, time.Time, string, uint64, bool) {}
+func (r *testRecorder) Shutdown(context.Context) error {
+	r.shutdown = true
+	return nil
+}
+
+func TestAddFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o := AddFlags(fs)
+	if o.Enabled() {
+		t.Errorf("enabled by default")
+	}
+	if err := fs.Parse([]string{"-otel-endpoint=localhost:4317", "-otel-insecure"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !o.Enabled() || !o.Insecure {
+		t.Errorf("incorrect options: %+v", o)
+	}
+	o.ServiceName = "nightly"
+	if got := o.serviceName(); got != "nightly" {
+		t.Errorf("incorrect service name: got %s", got)
+	}
+	var nilOpts *Options
+	if nilOpts.Enabled() {
+		t.Errorf("nil options enabled")
+	}
+}
+
+func TestStart(t *testing.T) {
+	r, err := Start(context.Background(), &Options{})
+	if err != nil || r != Noop {
+		t.Errorf("disabled telemetry: got %v, %v want Noop", r, err)
+	}
+
+	old := newRecorder
+	defer func() { newRecorder = old }()
+	want := &testRecorder{}
+	newRecorder = func(context.Context, *Options) (Recorder, error) { return want, nil }
+	r, err = Start(context.Background(), &Options{Endpoint: "localhost:4317"})
+	if err != nil || r != want {
+		t.Errorf("enabled telemetry: got %v, %v", r, err)
+	}
+	Stop(r)
+	if !want.shutdown {
+		t.Errorf("recorder not shut down")
+	}
+
+	cases := []struct {
+		desc     string
+		err      error
+		wantCode int
+	}{
+		{desc: "collector unreachable", err: fmt.Errorf("connection refused"), wantCode: cli.ExitUnreachable},
+		{desc: "not built in", err: cli.ConfigError(fmt.Errorf("built without")), wantCode: cli.ExitConfig},
+	}
+	for _, c := range cases {
+		newRecorder = func(context.Context, *Options) (Recorder, error) { return nil, c.err }
+		_, err := Start(context.Background(), &Options{Endpoint: "localhost:4317"})
+		if code := cli.ExitCode(err); code != c.wantCode {
+			t.Errorf("%s: incorrect exit code: got %d want %d (%v)", c.desc, code, c.wantCode, err)
+		}
+	}
+}
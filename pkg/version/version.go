@@ -0,0 +1,89 @@
+// Package version describes the build of the TSBS binaries, so generated
+// data and benchmark results can be traced back to the code that produced
+// them.
+//
+// The version, commit and date are set at build time with -ldflags, e.g.:
+//
+//	go build -ldflags "-X github.com/timescale/tsbs/pkg/version.Version=v0.2.0 \
+//	    -X github.com/timescale/tsbs/pkg/version.Commit=$(git rev-parse HEAD) \
+//	    -X github.com/timescale/tsbs/pkg/version.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them, the commit and date come from the VCS information the Go
+// toolchain embeds when building from a checkout, if any.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set with -ldflags "-X github.com/timescale/tsbs/pkg/version.<Name>=<value>"
+var (
+	// Version is the released version, or "dev" for other builds
+	Version = "dev"
+	// Commit is the git commit the binary was built from
+	Commit = ""
+	// Date is the time the binary was built (or, without -ldflags, the
+	// time of the commit), in RFC3339
+	Date = ""
+)
+
+// Info is the build metadata of a binary, as recorded in manifests and
+// results
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"go_version"`
+	// Modified is set when the binary was built from a checkout with
+	// uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+}
+
+// Get returns the build metadata of the running binary
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if len(info.Commit) == 0 {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if len(info.Date) == 0 {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// String returns the metadata in one line, e.g.,
+// "v0.2.0 (commit 1a2b3c4, built 2020-01-01T00:00:00Z, go1.13)"
+func (i Info) String() string {
+	s := i.Version + " ("
+	if len(i.Commit) > 0 {
+		commit := i.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if i.Modified {
+			commit += "-modified"
+		}
+		s += fmt.Sprintf("commit %s, ", commit)
+	}
+	if len(i.Date) > 0 {
+		s += fmt.Sprintf("built %s, ", i.Date)
+	}
+	return s + i.GoVersion + ")"
+}
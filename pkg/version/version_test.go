@@ -0,0 +1,46 @@
+package version
+
+import "testing"
+
+func TestInfoString(t *testing.T) {
+	cases := []struct {
+		desc string
+		info Info
+		want string
+	}{
+		{
+			desc: "no vcs information",
+			info: Info{Version: "dev", GoVersion: "go1.13"},
+			want: "dev (go1.13)",
+		},
+		{
+			desc: "release",
+			info: Info{Version: "v0.2.0", Commit: "1a2b3c4d5e6f7a8b9c0d", Date: "2020-01-01T00:00:00Z", GoVersion: "go1.13"},
+			want: "v0.2.0 (commit 1a2b3c4d5e6f, built 2020-01-01T00:00:00Z, go1.13)",
+		},
+		{
+			desc: "modified checkout",
+			info: Info{Version: "dev", Commit: "1a2b3c4", Modified: true, GoVersion: "go1.13"},
+			want: "dev (commit 1a2b3c4-modified, go1.13)",
+		},
+	}
+	for _, c := range cases {
+		if got := c.info.String(); got != c.want {
+			t.Errorf("%s: incorrect string: got %s want %s", c.desc, got, c.want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	oldVersion, oldCommit, oldDate := Version, Commit, Date
+	defer func() { Version, Commit, Date = oldVersion, oldCommit, oldDate }()
+
+	Version, Commit, Date = "v1.0.0", "abc", "2020-01-01T00:00:00Z"
+	info := Get()
+	if info.Version != Version || info.Commit != Commit || info.Date != Date {
+		t.Errorf("ldflags values not used: got %+v", info)
+	}
+	if len(info.GoVersion) == 0 {
+		t.Errorf("missing Go version")
+	}
+}
@@ -2,19 +2,27 @@ package query
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"runtime/pprof"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/timescale/tsbs/pkg/cli"
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/results"
+	"github.com/timescale/tsbs/pkg/telemetry"
 )
 
 const (
 	labelAllQueries  = "all queries"
 	labelColdQueries = "cold queries"
 	labelWarmQueries = "warm queries"
+
+	errTargetMismatchFmt = "queries are for the %s target, but this runner expects %s: aborting."
 )
 
 // BenchmarkRunner contains the common components for running a query benchmarking
@@ -27,9 +35,16 @@ type BenchmarkRunner struct {
 	dbName         string
 	workers        uint
 	limit          uint64
+	maxDuration    time.Duration
+	cpuProfile     string
 	memProfile     string
 	printResponses bool
 	debug          int
+	targets        []string
+	resultsOpts    *results.Options
+	telemetryOpts  *telemetry.Options
+	results        *results.Run
+	telemetry      telemetry.Recorder
 }
 
 // NewBenchmarkRunner creates a new instance of BenchmarkRunner which is
@@ -43,12 +58,17 @@ func NewBenchmarkRunner() *BenchmarkRunner {
 	flag.StringVar(&ret.dbName, "db-name", "benchmark", "Name of database to use for queries")
 	flag.Uint64Var(&ret.sp.burnIn, "burn-in", 0, "Number of queries to ignore before collecting statistics.")
 	flag.Uint64Var(&ret.limit, "limit", 0, "Limit the number of queries to send, 0 = no limit")
+	flag.DurationVar(&ret.maxDuration, "max-duration", 0, "Stop sending queries after running for this long, 0 = no limit")
 	flag.Uint64Var(&ret.sp.printInterval, "print-interval", 100, "Print timing stats to stderr after this many queries (0 to disable)")
-	flag.StringVar(&ret.memProfile, "memprofile", "", "Write a memory profile to this file.")
+	flag.StringVar(&ret.cpuProfile, "cpu-profile", "", "Write a CPU profile to this file.")
+	flag.StringVar(&ret.memProfile, "mem-profile", "", "Write a memory profile to this file.")
+	flag.StringVar(&ret.memProfile, "memprofile", "", "Deprecated: use -mem-profile.")
 	flag.UintVar(&ret.workers, "workers", 1, "Number of concurrent requests to make.")
 	flag.BoolVar(&ret.sp.prewarmQueries, "prewarm-queries", false, "Run each query twice in a row so the warm query is guaranteed to be a cache hit")
 	flag.BoolVar(&ret.printResponses, "print-responses", false, "Pretty print response bodies for correctness checking (default false).")
 	flag.IntVar(&ret.debug, "debug", 0, "Whether to print debug messages.")
+	ret.resultsOpts = results.AddFlags(flag.CommandLine)
+	ret.telemetryOpts = telemetry.AddFlags(flag.CommandLine)
 
 	return ret
 }
@@ -73,6 +93,21 @@ func (b *BenchmarkRunner) DatabaseName() string {
 	return b.dbName
 }
 
+// ExpectTargets sets the targets of the queries this runner executes, e.g.,
+// both mongo and mongo-naive for MongoDB. Run aborts if the input's header
+// names another target. If never called, the target is not checked.
+func (b *BenchmarkRunner) ExpectTargets(targets ...string) {
+	b.targets = targets
+}
+
+// SaveResults saves the results of the run described by md, which ended with
+// runErr, as given by -results-db and -results-file, if any (see
+// results.SaveRun), so tools can end with
+// return md.Finish(runner.SaveResults(md, runner.Run(...))).
+func (b *BenchmarkRunner) SaveResults(md *cli.Metadata, runErr error) error {
+	return results.SaveRun(b.resultsOpts, md, b.results, runErr)
+}
+
 // ProcessorCreate is a function that creates a new Procesor (called in Run)
 type ProcessorCreate func() Processor
 
@@ -86,8 +121,11 @@ type Processor interface {
 
 // Run does the bulk of the benchmark execution. It launches a gorountine to track
 // stats, creates workers to process queries, read in the input, execute the queries,
-// and then does cleanup.
-func (b *BenchmarkRunner) Run(queryPool *sync.Pool, createFn ProcessorCreate) {
+// and then does cleanup. An interrupt or SIGTERM stops reading the input, but the
+// queries already read are still run and the stats printed before
+// cli.ErrInterrupted is returned; a second one exits immediately. Reaching
+// -max-duration stops reading the input the same way, but is not an error.
+func (b *BenchmarkRunner) Run(queryPool *sync.Pool, createFn ProcessorCreate) error {
 	if b.workers == 0 {
 		panic("must have at least one worker")
 	}
@@ -96,6 +134,28 @@ func (b *BenchmarkRunner) Run(queryPool *sync.Pool, createFn ProcessorCreate) {
 	}
 	b.c = make(chan Query, b.workers)
 
+	// (Optional) start a CPU profile that covers the whole run:
+	if len(b.cpuProfile) > 0 {
+		f, err := os.Create(b.cpuProfile)
+		if err != nil {
+			logging.Fatal("could not create CPU profile", "file", b.cpuProfile, "error", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			logging.Fatal("could not start CPU profile", "error", err)
+		}
+		defer func() {
+			pprof.StopCPUProfile()
+			f.Close()
+		}()
+	}
+
+	rec, err := telemetry.Start(context.Background(), b.telemetryOpts)
+	if err != nil {
+		return err
+	}
+	b.telemetry = rec
+	defer telemetry.Stop(rec)
+
 	// Launch the stats processor:
 	go b.sp.process(b.workers)
 
@@ -108,8 +168,27 @@ func (b *BenchmarkRunner) Run(queryPool *sync.Pool, createFn ProcessorCreate) {
 
 	// Read in jobs, closing the job channel when done:
 	input := bufio.NewReaderSize(os.Stdin, 1<<20)
+	if err := b.checkHeader(input); err != nil {
+		return cli.DataError(err)
+	}
+	ctx, stop := cli.NotifyInterrupt(context.Background())
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		// restore the default behavior for the next interrupt
+		stop()
+	}()
+	ctx, cancelMaxDuration := cli.WithMaxDuration(ctx, b.maxDuration)
+	defer cancelMaxDuration()
 	wallStart := time.Now()
-	b.scanner.setReader(input).scan(queryPool, b.c)
+	b.scanner.setReader(input).scan(ctx, queryPool, b.c)
+	interrupted := false
+	if context.Cause(ctx) == cli.ErrMaxDuration {
+		logging.Info("reached max duration, finishing running the queries already read")
+	} else if ctx.Err() != nil {
+		interrupted = true
+		logging.Warn("caught interrupt, finishing running the queries already read")
+	}
 	close(b.c)
 
 	// Block for workers to finish sending requests, closing the stats
@@ -119,30 +198,75 @@ func (b *BenchmarkRunner) Run(queryPool *sync.Pool, createFn ProcessorCreate) {
 
 	wallEnd := time.Now()
 	wallTook := wallEnd.Sub(wallStart)
-	_, err := fmt.Printf("wall clock time: %fsec\n", float64(wallTook.Nanoseconds())/1e9)
+	b.results = &results.Run{
+		Workers: b.workers,
+		Seconds: wallTook.Seconds(),
+		Queries: b.sp.results,
+	}
+	_, err = fmt.Printf("wall clock time: %fsec\n", float64(wallTook.Nanoseconds())/1e9)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("could not write results", "error", err)
 	}
 
 	// (Optional) create a memory profile:
 	if len(b.memProfile) > 0 {
 		f, err := os.Create(b.memProfile)
 		if err != nil {
-			log.Fatal(err)
+			logging.Fatal("could not create memory profile", "file", b.memProfile, "error", err)
+		}
+		if err := pprof.WriteHeapProfile(f); err != nil {
+			logging.Fatal("could not write memory profile", "file", b.memProfile, "error", err)
 		}
-		pprof.WriteHeapProfile(f)
 		f.Close()
 	}
+	if interrupted {
+		return cli.ErrInterrupted
+	}
+	return nil
+}
+
+// checkHeader consumes the query file header at the start of the input, if
+// any, and checks that the queries are for one of the expected targets
+func (b *BenchmarkRunner) checkHeader(br *bufio.Reader) error {
+	h, err := ReadFileHeader(br)
+	if err != nil {
+		return fmt.Errorf("could not read query file header: %v", err)
+	}
+	if len(b.targets) == 0 {
+		return nil
+	}
+	if h == nil {
+		logging.Warn("input has no header, cannot check the queries are for the target", "targets", strings.Join(b.targets, ","))
+		return nil
+	}
+	for _, t := range b.targets {
+		if h.Target == t {
+			logging.Info("running queries", "header", h.String())
+			return nil
+		}
+	}
+	return fmt.Errorf(errTargetMismatchFmt, h.Target, strings.Join(b.targets, " or "))
 }
 
 func (b *BenchmarkRunner) processorHandler(wg *sync.WaitGroup, qPool *sync.Pool, p Processor, workerNum int) {
 	p.Init(workerNum)
+	traced := b.telemetryOpts.Enabled()
 	for q := range b.c {
+		var start time.Time
+		if traced {
+			start = time.Now()
+		}
 		//p.ProcessQuery(b.sp, q)
 		stats, err := p.ProcessQuery(q, false)
 		if err != nil {
 			panic(err)
 		}
+		if traced {
+			b.telemetry.Query(workerNum, start, time.Now(), string(q.HumanLabelName()), q.GetID(), false)
+		}
+		if len(stats) > 0 && logging.DebugEnabled() {
+			logging.Debug("ran query", "worker", workerNum, "id", q.GetID(), "label", string(q.HumanLabelName()), "ms", stats[0].value)
+		}
 		b.sp.sendStats(stats)
 
 		// If PrewarmQueries is set, we run the query as 'cold' first (see above),
@@ -150,10 +274,16 @@ func (b *BenchmarkRunner) processorHandler(wg *sync.WaitGroup, qPool *sync.Pool,
 		// stat. This guarantees that the warm stat will reflect optimal cache performance.
 		if b.sp.prewarmQueries {
 			// Warm run
+			if traced {
+				start = time.Now()
+			}
 			stats, err = p.ProcessQuery(q, true)
 			if err != nil {
 				panic(err)
 			}
+			if traced {
+				b.telemetry.Query(workerNum, start, time.Now(), string(q.HumanLabelName()), q.GetID(), true)
+			}
 			b.sp.sendStatsWarm(stats)
 		}
 		qPool.Put(q)
@@ -1,6 +1,8 @@
 package query
 
 import (
+	"bufio"
+	"bytes"
 	"sync"
 	"testing"
 )
@@ -52,3 +54,58 @@ func TestProcessorHandler(t *testing.T) {
 		t.Errorf("total queries wrong: want %d got %d", qLimit, p1.count+p2.count)
 	}
 }
+
+func TestCheckHeader(t *testing.T) {
+	var mongo bytes.Buffer
+	WriteFileHeader(&mongo, &FileHeader{Target: "mongo-naive", UseCase: "devops"})
+	cases := []struct {
+		desc        string
+		targets     []string
+		input       []byte
+		shouldError bool
+	}{
+		{
+			desc:  "no header, no targets",
+			input: []byte("gob"),
+		},
+		{
+			desc:    "no header",
+			targets: []string{"mongo"},
+			input:   []byte("gob"),
+		},
+		{
+			desc:  "header, no targets",
+			input: append(append([]byte{}, mongo.Bytes()...), "gob"...),
+		},
+		{
+			desc:    "header of an expected target",
+			targets: []string{"mongo", "mongo-naive"},
+			input:   append(append([]byte{}, mongo.Bytes()...), "gob"...),
+		},
+		{
+			desc:        "header of another target",
+			targets:     []string{"timescaledb"},
+			input:       append(append([]byte{}, mongo.Bytes()...), "gob"...),
+			shouldError: true,
+		},
+	}
+	for _, c := range cases {
+		b := &BenchmarkRunner{}
+		b.ExpectTargets(c.targets...)
+		br := bufio.NewReader(bytes.NewReader(c.input))
+		err := b.checkHeader(br)
+		if c.shouldError {
+			if err == nil {
+				t.Errorf("%s: did not error when should", c.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if rest, _ := br.ReadString('\n'); rest != "gob" {
+			t.Errorf("%s: incorrect remaining input: got %q", c.desc, rest)
+		}
+	}
+}
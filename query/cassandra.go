@@ -1,6 +1,7 @@
 package query
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -26,7 +27,7 @@ type Cassandra struct {
 	TagSets         [][]string // semantically, each subgroup is OR'ed and they are all AND'ed together
 }
 
-//CassandraPool is a sync.Pool of Cassandra Query types
+// CassandraPool is a sync.Pool of Cassandra Query types
 var CassandraPool = sync.Pool{
 	New: func() interface{} {
 		return &Cassandra{
@@ -63,6 +64,28 @@ func (q *Cassandra) String() string {
 	return fmt.Sprintf("HumanLabel: %s, HumanDescription: %s, MeasurementName: %s, AggregationType: %s, TimeStart: %s, TimeEnd: %s, GroupByDuration: %s, TagSets: %s", q.HumanLabel, q.HumanDescription, q.MeasurementName, q.AggregationType, q.TimeStart, q.TimeEnd, q.GroupByDuration, q.TagSets)
 }
 
+// MarshalJSON encodes the Query as JSON, e.g., for clients of
+// tsbs_generate_queries' HTTP mode
+func (q *Cassandra) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type             string     `json:"type"`
+		HumanLabel       string     `json:"human_label"`
+		HumanDescription string     `json:"human_description"`
+		MeasurementName  string     `json:"measurement_name"`
+		FieldName        string     `json:"field_name"`
+		AggregationType  string     `json:"aggregation_type"`
+		TimeStart        time.Time  `json:"time_start"`
+		TimeEnd          time.Time  `json:"time_end"`
+		GroupByDuration  string     `json:"group_by_duration"`
+		ForEveryN        string     `json:"for_every_n"`
+		WhereClause      string     `json:"where_clause"`
+		OrderBy          string     `json:"order_by"`
+		Limit            int        `json:"limit"`
+		TagSets          [][]string `json:"tag_sets"`
+	}{"cassandra", string(q.HumanLabel), string(q.HumanDescription), string(q.MeasurementName), string(q.FieldName), string(q.AggregationType),
+		q.TimeStart, q.TimeEnd, q.GroupByDuration.String(), string(q.ForEveryN), string(q.WhereClause), string(q.OrderBy), q.Limit, q.TagSets})
+}
+
 // HumanLabelName returns the human readable name of this Query
 func (q *Cassandra) HumanLabelName() []byte {
 	return q.HumanLabel
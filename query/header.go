@@ -0,0 +1,120 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// FileHeaderVersion is the version of the header layout written by
+// WriteFileHeader. New fields may be appended without incrementing it, since
+// the header records its own length; it is only incremented for incompatible
+// changes.
+const FileHeaderVersion = 1
+
+// fileHeaderMagic starts every query file header. A gob stream cannot begin
+// with these bytes, so query files without a header are told apart from
+// those with one.
+var fileHeaderMagic = []byte("TSBSQ")
+
+// fileHeaderPrefixLen is the length of the magic, version and body length
+// that precede the body of the header
+const fileHeaderPrefixLen = 5 + 1 + 2
+
+// fileHeaderFixedLen is the length of the fixed size fields of the body
+const fileHeaderFixedLen = 8 + 8 + 8
+
+// FileHeader describes a file of generated queries so that query runners can
+// check it is meant for their database before executing it. It is written
+// before the gob encoded queries.
+//
+// The binary layout is the magic "TSBSQ", a uint8 header version and a uint16
+// length of the body that follows; the body is a uint64 scale, an int64 seed,
+// a uint64 count, then a uint8 length followed by the target name and a uint8
+// length followed by the use case name. All integers are big endian.
+type FileHeader struct {
+	// Target is the name of the database the queries are written for, e.g.,
+	// timescaledb
+	Target string
+	// UseCase is the use case of the data the queries are for
+	UseCase string
+	// Scale is the scale of the data the queries are for
+	Scale uint64
+	// Seed is the seed the queries were generated with
+	Seed int64
+	// Count is the number of queries requested, before splitting them
+	// between interleaved groups
+	Count uint64
+}
+
+// String returns a short human readable description of h
+func (h *FileHeader) String() string {
+	return fmt.Sprintf("target %s, use case %s, scale %d, seed %d, count %d", h.Target, h.UseCase, h.Scale, h.Seed, h.Count)
+}
+
+// WriteFileHeader writes h to w in the binary header layout
+func WriteFileHeader(w io.Writer, h *FileHeader) error {
+	if len(h.Target) > 255 || len(h.UseCase) > 255 {
+		return fmt.Errorf("target or use case name too long for header: %s, %s", h.Target, h.UseCase)
+	}
+	body := make([]byte, fileHeaderFixedLen, fileHeaderFixedLen+2+len(h.Target)+len(h.UseCase))
+	binary.BigEndian.PutUint64(body, h.Scale)
+	binary.BigEndian.PutUint64(body[8:], uint64(h.Seed))
+	binary.BigEndian.PutUint64(body[16:], h.Count)
+	body = append(body, byte(len(h.Target)))
+	body = append(body, h.Target...)
+	body = append(body, byte(len(h.UseCase)))
+	body = append(body, h.UseCase...)
+
+	buf := make([]byte, 0, fileHeaderPrefixLen+len(body))
+	buf = append(buf, fileHeaderMagic...)
+	buf = append(buf, FileHeaderVersion)
+	buf = append(buf, byte(len(body)>>8), byte(len(body)))
+	buf = append(buf, body...)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadFileHeader reads a header from the start of br. If the queries do not
+// start with a header (e.g., they were generated before headers were added),
+// nothing is consumed and it returns nil with no error.
+func ReadFileHeader(br *bufio.Reader) (*FileHeader, error) {
+	prefix, err := br.Peek(fileHeaderPrefixLen)
+	if !bytes.HasPrefix(prefix, fileHeaderMagic) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("truncated query file header: %v", err)
+	}
+	if version := prefix[len(fileHeaderMagic)]; version > FileHeaderVersion {
+		return nil, fmt.Errorf("query file header version %d is newer than supported version %d; upgrade tsbs", version, FileHeaderVersion)
+	}
+	bodyLen := int(binary.BigEndian.Uint16(prefix[len(fileHeaderMagic)+1:]))
+	if _, err := br.Discard(fileHeaderPrefixLen); err != nil {
+		return nil, err
+	}
+	body := make([]byte, bodyLen)
+	if _, err := io.ReadFull(br, body); err != nil {
+		return nil, fmt.Errorf("truncated query file header: %v", err)
+	}
+
+	// fields appended by later versions are skipped
+	if len(body) < fileHeaderFixedLen {
+		return nil, fmt.Errorf("query file header body too short: %d bytes", len(body))
+	}
+	h := &FileHeader{
+		Scale: binary.BigEndian.Uint64(body),
+		Seed:  int64(binary.BigEndian.Uint64(body[8:])),
+		Count: binary.BigEndian.Uint64(body[16:]),
+	}
+	rest := body[fileHeaderFixedLen:]
+	for _, s := range []*string{&h.Target, &h.UseCase} {
+		if len(rest) < 1 || len(rest) < 1+int(rest[0]) {
+			return nil, fmt.Errorf("query file header body too short: %d bytes", len(body))
+		}
+		*s = string(rest[1 : 1+int(rest[0])])
+		rest = rest[1+int(rest[0]):]
+	}
+	return h, nil
+}
@@ -0,0 +1,123 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFileHeaderRoundTrip(t *testing.T) {
+	h := &FileHeader{
+		Target:  "timescaledb",
+		UseCase: "devops",
+		Scale:   4000,
+		Seed:    -123,
+		Count:   1000,
+	}
+	var buf bytes.Buffer
+	if err := WriteFileHeader(&buf, h); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf.WriteString("queries")
+
+	br := bufio.NewReader(&buf)
+	got, err := ReadFileHeader(br)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got == nil {
+		t.Fatalf("header not found")
+	}
+	if *got != *h {
+		t.Errorf("incorrect header: got %v want %v", got, h)
+	}
+	rest, _ := br.ReadString('\n')
+	if rest != "queries" {
+		t.Errorf("header not fully consumed: got %q", rest)
+	}
+}
+
+func TestReadFileHeader(t *testing.T) {
+	var valid bytes.Buffer
+	WriteFileHeader(&valid, &FileHeader{Target: "mongo", UseCase: "cpu-only"})
+	// a later version of the header with an extra field appended
+	extended := append([]byte{}, valid.Bytes()...)
+	extended[fileHeaderPrefixLen-1] += 3
+	extended = append(extended, 1, 2, 3)
+	newer := append([]byte{}, valid.Bytes()...)
+	newer[len(fileHeaderMagic)] = FileHeaderVersion + 1
+	short := append([]byte{}, valid.Bytes()[:fileHeaderPrefixLen]...)
+	short[fileHeaderPrefixLen-1] = 2
+	short = append(short, 0, 0)
+
+	cases := []struct {
+		desc       string
+		input      []byte
+		wantTarget string
+		wantRest   string
+		errPrefix  string
+	}{
+		{
+			desc:     "no header",
+			input:    []byte("gob"),
+			wantRest: "gob",
+		},
+		{
+			desc:       "valid header",
+			input:      append(append([]byte{}, valid.Bytes()...), "rest"...),
+			wantTarget: "mongo",
+			wantRest:   "rest",
+		},
+		{
+			desc:       "extended header",
+			input:      append(extended, "rest"...),
+			wantTarget: "mongo",
+			wantRest:   "rest",
+		},
+		{
+			desc:      "newer header version",
+			input:     newer,
+			errPrefix: "query file header version",
+		},
+		{
+			desc:      "truncated prefix",
+			input:     valid.Bytes()[:fileHeaderPrefixLen-1],
+			errPrefix: "truncated query file header",
+		},
+		{
+			desc:      "truncated body",
+			input:     valid.Bytes()[:valid.Len()-1],
+			errPrefix: "truncated query file header",
+		},
+		{
+			desc:      "body too short",
+			input:     short,
+			errPrefix: "query file header body too short",
+		},
+	}
+	for _, c := range cases {
+		br := bufio.NewReader(bytes.NewReader(c.input))
+		h, err := ReadFileHeader(br)
+		if c.errPrefix != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), c.errPrefix) {
+				t.Errorf("%s: incorrect error: got %v want prefix %s", c.desc, err, c.errPrefix)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.desc, err)
+			continue
+		}
+		if c.wantTarget == "" && h != nil {
+			t.Errorf("%s: unexpected header: %v", c.desc, h)
+		} else if c.wantTarget != "" && (h == nil || h.Target != c.wantTarget || h.UseCase != "cpu-only") {
+			t.Errorf("%s: incorrect header: got %v want target %s", c.desc, h, c.wantTarget)
+		}
+		rest := make([]byte, len(c.input))
+		n, _ := br.Read(rest)
+		if got := string(rest[:n]); got != c.wantRest {
+			t.Errorf("%s: incorrect remaining input: got %q want %q", c.desc, got, c.wantRest)
+		}
+	}
+}
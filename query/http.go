@@ -1,6 +1,7 @@
 package query
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 )
@@ -53,6 +54,21 @@ func (q *HTTP) String() string {
 	return fmt.Sprintf("HumanLabel: \"%s\", HumanDescription: \"%s\", Method: \"%s\", Path: \"%s\", Body: \"%s\"", q.HumanLabel, q.HumanDescription, q.Method, q.Path, q.Body)
 }
 
+// MarshalJSON encodes the Query as JSON, e.g., for clients of
+// tsbs_generate_queries' HTTP mode
+func (q *HTTP) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type             string `json:"type"`
+		HumanLabel       string `json:"human_label"`
+		HumanDescription string `json:"human_description"`
+		Method           string `json:"method"`
+		Path             string `json:"path"`
+		Body             string `json:"body"`
+		StartTimestamp   int64  `json:"start_timestamp"`
+		EndTimestamp     int64  `json:"end_timestamp"`
+	}{"http", string(q.HumanLabel), string(q.HumanDescription), string(q.Method), string(q.Path), string(q.Body), q.StartTimestamp, q.EndTimestamp})
+}
+
 // HumanLabelName returns the human readable name of this Query
 func (q *HTTP) HumanLabelName() []byte {
 	return q.HumanLabel
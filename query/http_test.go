@@ -1,6 +1,9 @@
 package query
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 func TestNewHTTP(t *testing.T) {
 	check := func(q *HTTP) {
@@ -52,3 +55,22 @@ func TestHTTPSetAndGetID(t *testing.T) {
 		q.Release()
 	}
 }
+
+func TestHTTPMarshalJSON(t *testing.T) {
+	q := NewHTTP()
+	defer q.Release()
+	q.HumanLabel = []byte("foo")
+	q.HumanDescription = []byte("bar")
+	q.Method = []byte("GET")
+	q.Path = []byte("/query?q=SELECT")
+	q.StartTimestamp = 1
+	q.EndTimestamp = 5
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"type":"http","human_label":"foo","human_description":"bar","method":"GET","path":"/query?q=SELECT","body":"","start_timestamp":1,"end_timestamp":5}`
+	if got := string(b); got != want {
+		t.Errorf("incorrect JSON:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package query
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 
@@ -49,6 +50,19 @@ func (q *Mongo) String() string {
 	return fmt.Sprintf("HumanLabel: %s, HumanDescription: %s", q.HumanLabel, q.HumanDescription)
 }
 
+// MarshalJSON encodes the Query as JSON, e.g., for clients of
+// tsbs_generate_queries' HTTP mode. The pipeline is encoded with
+// encoding/json, not as MongoDB extended JSON.
+func (q *Mongo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type             string   `json:"type"`
+		HumanLabel       string   `json:"human_label"`
+		HumanDescription string   `json:"human_description"`
+		CollectionName   string   `json:"collection_name"`
+		Pipeline         []bson.M `json:"pipeline"`
+	}{"mongo", string(q.HumanLabel), string(q.HumanDescription), string(q.CollectionName), q.BsonDoc})
+}
+
 // HumanLabelName returns the human readable name of this Query
 func (q *Mongo) HumanLabelName() []byte {
 	return q.HumanLabel
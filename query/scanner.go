@@ -1,10 +1,12 @@
 package query
 
 import (
+	"context"
 	"encoding/gob"
 	"io"
-	"log"
 	"sync"
+
+	"github.com/timescale/tsbs/pkg/cli"
 )
 
 // scanner is used to read in Queries from a Reader where they are
@@ -25,8 +27,9 @@ func (qs *scanner) setReader(r io.Reader) *scanner {
 	return qs
 }
 
-// scan reads encoded Queries and places them into a channel
-func (qs *scanner) scan(pool *sync.Pool, c chan Query) {
+// scan reads encoded Queries and places them into a channel until the input
+// is exhausted or ctx is done
+func (qs *scanner) scan(ctx context.Context, pool *sync.Pool, c chan Query) {
 	dec := gob.NewDecoder(qs.r)
 
 	n := uint64(0)
@@ -34,6 +37,9 @@ func (qs *scanner) scan(pool *sync.Pool, c chan Query) {
 		if *qs.limit > 0 && n >= *qs.limit {
 			break
 		}
+		if ctx.Err() != nil {
+			break
+		}
 
 		q := pool.Get().(Query)
 		err := dec.Decode(q)
@@ -41,7 +47,7 @@ func (qs *scanner) scan(pool *sync.Pool, c chan Query) {
 			break
 		}
 		if err != nil {
-			log.Fatal(err)
+			cli.Fatal(cli.ExitData, "could not decode query", "query", n, "error", err)
 		}
 
 		q.SetID(n)
@@ -3,6 +3,7 @@ package query
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"sync"
@@ -66,7 +67,7 @@ func runScan(t *testing.T, b *bytes.Buffer, limit, numQueries uint64, pool *sync
 		wg.Done()
 	}()
 	input := bufio.NewReaderSize(bytes.NewReader(b.Bytes()), 1<<20)
-	scanner.setReader(input).scan(pool, queryChan)
+	scanner.setReader(input).scan(context.Background(), pool, queryChan)
 	close(queryChan)
 	wg.Wait()
 	if got != numQueries {
@@ -113,6 +114,25 @@ func TestScannerLimit(t *testing.T) {
 	}
 }
 
+func TestScannerInterrupted(t *testing.T) {
+	var b bytes.Buffer
+	err := encodeQueries(&b, 3, func(i uint64) Query {
+		return &testQuery{HumanLabel: []byte("testlabel")}
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limit := uint64(0)
+	queryChan := make(chan Query, 3)
+	newScanner(&limit).setReader(&b).scan(ctx, &testQueryPool, queryChan)
+	close(queryChan)
+	if got := len(queryChan); got != 0 {
+		t.Errorf("interrupted scanner sent queries: got %d want 0", got)
+	}
+}
+
 func TestScanTimescaleDB(t *testing.T) {
 	labelFmt := "tslabel%d"
 	descFmt := "tsdesc%d"
@@ -2,18 +2,22 @@ package query
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"sync"
+
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/results"
+	"github.com/timescale/tsbs/pkg/version"
 )
 
 // statProcessor is used to collect, analyze, and print query execution statistics.
 type statProcessor struct {
-	prewarmQueries bool       // PrewarmQueries tells the StatProcessor whether we're running each query twice to prewarm the cache
-	c              chan *Stat // c is the channel for Stats to be sent for processing
-	limit          *uint64    // limit is the number of statistics to analyze before stopping
-	burnIn         uint64     // burnIn is the number of statistics to ignore before analyzing
-	printInterval  uint64     // printInterval is how often print intermediate stats (number of queries)
+	prewarmQueries bool            // PrewarmQueries tells the StatProcessor whether we're running each query twice to prewarm the cache
+	c              chan *Stat      // c is the channel for Stats to be sent for processing
+	limit          *uint64         // limit is the number of statistics to analyze before stopping
+	burnIn         uint64          // burnIn is the number of statistics to ignore before analyzing
+	printInterval  uint64          // printInterval is how often print intermediate stats (number of queries)
+	results        []results.Query // results are the final stats, set once processing is done
 	wg             sync.WaitGroup
 }
 
@@ -60,10 +64,7 @@ func (sp *statProcessor) process(workers uint) {
 			statPool.Put(stat)
 			continue
 		} else if i == sp.burnIn && sp.burnIn > 0 {
-			_, err := fmt.Fprintf(os.Stderr, "burn-in complete after %d queries with %d workers\n", sp.burnIn, workers)
-			if err != nil {
-				log.Fatal(err)
-			}
+			logging.Info("burn-in complete", "queries", sp.burnIn, "workers", workers)
 		}
 		if _, ok := statMapping[string(stat.label)]; !ok {
 			statMapping[string(stat.label)] = newStatGroup(*sp.limit)
@@ -93,32 +94,24 @@ func (sp *statProcessor) process(workers uint) {
 
 		statPool.Put(stat)
 
-		// print stats to stderr (if printInterval is greater than zero):
+		// log progress (if printInterval is greater than zero):
 		if sp.printInterval > 0 && i > 0 && i%sp.printInterval == 0 && (i < *sp.limit || *sp.limit == 0) {
-			_, err := fmt.Fprintf(os.Stderr, "after %d queries with %d workers:\n", i-sp.burnIn, workers)
-			if err != nil {
-				log.Fatal(err)
-			}
-			err = writeStatGroupMap(os.Stderr, statMapping)
-			if err != nil {
-				log.Fatal(err)
-			}
-			_, err = fmt.Fprintf(os.Stderr, "\n")
-			if err != nil {
-				log.Fatal(err)
-			}
+			logStatGroupMap("progress", statMapping, "queries", i-sp.burnIn, "workers", workers)
 		}
 	}
 
 	// the final stats output goes to stdout:
 	_, err := fmt.Printf("run complete after %d queries with %d workers:\n", i-sp.burnIn, workers)
-	if err != nil {
-		log.Fatal(err)
+	if err == nil {
+		err = writeStatGroupMap(os.Stdout, statMapping)
+	}
+	if err == nil {
+		_, err = fmt.Printf("built by tsbs %s\n", version.Get())
 	}
-	err = writeStatGroupMap(os.Stdout, statMapping)
 	if err != nil {
-		log.Fatal(err)
+		logging.Fatal("could not write results", "error", err)
 	}
+	sp.results = statGroupResults(statMapping)
 	sp.wg.Done()
 }
 
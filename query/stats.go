@@ -6,6 +6,9 @@ import (
 	"math"
 	"sort"
 	"sync"
+
+	"github.com/timescale/tsbs/pkg/logging"
+	"github.com/timescale/tsbs/pkg/results"
 )
 
 // Stat represents one statistical measurement, typically used to store the
@@ -148,6 +151,53 @@ func (s *statGroup) write(w io.Writer) error {
 	return err
 }
 
+// statGroupResults returns the statistics of each of a map of StatGroups,
+// ordered by their keys
+func statGroupResults(statGroups map[string]*statGroup) []results.Query {
+	keys := make([]string, 0, len(statGroups))
+	for k := range statGroups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make([]results.Query, 0, len(keys))
+	for _, k := range keys {
+		s := statGroups[k]
+		ret = append(ret, results.Query{
+			Label:    k,
+			Count:    s.count,
+			MinMs:    s.min,
+			MedianMs: s.median(),
+			MeanMs:   s.mean,
+			MaxMs:    s.max,
+			StdDevMs: s.stdDev,
+			SumMs:    s.sum,
+		})
+	}
+	return ret
+}
+
+// logStatGroupMap logs msg with args and the statistics of each of a map of
+// StatGroups, ordered by their keys, at the info level
+func logStatGroupMap(msg string, statGroups map[string]*statGroup, args ...interface{}) {
+	keys := make([]string, 0, len(statGroups))
+	for k := range statGroups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := statGroups[k]
+		logging.Info(msg, append(args,
+			"label", k,
+			"min_ms", s.min,
+			"median_ms", s.median(),
+			"mean_ms", s.mean,
+			"max_ms", s.max,
+			"stddev_ms", s.stdDev,
+			"sum_sec", s.sum/1e3,
+			"count", s.count)...)
+	}
+}
+
 // writeStatGroupMap writes a map of StatGroups in an ordered fashion by
 // key that they are stored by
 func writeStatGroupMap(w io.Writer, statGroups map[string]*statGroup) error {
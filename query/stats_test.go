@@ -375,3 +375,19 @@ func TestWriteStatGroupMap(t *testing.T) {
 		}
 	}
 }
+
+func TestStatGroupResults(t *testing.T) {
+	sg := newStatGroup(0)
+	for _, v := range []float64{1, 2, 6} {
+		sg.push(v)
+	}
+	m := map[string]*statGroup{"b": sg, "a": newStatGroup(0)}
+	got := statGroupResults(m)
+	if len(got) != 2 || got[0].Label != "a" || got[1].Label != "b" {
+		t.Fatalf("incorrect results: %+v", got)
+	}
+	r := got[1]
+	if r.Count != 3 || r.MinMs != 1 || r.MedianMs != 2 || r.MeanMs != 3 || r.MaxMs != 6 || r.SumMs != 9 || r.StdDevMs != sg.stdDev {
+		t.Errorf("incorrect statistics: %+v", r)
+	}
+}
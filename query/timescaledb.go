@@ -1,6 +1,7 @@
 package query
 
 import (
+	"encoding/json"
 	"fmt"
 	"sync"
 )
@@ -48,6 +49,18 @@ func (q *TimescaleDB) String() string {
 	return fmt.Sprintf("HumanLabel: %s, HumanDescription: %s, Hypertable: %s, Query: %s", q.HumanLabel, q.HumanDescription, q.Hypertable, q.SqlQuery)
 }
 
+// MarshalJSON encodes the Query as JSON, e.g., for clients of
+// tsbs_generate_queries' HTTP mode
+func (q *TimescaleDB) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type             string `json:"type"`
+		HumanLabel       string `json:"human_label"`
+		HumanDescription string `json:"human_description"`
+		Hypertable       string `json:"hypertable"`
+		SQLQuery         string `json:"sql_query"`
+	}{"timescaledb", string(q.HumanLabel), string(q.HumanDescription), string(q.Hypertable), string(q.SqlQuery)})
+}
+
 // HumanLabelName returns the human readable name of this Query
 func (q *TimescaleDB) HumanLabelName() []byte {
 	return q.HumanLabel
@@ -1,6 +1,9 @@
 package query
 
-import "testing"
+import (
+	"encoding/json"
+	"testing"
+)
 
 func TestNewTimescaleDB(t *testing.T) {
 	check := func(tq *TimescaleDB) {
@@ -40,3 +43,20 @@ func TestTimescaleDBSetAndGetID(t *testing.T) {
 		q.Release()
 	}
 }
+
+func TestTimescaleDBMarshalJSON(t *testing.T) {
+	q := NewTimescaleDB()
+	defer q.Release()
+	q.HumanLabel = []byte("foo")
+	q.HumanDescription = []byte("bar")
+	q.Hypertable = []byte("cpu")
+	q.SqlQuery = []byte("SELECT * FROM cpu")
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"type":"timescaledb","human_label":"foo","human_description":"bar","hypertable":"cpu","sql_query":"SELECT * FROM cpu"}`
+	if got := string(b); got != want {
+		t.Errorf("incorrect JSON:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
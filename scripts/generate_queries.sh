@@ -64,6 +64,12 @@ pushd ${BULK_DATA_DIR}
 # Loop over all requested queries types and generate data
 for QUERY_TYPE in ${QUERY_TYPES}; do
     for FORMAT in ${FORMATS}; do
+        # Skip query types the format declares it does not support
+        CAPABILITIES=$($EXE_FILE_NAME -format $FORMAT -capabilities 2> /dev/null)
+        if [[ -n "$CAPABILITIES" ]] && ! echo "$CAPABILITIES" | grep -q "\"${QUERY_TYPE}\""; then
+            echo "WARNING: query type $QUERY_TYPE is not supported by $FORMAT, skip generating queries"
+            continue
+        fi
         DATA_FILE_NAME="queries_${FORMAT}_${QUERY_TYPE}_${EXE_FILE_VERSION}_${QUERIES}_${SCALE}_${SEED}_${TS_START}_${TS_END}_${USE_CASE}.dat.gz"
         if [ -f "$DATA_FILE_NAME" ]; then
             echo "WARNING: file $DATA_FILE_NAME already exists, skip generating new data"